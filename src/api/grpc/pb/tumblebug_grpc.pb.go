@@ -0,0 +1,879 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: tumblebug.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Tumblebug_CreateNamespace_FullMethodName      = "/tumblebug.v1.Tumblebug/CreateNamespace"
+	Tumblebug_GetNamespace_FullMethodName         = "/tumblebug.v1.Tumblebug/GetNamespace"
+	Tumblebug_ListNamespaces_FullMethodName       = "/tumblebug.v1.Tumblebug/ListNamespaces"
+	Tumblebug_UpdateNamespace_FullMethodName      = "/tumblebug.v1.Tumblebug/UpdateNamespace"
+	Tumblebug_DeleteNamespace_FullMethodName      = "/tumblebug.v1.Tumblebug/DeleteNamespace"
+	Tumblebug_CreateMci_FullMethodName            = "/tumblebug.v1.Tumblebug/CreateMci"
+	Tumblebug_GetMci_FullMethodName               = "/tumblebug.v1.Tumblebug/GetMci"
+	Tumblebug_ControlMci_FullMethodName           = "/tumblebug.v1.Tumblebug/ControlMci"
+	Tumblebug_WatchMciProvisioning_FullMethodName = "/tumblebug.v1.Tumblebug/WatchMciProvisioning"
+	Tumblebug_CreateVNet_FullMethodName           = "/tumblebug.v1.Tumblebug/CreateVNet"
+	Tumblebug_GetVNet_FullMethodName              = "/tumblebug.v1.Tumblebug/GetVNet"
+	Tumblebug_ListVNets_FullMethodName            = "/tumblebug.v1.Tumblebug/ListVNets"
+	Tumblebug_DeleteVNet_FullMethodName           = "/tumblebug.v1.Tumblebug/DeleteVNet"
+	Tumblebug_CreateSecurityGroup_FullMethodName  = "/tumblebug.v1.Tumblebug/CreateSecurityGroup"
+	Tumblebug_GetSecurityGroup_FullMethodName     = "/tumblebug.v1.Tumblebug/GetSecurityGroup"
+	Tumblebug_ListSecurityGroups_FullMethodName   = "/tumblebug.v1.Tumblebug/ListSecurityGroups"
+	Tumblebug_DeleteSecurityGroup_FullMethodName  = "/tumblebug.v1.Tumblebug/DeleteSecurityGroup"
+	Tumblebug_CreateSshKey_FullMethodName         = "/tumblebug.v1.Tumblebug/CreateSshKey"
+	Tumblebug_GetSshKey_FullMethodName            = "/tumblebug.v1.Tumblebug/GetSshKey"
+	Tumblebug_ListSshKeys_FullMethodName          = "/tumblebug.v1.Tumblebug/ListSshKeys"
+	Tumblebug_DeleteSshKey_FullMethodName         = "/tumblebug.v1.Tumblebug/DeleteSshKey"
+)
+
+// TumblebugClient is the client API for Tumblebug service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TumblebugClient interface {
+	CreateNamespace(ctx context.Context, in *CreateNamespaceRequest, opts ...grpc.CallOption) (*Namespace, error)
+	GetNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*Namespace, error)
+	ListNamespaces(ctx context.Context, in *ListNamespacesRequest, opts ...grpc.CallOption) (*ListNamespacesResponse, error)
+	UpdateNamespace(ctx context.Context, in *UpdateNamespaceRequest, opts ...grpc.CallOption) (*Namespace, error)
+	DeleteNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*SimpleResponse, error)
+	CreateMci(ctx context.Context, in *CreateMciRequest, opts ...grpc.CallOption) (*Mci, error)
+	GetMci(ctx context.Context, in *MciRequest, opts ...grpc.CallOption) (*Mci, error)
+	ControlMci(ctx context.Context, in *ControlMciRequest, opts ...grpc.CallOption) (*SimpleResponse, error)
+	// WatchMciProvisioning streams the status of the MCI until the provisioning (or the ongoing action) is completed
+	WatchMciProvisioning(ctx context.Context, in *MciRequest, opts ...grpc.CallOption) (Tumblebug_WatchMciProvisioningClient, error)
+	CreateVNet(ctx context.Context, in *CreateVNetRequest, opts ...grpc.CallOption) (*VNet, error)
+	GetVNet(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*VNet, error)
+	ListVNets(ctx context.Context, in *ListResourcesRequest, opts ...grpc.CallOption) (*ListVNetsResponse, error)
+	DeleteVNet(ctx context.Context, in *DeleteResourceRequest, opts ...grpc.CallOption) (*SimpleResponse, error)
+	CreateSecurityGroup(ctx context.Context, in *CreateSecurityGroupRequest, opts ...grpc.CallOption) (*SecurityGroup, error)
+	GetSecurityGroup(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*SecurityGroup, error)
+	ListSecurityGroups(ctx context.Context, in *ListResourcesRequest, opts ...grpc.CallOption) (*ListSecurityGroupsResponse, error)
+	DeleteSecurityGroup(ctx context.Context, in *DeleteResourceRequest, opts ...grpc.CallOption) (*SimpleResponse, error)
+	CreateSshKey(ctx context.Context, in *CreateSshKeyRequest, opts ...grpc.CallOption) (*SshKey, error)
+	GetSshKey(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*SshKey, error)
+	ListSshKeys(ctx context.Context, in *ListResourcesRequest, opts ...grpc.CallOption) (*ListSshKeysResponse, error)
+	DeleteSshKey(ctx context.Context, in *DeleteResourceRequest, opts ...grpc.CallOption) (*SimpleResponse, error)
+}
+
+type tumblebugClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTumblebugClient(cc grpc.ClientConnInterface) TumblebugClient {
+	return &tumblebugClient{cc}
+}
+
+func (c *tumblebugClient) CreateNamespace(ctx context.Context, in *CreateNamespaceRequest, opts ...grpc.CallOption) (*Namespace, error) {
+	out := new(Namespace)
+	err := c.cc.Invoke(ctx, Tumblebug_CreateNamespace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) GetNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*Namespace, error) {
+	out := new(Namespace)
+	err := c.cc.Invoke(ctx, Tumblebug_GetNamespace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) ListNamespaces(ctx context.Context, in *ListNamespacesRequest, opts ...grpc.CallOption) (*ListNamespacesResponse, error) {
+	out := new(ListNamespacesResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_ListNamespaces_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) UpdateNamespace(ctx context.Context, in *UpdateNamespaceRequest, opts ...grpc.CallOption) (*Namespace, error) {
+	out := new(Namespace)
+	err := c.cc.Invoke(ctx, Tumblebug_UpdateNamespace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) DeleteNamespace(ctx context.Context, in *NamespaceRequest, opts ...grpc.CallOption) (*SimpleResponse, error) {
+	out := new(SimpleResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_DeleteNamespace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) CreateMci(ctx context.Context, in *CreateMciRequest, opts ...grpc.CallOption) (*Mci, error) {
+	out := new(Mci)
+	err := c.cc.Invoke(ctx, Tumblebug_CreateMci_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) GetMci(ctx context.Context, in *MciRequest, opts ...grpc.CallOption) (*Mci, error) {
+	out := new(Mci)
+	err := c.cc.Invoke(ctx, Tumblebug_GetMci_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) ControlMci(ctx context.Context, in *ControlMciRequest, opts ...grpc.CallOption) (*SimpleResponse, error) {
+	out := new(SimpleResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_ControlMci_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) WatchMciProvisioning(ctx context.Context, in *MciRequest, opts ...grpc.CallOption) (Tumblebug_WatchMciProvisioningClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Tumblebug_ServiceDesc.Streams[0], Tumblebug_WatchMciProvisioning_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &tumblebugWatchMciProvisioningClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Tumblebug_WatchMciProvisioningClient interface {
+	Recv() (*MciProgress, error)
+	grpc.ClientStream
+}
+
+type tumblebugWatchMciProvisioningClient struct {
+	grpc.ClientStream
+}
+
+func (x *tumblebugWatchMciProvisioningClient) Recv() (*MciProgress, error) {
+	m := new(MciProgress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *tumblebugClient) CreateVNet(ctx context.Context, in *CreateVNetRequest, opts ...grpc.CallOption) (*VNet, error) {
+	out := new(VNet)
+	err := c.cc.Invoke(ctx, Tumblebug_CreateVNet_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) GetVNet(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*VNet, error) {
+	out := new(VNet)
+	err := c.cc.Invoke(ctx, Tumblebug_GetVNet_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) ListVNets(ctx context.Context, in *ListResourcesRequest, opts ...grpc.CallOption) (*ListVNetsResponse, error) {
+	out := new(ListVNetsResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_ListVNets_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) DeleteVNet(ctx context.Context, in *DeleteResourceRequest, opts ...grpc.CallOption) (*SimpleResponse, error) {
+	out := new(SimpleResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_DeleteVNet_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) CreateSecurityGroup(ctx context.Context, in *CreateSecurityGroupRequest, opts ...grpc.CallOption) (*SecurityGroup, error) {
+	out := new(SecurityGroup)
+	err := c.cc.Invoke(ctx, Tumblebug_CreateSecurityGroup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) GetSecurityGroup(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*SecurityGroup, error) {
+	out := new(SecurityGroup)
+	err := c.cc.Invoke(ctx, Tumblebug_GetSecurityGroup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) ListSecurityGroups(ctx context.Context, in *ListResourcesRequest, opts ...grpc.CallOption) (*ListSecurityGroupsResponse, error) {
+	out := new(ListSecurityGroupsResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_ListSecurityGroups_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) DeleteSecurityGroup(ctx context.Context, in *DeleteResourceRequest, opts ...grpc.CallOption) (*SimpleResponse, error) {
+	out := new(SimpleResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_DeleteSecurityGroup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) CreateSshKey(ctx context.Context, in *CreateSshKeyRequest, opts ...grpc.CallOption) (*SshKey, error) {
+	out := new(SshKey)
+	err := c.cc.Invoke(ctx, Tumblebug_CreateSshKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) GetSshKey(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*SshKey, error) {
+	out := new(SshKey)
+	err := c.cc.Invoke(ctx, Tumblebug_GetSshKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) ListSshKeys(ctx context.Context, in *ListResourcesRequest, opts ...grpc.CallOption) (*ListSshKeysResponse, error) {
+	out := new(ListSshKeysResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_ListSshKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tumblebugClient) DeleteSshKey(ctx context.Context, in *DeleteResourceRequest, opts ...grpc.CallOption) (*SimpleResponse, error) {
+	out := new(SimpleResponse)
+	err := c.cc.Invoke(ctx, Tumblebug_DeleteSshKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TumblebugServer is the server API for Tumblebug service.
+// All implementations must embed UnimplementedTumblebugServer
+// for forward compatibility
+type TumblebugServer interface {
+	CreateNamespace(context.Context, *CreateNamespaceRequest) (*Namespace, error)
+	GetNamespace(context.Context, *NamespaceRequest) (*Namespace, error)
+	ListNamespaces(context.Context, *ListNamespacesRequest) (*ListNamespacesResponse, error)
+	UpdateNamespace(context.Context, *UpdateNamespaceRequest) (*Namespace, error)
+	DeleteNamespace(context.Context, *NamespaceRequest) (*SimpleResponse, error)
+	CreateMci(context.Context, *CreateMciRequest) (*Mci, error)
+	GetMci(context.Context, *MciRequest) (*Mci, error)
+	ControlMci(context.Context, *ControlMciRequest) (*SimpleResponse, error)
+	// WatchMciProvisioning streams the status of the MCI until the provisioning (or the ongoing action) is completed
+	WatchMciProvisioning(*MciRequest, Tumblebug_WatchMciProvisioningServer) error
+	CreateVNet(context.Context, *CreateVNetRequest) (*VNet, error)
+	GetVNet(context.Context, *ResourceRequest) (*VNet, error)
+	ListVNets(context.Context, *ListResourcesRequest) (*ListVNetsResponse, error)
+	DeleteVNet(context.Context, *DeleteResourceRequest) (*SimpleResponse, error)
+	CreateSecurityGroup(context.Context, *CreateSecurityGroupRequest) (*SecurityGroup, error)
+	GetSecurityGroup(context.Context, *ResourceRequest) (*SecurityGroup, error)
+	ListSecurityGroups(context.Context, *ListResourcesRequest) (*ListSecurityGroupsResponse, error)
+	DeleteSecurityGroup(context.Context, *DeleteResourceRequest) (*SimpleResponse, error)
+	CreateSshKey(context.Context, *CreateSshKeyRequest) (*SshKey, error)
+	GetSshKey(context.Context, *ResourceRequest) (*SshKey, error)
+	ListSshKeys(context.Context, *ListResourcesRequest) (*ListSshKeysResponse, error)
+	DeleteSshKey(context.Context, *DeleteResourceRequest) (*SimpleResponse, error)
+	mustEmbedUnimplementedTumblebugServer()
+}
+
+// UnimplementedTumblebugServer must be embedded to have forward compatible implementations.
+type UnimplementedTumblebugServer struct {
+}
+
+func (UnimplementedTumblebugServer) CreateNamespace(context.Context, *CreateNamespaceRequest) (*Namespace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateNamespace not implemented")
+}
+func (UnimplementedTumblebugServer) GetNamespace(context.Context, *NamespaceRequest) (*Namespace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNamespace not implemented")
+}
+func (UnimplementedTumblebugServer) ListNamespaces(context.Context, *ListNamespacesRequest) (*ListNamespacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNamespaces not implemented")
+}
+func (UnimplementedTumblebugServer) UpdateNamespace(context.Context, *UpdateNamespaceRequest) (*Namespace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateNamespace not implemented")
+}
+func (UnimplementedTumblebugServer) DeleteNamespace(context.Context, *NamespaceRequest) (*SimpleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNamespace not implemented")
+}
+func (UnimplementedTumblebugServer) CreateMci(context.Context, *CreateMciRequest) (*Mci, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateMci not implemented")
+}
+func (UnimplementedTumblebugServer) GetMci(context.Context, *MciRequest) (*Mci, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMci not implemented")
+}
+func (UnimplementedTumblebugServer) ControlMci(context.Context, *ControlMciRequest) (*SimpleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ControlMci not implemented")
+}
+func (UnimplementedTumblebugServer) WatchMciProvisioning(*MciRequest, Tumblebug_WatchMciProvisioningServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMciProvisioning not implemented")
+}
+func (UnimplementedTumblebugServer) CreateVNet(context.Context, *CreateVNetRequest) (*VNet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateVNet not implemented")
+}
+func (UnimplementedTumblebugServer) GetVNet(context.Context, *ResourceRequest) (*VNet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVNet not implemented")
+}
+func (UnimplementedTumblebugServer) ListVNets(context.Context, *ListResourcesRequest) (*ListVNetsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVNets not implemented")
+}
+func (UnimplementedTumblebugServer) DeleteVNet(context.Context, *DeleteResourceRequest) (*SimpleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVNet not implemented")
+}
+func (UnimplementedTumblebugServer) CreateSecurityGroup(context.Context, *CreateSecurityGroupRequest) (*SecurityGroup, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSecurityGroup not implemented")
+}
+func (UnimplementedTumblebugServer) GetSecurityGroup(context.Context, *ResourceRequest) (*SecurityGroup, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSecurityGroup not implemented")
+}
+func (UnimplementedTumblebugServer) ListSecurityGroups(context.Context, *ListResourcesRequest) (*ListSecurityGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSecurityGroups not implemented")
+}
+func (UnimplementedTumblebugServer) DeleteSecurityGroup(context.Context, *DeleteResourceRequest) (*SimpleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSecurityGroup not implemented")
+}
+func (UnimplementedTumblebugServer) CreateSshKey(context.Context, *CreateSshKeyRequest) (*SshKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSshKey not implemented")
+}
+func (UnimplementedTumblebugServer) GetSshKey(context.Context, *ResourceRequest) (*SshKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSshKey not implemented")
+}
+func (UnimplementedTumblebugServer) ListSshKeys(context.Context, *ListResourcesRequest) (*ListSshKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSshKeys not implemented")
+}
+func (UnimplementedTumblebugServer) DeleteSshKey(context.Context, *DeleteResourceRequest) (*SimpleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSshKey not implemented")
+}
+func (UnimplementedTumblebugServer) mustEmbedUnimplementedTumblebugServer() {}
+
+// UnsafeTumblebugServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TumblebugServer will
+// result in compilation errors.
+type UnsafeTumblebugServer interface {
+	mustEmbedUnimplementedTumblebugServer()
+}
+
+func RegisterTumblebugServer(s grpc.ServiceRegistrar, srv TumblebugServer) {
+	s.RegisterService(&Tumblebug_ServiceDesc, srv)
+}
+
+func _Tumblebug_CreateNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateNamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).CreateNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_CreateNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).CreateNamespace(ctx, req.(*CreateNamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_GetNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).GetNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_GetNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).GetNamespace(ctx, req.(*NamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_ListNamespaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNamespacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).ListNamespaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_ListNamespaces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).ListNamespaces(ctx, req.(*ListNamespacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_UpdateNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateNamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).UpdateNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_UpdateNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).UpdateNamespace(ctx, req.(*UpdateNamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_DeleteNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).DeleteNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_DeleteNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).DeleteNamespace(ctx, req.(*NamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_CreateMci_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateMciRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).CreateMci(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_CreateMci_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).CreateMci(ctx, req.(*CreateMciRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_GetMci_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MciRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).GetMci(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_GetMci_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).GetMci(ctx, req.(*MciRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_ControlMci_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlMciRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).ControlMci(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_ControlMci_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).ControlMci(ctx, req.(*ControlMciRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_WatchMciProvisioning_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MciRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TumblebugServer).WatchMciProvisioning(m, &tumblebugWatchMciProvisioningServer{stream})
+}
+
+type Tumblebug_WatchMciProvisioningServer interface {
+	Send(*MciProgress) error
+	grpc.ServerStream
+}
+
+type tumblebugWatchMciProvisioningServer struct {
+	grpc.ServerStream
+}
+
+func (x *tumblebugWatchMciProvisioningServer) Send(m *MciProgress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Tumblebug_CreateVNet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateVNetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).CreateVNet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_CreateVNet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).CreateVNet(ctx, req.(*CreateVNetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_GetVNet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResourceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).GetVNet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_GetVNet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).GetVNet(ctx, req.(*ResourceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_ListVNets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResourcesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).ListVNets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_ListVNets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).ListVNets(ctx, req.(*ListResourcesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_DeleteVNet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteResourceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).DeleteVNet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_DeleteVNet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).DeleteVNet(ctx, req.(*DeleteResourceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_CreateSecurityGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSecurityGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).CreateSecurityGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_CreateSecurityGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).CreateSecurityGroup(ctx, req.(*CreateSecurityGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_GetSecurityGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResourceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).GetSecurityGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_GetSecurityGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).GetSecurityGroup(ctx, req.(*ResourceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_ListSecurityGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResourcesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).ListSecurityGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_ListSecurityGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).ListSecurityGroups(ctx, req.(*ListResourcesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_DeleteSecurityGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteResourceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).DeleteSecurityGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_DeleteSecurityGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).DeleteSecurityGroup(ctx, req.(*DeleteResourceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_CreateSshKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSshKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).CreateSshKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_CreateSshKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).CreateSshKey(ctx, req.(*CreateSshKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_GetSshKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResourceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).GetSshKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_GetSshKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).GetSshKey(ctx, req.(*ResourceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_ListSshKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResourcesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).ListSshKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_ListSshKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).ListSshKeys(ctx, req.(*ListResourcesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tumblebug_DeleteSshKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteResourceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TumblebugServer).DeleteSshKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tumblebug_DeleteSshKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TumblebugServer).DeleteSshKey(ctx, req.(*DeleteResourceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Tumblebug_ServiceDesc is the grpc.ServiceDesc for Tumblebug service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Tumblebug_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tumblebug.v1.Tumblebug",
+	HandlerType: (*TumblebugServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateNamespace",
+			Handler:    _Tumblebug_CreateNamespace_Handler,
+		},
+		{
+			MethodName: "GetNamespace",
+			Handler:    _Tumblebug_GetNamespace_Handler,
+		},
+		{
+			MethodName: "ListNamespaces",
+			Handler:    _Tumblebug_ListNamespaces_Handler,
+		},
+		{
+			MethodName: "UpdateNamespace",
+			Handler:    _Tumblebug_UpdateNamespace_Handler,
+		},
+		{
+			MethodName: "DeleteNamespace",
+			Handler:    _Tumblebug_DeleteNamespace_Handler,
+		},
+		{
+			MethodName: "CreateMci",
+			Handler:    _Tumblebug_CreateMci_Handler,
+		},
+		{
+			MethodName: "GetMci",
+			Handler:    _Tumblebug_GetMci_Handler,
+		},
+		{
+			MethodName: "ControlMci",
+			Handler:    _Tumblebug_ControlMci_Handler,
+		},
+		{
+			MethodName: "CreateVNet",
+			Handler:    _Tumblebug_CreateVNet_Handler,
+		},
+		{
+			MethodName: "GetVNet",
+			Handler:    _Tumblebug_GetVNet_Handler,
+		},
+		{
+			MethodName: "ListVNets",
+			Handler:    _Tumblebug_ListVNets_Handler,
+		},
+		{
+			MethodName: "DeleteVNet",
+			Handler:    _Tumblebug_DeleteVNet_Handler,
+		},
+		{
+			MethodName: "CreateSecurityGroup",
+			Handler:    _Tumblebug_CreateSecurityGroup_Handler,
+		},
+		{
+			MethodName: "GetSecurityGroup",
+			Handler:    _Tumblebug_GetSecurityGroup_Handler,
+		},
+		{
+			MethodName: "ListSecurityGroups",
+			Handler:    _Tumblebug_ListSecurityGroups_Handler,
+		},
+		{
+			MethodName: "DeleteSecurityGroup",
+			Handler:    _Tumblebug_DeleteSecurityGroup_Handler,
+		},
+		{
+			MethodName: "CreateSshKey",
+			Handler:    _Tumblebug_CreateSshKey_Handler,
+		},
+		{
+			MethodName: "GetSshKey",
+			Handler:    _Tumblebug_GetSshKey_Handler,
+		},
+		{
+			MethodName: "ListSshKeys",
+			Handler:    _Tumblebug_ListSshKeys_Handler,
+		},
+		{
+			MethodName: "DeleteSshKey",
+			Handler:    _Tumblebug_DeleteSshKey_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchMciProvisioning",
+			Handler:       _Tumblebug_WatchMciProvisioning_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "tumblebug.proto",
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server is to handle gRPC API
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// callerKey is the context key of the caller of an RPC, filled by the auth interceptors after authentication
+type callerKey struct{}
+
+// withCallerSlot returns the context with an empty caller to be filled by the auth interceptors
+func withCallerSlot(ctx context.Context) (context.Context, *caller) {
+	c := &caller{}
+	return context.WithValue(ctx, callerKey{}, c), c
+}
+
+// setCaller stores the authenticated caller to the context prepared by withCallerSlot
+func setCaller(ctx context.Context, c caller) {
+	if slot, ok := ctx.Value(callerKey{}).(*caller); ok {
+		*slot = c
+	}
+}
+
+// auditServerStream is a server stream with the context for the audit
+type auditServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the caller slot
+func (s *auditServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// auditUnaryInterceptor records mutating unary RPCs to the audit log (same as the Audit middleware of the REST API).
+// It runs before the auth interceptor, so rejected calls are also recorded, with the verified caller only.
+func auditUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if isReadOnlyMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	timestamp := time.Now()
+	ctx, c := withCallerSlot(ctx)
+	resp, err := handler(ctx, req)
+	appendAuditRecord(ctx, timestamp, info.FullMethod, *c, req, err)
+	return resp, err
+}
+
+// auditStreamInterceptor records mutating streaming RPCs to the audit log (all streaming RPCs are read-only for now)
+func auditStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if isReadOnlyMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	timestamp := time.Now()
+	ctx, c := withCallerSlot(ss.Context())
+	err := handler(srv, &auditServerStream{ServerStream: ss, ctx: ctx})
+	appendAuditRecord(ctx, timestamp, info.FullMethod, *c, nil, err)
+	return err
+}
+
+// appendAuditRecord stores the audit record of the RPC
+func appendAuditRecord(ctx context.Context, timestamp time.Time, fullMethod string, c caller, req interface{}, err error) {
+	user := c.name
+	if user == "" {
+		user = "anonymous"
+	}
+	requestId := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		requestId = firstMetadata(md, "x-request-id")
+	}
+	remoteIp := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteIp = p.Addr.String()
+		if host, _, splitErr := net.SplitHostPort(remoteIp); splitErr == nil {
+			remoteIp = host
+		}
+	}
+
+	var body interface{}
+	if m, ok := req.(proto.Message); ok {
+		if b, marshalErr := protojson.Marshal(m); marshalErr == nil {
+			json.Unmarshal(b, &body)
+		}
+	}
+
+	record := model.AuditRecord{
+		Timestamp:   timestamp,
+		User:        user,
+		Method:      "gRPC",
+		Route:       fullMethod,
+		Path:        fullMethod,
+		RequestBody: common.RedactAuditBody(fullMethod, body),
+		Status:      httpStatusOf(err),
+		RequestId:   requestId,
+		RemoteIp:    remoteIp,
+	}
+	if auditErr := common.AppendAuditRecord(record); auditErr != nil {
+		log.Error().Err(auditErr).Msgf("Failed to store audit record (%s)", fullMethod)
+	}
+}
+
+// httpStatusOf returns the HTTP status code for the result of an RPC (the reverse of toStatusError)
+func httpStatusOf(err error) int {
+	switch status.Code(err) {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server is to handle gRPC API
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/rest/server/middlewares/authmw"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/m-cmp/mc-iam-manager/iamtokenvalidator"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// readOnlyMethodPrefixes are the prefixes of RPCs which do not change any object (allowed for readonly users and keys)
+var readOnlyMethodPrefixes = []string{"Get", "List", "Watch"}
+
+// authenticator authenticates RPCs in the same way as the REST API (TB_AUTH_ENABLED and TB_AUTH_MODE)
+type authenticator struct {
+	enabled        bool
+	mode           string
+	basicAuthUsers []authmw.BasicAuthUser
+	adminUser      string
+	adminPass      string
+}
+
+// caller is the authenticated caller of an RPC
+type caller struct {
+	name     string
+	readOnly bool
+	// nsId is the namespace of a namespace-restricted API key
+	nsId string
+}
+
+// nsRequest is a request with the namespace ID
+type nsRequest interface {
+	GetNsId() string
+}
+
+// newAuthenticator returns the authenticator by the environment variables for the auth of the REST API
+func newAuthenticator() (*authenticator, error) {
+	a := &authenticator{
+		enabled:   os.Getenv("TB_AUTH_ENABLED") == "true",
+		mode:      os.Getenv("TB_AUTH_MODE"),
+		adminUser: os.Getenv("TB_API_USERNAME"),
+		adminPass: os.Getenv("TB_API_PASSWORD"),
+	}
+	if !a.enabled {
+		return a, nil
+	}
+	switch a.mode {
+	case "basic":
+		users, err := authmw.LoadBasicAuthUsers(os.Getenv("TB_API_USERS_FILE"), os.Getenv("TB_API_USERS"), a.adminUser, a.adminPass)
+		if err != nil {
+			return nil, err
+		}
+		a.basicAuthUsers = users
+	case "jwt", "apikey":
+		// the public key of the IAM manager is loaded by the REST API server (InitJwtAuthMw)
+	default:
+		return nil, fmt.Errorf("TB_AUTH_MODE is not set properly (%s); 'basic', 'jwt' or 'apikey' is required", a.mode)
+	}
+	return a, nil
+}
+
+// isReadOnlyMethod returns true if the RPC (full method name) does not change any object
+func isReadOnlyMethod(fullMethod string) bool {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, prefix := range readOnlyMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate returns the caller of the RPC by the metadata (authorization or x-api-key)
+func (a *authenticator) authenticate(ctx context.Context) (caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := firstMetadata(md, "authorization")
+
+	switch a.mode {
+	case "basic":
+		username, password, ok := parseBasicAuth(authorization)
+		if !ok {
+			return caller{}, status.Error(codes.Unauthenticated, "basic auth credential is required (authorization metadata)")
+		}
+		// Be careful to use constant time comparison to prevent timing attacks
+		for _, u := range a.basicAuthUsers {
+			if subtle.ConstantTimeCompare([]byte(username), []byte(u.Username)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(password), []byte(u.Password)) == 1 {
+				return caller{name: u.Username, readOnly: u.Role == authmw.RoleReadOnly}, nil
+			}
+		}
+		return caller{}, status.Error(codes.Unauthenticated, "invalid credential")
+
+	case "jwt":
+		tokenString := strings.TrimPrefix(authorization, "Bearer ")
+		if tokenString == "" || tokenString == authorization {
+			return caller{}, status.Error(codes.Unauthenticated, "bearer token is required (authorization metadata)")
+		}
+		token, err := jwt.Parse(tokenString, iamtokenvalidator.Keyfunction)
+		if err != nil || !token.Valid {
+			return caller{}, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		claims, err := iamtokenvalidator.GetTokenClaimsByIamManagerClaims(tokenString)
+		if err != nil {
+			return caller{}, status.Error(codes.Unauthenticated, "invalid token claims")
+		}
+		return caller{name: claims.UserName}, nil
+
+	case "apikey":
+		if username, password, ok := parseBasicAuth(authorization); ok {
+			if a.adminUser != "" &&
+				subtle.ConstantTimeCompare([]byte(username), []byte(a.adminUser)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(password), []byte(a.adminPass)) == 1 {
+				return caller{name: username}, nil
+			}
+			return caller{}, status.Error(codes.Unauthenticated, "invalid admin credential")
+		}
+		key := firstMetadata(md, strings.ToLower(model.ApiKeyHeader))
+		if key == "" {
+			return caller{}, status.Errorf(codes.Unauthenticated, "%s metadata is required", strings.ToLower(model.ApiKeyHeader))
+		}
+		keyInfo, err := common.ValidateApiKey(key)
+		if err != nil {
+			return caller{}, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return caller{name: "apikey:" + keyInfo.Name, readOnly: keyInfo.Scope == model.ApiKeyScopeReadOnly, nsId: keyInfo.NsId}, nil
+	}
+	return caller{}, status.Error(codes.Unauthenticated, "unknown auth mode")
+}
+
+// authorize checks the permission of the caller for the RPC and the request
+func (a *authenticator) authorize(c caller, fullMethod string, req interface{}) error {
+	readOnly := isReadOnlyMethod(fullMethod)
+	if c.readOnly && !readOnly {
+		return status.Errorf(codes.PermissionDenied, "%s is not allowed for the read-only caller (%s)", fullMethod, c.name)
+	}
+	// a namespace-restricted key can read and change objects only in its namespace
+	// (read RPCs without a namespace, e.g. ListNamespaces, are allowed as GET /tumblebug/ns of the REST API)
+	if c.nsId != "" {
+		r, ok := req.(nsRequest)
+		if (ok && r.GetNsId() != c.nsId) || (!ok && !readOnly) {
+			return status.Errorf(codes.PermissionDenied, "the caller (%s) is restricted to the namespace (%s)", c.name, c.nsId)
+		}
+	}
+	return nil
+}
+
+// authorizedServerStream authorizes the request messages of a streaming RPC when they are received
+type authorizedServerStream struct {
+	grpc.ServerStream
+	authorize func(req interface{}) error
+}
+
+// RecvMsg receives the request message and checks the permission of the caller for it
+func (s *authorizedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.authorize(m)
+}
+
+// unaryInterceptor authenticates and authorizes unary RPCs
+func (a *authenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if common.IsDraining() && !isReadOnlyMethod(info.FullMethod) {
+		return nil, status.Error(codes.Unavailable, "CB-Tumblebug is shutting down and does not accept new requests")
+	}
+	if a.enabled {
+		c, err := a.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		setCaller(ctx, c)
+		if err := a.authorize(c, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor authenticates and authorizes streaming RPCs (all streaming RPCs are read-only)
+func (a *authenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if a.enabled {
+		c, err := a.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		setCaller(ss.Context(), c)
+		if err := a.authorize(c, info.FullMethod, nil); err != nil {
+			return err
+		}
+		// the request message (with the namespace) is received by the handler after the interceptor
+		ss = &authorizedServerStream{ServerStream: ss, authorize: func(req interface{}) error {
+			return a.authorize(c, info.FullMethod, req)
+		}}
+	}
+	return handler(srv, ss)
+}
+
+// recoveryUnaryInterceptor returns Internal instead of crashing the server by a panic in a handler
+func recoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Msgf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Errorf(codes.Internal, "panic in %s: %v", info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// recoveryStreamInterceptor returns Internal instead of crashing the server by a panic in a handler
+func recoveryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Msgf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Errorf(codes.Internal, "panic in %s: %v", info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// firstMetadata returns the first value of the key in the metadata
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// parseBasicAuth parses the basic auth credential ("Basic base64(username:password)")
+func parseBasicAuth(authorization string) (string, string, bool) {
+	const prefix = "Basic "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(authorization[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	return username, password, ok
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/grpc/pb"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "grpcserver")
+	if err != nil {
+		panic(err)
+	}
+	store, err := bolt.NewBoltStore(context.Background(), bolt.Config{Path: filepath.Join(dir, "kvstore.db")})
+	if err != nil {
+		panic(err)
+	}
+	kvstore.InitializeStore(store)
+	code := m.Run()
+	store.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newBufconnClient serves the gRPC server with the authenticator on an in-memory listener and returns a client
+func newBufconnClient(t *testing.T, auth *authenticator) pb.TumblebugClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	s := newGrpcServer(auth)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewTumblebugClient(conn)
+}
+
+func TestNamespaceRestrictedApiKey(t *testing.T) {
+	for _, nsId := range []string{"grpc-ns01", "grpc-ns02"} {
+		if _, err := common.CreateNs(&model.NsReq{Name: nsId}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created, err := common.CreateApiKey(&model.ApiKeyReq{Name: "grpc-ns01-key", Scope: model.ApiKeyScopeReadWrite, NsId: "grpc-ns01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newBufconnClient(t, &authenticator{enabled: true, mode: "apikey"})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", created.Key)
+
+	tests := []struct {
+		name   string
+		call   func() error
+		denied bool
+	}{
+		{"get namespace in own namespace", func() error {
+			_, err := client.GetNamespace(ctx, &pb.NamespaceRequest{NsId: "grpc-ns01"})
+			return err
+		}, false},
+		{"get namespace in other namespace", func() error {
+			_, err := client.GetNamespace(ctx, &pb.NamespaceRequest{NsId: "grpc-ns02"})
+			return err
+		}, true},
+		{"list vNets in other namespace", func() error {
+			_, err := client.ListVNets(ctx, &pb.ListResourcesRequest{NsId: "grpc-ns02"})
+			return err
+		}, true},
+		{"get ssh key in other namespace", func() error {
+			_, err := client.GetSshKey(ctx, &pb.ResourceRequest{NsId: "grpc-ns02", ResourceId: "key01"})
+			return err
+		}, true},
+		{"get mci in other namespace", func() error {
+			_, err := client.GetMci(ctx, &pb.MciRequest{NsId: "grpc-ns02", MciId: "mci01"})
+			return err
+		}, true},
+		{"delete vNet in other namespace", func() error {
+			_, err := client.DeleteVNet(ctx, &pb.DeleteResourceRequest{NsId: "grpc-ns02", ResourceId: "vnet01"})
+			return err
+		}, true},
+		{"list namespaces", func() error {
+			_, err := client.ListNamespaces(ctx, &pb.ListNamespacesRequest{})
+			return err
+		}, false},
+		{"watch mci in other namespace", func() error {
+			watchCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+			defer cancel()
+			stream, err := client.WatchMciProvisioning(watchCtx, &pb.MciRequest{NsId: "grpc-ns02", MciId: "mci01"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			denied := status.Code(err) == codes.PermissionDenied
+			if denied != tt.denied {
+				t.Errorf("got %v, want denied=%v", err, tt.denied)
+			}
+		})
+	}
+}
+
+func TestReadOnlyApiKey(t *testing.T) {
+	created, err := common.CreateApiKey(&model.ApiKeyReq{Name: "grpc-viewer", Scope: model.ApiKeyScopeReadOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newBufconnClient(t, &authenticator{enabled: true, mode: "apikey"})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", created.Key)
+
+	if _, err := client.ListNamespaces(ctx, &pb.ListNamespacesRequest{}); err != nil {
+		t.Errorf("ListNamespaces: unexpected error %v", err)
+	}
+	_, err = client.DeleteNamespace(ctx, &pb.NamespaceRequest{NsId: "grpc-ns01"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("DeleteNamespace: got %v, want PermissionDenied", err)
+	}
+}
+
+func TestMissingApiKey(t *testing.T) {
+	client := newBufconnClient(t, &authenticator{enabled: true, mode: "apikey"})
+	_, err := client.ListNamespaces(context.Background(), &pb.ListNamespacesRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("got %v, want Unauthenticated", err)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server is to handle gRPC API
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/grpc/pb"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// mciWatchInterval is the interval to check the status of the MCI for WatchMciProvisioning
+	mciWatchInterval = 5 * time.Second
+	// mciWatchWaitForCreation is how long WatchMciProvisioning waits for the MCI to be created
+	mciWatchWaitForCreation = 1 * time.Minute
+)
+
+// newRequestId returns the request ID of the RPC (x-request-id metadata or a new one) and registers the request details,
+// so that the progress of the request can be retrieved by GET /request/{reqId}.
+// An ID already in use is rejected (same as the RequestIdAndDetailsIssuer middleware of the REST API).
+func newRequestId(ctx context.Context, fullMethod string) (string, error) {
+	reqID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		reqID = firstMetadata(md, "x-request-id")
+	}
+	if reqID == "" {
+		reqID = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	details := common.RequestDetails{
+		StartTime:   time.Now(),
+		Status:      common.RequestStatusHandling,
+		RequestInfo: common.RequestInfo{Method: "gRPC", URL: fullMethod, Header: map[string]string{echo.HeaderXRequestID: reqID}},
+	}
+	if _, loaded := common.RequestMap.LoadOrStore(reqID, details); loaded {
+		return "", status.Errorf(codes.AlreadyExists, "the x-request-id (%s) is already in use", reqID)
+	}
+	return reqID, nil
+}
+
+// endRequest updates the request details with the result of the RPC
+func endRequest(reqID string, err error) {
+	if v, ok := common.RequestMap.Load(reqID); ok {
+		details := v.(common.RequestDetails)
+		details.EndTime = time.Now()
+		details.DurationMs = details.EndTime.Sub(details.StartTime).Milliseconds()
+		details.Status = common.RequestStatusSuccess
+		if err != nil {
+			details.Status = common.RequestStatusError
+			details.ErrorResponse = err.Error()
+		}
+		common.RequestMap.Store(reqID, details)
+	}
+}
+
+// toPbVm converts the VM object to the gRPC message
+func toPbVm(vm model.TbVmInfo) *pb.Vm {
+	return &pb.Vm{
+		Id:             vm.Id,
+		Uid:            vm.Uid,
+		Name:           vm.Name,
+		SubGroupId:     vm.SubGroupId,
+		Status:         vm.Status,
+		TargetStatus:   vm.TargetStatus,
+		TargetAction:   vm.TargetAction,
+		ConnectionName: vm.ConnectionName,
+		SpecId:         vm.SpecId,
+		ImageId:        vm.ImageId,
+		PublicIp:       vm.PublicIP,
+		PrivateIp:      vm.PrivateIP,
+		Region:         vm.Region.Region,
+		Zone:           vm.Region.Zone,
+		SystemMessage:  vm.SystemMessage,
+	}
+}
+
+// toPbMci converts the MCI object to the gRPC message
+func toPbMci(mci *model.TbMciInfo) *pb.Mci {
+	result := &pb.Mci{
+		Id:            mci.Id,
+		Uid:           mci.Uid,
+		Name:          mci.Name,
+		Description:   mci.Description,
+		Status:        mci.Status,
+		TargetStatus:  mci.TargetStatus,
+		TargetAction:  mci.TargetAction,
+		SystemMessage: mci.SystemMessage,
+	}
+	for _, vm := range mci.Vm {
+		result.Vm = append(result.Vm, toPbVm(vm))
+	}
+	return result
+}
+
+// toPbMciProgress converts the MCI status to the progress message of WatchMciProvisioning
+func toPbMciProgress(mciStatus *model.MciStatusInfo) *pb.MciProgress {
+	count := mciStatus.StatusCount
+	progress := &pb.MciProgress{
+		MciId:        mciStatus.Id,
+		Status:       mciStatus.Status,
+		TargetStatus: mciStatus.TargetStatus,
+		TargetAction: mciStatus.TargetAction,
+		StatusCount: &pb.StatusCount{
+			Total:       int32(count.CountTotal),
+			Creating:    int32(count.CountCreating),
+			Running:     int32(count.CountRunning),
+			Failed:      int32(count.CountFailed),
+			Suspended:   int32(count.CountSuspended),
+			Rebooting:   int32(count.CountRebooting),
+			Terminated:  int32(count.CountTerminated),
+			Suspending:  int32(count.CountSuspending),
+			Resuming:    int32(count.CountResuming),
+			Terminating: int32(count.CountTerminating),
+			Undefined:   int32(count.CountUndefined),
+		},
+		Time: time.Now().Format(time.RFC3339),
+	}
+	for _, vm := range mciStatus.Vm {
+		progress.Vm = append(progress.Vm, &pb.Vm{
+			Id:            vm.Id,
+			Uid:           vm.Uid,
+			Name:          vm.Name,
+			Status:        vm.Status,
+			TargetStatus:  vm.TargetStatus,
+			TargetAction:  vm.TargetAction,
+			PublicIp:      vm.PublicIp,
+			PrivateIp:     vm.PrivateIp,
+			SystemMessage: vm.SystemMessage,
+		})
+	}
+	// the ongoing action is completed when the target action is cleared
+	progress.Done = mciStatus.TargetAction == model.ActionComplete || mciStatus.TargetAction == ""
+	return progress
+}
+
+// CreateMci creates an MCI by the dynamic request (common specs and images).
+// The progress can be watched by WatchMciProvisioning or GET /request/{reqId} (x-request-id metadata).
+func (s *tumblebugServer) CreateMci(ctx context.Context, req *pb.CreateMciRequest) (*pb.Mci, error) {
+	mciReq := &model.TbMciDynamicReq{
+		Name:            req.GetName(),
+		Description:     req.GetDescription(),
+		InstallMonAgent: req.GetInstallMonAgent(),
+	}
+	for _, vm := range req.GetVm() {
+		vmReq := model.TbVmDynamicReq{
+			Name:           vm.GetName(),
+			Description:    vm.GetDescription(),
+			CommonSpec:     vm.GetCommonSpec(),
+			CommonImage:    vm.GetCommonImage(),
+			RootDiskType:   vm.GetRootDiskType(),
+			RootDiskSize:   vm.GetRootDiskSize(),
+			ConnectionName: vm.GetConnectionName(),
+			Zone:           vm.GetZone(),
+		}
+		if vm.GetSubGroupSize() > 0 {
+			vmReq.SubGroupSize = strconv.Itoa(int(vm.GetSubGroupSize()))
+		}
+		mciReq.Vm = append(mciReq.Vm, vmReq)
+	}
+	reqID, err := newRequestId(ctx, pb.Tumblebug_CreateMci_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+	mci, err := infra.CreateMciDynamic(common.WithRequestId(ctx, reqID), reqID, req.GetNsId(), mciReq, req.GetDeployOption())
+	endRequest(reqID, err)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbMci(mci), nil
+}
+
+// GetMci returns the MCI with the current status of VMs
+func (s *tumblebugServer) GetMci(ctx context.Context, req *pb.MciRequest) (*pb.Mci, error) {
+	mci, err := infra.GetMciInfo(req.GetNsId(), req.GetMciId())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbMci(mci), nil
+}
+
+// ControlMci performs the action (suspend, resume, reboot, terminate, refine, continue, withdraw) for the MCI
+func (s *tumblebugServer) ControlMci(ctx context.Context, req *pb.ControlMciRequest) (*pb.SimpleResponse, error) {
+	result, err := infra.HandleMciAction(req.GetNsId(), req.GetMciId(), req.GetAction(), req.GetForce())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.SimpleResponse{Message: result}, nil
+}
+
+// WatchMciProvisioning streams the status of the MCI whenever it is changed until the ongoing action is completed.
+// It waits for the MCI to be created for a while, so it can be called right after CreateMci is requested.
+func (s *tumblebugServer) WatchMciProvisioning(req *pb.MciRequest, stream pb.Tumblebug_WatchMciProvisioningServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(mciWatchInterval)
+	defer ticker.Stop()
+	waitUntil := time.Now().Add(mciWatchWaitForCreation)
+	lastStatus := ""
+
+	for {
+		mciStatus, err := infra.GetMciStatus(req.GetNsId(), req.GetMciId())
+		var apiErr *common.ApiError
+		switch {
+		case err == nil:
+			progress := toPbMciProgress(mciStatus)
+			current := fmt.Sprintf("%s/%s/%s/%v", progress.Status, progress.TargetStatus, progress.TargetAction, progress.StatusCount)
+			if current != lastStatus || progress.Done {
+				if err := stream.Send(progress); err != nil {
+					return err
+				}
+				lastStatus = current
+			}
+			if progress.Done {
+				return nil
+			}
+		case errors.As(err, &apiErr) && apiErr.Code == model.ErrCodeResourceNotFound && time.Now().Before(waitUntil):
+			// the MCI is not created yet
+		default:
+			return toStatusError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-common.DrainingNotify():
+			return toStatusError(fmt.Errorf("CB-Tumblebug is shutting down"))
+		case <-ticker.C:
+		}
+	}
+}
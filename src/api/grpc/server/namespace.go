@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server is to handle gRPC API
+package server
+
+import (
+	"context"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/grpc/pb"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// toPbNamespace converts the namespace object to the gRPC message
+func toPbNamespace(ns model.NsInfo) *pb.Namespace {
+	return &pb.Namespace{Id: ns.Id, Uid: ns.Uid, Name: ns.Name, Description: ns.Description}
+}
+
+// CreateNamespace creates a namespace
+func (s *tumblebugServer) CreateNamespace(ctx context.Context, req *pb.CreateNamespaceRequest) (*pb.Namespace, error) {
+	ns, err := common.CreateNs(&model.NsReq{Name: req.GetName(), Description: req.GetDescription()})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbNamespace(ns), nil
+}
+
+// GetNamespace returns the namespace
+func (s *tumblebugServer) GetNamespace(ctx context.Context, req *pb.NamespaceRequest) (*pb.Namespace, error) {
+	ns, err := common.GetNs(req.GetNsId())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbNamespace(ns), nil
+}
+
+// ListNamespaces returns all namespaces
+func (s *tumblebugServer) ListNamespaces(ctx context.Context, req *pb.ListNamespacesRequest) (*pb.ListNamespacesResponse, error) {
+	nsList, err := common.ListNs()
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	resp := &pb.ListNamespacesResponse{}
+	for _, ns := range nsList {
+		resp.Namespaces = append(resp.Namespaces, toPbNamespace(ns))
+	}
+	return resp, nil
+}
+
+// UpdateNamespace updates the description of the namespace
+func (s *tumblebugServer) UpdateNamespace(ctx context.Context, req *pb.UpdateNamespaceRequest) (*pb.Namespace, error) {
+	current, err := common.GetNs(req.GetNsId())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	ns, err := common.UpdateNs(req.GetNsId(), &model.NsReq{
+		Name:               current.Name,
+		Description:        req.GetDescription(),
+		AllowedConnections: current.AllowedConnections,
+		AllowedProviders:   current.AllowedProviders,
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbNamespace(ns), nil
+}
+
+// DeleteNamespace deletes the namespace (it should be empty)
+func (s *tumblebugServer) DeleteNamespace(ctx context.Context, req *pb.NamespaceRequest) (*pb.SimpleResponse, error) {
+	if err := common.DelNs(req.GetNsId()); err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.SimpleResponse{Message: "The ns " + req.GetNsId() + " has been deleted"}, nil
+}
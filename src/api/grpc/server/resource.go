@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server is to handle gRPC API
+package server
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/grpc/pb"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+)
+
+// toPbVNet converts the vNet object to the gRPC message
+func toPbVNet(vNet model.TbVNetInfo) *pb.VNet {
+	result := &pb.VNet{
+		Id:             vNet.Id,
+		Uid:            vNet.Uid,
+		Name:           vNet.Name,
+		ConnectionName: vNet.ConnectionName,
+		CidrBlock:      vNet.CidrBlock,
+		Status:         vNet.Status,
+		Description:    vNet.Description,
+		CspResourceId:  vNet.CspResourceId,
+	}
+	for _, subnet := range vNet.SubnetInfoList {
+		result.Subnets = append(result.Subnets, &pb.Subnet{
+			Id:          subnet.Id,
+			Name:        subnet.Name,
+			Ipv4Cidr:    subnet.IPv4_CIDR,
+			Zone:        subnet.Zone,
+			Status:      subnet.Status,
+			Description: subnet.Description,
+		})
+	}
+	return result
+}
+
+// toPbSecurityGroup converts the security group object to the gRPC message
+func toPbSecurityGroup(sg model.TbSecurityGroupInfo) *pb.SecurityGroup {
+	result := &pb.SecurityGroup{
+		Id:             sg.Id,
+		Uid:            sg.Uid,
+		Name:           sg.Name,
+		ConnectionName: sg.ConnectionName,
+		VnetId:         sg.VNetId,
+		Description:    sg.Description,
+		CspResourceId:  sg.CspResourceId,
+	}
+	for _, rule := range sg.FirewallRules {
+		result.FirewallRules = append(result.FirewallRules, &pb.FirewallRule{
+			Direction:  rule.Direction,
+			IpProtocol: rule.IPProtocol,
+			FromPort:   rule.FromPort,
+			ToPort:     rule.ToPort,
+			Cidr:       rule.CIDR,
+		})
+	}
+	return result
+}
+
+// toPbSshKey converts the SSH key object to the gRPC message (the private key is not included)
+func toPbSshKey(key model.TbSshKeyInfo) *pb.SshKey {
+	return &pb.SshKey{
+		Id:             key.Id,
+		Uid:            key.Uid,
+		Name:           key.Name,
+		ConnectionName: key.ConnectionName,
+		Description:    key.Description,
+		CspResourceId:  key.CspResourceId,
+		Fingerprint:    key.Fingerprint,
+		Username:       key.Username,
+		PublicKey:      key.PublicKey,
+	}
+}
+
+// deletedResponse returns the message for the deleted resource (the same as the REST API)
+func deletedResponse(resourceType string, resourceId string) *pb.SimpleResponse {
+	return &pb.SimpleResponse{Message: "The " + resourceType + " " + resourceId + " has been deleted"}
+}
+
+// CreateVNet creates a vNet with the subnets
+func (s *tumblebugServer) CreateVNet(ctx context.Context, req *pb.CreateVNetRequest) (*pb.VNet, error) {
+	vNetReq := &model.TbVNetReq{
+		Name:           req.GetName(),
+		ConnectionName: req.GetConnectionName(),
+		CidrBlock:      req.GetCidrBlock(),
+		Description:    req.GetDescription(),
+	}
+	for _, subnet := range req.GetSubnets() {
+		vNetReq.SubnetInfoList = append(vNetReq.SubnetInfoList, model.TbSubnetReq{
+			Name:        subnet.GetName(),
+			IPv4_CIDR:   subnet.GetIpv4Cidr(),
+			Zone:        subnet.GetZone(),
+			Description: subnet.GetDescription(),
+		})
+	}
+	if err := resource.ValidateVNetReq(vNetReq); err != nil {
+		return nil, toStatusError(err)
+	}
+	vNet, err := resource.CreateVNet(ctx, req.GetNsId(), vNetReq)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbVNet(vNet), nil
+}
+
+// GetVNet returns the vNet
+func (s *tumblebugServer) GetVNet(ctx context.Context, req *pb.ResourceRequest) (*pb.VNet, error) {
+	vNet, err := resource.GetVNet(req.GetNsId(), req.GetResourceId())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbVNet(vNet), nil
+}
+
+// ListVNets returns the vNets in the namespace (filtered by the key and the value if given)
+func (s *tumblebugServer) ListVNets(ctx context.Context, req *pb.ListResourcesRequest) (*pb.ListVNetsResponse, error) {
+	list, err := resource.ListResource(req.GetNsId(), model.StrVNet, req.GetFilterKey(), req.GetFilterVal())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	resp := &pb.ListVNetsResponse{}
+	vNets, _ := list.([]model.TbVNetInfo)
+	for _, vNet := range vNets {
+		resp.Vnets = append(resp.Vnets, toPbVNet(vNet))
+	}
+	return resp, nil
+}
+
+// DeleteVNet deletes the vNet with the subnets
+func (s *tumblebugServer) DeleteVNet(ctx context.Context, req *pb.DeleteResourceRequest) (*pb.SimpleResponse, error) {
+	action := ""
+	if req.GetForce() {
+		action = resource.ActionForce.String()
+	}
+	result, err := resource.DeleteVNet(req.GetNsId(), req.GetResourceId(), action)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.SimpleResponse{Message: result.Message}, nil
+}
+
+// CreateSecurityGroup creates a security group with the firewall rules
+func (s *tumblebugServer) CreateSecurityGroup(ctx context.Context, req *pb.CreateSecurityGroupRequest) (*pb.SecurityGroup, error) {
+	rules := []model.TbFirewallRuleInfo{}
+	for _, rule := range req.GetFirewallRules() {
+		rules = append(rules, model.TbFirewallRuleInfo{
+			Direction:  rule.GetDirection(),
+			IPProtocol: rule.GetIpProtocol(),
+			FromPort:   rule.GetFromPort(),
+			ToPort:     rule.GetToPort(),
+			CIDR:       rule.GetCidr(),
+		})
+	}
+	sgReq := &model.TbSecurityGroupReq{
+		Name:           req.GetName(),
+		ConnectionName: req.GetConnectionName(),
+		VNetId:         req.GetVnetId(),
+		Description:    req.GetDescription(),
+		FirewallRules:  &rules,
+	}
+	sg, err := resource.CreateSecurityGroup(ctx, req.GetNsId(), sgReq, "")
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbSecurityGroup(sg), nil
+}
+
+// GetSecurityGroup returns the security group
+func (s *tumblebugServer) GetSecurityGroup(ctx context.Context, req *pb.ResourceRequest) (*pb.SecurityGroup, error) {
+	res, err := resource.GetResource(req.GetNsId(), model.StrSecurityGroup, req.GetResourceId())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	sg, _ := res.(model.TbSecurityGroupInfo)
+	return toPbSecurityGroup(sg), nil
+}
+
+// ListSecurityGroups returns the security groups in the namespace (filtered by the key and the value if given)
+func (s *tumblebugServer) ListSecurityGroups(ctx context.Context, req *pb.ListResourcesRequest) (*pb.ListSecurityGroupsResponse, error) {
+	list, err := resource.ListResource(req.GetNsId(), model.StrSecurityGroup, req.GetFilterKey(), req.GetFilterVal())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	resp := &pb.ListSecurityGroupsResponse{}
+	sgs, _ := list.([]model.TbSecurityGroupInfo)
+	for _, sg := range sgs {
+		resp.SecurityGroups = append(resp.SecurityGroups, toPbSecurityGroup(sg))
+	}
+	return resp, nil
+}
+
+// DeleteSecurityGroup deletes the security group
+func (s *tumblebugServer) DeleteSecurityGroup(ctx context.Context, req *pb.DeleteResourceRequest) (*pb.SimpleResponse, error) {
+	err := resource.DelResource(req.GetNsId(), model.StrSecurityGroup, req.GetResourceId(), strconv.FormatBool(req.GetForce()))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return deletedResponse(model.StrSecurityGroup, req.GetResourceId()), nil
+}
+
+// CreateSshKey creates an SSH key (the private key can be retrieved only by the REST API)
+func (s *tumblebugServer) CreateSshKey(ctx context.Context, req *pb.CreateSshKeyRequest) (*pb.SshKey, error) {
+	keyReq := &model.TbSshKeyReq{
+		Name:           req.GetName(),
+		ConnectionName: req.GetConnectionName(),
+		Description:    req.GetDescription(),
+	}
+	key, err := resource.CreateSshKey(ctx, req.GetNsId(), keyReq, "")
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toPbSshKey(key), nil
+}
+
+// GetSshKey returns the SSH key
+func (s *tumblebugServer) GetSshKey(ctx context.Context, req *pb.ResourceRequest) (*pb.SshKey, error) {
+	res, err := resource.GetResource(req.GetNsId(), model.StrSSHKey, req.GetResourceId())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	key, _ := res.(model.TbSshKeyInfo)
+	return toPbSshKey(key), nil
+}
+
+// ListSshKeys returns the SSH keys in the namespace (filtered by the key and the value if given)
+func (s *tumblebugServer) ListSshKeys(ctx context.Context, req *pb.ListResourcesRequest) (*pb.ListSshKeysResponse, error) {
+	list, err := resource.ListResource(req.GetNsId(), model.StrSSHKey, req.GetFilterKey(), req.GetFilterVal())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	resp := &pb.ListSshKeysResponse{}
+	keys, _ := list.([]model.TbSshKeyInfo)
+	for _, key := range keys {
+		resp.SshKeys = append(resp.SshKeys, toPbSshKey(key))
+	}
+	return resp, nil
+}
+
+// DeleteSshKey deletes the SSH key
+func (s *tumblebugServer) DeleteSshKey(ctx context.Context, req *pb.DeleteResourceRequest) (*pb.SimpleResponse, error) {
+	err := resource.DelResource(req.GetNsId(), model.StrSSHKey, req.GetResourceId(), strconv.FormatBool(req.GetForce()))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return deletedResponse(model.StrSSHKey, req.GetResourceId()), nil
+}
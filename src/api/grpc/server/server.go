@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server is to handle gRPC API
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/grpc/pb"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// tumblebugServer implements the Tumblebug gRPC service by the core functions (no REST round-trip)
+type tumblebugServer struct {
+	pb.UnimplementedTumblebugServer
+}
+
+// NewServer returns the gRPC server with the Tumblebug service and the interceptors (auth by TB_AUTH_ENABLED and TB_AUTH_MODE)
+func NewServer() (*grpc.Server, error) {
+	auth, err := newAuthenticator()
+	if err != nil {
+		return nil, err
+	}
+
+	return newGrpcServer(auth), nil
+}
+
+// newGrpcServer returns the gRPC server with the Tumblebug service and the interceptors by the authenticator
+func newGrpcServer(auth *authenticator) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoveryUnaryInterceptor, auditUnaryInterceptor, auth.unaryInterceptor),
+		grpc.ChainStreamInterceptor(recoveryStreamInterceptor, auditStreamInterceptor, auth.streamInterceptor),
+	)
+	pb.RegisterTumblebugServer(s, &tumblebugServer{})
+	// allow clients such as grpcurl to list the services
+	reflection.Register(s)
+	return s
+}
+
+// RunServer starts the gRPC API server on the port (TB_GRPC_PORT) and stops it gracefully when the server is draining
+func RunServer(port string) {
+
+	log.Info().Msg("gRPC API Server is starting")
+
+	s, err := NewServer()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize gRPC API Server. EXITING...")
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to listen on port %s for gRPC API Server. EXITING...", port)
+	}
+
+	go func() {
+		<-common.DrainingNotify()
+		log.Info().Msg("Stopping gRPC API Server gracefully...")
+		s.GracefulStop()
+	}()
+
+	log.Info().Msgf("gRPC API Server is listening on port %s", port)
+	if err := s.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		log.Error().Err(err).Msg("Error in gRPC API Server")
+	}
+}
+
+// toStatusError converts the error of core functions to the gRPC status error with the code by the error code
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	response, httpStatus := common.ToErrorResponse(err, http.StatusInternalServerError)
+
+	code := codes.Internal
+	switch httpStatus {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Errorf(code, "[%s] %s", response.Code, response.Message)
+}
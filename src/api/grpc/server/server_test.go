@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/grpc/pb"
+	"github.com/cloud-barista/cb-tumblebug/src/api/rest/server/middlewares/authmw"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newBasicAuthClient returns a client of the server with basic auth and the context with the credential and the request ID
+func newBasicAuthClient(t *testing.T) (pb.TumblebugClient, func(password string, reqId string) context.Context) {
+	t.Helper()
+	client := newBufconnClient(t, &authenticator{enabled: true, mode: "basic", basicAuthUsers: []authmw.BasicAuthUser{
+		{Username: "grpc-admin", Password: "secret", Role: authmw.RoleAdmin},
+	}})
+	withAuth := func(password string, reqId string) context.Context {
+		credential := base64.StdEncoding.EncodeToString([]byte("grpc-admin:" + password))
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic "+credential, "x-request-id", reqId)
+	}
+	return client, withAuth
+}
+
+// findAuditRecord returns the audit record of the RPC with the request ID
+func findAuditRecord(t *testing.T, fullMethod string, reqId string) model.AuditRecord {
+	t.Helper()
+	list, err := common.ListAuditRecords(common.AuditFilter{PathPrefix: fullMethod})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range list.Records {
+		if record.RequestId == reqId {
+			return record
+		}
+	}
+	t.Fatalf("no audit record of %s (%s)", fullMethod, reqId)
+	return model.AuditRecord{}
+}
+
+func TestNamespaceRpc(t *testing.T) {
+	client, withAuth := newBasicAuthClient(t)
+
+	created, err := client.CreateNamespace(withAuth("secret", "grpc-ns-create"), &pb.CreateNamespaceRequest{Name: "grpc-ns-rpc", Description: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer common.DelNs("grpc-ns-rpc")
+	ns, err := client.GetNamespace(withAuth("secret", "grpc-ns-get"), &pb.NamespaceRequest{NsId: created.GetId()})
+	if err != nil || ns.GetId() != "grpc-ns-rpc" || ns.GetDescription() != "test" {
+		t.Errorf("GetNamespace: got %v (%v)", ns, err)
+	}
+
+	record := findAuditRecord(t, pb.Tumblebug_CreateNamespace_FullMethodName, "grpc-ns-create")
+	body, _ := json.Marshal(record.RequestBody)
+	if record.User != "grpc-admin" || record.Status != http.StatusOK || string(body) != `{"description":"test","name":"grpc-ns-rpc"}` {
+		t.Errorf("audit record: got %+v (body %s)", record, body)
+	}
+
+	// a rejected call is recorded without the claimed user
+	_, err = client.CreateNamespace(withAuth("wrong", "grpc-ns-rejected"), &pb.CreateNamespaceRequest{Name: "grpc-ns-rejected"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("got %v, want Unauthenticated", err)
+	}
+	record = findAuditRecord(t, pb.Tumblebug_CreateNamespace_FullMethodName, "grpc-ns-rejected")
+	if record.User != "anonymous" || record.Status != http.StatusUnauthorized {
+		t.Errorf("audit record of a rejected call: got %+v", record)
+	}
+
+	// read RPCs are not recorded
+	list, _ := common.ListAuditRecords(common.AuditFilter{PathPrefix: pb.Tumblebug_GetNamespace_FullMethodName})
+	if len(list.Records) != 0 {
+		t.Errorf("read RPCs recorded: %+v", list.Records)
+	}
+}
+
+func TestResourceRpc(t *testing.T) {
+	client, withAuth := newBasicAuthClient(t)
+	if _, err := common.CreateNs(&model.NsReq{Name: "grpc-ns-resource"}); err != nil {
+		t.Fatal(err)
+	}
+	defer common.DelNs("grpc-ns-resource")
+
+	vNets, err := client.ListVNets(withAuth("secret", "grpc-vnet-list"), &pb.ListResourcesRequest{NsId: "grpc-ns-resource"})
+	if err != nil || len(vNets.GetVnets()) != 0 {
+		t.Errorf("ListVNets: got %v (%v)", vNets, err)
+	}
+
+	_, err = client.DeleteVNet(withAuth("secret", "grpc-vnet-delete"), &pb.DeleteResourceRequest{NsId: "grpc-ns-resource", ResourceId: "vnet-missing"})
+	if err == nil {
+		t.Fatal("DeleteVNet of a missing vNet succeeded")
+	}
+	record := findAuditRecord(t, pb.Tumblebug_DeleteVNet_FullMethodName, "grpc-vnet-delete")
+	if record.User != "grpc-admin" || record.Status == http.StatusOK || record.Status != httpStatusOf(err) {
+		t.Errorf("audit record: got %+v for %v", record, err)
+	}
+}
+
+func TestMciRpcDuplicatedRequestId(t *testing.T) {
+	client, withAuth := newBasicAuthClient(t)
+
+	inUse := common.RequestDetails{StartTime: time.Now(), Status: common.RequestStatusHandling}
+	common.RequestMap.Store("grpc-mci-in-use", inUse)
+	defer common.RequestMap.Delete("grpc-mci-in-use")
+
+	_, err := client.CreateMci(withAuth("secret", "grpc-mci-in-use"), &pb.CreateMciRequest{NsId: "grpc-ns-mci", Name: "mci01"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("got %v, want AlreadyExists", err)
+	}
+	// the details of the request in progress are kept
+	if v, ok := common.RequestMap.Load("grpc-mci-in-use"); !ok || v.(common.RequestDetails).Status != common.RequestStatusHandling || v.(common.RequestDetails).RequestInfo.URL != "" {
+		t.Errorf("request details: got %+v", v)
+	}
+	record := findAuditRecord(t, pb.Tumblebug_CreateMci_FullMethodName, "grpc-mci-in-use")
+	if record.User != "grpc-admin" || record.Status != http.StatusConflict {
+		t.Errorf("audit record: got %+v", record)
+	}
+}
+
+func TestWatchMciProvisioningRpc(t *testing.T) {
+	client, withAuth := newBasicAuthClient(t)
+	if _, err := common.CreateNs(&model.NsReq{Name: "grpc-ns-watch"}); err != nil {
+		t.Fatal(err)
+	}
+	defer common.DelNs("grpc-ns-watch")
+
+	// an MCI without VMs has completed its action
+	key := common.GenMciKey("grpc-ns-watch", "mci01", "")
+	val, _ := json.Marshal(model.TbMciInfo{Id: "mci01", Name: "mci01", TargetAction: model.ActionComplete})
+	if err := kvstore.Put(key, string(val)); err != nil {
+		t.Fatal(err)
+	}
+	defer kvstore.Delete(key)
+
+	ctx, cancel := context.WithTimeout(withAuth("secret", "grpc-mci-watch"), 10*time.Second)
+	defer cancel()
+	stream, err := client.WatchMciProvisioning(ctx, &pb.MciRequest{NsId: "grpc-ns-watch", MciId: "mci01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, err := stream.Recv()
+	if err != nil || !progress.GetDone() {
+		t.Fatalf("got %v (%v), want the done progress", progress, err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v after the done progress, want EOF", err)
+	}
+
+	// the stream is also authenticated
+	stream, err = client.WatchMciProvisioning(withAuth("wrong", "grpc-mci-watch-rejected"), &pb.MciRequest{NsId: "grpc-ns-watch", MciId: "mci01"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("got %v, want Unauthenticated", err)
+	}
+}
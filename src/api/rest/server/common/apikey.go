@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to handle REST API for common funcitonalities
+package common
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// RestPostApiKey godoc
+// @ID PostApiKey
+// @Summary Create API key
+// @Description Create a named API key for TB_AUTH_MODE=apikey. The key is returned only once (only its hash is stored).
+// @Description A read-only key can call GET routes only. A key with nsId can access the namespace only.
+// @Description API keys can be managed only with the admin credential (TB_API_USERNAME and TB_API_PASSWORD).
+// @Tags [Admin] API Key Management
+// @Accept  json
+// @Produce  json
+// @Param apiKeyReq body model.ApiKeyReq true "Name, scope and namespace restriction of the API key"
+// @Success 200 {object} model.ApiKeyCreateResult
+// @Failure 400 {object} model.SimpleMsg
+// @Router /apikeys [post]
+// @Security BasicAuth
+func RestPostApiKey(c echo.Context) error {
+
+	u := &model.ApiKeyReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.CreateApiKey(u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetAllApiKeys godoc
+// @ID GetAllApiKeys
+// @Summary List API keys
+// @Description List all API keys (secrets are not included)
+// @Tags [Admin] API Key Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.ApiKeyInfoList
+// @Failure 500 {object} model.SimpleMsg
+// @Router /apikeys [get]
+// @Security BasicAuth
+func RestGetAllApiKeys(c echo.Context) error {
+
+	content, err := common.ListApiKeys()
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDelApiKey godoc
+// @ID DelApiKey
+// @Summary Revoke API key
+// @Description Revoke the API key. Requests with the key are rejected immediately.
+// @Tags [Admin] API Key Management
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Name of API key"
+// @Success 200 {object} model.ApiKeyInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Router /apikeys/{name} [delete]
+// @Security BasicAuth
+func RestDelApiKey(c echo.Context) error {
+
+	content, err := common.RevokeApiKey(c.Param("name"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostRotateApiKey godoc
+// @ID PostRotateApiKey
+// @Summary Rotate API key
+// @Description Issue a new secret for the API key. The previous key becomes invalid immediately and the new key is returned only once.
+// @Tags [Admin] API Key Management
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Name of API key"
+// @Success 200 {object} model.ApiKeyCreateResult
+// @Failure 400 {object} model.SimpleMsg
+// @Router /apikeys/{name}/rotate [post]
+// @Security BasicAuth
+func RestPostRotateApiKey(c echo.Context) error {
+
+	content, err := common.RotateApiKey(c.Param("name"))
+	return common.EndRequestWithLog(c, err, content)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to handle REST API for common funcitonalities
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+)
+
+// RestGetAuditRecords godoc
+// @ID GetAuditRecords
+// @Summary List audit records
+// @Description List audit records of mutating API calls (POST, PUT, PATCH, DELETE) with optional filters and pagination.
+// @Description Records are sorted by timestamp (newest first). Sensitive values in request bodies are redacted.
+// @Tags [Admin] API Request Management
+// @Accept  json
+// @Produce  json
+// @Param from query string false "Filter by time of call (RFC3339, e.g., 2024-10-01T00:00:00Z)"
+// @Param to query string false "Filter by time of call (RFC3339, e.g., 2024-10-02T00:00:00Z)"
+// @Param user query string false "Filter by user of call"
+// @Param path query string false "Filter by prefix of request path (e.g., /tumblebug/ns/default/mci)"
+// @Param page query int false "Page number starting from 1"
+// @Param pageSize query int false "Number of records in a page (default: 100 if page is given)"
+// @Success 200 {object} model.AuditRecordList
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /audit [get]
+func RestGetAuditRecords(c echo.Context) error {
+	filter := common.AuditFilter{
+		User:       c.QueryParam("user"),
+		PathPrefix: c.QueryParam("path"),
+	}
+
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'from' (RFC3339 is required): "+err.Error())
+		}
+		filter.From = t
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'to' (RFC3339 is required): "+err.Error())
+		}
+		filter.To = t
+	}
+	if page := c.QueryParam("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'page' (positive integer is required)")
+		}
+		filter.Page = n
+	}
+	if pageSize := c.QueryParam("pageSize"); pageSize != "" {
+		n, err := strconv.Atoi(pageSize)
+		if err != nil || n < 1 {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'pageSize' (positive integer is required)")
+		}
+		filter.PageSize = n
+	}
+
+	content, err := common.ListAuditRecords(filter)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, content)
+	}
+	return c.JSON(http.StatusOK, &content)
+}
+
+// RestVerifyAuditRecords godoc
+// @ID VerifyAuditRecords
+// @Summary Verify audit records
+// @Description Verify the hash chain of the stored audit records to detect modification or deletion of records
+// @Tags [Admin] API Request Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.AuditVerifyResult
+// @Failure 500 {object} model.SimpleMsg
+// @Router /audit/verify [get]
+func RestVerifyAuditRecords(c echo.Context) error {
+
+	content, err := common.VerifyAuditRecords()
+	return common.EndRequestWithLog(c, err, content)
+}
@@ -21,7 +21,6 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -183,43 +182,65 @@ func RestGetRequest(c echo.Context) error {
 // RestGetAllRequests godoc
 // @ID GetAllRequests
 // @Summary Get all requests
-// @Description Get details of all requests with optional filters.
+// @Description Get details of all requests with optional filters and pagination.
+// @Description Requests are sorted by startTime (newest first). Pagination is applied when page or pageSize is given.
 // @Tags [Admin] API Request Management
 // @Accept  json
 // @Produce  json
 // @Param status query string false "Filter by request status (Handling, Error, Success)"
 // @Param method query string false "Filter by HTTP method (GET, POST, etc.)"
 // @Param url query string false "Filter by request URL"
+// @Param pathPrefix query string false "Filter by prefix of request path (e.g., /tumblebug/ns/default/mci)"
 // @Param time query string false "Filter by time in minutes from now (to get recent requests)"
+// @Param from query string false "Filter by start time of request (RFC3339, e.g., 2024-10-01T00:00:00Z)"
+// @Param to query string false "Filter by start time of request (RFC3339, e.g., 2024-10-02T00:00:00Z)"
+// @Param page query int false "Page number starting from 1"
+// @Param pageSize query int false "Number of requests in a page (default: 100 if page is given)"
 // @Param savefile query string false "Option to save the results to a file (set 'true' to activate)"
-// @Success 200 {object} map[string][]common.RequestDetails
+// @Success 200 {object} common.RequestList
+// @Failure 400 {object} model.SimpleMsg
 // @Router /requests [get]
 func RestGetAllRequests(c echo.Context) error {
-	// Filter parameters
-	statusFilter := strings.ToLower(c.QueryParam("status"))
-	methodFilter := strings.ToLower(c.QueryParam("method"))
-	urlFilter := strings.ToLower(c.QueryParam("url"))
-	timeFilter := c.QueryParam("time") // in minutes
-
-	var timeLimit time.Time
-	if minutes, err := strconv.Atoi(timeFilter); err == nil {
-		timeLimit = time.Now().Add(-time.Duration(minutes) * time.Minute)
+	filter := common.RequestFilter{
+		Status:     c.QueryParam("status"),
+		Method:     c.QueryParam("method"),
+		Url:        c.QueryParam("url"),
+		PathPrefix: c.QueryParam("pathPrefix"),
 	}
 
-	var allRequests []common.RequestDetails
-
-	// Filtering the requests
-	common.RequestMap.Range(func(key, value interface{}) bool {
-		if details, ok := value.(common.RequestDetails); ok {
-			if (statusFilter == "" || strings.ToLower(details.Status) == statusFilter) &&
-				(methodFilter == "" || strings.ToLower(details.RequestInfo.Method) == methodFilter) &&
-				(urlFilter == "" || strings.Contains(strings.ToLower(details.RequestInfo.URL), urlFilter)) &&
-				(timeFilter == "" || details.StartTime.After(timeLimit)) {
-				allRequests = append(allRequests, details)
-			}
+	if minutes, err := strconv.Atoi(c.QueryParam("time")); err == nil {
+		filter.From = time.Now().Add(-time.Duration(minutes) * time.Minute)
+	}
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'from' (RFC3339 is required): "+err.Error())
 		}
-		return true
-	})
+		filter.From = t
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'to' (RFC3339 is required): "+err.Error())
+		}
+		filter.To = t
+	}
+	if page := c.QueryParam("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'page' (positive integer is required)")
+		}
+		filter.Page = n
+	}
+	if pageSize := c.QueryParam("pageSize"); pageSize != "" {
+		n, err := strconv.Atoi(pageSize)
+		if err != nil || n < 1 {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'pageSize' (positive integer is required)")
+		}
+		filter.PageSize = n
+	}
+
+	result := common.ListRequests(filter)
 
 	// Option to save the result to a file
 	if c.QueryParam("savefile") == "true" {
@@ -232,14 +253,14 @@ func RestGetAllRequests(c echo.Context) error {
 		defer file.Close()
 
 		// Write each request detail in a new line
-		for _, detail := range allRequests {
+		for _, detail := range result.Requests {
 			jsonLine, _ := json.Marshal(detail)
 			file.Write(jsonLine)
 			file.WriteString("\n")
 		}
 	}
 
-	return Send(c, http.StatusOK, map[string][]common.RequestDetails{"requests": allRequests})
+	return Send(c, http.StatusOK, result)
 }
 
 // RestDeleteRequest godoc
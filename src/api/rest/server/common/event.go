@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to handle REST API for common funcitonalities
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+)
+
+const (
+	// eventStreamBufferSize is the number of events buffered per connection (events are dropped beyond it)
+	eventStreamBufferSize = 256
+	// eventStreamKeepAlive is the interval of keepalive comments for proxies not to drop idle streams
+	eventStreamKeepAlive = 15 * time.Second
+)
+
+// writeServerSentEvent writes an event in text/event-stream format and flushes it
+func writeServerSentEvent(c echo.Context, id string, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(c.Response(), "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(c.Response(), "event: %s\ndata: %s\n\n", eventType, payload); err != nil {
+		return err
+	}
+	c.Response().Flush()
+	return nil
+}
+
+// RestGetEventStream godoc
+// @ID GetEventStream
+// @Summary Stream status change events of namespace
+// @Description Stream events of the namespace by Server-Sent Events (text/event-stream) instead of polling.
+// @Description Events: mci.created, mci.deleted, mci.statusChanged, vm.statusChanged, k8scluster.created, k8scluster.deleted, k8scluster.statusChanged, vNet.statusChanged.
+// @Description A keepalive comment is sent every 15 seconds. If the client is too slow, events are dropped and an "events.dropped" event reports the number.
+// @Tags [Infra Resource] Event Stream
+// @Produce  text/event-stream
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId query string false "Receive events of the MCI (and its VMs) only"
+// @Param resourceType query string false "Receive events of the resource type only" Enums(mci, vm, k8scluster, vNet)
+// @Success 200 {object} model.WebhookEvent
+// @Failure 404 {object} model.SimpleMsg
+// @Router /stream-response/ns/{nsId}/events [get]
+func RestGetEventStream(c echo.Context) error {
+	nsId := c.Param("nsId")
+	resourceType := c.QueryParam("resourceType")
+
+	mciTarget := ""
+	if mciId := c.QueryParam("mciId"); mciId != "" {
+		mciTarget = common.GenMciKey(nsId, mciId, "")
+	}
+
+	sub := common.SubscribeEvents(eventStreamBufferSize)
+	defer sub.Close()
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
+	// disable response buffering of reverse proxies (e.g., nginx)
+	c.Response().Header().Set("X-Accel-Buffering", "no")
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			// client disconnected
+			return nil
+		case <-common.DrainingNotify():
+			// server is shutting down
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Response(), ": keepalive\n\n"); err != nil {
+				return nil
+			}
+			c.Response().Flush()
+		case event := <-sub.C:
+			if event.NsId != nsId {
+				continue
+			}
+			if mciTarget != "" && event.Target != mciTarget && !strings.HasPrefix(event.Target, mciTarget+"/") {
+				continue
+			}
+			if resourceType != "" && !strings.HasPrefix(event.Type, resourceType+".") {
+				continue
+			}
+			if dropped := sub.Dropped(); dropped > 0 {
+				if err := writeServerSentEvent(c, "", "events.dropped", map[string]int64{"dropped": dropped}); err != nil {
+					return nil
+				}
+			}
+			if err := writeServerSentEvent(c, event.Id, event.Type, event); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// RestGetNsEvents godoc
+// @ID GetNsEvents
+// @Summary List events in the timeline of namespace
+// @Description List events recorded in the namespace (creation, deletion and status changes of MCIs, VMs, K8sClusters and vNets,
+// @Description policy actions and command executions) with optional filters and pagination. Events are sorted by time (newest first).
+// @Description The actor is the user of the API call which caused the event, or system for background changes.
+// @Description Up to TB_NS_EVENT_MAX_PER_NS events are kept per namespace for TB_NS_EVENT_RETENTION_DAYS.
+// @Tags [Infra Resource] Event Stream
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param from query string false "Filter by time of event (RFC3339, e.g., 2024-10-01T00:00:00Z)"
+// @Param to query string false "Filter by time of event (RFC3339, e.g., 2024-10-02T00:00:00Z)"
+// @Param resourceType query string false "Filter by resource type" Enums(mci, vm, k8s, vNet)
+// @Param resourceId query string false "Filter by resource ID (an MCI ID also matches its VMs, given as mciId/vmId)"
+// @Param page query int false "Page number starting from 1"
+// @Param pageSize query int false "Number of events in a page (default: 100 if page is given)"
+// @Success 200 {object} model.NsEventList
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/events [get]
+func RestGetNsEvents(c echo.Context) error {
+	nsId := c.Param("nsId")
+	filter := common.NsEventFilter{
+		ResourceType: c.QueryParam("resourceType"),
+		ResourceId:   c.QueryParam("resourceId"),
+	}
+
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'from' (RFC3339 is required): "+err.Error())
+		}
+		filter.From = t
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'to' (RFC3339 is required): "+err.Error())
+		}
+		filter.To = t
+	}
+	if page := c.QueryParam("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'page' (positive integer is required)")
+		}
+		filter.Page = n
+	}
+	if pageSize := c.QueryParam("pageSize"); pageSize != "" {
+		n, err := strconv.Atoi(pageSize)
+		if err != nil || n < 1 {
+			return SendMessage(c, http.StatusBadRequest, "Invalid 'pageSize' (positive integer is required)")
+		}
+		filter.PageSize = n
+	}
+
+	content, err := common.ListNsEvents(nsId, filter)
+	return common.EndRequestWithLog(c, err, content)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to handle REST API for common funcitonalities
+package common
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+)
+
+// RestGetAllJobs godoc
+// @ID GetAllJobs
+// @Summary List async jobs
+// @Description List all async jobs for long-running operations (newest first)
+// @Tags [Admin] API Request Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.JobInfoList
+// @Failure 500 {object} model.SimpleMsg
+// @Router /jobs [get]
+func RestGetAllJobs(c echo.Context) error {
+
+	content, err := common.ListJobs()
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetJob godoc
+// @ID GetJob
+// @Summary Get async job
+// @Description Get the state, progress and result of an async job
+// @Tags [Admin] API Request Management
+// @Accept  json
+// @Produce  json
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} model.JobInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Router /jobs/{jobId} [get]
+func RestGetJob(c echo.Context) error {
+
+	content, err := common.GetJob(c.Param("jobId"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestCancelJob godoc
+// @ID CancelJob
+// @Summary Cancel async job
+// @Description Request cancellation of a running async job. The job becomes Canceling and then Canceled when the operation stops.
+// @Tags [Admin] API Request Management
+// @Accept  json
+// @Produce  json
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} model.JobInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Router /jobs/{jobId} [delete]
+func RestCancelJob(c echo.Context) error {
+
+	content, err := common.CancelJob(c.Param("jobId"))
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
@@ -34,6 +34,7 @@ import (
 // @Param labelType path string true "Label Type" Enums(ns, mci, subGroup, vm, k8s, vNet, subnet, securityGroup, sshKey, dataDisk)
 // @Param uid path string true "Resource uid"
 // @Param labels body model.Label true "Labels to create or update"
+// @Param propagateToCsp query bool false "Propagate the labels (except sys.*) to CSP tags (vNet, securityGroup, vm, dataDisk only)" default(false)
 // @Success 200 {object} model.SimpleMsg "Label created or updated successfully"
 // @Failure 400 {object} model.SimpleMsg "Invalid request"
 // @Failure 500 {object} model.SimpleMsg "Internal Server Error"
@@ -48,6 +49,11 @@ func RestCreateOrUpdateLabel(c echo.Context) error {
 	if err := c.Bind(&labelReq); err != nil {
 		return common.EndRequestWithLog(c, fmt.Errorf("Invalid request body"), nil)
 	}
+	for key := range labelReq.Labels {
+		if label.IsSystemLabelKey(key) {
+			return common.EndRequestWithLog(c, fmt.Errorf("system-managed label key (%s) cannot be modified", key), nil)
+		}
+	}
 
 	// Get the resource key
 	resourceKey := fmt.Sprintf("/%s/%s", labelType, uid)
@@ -58,6 +64,23 @@ func RestCreateOrUpdateLabel(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
+	// Propagate the labels to CSP tags. A failure is recorded on the label object without failing the request.
+	if c.QueryParam("propagateToCsp") == "true" {
+		if !label.IsTaggableLabelType(labelType) {
+			return common.EndRequestWithLog(c, fmt.Errorf("labels of %s cannot be propagated to CSP tags", labelType), nil)
+		}
+		if err := common.CheckSpiderFeature(model.SpiderFeatureTagList, "propagation to CSP tags"); err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		labelInfo, err := label.PropagateLabelsToCsp(labelType, uid, labelReq.Labels)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		if labelInfo.LastSyncStatus == model.LabelSyncFailed {
+			return common.EndRequestWithLog(c, nil, map[string]string{"message": "Label created or updated successfully, but failed to propagate to CSP tags: " + labelInfo.LastSyncError})
+		}
+	}
+
 	return common.EndRequestWithLog(c, nil, map[string]string{"message": "Label created or updated successfully"})
 }
 
@@ -71,6 +94,7 @@ func RestCreateOrUpdateLabel(c echo.Context) error {
 // @Param labelType path string true "Label Type" Enums(ns, mci, subGroup, vm, k8s, vNet, subnet, securityGroup, sshKey, dataDisk)
 // @Param uid path string true "Resource uid"
 // @Param key path string true "Label key to remove"
+// @Param propagateToCsp query bool false "Remove the tag from the CSP resource as well (vNet, securityGroup, vm, dataDisk only)" default(false)
 // @Success 200 {object} model.SimpleMsg "Label removed successfully"
 // @Failure 400 {object} model.SimpleMsg "Invalid request"
 // @Failure 500 {object} model.SimpleMsg "Internal Server Error"
@@ -80,6 +104,9 @@ func RestRemoveLabel(c echo.Context) error {
 	labelType := c.Param("labelType")
 	uid := c.Param("uid")
 	key := c.Param("key")
+	if label.IsSystemLabelKey(key) {
+		return common.EndRequestWithLog(c, fmt.Errorf("system-managed label key (%s) cannot be removed", key), nil)
+	}
 
 	// Remove the label from the KV store
 	err := label.RemoveLabel(labelType, uid, key)
@@ -87,9 +114,53 @@ func RestRemoveLabel(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
+	// Remove the tag from the CSP resource. A failure is recorded on the label object without failing the request.
+	if c.QueryParam("propagateToCsp") == "true" {
+		if !label.IsTaggableLabelType(labelType) {
+			return common.EndRequestWithLog(c, fmt.Errorf("labels of %s cannot be propagated to CSP tags", labelType), nil)
+		}
+		if err := common.CheckSpiderFeature(model.SpiderFeatureTagList, "propagation to CSP tags"); err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		labelInfo, err := label.RemoveLabelFromCsp(labelType, uid, key)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		if labelInfo.LastSyncStatus == model.LabelSyncFailed {
+			return common.EndRequestWithLog(c, nil, map[string]string{"message": "Label removed successfully, but failed to remove the CSP tag: " + labelInfo.LastSyncError})
+		}
+	}
+
 	return common.EndRequestWithLog(c, nil, map[string]string{"message": "Label removed successfully"})
 }
 
+// RestResyncLabelsToCsp godoc
+// @ID ResyncLabelsToCsp
+// @Summary Retry failed propagations of labels to CSP tags
+// @Description Retry the propagation of labels to CSP tags for the label objects whose last sync was failed
+// @Tags [Infra Resource] Common Utility
+// @Accept  json
+// @Produce  json
+// @Param labelType query string false "Label Type (empty means all taggable types)" Enums(all, vNet, securityGroup, vm, dataDisk)
+// @Success 200 {object} model.LabelSyncResult "Re-sync results"
+// @Failure 400 {object} model.SimpleMsg "Invalid request"
+// @Failure 500 {object} model.SimpleMsg "Internal Server Error"
+// @Router /label/cspTagSync [post]
+func RestResyncLabelsToCsp(c echo.Context) error {
+
+	labelType := c.QueryParam("labelType")
+	if err := common.CheckSpiderFeature(model.SpiderFeatureTagList, "propagation to CSP tags"); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := label.ResyncLabelsToCsp(labelType)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	return common.EndRequestWithLog(c, nil, result)
+}
+
 // RestGetLabels godoc
 // @ID GetLabels
 // @Summary Get labels for a resource
@@ -132,11 +203,15 @@ type ResourcesResponse struct {
 // @Description - `notin` : Selects resources where the label key is not in the specified set of values (e.g., `env notin (production, staging)`).
 // @Description - `exists` : Selects resources where the label key exists (e.g., `env exists`).
 // @Description - `!exists` : Selects resources where the label key does not exist (e.g., `env !exists`).
+// @Description - `>`, `>=`, `<`, `<=` : Selects resources where the label value is numeric and satisfies the comparison (e.g., `replicaCount>=3`). Resources with a non-numeric value are excluded.
+// @Description - `prefix` : Selects resources which have any label key starting with the given prefix (e.g., `prefix team/`).
+// @Description Requirements are separated by commas and all of them must be satisfied. Commas inside parentheses belong to the value list of `in`/`notin`. Whitespace around keys, operators and values is ignored.
+// @Description An invalid selector (e.g., a non-numeric operand for a numeric operator) returns 400.
 // @Tags [Infra Resource] Common Utility
 // @Accept  json
 // @Produce  json
 // @Param labelType path string true "Label Type" Enums(ns, mci, subGroup, vm, k8s, vNet, subnet, securityGroup, sshKey, dataDisk)
-// @Param labelSelector query string true "Label selector query. Example: env=production,tier=backend"
+// @Param labelSelector query string true "Label selector query. Example: env=production,tier=backend,replicaCount>=2"
 // @Success 200 {object} ResourcesResponse "Matched resources"
 // @Failure 400 {object} model.SimpleMsg "Invalid request"
 // @Failure 500 {object} model.SimpleMsg "Internal Server Error"
@@ -184,3 +259,32 @@ func RestGetSystemLabelInfo(c echo.Context) error {
 
 	return common.EndRequestWithLog(c, nil, systemLabelInfo)
 }
+
+// RestApplyLabelsBySelector godoc
+// @ID ApplyLabelsBySelector
+// @Summary Add or remove labels of resources matched by a label selector
+// @Description Add or remove labels of all resources matched by a label selector at once.
+// @Description Each resource is updated with a single write and the result is reported per resource.
+// @Description Use dryRun to get the list of resources which would be modified. System-managed label keys (sys.*) cannot be modified.
+// @Tags [Infra Resource] Common Utility
+// @Accept  json
+// @Produce  json
+// @Param labelBulkReq body model.LabelBulkReq true "Label selector and labels to add or remove"
+// @Success 200 {object} model.LabelBulkResult "Results per resource"
+// @Failure 400 {object} model.SimpleMsg "Invalid request"
+// @Failure 500 {object} model.SimpleMsg "Internal Server Error"
+// @Router /labels/bulk [post]
+func RestApplyLabelsBySelector(c echo.Context) error {
+
+	req := &model.LabelBulkReq{}
+	if err := c.Bind(req); err != nil {
+		return common.EndRequestWithLog(c, fmt.Errorf("Invalid request body"), nil)
+	}
+
+	result, err := label.ApplyLabelsBySelector(req)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	return common.EndRequestWithLog(c, nil, result)
+}
@@ -15,10 +15,15 @@ limitations under the License.
 package common
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+
 	"github.com/labstack/echo/v4"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
 )
 
 func RestCheckNs(c echo.Context) error {
@@ -75,6 +80,134 @@ func RestDelNs(c echo.Context) error {
 	return common.EndRequestWithLog(c, err, content)
 }
 
+// RestPutNsQuota godoc
+// @ID PutNsQuota
+// @Summary Set quota of namespace
+// @Description Set the resource limits of the namespace (0 means unlimited).
+// @Description Requests for MCI, subGroup, vNet, dataDisk and K8sCluster creation which would exceed a limit are rejected with 429.
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param quota body model.NsQuota true "Resource limits of the namespace"
+// @Success 200 {object} model.NsQuota
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/quota [put]
+func RestPutNsQuota(c echo.Context) error {
+
+	u := &model.NsQuota{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.UpdateNsQuota(c.Param("nsId"), u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetNsQuota godoc
+// @ID GetNsQuota
+// @Summary Get quota of namespace
+// @Description Get the resource limits of the namespace (0 means unlimited)
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Success 200 {object} model.NsQuota
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/quota [get]
+func RestGetNsQuota(c echo.Context) error {
+
+	content, err := common.GetNsQuota(c.Param("nsId"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetNsQuotaUsage godoc
+// @ID GetNsQuotaUsage
+// @Summary Get quota usage of namespace
+// @Description Get the quota and the current resource consumption (VMs, vCPUs, vNets, dataDisk GB) of the namespace
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Success 200 {object} model.NsQuotaUsageInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/quota/usage [get]
+func RestGetNsQuotaUsage(c echo.Context) error {
+
+	content, err := resource.GetNsQuotaUsage(c.Param("nsId"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPutNsNamingPolicy godoc
+// @ID PutNsNamingPolicy
+// @Summary Set naming policy of namespace
+// @Description Set the naming convention (required prefix, max length, allow/deny regular expressions) of the namespace.
+// @Description Requests for vNet, securityGroup, sshKey, MCI and K8sCluster creation with a violating name are rejected with 400.
+// @Description The policy is checked only at creation, so existing resources are not affected. Shared resources and registered resources are not checked.
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param policy body model.NsNamingPolicy true "Naming policy of the namespace"
+// @Success 200 {object} model.NsNamingPolicy
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/namingPolicy [put]
+func RestPutNsNamingPolicy(c echo.Context) error {
+
+	u := &model.NsNamingPolicy{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.UpdateNsNamingPolicy(c.Param("nsId"), u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetNsNamingPolicy godoc
+// @ID GetNsNamingPolicy
+// @Summary Get naming policy of namespace
+// @Description Get the naming convention of the namespace (empty fields are not enforced)
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Success 200 {object} model.NsNamingPolicy
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/namingPolicy [get]
+func RestGetNsNamingPolicy(c echo.Context) error {
+
+	content, err := common.GetNsNamingPolicy(c.Param("nsId"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostNsNamingPolicyValidate godoc
+// @ID PostNsNamingPolicyValidate
+// @Summary Validate names against naming policy of namespace
+// @Description Check names against the ID format and the naming policy of the namespace without creating anything (e.g., pre-check in CI pipelines).
+// @Description Each result has the violated element of the policy (idFormat, requiredPrefix, maxLength, allowPatterns, denyPatterns[i]).
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param req body model.NsNamingValidateReq true "Names to validate"
+// @Success 200 {object} model.NsNamingValidateResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/namingPolicy/validate [post]
+func RestPostNsNamingPolicyValidate(c echo.Context) error {
+
+	u := &model.NsNamingValidateReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.ValidateNsNames(c.Param("nsId"), u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
 // JSONResult's data field will be overridden by the specific type
 type JSONResult struct {
 	//Code    int          `json:"code" `
@@ -91,7 +224,7 @@ type RestGetAllNsResponse struct {
 // RestGetAllNs godoc
 // @ID GetAllNs
 // @Summary List all namespaces or namespaces' ID
-// @Description List all namespaces or namespaces' ID
+// @Description List all namespaces or namespaces' ID (only the namespace of the API key for a namespace-restricted API key)
 // @Tags [Admin] System Configuration
 // @Accept  json
 // @Produce  json
@@ -104,15 +237,25 @@ func RestGetAllNs(c echo.Context) error {
 
 	optionFlag := c.QueryParam("option")
 
+	// a namespace-restricted API key gets its namespace only
 	var content RestGetAllNsResponse
 	if optionFlag == "id" {
-		content := model.IdList{}
-		var err error
-		content.IdList, err = common.ListNsId()
+		content := model.IdList{IdList: []string{}}
+		nsIdList, err := common.ListNsId()
+		for _, nsId := range nsIdList {
+			if common.IsNsAccessible(c, nsId) {
+				content.IdList = append(content.IdList, nsId)
+			}
+		}
 		return common.EndRequestWithLog(c, err, content)
 	} else {
 		nsList, err := common.ListNs()
-		content.Ns = nsList
+		content.Ns = []model.NsInfo{}
+		for _, ns := range nsList {
+			if common.IsNsAccessible(c, ns.Id) {
+				content.Ns = append(content.Ns, ns)
+			}
+		}
 		return common.EndRequestWithLog(c, err, content)
 	}
 }
@@ -166,13 +309,15 @@ func RestPostNs(c echo.Context) error {
 // RestPutNs godoc
 // @ID PutNs
 // @Summary Update namespace
-// @Description Update namespace
+// @Description Update namespace. allowedConnections and allowedProviders restrict the connections usable in the namespace (empty: unrestricted);
+// @Description a provider in allowedProviders allows all its connections. Creating MCIs, resources and K8sClusters with other connections fails with 403.
 // @Tags [Admin] System Configuration
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param namespace body model.NsReq true "Details to update existing namespace"
 // @Success 200 {object} model.NsInfo
+// @Failure 400 {object} model.SimpleMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId} [put]
@@ -186,3 +331,136 @@ func RestPutNs(c echo.Context) error {
 	content, err := common.UpdateNs(c.Param("nsId"), u)
 	return common.EndRequestWithLog(c, err, content)
 }
+
+// restNsExport returns the export document of the namespace (json or tar.gz by the format query)
+func restNsExport(c echo.Context, includeSecrets bool) error {
+
+	if err := Validate(c, []string{"nsId"}); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	nsId := c.Param("nsId")
+
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "tar.gz" {
+		return common.EndRequestWithLog(c, fmt.Errorf("invalid format (%s); use json or tar.gz", format), nil)
+	}
+
+	doc, err := common.ExportNs(nsId, includeSecrets)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	if format != "tar.gz" {
+		return common.EndRequestWithLog(c, nil, doc)
+	}
+
+	archive, err := common.ArchiveNsExport(doc)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s-export.tar.gz", nsId))
+	return c.Blob(http.StatusOK, "application/gzip", archive)
+}
+
+// RestGetNsExport godoc
+// @ID GetNsExport
+// @Summary Export namespace
+// @Description Export all metadata of the namespace (resources, MCIs, labels, policies, specs and images) as a single document for backup.
+// @Description Secrets (e.g., private keys of sshKeys) are excluded; use POST /ns/{nsId}/export to include them.
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param format query string false "Format of the document" Enums(json, tar.gz) default(json)
+// @Success 200 {object} model.NsExportDoc
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/export [get]
+func RestGetNsExport(c echo.Context) error {
+	if c.QueryParam("includeSecrets") == "true" {
+		return common.EndRequestWithLog(c, common.NewValidationFailedError("secrets are exported only by POST /ns/%s/export", c.Param("nsId")), nil)
+	}
+	return restNsExport(c, false)
+}
+
+// RestPostNsExport godoc
+// @ID PostNsExport
+// @Summary Export namespace with secrets
+// @Description Export all metadata of the namespace like GET /ns/{nsId}/export. Secrets (e.g., private keys of sshKeys and admin passwords of sqlDbs)
+// @Description are included in plaintext if includeSecrets is true; they are encrypted again on import. The request is audited and denied for read-only callers.
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param format query string false "Format of the document" Enums(json, tar.gz) default(json)
+// @Param nsExportReq body model.NsExportReq true "Export options"
+// @Success 200 {object} model.NsExportDoc
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/export [post]
+func RestPostNsExport(c echo.Context) error {
+	req := &model.NsExportReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return restNsExport(c, req.IncludeSecrets)
+}
+
+// RestPostNsImport godoc
+// @ID PostNsImport
+// @Summary Import namespace
+// @Description Recreate the metadata of a namespace from an export document (JSON or tar.gz). Only metadata is restored;
+// @Description objects referencing CSP resources are labeled sys.imported=unverified so that a reconcile can verify them.
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsExport body model.NsExportDoc true "Export document (JSON or tar.gz made by the export API)"
+// @Param nsId query string false "Namespace ID to import into (default: nsId of the document)"
+// @Param mode query string false "Handling of id collision: fail, skip (keep the existing objects) or rename (import the colliding objects with new ids, e.g., vnet01-1)" Enums(fail, skip, rename) default(fail)
+// @Success 200 {object} model.NsImportResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/import [post]
+func RestPostNsImport(c echo.Context) error {
+
+	data, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	doc, err := common.ParseNsExport(data)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := common.ImportNs(doc, c.QueryParam("nsId"), c.QueryParam("mode"))
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, nil, result)
+}
+
+// RestGetNsLocks godoc
+// @ID GetNsLocks
+// @Summary List locks of namespace
+// @Description List the distributed locks held in the namespace (e.g., for shared resource creation). A lock expires at expiresAt if its holder stops renewing it.
+// @Tags [Admin] System Configuration
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Success 200 {object} []common.LockInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/locks [get]
+func RestGetNsLocks(c echo.Context) error {
+
+	if err := Validate(c, []string{"nsId"}); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.ListNsLocks(c.Param("nsId"))
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
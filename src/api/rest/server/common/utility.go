@@ -15,6 +15,8 @@ limitations under the License.
 package common
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,6 +28,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
 	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
 	"github.com/rs/zerolog/log"
@@ -76,7 +79,7 @@ func Validate(c echo.Context, params []string) error {
 // @ID GetReadyz
 // RestGetReadyz godoc
 // @Summary Check Tumblebug is ready
-// @Description Check Tumblebug is ready
+// @Description Check Tumblebug is ready (503 while the server is starting or draining for shutdown)
 // @Tags [Admin] System Management
 // @Accept  json
 // @Produce  json
@@ -90,9 +93,27 @@ func RestGetReadyz(c echo.Context) error {
 		message.Message = "CB-Tumblebug is NOT ready"
 		return c.JSON(http.StatusServiceUnavailable, &message)
 	}
+	if common.IsDraining() {
+		message.Message = fmt.Sprintf("CB-Tumblebug is draining (in-flight operations: %d)", common.InFlightOperationCount())
+		return c.JSON(http.StatusServiceUnavailable, &message)
+	}
 	return c.JSON(http.StatusOK, &message)
 }
 
+// RestGetHealth godoc
+// @ID GetHealth
+// @Summary Get health of CB-Tumblebug and its dependencies
+// @Description Get the status (ok, degraded or down) of the kvstore, CB-Spider and CB-Dragonfly (if configured) with probe latencies,
+// @Description and the number of in-flight long operations. It returns 200 even if components are down (see the status field).
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} common.HealthSummary
+// @Router /health [get]
+func RestGetHealth(c echo.Context) error {
+	return c.JSON(http.StatusOK, common.GetHealthSummary())
+}
+
 // RestCheckHTTPVersion godoc
 // @ID CheckHTTPVersion
 // @Summary Check HTTP version of incoming request
@@ -115,6 +136,20 @@ func RestCheckHTTPVersion(c echo.Context) error {
 	return c.JSON(http.StatusOK, &okMessage)
 }
 
+// RestGetMetrics godoc
+// @ID GetMetrics
+// @Summary Get metrics of CB-Tumblebug in Prometheus format
+// @Description Returns API request counts and latency per route, failed calls to CB-Spider, the number of objects per namespace and in-flight operations in Prometheus text exposition format.
+// @Tags [Admin] System Management
+// @Produce plain
+// @Success 200 {string} string "Metrics in Prometheus text exposition format"
+// @Router /metrics [get]
+func RestGetMetrics(c echo.Context) error {
+	var buf bytes.Buffer
+	metrics.Write(&buf)
+	return c.Blob(http.StatusOK, metrics.ContentType, buf.Bytes())
+}
+
 // RestGetPublicKeyForCredentialEncryption godoc
 // @ID GetPublicKeyForCredentialEncryption
 // @Summary Get RSA Public Key for Credential Encryption
@@ -139,18 +174,20 @@ func RestGetPublicKeyForCredentialEncryption(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param CredentialReq body model.CredentialReq true "Credential request info"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.CredentialInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /credential [post]
 func RestRegisterCredential(c echo.Context) error {
 
 	u := &model.CredentialReq{}
-	if err := c.Bind(u); err != nil {
+	if err := common.BindRequest(c, u); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	content, err := common.RegisterCredential(*u)
+	content, err := common.RegisterCredential(common.NewRequestContext(c), *u)
 	return common.EndRequestWithLog(c, err, content)
 
 }
@@ -177,17 +214,44 @@ func RestGetConnConfig(c echo.Context) error {
 
 }
 
+// RestPutConnConfigRootDisk godoc
+// @ID PutConnConfigRootDisk
+// @Summary Set default root disk of ConnConfig
+// @Description Set the default root disk (type and size) of VMs created dynamically (mciDynamic, vmDynamic) with the connection.
+// @Description The rootDiskType and rootDiskSize of a VM request win over the defaults. Use "" or "default" to follow the CSP default.
+// @Tags [Admin] Credential Management
+// @Accept  json
+// @Produce  json
+// @Param connConfigName path string true "Name of connection config (cloud config)"
+// @Param rootDisk body model.ConnConfigRootDiskReq true "Default root disk of the connection"
+// @Success 200 {object} model.ConnConfig
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /connConfig/{connConfigName}/rootDisk [put]
+func RestPutConnConfigRootDisk(c echo.Context) error {
+
+	u := &model.ConnConfigRootDiskReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.UpdateConnConfigRootDisk(c.Param("connConfigName"), u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
 // RestGetConnConfigList func is a rest api wrapper for GetConnConfigList.
 // RestGetConnConfigList godoc
 // @ID GetConnConfigList
 // @Summary List all registered ConnConfig
-// @Description List all registered ConnConfig
+// @Description List all registered ConnConfig. Each connection has its health (healthy, failing, neverChecked) derived from
+// @Description the latest verification and the calls failed by auth errors (lastVerifiedTime, lastCheckedTime, consecutiveFailures, lastError).
 // @Tags [Admin] Credential Management
 // @Accept  json
 // @Produce  json
 // @Param filterCredentialHolder query string false "filter objects by Credential Holder" default()
-// @Param filterVerified query boolean false "filter verified connections only" Enums(true, false) default(true)
+// @Param filterVerified query boolean false "filter verified connections only (default: false if filterHealth is given)" Enums(true, false) default(true)
 // @Param filterRegionRepresentative query boolean false "filter connections with the representative region only" Enums(true, false) default(false)
+// @Param filterHealth query string false "filter connections by health" Enums(healthy, failing, neverChecked)
 // @Success 200 {object} model.ConnConfigList
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
@@ -198,9 +262,12 @@ func RestGetConnConfigList(c echo.Context) error {
 	filterVerified := c.QueryParam("filterVerified")
 	filterRegionRepresentative := c.QueryParam("filterRegionRepresentative")
 
+	filterHealth := c.QueryParam("filterHealth")
+
 	filterVerifiedBool, err := strconv.ParseBool(filterVerified)
 	if err != nil {
-		filterVerifiedBool = true
+		// failing connections are not verified, so all connections are filtered by health
+		filterVerifiedBool = filterHealth == ""
 	}
 	filterRegionRepresentativeBool, err := strconv.ParseBool(filterRegionRepresentative)
 	if err != nil {
@@ -208,9 +275,88 @@ func RestGetConnConfigList(c echo.Context) error {
 	}
 
 	content, err := common.GetConnConfigList(filterCredentialHolder, filterVerifiedBool, filterRegionRepresentativeBool)
+	if err == nil && filterHealth != "" {
+		content, err = common.FilterConnConfigByHealth(content, filterHealth)
+	}
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostVerifyConnConfig godoc
+// @ID PostVerifyConnConfig
+// @Summary Re-verify ConnConfig
+// @Description Re-verify the connection against CB-Spider and record the result (verified, lastVerifiedTime, lastCheckedTime, consecutiveFailures, lastError)
+// @Tags [Admin] Credential Management
+// @Accept  json
+// @Produce  json
+// @Param connConfigName path string true "Name of connection config (cloud config)" default(aws-ap-northeast-2)
+// @Success 200 {object} model.ConnConfig
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /connConfig/{connConfigName}/verify [post]
+func RestPostVerifyConnConfig(c echo.Context) error {
+	connConfigName := c.Param("connConfigName")
+	content, err := common.VerifyConnConfig(connConfigName)
 	return common.EndRequestWithLog(c, err, content)
 }
 
+// RestPostVerifyAllConnConfig godoc
+// @ID PostVerifyAllConnConfig
+// @Summary Re-verify all ConnConfigs
+// @Description Re-verify all connections against CB-Spider and record the results, to spot broken credentials (e.g., revoked keys, expired tokens)
+// @Tags [Admin] Credential Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.ConnConfigList
+// @Failure 500 {object} model.SimpleMsg
+// @Router /connConfig/verify [post]
+func RestPostVerifyAllConnConfig(c echo.Context) error {
+	content, err := common.VerifyAllConnConfig()
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetConnConfigExport godoc
+// @ID GetConnConfigExport
+// @Summary Export connection configs
+// @Description Export the connection configs and their region assignments to bootstrap another CB-Tumblebug which uses the same CSP accounts.
+// @Description Credentials are referred by name only (no secrets are exported).
+// @Tags [Admin] Credential Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.ConnConfigExport
+// @Failure 500 {object} model.SimpleMsg
+// @Router /connConfig/export [get]
+func RestGetConnConfigExport(c echo.Context) error {
+	content, err := common.ExportConnConfigs()
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostConnConfigImport godoc
+// @ID PostConnConfigImport
+// @Summary Import connection configs
+// @Description Recreate the connection configs of an export document against CB-Spider, which must already hold the credentials of the same names.
+// @Description Each imported connection is verified. Connections whose credential is missing in CB-Spider are reported as missingCredential.
+// @Description The import is idempotent: existing connections are kept and reported as existing.
+// @Tags [Admin] Credential Management
+// @Accept  json
+// @Produce  json
+// @Param connConfigExport body model.ConnConfigExport true "Document exported by GET /connConfig/export"
+// @Success 200 {object} model.ConnConfigImportResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /connConfig/import [post]
+func RestPostConnConfigImport(c echo.Context) error {
+	doc := &model.ConnConfigExport{}
+	if err := c.Bind(doc); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.ImportConnConfigs(*doc)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
 // RestGetProviderList func is a rest api wrapper for GetProviderList.
 // RestGetProviderList godoc
 // @ID GetProviderList
@@ -230,6 +376,39 @@ func RestGetProviderList(c echo.Context) error {
 
 }
 
+// RestGetProviderCapabilities godoc
+// @ID GetProviderCapabilities
+// @Summary Get capabilities of a provider
+// @Description Get the support level (supported, partial, unsupported) of features (nlb, k8scluster, vpn, customImage, dataDisk, spotInstance, ...) for the provider
+// @Tags [Admin] Multi-Cloud Information
+// @Accept  json
+// @Produce  json
+// @Param providerName path string true "Name of the CSP to retrieve" default(aws)
+// @Success 200 {object} common.ProviderCapabilities
+// @Failure 400 {object} model.SimpleMsg
+// @Router /provider/{providerName}/capabilities [get]
+func RestGetProviderCapabilities(c echo.Context) error {
+	content, err := common.GetProviderCapabilities(c.Param("providerName"))
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
+// RestGetCapabilityMatrix godoc
+// @ID GetCapabilityMatrix
+// @Summary Get capability matrix of all providers
+// @Description Get the support level (supported, partial, unsupported) of features for all providers with notes
+// @Tags [Admin] Multi-Cloud Information
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} common.CapabilityMatrix
+// @Router /capabilities [get]
+func RestGetCapabilityMatrix(c echo.Context) error {
+	content := common.GetCapabilityMatrix()
+	return common.EndRequestWithLog(c, nil, content)
+}
+
 // RestGetRegion func is a rest api wrapper for GetRegion.
 // RestGetRegion godoc
 // @ID GetRegion
@@ -253,6 +432,74 @@ func RestGetRegion(c echo.Context) error {
 	return common.EndRequestWithLog(c, err, content)
 }
 
+// RestPostCustomRegion godoc
+// @ID PostCustomRegion
+// @Summary Add a custom region to the cloud info
+// @Description Add a region (e.g., a new region of a CSP or a private OpenStack region) to the cloud info at runtime and register it to CB-Spider.
+// @Description The custom region is kept in the Key-Value store and overrides the region of the cloudinfo asset with the same name.
+// @Tags [Admin] Multi-Cloud Information
+// @Accept  json
+// @Produce  json
+// @Param providerName path string true "Name of the CSP" default(openstack)
+// @Param regionName path string true "Name of the region" default(private-region01)
+// @Param regionReq body model.RegionDetail true "Region detail (zones are required)"
+// @Success 200 {object} model.RegionDetail
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /cloudInfo/provider/{providerName}/region/{regionName} [post]
+func RestPostCustomRegion(c echo.Context) error {
+	req := model.RegionDetail{}
+	if err := c.Bind(&req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	content, err := common.CreateCustomRegion(c.Param("providerName"), c.Param("regionName"), req)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPutCustomRegion godoc
+// @ID PutCustomRegion
+// @Summary Update a custom region of the cloud info
+// @Description Update the custom region (or override the region of the cloudinfo asset) and register it to CB-Spider
+// @Tags [Admin] Multi-Cloud Information
+// @Accept  json
+// @Produce  json
+// @Param providerName path string true "Name of the CSP" default(openstack)
+// @Param regionName path string true "Name of the region" default(private-region01)
+// @Param regionReq body model.RegionDetail true "Region detail (zones are required)"
+// @Success 200 {object} model.RegionDetail
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /cloudInfo/provider/{providerName}/region/{regionName} [put]
+func RestPutCustomRegion(c echo.Context) error {
+	req := model.RegionDetail{}
+	if err := c.Bind(&req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	content, err := common.UpdateCustomRegion(c.Param("providerName"), c.Param("regionName"), req)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDeleteCustomRegion godoc
+// @ID DeleteCustomRegion
+// @Summary Delete a custom region of the cloud info
+// @Description Delete the custom region. The region of the cloudinfo asset is restored if it was overridden.
+// @Tags [Admin] Multi-Cloud Information
+// @Accept  json
+// @Produce  json
+// @Param providerName path string true "Name of the CSP" default(openstack)
+// @Param regionName path string true "Name of the region" default(private-region01)
+// @Success 200 {object} model.SimpleMsg
+// @Failure 400 {object} model.SimpleMsg
+// @Router /cloudInfo/provider/{providerName}/region/{regionName} [delete]
+func RestDeleteCustomRegion(c echo.Context) error {
+	err := common.DeleteCustomRegion(c.Param("providerName"), c.Param("regionName"))
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	content := model.SimpleMsg{Message: "The custom region " + c.Param("regionName") + " has been deleted"}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
 // RestGetRegions func is a rest api wrapper for GetRegion.
 // RestGetRegions godoc
 // @ID GetRegions
@@ -329,33 +576,206 @@ func RestGetK8sClusterInfo(c echo.Context) error {
 	return common.EndRequestWithLog(c, err, content)
 }
 
-// ObjectList struct consists of object IDs
-type ObjectList struct {
-	Object []string `json:"object"`
-}
-
 // func RestGetObjects is a rest api wrapper for GetObjectList.
 // RestGetObjects godoc
 // @ID GetObjects
 // @Summary List all objects for a given key
-// @Description List all objects for a given key
+// @Description List all objects for a given key. Use limit and continueToken for pagination,
+// @Description depth=1 to list only the immediate children (directory view), and keysOnly=false to get values as well.
 // @Tags [Admin] System Management
 // @Accept  json
 // @Produce  json
 // @Param key query string true "retrieve objects by key"
-// @Success 200 {object} model.SimpleMsg
-// @Failure 404 {object} model.SimpleMsg
+// @Param keysOnly query boolean false "Return keys only" default(true)
+// @Param limit query int false "Max number of objects in a page (0: no pagination)" default(0)
+// @Param continueToken query string false "Token of the next page (given by the previous page)"
+// @Param depth query int false "1: immediate children only, 0: all descendants" Enums(0, 1) default(0)
+// @Success 200 {object} common.ObjectListResult
+// @Failure 400 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /objects [get]
 func RestGetObjects(c echo.Context) error {
 	parentKey := c.QueryParam("key")
-	content := common.GetObjectList(parentKey)
 
-	objectList := ObjectList{}
-	for _, v := range content {
-		objectList.Object = append(objectList.Object, v)
+	option := common.ObjectListOption{
+		KeysOnly:      c.QueryParam("keysOnly") != "false",
+		ContinueToken: c.QueryParam("continueToken"),
+	}
+	var err error
+	if v := c.QueryParam("limit"); v != "" {
+		if option.Limit, err = strconv.Atoi(v); err != nil {
+			return common.EndRequestWithLog(c, fmt.Errorf("invalid limit (%s)", v), nil)
+		}
+	}
+	if v := c.QueryParam("depth"); v != "" {
+		if option.Depth, err = strconv.Atoi(v); err != nil {
+			return common.EndRequestWithLog(c, fmt.Errorf("invalid depth (%s)", v), nil)
+		}
+	}
+
+	content, err := common.GetObjectListWithOption(parentKey, option)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return c.JSON(http.StatusOK, &content)
+}
+
+// RestGetObjectStats godoc
+// @ID GetObjectStats
+// @Summary Get the number and the total size of objects for a given key
+// @Description Get the number and the total size (bytes of keys and values) of objects under a key prefix without transferring them
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Param key query string true "key prefix of objects"
+// @Success 200 {object} common.ObjectStats
+// @Failure 500 {object} model.SimpleMsg
+// @Router /objects/stats [get]
+func RestGetObjectStats(c echo.Context) error {
+	content, err := common.GetObjectStats(c.QueryParam("key"))
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
+// RestHeadObjects is the HEAD of /objects returning the number and the total size of objects
+// in the X-Object-Count and X-Object-Size headers
+func RestHeadObjects(c echo.Context) error {
+	content, err := common.GetObjectStats(c.QueryParam("key"))
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	c.Response().Header().Set("X-Object-Count", strconv.FormatInt(content.Count, 10))
+	c.Response().Header().Set("X-Object-Size", strconv.FormatInt(content.Size, 10))
+	return c.NoContent(http.StatusOK)
+}
+
+// RestGetConsistency godoc
+// @ID GetConsistency
+// @Summary Check consistency of objects
+// @Description Scan all namespaces for orphaned child objects (subnets without vNets, snapshots without dataDisks, VMs and subGroups without MCIs)
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} common.ConsistencyReport
+// @Failure 500 {object} model.SimpleMsg
+// @Router /consistency [get]
+func RestGetConsistency(c echo.Context) error {
+	content, err := common.CheckConsistency(false)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
+// RestPostConsistencyRepair godoc
+// @ID PostConsistencyRepair
+// @Summary Repair consistency of objects
+// @Description Delete orphaned child objects (subnets without vNets, snapshots without dataDisks, VMs and subGroups without MCIs)
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} common.ConsistencyReport
+// @Failure 500 {object} model.SimpleMsg
+// @Router /consistency/repair [post]
+func RestPostConsistencyRepair(c echo.Context) error {
+	content, err := common.CheckConsistency(true)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
+// RestPostSyncSpider godoc
+// @ID PostSyncSpider
+// @Summary Sync cloud info with CB-Spider
+// @Description Re-create drivers, regions and connection configs missing in CB-Spider (e.g., after CB-Spider is restarted with a fresh database).
+// @Description Credentials cannot be restored; connections whose credential is missing are reported as brokenConnections and need credential re-registration.
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} common.SpiderSyncReport
+// @Failure 500 {object} model.SimpleMsg
+// @Router /admin/syncSpider [post]
+func RestPostSyncSpider(c echo.Context) error {
+	content, err := common.SyncSpider()
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
+	}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
+// RestGetSpiderVersion godoc
+// @ID GetSpiderVersion
+// @Summary Get the version of CB-Spider
+// @Description Get the version of CB-Spider detected at the startup (or detect it again with refresh=true),
+// @Description whether it is in the supported range and the version-dependent request features (e.g., IDTransformMode, TagList) enabled for it.
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Param refresh query bool false "Detect the version of CB-Spider again" default(false)
+// @Success 200 {object} model.SpiderVersionInfo
+// @Router /admin/spiderVersion [get]
+func RestGetSpiderVersion(c echo.Context) error {
+	if c.QueryParam("refresh") == "true" {
+		return common.EndRequestWithLog(c, nil, common.DetectSpiderVersion())
+	}
+	return common.EndRequestWithLog(c, nil, common.GetSpiderVersionInfo())
+}
+
+// RestPostMigrateKvStore godoc
+// @ID PostMigrateKvStore
+// @Summary Copy all keys between backends of the key-value store
+// @Description Copy all keys (or the keys with keyPrefix) from the source backend of the key-value store (the current backend if omitted)
+// @Description to the target backend, e.g., from etcd to the embedded store (bolt) for a single-node deployment or back to etcd.
+// @Description Only the backends of the server configuration (TB_ETCD_ENDPOINTS, TB_KVSTORE_BOLT_PATH) or listed in
+// @Description TB_KVSTORE_MIGRATE_ETCD_ENDPOINTS and TB_KVSTORE_MIGRATE_BOLT_PATHS (comma-separated) can be the source or the target.
+// @Description The existing keys of the target are kept unless overwrite is true.
+// @Description CB-Tumblebug keeps using the current backend; restart it with TB_KVSTORE_TYPE (and TB_KVSTORE_BOLT_PATH) of the target to switch.
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Param kvStoreMigrateReq body model.KvStoreMigrateReq true "Source and target backends of the key-value store"
+// @Success 200 {object} model.KvStoreMigrateResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 409 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /admin/kvstore/migrate [post]
+func RestPostMigrateKvStore(c echo.Context) error {
+	req := &model.KvStoreMigrateReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	result, err := common.MigrateKvStore(c.Request().Context(), req)
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPostGc godoc
+// @ID PostGc
+// @Summary Garbage collection of orphaned CSP resources
+// @Description List the CSP resources created by CB-Tumblebug (named by uid) which have no record in CB-Tumblebug (e.g., left by a failed provisioning), grouped by connection.
+// @Description The dry run returns a confirmToken; call again with dryRun=false and the token to delete the listed resources through CB-Spider.
+// @Description Resources younger than TB_GC_MIN_AGE_MIN (default 60 minutes) are skipped, and every deletion is written to the audit log.
+// @Tags [Admin] System Management
+// @Accept  json
+// @Produce  json
+// @Param dryRun query boolean false "List orphaned resources without deleting them" default(true)
+// @Param confirmToken query string false "Confirm token returned by the dry run (required if dryRun=false)"
+// @Success 200 {object} model.GcResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /admin/gc [post]
+func RestPostGc(c echo.Context) error {
+	dryRun := c.QueryParam("dryRun") != "false"
+	confirmToken := c.QueryParam("confirmToken")
+	reqId := c.Request().Header.Get(echo.HeaderXRequestID)
+
+	content, err := infra.CollectOrphanedResources(dryRun, confirmToken, reqId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, model.SimpleMsg{Message: err.Error()})
 	}
-	return c.JSON(http.StatusOK, &objectList)
+	return common.EndRequestWithLog(c, nil, content)
 }
 
 // func RestGetObject is a rest api wrapper for GetObject.
@@ -451,10 +871,12 @@ type RestInspectResourcesRequest struct {
 // @ID InspectResources
 // @Summary Inspect Resources (vNet, securityGroup, sshKey, vm) registered in CB-Tumblebug, CB-Spider, CSP
 // @Description Inspect Resources (vNet, securityGroup, sshKey, vm) registered in CB-Tumblebug, CB-Spider, CSP
+// @Description Resources on CB-Tumblebug whose definition differs from the CSP (e.g., CIDR, firewall rules, tags, attached disks) are listed in "drifted" with the field-level differences.
 // @Tags [Admin] System Management
 // @Accept  json
 // @Produce  json
 // @Param connectionName body RestInspectResourcesRequest true "Specify connectionName and resource type"
+// @Param suggest query bool false "Suggest how to reconcile the drifted resources (update the Tumblebug record or push the Tumblebug state back to the CSP). Suggestions are not executed." default(false)
 // @Success 200 {object} model.InspectResource
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
@@ -475,7 +897,7 @@ func RestInspectResources(c echo.Context) error {
 	// } else if u.Type == "vm" {
 	// 	content, err = infra.InspectVMs(u.ConnectionName)
 	// }
-	content, err = infra.InspectResources(u.ConnectionName, u.ResourceType)
+	content, err = infra.InspectResourcesWithDrift(u.ConnectionName, u.ResourceType, c.QueryParam("suggest") == "true")
 	return common.EndRequestWithLog(c, err, content)
 
 }
@@ -484,6 +906,7 @@ func RestInspectResources(c echo.Context) error {
 // @ID InspectResourcesOverview
 // @Summary Inspect Resources Overview (vNet, securityGroup, sshKey, vm) registered in CB-Tumblebug and CSP for all connections
 // @Description Inspect Resources Overview (vNet, securityGroup, sshKey, vm) registered in CB-Tumblebug and CSP for all connections
+// @Description driftedOverview counts the resources on CB-Tumblebug whose definition differs from the CSP.
 // @Tags [Admin] System Management
 // @Accept  json
 // @Produce  json
@@ -548,7 +971,9 @@ type RestRegisterCspNativeResourcesRequestAll struct {
 // @Param Request body RestRegisterCspNativeResourcesRequestAll true "Specify NS Id and MCI Name"
 // @Param option query string false "Option to specify resourceType" Enums(onlyVm, exceptVm)
 // @Param mciFlag query string false "Flag to show VMs in a collective MCI form (y,n)" Enums(y, n) default(y)
+// @Param async query bool false "Run as an async job and return the job immediately (track it by GET /jobs/{jobId})" default(false)
 // @Success 200 {object} model.RegisterResourceAllResult
+// @Success 202 {object} model.JobInfo
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /registerCspResourcesAll [post]
@@ -561,6 +986,14 @@ func RestRegisterCspNativeResourcesAll(c echo.Context) error {
 	option := c.QueryParam("option")
 	mciFlag := c.QueryParam("mciFlag")
 
+	if c.QueryParam("async") == "true" {
+		job, err := infra.RegisterCspNativeResourcesAllAsync(u.NsId, u.MciName, option, mciFlag)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		return c.JSON(http.StatusAccepted, job)
+	}
+
 	content, err := infra.RegisterCspNativeResourcesAll(u.NsId, u.MciName, option, mciFlag)
 	return common.EndRequestWithLog(c, err, content)
 }
@@ -568,36 +1001,86 @@ func RestRegisterCspNativeResourcesAll(c echo.Context) error {
 // RestForwardAnyReqToAny godoc
 // @ID ForwardAnyReqToAny
 // @Summary Forward any (GET) request to CB-Spider
-// @Description Forward any (GET) request to CB-Spider
+// @Description Forward any (GET) request to CB-Spider (or to a host allowed by TB_FORWARD_ALLOWED_HOSTS when an absolute http(s) URL is given as the path).
+// @Description The upstream response is streamed with its status, Content-Type and Content-Length, and X-Request-Id is propagated to the upstream.
+// @Description The request is limited by TB_FORWARD_TIMEOUT_SEC (504 on timeout) and the response by TB_FORWARD_MAX_RESPONSE_MB (502 if exceeded).
 // @Tags [Admin] API Request Management
 // @Accept  json
 // @Produce  json
 // @Param path path string true "Internal call path to CB-Spider (path without /spider/ prefix) - see [https://documenter.getpostman.com/view/24786935/2s9Ykq8Lpf#231eec23-b0ab-4966-83ce-a0ef92ead7bc] for more details"" default(vmspec)
 // @Param Request body interface{} false "Request body (various formats) - see [https://documenter.getpostman.com/view/24786935/2s9Ykq8Lpf#231eec23-b0ab-4966-83ce-a0ef92ead7bc] for more details"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} common.ForwardError
+// @Failure 403 {object} common.ForwardError
+// @Failure 502 {object} common.ForwardError
+// @Failure 504 {object} common.ForwardError
 // @Router /forward/{path} [post]
 func RestForwardAnyReqToAny(c echo.Context) error {
 
 	reqPath := c.Param("*")
 	reqPath, err := url.PathUnescape(reqPath)
 	if err != nil {
-		return common.EndRequestWithLog(c, err, nil)
+		return common.EndForwardRequest(c, http.StatusBadRequest, 0, &common.ForwardError{StatusCode: http.StatusBadRequest, Message: err.Error()})
 	}
 
 	log.Info().Msgf("reqPath: %s", reqPath)
 
+	targetUrl, err := common.ResolveForwardUrl(reqPath, c.QueryString())
+	if err != nil {
+		return common.EndForwardRequest(c, http.StatusForbidden, 0, err)
+	}
+
 	method := "GET"
-	var requestBody interface{}
+	var bodyBytes []byte
 	if c.Request().Body != nil {
-		bodyBytes, err := io.ReadAll(c.Request().Body)
+		bodyBytes, err = io.ReadAll(c.Request().Body)
 		if err != nil {
-			return common.EndRequestWithLog(c, fmt.Errorf("Failed to read request body: %v", err), nil)
+			return common.EndForwardRequest(c, http.StatusBadRequest, 0, &common.ForwardError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("Failed to read request body: %v", err)})
 		}
-		requestBody = bodyBytes
-	} else {
-		requestBody = common.NoBody
 	}
 
-	content, err := common.ForwardRequestToAny(reqPath, method, requestBody)
-	return common.EndRequestWithLog(c, err, content)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), common.ForwardTimeout())
+	defer cancel()
+
+	reqId := c.Request().Header.Get(echo.HeaderXRequestID)
+	resp, err := common.ForwardRequest(ctx, method, targetUrl, bodyBytes, c.Request().Header, reqId)
+	if err != nil {
+		return common.EndForwardRequest(c, http.StatusBadGateway, 0, err)
+	}
+	defer resp.Body.Close()
+
+	maxBytes := common.ForwardMaxBytes()
+	if resp.ContentLength > maxBytes {
+		return common.EndForwardRequest(c, http.StatusBadGateway, 0, &common.ForwardError{
+			StatusCode: http.StatusBadGateway,
+			Message:    fmt.Sprintf("upstream response (%d bytes) exceeds the limit (%d bytes)", resp.ContentLength, maxBytes),
+			Upstream:   targetUrl,
+		})
+	}
+
+	// Pass through the headers of the upstream response.
+	// Without Content-Length, the response is sent with chunked transfer encoding.
+	for _, h := range common.ForwardResponseHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			c.Response().Header().Set(h, v)
+		}
+	}
+	c.Response().Header().Set(echo.HeaderXRequestID, reqId)
+	c.Response().WriteHeader(resp.StatusCode)
+
+	written, err := io.Copy(c.Response(), io.LimitReader(resp.Body, maxBytes))
+	c.Response().Flush()
+	if err == nil && written == maxBytes {
+		// the stream is cut at the limit if the upstream has more to send
+		if n, _ := resp.Body.Read(make([]byte, 1)); n > 0 {
+			err = fmt.Errorf("upstream response exceeds the limit (%d bytes) and is truncated", maxBytes)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to stream the response from %s", targetUrl)
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("upstream did not complete the response in time: %w", err)
+		}
+	}
+	return common.EndForwardRequest(c, resp.StatusCode, written, err)
 }
@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to handle REST API for common funcitonalities
+package common
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// RestPostWebhook godoc
+// @ID PostWebhook
+// @Summary Subscribe webhook
+// @Description Subscribe a URL to lifecycle events (mci.created, mci.deleted, vm.statusChanged, k8scluster.created, k8scluster.deleted).
+// @Description Events are delivered by POST with retries and exponential backoff. If a secret is given, the payload is signed by HMAC-SHA256 in X-Tumblebug-Signature header (sha256=hex).
+// @Tags [Admin] Webhook Management
+// @Accept  json
+// @Produce  json
+// @Param webhookReq body model.WebhookReq true "Target URL, secret and filters of the webhook"
+// @Success 200 {object} model.WebhookInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Router /webhooks [post]
+func RestPostWebhook(c echo.Context) error {
+
+	u := &model.WebhookReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := common.CreateWebhook(u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetAllWebhooks godoc
+// @ID GetAllWebhooks
+// @Summary List webhooks
+// @Description List all webhook subscriptions (secrets are not included)
+// @Tags [Admin] Webhook Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.WebhookInfoList
+// @Failure 500 {object} model.SimpleMsg
+// @Router /webhooks [get]
+func RestGetAllWebhooks(c echo.Context) error {
+
+	content, err := common.ListWebhooks()
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetWebhook godoc
+// @ID GetWebhook
+// @Summary Get webhook
+// @Description Get the webhook subscription (the secret is not included)
+// @Tags [Admin] Webhook Management
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Success 200 {object} model.WebhookInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Router /webhooks/{webhookId} [get]
+func RestGetWebhook(c echo.Context) error {
+
+	content, err := common.GetWebhook(c.Param("webhookId"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDelWebhook godoc
+// @ID DelWebhook
+// @Summary Unsubscribe webhook
+// @Description Delete the webhook subscription and its delivery attempts
+// @Tags [Admin] Webhook Management
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Success 200 {object} model.SimpleMsg
+// @Failure 400 {object} model.SimpleMsg
+// @Router /webhooks/{webhookId} [delete]
+func RestDelWebhook(c echo.Context) error {
+
+	err := common.DelWebhook(c.Param("webhookId"))
+	content := map[string]string{"message": "The webhook " + c.Param("webhookId") + " has been deleted"}
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetWebhookDeliveries godoc
+// @ID GetWebhookDeliveries
+// @Summary Get delivery attempts of webhook
+// @Description Get the recent delivery attempts of the webhook (newest first)
+// @Tags [Admin] Webhook Management
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Success 200 {object} model.WebhookDeliveryList
+// @Failure 400 {object} model.SimpleMsg
+// @Router /webhooks/{webhookId}/deliveries [get]
+func RestGetWebhookDeliveries(c echo.Context) error {
+
+	content, err := common.GetWebhookDeliveries(c.Param("webhookId"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostTestWebhook godoc
+// @ID PostTestWebhook
+// @Summary Test webhook
+// @Description Send a test event (webhook.test) to the webhook once and return the delivery attempt
+// @Tags [Admin] Webhook Management
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Success 200 {object} model.WebhookDelivery
+// @Failure 400 {object} model.SimpleMsg
+// @Router /webhooks/{webhookId}/test [post]
+func RestPostTestWebhook(c echo.Context) error {
+
+	content, err := common.TestWebhook(c.Param("webhookId"), c.Request().Header.Get(echo.HeaderXRequestID))
+	return common.EndRequestWithLog(c, err, content)
+}
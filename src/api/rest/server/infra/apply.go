@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mci is to handle REST API for mci
+package infra
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+)
+
+// RestPostApplyNs godoc
+// @ID PostApplyNs
+// @Summary Apply desired state to namespace
+// @Description Converge the namespace to the manifest (vNet, securityGroup, sshKey, mciDynamic, mci) by the existing create/update/delete functions.
+// @Description The changes are executed in the order of the dependencies (vNet, sshKey, securityGroup, then MCI; deletions in the reverse order).
+// @Description Resources which are not in the manifest are deleted only with prune=true. Changes which cannot be done in place are reported as replace and skipped.
+// @Description The manifest can be JSON or YAML (Content-Type: application/yaml).
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Accept  application/yaml
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param manifest body model.NsManifest true "Desired state of the namespace"
+// @Param plan query bool false "Return the changes without executing them" default(false)
+// @Param prune query bool false "Delete the resources which are not in the manifest" default(false)
+// @Param async query bool false "Run as an async job and return the job immediately (track it by GET /jobs/{jobId})" default(false)
+// @Success 200 {object} model.ApplyResult
+// @Success 202 {object} model.JobInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/apply [post]
+func RestPostApplyNs(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	planOnly := c.QueryParam("plan") == "true"
+	prune := c.QueryParam("prune") == "true"
+
+	data, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	if strings.Contains(c.Request().Header.Get(echo.HeaderContentType), "yaml") {
+		data, err = common.YamlToJson(data)
+		if err != nil {
+			return common.EndRequestWithLog(c, common.NewValidationFailedError("invalid YAML manifest: %v", err), nil)
+		}
+	}
+	manifest := &model.NsManifest{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if c.QueryParam("strict") == "true" {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(manifest); err != nil && err != io.EOF {
+		return common.EndRequestWithLog(c, common.ToRequestValidationError(err), nil)
+	}
+
+	if !planOnly && c.QueryParam("async") == "true" {
+		job, err := infra.ApplyNsAsync(nsId, manifest, prune)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		return c.JSON(http.StatusAccepted, job)
+	}
+
+	result, err := infra.ApplyNs(common.NewRequestContext(c), nsId, manifest, planOnly, prune)
+	if err != nil && len(result.Changes) == 0 {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, nil, result)
+}
@@ -15,6 +15,8 @@ limitations under the License.
 package infra
 
 import (
+	"strconv"
+
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
 	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
@@ -96,23 +98,54 @@ func RestGetAllBenchmark(c echo.Context) error {
 
 // RestGetLatencyBenchmark godoc
 // @ID GetLatencyBenchmark
-// @Summary Run MCI benchmark for network latency
-// @Description Run MCI benchmark for network latency
+// @Summary Start MCI benchmark for network latency
+// @Description Start an async run which measures the round-trip time of all VM pairs in MCI (via the benchmark agent of each VM).
+// @Description The run returns immediately; poll GET /ns/{nsId}/benchmarkLatency/result/{runId} for the matrix with the status of each cell (pending, done, failed).
+// @Description A completed run includes min/avg/max summaries per region pair.
 // @Tags [MC-Infra] MCI Performance Benchmarking (WIP)
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(system)
 // @Param mciId path string true "MCI ID" default(probe)
-// @Success 200 {object} model.BenchmarkInfoArray
-// @Failure 404 {object} model.SimpleMsg
+// @Param concurrency query int false "Number of measurements in parallel (max 50)" default(10)
+// @Success 200 {object} model.LatencyBenchmarkRun
+// @Failure 400 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/benchmarkLatency/mci/{mciId} [get]
 func RestGetBenchmarkLatency(c echo.Context) error {
 
 	nsId := c.Param("nsId")
 	mciId := c.Param("mciId")
+	concurrency, _ := strconv.Atoi(c.QueryParam("concurrency"))
 
-	content, err := infra.RunLatencyBenchmark(nsId, mciId, "")
+	content, err := infra.StartLatencyBenchmark(nsId, mciId, concurrency)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetBenchmarkLatencyResult godoc
+// @ID GetBenchmarkLatencyResult
+// @Summary Get the result of MCI benchmark for network latency
+// @Description Get the latency matrix of the run (partially filled while the run is in progress)
+// @Tags [MC-Infra] MCI Performance Benchmarking (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(system)
+// @Param runId path string true "Run ID of the latency benchmark"
+// @Success 200 {object} model.LatencyBenchmarkRun
+// @Failure 400 {object} model.SimpleMsg
+// @Router /ns/{nsId}/benchmarkLatency/result/{runId} [get]
+func RestGetBenchmarkLatencyResult(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	runId := c.Param("runId")
+
+	content, err := infra.GetLatencyBenchmarkResult(nsId, runId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
 	return common.EndRequestWithLog(c, err, content)
 }
 
@@ -144,3 +144,171 @@ func RestPostMciVmSnapshot(c echo.Context) error {
 	}
 	return common.EndRequestWithLog(c, err, result)
 }
+
+// RestPutMciVmPublicIp godoc
+// @ID PutMciVmPublicIp
+// @Summary Attach a public IP to VM
+// @Description Allocate (or reuse the given elastic/static address) and associate a public IP to an existing VM, and check the SSH port with the new address.
+// @Description The change is recorded in the events of the VM.
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param vmId path string true "VM ID" default(g1-1)
+// @Param publicIpReq body model.TbVmPublicIpReq false "Address to reuse (a new address is allocated if empty)"
+// @Success 200 {object} model.TbVmPublicIpInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Failure 501 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/vm/{vmId}/publicIp [put]
+func RestPutMciVmPublicIp(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	vmId := c.Param("vmId")
+
+	u := &model.TbVmPublicIpReq{}
+	if c.Request().ContentLength > 0 {
+		if err := c.Bind(u); err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+	}
+
+	result, err := infra.AttachPublicIp(nsId, mciId, vmId, u)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestDelMciVmPublicIp godoc
+// @ID DelMciVmPublicIp
+// @Summary Detach the public IP from VM
+// @Description Disassociate the public IP from VM (the address is released unless release=false, to be reused later).
+// @Description If it removes the only access path (no other bastion in the subnet, or the VM is a bastion), it fails unless force=true.
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param vmId path string true "VM ID" default(g1-1)
+// @Param release query boolean false "Release the address" default(true)
+// @Param force query boolean false "Detach even if the VM (or VMs using it as a bastion) becomes inaccessible" default(false)
+// @Success 200 {object} model.TbVmPublicIpInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Failure 501 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/vm/{vmId}/publicIp [delete]
+func RestDelMciVmPublicIp(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	vmId := c.Param("vmId")
+	release := c.QueryParam("release") != "false"
+	force := c.QueryParam("force") == "true"
+
+	result, err := infra.DetachPublicIp(nsId, mciId, vmId, release, force)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPostMciVmResize godoc
+// @ID PostMciVmResize
+// @Summary Resize VM (change the spec)
+// @Description Change the spec (instance type) of VM. The spec must be in the same provider, region and architecture.
+// @Description A running VM is stopped and restarted only if confirmStop is true. All checks are done before the VM is stopped.
+// @Description Each step is recorded with its time in the request (GET /request/{reqId}).
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param vmId path string true "VM ID" default(g1-1)
+// @Param vmResizeReq body model.TbVmResizeReq true "Target spec of the VM"
+// @Param x-request-id header string false "Custom request ID"
+// @Success 200 {object} model.TbVmResizeResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Failure 501 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/vm/{vmId}/resize [post]
+func RestPostMciVmResize(c echo.Context) error {
+
+	reqID := c.Request().Header.Get(echo.HeaderXRequestID)
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	vmId := c.Param("vmId")
+
+	u := &model.TbVmResizeReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.ResizeVm(reqID, nsId, mciId, vmId, u)
+	if err != nil && len(result.Steps) == 0 {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPutMciProtection godoc
+// @ID PutMciProtection
+// @Summary Set the protection of MCI
+// @Description Set the protection of MCI. A protected MCI cannot be deleted or terminated (409) until the protection is removed,
+// @Description even with option=force. DELETE /ns/{nsId}/mci skips protected MCIs.
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param protectionReq body model.ProtectionReq true "Protection of the MCI"
+// @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/protection [put]
+func RestPutMciProtection(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	u := &model.ProtectionReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.SetMciProtection(nsId, mciId, u.Protected)
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPutMciVmProtection godoc
+// @ID PutMciVmProtection
+// @Summary Set the protection of VM
+// @Description Set the protection of VM. A protected VM (and its MCI) cannot be deleted or terminated (409) until the protection is removed,
+// @Description even with option=force.
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param vmId path string true "VM ID" default(g1-1)
+// @Param protectionReq body model.ProtectionReq true "Protection of the VM"
+// @Success 200 {object} model.TbVmInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/vm/{vmId}/protection [put]
+func RestPutMciVmProtection(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	vmId := c.Param("vmId")
+
+	u := &model.ProtectionReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.SetVmProtection(nsId, mciId, vmId, u.Protected)
+	return common.EndRequestWithLog(c, err, result)
+}
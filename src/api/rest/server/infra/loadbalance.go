@@ -76,7 +76,7 @@ func RestPostMcNLB(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	content, err := infra.CreateMcSwNlb(nsId, mciId, u, "")
+	content, err := infra.CreateMcSwNlb(common.NewRequestContext(c), nsId, mciId, u, "")
 	return common.EndRequestWithLog(c, err, content)
 }
 
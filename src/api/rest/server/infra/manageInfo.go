@@ -16,6 +16,8 @@ package infra
 
 import (
 	"fmt"
+	"net/http"
+	"strconv"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
 	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
@@ -46,8 +48,13 @@ type JSONResult struct {
 // @Param option query string false "Option" Enums(default, id, status, accessinfo)
 // @Param filterKey query string false "(For option=id) Field key for filtering (ex: connectionName)"
 // @Param filterVal query string false "(For option=id) Field value for filtering (ex: aws-ap-northeast-2)"
-// @Param accessInfoOption query string false "(For option=accessinfo) accessInfoOption (showSshKey)"
+// @Param accessInfoOption query string false "(For option=accessinfo) accessInfoOption (showSshKey: return the private keys in plaintext with revealPrivateKey=true, masked otherwise)"
+// @Param revealPrivateKey query boolean false "(For option=accessinfo and accessInfoOption=showSshKey) Return the private keys in plaintext (the request is audited)" default(false)
+// @Param revealPassword query boolean false "(For option=default) Return the passwords of the VM users in plaintext (the request is audited)" default(false)
+// @Param If-None-Match header string false "(For option=default) ETag of the MCI from the previous response; 304 is returned if the MCI is not changed"
+// @Param refresh query boolean false "(For option=default) Ignore If-None-Match to get the latest status from the CSPs, (For option=status) Fetch the status of all VMs from the CSPs instead of the cache" default(false)
 // @success 200 {object} JSONResult{[DEFAULT]=model.TbMciInfo,[ID]=model.IdList,[STATUS]=model.MciStatusInfo,[AccessInfo]=model.MciAccessInfo} "Different return structures by the given action param"
+// @Success 304 "The MCI is not changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci/{mciId} [get]
@@ -68,7 +75,13 @@ func RestGetMci(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, content)
 	} else if option == "status" {
 
-		result, err := infra.GetMciStatus(nsId, mciId)
+		var result *model.MciStatusInfo
+		var err error
+		if c.QueryParam("refresh") == "true" {
+			result, err = infra.GetMciStatus(nsId, mciId)
+		} else {
+			result, err = infra.GetMciStatusFromCache(nsId, mciId)
+		}
 		if err != nil {
 			return common.EndRequestWithLog(c, err, nil)
 		}
@@ -82,13 +95,30 @@ func RestGetMci(c echo.Context) error {
 
 	} else if option == "accessinfo" {
 
+		// The private keys are returned only with ?revealPrivateKey=true (the request is audited)
+		if c.QueryParam("revealPrivateKey") != "true" {
+			accessInfoOption = ""
+		}
 		result, err := infra.GetMciAccessInfo(nsId, mciId, accessInfoOption)
 		return common.EndRequestWithLog(c, err, result)
 
 	} else {
 
+		// skip the status update from the CSPs if the stored MCI is not changed since the client got it
+		if revision, err := infra.GetMciRevision(nsId, mciId); err == nil && common.IsNotModified(c, revision) {
+			return common.EndRequestNotModified(c, revision)
+		}
+
 		result, err := infra.GetMciInfo(nsId, mciId)
-		return common.EndRequestWithLog(c, err, result)
+		if err == nil {
+			for i := range result.Vm {
+				if err = revealOrMaskVmInfo(c, &result.Vm[i]); err != nil {
+					return common.EndRequestWithLog(c, err, nil)
+				}
+			}
+		}
+		revision, _ := infra.GetMciRevision(nsId, mciId)
+		return common.EndRequestWithRevision(c, revision, err, result)
 
 	}
 }
@@ -98,6 +128,13 @@ type RestGetAllMciResponse struct {
 	Mci []model.TbMciInfo `json:"mci"`
 }
 
+// RestGetAllMciPageResponse is a response structure for RestGetAllMci with paging or field selection
+type RestGetAllMciPageResponse struct {
+	// Mci is the MCIs in the page (with the selected fields only if fields are given)
+	Mci []model.TbMciInfo `json:"mci"`
+	model.ListPage
+}
+
 // RestGetAllMciStatusResponse is a response structure for RestGetAllMciStatus
 type RestGetAllMciStatusResponse struct {
 	Mci []model.MciStatusInfo `json:"mci"`
@@ -112,7 +149,15 @@ type RestGetAllMciStatusResponse struct {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param option query string false "Option" Enums(id, simple, status)
-// @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllMciResponse,[SIMPLE]=RestGetAllMciResponse,[ID]=model.IdList,[STATUS]=RestGetAllMciStatusResponse} "Different return structures by the given option param"
+// @Param limit query int false "Max number of MCIs in a page (for default and simple options)"
+// @Param offset query int false "Index of the first MCI in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,status,vm.id,vm.status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of MCIs and VMs" default(false)
+// @Param If-None-Match header string false "(Except option=id,status) ETag of the list from the previous response; 304 is returned if no MCI is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs, (For option=status) Fetch the status of all VMs from the CSPs instead of the cache" default(false)
+// @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllMciResponse,[SIMPLE]=RestGetAllMciResponse,[ID]=model.IdList,[STATUS]=RestGetAllMciStatusResponse,[PAGE]=RestGetAllMciPageResponse} "Different return structures by the given option param (PAGE if any of limit, offset, nextToken, fields and excludeKeyValue is given)"
+// @Success 304 "No MCI is changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci [get]
@@ -129,65 +174,185 @@ func RestGetAllMci(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, content)
 	} else if option == "status" {
 		// return MCI Status objects (diffent with MCI objects)
-		result, err := infra.ListMciStatus(nsId)
+		result, err := infra.ListMciStatus(nsId, c.QueryParam("refresh") == "true")
 		if err != nil {
 			return common.EndRequestWithLog(c, err, nil)
 		}
 		content := RestGetAllMciStatusResponse{}
 		content.Mci = result
 		return common.EndRequestWithLog(c, err, content)
+	}
+
+	listOption, err := common.ParseListOption(c.QueryParam("limit"), c.QueryParam("offset"), c.QueryParam("nextToken"), c.QueryParam("fields"), c.QueryParam("excludeKeyValue"))
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	// skip the status update from the CSPs if the stored MCIs are not changed since the client got them
+	if revision, err := infra.GetMciListRevision(nsId); err == nil && common.IsNotModified(c, revision) {
+		return common.EndRequestNotModified(c, revision)
+	}
+
+	var content interface{}
+	if listOption.IsSet() {
+		// MCI in page (with the selected fields only)
+		if option != "simple" {
+			option = "status"
+		}
+		result, page, err := infra.ListMciInfoWithOption(nsId, option, listOption)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		content = common.NewListResponse("mci", result, page)
 	} else if option == "simple" {
 		// MCI in simple (without VM information)
 		result, err := infra.ListMciInfo(nsId, option)
 		if err != nil {
 			return common.EndRequestWithLog(c, err, nil)
 		}
-		content := RestGetAllMciResponse{}
-		content.Mci = result
-		return common.EndRequestWithLog(c, err, content)
+		content = RestGetAllMciResponse{Mci: result}
 	} else {
 		// MCI in detail (with status information)
 		result, err := infra.ListMciInfo(nsId, "status")
 		if err != nil {
 			return common.EndRequestWithLog(c, err, nil)
 		}
-		content := RestGetAllMciResponse{}
-		content.Mci = result
-		return common.EndRequestWithLog(c, err, content)
+		content = RestGetAllMciResponse{Mci: result}
 	}
-}
 
-/*
-	function RestPutMci not yet implemented
+	revision, _ := infra.GetMciListRevision(nsId)
+	return common.EndRequestWithRevision(c, revision, nil, content)
+}
 
 // RestPutMci godoc
 // @ID PutMci
 // @Summary Update MCI
-// @Description Update MCI
+// @Description Update the editable fields (description, installMonAgent, label, placementAlgo) of MCI. Omitted fields are not changed.
+// @Description The label replaces the user labels of the MCI (system labels with sys.* keys are kept). name, systemLabel and vm cannot be updated (400).
+// @Description With If-Match (the ETag from GET /ns/{nsId}/mci/{mciId}), the update is rejected (412) if the MCI is changed after the client got it.
 // @Tags [MC-Infra] MCI Provisioning and Management
 // @Accept  json
 // @Produce  json
-// @Param mciInfo body TbMciInfo true "Details for an MCI object"
-// @Success 200 {object} TbMciInfo
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param mciUpdateReq body model.TbMciUpdateReq true "Fields of the MCI to update"
+// @Param If-Match header string false "ETag of the MCI from the previous response; 412 is returned if the MCI is changed"
+// @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.SimpleMsg
 // @Failure 404 {object} model.SimpleMsg
+// @Failure 412 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci/{mciId} [put]
 func RestPutMci(c echo.Context) error {
-	return nil
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	u := &model.TbMciUpdateReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	// reject the update if the MCI is changed since the client got it (optimistic concurrency)
+	if revision, err := infra.GetMciRevision(nsId, mciId); err == nil && common.IsPreconditionFailed(c, revision) {
+		err := common.NewPreconditionFailedError("MCI %s is changed after the given ETag (If-Match); get the MCI again and retry", mciId)
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.UpdateMci(nsId, mciId, u)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	revision, _ := infra.GetMciRevision(nsId, mciId)
+	return common.EndRequestWithRevision(c, revision, nil, result)
+}
+
+// RestPostMoveMci godoc
+// @ID PostMoveMci
+// @Summary Move MCI to another namespace or rename MCI
+// @Description Move MCI (with its VMs, subGroups, NLBs, policies and labels) to another namespace and/or rename it without recreating the VMs.
+// @Description The connection allowlist and the quota of the target namespace are validated first. For a move to another namespace,
+// @Description the resources used by the VMs (vNet, securityGroup, sshKey, dataDisk) should exist in the target namespace with the same IDs
+// @Description (e.g., by POST /ns/{nsId}/registerCspResource/...). All records are moved in a single transaction (fully moved or untouched).
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param mciMoveReq body model.MciMoveReq true "Target namespace (and new ID) of the MCI"
+// @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 403 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 409 {object} model.SimpleMsg
+// @Failure 429 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/move [post]
+func RestPostMoveMci(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	u := &model.MciMoveReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.MoveMci(nsId, mciId, u)
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPostMciRetryFailed godoc
+// @ID PostMciRetryFailed
+// @Summary Retry the creation of the failed VMs of MCI
+// @Description Re-provision the VMs of the MCI in Failed status with their stored requests, instead of deleting the partially failed MCI.
+// @Description The remnant of each VM in the CSP is deleted first, and a VM is retried up to maxRetries times (TB_VM_CREATE_MAX_RETRIES, default 3).
+// @Description It is safe to call repeatedly (VMs which are not failed are not touched), and it is rejected (409) while VMs are being created.
+// @Description The result reports the retried VMs, the recovered ones and the ones which remain failed.
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param mciRetryFailedReq body model.MciRetryFailedReq false "Max retries per VM"
+// @Success 200 {object} model.MciRetryFailedResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 409 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/actions/retryFailed [post]
+func RestPostMciRetryFailed(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	u := &model.MciRetryFailedReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.RetryFailedMciVms(nsId, mciId, u)
+	return common.EndRequestWithLog(c, err, result)
 }
-*/
 
 // RestDelMci godoc
 // @ID DelMci
 // @Summary Delete MCI
-// @Description Delete MCI
+// @Description Delete MCI. With soft delete (TB_SOFT_DELETE=true or permanent=false), the metadata of the MCI is kept in the trash
+// @Description for the retention window (TB_TRASH_RETENTION_HOURS) and can be restored by POST /ns/{nsId}/trash/{itemId}/restore.
+// @Description With option=terminate, VMs are terminated in parallel (TB_MCI_DELETE_PARALLELISM) and retried (TB_VM_TERMINATE_MAX_ATTEMPTS).
+// @Description VMs failed in all attempts are marked FailedToTerminate and the deletion is rejected; option=force removes the records
+// @Description and lists the VMs remaining in CSP as "[Manual cleanup]". The progress of each VM is reported by the async job.
 // @Tags [MC-Infra] MCI Provisioning and Management
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param option query string false "Option for delete MCI (support force delete)" Enums(terminate,force)
+// @Param async query bool false "Run as an async job and return the job immediately (track it by GET /jobs/{jobId})" default(false)
+// @Param permanent query bool false "Delete permanently (true) or move to the trash (false); the default is set by TB_SOFT_DELETE"
 // @Success 200 {object} model.IdList
+// @Success 202 {object} model.JobInfo
 // @Failure 404 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci/{mciId} [delete]
 func RestDelMci(c echo.Context) error {
@@ -195,7 +360,20 @@ func RestDelMci(c echo.Context) error {
 	nsId := c.Param("nsId")
 	mciId := c.Param("mciId")
 	option := c.QueryParam("option")
+	soft := common.UseSoftDelete(c.QueryParam("permanent"))
 
+	if c.QueryParam("async") == "true" {
+		job, err := infra.DelMciAsync(nsId, mciId, option, soft)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		return c.JSON(http.StatusAccepted, job)
+	}
+
+	if soft {
+		content, err := infra.TrashMci(c.Request().Context(), nsId, mciId, option)
+		return common.EndRequestWithLog(c, err, &content)
+	}
 	content, err := infra.DelMci(nsId, mciId, option)
 	return common.EndRequestWithLog(c, err, content)
 }
@@ -228,7 +406,7 @@ func RestDelAllMci(c echo.Context) error {
 // RestGetMciVm godoc
 // @ID GetMciVm
 // @Summary Get VM in specified MCI
-// @Description Get VM in specified MCI
+// @Description Get VM in specified MCI (the password of the VM user is masked unless revealPassword=true)
 // @Tags [MC-Infra] MCI Provisioning and Management
 // @Accept  json
 // @Produce  json
@@ -236,6 +414,7 @@ func RestDelAllMci(c echo.Context) error {
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param vmId path string true "VM ID" default(g1-1)
 // @Param option query string false "Option for MCI" Enums(default, status, idsInDetail)
+// @Param revealPassword query boolean false "(For option=default) Return the password of the VM user in plaintext (the request is audited)" default(false)
 // @success 200 {object} JSONResult{[DEFAULT]=model.TbVmInfo,[STATUS]=model.TbVmStatusInfo,[IDNAME]=model.TbIdNameInDetailInfo} "Different return structures by the given option param"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
@@ -259,10 +438,65 @@ func RestGetMciVm(c echo.Context) error {
 
 	default:
 		result, err := infra.ListVmInfo(nsId, mciId, vmId)
+		if err == nil {
+			err = revealOrMaskVmInfo(c, result)
+		}
 		return common.EndRequestWithLog(c, err, result)
 	}
 }
 
+// revealOrMaskVmInfo returns the password of the VM user in plaintext only with ?revealPassword=true
+// (the request is audited and denied for the readonly role), masked otherwise
+func revealOrMaskVmInfo(c echo.Context, vm *model.TbVmInfo) error {
+	if c.QueryParam("revealPassword") == "true" {
+		return infra.DecryptVmUserPassword(vm)
+	}
+	infra.MaskVmInfo(vm)
+	return nil
+}
+
+// RestGetMciVmConsole godoc
+// @ID GetMciVmConsole
+// @Summary Get the console output (serial port / boot log) of VM
+// @Description Get the console output of VM from the CSP (AWS GetConsoleOutput, GCP serial port, Azure boot diagnostics) to debug a VM which is not reachable by SSH.
+// @Description The last 64 KB of the output is returned, and it is cached for 30 seconds to avoid throttling of the CSP API.
+// @Description Providers without the vmConsole capability return 501 (see GET /tumblebug/provider/{providerName}/capabilities).
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param vmId path string true "VM ID" default(g1-1)
+// @Param tail query int false "Number of the last lines to return (all lines within 64 KB if 0)" default(0)
+// @Success 200 {object} model.TbVmConsoleInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Failure 501 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/vm/{vmId}/console [get]
+func RestGetMciVmConsole(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	vmId := c.Param("vmId")
+
+	tail := 0
+	if tailParam := c.QueryParam("tail"); tailParam != "" {
+		var err error
+		tail, err = strconv.Atoi(tailParam)
+		if err != nil {
+			err = fmt.Errorf("invalid tail param (%s): %w", tailParam, err)
+			return common.EndRequestWithLog(c, err, nil)
+		}
+	}
+
+	result, err := infra.GetVmConsole(nsId, mciId, vmId, tail)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, result)
+}
+
 /* RestPutMciVm function not yet implemented
 // RestPutSshKey godoc
 // @ID PutSshKey
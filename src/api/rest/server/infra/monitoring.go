@@ -15,6 +15,9 @@ limitations under the License.
 package infra
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
 	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
@@ -24,13 +27,15 @@ import (
 // RestPostInstallMonitorAgentToMci godoc
 // @ID PostInstallMonitorAgentToMci
 // @Summary Install monitoring agent (CB-Dragonfly agent) to MCI
-// @Description Install monitoring agent (CB-Dragonfly agent) to MCI
+// @Description Install monitoring agent (CB-Dragonfly agent) to MCI. VMs already installed or in progress are skipped.
+// @Description The agent status of each VM is kept in the VM object (see GET /ns/{nsId}/monitoring/status/mci/{mciId}).
 // @Tags [MC-Infra] MCI Resource Monitor (for developer)
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param mciInfo body model.MciCmdReq true "Details for an MCI object"
+// @Param option query string false "Option: retryFailed installs the agent only to the VMs whose installation failed" Enums(retryFailed)
 // @Success 200 {object} model.AgentInstallContentWrapper
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
@@ -45,7 +50,58 @@ func RestPostInstallMonitorAgentToMci(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 	// mciTmpSystemLabel := model.DefaultSystemLabel
-	content, err := infra.InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, req)
+	option := c.QueryParam("option")
+	content, err := infra.InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, req, option)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDelMonitorAgentFromMci godoc
+// @ID DelMonitorAgentFromMci
+// @Summary Uninstall monitoring agent (CB-Dragonfly agent) from MCI
+// @Description Uninstall monitoring agent (CB-Dragonfly agent) from the VMs of MCI whose agent status is installed or failed
+// @Tags [MC-Infra] MCI Resource Monitor (for developer)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param userName query string false "SSH user name of the VMs (found automatically if empty)"
+// @Success 200 {object} model.AgentInstallContentWrapper
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/monitoring/mci/{mciId} [delete]
+func RestDelMonitorAgentFromMci(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	userName := c.QueryParam("userName")
+
+	content, err := infra.UninstallMonitorAgentFromMci(nsId, mciId, model.StrMCI, userName)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetMonitorAgentStatus godoc
+// @ID GetMonitorAgentStatus
+// @Summary Get monitoring agent (CB-Dragonfly agent) status of MCI
+// @Description Get monitoring agent status (notInstalled, installing, installed, failed, uninstalling) of each VM in MCI.
+// @Description The status is read from the VM objects without calling CB-Dragonfly.
+// @Tags [MC-Infra] MCI Resource Monitor (for developer)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Success 200 {object} model.MonAgentStatusResponse
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/monitoring/status/mci/{mciId} [get]
+func RestGetMonitorAgentStatus(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	content, err := infra.GetMonitorAgentStatus(nsId, mciId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
 	return common.EndRequestWithLog(c, err, content)
 }
 
@@ -76,6 +132,9 @@ func RestPutMonitorAgentStatusInstalled(c echo.Context) error {
 	}
 
 	result, err := infra.ListVmInfo(nsId, mciId, vmId)
+	if err == nil {
+		infra.MaskVmInfo(result)
+	}
 	return common.EndRequestWithLog(c, err, result)
 }
 
@@ -107,3 +166,43 @@ func RestGetMonitorData(c echo.Context) error {
 	content, err := infra.GetMonitoringData(nsId, mciId, metric)
 	return common.EndRequestWithLog(c, err, content)
 }
+
+// RestGetMonitorDataAggregate godoc
+// @ID GetMonitorDataAggregate
+// @Summary Get aggregated monitoring data of MCI for specified monitoring metric
+// @Description Get the aggregate (avg, max, p95) of the metric for the window per VM, per subGroup and MCI-wide.
+// @Description VMs which failed to report the metric are flagged (reported: false).
+// @Description With threshold, exceeded reports whether the MCI-wide aggregate is over the threshold with the offending VMs.
+// @Tags [MC-Infra] MCI Resource Monitor (for developer)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param metric path string true "Metric type: cpu, mem, disk, net" Enums(cpu, mem, disk, net)
+// @Param fn query string false "Aggregate function" Enums(avg, max, p95) default(avg)
+// @Param window query string false "Time window of the series (e.g., 5m, 1h)" default(5m)
+// @Param threshold query number false "Threshold to check (e.g., 80)"
+// @Success 200 {object} model.MonAggregateResponse
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/monitoring/mci/{mciId}/metric/{metric}/aggregate [get]
+func RestGetMonitorDataAggregate(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	metric := c.Param("metric")
+	fn := c.QueryParam("fn")
+	window := c.QueryParam("window")
+
+	var threshold *float64
+	if thresholdStr := c.QueryParam("threshold"); thresholdStr != "" {
+		value, err := strconv.ParseFloat(thresholdStr, 64)
+		if err != nil {
+			return common.EndRequestWithLog(c, fmt.Errorf("invalid threshold: %s", thresholdStr), nil)
+		}
+		threshold = &value
+	}
+
+	content, err := infra.GetMonitoringAggregate(nsId, mciId, metric, fn, window, threshold)
+	return common.EndRequestWithLog(c, err, content)
+}
@@ -219,8 +219,10 @@ func ExtractSitesInfoFromMciInfo(nsId, mciId string) (*networkSiteModel.SitesInf
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param vpnId path string true "VPN ID" default(vpn01)
 // @Param vpnReq body model.RestPostVpnRequest true "Sites info for VPN configuration"
+// @Param allowOverlap query boolean false "Allow the CIDR blocks of the vNets of the sites to overlap" default(false)
 // @Success 200 {object} model.SimpleMsg "OK"
 // @Failure 400 {object} model.SimpleMsg "Bad Request"
+// @Failure 409 {object} model.SimpleMsg "Conflict"
 // @Failure 500 {object} model.SimpleMsg "Internal Server Error"
 // @Failure 503 {object} model.SimpleMsg "Service Unavailable"
 // @Router /stream-response/ns/{nsId}/mci/{mciId}/vpn/{vpnId} [post]
@@ -278,6 +280,16 @@ func RestPostSiteToSiteVpn(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, res)
 	}
 
+	// Check the overlap of the CIDR blocks of the sites
+	err := infra.CheckVpnSiteCidrOverlap(nsId, []model.VpnSite{model.VpnSite(vpnReq.Site1), model.VpnSite(vpnReq.Site2)}, c.QueryParam("allowOverlap") == "true")
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		res := model.SimpleMsg{
+			Message: err.Error(),
+		}
+		return c.JSON(http.StatusConflict, res)
+	}
+
 	// Prepare for streaming response
 	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	c.Response().WriteHeader(http.StatusOK)
@@ -289,8 +301,6 @@ func RestPostSiteToSiteVpn(c echo.Context) error {
 	apiPass := os.Getenv("TB_API_PASSWORD")
 	client.SetBasicAuth(apiUser, apiPass)
 
-	trId := fmt.Sprintf("%s-%s-%s", nsId, mciId, vpnId)
-
 	// set endpoint
 	epTerrarium := model.TerrariumRestUrl
 
@@ -300,7 +310,7 @@ func RestPostSiteToSiteVpn(c echo.Context) error {
 	requestBody := common.NoBody
 	resReadyz := new(networkSiteModel.Response)
 
-	err := common.ExecuteHttpRequest(
+	err = common.ExecuteHttpRequest(
 		client,
 		method,
 		url,
@@ -329,415 +339,25 @@ func RestPostSiteToSiteVpn(c echo.Context) error {
 	}
 	c.Response().Flush()
 
-	cspSet := whichCspSet(vpnReq.Site1.CSP, vpnReq.Site2.CSP)
-
-	// Check the CSPs of the sites
-	switch cspSet {
-	case "aws,gcp", "gcp,aws":
-
-		// issue a terrarium
-		method = "POST"
-		url = fmt.Sprintf("%s/tr", epTerrarium)
-		reqTr := new(terrariumModel.TerrariumInfo)
-		reqTr.Id = trId
-		reqTr.Description = "VPN between GCP and AWS"
-
-		resTrInfo := new(terrariumModel.TerrariumInfo)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(*reqTr),
-			reqTr,
-			resTrInfo,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resTrInfo.Id: %s", resTrInfo.Id)
-		log.Trace().Msgf("resTrInfo: %+v", resTrInfo)
-
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: "successully created a terrarium (trId: " + resTrInfo.Id + ")",
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// init env
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-aws/env", epTerrarium, trId)
-		requestBody = common.NoBody
-		resTerrariumEnv := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(requestBody),
-			&requestBody,
-			resTerrariumEnv,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resInit: %+v", resTerrariumEnv.Message)
-		log.Trace().Msgf("resInit: %+v", resTerrariumEnv.Detail)
-
-		// flush a response
-		res = model.SimpleMsg{
-			Message: resTerrariumEnv.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// generate infracode
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-aws/infracode", epTerrarium, trId)
-		reqInfracode := new(terrariumModel.CreateInfracodeOfGcpAwsVpnRequest)
-
-		if vpnReq.Site1.CSP == "aws" {
-			reqInfracode.TfVars.AwsRegion = vpnReq.Site1.Region
-			reqInfracode.TfVars.AwsVpcId = vpnReq.Site1.VNet
-			reqInfracode.TfVars.AwsSubnetId = vpnReq.Site1.Subnet
-			reqInfracode.TfVars.GcpRegion = vpnReq.Site2.Region
-			reqInfracode.TfVars.GcpVpcNetworkName = vpnReq.Site2.VNet
-		} else {
-			reqInfracode.TfVars.AwsRegion = vpnReq.Site2.Region
-			reqInfracode.TfVars.AwsVpcId = vpnReq.Site2.VNet
-			reqInfracode.TfVars.AwsSubnetId = vpnReq.Site2.Subnet
-			reqInfracode.TfVars.GcpRegion = vpnReq.Site1.Region
-			reqInfracode.TfVars.GcpVpcNetworkName = vpnReq.Site1.VNet
-		}
-
-		resInfracode := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(*reqInfracode),
-			reqInfracode,
-			resInfracode,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resInfracode: %+v", resInfracode.Message)
-		log.Trace().Msgf("resInfracode: %+v", resInfracode.Detail)
-
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: resInfracode.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// check the infracode by plan
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-aws/plan", epTerrarium, trId)
-		requestBody = common.NoBody
-		resPlan := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(requestBody),
-			&requestBody,
-			resPlan,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resPlan: %+v", resPlan.Message)
-		log.Trace().Msgf("resPlan: %+v", resPlan.Detail)
-
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: resPlan.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// apply
-		// wait until the task is completed
-		// or response immediately with requestId as it is a time-consuming task
-		// and provide seperate api to check the status
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-aws", epTerrarium, trId)
-		requestBody = common.NoBody
-		resApply := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(requestBody),
-			&requestBody,
-			resApply,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resApply: %+v", resApply.Message)
-		log.Trace().Msgf("resApply: %+v", resApply.Detail)
-
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: resApply.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-	case "gcp,azure", "azure,gcp":
-		// issue a terrarium
-		method = "POST"
-		url = fmt.Sprintf("%s/tr", epTerrarium)
-		reqTr := new(terrariumModel.TerrariumInfo)
-		reqTr.Id = trId
-		reqTr.Description = "VPN between GCP and Azure"
-
-		resTrInfo := new(terrariumModel.TerrariumInfo)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(*reqTr),
-			reqTr,
-			resTrInfo,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resTrInfo.Id: %s", resTrInfo.Id)
-		log.Trace().Msgf("resTrInfo: %+v", resTrInfo)
-
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: "successully created a terrarium (trId: " + resTrInfo.Id + ")",
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// init env
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-azure/env", epTerrarium, trId)
-		requestBody = common.NoBody
-		resTerrariumEnv := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(requestBody),
-			&requestBody,
-			resTerrariumEnv,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resInit: %+v", resTerrariumEnv.Message)
-		log.Trace().Msgf("resInit: %+v", resTerrariumEnv.Detail)
-
-		// flush a response
-		res = model.SimpleMsg{
-			Message: resTerrariumEnv.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// generate infracode
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-azure/infracode", epTerrarium, trId)
-		reqInfracode := new(terrariumModel.CreateInfracodeOfGcpAzureVpnRequest)
-
-		if vpnReq.Site1.CSP == "azure" {
-			// Site1 is Azure
-			reqInfracode.TfVars.AzureRegion = vpnReq.Site1.Region
-			reqInfracode.TfVars.AzureVirtualNetworkName = vpnReq.Site1.VNet
-			reqInfracode.TfVars.AzureResourceGroupName = vpnReq.Site1.ResourceGroup
-			reqInfracode.TfVars.AzureGatewaySubnetCidrBlock = vpnReq.Site1.GatewaySubnetCidr
-			// Site2 is GCP
-			reqInfracode.TfVars.GcpRegion = vpnReq.Site2.Region
-			reqInfracode.TfVars.GcpVpcNetworkName = vpnReq.Site2.VNet
-		} else {
-			// Site1 is GCP
-			reqInfracode.TfVars.GcpRegion = vpnReq.Site1.Region
-			reqInfracode.TfVars.GcpVpcNetworkName = vpnReq.Site1.VNet
-			// site2 is Azure
-			reqInfracode.TfVars.AzureRegion = vpnReq.Site2.Region
-			reqInfracode.TfVars.AzureVirtualNetworkName = vpnReq.Site2.VNet
-			reqInfracode.TfVars.AzureResourceGroupName = vpnReq.Site2.ResourceGroup
-			reqInfracode.TfVars.AzureGatewaySubnetCidrBlock = vpnReq.Site2.GatewaySubnetCidr
-		}
-
-		resInfracode := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(*reqInfracode),
-			reqInfracode,
-			resInfracode,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resInfracode: %+v", resInfracode.Message)
-		log.Trace().Msgf("resInfracode: %+v", resInfracode.Detail)
-
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: resInfracode.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// check the infracode by plan
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-azure/plan", epTerrarium, trId)
-		requestBody = common.NoBody
-		resPlan := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(requestBody),
-			&requestBody,
-			resPlan,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
-
-		log.Debug().Msgf("resPlan: %+v", resPlan.Message)
-		log.Trace().Msgf("resPlan: %+v", resPlan.Detail)
-
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: resPlan.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
-		}
-		c.Response().Flush()
-
-		// apply
-		// wait until the task is completed
-		// or response immediately with requestId as it is a time-consuming task
-		// and provide seperate api to check the status
-		method = "POST"
-		url = fmt.Sprintf("%s/tr/%s/vpn/gcp-azure", epTerrarium, trId)
-		requestBody = common.NoBody
-		resApply := new(networkSiteModel.Response)
-
-		err = common.ExecuteHttpRequest(
-			client,
-			method,
-			url,
-			nil,
-			common.SetUseBody(requestBody),
-			&requestBody,
-			resApply,
-			common.VeryShortDuration,
-		)
-
-		if err != nil {
-			log.Err(err).Msg("")
-			res := model.SimpleMsg{Message: err.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
+	_, err = infra.CreateVpn(nsId, mciId, vpnId, model.VpnSite(vpnReq.Site1), model.VpnSite(vpnReq.Site2), streamVpnProgress(c, enc))
+	if err != nil {
+		log.Err(err).Msg("")
+		res := model.SimpleMsg{Message: err.Error()}
+		return c.JSON(http.StatusInternalServerError, res)
+	}
 
-		log.Debug().Msgf("resApply: %+v", resApply.Message)
-		log.Trace().Msgf("resApply: %+v", resApply.Detail)
+	return nil
+}
 
-		// Flush a response
-		res = model.SimpleMsg{
-			Message: resApply.Message,
-		}
-		if err := enc.Encode(res); err != nil {
-			return err
+// streamVpnProgress returns a callback which flushes the progress messages of a VPN operation
+func streamVpnProgress(c echo.Context, enc *json.Encoder) func(string) {
+	return func(msg string) {
+		if err := enc.Encode(model.SimpleMsg{Message: msg}); err != nil {
+			log.Warn().Err(err).Msg("failed to stream the VPN progress")
+			return
 		}
 		c.Response().Flush()
-
-	default:
-		log.Warn().Msgf("not valid CSP set: %s", cspSet)
 	}
-
-	return nil
 }
 
 var validCspSet = map[string]bool{
@@ -757,10 +377,6 @@ func isValidCspSet(csp1, csp2 string) bool {
 	return validCspSet[csp1+","+csp2]
 }
 
-func whichCspSet(csp1, csp2 string) string {
-	return csp1 + "," + csp2
-}
-
 // RestDeleteSiteToSiteVpn godoc
 // @ID DeleteSiteToSiteVpn
 // @Summary Delete a site-to-site VPN (Currently, GCP-AWS is supported)
@@ -819,8 +435,6 @@ func RestDeleteSiteToSiteVpn(c echo.Context) error {
 	apiPass := os.Getenv("TB_API_PASSWORD")
 	client.SetBasicAuth(apiUser, apiPass)
 
-	trId := fmt.Sprintf("%s-%s-%s", nsId, mciId, vpnId)
-
 	// set endpoint
 	epTerrarium := model.TerrariumRestUrl
 
@@ -860,164 +474,66 @@ func RestDeleteSiteToSiteVpn(c echo.Context) error {
 	}
 	c.Response().Flush()
 
-	// Get the terrarium info
-	method = "GET"
-	url = fmt.Sprintf("%s/tr/%s", epTerrarium, trId)
-	requestBody = common.NoBody
-	resTrInfo := new(terrariumModel.TerrariumInfo)
-
-	err = common.ExecuteHttpRequest(
-		client,
-		method,
-		url,
-		nil,
-		common.SetUseBody(requestBody),
-		&requestBody,
-		resTrInfo,
-		common.VeryShortDuration,
-	)
-
+	err = infra.DeleteVpn(nsId, mciId, vpnId, streamVpnProgress(c, enc))
 	if err != nil {
 		log.Err(err).Msg("")
 		res := model.SimpleMsg{Message: err.Error()}
 		return c.JSON(http.StatusInternalServerError, res)
 	}
 
-	log.Debug().Msgf("resTrInfo.Id: %s", resTrInfo.Id)
-	log.Trace().Msgf("resTrInfo: %+v", resTrInfo)
-	enrichments := resTrInfo.Enrichments
-
-	// Flush a response
-	msg := fmt.Sprintf("successully got the terrarium (trId: %s) for the enrichment (%s)", resTrInfo.Id, enrichments)
-	res = model.SimpleMsg{
-		Message: msg,
-	}
-	if err := enc.Encode(res); err != nil {
-		return err
-	}
-	c.Response().Flush()
-
-	// delete enrichments
-	method = "DELETE"
-	url = fmt.Sprintf("%s/tr/%s/%s", epTerrarium, trId, enrichments)
-	requestBody = common.NoBody
-	resDeleteEnrichments := new(networkSiteModel.Response)
-
-	err = common.ExecuteHttpRequest(
-		client,
-		method,
-		url,
-		nil,
-		common.SetUseBody(requestBody),
-		&requestBody,
-		resDeleteEnrichments,
-		common.VeryShortDuration,
-	)
-
-	if err != nil {
-		log.Err(err).Msg("")
-		res := model.SimpleMsg{Message: err.Error()}
-		return c.JSON(http.StatusInternalServerError, res)
-	}
-
-	log.Debug().Msgf("resDeleteEnrichments: %+v", resDeleteEnrichments.Message)
-	log.Trace().Msgf("resDeleteEnrichments: %+v", resDeleteEnrichments.Detail)
-
-	// Flush a response
-	res = model.SimpleMsg{
-		Message: resDeleteEnrichments.Message,
-	}
-	if err := enc.Encode(res); err != nil {
-		return err
-	}
-	c.Response().Flush()
-
-	// delete env
-	method = "DELETE"
-	url = fmt.Sprintf("%s/tr/%s/%s/env", epTerrarium, trId, enrichments)
-	requestBody = common.NoBody
-	resDeleteEnv := new(networkSiteModel.Response)
-
-	err = common.ExecuteHttpRequest(
-		client,
-		method,
-		url,
-		nil,
-		common.SetUseBody(requestBody),
-		&requestBody,
-		resDeleteEnv,
-		common.VeryShortDuration,
-	)
-
-	if err != nil {
-		log.Err(err).Msg("")
-		res := model.SimpleMsg{Message: err.Error()}
-		return c.JSON(http.StatusInternalServerError, res)
-	}
-
-	log.Debug().Msgf("resDeleteEnv: %+v", resDeleteEnv.Message)
-	log.Trace().Msgf("resDeleteEnv: %+v", resDeleteEnv.Detail)
-
-	// Flush a response
-	res = model.SimpleMsg{
-		Message: resDeleteEnv.Message,
-	}
-	if err := enc.Encode(res); err != nil {
-		return err
-	}
-	c.Response().Flush()
-
-	// delete terrarium
-	method = "DELETE"
-	url = fmt.Sprintf("%s/tr/%s", epTerrarium, trId)
-	requestBody = common.NoBody
-	resDeleteTr := new(networkSiteModel.Response)
+	return nil
+}
 
-	err = common.ExecuteHttpRequest(
-		client,
-		method,
-		url,
-		nil,
-		common.SetUseBody(requestBody),
-		&requestBody,
-		resDeleteTr,
-		common.VeryShortDuration,
-	)
+// RestGetSiteToSiteVpnHealth godoc
+// @ID GetSiteToSiteVpnHealth
+// @Summary Get the tunnel health of a site-to-site VPN
+// @Description Get the status (Up, Down, Degraded) of each tunnel of a site-to-site VPN from the tofu state in mc-terrarium.
+// @Description The result is cached for 30 seconds (cached: true) unless refresh is true.
+// @Description The VPN is Up if all tunnels are up, Down if no tunnel is up, and Degraded otherwise.
+// @Description The history keeps the recent status changes, and an event (vpn.degraded) is emitted when the VPN becomes Degraded or Down.
+// @Tags [Infra Resource] Site-to-site VPN Management (under development)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param vpnId path string true "VPN ID" default(vpn01)
+// @Param refresh query boolean false "Refresh the status from mc-terrarium (bypass the cache)" default(false)
+// @Success 200 {object} model.VpnHealthInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/vpn/{vpnId}/health [get]
+func RestGetSiteToSiteVpnHealth(c echo.Context) error {
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	vpnId := c.Param("vpnId")
+	refresh := strings.EqualFold(c.QueryParam("refresh"), "true")
 
+	result, err := infra.GetVpnHealth(nsId, mciId, vpnId, refresh)
 	if err != nil {
-		log.Err(err).Msg("")
-		res := model.SimpleMsg{Message: err.Error()}
-		return c.JSON(http.StatusInternalServerError, res)
-	}
-
-	log.Debug().Msgf("resDeleteTr: %+v", resDeleteTr.Message)
-	log.Trace().Msgf("resDeleteTr: %+v", resDeleteTr.Detail)
-
-	// Flush a response
-	res = model.SimpleMsg{
-		Message: resDeleteTr.Message,
+		return common.EndRequestWithLog(c, err, nil)
 	}
-	if err := enc.Encode(res); err != nil {
-		return err
-	}
-	c.Response().Flush()
-
-	return nil
+	return common.EndRequestWithLog(c, err, result)
 }
 
 // RestPutSiteToSiteVpn godoc
 // @ID PutSiteToSiteVpn
-// @Summary (To be provided) Update a site-to-site VPN
-// @Description (To be provided) Update a site-to-site VPN
+// @Summary Update the sites of a site-to-site VPN (add or remove sites without recreation)
+// @Description Update the sites of a site-to-site VPN with the desired site list.
+// @Description Only the connections of the removed sites are deleted and only the connections for the added sites
+// @Description (with each site of a supported CSP pair: GCP-AWS, GCP-Azure) are created. The other connections are untouched.
+// @Description If a site addition fails, the connections created for the site are rolled back.
+// @Description The per-site operation states are available by the request status API with the requestId in the stream.
 // @Tags [Infra Resource] Site-to-site VPN Management (under development)
 // @Accept  json
 // @Produce  json-stream
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param vpnId path string true "VPN ID" default(vpn01)
-// @Param vpnReq body model.RestPostVpnGcpToAwsRequest true "Resources info for VPN tunnel configuration between GCP and AWS"
+// @Param vpnReq body model.RestPutVpnRequest true "Desired sites of the VPN"
+// @Param allowOverlap query boolean false "Allow the CIDR blocks of the vNets of the sites to overlap" default(false)
 // @Success 200 {object} model.SimpleMsg "OK"
 // @Failure 400 {object} model.SimpleMsg "Bad Request"
+// @Failure 409 {object} model.SimpleMsg "Conflict"
 // @Failure 500 {object} model.SimpleMsg "Internal Server Error"
 // @Failure 503 {object} model.SimpleMsg "Service Unavailable"
 // @Router /stream-response/ns/{nsId}/mci/{mciId}/vpn/{vpnId} [put]
@@ -1053,67 +569,56 @@ func RestPutSiteToSiteVpn(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, res)
 	}
 
+	vpnReq := new(networkSiteModel.RestPutVpnRequest)
+	if err := c.Bind(vpnReq); err != nil {
+		err2 := fmt.Errorf("invalid request format, %v", err)
+		log.Warn().Err(err).Msg("invalid request format")
+		res := model.SimpleMsg{
+			Message: err2.Error(),
+		}
+		return c.JSON(http.StatusBadRequest, res)
+	}
+	sites := []model.VpnSite{}
+	for _, site := range vpnReq.Sites {
+		sites = append(sites, model.VpnSite(site))
+	}
+
+	// check the VPN before streaming
+	if _, err := infra.GetVpnInfo(nsId, mciId, vpnId); err != nil {
+		log.Warn().Err(err).Msg("")
+		res := model.SimpleMsg{
+			Message: err.Error(),
+		}
+		return c.JSON(http.StatusBadRequest, res)
+	}
+
+	// Check the overlap of the CIDR blocks of the sites
+	if err := infra.CheckVpnSiteCidrOverlap(nsId, sites, c.QueryParam("allowOverlap") == "true"); err != nil {
+		log.Warn().Err(err).Msg("")
+		res := model.SimpleMsg{
+			Message: err.Error(),
+		}
+		return c.JSON(http.StatusConflict, res)
+	}
+
 	// Prepare for streaming response
 	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	c.Response().WriteHeader(http.StatusOK)
 	enc := json.NewEncoder(c.Response())
+	progress := streamVpnProgress(c, enc)
 
-	// Flush a response
-	res := model.SimpleMsg{
-		Message: "note - API to be provided",
+	status, err := infra.UpdateVpnSites(nsId, mciId, vpnId, sites, progress)
+	if err != nil {
+		log.Err(err).Msg("")
+		progress(err.Error())
+		return nil
 	}
-	if err := enc.Encode(res); err != nil {
+	if err := enc.Encode(status); err != nil {
 		return err
 	}
 	c.Response().Flush()
 
 	return nil
-
-	// Initialize resty client with basic auth
-	// client := resty.New()
-	// apiUser := os.Getenv("TB_API_USERNAME")
-	// apiPass := os.Getenv("TB_API_PASSWORD")
-	// client.SetBasicAuth(apiUser, apiPass)
-
-	// epTerrarium := "http://localhost:8888/terrarium"
-	// trId := fmt.Sprintf("%s-%s-%s", nsId, mciId, vpnId)
-
-	// // check readyz
-	// method := "GET"
-	// url := fmt.Sprintf("%s/readyz", epTerrarium)
-	// requestBody := common.NoBody
-	// resReadyz := new(networkSiteModel.Response)
-
-	// err := common.ExecuteHttpRequest(
-	// 	client,
-	// 	method,
-	// 	url,
-	// 	nil,
-	// 	common.SetUseBody(requestBody),
-	// 	&requestBody,
-	// 	resReadyz,
-	// 	common.VeryShortDuration,
-	// )
-
-	// if err != nil {
-	// 	log.Err(err).Msg("")
-	// 	res := model.SimpleMsg{
-	// 		Message: err.Error(),
-	// 	}
-	// 	return c.JSON(http.StatusServiceUnavailable, res)
-	// }
-	// log.Debug().Msgf("resReadyz: %+v", resReadyz)
-
-	// // Flush a response
-	// res := model.SimpleMsg{
-	// 	Message: resReadyz.Message,
-	// }
-	// if err := enc.Encode(res); err != nil {
-	// 	return err
-	// }
-	// c.Response().Flush()
-
-	// return nil
 }
 
 // RestGetSiteToSiteVpn godoc
@@ -1311,6 +816,7 @@ func RestGetSiteToSiteVpn(c echo.Context) error {
 // @ID GetRequestStatusOfSiteToSiteVpn
 // @Summary Check the status of a specific request by its ID
 // @Description Check the status of a specific request by its ID
+// @Description (the per-site operation states for the requestId of a VPN update)
 // @Tags [Infra Resource] Site-to-site VPN Management (under development)
 // @Accept  json
 // @Produce  json
@@ -1369,6 +875,11 @@ func RestGetRequestStatusOfSiteToSiteVpn(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, res)
 	}
 
+	// the per-site operation states of a VPN update request
+	if status, found := infra.GetVpnUpdateStatus(nsId, mciId, vpnId, reqId); found {
+		return c.JSON(http.StatusOK, status)
+	}
+
 	// Initialize resty client with basic auth
 	client := resty.New()
 	apiUser := os.Getenv("TB_API_USERNAME")
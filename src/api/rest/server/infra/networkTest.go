@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mci is to handle REST API for mci
+package infra
+
+import (
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+)
+
+// RestPostNetworkTest godoc
+// @ID PostNetworkTest
+// @Summary Start MCI network reachability test between VMs
+// @Description Start an async run which probes the reachability from each VM of MCI to the private and public IPs of every other VM
+// @Description on the given ports (tcp via nc, udp via nc -u, http via curl, icmp via ping) through the remote command channel.
+// @Description The run returns immediately; poll GET /ns/{nsId}/mci/{mciId}/networkTest/{runId} for the reachability matrix
+// @Description with the latency and the failure reason of each cell. Use sourceSubGroupId and targetSubGroupId to test between subGroups.
+// @Tags [MC-Infra] MCI Performance Benchmarking (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param networkTestReq body model.NetworkTestReq true "Ports and scope of the network test"
+// @Success 200 {object} model.NetworkTestRun
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/networkTest [post]
+func RestPostNetworkTest(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	req := model.NetworkTestReq{}
+	if err := c.Bind(&req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := infra.StartNetworkTest(nsId, mciId, req)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetAllNetworkTest godoc
+// @ID GetAllNetworkTest
+// @Summary List MCI network test runs
+// @Description List the network test runs of MCI (newest first, without the matrix) to compare the results over time
+// @Tags [MC-Infra] MCI Performance Benchmarking (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Success 200 {object} model.NetworkTestRunList
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/networkTest [get]
+func RestGetAllNetworkTest(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	content, err := infra.ListNetworkTestRuns(nsId, mciId)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetNetworkTest godoc
+// @ID GetNetworkTest
+// @Summary Get the result of MCI network test
+// @Description Get the reachability matrix of the run (partially filled while the run is in progress)
+// @Tags [MC-Infra] MCI Performance Benchmarking (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param runId path string true "Run ID of the network test"
+// @Success 200 {object} model.NetworkTestRun
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/networkTest/{runId} [get]
+func RestGetNetworkTest(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	runId := c.Param("runId")
+
+	content, err := infra.GetNetworkTestResult(nsId, mciId, runId)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostNetworkTestSuggestRules godoc
+// @ID PostNetworkTestSuggestRules
+// @Summary Suggest security group rules from the failed probes of MCI network test
+// @Description Map the unreachable probes of the run to the security groups attached to the target VMs and propose the missing
+// @Description ingress rules (protocol, port and /32 CIDR of the source VM) per security group. Probes already allowed by a security group are skipped with the reason.
+// @Description 0.0.0.0/0 is proposed only for probes to public IPs from a source VM with a public IP if allowPublic=true.
+// @Description With apply=true, the rules are added to the security groups and the fixed probes are re-run (see recheck in the result).
+// @Tags [MC-Infra] MCI Performance Benchmarking (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param runId path string true "Run ID of the network test"
+// @Param apply query bool false "Add the suggested rules to the security groups and re-run the fixed probes" default(false)
+// @Param allowPublic query bool false "Allow 0.0.0.0/0 for probes to public IPs" default(false)
+// @Success 200 {object} model.NetworkTestRuleSuggestionResult
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/networkTest/{runId}/suggestRules [post]
+func RestPostNetworkTestSuggestRules(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	runId := c.Param("runId")
+	apply := c.QueryParam("apply") == "true"
+	allowPublic := c.QueryParam("allowPublic") == "true"
+
+	content, err := infra.SuggestNetworkTestRules(nsId, mciId, runId, apply, allowPublic)
+	return common.EndRequestWithLog(c, err, content)
+}
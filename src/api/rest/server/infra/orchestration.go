@@ -28,6 +28,11 @@ import (
 // @ID PostMciPolicy
 // @Summary Create MCI Automation policy
 // @Description Create MCI Automation policy
+// @Description
+// @Description A policy with `scalingRule` scales the subGroup by a threshold instead of autoCondition and autoAction
+// @Description (e.g., if avg cpu across the subGroup > 70 for 300 seconds, scale out by 2 up to maxSize).
+// @Description Invalid rules are kept with `validationErrors` and the status `Invalid`, and are not evaluated.
+// @Description `disabled` policies are not evaluated. Evaluations are recorded in GET /ns/{nsId}/policy/mci/{mciId}/history.
 // @Tags [MC-Infra] MCI Orchestration Management (WIP)
 // @Accept  json
 // @Produce  json
@@ -83,6 +88,28 @@ func RestGetMciPolicy(c echo.Context) error {
 	return common.EndRequestWithLog(c, err, result)
 }
 
+// RestGetMciPolicyHistory godoc
+// @ID GetMciPolicyHistory
+// @Summary Get the evaluation history of MCI Policy
+// @Description Get the evaluation history of the scaling rules of MCI Policy (latest last, up to TB_MCI_POLICY_HISTORY_MAX)
+// @Tags [MC-Infra] MCI Orchestration Management (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Success 200 {object} model.MciPolicyHistory
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/policy/mci/{mciId}/history [get]
+func RestGetMciPolicyHistory(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	result, err := infra.GetMciPolicyHistory(nsId, mciId)
+	return common.EndRequestWithLog(c, err, result)
+}
+
 // Response structure for RestGetAllMciPolicy
 type RestGetAllMciPolicyResponse struct {
 	MciPolicy []model.MciPolicyInfo `json:"mciPolicy"`
@@ -174,3 +201,143 @@ func RestDelAllMciPolicy(c echo.Context) error {
 	result, err := infra.DelAllMciPolicy(nsId)
 	return common.EndRequestWithLog(c, err, result)
 }
+
+// RestPostRecoveryPolicy godoc
+// @ID PostRecoveryPolicy
+// @Summary Create VM auto-recovery policy of MCI
+// @Description Create a policy to recover VMs of the MCI (or its subGroup) which failed or were terminated outside CB-Tumblebug.
+// @Description The action (reboot, recreateFromSpec, recreateFromLatestSnapshot) is executed up to maxRestarts times per VM, then the recovery backs off (vm.recoveryBackedOff event).
+// @Tags [MC-Infra] MCI Orchestration Management (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param recoveryPolicyReq body model.RecoveryPolicyReq true "Details for a VM auto-recovery policy request"
+// @Success 200 {object} model.RecoveryPolicyInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/policy/recovery/mci/{mciId} [post]
+func RestPostRecoveryPolicy(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	req := &model.RecoveryPolicyReq{}
+	if err := c.Bind(req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := infra.CreateRecoveryPolicy(nsId, mciId, req)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetRecoveryPolicy godoc
+// @ID GetRecoveryPolicy
+// @Summary Get VM auto-recovery policy of MCI
+// @Description Get VM auto-recovery policy of MCI (with the restart counts of VMs)
+// @Tags [MC-Infra] MCI Orchestration Management (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Success 200 {object} model.RecoveryPolicyInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Router /ns/{nsId}/policy/recovery/mci/{mciId} [get]
+func RestGetRecoveryPolicy(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	content, err := infra.GetRecoveryPolicy(nsId, mciId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// Response structure for RestGetAllRecoveryPolicy
+type RestGetAllRecoveryPolicyResponse struct {
+	RecoveryPolicy []model.RecoveryPolicyInfo `json:"recoveryPolicy"`
+}
+
+// RestGetAllRecoveryPolicy godoc
+// @ID GetAllRecoveryPolicy
+// @Summary List all VM auto-recovery policies
+// @Description List all VM auto-recovery policies in the namespace
+// @Tags [MC-Infra] MCI Orchestration Management (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Success 200 {object} RestGetAllRecoveryPolicyResponse
+// @Failure 400 {object} model.SimpleMsg
+// @Router /ns/{nsId}/policy/recovery [get]
+func RestGetAllRecoveryPolicy(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	result, err := infra.ListRecoveryPolicy(nsId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content := RestGetAllRecoveryPolicyResponse{RecoveryPolicy: result}
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPutRecoveryPolicy godoc
+// @ID PutRecoveryPolicy
+// @Summary Update VM auto-recovery policy of MCI
+// @Description Update VM auto-recovery policy of MCI. Set paused to pause or resume the policy, and resetRestarts to clear the back-off.
+// @Tags [MC-Infra] MCI Orchestration Management (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param recoveryPolicyUpdateReq body model.RecoveryPolicyUpdateReq true "Fields to update (empty fields are not changed)"
+// @Success 200 {object} model.RecoveryPolicyInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Router /ns/{nsId}/policy/recovery/mci/{mciId} [put]
+func RestPutRecoveryPolicy(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	req := &model.RecoveryPolicyUpdateReq{}
+	if err := c.Bind(req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := infra.UpdateRecoveryPolicy(nsId, mciId, req)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDelRecoveryPolicy godoc
+// @ID DelRecoveryPolicy
+// @Summary Delete VM auto-recovery policy of MCI
+// @Description Delete VM auto-recovery policy of MCI (the policy is also deleted with the MCI)
+// @Tags [MC-Infra] MCI Orchestration Management (WIP)
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Success 200 {object} model.SimpleMsg
+// @Failure 400 {object} model.SimpleMsg
+// @Router /ns/{nsId}/policy/recovery/mci/{mciId} [delete]
+func RestDelRecoveryPolicy(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+
+	err := infra.DelRecoveryPolicy(nsId, mciId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	result := model.SimpleMsg{Message: "Deleted the recovery policy of the MCI " + mciId}
+	return common.EndRequestWithLog(c, err, result)
+}
@@ -33,7 +33,9 @@ import (
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param mciReq body model.TbMciReq true "Details for an MCI object"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci [post]
@@ -42,12 +44,15 @@ func RestPostMci(c echo.Context) error {
 	nsId := c.Param("nsId")
 
 	req := &model.TbMciReq{}
-	if err := c.Bind(req); err != nil {
+	if err := common.BindRequest(c, req); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	option := "create"
-	result, err := infra.CreateMci(nsId, req, option)
+	result, err := infra.CreateMci(common.NewRequestContext(c), nsId, req, option)
+	if result != nil {
+		infra.MaskMciInfo(result)
+	}
 	return common.EndRequestWithLog(c, err, result)
 }
 
@@ -55,12 +60,16 @@ func RestPostMci(c echo.Context) error {
 // @ID PostRegisterCSPNativeVM
 // @Summary Register existing VM in a CSP to Cloud-Barista MCI
 // @Description Register existing VM in a CSP to Cloud-Barista MCI
+// @Description The vNet, subnet, security groups and SSH key of the VM are linked to the objects already registered in the namespace, or registered together.
+// @Description If sshKeyId of a VM is an SSH key in the namespace with the private key, the key is kept and the SSH reachability of the VM is checked (see systemMessage of the VM).
 // @Tags [MC-Infra] MCI Provisioning and Management
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param mciReq body model.TbMciReq true "Details for an MCI object with existing CSP VM ID"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/registerCspVm [post]
@@ -69,12 +78,47 @@ func RestPostRegisterCSPNativeVM(c echo.Context) error {
 	nsId := c.Param("nsId")
 
 	req := &model.TbMciReq{}
-	if err := c.Bind(req); err != nil {
+	if err := common.BindRequest(c, req); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	option := "register"
-	result, err := infra.CreateMci(nsId, req, option)
+	result, err := infra.CreateMci(common.NewRequestContext(c), nsId, req, option)
+	if result != nil {
+		infra.MaskMciInfo(result)
+	}
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPostImportCspVms godoc
+// @ID PostImportCspVms
+// @Summary Import CSP VMs of a connection into a new MCI
+// @Description Register the VMs of the connection which are not managed by CB-TB (matching the tag filter) into a new MCI.
+// @Description The vNet, subnet, security groups and SSH key of each VM are linked or registered together. Use dryRun to list the VMs to be imported.
+// @Tags [MC-Infra] MCI Provisioning and Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param importReq body model.CspVmImportReq true "Connection, MCI name and tag filter of the CSP VMs to import"
+// @Param dryRun query bool false "List the VMs to be imported without registering them" default(false)
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Success 200 {object} model.CspVmImportResult
+// @Failure 400 {object} model.ValidationErrorMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 409 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/registerCspVm/bulk [post]
+func RestPostImportCspVms(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	dryRun := c.QueryParam("dryRun") == "true"
+
+	req := &model.CspVmImportReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.ImportCspVms(common.NewRequestContext(c), nsId, req, dryRun)
 	return common.EndRequestWithLog(c, err, result)
 }
 
@@ -86,7 +130,9 @@ func RestPostRegisterCSPNativeVM(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Param option query string false "Option for the purpose of system MCI" Enums(probe)
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /systemMci [post]
@@ -95,11 +141,11 @@ func RestPostSystemMci(c echo.Context) error {
 	option := c.QueryParam("option")
 
 	req := &model.TbMciDynamicReq{}
-	if err := c.Bind(req); err != nil {
+	if err := common.BindRequest(c, req); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	result, err := infra.CreateSystemMciDynamic(option)
+	result, err := infra.CreateSystemMciDynamic(common.NewRequestContext(c), option)
 	return common.EndRequestWithLog(c, err, result)
 }
 
@@ -107,6 +153,7 @@ func RestPostSystemMci(c echo.Context) error {
 // @ID PostMciDynamic
 // @Summary Create MCI Dynamically
 // @Description Create MCI Dynamically from common spec and image
+// @Description commonImage can be an alias such as ubuntu22.04, which is resolved to the newest matched image in the region of each VM.
 // @Tags [MC-Infra] MCI Provisioning and Management
 // @Accept  json
 // @Produce  json
@@ -114,7 +161,9 @@ func RestPostSystemMci(c echo.Context) error {
 // @Param mciReq body model.TbMciDynamicReq true "Request body to provision MCI dynamically. Must include commonSpec and commonImage info of each VM request.(ex: {name: mci01,vm: [{commonImage: aws+ap-northeast-2+ubuntu22.04,commonSpec: aws+ap-northeast-2+t2.small}]} ) You can use /mciRecommendVm and /mciDynamicCheckRequest to get it) Check the guide: https://github.com/cloud-barista/cb-tumblebug/discussions/1570"
 // @Param option query string false "Option for MCI creation" Enums(hold)
 // @Param x-request-id header string false "Custom request ID"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mciDynamic [post]
@@ -125,16 +174,17 @@ func RestPostMciDynamic(c echo.Context) error {
 	option := c.QueryParam("option")
 
 	req := &model.TbMciDynamicReq{}
-	if err := c.Bind(req); err != nil {
+	if err := common.BindRequest(c, req); err != nil {
 		log.Warn().Err(err).Msg("invalid request")
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	result, err := infra.CreateMciDynamic(reqID, nsId, req, option)
+	result, err := infra.CreateMciDynamic(common.NewRequestContext(c), reqID, nsId, req, option)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to create MCI dynamically")
 		return common.EndRequestWithLog(c, err, nil)
 	}
+	infra.MaskMciInfo(result)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -148,7 +198,9 @@ func RestPostMciDynamic(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param vmReq body model.TbVmDynamicReq true "Details for Vm dynamic request"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci/{mciId}/vmDynamic [post]
@@ -158,11 +210,14 @@ func RestPostMciVmDynamic(c echo.Context) error {
 	mciId := c.Param("mciId")
 
 	req := &model.TbVmDynamicReq{}
-	if err := c.Bind(req); err != nil {
+	if err := common.BindRequest(c, req); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	result, err := infra.CreateMciVmDynamic(nsId, mciId, req)
+	result, err := infra.CreateMciVmDynamic(common.NewRequestContext(c), nsId, mciId, req)
+	if result != nil {
+		infra.MaskMciInfo(result)
+	}
 	return common.EndRequestWithLog(c, err, result)
 }
 
@@ -170,18 +225,21 @@ func RestPostMciVmDynamic(c echo.Context) error {
 // @ID PostMciDynamicCheckRequest
 // @Summary Check available ConnectionConfig list for creating MCI Dynamically
 // @Description Check available ConnectionConfig list before create MCI Dynamically from common spec and image
+// @Description If commonImage is given (an image id or an alias such as ubuntu22.04), selectedImage shows the image id resolved for each connection.
 // @Tags [MC-Infra] MCI Provisioning and Management
 // @Accept  json
 // @Produce  json
 // @Param mciReq body model.MciConnectionConfigCandidatesReq true "Details for MCI dynamic request information"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.CheckMciDynamicReqInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /mciDynamicCheckRequest [post]
 func RestPostMciDynamicCheckRequest(c echo.Context) error {
 
 	req := &model.MciConnectionConfigCandidatesReq{}
-	if err := c.Bind(req); err != nil {
+	if err := common.BindRequest(c, req); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
@@ -199,7 +257,9 @@ func RestPostMciDynamicCheckRequest(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param vmReq body model.TbVmReq true "Details for VMs(subGroup)"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci/{mciId}/vm [post]
@@ -209,10 +269,13 @@ func RestPostMciVm(c echo.Context) error {
 	mciId := c.Param("mciId")
 
 	vmInfoData := &model.TbVmReq{}
-	if err := c.Bind(vmInfoData); err != nil {
+	if err := common.BindRequest(c, vmInfoData); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 	result, err := infra.CreateMciGroupVm(nsId, mciId, vmInfoData, true)
+	if result != nil {
+		infra.MaskMciInfo(result)
+	}
 	return common.EndRequestWithLog(c, err, result)
 }
 
@@ -227,7 +290,9 @@ func RestPostMciVm(c echo.Context) error {
 // @Param mciId path string true "MCI ID" default(mci01)
 // @Param subgroupId path string true "subGroup ID" default(g1)
 // @Param vmReq body model.TbScaleOutSubGroupReq true "subGroup scaleOut request"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbMciInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/mci/{mciId}/subgroup/{subgroupId} [post]
@@ -238,7 +303,7 @@ func RestPostMciSubGroupScaleOut(c echo.Context) error {
 	subgroupId := c.Param("subgroupId")
 
 	scaleOutReq := &model.TbScaleOutSubGroupReq{}
-	if err := c.Bind(scaleOutReq); err != nil {
+	if err := common.BindRequest(c, scaleOutReq); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
@@ -21,10 +21,16 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// RecommendationDiagnosticHeader is the response header which explains why no spec is recommended
+const RecommendationDiagnosticHeader = "X-Recommendation-Diagnostic"
+
 // RestRecommendVm godoc
 // @ID RecommendVm
 // @Summary Recommend MCI plan (filter and priority)
 // @Description Recommend MCI plan (filter and priority) Find details from https://github.com/cloud-barista/cb-tumblebug/discussions/1234
+// @Description Filter metrics include acceleratorModel, acceleratorCount, architecture (x86_64, arm64) and spotCostPerHour.
+// @Description Multiple priority policies are combined by their weights, and cost can prefer spot price with the parameter priceType=spot.
+// @Description If no spec satisfies the filter, an empty list is returned with the X-Recommendation-Diagnostic header explaining which constraint eliminated all candidates.
 // @Tags [MC-Infra] MCI Provisioning and Management
 // @Accept  json
 // @Produce  json
@@ -42,7 +48,10 @@ func RestRecommendVm(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	content, err := infra.RecommendVm(nsId, *u)
+	content, diagnostic, err := infra.RecommendVmWithDiagnostic(nsId, *u)
+	if diagnostic != "" {
+		c.Response().Header().Set(RecommendationDiagnosticHeader, diagnostic)
+	}
 	return common.EndRequestWithLog(c, err, content)
 }
 
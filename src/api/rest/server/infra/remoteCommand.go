@@ -29,6 +29,11 @@ import (
 // @ID PostCmdMci
 // @Summary Send a command to specified MCI
 // @Description Send a command to specified MCI
+// @Description
+// @Description Commands to Linux VMs are executed by SSH (bash). Commands to Windows VMs (detected from the image) are executed
+// @Description by WinRM with the Administrator password returned by the CSP, or by SSH (OpenSSH on the image) otherwise.
+// @Description The shell hint (bash, powershell, cmd) defaults to bash for Linux and powershell for Windows.
+// @Description The transport and the shell used for each VM are given in the results.
 // @Tags [MC-Infra] MCI Remote Command
 // @Accept  json
 // @Produce  json
@@ -79,6 +84,7 @@ func RestPostCmdMci(c echo.Context) error {
 // @Description Transfer a file to specified MCI to the specified path.
 // @Description The file size should be less than 10MB.
 // @Description Not for gerneral file transfer but for specific purpose (small configuration files).
+// @Description For Windows VMs, the path is a Windows path (e.g., C:/Users/Administrator/) and the file is written by WinRM (or SCP of OpenSSH).
 // @Tags [MC-Infra] MCI Remote Command
 // @Accept  multipart/form-data
 // @Produce  json
@@ -219,3 +225,51 @@ func RestRemoveBastionNodes(c echo.Context) error {
 	content, err := infra.RemoveBastionNodes(nsId, mciId, bastionVmId)
 	return common.EndRequestWithLog(c, err, content)
 }
+
+// RestPostInstallGpuDriverToMci godoc
+// @ID PostInstallGpuDriverToMci
+// @Summary Install the GPU driver to the GPU VMs of MCI
+// @Description Install the NVIDIA driver (and the CUDA toolkit optionally) to the GPU VMs of MCI in parallel.
+// @Description GPU VMs are detected from the spec (acceleratorType=gpu); the other VMs are skipped with a note.
+// @Description The driver is installed from the NVIDIA CUDA repository of the OS (Ubuntu, Debian, RHEL family and Amazon Linux)
+// @Description with a timeout per VM (TB_GPU_DRIVER_INSTALL_TIMEOUT_MIN). VMs are rebooted if the driver is not loaded without reboot (reboot=auto).
+// @Description The driver is verified by nvidia-smi and the driver and CUDA versions are stored in the VM (gpu).
+// @Description The tail of the installer log is returned for a failed VM.
+// @Tags [MC-Infra] MCI Remote Command
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param gpuDriverInstallReq body model.GpuDriverInstallReq true "GPU driver installation request"
+// @Param subGroupId query string false "subGroupId to install the driver only for VMs in subGroup of MCI" default(g1)
+// @Param vmId query string false "vmId to install the driver only for a VM in MCI" default(g1-1)
+// @Param async query bool false "Run as an async job and return the job immediately (track it by GET /jobs/{jobId})" default(false)
+// @Param x-request-id header string false "Custom request ID"
+// @Success 200 {object} model.GpuDriverInstallResult
+// @Success 202 {object} model.JobInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/installGpuDriver [post]
+func RestPostInstallGpuDriverToMci(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	subGroupId := c.QueryParam("subGroupId")
+	vmId := c.QueryParam("vmId")
+
+	req := &model.GpuDriverInstallReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	if c.QueryParam("async") == "true" {
+		job, err := infra.InstallGpuDriverToMciAsync(nsId, mciId, subGroupId, vmId, req)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		return c.JSON(http.StatusAccepted, job)
+	}
+
+	result, err := infra.InstallGpuDriverToMci(c.Request().Context(), nsId, mciId, subGroupId, vmId, req)
+	return common.EndRequestWithLog(c, err, result)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mci is to handle REST API for mci
+package infra
+
+import (
+	"net/http"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+)
+
+// restMciSoftware runs the software action on the MCI (as an async job with async=true)
+func restMciSoftware(c echo.Context, action string, req *model.SoftwareInstallReq) error {
+	nsId := c.Param("nsId")
+	mciId := c.Param("mciId")
+	name := c.Param("name")
+	subGroupId := c.QueryParam("subGroupId")
+
+	if c.QueryParam("async") == "true" {
+		job, err := infra.HandleMciSoftwareAsync(nsId, mciId, subGroupId, name, action, req)
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+		return c.JSON(http.StatusAccepted, job)
+	}
+
+	result, err := infra.HandleMciSoftware(c.Request().Context(), nsId, mciId, subGroupId, name, action, req)
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPostMciSoftware godoc
+// @ID PostMciSoftware
+// @Summary Install a software of the catalog to MCI
+// @Description Install a software of the software catalog (assets/softwarecatalog.yaml, e.g., docker, nginx, node_exporter, haproxy)
+// @Description to the VMs of MCI in parallel by the commands for the OS family of each VM.
+// @Description The installed version is stored in the VM (software). Installs and uninstalls of the same software in an MCI are serialized.
+// @Description Software can be added to the catalog without code changes.
+// @Tags [MC-Infra] MCI Remote Command
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param name path string true "Software name in the catalog" default(docker)
+// @Param softwareInstallReq body model.SoftwareInstallReq false "Software install request"
+// @Param subGroupId query string false "subGroupId to install the software only for VMs in subGroup of MCI" default(g1)
+// @Param async query bool false "Run as an async job and return the job immediately (track it by GET /jobs/{jobId})" default(false)
+// @Param x-request-id header string false "Custom request ID"
+// @Success 200 {object} model.SoftwareActionResult
+// @Success 202 {object} model.JobInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/software/{name} [post]
+func RestPostMciSoftware(c echo.Context) error {
+
+	req := &model.SoftwareInstallReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return restMciSoftware(c, model.SoftwareActionInstall, req)
+}
+
+// RestDelMciSoftware godoc
+// @ID DelMciSoftware
+// @Summary Uninstall a software of the catalog from MCI
+// @Description Uninstall a software of the software catalog from the VMs of MCI in parallel and remove it from the VMs (software).
+// @Tags [MC-Infra] MCI Remote Command
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param name path string true "Software name in the catalog" default(docker)
+// @Param subGroupId query string false "subGroupId to uninstall the software only for VMs in subGroup of MCI" default(g1)
+// @Param userName query string false "SSH user of the VMs (default: the verified user of each VM)"
+// @Param async query bool false "Run as an async job and return the job immediately (track it by GET /jobs/{jobId})" default(false)
+// @Param x-request-id header string false "Custom request ID"
+// @Success 200 {object} model.SoftwareActionResult
+// @Success 202 {object} model.JobInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/software/{name} [delete]
+func RestDelMciSoftware(c echo.Context) error {
+
+	req := &model.SoftwareInstallReq{UserName: c.QueryParam("userName")}
+	return restMciSoftware(c, model.SoftwareActionUninstall, req)
+}
+
+// RestGetMciSoftware godoc
+// @ID GetMciSoftware
+// @Summary Get the status of a software of the catalog in MCI
+// @Description Get the installed version of a software of the software catalog in the VMs of MCI by its status command
+// @Description and refresh the version stored in the VMs (software).
+// @Tags [MC-Infra] MCI Remote Command
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param mciId path string true "MCI ID" default(mci01)
+// @Param name path string true "Software name in the catalog" default(docker)
+// @Param subGroupId query string false "subGroupId to check the software only for VMs in subGroup of MCI" default(g1)
+// @Param userName query string false "SSH user of the VMs (default: the verified user of each VM)"
+// @Param x-request-id header string false "Custom request ID"
+// @Success 200 {object} model.SoftwareActionResult
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/mci/{mciId}/software/{name} [get]
+func RestGetMciSoftware(c echo.Context) error {
+
+	req := &model.SoftwareInstallReq{UserName: c.QueryParam("userName")}
+	return restMciSoftware(c, model.SoftwareActionStatus, req)
+}
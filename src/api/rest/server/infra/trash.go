@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mci is to handle REST API for mci
+package infra
+
+import (
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+)
+
+// RestGetAllTrash godoc
+// @ID GetAllTrash
+// @Summary List soft-deleted MCIs and resources in the trash
+// @Description List the trash items of the namespace (oldest first). Items are purged automatically after TB_TRASH_RETENTION_HOURS.
+// @Tags [Namespace management] Trash
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Success 200 {object} model.TrashItemList
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/trash [get]
+func RestGetAllTrash(c echo.Context) error {
+	nsId := c.Param("nsId")
+
+	items, err := common.ListTrashItems(nsId)
+	return common.EndRequestWithLog(c, err, model.TrashItemList{Items: items})
+}
+
+// RestGetTrash godoc
+// @ID GetTrash
+// @Summary Get a trash item
+// @Description Get the trash item with the metadata (Key-Value records and labels) of the deleted object
+// @Tags [Namespace management] Trash
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param itemId path string true "Trash item ID"
+// @Success 200 {object} model.TrashItem
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/trash/{itemId} [get]
+func RestGetTrash(c echo.Context) error {
+	nsId := c.Param("nsId")
+	itemId := c.Param("itemId")
+
+	item, err := common.GetTrashItem(nsId, itemId)
+	return common.EndRequestWithLog(c, err, item)
+}
+
+// RestPostRestoreTrash godoc
+// @ID PostRestoreTrash
+// @Summary Restore a trash item
+// @Description Restore the MCI or resource in the trash item. If the id is taken, the restored object gets a suffixed id (e.g., mci01-1).
+// @Description A resource is restored only if its CSP resource still exists. The status of the VMs of a restored MCI is refreshed from the CSPs.
+// @Tags [Namespace management] Trash
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param itemId path string true "Trash item ID"
+// @Success 200 {object} model.TrashRestoreResult
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 409 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/trash/{itemId}/restore [post]
+func RestPostRestoreTrash(c echo.Context) error {
+	nsId := c.Param("nsId")
+	itemId := c.Param("itemId")
+
+	result, err := infra.RestoreTrashItem(nsId, itemId)
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestDelTrash godoc
+// @ID DelTrash
+// @Summary Purge a trash item permanently
+// @Description Delete the trash item permanently. The CSP resource kept for a soft-deleted resource is deleted as well.
+// @Tags [Namespace management] Trash
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param itemId path string true "Trash item ID"
+// @Param force query bool false "Purge the item even if the CSP resource cannot be deleted" default(false)
+// @Success 200 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/trash/{itemId} [delete]
+func RestDelTrash(c echo.Context) error {
+	nsId := c.Param("nsId")
+	itemId := c.Param("itemId")
+	force := c.QueryParam("force") == "true"
+
+	result, err := infra.PurgeTrashItem(nsId, itemId, force)
+	return common.EndRequestWithLog(c, err, result)
+}
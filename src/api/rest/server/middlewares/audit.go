@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// auditUser returns the authenticated user of the request (JWT user name, basic auth username or client certificate CN).
+// The audit middleware runs before the auth middleware, so a rejected request is recorded as "anonymous".
+func auditUser(c echo.Context) string {
+	return common.RequestUser(c)
+}
+
+// Audit records mutating API calls (POST, PUT, PATCH, DELETE) and reads of secrets
+// (GET with revealPrivateKey=true or revealPassword=true) to the audit log.
+// Sensitive values in request bodies (e.g., credentials) are redacted before being stored.
+func Audit(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		switch req.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		case http.MethodGet:
+			if c.QueryParam("revealPrivateKey") != "true" && c.QueryParam("revealPassword") != "true" {
+				return next(c)
+			}
+		default:
+			return next(c)
+		}
+
+		timestamp := time.Now()
+
+		var body interface{}
+		if req.Body != nil {
+			bodyBytes, err := io.ReadAll(req.Body)
+			if err == nil {
+				json.Unmarshal(bodyBytes, &body)
+				// Write the body back for further processing
+				req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+			}
+		}
+
+		err := next(c)
+
+		status := c.Response().Status
+		if he, ok := err.(*echo.HTTPError); ok {
+			status = he.Code
+		}
+
+		pathParams := map[string]string{}
+		for i, name := range c.ParamNames() {
+			if i < len(c.ParamValues()) {
+				pathParams[name] = c.ParamValues()[i]
+			}
+		}
+
+		record := model.AuditRecord{
+			Timestamp:   timestamp,
+			User:        auditUser(c),
+			Method:      req.Method,
+			Route:       c.Path(),
+			Path:        req.URL.Path,
+			PathParams:  pathParams,
+			RequestBody: common.RedactAuditBody(req.URL.Path, body),
+			Status:      status,
+			RequestId:   req.Header.Get(echo.HeaderXRequestID),
+			RemoteIp:    c.RealIP(),
+		}
+		if auditErr := common.AppendAuditRecord(record); auditErr != nil {
+			log.Error().Err(auditErr).Msgf("Failed to store audit record (%s %s)", req.Method, req.URL.Path)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/api/rest/server/middlewares/authmw"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "middlewares")
+	if err != nil {
+		panic(err)
+	}
+	store, err := bolt.NewBoltStore(context.Background(), bolt.Config{Path: filepath.Join(dir, "kvstore.db")})
+	if err != nil {
+		panic(err)
+	}
+	kvstore.InitializeStore(store)
+	code := m.Run()
+	store.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestAuditRecordsVerifiedUserAndConnectionIp(t *testing.T) {
+	e := echo.New()
+	e.IPExtractor = ClientIpExtractor("")
+	e.Use(Audit)
+	e.Use(middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
+		Validator: authmw.BasicAuthValidator([]authmw.BasicAuthUser{{Username: "admin", Password: "secret", Role: authmw.RoleAdmin}}),
+	}))
+	e.POST("/tumblebug/ns", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	tests := []struct {
+		name     string
+		password string
+		user     string
+		status   int
+	}{
+		{"verified", "secret", "admin", http.StatusOK},
+		// the username of a rejected request is only claimed
+		{"rejected", "wrong", "anonymous", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqId := "audit-" + tt.name
+			req := httptest.NewRequest(http.MethodPost, "/tumblebug/ns", nil)
+			req.SetBasicAuth("admin", tt.password)
+			req.RemoteAddr = "203.0.113.7:40000"
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			req.Header.Set(echo.HeaderXRequestID, reqId)
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			list, err := common.ListAuditRecords(common.AuditFilter{PathPrefix: "/tumblebug/ns"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, record := range list.Records {
+				if record.RequestId != reqId {
+					continue
+				}
+				found = true
+				if record.User != tt.user || record.Status != tt.status || record.RemoteIp != "203.0.113.7" {
+					t.Errorf("got user %s, status %d, remote IP %s, want %s, %d, 203.0.113.7", record.User, record.Status, record.RemoteIp, tt.user, tt.status)
+				}
+			}
+			if !found {
+				t.Errorf("no audit record of %s", reqId)
+			}
+		})
+	}
+}
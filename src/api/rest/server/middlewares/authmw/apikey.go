@@ -0,0 +1,99 @@
+package authmw
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+)
+
+// apiKeyAdminPath is the prefix of the API key management routes which require the admin credential
+const apiKeyAdminPath = "/tumblebug/apikeys"
+
+// ApiKeyNsDeniedRoutes are routes without a namespace in the path which return objects of all namespaces
+// (denied for a namespace-restricted API key)
+var ApiKeyNsDeniedRoutes = []string{
+	"/tumblebug/object",
+	"/tumblebug/objects",
+	"/tumblebug/objects/stats",
+	"/tumblebug/request/:reqId",
+	"/tumblebug/requests",
+	"/tumblebug/audit",
+	"/tumblebug/audit/verify",
+	"/tumblebug/jobs",
+	"/tumblebug/jobs/:jobId",
+	"/tumblebug/consistency",
+	"/tumblebug/label/:labelType/:uid",
+	"/tumblebug/resources/:labelType",
+	"/tumblebug/webhooks",
+	"/tumblebug/webhooks/:webhookId",
+	"/tumblebug/webhooks/:webhookId/deliveries",
+}
+
+var apiKeyNsDeniedRouteSet = routeSet(ApiKeyNsDeniedRoutes)
+
+// ApiKeyAuthMw returns the middleware for API key authentication (TB_AUTH_MODE=apikey).
+// Requests are authenticated by the X-API-Key header, or by the admin credential (basic auth) for bootstrap.
+// API key management routes can be called only with the admin credential.
+func ApiKeyAuthMw(skipPaths []string, adminUser string, adminPass string) echo.MiddlewareFunc {
+	skip := map[string]bool{}
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skip[c.Path()] {
+				return next(c)
+			}
+
+			// Be careful to use constant time comparison to prevent timing attacks
+			if username, password, ok := c.Request().BasicAuth(); ok {
+				if adminUser != "" &&
+					subtle.ConstantTimeCompare([]byte(username), []byte(adminUser)) == 1 &&
+					subtle.ConstantTimeCompare([]byte(password), []byte(adminPass)) == 1 {
+					c.Set("authenticated", true)
+					c.Set("name", username)
+					c.Set("role", "admin")
+					return next(c)
+				}
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid admin credential")
+			}
+
+			key := c.Request().Header.Get(model.ApiKeyHeader)
+			if key == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("%s header is required", model.ApiKeyHeader))
+			}
+			keyInfo, err := common.ValidateApiKey(key)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+
+			if strings.HasPrefix(c.Path(), apiKeyAdminPath) {
+				return echo.NewHTTPError(http.StatusForbidden, "API keys cannot be managed by an API key (admin credential is required)")
+			}
+
+			// same predicate as the readonly role of basic auth (method, denied GET routes and secret-revealing query flags)
+			readOnly := IsReadOnlyRequest(c)
+			if keyInfo.Scope == model.ApiKeyScopeReadOnly && !readOnly {
+				return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("the API key (%s) is %s", keyInfo.Name, model.ApiKeyScopeReadOnly))
+			}
+			// a namespace-restricted key can change objects only in its namespace (checked by NsValidation)
+			// and cannot read the objects of other namespaces by the routes without a namespace
+			// (the namespace list is filtered by the handler)
+			if keyInfo.NsId != "" && c.Param("nsId") == "" && (!readOnly || apiKeyNsDeniedRouteSet[c.Path()]) {
+				return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("the API key (%s) is restricted to the namespace (%s)", keyInfo.Name, keyInfo.NsId))
+			}
+
+			c.Set("authenticated", true)
+			c.Set("name", "apikey:"+keyInfo.Name)
+			c.Set("role", keyInfo.Scope)
+			c.Set(common.ContextKeyApiKeyNsId, keyInfo.NsId)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package authmw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rest_common "github.com/cloud-barista/cb-tumblebug/src/api/rest/server/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/labstack/echo/v4"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "authmw")
+	if err != nil {
+		panic(err)
+	}
+	store, err := bolt.NewBoltStore(context.Background(), bolt.Config{Path: filepath.Join(dir, "kvstore.db")})
+	if err != nil {
+		panic(err)
+	}
+	kvstore.InitializeStore(store)
+	code := m.Run()
+	store.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestApiKeyAuthMwReadOnlyScope(t *testing.T) {
+	created, err := common.CreateApiKey(&model.ApiKeyReq{Name: "viewer", Scope: model.ApiKeyScopeReadOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		route    string
+		target   string
+		wantCode int
+	}{
+		{"get mci", http.MethodGet, "/tumblebug/ns/:nsId/mci/:mciId", "/tumblebug/ns/default/mci/mci01", http.StatusOK},
+		{"terminate mci by get", http.MethodGet, "/tumblebug/ns/:nsId/control/mci/:mciId", "/tumblebug/ns/default/control/mci/mci01?action=terminate", http.StatusForbidden},
+		{"export with secrets", http.MethodGet, "/tumblebug/ns/:nsId/export", "/tumblebug/ns/default/export?includeSecrets=true", http.StatusForbidden},
+		{"read-only post", http.MethodPost, "/tumblebug/lookupSpecs", "/tumblebug/lookupSpecs", http.StatusOK},
+		{"delete mci", http.MethodDelete, "/tumblebug/ns/:nsId/mci/:mciId", "/tumblebug/ns/default/mci/mci01", http.StatusForbidden},
+	}
+
+	e := echo.New()
+	handler := ApiKeyAuthMw(nil, "admin", "secret")(func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set(model.ApiKeyHeader, created.Key)
+			c := e.NewContext(req, httptest.NewRecorder())
+			c.SetPath(tt.route)
+			code := http.StatusOK
+			if err := handler(c); err != nil {
+				he, ok := err.(*echo.HTTPError)
+				if !ok {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				code = he.Code
+			}
+			if code != tt.wantCode {
+				t.Errorf("%s %s: got %d, want %d", tt.method, tt.target, code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestApiKeyAuthMwNamespaceRestriction(t *testing.T) {
+	for _, nsId := range []string{"ns-restricted", "ns-other"} {
+		if _, err := common.CreateNs(&model.NsReq{Name: nsId}); err != nil {
+			t.Fatal(err)
+		}
+		defer common.DelNs(nsId)
+	}
+	created, err := common.CreateApiKey(&model.ApiKeyReq{Name: "ns-reader", Scope: model.ApiKeyScopeReadWrite, NsId: "ns-restricted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/tumblebug/ns", rest_common.RestGetAllNs)
+	e.GET("/tumblebug/ns/:nsId/mci", ok, common.NsValidation())
+	for _, route := range ApiKeyNsDeniedRoutes {
+		e.GET(route, ok)
+	}
+	e.Use(ApiKeyAuthMw(nil, "admin", "secret"))
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			reqId := c.Request().Header.Get(echo.HeaderXRequestID)
+			common.RequestMap.Store(reqId, common.RequestDetails{StartTime: time.Now(), Status: common.RequestStatusHandling})
+			defer common.RequestMap.Delete(reqId)
+			return next(c)
+		}
+	})
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(model.ApiKeyHeader, created.Key)
+		req.Header.Set(echo.HeaderXRequestID, "apikey-ns-test")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		target   string
+		wantCode int
+	}{
+		{"/tumblebug/ns/ns-restricted/mci", http.StatusOK},
+		{"/tumblebug/ns/ns-other/mci", http.StatusForbidden},
+		{"/tumblebug/object?key=/ns/ns-other/resources/sshKey/key01", http.StatusForbidden},
+		{"/tumblebug/objects?key=/ns/ns-other", http.StatusForbidden},
+		{"/tumblebug/objects/stats", http.StatusForbidden},
+		{"/tumblebug/requests", http.StatusForbidden},
+		{"/tumblebug/request/req01", http.StatusForbidden},
+		{"/tumblebug/audit", http.StatusForbidden},
+		{"/tumblebug/audit/verify", http.StatusForbidden},
+		{"/tumblebug/jobs", http.StatusForbidden},
+		{"/tumblebug/resources/mci?labelSelector=env%3Dprod", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := get(tt.target); rec.Code != tt.wantCode {
+			t.Errorf("GET %s: got %d, want %d", tt.target, rec.Code, tt.wantCode)
+		}
+	}
+
+	// the namespace list has the namespace of the key only
+	rec := get("/tumblebug/ns")
+	list := rest_common.RestGetAllNsResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("GET /tumblebug/ns: %d %s", rec.Code, rec.Body.String())
+	}
+	if len(list.Ns) != 1 || list.Ns[0].Id != "ns-restricted" {
+		t.Errorf("GET /tumblebug/ns: got %+v, want ns-restricted only", list.Ns)
+	}
+	rec = get("/tumblebug/ns?option=id")
+	ids := model.IdList{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &ids); err != nil || len(ids.IdList) != 1 || ids.IdList[0] != "ns-restricted" {
+		t.Errorf("GET /tumblebug/ns?option=id: got %s, want ns-restricted only", rec.Body.String())
+	}
+}
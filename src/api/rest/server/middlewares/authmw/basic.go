@@ -0,0 +1,180 @@
+package authmw
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v2"
+)
+
+// Roles of basic auth users
+const (
+	RoleAdmin    = "admin"
+	RoleReadOnly = "readonly"
+)
+
+// BasicAuthUser is a user for basic auth with a role
+type BasicAuthUser struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	Role     string `yaml:"role" json:"role"`
+}
+
+// ReadOnlyPostRoutes are POST routes which do not change any object (allowed for the readonly role)
+var ReadOnlyPostRoutes = []string{
+	"/tumblebug/lookupSpecs",
+	"/tumblebug/lookupSpec",
+	"/tumblebug/lookupImages",
+	"/tumblebug/lookupImage",
+	"/tumblebug/inspectResources",
+	"/tumblebug/mciRecommendVm",
+	"/tumblebug/mciDynamicCheckRequest",
+	"/tumblebug/util/net/design",
+	"/tumblebug/util/net/validate",
+	"/tumblebug/util/vNet/design",
+	"/tumblebug/ns/:nsId/k8sClusterDynamicCheckRequest",
+	"/tumblebug/ns/:nsId/resources/filterSpecsByRange",
+	"/tumblebug/ns/:nsId/resources/searchImage",
+}
+
+// ReadOnlyDeniedGetRoutes are GET routes which change objects or return credentials (denied for the readonly role)
+var ReadOnlyDeniedGetRoutes = []string{
+	"/tumblebug/loadAssets",
+	"/tumblebug/ns/:nsId/control/mci/:mciId",
+	"/tumblebug/ns/:nsId/control/mci/:mciId/vm/:vmId",
+	"/tumblebug/ns/:nsId/benchmarkLatency/mci/:mciId",
+	"/tumblebug/ns/:nsId/mci/:mciId/software/:name",
+	"/tumblebug/ns/:nsId/k8scluster/:k8sClusterId/kubeconfig",
+	// raw objects of the Key-Value store (secrets are not masked)
+	"/tumblebug/object",
+	"/tumblebug/objects",
+	"/tumblebug/objects/stats",
+}
+
+// ReadOnlyDeniedQueryFlags are query parameters which reveal secrets in the response (denied for the readonly role)
+var ReadOnlyDeniedQueryFlags = map[string]string{
+	"revealPrivateKey": "true",
+	"revealPassword":   "true",
+	"includeSecrets":   "true",
+}
+
+var (
+	readOnlyPostRouteSet      = routeSet(ReadOnlyPostRoutes)
+	readOnlyDeniedGetRouteSet = routeSet(ReadOnlyDeniedGetRoutes)
+)
+
+// routeSet returns the set of the routes
+func routeSet(routes []string) map[string]bool {
+	set := map[string]bool{}
+	for _, route := range routes {
+		set[route] = true
+	}
+	return set
+}
+
+// IsReadOnlyRequest returns true if the request neither changes any object nor reveals secrets:
+// GET (HEAD, OPTIONS) routes except ReadOnlyDeniedGetRoutes and ReadOnlyDeniedQueryFlags, and ReadOnlyPostRoutes.
+func IsReadOnlyRequest(c echo.Context) bool {
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if readOnlyDeniedGetRouteSet[c.Path()] {
+			return false
+		}
+		for flag, value := range ReadOnlyDeniedQueryFlags {
+			if strings.EqualFold(c.QueryParam(flag), value) {
+				return false
+			}
+		}
+		return true
+	case http.MethodPost:
+		return readOnlyPostRouteSet[c.Path()]
+	}
+	return false
+}
+
+// LoadBasicAuthUsers returns basic auth users from the users file (YAML or JSON list),
+// or the users string (e.g., "admin:pw:admin,viewer:pw:readonly").
+// If neither is given, the default user is used as an admin.
+func LoadBasicAuthUsers(usersFile string, users string, defaultUser string, defaultPass string) ([]BasicAuthUser, error) {
+	result := []BasicAuthUser{}
+
+	switch {
+	case usersFile != "":
+		data, err := os.ReadFile(usersFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read users file (%s): %w", usersFile, err)
+		}
+		// YAML is a superset of JSON
+		if err := yaml.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse users file (%s): %w", usersFile, err)
+		}
+	case users != "":
+		for _, entry := range strings.Split(users, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			// password may contain ':' (username is before the first one and role is after the last one)
+			first := strings.Index(entry, ":")
+			last := strings.LastIndex(entry, ":")
+			if first < 0 || first == last {
+				return nil, fmt.Errorf("invalid user entry (%s): username:password:role is required", strings.SplitN(entry, ":", 2)[0])
+			}
+			result = append(result, BasicAuthUser{
+				Username: entry[:first],
+				Password: entry[first+1 : last],
+				Role:     entry[last+1:],
+			})
+		}
+	default:
+		result = append(result, BasicAuthUser{Username: defaultUser, Password: defaultPass, Role: RoleAdmin})
+	}
+
+	for _, u := range result {
+		if u.Username == "" {
+			return nil, fmt.Errorf("username is required for basic auth users")
+		}
+		if u.Role != RoleAdmin && u.Role != RoleReadOnly {
+			return nil, fmt.Errorf("invalid role (%s) of user (%s): %s or %s is required", u.Role, u.Username, RoleAdmin, RoleReadOnly)
+		}
+	}
+	return result, nil
+}
+
+// BasicAuthValidator returns the validator for the basic auth middleware.
+// The name and role of the authenticated user are set to the context.
+func BasicAuthValidator(users []BasicAuthUser) func(username, password string, c echo.Context) (bool, error) {
+	return func(username, password string, c echo.Context) (bool, error) {
+		for _, u := range users {
+			// Be careful to use constant time comparison to prevent timing attacks
+			if subtle.ConstantTimeCompare([]byte(username), []byte(u.Username)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(password), []byte(u.Password)) == 1 {
+				c.Set("authenticated", true)
+				c.Set("name", u.Username)
+				c.Set("role", u.Role)
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// RoleAuthorization allows the readonly role to call only read-only requests (see IsReadOnlyRequest).
+// Other requests by the readonly role are rejected with 403.
+func RoleAuthorization() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("role").(string)
+			if role != RoleReadOnly || IsReadOnlyRequest(c) {
+				return next(c)
+			}
+			name, _ := c.Get("name").(string)
+			return echo.NewHTTPError(http.StatusForbidden,
+				fmt.Sprintf("the %s role is required for %s %s (user %s has the %s role)", RoleAdmin, c.Request().Method, c.Path(), name, RoleReadOnly))
+		}
+	}
+}
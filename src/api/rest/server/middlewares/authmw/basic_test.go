@@ -0,0 +1,65 @@
+package authmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestIsReadOnlyRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		route  string
+		target string
+		want   bool
+	}{
+		{"get mci", http.MethodGet, "/tumblebug/ns/:nsId/mci/:mciId", "/tumblebug/ns/default/mci/mci01", true},
+		{"terminate mci by get", http.MethodGet, "/tumblebug/ns/:nsId/control/mci/:mciId", "/tumblebug/ns/default/control/mci/mci01?action=terminate", false},
+		{"reboot vm by get", http.MethodGet, "/tumblebug/ns/:nsId/control/mci/:mciId/vm/:vmId", "/tumblebug/ns/default/control/mci/mci01/vm/vm01?action=reboot", false},
+		{"load assets", http.MethodGet, "/tumblebug/loadAssets", "/tumblebug/loadAssets", false},
+		{"export without secrets", http.MethodGet, "/tumblebug/ns/:nsId/export", "/tumblebug/ns/default/export", true},
+		{"export with secrets", http.MethodGet, "/tumblebug/ns/:nsId/export", "/tumblebug/ns/default/export?includeSecrets=true", false},
+		{"reveal private key", http.MethodGet, "/tumblebug/ns/:nsId/resources/sshKey/:resourceId", "/tumblebug/ns/default/resources/sshKey/key01?revealPrivateKey=TRUE", false},
+		{"masked private key", http.MethodGet, "/tumblebug/ns/:nsId/resources/sshKey/:resourceId", "/tumblebug/ns/default/resources/sshKey/key01?revealPrivateKey=false", true},
+		{"raw object", http.MethodGet, "/tumblebug/object", "/tumblebug/object?key=/ns/default/resources/sshKey/key01", false},
+		{"raw objects", http.MethodGet, "/tumblebug/objects", "/tumblebug/objects?key=/ns/default", false},
+		{"raw objects by head", http.MethodHead, "/tumblebug/objects", "/tumblebug/objects?key=/ns/default", false},
+		{"object stats", http.MethodGet, "/tumblebug/objects/stats", "/tumblebug/objects/stats", false},
+		{"read-only post", http.MethodPost, "/tumblebug/lookupSpecs", "/tumblebug/lookupSpecs", true},
+		{"create mci", http.MethodPost, "/tumblebug/ns/:nsId/mci", "/tumblebug/ns/default/mci", false},
+		{"delete mci", http.MethodDelete, "/tumblebug/ns/:nsId/mci/:mciId", "/tumblebug/ns/default/mci/mci01", false},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := e.NewContext(httptest.NewRequest(tt.method, tt.target, nil), httptest.NewRecorder())
+			c.SetPath(tt.route)
+			if got := IsReadOnlyRequest(c); got != tt.want {
+				t.Errorf("IsReadOnlyRequest(%s %s) = %t, want %t", tt.method, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleAuthorization(t *testing.T) {
+	e := echo.New()
+	handler := RoleAuthorization()(func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	for _, role := range []string{RoleAdmin, RoleReadOnly} {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/tumblebug/ns/default/control/mci/mci01?action=terminate", nil), httptest.NewRecorder())
+		c.SetPath("/tumblebug/ns/:nsId/control/mci/:mciId")
+		c.Set("role", role)
+		err := handler(c)
+		he, denied := err.(*echo.HTTPError)
+		if role == RoleReadOnly && (!denied || he.Code != http.StatusForbidden) {
+			t.Errorf("%s role: expected 403, got %v", role, err)
+		}
+		if role == RoleAdmin && err != nil {
+			t.Errorf("%s role: expected no error, got %v", role, err)
+		}
+	}
+}
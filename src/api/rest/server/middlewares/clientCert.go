@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/labstack/echo/v4"
+)
+
+// ClientCertCNKey is the context key for the common name (CN) of the verified client certificate (mutual TLS)
+const ClientCertCNKey = common.ClientCertCNKey
+
+// ClientCertIdentity makes the CN of the verified client certificate available to handlers via context
+func ClientCertIdentity(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if state := c.Request().TLS; state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+			c.Set(ClientCertCNKey, state.VerifiedChains[0][0].Subject.CommonName)
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// ClientIpExtractor returns the extractor of the client IP (c.RealIP) used by the rate limiter and the audit log.
+// Without trusted proxies, the IP of the connection is used and X-Forwarded-For / X-Real-IP are ignored
+// (they are set by the client). With trustedProxies (comma-separated CIDR blocks or IPs, e.g., TB_TRUSTED_PROXIES),
+// the client IP is taken from X-Forwarded-For only across the hops of the trusted proxies.
+func ClientIpExtractor(trustedProxies string) echo.IPExtractor {
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	trusted := 0
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Warn().Err(err).Msgf("ignored the invalid trusted proxy %s", proxy)
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+		trusted++
+	}
+	if trusted == 0 {
+		return echo.ExtractIPDirect()
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/labstack/echo/v4"
+)
+
+// Draining rejects new mutating requests (other than GET, HEAD and OPTIONS) with 503 and Retry-After
+// while the server is shutting down, so that in-flight operations can finish.
+func Draining(retryAfterSec int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !common.IsDraining() {
+				return next(c)
+			}
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfterSec))
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"message": "CB-Tumblebug is shutting down and does not accept new requests"})
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// Metrics records the count and latency of HTTP requests per route for the metrics endpoint
+func Metrics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		status := c.Response().Status
+		if err != nil {
+			if he, ok := err.(*echo.HTTPError); ok {
+				status = he.Code
+			}
+		}
+		// use the registered route path (e.g., /tumblebug/ns/:nsId/mci) to keep the cardinality low
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHttpRequest(c.Request().Method, route, status, time.Since(start))
+		return err
+	}
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/labstack/echo/v4"
+)
+
+// trackedObject returns the object (e.g., /ns/default/mci/mci01) changed by the request, or an empty string
+func trackedObject(c echo.Context) string {
+	nsId := c.Param("nsId")
+	if nsId == "" {
+		return ""
+	}
+	if mciId := c.Param("mciId"); mciId != "" {
+		if vmId := c.Param("vmId"); vmId != "" {
+			return "/ns/" + nsId + "/mci/" + mciId + "/vm/" + vmId
+		}
+		return "/ns/" + nsId + "/mci/" + mciId
+	}
+	if k8sClusterId := c.Param("k8sClusterId"); k8sClusterId != "" {
+		return "/ns/" + nsId + "/k8scluster/" + k8sClusterId
+	}
+	if vNetId := c.Param("vNetId"); vNetId != "" {
+		return "/ns/" + nsId + "/resources/vNet/" + vNetId
+	}
+
+	// for creation, the object name is given in the request body
+	if c.Request().Method != http.MethodPost || c.Request().Body == nil {
+		return ""
+	}
+	objectType := ""
+	switch {
+	case strings.HasSuffix(c.Path(), "/:nsId/mci"), strings.HasSuffix(c.Path(), "/:nsId/mciDynamic"):
+		objectType = "mci"
+	case strings.HasSuffix(c.Path(), "/:nsId/k8scluster"), strings.HasSuffix(c.Path(), "/:nsId/k8sclusterDynamic"):
+		objectType = "k8scluster"
+	case strings.HasSuffix(c.Path(), "/:nsId/resources/vNet"):
+		objectType = "resources/vNet"
+	default:
+		return ""
+	}
+	bodyBytes, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return ""
+	}
+	// Write the body back for further processing
+	c.Request().Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	var body struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(bodyBytes, &body) != nil || body.Name == "" {
+		return ""
+	}
+	return "/ns/" + nsId + "/" + objectType + "/" + body.Name
+}
+
+// ObjectRequestTracker records the request which is changing an MCI, VM, K8sCluster or vNet,
+// so that webhook events and namespace events from the object carry the X-Request-Id and the user of the request.
+func ObjectRequestTracker(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		target := trackedObject(c)
+		if target == "" {
+			return next(c)
+		}
+		done := common.TrackObjectRequest(target, c.Request().Header.Get(echo.HeaderXRequestID))
+		defer done()
+		doneActor := common.TrackObjectActor(target, func() string { return common.RequestUser(c) })
+		defer doneActor()
+		return next(c)
+	}
+}
@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitConfig is the configuration of the rate limiter.
+// Rates are requests per second for each client IP; a rate of 0 disables the limit for the class of routes.
+type RateLimitConfig struct {
+	ReadRate    float64  // rate for read routes (GET, HEAD, OPTIONS)
+	ReadBurst   int      // burst for read routes
+	WriteRate   float64  // rate for mutating routes (POST, PUT, PATCH, DELETE)
+	WriteBurst  int      // burst for mutating routes
+	ExemptPaths []string // routes which are not limited (e.g., /tumblebug/readyz)
+}
+
+// rateLimitClientId returns the identity of the client for rate limiting.
+// The limiter runs before authentication, so the credentials in the request (basic auth username,
+// API key name or JWT subject) are not verified yet and must not be used; the client IP (see ClientIpExtractor) is used instead.
+func rateLimitClientId(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// newRateLimiterStore returns an in-memory store of per-client buckets (nil if the rate is disabled)
+func newRateLimiterStore(r float64, burst int) *middleware.RateLimiterMemoryStore {
+	if r <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(r))
+	}
+	return middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(r),
+		Burst:     burst,
+		ExpiresIn: 3 * time.Minute,
+	})
+}
+
+// RateLimit limits requests per client IP with separate buckets for read and mutating routes.
+// Requests over the limit are rejected with 429 and Retry-After.
+func RateLimit(config RateLimitConfig) echo.MiddlewareFunc {
+	readStore := newRateLimiterStore(config.ReadRate, config.ReadBurst)
+	writeStore := newRateLimiterStore(config.WriteRate, config.WriteBurst)
+
+	exempt := map[string]bool{}
+	for _, path := range config.ExemptPaths {
+		exempt[path] = true
+	}
+
+	retryAfter := func(r float64) string {
+		return strconv.Itoa(int(math.Max(1, math.Ceil(1/r))))
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if exempt[c.Path()] {
+				return next(c)
+			}
+
+			store, r, class := writeStore, config.WriteRate, "mutating"
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				store, r, class = readStore, config.ReadRate, "read"
+			}
+			if store == nil {
+				return next(c)
+			}
+
+			clientId := rateLimitClientId(c)
+			allowed, err := store.Allow(clientId)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to check the rate limit")
+				return next(c)
+			}
+			if !allowed {
+				c.Response().Header().Set("Retry-After", retryAfter(r))
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"message": fmt.Sprintf("Too many %s requests from %s (limit: %g req/s)", class, clientId, r),
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newRateLimitTestServer(config RateLimitConfig) *echo.Echo {
+	return newRateLimitTestServerWithProxies(config, "")
+}
+
+func newRateLimitTestServerWithProxies(config RateLimitConfig, trustedProxies string) *echo.Echo {
+	e := echo.New()
+	e.IPExtractor = ClientIpExtractor(trustedProxies)
+	e.Use(RateLimit(config))
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "ok") }
+	e.GET("/tumblebug/ns", ok)
+	e.POST("/tumblebug/ns", ok)
+	e.GET("/tumblebug/readyz", ok)
+	return e
+}
+
+func doRateLimitRequest(e *echo.Echo, method, path, remoteAddr string, setHeader func(*http.Request)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	if setHeader != nil {
+		setHeader(req)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestRateLimitIgnoresUnverifiedIdentities checks that rotating usernames, API keys or JWT subjects
+// in the request does not give a client a fresh bucket
+func TestRateLimitIgnoresUnverifiedIdentities(t *testing.T) {
+	identities := []struct {
+		name      string
+		setHeader func(*http.Request, int)
+	}{
+		{"basic auth username", func(r *http.Request, i int) { r.SetBasicAuth("user"+strconv.Itoa(i), "wrong") }},
+		{"api key name", func(r *http.Request, i int) { r.Header.Set("X-API-Key", "tb_key"+strconv.Itoa(i)+"_secret") }},
+		// unsigned tokens with different subjects ({"sub":"a"} and {"sub":"b"})
+		{"jwt subject", func(r *http.Request, i int) {
+			tokens := []string{
+				"eyJhbGciOiJub25lIn0.eyJzdWIiOiJhIn0.",
+				"eyJhbGciOiJub25lIn0.eyJzdWIiOiJiIn0.",
+			}
+			r.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens[i%len(tokens)])
+		}},
+	}
+
+	for _, tc := range identities {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newRateLimitTestServer(RateLimitConfig{ReadRate: 0.001, ReadBurst: 2})
+			for i := 0; i < 2; i++ {
+				i := i
+				rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.1:1234", func(r *http.Request) { tc.setHeader(r, i) })
+				if rec.Code != http.StatusOK {
+					t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+				}
+			}
+			rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.1:1234", func(r *http.Request) { tc.setHeader(r, 2) })
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("expected 429 for a new identity from the same IP, got %d", rec.Code)
+			}
+			if rec.Header().Get("Retry-After") == "" {
+				t.Errorf("expected Retry-After header on 429")
+			}
+		})
+	}
+}
+
+func TestRateLimitPerClientIp(t *testing.T) {
+	e := newRateLimitTestServer(RateLimitConfig{ReadRate: 0.001, ReadBurst: 1})
+
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.1:1234", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.1:5678", nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for the same IP, got %d", rec.Code)
+	}
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.2:1234", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for another IP, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitReadAndWriteBuckets(t *testing.T) {
+	e := newRateLimitTestServer(RateLimitConfig{ReadRate: 0.001, ReadBurst: 1, WriteRate: 0.001, WriteBurst: 1})
+
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.1:1234", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for read, got %d", rec.Code)
+	}
+	if rec := doRateLimitRequest(e, http.MethodPost, "/tumblebug/ns", "10.0.0.1:1234", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for write (separate bucket), got %d", rec.Code)
+	}
+	if rec := doRateLimitRequest(e, http.MethodPost, "/tumblebug/ns", "10.0.0.1:1234", nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for second write, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitExemptPaths(t *testing.T) {
+	e := newRateLimitTestServer(RateLimitConfig{
+		ReadRate:    0.001,
+		ReadBurst:   1,
+		ExemptPaths: []string{"/tumblebug/readyz"},
+	})
+
+	for i := 0; i < 5; i++ {
+		if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/readyz", "10.0.0.1:1234", nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 for exempt path, got %d", i, rec.Code)
+		}
+	}
+}
+
+// TestRateLimitIgnoresSpoofedForwardedFor checks that rotating X-Forwarded-For or X-Real-IP
+// does not give a client a fresh bucket unless the request comes from a trusted proxy
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	e := newRateLimitTestServer(RateLimitConfig{ReadRate: 0.001, ReadBurst: 1})
+
+	spoof := func(i int) func(*http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set(echo.HeaderXForwardedFor, "203.0.113."+strconv.Itoa(i))
+			r.Header.Set(echo.HeaderXRealIP, "198.51.100."+strconv.Itoa(i))
+		}
+	}
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.1:1234", spoof(1)); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	for i := 2; i < 5; i++ {
+		if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.1:1234", spoof(i)); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("request %d: expected 429 for a spoofed X-Forwarded-For, got %d", i, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitTrustedProxy(t *testing.T) {
+	e := newRateLimitTestServerWithProxies(RateLimitConfig{ReadRate: 0.001, ReadBurst: 1}, "10.0.0.100, 192.0.2.0/24")
+
+	forwardedFor := func(xff string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set(echo.HeaderXForwardedFor, xff) }
+	}
+	// the clients behind the trusted proxy have their own buckets
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.100:1234", forwardedFor("203.0.113.1")); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.100:1234", forwardedFor("203.0.113.2")); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for another client behind the trusted proxy, got %d", rec.Code)
+	}
+	// a client prepending an address to X-Forwarded-For is identified by the address the proxy appended
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.100:1234", forwardedFor("198.51.100.7, 203.0.113.1")); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for a spoofed address before the proxy hop, got %d", rec.Code)
+	}
+	// X-Forwarded-For from an untrusted peer is ignored
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.5:1234", forwardedFor("203.0.113.3")); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := doRateLimitRequest(e, http.MethodGet, "/tumblebug/ns", "10.0.0.5:1234", forwardedFor("203.0.113.4")); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for an untrusted peer, got %d", rec.Code)
+	}
+}
@@ -33,7 +33,7 @@ func RequestIdAndDetailsIssuer(next echo.HandlerFunc) echo.HandlerFunc {
 
 		details := common.RequestDetails{
 			StartTime:   time.Now(),
-			Status:      "Handling",
+			Status:      common.RequestStatusHandling,
 			RequestInfo: common.ExtractRequestInfo(c.Request()),
 		}
 		common.RequestMap.Store(reqID, details)
@@ -3,6 +3,7 @@ package middlewares
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
@@ -17,6 +18,18 @@ func ResponseBodyDump() echo.MiddlewareFunc {
 			if c.Path() == "/tumblebug/api" {
 				return true
 			}
+			// Skip dumping long-lived event streams
+			if c.Path() == "/tumblebug/stream-response/ns/:nsId/events" {
+				return true
+			}
+			// Skip dumping forwarded responses which are streamed and may be large
+			if c.Path() == "/tumblebug/forward/*" {
+				return true
+			}
+			// Skip dumping sensitive data such as kubeconfig
+			if c.Path() == "/tumblebug/ns/:nsId/k8scluster/:k8sClusterId/kubeconfig" {
+				return true
+			}
 			return false
 		},
 		Handler: func(c echo.Context, reqBody, resBody []byte) {
@@ -33,54 +46,68 @@ func ResponseBodyDump() echo.MiddlewareFunc {
 			// log.Debug().Msgf("Request body: %s", string(reqBody))
 			// log.Debug().Msgf("Response body: %s", string(resBody))
 
-			// Dump the response body if content type is "application/json" or "application/json; charset=UTF-8"
-			if contentType == echo.MIMEApplicationJSONCharsetUTF8 || contentType == echo.MIMEApplicationJSON {
-				// Load or check the request by ID
-				v, ok := common.RequestMap.Load(reqID)
-				if !ok {
-					log.Error().Msg("Request ID not found in common.RequestMap")
-					return
-				}
+			// Load or check the request by ID
+			v, ok := common.RequestMap.Load(reqID)
+			if !ok {
+				log.Error().Msg("Request ID not found in common.RequestMap")
+				return
+			}
 
-				// Ensure the loaded value is of the correct type
-				details, ok := v.(common.RequestDetails)
-				if !ok {
-					log.Error().Msg("Loaded value from common.RequestMap is not of type common.RequestDetails")
-					return
-				}
-				//log.Trace().Msg("OK, common.RequestMap.Load(reqID)")
-				details.EndTime = time.Now()
+			// Ensure the loaded value is of the correct type
+			details, ok := v.(common.RequestDetails)
+			if !ok {
+				log.Error().Msg("Loaded value from common.RequestMap is not of type common.RequestDetails")
+				return
+			}
+			//log.Trace().Msg("OK, common.RequestMap.Load(reqID)")
+			details.EndTime = time.Now()
+			details.DurationMs = details.EndTime.Sub(details.StartTime).Milliseconds()
+			details.ResponseSize = len(resBody)
+
+			// Check and store error response
+			// 1XX: Information responses
+			// 2XX: Successful responses (200 OK, 201 Created, 202 Accepted, 204 No Content)
+			// 3XX: Redirection messages
+			// 4XX: Client error responses (400 Bad Request, 401 Unauthorized, 404 Not Found, 408 Request Timeout)
+			// 5XX: Server error responses (500 Internal Server Error, 501 Not Implemented, 503 Service Unavailable)
+			details.Status = common.RequestStatusSuccess
+			if c.Response().Status >= 400 {
+				details.Status = common.RequestStatusError
+			}
 
+			// Dump the response body if content type is "application/json" or "application/json; charset=UTF-8".
+			// Sensitive fields (e.g., privateKey) are redacted before being stored.
+			// Bodies which cannot be redacted (non-JSON, invalid or too large to parse) are not stored.
+			if contentType == echo.MIMEApplicationJSONCharsetUTF8 || contentType == echo.MIMEApplicationJSON {
 				// Set "X-Request-Id" in response header
 				c.Response().Header().Set(echo.HeaderXRequestID, reqID)
 
-				// Split the response body by newlines to handle multiple JSON objects (i.e., streaming response)
-				parts := bytes.Split(resBody, []byte("\n"))
-				if len(parts) == 0 {
+				if len(resBody) == 0 {
 					log.Error().Msg("Response body is empty")
 					return
 				}
-				responseJsonLines := parts[:len(parts)-1]
+				if maxBytes := common.ResponseDumpMaxBytes(); len(resBody) > maxBytes {
+					details.ResponseData = common.OmittedResponseBody(contentType, len(resBody), fmt.Sprintf("larger than %d bytes", maxBytes))
+					common.RequestMap.Store(reqID, details)
+					return
+				}
+				// Split the response body by newlines to handle multiple JSON objects (i.e., streaming response)
+				responseJsonLines := bytes.Split(bytes.TrimRight(resBody, "\n"), []byte("\n"))
 
 				// Unmarshal the latest response body
 				latestResponse := responseJsonLines[len(responseJsonLines)-1]
 				var resData interface{}
 				if err := json.Unmarshal(latestResponse, &resData); err != nil {
 					log.Error().Err(err).Msg("Error while unmarshaling response body")
+					// the body is not stored since its fields cannot be redacted
+					details.ResponseData = common.OmittedResponseBody(contentType, len(resBody), "invalid JSON")
+					common.RequestMap.Store(reqID, details)
 					return
 				}
 
-				// Check and store error response
-				// 1XX: Information responses
-				// 2XX: Successful responses (200 OK, 201 Created, 202 Accepted, 204 No Content)
-				// 3XX: Redirection messages
-				// 4XX: Client error responses (400 Bad Request, 401 Unauthorized, 404 Not Found, 408 Request Timeout)
-				// 5XX: Server error responses (500 Internal Server Error, 501 Not Implemented, 503 Service Unavailable)
-				details.Status = "Success"
-				if c.Response().Status >= 400 {
-					details.Status = "Error"
+				if details.Status == common.RequestStatusError {
 					if data, ok := resData.(map[string]interface{}); ok {
-						details.ErrorResponse = data["message"].(string)
+						details.ErrorResponse, _ = data["message"].(string)
 					}
 				}
 
@@ -98,25 +125,24 @@ func ResponseBodyDump() echo.MiddlewareFunc {
 						}
 						responseJsonArray = append(responseJsonArray, obj)
 					}
-					details.ResponseData = responseJsonArray
+					details.ResponseData = common.RedactResponseData(responseJsonArray)
 				} else {
 					// single response
 					// type casting is required
 					switch data := resData.(type) {
-					case map[string]interface{}:
-						details.ResponseData = data
-					case []interface{}:
-						details.ResponseData = data
-					case string:
-						details.ResponseData = data
+					case map[string]interface{}, []interface{}, string:
+						details.ResponseData = common.RedactResponseData(data)
 					default:
 						log.Error().Msgf("unexpected response data type (%T)", data)
 					}
 				}
-
-				// Store details of the request
-				common.RequestMap.Store(reqID, details)
+			} else if len(resBody) > 0 {
+				// Non-JSON responses (e.g., a private key or kubeconfig as text) cannot be redacted, so only their size is stored
+				details.ResponseData = common.OmittedResponseBody(contentType, len(resBody), "not JSON")
 			}
+
+			// Store details of the request
+			common.RequestMap.Store(reqID, details)
 			// log.Debug().Msg("Start - BodyDump() middleware")
 		},
 	})
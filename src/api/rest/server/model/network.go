@@ -66,6 +66,11 @@ type RestPostVpnRequest struct {
 	Site2 SiteDetail `json:"site2"`
 }
 
+// RestPutVpnRequest is the desired sites of a VPN (the sites are added or removed incrementally)
+type RestPutVpnRequest struct {
+	Sites []SiteDetail `json:"sites"`
+}
+
 type Response struct {
 	Success bool                   `json:"success" example:"true"`
 	Status  int                    `json:"status,omitempty" example:"200"`
@@ -62,6 +62,14 @@ func RestDelResource(c echo.Context) error {
 
 	forceFlag := c.QueryParam("force")
 
+	// move to the trash (keeping the CSP resource) if soft delete applies to the resource type
+	permanent := c.QueryParam("permanent")
+	if common.UseSoftDelete(permanent) && (resource.IsTrashableResourceType(resourceType) || permanent != "") {
+		item, err := resource.TrashResource(nsId, resourceType, resourceId)
+		content := map[string]string{"message": "The " + resourceType + " " + resourceId + " has been moved to the trash (" + item.Id + ")"}
+		return common.EndRequestWithLog(c, err, content)
+	}
+
 	err := resource.DelResource(nsId, resourceType, resourceId, forceFlag)
 	content := map[string]string{"message": "The " + resourceType + " " + resourceId + " has been deleted"}
 	return common.EndRequestWithLog(c, err, content)
@@ -115,11 +123,32 @@ func RestGetAllResources(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, content)
 	} else {
 
+		listOption, err := common.ParseListOption(c.QueryParam("limit"), c.QueryParam("offset"), c.QueryParam("nextToken"), c.QueryParam("fields"), c.QueryParam("excludeKeyValue"))
+		if err != nil {
+			return common.EndRequestWithLog(c, err, nil)
+		}
+
+		// skip the status update from the CSPs if the stored resources are not changed since the client got them
+		if revision, err := resource.GetResourceListRevision(nsId, resourceType); err == nil && common.IsNotModified(c, revision) {
+			return common.EndRequestNotModified(c, revision)
+		}
+
+		if listOption.IsSet() {
+			resourceList, page, err := resource.ListResourceWithOption(nsId, resourceType, filterKey, filterVal, listOption)
+			if err != nil {
+				err := fmt.Errorf("Failed to list " + resourceType + "s; " + err.Error())
+				return common.EndRequestWithLog(c, err, nil)
+			}
+			revision, _ := resource.GetResourceListRevision(nsId, resourceType)
+			return common.EndRequestWithRevision(c, revision, nil, common.NewListResponse(resourceType, resourceList, page))
+		}
+
 		resourceList, err := resource.ListResource(nsId, resourceType, filterKey, filterVal)
 		if err != nil {
 			err := fmt.Errorf("Failed to list " + resourceType + "s; " + err.Error())
 			return common.EndRequestWithLog(c, err, nil)
 		}
+		revision, _ := resource.GetResourceListRevision(nsId, resourceType)
 
 		switch resourceType {
 		case model.StrImage:
@@ -128,49 +157,69 @@ func RestGetAllResources(c echo.Context) error {
 			}
 
 			content.Image = resourceList.([]model.TbImageInfo) // type assertion (interface{} -> array)
-			return common.EndRequestWithLog(c, err, content)
+			return common.EndRequestWithRevision(c, revision, err, content)
 		case model.StrCustomImage:
 			var content struct {
 				Image []model.TbCustomImageInfo `json:"customImage"`
 			}
 
 			content.Image = resourceList.([]model.TbCustomImageInfo) // type assertion (interface{} -> array)
-			return common.EndRequestWithLog(c, err, content)
+			return common.EndRequestWithRevision(c, revision, err, content)
 		case model.StrSecurityGroup:
 			var content struct {
 				SecurityGroup []model.TbSecurityGroupInfo `json:"securityGroup"`
 			}
 
 			content.SecurityGroup = resourceList.([]model.TbSecurityGroupInfo) // type assertion (interface{} -> array)
-			return common.EndRequestWithLog(c, err, content)
+			return common.EndRequestWithRevision(c, revision, err, content)
 		case model.StrSpec:
 			var content struct {
 				Spec []model.TbSpecInfo `json:"spec"`
 			}
 
 			content.Spec = resourceList.([]model.TbSpecInfo) // type assertion (interface{} -> array)
-			return common.EndRequestWithLog(c, err, content)
+			return common.EndRequestWithRevision(c, revision, err, content)
 		case model.StrSSHKey:
 			var content struct {
 				SshKey []model.TbSshKeyInfo `json:"sshKey"`
 			}
 
 			content.SshKey = resourceList.([]model.TbSshKeyInfo) // type assertion (interface{} -> array)
-			return common.EndRequestWithLog(c, err, content)
+			for i := range content.SshKey {
+				resource.MaskSshKeyInfo(&content.SshKey[i])
+			}
+			return common.EndRequestWithRevision(c, revision, err, content)
 		case model.StrVNet:
 			var content struct {
 				VNet []model.TbVNetInfo `json:"vNet"`
 			}
 
 			content.VNet = resourceList.([]model.TbVNetInfo) // type assertion (interface{} -> array)
-			return common.EndRequestWithLog(c, err, content)
+			return common.EndRequestWithRevision(c, revision, err, content)
 		case model.StrDataDisk:
 			var content struct {
 				DataDisk []model.TbDataDiskInfo `json:"dataDisk"`
 			}
 
 			content.DataDisk = resourceList.([]model.TbDataDiskInfo) // type assertion (interface{} -> array)
-			return common.EndRequestWithLog(c, err, content)
+			return common.EndRequestWithRevision(c, revision, err, content)
+		case model.StrObjectStorage:
+			var content struct {
+				ObjectStorage []model.TbObjectStorageInfo `json:"objectStorage"`
+			}
+
+			content.ObjectStorage = resourceList.([]model.TbObjectStorageInfo) // type assertion (interface{} -> array)
+			return common.EndRequestWithRevision(c, revision, err, content)
+		case model.StrSqlDb:
+			var content struct {
+				SqlDb []model.TbSqlDbInfo `json:"sqlDb"`
+			}
+
+			content.SqlDb = resourceList.([]model.TbSqlDbInfo) // type assertion (interface{} -> array)
+			for i := range content.SqlDb {
+				resource.MaskSqlDbInfo(&content.SqlDb[i])
+			}
+			return common.EndRequestWithRevision(c, revision, err, content)
 		default:
 			err := fmt.Errorf("Not accepatble resourceType: " + resourceType)
 			return common.EndRequestWithLog(c, err, nil)
@@ -193,12 +242,28 @@ func RestGetResource(c echo.Context) error {
 	resourceId = strings.ReplaceAll(resourceId, " ", "+")
 	resourceId = strings.ReplaceAll(resourceId, "%2B", "+")
 
+	// skip the status update from the CSP if the stored resource is not changed since the client got it
+	if revision, err := resource.GetResourceRevision(nsId, resourceType, resourceId); err == nil && common.IsNotModified(c, revision) {
+		return common.EndRequestNotModified(c, revision)
+	}
+
 	result, err := resource.GetResource(nsId, resourceType, resourceId)
 	if err != nil {
 		errorMessage := fmt.Errorf("Failed to find " + resourceType + " " + resourceId)
 		return common.EndRequestWithLog(c, errorMessage, nil)
 	}
-	return common.EndRequestWithLog(c, err, result)
+	// The private key is returned only with ?revealPrivateKey=true (the request is audited)
+	if sshKey, ok := result.(model.TbSshKeyInfo); ok && c.QueryParam("revealPrivateKey") != "true" {
+		resource.MaskSshKeyInfo(&sshKey)
+		result = sshKey
+	}
+	// The admin password of sqlDb is returned only with ?revealPassword=true
+	if sqlDb, ok := result.(model.TbSqlDbInfo); ok && c.QueryParam("revealPassword") != "true" {
+		resource.MaskSqlDbInfo(&sqlDb)
+		result = sqlDb
+	}
+	revision, _ := resource.GetResourceRevision(nsId, resourceType, resourceId)
+	return common.EndRequestWithRevision(c, revision, err, result)
 }
 
 // RestCheckResource godoc
@@ -321,7 +386,7 @@ func RestCreateSharedResource(c echo.Context) error {
 	// default of connectionConfig is empty string. with empty string, register all resources.
 	connectionName := c.QueryParam("connectionName")
 
-	err := resource.CreateSharedResource(nsId, resType, connectionName)
+	err := resource.CreateSharedResource(common.NewRequestContext(c), nsId, resType, connectionName)
 	content := map[string]string{"message": "Done"}
 	return common.EndRequestWithLog(c, err, content)
 }
@@ -345,6 +410,49 @@ func RestDelAllSharedResources(c echo.Context) error {
 	return common.EndRequestWithLog(c, err, content)
 }
 
+// RestPutNsResourceDefaults godoc
+// @ID PutNsResourceDefaults
+// @Summary Set defaults of shared resources in namespace
+// @Description Set the defaults of shared resources (vNet CIDR supernet, default securityGroup rules and sshKey naming convention)
+// @Description used by sharedResource creation and dynamic provisioning in the namespace. Empty fields follow the global defaults.
+// @Description The defaults apply only to shared resources created afterwards.
+// @Tags [Infra Resource] Common Utility
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param resourceDefaults body model.NsResourceDefaults true "Defaults of shared resources in the namespace"
+// @Success 200 {object} model.NsResourceDefaultsInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resourceDefaults [put]
+func RestPutNsResourceDefaults(c echo.Context) error {
+
+	u := &model.NsResourceDefaults{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.UpdateNsResourceDefaults(c.Param("nsId"), u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetNsResourceDefaults godoc
+// @ID GetNsResourceDefaults
+// @Summary Get defaults of shared resources in namespace
+// @Description Get the defaults of shared resources set for the namespace and the effective defaults (namespace override merged over global)
+// @Tags [Infra Resource] Common Utility
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Success 200 {object} model.NsResourceDefaultsInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resourceDefaults [get]
+func RestGetNsResourceDefaults(c echo.Context) error {
+
+	content, err := resource.GetNsResourceDefaults(c.Param("nsId"))
+	return common.EndRequestWithLog(c, err, content)
+}
+
 /*
 // Request structure for RestRegisterExistingResources
 type RestRegisterExistingResourcesRequest struct {
@@ -57,6 +57,38 @@ func RestPostCustomImage(c echo.Context) error {
 	return common.EndRequestWithLog(c, err, content)
 }
 
+// RestPostCustomImageCopy godoc
+// @ID PostCustomImageCopy
+// @Summary Copy a Custom Image to another region
+// @Description Copy a Custom Image to the region of the target connection (supported for AWS, GCP and Azure).
+// @Description The copied Custom Image is returned with the status Copying, and becomes Available when the copy is finished.
+// @Description The copy requires the MyImage copy API of CB-Spider (412 is returned if the detected CB-Spider does not provide it).
+// @Tags [Infra Resource] Image Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param customImageId path string true "customImage ID"
+// @Param customImageCopyReq body model.TbCustomImageCopyReq true "Request to copy the Custom Image"
+// @Success 200 {object} model.TbCustomImageInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 412 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Failure 501 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/customImage/{customImageId}/copy [post]
+func RestPostCustomImageCopy(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	customImageId := c.Param("resourceId")
+
+	u := &model.TbCustomImageCopyReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.CopyCustomImage(nsId, customImageId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
 // RestGetCustomImage godoc
 // @ID GetCustomImage
 // @Summary Get customImage
@@ -66,7 +98,10 @@ func RestPostCustomImage(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param customImageId path string true "customImage ID"
+// @Param If-None-Match header string false "ETag of the resource from the previous response; 304 is returned if the resource is not changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSP" default(false)
 // @Success 200 {object} model.TbCustomImageInfo
+// @Success 304 "The resource is not changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/customImage/{customImageId} [get]
@@ -91,7 +126,15 @@ type RestGetAllCustomImageResponse struct {
 // @Param option query string false "Option" Enums(id)
 // @Param filterKey query string false "Field key for filtering (ex:guestOS)"
 // @Param filterVal query string false "Field value for filtering (ex: Ubuntu18.04)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
 // @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllCustomImageResponse,[ID]=model.IdList} "Different return structures by the given option param"
+// @Success 304 "No item is changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/customImage [get]
@@ -50,7 +50,58 @@ func RestPostDataDisk(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	content, err := resource.CreateDataDisk(nsId, u, optionFlag)
+	content, err := resource.CreateDataDisk(common.NewRequestContext(c), nsId, u, optionFlag)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostRegisterDataDisk godoc
+// @ID PostRegisterDataDisk
+// @Summary Register Data Disk (created in CSP)
+// @Description Register the Data Disk, which was created in CSP, with its current attachment state.
+// @Description If the Data Disk is attached to a VM registered in the namespace, the Data Disk is linked to the VM.
+// @Description The request body can be taken from registerHint of the dataDisk in onCspOnly of inspectResources.
+// @Tags [Infra Resource] Data Disk Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param dataDiskRegisterReq body model.TbRegisterDataDiskReq true "Information required to register the Data Disk created externally"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Success 200 {object} model.TbDataDiskInfo
+// @Failure 400 {object} model.ValidationErrorMsg
+// @Failure 500 {object} model.ErrorResponse
+// @Router /ns/{nsId}/registerCspResource/dataDisk [post]
+func RestPostRegisterDataDisk(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	u := &model.TbRegisterDataDiskReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := infra.RegisterDataDisk(common.NewRequestContext(c), nsId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDeleteDeregisterDataDisk godoc
+// @ID DeleteDeregisterDataDisk
+// @Summary Deregister Data Disk (created in CSP)
+// @Description Deregister the Data Disk from CB-Tumblebug and CB-Spider. The Data Disk in CSP is not deleted (nor detached).
+// @Tags [Infra Resource] Data Disk Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param dataDiskId path string true "Data Disk ID"
+// @Success 200 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /ns/{nsId}/deregisterCspResource/dataDisk/{dataDiskId} [delete]
+func RestDeleteDeregisterDataDisk(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	dataDiskId := c.Param("dataDiskId")
+
+	content, err := infra.DeregisterDataDisk(nsId, dataDiskId)
 	return common.EndRequestWithLog(c, err, content)
 }
 
@@ -82,6 +133,142 @@ func RestPutDataDisk(c echo.Context) error {
 	return common.EndRequestWithLog(c, err, content)
 }
 
+// RestPutDataDiskSize godoc
+// @ID PutDataDiskSize
+// @Summary Resize Data Disk
+// @Description Grow Data Disk to the given size (GB). An attached Data Disk can be resized only for the providers which support online resize.
+// @Description filesystemExpansionRequired in the result tells whether the filesystem must be expanded in the guest OS.
+// @Tags [Infra Resource] Data Disk Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param dataDiskId path string true "DataDisk ID"
+// @Param dataDiskResizeReq body model.TbDataDiskResizeReq true "Request body to resize the dataDisk"
+// @Success 200 {object} model.TbDataDiskResizeResult
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/dataDisk/{dataDiskId}/size [put]
+func RestPutDataDiskSize(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	dataDiskId := c.Param("resourceId")
+
+	u := &model.TbDataDiskResizeReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.ResizeDataDisk(nsId, dataDiskId, u.DiskSize)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostDataDiskSnapshot godoc
+// @ID PostDataDiskSnapshot
+// @Summary Create a snapshot of Data Disk
+// @Description Create a snapshot of Data Disk. An attached Data Disk requires force=true since its data may not be consistent.
+// @Tags [Infra Resource] Data Disk Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param dataDiskId path string true "DataDisk ID"
+// @Param dataDiskSnapshotReq body model.TbDataDiskSnapshotReq true "Request body to create a snapshot of the dataDisk"
+// @Success 200 {object} model.TbDataDiskSnapshotInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/dataDisk/{dataDiskId}/snapshot [post]
+func RestPostDataDiskSnapshot(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	dataDiskId := c.Param("resourceId")
+
+	u := &model.TbDataDiskSnapshotReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.SnapshotDataDisk(nsId, dataDiskId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetAllDataDiskSnapshot godoc
+// @ID GetAllDataDiskSnapshot
+// @Summary List all snapshots of Data Disk
+// @Description List all snapshots of Data Disk
+// @Tags [Infra Resource] Data Disk Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param dataDiskId path string true "DataDisk ID"
+// @Success 200 {object} model.TbDataDiskSnapshotListResponse
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/dataDisk/{dataDiskId}/snapshot [get]
+func RestGetAllDataDiskSnapshot(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	dataDiskId := c.Param("resourceId")
+
+	content, err := resource.ListDataDiskSnapshots(nsId, dataDiskId)
+	return common.EndRequestWithLog(c, err, model.TbDataDiskSnapshotListResponse{Snapshot: content})
+}
+
+// RestDelDataDiskSnapshot godoc
+// @ID DelDataDiskSnapshot
+// @Summary Delete a snapshot of Data Disk
+// @Description Delete a snapshot of Data Disk
+// @Tags [Infra Resource] Data Disk Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param dataDiskId path string true "DataDisk ID"
+// @Param snapshotId path string true "Snapshot ID"
+// @Success 200 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/dataDisk/{dataDiskId}/snapshot/{snapshotId} [delete]
+func RestDelDataDiskSnapshot(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	dataDiskId := c.Param("resourceId")
+	snapshotId := c.Param("snapshotId")
+
+	err := resource.DeleteDataDiskSnapshot(nsId, dataDiskId, snapshotId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	content := model.SimpleMsg{Message: "The snapshot " + snapshotId + " has been deleted"}
+	return common.EndRequestWithLog(c, nil, content)
+}
+
+// RestPostDataDiskFromSnapshot godoc
+// @ID PostDataDiskFromSnapshot
+// @Summary Create Data Disk from a snapshot
+// @Description Create a new Data Disk from a snapshot of Data Disk (in the region of the snapshot)
+// @Tags [Infra Resource] Data Disk Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param dataDiskId path string true "DataDisk ID"
+// @Param snapshotId path string true "Snapshot ID"
+// @Param dataDiskFromSnapshotReq body model.TbDataDiskFromSnapshotReq true "Request body to create a dataDisk from the snapshot"
+// @Success 200 {object} model.TbDataDiskInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/dataDisk/{dataDiskId}/snapshot/{snapshotId}/dataDisk [post]
+func RestPostDataDiskFromSnapshot(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	dataDiskId := c.Param("resourceId")
+	snapshotId := c.Param("snapshotId")
+
+	u := &model.TbDataDiskFromSnapshotReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.CreateDataDiskFromSnapshot(common.NewRequestContext(c), nsId, dataDiskId, snapshotId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
 // RestGetDataDisk godoc
 // @ID GetDataDisk
 // @Summary Get Data Disk
@@ -91,7 +278,10 @@ func RestPutDataDisk(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param dataDiskId path string true "Data Disk ID"
+// @Param If-None-Match header string false "ETag of the resource from the previous response; 304 is returned if the resource is not changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSP" default(false)
 // @Success 200 {object} model.TbDataDiskInfo
+// @Success 304 "The resource is not changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/dataDisk/{dataDiskId} [get]
@@ -116,7 +306,15 @@ type RestGetAllDataDiskResponse struct {
 // @Param option query string false "Option" Enums(id)
 // @Param filterKey query string false "Field key for filtering (ex: systemLabel)"
 // @Param filterVal query string false "Field value for filtering (ex: Registered from CSP resource)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
 // @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllDataDiskResponse,[ID]=model.IdList} "Different return structures by the given option param"
+// @Success 304 "No item is changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/dataDisk [get]
@@ -134,6 +332,7 @@ func RestGetAllDataDisk(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param dataDiskId path string true "Data Disk ID"
+// @Param permanent query bool false "Delete permanently (true) or move to the trash keeping the CSP resource (false); the default is set by TB_SOFT_DELETE"
 // @Success 200 {object} model.SimpleMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/dataDisk/{dataDiskId} [delete]
@@ -236,7 +435,7 @@ func RestPostVmDataDisk(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	result, err := infra.ProvisionDataDisk(nsId, mciId, vmId, u)
+	result, err := infra.ProvisionDataDisk(common.NewRequestContext(c), nsId, mciId, vmId, u)
 	if err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
@@ -148,11 +148,12 @@ func RestLookupImage(c echo.Context) error {
 // RestLookupImageList godoc
 // @ID LookupImageList
 // @Summary Lookup image list
-// @Description Lookup image list
+// @Description Lookup image list (cached per connection for TB_LOOKUP_CACHE_TTL_MIN; use refresh=true to bypass the cache)
 // @Tags [Infra Resource] Image Management
 // @Accept  json
 // @Produce  json
 // @Param lookupImagesReq body common.TbConnectionName true "Specify connectionName"
+// @Param refresh query bool false "Bypass the cached lookup and query CB-Spider again" default(false)
 // @Success 200 {object} model.SpiderImageList
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
@@ -165,6 +166,9 @@ func RestLookupImageList(c echo.Context) error {
 	}
 
 	log.Debug().Msg("[Lookup images]")
+	if c.QueryParam("refresh") == "true" {
+		resource.InvalidateLookupCache(resource.LookupCacheImage, u.ConnectionName)
+	}
 	content, err := resource.LookupImageList(u.ConnectionName)
 	return common.EndRequestWithLog(c, err, content)
 
@@ -251,7 +255,15 @@ type RestGetAllImageResponse struct {
 // @Param option query string false "Option" Enums(id)
 // @Param filterKey query string false "Field key for filtering (ex:guestOS)"
 // @Param filterVal query string false "Field value for filtering (ex: Ubuntu18.04)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
 // @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllImageResponse,[ID]=model.IdList} "Different return structures by the given option param"
+// @Success 304 "No item is changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/image [get]
@@ -294,20 +306,17 @@ func RestDelAllImage(c echo.Context) error {
 	return nil
 }
 
-// Response structure for RestSearchImage
-type RestSearchImageRequest struct {
-	Keywords []string `json:"keywords"`
-}
-
 // RestSearchImage godoc
 // @ID SearchImage
 // @Summary Search image
-// @Description Search image
+// @Description Search image by keywords of the name and the normalized OS metadata (osDistribution, osVersion, architecture).
+// @Description osDistribution is one of ubuntu, debian, rhel, centos, rocky, almalinux, amazonlinux, oraclelinux, sles, fedora, cos, windows and unknown.
+// @Description osVersion matches the version and its minor versions (e.g., 22 matches 22.04).
 // @Tags [Infra Resource] Image Management
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(system)
-// @Param keywords body RestSearchImageRequest true "Keywords"
+// @Param keywords body model.SearchImageRequest true "Keywords and OS metadata"
 // @Success 200 {object} RestGetAllImageResponse
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
@@ -316,12 +325,12 @@ func RestSearchImage(c echo.Context) error {
 
 	nsId := c.Param("nsId")
 
-	u := &RestSearchImageRequest{}
+	u := &model.SearchImageRequest{}
 	if err := c.Bind(u); err != nil {
 		return err
 	}
 
-	content, err := resource.SearchImage(nsId, u.Keywords...)
+	content, err := resource.SearchImageWithOption(nsId, *u)
 	result := RestGetAllImageResponse{}
 	result.Image = content
 	return common.EndRequestWithLog(c, err, result)
@@ -15,9 +15,11 @@ limitations under the License.
 package resource
 
 import (
+	"encoding/base64"
 	"net/http"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
 	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
 	"github.com/labstack/echo/v4"
@@ -102,7 +104,9 @@ func RestCheckNodeGroupsOnK8sCreation(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param option query string false "Option: [required params for register] connectionName, name, cspResourceId" Enums(register)
 // @Param k8sClusterReq body model.TbK8sClusterReq true "Details of the K8sCluster object"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbK8sClusterInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/k8scluster [post]
@@ -113,13 +117,13 @@ func RestPostK8sCluster(c echo.Context) error {
 	optionFlag := c.QueryParam("option")
 
 	u := &model.TbK8sClusterReq{}
-	if err := c.Bind(u); err != nil {
-		return err
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	log.Debug().Msg("[POST K8sCluster]")
 
-	content, err := resource.CreateK8sCluster(nsId, u, optionFlag)
+	content, err := resource.CreateK8sCluster(common.NewRequestContext(c), nsId, u, optionFlag)
 
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -130,6 +134,135 @@ func RestPostK8sCluster(c echo.Context) error {
 	return c.JSON(http.StatusCreated, content)
 }
 
+// RestPostK8sClusterDynamic func is a rest api wrapper for CreateK8sClusterDynamic.
+// RestPostK8sClusterDynamic godoc
+// @ID PostK8sClusterDynamic
+// @Summary Create K8sCluster Dynamically
+// @Description Create K8sCluster Dynamically from high-level requirements (provider, region, node count, vCPU/memory range).
+// @Description Spec, version and default network resources (vNet/subnets, sshKey, securityGroup) are resolved and created automatically.
+// @Tags [Kubernetes] Cluster Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param k8sClusterDynamicReq body model.TbK8sClusterDynamicReq true "Requirements of the K8sCluster. You can use /ns/{nsId}/k8sClusterDynamicCheckRequest to see the resolved plan"
+// @Param x-request-id header string false "Custom request ID"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Success 200 {object} model.TbK8sClusterInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/k8sclusterDynamic [post]
+func RestPostK8sClusterDynamic(c echo.Context) error {
+	reqID := c.Request().Header.Get(echo.HeaderXRequestID)
+
+	nsId := c.Param("nsId")
+
+	req := &model.TbK8sClusterDynamicReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		log.Warn().Err(err).Msg("invalid request")
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.CreateK8sClusterDynamic(common.NewRequestContext(c), reqID, nsId, req)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to create K8sCluster dynamically")
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPostK8sClusterDynamicCheckRequest func is a rest api wrapper for CheckK8sClusterDynamicReq.
+// RestPostK8sClusterDynamicCheckRequest godoc
+// @ID PostK8sClusterDynamicCheckRequest
+// @Summary Check the resolved plan for creating K8sCluster Dynamically
+// @Description Resolve requirements into a concrete K8sCluster creation request without creating anything
+// @Tags [Kubernetes] Cluster Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param k8sClusterDynamicReq body model.TbK8sClusterDynamicReq true "Requirements of the K8sCluster"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Success 200 {object} model.TbK8sClusterDynamicCheckInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/k8sClusterDynamicCheckRequest [post]
+func RestPostK8sClusterDynamicCheckRequest(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	req := &model.TbK8sClusterDynamicReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := infra.CheckK8sClusterDynamicReq(nsId, req)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, err, result)
+}
+
+// RestPostRegisterK8sCluster func is a rest api wrapper for RegisterK8sCluster.
+// RestPostRegisterK8sCluster godoc
+// @ID PostRegisterK8sCluster
+// @Summary Register K8sCluster (created in CSP)
+// @Description Register the K8sCluster, which was created in CSP (e.g., EKS, AKS, GKE)
+// @Tags [Kubernetes] Cluster Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param k8sClusterRegisterReq body model.TbRegisterK8sClusterReq true "Information required to register the K8sCluster created externally"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Success 201 {object} model.TbK8sClusterInfo
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/registerCspResource/k8sCluster [post]
+func RestPostRegisterK8sCluster(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	req := &model.TbRegisterK8sClusterReq{}
+	if err := common.BindRequest(c, req); err != nil {
+		log.Warn().Err(err).Msg("invalid request")
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.RegisterK8sCluster(common.NewRequestContext(c), nsId, req)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, content)
+}
+
+// RestDeleteDeregisterK8sCluster func is a rest api wrapper for DeregisterK8sCluster.
+// RestDeleteDeregisterK8sCluster godoc
+// @ID DeleteDeregisterK8sCluster
+// @Summary Deregister K8sCluster (created in CSP)
+// @Description Deregister the K8sCluster from CB-Tumblebug and CB-Spider. The K8sCluster in CSP is not deleted.
+// @Tags [Kubernetes] Cluster Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param k8sClusterId path string true "K8sCluster ID"
+// @Success 200 {object} model.SimpleMsg
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/deregisterCspResource/k8sCluster/{k8sClusterId} [delete]
+func RestDeleteDeregisterK8sCluster(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	k8sClusterId := c.Param("k8sClusterId")
+
+	content, err := resource.DeregisterK8sCluster(nsId, k8sClusterId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, content)
+}
+
 /*
 	function RestPutK8sCluster not yet implemented
 
@@ -165,7 +298,9 @@ func RestPutK8sCluster(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param k8sClusterId path string true "K8sCluster ID" default(k8scluster-01)
 // @Param k8sNodeGroupReq body model.TbK8sNodeGroupReq true "Details of the K8sNodeGroup object" default(ng-01)
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbK8sClusterInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/k8scluster/{k8sClusterId}/k8snodegroup [post]
@@ -175,8 +310,8 @@ func RestPostK8sNodeGroup(c echo.Context) error {
 	k8sClusterId := c.Param("k8sClusterId")
 
 	u := &model.TbK8sNodeGroupReq{}
-	if err := c.Bind(u); err != nil {
-		return err
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	log.Debug().Msg("[POST K8sNodeGroup]")
@@ -243,7 +378,9 @@ func RestDeleteK8sNodeGroup(c echo.Context) error {
 // @Param k8sClusterId path string true "K8sCluster ID" default(k8scluster-01)
 // @Param k8sNodeGroupName path string true "K8sNodeGroup Name" default(ng-01)
 // @Param setK8sNodeGroupAutoscalingReq body model.TbSetK8sNodeGroupAutoscalingReq true "Details of the TbSetK8sNodeGroupAutoscalingReq object"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbSetK8sNodeGroupAutoscalingRes
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/k8scluster/{k8sClusterId}/k8snodegroup/{k8sNodeGroupName}/onautoscaling [put]
@@ -254,8 +391,8 @@ func RestPutSetK8sNodeGroupAutoscaling(c echo.Context) error {
 	k8sNodeGroupName := c.Param("k8sNodeGroupName")
 
 	u := &model.TbSetK8sNodeGroupAutoscalingReq{}
-	if err := c.Bind(u); err != nil {
-		return err
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	log.Debug().Msg("[PUT K8s Set AutoScaling]")
@@ -283,7 +420,9 @@ func RestPutSetK8sNodeGroupAutoscaling(c echo.Context) error {
 // @Param k8sClusterId path string true "K8sCluster ID" default(k8scluster-01)
 // @Param k8sNodeGroupName path string true "K8sNodeGroup Name" default(ng-01)
 // @Param changeK8sNodeGroupAutoscaleSizeReq body model.TbChangeK8sNodeGroupAutoscaleSizeReq true "Details of the TbChangeK8sNodeGroupAutoscaleSizeReq object"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbChangeK8sNodeGroupAutoscaleSizeRes
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/k8scluster/{k8sClusterId}/k8snodegroup/{k8sNodeGroupName}/autoscalesize [put]
@@ -294,8 +433,8 @@ func RestPutChangeK8sNodeGroupAutoscaleSize(c echo.Context) error {
 	k8sNodeGroupName := c.Param("k8sNodeGroupName")
 
 	u := &model.TbChangeK8sNodeGroupAutoscaleSizeReq{}
-	if err := c.Bind(u); err != nil {
-		return err
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	log.Debug().Msg("[PUT K8s Change AutoScale Size]")
@@ -339,6 +478,68 @@ func RestGetK8sCluster(c echo.Context) error {
 	}
 }
 
+// RestGetK8sClusterKubeconfig func is a rest api wrapper for GetK8sClusterKubeconfig.
+// RestGetK8sClusterKubeconfig godoc
+// @ID GetK8sClusterKubeconfig
+// @Summary Get kubeconfig of K8sCluster
+// @Description Get kubeconfig of K8sCluster. The kubeconfig is always fetched from CSP (not cached) and is not recorded in the request log.
+// @Description If endpoint is given (ex: private endpoint via bastion), the server address in the kubeconfig is replaced with it.
+// @Description The endpoint must be an http(s) URL with a host (and port) only; otherwise 400 is returned.
+// @Tags [Kubernetes] Cluster Management
+// @Accept  json
+// @Produce  plain
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param k8sClusterId path string true "K8sCluster ID" default(k8scluster-01)
+// @Param base64 query boolean false "Return the kubeconfig encoded in base64 instead of raw YAML" default(false)
+// @Param endpoint query string false "Server endpoint to replace in the kubeconfig (ex: https://127.0.0.1:6443)"
+// @Success 200 {string} string "kubeconfig (YAML or base64)"
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/k8scluster/{k8sClusterId}/kubeconfig [get]
+func RestGetK8sClusterKubeconfig(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	k8sClusterId := c.Param("k8sClusterId")
+
+	base64Flag := c.QueryParam("base64") == "true"
+	endpoint := c.QueryParam("endpoint")
+
+	kubeconfig, err := resource.GetK8sClusterKubeconfig(nsId, k8sClusterId, endpoint)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	if base64Flag {
+		encoded := base64.StdEncoding.EncodeToString([]byte(kubeconfig))
+		return common.EndRequestWithSensitiveData(c, echo.MIMETextPlainCharsetUTF8, []byte(encoded))
+	}
+	return common.EndRequestWithSensitiveData(c, "application/yaml", []byte(kubeconfig))
+}
+
+// RestGetK8sClusterEvents func is a rest api wrapper for GetK8sClusterEvents.
+// RestGetK8sClusterEvents godoc
+// @ID GetK8sClusterEvents
+// @Summary Get status transition events of K8sCluster
+// @Description Get status transition events (old status, new status, time, message) of K8sCluster recorded by the status poller
+// @Tags [Kubernetes] Cluster Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param k8sClusterId path string true "K8sCluster ID" default(k8scluster-01)
+// @Success 200 {object} model.TbK8sClusterEventList
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/k8scluster/{k8sClusterId}/events [get]
+func RestGetK8sClusterEvents(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	k8sClusterId := c.Param("k8sClusterId")
+
+	content, err := resource.GetK8sClusterEvents(nsId, k8sClusterId)
+	return common.EndRequestWithLog(c, err, content)
+}
+
 // Response structure for RestGetAllK8sCluster
 type RestGetAllK8sClusterResponse struct {
 	K8sCluster []model.TbK8sClusterInfo `json:"cluster"`
@@ -471,7 +672,9 @@ func RestDeleteAllK8sCluster(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param k8sClusterId path string true "K8sCluster ID" default(k8scluster-01)
 // @Param upgradeK8sClusterReq body model.TbUpgradeK8sClusterReq true "Details of the TbUpgradeK8sClusterReq object"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.SimpleMsg
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/k8scluster/{k8sClusterId}/upgrade [put]
@@ -481,8 +684,8 @@ func RestPutUpgradeK8sCluster(c echo.Context) error {
 	k8sClusterId := c.Param("k8sClusterId")
 
 	u := &model.TbUpgradeK8sClusterReq{}
-	if err := c.Bind(u); err != nil {
-		return err
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	log.Debug().Msg("[PUT Upgrade K8sCluster]")
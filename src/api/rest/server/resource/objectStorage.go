@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to handle REST API for resource
+package resource
+
+import (
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/labstack/echo/v4"
+)
+
+// RestPostObjectStorage godoc
+// @ID PostObjectStorage
+// @Summary Create Object Storage
+// @Description Create Object Storage (a bucket of AWS S3, GCP GCS or Azure Blob) via CB-Spider
+// @Description publicAccessBlock is true by default.
+// @Tags [Infra Resource] Object Storage Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param objectStorageReq body model.TbObjectStorageReq true "Details for an Object Storage object"
+// @Success 200 {object} model.TbObjectStorageInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Failure 501 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/objectStorage [post]
+func RestPostObjectStorage(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	u := &model.TbObjectStorageReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.CreateObjectStorage(nsId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPutObjectStorage godoc
+// @ID PutObjectStorage
+// @Summary Update Object Storage options
+// @Description Update the options (versioning, public access block) of Object Storage
+// @Tags [Infra Resource] Object Storage Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param objectStorageId path string true "Object Storage ID"
+// @Param objectStorageUpdateReq body model.TbObjectStorageUpdateReq true "Options of the Object Storage"
+// @Success 200 {object} model.TbObjectStorageInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/objectStorage/{objectStorageId} [put]
+func RestPutObjectStorage(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	objectStorageId := c.Param("resourceId")
+
+	u := &model.TbObjectStorageUpdateReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.UpdateObjectStorage(nsId, objectStorageId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetObjectStorage godoc
+// @ID GetObjectStorage
+// @Summary Get Object Storage
+// @Description Get Object Storage
+// @Tags [Infra Resource] Object Storage Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param objectStorageId path string true "Object Storage ID"
+// @Param If-None-Match header string false "ETag of the resource from the previous response; 304 is returned if the resource is not changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSP" default(false)
+// @Success 200 {object} model.TbObjectStorageInfo
+// @Success 304 "The resource is not changed (ETag of If-None-Match)"
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/objectStorage/{objectStorageId} [get]
+func RestGetObjectStorage(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
+
+// Response struct for RestGetAllObjectStorage
+type RestGetAllObjectStorageResponse struct {
+	ObjectStorage []model.TbObjectStorageInfo `json:"objectStorage"`
+}
+
+// RestGetAllObjectStorage godoc
+// @ID GetAllObjectStorage
+// @Summary List all Object Storages or Object Storages' ID
+// @Description List all Object Storages or Object Storages' ID
+// @Tags [Infra Resource] Object Storage Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param option query string false "Option" Enums(id)
+// @Param filterKey query string false "Field key for filtering (ex: systemLabel)"
+// @Param filterVal query string false "Field value for filtering (ex: Registered from CSP resource)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
+// @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllObjectStorageResponse,[ID]=model.IdList} "Different return structures by the given option param"
+// @Success 304 "No item is changed (ETag of If-None-Match)"
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/objectStorage [get]
+func RestGetAllObjectStorage(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
+
+// RestDelObjectStorage godoc
+// @ID DelObjectStorage
+// @Summary Delete Object Storage
+// @Description Delete Object Storage (use force=true to delete a bucket which is not empty)
+// @Tags [Infra Resource] Object Storage Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param objectStorageId path string true "Object Storage ID"
+// @Param force query string false "Force to delete the bucket with its objects" Enums(true, false)
+// @Success 200 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/objectStorage/{objectStorageId} [delete]
+func RestDelObjectStorage(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
+
+// RestDelAllObjectStorage godoc
+// @ID DelAllObjectStorage
+// @Summary Delete all Object Storages
+// @Description Delete all Object Storages
+// @Tags [Infra Resource] Object Storage Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param match query string false "Delete resources containing matched ID-substring only" default()
+// @Success 200 {object} model.IdList
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/objectStorage [delete]
+func RestDelAllObjectStorage(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
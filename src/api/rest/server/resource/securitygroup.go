@@ -31,7 +31,9 @@ import (
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param option query string false "Option: [required params for register] connectionName, name, vNetId, cspResourceId" Enums(register)
 // @Param securityGroupReq body model.TbSecurityGroupReq true "Details for an securityGroup object"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 200 {object} model.TbSecurityGroupInfo
+// @Failure 400 {object} model.ValidationErrorMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/securityGroup [post]
@@ -42,11 +44,65 @@ func RestPostSecurityGroup(c echo.Context) error {
 	optionFlag := c.QueryParam("option")
 
 	u := &model.TbSecurityGroupReq{}
-	if err := c.Bind(u); err != nil {
+	if err := common.BindRequest(c, u); err != nil {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	content, err := resource.CreateSecurityGroup(nsId, u, optionFlag)
+	content, err := resource.CreateSecurityGroup(common.NewRequestContext(c), nsId, u, optionFlag)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostRegisterSecurityGroup godoc
+// @ID PostRegisterSecurityGroup
+// @Summary Register Security Group (created in CSP)
+// @Description Register the Security Group, which was created in CSP, with its firewall rules.
+// @Description The Security Group is linked to the registered VNet which owns it in CSP.
+// @Description If the VNet is not registered, cspVNetId is stored with vNetUnregistered flag, and the Security Group is linked when the VNet is registered.
+// @Tags [Infra Resource] Security Group Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param securityGroupRegisterReq body model.TbRegisterSecurityGroupReq true "Information required to register the Security Group created externally"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Success 200 {object} model.TbSecurityGroupInfo
+// @Failure 400 {object} model.ValidationErrorMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /ns/{nsId}/registerCspResource/securityGroup [post]
+func RestPostRegisterSecurityGroup(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	u := &model.TbRegisterSecurityGroupReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.RegisterSecurityGroup(common.NewRequestContext(c), nsId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDeleteDeregisterSecurityGroup godoc
+// @ID DeleteDeregisterSecurityGroup
+// @Summary Deregister Security Group (created in CSP)
+// @Description Deregister the Security Group from CB-Tumblebug and CB-Spider. The Security Group in CSP is not deleted.
+// @Tags [Infra Resource] Security Group Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param securityGroupId path string true "Security Group ID"
+// @Success 200 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /ns/{nsId}/deregisterCspResource/securityGroup/{securityGroupId} [delete]
+func RestDeleteDeregisterSecurityGroup(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	securityGroupId := c.Param("securityGroupId")
+
+	content, err := resource.DeregisterSecurityGroup(nsId, securityGroupId)
 	return common.EndRequestWithLog(c, err, content)
 }
 
@@ -81,7 +137,10 @@ func RestPutSecurityGroup(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param securityGroupId path string true "Security Group ID"
+// @Param If-None-Match header string false "ETag of the resource from the previous response; 304 is returned if the resource is not changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSP" default(false)
 // @Success 200 {object} model.TbSecurityGroupInfo
+// @Success 304 "The resource is not changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/securityGroup/{securityGroupId} [get]
@@ -106,7 +165,15 @@ type RestGetAllSecurityGroupResponse struct {
 // @Param option query string false "Option" Enums(id)
 // @Param filterKey query string false "Field key for filtering (ex: systemLabel)"
 // @Param filterVal query string false "Field value for filtering (ex: Registered from CSP resource)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
 // @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllSecurityGroupResponse,[ID]=model.IdList} "Different return structures by the given option param"
+// @Success 304 "No item is changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/securityGroup [get]
@@ -124,6 +191,7 @@ func RestGetAllSecurityGroup(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param securityGroupId path string true "Security Group ID"
+// @Param permanent query bool false "Delete permanently (true) or move to the trash keeping the CSP resource (false); the default is set by TB_SOFT_DELETE"
 // @Success 200 {object} model.SimpleMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/securityGroup/{securityGroupId} [delete]
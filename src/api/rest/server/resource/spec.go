@@ -143,11 +143,12 @@ func RestLookupSpec(c echo.Context) error {
 // RestLookupSpecList godoc
 // @ID LookupSpecList
 // @Summary Lookup spec list
-// @Description Lookup spec list
+// @Description Lookup spec list (cached per connection for TB_LOOKUP_CACHE_TTL_MIN; use refresh=true to bypass the cache)
 // @Tags [Infra Resource] Spec Management
 // @Accept  json
 // @Produce  json
 // @Param lookupSpecsReq body common.TbConnectionName true "Specify connectionName"
+// @Param refresh query bool false "Bypass the cached lookup and query CB-Spider again" default(false)
 // @Success 200 {object} model.SpiderSpecList
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
@@ -160,6 +161,9 @@ func RestLookupSpecList(c echo.Context) error {
 	}
 
 	log.Debug().Msg("[Lookup specs]")
+	if c.QueryParam("refresh") == "true" {
+		resource.InvalidateLookupCache(resource.LookupCacheSpec, u.ConnectionName)
+	}
 	content, err := resource.LookupSpecList(u.ConnectionName)
 	return common.EndRequestWithLog(c, err, content)
 
@@ -168,12 +172,15 @@ func RestLookupSpecList(c echo.Context) error {
 // RestFetchSpecs godoc
 // @ID FetchSpecs
 // @Summary Fetch specs
-// @Description Fetch specs
+// @Description Fetch specs from CSPs and save the difference from the stored specs (new and changed specs are written,
+// @Description and specs which disappeared from the CSP are marked as unavailable). Connections are processed in parallel.
 // @Tags [Infra Resource] Spec Management
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(system)
-// @Success 200 {object} model.SimpleMsg
+// @Param fetchSpecsReq body RestLookupSpecRequest false "Specify connectionName to fetch specs of the connection only"
+// @Param provider query string false "Fetch specs of the connections of the provider only (e.g., aws, gcp)"
+// @Success 200 {object} model.SpecFetchSummary
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/fetchSpecs [post]
@@ -186,42 +193,45 @@ func RestFetchSpecs(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	var connConfigCount, specCount uint
-	var err error
-
 	if u.ConnectionName == "" {
-		connConfigCount, specCount, err = resource.FetchSpecsForAllConnConfigs(nsId)
-		if err != nil {
-			return common.EndRequestWithLog(c, err, nil)
-		}
-	} else {
-		connConfigCount = 1
-		specCount, err = resource.FetchSpecsForConnConfig(u.ConnectionName, nsId)
-		if err != nil {
-			return common.EndRequestWithLog(c, err, nil)
-		}
+		content, err := resource.FetchSpecsForAllConnConfigs(nsId, c.QueryParam("provider"))
+		return common.EndRequestWithLog(c, err, content)
 	}
 
-	content := map[string]string{
-		"message": "Fetched " + fmt.Sprint(specCount) + " specs (from " + fmt.Sprint(connConfigCount) + " connConfigs)"}
-	return common.EndRequestWithLog(c, err, content)
+	result, err := resource.FetchSpecsForConnConfig(u.ConnectionName, nsId)
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	content := model.SpecFetchSummary{
+		ConnConfigCount: 1,
+		Added:           result.Added,
+		Updated:         result.Updated,
+		Unchanged:       result.Unchanged,
+		Removed:         result.Removed,
+		Results:         []model.SpecFetchResult{result},
+	}
+	return common.EndRequestWithLog(c, nil, content)
 }
 
 // RestFilterSpecsResponse is Response structure for RestFilterSpecs
 type RestFilterSpecsResponse struct {
 	Spec []model.TbSpecInfo `json:"spec"`
+	// Total is the number of matched specs before limit and offset are applied
+	Total int `json:"total"`
 }
 
 // RestFilterSpecsByRange godoc
 // @ID FilterSpecsByRange
 // @Summary Filter specs by range
-// @Description Filter specs by range
+// @Description Filter specs by range. The result can be sorted (sortBy: vCPU, memoryGiB, costPerHour, evaluationScore01, ...; order: asc or desc)
+// @Description and paged (limit, offset); total is the number of all matched specs. Specs with unknown cost are placed last when sorting by cost.
 // @Tags [Infra Resource] Spec Management
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(system)
 // @Param specRangeFilter body model.FilterSpecsByRangeRequest false "Filter for range-filtering specs"
 // @Success 200 {object} RestFilterSpecsResponse
+// @Failure 400 {object} model.SimpleMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/filterSpecsByRange [post]
@@ -235,9 +245,10 @@ func RestFilterSpecsByRange(c echo.Context) error {
 	}
 
 	log.Debug().Msg("[Filter specs]")
-	content, err := resource.FilterSpecsByRange(nsId, *u)
+	content, total, err := resource.FilterSpecsByRangeWithTotal(nsId, *u)
 	result := RestFilterSpecsResponse{}
 	result.Spec = content
+	result.Total = total
 	return common.EndRequestWithLog(c, err, result)
 }
 
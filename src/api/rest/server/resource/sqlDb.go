@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to handle REST API for resource
+package resource
+
+import (
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/labstack/echo/v4"
+)
+
+// RestPostSqlDb godoc
+// @ID PostSqlDb
+// @Summary Create SQL DB
+// @Description Create a managed MySQL/PostgreSQL DB (AWS RDS, GCP Cloud SQL or Azure Database) in the subnet of a vNet via CB-Spider
+// @Description The admin password is generated and returned only in this response (use revealPassword=true to get it later).
+// @Description The status is Creating until the DB is available; the endpoint is filled when it is Available.
+// @Tags [Infra Resource] SQL DB Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param sqlDbReq body model.TbSqlDbReq true "Details for a SQL DB object"
+// @Success 200 {object} model.TbSqlDbInfo
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Failure 501 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/sqlDb [post]
+func RestPostSqlDb(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	u := &model.TbSqlDbReq{}
+	if err := c.Bind(u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.CreateSqlDb(nsId, u)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestGetSqlDb godoc
+// @ID GetSqlDb
+// @Summary Get SQL DB
+// @Description Get SQL DB (the admin password is masked unless revealPassword=true, which is audited)
+// @Tags [Infra Resource] SQL DB Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param sqlDbId path string true "SQL DB ID"
+// @Param revealPassword query boolean false "Reveal the admin password" default(false)
+// @Param If-None-Match header string false "ETag of the resource from the previous response; 304 is returned if the resource is not changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSP" default(false)
+// @Success 200 {object} model.TbSqlDbInfo
+// @Success 304 "The resource is not changed (ETag of If-None-Match)"
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/sqlDb/{sqlDbId} [get]
+func RestGetSqlDb(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
+
+// Response struct for RestGetAllSqlDb
+type RestGetAllSqlDbResponse struct {
+	SqlDb []model.TbSqlDbInfo `json:"sqlDb"`
+}
+
+// RestGetAllSqlDb godoc
+// @ID GetAllSqlDb
+// @Summary List all SQL DBs or SQL DBs' ID
+// @Description List all SQL DBs or SQL DBs' ID (the admin passwords are masked)
+// @Tags [Infra Resource] SQL DB Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param option query string false "Option" Enums(id)
+// @Param filterKey query string false "Field key for filtering (ex: systemLabel)"
+// @Param filterVal query string false "Field value for filtering (ex: Registered from CSP resource)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
+// @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllSqlDbResponse,[ID]=model.IdList} "Different return structures by the given option param"
+// @Success 304 "No item is changed (ETag of If-None-Match)"
+// @Failure 404 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/sqlDb [get]
+func RestGetAllSqlDb(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
+
+// RestDelSqlDb godoc
+// @ID DelSqlDb
+// @Summary Delete SQL DB
+// @Description Delete SQL DB
+// @Tags [Infra Resource] SQL DB Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param sqlDbId path string true "SQL DB ID"
+// @Success 200 {object} model.SimpleMsg
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/sqlDb/{sqlDbId} [delete]
+func RestDelSqlDb(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
+
+// RestDelAllSqlDb godoc
+// @ID DelAllSqlDb
+// @Summary Delete all SQL DBs
+// @Description Delete all SQL DBs
+// @Tags [Infra Resource] SQL DB Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param match query string false "Delete resources containing matched ID-substring only" default()
+// @Success 200 {object} model.IdList
+// @Failure 404 {object} model.SimpleMsg
+// @Router /ns/{nsId}/resources/sqlDb [delete]
+func RestDelAllSqlDb(c echo.Context) error {
+	// This is a dummy function for Swagger.
+	return nil
+}
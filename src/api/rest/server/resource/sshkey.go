@@ -46,7 +46,61 @@ func RestPostSshKey(c echo.Context) error {
 		return common.EndRequestWithLog(c, err, nil)
 	}
 
-	content, err := resource.CreateSshKey(nsId, u, optionFlag)
+	content, err := resource.CreateSshKey(common.NewRequestContext(c), nsId, u, optionFlag)
+	resource.MaskSshKeyInfo(&content)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestPostRegisterSshKey godoc
+// @ID PostRegisterSshKey
+// @Summary Register SSH Key (created in CSP)
+// @Description Register the key pair, which was created in CSP.
+// @Description privateKey is optional. Without it, the SSH Key is registered as publicOnly and command execution is not available for VMs using it.
+// @Tags [Infra Resource] Access Key Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param sshKeyRegisterReq body model.TbRegisterSshKeyReq true "Information required to register the key pair created externally"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Success 200 {object} model.TbSshKeyInfo
+// @Failure 400 {object} model.ValidationErrorMsg
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /ns/{nsId}/registerCspResource/sshKey [post]
+func RestPostRegisterSshKey(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	u := &model.TbRegisterSshKeyReq{}
+	if err := common.BindRequest(c, u); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	content, err := resource.RegisterSshKey(common.NewRequestContext(c), nsId, u)
+	resource.MaskSshKeyInfo(&content)
+	return common.EndRequestWithLog(c, err, content)
+}
+
+// RestDeleteDeregisterSshKey godoc
+// @ID DeleteDeregisterSshKey
+// @Summary Deregister SSH Key (created in CSP)
+// @Description Deregister the SSH Key from CB-Tumblebug and CB-Spider. The key pair in CSP is not deleted.
+// @Tags [Infra Resource] Access Key Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param sshKeyId path string true "SSH Key ID"
+// @Success 200 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /ns/{nsId}/deregisterCspResource/sshKey/{sshKeyId} [delete]
+func RestDeleteDeregisterSshKey(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+	sshKeyId := c.Param("sshKeyId")
+
+	content, err := resource.DeregisterSshKey(nsId, sshKeyId)
 	return common.EndRequestWithLog(c, err, content)
 }
 
@@ -75,19 +129,24 @@ func RestPutSshKey(c echo.Context) error {
 	}
 
 	content, err := resource.UpdateSshKey(nsId, sshKeyId, *u)
+	resource.MaskSshKeyInfo(&content)
 	return common.EndRequestWithLog(c, err, content)
 }
 
 // RestGetSshKey godoc
 // @ID GetSshKey
 // @Summary Get SSH Key
-// @Description Get SSH Key
+// @Description Get SSH Key. The private key is masked unless revealPrivateKey=true is given (the request is audited).
 // @Tags [Infra Resource] Access Key Management
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param sshKeyId path string true "SSH Key ID"
+// @Param revealPrivateKey query boolean false "Return the private key in plaintext" default(false)
+// @Param If-None-Match header string false "ETag of the resource from the previous response; 304 is returned if the resource is not changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSP" default(false)
 // @Success 200 {object} model.TbSshKeyInfo
+// @Success 304 "The resource is not changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/sshKey/{sshKeyId} [get]
@@ -112,7 +171,15 @@ type RestGetAllSshKeyResponse struct {
 // @Param option query string false "Option" Enums(id)
 // @Param filterKey query string false "Field key for filtering (ex: systemLabel)"
 // @Param filterVal query string false "Field value for filtering (ex: Registered from CSP resource)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
 // @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllSshKeyResponse,[ID]=model.IdList} "Different return structures by the given option param"
+// @Success 304 "No item is changed (ETag of If-None-Match)"
 // @Failure 404 {object} model.SimpleMsg
 // @Failure 500 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/sshKey [get]
@@ -130,6 +197,7 @@ func RestGetAllSshKey(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param sshKeyId path string true "SSH Key ID"
+// @Param permanent query bool false "Delete permanently (true) or move to the trash keeping the CSP resource (false); the default is set by TB_SOFT_DELETE"
 // @Success 200 {object} model.SimpleMsg
 // @Failure 404 {object} model.SimpleMsg
 // @Router /ns/{nsId}/resources/sshKey/{sshKeyId} [delete]
@@ -154,3 +222,22 @@ func RestDelAllSshKey(c echo.Context) error {
 	// This is a dummy function for Swagger.
 	return nil
 }
+
+// RestPostEncryptSshKeys godoc
+// @ID PostEncryptSshKeys
+// @Summary Encrypt SSH private keys stored in plaintext
+// @Description Encrypt the private keys of SSH Keys stored in plaintext in all namespaces with TB_ENCRYPTION_KEY (migration)
+// @Tags [Infra Resource] Access Key Management
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.IdList
+// @Failure 400 {object} model.SimpleMsg
+// @Failure 500 {object} model.SimpleMsg
+// @Router /sshKey/encryptPrivateKeys [post]
+func RestPostEncryptSshKeys(c echo.Context) error {
+	idList, err := resource.EncryptPlaintextSshKeys()
+	if err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	return common.EndRequestWithLog(c, nil, model.IdList{IdList: idList})
+}
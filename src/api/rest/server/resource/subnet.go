@@ -36,8 +36,8 @@ import (
 // @Param vNetId path string true "VNet ID"
 // @Param subnetReq body model.TbSubnetReq true "Details for an Subnet object"
 // @Success 200 {object} model.TbSubnetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId}/subnet [post]
 func RestPostSubnet(c echo.Context) error {
 
@@ -46,27 +46,27 @@ func RestPostSubnet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	reqt := &model.TbSubnetReq{}
 	if err := c.Bind(reqt); err != nil {
 		log.Warn().Err(err).Msg("")
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusBadRequest)
 	}
 
 	// [Process]
 	resp, err := resource.CreateSubnet(nsId, vNetId, reqt)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output]
@@ -84,8 +84,8 @@ func RestPostSubnet(c echo.Context) error {
 // @Param vNetId path string true "VNet ID"
 // @Param subnetId path string true "Subnet ID"
 // @Success 200 {object} model.TbSubnetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId}/subnet/{subnetId} [get]
 func RestGetSubnet(c echo.Context) error {
 
@@ -94,27 +94,27 @@ func RestGetSubnet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	subnetId := c.Param("subnetId")
 	if err := common.CheckString(subnetId); err != nil {
 		errMsg := fmt.Errorf("invalid subnetId (%s)", subnetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// [Process]
 	resp, err := resource.GetSubnet(nsId, vNetId, subnetId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output]
@@ -136,8 +136,8 @@ type RestGetAllSubnetResponse struct {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param vNetId path string true "VNet ID"
 // @Success 200 {object} RestGetAllSubnetResponse
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId}/subnet [get]
 func RestGetListSubnet(c echo.Context) error {
 
@@ -146,20 +146,20 @@ func RestGetListSubnet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// [Process]
 	ret, err := resource.ListSubnet(nsId, vNetId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output]
@@ -179,8 +179,8 @@ func RestGetListSubnet(c echo.Context) error {
 // @Produce  json
 // @Param subnetInfo body model.TbSubnetInfo true "Details for an Subnet object"
 // @Success 200 {object} model.TbSubnetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId}/subnet/{subnetId} [put]
 func RestPutSubnet(c echo.Context) error {
 	//nsId := c.Param("nsId")
@@ -211,7 +211,7 @@ type RestGetAllSubnetResponse struct {
 // @Param subnetId path string true "Subnet ID"
 // @Param action query string false "Action" Enums(refine, force)
 // @Success 200 {object} model.SimpleMsg
-// @Failure 404 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId}/subnet/{subnetId} [delete]
 func RestDelSubnet(c echo.Context) error {
 
@@ -221,20 +221,20 @@ func RestDelSubnet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 	subnetId := c.Param("subnetId")
 	if err := common.CheckString(subnetId); err != nil {
 		errMsg := fmt.Errorf("invalid subnetId (%s)", subnetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	paramAction := c.QueryParam("action")
@@ -242,7 +242,7 @@ func RestDelSubnet(c echo.Context) error {
 	if !vaild {
 		errMsg := fmt.Errorf("invalid action (%s)", action)
 		log.Warn().Err(errMsg).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	var resp model.SimpleMsg
@@ -253,19 +253,19 @@ func RestDelSubnet(c echo.Context) error {
 		resp, err = resource.DeleteSubnet(nsId, vNetId, subnetId, action.String())
 		if err != nil {
 			log.Error().Err(err).Msg("")
-			return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+			return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 		}
 	case resource.ActionRefine:
 		// [Process]
 		resp, err = resource.RefineSubnet(nsId, vNetId, subnetId)
 		if err != nil {
 			log.Error().Err(err).Msg("")
-			return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+			return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 		}
 	default:
 		errMsg := fmt.Errorf("invalid action (%s)", action)
 		log.Warn().Err(errMsg).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// [Output]
@@ -282,7 +282,7 @@ func RestDelSubnet(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Success 200 {object} model.SimpleMsg
-// @Failure 404 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId}/subnet [delete]
 func RestDelAllSubnet(c echo.Context) error {
 	// This is a dummy function for Swagger.
@@ -301,8 +301,8 @@ func RestDelAllSubnet(c echo.Context) error {
 // @Param vNetId path string true "VNet ID"
 // @Param subnetReq body model.TbRegisterSubnetReq true "Details for an Subnet object"
 // @Success 200 {object} model.TbSubnetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/registerCspResource/vNet/{vNetId}/subnet [post]
 func RestPostRegisterSubnet(c echo.Context) error {
 
@@ -311,27 +311,27 @@ func RestPostRegisterSubnet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	reqt := &model.TbRegisterSubnetReq{}
 	if err := c.Bind(reqt); err != nil {
 		log.Warn().Err(err).Msg("")
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusBadRequest)
 	}
 
 	// [Process]
 	resp, err := resource.RegisterSubnet(nsId, vNetId, reqt)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output]
@@ -349,7 +349,7 @@ func RestPostRegisterSubnet(c echo.Context) error {
 // @Param vNetId path string true "VNet ID"
 // @Param subnetId path string true "Subnet ID"
 // @Success 200 {object} model.SimpleMsg
-// @Failure 404 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
 // @Router /ns/{nsId}/deregisterCspResource/vNet/{vNetId}/subnet/{subnetId} [delete]
 func RestDeleteDeregisterSubnet(c echo.Context) error {
 
@@ -359,26 +359,26 @@ func RestDeleteDeregisterSubnet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 	subnetId := c.Param("subnetId")
 	if err := common.CheckString(subnetId); err != nil {
 		errMsg := fmt.Errorf("invalid subnetId (%s)", subnetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// [Process]
 	resp, err := resource.DeregisterSubnet(nsId, vNetId, subnetId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output]
@@ -29,14 +29,20 @@ import (
 // @ID PostVNet
 // @Summary Create VNet
 // @Description Create a new VNet
+// @Description For a dual-stack vNet, set ipv6CidrBlock (and ipv6_CIDR of the subnets) or assignIpv6=auto.
+// @Description Providers without IPv6 support in the capability matrix (GET /capabilities) are rejected with 400.
 // @Tags [Infra Resource] Network Management
 // @Accept  json
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param vNetReq body model.TbVNetReq false "Details for an VNet object"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
+// @Param allowOverlap query boolean false "Allow the CIDR block to overlap other vNets in the same namespace and CSP account (same as allowOverlap in the body)" default(false)
 // @Success 201 {object} model.TbVNetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 400 {object} model.ValidationErrorMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet [post]
 func RestPostVNet(c echo.Context) error {
 
@@ -46,34 +52,65 @@ func RestPostVNet(c echo.Context) error {
 	if err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// Create vNet
 	// [Input] Bind the request body
 	reqt := &model.TbVNetReq{}
-	if err := c.Bind(reqt); err != nil {
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: err.Error()})
+	if err := common.BindRequest(c, reqt); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+	if c.QueryParam("allowOverlap") == "true" {
+		reqt.AllowOverlap = true
 	}
 
 	// [Validation] Validate the request
 	err = resource.ValidateVNetReq(reqt)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	// [Process] Create new vNet
-	resp, err := resource.CreateVNet(nsId, reqt)
+	resp, err := resource.CreateVNet(common.NewRequestContext(c), nsId, reqt)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output] Return the created vNet info
 	return c.JSON(http.StatusCreated, resp)
 }
 
+// RestPostVNetValidate godoc
+// @ID PostVNetValidate
+// @Summary Validate VNet request (dry run)
+// @Description Validate a VNet request without creating it: the request itself, the connection, the existence of the vNet, the CIDR overlap with other vNets,
+// @Description the zones of the subnets, and the network constraints of the provider in cloud info (prefix lengths, max subnets per vNet, reserved CIDRs).
+// @Description All errors and warnings are returned; valid is false if there is any error.
+// @Tags [Infra Resource] Network Management
+// @Accept  json
+// @Produce  json
+// @Param nsId path string true "Namespace ID" default(default)
+// @Param vNetReq body model.TbVNetReq true "Details for an VNet object to validate"
+// @Success 200 {object} model.VNetValidationResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /ns/{nsId}/resources/vNet/validate [post]
+func RestPostVNetValidate(c echo.Context) error {
+
+	nsId := c.Param("nsId")
+
+	reqt := &model.TbVNetReq{}
+	if err := common.BindRequest(c, reqt); err != nil {
+		return common.EndRequestWithLog(c, err, nil)
+	}
+
+	result, err := resource.ValidateVNetReqDryRun(nsId, reqt)
+	return common.EndRequestWithLog(c, err, result)
+}
+
 /*
 	function RestPutVNet not yet implemented
 
@@ -86,8 +123,8 @@ func RestPostVNet(c echo.Context) error {
 // @Produce  json
 // @Param vNetInfo body model.TbVNetInfo true "Details for an VNet object"
 // @Success 200 {object} model.TbVNetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId} [put]
 */
 // func RestPutVNet(c echo.Context) error {
@@ -106,8 +143,8 @@ func RestPostVNet(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param vNetId path string true "VNet ID"
 // @Success 200 {object} model.TbVNetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId} [get]
 func RestGetVNet(c echo.Context) error {
 	// [Input]
@@ -116,21 +153,21 @@ func RestGetVNet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// [Process]
 	resp, err := resource.GetVNet(nsId, vNetId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output]
@@ -153,9 +190,17 @@ type RestGetAllVNetResponse struct {
 // @Param option query string false "Option" Enums(id)
 // @Param filterKey query string false "Field key for filtering (ex: cspResourceName)"
 // @Param filterVal query string false "Field value for filtering (ex: default-alibaba-ap-northeast-1-vpc)"
+// @Param limit query int false "Max number of items in a page"
+// @Param offset query int false "Index of the first item in the page"
+// @Param nextToken query string false "Continue token from the previous page (overrides offset)"
+// @Param fields query string false "Comma-separated JSON paths of the fields to return (ex: id,name,status)"
+// @Param excludeKeyValue query boolean false "Exclude keyValueList of the items" default(false)
+// @Param If-None-Match header string false "ETag of the list from the previous response; 304 is returned if no item is changed"
+// @Param refresh query boolean false "Ignore If-None-Match to get the latest status from the CSPs" default(false)
 // @Success 200 {object} JSONResult{[DEFAULT]=RestGetAllVNetResponse,[ID]=model.IdList} "Different return structures by the given option param"
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Success 304 "No item is changed (ETag of If-None-Match)"
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet [get]
 func RestGetAllVNet(c echo.Context) error {
 	// This is a dummy function for Swagger.
@@ -175,8 +220,9 @@ func RestGetAllVNet(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param vNetId path string true "VNet ID"
 // @Param action query string false "Action" Enums(withsubnets,refine,force)
+// @Param permanent query bool false "Delete permanently (true) or move to the trash keeping the CSP resource (false); the default is set by TB_SOFT_DELETE"
 // @Success 200 {object} model.SimpleMsg
-// @Failure 404 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet/{vNetId} [delete]
 func RestDelVNet(c echo.Context) error {
 	// [Input]
@@ -185,14 +231,14 @@ func RestDelVNet(c echo.Context) error {
 	if err := common.CheckString(nsId); err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	vNetId := c.Param("vNetId")
 	if err := common.CheckString(vNetId); err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	actionParam := c.QueryParam("action")
@@ -200,7 +246,7 @@ func RestDelVNet(c echo.Context) error {
 	if !valid {
 		errMsg := fmt.Errorf("invalid action (%s)", action)
 		log.Warn().Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 
 	}
 
@@ -209,23 +255,33 @@ func RestDelVNet(c echo.Context) error {
 
 	switch action {
 	case resource.ActionNone, resource.ActionWithSubnets, resource.ActionForce:
+		if common.UseSoftDelete(c.QueryParam("permanent")) {
+			// [Process] Move the vNet to the trash (the CSP resource is deleted when the trash item is purged)
+			item, err := resource.TrashResource(nsId, model.StrVNet, vNetId)
+			if err != nil {
+				log.Error().Err(err).Msg("")
+				return common.EndRequestWithLog(c, err, nil)
+			}
+			resp = model.SimpleMsg{Message: "The vNet " + vNetId + " has been moved to the trash (" + item.Id + ")"}
+			break
+		}
 		// [Process]
 		resp, err = resource.DeleteVNet(nsId, vNetId, action.String())
 		if err != nil {
 			log.Error().Err(err).Msg("")
-			return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+			return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 		}
 	case resource.ActionRefine:
 		// [Process]
 		resp, err = resource.RefineVNet(nsId, vNetId)
 		if err != nil {
 			log.Error().Err(err).Msg("")
-			return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+			return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 		}
 	default:
 		errMsg := fmt.Errorf("invalid action (%s)", action)
 		log.Warn().Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// [Output]
@@ -242,7 +298,7 @@ func RestDelVNet(c echo.Context) error {
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param match query string false "Delete resources containing matched ID-substring only" default()
 // @Success 200 {object} model.IdList
-// @Failure 404 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
 // @Router /ns/{nsId}/resources/vNet [delete]
 func RestDelAllVNet(c echo.Context) error {
 	// This is a dummy function for Swagger.
@@ -258,9 +314,11 @@ func RestDelAllVNet(c echo.Context) error {
 // @Produce  json
 // @Param nsId path string true "Namespace ID" default(default)
 // @Param vNetRegisterReq body model.TbRegisterVNetReq true "Inforamation required to register the VNet created externally"
+// @Param strict query boolean false "Reject unknown fields in the request body" default(false)
 // @Success 201 {object} model.TbVNetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 400 {object} model.ValidationErrorMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/registerCspResource/vNet [post]
 func RestPostRegisterVNet(c echo.Context) error {
 
@@ -270,7 +328,7 @@ func RestPostRegisterVNet(c echo.Context) error {
 	if err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	// action := c.QueryParam("action")
@@ -285,16 +343,16 @@ func RestPostRegisterVNet(c echo.Context) error {
 	// Register vNet if the action is 'register'
 	// [Input] Bind the request body
 	reqt := &model.TbRegisterVNetReq{}
-	if err := c.Bind(reqt); err != nil {
+	if err := common.BindRequest(c, reqt); err != nil {
 		log.Warn().Err(err).Msgf("")
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithLog(c, err, nil)
 	}
 
 	// [Process] Register the VNet created externally
-	resp, err := resource.RegisterVNet(nsId, reqt)
+	resp, err := resource.RegisterVNet(common.NewRequestContext(c), nsId, reqt)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output] Return the registered vNet info
@@ -312,8 +370,8 @@ func RestPostRegisterVNet(c echo.Context) error {
 // @Param vNetId path string true "VNet ID"
 // @Param withSubnets query string false "Delete subnets as well" Enums(true,false)
 // @Success 201 {object} model.TbVNetInfo
-// @Failure 404 {object} model.SimpleMsg
-// @Failure 500 {object} model.SimpleMsg
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
 // @Router /ns/{nsId}/deregisterCspResource/vNet/{vNetId} [delete]
 func RestDeleteDeregisterVNet(c echo.Context) error {
 
@@ -323,7 +381,7 @@ func RestDeleteDeregisterVNet(c echo.Context) error {
 	if err != nil {
 		errMsg := fmt.Errorf("invalid nsId (%s)", nsId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	vNetId := c.Param("vNetId")
@@ -331,14 +389,14 @@ func RestDeleteDeregisterVNet(c echo.Context) error {
 	if err != nil {
 		errMsg := fmt.Errorf("invalid vNetId (%s)", vNetId)
 		log.Warn().Err(err).Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 
 	withSubnets := c.QueryParam("withSubnets")
 	if withSubnets != "" && withSubnets != "true" && withSubnets != "false" {
 		errMsg := fmt.Errorf("invalid option, withSubnets (%s)", withSubnets)
 		log.Warn().Msgf(errMsg.Error())
-		return c.JSON(http.StatusBadRequest, model.SimpleMsg{Message: errMsg.Error()})
+		return common.EndRequestWithError(c, errMsg, http.StatusBadRequest)
 	}
 	if withSubnets == "" {
 		withSubnets = "false"
@@ -348,7 +406,7 @@ func RestDeleteDeregisterVNet(c echo.Context) error {
 	resp, err := resource.DeregisterVNet(nsId, vNetId, withSubnets)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return c.JSON(http.StatusInternalServerError, model.SimpleMsg{Message: err.Error()})
+		return common.EndRequestWithError(c, err, http.StatusInternalServerError)
 	}
 
 	// [Output] Return the deregistered result
@@ -19,6 +19,7 @@ import (
 
 	// "log"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -40,7 +41,6 @@ import (
 	rest_resource "github.com/cloud-barista/cb-tumblebug/src/api/rest/server/resource"
 	rest_netutil "github.com/cloud-barista/cb-tumblebug/src/api/rest/server/util"
 
-	"crypto/subtle"
 	"fmt"
 	"os"
 
@@ -89,6 +89,15 @@ const (
  ________________________________________________`
 )
 
+// envFloat returns the number in the environment variable or the default value if it is not set or invalid
+func envFloat(key string, defaultValue float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || v < 0 {
+		return defaultValue
+	}
+	return v
+}
+
 // RunServer func start Rest API server
 func RunServer() {
 
@@ -96,21 +105,52 @@ func RunServer() {
 
 	e := echo.New()
 
+	// The client IP (for the rate limit and the audit log) is not taken from the headers set by the client
+	// unless the request comes through the trusted proxies
+	e.IPExtractor = middlewares.ClientIpExtractor(os.Getenv("TB_TRUSTED_PROXIES"))
+
 	// Middleware
 	// e.Use(middleware.Logger())
 	APILogSkipPatterns := [][]string{
 		{"/tumblebug/api"},
 		{"/mci", "option=status"},
+		{"/tumblebug/metrics"},
 	}
 	e.Use(middlewares.Zerologger(APILogSkipPatterns))
 
+	// Custom middleware for request metrics (count and latency per route)
+	e.Use(middlewares.Metrics)
+
 	e.Use(middleware.Recover())
-	// limit the application to 20 requests/sec using the default in-memory store
-	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(20)))
+
+	// Grace period to wait for in-flight operations (e.g., MCI provisioning) on shutdown
+	shutdownGracePeriodSec, err := strconv.Atoi(common.NVL(os.Getenv("TB_SHUTDOWN_GRACE_PERIOD_SEC"), "60"))
+	if err != nil || shutdownGracePeriodSec < 0 {
+		shutdownGracePeriodSec = 60
+	}
+	// reject new mutating requests while the server is draining
+	e.Use(middlewares.Draining(shutdownGracePeriodSec))
+	// limit requests per client IP with separate limits for read and mutating routes
+	e.Use(middlewares.RateLimit(middlewares.RateLimitConfig{
+		ReadRate:    envFloat("TB_RATE_LIMIT_READ_RPS", 50),
+		ReadBurst:   int(envFloat("TB_RATE_LIMIT_READ_BURST", 100)),
+		WriteRate:   envFloat("TB_RATE_LIMIT_WRITE_RPS", 10),
+		WriteBurst:  int(envFloat("TB_RATE_LIMIT_WRITE_BURST", 20)),
+		ExemptPaths: []string{"/tumblebug/readyz", "/tumblebug/health", "/tumblebug/httpVersion", "/tumblebug/metrics"},
+	}))
+
+	// Custom middleware for the identity of client certificate (mutual TLS)
+	e.Use(middlewares.ClientCertIdentity)
 
 	// Custom middleware for RequestID and RequestDetails
 	e.Use(middlewares.RequestIdAndDetailsIssuer)
 
+	// Custom middleware to attach X-Request-Id (and the user) to events from the objects changed by the request
+	e.Use(middlewares.ObjectRequestTracker)
+
+	// Custom middleware for audit log of mutating API calls
+	e.Use(middlewares.Audit)
+
 	// Custom middleware for tracing
 	e.Use(middlewares.TracingMiddleware)
 
@@ -132,7 +172,9 @@ func RunServer() {
 	// e.GET("/tumblebug/swagger/*", echoSwagger.WrapHandler)
 	// e.GET("/tumblebug/swaggerActive", rest_common.RestGetSwagger)
 	e.GET("/tumblebug/readyz", rest_common.RestGetReadyz)
+	e.GET("/tumblebug/health", rest_common.RestGetHealth)
 	e.GET("/tumblebug/httpVersion", rest_common.RestCheckHTTPVersion)
+	e.GET("/tumblebug/metrics", rest_common.RestGetMetrics)
 	e.POST("tumblebug/testStreamResponse", rest_common.RestTestStreamResponse)
 
 	allowedOrigins := os.Getenv("TB_ALLOW_ORIGINS")
@@ -144,6 +186,8 @@ func RunServer() {
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{allowedOrigins},
 		AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete},
+		// dashboards use the ETag for conditional GETs (If-None-Match)
+		ExposeHeaders: []string{"ETag"},
 	}))
 
 	// Conditions to prevent abnormal operation due to typos (e.g., ture, falss, etc.)
@@ -153,13 +197,22 @@ func RunServer() {
 	apiUser := os.Getenv("TB_API_USERNAME")
 	apiPass := os.Getenv("TB_API_PASSWORD")
 
+	// Allow Prometheus to scrape the metrics endpoint without credentials
+	metricsAuthSkip := os.Getenv("TB_METRICS_AUTH_SKIP") == "true"
+
 	// Setup Middlewares for auth
 	var basicAuthMw echo.MiddlewareFunc
 	var jwtAuthMw echo.MiddlewareFunc
+	var apiKeyAuthMw echo.MiddlewareFunc
 
 	if authEnabled {
 		switch authMode {
 		case "basic":
+			// Setup users with roles (admin, readonly) for basic auth
+			basicAuthUsers, err := authmw.LoadBasicAuthUsers(os.Getenv("TB_API_USERS_FILE"), os.Getenv("TB_API_USERS"), apiUser, apiPass)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to load users for Basic Auth. Check TB_API_USERS or TB_API_USERS_FILE. EXITING...")
+			}
 			// Setup Basic Auth Middleware
 			basicAuthMw = middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
 				Skipper: func(c echo.Context) bool {
@@ -167,18 +220,14 @@ func RunServer() {
 						c.Path() == "/tumblebug/httpVersion" {
 						return true
 					}
-					return false
-				},
-				Validator: func(username, password string, c echo.Context) (bool, error) {
-					// Be careful to use constant time comparison to prevent timing attacks
-					if subtle.ConstantTimeCompare([]byte(username), []byte(apiUser)) == 1 &&
-						subtle.ConstantTimeCompare([]byte(password), []byte(apiPass)) == 1 {
-						return true, nil
+					if metricsAuthSkip && c.Path() == "/tumblebug/metrics" {
+						return true
 					}
-					return false, nil
+					return false
 				},
+				Validator: authmw.BasicAuthValidator(basicAuthUsers),
 			})
-			log.Info().Msg("Basic Auth Middleware is initialized successfully")
+			log.Info().Msgf("Basic Auth Middleware is initialized successfully (%d users)", len(basicAuthUsers))
 		case "jwt":
 			// Setup JWT Auth Middleware
 			err := authmw.InitJwtAuthMw(os.Getenv("TB_IAM_MANAGER_REST_URL"), "/api/auth/certs")
@@ -189,11 +238,22 @@ func RunServer() {
 					{"/tumblebug/readyz"},
 					{"/tumblebug/httpVersion"},
 				}
+				if metricsAuthSkip {
+					authSkipPatterns = append(authSkipPatterns, []string{"/tumblebug/metrics"})
+				}
 				jwtAuthMw = authmw.JwtAuthMw(authSkipPatterns)
 				log.Info().Msg("JWT Auth Middleware is initialized successfully")
 			}
+		case "apikey":
+			// Setup API Key Auth Middleware (the basic auth credential is used as the admin credential)
+			apiKeySkipPaths := []string{"/tumblebug/readyz", "/tumblebug/httpVersion"}
+			if metricsAuthSkip {
+				apiKeySkipPaths = append(apiKeySkipPaths, "/tumblebug/metrics")
+			}
+			apiKeyAuthMw = authmw.ApiKeyAuthMw(apiKeySkipPaths, apiUser, apiPass)
+			log.Info().Msg("API Key Auth Middleware is initialized successfully")
 		default:
-			log.Fatal().Msg("TB_AUTH_MODE is not set properly. Please set it to 'basic' or 'jwt'. EXITING...")
+			log.Fatal().Msg("TB_AUTH_MODE is not set properly. Please set it to 'basic', 'jwt' or 'apikey'. EXITING...")
 		}
 	}
 
@@ -201,6 +261,14 @@ func RunServer() {
 	if authEnabled && authMode == "basic" && basicAuthMw != nil {
 		log.Debug().Msg("Setting up Basic Auth Middleware for root group")
 		e.Use(basicAuthMw)
+		// readonly users can call read-only requests only (GET routes without secrets and read-only POST routes)
+		e.Use(authmw.RoleAuthorization())
+	}
+
+	// Set API key auth middleware for root group
+	if authEnabled && authMode == "apikey" && apiKeyAuthMw != nil {
+		log.Debug().Msg("Setting up API Key Auth Middleware for root group")
+		e.Use(apiKeyAuthMw)
 	}
 
 	// [Temp - start] For JWT auth test, a route group and an API
@@ -223,10 +291,20 @@ func RunServer() {
 
 	e.GET("/tumblebug/cloudInfo", rest_common.RestGetCloudInfo)
 	e.GET("/tumblebug/connConfig", rest_common.RestGetConnConfigList)
+	e.GET("/tumblebug/connConfig/export", rest_common.RestGetConnConfigExport)
+	e.POST("/tumblebug/connConfig/import", rest_common.RestPostConnConfigImport)
+	e.POST("/tumblebug/connConfig/verify", rest_common.RestPostVerifyAllConnConfig)
 	e.GET("/tumblebug/connConfig/:connConfigName", rest_common.RestGetConnConfig)
+	e.PUT("/tumblebug/connConfig/:connConfigName/rootDisk", rest_common.RestPutConnConfigRootDisk)
+	e.POST("/tumblebug/connConfig/:connConfigName/verify", rest_common.RestPostVerifyConnConfig)
 	e.GET("/tumblebug/provider", rest_common.RestGetProviderList)
+	e.GET("/tumblebug/provider/:providerName/capabilities", rest_common.RestGetProviderCapabilities)
+	e.GET("/tumblebug/capabilities", rest_common.RestGetCapabilityMatrix)
 	e.GET("/tumblebug/provider/:providerName/region", rest_common.RestGetRegions)
 	e.GET("/tumblebug/provider/:providerName/region/:regionName", rest_common.RestGetRegion)
+	e.POST("/tumblebug/cloudInfo/provider/:providerName/region/:regionName", rest_common.RestPostCustomRegion)
+	e.PUT("/tumblebug/cloudInfo/provider/:providerName/region/:regionName", rest_common.RestPutCustomRegion)
+	e.DELETE("/tumblebug/cloudInfo/provider/:providerName/region/:regionName", rest_common.RestDeleteCustomRegion)
 	e.GET("/tumblebug/regionFromCsp", rest_common.RestGetRegionListFromCsp)
 	e.GET("/tumblebug/k8sClusterInfo", rest_common.RestGetK8sClusterInfo)
 
@@ -245,6 +323,24 @@ func RunServer() {
 	e.POST("/tumblebug/registerCspResources", rest_common.RestRegisterCspNativeResources)
 	e.POST("/tumblebug/registerCspResourcesAll", rest_common.RestRegisterCspNativeResourcesAll)
 
+	e.POST("/tumblebug/apikeys", rest_common.RestPostApiKey)
+	e.GET("/tumblebug/apikeys", rest_common.RestGetAllApiKeys)
+	e.DELETE("/tumblebug/apikeys/:name", rest_common.RestDelApiKey)
+	e.POST("/tumblebug/apikeys/:name/rotate", rest_common.RestPostRotateApiKey)
+
+	e.POST("/tumblebug/webhooks", rest_common.RestPostWebhook)
+	e.GET("/tumblebug/webhooks", rest_common.RestGetAllWebhooks)
+	e.GET("/tumblebug/webhooks/:webhookId", rest_common.RestGetWebhook)
+	e.DELETE("/tumblebug/webhooks/:webhookId", rest_common.RestDelWebhook)
+	e.GET("/tumblebug/webhooks/:webhookId/deliveries", rest_common.RestGetWebhookDeliveries)
+	e.POST("/tumblebug/webhooks/:webhookId/test", rest_common.RestPostTestWebhook)
+
+	e.GET("/tumblebug/jobs", rest_common.RestGetAllJobs)
+	e.GET("/tumblebug/audit", rest_common.RestGetAuditRecords)
+	e.GET("/tumblebug/audit/verify", rest_common.RestVerifyAuditRecords)
+	e.GET("/tumblebug/jobs/:jobId", rest_common.RestGetJob)
+	e.DELETE("/tumblebug/jobs/:jobId", rest_common.RestCancelJob)
+
 	// @Tags [Admin] System Configuration
 	e.POST("/tumblebug/config", rest_common.RestPostConfig)
 	e.GET("/tumblebug/config/:configId", rest_common.RestGetConfig)
@@ -259,12 +355,22 @@ func RunServer() {
 
 	e.GET("/tumblebug/object", rest_common.RestGetObject)
 	e.GET("/tumblebug/objects", rest_common.RestGetObjects)
+	e.HEAD("/tumblebug/objects", rest_common.RestHeadObjects)
+	e.GET("/tumblebug/objects/stats", rest_common.RestGetObjectStats)
 	e.DELETE("/tumblebug/object", rest_common.RestDeleteObject)
 	e.DELETE("/tumblebug/objects", rest_common.RestDeleteObjects)
+	e.GET("/tumblebug/consistency", rest_common.RestGetConsistency)
+	e.POST("/tumblebug/consistency/repair", rest_common.RestPostConsistencyRepair)
+	e.POST("/tumblebug/admin/syncSpider", rest_common.RestPostSyncSpider)
+	e.GET("/tumblebug/admin/spiderVersion", rest_common.RestGetSpiderVersion)
+	e.POST("/tumblebug/admin/kvstore/migrate", rest_common.RestPostMigrateKvStore)
+	e.POST("/tumblebug/admin/gc", rest_common.RestPostGc)
 
 	e.GET("/tumblebug/loadAssets", rest_resource.RestLoadAssets)
 	e.POST("/tumblebug/ns/:nsId/sharedResource", rest_resource.RestCreateSharedResource)
 	e.DELETE("/tumblebug/ns/:nsId/sharedResources", rest_resource.RestDelAllSharedResources)
+	e.PUT("/tumblebug/ns/:nsId/resourceDefaults", rest_resource.RestPutNsResourceDefaults)
+	e.GET("/tumblebug/ns/:nsId/resourceDefaults", rest_resource.RestGetNsResourceDefaults)
 
 	e.POST("/tumblebug/forward/*", rest_common.RestForwardAnyReqToAny)
 
@@ -279,6 +385,9 @@ func RunServer() {
 	// Route for stream response subgroup
 	streamResponseGroup := e.Group("/tumblebug/stream-response/ns", common.NsValidation())
 
+	// Event stream (Server-Sent Events) of status changes in a namespace
+	streamResponseGroup.GET("/:nsId/events", rest_common.RestGetEventStream)
+
 	//Namespace Management
 	g.POST("", rest_common.RestPostNs)
 	g.GET("/:nsId", rest_common.RestGetNs)
@@ -286,10 +395,36 @@ func RunServer() {
 	g.PUT("/:nsId", rest_common.RestPutNs)
 	g.DELETE("/:nsId", rest_common.RestDelNs)
 	g.DELETE("", rest_common.RestDelAllNs)
+	g.GET("/:nsId/export", rest_common.RestGetNsExport)
+	g.POST("/:nsId/export", rest_common.RestPostNsExport)
+	g.POST("/import", rest_common.RestPostNsImport)
+	g.GET("/:nsId/locks", rest_common.RestGetNsLocks)
+	g.POST("/:nsId/apply", rest_infra.RestPostApplyNs)
+
+	// Trash of soft-deleted MCIs and resources
+	g.GET("/:nsId/trash", rest_infra.RestGetAllTrash)
+	g.GET("/:nsId/trash/:itemId", rest_infra.RestGetTrash)
+	g.POST("/:nsId/trash/:itemId/restore", rest_infra.RestPostRestoreTrash)
+	g.DELETE("/:nsId/trash/:itemId", rest_infra.RestDelTrash)
+
+	// Namespace Quota
+	g.PUT("/:nsId/quota", rest_common.RestPutNsQuota)
+	g.GET("/:nsId/quota", rest_common.RestGetNsQuota)
+	g.GET("/:nsId/quota/usage", rest_common.RestGetNsQuotaUsage)
+
+	// Namespace Naming Policy
+	g.PUT("/:nsId/namingPolicy", rest_common.RestPutNsNamingPolicy)
+	g.GET("/:nsId/namingPolicy", rest_common.RestGetNsNamingPolicy)
+	g.POST("/:nsId/namingPolicy/validate", rest_common.RestPostNsNamingPolicyValidate)
+
+	// Namespace Event Timeline
+	g.GET("/:nsId/events", rest_common.RestGetNsEvents)
 
 	// Resource Label
 	e.PUT("/tumblebug/label/:labelType/:uid", rest_label.RestCreateOrUpdateLabel)
 	e.DELETE("/tumblebug/label/:labelType/:uid/:key", rest_label.RestRemoveLabel)
+	e.POST("/tumblebug/label/cspTagSync", rest_label.RestResyncLabelsToCsp)
+	e.POST("/tumblebug/labels/bulk", rest_label.RestApplyLabelsBySelector)
 	e.GET("/tumblebug/label/:labelType/:uid", rest_label.RestGetLabels)
 	e.GET("/tumblebug/resources/:labelType", rest_label.RestGetResourcesByLabelSelector)
 	e.GET("/tumblebug/labelInfo", rest_label.RestGetSystemLabelInfo)
@@ -297,6 +432,7 @@ func RunServer() {
 	//MCI Management
 	g.POST("/:nsId/mci", rest_infra.RestPostMci)
 	g.POST("/:nsId/registerCspVm", rest_infra.RestPostRegisterCSPNativeVM)
+	g.POST("/:nsId/registerCspVm/bulk", rest_infra.RestPostImportCspVms)
 
 	e.POST("/tumblebug/mciRecommendVm", rest_infra.RestRecommendVm)
 	e.POST("/tumblebug/mciDynamicCheckRequest", rest_infra.RestPostMciDynamicCheckRequest)
@@ -320,12 +456,15 @@ func RunServer() {
 	g.GET("/:nsId/mci", rest_infra.RestGetAllMci, middleware.TimeoutWithConfig(timeoutConfig),
 		middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(2)))
 
-	// g.PUT("/:nsId/mci/:mciId", rest_infra.RestPutMci)
+	g.PUT("/:nsId/mci/:mciId", rest_infra.RestPutMci)
+	g.POST("/:nsId/mci/:mciId/move", rest_infra.RestPostMoveMci)
+	g.POST("/:nsId/mci/:mciId/actions/retryFailed", rest_infra.RestPostMciRetryFailed)
 	g.DELETE("/:nsId/mci/:mciId", rest_infra.RestDelMci)
 	g.DELETE("/:nsId/mci", rest_infra.RestDelAllMci)
 
 	g.POST("/:nsId/mci/:mciId/vm", rest_infra.RestPostMciVm)
 	g.GET("/:nsId/mci/:mciId/vm/:vmId", rest_infra.RestGetMciVm)
+	g.GET("/:nsId/mci/:mciId/vm/:vmId/console", rest_infra.RestGetMciVmConsole)
 	g.GET("/:nsId/mci/:mciId/subgroup", rest_infra.RestGetMciGroupIds)
 	g.GET("/:nsId/mci/:mciId/subgroup/:subgroupId", rest_infra.RestGetMciGroupVms)
 	g.POST("/:nsId/mci/:mciId/subgroup/:subgroupId", rest_infra.RestPostMciSubGroupScaleOut)
@@ -333,6 +472,8 @@ func RunServer() {
 	//g.GET("/:nsId/mci/:mciId/vm", rest_infra.RestGetAllMciVm)
 	// g.PUT("/:nsId/mci/:mciId/vm/:vmId", rest_infra.RestPutMciVm)
 	g.DELETE("/:nsId/mci/:mciId/vm/:vmId", rest_infra.RestDelMciVm)
+	g.PUT("/:nsId/mci/:mciId/protection", rest_infra.RestPutMciProtection)
+	g.PUT("/:nsId/mci/:mciId/vm/:vmId/protection", rest_infra.RestPutMciVmProtection)
 	//g.DELETE("/:nsId/mci/:mciId/vm", rest_infra.RestDelAllMciVm)
 
 	//g.POST("/:nsId/mci/recommend", rest_infra.RestPostMciRecommend)
@@ -342,6 +483,10 @@ func RunServer() {
 
 	g.POST("/:nsId/cmd/mci/:mciId", rest_infra.RestPostCmdMci)
 	g.POST("/:nsId/transferFile/mci/:mciId", rest_infra.RestPostFileToMci)
+	g.POST("/:nsId/mci/:mciId/installGpuDriver", rest_infra.RestPostInstallGpuDriverToMci)
+	g.POST("/:nsId/mci/:mciId/software/:name", rest_infra.RestPostMciSoftware)
+	g.GET("/:nsId/mci/:mciId/software/:name", rest_infra.RestGetMciSoftware)
+	g.DELETE("/:nsId/mci/:mciId/software/:name", rest_infra.RestDelMciSoftware)
 	g.PUT("/:nsId/mci/:mciId/vm/:targetVmId/bastion/:bastionVmId", rest_infra.RestSetBastionNodes)
 	g.DELETE("/:nsId/mci/:mciId/bastion/:bastionVmId", rest_infra.RestRemoveBastionNodes)
 	g.GET("/:nsId/mci/:mciId/vm/:targetVmId/bastion", rest_infra.RestGetBastionNodes)
@@ -350,6 +495,11 @@ func RunServer() {
 	g.POST("/:nsId/benchmark/mci/:mciId", rest_infra.RestGetBenchmark)
 	g.POST("/:nsId/benchmarkAll/mci/:mciId", rest_infra.RestGetAllBenchmark)
 	g.GET("/:nsId/benchmarkLatency/mci/:mciId", rest_infra.RestGetBenchmarkLatency)
+	g.GET("/:nsId/benchmarkLatency/result/:runId", rest_infra.RestGetBenchmarkLatencyResult)
+	g.POST("/:nsId/mci/:mciId/networkTest", rest_infra.RestPostNetworkTest)
+	g.GET("/:nsId/mci/:mciId/networkTest", rest_infra.RestGetAllNetworkTest)
+	g.GET("/:nsId/mci/:mciId/networkTest/:runId", rest_infra.RestGetNetworkTest)
+	g.POST("/:nsId/mci/:mciId/networkTest/:runId/suggestRules", rest_infra.RestPostNetworkTestSuggestRules)
 
 	// VPN Sites info
 	g.GET("/:nsId/mci/:mciId/site", rest_infra.RestGetSitesInMci)
@@ -360,6 +510,7 @@ func RunServer() {
 	streamResponseGroup.PUT("/:nsId/mci/:mciId/vpn/:vpnId", rest_infra.RestPutSiteToSiteVpn)
 	streamResponseGroup.DELETE("/:nsId/mci/:mciId/vpn/:vpnId", rest_infra.RestDeleteSiteToSiteVpn)
 	g.GET("/:nsId/mci/:mciId/vpn/:vpnId/request/:requestId", rest_infra.RestGetRequestStatusOfSiteToSiteVpn)
+	g.GET("/:nsId/mci/:mciId/vpn/:vpnId/health", rest_infra.RestGetSiteToSiteVpnHealth)
 	// TBD
 	// g.POST("/:nsId/mci/:mciId/vpn/:vpnId", rest_infra.RestPostVpnGcpToAws)
 	// g.PUT("/:nsId/mci/:mciId/vpn/:vpnId", rest_infra.RestPutVpnGcpToAws)
@@ -368,20 +519,32 @@ func RunServer() {
 	//MCI AUTO Policy
 	g.POST("/:nsId/policy/mci/:mciId", rest_infra.RestPostMciPolicy)
 	g.GET("/:nsId/policy/mci/:mciId", rest_infra.RestGetMciPolicy)
+	g.GET("/:nsId/policy/mci/:mciId/history", rest_infra.RestGetMciPolicyHistory)
 	g.GET("/:nsId/policy/mci", rest_infra.RestGetAllMciPolicy)
 	g.PUT("/:nsId/policy/mci/:mciId", rest_infra.RestPutMciPolicy)
 	g.DELETE("/:nsId/policy/mci/:mciId", rest_infra.RestDelMciPolicy)
 	g.DELETE("/:nsId/policy/mci", rest_infra.RestDelAllMciPolicy)
 
+	g.POST("/:nsId/policy/recovery/mci/:mciId", rest_infra.RestPostRecoveryPolicy)
+	g.GET("/:nsId/policy/recovery/mci/:mciId", rest_infra.RestGetRecoveryPolicy)
+	g.GET("/:nsId/policy/recovery", rest_infra.RestGetAllRecoveryPolicy)
+	g.PUT("/:nsId/policy/recovery/mci/:mciId", rest_infra.RestPutRecoveryPolicy)
+	g.DELETE("/:nsId/policy/recovery/mci/:mciId", rest_infra.RestDelRecoveryPolicy)
+
 	g.POST("/:nsId/monitoring/install/mci/:mciId", rest_infra.RestPostInstallMonitorAgentToMci)
 	g.GET("/:nsId/monitoring/mci/:mciId/metric/:metric", rest_infra.RestGetMonitorData)
+	g.GET("/:nsId/monitoring/mci/:mciId/metric/:metric/aggregate", rest_infra.RestGetMonitorDataAggregate)
 	g.PUT("/:nsId/monitoring/status/mci/:mciId/vm/:vmId", rest_infra.RestPutMonitorAgentStatusInstalled)
+	g.GET("/:nsId/monitoring/status/mci/:mciId", rest_infra.RestGetMonitorAgentStatus)
+	g.DELETE("/:nsId/monitoring/mci/:mciId", rest_infra.RestDelMonitorAgentFromMci)
 
 	// K8sCluster
 	e.GET("/tumblebug/availableK8sClusterVersion", rest_resource.RestGetAvailableK8sClusterVersion)
 	e.GET("/tumblebug/availableK8sClusterNodeImage", rest_resource.RestGetAvailableK8sClusterNodeImage)
 	e.GET("/tumblebug/checkNodeGroupsOnK8sCreation", rest_resource.RestCheckNodeGroupsOnK8sCreation)
 	g.POST("/:nsId/k8scluster", rest_resource.RestPostK8sCluster)
+	g.POST("/:nsId/k8sclusterDynamic", rest_resource.RestPostK8sClusterDynamic)
+	g.POST("/:nsId/k8sClusterDynamicCheckRequest", rest_resource.RestPostK8sClusterDynamicCheckRequest)
 	g.POST("/:nsId/k8scluster/:k8sClusterId/k8snodegroup", rest_resource.RestPostK8sNodeGroup)
 	g.DELETE("/:nsId/k8scluster/:k8sClusterId/k8snodegroup/:k8sNodeGroupName", rest_resource.RestDeleteK8sNodeGroup)
 	g.PUT("/:nsId/k8scluster/:k8sClusterId/k8snodegroup/:k8sNodeGroupName/onautoscaling", rest_resource.RestPutSetK8sNodeGroupAutoscaling)
@@ -390,6 +553,8 @@ func RunServer() {
 		middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(2)))
 	g.GET("/:nsId/k8scluster", rest_resource.RestGetAllK8sCluster, middleware.TimeoutWithConfig(timeoutConfig),
 		middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(2)))
+	g.GET("/:nsId/k8scluster/:k8sClusterId/kubeconfig", rest_resource.RestGetK8sClusterKubeconfig)
+	g.GET("/:nsId/k8scluster/:k8sClusterId/events", rest_resource.RestGetK8sClusterEvents)
 	g.DELETE("/:nsId/k8scluster/:k8sClusterId", rest_resource.RestDeleteK8sCluster)
 	g.DELETE("/:nsId/k8scluster", rest_resource.RestDeleteAllK8sCluster)
 	g.PUT("/:nsId/k8scluster/:k8sClusterId/upgrade", rest_resource.RestPutUpgradeK8sCluster)
@@ -406,6 +571,9 @@ func RunServer() {
 
 	// VM snapshot -> creates one customImage and 'n' dataDisks
 	g.POST("/:nsId/mci/:mciId/vm/:vmId/snapshot", rest_infra.RestPostMciVmSnapshot)
+	g.PUT("/:nsId/mci/:mciId/vm/:vmId/publicIp", rest_infra.RestPutMciVmPublicIp)
+	g.DELETE("/:nsId/mci/:mciId/vm/:vmId/publicIp", rest_infra.RestDelMciVmPublicIp)
+	g.POST("/:nsId/mci/:mciId/vm/:vmId/resize", rest_infra.RestPostMciVmResize)
 
 	// These REST APIs are for dev/test only
 	g.POST("/:nsId/mci/:mciId/nlb/:resourceId/vm", rest_infra.RestAddNLBVMs)
@@ -416,12 +584,30 @@ func RunServer() {
 	g.GET("/:nsId/resources/dataDisk/:resourceId", rest_resource.RestGetResource)
 	g.GET("/:nsId/resources/dataDisk", rest_resource.RestGetAllResources)
 	g.PUT("/:nsId/resources/dataDisk/:resourceId", rest_resource.RestPutDataDisk)
+	g.PUT("/:nsId/resources/dataDisk/:resourceId/size", rest_resource.RestPutDataDiskSize)
+	g.POST("/:nsId/resources/dataDisk/:resourceId/snapshot", rest_resource.RestPostDataDiskSnapshot)
+	g.GET("/:nsId/resources/dataDisk/:resourceId/snapshot", rest_resource.RestGetAllDataDiskSnapshot)
+	g.DELETE("/:nsId/resources/dataDisk/:resourceId/snapshot/:snapshotId", rest_resource.RestDelDataDiskSnapshot)
+	g.POST("/:nsId/resources/dataDisk/:resourceId/snapshot/:snapshotId/dataDisk", rest_resource.RestPostDataDiskFromSnapshot)
 	g.DELETE("/:nsId/resources/dataDisk/:resourceId", rest_resource.RestDelResource)
 	g.DELETE("/:nsId/resources/dataDisk", rest_resource.RestDelAllResources)
 	g.GET("/:nsId/mci/:mciId/vm/:vmId/dataDisk", rest_resource.RestGetVmDataDisk)
 	g.POST("/:nsId/mci/:mciId/vm/:vmId/dataDisk", rest_resource.RestPostVmDataDisk)
 	g.PUT("/:nsId/mci/:mciId/vm/:vmId/dataDisk", rest_resource.RestPutVmDataDisk)
 
+	g.POST("/:nsId/resources/objectStorage", rest_resource.RestPostObjectStorage)
+	g.GET("/:nsId/resources/objectStorage/:resourceId", rest_resource.RestGetResource)
+	g.GET("/:nsId/resources/objectStorage", rest_resource.RestGetAllResources)
+	g.PUT("/:nsId/resources/objectStorage/:resourceId", rest_resource.RestPutObjectStorage)
+	g.DELETE("/:nsId/resources/objectStorage/:resourceId", rest_resource.RestDelResource)
+	g.DELETE("/:nsId/resources/objectStorage", rest_resource.RestDelAllResources)
+
+	g.POST("/:nsId/resources/sqlDb", rest_resource.RestPostSqlDb)
+	g.GET("/:nsId/resources/sqlDb/:resourceId", rest_resource.RestGetResource)
+	g.GET("/:nsId/resources/sqlDb", rest_resource.RestGetAllResources)
+	g.DELETE("/:nsId/resources/sqlDb/:resourceId", rest_resource.RestDelResource)
+	g.DELETE("/:nsId/resources/sqlDb", rest_resource.RestDelAllResources)
+
 	g.POST("/:nsId/resources/image", rest_resource.RestPostImage)
 	g.GET("/:nsId/resources/image/:imageId", rest_resource.RestGetImage)
 	g.GET("/:nsId/resources/image", rest_resource.RestGetAllResources)
@@ -431,6 +617,7 @@ func RunServer() {
 
 	g.POST("/:nsId/resources/customImage", rest_resource.RestPostCustomImage)
 	g.GET("/:nsId/resources/customImage/:resourceId", rest_resource.RestGetResource)
+	g.POST("/:nsId/resources/customImage/:resourceId/copy", rest_resource.RestPostCustomImageCopy)
 	g.GET("/:nsId/resources/customImage", rest_resource.RestGetAllResources)
 	// g.PUT("/:nsId/resources/customImage/:resourceId", rest_resource.RestPutCustomImage)
 	g.DELETE("/:nsId/resources/customImage/:resourceId", rest_resource.RestDelResource)
@@ -442,6 +629,7 @@ func RunServer() {
 	g.PUT("/:nsId/resources/sshKey/:resourceId", rest_resource.RestPutSshKey)
 	g.DELETE("/:nsId/resources/sshKey/:resourceId", rest_resource.RestDelResource)
 	g.DELETE("/:nsId/resources/sshKey", rest_resource.RestDelAllResources)
+	e.POST("/tumblebug/sshKey/encryptPrivateKeys", rest_resource.RestPostEncryptSshKeys)
 
 	g.POST("/:nsId/resources/spec", rest_resource.RestPostSpec)
 	g.GET("/:nsId/resources/spec/:resourceId", rest_resource.RestGetSpec)
@@ -466,6 +654,7 @@ func RunServer() {
 
 	// Network management: vNet
 	g.POST("/:nsId/resources/vNet", rest_resource.RestPostVNet)
+	g.POST("/:nsId/resources/vNet/validate", rest_resource.RestPostVNetValidate)
 	g.GET("/:nsId/resources/vNet/:vNetId", rest_resource.RestGetVNet)
 	g.GET("/:nsId/resources/vNet", rest_resource.RestGetAllResources)
 	// g.PUT("/:nsId/resources/vNet/:resourceId", rest_resource.RestPutVNet)
@@ -486,6 +675,22 @@ func RunServer() {
 	g.POST("/:nsId/registerCspResource/vNet/:vNetId/subnet", rest_resource.RestPostRegisterSubnet)
 	g.DELETE("/:nsId/deregisterCspResource/vNet/:vNetId/subnet/:subnetId", rest_resource.RestDeleteDeregisterSubnet)
 
+	// Security group management: register securityGroup, which was created in CSP
+	g.POST("/:nsId/registerCspResource/securityGroup", rest_resource.RestPostRegisterSecurityGroup)
+	g.DELETE("/:nsId/deregisterCspResource/securityGroup/:securityGroupId", rest_resource.RestDeleteDeregisterSecurityGroup)
+
+	// SSH key management: register sshKey, which was created in CSP
+	g.POST("/:nsId/registerCspResource/sshKey", rest_resource.RestPostRegisterSshKey)
+	g.DELETE("/:nsId/deregisterCspResource/sshKey/:sshKeyId", rest_resource.RestDeleteDeregisterSshKey)
+
+	// Data disk management: register dataDisk, which was created in CSP
+	g.POST("/:nsId/registerCspResource/dataDisk", rest_resource.RestPostRegisterDataDisk)
+	g.DELETE("/:nsId/deregisterCspResource/dataDisk/:dataDiskId", rest_resource.RestDeleteDeregisterDataDisk)
+
+	// K8sCluster management: register K8sCluster, which was created in CSP
+	g.POST("/:nsId/registerCspResource/k8sCluster", rest_resource.RestPostRegisterK8sCluster)
+	g.DELETE("/:nsId/deregisterCspResource/k8sCluster/:k8sClusterId", rest_resource.RestDeleteDeregisterK8sCluster)
+
 	/*
 		g.POST("/:nsId/resources/publicIp", resource.RestPostPublicIp)
 		g.GET("/:nsId/resources/publicIp/:publicIpId", resource.RestGetPublicIp)
@@ -522,9 +727,25 @@ func RunServer() {
 	selfIp := selfEndpoint[0]
 	selfPort := selfEndpoint[1]
 
-	apiServer := fmt.Sprintf("http://%s:%s/tumblebug/readyz", selfIp, selfPort)
+	// Native TLS (optional mutual TLS with client CA)
+	tlsCertFile := os.Getenv("TB_TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TB_TLS_KEY_FILE")
+	tlsClientCAFile := os.Getenv("TB_TLS_CLIENT_CA")
+	tlsEnabled := tlsCertFile != "" || tlsKeyFile != ""
+	if tlsEnabled && (tlsCertFile == "" || tlsKeyFile == "") {
+		log.Fatal().Msg("Both TB_TLS_CERT_FILE and TB_TLS_KEY_FILE are required to enable TLS. EXITING...")
+	}
+	if !tlsEnabled && tlsClientCAFile != "" {
+		log.Fatal().Msg("TB_TLS_CLIENT_CA requires TB_TLS_CERT_FILE and TB_TLS_KEY_FILE. EXITING...")
+	}
+	scheme := "http"
+	if tlsEnabled {
+		scheme = "https"
+	}
+
+	apiServer := fmt.Sprintf("%s://%s:%s/tumblebug/readyz", scheme, selfIp, selfPort)
 	//apiDashboard := fmt.Sprintf("http://%s:%s", selfIp, "1325")
-	apiDashboard := fmt.Sprintf("http://%s:%s/tumblebug/api", selfIp, selfPort)
+	apiDashboard := fmt.Sprintf("%s://%s:%s/tumblebug/api", scheme, selfIp, selfPort)
 	mapUI := fmt.Sprintf("http://%s:%s", selfIp, "1324")
 
 	fmt.Print(resetColor)
@@ -552,6 +773,18 @@ func RunServer() {
 		os.Interrupt, syscall.SIGKILL, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
+	if tlsEnabled {
+		tlsConfig, err := newTLSConfig(gracefulShutdownContext, tlsCertFile, tlsKeyFile, tlsClientCAFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to configure TLS. Check TB_TLS_CERT_FILE, TB_TLS_KEY_FILE and TB_TLS_CLIENT_CA. EXITING...")
+		}
+		e.TLSServer.TLSConfig = tlsConfig
+		e.TLSServer.Addr = ":" + selfPort
+		if tlsClientCAFile != "" {
+			log.Info().Msg("Mutual TLS is enabled (client certificates are required)")
+		}
+	}
+
 	// Wait graceful shutdown (and then main thread will be finished)
 	var wg sync.WaitGroup
 
@@ -562,6 +795,14 @@ func RunServer() {
 		// Block until a signal is triggered
 		<-gracefulShutdownContext.Done()
 
+		// Stop accepting new mutating requests and wait for in-flight operations
+		log.Info().Msg("Draining CB-Tumblebug API Server...")
+		common.StartDraining()
+		remaining := common.WaitForOperations(time.Duration(shutdownGracePeriodSec) * time.Second)
+		if remaining > 0 {
+			log.Warn().Msgf("%d in-flight operations are interrupted by %s", remaining, common.ShutdownReason)
+		}
+
 		log.Info().Msg("Stopping CB-Tumblebug API Server gracefully... (within 10s)")
 		ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
 		defer cancel()
@@ -573,7 +814,13 @@ func RunServer() {
 	}(&wg)
 
 	model.SystemReady = true
-	if err := e.Start(":" + selfPort); err != nil && err != http.ErrServerClosed {
+	if tlsEnabled {
+		// the certificate is served by TLSConfig.GetCertificate to allow reloading without restart
+		err = e.StartServer(e.TLSServer)
+	} else {
+		err = e.Start(":" + selfPort)
+	}
+	if err != nil && err != http.ErrServerClosed {
 		log.Error().Err(err).Msg("Error in Starting CB-Tumblebug API Server")
 		e.Logger.Panic("Shuttig down the server: ", err)
 	}
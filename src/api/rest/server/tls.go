@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server is to handle REST API
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// certReloader serves the server certificate and reloads it from files without restarting the server
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// newCertReloader loads the certificate and key files (fails if they are unreadable or mismatched)
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the certificate again. The current certificate is kept if the files are invalid.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate (%s) and key (%s): %w", r.certFile, r.keyFile, err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate returns the current certificate (for tls.Config.GetCertificate)
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch reloads the certificate on SIGHUP or when the certificate or key file is changed until ctx is done
+func (r *certReloader) watch(ctx context.Context) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	var events chan fsnotify.Event
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to watch TLS certificate files (reload by SIGHUP only)")
+	} else {
+		// watch the directories since certificates are often replaced by rename or symlink swap
+		for _, dir := range []string{filepath.Dir(r.certFile), filepath.Dir(r.keyFile)} {
+			if err := watcher.Add(dir); err != nil {
+				log.Warn().Err(err).Msgf("Failed to watch directory (%s) of TLS certificate files", dir)
+			}
+		}
+		events = watcher.Events
+	}
+
+	go func() {
+		defer signal.Stop(sighup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sighup:
+				log.Info().Msg("SIGHUP is received. Reloading TLS certificate")
+			case event, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				name := filepath.Clean(event.Name)
+				if name != filepath.Clean(r.certFile) && name != filepath.Clean(r.keyFile) &&
+					filepath.Base(name) != "..data" {
+					continue
+				}
+				log.Info().Msgf("TLS certificate file is changed (%s). Reloading TLS certificate", event.Name)
+			}
+			if err := r.reload(); err != nil {
+				log.Error().Err(err).Msg("Failed to reload TLS certificate. The current certificate is kept")
+				continue
+			}
+			log.Info().Msg("TLS certificate is reloaded")
+		}
+	}()
+}
+
+// newTLSConfig returns the TLS configuration of the API server.
+// If clientCAFile is given, clients must present a certificate signed by the CA (mutual TLS).
+func newTLSConfig(ctx context.Context, certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	reloader.watch(ctx)
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if clientCAFile != "" {
+		caPem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file (%s): %w", clientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("no valid certificate is found in client CA file (%s)", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
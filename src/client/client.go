@@ -0,0 +1,317 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is the Go client of the CB-Tumblebug REST API.
+//
+// The request and response types are the ones of the model package, so the client stays in sync with the server.
+//
+//	tb := client.New("http://localhost:1323/tumblebug", client.WithBasicAuth("default", "default"))
+//	mci, err := tb.CreateMciDynamic(ctx, "default", &model.TbMciDynamicReq{...}, "")
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultBaseUrl is the base URL of a local CB-Tumblebug
+const DefaultBaseUrl = "http://localhost:1323/tumblebug"
+
+// headerRequestId is the header of the request ID (the client does not depend on the server packages for it)
+const headerRequestId = "X-Request-Id"
+
+// RetryPolicy is the retry policy of the requests
+type RetryPolicy struct {
+	MaxAttempts   int           // total number of attempts (1 disables retry)
+	Backoff       time.Duration // wait before the 2nd attempt (doubled for each next attempt)
+	MaxBackoff    time.Duration // upper bound of the wait
+	NonIdempotent bool          // retry POST as well (only idempotent methods are retried by default)
+}
+
+// DefaultRetryPolicy returns the default retry policy (3 attempts with the backoff from 500ms up to 5s)
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, Backoff: 500 * time.Millisecond, MaxBackoff: 5 * time.Second}
+}
+
+// shouldRetry returns true if the attempt (starts from 1) failed by a transport error, a gateway status
+// or the rate limit and should be retried by the policy
+func (p RetryPolicy) shouldRetry(method string, attempt int, err error, statusCode int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+	default:
+		if !p.NonIdempotent {
+			return false
+		}
+	}
+	if err != nil {
+		return true
+	}
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// wait returns the wait before the next attempt (attempt starts from 1)
+func (p RetryPolicy) wait(attempt int) time.Duration {
+	d := p.Backoff << (attempt - 1)
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Client is the client of the CB-Tumblebug REST API
+type Client struct {
+	baseUrl string
+	http    *resty.Client
+	retry   RetryPolicy
+	timeout time.Duration
+}
+
+// options are the options of the client
+type options struct {
+	httpClient *http.Client
+	username   string
+	password   string
+	token      string
+	apiKey     string
+	timeout    time.Duration
+	retry      RetryPolicy
+}
+
+// Option is an option of the client
+type Option func(*options)
+
+// WithBasicAuth sets the credential for TB_AUTH_MODE=basic (or the admin of TB_AUTH_MODE=apikey)
+func WithBasicAuth(username string, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithBearerToken sets the token for TB_AUTH_MODE=jwt
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithApiKey sets the API key for TB_AUTH_MODE=apikey
+func WithApiKey(key string) Option {
+	return func(o *options) {
+		o.apiKey = key
+	}
+}
+
+// WithRetryPolicy overrides the retry policy (DefaultRetryPolicy by default)
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithTimeout sets the timeout of each request (streaming requests are limited only by the context)
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithHttpClient sets the underlying HTTP client (e.g., for custom TLS or transport)
+func WithHttpClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// New returns the client of the CB-Tumblebug REST API at baseUrl (e.g., http://localhost:1323/tumblebug)
+func New(baseUrl string, opts ...Option) *Client {
+	if baseUrl == "" {
+		baseUrl = DefaultBaseUrl
+	}
+	o := options{retry: DefaultRetryPolicy()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	httpClient := resty.New()
+	if o.httpClient != nil {
+		httpClient = resty.NewWithClient(o.httpClient)
+	}
+	if o.username != "" {
+		httpClient.SetBasicAuth(o.username, o.password)
+	}
+	if o.token != "" {
+		httpClient.SetAuthToken(o.token)
+	}
+	if o.apiKey != "" {
+		httpClient.SetHeader(model.ApiKeyHeader, o.apiKey)
+	}
+
+	c := &Client{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		http:    httpClient,
+		retry:   o.retry,
+		timeout: o.timeout,
+	}
+	return c
+}
+
+// APIError is the error response of the REST API
+type APIError struct {
+	StatusCode int
+	model.ErrorResponse
+}
+
+// Error returns the error message with the status and the error code
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("tumblebug: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("tumblebug: %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if the error means the object does not exist
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.Code == model.ErrCodeResourceNotFound)
+}
+
+// requestIdKey is the context key of the request ID
+type requestIdKey struct{}
+
+// ContextWithRequestId returns the context to send the request ID (X-Request-Id) with the requests,
+// so that the progress of a long-running request can be retrieved by GetRequest
+func ContextWithRequestId(ctx context.Context, reqId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, reqId)
+}
+
+// RequestInfo is the information of a request handled by the server
+type RequestInfo struct {
+	Method string            `json:"method"`
+	URL    string            `json:"url"`
+	Header map[string]string `json:"header"`
+	Body   interface{}       `json:"body,omitempty"`
+}
+
+// RequestDetails is the details and the progress of a request handled by the server
+type RequestDetails struct {
+	StartTime     time.Time   `json:"startTime"`
+	EndTime       time.Time   `json:"endTime"`
+	Status        string      `json:"status"` // e.g., Handling, Error, Success
+	RequestInfo   RequestInfo `json:"requestInfo"`
+	ResponseData  interface{} `json:"responseData"`
+	ErrorResponse string      `json:"errorResponse"`
+	DurationMs    int64       `json:"durationMs"`
+	ResponseSize  int         `json:"responseSize"`
+}
+
+// GetRequest returns the details and the progress of the request (GET /request/{reqId})
+func (c *Client) GetRequest(ctx context.Context, reqId string) (*RequestDetails, error) {
+	result := &RequestDetails{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/request/" + url.PathEscape(reqId), result: result})
+	return result, err
+}
+
+// request is a request to the REST API
+type request struct {
+	method string
+	path   string
+	query  map[string]string
+	header map[string]string
+	body   interface{}
+	result interface{}
+}
+
+// do sends the request with retries by the retry policy and decodes the result or the error response
+func (c *Client) do(ctx context.Context, r request) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	url := c.baseUrl + r.path
+	var resp *resty.Response
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		req := c.http.R().SetContext(ctx)
+		if r.query != nil {
+			req.SetQueryParams(r.query)
+		}
+		if r.header != nil {
+			req.SetHeaders(r.header)
+		}
+		if reqId, ok := ctx.Value(requestIdKey{}).(string); ok && reqId != "" {
+			req.SetHeader(headerRequestId, reqId)
+		}
+		if r.body != nil {
+			req.SetHeader("Content-Type", "application/json").SetBody(r.body)
+		}
+
+		resp, err = req.Execute(r.method, url)
+
+		statusCode := 0
+		if err == nil {
+			statusCode = resp.StatusCode()
+		}
+		if ctx.Err() != nil || !c.retry.shouldRetry(r.method, attempt, err, statusCode) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.retry.wait(attempt)):
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("tumblebug: %s %s: %w", r.method, r.path, err)
+	}
+	if resp.IsError() {
+		return newAPIError(resp.StatusCode(), resp.Body())
+	}
+	if r.result != nil && len(resp.Body()) > 0 {
+		if err := json.Unmarshal(resp.Body(), r.result); err != nil {
+			return fmt.Errorf("tumblebug: %s %s: invalid response: %w", r.method, r.path, err)
+		}
+	}
+	return nil
+}
+
+// newAPIError returns the APIError by the error response body (the message is the body if it is not an ErrorResponse)
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+	if json.Unmarshal(body, &apiErr.ErrorResponse) != nil || apiErr.Message == "" {
+		apiErr.ErrorResponse = model.ErrorResponse{Message: strings.TrimSpace(string(body))}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is the Go client of the CB-Tumblebug REST API.
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// mciPath returns the path of the MCI (or all MCIs if mciId is empty)
+func mciPath(nsId string, mciId string) string {
+	path := "/ns/" + url.PathEscape(nsId) + "/mci"
+	if mciId != "" {
+		path += "/" + url.PathEscape(mciId)
+	}
+	return path
+}
+
+// CreateMciDynamic creates an MCI by common specs and images (POST /ns/{nsId}/mciDynamic).
+// deployOption is "hold" to hold the provisioning until the continue action, or empty.
+// Use ContextWithRequestId to track the progress by GetRequest.
+func (c *Client) CreateMciDynamic(ctx context.Context, nsId string, req *model.TbMciDynamicReq, deployOption string) (*model.TbMciInfo, error) {
+	result := &model.TbMciInfo{}
+	r := request{method: http.MethodPost, path: "/ns/" + url.PathEscape(nsId) + "/mciDynamic", body: req, result: result}
+	if deployOption != "" {
+		r.query = map[string]string{"option": deployOption}
+	}
+	err := c.do(ctx, r)
+	return result, err
+}
+
+// CheckMciDynamicRequest checks the available connection configs for the common specs and images of an MCI dynamic request
+// (POST /mciDynamicCheckRequest)
+func (c *Client) CheckMciDynamicRequest(ctx context.Context, req *model.MciConnectionConfigCandidatesReq) (*model.CheckMciDynamicReqInfo, error) {
+	result := &model.CheckMciDynamicReqInfo{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/mciDynamicCheckRequest", body: req, result: result})
+	return result, err
+}
+
+// GetMci returns the MCI with the current status of VMs (GET /ns/{nsId}/mci/{mciId})
+func (c *Client) GetMci(ctx context.Context, nsId string, mciId string) (*model.TbMciInfo, error) {
+	result := &model.TbMciInfo{}
+	err := c.do(ctx, request{method: http.MethodGet, path: mciPath(nsId, mciId), result: result})
+	return result, err
+}
+
+// GetMciStatus returns the status of the MCI and its VMs (GET /ns/{nsId}/mci/{mciId}?option=status)
+func (c *Client) GetMciStatus(ctx context.Context, nsId string, mciId string) (*model.MciStatusInfo, error) {
+	var result struct {
+		Status *model.MciStatusInfo `json:"status"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: mciPath(nsId, mciId), query: map[string]string{"option": "status"}, result: &result})
+	if result.Status == nil {
+		result.Status = &model.MciStatusInfo{}
+	}
+	return result.Status, err
+}
+
+// ListMci returns all MCIs in the namespace (GET /ns/{nsId}/mci)
+func (c *Client) ListMci(ctx context.Context, nsId string) ([]model.TbMciInfo, error) {
+	var result struct {
+		Mci []model.TbMciInfo `json:"mci"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: mciPath(nsId, ""), result: &result})
+	return result.Mci, err
+}
+
+// ListMciId returns the IDs of all MCIs in the namespace (GET /ns/{nsId}/mci?option=id)
+func (c *Client) ListMciId(ctx context.Context, nsId string) ([]string, error) {
+	result := &model.IdList{}
+	err := c.do(ctx, request{method: http.MethodGet, path: mciPath(nsId, ""), query: map[string]string{"option": "id"}, result: result})
+	return result.IdList, err
+}
+
+// ControlMci performs the action (suspend, resume, reboot, terminate, refine, continue, withdraw) for the MCI
+// (GET /ns/{nsId}/control/mci/{mciId})
+func (c *Client) ControlMci(ctx context.Context, nsId string, mciId string, action string, force bool) (*model.SimpleMsg, error) {
+	result := &model.SimpleMsg{}
+	query := map[string]string{"action": action, "force": strconv.FormatBool(force)}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/ns/" + url.PathEscape(nsId) + "/control/mci/" + url.PathEscape(mciId), query: query, result: result})
+	return result, err
+}
+
+// DeleteMci deletes the MCI (DELETE /ns/{nsId}/mci/{mciId}).
+// option is "terminate" to terminate VMs before the deletion, "force" to delete the object regardless of the status, or empty.
+func (c *Client) DeleteMci(ctx context.Context, nsId string, mciId string, option string) ([]string, error) {
+	result := &model.IdList{}
+	r := request{method: http.MethodDelete, path: mciPath(nsId, mciId), result: result}
+	if option != "" {
+		r.query = map[string]string{"option": option}
+	}
+	err := c.do(ctx, r)
+	return result.IdList, err
+}
+
+// SendCommandToMci runs the command on the VMs of the MCI by SSH (POST /ns/{nsId}/cmd/mci/{mciId}).
+// Set subGroupId or vmId to run the command only on the VMs of the subgroup or the VM.
+func (c *Client) SendCommandToMci(ctx context.Context, nsId string, mciId string, req *model.MciCmdReq, subGroupId string, vmId string) (*model.MciSshCmdResult, error) {
+	result := &model.MciSshCmdResult{}
+	query := map[string]string{}
+	if subGroupId != "" {
+		query["subGroupId"] = subGroupId
+	}
+	if vmId != "" {
+		query["vmId"] = vmId
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/ns/" + url.PathEscape(nsId) + "/cmd/mci/" + url.PathEscape(mciId), query: query, body: req, result: result})
+	return result, err
+}
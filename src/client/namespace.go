@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is the Go client of the CB-Tumblebug REST API.
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// CreateNamespace creates a namespace (POST /ns)
+func (c *Client) CreateNamespace(ctx context.Context, req *model.NsReq) (*model.NsInfo, error) {
+	result := &model.NsInfo{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/ns", body: req, result: result})
+	return result, err
+}
+
+// GetNamespace returns the namespace (GET /ns/{nsId})
+func (c *Client) GetNamespace(ctx context.Context, nsId string) (*model.NsInfo, error) {
+	result := &model.NsInfo{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/ns/" + url.PathEscape(nsId), result: result})
+	return result, err
+}
+
+// ListNamespaces returns all namespaces (GET /ns)
+func (c *Client) ListNamespaces(ctx context.Context) ([]model.NsInfo, error) {
+	var result struct {
+		Ns []model.NsInfo `json:"ns"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/ns", result: &result})
+	return result.Ns, err
+}
+
+// UpdateNamespace updates the namespace (PUT /ns/{nsId})
+func (c *Client) UpdateNamespace(ctx context.Context, nsId string, req *model.NsReq) (*model.NsInfo, error) {
+	result := &model.NsInfo{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/ns/" + url.PathEscape(nsId), body: req, result: result})
+	return result, err
+}
+
+// DeleteNamespace deletes the namespace, which should be empty (DELETE /ns/{nsId})
+func (c *Client) DeleteNamespace(ctx context.Context, nsId string) (*model.SimpleMsg, error) {
+	result := &model.SimpleMsg{}
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/ns/" + url.PathEscape(nsId), result: result})
+	return result, err
+}
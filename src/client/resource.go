@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is the Go client of the CB-Tumblebug REST API.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// resourcePath returns the path of the resource (or all resources of the type if resourceId is empty)
+func resourcePath(nsId string, resourceType string, resourceId string) string {
+	path := "/ns/" + url.PathEscape(nsId) + "/resources/" + resourceType
+	if resourceId != "" {
+		path += "/" + url.PathEscape(resourceId)
+	}
+	return path
+}
+
+// CreateResource creates a resource of the type (POST /ns/{nsId}/resources/{resourceType}) and returns it as T
+// (e.g., CreateResource[model.TbDataDiskInfo](ctx, c, "default", model.StrDataDisk, &model.TbDataDiskReq{...}, ""))
+func CreateResource[T any](ctx context.Context, c *Client, nsId string, resourceType string, req interface{}, option string) (*T, error) {
+	result := new(T)
+	r := request{method: http.MethodPost, path: resourcePath(nsId, resourceType, ""), body: req, result: result}
+	if option != "" {
+		r.query = map[string]string{"option": option}
+	}
+	err := c.do(ctx, r)
+	return result, err
+}
+
+// GetResource returns the resource of the type as T (GET /ns/{nsId}/resources/{resourceType}/{resourceId})
+func GetResource[T any](ctx context.Context, c *Client, nsId string, resourceType string, resourceId string) (*T, error) {
+	result := new(T)
+	err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(nsId, resourceType, resourceId), result: result})
+	return result, err
+}
+
+// ListResources returns the resources of the type as []T (GET /ns/{nsId}/resources/{resourceType}).
+// Set filterKey and filterVal to list the resources whose field (filterKey) is filterVal only.
+func ListResources[T any](ctx context.Context, c *Client, nsId string, resourceType string, filterKey string, filterVal string) ([]T, error) {
+	// the list is wrapped by the resource type (e.g., {"vNet": [...]})
+	result := map[string]json.RawMessage{}
+	query := map[string]string{}
+	if filterKey != "" {
+		query["filterKey"] = filterKey
+		query["filterVal"] = filterVal
+	}
+	if err := c.do(ctx, request{method: http.MethodGet, path: resourcePath(nsId, resourceType, ""), query: query, result: &result}); err != nil {
+		return nil, err
+	}
+	list := []T{}
+	if raw, ok := result[resourceType]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// DeleteResource deletes the resource of the type (DELETE /ns/{nsId}/resources/{resourceType}/{resourceId})
+func DeleteResource(ctx context.Context, c *Client, nsId string, resourceType string, resourceId string, force bool) (*model.SimpleMsg, error) {
+	result := &model.SimpleMsg{}
+	query := map[string]string{"force": strconv.FormatBool(force)}
+	err := c.do(ctx, request{method: http.MethodDelete, path: resourcePath(nsId, resourceType, resourceId), query: query, result: result})
+	return result, err
+}
+
+// CreateVNet creates a vNet with the subnets (POST /ns/{nsId}/resources/vNet)
+func (c *Client) CreateVNet(ctx context.Context, nsId string, req *model.TbVNetReq) (*model.TbVNetInfo, error) {
+	return CreateResource[model.TbVNetInfo](ctx, c, nsId, model.StrVNet, req, "")
+}
+
+// GetVNet returns the vNet (GET /ns/{nsId}/resources/vNet/{vNetId})
+func (c *Client) GetVNet(ctx context.Context, nsId string, vNetId string) (*model.TbVNetInfo, error) {
+	return GetResource[model.TbVNetInfo](ctx, c, nsId, model.StrVNet, vNetId)
+}
+
+// ListVNets returns the vNets in the namespace (GET /ns/{nsId}/resources/vNet)
+func (c *Client) ListVNets(ctx context.Context, nsId string) ([]model.TbVNetInfo, error) {
+	return ListResources[model.TbVNetInfo](ctx, c, nsId, model.StrVNet, "", "")
+}
+
+// DeleteVNet deletes the vNet (DELETE /ns/{nsId}/resources/vNet/{vNetId}).
+// action is "withsubnets" to delete the subnets together, "refine" to remove the object only if it does not exist in the CSP, "force", or empty.
+func (c *Client) DeleteVNet(ctx context.Context, nsId string, vNetId string, action string) (*model.SimpleMsg, error) {
+	result := &model.SimpleMsg{}
+	r := request{method: http.MethodDelete, path: resourcePath(nsId, model.StrVNet, vNetId), result: result}
+	if action != "" {
+		r.query = map[string]string{"action": action}
+	}
+	err := c.do(ctx, r)
+	return result, err
+}
+
+// CreateSecurityGroup creates a security group (POST /ns/{nsId}/resources/securityGroup)
+func (c *Client) CreateSecurityGroup(ctx context.Context, nsId string, req *model.TbSecurityGroupReq) (*model.TbSecurityGroupInfo, error) {
+	return CreateResource[model.TbSecurityGroupInfo](ctx, c, nsId, model.StrSecurityGroup, req, "")
+}
+
+// GetSecurityGroup returns the security group (GET /ns/{nsId}/resources/securityGroup/{securityGroupId})
+func (c *Client) GetSecurityGroup(ctx context.Context, nsId string, securityGroupId string) (*model.TbSecurityGroupInfo, error) {
+	return GetResource[model.TbSecurityGroupInfo](ctx, c, nsId, model.StrSecurityGroup, securityGroupId)
+}
+
+// ListSecurityGroups returns the security groups in the namespace (GET /ns/{nsId}/resources/securityGroup)
+func (c *Client) ListSecurityGroups(ctx context.Context, nsId string) ([]model.TbSecurityGroupInfo, error) {
+	return ListResources[model.TbSecurityGroupInfo](ctx, c, nsId, model.StrSecurityGroup, "", "")
+}
+
+// DeleteSecurityGroup deletes the security group (DELETE /ns/{nsId}/resources/securityGroup/{securityGroupId})
+func (c *Client) DeleteSecurityGroup(ctx context.Context, nsId string, securityGroupId string, force bool) (*model.SimpleMsg, error) {
+	return DeleteResource(ctx, c, nsId, model.StrSecurityGroup, securityGroupId, force)
+}
+
+// CreateSshKey creates an SSH key (POST /ns/{nsId}/resources/sshKey); the private key is returned only by this call
+func (c *Client) CreateSshKey(ctx context.Context, nsId string, req *model.TbSshKeyReq) (*model.TbSshKeyInfo, error) {
+	return CreateResource[model.TbSshKeyInfo](ctx, c, nsId, model.StrSSHKey, req, "")
+}
+
+// GetSshKey returns the SSH key (GET /ns/{nsId}/resources/sshKey/{sshKeyId})
+func (c *Client) GetSshKey(ctx context.Context, nsId string, sshKeyId string) (*model.TbSshKeyInfo, error) {
+	return GetResource[model.TbSshKeyInfo](ctx, c, nsId, model.StrSSHKey, sshKeyId)
+}
+
+// ListSshKeys returns the SSH keys in the namespace (GET /ns/{nsId}/resources/sshKey)
+func (c *Client) ListSshKeys(ctx context.Context, nsId string) ([]model.TbSshKeyInfo, error) {
+	return ListResources[model.TbSshKeyInfo](ctx, c, nsId, model.StrSSHKey, "", "")
+}
+
+// DeleteSshKey deletes the SSH key (DELETE /ns/{nsId}/resources/sshKey/{sshKeyId})
+func (c *Client) DeleteSshKey(ctx context.Context, nsId string, sshKeyId string, force bool) (*model.SimpleMsg, error) {
+	return DeleteResource(ctx, c, nsId, model.StrSSHKey, sshKeyId, force)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is the Go client of the CB-Tumblebug REST API.
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	networkSiteModel "github.com/cloud-barista/cb-tumblebug/src/api/rest/server/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// ErrStopStream can be returned by a stream handler to stop the stream without an error
+var ErrStopStream = errors.New("stop the stream")
+
+// openStream sends the request of a stream-response API and returns the response body to read the stream.
+// Streaming requests are not retried since a part of the stream may have been handled.
+func (c *Client) openStream(ctx context.Context, r request) (io.ReadCloser, error) {
+	req := c.http.R().SetContext(ctx).SetDoNotParseResponse(true)
+	if r.query != nil {
+		req.SetQueryParams(r.query)
+	}
+	if r.header != nil {
+		req.SetHeaders(r.header)
+	}
+	if reqId, ok := ctx.Value(requestIdKey{}).(string); ok && reqId != "" {
+		req.SetHeader(headerRequestId, reqId)
+	}
+	if r.body != nil {
+		req.SetHeader("Content-Type", "application/json").SetBody(r.body)
+	}
+
+	resp, err := req.Execute(r.method, c.baseUrl+r.path)
+	if err != nil {
+		return nil, fmt.Errorf("tumblebug: %s %s: %w", r.method, r.path, err)
+	}
+	body := resp.RawBody()
+	if resp.StatusCode() >= http.StatusBadRequest {
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		return nil, newAPIError(resp.StatusCode(), data)
+	}
+	return body, nil
+}
+
+// readJsonStream calls handle for each JSON object of the stream (newline-delimited or concatenated JSON)
+func readJsonStream(body io.Reader, handle func(json.RawMessage) error) error {
+	decoder := json.NewDecoder(body)
+	for {
+		var message json.RawMessage
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := handle(message); err != nil {
+			if errors.Is(err, ErrStopStream) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// ServerSentEvent is an event of the Server-Sent Events stream
+type ServerSentEvent struct {
+	Id   string
+	Type string
+	Data json.RawMessage
+}
+
+// readServerSentEvents calls handle for each event of the text/event-stream (comments such as keepalive are skipped)
+func readServerSentEvents(body io.Reader, handle func(ServerSentEvent) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	event := ServerSentEvent{}
+	data := []string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// a blank line dispatches the event
+			if len(data) > 0 {
+				event.Data = json.RawMessage(strings.Join(data, "\n"))
+				if err := handle(event); err != nil {
+					if errors.Is(err, ErrStopStream) {
+						return nil
+					}
+					return err
+				}
+			}
+			event = ServerSentEvent{}
+			data = data[:0]
+		case strings.HasPrefix(line, ":"):
+			// comment (keepalive)
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "id":
+				event.Id = value
+			case "event":
+				event.Type = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// StreamEvents receives the status change events of the namespace until ctx is canceled or handle returns an error
+// (GET /stream-response/ns/{nsId}/events). Set mciId or resourceType (mci, vm, k8scluster, vNet) to filter the events.
+// The "events.dropped" event is reported with Type "events.dropped" and the number in Data.
+func (c *Client) StreamEvents(ctx context.Context, nsId string, mciId string, resourceType string, handle func(ServerSentEvent) error) error {
+	query := map[string]string{}
+	if mciId != "" {
+		query["mciId"] = mciId
+	}
+	if resourceType != "" {
+		query["resourceType"] = resourceType
+	}
+	body, err := c.openStream(ctx, request{
+		method: http.MethodGet,
+		path:   "/stream-response/ns/" + url.PathEscape(nsId) + "/events",
+		query:  query,
+		header: map[string]string{"Accept": "text/event-stream"},
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	err = readServerSentEvents(body, handle)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// StreamWebhookEvents is StreamEvents with the events decoded as model.WebhookEvent
+func (c *Client) StreamWebhookEvents(ctx context.Context, nsId string, mciId string, resourceType string, handle func(model.WebhookEvent) error) error {
+	return c.StreamEvents(ctx, nsId, mciId, resourceType, func(e ServerSentEvent) error {
+		event := model.WebhookEvent{}
+		if err := json.Unmarshal(e.Data, &event); err != nil {
+			return err
+		}
+		if event.Type == "" {
+			event.Type = e.Type
+		}
+		return handle(event)
+	})
+}
+
+// vpnStreamPath returns the path of the stream-response API of the VPN
+func vpnStreamPath(nsId string, mciId string, vpnId string) string {
+	return "/stream-response/ns/" + url.PathEscape(nsId) + "/mci/" + url.PathEscape(mciId) + "/vpn/" + url.PathEscape(vpnId)
+}
+
+// CreateSiteToSiteVpn creates the VPN between the sites and calls handle for each progress message of the stream
+// (POST /stream-response/ns/{nsId}/mci/{mciId}/vpn/{vpnId})
+func (c *Client) CreateSiteToSiteVpn(ctx context.Context, nsId string, mciId string, vpnId string, req *networkSiteModel.RestPostVpnRequest, handle func(json.RawMessage) error) error {
+	return c.streamJson(ctx, request{method: http.MethodPost, path: vpnStreamPath(nsId, mciId, vpnId), body: req}, handle)
+}
+
+// UpdateSiteToSiteVpn updates the sites of the VPN and calls handle for each progress message of the stream
+// (PUT /stream-response/ns/{nsId}/mci/{mciId}/vpn/{vpnId})
+func (c *Client) UpdateSiteToSiteVpn(ctx context.Context, nsId string, mciId string, vpnId string, req *networkSiteModel.RestPutVpnRequest, handle func(json.RawMessage) error) error {
+	return c.streamJson(ctx, request{method: http.MethodPut, path: vpnStreamPath(nsId, mciId, vpnId), body: req}, handle)
+}
+
+// DeleteSiteToSiteVpn deletes the VPN and calls handle for each progress message of the stream
+// (DELETE /stream-response/ns/{nsId}/mci/{mciId}/vpn/{vpnId})
+func (c *Client) DeleteSiteToSiteVpn(ctx context.Context, nsId string, mciId string, vpnId string, handle func(json.RawMessage) error) error {
+	return c.streamJson(ctx, request{method: http.MethodDelete, path: vpnStreamPath(nsId, mciId, vpnId)}, handle)
+}
+
+// streamJson sends the request of a stream-response API and calls handle for each JSON message
+func (c *Client) streamJson(ctx context.Context, r request, handle func(json.RawMessage) error) error {
+	body, err := c.openStream(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return readJsonStream(body, handle)
+}
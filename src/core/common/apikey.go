@@ -0,0 +1,232 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvutil"
+	"github.com/rs/zerolog/log"
+)
+
+// ContextKeyApiKeyNsId is the context key for the namespace restriction of the API key of the request
+const ContextKeyApiKeyNsId = "apiKeyNsId"
+
+// apiKeyPrefix is the prefix of API keys (tb.{name}.{secret})
+const apiKeyPrefix = "tb."
+
+// apiKeyRecord is the API key object stored in the Key-Value store (only the hash of the secret is stored)
+type apiKeyRecord struct {
+	model.ApiKeyInfo
+	SecretHash string `json:"secretHash"`
+}
+
+// GenApiKeyKey is func to generate the key of an API key object
+func GenApiKeyKey(name string) string {
+	return "/apikey/" + name
+}
+
+// hashApiKeySecret returns the SHA-256 of the secret of an API key
+func hashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newApiKeySecret returns a random secret and the key string for the API key
+func newApiKeySecret(name string) (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := hex.EncodeToString(b)
+	return secret, apiKeyPrefix + name + "." + secret, nil
+}
+
+// ParseApiKeyName returns the name part of the API key string (the key is not validated)
+func ParseApiKeyName(key string) string {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return ""
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, apiKeyPrefix), ".", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+func getApiKeyRecord(name string) (apiKeyRecord, error) {
+	record := apiKeyRecord{}
+	keyValue, err := kvstore.GetKv(GenApiKeyKey(name))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return record, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return record, fmt.Errorf("the API key (%s) is not found", name)
+	}
+	err = json.Unmarshal([]byte(keyValue.Value), &record)
+	return record, err
+}
+
+func putApiKeyRecord(record apiKeyRecord) error {
+	val, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return kvstore.Put(GenApiKeyKey(record.Name), string(val))
+}
+
+// CreateApiKey creates a named API key. The key string is returned only once.
+func CreateApiKey(req *model.ApiKeyReq) (model.ApiKeyCreateResult, error) {
+	result := model.ApiKeyCreateResult{}
+
+	if err := CheckString(req.Name); err != nil {
+		return result, err
+	}
+	if req.Scope != model.ApiKeyScopeReadOnly && req.Scope != model.ApiKeyScopeReadWrite {
+		return result, fmt.Errorf("invalid scope (%s): %s or %s is required", req.Scope, model.ApiKeyScopeReadOnly, model.ApiKeyScopeReadWrite)
+	}
+	if req.NsId != "" {
+		check, err := CheckNs(req.NsId)
+		if err != nil {
+			return result, err
+		}
+		if !check {
+			return result, fmt.Errorf("the namespace (%s) does not exist", req.NsId)
+		}
+	}
+	if _, err := getApiKeyRecord(req.Name); err == nil {
+		return result, fmt.Errorf("the API key (%s) already exists", req.Name)
+	}
+
+	secret, key, err := newApiKeySecret(req.Name)
+	if err != nil {
+		return result, err
+	}
+	record := apiKeyRecord{
+		ApiKeyInfo: model.ApiKeyInfo{
+			Name:        req.Name,
+			Scope:       req.Scope,
+			NsId:        req.NsId,
+			Description: req.Description,
+			CreatedTime: time.Now(),
+		},
+		SecretHash: hashApiKeySecret(secret),
+	}
+	if err := putApiKeyRecord(record); err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+
+	result.ApiKeyInfo = record.ApiKeyInfo
+	result.Key = key
+	return result, nil
+}
+
+// ListApiKeys returns all API keys (without secrets)
+func ListApiKeys() (model.ApiKeyInfoList, error) {
+	result := model.ApiKeyInfoList{ApiKeys: []model.ApiKeyInfo{}}
+
+	keyValue, err := kvstore.GetKvList("/apikey")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	for _, kv := range kvutil.FilterKvListBy(keyValue, "/apikey", 1) {
+		record := apiKeyRecord{}
+		if err := json.Unmarshal([]byte(kv.Value), &record); err != nil {
+			log.Error().Err(err).Str("key", kv.Key).Msg("Failed to unmarshal API key")
+			continue
+		}
+		result.ApiKeys = append(result.ApiKeys, record.ApiKeyInfo)
+	}
+	sort.Slice(result.ApiKeys, func(i, j int) bool {
+		return result.ApiKeys[i].Name < result.ApiKeys[j].Name
+	})
+	return result, nil
+}
+
+// RevokeApiKey revokes the API key. A revoked key is kept for reference but cannot be used or rotated.
+func RevokeApiKey(name string) (model.ApiKeyInfo, error) {
+	record, err := getApiKeyRecord(name)
+	if err != nil {
+		return model.ApiKeyInfo{}, err
+	}
+	if record.Revoked {
+		return record.ApiKeyInfo, fmt.Errorf("the API key (%s) is already revoked", name)
+	}
+	record.Revoked = true
+	record.RevokedTime = time.Now()
+	err = putApiKeyRecord(record)
+	return record.ApiKeyInfo, err
+}
+
+// RotateApiKey issues a new secret for the API key. The previous key string becomes invalid immediately.
+func RotateApiKey(name string) (model.ApiKeyCreateResult, error) {
+	result := model.ApiKeyCreateResult{}
+
+	record, err := getApiKeyRecord(name)
+	if err != nil {
+		return result, err
+	}
+	if record.Revoked {
+		return result, fmt.Errorf("the API key (%s) is revoked and cannot be rotated", name)
+	}
+	secret, key, err := newApiKeySecret(name)
+	if err != nil {
+		return result, err
+	}
+	record.SecretHash = hashApiKeySecret(secret)
+	record.RotatedTime = time.Now()
+	if err := putApiKeyRecord(record); err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+
+	result.ApiKeyInfo = record.ApiKeyInfo
+	result.Key = key
+	return result, nil
+}
+
+// ValidateApiKey checks the API key string against the stored hash and returns the key information
+func ValidateApiKey(key string) (model.ApiKeyInfo, error) {
+	name := ParseApiKeyName(key)
+	if name == "" {
+		return model.ApiKeyInfo{}, fmt.Errorf("malformed API key")
+	}
+	record, err := getApiKeyRecord(name)
+	if err != nil {
+		return model.ApiKeyInfo{}, fmt.Errorf("invalid API key")
+	}
+	secret := strings.TrimPrefix(key, apiKeyPrefix+name+".")
+	// Be careful to use constant time comparison to prevent timing attacks
+	if subtle.ConstantTimeCompare([]byte(hashApiKeySecret(secret)), []byte(record.SecretHash)) != 1 {
+		return model.ApiKeyInfo{}, fmt.Errorf("invalid API key")
+	}
+	if record.Revoked {
+		return model.ApiKeyInfo{}, fmt.Errorf("the API key (%s) is revoked", name)
+	}
+	return record.ApiKeyInfo, nil
+}
@@ -0,0 +1,372 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// auditKeyPrefix is the prefix of audit records in the Key-Value store (/audit/{yyyy-mm-dd}/{recordId})
+const auditKeyPrefix = "/audit/"
+
+// auditDayLayout is the layout of daily partitions of audit records
+const auditDayLayout = "2006-01-02"
+
+// auditSensitiveKeys are (lowercase) substrings of field names whose values are redacted in audit records
+var auditSensitiveKeys = []string{"password", "secret", "token", "credential", "privatekey", "aeskey", "apikey", "accesskey", "kubeconfig"}
+
+// auditCredentialFields are the fields of credential registration kept in audit records (all others are redacted)
+var auditCredentialFields = map[string]bool{"providerName": true, "credentialHolder": true}
+
+var (
+	// auditLock serializes appending audit records to keep the hash chain consistent
+	auditLock sync.Mutex
+	// lastAuditHash is the hash of the latest audit record (loaded from the Key-Value store at the first append)
+	lastAuditHash       string
+	lastAuditHashLoaded bool
+	// lastAuditSeq is the sequence of the latest audit record (the append time in nanoseconds, strictly increasing)
+	lastAuditSeq int64
+)
+
+// AuditFilter is a set of conditions to query audit records
+type AuditFilter struct {
+	From       time.Time // records at or after this time
+	To         time.Time // records at or before this time
+	User       string    // user of the call (exact match)
+	PathPrefix string    // prefix of the request path (e.g., /tumblebug/ns/default/mci)
+	Page       int       // page number starting from 1 (0 means no pagination)
+	PageSize   int       // number of records in a page (0 means no pagination)
+}
+
+// GenAuditKey is func to generate the key of an audit record (timestamp is the append time of the record)
+func GenAuditKey(timestamp time.Time, recordId string) string {
+	return auditKeyPrefix + timestamp.UTC().Format(auditDayLayout) + "/" + recordId
+}
+
+// auditSeqOf returns the sequence of the stored record (the zero-padded first part of Id for records without Seq)
+func auditSeqOf(record model.AuditRecord) int64 {
+	if record.Seq != 0 {
+		return record.Seq
+	}
+	var seq int64
+	fmt.Sscanf(record.Id, "%d", &seq)
+	return seq
+}
+
+// redactAuditValue masks values of sensitive fields recursively
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			lowerKey := strings.ToLower(key)
+			sensitive := false
+			for _, s := range auditSensitiveKeys {
+				if strings.Contains(lowerKey, s) {
+					sensitive = true
+					break
+				}
+			}
+			if sensitive {
+				redacted[key] = model.AuditRedactedValue
+			} else {
+				redacted[key] = redactAuditValue(val)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, val := range v {
+			redacted[i] = redactAuditValue(val)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// RedactAuditBody returns the request body with sensitive values masked.
+// For credential registration, only the provider and holder are kept regardless of the field names.
+func RedactAuditBody(path string, body interface{}) interface{} {
+	if body == nil {
+		return nil
+	}
+	if strings.HasPrefix(path, "/tumblebug/credential") {
+		m, ok := body.(map[string]interface{})
+		if !ok {
+			return model.AuditRedactedValue
+		}
+		redacted := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			if auditCredentialFields[key] {
+				redacted[key] = val
+			} else {
+				redacted[key] = model.AuditRedactedValue
+			}
+		}
+		return redacted
+	}
+	return redactAuditValue(body)
+}
+
+// hashAuditRecord returns the SHA-256 of the record (with PrevHash and without Hash)
+func hashAuditRecord(record model.AuditRecord) (string, error) {
+	record.Hash = ""
+	val, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(val)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// listAuditKvs returns audit records in the Key-Value store sorted by key (oldest first).
+// If from is given, only the daily partitions from the day are read.
+func listAuditKvs(from time.Time, to time.Time) ([]kvstore.KeyValue, error) {
+	kvs := []kvstore.KeyValue{}
+	if from.IsZero() {
+		keyValue, err := kvstore.GetKvList(auditKeyPrefix)
+		if err != nil {
+			return kvs, err
+		}
+		kvs = keyValue
+	} else {
+		if to.IsZero() {
+			to = time.Now()
+		}
+		day := from.UTC().Truncate(24 * time.Hour)
+		for !day.After(to.UTC()) {
+			keyValue, err := kvstore.GetKvList(auditKeyPrefix + day.Format(auditDayLayout) + "/")
+			if err != nil {
+				return kvs, err
+			}
+			kvs = append(kvs, keyValue...)
+			day = day.Add(24 * time.Hour)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
+	return kvs, nil
+}
+
+// AppendAuditRecord stores an audit record chained to the previous record.
+// The key and the sequence are assigned at append time under the lock, so the order of keys is the order of the chain
+// even if the calls overlap (record.Timestamp is the time when the call was received). Records are never updated once stored.
+func AppendAuditRecord(record model.AuditRecord) error {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+
+	if !lastAuditHashLoaded {
+		kvs, err := listAuditKvs(time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		if len(kvs) > 0 {
+			latest := model.AuditRecord{}
+			if err := json.Unmarshal([]byte(kvs[len(kvs)-1].Value), &latest); err == nil {
+				lastAuditHash = latest.Hash
+				lastAuditSeq = auditSeqOf(latest)
+			}
+		}
+		lastAuditHashLoaded = true
+	}
+
+	record.Timestamp = record.Timestamp.UTC()
+	// the append time (strictly increasing) is the sequence, and zero-padded it keeps the records sorted by key
+	appendedAt := time.Now().UTC()
+	if appendedAt.UnixNano() <= lastAuditSeq {
+		appendedAt = time.Unix(0, lastAuditSeq+1).UTC()
+	}
+	record.Seq = appendedAt.UnixNano()
+	record.Id = fmt.Sprintf("%020d-%s", record.Seq, strings.ReplaceAll(record.RequestId, "/", "_"))
+	record.PrevHash = lastAuditHash
+	hash, err := hashAuditRecord(record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	val, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = kvstore.Put(GenAuditKey(appendedAt, record.Id), string(val))
+	if err != nil {
+		return err
+	}
+	lastAuditHash = hash
+	lastAuditSeq = record.Seq
+	return nil
+}
+
+// ListAuditRecords returns the audit records which match the filter (newest first)
+func ListAuditRecords(filter AuditFilter) (model.AuditRecordList, error) {
+	result := model.AuditRecordList{Records: []model.AuditRecord{}}
+
+	kvs, err := listAuditKvs(filter.From, filter.To)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+
+	matched := []model.AuditRecord{}
+	for i := len(kvs) - 1; i >= 0; i-- {
+		record := model.AuditRecord{}
+		if err := json.Unmarshal([]byte(kvs[i].Value), &record); err != nil {
+			log.Error().Err(err).Str("key", kvs[i].Key).Msg("Failed to unmarshal audit record")
+			continue
+		}
+		if !filter.From.IsZero() && record.Timestamp.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && record.Timestamp.After(filter.To) {
+			continue
+		}
+		if filter.User != "" && record.User != filter.User {
+			continue
+		}
+		if filter.PathPrefix != "" && !strings.HasPrefix(record.Path, filter.PathPrefix) {
+			continue
+		}
+		matched = append(matched, record)
+	}
+
+	result.Total = len(matched)
+	result.Records = matched
+	if filter.Page <= 0 && filter.PageSize <= 0 {
+		return result, nil
+	}
+
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	result.Page = page
+	result.PageSize = pageSize
+
+	start := (page - 1) * pageSize
+	if start >= len(matched) {
+		result.Records = []model.AuditRecord{}
+		return result, nil
+	}
+	end := start + pageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+	result.Records = matched[start:end]
+	return result, nil
+}
+
+// VerifyAuditRecords checks the hash chain of the stored audit records.
+// The oldest retained record is the anchor of the chain since older records may be deleted by the retention policy.
+func VerifyAuditRecords() (model.AuditVerifyResult, error) {
+	result := model.AuditVerifyResult{}
+
+	kvs, err := listAuditKvs(time.Time{}, time.Time{})
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+
+	prevHash := ""
+	for i, kv := range kvs {
+		record := model.AuditRecord{}
+		if err := json.Unmarshal([]byte(kv.Value), &record); err != nil {
+			result.BrokenAt = kv.Key
+			result.Message = "audit record cannot be parsed"
+			return result, nil
+		}
+		hash, err := hashAuditRecord(record)
+		if err != nil {
+			return result, err
+		}
+		if hash != record.Hash || (i > 0 && record.PrevHash != prevHash) {
+			result.BrokenAt = record.Id
+			result.Message = "audit record is modified or the previous record is missing"
+			return result, nil
+		}
+		prevHash = record.Hash
+		result.Checked++
+	}
+
+	result.Valid = true
+	result.Message = "audit records are consistent"
+	return result, nil
+}
+
+// PruneAuditRecords deletes the daily partitions of audit records older than retentionDays.
+// It returns the number of deleted records.
+func PruneAuditRecords(retentionDays int) (int, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -retentionDays).Format(auditDayLayout)
+
+	kvs, err := kvstore.GetKvList(auditKeyPrefix)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, kv := range kvs {
+		day := strings.SplitN(strings.TrimPrefix(kv.Key, auditKeyPrefix), "/", 2)[0]
+		if day >= cutoff {
+			continue
+		}
+		if err := kvstore.Delete(kv.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// StartAuditRetention runs PruneAuditRecords periodically in background
+func StartAuditRetention(interval time.Duration, retentionDays int) {
+	if retentionDays <= 0 {
+		log.Info().Msg("Retention policy for audit records is disabled")
+		return
+	}
+	log.Info().Msgf("Retention policy for audit records: %d days", retentionDays)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			deleted, err := PruneAuditRecords(retentionDays)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to prune audit records")
+				continue
+			}
+			if deleted > 0 {
+				log.Info().Msgf("Pruned %d audit records by the retention policy", deleted)
+			}
+		}
+	}()
+}
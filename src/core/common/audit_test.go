@@ -0,0 +1,94 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+func TestAuditChainWithOverlappingRequests(t *testing.T) {
+	start := time.Now()
+	// a long request received first is appended after the short requests received later
+	records := []model.AuditRecord{
+		{Timestamp: start.Add(2 * time.Second), Method: "POST", Path: "/tumblebug/ns/default/mci/short1", RequestId: "2"},
+		{Timestamp: start.Add(3 * time.Second), Method: "POST", Path: "/tumblebug/ns/default/mci/short2", RequestId: "3"},
+		{Timestamp: start, Method: "POST", Path: "/tumblebug/ns/default/mci/long", RequestId: "1"},
+	}
+	for _, record := range records {
+		if err := AppendAuditRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			record := model.AuditRecord{Timestamp: start.Add(time.Duration(-i) * time.Second), Method: "DELETE", RequestId: fmt.Sprint(100 + i)}
+			if err := AppendAuditRecord(record); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	result, err := VerifyAuditRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checked != 23 {
+		t.Fatalf("audit chain is reported broken: %+v", result)
+	}
+
+	// the chain continues from the latest record after a restart
+	auditLock.Lock()
+	lastAuditHashLoaded = false
+	lastAuditHash = ""
+	lastAuditSeq = 0
+	auditLock.Unlock()
+	if err := AppendAuditRecord(model.AuditRecord{Timestamp: start.Add(-time.Hour), Method: "PUT", RequestId: "restart"}); err != nil {
+		t.Fatal(err)
+	}
+	result, err = VerifyAuditRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checked != 24 {
+		t.Fatalf("audit chain is reported broken after a restart: %+v", result)
+	}
+
+	list, err := ListAuditRecords(AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(list.Records); i++ {
+		if list.Records[i-1].Seq <= list.Records[i].Seq {
+			t.Fatalf("records are not in the order of append: %d, %d", list.Records[i-1].Seq, list.Records[i].Seq)
+		}
+	}
+}
+
+func TestRedactAuditBody(t *testing.T) {
+	body := map[string]interface{}{
+		"name":     "mci01",
+		"password": "pw",
+		"vm":       []interface{}{map[string]interface{}{"sshPrivateKey": "key", "spec": "t3.small"}},
+	}
+	redacted := RedactAuditBody("/tumblebug/ns/default/mci", body).(map[string]interface{})
+	if redacted["name"] != "mci01" || redacted["password"] != model.AuditRedactedValue {
+		t.Errorf("redacted = %v", redacted)
+	}
+	vm := redacted["vm"].([]interface{})[0].(map[string]interface{})
+	if vm["sshPrivateKey"] != model.AuditRedactedValue || vm["spec"] != "t3.small" {
+		t.Errorf("redacted vm = %v", vm)
+	}
+
+	credential := RedactAuditBody("/tumblebug/credential", map[string]interface{}{"providerName": "aws", "credentialKeyValueList": "x"}).(map[string]interface{})
+	if credential["providerName"] != "aws" || credential["credentialKeyValueList"] != model.AuditRedactedValue {
+		t.Errorf("redacted credential = %v", credential)
+	}
+}
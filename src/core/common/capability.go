@@ -0,0 +1,265 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Features in the provider capability registry
+const (
+	CapabilityNlb                  string = "nlb"
+	CapabilityK8sCluster           string = "k8scluster"
+	CapabilityVpn                  string = "vpn"
+	CapabilityCustomImage          string = "customImage"
+	CapabilityCustomImageCopy      string = "customImageCopy"
+	CapabilityDataDisk             string = "dataDisk"
+	CapabilityDataDiskOnlineResize string = "dataDiskOnlineResize"
+	CapabilitySpotInstance         string = "spotInstance"
+	CapabilityObjectStorage        string = "objectStorage"
+	CapabilitySqlDb                string = "sqlDb"
+	CapabilityVmConsole            string = "vmConsole"
+	CapabilityPublicIp             string = "publicIp"
+	CapabilityVmResize             string = "vmResize"
+	CapabilityIpv6                 string = "ipv6"
+)
+
+// Support levels of a feature
+const (
+	CapabilitySupported   string = "supported"
+	CapabilityPartial     string = "partial"
+	CapabilityUnsupported string = "unsupported"
+)
+
+// capabilityFeatures is the order of features in the capability matrix
+var capabilityFeatures = []string{
+	CapabilityNlb,
+	CapabilityK8sCluster,
+	CapabilityVpn,
+	CapabilityCustomImage,
+	CapabilityCustomImageCopy,
+	CapabilityDataDisk,
+	CapabilityDataDiskOnlineResize,
+	CapabilitySpotInstance,
+	CapabilityObjectStorage,
+	CapabilitySqlDb,
+	CapabilityVmConsole,
+	CapabilityPublicIp,
+	CapabilityVmResize,
+	CapabilityIpv6,
+}
+
+// Capability is the support level of a feature for a provider
+type Capability struct {
+	Support string `json:"support" example:"supported" enums:"supported,partial,unsupported"`
+	Note    string `json:"note,omitempty" example:"node groups can be added only after the cluster is created"`
+}
+
+// ProviderCapabilities is the capabilities of a provider
+type ProviderCapabilities struct {
+	ProviderName string                `json:"providerName" example:"aws"`
+	Capabilities map[string]Capability `json:"capabilities"`
+}
+
+// CapabilityMatrix is the capabilities of all providers
+type CapabilityMatrix struct {
+	Features  []string               `json:"features"`
+	Providers []ProviderCapabilities `json:"providers"`
+}
+
+// capabilityTable is the hard-coded support of features which are not described by the assets.
+// Providers not in the table of a feature are unsupported (with the default note of the feature).
+var capabilityTable = map[string]map[string]Capability{
+	CapabilityNlb: {
+		"aws":        {Support: CapabilitySupported},
+		"azure":      {Support: CapabilitySupported},
+		"gcp":        {Support: CapabilitySupported},
+		"alibaba":    {Support: CapabilitySupported},
+		"tencent":    {Support: CapabilitySupported},
+		"ibm":        {Support: CapabilitySupported},
+		"nhncloud":   {Support: CapabilitySupported},
+		"ncpvpc":     {Support: CapabilitySupported},
+		"ktcloudvpc": {Support: CapabilityPartial, Note: "health checker settings cannot be changed"},
+	},
+	// site-to-site VPN via mc-terrarium (templates vpn/gcp-aws and vpn/gcp-azure)
+	CapabilityVpn: {
+		"gcp":   {Support: CapabilitySupported, Note: "peer sites must be in AWS or Azure"},
+		"aws":   {Support: CapabilityPartial, Note: "only with GCP sites"},
+		"azure": {Support: CapabilityPartial, Note: "only with GCP sites"},
+	},
+	CapabilityCustomImage: {
+		"aws":       {Support: CapabilitySupported},
+		"azure":     {Support: CapabilitySupported},
+		"gcp":       {Support: CapabilitySupported},
+		"alibaba":   {Support: CapabilitySupported},
+		"tencent":   {Support: CapabilitySupported},
+		"ibm":       {Support: CapabilitySupported},
+		"ncp":       {Support: CapabilitySupported},
+		"ncpvpc":    {Support: CapabilitySupported},
+		"nhncloud":  {Support: CapabilitySupported},
+		"openstack": {Support: CapabilitySupported},
+	},
+	// copying a custom image to another region via Spider (AWS CopyImage, GCP global images, Azure shared image gallery)
+	CapabilityCustomImageCopy: {
+		"aws":   {Support: CapabilitySupported},
+		"gcp":   {Support: CapabilitySupported, Note: "images are global; the copy is registered in the target region"},
+		"azure": {Support: CapabilitySupported, Note: "via shared image gallery"},
+	},
+	CapabilityDataDisk: {
+		"aws":       {Support: CapabilitySupported},
+		"azure":     {Support: CapabilitySupported},
+		"gcp":       {Support: CapabilitySupported},
+		"alibaba":   {Support: CapabilitySupported},
+		"tencent":   {Support: CapabilitySupported},
+		"ibm":       {Support: CapabilitySupported},
+		"ncp":       {Support: CapabilitySupported},
+		"ncpvpc":    {Support: CapabilitySupported},
+		"nhncloud":  {Support: CapabilitySupported},
+		"ktcloud":   {Support: CapabilityPartial, Note: "max 500 GB per disk"},
+		"openstack": {Support: CapabilitySupported},
+	},
+	// resizing a data disk while it is attached to a VM
+	CapabilityDataDiskOnlineResize: {
+		"aws":     {Support: CapabilitySupported},
+		"azure":   {Support: CapabilitySupported},
+		"gcp":     {Support: CapabilitySupported},
+		"alibaba": {Support: CapabilitySupported},
+		"tencent": {Support: CapabilitySupported},
+		"ibm":     {Support: CapabilitySupported},
+	},
+	// buckets via the object storage API of CB-Spider
+	CapabilityObjectStorage: {
+		"aws":   {Support: CapabilitySupported},
+		"gcp":   {Support: CapabilitySupported},
+		"azure": {Support: CapabilityPartial, Note: "a bucket is a blob container of a storage account; versioning is applied to the storage account"},
+	},
+	// managed MySQL/PostgreSQL via the managed DB API of CB-Spider
+	CapabilitySqlDb: {
+		"aws":   {Support: CapabilitySupported},
+		"gcp":   {Support: CapabilityPartial, Note: "the vNet requires private services access for the private IP of the DB"},
+		"azure": {Support: CapabilitySupported, Note: "flexible server with the delegated subnet"},
+	},
+	// console output of a VM (AWS GetConsoleOutput, GCP serial port, Azure boot diagnostics)
+	CapabilityVmConsole: {
+		"aws":   {Support: CapabilitySupported, Note: "the output is updated by AWS only at boot, reboot and termination"},
+		"gcp":   {Support: CapabilitySupported},
+		"azure": {Support: CapabilityPartial, Note: "boot diagnostics must be enabled for the VM"},
+	},
+	// attaching/detaching a public IP to/from an existing VM (elastic/static addresses can be reused)
+	CapabilityPublicIp: {
+		"aws":     {Support: CapabilitySupported},
+		"azure":   {Support: CapabilitySupported},
+		"gcp":     {Support: CapabilitySupported},
+		"alibaba": {Support: CapabilitySupported},
+		"tencent": {Support: CapabilitySupported},
+	},
+	// changing the spec (instance type) of a stopped VM
+	CapabilityVmResize: {
+		"aws":     {Support: CapabilitySupported},
+		"azure":   {Support: CapabilitySupported, Note: "the target size must be available in the hardware cluster of the VM"},
+		"gcp":     {Support: CapabilitySupported},
+		"alibaba": {Support: CapabilitySupported},
+		"tencent": {Support: CapabilitySupported},
+	},
+	// dual-stack (IPv4/IPv6) vNets and subnets
+	CapabilityIpv6: {
+		"aws": {Support: CapabilitySupported, Note: "assignIpv6=auto allocates an Amazon-provided /56 to the vNet and a /64 to each subnet"},
+		"gcp": {Support: CapabilityPartial, Note: "IPv6 ranges are allocated by GCP (assignIpv6=auto only)"},
+	},
+	CapabilitySpotInstance: {
+		"aws":     {Support: CapabilityPartial, Note: "spot prices are used for recommendation; VMs are provisioned on demand"},
+		"azure":   {Support: CapabilityPartial, Note: "spot prices are used for recommendation; VMs are provisioned on demand"},
+		"gcp":     {Support: CapabilityPartial, Note: "spot prices are used for recommendation; VMs are provisioned on demand"},
+		"alibaba": {Support: CapabilityPartial, Note: "spot prices are used for recommendation; VMs are provisioned on demand"},
+		"tencent": {Support: CapabilityPartial, Note: "spot prices are used for recommendation; VMs are provisioned on demand"},
+	},
+}
+
+// capabilityUnsupportedNotes are the notes of unsupported features
+var capabilityUnsupportedNotes = map[string]string{
+	CapabilityVpn:                  "site-to-site VPN is provided only between GCP and AWS or Azure",
+	CapabilityDataDiskOnlineResize: "detach the dataDisk before resizing",
+	CapabilityVmConsole:            "use remote commands (SSH) to get the logs of the VM",
+	CapabilityVmResize:             "create a new VM with the spec and delete the old VM",
+	CapabilityIpv6:                 "only IPv4 vNets can be created for the provider",
+}
+
+// GetCapability returns the support level of the feature for the provider
+func GetCapability(providerName string, feature string) Capability {
+	providerName = strings.ToLower(providerName)
+
+	// kubernetes cluster support is described by the k8sclusterinfo asset
+	if feature == CapabilityK8sCluster {
+		detail := getK8sClusterDetail(providerName)
+		if detail == nil {
+			return Capability{Support: CapabilityUnsupported}
+		}
+		if !detail.NodeGroupsOnCreation {
+			return Capability{Support: CapabilityPartial, Note: "node groups can be added only after the cluster is created"}
+		}
+		return Capability{Support: CapabilitySupported}
+	}
+
+	if capability, ok := capabilityTable[feature][providerName]; ok {
+		return capability
+	}
+	return Capability{Support: CapabilityUnsupported, Note: capabilityUnsupportedNotes[feature]}
+}
+
+// IsCapabilitySupported returns true if the feature is supported (fully or partially) for the provider
+func IsCapabilitySupported(providerName string, feature string) bool {
+	return GetCapability(providerName, feature).Support != CapabilityUnsupported
+}
+
+// CheckCapability returns NotSupportedError if the feature is unsupported for the provider
+func CheckCapability(providerName string, feature string, operation string) error {
+	if !IsCapabilitySupported(providerName, feature) {
+		return &NotSupportedError{Operation: operation, ProviderName: providerName}
+	}
+	return nil
+}
+
+// GetProviderCapabilities returns the capabilities of the provider
+func GetProviderCapabilities(providerName string) (ProviderCapabilities, error) {
+	providerName = strings.ToLower(providerName)
+	if _, ok := RuntimeCloudInfo.CSPs[providerName]; !ok {
+		return ProviderCapabilities{}, fmt.Errorf("provider '%s' not found", providerName)
+	}
+	capabilities := ProviderCapabilities{ProviderName: providerName, Capabilities: map[string]Capability{}}
+	for _, feature := range capabilityFeatures {
+		capabilities.Capabilities[feature] = GetCapability(providerName, feature)
+	}
+	return capabilities, nil
+}
+
+// GetCapabilityMatrix returns the capabilities of all providers in the cloud info
+func GetCapabilityMatrix() CapabilityMatrix {
+	matrix := CapabilityMatrix{Features: capabilityFeatures, Providers: []ProviderCapabilities{}}
+	providerNames := make([]string, 0, len(RuntimeCloudInfo.CSPs))
+	for providerName := range RuntimeCloudInfo.CSPs {
+		providerNames = append(providerNames, providerName)
+	}
+	sort.Strings(providerNames)
+	for _, providerName := range providerNames {
+		capabilities, err := GetProviderCapabilities(providerName)
+		if err != nil {
+			continue
+		}
+		matrix.Providers = append(matrix.Providers, capabilities)
+	}
+	return matrix
+}
@@ -17,12 +17,15 @@ package common
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
 	"github.com/go-resty/resty/v2"
 	"github.com/labstack/echo/v4"
@@ -69,7 +72,7 @@ func limitConcurrentRequests(requestKey string, limit int) bool {
 	currentCount := count.(int)
 
 	if currentCount >= limit {
-		fmt.Printf("[%s] requests for %s \n", currentCount, requestKey)
+		fmt.Printf("[%d] requests for %s \n", currentCount, requestKey)
 		return false
 	}
 
@@ -100,6 +103,7 @@ func ExecuteHttpRequest[B any, T any](
 	body *B,
 	result *T, // Generic type
 	cacheDuration time.Duration,
+	opts ...HttpRequestOption, // per-call options (e.g., WithoutRetry(), WithoutCircuitBreaker())
 ) error {
 
 	// Generate cache key for GET method only
@@ -176,50 +180,124 @@ func ExecuteHttpRequest[B any, T any](
 		}
 	}
 
+	switch method {
+	case "GET", "POST", "PUT", "DELETE":
+	default:
+		return fmt.Errorf("Unsupported rest method: %s", method)
+	}
+
+	options := newHttpRequestOptions(opts)
+	endpoint := spiderEndpoint(url)
+	breakerKey := circuitBreakerKey(url, options.provider)
+	var breaker *circuitBreaker
+	if options.circuitBreaker {
+		breaker = getCircuitBreaker(breakerKey)
+	}
+
 	// Perform the HTTP request using Resty
 	//client.SetDebug(true)
 	// SetAllowGetMethodPayload should be set to true for GET method to allow payload
 	// NOTE: Need to removed when cb-spider api is stopped to use GET method with payload
 	client.SetAllowGetMethodPayload(true)
-	req := client.R().SetHeader("Content-Type", "application/json").SetResult(result)
-
-	if headers != nil {
-		req = req.SetHeaders(headers)
-	}
-
-	if useBody {
-		req = req.SetBody(body)
-	}
 
 	var resp *resty.Response
 	var err error
+	startTime := time.Now()
 
-	// Execute HTTP method based on the given type
-	switch method {
-	case "GET":
-		resp, err = req.Get(url)
-	case "POST":
-		resp, err = req.Post(url)
-	case "PUT":
-		resp, err = req.Put(url)
-	case "DELETE":
-		resp, err = req.Delete(url)
-	default:
-		return fmt.Errorf("Unsupported rest method: %s", method)
+	for attempt := 1; ; attempt++ {
+		// wait for the rate limit of the provider before the circuit breaker admits the call
+		if endpoint != "" {
+			if err = waitProviderThrottle(options.provider); err != nil {
+				break
+			}
+		}
+		if breaker != nil {
+			if err = breaker.allow(breakerKey); err != nil {
+				break
+			}
+		}
+
+		req := client.R().SetHeader("Content-Type", "application/json").SetResult(result)
+		if headers != nil {
+			req = req.SetHeaders(headers)
+		}
+		if reqId := RequestIdFromContext(options.ctx); reqId != "" {
+			req = req.SetHeader(echo.HeaderXRequestID, reqId)
+		}
+		if useBody {
+			req = req.SetBody(body)
+		}
+
+		// Execute HTTP method based on the given type
+		switch method {
+		case "GET":
+			resp, err = req.Get(url)
+		case "POST":
+			resp, err = req.Post(url)
+		case "PUT":
+			resp, err = req.Put(url)
+		case "DELETE":
+			resp, err = req.Delete(url)
+		}
+
+		statusCode := 0
+		if err == nil {
+			statusCode = resp.StatusCode()
+		}
+		if breaker != nil {
+			breaker.record(breakerKey, isUpstreamFailure(err, statusCode))
+		}
+		if !options.retry.ShouldRetry(method, attempt, err, statusCode) {
+			break
+		}
+		wait := options.retry.Wait(attempt)
+		LoggerFromContext(options.ctx).Debug().Msgf("Retrying %s %s in %v (attempt %d/%d failed: status %d, err %v)", method, url, wait, attempt, options.retry.MaxAttempts, statusCode, err)
+		time.Sleep(wait)
 	}
 
 	if err != nil {
 		if method == "GET" {
 			requestDone(requestKey)
 		}
-		return fmt.Errorf("[Error from: %s] Message: %s", url, err.Error())
+		var apiErr *ApiError
+		if errors.As(err, &apiErr) && apiErr.Code == model.ErrCodeRateLimited {
+			metrics.ObserveSpiderCall(method, endpoint, "throttled", time.Since(startTime))
+			return err
+		}
+		var unavailableErr *UpstreamUnavailableError
+		if errors.As(err, &unavailableErr) {
+			if endpoint != "" {
+				metrics.ObserveSpiderCall(method, endpoint, "unavailable", time.Since(startTime))
+			}
+			return err
+		}
+		if endpoint != "" {
+			metrics.IncSpiderCallError(method, endpoint)
+			metrics.ObserveSpiderCall(method, endpoint, "error", time.Since(startTime))
+		}
+		return NewUpstreamError(url, err)
 	}
 
 	if resp.IsError() {
 		if method == "GET" {
 			requestDone(requestKey)
 		}
-		return fmt.Errorf("[Error from: %s] Status code: %s, Message: %s", url, resp.Status(), resp.Body())
+		if endpoint != "" {
+			metrics.IncSpiderCallError(method, endpoint)
+			metrics.ObserveSpiderCall(method, endpoint, "error", time.Since(startTime))
+		}
+		err = fmt.Errorf("[Error from: %s] Status code: %s, Message: %s", url, resp.Status(), resp.Body())
+		// record the auth error in the connection config to detect broken credentials early
+		if endpoint != "" && useBody && !options.skipConnConfigHealth && IsAuthError(err) {
+			if connectionName := connectionNameOfBody(body); connectionName != "" {
+				go recordConnConfigCheck(connectionName, err)
+			}
+		}
+		return err
+	}
+
+	if endpoint != "" {
+		metrics.ObserveSpiderCall(method, endpoint, "success", time.Since(startTime))
 	}
 
 	// Update the cache for GET method only
@@ -259,6 +337,8 @@ type RequestDetails struct {
 	RequestInfo   RequestInfo `json:"requestInfo"`   // Extracted information about the request.
 	ResponseData  interface{} `json:"responseData"`  // The data sent back in response to the request.
 	ErrorResponse string      `json:"errorResponse"` // A message describing any error that occurred during request processing.
+	DurationMs    int64       `json:"durationMs"`    // The time taken to process the request in milliseconds.
+	ResponseSize  int         `json:"responseSize"`  // The size of the response body in bytes.
 }
 
 // RequestMap is a map for request details
@@ -326,21 +406,20 @@ func EndRequestWithLog(c echo.Context, err error, responseData interface{}) erro
 	if v, ok := RequestMap.Load(reqID); ok {
 		details := v.(RequestDetails)
 		details.EndTime = time.Now()
+		details.DurationMs = details.EndTime.Sub(details.StartTime).Milliseconds()
 
 		c.Response().Header().Set(echo.HeaderXRequestID, reqID)
 
 		if err != nil {
-			details.Status = "Error"
-			details.ErrorResponse = err.Error()
-			RequestMap.Store(reqID, details)
-			if responseData == nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
-			} else {
-				return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+			// errors without a code: 400 if no data is given, 500 otherwise
+			defaultStatus := http.StatusBadRequest
+			if responseData != nil {
+				defaultStatus = http.StatusInternalServerError
 			}
+			return endRequestWithError(c, reqID, details, err, defaultStatus)
 		}
 
-		details.Status = "Success"
+		details.Status = RequestStatusSuccess
 		details.ResponseData = responseData
 		RequestMap.Store(reqID, details)
 		return c.JSON(http.StatusOK, responseData)
@@ -349,6 +428,60 @@ func EndRequestWithLog(c echo.Context, err error, responseData interface{}) erro
 	return c.JSON(http.StatusNotFound, map[string]string{"message": "Invalid Request ID"})
 }
 
+// EndRequestWithError updates the request details with the error and sends the ErrorResponse.
+// The HTTP status is given by the code of the error (defaultStatus for errors without a code).
+func EndRequestWithError(c echo.Context, err error, defaultStatus int) error {
+
+	reqID := c.Request().Header.Get(echo.HeaderXRequestID)
+
+	if v, ok := RequestMap.Load(reqID); ok {
+		details := v.(RequestDetails)
+		details.EndTime = time.Now()
+		details.DurationMs = details.EndTime.Sub(details.StartTime).Milliseconds()
+		c.Response().Header().Set(echo.HeaderXRequestID, reqID)
+		return endRequestWithError(c, reqID, details, err, defaultStatus)
+	}
+
+	return c.JSON(http.StatusNotFound, map[string]string{"message": "Invalid Request ID"})
+}
+
+// endRequestWithError stores the request details with the error and sends the ErrorResponse
+func endRequestWithError(c echo.Context, reqID string, details RequestDetails, err error, defaultStatus int) error {
+	details.Status = RequestStatusError
+	details.ErrorResponse = err.Error()
+	RequestMap.Store(reqID, details)
+
+	response, status := ToErrorResponse(err, defaultStatus)
+	response.RequestId = reqID
+	var unavailableErr *UpstreamUnavailableError
+	if errors.As(err, &unavailableErr) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(unavailableErr.RetryAfter.Seconds())+1))
+	}
+	return c.JSON(status, response)
+}
+
+// EndRequestWithSensitiveData updates the request details without keeping the response data
+// (e.g., credentials or kubeconfig) and sends the given content with the given content type.
+func EndRequestWithSensitiveData(c echo.Context, contentType string, content []byte) error {
+
+	reqID := c.Request().Header.Get(echo.HeaderXRequestID)
+
+	if v, ok := RequestMap.Load(reqID); ok {
+		details := v.(RequestDetails)
+		details.EndTime = time.Now()
+		details.DurationMs = details.EndTime.Sub(details.StartTime).Milliseconds()
+		details.Status = RequestStatusSuccess
+		details.ResponseData = "[MASKED] sensitive data is not recorded"
+		details.ResponseSize = len(content)
+		RequestMap.Store(reqID, details)
+
+		c.Response().Header().Set(echo.HeaderXRequestID, reqID)
+		return c.Blob(http.StatusOK, contentType, content)
+	}
+
+	return c.JSON(http.StatusNotFound, map[string]string{"message": "Invalid Request ID"})
+}
+
 // UpdateRequestProgress updates the handling status of the request.
 func UpdateRequestProgress(reqID string, progressData interface{}) {
 	if v, ok := RequestMap.Load(reqID); ok {
@@ -366,41 +499,3 @@ func UpdateRequestProgress(reqID string, progressData interface{}) {
 		RequestMap.Store(reqID, details)
 	}
 }
-
-// ForwardRequestToAny forwards the given request to the specified path
-func ForwardRequestToAny(reqPath string, method string, requestBody interface{}) (interface{}, error) {
-	client := resty.New()
-	var callResult interface{}
-
-	url := model.SpiderRestUrl + "/" + reqPath
-
-	var requestBodyBytes []byte
-	var ok bool
-	if requestBodyBytes, ok = requestBody.([]byte); !ok {
-		return nil, fmt.Errorf("requestBody is not []byte type")
-	}
-
-	var requestBodyMap map[string]interface{}
-	err := json.Unmarshal(requestBodyBytes, &requestBodyMap)
-	if err != nil {
-		return nil, fmt.Errorf("JSON unmarshal error: %v", err)
-	}
-
-	err = ExecuteHttpRequest(
-		client,
-		method,
-		url,
-		nil,
-		SetUseBody(requestBodyMap),
-		&requestBodyMap,
-		&callResult,
-		MediumDuration,
-	)
-
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return nil, err
-	}
-
-	return callResult, nil
-}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// TestMain runs the tests with the embedded kvstore in a temporary directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "tb-common")
+	if err != nil {
+		panic(err)
+	}
+	store, err := bolt.NewBoltStore(context.Background(), bolt.Config{Path: filepath.Join(dir, "kvstore.db")})
+	if err != nil {
+		panic(err)
+	}
+	if err := kvstore.InitializeStore(store); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	store.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// authErrorPatterns are the (lowercase) fragments of CSP error messages caused by invalid, revoked or expired credentials
+var authErrorPatterns = []string{
+	"authfailure",
+	"unauthorized",
+	"invalidclienttokenid",
+	"signaturedoesnotmatch",
+	"expiredtoken",
+	"token has expired",
+	"authenticationfailed",
+	"authorizationfailed",
+	"invalidauthenticationtoken",
+	"invalid_grant",
+	"invalid_client",
+	"invalidaccesskeyid",
+	"status code: 401",
+	"status code: 403",
+}
+
+// IsAuthError returns true if the error from CB-Spider is caused by the credential of the connection
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, pattern := range authErrorPatterns {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnConfigHealth returns the health of the connection config derived from its checks
+func ConnConfigHealth(connConfig model.ConnConfig) string {
+	switch {
+	case connConfig.LastCheckedTime.IsZero():
+		return model.ConnConfigNeverChecked
+	case connConfig.ConsecutiveFailures > 0:
+		return model.ConnConfigFailing
+	default:
+		return model.ConnConfigHealthy
+	}
+}
+
+// FilterConnConfigByHealth returns the connection configs of the health (healthy, failing, neverChecked)
+func FilterConnConfigByHealth(connConfigs model.ConnConfigList, health string) (model.ConnConfigList, error) {
+	switch health {
+	case model.ConnConfigHealthy, model.ConnConfigFailing, model.ConnConfigNeverChecked:
+	default:
+		return model.ConnConfigList{}, NewValidationFailedError("Invalid health (%s); use one of [%s, %s, %s]", health, model.ConnConfigHealthy, model.ConnConfigFailing, model.ConnConfigNeverChecked)
+	}
+	filtered := model.ConnConfigList{}
+	for _, connConfig := range connConfigs.Connectionconfig {
+		if ConnConfigHealth(connConfig) == health {
+			filtered.Connectionconfig = append(filtered.Connectionconfig, connConfig)
+		}
+	}
+	return filtered, nil
+}
+
+// applyConnConfigCheck updates the check fields of the connection config with the result of a check (err is nil if succeeded)
+func applyConnConfigCheck(connConfig *model.ConnConfig, err error) {
+	now := time.Now().UTC()
+	connConfig.LastCheckedTime = now
+	if err == nil {
+		connConfig.LastVerifiedTime = now
+		connConfig.ConsecutiveFailures = 0
+		connConfig.LastError = ""
+	} else {
+		connConfig.ConsecutiveFailures++
+		connConfig.LastError = err.Error()
+	}
+	connConfig.Health = ConnConfigHealth(*connConfig)
+}
+
+// putConnConfig stores the connection config
+func putConnConfig(connConfig model.ConnConfig) error {
+	val, err := json.Marshal(connConfig)
+	if err != nil {
+		return err
+	}
+	return kvstore.Put(GenConnectionKey(connConfig.ConfigName), string(val))
+}
+
+// VerifyConnConfig re-verifies the connection config against CB-Spider and records the result
+// (Verified, lastVerifiedTime, lastCheckedTime, consecutiveFailures and lastError)
+func VerifyConnConfig(connConfigName string) (model.ConnConfig, error) {
+	connConfig, err := GetConnConfig(connConfigName)
+	if err != nil {
+		return model.ConnConfig{}, NewResourceNotFoundError("connConfig", connConfigName)
+	}
+
+	verified, checkErr := CheckConnConfigAvailable(context.Background(), connConfigName)
+	if checkErr != nil {
+		log.Warn().Err(checkErr).Msgf("Connection config %s is not available", connConfigName)
+	}
+	connConfig.Verified = verified
+	applyConnConfigCheck(&connConfig, checkErr)
+
+	if err := putConnConfig(connConfig); err != nil {
+		log.Error().Err(err).Msg("")
+		return model.ConnConfig{}, err
+	}
+	return connConfig, nil
+}
+
+// VerifyAllConnConfig re-verifies all connection configs (in parallel up to connConfigImportConcurrency)
+func VerifyAllConnConfig() (model.ConnConfigList, error) {
+	allConnections, err := GetConnConfigList("", false, false)
+	if err != nil {
+		return model.ConnConfigList{}, err
+	}
+
+	results := make([]model.ConnConfig, len(allConnections.Connectionconfig))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, connConfigImportConcurrency)
+	for i, connConfig := range allConnections.Connectionconfig {
+		wg.Add(1)
+		go func(i int, connConfig model.ConnConfig) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := VerifyConnConfig(connConfig.ConfigName)
+			if err != nil {
+				result = connConfig
+			}
+			results[i] = result
+		}(i, connConfig)
+	}
+	wg.Wait()
+
+	return model.ConnConfigList{Connectionconfig: results}, nil
+}
+
+// recordConnConfigCheck records the result of a check (err is nil if succeeded) in the stored connection config.
+// It is also used for a call to CB-Spider through the connection failed by an auth error.
+func recordConnConfigCheck(connConfigName string, checkErr error) {
+	connConfig, err := GetConnConfig(connConfigName)
+	if err != nil {
+		return
+	}
+	applyConnConfigCheck(&connConfig, checkErr)
+	if err := putConnConfig(connConfig); err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	if checkErr != nil {
+		log.Warn().Msgf("Connection config %s failed (consecutive failures: %d): %v", connConfigName, connConfig.ConsecutiveFailures, checkErr)
+	}
+}
+
+// withoutConnConfigHealth makes the failure of the call not recorded in the connection config
+// (for the verification itself, which records its own result)
+func withoutConnConfigHealth() HttpRequestOption {
+	return func(o *httpRequestOptions) {
+		o.skipConnConfigHealth = true
+	}
+}
+
+// connectionNameOfBody returns the connection name of the request body to CB-Spider ("" if none)
+func connectionNameOfBody(body interface{}) string {
+	val, err := json.Marshal(body)
+	if err != nil {
+		return ""
+	}
+	req := model.SpiderConnectionName{}
+	if err := json.Unmarshal(val, &req); err != nil {
+		return ""
+	}
+	return req.ConnectionName
+}
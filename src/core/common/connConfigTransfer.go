@@ -0,0 +1,262 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// connConfigImportConcurrency is the number of connections verified concurrently in ImportConnConfigs
+const connConfigImportConcurrency = 10
+
+// ExportConnConfigs returns the document of all connection configs and their region assignments.
+// Credentials are referred by name only (no secrets are exported).
+func ExportConnConfigs() (model.ConnConfigExport, error) {
+	doc := model.ConnConfigExport{ExportedAt: time.Now().UTC(), Connections: []model.ConnConfigExportItem{}}
+
+	connections, err := GetConnConfigList("", false, false)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return doc, err
+	}
+	for _, connConfig := range connections.Connectionconfig {
+		doc.Connections = append(doc.Connections, model.ConnConfigExportItem{
+			ConfigName:           connConfig.ConfigName,
+			ProviderName:         strings.ToLower(connConfig.ProviderName),
+			DriverName:           connConfig.DriverName,
+			CredentialName:       connConfig.CredentialName,
+			CredentialHolder:     connConfig.CredentialHolder,
+			RegionZoneInfoName:   connConfig.RegionZoneInfoName,
+			RegionZoneInfo:       connConfig.RegionZoneInfo,
+			RegionRepresentative: connConfig.RegionRepresentative,
+			RootDiskType:         connConfig.RootDiskType,
+			RootDiskSize:         connConfig.RootDiskSize,
+		})
+	}
+	sort.Slice(doc.Connections, func(i, j int) bool { return doc.Connections[i].ConfigName < doc.Connections[j].ConfigName })
+	return doc, nil
+}
+
+// ImportConnConfigs recreates the connection configs of the export document against CB-Spider,
+// which must already hold the credentials of the same names. Missing drivers and regions are registered,
+// connections which already exist are kept (the import is idempotent), and each imported connection is verified.
+func ImportConnConfigs(doc model.ConnConfigExport) (model.ConnConfigImportResult, error) {
+	result := model.ConnConfigImportResult{
+		Created:           []string{},
+		Existing:          []string{},
+		Unverified:        []string{},
+		MissingCredential: []model.ConnConfigImportFailure{},
+		Failed:            []model.ConnConfigImportFailure{},
+	}
+
+	drivers, err := spiderNameSet("driver", "DriverName")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	credentials, err := spiderNameSet("credential", "CredentialName")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	spiderConnConfigs, err := spiderNameSet("connectionconfig", "ConfigName")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	regionList, err := RetrieveRegionListFromCsp()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	regionZones := map[string]bool{}
+	for _, region := range regionList.Region {
+		regionZones[region.RegionName] = true
+	}
+
+	fail := func(item model.ConnConfigExportItem, err error) {
+		log.Error().Err(err).Msgf("Failed to import the connection config %s", item.ConfigName)
+		result.Failed = append(result.Failed, model.ConnConfigImportFailure{ConfigName: item.ConfigName, CredentialName: item.CredentialName, Error: err.Error()})
+	}
+
+	imported := []model.ConnConfigExportItem{}
+	for _, item := range doc.Connections {
+		providerName := strings.ToLower(item.ProviderName)
+		if item.ConfigName == "" || providerName == "" || item.CredentialName == "" || item.RegionZoneInfoName == "" {
+			fail(item, fmt.Errorf("configName, providerName, credentialName and regionZoneInfoName are required"))
+			continue
+		}
+
+		// the credential holds secrets, so it must be registered in CB-Spider in advance
+		if !credentials[item.CredentialName] {
+			result.MissingCredential = append(result.MissingCredential, model.ConnConfigImportFailure{
+				ConfigName:     item.ConfigName,
+				CredentialName: item.CredentialName,
+				Error:          "credential is not registered in CB-Spider (register the credential first)",
+			})
+			continue
+		}
+
+		existing, tbExists, err := getStoredConnConfig(item.ConfigName)
+		if err != nil {
+			fail(item, err)
+			continue
+		}
+		if tbExists && existing.CredentialName != item.CredentialName {
+			fail(item, fmt.Errorf("the connection config already exists with the credential %s", existing.CredentialName))
+			continue
+		}
+
+		// driver and regions of the provider
+		if item.DriverName != "" && !drivers[item.DriverName] {
+			registered, err := RegisterCloudInfo(providerName)
+			if registered.DriverError != "" {
+				fail(item, fmt.Errorf("failed to register the driver %s: %v", item.DriverName, err))
+				continue
+			}
+			for _, regionName := range registered.Succeeded {
+				regionZones[providerName+"-"+regionName] = true
+				for _, zone := range RuntimeCloudInfo.CSPs[providerName].Regions[regionName].Zones {
+					regionZones[providerName+"-"+regionName+"-"+zone] = true
+				}
+			}
+			drivers[item.DriverName] = true
+		}
+		if !regionZones[item.RegionZoneInfoName] {
+			regionName, ok := findRegionOfRegionZone(providerName, item.RegionZoneInfoName)
+			if !ok {
+				fail(item, fmt.Errorf("regionZone %s is not found in cloud info", item.RegionZoneInfoName))
+				continue
+			}
+			if err := RegisterRegionZone(providerName, regionName); err != nil {
+				fail(item, fmt.Errorf("failed to register the region %s: %w", regionName, err))
+				continue
+			}
+			regionZones[providerName+"-"+regionName] = true
+			for _, zone := range RuntimeCloudInfo.CSPs[providerName].Regions[regionName].Zones {
+				regionZones[providerName+"-"+regionName+"-"+zone] = true
+			}
+		}
+
+		connConfig := model.ConnConfig{
+			ConfigName:         item.ConfigName,
+			ProviderName:       strings.ToUpper(providerName),
+			DriverName:         item.DriverName,
+			CredentialName:     item.CredentialName,
+			CredentialHolder:   item.CredentialHolder,
+			RegionZoneInfoName: item.RegionZoneInfoName,
+		}
+		spiderConnConfig := model.SpiderConnConfig{
+			ConfigName:     item.ConfigName,
+			ProviderName:   strings.ToUpper(providerName),
+			DriverName:     item.DriverName,
+			CredentialName: item.CredentialName,
+			RegionName:     item.RegionZoneInfoName,
+		}
+		created := false
+		if !spiderConnConfigs[item.ConfigName] {
+			spiderConnConfig, err = postSpiderConnConfig(connConfig)
+			if err != nil {
+				fail(item, err)
+				continue
+			}
+			spiderConnConfigs[item.ConfigName] = true
+			created = true
+		}
+		if !tbExists {
+			if _, err := saveConnConfig(spiderConnConfig, item.CredentialHolder); err != nil {
+				fail(item, err)
+				continue
+			}
+			created = true
+		}
+
+		if created {
+			result.Created = append(result.Created, item.ConfigName)
+		} else {
+			result.Existing = append(result.Existing, item.ConfigName)
+		}
+		imported = append(imported, item)
+	}
+
+	// verify the imported connections and restore their settings
+	var wg sync.WaitGroup
+	var resultMutex sync.Mutex
+	sem := make(chan struct{}, connConfigImportConcurrency)
+	for _, item := range imported {
+		wg.Add(1)
+		go func(item model.ConnConfigExportItem) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			verified, err := CheckConnConfigAvailable(context.Background(), item.ConfigName)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Connection config %s is not available", item.ConfigName)
+			}
+			if err := updateImportedConnConfig(item, verified, err); err != nil {
+				log.Error().Err(err).Msgf("Failed to update the connection config %s", item.ConfigName)
+			}
+			if !verified {
+				resultMutex.Lock()
+				result.Unverified = append(result.Unverified, item.ConfigName)
+				resultMutex.Unlock()
+			}
+		}(item)
+	}
+	wg.Wait()
+	sort.Strings(result.Unverified)
+
+	log.Info().Msgf("Imported connection configs (created: %d, existing: %d, unverified: %d, missing credential: %d, failed: %d)",
+		len(result.Created), len(result.Existing), len(result.Unverified), len(result.MissingCredential), len(result.Failed))
+	return result, nil
+}
+
+// updateImportedConnConfig stores the verification (with its check result) and the settings of the export document in the connection config
+func updateImportedConnConfig(item model.ConnConfigExportItem, verified bool, checkErr error) error {
+	connConfig, err := GetConnConfig(item.ConfigName)
+	if err != nil {
+		return err
+	}
+	connConfig.Verified = verified
+	applyConnConfigCheck(&connConfig, checkErr)
+	connConfig.RegionRepresentative = item.RegionRepresentative
+	if connConfig.RootDiskType == "" && connConfig.RootDiskSize == "" {
+		connConfig.RootDiskType = item.RootDiskType
+		connConfig.RootDiskSize = item.RootDiskSize
+	}
+	if verified {
+		regionInfo, err := GetRegion(connConfig.ProviderName, connConfig.RegionDetail.RegionName)
+		if err == nil {
+			connConfig.RegionDetail = regionInfo
+		}
+	}
+
+	val, err := json.Marshal(connConfig)
+	if err != nil {
+		return err
+	}
+	return kvstore.Put(GenConnectionKey(connConfig.ConfigName), string(val))
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// orphanRule is a kind of child objects which must have a parent object.
+// A child key is {prefix}{parentId}/{childType}/{childId} and its parent key is {prefix}{parentId}.
+type orphanRule struct {
+	prefix    string // relative to /ns/{nsId}
+	childType string
+}
+
+// orphanRules are the kinds of child objects checked by CheckConsistency
+var orphanRules = []orphanRule{
+	{"/resources/" + model.StrVNet + "/", model.StrSubnet},
+	{"/resources/" + model.StrDataDisk + "/", model.StrDataDiskSnapshot},
+	{"/mci/", "vm"},
+	{"/mci/", "subgroup"},
+}
+
+// OrphanObject is a child object without its parent object in the Key-Value store
+type OrphanObject struct {
+	Key       string `json:"key" example:"/ns/default/resources/vNet/vnet01/subnet/subnet01"`
+	Kind      string `json:"kind" example:"subnet"`
+	ParentKey string `json:"parentKey" example:"/ns/default/resources/vNet/vnet01"`
+}
+
+// ConsistencyReport is the result of a consistency check of the Key-Value store
+type ConsistencyReport struct {
+	Orphans []OrphanObject `json:"orphans"`
+	// Repaired is the number of deleted orphans (only with repair)
+	Repaired int `json:"repaired"`
+}
+
+// CheckConsistency scans all namespaces for orphaned child objects (e.g., subnets without vNets, VMs without MCIs).
+// If repair is true, the orphans are deleted.
+func CheckConsistency(repair bool) (ConsistencyReport, error) {
+	report := ConsistencyReport{Orphans: []OrphanObject{}}
+
+	nsIdList, err := ListNsId()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return report, err
+	}
+
+	for _, nsId := range nsIdList {
+		nsKey := "/ns/" + nsId
+		for _, rule := range orphanRules {
+			prefix := nsKey + rule.prefix
+			keyValue, _, err := kvstore.GetKvListPage(prefix, "", 0, true)
+			if err != nil {
+				log.Error().Err(err).Msg("")
+				return report, err
+			}
+			existing := make(map[string]bool, len(keyValue))
+			for _, kv := range keyValue {
+				existing[kv.Key] = true
+			}
+			for _, kv := range keyValue {
+				// {parentId}/{childType}/{childId}
+				parts := strings.Split(strings.TrimPrefix(kv.Key, prefix), "/")
+				if len(parts) != 3 || parts[1] != rule.childType {
+					continue
+				}
+				parentKey := prefix + parts[0]
+				if existing[parentKey] {
+					continue
+				}
+				report.Orphans = append(report.Orphans, OrphanObject{Key: kv.Key, Kind: rule.childType, ParentKey: parentKey})
+			}
+		}
+	}
+
+	if repair {
+		for _, orphan := range report.Orphans {
+			if err := kvstore.Delete(orphan.Key); err != nil {
+				log.Error().Err(err).Msgf("Failed to delete the orphan %s", orphan.Key)
+				return report, err
+			}
+			report.Repaired++
+		}
+	}
+	log.Info().Msgf("Consistency check: %d orphans found, %d repaired", len(report.Orphans), report.Repaired)
+	return report, nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// customRegionKeyPrefix is the prefix of custom regions in the Key-Value store (/cloudInfo/region/{provider}/{region})
+const customRegionKeyPrefix = "/cloudInfo/region/"
+
+var (
+	customRegionLock sync.Mutex
+	// staticRegions keeps the regions of the cloudinfo asset overridden by custom regions ({provider}/{region})
+	staticRegions = map[string]model.RegionDetail{}
+)
+
+// genCustomRegionKey returns the key of the custom region
+func genCustomRegionKey(providerName string, regionName string) string {
+	return customRegionKeyPrefix + providerName + "/" + regionName
+}
+
+// setRuntimeRegion replaces (or deletes if detail is nil) the region of RuntimeCloudInfo.
+// The maps are copied on write since RuntimeCloudInfo is read without locks.
+func setRuntimeRegion(providerName string, regionName string, detail *model.RegionDetail) {
+	csps := make(map[string]model.CSPDetail, len(RuntimeCloudInfo.CSPs))
+	for name, cspDetail := range RuntimeCloudInfo.CSPs {
+		csps[name] = cspDetail
+	}
+	cspDetail := csps[providerName]
+	regions := make(map[string]model.RegionDetail, len(cspDetail.Regions)+1)
+	for name, regionDetail := range cspDetail.Regions {
+		regions[name] = regionDetail
+	}
+	if detail == nil {
+		delete(regions, regionName)
+	} else {
+		regions[regionName] = *detail
+	}
+	cspDetail.Regions = regions
+	csps[providerName] = cspDetail
+	RuntimeCloudInfo = model.CloudInfo{CSPs: csps}
+}
+
+// validateCustomRegion validates and normalizes the custom region
+func validateCustomRegion(providerName string, regionName string, detail *model.RegionDetail) error {
+	if _, ok := RuntimeCloudInfo.CSPs[providerName]; !ok {
+		return fmt.Errorf("provider '%s' not found in cloud info", providerName)
+	}
+	if err := CheckString(regionName); err != nil {
+		return err
+	}
+	detail.RegionName = regionName
+	if detail.RegionId == "" {
+		detail.RegionId = regionName
+	}
+	if len(detail.Zones) == 0 {
+		return fmt.Errorf("zones of the region '%s' must not be empty", regionName)
+	}
+	seen := map[string]bool{}
+	for _, zone := range detail.Zones {
+		if zone == "" || strings.ContainsAny(zone, " /") {
+			return fmt.Errorf("invalid zone name '%s'", zone)
+		}
+		if seen[zone] {
+			return fmt.Errorf("duplicated zone '%s'", zone)
+		}
+		seen[zone] = true
+	}
+	if detail.Location.Latitude < -90 || detail.Location.Latitude > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90 (%v)", detail.Location.Latitude)
+	}
+	if detail.Location.Longitude < -180 || detail.Location.Longitude > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180 (%v)", detail.Location.Longitude)
+	}
+	return nil
+}
+
+// applyCustomRegion applies the custom region to RuntimeCloudInfo (keeping the overridden static region)
+func applyCustomRegion(providerName string, regionName string, detail model.RegionDetail) {
+	staticKey := providerName + "/" + regionName
+	if _, overridden := staticRegions[staticKey]; !overridden {
+		if staticDetail, ok := RuntimeCloudInfo.CSPs[providerName].Regions[regionName]; ok {
+			staticRegions[staticKey] = staticDetail
+		}
+	}
+	setRuntimeRegion(providerName, regionName, &detail)
+}
+
+// LoadCustomRegions applies the custom regions kept in the Key-Value store to RuntimeCloudInfo.
+// It is called at startup (before registering cloud info to CB-Spider).
+func LoadCustomRegions() error {
+	customRegionLock.Lock()
+	defer customRegionLock.Unlock()
+
+	keyValue, err := kvstore.GetKvList(customRegionKeyPrefix)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	for _, kv := range keyValue {
+		parts := strings.Split(strings.TrimPrefix(kv.Key, customRegionKeyPrefix), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		detail := model.RegionDetail{}
+		if err := json.Unmarshal([]byte(kv.Value), &detail); err != nil {
+			log.Warn().Err(err).Msgf("Skip the invalid custom region %s", kv.Key)
+			continue
+		}
+		if _, ok := RuntimeCloudInfo.CSPs[parts[0]]; !ok {
+			log.Warn().Msgf("Skip the custom region %s (provider not found)", kv.Key)
+			continue
+		}
+		applyCustomRegion(parts[0], parts[1], detail)
+	}
+	log.Info().Msgf("Loaded %d custom regions", len(keyValue))
+	return nil
+}
+
+// putCustomRegion creates (or updates if update is true) the custom region, and registers it to CB-Spider
+func putCustomRegion(providerName string, regionName string, detail model.RegionDetail, update bool) (model.RegionDetail, error) {
+	providerName = strings.ToLower(providerName)
+	regionName = strings.ToLower(regionName)
+	if err := validateCustomRegion(providerName, regionName, &detail); err != nil {
+		return model.RegionDetail{}, err
+	}
+
+	customRegionLock.Lock()
+	defer customRegionLock.Unlock()
+
+	key := genCustomRegionKey(providerName, regionName)
+	existing, err := kvstore.Get(key)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RegionDetail{}, err
+	}
+	_, inCloudInfo := RuntimeCloudInfo.CSPs[providerName].Regions[regionName]
+	if !update && existing != "" {
+		return model.RegionDetail{}, fmt.Errorf("custom region '%s' of '%s' already exists (use PUT to update)", regionName, providerName)
+	}
+	if update && existing == "" && !inCloudInfo {
+		return model.RegionDetail{}, fmt.Errorf("region '%s' of '%s' not found", regionName, providerName)
+	}
+
+	previous, hadPrevious := RuntimeCloudInfo.CSPs[providerName].Regions[regionName]
+	applyCustomRegion(providerName, regionName, detail)
+	if err := RegisterRegionZone(providerName, regionName); err != nil {
+		// roll back the runtime change
+		if hadPrevious {
+			setRuntimeRegion(providerName, regionName, &previous)
+		} else {
+			setRuntimeRegion(providerName, regionName, nil)
+		}
+		return model.RegionDetail{}, fmt.Errorf("failed to register the region '%s' to CB-Spider: %w", regionName, err)
+	}
+
+	val, _ := json.Marshal(detail)
+	if err := kvstore.Put(key, string(val)); err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RegionDetail{}, err
+	}
+	log.Info().Msgf("Custom region %s/%s is applied (zones: %v)", providerName, regionName, detail.Zones)
+	return detail, nil
+}
+
+// CreateCustomRegion adds a region (e.g., a new region of a CSP or a private OpenStack region) to the cloud info at runtime.
+// A region of the cloudinfo asset with the same name is overridden by the custom region.
+func CreateCustomRegion(providerName string, regionName string, detail model.RegionDetail) (model.RegionDetail, error) {
+	return putCustomRegion(providerName, regionName, detail, false)
+}
+
+// UpdateCustomRegion updates the custom region (or overrides the region of the cloudinfo asset)
+func UpdateCustomRegion(providerName string, regionName string, detail model.RegionDetail) (model.RegionDetail, error) {
+	return putCustomRegion(providerName, regionName, detail, true)
+}
+
+// DeleteCustomRegion deletes the custom region. The region of the cloudinfo asset is restored if it was overridden.
+func DeleteCustomRegion(providerName string, regionName string) error {
+	providerName = strings.ToLower(providerName)
+	regionName = strings.ToLower(regionName)
+
+	customRegionLock.Lock()
+	defer customRegionLock.Unlock()
+
+	key := genCustomRegionKey(providerName, regionName)
+	existing, err := kvstore.Get(key)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	if existing == "" {
+		return fmt.Errorf("custom region '%s' of '%s' not found", regionName, providerName)
+	}
+	if err := kvstore.Delete(key); err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	staticKey := providerName + "/" + regionName
+	if staticDetail, ok := staticRegions[staticKey]; ok {
+		delete(staticRegions, staticKey)
+		setRuntimeRegion(providerName, regionName, &staticDetail)
+		if err := RegisterRegionZone(providerName, regionName); err != nil {
+			log.Warn().Err(err).Msgf("Failed to restore the region %s/%s in CB-Spider", providerName, regionName)
+		}
+		return nil
+	}
+
+	detail := RuntimeCloudInfo.CSPs[providerName].Regions[regionName]
+	setRuntimeRegion(providerName, regionName, nil)
+
+	// remove the regionZones from CB-Spider (they are kept if still used by connection configs)
+	regionZoneNames := []string{providerName + "-" + regionName}
+	for _, zone := range detail.Zones {
+		regionZoneNames = append(regionZoneNames, providerName+"-"+regionName+"-"+zone)
+	}
+	for _, regionZoneName := range regionZoneNames {
+		var callResult interface{}
+		requestBody := NoBody
+		err := ExecuteHttpRequest(
+			resty.New(),
+			"DELETE",
+			model.SpiderRestUrl+"/region/"+regionZoneName,
+			nil,
+			SetUseBody(requestBody),
+			&requestBody,
+			&callResult,
+			VeryShortDuration,
+			WithoutRetry(),
+		)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to delete the regionZone %s from CB-Spider", regionZoneName)
+		}
+	}
+	log.Info().Msgf("Custom region %s/%s is deleted", providerName, regionName)
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SecretMask is the placeholder returned instead of secret values (e.g., private keys) in API responses
+const SecretMask = "********"
+
+// encryptedSecretPrefix marks a value encrypted by EncryptSecret.
+// Format: tbenc:v1:{base64(nonce|data key encrypted by the master key)}:{base64(nonce|value encrypted by the data key)}
+const encryptedSecretPrefix = "tbenc:v1:"
+
+var (
+	masterKeyOnce sync.Once
+	masterKey     []byte
+	masterKeyErr  error
+)
+
+// loadMasterKey returns the master key given by TB_ENCRYPTION_KEY or the file of TB_ENCRYPTION_KEY_FILE.
+// A base64-encoded 32-byte value is used as is; any other value is used as a passphrase (SHA-256).
+// It returns nil if no master key is configured.
+func loadMasterKey() ([]byte, error) {
+	masterKeyOnce.Do(func() {
+		value := os.Getenv("TB_ENCRYPTION_KEY")
+		if value == "" {
+			if path := os.Getenv("TB_ENCRYPTION_KEY_FILE"); path != "" {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					masterKeyErr = fmt.Errorf("failed to read the master key file (%s): %w", path, err)
+					return
+				}
+				value = string(data)
+			}
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			log.Warn().Msg("TB_ENCRYPTION_KEY is not set; secrets (e.g., SSH private keys) are stored without encryption")
+			return
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil && len(decoded) == 32 {
+			masterKey = decoded
+			return
+		}
+		sum := sha256.Sum256([]byte(value))
+		masterKey = sum[:]
+	})
+	return masterKey, masterKeyErr
+}
+
+// EncryptionEnabled returns true if a master key is configured
+func EncryptionEnabled() bool {
+	key, err := loadMasterKey()
+	return err == nil && key != nil
+}
+
+// IsEncryptedSecret returns true if the value is encrypted by EncryptSecret
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedSecretPrefix)
+}
+
+// sealAesGcm encrypts the plaintext with the key and returns nonce|ciphertext
+func sealAesGcm(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(crand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openAesGcm decrypts nonce|ciphertext with the key
+func openAesGcm(key []byte, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// EncryptSecret encrypts the value with a random data key (AES-GCM), which is encrypted by the master key (envelope encryption).
+// The value is returned as is if it is empty or already encrypted, or if no master key is configured.
+func EncryptSecret(plaintext string) (string, error) {
+	if plaintext == "" || IsEncryptedSecret(plaintext) {
+		return plaintext, nil
+	}
+	key, err := loadMasterKey()
+	if err != nil {
+		return "", err
+	}
+	if key == nil {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(crand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	sealedValue, err := sealAesGcm(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	sealedDataKey, err := sealAesGcm(key, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealedDataKey) + ":" + base64.StdEncoding.EncodeToString(sealedValue), nil
+}
+
+// DecryptSecret decrypts the value encrypted by EncryptSecret (a plaintext value is returned as is)
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	key, err := loadMasterKey()
+	if err != nil {
+		return "", err
+	}
+	if key == nil {
+		return "", fmt.Errorf("the value is encrypted but TB_ENCRYPTION_KEY is not set")
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedSecretPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	sealedDataKey, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	sealedValue, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	dataKey, err := openAesGcm(key, sealedDataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the data key (the master key may be changed): %w", err)
+	}
+	plaintext, err := openAesGcm(dataKey, sealedValue)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the value: %w", err)
+	}
+	return string(plaintext), nil
+}
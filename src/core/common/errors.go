@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// ErrNotSupported is matched (errors.Is) by the error returned for an operation which the provider does not support
+var ErrNotSupported = errors.New("operation is not supported")
+
+// NotSupportedError is returned up front when the operation is not supported by the provider
+type NotSupportedError struct {
+	Operation    string
+	ProviderName string
+}
+
+func (e *NotSupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported for the provider (%s)", e.Operation, e.ProviderName)
+}
+
+// Is makes errors.Is(err, ErrNotSupported) true
+func (e *NotSupportedError) Is(target error) bool {
+	return target == ErrNotSupported
+}
+
+// ApiError is an error with a machine-readable code (model.ErrCode*) which is mapped to an HTTP status
+type ApiError struct {
+	Code    string
+	Message string
+	Details interface{}
+	// Err is the cause of the error (if any)
+	Err error
+}
+
+func (e *ApiError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the cause of the error
+func (e *ApiError) Unwrap() error {
+	return e.Err
+}
+
+// NewResourceNotFoundError returns ApiError for a resource which does not exist
+func NewResourceNotFoundError(resourceType string, resourceId string) error {
+	return &ApiError{
+		Code:    model.ErrCodeResourceNotFound,
+		Message: fmt.Sprintf("The %s %s does not exist.", resourceType, resourceId),
+		Details: map[string]string{"resourceType": resourceType, "resourceId": resourceId},
+	}
+}
+
+// NewResourceInUseError returns ApiError for a resource which is used by other objects
+func NewResourceInUseError(resourceType string, resourceId string, usedBy []string) error {
+	message := fmt.Sprintf("The %s %s is in use", resourceType, resourceId)
+	if len(usedBy) > 0 {
+		message += " by [" + strings.Join(usedBy, ", ") + "]"
+	}
+	return &ApiError{
+		Code:    model.ErrCodeResourceInUse,
+		Message: message + ".",
+		Details: map[string]interface{}{"resourceType": resourceType, "resourceId": resourceId, "usedBy": usedBy},
+	}
+}
+
+// NewConflictError returns ApiError for a request which conflicts with the current state
+func NewConflictError(format string, args ...interface{}) error {
+	return &ApiError{Code: model.ErrCodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewPreconditionFailedError returns ApiError for a request whose precondition does not match the current state
+func NewPreconditionFailedError(format string, args ...interface{}) error {
+	return &ApiError{Code: model.ErrCodePreconditionFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewValidationFailedError returns ApiError for an invalid request
+func NewValidationFailedError(format string, args ...interface{}) error {
+	return &ApiError{Code: model.ErrCodeValidationFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewUpstreamError returns ApiError for a failure to reach the upstream (e.g., CB-Spider)
+func NewUpstreamError(upstream string, err error) error {
+	return &ApiError{
+		Code:    model.ErrCodeUpstreamUnavailable,
+		Message: fmt.Sprintf("[Error from: %s] Message: %s", upstream, err.Error()),
+		Details: map[string]string{"upstream": upstream},
+		Err:     err,
+	}
+}
+
+// apiErrorStatus is the HTTP status of each error code
+var apiErrorStatus = map[string]int{
+	model.ErrCodeResourceNotFound:    http.StatusNotFound,
+	model.ErrCodeResourceInUse:       http.StatusConflict,
+	model.ErrCodeConflict:            http.StatusConflict,
+	model.ErrCodePreconditionFailed:  http.StatusPreconditionFailed,
+	model.ErrCodeValidationFailed:    http.StatusBadRequest,
+	model.ErrCodeForbidden:           http.StatusForbidden,
+	model.ErrCodeUpstreamUnavailable: http.StatusBadGateway,
+	model.ErrCodeNotSupported:        http.StatusNotImplemented,
+	model.ErrCodeQuotaExceeded:       http.StatusTooManyRequests,
+	model.ErrCodeRateLimited:         http.StatusTooManyRequests,
+	model.ErrCodeBadRequest:          http.StatusBadRequest,
+	model.ErrCodeInternal:            http.StatusInternalServerError,
+}
+
+// ToErrorResponse returns the ErrorResponse and the HTTP status of the error.
+// Errors without a code get defaultStatus (BadRequest for 4xx, InternalError for the others).
+func ToErrorResponse(err error, defaultStatus int) (model.ErrorResponse, int) {
+	response := model.ErrorResponse{Message: err.Error()}
+
+	var validationErr *RequestValidationError
+	var apiErr *ApiError
+	var quotaErr *QuotaExceededError
+	var unavailableErr *UpstreamUnavailableError
+	switch {
+	case errors.As(ToRequestValidationError(err), &validationErr):
+		response.Code = model.ErrCodeValidationFailed
+		response.Message = validationErr.Error()
+		response.Details = validationErr.Fields
+	case errors.As(err, &apiErr):
+		response.Code = apiErr.Code
+		response.Details = apiErr.Details
+	case errors.As(err, &quotaErr):
+		response.Code = model.ErrCodeQuotaExceeded
+	case errors.As(err, &unavailableErr):
+		// the circuit to the upstream is open (retry later)
+		response.Code = model.ErrCodeUpstreamUnavailable
+		return response, http.StatusServiceUnavailable
+	case errors.Is(err, ErrNotSupported):
+		response.Code = model.ErrCodeNotSupported
+	default:
+		if defaultStatus >= 400 && defaultStatus < 500 {
+			response.Code = model.ErrCodeBadRequest
+		} else {
+			response.Code = model.ErrCodeInternal
+		}
+		return response, defaultStatus
+	}
+	if status, ok := apiErrorStatus[response.Code]; ok {
+		return response, status
+	}
+	return response, defaultStatus
+}
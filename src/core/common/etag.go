@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/labstack/echo/v4"
+)
+
+// GetKvRevision returns the revision of the objects stored at the keys (including their child keys).
+// The revision is a hash of the stored key-values, so it changes whenever any of them (e.g., a VM status) is updated.
+func GetKvRevision(keys ...string) (string, error) {
+	hash := sha256.New()
+	for _, key := range keys {
+		keyValues, err := kvstore.GetKvList(key)
+		if err != nil {
+			return "", err
+		}
+		for _, kv := range keyValues {
+			// exclude the keys sharing the prefix only (e.g., mci01 and mci010)
+			if kv.Key != key && !strings.HasPrefix(kv.Key, key+"/") {
+				continue
+			}
+			hash.Write([]byte(kv.Key))
+			hash.Write([]byte{0})
+			hash.Write([]byte(kv.Value))
+			hash.Write([]byte{0})
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:32], nil
+}
+
+// genETag returns the ETag of the revision for the request (the query params select the representation)
+func genETag(c echo.Context, revision string) string {
+	query := c.Request().URL.Query()
+	query.Del("refresh")
+	hash := sha256.Sum256([]byte(revision + "?" + query.Encode()))
+	return "\"" + hex.EncodeToString(hash[:])[:32] + "\""
+}
+
+// IsNotModified returns true if the If-None-Match header of the request matches the revision.
+// It always returns false if refresh=true is requested, to get the latest status from the CSP.
+func IsNotModified(c echo.Context, revision string) bool {
+	if revision == "" || c.QueryParam("refresh") == "true" {
+		return false
+	}
+	ifNoneMatch := c.Request().Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag := genETag(c, revision)
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPreconditionFailed returns true if the If-Match header of the request is given and does not match the revision.
+// The ETag from the GET of the object (without query params) or "*" can be used for If-Match.
+func IsPreconditionFailed(c echo.Context, revision string) bool {
+	ifMatch := c.Request().Header.Get("If-Match")
+	if ifMatch == "" {
+		return false
+	}
+	etag := genETag(c, revision)
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return false
+		}
+	}
+	return true
+}
+
+// EndRequestNotModified updates the request details and sends 304 Not Modified with the ETag of the revision
+func EndRequestNotModified(c echo.Context, revision string) error {
+
+	reqID := c.Request().Header.Get(echo.HeaderXRequestID)
+
+	if v, ok := RequestMap.Load(reqID); ok {
+		details := v.(RequestDetails)
+		details.EndTime = time.Now()
+		details.DurationMs = details.EndTime.Sub(details.StartTime).Milliseconds()
+		details.Status = RequestStatusSuccess
+		details.ResponseData = "[NOT MODIFIED] " + genETag(c, revision)
+		RequestMap.Store(reqID, details)
+
+		c.Response().Header().Set(echo.HeaderXRequestID, reqID)
+	}
+
+	c.Response().Header().Set("ETag", genETag(c, revision))
+	return c.NoContent(http.StatusNotModified)
+}
+
+// EndRequestWithRevision sets the ETag of the revision (for successful requests) and sends the response by EndRequestWithLog
+func EndRequestWithRevision(c echo.Context, revision string, err error, responseData interface{}) error {
+	if err == nil && revision != "" {
+		c.Response().Header().Set("ETag", genETag(c, revision))
+	}
+	return EndRequestWithLog(c, err, responseData)
+}
@@ -0,0 +1,135 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/labstack/echo/v4"
+)
+
+// putEtagTestVm stores the VM object of the MCI with the given status
+func putEtagTestVm(t *testing.T, mciId string, status string) {
+	t.Helper()
+	if err := kvstore.Put(GenMciKey("etag-test", mciId, "vm01"), `{"id":"vm01","status":"`+status+`"}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKvRevisionChangedByVmStatus(t *testing.T) {
+	mciKey := GenMciKey("etag-test", "mci01", "")
+	if err := kvstore.Put(mciKey, `{"id":"mci01"}`); err != nil {
+		t.Fatal(err)
+	}
+	putEtagTestVm(t, "mci01", "Running")
+	putEtagTestVm(t, "mci010", "Running")
+	t.Cleanup(func() {
+		kvstore.Delete(mciKey)
+		kvstore.Delete(GenMciKey("etag-test", "mci01", "vm01"))
+		kvstore.Delete(GenMciKey("etag-test", "mci010", "vm01"))
+	})
+
+	revision, err := GetKvRevision(mciKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := GetKvRevision(mciKey); again != revision {
+		t.Errorf("the revision is not stable: %s, %s", revision, again)
+	}
+
+	// an MCI sharing the key prefix only does not change the revision
+	putEtagTestVm(t, "mci010", "Suspended")
+	if got, _ := GetKvRevision(mciKey); got != revision {
+		t.Errorf("the revision is changed by another MCI: %s, %s", revision, got)
+	}
+
+	putEtagTestVm(t, "mci01", "Suspended")
+	if got, _ := GetKvRevision(mciKey); got == revision {
+		t.Errorf("the revision is not changed by the VM status")
+	}
+}
+
+// TestConditionalGet checks that 304 is returned without the status update from the CSP
+// while the stored object is not changed, and that a changed VM status invalidates the ETag
+func TestConditionalGet(t *testing.T) {
+	mciKey := GenMciKey("etag-test", "mci02", "")
+	putEtagTestVm(t, "mci02", "Running")
+	t.Cleanup(func() { kvstore.Delete(GenMciKey("etag-test", "mci02", "vm01")) })
+
+	var refreshes int32
+	spider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&refreshes, 1)
+	}))
+	defer spider.Close()
+
+	// the handler follows the conditional GET of the MCI (the status is updated from CB-Spider)
+	e := echo.New()
+	e.GET("/tumblebug/ns/etag-test/mci/mci02", func(c echo.Context) error {
+		if revision, err := GetKvRevision(mciKey); err == nil && IsNotModified(c, revision) {
+			return EndRequestNotModified(c, revision)
+		}
+		resp, err := http.Get(spider.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		revision, _ := GetKvRevision(mciKey)
+		return EndRequestWithRevision(c, revision, err, map[string]string{"id": "mci02"})
+	})
+	get := func(query string, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		reqId := "etag-test"
+		RequestMap.Store(reqId, RequestDetails{StartTime: time.Now(), Status: RequestStatusHandling})
+		defer RequestMap.Delete(reqId)
+
+		req := httptest.NewRequest(http.MethodGet, "/tumblebug/ns/etag-test/mci/mci02"+query, nil)
+		req.Header.Set(echo.HeaderXRequestID, reqId)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("", "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, ETag %q", rec.Code, etag)
+	}
+
+	rec = get("", etag)
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != etag || rec.Body.Len() != 0 {
+		t.Errorf("status %d, ETag %q, body %q, want 304 with %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String(), etag)
+	}
+	if n := atomic.LoadInt32(&refreshes); n != 1 {
+		t.Errorf("refreshes %d, want 1 (304 must skip the refresh)", n)
+	}
+
+	// weak and listed ETags are matched as well
+	if rec = get("", `"other", W/`+etag); rec.Code != http.StatusNotModified {
+		t.Errorf("status %d for the listed weak ETag, want 304", rec.Code)
+	}
+
+	// the ETag depends on the representation selected by the query params
+	if rec = get("?option=status", etag); rec.Code != http.StatusOK {
+		t.Errorf("status %d for another representation, want 200", rec.Code)
+	}
+
+	// refresh=true gets the latest status regardless of If-None-Match
+	if rec = get("?refresh=true", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") != etag {
+		t.Errorf("status %d, ETag %q for refresh=true, want 200 with %s", rec.Code, rec.Header().Get("ETag"), etag)
+	}
+
+	putEtagTestVm(t, "mci02", "Suspended")
+	refreshesBefore := atomic.LoadInt32(&refreshes)
+	rec = get("", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("status %d, ETag %q after the VM status is changed, want 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+	if n := atomic.LoadInt32(&refreshes); n != refreshesBefore+1 {
+		t.Errorf("refreshes %d, want %d", n, refreshesBefore+1)
+	}
+}
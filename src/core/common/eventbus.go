@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// EventSubscription is a local subscription to events (e.g., for an SSE stream).
+// Events are dropped (and counted) if the subscriber does not consume them fast enough.
+type EventSubscription struct {
+	C <-chan model.WebhookEvent
+
+	ch      chan model.WebhookEvent
+	dropped atomic.Int64
+}
+
+var (
+	eventSubscribersLock sync.RWMutex
+	eventSubscribers     = map[*EventSubscription]struct{}{}
+)
+
+// SubscribeEvents subscribes to all events with the buffer size. Close must be called to unsubscribe.
+func SubscribeEvents(bufferSize int) *EventSubscription {
+	ch := make(chan model.WebhookEvent, bufferSize)
+	sub := &EventSubscription{C: ch, ch: ch}
+
+	eventSubscribersLock.Lock()
+	eventSubscribers[sub] = struct{}{}
+	eventSubscribersLock.Unlock()
+	return sub
+}
+
+// Close unsubscribes from events
+func (s *EventSubscription) Close() {
+	eventSubscribersLock.Lock()
+	delete(eventSubscribers, s)
+	eventSubscribersLock.Unlock()
+}
+
+// Dropped returns the number of events dropped since the last call
+func (s *EventSubscription) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// publishEvent delivers the event to local subscribers without blocking
+func publishEvent(event model.WebhookEvent) {
+	eventSubscribersLock.RLock()
+	defer eventSubscribersLock.RUnlock()
+	for sub := range eventSubscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// ForwardError is a structured error of the forwarding proxy
+type ForwardError struct {
+	StatusCode int    `json:"statusCode"`          // HTTP status returned to the client (e.g., 403, 502, 504)
+	Message    string `json:"message"`             // description of the error
+	Upstream   string `json:"upstream,omitempty"`  // destination URL of the forwarded request
+	RequestId  string `json:"requestId,omitempty"` // X-Request-Id of the request
+}
+
+func (e *ForwardError) Error() string {
+	return e.Message
+}
+
+// forwardRequestHeaders are the request headers passed through to the upstream
+var forwardRequestHeaders = []string{
+	echo.HeaderContentType,
+	echo.HeaderAccept,
+	echo.HeaderAcceptEncoding,
+}
+
+// ForwardResponseHeaders are the response headers passed through from the upstream
+// (hop-by-hop headers such as Connection and Transfer-Encoding are handled by the server)
+var ForwardResponseHeaders = []string{
+	echo.HeaderContentType,
+	echo.HeaderContentLength,
+	echo.HeaderContentEncoding,
+	echo.HeaderContentDisposition,
+	echo.HeaderLastModified,
+	"Cache-Control",
+	"ETag",
+}
+
+// forwardClient does not follow redirects, since a redirect may point to a host outside the allowlist
+var forwardClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ForwardTimeout returns the timeout of a forwarded request (TB_FORWARD_TIMEOUT_SEC, default 60)
+func ForwardTimeout() time.Duration {
+	sec, err := strconv.Atoi(NVL(os.Getenv("TB_FORWARD_TIMEOUT_SEC"), "60"))
+	if err != nil || sec <= 0 {
+		sec = 60
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// ForwardMaxBytes returns the size limit of a forwarded response (TB_FORWARD_MAX_RESPONSE_MB, default 100)
+func ForwardMaxBytes() int64 {
+	mb, err := strconv.Atoi(NVL(os.Getenv("TB_FORWARD_MAX_RESPONSE_MB"), "100"))
+	if err != nil || mb <= 0 {
+		mb = 100
+	}
+	return int64(mb) << 20
+}
+
+// forwardAllowedHosts returns the hosts which requests can be forwarded to.
+// The host of CB-Spider is always allowed; others are given by TB_FORWARD_ALLOWED_HOSTS (comma-separated host or host:port).
+func forwardAllowedHosts() []string {
+	hosts := []string{}
+	if u, err := url.Parse(model.SpiderRestUrl); err == nil && u.Host != "" {
+		hosts = append(hosts, strings.ToLower(u.Host))
+	}
+	for _, h := range strings.Split(os.Getenv("TB_FORWARD_ALLOWED_HOSTS"), ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// isForwardAllowedHost checks the host (host or host:port) of the destination against the allowlist
+func isForwardAllowedHost(host string) bool {
+	host = strings.ToLower(host)
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, allowed := range forwardAllowedHosts() {
+		// an entry without a port allows any port of the host
+		if allowed == host || (!strings.Contains(allowed, ":") && allowed == hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveForwardUrl returns the destination URL of the forwarding proxy.
+// reqPath is a path of CB-Spider (without /spider/ prefix) or an absolute http(s) URL of an allowed host.
+func ResolveForwardUrl(reqPath string, rawQuery string) (string, error) {
+	targetUrl := model.SpiderRestUrl + "/" + strings.TrimPrefix(reqPath, "/")
+	if strings.HasPrefix(reqPath, "http://") || strings.HasPrefix(reqPath, "https://") {
+		targetUrl = reqPath
+	}
+
+	u, err := url.Parse(targetUrl)
+	if err != nil || u.Host == "" {
+		return "", &ForwardError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("invalid forwarding path (%s)", reqPath)}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", &ForwardError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("unsupported scheme (%s)", u.Scheme)}
+	}
+	if !isForwardAllowedHost(u.Host) {
+		return "", &ForwardError{StatusCode: http.StatusForbidden, Message: fmt.Sprintf("forwarding to the host (%s) is not allowed", u.Host), Upstream: u.Host}
+	}
+	if rawQuery != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += rawQuery
+	}
+	return u.String(), nil
+}
+
+// ForwardRequest sends the request to the destination and returns the upstream response without reading its body.
+// The caller must close the response body. Failures are returned as ForwardError (502, or 504 on timeout).
+func ForwardRequest(ctx context.Context, method string, targetUrl string, body []byte, header http.Header, reqId string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, targetUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, &ForwardError{StatusCode: http.StatusBadRequest, Message: err.Error(), Upstream: targetUrl, RequestId: reqId}
+	}
+	for _, h := range forwardRequestHeaders {
+		if v := header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	if len(body) > 0 && req.Header.Get(echo.HeaderContentType) == "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if reqId != "" {
+		req.Header.Set(echo.HeaderXRequestID, reqId)
+	}
+
+	resp, err := forwardClient.Do(req)
+	if err != nil {
+		if endpoint := spiderEndpoint(targetUrl); endpoint != "" {
+			metrics.IncSpiderCallError(method, endpoint)
+		}
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			log.Error().Err(err).Msgf("Timeout while forwarding the request to %s", targetUrl)
+			return nil, &ForwardError{StatusCode: http.StatusGatewayTimeout, Message: "upstream did not respond in time", Upstream: targetUrl, RequestId: reqId}
+		}
+		log.Error().Err(err).Msgf("Failed to forward the request to %s", targetUrl)
+		return nil, &ForwardError{StatusCode: http.StatusBadGateway, Message: fmt.Sprintf("failed to reach upstream: %v", err), Upstream: targetUrl, RequestId: reqId}
+	}
+	if endpoint := spiderEndpoint(targetUrl); endpoint != "" && resp.StatusCode >= http.StatusBadRequest {
+		metrics.IncSpiderCallError(method, endpoint)
+	}
+	return resp, nil
+}
+
+// EndForwardRequest updates the request details of a forwarded request.
+// The response body is not recorded since it is streamed to the client.
+// If err is a ForwardError and nothing has been written yet, the structured error is sent.
+func EndForwardRequest(c echo.Context, statusCode int, size int64, err error) error {
+	reqID := c.Request().Header.Get(echo.HeaderXRequestID)
+
+	if v, ok := RequestMap.Load(reqID); ok {
+		details := v.(RequestDetails)
+		details.EndTime = time.Now()
+		details.DurationMs = details.EndTime.Sub(details.StartTime).Milliseconds()
+		details.ResponseSize = int(size)
+		details.Status = RequestStatusSuccess
+		details.ResponseData = fmt.Sprintf("[STREAMED] %d bytes with status %d", size, statusCode)
+		if err != nil || statusCode >= http.StatusBadRequest {
+			details.Status = RequestStatusError
+		}
+		if err != nil {
+			details.ErrorResponse = err.Error()
+		}
+		RequestMap.Store(reqID, details)
+	}
+
+	if err == nil || c.Response().Committed {
+		return nil
+	}
+	var forwardErr *ForwardError
+	if !errors.As(err, &forwardErr) {
+		forwardErr = &ForwardError{StatusCode: http.StatusBadGateway, Message: err.Error()}
+	}
+	forwardErr.RequestId = reqID
+	c.Response().Header().Set(echo.HeaderXRequestID, reqID)
+	return c.JSON(forwardErr.StatusCode, forwardErr)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/go-resty/resty/v2"
+)
+
+// Status values of a health component
+const (
+	HealthOk       string = "ok"
+	HealthDegraded string = "degraded"
+	HealthDown     string = "down"
+)
+
+// healthSlowThreshold is the latency above which a component is reported as degraded
+const healthSlowThreshold = 2 * time.Second
+
+// HealthComponent is the health of a component which CB-Tumblebug depends on
+type HealthComponent struct {
+	Name      string `json:"name" example:"kvstore"`
+	Status    string `json:"status" example:"ok"`
+	Message   string `json:"message,omitempty"`
+	LatencyMs int64  `json:"latencyMs" example:"3"`
+	// Optional components do not make the overall status down
+	Optional bool `json:"optional,omitempty"`
+}
+
+// HealthSummary is the health of CB-Tumblebug and its dependencies
+type HealthSummary struct {
+	Status     string            `json:"status" example:"ok"`
+	CheckedAt  time.Time         `json:"checkedAt"`
+	Components []HealthComponent `json:"components"`
+	// InFlightOperations is the number of long operations in progress (e.g., MCI provisioning)
+	InFlightOperations int  `json:"inFlightOperations" example:"2"`
+	Draining           bool `json:"draining"`
+	SystemReady        bool `json:"systemReady"`
+	// SpiderVersion is the detected version of CB-Spider (the status is degraded if it is out of the supported range)
+	SpiderVersion model.SpiderVersionInfo `json:"spiderVersion"`
+}
+
+var (
+	healthLock   sync.Mutex
+	healthCached HealthSummary
+)
+
+// healthCacheDuration returns how long a health summary is reused (TB_HEALTH_CACHE_SEC, default 5)
+func healthCacheDuration() time.Duration {
+	return time.Duration(envInt("TB_HEALTH_CACHE_SEC", 5)) * time.Second
+}
+
+// healthStatusOf returns ok, or degraded if the probe was slow
+func healthStatusOf(latency time.Duration) (string, string) {
+	if latency > healthSlowThreshold {
+		return HealthDegraded, fmt.Sprintf("slow response (%v)", latency.Round(time.Millisecond))
+	}
+	return HealthOk, ""
+}
+
+// checkKvstoreHealth probes the Key-Value store with a round trip of put, get and delete of a probe key
+func checkKvstoreHealth() HealthComponent {
+	component := HealthComponent{Name: "kvstore"}
+	key := "/health/probe/" + GenUid()
+	start := time.Now()
+	err := kvstore.Put(key, "probe")
+	if err == nil {
+		var value string
+		value, err = kvstore.Get(key)
+		if err == nil && value != "probe" {
+			err = fmt.Errorf("probe value mismatch")
+		}
+	}
+	if err == nil {
+		err = kvstore.Delete(key)
+	}
+	latency := time.Since(start)
+	component.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		component.Status = HealthDown
+		component.Message = err.Error()
+		return component
+	}
+	component.Status, component.Message = healthStatusOf(latency)
+	return component
+}
+
+// checkSpiderHealth probes CB-Spider (/readyz) bypassing retries and the circuit breaker
+func checkSpiderHealth() HealthComponent {
+	component := HealthComponent{Name: "cb-spider"}
+	var callResult interface{}
+	requestBody := NoBody
+	start := time.Now()
+	err := ExecuteHttpRequest(
+		resty.New(),
+		"GET",
+		model.SpiderRestUrl+"/readyz",
+		nil,
+		SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		VeryShortDuration,
+		WithoutRetry(),
+		WithoutCircuitBreaker(),
+	)
+	latency := time.Since(start)
+	component.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		component.Status = HealthDown
+		component.Message = err.Error()
+		return component
+	}
+	component.Status, component.Message = healthStatusOf(latency)
+	return component
+}
+
+// checkDragonflyHealth probes the monitoring service (CB-Dragonfly) if it is configured
+func checkDragonflyHealth() HealthComponent {
+	component := HealthComponent{Name: "cb-dragonfly", Optional: true}
+	start := time.Now()
+	resp, err := resty.New().SetTimeout(5 * time.Second).R().Get(model.DragonflyRestUrl + "/healthcheck")
+	latency := time.Since(start)
+	component.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		component.Status = HealthDown
+		component.Message = err.Error()
+		return component
+	}
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		component.Status = HealthDown
+		component.Message = fmt.Sprintf("status %d", resp.StatusCode())
+		return component
+	}
+	component.Status, component.Message = healthStatusOf(latency)
+	return component
+}
+
+// GetHealthSummary returns the health of CB-Tumblebug and its dependencies.
+// The result is cached for TB_HEALTH_CACHE_SEC to avoid probe storms.
+func GetHealthSummary() HealthSummary {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	if !healthCached.CheckedAt.IsZero() && time.Since(healthCached.CheckedAt) < healthCacheDuration() {
+		return healthCached
+	}
+
+	probes := []func() HealthComponent{checkKvstoreHealth, checkSpiderHealth}
+	if model.DragonflyRestUrl != "" {
+		probes = append(probes, checkDragonflyHealth)
+	}
+	components := make([]HealthComponent, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe func() HealthComponent) {
+			defer wg.Done()
+			components[i] = probe()
+		}(i, probe)
+	}
+	wg.Wait()
+
+	// detect the version of CB-Spider if it was not ready at the startup
+	spiderVersionInfo := GetSpiderVersionInfo()
+	for _, component := range components {
+		if component.Name == "cb-spider" && component.Status != HealthDown && spiderVersionInfo.DetectedTime.IsZero() {
+			spiderVersionInfo = DetectSpiderVersion()
+		}
+	}
+
+	summary := HealthSummary{
+		Status:             HealthOk,
+		CheckedAt:          time.Now(),
+		Components:         components,
+		InFlightOperations: InFlightOperationCount(),
+		Draining:           IsDraining(),
+		SystemReady:        model.SystemReady,
+		SpiderVersion:      spiderVersionInfo,
+	}
+	for _, component := range components {
+		switch {
+		case component.Status == HealthDown && !component.Optional:
+			summary.Status = HealthDown
+		case component.Status != HealthOk && summary.Status == HealthOk:
+			summary.Status = HealthDegraded
+		}
+	}
+	if summary.Status == HealthOk && (summary.Draining || !summary.SystemReady || !summary.SpiderVersion.Compatible) {
+		summary.Status = HealthDegraded
+	}
+
+	healthCached = summary
+	return summary
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvutil"
+	"github.com/rs/zerolog/log"
+)
+
+// JobFunc is a long-running operation handled by an async job.
+// It should stop as soon as possible when ctx is canceled.
+type JobFunc func(ctx context.Context) (interface{}, error)
+
+// jobIdKey is the context key for the id of the job which runs the operation
+type jobIdKey struct{}
+
+// jobCancelMap keeps the cancel functions of the jobs running in this server (jobId -> context.CancelFunc)
+var jobCancelMap = sync.Map{}
+
+// jobLock serializes read-modify-write of job objects
+var jobLock sync.Mutex
+
+// GenJobKey is func to generate the key of a job object
+func GenJobKey(jobId string) string {
+	return "/job/" + jobId
+}
+
+// putJob stores the job object in the Key-Value store
+func putJob(job model.JobInfo) error {
+	val, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return kvstore.Put(GenJobKey(job.Id), string(val))
+}
+
+// updateJob applies the update function to the job object and stores it
+func updateJob(jobId string, update func(job *model.JobInfo)) (model.JobInfo, error) {
+	jobLock.Lock()
+	defer jobLock.Unlock()
+
+	job, err := GetJob(jobId)
+	if err != nil {
+		return job, err
+	}
+	update(&job)
+	job.UpdatedTime = time.Now()
+	err = putJob(job)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to update job (%s)", jobId)
+	}
+	return job, err
+}
+
+// StartJob creates an async job and runs the operation in background.
+// The job object is returned immediately and can be tracked by GetJob.
+func StartJob(jobType string, target string, fn JobFunc) (model.JobInfo, error) {
+	now := time.Now()
+	job := model.JobInfo{
+		Id:          "job-" + GenUid(),
+		Type:        jobType,
+		Target:      target,
+		Status:      model.JobPending,
+		CreatedTime: now,
+		UpdatedTime: now,
+	}
+	err := putJob(job)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create job")
+		return job, err
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), jobIdKey{}, job.Id))
+	jobCancelMap.Store(job.Id, cancel)
+
+	// Register the job to the shutdown coordinator
+	endOperation := BeginOperation("job "+job.Id+" ("+jobType+")", func(reason string) {
+		cancel()
+		updateJob(job.Id, func(j *model.JobInfo) {
+			j.Status = model.JobInterrupted
+			j.Error = "the job is interrupted by " + reason
+			j.EndTime = time.Now()
+		})
+	})
+
+	go func() {
+		defer func() {
+			cancel()
+			jobCancelMap.Delete(job.Id)
+			endOperation()
+		}()
+
+		updateJob(job.Id, func(j *model.JobInfo) {
+			if j.Status == model.JobPending {
+				j.Status = model.JobRunning
+			}
+		})
+
+		result, err := fn(ctx)
+
+		updateJob(job.Id, func(j *model.JobInfo) {
+			j.Result = result
+			j.EndTime = time.Now()
+			switch {
+			case err != nil && (errors.Is(err, context.Canceled) || ctx.Err() != nil):
+				j.Status = model.JobCanceled
+				j.Error = err.Error()
+			case err != nil:
+				j.Status = model.JobFailed
+				j.Error = err.Error()
+			default:
+				// the operation may finish even if the cancellation was requested
+				j.Status = model.JobSucceeded
+			}
+		})
+		log.Info().Msgf("Job (%s, %s) is finished", job.Id, jobType)
+	}()
+
+	return job, nil
+}
+
+// UpdateJobProgress records the latest progress message of the job which runs with ctx.
+// It does nothing if the operation is not running as a job.
+func UpdateJobProgress(ctx context.Context, progress string) {
+	jobId, ok := ctx.Value(jobIdKey{}).(string)
+	if !ok || jobId == "" {
+		return
+	}
+	updateJob(jobId, func(j *model.JobInfo) {
+		j.Progress = progress
+	})
+}
+
+// GetJob returns the job object
+func GetJob(jobId string) (model.JobInfo, error) {
+	job := model.JobInfo{}
+
+	keyValue, err := kvstore.GetKv(GenJobKey(jobId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return job, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return job, fmt.Errorf("the job (%s) is not found", jobId)
+	}
+	err = json.Unmarshal([]byte(keyValue.Value), &job)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return job, err
+	}
+	return job, nil
+}
+
+// ListJobs returns all job objects (newest first)
+func ListJobs() (model.JobInfoList, error) {
+	result := model.JobInfoList{Jobs: []model.JobInfo{}}
+
+	keyValue, err := kvstore.GetKvList("/job")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	for _, kv := range kvutil.FilterKvListBy(keyValue, "/job", 1) {
+		job := model.JobInfo{}
+		err = json.Unmarshal([]byte(kv.Value), &job)
+		if err != nil {
+			log.Error().Err(err).Str("key", kv.Key).Msg("Failed to unmarshal job")
+			continue
+		}
+		result.Jobs = append(result.Jobs, job)
+	}
+	sort.Slice(result.Jobs, func(i, j int) bool {
+		return result.Jobs[i].CreatedTime.After(result.Jobs[j].CreatedTime)
+	})
+	return result, nil
+}
+
+// CancelJob requests cancellation of the job. The job is canceled when the operation observes the cancellation.
+func CancelJob(jobId string) (model.JobInfo, error) {
+	job, err := GetJob(jobId)
+	if err != nil {
+		return job, err
+	}
+	if job.Status != model.JobPending && job.Status != model.JobRunning {
+		return job, fmt.Errorf("the job (%s) is %s and cannot be canceled", jobId, job.Status)
+	}
+
+	v, ok := jobCancelMap.Load(jobId)
+	if !ok {
+		return job, fmt.Errorf("the job (%s) is not running in this server", jobId)
+	}
+
+	job, err = updateJob(jobId, func(j *model.JobInfo) {
+		j.Status = model.JobCanceling
+	})
+	v.(context.CancelFunc)()
+	return job, err
+}
+
+// MarkOrphanedJobsInterrupted marks the jobs left unfinished by a previous server process as Interrupted.
+// It should be called once when the server starts.
+func MarkOrphanedJobsInterrupted() {
+	jobList, err := ListJobs()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check orphaned jobs")
+		return
+	}
+	for _, job := range jobList.Jobs {
+		if job.Status != model.JobPending && job.Status != model.JobRunning && job.Status != model.JobCanceling {
+			continue
+		}
+		if _, ok := jobCancelMap.Load(job.Id); ok {
+			continue
+		}
+		updateJob(job.Id, func(j *model.JobInfo) {
+			j.Status = model.JobInterrupted
+			j.Error = "the server was restarted while the job was in progress"
+			j.EndTime = time.Now()
+		})
+		log.Warn().Msgf("Job (%s, %s) is marked as %s", job.Id, job.Type, model.JobInterrupted)
+	}
+}
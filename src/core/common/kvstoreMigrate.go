@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/driver"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// kvStoreMigrateLock allows one migration of the key-value store at a time
+var kvStoreMigrateLock sync.Mutex
+
+// currentKvStoreConfig returns the backend of the key-value store used by CB-Tumblebug
+func currentKvStoreConfig() model.KvStoreConfig {
+	config := model.KvStoreConfig{Type: model.KvStoreType}
+	if config.Type == driver.TypeBolt {
+		config.Path = model.KvStoreBoltPath
+	} else {
+		config.Type = driver.TypeEtcd
+		config.Endpoints = strings.Split(model.EtcdEndpoints, ",")
+	}
+	return config
+}
+
+// kvStoreName returns the type and location of the backend (e.g., bolt:../meta_db/dat/kvstore.db)
+func kvStoreName(config model.KvStoreConfig) string {
+	if config.Type == driver.TypeBolt {
+		return config.Type + ":" + config.Path
+	}
+	return config.Type + ":" + strings.Join(config.Endpoints, ",")
+}
+
+// sameKvStore returns true if the configs point to the same backend
+func sameKvStore(a model.KvStoreConfig, b model.KvStoreConfig) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type == driver.TypeBolt {
+		pathA, errA := filepath.Abs(a.Path)
+		pathB, errB := filepath.Abs(b.Path)
+		return errA == nil && errB == nil && pathA == pathB
+	}
+	for _, endpointA := range a.Endpoints {
+		for _, endpointB := range b.Endpoints {
+			if strings.TrimSuffix(endpointA, "/") == strings.TrimSuffix(endpointB, "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateKvStoreConfig checks the type and the location of the backend
+func validateKvStoreConfig(config model.KvStoreConfig) error {
+	switch config.Type {
+	case driver.TypeEtcd:
+		if len(config.Endpoints) == 0 {
+			return NewValidationFailedError("endpoints are required for the kvstore type %s", config.Type)
+		}
+	case driver.TypeBolt:
+		if config.Path == "" {
+			return NewValidationFailedError("path is required for the kvstore type %s", config.Type)
+		}
+	default:
+		return NewValidationFailedError("unknown kvstore type: %s (supported: %s, %s)", config.Type, driver.TypeEtcd, driver.TypeBolt)
+	}
+	return nil
+}
+
+// allowedKvStores returns the etcd endpoints and the BoltDB files which can be the source or the target of a migration:
+// the backends of the server configuration (TB_ETCD_ENDPOINTS and TB_KVSTORE_BOLT_PATH)
+// and the ones listed in TB_KVSTORE_MIGRATE_ETCD_ENDPOINTS and TB_KVSTORE_MIGRATE_BOLT_PATHS (comma-separated)
+func allowedKvStores() (map[string]bool, map[string]bool) {
+	etcdEndpoints := map[string]bool{}
+	for _, list := range []string{model.EtcdEndpoints, os.Getenv("TB_KVSTORE_MIGRATE_ETCD_ENDPOINTS")} {
+		for _, endpoint := range strings.Split(list, ",") {
+			if endpoint = strings.TrimSuffix(strings.TrimSpace(endpoint), "/"); endpoint != "" {
+				etcdEndpoints[endpoint] = true
+			}
+		}
+	}
+	boltPaths := map[string]bool{}
+	for _, list := range []string{model.KvStoreBoltPath, os.Getenv("TB_KVSTORE_MIGRATE_BOLT_PATHS")} {
+		for _, path := range strings.Split(list, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				if absPath, err := filepath.Abs(path); err == nil {
+					boltPaths[absPath] = true
+				}
+			}
+		}
+	}
+	return etcdEndpoints, boltPaths
+}
+
+// checkKvStoreAllowed checks that the backend is allowed by the server configuration,
+// so that the request cannot read or write an arbitrary etcd or file
+func checkKvStoreAllowed(config model.KvStoreConfig) error {
+	etcdEndpoints, boltPaths := allowedKvStores()
+	switch config.Type {
+	case driver.TypeEtcd:
+		for _, endpoint := range config.Endpoints {
+			if !etcdEndpoints[strings.TrimSuffix(strings.TrimSpace(endpoint), "/")] {
+				return NewValidationFailedError("the etcd endpoint (%s) is not allowed; use TB_ETCD_ENDPOINTS or one of TB_KVSTORE_MIGRATE_ETCD_ENDPOINTS", endpoint)
+			}
+		}
+	case driver.TypeBolt:
+		absPath, err := filepath.Abs(config.Path)
+		if err != nil || !boltPaths[absPath] {
+			return NewValidationFailedError("the bolt path (%s) is not allowed; use TB_KVSTORE_BOLT_PATH or one of TB_KVSTORE_MIGRATE_BOLT_PATHS", config.Path)
+		}
+	}
+	return nil
+}
+
+// openKvStore opens the backend of the config (with the etcd credential of the server configuration)
+func openKvStore(ctx context.Context, config model.KvStoreConfig) (kvstore.Store, error) {
+	driverConfig := driver.Config{
+		Type:            config.Type,
+		EtcdEndpoints:   config.Endpoints,
+		EtcdDialTimeout: 5 * time.Second,
+		BoltPath:        config.Path,
+	}
+	if os.Getenv("TB_ETCD_AUTH_ENABLED") == "true" {
+		driverConfig.EtcdUsername = os.Getenv("TB_ETCD_USERNAME")
+		driverConfig.EtcdPassword = os.Getenv("TB_ETCD_PASSWORD")
+	}
+	return driver.NewStore(ctx, driverConfig)
+}
+
+// openKvStoreOrCurrent opens the backend of the config and returns the function to close it.
+// The current store of CB-Tumblebug is reused (and kept open) for the current backend
+// since the embedded store can be opened by only one process.
+func openKvStoreOrCurrent(ctx context.Context, config model.KvStoreConfig, current model.KvStoreConfig) (kvstore.Store, func(), error) {
+	if sameKvStore(config, current) {
+		store, err := kvstore.CurrentStore()
+		return store, func() {}, err
+	}
+	store, err := openKvStore(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, func() { store.Close() }, nil
+}
+
+// MigrateKvStore copies all keys (or the keys with req.KeyPrefix) from the source backend of the key-value store
+// (the current backend if omitted) to the target backend, e.g., from etcd to the embedded store for a single-node deployment.
+// The source or the target can be the current backend (e.g., to import the keys of the embedded store into etcd).
+// Only the backends of the server configuration can be used (see allowedKvStores).
+// The existing keys of the target are kept unless req.Overwrite is true.
+// CB-Tumblebug keeps using the current backend; restart it with TB_KVSTORE_TYPE of the target to switch.
+func MigrateKvStore(ctx context.Context, req *model.KvStoreMigrateReq) (model.KvStoreMigrateResult, error) {
+	result := model.KvStoreMigrateResult{KeyPrefix: req.KeyPrefix}
+
+	current := currentKvStoreConfig()
+	source := current
+	if req.Source != nil {
+		source = *req.Source
+		if err := validateKvStoreConfig(source); err != nil {
+			return result, err
+		}
+		if err := checkKvStoreAllowed(source); err != nil {
+			return result, err
+		}
+	}
+	if err := validateKvStoreConfig(req.Target); err != nil {
+		return result, err
+	}
+	if err := checkKvStoreAllowed(req.Target); err != nil {
+		return result, err
+	}
+	if sameKvStore(source, req.Target) {
+		return result, NewValidationFailedError("the target kvstore (%s) is the same as the source", kvStoreName(req.Target))
+	}
+	result.Source = kvStoreName(source)
+	result.Target = kvStoreName(req.Target)
+
+	if !kvStoreMigrateLock.TryLock() {
+		return result, NewConflictError("a migration of kvstore is in progress")
+	}
+	defer kvStoreMigrateLock.Unlock()
+
+	src, closeSrc, err := openKvStoreOrCurrent(ctx, source, current)
+	if err != nil {
+		return result, fmt.Errorf("failed to open the source kvstore (%s): %w", result.Source, err)
+	}
+	defer closeSrc()
+	dst, closeDst, err := openKvStoreOrCurrent(ctx, req.Target, current)
+	if err != nil {
+		return result, fmt.Errorf("failed to open the target kvstore (%s): %w", result.Target, err)
+	}
+	defer closeDst()
+
+	log.Info().Msgf("Migrating kvstore from %s to %s (keyPrefix: %q, overwrite: %t)", result.Source, result.Target, req.KeyPrefix, req.Overwrite)
+	startTime := time.Now()
+	stats, err := kvstore.CopyStore(ctx, src, dst, req.KeyPrefix, req.Overwrite)
+	result.TotalKeys = stats.Total
+	result.CopiedKeys = stats.Copied
+	result.SkippedKeys = stats.Skipped
+	result.ElapsedTime = int(math.Round(time.Since(startTime).Seconds()))
+	if err != nil {
+		result.Message = err.Error()
+		log.Error().Err(err).Msgf("Failed to migrate kvstore from %s to %s (copied: %d)", result.Source, result.Target, result.CopiedKeys)
+		return result, err
+	}
+	log.Info().Msgf("Migrated kvstore from %s to %s (total: %d, copied: %d, skipped: %d)",
+		result.Source, result.Target, result.TotalKeys, result.CopiedKeys, result.SkippedKeys)
+	return result, nil
+}
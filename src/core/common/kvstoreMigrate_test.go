@@ -0,0 +1,137 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/driver"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// setKvStoreMigrateConfig sets the server configuration of the backends for the test
+func setKvStoreMigrateConfig(t *testing.T, boltPath string, etcdEndpoints string) {
+	t.Helper()
+	prevType, prevBoltPath, prevEtcdEndpoints := model.KvStoreType, model.KvStoreBoltPath, model.EtcdEndpoints
+	t.Cleanup(func() {
+		model.KvStoreType, model.KvStoreBoltPath, model.EtcdEndpoints = prevType, prevBoltPath, prevEtcdEndpoints
+	})
+	model.KvStoreType = driver.TypeBolt
+	model.KvStoreBoltPath = boltPath
+	model.EtcdEndpoints = etcdEndpoints
+}
+
+func isValidationFailed(err error) bool {
+	var apiErr *ApiError
+	return errors.As(err, &apiErr) && apiErr.Code == model.ErrCodeValidationFailed
+}
+
+func TestCheckKvStoreAllowed(t *testing.T) {
+	dir := t.TempDir()
+	setKvStoreMigrateConfig(t, filepath.Join(dir, "current.db"), "http://etcd:2379")
+	t.Setenv("TB_KVSTORE_MIGRATE_ETCD_ENDPOINTS", "http://etcd-backup:2379, http://etcd-dr:2379/")
+	t.Setenv("TB_KVSTORE_MIGRATE_BOLT_PATHS", filepath.Join(dir, "backup.db"))
+
+	tests := []struct {
+		name    string
+		config  model.KvStoreConfig
+		allowed bool
+	}{
+		{"etcd of server config", model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://etcd:2379"}}, true},
+		{"etcd of server config with trailing slash", model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://etcd:2379/"}}, true},
+		{"etcd in allowlist", model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://etcd-backup:2379"}}, true},
+		{"etcd in allowlist (listed with slash)", model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://etcd-dr:2379"}}, true},
+		{"etcd not allowed", model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://attacker:2379"}}, false},
+		{"etcd with one endpoint not allowed", model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://etcd:2379", "http://attacker:2379"}}, false},
+		{"bolt of server config", model.KvStoreConfig{Type: driver.TypeBolt, Path: filepath.Join(dir, "current.db")}, true},
+		{"bolt in allowlist", model.KvStoreConfig{Type: driver.TypeBolt, Path: filepath.Join(dir, "backup.db")}, true},
+		{"bolt in allowlist (unclean path)", model.KvStoreConfig{Type: driver.TypeBolt, Path: filepath.Join(dir, "sub", "..", "backup.db")}, true},
+		{"bolt not allowed", model.KvStoreConfig{Type: driver.TypeBolt, Path: filepath.Join(dir, "other.db")}, false},
+		{"bolt outside by traversal", model.KvStoreConfig{Type: driver.TypeBolt, Path: filepath.Join(dir, "..", "backup.db")}, false},
+		{"bolt system file", model.KvStoreConfig{Type: driver.TypeBolt, Path: "/etc/passwd"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkKvStoreAllowed(tt.config)
+			if tt.allowed && err != nil {
+				t.Errorf("expected allowed, got %v", err)
+			}
+			if !tt.allowed && !isValidationFailed(err) {
+				t.Errorf("expected validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrateKvStore(t *testing.T) {
+	dir := t.TempDir()
+	allowedPath := filepath.Join(dir, "allowed.db")
+	setKvStoreMigrateConfig(t, filepath.Join(dir, "current.db"), "http://127.0.0.1:2379")
+	t.Setenv("TB_KVSTORE_MIGRATE_ETCD_ENDPOINTS", "")
+	t.Setenv("TB_KVSTORE_MIGRATE_BOLT_PATHS", allowedPath)
+
+	for _, key := range []string{"/migrate-test/a", "/migrate-test/b"} {
+		if err := kvstore.Put(key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		req        model.KvStoreMigrateReq
+		wantCopied int64
+		wantErr    bool
+	}{
+		{"bolt target in allowlist", model.KvStoreMigrateReq{
+			Target:    model.KvStoreConfig{Type: driver.TypeBolt, Path: allowedPath},
+			KeyPrefix: "/migrate-test/",
+		}, 2, false},
+		{"bolt target not allowed", model.KvStoreMigrateReq{
+			Target:    model.KvStoreConfig{Type: driver.TypeBolt, Path: filepath.Join(dir, "other.db")},
+			KeyPrefix: "/migrate-test/",
+		}, 0, true},
+		{"bolt source not allowed", model.KvStoreMigrateReq{
+			Source: &model.KvStoreConfig{Type: driver.TypeBolt, Path: filepath.Join(dir, "other.db")},
+			Target: model.KvStoreConfig{Type: driver.TypeBolt, Path: allowedPath},
+		}, 0, true},
+		{"etcd target not allowed", model.KvStoreMigrateReq{
+			Target: model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://10.255.255.1:2379"}},
+		}, 0, true},
+		{"etcd source not allowed", model.KvStoreMigrateReq{
+			Source: &model.KvStoreConfig{Type: driver.TypeEtcd, Endpoints: []string{"http://10.255.255.1:2379"}},
+			Target: model.KvStoreConfig{Type: driver.TypeBolt, Path: allowedPath},
+		}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			result, err := MigrateKvStore(context.Background(), &req)
+			if tt.wantErr {
+				if !isValidationFailed(err) {
+					t.Fatalf("expected validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.CopiedKeys != tt.wantCopied {
+				t.Errorf("copied %d keys, want %d", result.CopiedKeys, tt.wantCopied)
+			}
+		})
+	}
+
+	// the copied keys are in the allowed target
+	target, err := bolt.NewBoltStore(context.Background(), bolt.Config{Path: allowedPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	value, err := target.Get("/migrate-test/a")
+	if err != nil || value != "value" {
+		t.Errorf("target value: %q, %v", value, err)
+	}
+}
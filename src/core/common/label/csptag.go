@@ -0,0 +1,292 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package label is to handle label selector for resources
+package label
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvutil"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// spiderTagResourceTypes maps the label types which can be propagated to CSP tags to CB-Spider resource types
+var spiderTagResourceTypes = map[string]string{
+	model.StrVNet:          "vpc",
+	model.StrSecurityGroup: "sg",
+	model.StrVM:            "vm",
+	model.StrDataDisk:      "disk",
+}
+
+// spiderTagAddRequest is the request body of CB-Spider's Add Tag API
+type spiderTagAddRequest struct {
+	ConnectionName string
+	ReqInfo        struct {
+		ResourceType string
+		ResourceName string
+		Tag          model.KeyValue
+	}
+}
+
+// spiderTagRemoveRequest is the request body of CB-Spider's Remove Tag API
+type spiderTagRemoveRequest struct {
+	ConnectionName string
+	ReqInfo        struct {
+		ResourceType string
+		ResourceName string
+	}
+}
+
+// cspTagTarget is the subset of resource object fields required to handle CSP tags
+type cspTagTarget struct {
+	ConnectionName  string `json:"connectionName"`
+	CspResourceName string `json:"cspResourceName"`
+}
+
+// IsTaggableLabelType checks whether labels of the labelType can be propagated to CSP tags
+func IsTaggableLabelType(labelType string) bool {
+	_, ok := spiderTagResourceTypes[labelType]
+	return ok
+}
+
+// getCspTagTarget reads the resource object of the label and returns the target for CSP tags
+func getCspTagTarget(resourceKey string) (cspTagTarget, error) {
+	target := cspTagTarget{}
+
+	resourceData, err := kvstore.Get(resourceKey)
+	if err != nil {
+		return target, err
+	}
+	if len(resourceData) == 0 {
+		return target, fmt.Errorf("resource object (%s) is not found", resourceKey)
+	}
+	err = json.Unmarshal([]byte(resourceData), &target)
+	if err != nil {
+		return target, err
+	}
+	if target.ConnectionName == "" || target.CspResourceName == "" {
+		return target, fmt.Errorf("resource object (%s) is not provisioned in CSP", resourceKey)
+	}
+	return target, nil
+}
+
+// callSpiderTagApi calls CB-Spider's tag API and returns an error if the request is failed
+func callSpiderTagApi(method string, url string, body interface{}) error {
+	client := resty.New()
+	client.SetTimeout(2 * time.Minute)
+
+	resp, err := client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Execute(method, url)
+	if err != nil {
+		return fmt.Errorf("an error occurred while requesting to CB-Spider: %w", err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("[Error from: %s] Status code: %s, Message: %s", url, resp.Status(), resp.Body())
+	}
+	return nil
+}
+
+// addCspTag adds a key/value as a tag of the CSP resource via CB-Spider
+func addCspTag(labelType string, target cspTagTarget, key string, value string) error {
+	reqBody := spiderTagAddRequest{ConnectionName: target.ConnectionName}
+	reqBody.ReqInfo.ResourceType = spiderTagResourceTypes[labelType]
+	reqBody.ReqInfo.ResourceName = target.CspResourceName
+	reqBody.ReqInfo.Tag = model.KeyValue{Key: key, Value: value}
+
+	return callSpiderTagApi(resty.MethodPost, model.SpiderRestUrl+"/tag", reqBody)
+}
+
+// removeCspTag removes a tag from the CSP resource via CB-Spider
+func removeCspTag(labelType string, target cspTagTarget, key string) error {
+	reqBody := spiderTagRemoveRequest{ConnectionName: target.ConnectionName}
+	reqBody.ReqInfo.ResourceType = spiderTagResourceTypes[labelType]
+	reqBody.ReqInfo.ResourceName = target.CspResourceName
+
+	return callSpiderTagApi(resty.MethodDelete, model.SpiderRestUrl+"/tag/"+key, reqBody)
+}
+
+// saveLabelInfo persists the label object in the Key-Value store
+func saveLabelInfo(labelKey string, labelInfo model.LabelInfo) error {
+	updatedLabelData, err := json.Marshal(labelInfo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal updated label info: %w", err)
+	}
+	err = kvstore.Put(labelKey, string(updatedLabelData))
+	if err != nil {
+		return fmt.Errorf("failed to put label info into kvstore: %w", err)
+	}
+	return nil
+}
+
+// syncLabelsToCsp pushes the given labels and pending removals to CSP tags and records the result on the label object.
+// A failure of the propagation is recorded on the label object (not returned as an error).
+func syncLabelsToCsp(labelType string, labelKey string, labelInfo *model.LabelInfo, labels map[string]string) error {
+	var syncErrs []string
+
+	target, err := getCspTagTarget(labelInfo.ResourceKey)
+	if err != nil {
+		syncErrs = append(syncErrs, err.Error())
+	} else {
+		remained := []string{}
+		for _, key := range labelInfo.PendingTagRemovals {
+			if err := removeCspTag(labelType, target, key); err != nil {
+				remained = append(remained, key)
+				syncErrs = append(syncErrs, fmt.Sprintf("remove %s: %s", key, err.Error()))
+			}
+		}
+		labelInfo.PendingTagRemovals = remained
+
+		for key, value := range labels {
+			if IsSystemLabelKey(key) {
+				continue
+			}
+			if err := addCspTag(labelType, target, key, value); err != nil {
+				syncErrs = append(syncErrs, fmt.Sprintf("add %s: %s", key, err.Error()))
+			}
+		}
+	}
+
+	if len(syncErrs) > 0 {
+		labelInfo.LastSyncStatus = model.LabelSyncFailed
+		labelInfo.LastSyncError = strings.Join(syncErrs, "; ")
+		log.Warn().Str("labelKey", labelKey).Msgf("Failed to propagate labels to CSP tags: %s", labelInfo.LastSyncError)
+	} else {
+		labelInfo.LastSyncStatus = model.LabelSyncSuccess
+		labelInfo.LastSyncError = ""
+	}
+
+	return saveLabelInfo(labelKey, *labelInfo)
+}
+
+// PropagateLabelsToCsp pushes the given labels of a resource to CSP tags.
+// System labels (sys.*) are not propagated. The result is recorded on the label object.
+func PropagateLabelsToCsp(labelType, uid string, labels map[string]string) (model.LabelInfo, error) {
+	if !IsTaggableLabelType(labelType) {
+		return model.LabelInfo{}, fmt.Errorf("labels of %s cannot be propagated to CSP tags", labelType)
+	}
+
+	labelKey := fmt.Sprintf("/label/%s/%s", labelType, uid)
+	labelInfo, err := GetLabels(labelType, uid)
+	if err != nil {
+		return labelInfo, err
+	}
+	if labelInfo.Labels == nil {
+		return labelInfo, fmt.Errorf("label object (%s) is not found", labelKey)
+	}
+
+	// Do not remove the tags which are added again
+	remained := []string{}
+	for _, key := range labelInfo.PendingTagRemovals {
+		if _, ok := labels[key]; !ok {
+			remained = append(remained, key)
+		}
+	}
+	labelInfo.PendingTagRemovals = remained
+
+	err = syncLabelsToCsp(labelType, labelKey, &labelInfo, labels)
+	return labelInfo, err
+}
+
+// RemoveLabelFromCsp removes the tag of the given label key from the CSP resource.
+// If it fails, the key is kept in the label object to be retried by ResyncLabelsToCsp.
+func RemoveLabelFromCsp(labelType, uid, key string) (model.LabelInfo, error) {
+	if !IsTaggableLabelType(labelType) {
+		return model.LabelInfo{}, fmt.Errorf("labels of %s cannot be propagated to CSP tags", labelType)
+	}
+
+	labelKey := fmt.Sprintf("/label/%s/%s", labelType, uid)
+	labelInfo, err := GetLabels(labelType, uid)
+	if err != nil {
+		return labelInfo, err
+	}
+	if labelInfo.Labels == nil {
+		return labelInfo, fmt.Errorf("label object (%s) is not found", labelKey)
+	}
+
+	pending := false
+	for _, k := range labelInfo.PendingTagRemovals {
+		if k == key {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		labelInfo.PendingTagRemovals = append(labelInfo.PendingTagRemovals, key)
+	}
+	err = syncLabelsToCsp(labelType, labelKey, &labelInfo, nil)
+	return labelInfo, err
+}
+
+// ResyncLabelsToCsp retries the propagation of labels for the label objects whose last sync was failed.
+// If labelType is empty or "all", all taggable label types are checked.
+func ResyncLabelsToCsp(labelType string) (model.LabelSyncResult, error) {
+	result := model.LabelSyncResult{Results: []model.LabelSyncResultItem{}}
+
+	labelTypes := []string{}
+	if labelType == "" || labelType == "all" {
+		for t := range spiderTagResourceTypes {
+			labelTypes = append(labelTypes, t)
+		}
+	} else if IsTaggableLabelType(labelType) {
+		labelTypes = append(labelTypes, labelType)
+	} else {
+		return result, fmt.Errorf("labels of %s cannot be propagated to CSP tags", labelType)
+	}
+
+	for _, t := range labelTypes {
+		listKey := fmt.Sprintf("/label/%s", t)
+		keyValue, err := kvstore.GetKvList(listKey)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		keyValue = kvutil.FilterKvListBy(keyValue, listKey, 1)
+
+		for _, kv := range keyValue {
+			var labelInfo model.LabelInfo
+			err = json.Unmarshal([]byte(kv.Value), &labelInfo)
+			if err != nil {
+				log.Error().Err(err).Str("labelKey", kv.Key).Msg("Failed to unmarshal label data")
+				continue
+			}
+			if labelInfo.LastSyncStatus != model.LabelSyncFailed {
+				continue
+			}
+
+			item := model.LabelSyncResultItem{
+				LabelType: t,
+				Uid:       strings.TrimPrefix(kv.Key, listKey+"/"),
+			}
+			err = syncLabelsToCsp(t, kv.Key, &labelInfo, labelInfo.Labels)
+			if err != nil {
+				item.Status = model.LabelSyncFailed
+				item.Error = err.Error()
+			} else {
+				item.Status = labelInfo.LastSyncStatus
+				item.Error = labelInfo.LastSyncError
+			}
+			result.Results = append(result.Results, item)
+		}
+	}
+
+	return result, nil
+}
@@ -17,7 +17,9 @@ package label
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
 	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
@@ -156,82 +158,283 @@ func GetLabels(labelType, uid string) (label model.LabelInfo, err error) {
 	return labelInfo, nil
 }
 
-// MatchesLabelSelector checks if the labels match the given label selector.
-func MatchesLabelSelector(labels map[string]string, labelSelector string) bool {
-	// Split the labelSelector into individual selectors
-	selectors := strings.Split(labelSelector, ",")
+// Label selector grammar
+//
+//	selector    := requirement { "," requirement }
+//	requirement := key ( "=" | "==" | "!=" ) value
+//	             | key ( ">" | ">=" | "<" | "<=" ) number
+//	             | key ( "in" | "notin" ) "(" value { "," value } ")"
+//	             | key ( "exists" | "!exists" )
+//	             | "prefix" keyPrefix
+//
+// Whitespace around keys, operators and values is ignored. Commas inside parentheses
+// belong to the value list. All requirements must be satisfied (logical AND).
+// A requirement starting with "prefix" is the prefix operator only if the next token is not an operator
+// (e.g., "prefix exists" checks the label whose key is "prefix").
+
+// selector operators
+const (
+	selectorOpEquals       = "="
+	selectorOpDoubleEquals = "=="
+	selectorOpNotEquals    = "!="
+	selectorOpGreaterThan  = ">"
+	selectorOpGreaterEqual = ">="
+	selectorOpLessThan     = "<"
+	selectorOpLessEqual    = "<="
+	selectorOpIn           = "in"
+	selectorOpNotIn        = "notin"
+	selectorOpExists       = "exists"
+	selectorOpNotExists    = "!exists"
+	selectorOpPrefix       = "prefix"
+)
 
-	for _, selector := range selectors {
-		selector = strings.TrimSpace(selector)
+// SelectorRequirement is a parsed requirement of a label selector
+type SelectorRequirement struct {
+	key      string
+	operator string
+	values   []string
+	number   float64
+}
 
-		switch {
-		case strings.Contains(selector, "!="):
-			parts := strings.SplitN(selector, "!=", 2)
-			key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
-			if val, ok := labels[key]; !ok || val == value {
-				return false
+// splitSelector splits a label selector by commas which are not inside parentheses
+func splitSelector(labelSelector string) ([]string, error) {
+	var parts []string
+	depth := 0
+	start := 0
+	for i, ch := range labelSelector {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("invalid label selector: unbalanced parenthesis at position %d", i)
 			}
-
-		case strings.Contains(selector, "="):
-			parts := strings.SplitN(selector, "=", 2)
-			key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
-			if val, ok := labels[key]; !ok || val != value {
-				return false
+		case ',':
+			if depth == 0 {
+				parts = append(parts, labelSelector[start:i])
+				start = i + 1
 			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid label selector: unbalanced parenthesis")
+	}
+	parts = append(parts, labelSelector[start:])
+	return parts, nil
+}
 
-		case strings.Contains(selector, " in "):
-			parts := strings.SplitN(selector, " in ", 2)
-			key := strings.TrimSpace(parts[0])
-			values := strings.Split(strings.Trim(parts[1], "()"), ",")
-			found := false
-			if val, ok := labels[key]; ok {
-				for _, v := range values {
-					if strings.TrimSpace(v) == val {
-						found = true
-						break
-					}
-				}
-			}
-			if !found {
-				return false
-			}
+// selectorSymbolicOps are the symbolic operators (longer operators first)
+var selectorSymbolicOps = []string{selectorOpNotExists, selectorOpNotEquals, selectorOpGreaterEqual, selectorOpLessEqual, selectorOpDoubleEquals, selectorOpEquals, selectorOpGreaterThan, selectorOpLessThan}
 
-		case strings.Contains(selector, " notin "):
-			parts := strings.SplitN(selector, " notin ", 2)
-			key := strings.TrimSpace(parts[0])
-			values := strings.Split(strings.Trim(parts[1], "()"), ",")
-			if val, ok := labels[key]; ok {
-				for _, v := range values {
-					if strings.TrimSpace(v) == val {
-						return false
-					}
-				}
-			}
+// selectorOperatorAt returns the operator token at the beginning of s (the text after the key)
+// and the text after the operator. ok is false if s does not start with an operator.
+func selectorOperatorAt(s string) (operator string, after string, ok bool) {
+	for _, op := range selectorSymbolicOps {
+		if strings.HasPrefix(s, op) {
+			return op, strings.TrimSpace(strings.TrimPrefix(s, op)), true
+		}
+	}
+	// a word operator ends at whitespace or the value list
+	wordEnd := strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '('
+	})
+	if wordEnd == -1 {
+		wordEnd = len(s)
+	}
+	switch word := s[:wordEnd]; word {
+	case selectorOpExists, selectorOpIn, selectorOpNotIn:
+		return word, strings.TrimSpace(s[wordEnd:]), true
+	}
+	return "", s, false
+}
 
-		case strings.HasSuffix(selector, " exists"):
-			key := strings.TrimSpace(strings.TrimSuffix(selector, " exists"))
-			if _, ok := labels[key]; !ok {
-				return false
+// parseSelectorRequirement parses a single requirement of a label selector.
+// The token after the key is the operator if it is one (so "prefix exists" is the exists operator of the key "prefix");
+// otherwise the requirement is the prefix operator if the first token is "prefix" (e.g., "prefix team/").
+func parseSelectorRequirement(requirement string) (SelectorRequirement, error) {
+	req := SelectorRequirement{}
+	requirement = strings.TrimSpace(requirement)
+	if requirement == "" {
+		return req, fmt.Errorf("invalid label selector: empty requirement")
+	}
+
+	// key is the leading run of characters until whitespace or an operator character
+	keyEnd := strings.IndexFunc(requirement, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("!=<>()", r)
+	})
+	if keyEnd == -1 {
+		return req, fmt.Errorf("invalid label selector (%s): missing operator", requirement)
+	}
+	req.key = requirement[:keyEnd]
+	rest := strings.TrimSpace(requirement[keyEnd:])
+	if req.key == "" {
+		return req, fmt.Errorf("invalid label selector (%s): missing key", requirement)
+	}
+
+	op, value, ok := selectorOperatorAt(rest)
+	if !ok {
+		// prefix operator: "prefix team/"
+		if req.key == selectorOpPrefix && rest != "" {
+			if strings.ContainsFunc(rest, unicode.IsSpace) || strings.ContainsAny(rest, "!=<>()") {
+				return req, fmt.Errorf("invalid label selector (%s): key prefix must not contain spaces or operator characters", requirement)
 			}
+			req.key = ""
+			req.operator = selectorOpPrefix
+			req.values = []string{rest}
+			return req, nil
+		}
+		if rest == "" {
+			return req, fmt.Errorf("invalid label selector (%s): missing operator", requirement)
+		}
+		word := strings.Fields(rest)[0]
+		if idx := strings.Index(word, "("); idx > 0 {
+			word = word[:idx]
+		}
+		return req, fmt.Errorf("invalid label selector (%s): unknown operator %q", requirement, word)
+	}
 
-		case strings.HasSuffix(selector, " !exists"):
-			key := strings.TrimSpace(strings.TrimSuffix(selector, " !exists"))
-			if _, ok := labels[key]; ok {
+	req.operator = op
+	switch op {
+	case selectorOpExists, selectorOpNotExists:
+		if value != "" {
+			return req, fmt.Errorf("invalid label selector (%s): unexpected value after %s", requirement, op)
+		}
+	case selectorOpDoubleEquals:
+		req.operator = selectorOpEquals
+		req.values = []string{value}
+	case selectorOpEquals, selectorOpNotEquals:
+		req.values = []string{value}
+	case selectorOpIn, selectorOpNotIn:
+		if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+			return req, fmt.Errorf("invalid label selector (%s): values of %s must be enclosed in parentheses", requirement, op)
+		}
+		for _, v := range strings.Split(value[1:len(value)-1], ",") {
+			req.values = append(req.values, strings.TrimSpace(v))
+		}
+	default:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return req, fmt.Errorf("invalid label selector (%s): value of operator %s must be numeric, got %q", requirement, op, value)
+		}
+		req.number = number
+		req.values = []string{value}
+	}
+	return req, nil
+}
+
+// ParseLabelSelector parses a label selector into requirements and returns an error if it is invalid
+func ParseLabelSelector(labelSelector string) ([]SelectorRequirement, error) {
+	parts, err := splitSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	requirements := make([]SelectorRequirement, 0, len(parts))
+	for _, part := range parts {
+		req, err := parseSelectorRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, req)
+	}
+	return requirements, nil
+}
+
+// matches checks if the labels satisfy the requirement.
+// A label value which is not numeric does not satisfy numeric operators.
+func (r SelectorRequirement) matches(labels map[string]string) bool {
+	val, ok := labels[r.key]
+
+	switch r.operator {
+	case selectorOpEquals:
+		return ok && val == r.values[0]
+	case selectorOpNotEquals:
+		return ok && val != r.values[0]
+	case selectorOpIn:
+		if !ok {
+			return false
+		}
+		for _, v := range r.values {
+			if v == val {
+				return true
+			}
+		}
+		return false
+	case selectorOpNotIn:
+		if !ok {
+			return true
+		}
+		for _, v := range r.values {
+			if v == val {
 				return false
 			}
-
-		default:
+		}
+		return true
+	case selectorOpExists:
+		return ok
+	case selectorOpNotExists:
+		return !ok
+	case selectorOpPrefix:
+		for key := range labels {
+			if strings.HasPrefix(key, r.values[0]) {
+				return true
+			}
+		}
+		return false
+	case selectorOpGreaterThan, selectorOpGreaterEqual, selectorOpLessThan, selectorOpLessEqual:
+		if !ok {
 			return false
 		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return false
+		}
+		switch r.operator {
+		case selectorOpGreaterThan:
+			return number > r.number
+		case selectorOpGreaterEqual:
+			return number >= r.number
+		case selectorOpLessThan:
+			return number < r.number
+		default:
+			return number <= r.number
+		}
 	}
+	return false
+}
 
+// matchesRequirements checks if the labels satisfy all requirements.
+func matchesRequirements(labels map[string]string, requirements []SelectorRequirement) bool {
+	for _, r := range requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
 	return true
 }
 
+// MatchesLabelSelector checks if the labels match the given label selector.
+// An invalid label selector does not match any labels.
+func MatchesLabelSelector(labels map[string]string, labelSelector string) bool {
+	requirements, err := ParseLabelSelector(labelSelector)
+	if err != nil {
+		log.Debug().Err(err).Msg("")
+		return false
+	}
+	return matchesRequirements(labels, requirements)
+}
+
 // GetResourcesByLabelSelector retrieves resources based on a label selector.
 func GetResourcesByLabelSelector(labelType, labelSelector string) ([]interface{}, error) {
 	var matchedResources []interface{}
 
+	// Parse the label selector first to report an invalid selector as an error
+	requirements, err := ParseLabelSelector(labelSelector)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
 	// Fetch all label entries for the resourceType
 	listKey := fmt.Sprintf("/label/%s", labelType)
 	keyValue, err := kvstore.GetKvList(listKey)
@@ -268,7 +471,7 @@ func GetResourcesByLabelSelector(labelType, labelSelector string) ([]interface{}
 			continue // Skip this entry and continue with the next one
 		}
 
-		if MatchesLabelSelector(labelInfo.Labels, labelSelector) {
+		if matchesRequirements(labelInfo.Labels, requirements) {
 			// Use the resource constructor to create a new resource instance
 			resource := resourceConstructor()
 
@@ -298,3 +501,116 @@ func GetResourcesByLabelSelector(labelType, labelSelector string) ([]interface{}
 	log.Info().Int("numMatchedResources", len(matchedResources)).Str("labelType", labelType).Msg("Matched resources found")
 	return matchedResources, nil
 }
+
+// IsSystemLabelKey checks whether the key is a system-managed label key (sys.*)
+func IsSystemLabelKey(key string) bool {
+	return strings.HasPrefix(key, "sys.")
+}
+
+// ApplyLabelsBySelector adds and removes labels of the resources matched by a label selector.
+// Each label object is updated with a single write, and the result is reported per resource.
+func ApplyLabelsBySelector(req *model.LabelBulkReq) (model.LabelBulkResult, error) {
+	result := model.LabelBulkResult{DryRun: req.DryRun, Results: []model.LabelBulkResultItem{}}
+
+	if len(req.AddLabels) == 0 && len(req.RemoveLabels) == 0 {
+		return result, fmt.Errorf("no labels to add or remove")
+	}
+	for key := range req.AddLabels {
+		if strings.TrimSpace(key) == "" {
+			return result, fmt.Errorf("label key must not be empty")
+		}
+		if IsSystemLabelKey(key) {
+			return result, fmt.Errorf("system-managed label key (%s) cannot be modified", key)
+		}
+	}
+	for _, key := range req.RemoveLabels {
+		if IsSystemLabelKey(key) {
+			return result, fmt.Errorf("system-managed label key (%s) cannot be modified", key)
+		}
+	}
+
+	requirements, err := ParseLabelSelector(req.LabelSelector)
+	if err != nil {
+		return result, err
+	}
+
+	listKey := "/label"
+	depth := 2
+	if req.LabelType != "" && req.LabelType != "all" {
+		if _, exists := model.ResourceTypeRegistry[req.LabelType]; !exists && req.LabelType != model.StrSubGroup {
+			return result, fmt.Errorf("unsupported label type: %s", req.LabelType)
+		}
+		listKey = fmt.Sprintf("/label/%s", req.LabelType)
+		depth = 1
+	}
+
+	keyValue, err := kvstore.GetKvList(listKey + "/")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	keyValue = kvutil.FilterKvListBy(keyValue, listKey, depth)
+
+	for _, kv := range keyValue {
+		var labelInfo model.LabelInfo
+		err := json.Unmarshal([]byte(kv.Value), &labelInfo)
+		if err != nil {
+			log.Error().Err(err).Str("labelKey", kv.Key).Msg("Failed to unmarshal label data")
+			continue
+		}
+		if !matchesRequirements(labelInfo.Labels, requirements) {
+			continue
+		}
+
+		segments := strings.Split(strings.TrimPrefix(kv.Key, "/label/"), "/")
+		item := model.LabelBulkResultItem{
+			LabelType:   segments[0],
+			Uid:         segments[len(segments)-1],
+			ResourceKey: labelInfo.ResourceKey,
+		}
+
+		// Check whether the labels would be changed
+		changed := false
+		for key, value := range req.AddLabels {
+			if v, ok := labelInfo.Labels[key]; !ok || v != value {
+				changed = true
+			}
+		}
+		for _, key := range req.RemoveLabels {
+			if _, ok := labelInfo.Labels[key]; ok {
+				changed = true
+			}
+		}
+
+		switch {
+		case !changed:
+			item.Status = "Unchanged"
+		case req.DryRun:
+			item.Status = "WouldModify"
+		default:
+			if labelInfo.Labels == nil {
+				labelInfo.Labels = map[string]string{}
+			}
+			for key, value := range req.AddLabels {
+				labelInfo.Labels[key] = value
+			}
+			for _, key := range req.RemoveLabels {
+				delete(labelInfo.Labels, key)
+			}
+			updatedLabelData, err := json.Marshal(labelInfo)
+			if err == nil {
+				err = kvstore.Put(kv.Key, string(updatedLabelData))
+			}
+			if err != nil {
+				item.Status = "Failed"
+				item.Error = err.Error()
+			} else {
+				item.Status = "Modified"
+			}
+		}
+		result.Results = append(result.Results, item)
+	}
+
+	log.Info().Int("numMatchedLabels", len(result.Results)).Bool("dryRun", req.DryRun).Msg("Applied labels by selector")
+	return result, nil
+}
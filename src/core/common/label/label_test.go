@@ -0,0 +1,97 @@
+package label
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []SelectorRequirement
+	}{
+		{"env=prod", []SelectorRequirement{{key: "env", operator: "=", values: []string{"prod"}}}},
+		{"env == prod", []SelectorRequirement{{key: "env", operator: "=", values: []string{"prod"}}}},
+		{"env!=dev", []SelectorRequirement{{key: "env", operator: "!=", values: []string{"dev"}}}},
+		{"cpu>=2", []SelectorRequirement{{key: "cpu", operator: ">=", values: []string{"2"}, number: 2}}},
+		{"cpu < 1.5", []SelectorRequirement{{key: "cpu", operator: "<", values: []string{"1.5"}, number: 1.5}}},
+		{"env in (prod, stage)", []SelectorRequirement{{key: "env", operator: "in", values: []string{"prod", "stage"}}}},
+		{"env notin(dev)", []SelectorRequirement{{key: "env", operator: "notin", values: []string{"dev"}}}},
+		{"env exists", []SelectorRequirement{{key: "env", operator: "exists"}}},
+		{"env!exists", []SelectorRequirement{{key: "env", operator: "!exists"}}},
+		{"prefix team/", []SelectorRequirement{{operator: "prefix", values: []string{"team/"}}}},
+		// a key named "prefix" followed by an operator is not the prefix operator
+		{"prefix exists", []SelectorRequirement{{key: "prefix", operator: "exists"}}},
+		{"prefix !exists", []SelectorRequirement{{key: "prefix", operator: "!exists"}}},
+		{"prefix=team", []SelectorRequirement{{key: "prefix", operator: "=", values: []string{"team"}}}},
+		{"prefix in (a,b)", []SelectorRequirement{{key: "prefix", operator: "in", values: []string{"a", "b"}}}},
+		// a key prefix which starts with an operator word is still a key prefix
+		{"prefix existsTeam/", []SelectorRequirement{{operator: "prefix", values: []string{"existsTeam/"}}}},
+		{"env in (a,b), prefix exists, cpu>1", []SelectorRequirement{
+			{key: "env", operator: "in", values: []string{"a", "b"}},
+			{key: "prefix", operator: "exists"},
+			{key: "cpu", operator: ">", values: []string{"1"}, number: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := ParseLabelSelector(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLabelSelectorInvalid(t *testing.T) {
+	for _, selector := range []string{
+		"",
+		"env",
+		"=prod",
+		"env exists prod",
+		"env in prod",
+		"env in (a,b",
+		"env unknown value",
+		"cpu > two",
+		"prefix",
+		"prefix team/ dev",
+		"prefix team=",
+	} {
+		t.Run(selector, func(t *testing.T) {
+			if _, err := ParseLabelSelector(selector); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestMatchesLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "prefix": "x", "team/owner": "alice", "cpu": "4"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"env=prod", true},
+		{"env in (dev, stage)", false},
+		{"prefix exists", true},
+		{"prefix team/", true},
+		{"prefix org/", false},
+		{"cpu>2, env!=dev", true},
+		{"missing !exists", true},
+		{"missing notin (a)", true},
+		{"invalid selector", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := MatchesLabelSelector(labels, tt.selector); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if MatchesLabelSelector(map[string]string{"env": "prod"}, "prefix exists") {
+		t.Errorf("the key prefix is matched without the label")
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// keyValueListField is the JSON field of the KeyValueList of objects
+const keyValueListField = "keyValueList"
+
+// ParseListOption parses the query params of a list request (limit, offset, nextToken, fields, excludeKeyValue)
+func ParseListOption(limit string, offset string, nextToken string, fields string, excludeKeyValue string) (model.ListOption, error) {
+	option := model.ListOption{NextToken: nextToken}
+
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			return model.ListOption{}, NewValidationFailedError("invalid limit (%s), it should be a non-negative integer", limit)
+		}
+		option.Limit = value
+	}
+	if offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			return model.ListOption{}, NewValidationFailedError("invalid offset (%s), it should be a non-negative integer", offset)
+		}
+		option.Offset = value
+	}
+	if nextToken != "" {
+		value, err := decodeListToken(nextToken)
+		if err != nil {
+			return model.ListOption{}, err
+		}
+		option.Offset = value
+	}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			option.Fields = append(option.Fields, field)
+		}
+	}
+	if excludeKeyValue != "" {
+		value, err := strconv.ParseBool(excludeKeyValue)
+		if err != nil {
+			return model.ListOption{}, NewValidationFailedError("invalid excludeKeyValue (%s), it should be true or false", excludeKeyValue)
+		}
+		option.ExcludeKeyValue = value
+	}
+	return option, nil
+}
+
+// encodeListToken returns the continue token for the offset
+func encodeListToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeListToken returns the offset of the continue token
+func decodeListToken(token string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, NewValidationFailedError("invalid nextToken (%s)", token)
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil || offset < 0 {
+		return 0, NewValidationFailedError("invalid nextToken (%s)", token)
+	}
+	return offset, nil
+}
+
+// GetListPageRange returns the range [start, end) of the page in a list of total items and the paging information
+func GetListPageRange(total int, option model.ListOption) (int, int, model.ListPage) {
+	page := model.ListPage{TotalCount: total}
+
+	start := option.Offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if option.Limit > 0 && start+option.Limit < total {
+		end = start + option.Limit
+		page.NextToken = encodeListToken(end)
+	}
+	return start, end, page
+}
+
+// PageList returns the page of the list (a slice) by the option and the paging information
+func PageList(list interface{}, option model.ListOption) (interface{}, model.ListPage) {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice {
+		return list, model.ListPage{}
+	}
+	start, end, page := GetListPageRange(value.Len(), option)
+	return value.Slice(start, end).Interface(), page
+}
+
+// NewListResponse returns the list response of the page with the paging information (key: JSON field of the list)
+func NewListResponse(key string, list interface{}, page model.ListPage) map[string]interface{} {
+	response := map[string]interface{}{key: list, "totalCount": page.TotalCount}
+	if page.NextToken != "" {
+		response["nextToken"] = page.NextToken
+	}
+	return response
+}
+
+// ShapeList returns the list with the selected fields only (and without KeyValueList if excluded).
+// The list is returned as it is if no field option is given.
+func ShapeList(list interface{}, option model.ListOption) (interface{}, error) {
+	if len(option.Fields) == 0 && !option.ExcludeKeyValue {
+		return list, nil
+	}
+
+	// convert the objects to generic JSON values to select the fields by the JSON paths
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	var shaped interface{}
+	if err := json.Unmarshal(data, &shaped); err != nil {
+		return nil, err
+	}
+
+	if option.ExcludeKeyValue {
+		shaped = removeJsonField(shaped, keyValueListField)
+	}
+	if len(option.Fields) > 0 {
+		paths := make([][]string, 0, len(option.Fields))
+		for _, field := range option.Fields {
+			paths = append(paths, strings.Split(field, "."))
+		}
+		shaped = selectJsonFields(shaped, paths)
+	}
+	return shaped, nil
+}
+
+// removeJsonField removes the field from all objects in the JSON value
+func removeJsonField(value interface{}, field string) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = removeJsonField(v[i], field)
+		}
+	case map[string]interface{}:
+		delete(v, field)
+		for key := range v {
+			v[key] = removeJsonField(v[key], field)
+		}
+	}
+	return value
+}
+
+// selectJsonFields keeps the fields of the paths only (paths are applied to each item of arrays)
+func selectJsonFields(value interface{}, paths [][]string) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = selectJsonFields(v[i], paths)
+		}
+		return v
+	case map[string]interface{}:
+		subPaths := map[string][][]string{}
+		wholeFields := map[string]bool{}
+		for _, path := range paths {
+			if len(path) == 1 {
+				wholeFields[path[0]] = true
+			} else {
+				subPaths[path[0]] = append(subPaths[path[0]], path[1:])
+			}
+		}
+		selected := map[string]interface{}{}
+		for key, fieldValue := range v {
+			if wholeFields[key] {
+				selected[key] = fieldValue
+			} else if sub, ok := subPaths[key]; ok {
+				selected[key] = selectJsonFields(fieldValue, sub)
+			}
+		}
+		return selected
+	default:
+		return value
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// lockKeyPrefix is the prefix of lock keys in the Key-Value store (/lock/ns/{nsId}/{section})
+const lockKeyPrefix = "/lock/"
+
+// LockInfo is the value of a lock key
+type LockInfo struct {
+	Name       string    `json:"name" example:"ns/default/sharedResource"`
+	Owner      string    `json:"owner" example:"tumblebug-0:1234:cnm8d2s0"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	// ExpiresAt is extended by the holder while it holds the lock (the lock expires if the holder crashes)
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// lockTtl returns the TTL of a lock which is not renewed (TB_LOCK_TTL_SEC, default 30)
+func lockTtl() time.Duration {
+	sec := envInt("TB_LOCK_TTL_SEC", 30)
+	if sec < 3 {
+		sec = 3
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// lockWaitTimeout returns how long to wait for a lock held by another (TB_LOCK_WAIT_SEC, default 600)
+func lockWaitTimeout() time.Duration {
+	return time.Duration(envInt("TB_LOCK_WAIT_SEC", 600)) * time.Second
+}
+
+// lockOwnerPrefix identifies this instance in the owner id of locks
+var lockOwnerPrefix = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// tryLock takes the lock if it is free or expired. It returns the mod revision of the lock key on success.
+// The value and the revision are read at once and the put is committed only if the key is not changed since the read.
+func tryLock(key string, info LockInfo) (int64, bool, error) {
+	kv, modRevision, err := kvstore.GetKvRevision(key)
+	if err != nil {
+		return 0, false, err
+	}
+	holder := LockInfo{}
+	if modRevision != 0 {
+		if json.Unmarshal([]byte(kv.Value), &holder) == nil && time.Now().Before(holder.ExpiresAt) {
+			return 0, false, nil
+		}
+	}
+
+	val, _ := json.Marshal(info)
+	ok, revision, err := kvstore.TxnRevision([]kvstore.TxnCompare{{Key: key, Revision: modRevision}}, []kvstore.KeyValue{{Key: key, Value: string(val)}}, nil)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if modRevision != 0 {
+		log.Warn().Msgf("Lock %s of %s was expired; taken over by %s", info.Name, holder.Owner, info.Owner)
+	}
+	return revision, true, nil
+}
+
+// Lock acquires the distributed lock of the name (e.g., ns/default/sharedResource) and returns the function to release it.
+// The lock is kept by renewing its expiry while it is held and expires after TB_LOCK_TTL_SEC if the holder crashes.
+// It waits up to TB_LOCK_WAIT_SEC while the lock is held by another.
+func Lock(name string) (func(), error) {
+	_, unlock, err := LockWithContext(context.Background(), name)
+	return unlock, err
+}
+
+// LockWithContext is Lock which also returns the context of the lock (derived from ctx).
+// The context is canceled when the lock is lost (not renewed before its expiry or taken over by another),
+// so the holder should stop its work on the cancellation.
+func LockWithContext(ctx context.Context, name string) (context.Context, func(), error) {
+	key := lockKeyPrefix + name
+	ttl := lockTtl()
+	info := LockInfo{Name: name, Owner: lockOwnerPrefix + ":" + GenUid()}
+
+	deadline := time.Now().Add(lockWaitTimeout())
+	wait := 100 * time.Millisecond
+	var modRevision int64
+	for {
+		info.AcquiredAt = time.Now()
+		info.ExpiresAt = info.AcquiredAt.Add(ttl)
+		revision, ok, err := tryLock(key, info)
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to acquire lock %s", name)
+			return nil, nil, err
+		}
+		if ok {
+			modRevision = revision
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, nil, fmt.Errorf("timeout while waiting for lock %s", name)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait < 2*time.Second {
+			wait *= 2
+		}
+	}
+	log.Debug().Msgf("Acquired lock %s (%s)", name, info.Owner)
+
+	// renew the expiry while the lock is held, and cancel the context of the lock if it is lost
+	lockCtx, cancel := context.WithCancel(ctx)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		expiresAt := info.ExpiresAt
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				renewal := info
+				renewal.ExpiresAt = time.Now().Add(ttl)
+				val, _ := json.Marshal(renewal)
+				ok, revision, err := kvstore.TxnRevision([]kvstore.TxnCompare{{Key: key, Revision: modRevision}}, []kvstore.KeyValue{{Key: key, Value: string(val)}}, nil)
+				if err == nil && !ok {
+					log.Error().Msgf("Lock %s (%s) was taken over by another; the holder is canceled", name, info.Owner)
+					cancel()
+					return
+				}
+				if err != nil {
+					if time.Now().After(expiresAt) {
+						log.Error().Err(err).Msgf("Lock %s (%s) was expired without renewal; the holder is canceled", name, info.Owner)
+						cancel()
+						return
+					}
+					log.Warn().Err(err).Msgf("Failed to renew lock %s (%s); retrying until %s", name, info.Owner, expiresAt.Format(time.RFC3339))
+					continue
+				}
+				modRevision = revision
+				expiresAt = renewal.ExpiresAt
+			}
+		}
+	}()
+
+	unlock := func() {
+		close(stop)
+		<-done
+		cancel()
+		// release only if the lock is still ours
+		ok, err := kvstore.Txn([]kvstore.TxnCompare{{Key: key, Revision: modRevision}}, nil, []string{key})
+		if err != nil || !ok {
+			log.Warn().Err(err).Msgf("Lock %s (%s) was not released (expired or taken over)", name, info.Owner)
+			return
+		}
+		log.Debug().Msgf("Released lock %s (%s)", name, info.Owner)
+	}
+	return lockCtx, unlock, nil
+}
+
+// LockNs acquires the distributed lock of the section (e.g., sharedResource, vNet/{vNetId}) in the namespace
+func LockNs(nsId string, section string) (func(), error) {
+	return Lock("ns/" + nsId + "/" + section)
+}
+
+// LockNsWithContext is LockNs which also returns the context canceled when the lock is lost (see LockWithContext)
+func LockNsWithContext(ctx context.Context, nsId string, section string) (context.Context, func(), error) {
+	return LockWithContext(ctx, "ns/"+nsId+"/"+section)
+}
+
+// ListNsLocks returns the locks held in the namespace (expired locks are included with their expiry)
+func ListNsLocks(nsId string) ([]LockInfo, error) {
+	locks := []LockInfo{}
+	keyValue, err := kvstore.GetKvList(lockKeyPrefix + "ns/" + nsId + "/")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return locks, err
+	}
+	for _, kv := range keyValue {
+		info := LockInfo{}
+		if err := json.Unmarshal([]byte(kv.Value), &info); err != nil {
+			continue
+		}
+		if info.Name == "" {
+			info.Name = strings.TrimPrefix(kv.Key, lockKeyPrefix)
+		}
+		locks = append(locks, info)
+	}
+	return locks, nil
+}
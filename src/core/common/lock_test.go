@@ -0,0 +1,151 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// TestTryLockConcurrentCreation checks that only one of the concurrent callers creates the lock key
+func TestTryLockConcurrentCreation(t *testing.T) {
+	const workers = 32
+	key := lockKeyPrefix + "test/creation"
+	var succeeded int32
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			info := LockInfo{Name: "test/creation", Owner: GenUid(), ExpiresAt: time.Now().Add(time.Minute)}
+			if _, ok, err := tryLock(key, info); err != nil {
+				t.Error(err)
+			} else if ok {
+				atomic.AddInt32(&succeeded, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d callers took the lock, want 1", succeeded)
+	}
+	kvstore.Delete(key)
+}
+
+// TestLockConcurrentCreation checks that only one of the concurrent callers holds the lock at a time
+// when they create the lock key at once
+func TestLockConcurrentCreation(t *testing.T) {
+	const workers = 4
+	var holders, maxHolders, acquired int32
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			unlock, err := Lock("test/concurrent")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				max := atomic.LoadInt32(&maxHolders)
+				if n <= max || atomic.CompareAndSwapInt32(&maxHolders, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			atomic.AddInt32(&acquired, 1)
+			unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if maxHolders != 1 {
+		t.Errorf("the lock was held by %d callers at once", maxHolders)
+	}
+	if acquired != workers {
+		t.Errorf("the lock was acquired %d times, want %d", acquired, workers)
+	}
+	if value, _ := kvstore.Get(lockKeyPrefix + "test/concurrent"); value != "" {
+		t.Errorf("the lock key is not released: %s", value)
+	}
+}
+
+func TestLockTakeOverExpired(t *testing.T) {
+	key := lockKeyPrefix + "test/expired"
+	expired, _ := json.Marshal(LockInfo{Name: "test/expired", Owner: "crashed", ExpiresAt: time.Now().Add(-time.Second)})
+	if err := kvstore.Put(key, string(expired)); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := Lock("test/expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := kvstore.Get(key)
+	holder := LockInfo{}
+	if err := json.Unmarshal([]byte(value), &holder); err != nil || holder.Owner == "crashed" {
+		t.Errorf("the expired lock was not taken over: %s", value)
+	}
+	unlock()
+}
+
+func TestLockRevisionOfHolder(t *testing.T) {
+	key := lockKeyPrefix + "test/revision"
+	revision, ok, err := tryLock(key, LockInfo{Name: "test/revision", Owner: "a", ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil || !ok {
+		t.Fatalf("tryLock: %v, %v", ok, err)
+	}
+	// a later write must not be reported as the revision of the holder
+	_, modRevision, err := kvstore.GetRevision(key)
+	if err != nil || modRevision != revision {
+		t.Fatalf("revision %d, want %d (%v)", revision, modRevision, err)
+	}
+	if _, ok, _ := tryLock(key, LockInfo{Name: "test/revision", Owner: "b", ExpiresAt: time.Now().Add(time.Minute)}); ok {
+		t.Errorf("the lock held by another was taken")
+	}
+	kvstore.Delete(key)
+}
+
+// TestLockContextCanceledWhenLost checks that the holder is notified when the lock is taken over
+func TestLockContextCanceledWhenLost(t *testing.T) {
+	t.Setenv("TB_LOCK_TTL_SEC", "3")
+	key := lockKeyPrefix + "test/lost"
+
+	ctx, unlock, err := LockWithContext(context.Background(), "test/lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := json.Marshal(LockInfo{Name: "test/lost", Owner: "other", ExpiresAt: time.Now().Add(time.Minute)})
+	if err := kvstore.Put(key, string(other)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context of the lost lock was not canceled")
+	}
+	// the lock of the other holder is kept by unlock
+	unlock()
+	if value, _ := kvstore.Get(key); value != string(other) {
+		t.Errorf("the lock of the other holder was changed: %s", value)
+	}
+	kvstore.Delete(key)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// spiderEndpoint returns the low-cardinality endpoint of a CB-Spider URL (e.g., http://localhost:1024/spider/vm/xxx -> /vm)
+// It returns an empty string if the URL is not for CB-Spider.
+func spiderEndpoint(rawUrl string) string {
+	if !strings.HasPrefix(rawUrl, model.SpiderRestUrl) {
+		return ""
+	}
+	path := strings.TrimPrefix(rawUrl, model.SpiderRestUrl)
+	if u, err := url.Parse(path); err == nil {
+		path = u.Path
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	return "/" + segments[0]
+}
+
+// countResources counts objects per namespace and type from the Key-Value store
+func countResources() (map[string]map[string]int, error) {
+	counts := map[string]map[string]int{}
+
+	nsIdList, err := ListNsId()
+	if err != nil {
+		return counts, err
+	}
+	for _, nsId := range nsIdList {
+		byType := map[string]int{
+			model.StrMCI:           0,
+			model.StrVM:            0,
+			model.StrVNet:          0,
+			model.StrSecurityGroup: 0,
+			model.StrSSHKey:        0,
+			model.StrDataDisk:      0,
+			model.StrObjectStorage: 0,
+			model.StrSqlDb:         0,
+			model.StrK8s:           0,
+		}
+
+		nsKey := "/ns/" + nsId
+		keyValue, err := kvstore.GetKvList(nsKey + "/")
+		if err != nil {
+			return counts, err
+		}
+		for _, kv := range keyValue {
+			segments := strings.Split(strings.TrimPrefix(kv.Key, nsKey+"/"), "/")
+			switch {
+			case segments[0] == "mci" && len(segments) == 2:
+				byType[model.StrMCI]++
+			case segments[0] == "mci" && len(segments) == 4 && segments[2] == "vm":
+				byType[model.StrVM]++
+			case segments[0] == "resources" && len(segments) == 3:
+				if _, ok := byType[segments[1]]; ok {
+					byType[segments[1]]++
+				}
+			case segments[0] == "k8scluster" && len(segments) == 2:
+				byType[model.StrK8s]++
+			}
+		}
+		counts[nsId] = byType
+	}
+	return counts, nil
+}
+
+// StartMetricsCollector registers gauges for in-flight operations and
+// refreshes the number of objects per namespace periodically in background
+func StartMetricsCollector(interval time.Duration) {
+	metrics.RegisterGaugeFunc("tumblebug_inflight_operations",
+		"Number of long-running operations in progress (e.g., MCI provisioning, async jobs)",
+		func() float64 { return float64(InFlightOperationCount()) })
+
+	refresh := func() {
+		counts, err := countResources()
+		if err != nil {
+			log.Debug().Err(err).Msg("Failed to refresh resource metrics")
+			return
+		}
+		metrics.SetResourceCounts(counts)
+	}
+
+	go func() {
+		refresh()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			refresh()
+		}
+	}()
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics is to collect statistics of CB-Tumblebug and expose them in Prometheus text format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// defaultBuckets are the upper bounds (seconds) of the latency histogram
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// sample is a value of a metric with a set of label values
+type sample struct {
+	labelValues []string
+	value       float64
+	// for histogram
+	bucketCounts []uint64
+	count        uint64
+}
+
+// metricVec is a metric family (counter, gauge or histogram) with labels
+type metricVec struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	buckets    []float64
+
+	mu      sync.Mutex
+	samples map[string]*sample
+}
+
+func newMetricVec(name, help, metricType string, labelNames ...string) *metricVec {
+	return &metricVec{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		samples:    map[string]*sample{},
+	}
+}
+
+// get returns the sample of the label values (mu must be held)
+func (m *metricVec) get(labelValues []string) *sample {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.samples[key]
+	if !ok {
+		s = &sample{labelValues: labelValues}
+		if m.metricType == "histogram" {
+			s.bucketCounts = make([]uint64, len(m.buckets))
+		}
+		m.samples[key] = s
+	}
+	return s
+}
+
+func (m *metricVec) add(delta float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value += delta
+}
+
+func (m *metricVec) set(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value = value
+}
+
+func (m *metricVec) observe(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.get(labelValues)
+	for i, upperBound := range m.buckets {
+		if value <= upperBound {
+			s.bucketCounts[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+func (m *metricVec) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = map[string]*sample{}
+}
+
+// formatLabels returns the label set in Prometheus format (e.g., {method="GET",status="200"})
+func formatLabels(names []string, values []string, extraName string, extraValue string) string {
+	pairs := []string{}
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, strconv.Quote(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", extraName, strconv.Quote(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (m *metricVec) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.metricType)
+
+	keys := make([]string, 0, len(m.samples))
+	for k := range m.samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := m.samples[k]
+		if m.metricType != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, formatLabels(m.labelNames, s.labelValues, "", ""), formatFloat(s.value))
+			continue
+		}
+		for i, upperBound := range m.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(m.labelNames, s.labelValues, "le", formatFloat(upperBound)), s.bucketCounts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(m.labelNames, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, formatLabels(m.labelNames, s.labelValues, "", ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, formatLabels(m.labelNames, s.labelValues, "", ""), s.count)
+	}
+}
+
+// gaugeFunc is a gauge whose value is computed when metrics are written
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+var (
+	httpRequestsTotal = newMetricVec("tumblebug_http_requests_total",
+		"Number of HTTP requests handled by CB-Tumblebug API", "counter", "method", "route", "status")
+	httpRequestDuration = func() *metricVec {
+		m := newMetricVec("tumblebug_http_request_duration_seconds",
+			"Latency of HTTP requests handled by CB-Tumblebug API", "histogram", "method", "route")
+		m.buckets = defaultBuckets
+		return m
+	}()
+	spiderCallErrorsTotal = newMetricVec("tumblebug_spider_call_errors_total",
+		"Number of failed calls to CB-Spider", "counter", "method", "endpoint")
+	spiderCallsTotal = newMetricVec("tumblebug_spider_calls_total",
+		"Number of calls to CB-Spider by result (success, error, unavailable)", "counter", "method", "endpoint", "result")
+	spiderCallDuration = func() *metricVec {
+		m := newMetricVec("tumblebug_spider_call_duration_seconds",
+			"Latency of calls to CB-Spider (including retries)", "histogram", "method", "endpoint")
+		m.buckets = defaultBuckets
+		return m
+	}()
+	spiderThrottleTotal = newMetricVec("tumblebug_spider_throttle_total",
+		"Number of calls to CB-Spider checked by the rate limit of the provider by result (passed, waited, timeout)", "counter", "provider", "result")
+	spiderThrottleWait = func() *metricVec {
+		m := newMetricVec("tumblebug_spider_throttle_wait_seconds",
+			"Time waited for the rate limit of the provider before calls to CB-Spider", "histogram", "provider")
+		m.buckets = defaultBuckets
+		return m
+	}()
+	lookupCacheTotal = newMetricVec("tumblebug_lookup_cache_requests_total",
+		"Number of lookups of the Spider spec/image cache by result (hit, miss)", "counter", "kind", "result")
+	upstreamCircuitOpen = newMetricVec("tumblebug_upstream_circuit_open",
+		"Whether the circuit breaker of the upstream endpoint is open (1) or closed (0)", "gauge", "upstream")
+	resourceCount = newMetricVec("tumblebug_resources",
+		"Number of objects per namespace and type (refreshed periodically)", "gauge", "namespace", "type")
+
+	gaugeFuncsLock sync.Mutex
+	gaugeFuncs     []gaugeFunc
+)
+
+// ObserveHttpRequest records an HTTP request. route should be the registered route path (e.g., /tumblebug/ns/:nsId/mci)
+// rather than the actual URL to keep the cardinality low.
+func ObserveHttpRequest(method string, route string, status int, duration time.Duration) {
+	httpRequestsTotal.add(1, method, route, strconv.Itoa(status))
+	httpRequestDuration.observe(duration.Seconds(), method, route)
+}
+
+// IncSpiderCallError records a failed call to CB-Spider
+func IncSpiderCallError(method string, endpoint string) {
+	spiderCallErrorsTotal.add(1, method, endpoint)
+}
+
+// ObserveSpiderCall records a call to CB-Spider with its result (success, error or unavailable)
+func ObserveSpiderCall(method string, endpoint string, result string, duration time.Duration) {
+	spiderCallsTotal.add(1, method, endpoint, result)
+	spiderCallDuration.observe(duration.Seconds(), method, endpoint)
+}
+
+// ObserveSpiderThrottle records a check of the rate limit of the provider with its result (passed, waited or timeout)
+// and the time waited for it
+func ObserveSpiderThrottle(provider string, result string, wait time.Duration) {
+	spiderThrottleTotal.add(1, provider, result)
+	if result == "waited" {
+		spiderThrottleWait.observe(wait.Seconds(), provider)
+	}
+}
+
+// SetUpstreamCircuitOpen records the state of the circuit breaker of the upstream endpoint
+func SetUpstreamCircuitOpen(upstream string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	upstreamCircuitOpen.set(value, upstream)
+}
+
+// IncLookupCache records a hit or miss of the Spider lookup cache (kind: spec, image)
+func IncLookupCache(kind string, result string) {
+	lookupCacheTotal.add(1, kind, result)
+}
+
+// SetResourceCounts replaces the number of objects per namespace and type
+func SetResourceCounts(counts map[string]map[string]int) {
+	resourceCount.reset()
+	for nsId, byType := range counts {
+		for resourceType, count := range byType {
+			resourceCount.set(float64(count), nsId, resourceType)
+		}
+	}
+}
+
+// RegisterGaugeFunc registers a gauge whose value is computed by fn at each scrape (fn should be cheap)
+func RegisterGaugeFunc(name string, help string, fn func() float64) {
+	gaugeFuncsLock.Lock()
+	defer gaugeFuncsLock.Unlock()
+	gaugeFuncs = append(gaugeFuncs, gaugeFunc{name: name, help: help, fn: fn})
+}
+
+// Write writes all metrics in Prometheus text exposition format
+func Write(w io.Writer) {
+	httpRequestsTotal.write(w)
+	httpRequestDuration.write(w)
+	spiderCallErrorsTotal.write(w)
+	spiderCallsTotal.write(w)
+	spiderCallDuration.write(w)
+	spiderThrottleTotal.write(w)
+	spiderThrottleWait.write(w)
+	upstreamCircuitOpen.write(w)
+	lookupCacheTotal.write(w)
+	resourceCount.write(w)
+
+	gaugeFuncsLock.Lock()
+	defer gaugeFuncsLock.Unlock()
+	for _, g := range gaugeFuncs {
+		fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+		fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+	}
+}
@@ -43,6 +43,11 @@ func NsValidation() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusNotFound, "The first character of name must be a lowercase letter, and all following characters must be a dash, lowercase letter, or digit, except the last character, which cannot be a dash.")
 			}
 
+			// Reject requests by a namespace-restricted API key for other namespaces
+			if !IsNsAccessible(c, nsId) {
+				return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("The API key is restricted to the namespace (%s)", c.Get(ContextKeyApiKeyNsId)))
+			}
+
 			check, err := CheckNs(nsId)
 
 			if !check || err != nil {
@@ -53,6 +58,12 @@ func NsValidation() echo.MiddlewareFunc {
 	}
 }
 
+// IsNsAccessible returns false if the request is made by a namespace-restricted API key for another namespace
+func IsNsAccessible(c echo.Context, nsId string) bool {
+	restrictedNsId, ok := c.Get(ContextKeyApiKeyNsId).(string)
+	return !ok || restrictedNsId == "" || restrictedNsId == nsId
+}
+
 func CreateNs(u *model.NsReq) (model.NsInfo, error) {
 	err := CheckString(u.Name)
 	if err != nil {
@@ -79,6 +90,8 @@ func CreateNs(u *model.NsReq) (model.NsInfo, error) {
 	content.Id = u.Name
 	content.Name = u.Name
 	content.Description = u.Description
+	content.AllowedConnections = u.AllowedConnections
+	content.AllowedProviders = normalizeAllowedProviders(u.AllowedProviders)
 
 	key := "/ns/" + content.Id
 	Val, _ := json.Marshal(content)
@@ -98,6 +111,7 @@ func CreateNs(u *model.NsReq) (model.NsInfo, error) {
 		model.LabelUid:         content.Uid,
 		model.LabelDescription: content.Description,
 	}
+	SetSystemLabels(labels, "")
 	err = label.CreateOrUpdateLabel(model.StrNamespace, content.Uid, key, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -147,6 +161,8 @@ func UpdateNs(id string, u *model.NsReq) (model.NsInfo, error) {
 	res.Id = id
 	res.Name = u.Name
 	res.Description = u.Description
+	res.AllowedConnections = u.AllowedConnections
+	res.AllowedProviders = normalizeAllowedProviders(u.AllowedProviders)
 
 	Key := "/ns/" + id
 	//mapA := map[string]string{"name": content.Name, "description": content.Description}
@@ -303,6 +319,11 @@ func ListNsId() ([]string, error) {
 
 }
 
+// GenNsResourceDefaultsKey is func to generate the key of the shared resource defaults of a namespace
+func GenNsResourceDefaultsKey(nsId string) string {
+	return "/ns/" + nsId + "/resourceDefaults"
+}
+
 func DelNs(id string) error {
 
 	err := CheckString(id)
@@ -330,6 +351,7 @@ func DelNs(id string) error {
 	specList := GetChildIdList(key + "/resources/spec")
 	sshKeyList := GetChildIdList(key + "/resources/sshKey")
 	//vNicList := GetChildIdList(key + "/resources/vNic")
+	trashList := GetChildIdList("/trash/" + id)
 
 	if len(mciList)+
 		len(imageList)+
@@ -337,7 +359,8 @@ func DelNs(id string) error {
 		//len(subnetList)
 		len(securityGroupList)+
 		len(specList)+
-		len(sshKeyList) > 0 {
+		len(sshKeyList)+
+		len(trashList) > 0 {
 		errString := "Cannot delete NS " + id + ", which is not empty. There exists at least one MCI or one of resources."
 		errString += " \n len(mciList): " + strconv.Itoa(len(mciList))
 		errString += " \n len(imageList): " + strconv.Itoa(len(imageList))
@@ -346,6 +369,7 @@ func DelNs(id string) error {
 		errString += " \n len(securityGroupList): " + strconv.Itoa(len(securityGroupList))
 		errString += " \n len(specList): " + strconv.Itoa(len(specList))
 		errString += " \n len(sshKeyList): " + strconv.Itoa(len(sshKeyList))
+		errString += " \n len(trashList): " + strconv.Itoa(len(trashList))
 		//errString += " \n len(subnetList): " + strconv.Itoa(len(subnetList))
 		//errString += " \n len(vNicList): " + strconv.Itoa(len(vNicList))
 
@@ -361,6 +385,30 @@ func DelNs(id string) error {
 		return err
 	}
 
+	// delete ns quota
+	err = kvstore.Delete(GenNsQuotaKey(id))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	// delete ns resource defaults
+	err = kvstore.Delete(GenNsResourceDefaultsKey(id))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	// delete ns naming policy
+	err = kvstore.Delete(GenNsNamingPolicyKey(id))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	// delete ns events
+	err = DelNsEvents(id)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
 	err = label.DeleteLabelObject(model.StrNamespace, ns.Uid)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -410,3 +458,51 @@ func CheckNs(id string) (bool, error) {
 	}
 	return false, nil
 }
+
+// normalizeAllowedProviders returns the provider names of the allowlist in lowercase
+func normalizeAllowedProviders(providers []string) []string {
+	if len(providers) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		result = AppendIfMissing(result, strings.ToLower(provider))
+	}
+	return result
+}
+
+// CheckNsConnectionAllowed returns ApiError (Forbidden) if the connection is not allowed by the allowlist of the namespace.
+// A connection is allowed if the allowlist is empty, the connection is listed, or its provider is listed
+// (so a provider-level allow covers any connection resolved for the provider, e.g., by the region representative).
+func CheckNsConnectionAllowed(nsId string, connectionName string) error {
+	ns, err := GetNs(nsId)
+	if err != nil {
+		return err
+	}
+	if len(ns.AllowedConnections) == 0 && len(ns.AllowedProviders) == 0 {
+		return nil
+	}
+	for _, allowed := range ns.AllowedConnections {
+		if allowed == connectionName {
+			return nil
+		}
+	}
+	providerName := ""
+	if connConfig, err := GetConnConfig(connectionName); err == nil {
+		providerName = strings.ToLower(connConfig.ProviderName)
+		for _, allowed := range ns.AllowedProviders {
+			if allowed == providerName {
+				return nil
+			}
+		}
+	}
+
+	err = &ApiError{
+		Code: model.ErrCodeForbidden,
+		Message: fmt.Sprintf("The connection %s (provider: %s) is not allowed in the namespace %s (allowedConnections: [%s], allowedProviders: [%s])",
+			connectionName, providerName, nsId, strings.Join(ns.AllowedConnections, ", "), strings.Join(ns.AllowedProviders, ", ")),
+		Details: map[string]interface{}{"connectionName": connectionName, "allowedConnections": ns.AllowedConnections, "allowedProviders": ns.AllowedProviders},
+	}
+	log.Warn().Msg(err.Error())
+	return err
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// GenNsNamingPolicyKey is func to generate the key of the naming policy of a namespace
+func GenNsNamingPolicyKey(nsId string) string {
+	return "/ns/" + nsId + "/namingPolicy"
+}
+
+// GetNsNamingPolicy returns the naming policy of the namespace (nothing enforced if the policy is not set)
+func GetNsNamingPolicy(nsId string) (model.NsNamingPolicy, error) {
+	policy := model.NsNamingPolicy{}
+
+	err := CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return policy, err
+	}
+
+	keyValue, err := kvstore.GetKv(GenNsNamingPolicyKey(nsId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return policy, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return policy, nil
+	}
+
+	err = json.Unmarshal([]byte(keyValue.Value), &policy)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return policy, err
+	}
+	return policy, nil
+}
+
+// UpdateNsNamingPolicy sets the naming policy of the namespace (existing objects are not checked again)
+func UpdateNsNamingPolicy(nsId string, policy *model.NsNamingPolicy) (model.NsNamingPolicy, error) {
+	_, err := GetNs(nsId)
+	if err != nil {
+		return model.NsNamingPolicy{}, err
+	}
+
+	if policy.MaxLength < 0 {
+		return model.NsNamingPolicy{}, NewValidationFailedError("maxLength cannot be negative (0 means no limit)")
+	}
+	if policy.MaxLength > 0 && len(policy.RequiredPrefix) > policy.MaxLength {
+		return model.NsNamingPolicy{}, NewValidationFailedError("requiredPrefix (%s) is longer than maxLength (%d)", policy.RequiredPrefix, policy.MaxLength)
+	}
+	for i, pattern := range policy.AllowPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return model.NsNamingPolicy{}, NewValidationFailedError("allowPatterns[%d] (%s) is not a valid regular expression: %v", i, pattern, err)
+		}
+	}
+	for i, pattern := range policy.DenyPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return model.NsNamingPolicy{}, NewValidationFailedError("denyPatterns[%d] (%s) is not a valid regular expression: %v", i, pattern, err)
+		}
+	}
+
+	val, err := json.Marshal(policy)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NsNamingPolicy{}, err
+	}
+	err = kvstore.Put(GenNsNamingPolicyKey(nsId), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NsNamingPolicy{}, err
+	}
+	return *policy, nil
+}
+
+// checkNamingPolicy returns the violated element of the policy and the reason (empty if the name is valid)
+func checkNamingPolicy(policy model.NsNamingPolicy, name string) (string, string) {
+	if policy.RequiredPrefix != "" && !strings.HasPrefix(name, policy.RequiredPrefix) {
+		return "requiredPrefix", fmt.Sprintf("the name must start with %q", policy.RequiredPrefix)
+	}
+	if policy.MaxLength > 0 && len(name) > policy.MaxLength {
+		return "maxLength", fmt.Sprintf("the name is %d characters long (max: %d)", len(name), policy.MaxLength)
+	}
+	if len(policy.AllowPatterns) > 0 {
+		matched := false
+		for _, pattern := range policy.AllowPatterns {
+			re, err := regexp.Compile(pattern)
+			if err == nil && re.MatchString(name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "allowPatterns", fmt.Sprintf("the name matches none of [%s]", strings.Join(policy.AllowPatterns, ", "))
+		}
+	}
+	for i, pattern := range policy.DenyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err == nil && re.MatchString(name) {
+			return fmt.Sprintf("denyPatterns[%d]", i), fmt.Sprintf("the name matches the denied pattern %q", pattern)
+		}
+	}
+	return "", ""
+}
+
+// CheckNsNamingPolicy returns ValidationFailed (400) if the name of a new object violates the naming policy of the namespace.
+// Shared resources and registered objects are not checked since their names are not given by the user.
+func CheckNsNamingPolicy(ctx context.Context, nsId string, resourceType string, name string) error {
+	via := CreatedViaFromContext(ctx)
+	if via == model.CreatedViaSharedResource || via == model.CreatedViaRegistered {
+		return nil
+	}
+
+	policy, err := GetNsNamingPolicy(nsId)
+	if err != nil {
+		return err
+	}
+	violation, reason := checkNamingPolicy(policy, name)
+	if violation == "" {
+		return nil
+	}
+
+	err = &ApiError{
+		Code:    model.ErrCodeValidationFailed,
+		Message: fmt.Sprintf("The %s name %s violates the naming policy of the namespace %s (%s): %s", resourceType, name, nsId, violation, reason),
+		Details: map[string]string{"resourceType": resourceType, "name": name, "violation": violation},
+	}
+	log.Warn().Msg(err.Error())
+	return err
+}
+
+// ValidateNsNames checks the names against the naming policy of the namespace without creating anything
+func ValidateNsNames(nsId string, req *model.NsNamingValidateReq) (model.NsNamingValidateResult, error) {
+	result := model.NsNamingValidateResult{NsId: nsId, Results: []model.NsNamingValidation{}}
+
+	_, err := GetNs(nsId)
+	if err != nil {
+		return result, err
+	}
+	if len(req.Names) == 0 {
+		return result, NewValidationFailedError("names are required")
+	}
+	policy, err := GetNsNamingPolicy(nsId)
+	if err != nil {
+		return result, err
+	}
+	result.Policy = policy
+
+	for _, name := range req.Names {
+		validation := model.NsNamingValidation{Name: name, Valid: true}
+		if err := CheckString(name); err != nil {
+			validation.Valid = false
+			validation.Violation = "idFormat"
+			validation.Message = err.Error()
+		} else if violation, reason := checkNamingPolicy(policy, name); violation != "" {
+			validation.Valid = false
+			validation.Violation = violation
+			validation.Message = reason
+		}
+		result.Results = append(result.Results, validation)
+	}
+	return result, nil
+}
@@ -403,7 +403,15 @@ func ValidateNetwork(network Network) error {
 	}
 
 	// Recursively validate each subnet
+	family, _ := CidrFamily(network.CidrBlock)
 	for _, subnet := range network.Subnets {
+		subnetFamily, err := CidrFamily(subnet.CidrBlock)
+		if err != nil {
+			return err
+		}
+		if subnetFamily != family {
+			return fmt.Errorf("subnet '%s' (IPv%d) is not in the address family of '%s' (IPv%d)", subnet.CidrBlock, subnetFamily, network.CidrBlock, family)
+		}
 		if !isSubnetOf(network.CidrBlock, subnet.CidrBlock) {
 			return fmt.Errorf("subnet '%s' is not a valid subnet of '%s'", subnet.CidrBlock, network.CidrBlock)
 		}
@@ -418,7 +426,9 @@ func ValidateNetwork(network Network) error {
 func isSubnetOf(parentCIDR, childCIDR string) bool {
 	_, parentNet, _ := net.ParseCIDR(parentCIDR)
 	_, childNet, _ := net.ParseCIDR(childCIDR)
-	return parentNet.Contains(childNet.IP)
+	parentPrefix, _ := parentNet.Mask.Size()
+	childPrefix, _ := childNet.Mask.Size()
+	return parentNet.Contains(childNet.IP) && childPrefix >= parentPrefix
 }
 
 // hasOverlappingSubnets checks if there are overlapping subnets within the same network.
@@ -440,6 +450,65 @@ func cidrOverlap(cidr1, cidr2 string) bool {
 	return net1.Contains(net2.IP) || net2.Contains(net1.IP)
 }
 
+// CidrOverlap checks if two CIDR blocks overlap (identical or nested blocks overlap, adjacent blocks do not).
+func CidrOverlap(cidr1, cidr2 string) (bool, error) {
+	if _, _, err := net.ParseCIDR(cidr1); err != nil {
+		return false, fmt.Errorf("invalid CIDR block '%s': %w", cidr1, err)
+	}
+	if _, _, err := net.ParseCIDR(cidr2); err != nil {
+		return false, fmt.Errorf("invalid CIDR block '%s': %w", cidr2, err)
+	}
+	return cidrOverlap(cidr1, cidr2), nil
+}
+
+// CidrFamily returns the address family (4 or 6) of the CIDR block
+func CidrFamily(cidrBlock string) (int, error) {
+	ip, _, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CIDR block '%s': %w", cidrBlock, err)
+	}
+	if ip.To4() != nil {
+		return 4, nil
+	}
+	return 6, nil
+}
+
+// Prefix lengths of IPv6 networks supported by the CSPs (e.g., AWS /44 to /60 for a VPC, /64 for a subnet)
+const (
+	MinIPv6NetworkPrefix = 44
+	MaxIPv6NetworkPrefix = 60
+	IPv6SubnetPrefix     = 64
+)
+
+// ValidateIPv6Network validates the IPv6 CIDR block of a network and its subnets
+// (prefix lengths supported by the CSPs, subnets in the network and not overlapping each other).
+func ValidateIPv6Network(network Network) error {
+	family, err := CidrFamily(network.CidrBlock)
+	if err != nil {
+		return err
+	}
+	if family != 6 {
+		return fmt.Errorf("'%s' is not an IPv6 CIDR block", network.CidrBlock)
+	}
+	prefix, _ := GetPrefix(network.CidrBlock)
+	if prefix < MinIPv6NetworkPrefix || prefix > MaxIPv6NetworkPrefix {
+		return fmt.Errorf("the prefix of the IPv6 CIDR block '%s' must be /%d to /%d", network.CidrBlock, MinIPv6NetworkPrefix, MaxIPv6NetworkPrefix)
+	}
+	for _, subnet := range network.Subnets {
+		subnetFamily, err := CidrFamily(subnet.CidrBlock)
+		if err != nil {
+			return err
+		}
+		if subnetFamily != 6 {
+			return fmt.Errorf("'%s' is not an IPv6 CIDR block", subnet.CidrBlock)
+		}
+		if subnetPrefix, _ := GetPrefix(subnet.CidrBlock); subnetPrefix != IPv6SubnetPrefix {
+			return fmt.Errorf("the IPv6 CIDR block of a subnet '%s' must be /%d", subnet.CidrBlock, IPv6SubnetPrefix)
+		}
+	}
+	return ValidateNetwork(network)
+}
+
 // ///////////////////////////////////////////////////////////////////////////////////
 // NextSubnet find and check the next subnet based on the base/parent network.
 func NextSubnet(currentSubnetCIDR string, baseNetworkCIDR string) (string, error) {
@@ -0,0 +1,50 @@
+package netutil
+
+import "testing"
+
+func TestCidrOverlap(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidr1   string
+		cidr2   string
+		overlap bool
+	}{
+		{"identical", "10.0.0.0/16", "10.0.0.0/16", true},
+		{"nested", "10.0.0.0/16", "10.0.128.0/24", true},
+		{"nested (reversed)", "10.0.128.0/24", "10.0.0.0/16", true},
+		{"nested at the end", "10.0.0.0/16", "10.0.255.0/24", true},
+		{"nested host", "192.168.0.0/24", "192.168.0.255/32", true},
+		{"identical with host bits", "10.0.1.7/24", "10.0.1.0/24", true},
+		{"adjacent", "10.0.0.0/24", "10.0.1.0/24", false},
+		{"adjacent (reversed)", "10.0.1.0/24", "10.0.0.0/24", false},
+		{"adjacent with different sizes", "10.0.0.0/16", "10.1.0.0/24", false},
+		{"adjacent hosts", "192.168.0.0/32", "192.168.0.1/32", false},
+		{"disjoint", "10.0.0.0/16", "172.16.0.0/16", false},
+		{"all addresses", "0.0.0.0/0", "172.16.0.0/16", true},
+		{"ipv6 nested", "2001:db8::/32", "2001:db8:1::/48", true},
+		{"ipv6 adjacent", "2001:db8::/48", "2001:db8:1::/48", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlap, err := CidrOverlap(tt.cidr1, tt.cidr2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if overlap != tt.overlap {
+				t.Errorf("CidrOverlap(%s, %s) = %v, want %v", tt.cidr1, tt.cidr2, overlap, tt.overlap)
+			}
+		})
+	}
+}
+
+func TestCidrOverlapInvalid(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"10.0.0.0", "10.0.0.0/16"},
+		{"10.0.0.0/16", "10.0.0.0/33"},
+		{"10.0.0.0/16", ""},
+	} {
+		if _, err := CidrOverlap(pair[0], pair[1]); err == nil {
+			t.Errorf("CidrOverlap(%q, %q): expected an error", pair[0], pair[1])
+		}
+	}
+}
@@ -0,0 +1,609 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/label"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// nsExportVersion is the version of the namespace export document
+const nsExportVersion = "v1"
+
+// nsExportSecretFields are the fields of objects which hold secrets (excluded from an export by default)
+var nsExportSecretFields = map[string]string{
+	"/resources/" + model.StrSSHKey + "/": "privateKey",
+	"/resources/" + model.StrSqlDb + "/":  "adminPassword",
+	"/vm/":                                "vmUserPassword",
+}
+
+// nsExportSecretField returns the secret field of the object (relative key) or "" if it has no secret
+func nsExportSecretField(relKey string) string {
+	for keyPart, field := range nsExportSecretFields {
+		if strings.Contains(relKey, keyPart) {
+			return field
+		}
+	}
+	return ""
+}
+
+// ExportNs returns all metadata of the namespace (objects, labels, specs and images).
+// Secrets are exported in plaintext (decrypted) only if includeSecrets is true;
+// they are encrypted again with the master key of the instance on import.
+func ExportNs(nsId string, includeSecrets bool) (model.NsExportDoc, error) {
+	doc := model.NsExportDoc{Version: nsExportVersion, ExportedAt: time.Now(), NsId: nsId, SecretsIncluded: includeSecrets}
+
+	nsKey := "/ns/" + nsId
+	nsValue, err := kvstore.Get(nsKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return doc, err
+	}
+	if nsValue == "" {
+		return doc, fmt.Errorf("the namespace %s does not exist", nsId)
+	}
+	doc.Namespace = json.RawMessage(nsValue)
+
+	keyValue, err := kvstore.GetKvList(nsKey + "/")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return doc, err
+	}
+	doc.Objects = make([]model.NsExportKv, 0, len(keyValue))
+	for _, kv := range keyValue {
+		relKey := strings.TrimPrefix(kv.Key, nsKey)
+		// the event timeline is the history of the source namespace, not its state
+		if strings.HasPrefix(relKey, "/events/") {
+			continue
+		}
+		value := []byte(kv.Value)
+		if field := nsExportSecretField(relKey); field != "" {
+			value, err = exportSecretField(value, field, includeSecrets)
+			if err != nil {
+				return doc, fmt.Errorf("failed to export the secret of %s: %w", kv.Key, err)
+			}
+		}
+		if !json.Valid(value) {
+			log.Warn().Msgf("Skip exporting %s (not a JSON value)", kv.Key)
+			continue
+		}
+		doc.Objects = append(doc.Objects, model.NsExportKv{Key: relKey, Value: json.RawMessage(value)})
+	}
+
+	labelKeyValue, err := kvstore.GetKvList("/label/")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return doc, err
+	}
+	doc.Labels = []model.NsExportKv{}
+	for _, kv := range labelKeyValue {
+		labelInfo := model.LabelInfo{}
+		if err := json.Unmarshal([]byte(kv.Value), &labelInfo); err != nil {
+			continue
+		}
+		if labelInfo.ResourceKey == nsKey || strings.HasPrefix(labelInfo.ResourceKey, nsKey+"/") {
+			doc.Labels = append(doc.Labels, model.NsExportKv{Key: kv.Key, Value: json.RawMessage(kv.Value)})
+		}
+	}
+
+	doc.Specs = []model.TbSpecInfo{}
+	if err := model.ORM.Where("Namespace = ?", nsId).Find(&doc.Specs); err != nil {
+		log.Error().Err(err).Msg("")
+		return doc, err
+	}
+	doc.Images = []model.TbImageInfo{}
+	if err := model.ORM.Where("Namespace = ?", nsId).Find(&doc.Images); err != nil {
+		log.Error().Err(err).Msg("")
+		return doc, err
+	}
+
+	log.Info().Msgf("Exported the namespace %s (%d objects, %d labels, %d specs, %d images)", nsId, len(doc.Objects), len(doc.Labels), len(doc.Specs), len(doc.Images))
+	return doc, nil
+}
+
+// exportSecretField removes (or decrypts) the secret field of the object value
+func exportSecretField(value []byte, field string, includeSecrets bool) ([]byte, error) {
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(value, &obj); err != nil {
+		return value, nil
+	}
+	secret, ok := obj[field].(string)
+	if !ok || secret == "" {
+		return value, nil
+	}
+	if includeSecrets {
+		decrypted, err := DecryptSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		obj[field] = decrypted
+	} else {
+		obj[field] = ""
+	}
+	return json.Marshal(obj)
+}
+
+// ArchiveNsExport returns the export document as a tar.gz archive with a single file ({nsId}.json)
+func ArchiveNsExport(doc model.NsExportDoc) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	header := &tar.Header{Name: doc.NsId + ".json", Mode: 0600, Size: int64(len(data)), ModTime: doc.ExportedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseNsExport parses the export document given as JSON or as a tar.gz archive made by ArchiveNsExport
+func ParseNsExport(data []byte) (model.NsExportDoc, error) {
+	doc := model.NsExportDoc{}
+	// gzip magic number
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return doc, fmt.Errorf("invalid tar.gz archive: %w", err)
+		}
+		tr := tar.NewReader(gr)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return doc, fmt.Errorf("no JSON document in the archive")
+			}
+			if err != nil {
+				return doc, fmt.Errorf("invalid tar.gz archive: %w", err)
+			}
+			if header.Typeflag == tar.TypeReg && strings.HasSuffix(header.Name, ".json") {
+				data, err = io.ReadAll(tr)
+				if err != nil {
+					return doc, fmt.Errorf("invalid tar.gz archive: %w", err)
+				}
+				break
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return doc, fmt.Errorf("invalid export document: %w", err)
+	}
+	if doc.Version != nsExportVersion {
+		return doc, fmt.Errorf("unsupported export version (%s)", doc.Version)
+	}
+	if doc.NsId == "" {
+		return doc, fmt.Errorf("the export document has no nsId")
+	}
+	return doc, nil
+}
+
+// nsImportRenameCandidates is the max number of suffixes (e.g., vnet01-1) tried to rename a colliding object
+const nsImportRenameCandidates = 1000
+
+// nsObjectType returns the type and id of the object by its key relative to the namespace
+// (e.g., /resources/vNet/vnet01 -> vNet, vnet01 and /mci/mci01/vm/vm01 -> vm, vm01)
+func nsObjectType(relKey string) (string, string) {
+	segments := strings.Split(strings.Trim(relKey, "/"), "/")
+	if len(segments) < 2 {
+		return "", ""
+	}
+	return segments[len(segments)-2], segments[len(segments)-1]
+}
+
+// nsRenameIdKey returns the key of the renamed id of the object type in the id map
+func nsRenameIdKey(objectType string, id string) string {
+	return strings.ToLower(objectType) + "/" + id
+}
+
+// moveNsObjectKey returns the key of the object after its nearest renamed parent moved (the key itself if no parent is renamed)
+func moveNsObjectKey(relKey string, keyMap map[string]string) string {
+	for i := strings.LastIndex(relKey, "/"); i > 0; i = strings.LastIndex(relKey[:i], "/") {
+		if newParent, ok := keyMap[relKey[:i]]; ok {
+			return newParent + relKey[i:]
+		}
+	}
+	return relKey
+}
+
+// planNsImportRenames gives new ids (e.g., vnet01-1) to the objects of the document colliding with the existing objects
+// of the namespace. The children of a renamed object move with it (e.g., /mci/mci01/vm/vm01 -> /mci/mci01-1/vm/vm01).
+// It returns the new keys of the renamed and moved objects, the new ids by nsRenameIdKey and the keys of the colliding
+// objects which cannot be renamed (namespace-level objects without an id).
+func planNsImportRenames(nsKey string, objects []model.NsExportKv) (map[string]string, map[string]string, []string, error) {
+	docKeys := map[string]bool{}
+	relKeys := []string{}
+	for _, obj := range objects {
+		docKeys[obj.Key] = true
+		relKeys = append(relKeys, obj.Key)
+	}
+	// parents first
+	sort.SliceStable(relKeys, func(i, j int) bool {
+		return strings.Count(relKeys[i], "/") < strings.Count(relKeys[j], "/")
+	})
+
+	keyMap := map[string]string{}
+	idMap := map[string]string{}
+	unrenamable := []string{}
+	for _, relKey := range relKeys {
+		newKey := moveNsObjectKey(relKey, keyMap)
+		existing, err := kvstore.Get(nsKey + newKey)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if existing == "" {
+			if newKey != relKey {
+				keyMap[relKey] = newKey
+			}
+			continue
+		}
+
+		objectType, id := nsObjectType(newKey)
+		if objectType == "" {
+			unrenamable = append(unrenamable, relKey)
+			continue
+		}
+		parent := strings.TrimSuffix(newKey, id)
+		renamed := false
+		for i := 1; i <= nsImportRenameCandidates && !renamed; i++ {
+			candidate := fmt.Sprintf("%s%s-%d", parent, id, i)
+			if docKeys[candidate] {
+				continue
+			}
+			existing, err := kvstore.Get(nsKey + candidate)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if existing == "" {
+				keyMap[relKey] = candidate
+				idMap[nsRenameIdKey(objectType, id)] = fmt.Sprintf("%s-%d", id, i)
+				docKeys[candidate] = true
+				renamed = true
+			}
+		}
+		if !renamed {
+			return nil, nil, nil, fmt.Errorf("failed to find a new id for the object %s", relKey)
+		}
+	}
+	return keyMap, idMap, unrenamable, nil
+}
+
+// renameNsObjectRefs replaces the ids of the renamed objects in the reference fields of the object
+// (fields named by the object type with Id or Ids, e.g., vNetId, sshKeyId and securityGroupIds)
+func renameNsObjectRefs(obj map[string]interface{}, idMap map[string]string) {
+	for field, value := range obj {
+		lower := strings.ToLower(field)
+		objectType := ""
+		switch {
+		case strings.HasSuffix(lower, "ids"):
+			objectType = strings.TrimSuffix(lower, "ids")
+		case strings.HasSuffix(lower, "id"):
+			objectType = strings.TrimSuffix(lower, "id")
+		}
+		switch v := value.(type) {
+		case string:
+			if newId, ok := idMap[nsRenameIdKey(objectType, v)]; ok && objectType != "" {
+				obj[field] = newId
+			}
+		case []interface{}:
+			for i, item := range v {
+				if id, ok := item.(string); ok && objectType != "" {
+					if newId, ok := idMap[nsRenameIdKey(objectType, id)]; ok {
+						v[i] = newId
+					}
+				} else if nested, ok := item.(map[string]interface{}); ok {
+					renameNsObjectRefs(nested, idMap)
+				}
+			}
+		case map[string]interface{}:
+			renameNsObjectRefs(v, idMap)
+		}
+	}
+}
+
+// ImportNs recreates the metadata of the namespace from the export document.
+// nsId overrides the namespace of the document if given. On id collision, mode decides:
+// fail (default) rejects the import, skip keeps the existing objects, and rename imports the colliding objects
+// with new ids (e.g., vnet01-1) and updates the references to them in the imported objects.
+// Only metadata is restored; objects referencing CSP resources are labeled sys.imported=unverified.
+func ImportNs(doc model.NsExportDoc, nsId string, mode string) (model.NsImportResult, error) {
+	result := model.NsImportResult{}
+	if mode == "" {
+		mode = model.NsImportModeFail
+	}
+	if mode != model.NsImportModeFail && mode != model.NsImportModeSkip && mode != model.NsImportModeRename {
+		return result, fmt.Errorf("invalid import mode (%s); use fail, skip or rename", mode)
+	}
+	if nsId == "" {
+		nsId = doc.NsId
+	}
+	if err := CheckString(nsId); err != nil {
+		return result, err
+	}
+
+	nsExists, err := CheckNs(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+
+	renamed := false
+	if nsExists {
+		switch mode {
+		case model.NsImportModeFail:
+			for _, obj := range doc.Objects {
+				value, err := kvstore.Get("/ns/" + nsId + obj.Key)
+				if err != nil {
+					return result, err
+				}
+				if value != "" {
+					return result, fmt.Errorf("the object %s already exists in the namespace %s (use mode skip or rename)", obj.Key, nsId)
+				}
+			}
+		}
+	}
+	if nsId != doc.NsId {
+		renamed = true
+	}
+	result.NsId = nsId
+	nsKey := "/ns/" + nsId
+
+	// new ids of the colliding objects (rename mode)
+	keyMap := map[string]string{}
+	idMap := map[string]string{}
+	skipKeys := map[string]bool{}
+	if nsExists && mode == model.NsImportModeRename {
+		var unrenamable []string
+		keyMap, idMap, unrenamable, err = planNsImportRenames(nsKey, doc.Objects)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		for _, relKey := range unrenamable {
+			skipKeys[relKey] = true
+		}
+	}
+
+	// rewrite references to the source namespace and give new uids (to keep labels of the source apart)
+	rewrite := func(value []byte) []byte { return value }
+	uidMap := map[string]string{}
+	if renamed {
+		from := []byte("/ns/" + doc.NsId + "/")
+		to := []byte(nsKey + "/")
+		rewrite = func(value []byte) []byte { return bytes.ReplaceAll(value, from, to) }
+	}
+	renewUid := func(obj map[string]interface{}, moved bool) {
+		if !renamed && !moved {
+			return
+		}
+		if oldUid, ok := obj["uid"].(string); ok && oldUid != "" {
+			newUid := GenUid()
+			uidMap[oldUid] = newUid
+			obj["uid"] = newUid
+		}
+	}
+
+	// namespace object
+	if !nsExists {
+		nsObj := map[string]interface{}{}
+		if err := json.Unmarshal(doc.Namespace, &nsObj); err != nil {
+			return result, fmt.Errorf("invalid namespace object: %w", err)
+		}
+		nsObj["id"] = nsId
+		if renamed {
+			nsObj["name"] = nsId
+		}
+		renewUid(nsObj, false)
+		val, _ := json.Marshal(nsObj)
+		if err := kvstore.Put(nsKey, string(val)); err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+	}
+
+	// objects (resources, MCIs, policies, ...)
+	importedKeys := map[string]bool{}
+	cspReferenced := map[string]bool{}
+	renamedKeys := map[string]string{}
+	for _, obj := range doc.Objects {
+		if skipKeys[obj.Key] {
+			result.Skipped = append(result.Skipped, nsKey+obj.Key)
+			continue
+		}
+		newRelKey, moved := keyMap[obj.Key]
+		if !moved {
+			newRelKey = obj.Key
+		}
+		key := nsKey + newRelKey
+		if mode == model.NsImportModeSkip {
+			existing, err := kvstore.Get(key)
+			if err != nil {
+				return result, err
+			}
+			if existing != "" {
+				result.Skipped = append(result.Skipped, key)
+				continue
+			}
+		}
+
+		value := rewrite([]byte(obj.Value))
+		objMap := map[string]interface{}{}
+		if err := json.Unmarshal(value, &objMap); err == nil {
+			renewUid(objMap, moved)
+			if len(idMap) > 0 {
+				_, oldId := nsObjectType(obj.Key)
+				_, newId := nsObjectType(newRelKey)
+				if oldId != newId {
+					if objMap["id"] == oldId {
+						objMap["id"] = newId
+					}
+					if objMap["name"] == oldId {
+						objMap["name"] = newId
+					}
+				}
+				renameNsObjectRefs(objMap, idMap)
+			}
+			if field := nsExportSecretField(obj.Key); field != "" {
+				if secret, ok := objMap[field].(string); ok && secret != "" {
+					encrypted, err := EncryptSecret(secret)
+					if err != nil {
+						return result, fmt.Errorf("failed to encrypt the secret of %s: %w", key, err)
+					}
+					objMap[field] = encrypted
+				}
+			}
+			if cspResourceId, ok := objMap["cspResourceId"].(string); ok && cspResourceId != "" {
+				cspReferenced[key] = true
+			}
+			value, _ = json.Marshal(objMap)
+		}
+		if err := kvstore.Put(key, string(value)); err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		importedKeys[key] = true
+		if moved {
+			renamedKeys[nsKey+obj.Key] = key
+		}
+		result.Imported++
+	}
+
+	// labels of the namespace and the imported objects
+	for _, kv := range doc.Labels {
+		labelInfo := model.LabelInfo{}
+		if err := json.Unmarshal(rewrite([]byte(kv.Value)), &labelInfo); err != nil {
+			continue
+		}
+		oldResourceKey := labelInfo.ResourceKey
+		if newKey, ok := renamedKeys[oldResourceKey]; ok {
+			labelInfo.ResourceKey = newKey
+		}
+		if labelInfo.ResourceKey != nsKey && !importedKeys[labelInfo.ResourceKey] {
+			continue
+		}
+		if labelInfo.ResourceKey == nsKey && nsExists {
+			continue
+		}
+		labelKey := kv.Key
+		if labelInfo.Labels == nil {
+			labelInfo.Labels = map[string]string{}
+		}
+		if renamed {
+			labelInfo.Labels[model.LabelNamespace] = nsId
+		}
+		if oldUid := labelInfo.Labels[model.LabelUid]; uidMap[oldUid] != "" {
+			labelInfo.Labels[model.LabelUid] = uidMap[oldUid]
+			labelKey = strings.TrimSuffix(labelKey, oldUid) + uidMap[oldUid]
+		}
+		if labelInfo.ResourceKey != oldResourceKey {
+			_, oldId := nsObjectType(oldResourceKey)
+			_, newId := nsObjectType(labelInfo.ResourceKey)
+			if labelInfo.Labels[model.LabelId] == oldId {
+				labelInfo.Labels[model.LabelId] = newId
+			}
+			if labelInfo.Labels[model.LabelName] == oldId {
+				labelInfo.Labels[model.LabelName] = newId
+			}
+		}
+		if cspReferenced[labelInfo.ResourceKey] {
+			labelInfo.Labels[model.LabelImported] = model.NsImportUnverified
+			result.Unverified = append(result.Unverified, labelInfo.ResourceKey)
+			delete(cspReferenced, labelInfo.ResourceKey)
+		}
+		val, _ := json.Marshal(labelInfo)
+		if err := kvstore.Put(labelKey, string(val)); err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+	}
+	// objects referencing CSP resources without label records
+	for key := range cspReferenced {
+		objValue, err := kvstore.Get(key)
+		if err != nil || objValue == "" {
+			continue
+		}
+		obj := struct {
+			ResourceType string `json:"resourceType"`
+			Uid          string `json:"uid"`
+		}{}
+		if json.Unmarshal([]byte(objValue), &obj) != nil || obj.ResourceType == "" || obj.Uid == "" {
+			continue
+		}
+		labels := map[string]string{model.LabelImported: model.NsImportUnverified}
+		if err := label.CreateOrUpdateLabel(obj.ResourceType, obj.Uid, key, labels); err != nil {
+			log.Warn().Err(err).Msgf("Failed to label the imported object %s", key)
+			continue
+		}
+		result.Unverified = append(result.Unverified, key)
+	}
+
+	// specs and images (kept in the database)
+	for _, spec := range doc.Specs {
+		spec.Namespace = nsId
+		has, err := model.ORM.Exist(&model.TbSpecInfo{Namespace: nsId, Id: spec.Id})
+		if err != nil {
+			return result, err
+		}
+		if has {
+			result.Skipped = append(result.Skipped, "spec:"+spec.Id)
+			continue
+		}
+		if _, err := model.ORM.Insert(&spec); err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		result.Imported++
+	}
+	for _, image := range doc.Images {
+		image.Namespace = nsId
+		has, err := model.ORM.Exist(&model.TbImageInfo{Namespace: nsId, Id: image.Id})
+		if err != nil {
+			return result, err
+		}
+		if has {
+			result.Skipped = append(result.Skipped, "image:"+image.Id)
+			continue
+		}
+		if _, err := model.ORM.Insert(&image); err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		result.Imported++
+	}
+
+	log.Info().Msgf("Imported the namespace %s as %s (imported: %d, skipped: %d, unverified: %d)", doc.NsId, nsId, result.Imported, len(result.Skipped), len(result.Unverified))
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+func TestImportNsRenameCollidingObjects(t *testing.T) {
+	nsId := "import-rename"
+	kvstore.Put("/ns/"+nsId, `{"id":"import-rename","name":"import-rename"}`)
+	kvstore.Put("/ns/"+nsId+"/resources/vNet/vnet01", `{"id":"vnet01","name":"vnet01","uid":"existing-vnet"}`)
+
+	doc := model.NsExportDoc{
+		NsId:      nsId,
+		Namespace: json.RawMessage(`{"id":"import-rename","name":"import-rename"}`),
+		Objects: []model.NsExportKv{
+			{Key: "/resources/vNet/vnet01", Value: json.RawMessage(`{"id":"vnet01","name":"vnet01","uid":"src-vnet"}`)},
+			{Key: "/resources/vNet/vnet01/subnet/subnet01", Value: json.RawMessage(`{"id":"subnet01","vNetId":"vnet01","uid":"src-subnet"}`)},
+			{Key: "/resources/securityGroup/sg01", Value: json.RawMessage(`{"id":"sg01","vNetId":"vnet01","uid":"src-sg"}`)},
+		},
+	}
+
+	result, err := ImportNs(doc, "", model.NsImportModeRename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NsId != nsId {
+		t.Errorf("namespace renamed to %s; only the colliding objects should be renamed", result.NsId)
+	}
+	if result.Imported != 3 {
+		t.Errorf("imported %d objects, want 3", result.Imported)
+	}
+
+	existing, _ := kvstore.Get("/ns/" + nsId + "/resources/vNet/vnet01")
+	if existing != `{"id":"vnet01","name":"vnet01","uid":"existing-vnet"}` {
+		t.Errorf("the existing vNet is changed: %s", existing)
+	}
+
+	tests := []struct {
+		key   string
+		field string
+		want  string
+	}{
+		{"/resources/vNet/vnet01-1", "id", "vnet01-1"},
+		{"/resources/vNet/vnet01-1", "name", "vnet01-1"},
+		{"/resources/vNet/vnet01-1/subnet/subnet01", "id", "subnet01"},
+		{"/resources/vNet/vnet01-1/subnet/subnet01", "vNetId", "vnet01-1"},
+		{"/resources/securityGroup/sg01", "vNetId", "vnet01-1"},
+	}
+	for _, tt := range tests {
+		value, _ := kvstore.Get("/ns/" + nsId + tt.key)
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(value), &obj); err != nil {
+			t.Errorf("%s: %v (%s)", tt.key, err, value)
+			continue
+		}
+		if obj[tt.field] != tt.want {
+			t.Errorf("%s %s = %v, want %s", tt.key, tt.field, obj[tt.field], tt.want)
+		}
+	}
+}
+
+func TestRenameNsObjectRefs(t *testing.T) {
+	idMap := map[string]string{
+		nsRenameIdKey("securityGroup", "sg01"): "sg01-1",
+		nsRenameIdKey("sshKey", "key01"):       "key01-1",
+	}
+	obj := map[string]interface{}{
+		"id":               "sg01",
+		"securityGroupIds": []interface{}{"sg01", "sg02"},
+		"sshKeyId":         "key01",
+		"vNetId":           "sg01",
+		"vm":               []interface{}{map[string]interface{}{"sshKeyId": "key01"}},
+	}
+	renameNsObjectRefs(obj, idMap)
+
+	if obj["id"] != "sg01" {
+		t.Errorf("id is changed: %v", obj["id"])
+	}
+	if ids := obj["securityGroupIds"].([]interface{}); ids[0] != "sg01-1" || ids[1] != "sg02" {
+		t.Errorf("securityGroupIds = %v", ids)
+	}
+	if obj["sshKeyId"] != "key01-1" {
+		t.Errorf("sshKeyId = %v", obj["sshKeyId"])
+	}
+	if obj["vNetId"] != "sg01" {
+		t.Errorf("vNetId of another type is changed: %v", obj["vNetId"])
+	}
+	if nested := obj["vm"].([]interface{})[0].(map[string]interface{}); nested["sshKeyId"] != "key01-1" {
+		t.Errorf("nested sshKeyId = %v", nested["sshKeyId"])
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	nsEventQueue     = make(chan nsEventItem, 1000)
+	nsEventWriterRun sync.Once
+
+	// objectActorMap keeps the user of the request which is changing the object (target -> *objectActor)
+	objectActorMap = sync.Map{}
+)
+
+// nsEventItem is a queued event of the namespace
+type nsEventItem struct {
+	nsId  string
+	event model.NsEvent
+}
+
+// NsEventFilter is a set of conditions to query the events of a namespace
+type NsEventFilter struct {
+	From         time.Time // events at or after this time
+	To           time.Time // events at or before this time
+	ResourceType string    // type of the resource (exact match)
+	ResourceId   string    // ID of the resource (exact match, or the prefix before "/" for the VMs of an MCI)
+	Page         int       // page number starting from 1 (0 means no pagination)
+	PageSize     int       // number of events in a page (0 means no pagination)
+}
+
+// GenNsEventKey is func to generate the key of an event of the namespace (/ns/{nsId}/events/{eventId})
+func GenNsEventKey(nsId string, eventId string) string {
+	return "/ns/" + nsId + "/events/" + eventId
+}
+
+// nsEventMaxPerNs returns the number of events kept per namespace (TB_NS_EVENT_MAX_PER_NS, default 10000)
+func nsEventMaxPerNs() int {
+	max, err := strconv.Atoi(NVL(os.Getenv("TB_NS_EVENT_MAX_PER_NS"), "10000"))
+	if err != nil || max <= 0 {
+		max = 10000
+	}
+	return max
+}
+
+// objectActor resolves the user of the request which is changing the object.
+// It is resolved when an event occurs, since the user is authenticated after the object is tracked.
+type objectActor struct {
+	resolve func() string
+}
+
+// TrackObjectActor records the user (resolved by actor) of the API request which is changing the object
+// (e.g., /ns/default/mci/mci01), so that events of the object carry the actor. The returned function must be called
+// when the request is finished.
+func TrackObjectActor(target string, actor func() string) func() {
+	if target == "" || actor == nil {
+		return func() {}
+	}
+	tracked := &objectActor{resolve: actor}
+	objectActorMap.Store(target, tracked)
+	return func() {
+		objectActorMap.CompareAndDelete(target, tracked)
+	}
+}
+
+// lookupObjectActor returns the user of the request which is changing the object or its parents (system if none)
+func lookupObjectActor(target string) string {
+	if v := lookupTrackedObject(&objectActorMap, target); v != nil {
+		return NVL(v.(*objectActor).resolve(), model.NsEventActorSystem)
+	}
+	return model.NsEventActorSystem
+}
+
+// RecordNsEvent appends the event on the object (target, e.g., /ns/default/mci/mci01) to the timeline of the namespace
+// asynchronously. The time, the actor and the request ID are filled from the API request which is changing the object.
+func RecordNsEvent(nsId string, target string, event model.NsEvent) {
+	nsEventWriterRun.Do(func() {
+		go runNsEventWriter()
+	})
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Time = event.Time.UTC()
+	// zero-padded timestamp keeps the events sorted by key
+	event.Id = fmt.Sprintf("%020d-%s", event.Time.UnixNano(), GenUid())
+	event.RequestId = lookupObjectRequest(target)
+	event.Actor = lookupObjectActor(target)
+
+	select {
+	case nsEventQueue <- nsEventItem{nsId: nsId, event: event}:
+	default:
+		log.Warn().Msgf("Namespace event queue is full. Event (%s, %s/%s) is dropped", event.EventType, event.ResourceType, event.ResourceId)
+	}
+}
+
+// runNsEventWriter stores the queued events and keeps the number of events of each namespace in the cap
+func runNsEventWriter() {
+	for item := range nsEventQueue {
+		val, err := json.Marshal(item.event)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			continue
+		}
+		if err := kvstore.Put(GenNsEventKey(item.nsId, item.event.Id), string(val)); err != nil {
+			log.Error().Err(err).Msgf("Failed to store the event of namespace %s", item.nsId)
+			continue
+		}
+		if _, err := trimNsEvents(item.nsId, nsEventMaxPerNs()); err != nil {
+			log.Error().Err(err).Msgf("Failed to trim the events of namespace %s", item.nsId)
+		}
+	}
+}
+
+// trimNsEvents deletes the oldest events of the namespace over max. It returns the number of deleted events.
+func trimNsEvents(nsId string, max int) (int, error) {
+	prefix := GenNsEventKey(nsId, "")
+	count, _, err := kvstore.GetStats(prefix)
+	if err != nil {
+		return 0, err
+	}
+	if count <= int64(max) {
+		return 0, nil
+	}
+	kvs, _, err := kvstore.GetKvListPage(prefix, "", count-int64(max), true)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, kv := range kvs {
+		if err := kvstore.Delete(kv.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// ListNsEvents returns the events of the namespace which match the filter (newest first)
+func ListNsEvents(nsId string, filter NsEventFilter) (model.NsEventList, error) {
+	result := model.NsEventList{Events: []model.NsEvent{}}
+
+	if err := CheckString(nsId); err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	if check, _ := CheckNs(nsId); !check {
+		return result, NewResourceNotFoundError(model.StrNamespace, nsId)
+	}
+
+	kvs, err := kvstore.GetKvList(GenNsEventKey(nsId, ""))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key > kvs[j].Key
+	})
+
+	matched := []model.NsEvent{}
+	for _, kv := range kvs {
+		event := model.NsEvent{}
+		if err := json.Unmarshal([]byte(kv.Value), &event); err != nil {
+			log.Error().Err(err).Str("key", kv.Key).Msg("Failed to unmarshal namespace event")
+			continue
+		}
+		if !filter.From.IsZero() && event.Time.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && event.Time.After(filter.To) {
+			continue
+		}
+		if filter.ResourceType != "" && event.ResourceType != filter.ResourceType {
+			continue
+		}
+		if filter.ResourceId != "" && event.ResourceId != filter.ResourceId && !strings.HasPrefix(event.ResourceId, filter.ResourceId+"/") {
+			continue
+		}
+		matched = append(matched, event)
+	}
+
+	result.Total = len(matched)
+	result.Events = matched
+	if filter.Page <= 0 && filter.PageSize <= 0 {
+		return result, nil
+	}
+
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	result.Page = page
+	result.PageSize = pageSize
+
+	start := (page - 1) * pageSize
+	if start >= len(matched) {
+		result.Events = []model.NsEvent{}
+		return result, nil
+	}
+	end := start + pageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+	result.Events = matched[start:end]
+	return result, nil
+}
+
+// DelNsEvents deletes all events of the namespace
+func DelNsEvents(nsId string) error {
+	kvs, err := kvstore.GetKvList(GenNsEventKey(nsId, ""))
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		if err := kvstore.Delete(kv.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneNsEvents deletes the events older than retentionDays (and over the cap) in all namespaces.
+// It returns the number of deleted events.
+func PruneNsEvents(retentionDays int) (int, error) {
+	nsIdList, err := ListNsId()
+	if err != nil {
+		return 0, err
+	}
+	// the event ID starts with the zero-padded timestamp, so the older events have smaller keys
+	cutoff := fmt.Sprintf("%020d", time.Now().UTC().AddDate(0, 0, -retentionDays).UnixNano())
+
+	deleted := 0
+	for _, nsId := range nsIdList {
+		if retentionDays > 0 {
+			prefix := GenNsEventKey(nsId, "")
+			kvs, err := kvstore.GetKvList(prefix)
+			if err != nil {
+				return deleted, err
+			}
+			for _, kv := range kvs {
+				if strings.TrimPrefix(kv.Key, prefix) >= cutoff {
+					continue
+				}
+				if err := kvstore.Delete(kv.Key); err != nil {
+					return deleted, err
+				}
+				deleted++
+			}
+		}
+		trimmed, err := trimNsEvents(nsId, nsEventMaxPerNs())
+		deleted += trimmed
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// StartNsEventRetention runs PruneNsEvents periodically in background
+func StartNsEventRetention(interval time.Duration, retentionDays int) {
+	if retentionDays <= 0 {
+		log.Info().Msgf("Retention policy for namespace events is disabled (up to %d events per namespace)", nsEventMaxPerNs())
+	} else {
+		log.Info().Msgf("Retention policy for namespace events: %d days (up to %d events per namespace)", retentionDays, nsEventMaxPerNs())
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			deleted, err := PruneNsEvents(retentionDays)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to prune namespace events")
+				continue
+			}
+			if deleted > 0 {
+				log.Info().Msgf("Pruned %d namespace events by the retention policy", deleted)
+			}
+		}
+	}()
+}
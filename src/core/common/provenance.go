@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/labstack/echo/v4"
+)
+
+// ClientCertCNKey is the context key for the common name (CN) of the verified client certificate (mutual TLS)
+const ClientCertCNKey = "clientCertCN"
+
+// requestUserKey is the context key of the authenticated user of the request
+type requestUserKey struct{}
+
+// createdViaKey is the context key of the way the objects are created in the request
+type createdViaKey struct{}
+
+// RequestUser returns the authenticated user of the request (JWT user name, basic auth username or client certificate CN).
+// Only the user verified by the auth middleware ("name") is used, not the username claimed in the Authorization header.
+func RequestUser(c echo.Context) string {
+	if name, ok := c.Get("name").(string); ok && name != "" {
+		return name
+	}
+	if cn, ok := c.Get(ClientCertCNKey).(string); ok && cn != "" {
+		return "cert:" + cn
+	}
+	return "anonymous"
+}
+
+// WithRequestUser returns the context with the authenticated user of the request
+func WithRequestUser(ctx context.Context, user string) context.Context {
+	if user == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestUserKey{}, user)
+}
+
+// WithCreatedVia returns the context in which objects are created via the given way (model.CreatedVia*).
+// It overrides the way of the outer context (e.g., a shared resource created during mciDynamic).
+func WithCreatedVia(ctx context.Context, via string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, createdViaKey{}, via)
+}
+
+// CreatedViaFromContext returns the way objects are created in the context (empty if not set)
+func CreatedViaFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	via, _ := ctx.Value(createdViaKey{}).(string)
+	return via
+}
+
+// NewProvenance returns the provenance of an object created in the context.
+// defaultVia is used if the context does not tell the way the object is created.
+func NewProvenance(ctx context.Context, defaultVia string) model.Provenance {
+	provenance := model.Provenance{CreatedVia: defaultVia}
+	if ctx == nil {
+		return provenance
+	}
+	if via := CreatedViaFromContext(ctx); via != "" {
+		provenance.CreatedVia = via
+	}
+	provenance.CreatedByRequestId = RequestIdFromContext(ctx)
+	provenance.CreatedByUser, _ = ctx.Value(requestUserKey{}).(string)
+	return provenance
+}
+
+// SetProvenanceLabels adds the provenance to the system labels of the object (sys.createdBy, sys.createdByRequestId, sys.createdVia)
+func SetProvenanceLabels(labels map[string]string, provenance model.Provenance) {
+	if provenance.CreatedByUser != "" {
+		labels[model.LabelCreatedBy] = provenance.CreatedByUser
+	}
+	if provenance.CreatedByRequestId != "" {
+		labels[model.LabelCreatedByReqId] = provenance.CreatedByRequestId
+	}
+	if provenance.CreatedVia != "" {
+		labels[model.LabelCreatedVia] = provenance.CreatedVia
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// QuotaExceededError is returned when a request would exceed a quota limit of the namespace
+type QuotaExceededError struct {
+	NsId      string
+	Dimension string
+	Limit     int
+	Usage     int
+	Requested int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded in namespace %s: %s (limit: %d, current usage: %d, requested: %d)",
+		e.NsId, e.Dimension, e.Limit, e.Usage, e.Requested)
+}
+
+// GenNsQuotaKey is func to generate the key of the quota object of a namespace
+func GenNsQuotaKey(nsId string) string {
+	return "/ns/" + nsId + "/quota"
+}
+
+// GetNsQuota returns the quota of the namespace (no limit if the quota is not set)
+func GetNsQuota(nsId string) (model.NsQuota, error) {
+	quota := model.NsQuota{}
+
+	err := CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return quota, err
+	}
+
+	keyValue, err := kvstore.GetKv(GenNsQuotaKey(nsId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return quota, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return quota, nil
+	}
+
+	err = json.Unmarshal([]byte(keyValue.Value), &quota)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return quota, err
+	}
+	return quota, nil
+}
+
+// UpdateNsQuota sets the quota of the namespace
+func UpdateNsQuota(nsId string, quota *model.NsQuota) (model.NsQuota, error) {
+	err := CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NsQuota{}, err
+	}
+
+	if quota.MaxVms < 0 || quota.MaxVCpus < 0 || quota.MaxVNets < 0 || quota.MaxDataDiskGB < 0 {
+		return model.NsQuota{}, fmt.Errorf("quota limits cannot be negative (0 means unlimited)")
+	}
+
+	val, err := json.Marshal(quota)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NsQuota{}, err
+	}
+	err = kvstore.Put(GenNsQuotaKey(nsId), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NsQuota{}, err
+	}
+	return *quota, nil
+}
+
+// CheckQuotaLimit returns QuotaExceededError if the requested amount exceeds the limit of the dimension
+func CheckQuotaLimit(nsId string, dimension string, limit int, usage int, requested int) error {
+	if limit <= 0 || requested <= 0 {
+		return nil
+	}
+	if usage+requested > limit {
+		err := &QuotaExceededError{
+			NsId:      nsId,
+			Dimension: dimension,
+			Limit:     limit,
+			Usage:     usage,
+			Requested: requested,
+		}
+		log.Warn().Msg(err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	crand "crypto/rand"
+	"math/big"
+)
+
+// SuffixCharset is the charset of RandomSuffix (valid in resource names of all CSPs)
+const SuffixCharset = "abcdefghijklmnopqrstuvwxyz1234567890"
+
+// RandomIndex returns a uniformly random int in [0, n) from crypto/rand.
+// Unlike math/rand with a time-based seed, concurrent callers never get the same sequence.
+func RandomIndex(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	v, err := crand.Int(crand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return int(v.Int64())
+}
+
+// RandomString returns a random string of n characters from the charset
+func RandomString(charset string, n int) string {
+	if n <= 0 || charset == "" {
+		return ""
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = charset[RandomIndex(len(charset))]
+	}
+	return string(b)
+}
+
+// RandomSuffix returns a random string of n lowercase letters and digits for resource name suffixes
+func RandomSuffix(n int) string {
+	return RandomString(SuffixCharset, n)
+}
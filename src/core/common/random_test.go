@@ -0,0 +1,101 @@
+package common
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// collectConcurrently returns the samples generated by the goroutines at the same time
+// (callers with the same time-based seed got the same sequence before crypto/rand)
+func collectConcurrently(samples int, goroutines int, gen func() string) []string {
+	results := make([][]string, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < samples/goroutines; i++ {
+				results[g] = append(results[g], gen())
+			}
+		}(g)
+	}
+	wg.Wait()
+	all := []string{}
+	for _, r := range results {
+		all = append(all, r...)
+	}
+	return all
+}
+
+func TestRandomSuffixCollision(t *testing.T) {
+	const samples = 100000
+	// 36^10 suffixes: the chance of any collision in 100k samples is about 1e-6
+	values := collectConcurrently(samples, 100, func() string { return RandomSuffix(10) })
+	if len(values) != samples {
+		t.Fatalf("%d samples, want %d", len(values), samples)
+	}
+	seen := make(map[string]struct{}, samples)
+	for _, v := range values {
+		if len(v) != 10 || strings.Trim(v, SuffixCharset) != "" {
+			t.Fatalf("invalid suffix %q", v)
+		}
+		if _, ok := seen[v]; ok {
+			t.Fatalf("collision of %q in %d samples", v, samples)
+		}
+		seen[v] = struct{}{}
+	}
+}
+
+func TestRandomStringCollision(t *testing.T) {
+	const samples = 100000
+	const charset = "abcdef0123456789"
+	values := collectConcurrently(samples, 100, func() string { return RandomString(charset, 16) })
+	seen := make(map[string]struct{}, samples)
+	// every character of the charset appears at each position (no position is stuck)
+	used := make([]map[byte]struct{}, 16)
+	for i := range used {
+		used[i] = map[byte]struct{}{}
+	}
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			t.Fatalf("collision of %q in %d samples", v, samples)
+		}
+		seen[v] = struct{}{}
+		for i := 0; i < len(v); i++ {
+			used[i][v[i]] = struct{}{}
+		}
+	}
+	for i, chars := range used {
+		if len(chars) != len(charset) {
+			t.Errorf("position %d uses %d of %d characters", i, len(chars), len(charset))
+		}
+	}
+
+	if RandomString(charset, 0) != "" || RandomString("", 8) != "" {
+		t.Errorf("expected an empty string for no length or no charset")
+	}
+}
+
+func TestGenRandomPassword(t *testing.T) {
+	const samples = 100000
+	values := collectConcurrently(samples, 100, func() string { return GenRandomPassword(14) })
+	seen := make(map[string]struct{}, samples)
+	for _, pw := range values {
+		if _, ok := seen[pw]; ok {
+			t.Fatalf("collision of %q in %d samples", pw, samples)
+		}
+		seen[pw] = struct{}{}
+		if len(pw) != 14 {
+			t.Fatalf("invalid length of %q", pw)
+		}
+		for _, class := range []string{passwordUpper, passwordLower, passwordDigit, passwordSpecial} {
+			if !strings.ContainsAny(pw, class) {
+				t.Fatalf("%q has no character of %q", pw, class)
+			}
+		}
+	}
+	if pw := GenRandomPassword(4); len(pw) != 8 {
+		t.Errorf("length %d, want the minimum length 8", len(pw))
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Status values of RequestDetails
+const (
+	RequestStatusHandling string = "Handling"
+	RequestStatusSuccess  string = "Success"
+	RequestStatusError    string = "Error"
+)
+
+// ResponseRedactedValue replaces the values of sensitive fields in the stored response data
+const ResponseRedactedValue = "***"
+
+// defaultResponseRedactFields are the JSON field names redacted in the stored response data by default
+const defaultResponseRedactFields = "privateKey,credentials,clientSecret,kubeconfig,password"
+
+// responseRedactFields returns the (lowercase) JSON field names whose values are redacted in the stored response data
+// (TB_RESPONSE_REDACT_FIELDS, comma-separated, default privateKey,credentials,clientSecret,kubeconfig,password)
+func responseRedactFields() map[string]bool {
+	fields := map[string]bool{}
+	for _, f := range strings.Split(NVL(os.Getenv("TB_RESPONSE_REDACT_FIELDS"), defaultResponseRedactFields), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields[strings.ToLower(f)] = true
+		}
+	}
+	return fields
+}
+
+// ResponseDumpMaxBytes returns the max size of a JSON response body which is parsed and stored (redacted)
+// (TB_RESPONSE_DUMP_MAX_BYTES, default 1048576)
+func ResponseDumpMaxBytes() int {
+	n := envInt("TB_RESPONSE_DUMP_MAX_BYTES", 1048576)
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// RedactResponseData replaces the values of the sensitive fields (by name, case-insensitive) in nested objects and arrays
+func RedactResponseData(value interface{}) interface{} {
+	return redactResponseValue(value, responseRedactFields())
+}
+
+func redactResponseValue(value interface{}, fields map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			if fields[strings.ToLower(key)] && val != nil && val != "" {
+				redacted[key] = ResponseRedactedValue
+			} else {
+				redacted[key] = redactResponseValue(val, fields)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, val := range v {
+			redacted[i] = redactResponseValue(val, fields)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// OmittedResponseBody returns the placeholder stored instead of a response body which cannot be redacted
+// (not JSON, invalid JSON or larger than ResponseDumpMaxBytes)
+func OmittedResponseBody(contentType string, size int, reason string) string {
+	if contentType == "" {
+		contentType = "unknown content type"
+	}
+	return fmt.Sprintf("(response body omitted: %d bytes of %s, %s)", size, contentType, reason)
+}
+
+// RequestFilter is a set of conditions to query the request details in RequestMap
+type RequestFilter struct {
+	Status     string    // Handling, Error, Success (case-insensitive)
+	Method     string    // HTTP method (case-insensitive)
+	Url        string    // substring of the request URL (case-insensitive)
+	PathPrefix string    // prefix of the request path (e.g., /tumblebug/ns/default/mci)
+	From       time.Time // requests started at or after this time
+	To         time.Time // requests started at or before this time
+	Page       int       // page number starting from 1 (0 means no pagination)
+	PageSize   int       // number of requests in a page (0 means no pagination)
+}
+
+// RequestList is the result of a query on the request details
+type RequestList struct {
+	Total    int              `json:"total"`              // number of requests matched by the filter
+	Page     int              `json:"page,omitempty"`     // page number of the result
+	PageSize int              `json:"pageSize,omitempty"` // page size of the result
+	Requests []RequestDetails `json:"requests"`           // requests in the page (sorted by startTime, newest first)
+}
+
+// requestPath returns the path of the request URL without query parameters
+func requestPath(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return u.Path
+}
+
+// ListRequests returns the request details which match the filter, sorted by startTime (newest first)
+func ListRequests(filter RequestFilter) RequestList {
+	status := strings.ToLower(filter.Status)
+	method := strings.ToLower(filter.Method)
+	urlFilter := strings.ToLower(filter.Url)
+
+	matched := []RequestDetails{}
+	RequestMap.Range(func(key, value interface{}) bool {
+		details, ok := value.(RequestDetails)
+		if !ok {
+			return true
+		}
+		if status != "" && strings.ToLower(details.Status) != status {
+			return true
+		}
+		if method != "" && strings.ToLower(details.RequestInfo.Method) != method {
+			return true
+		}
+		if urlFilter != "" && !strings.Contains(strings.ToLower(details.RequestInfo.URL), urlFilter) {
+			return true
+		}
+		if filter.PathPrefix != "" && !strings.HasPrefix(requestPath(details.RequestInfo.URL), filter.PathPrefix) {
+			return true
+		}
+		if !filter.From.IsZero() && details.StartTime.Before(filter.From) {
+			return true
+		}
+		if !filter.To.IsZero() && details.StartTime.After(filter.To) {
+			return true
+		}
+		matched = append(matched, details)
+		return true
+	})
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].StartTime.After(matched[j].StartTime)
+	})
+
+	result := RequestList{Total: len(matched), Requests: matched}
+	if filter.Page <= 0 && filter.PageSize <= 0 {
+		return result
+	}
+
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	result.Page = page
+	result.PageSize = pageSize
+
+	start := (page - 1) * pageSize
+	if start >= len(matched) {
+		result.Requests = []RequestDetails{}
+		return result
+	}
+	end := start + pageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+	result.Requests = matched[start:end]
+	return result
+}
+
+// PruneRequests deletes completed requests (Success or Error) which ended before maxAge,
+// and then the oldest completed requests beyond maxCount. It returns the number of deleted requests.
+// Requests being handled are never deleted. maxAge or maxCount of 0 disables the condition.
+func PruneRequests(maxAge time.Duration, maxCount int) int {
+	type completedRequest struct {
+		reqId   string
+		endTime time.Time
+	}
+
+	deleted := 0
+	completed := []completedRequest{}
+	now := time.Now()
+
+	RequestMap.Range(func(key, value interface{}) bool {
+		details, ok := value.(RequestDetails)
+		if !ok || details.Status == RequestStatusHandling {
+			return true
+		}
+		if maxAge > 0 && now.Sub(details.EndTime) > maxAge {
+			RequestMap.Delete(key)
+			deleted++
+			return true
+		}
+		completed = append(completed, completedRequest{reqId: key.(string), endTime: details.EndTime})
+		return true
+	})
+
+	if maxCount > 0 && len(completed) > maxCount {
+		sort.Slice(completed, func(i, j int) bool {
+			return completed[i].endTime.Before(completed[j].endTime)
+		})
+		for _, r := range completed[:len(completed)-maxCount] {
+			RequestMap.Delete(r.reqId)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// StartRequestRetention runs PruneRequests periodically in background
+func StartRequestRetention(interval time.Duration, maxAge time.Duration, maxCount int) {
+	if maxAge <= 0 && maxCount <= 0 {
+		log.Info().Msg("Retention policy for request details is disabled")
+		return
+	}
+	log.Info().Msgf("Retention policy for request details: maxAge=%v, maxCount=%d", maxAge, maxCount)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			deleted := PruneRequests(maxAge, maxCount)
+			if deleted > 0 {
+				log.Debug().Msgf("Pruned %d request details by the retention policy", deleted)
+			}
+		}
+	}()
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// requestIdKey is the context key of the request ID
+type requestIdKey struct{}
+
+// NewRequestContext returns the context of the REST request with the request ID, the authenticated user
+// and a logger carrying the request ID. Handlers build it once and pass it to the core functions.
+func NewRequestContext(c echo.Context) context.Context {
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+	if reqId == "" {
+		reqId = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	return WithRequestUser(WithRequestId(c.Request().Context(), reqId), RequestUser(c))
+}
+
+// WithRequestId returns the context with the request ID (also added to the logger of the context as requestId)
+func WithRequestId(ctx context.Context, reqId string) context.Context {
+	if reqId == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, requestIdKey{}, reqId)
+	return WithLogFields(ctx, "requestId", reqId)
+}
+
+// RequestIdFromContext returns the request ID of the context (empty if none)
+func RequestIdFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	reqId, _ := ctx.Value(requestIdKey{}).(string)
+	return reqId
+}
+
+// WithLogFields returns the context with a logger having the given fields (key, value pairs, e.g., "nsId", nsId)
+func WithLogFields(ctx context.Context, keyValues ...string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logCtx := LoggerFromContext(ctx).With()
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		logCtx = logCtx.Str(keyValues[i], keyValues[i+1])
+	}
+	logger := logCtx.Logger()
+	return logger.WithContext(ctx)
+}
+
+// LoggerFromContext returns the logger of the context (the global logger if the context has none)
+func LoggerFromContext(ctx context.Context) *zerolog.Logger {
+	if ctx == nil {
+		return &log.Logger
+	}
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		return &log.Logger
+	}
+	return logger
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ShutdownReason is the reason recorded on objects which are interrupted by server shutdown
+const ShutdownReason = "server shutdown"
+
+// inFlightOperation is a long-running operation registered to the shutdown coordinator
+type inFlightOperation struct {
+	name       string
+	startTime  time.Time
+	checkpoint func(reason string)
+}
+
+var (
+	draining         atomic.Bool
+	operationSeq     atomic.Uint64
+	inFlightOpsMap   = sync.Map{} // operation id -> inFlightOperation
+	inFlightOpsCount atomic.Int64
+	drainingCh       = make(chan struct{})
+	drainingOnce     sync.Once
+
+	// operationIdleMu guards operationIdleCh and the changes of inFlightOpsCount between 0 and 1
+	operationIdleMu sync.Mutex
+	// operationIdleCh is closed while no operation is in flight (replaced when an operation begins after idle)
+	operationIdleCh = closedChannel()
+)
+
+// closedChannel returns a closed channel
+func closedChannel() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+// BeginOperation registers a long-running operation to the shutdown coordinator.
+// checkpoint (optional) is called with ShutdownReason if the operation is still running when the grace period expires,
+// so that the operation can leave its objects in a consistent state (e.g., mark them as failed).
+// The returned function must be called when the operation is finished.
+func BeginOperation(name string, checkpoint func(reason string)) func() {
+	id := operationSeq.Add(1)
+	inFlightOpsMap.Store(id, inFlightOperation{name: name, startTime: time.Now(), checkpoint: checkpoint})
+	operationIdleMu.Lock()
+	if inFlightOpsCount.Add(1) == 1 {
+		operationIdleCh = make(chan struct{})
+	}
+	operationIdleMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			inFlightOpsMap.Delete(id)
+			operationIdleMu.Lock()
+			if inFlightOpsCount.Add(-1) == 0 {
+				close(operationIdleCh)
+			}
+			operationIdleMu.Unlock()
+		})
+	}
+}
+
+// InFlightOperationCount returns the number of long-running operations in progress
+func InFlightOperationCount() int {
+	return int(inFlightOpsCount.Load())
+}
+
+// StartDraining makes the server stop accepting new mutating requests
+func StartDraining() {
+	draining.Store(true)
+	drainingOnce.Do(func() { close(drainingCh) })
+}
+
+// DrainingNotify returns a channel which is closed when the server starts draining
+// (long-lived streams should be closed so that the server can shut down)
+func DrainingNotify() <-chan struct{} {
+	return drainingCh
+}
+
+// IsDraining returns true if the server is shutting down
+func IsDraining() bool {
+	return draining.Load()
+}
+
+// WaitForOperations waits until all registered operations are finished or the grace period expires.
+// For the operations still running after the grace period, their checkpoint functions are called.
+// It returns the number of operations which were not finished.
+func WaitForOperations(gracePeriod time.Duration) int {
+	// operations may begin while waiting (e.g., jobs started before draining), so the idle channel is taken under the lock
+	operationIdleMu.Lock()
+	done := operationIdleCh
+	operationIdleMu.Unlock()
+
+	log.Info().Msgf("Waiting for %d in-flight operations to finish (grace period: %v)", InFlightOperationCount(), gracePeriod)
+	select {
+	case <-done:
+		log.Info().Msg("All in-flight operations are finished")
+		return 0
+	case <-time.After(gracePeriod):
+	}
+
+	remaining := 0
+	inFlightOpsMap.Range(func(key, value interface{}) bool {
+		op := value.(inFlightOperation)
+		remaining++
+		log.Warn().Msgf("Operation (%s, started at %s) is not finished within the grace period", op.name, op.startTime.Format(time.RFC3339))
+		if op.checkpoint != nil {
+			op.checkpoint(ShutdownReason)
+		}
+		return true
+	})
+	return remaining
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWaitForOperationsWhileBeginning checks that operations can begin while the shutdown waits for them
+func TestWaitForOperationsWhileBeginning(t *testing.T) {
+	endFirst := BeginOperation("first", nil)
+
+	var wg sync.WaitGroup
+	ends := make(chan func(), 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ends <- BeginOperation("concurrent", nil)
+		}()
+	}
+
+	result := make(chan int)
+	go func() { result <- WaitForOperations(5 * time.Second) }()
+
+	wg.Wait()
+	close(ends)
+	endFirst()
+	for end := range ends {
+		end()
+	}
+
+	select {
+	case remaining := <-result:
+		if remaining != 0 {
+			t.Errorf("%d operations remain", remaining)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("WaitForOperations did not return")
+	}
+	if InFlightOperationCount() != 0 {
+		t.Errorf("in-flight operations: %d", InFlightOperationCount())
+	}
+}
+
+func TestWaitForOperationsGracePeriod(t *testing.T) {
+	checkpointed := make(chan string, 1)
+	end := BeginOperation("slow", func(reason string) { checkpointed <- reason })
+	defer end()
+
+	if remaining := WaitForOperations(10 * time.Millisecond); remaining != 1 {
+		t.Errorf("remaining %d, want 1", remaining)
+	}
+	select {
+	case reason := <-checkpointed:
+		if reason != ShutdownReason {
+			t.Errorf("checkpoint reason %q", reason)
+		}
+	default:
+		t.Error("checkpoint was not called")
+	}
+}
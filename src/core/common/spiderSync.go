@@ -0,0 +1,216 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// RegionRegisterError is a region failed to be registered to CB-Spider
+type RegionRegisterError struct {
+	Region string `json:"region" example:"ap-northeast-2"`
+	Error  string `json:"error"`
+}
+
+// CloudInfoRegisterResult is the result of the registration of a provider (driver and regions) to CB-Spider
+type CloudInfoRegisterResult struct {
+	ProviderName string                `json:"providerName" example:"aws"`
+	DriverError  string                `json:"driverError,omitempty"`
+	Succeeded    []string              `json:"succeeded"`
+	Failed       []RegionRegisterError `json:"failed"`
+}
+
+// CloudInfoRegisterSummary is the result of the registration of all providers to CB-Spider
+type CloudInfoRegisterSummary struct {
+	Providers       []CloudInfoRegisterResult `json:"providers"`
+	FailedProviders int                       `json:"failedProviders"`
+}
+
+// BrokenConnection is a connection config which cannot be restored in CB-Spider
+type BrokenConnection struct {
+	ConfigName       string `json:"configName" example:"aws-ap-northeast-2"`
+	CredentialName   string `json:"credentialName" example:"aws"`
+	CredentialHolder string `json:"credentialHolder" example:"admin"`
+	Reason           string `json:"reason" example:"credential is not registered in CB-Spider (register the credential again)"`
+}
+
+// SpiderSyncReport is the result of the reconciliation of cloud info between CB-Tumblebug and CB-Spider
+type SpiderSyncReport struct {
+	// Drivers, Regions and ConnConfigs are the entries re-created in CB-Spider
+	Drivers     []string `json:"drivers"`
+	Regions     []string `json:"regions"`
+	ConnConfigs []string `json:"connConfigs"`
+	// BrokenConnections need credential re-registration (credentials hold secrets and are not kept by CB-Tumblebug)
+	BrokenConnections []BrokenConnection `json:"brokenConnections"`
+	// Failed lists the entries which could not be re-created
+	Failed []string `json:"failed"`
+}
+
+// spiderNameSet returns the set of names in the CB-Spider list (GET {SpiderRestUrl}/{kind})
+func spiderNameSet(kind string, nameField string) (map[string]bool, error) {
+	var callResult map[string][]map[string]interface{}
+	requestBody := NoBody
+	err := ExecuteHttpRequest(
+		resty.New(),
+		"GET",
+		model.SpiderRestUrl+"/"+kind,
+		nil,
+		SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		VeryShortDuration,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s of CB-Spider: %w", kind, err)
+	}
+	names := map[string]bool{}
+	for _, item := range callResult[kind] {
+		if name, ok := item[nameField].(string); ok {
+			names[name] = true
+		}
+	}
+	return names, nil
+}
+
+// findRegionOfRegionZone returns the region (key in cloud info) of the regionZone name ({provider}-{region}[-{zone}])
+func findRegionOfRegionZone(providerName string, regionZoneName string) (string, bool) {
+	cspDetail, ok := RuntimeCloudInfo.CSPs[providerName]
+	if !ok {
+		return "", false
+	}
+	for regionName, regionDetail := range cspDetail.Regions {
+		prefix := providerName + "-" + regionName
+		if strings.EqualFold(regionZoneName, prefix) {
+			return regionName, true
+		}
+		for _, zone := range regionDetail.Zones {
+			if strings.EqualFold(regionZoneName, prefix+"-"+zone) {
+				return regionName, true
+			}
+		}
+	}
+	return "", false
+}
+
+// SyncSpider compares the connection configs of CB-Tumblebug with the drivers, regions and connection configs of CB-Spider
+// and re-creates the missing entries (e.g., after CB-Spider is restarted with a fresh database).
+// Credentials cannot be restored; connections whose credential is missing are reported as broken.
+func SyncSpider() (SpiderSyncReport, error) {
+	report := SpiderSyncReport{Drivers: []string{}, Regions: []string{}, ConnConfigs: []string{}, BrokenConnections: []BrokenConnection{}, Failed: []string{}}
+
+	connections, err := GetConnConfigList("", false, false)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return report, err
+	}
+	if len(connections.Connectionconfig) == 0 {
+		return report, nil
+	}
+
+	drivers, err := spiderNameSet("driver", "DriverName")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return report, err
+	}
+	credentials, err := spiderNameSet("credential", "CredentialName")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return report, err
+	}
+	spiderConnConfigs, err := spiderNameSet("connectionconfig", "ConfigName")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return report, err
+	}
+	regionList, err := RetrieveRegionListFromCsp()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return report, err
+	}
+	regionZones := map[string]bool{}
+	for _, region := range regionList.Region {
+		regionZones[region.RegionName] = true
+	}
+
+	for _, connConfig := range connections.Connectionconfig {
+		providerName := strings.ToLower(connConfig.ProviderName)
+
+		// driver and regions of the provider
+		if !drivers[connConfig.DriverName] {
+			result, err := RegisterCloudInfo(providerName)
+			if result.DriverError != "" {
+				report.Failed = append(report.Failed, "driver:"+connConfig.DriverName)
+				log.Error().Err(err).Msgf("Failed to re-register the driver %s", connConfig.DriverName)
+			} else {
+				report.Drivers = append(report.Drivers, connConfig.DriverName)
+				for _, regionName := range result.Succeeded {
+					report.Regions = append(report.Regions, providerName+"-"+regionName)
+					regionZones[providerName+"-"+regionName] = true
+					for _, zone := range RuntimeCloudInfo.CSPs[providerName].Regions[regionName].Zones {
+						regionZones[providerName+"-"+regionName+"-"+zone] = true
+					}
+				}
+			}
+			drivers[connConfig.DriverName] = true
+		}
+		if !regionZones[connConfig.RegionZoneInfoName] {
+			regionName, ok := findRegionOfRegionZone(providerName, connConfig.RegionZoneInfoName)
+			if !ok {
+				report.Failed = append(report.Failed, "region:"+connConfig.RegionZoneInfoName)
+				log.Warn().Msgf("RegionZone %s is not found in cloud info", connConfig.RegionZoneInfoName)
+			} else if err := RegisterRegionZone(providerName, regionName); err != nil {
+				report.Failed = append(report.Failed, "region:"+connConfig.RegionZoneInfoName)
+				log.Error().Err(err).Msgf("Failed to re-register the region %s", regionName)
+			} else {
+				report.Regions = append(report.Regions, providerName+"-"+regionName)
+				regionZones[providerName+"-"+regionName] = true
+				for _, zone := range RuntimeCloudInfo.CSPs[providerName].Regions[regionName].Zones {
+					regionZones[providerName+"-"+regionName+"-"+zone] = true
+				}
+			}
+		}
+
+		// connection config (only if its credential still exists)
+		if !credentials[connConfig.CredentialName] {
+			report.BrokenConnections = append(report.BrokenConnections, BrokenConnection{
+				ConfigName:       connConfig.ConfigName,
+				CredentialName:   connConfig.CredentialName,
+				CredentialHolder: connConfig.CredentialHolder,
+				Reason:           "credential is not registered in CB-Spider (register the credential again)",
+			})
+			continue
+		}
+		if spiderConnConfigs[connConfig.ConfigName] {
+			continue
+		}
+		if _, err := postSpiderConnConfig(connConfig); err != nil {
+			report.Failed = append(report.Failed, "connConfig:"+connConfig.ConfigName)
+			continue
+		}
+		report.ConnConfigs = append(report.ConnConfigs, connConfig.ConfigName)
+	}
+
+	if len(report.BrokenConnections) > 0 {
+		log.Warn().Msgf("%d connection configs are broken; register their credentials again", len(report.BrokenConnections))
+	}
+	log.Info().Msgf("Synced cloud info with CB-Spider (drivers: %d, regions: %d, connConfigs: %d re-created; broken: %d, failed: %d)",
+		len(report.Drivers), len(report.Regions), len(report.ConnConfigs), len(report.BrokenConnections), len(report.Failed))
+	return report, nil
+}
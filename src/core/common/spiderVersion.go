@@ -0,0 +1,209 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	spiderVersionLock sync.RWMutex
+	spiderVersion     = model.SpiderVersionInfo{
+		MinVersion: model.SpiderMinVersion,
+		MaxVersion: model.SpiderMaxVersion,
+		Compatible: true,
+		Message:    "the version of CB-Spider is not detected yet",
+	}
+)
+
+// spiderVersionPattern matches a version (e.g., v0.9.7, 0.10.0-rc1, 0.9)
+var spiderVersionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// spiderVersionEndpoints are the endpoints of CB-Spider which may report its version (in the order of preference)
+var spiderVersionEndpoints = []string{"/version", "/readyz", "/endpointinfo"}
+
+// parseSpiderVersion returns the major, minor and patch numbers of the version in the text
+func parseSpiderVersion(text string) ([3]int, bool) {
+	m := spiderVersionPattern.FindStringSubmatch(text)
+	if m == nil {
+		return [3]int{}, false
+	}
+	v := [3]int{}
+	for i := 0; i < 3; i++ {
+		if m[i+1] != "" {
+			v[i], _ = strconv.Atoi(m[i+1])
+		}
+	}
+	return v, true
+}
+
+// compareSpiderVersion returns -1, 0 or 1 if the version a is lower than, equal to or higher than b
+func compareSpiderVersion(a [3]int, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// spiderVersionOf returns the version string of the numbers (e.g., 0.9.7)
+func spiderVersionOf(v [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// versionFromSpiderResponse finds the version in the response body of CB-Spider (a version field of JSON or the text)
+func versionFromSpiderResponse(body []byte) ([3]int, bool) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(body, &fields); err == nil {
+		for key, value := range fields {
+			if s, ok := value.(string); ok && strings.Contains(strings.ToLower(key), "version") {
+				if v, ok := parseSpiderVersion(s); ok {
+					return v, true
+				}
+			}
+		}
+		return [3]int{}, false
+	}
+	// text responses (e.g., endpointinfo) have the version after "version"
+	text := strings.ToLower(string(body))
+	if i := strings.Index(text, "version"); i >= 0 {
+		return parseSpiderVersion(text[i:])
+	}
+	return [3]int{}, false
+}
+
+// fetchSpiderVersion requests the endpoints of CB-Spider until one of them reports the version
+func fetchSpiderVersion() ([3]int, string, error) {
+	client := resty.New().SetTimeout(5 * time.Second)
+	var lastErr error
+	for _, endpoint := range spiderVersionEndpoints {
+		resp, err := client.R().Get(model.SpiderRestUrl + endpoint)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.IsError() {
+			lastErr = fmt.Errorf("%s: status %d", endpoint, resp.StatusCode())
+			continue
+		}
+		if v := resp.Header().Get("X-Spider-Version"); v != "" {
+			if version, ok := parseSpiderVersion(v); ok {
+				return version, endpoint, nil
+			}
+		}
+		if version, ok := versionFromSpiderResponse(resp.Body()); ok {
+			return version, endpoint, nil
+		}
+		lastErr = fmt.Errorf("%s: no version in the response", endpoint)
+	}
+	return [3]int{}, "", lastErr
+}
+
+// DetectSpiderVersion requests the version of CB-Spider, checks it against the supported range
+// (model.SpiderMinVersion <= version < model.SpiderMaxVersion) and enables the version-dependent request features.
+// A version out of the range is logged as a warning. The result is kept for GetSpiderVersionInfo.
+func DetectSpiderVersion() model.SpiderVersionInfo {
+	info := model.SpiderVersionInfo{
+		MinVersion:   model.SpiderMinVersion,
+		MaxVersion:   model.SpiderMaxVersion,
+		Compatible:   true,
+		Features:     map[string]bool{},
+		DetectedTime: time.Now(),
+	}
+
+	version, source, err := fetchSpiderVersion()
+	if err != nil {
+		// keep the request features enabled for the current target of CB-Spider if the version is unknown
+		info.Message = "failed to detect the version of CB-Spider: " + err.Error()
+		for feature := range model.SpiderFeatureMinVersions {
+			info.Features[feature] = true
+		}
+		log.Warn().Msgf("%s (version-dependent request fields are enabled)", info.Message)
+	} else {
+		info.Version = spiderVersionOf(version)
+		info.Source = source
+		minVersion, _ := parseSpiderVersion(model.SpiderMinVersion)
+		maxVersion, _ := parseSpiderVersion(model.SpiderMaxVersion)
+		if compareSpiderVersion(version, minVersion) < 0 || compareSpiderVersion(version, maxVersion) >= 0 {
+			info.Compatible = false
+			info.Message = fmt.Sprintf("CB-Spider %s is out of the supported range (>= %s, < %s); requests may be mismatched",
+				info.Version, model.SpiderMinVersion, model.SpiderMaxVersion)
+		}
+		for feature, featureVersion := range model.SpiderFeatureMinVersions {
+			v, _ := parseSpiderVersion(featureVersion)
+			info.Features[feature] = compareSpiderVersion(version, v) >= 0
+		}
+
+		if info.Compatible {
+			log.Info().Msgf("CB-Spider version: %s (supported: >= %s, < %s)", info.Version, model.SpiderMinVersion, model.SpiderMaxVersion)
+		} else {
+			log.Warn().Msg("**********************************************************************")
+			log.Warn().Msg("[CB-Spider version] " + info.Message)
+			log.Warn().Msgf("[CB-Spider version] request features: %v", info.Features)
+			log.Warn().Msg("**********************************************************************")
+		}
+	}
+
+	spiderVersionLock.Lock()
+	spiderVersion = info
+	spiderVersionLock.Unlock()
+	return GetSpiderVersionInfo()
+}
+
+// GetSpiderVersionInfo returns the version of CB-Spider detected by DetectSpiderVersion
+func GetSpiderVersionInfo() model.SpiderVersionInfo {
+	spiderVersionLock.RLock()
+	defer spiderVersionLock.RUnlock()
+	info := spiderVersion
+	info.Features = make(map[string]bool, len(spiderVersion.Features))
+	for feature, enabled := range spiderVersion.Features {
+		info.Features[feature] = enabled
+	}
+	return info
+}
+
+// IsSpiderFeatureSupported returns true if the request feature is supported by the detected version of CB-Spider
+// (true if the version is not detected)
+func IsSpiderFeatureSupported(feature string) bool {
+	spiderVersionLock.RLock()
+	defer spiderVersionLock.RUnlock()
+	if spiderVersion.Version == "" {
+		return true
+	}
+	return spiderVersion.Features[feature]
+}
+
+// CheckSpiderFeature returns PreconditionFailed error if the request feature is not supported by the detected version of CB-Spider
+func CheckSpiderFeature(feature string, operation string) error {
+	if IsSpiderFeatureSupported(feature) {
+		return nil
+	}
+	return NewPreconditionFailedError("%s requires %s of CB-Spider %s or later (detected: %s)",
+		operation, feature, model.SpiderFeatureMinVersions[feature], GetSpiderVersionInfo().Version)
+}
@@ -0,0 +1,186 @@
+package common
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+func TestParseSpiderVersion(t *testing.T) {
+	tests := []struct {
+		text    string
+		version [3]int
+		ok      bool
+	}{
+		{"v0.9.7", [3]int{0, 9, 7}, true},
+		{"0.10.0-rc1", [3]int{0, 10, 0}, true},
+		{"0.9", [3]int{0, 9, 0}, true},
+		{"CB-Spider v0.9.12 (build 20240410)", [3]int{0, 9, 12}, true},
+		{"latest", [3]int{}, false},
+		{"", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		version, ok := parseSpiderVersion(tt.text)
+		if ok != tt.ok || version != tt.version {
+			t.Errorf("parseSpiderVersion(%q) = %v, %v, want %v, %v", tt.text, version, ok, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestCompareSpiderVersion(t *testing.T) {
+	tests := []struct {
+		a, b [3]int
+		want int
+	}{
+		{[3]int{0, 9, 7}, [3]int{0, 9, 7}, 0},
+		{[3]int{0, 9, 7}, [3]int{0, 9, 10}, -1},
+		{[3]int{0, 10, 0}, [3]int{0, 9, 12}, 1},
+		{[3]int{1, 0, 0}, [3]int{0, 99, 99}, 1},
+		{[3]int{0, 8, 99}, [3]int{0, 9, 0}, -1},
+	}
+	for _, tt := range tests {
+		if got := compareSpiderVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareSpiderVersion(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersionFromSpiderResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		version [3]int
+		ok      bool
+	}{
+		{"json version field", `{"version":"v0.9.7"}`, [3]int{0, 9, 7}, true},
+		{"json field containing version", `{"message":"ready","SpiderVersion":"0.9.4"}`, [3]int{0, 9, 4}, true},
+		{"json without version", `{"message":"CB-Spider is ready"}`, [3]int{}, false},
+		{"json version not a string", `{"version":97}`, [3]int{}, false},
+		{"text after version", "<h1>CB-Spider</h1>\nVersion: 0.9.12\nAPI: /spider", [3]int{0, 9, 12}, true},
+		// the numbers before "version" (e.g., an address) are not the version
+		{"text with numbers before version", "listening on 10.0.0.1:1024 version v0.9.3", [3]int{0, 9, 3}, true},
+		{"text without version", "CB-Spider 0.9.7", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, ok := versionFromSpiderResponse([]byte(tt.body))
+			if ok != tt.ok || version != tt.version {
+				t.Errorf("got %v, %v, want %v, %v", version, ok, tt.version, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFetchSpiderVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]func(w http.ResponseWriter) // by the path of CB-Spider (others: 404)
+		version   [3]int
+		source    string
+		fails     bool
+	}{
+		{
+			name: "version endpoint",
+			responses: map[string]func(w http.ResponseWriter){
+				"/spider/version": func(w http.ResponseWriter) { w.Write([]byte(`{"version":"0.9.7"}`)) },
+			},
+			version: [3]int{0, 9, 7}, source: "/version",
+		},
+		{
+			name: "header of readyz",
+			responses: map[string]func(w http.ResponseWriter){
+				"/spider/readyz": func(w http.ResponseWriter) {
+					w.Header().Set("X-Spider-Version", "v0.9.4")
+					w.Write([]byte(`{"message":"CB-Spider is ready"}`))
+				},
+			},
+			version: [3]int{0, 9, 4}, source: "/readyz",
+		},
+		{
+			name: "text of endpointinfo",
+			responses: map[string]func(w http.ResponseWriter){
+				"/spider/readyz":       func(w http.ResponseWriter) { w.Write([]byte(`{"message":"CB-Spider is ready"}`)) },
+				"/spider/endpointinfo": func(w http.ResponseWriter) { w.Write([]byte("CB-Spider\n - Version: v0.9.12\n - REST API: /spider")) },
+			},
+			version: [3]int{0, 9, 12}, source: "/endpointinfo",
+		},
+		{
+			name: "no version",
+			responses: map[string]func(w http.ResponseWriter){
+				"/spider/readyz": func(w http.ResponseWriter) { w.Write([]byte(`{"message":"CB-Spider is ready"}`)) },
+			},
+			fails: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestSpiderServer(t, func(w http.ResponseWriter, r *http.Request) {
+				respond, ok := tt.responses[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				respond(w)
+			})
+			version, source, err := fetchSpiderVersion()
+			if tt.fails {
+				if err == nil {
+					t.Errorf("expected an error, got %v from %s", version, source)
+				}
+				return
+			}
+			if err != nil || version != tt.version || source != tt.source {
+				t.Errorf("got %v from %s (%v), want %v from %s", version, source, err, tt.version, tt.source)
+			}
+		})
+	}
+}
+
+func TestDetectSpiderVersion(t *testing.T) {
+	prev := GetSpiderVersionInfo()
+	t.Cleanup(func() {
+		spiderVersionLock.Lock()
+		spiderVersion = prev
+		spiderVersionLock.Unlock()
+	})
+
+	tests := []struct {
+		version    string
+		compatible bool
+		features   map[string]bool
+	}{
+		{"0.9.7", true, map[string]bool{model.SpiderFeatureIdTransformMode: true, model.SpiderFeatureTagList: true}},
+		{"0.9.1", true, map[string]bool{model.SpiderFeatureIdTransformMode: true, model.SpiderFeatureTagList: false}},
+		{"0.8.9", false, map[string]bool{model.SpiderFeatureIdTransformMode: false, model.SpiderFeatureTagList: false}},
+		{"0.10.0", false, map[string]bool{model.SpiderFeatureIdTransformMode: true, model.SpiderFeatureTagList: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			setTestSpiderServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"version":"v` + tt.version + `"}`))
+			})
+			info := DetectSpiderVersion()
+			if info.Version != tt.version || info.Compatible != tt.compatible {
+				t.Errorf("got %s (compatible %v), want %s (compatible %v)", info.Version, info.Compatible, tt.version, tt.compatible)
+			}
+			for feature, want := range tt.features {
+				if info.Features[feature] != want || IsSpiderFeatureSupported(feature) != want {
+					t.Errorf("feature %s: %v, want %v", feature, info.Features[feature], want)
+				}
+				if err := CheckSpiderFeature(feature, "test"); (err == nil) != want {
+					t.Errorf("CheckSpiderFeature(%s): %v", feature, err)
+				}
+			}
+		})
+	}
+
+	// all features are enabled if the version is not detected
+	setTestSpiderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	info := DetectSpiderVersion()
+	if info.Version != "" || !IsSpiderFeatureSupported(model.SpiderFeatureTagList) {
+		t.Errorf("got %+v, want all features enabled without a version", info)
+	}
+}
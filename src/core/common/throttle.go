@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// defaultProviderRateLimits are the default calls per second to CB-Spider for each provider
+// (CB-Spider makes one or more CSP API calls per call, so they are below the API quotas of the CSPs:
+// AWS EC2 refills 20 requests/s for describe calls, Azure ARM allows about 3 reads/s per subscription
+// on average (with bursts), and GCP Compute allows 20 requests/s per project)
+var defaultProviderRateLimits = map[string]int{
+	"aws":   10,
+	"azure": 5,
+	"gcp":   10,
+}
+
+// WithProvider makes the call to CB-Spider wait for the rate limit of the provider (see ProviderRateLimit)
+func WithProvider(providerName string) HttpRequestOption {
+	return func(o *httpRequestOptions) {
+		o.provider = strings.ToLower(providerName)
+	}
+}
+
+// WithConnectionProvider is WithProvider for the provider of the connection (no rate limit if the connection is not found)
+func WithConnectionProvider(connectionName string) HttpRequestOption {
+	providerName := ""
+	if connConfig, err := GetConnConfig(connectionName); err == nil {
+		providerName = connConfig.ProviderName
+	}
+	return WithProvider(providerName)
+}
+
+// ProviderRateLimit returns the calls per second to CB-Spider for the provider
+// (TB_SPIDER_RATE_LIMIT_{PROVIDER}, e.g., TB_SPIDER_RATE_LIMIT_AWS; TB_SPIDER_RATE_LIMIT_DEFAULT for providers
+// without a default, which is 0). 0 disables the rate limit.
+func ProviderRateLimit(providerName string) int {
+	defaultLimit, ok := defaultProviderRateLimits[providerName]
+	if !ok {
+		defaultLimit = envInt("TB_SPIDER_RATE_LIMIT_DEFAULT", 0)
+	}
+	return envInt("TB_SPIDER_RATE_LIMIT_"+strings.ToUpper(providerName), defaultLimit)
+}
+
+// throttleMaxWait returns how long a call waits for the rate limit before it fails (TB_SPIDER_THROTTLE_MAX_WAIT_SEC, default 60)
+func throttleMaxWait() time.Duration {
+	return time.Duration(envInt("TB_SPIDER_THROTTLE_MAX_WAIT_SEC", 60)) * time.Second
+}
+
+// tokenBucket is a token bucket of calls to CB-Spider for a provider.
+// Tokens are refilled at rate per second up to burst (2 seconds of calls).
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     int
+	tokens   float64
+	lastFill time.Time
+}
+
+// providerBuckets keeps the token buckets by provider
+var providerBuckets = sync.Map{}
+
+func getTokenBucket(providerName string, rate int) *tokenBucket {
+	b, _ := providerBuckets.LoadOrStore(providerName, &tokenBucket{rate: rate, tokens: float64(2 * rate), lastFill: time.Now()})
+	bucket := b.(*tokenBucket)
+	bucket.mu.Lock()
+	bucket.rate = rate // the limit may be changed at runtime
+	bucket.mu.Unlock()
+	return bucket
+}
+
+// reserve takes a token and returns how long the caller must wait for it.
+// The token is not taken if the wait would exceed maxWait.
+func (b *tokenBucket) reserve(maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	burst := float64(2 * b.rate)
+	b.tokens += now.Sub(b.lastFill).Seconds() * float64(b.rate)
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.lastFill = now
+
+	wait := time.Duration(0)
+	if b.tokens < 1 {
+		wait = time.Duration((1 - b.tokens) / float64(b.rate) * float64(time.Second))
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+	// the token may be negative: the next callers wait for the calls reserved before them
+	b.tokens--
+	return wait, true
+}
+
+// waitProviderThrottle waits for the rate limit of the provider before a call to CB-Spider.
+// It returns ApiError (RateLimited) if the call would wait longer than TB_SPIDER_THROTTLE_MAX_WAIT_SEC.
+func waitProviderThrottle(providerName string) error {
+	if providerName == "" {
+		return nil
+	}
+	rate := ProviderRateLimit(providerName)
+	if rate <= 0 {
+		return nil
+	}
+
+	maxWait := throttleMaxWait()
+	wait, ok := getTokenBucket(providerName, rate).reserve(maxWait)
+	if !ok {
+		metrics.ObserveSpiderThrottle(providerName, "timeout", 0)
+		return &ApiError{
+			Code:    model.ErrCodeRateLimited,
+			Message: fmt.Sprintf("calls to %s are throttled by the rate limit (%d calls/s); the call would wait %v (max %v)", providerName, rate, wait.Round(time.Second), maxWait),
+			Details: map[string]interface{}{"provider": providerName, "rateLimit": rate},
+		}
+	}
+	if wait > 0 {
+		metrics.ObserveSpiderThrottle(providerName, "waited", wait)
+		time.Sleep(wait)
+		return nil
+	}
+	metrics.ObserveSpiderThrottle(providerName, "passed", 0)
+	return nil
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// SoftDeleteDefault returns true if DELETE of MCIs and resources moves them to the trash by default
+// (TB_SOFT_DELETE, default false). ?permanent=true always deletes them permanently.
+func SoftDeleteDefault() bool {
+	return strings.EqualFold(os.Getenv("TB_SOFT_DELETE"), "true")
+}
+
+// UseSoftDelete returns true if a DELETE request moves the object to the trash
+// (permanent=true or false in the request overrides SoftDeleteDefault)
+func UseSoftDelete(permanent string) bool {
+	switch strings.ToLower(permanent) {
+	case "true":
+		return false
+	case "false":
+		return true
+	}
+	return SoftDeleteDefault()
+}
+
+// TrashRetention returns how long a trash item is kept before it is purged (TB_TRASH_RETENTION_HOURS, default 72)
+func TrashRetention() time.Duration {
+	hours, err := strconv.Atoi(NVL(os.Getenv("TB_TRASH_RETENTION_HOURS"), "72"))
+	if err != nil || hours <= 0 {
+		hours = 72
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// GenTrashKey is func to generate the key of a trash item
+func GenTrashKey(nsId string, itemId string) string {
+	return "/trash/" + nsId + "/" + itemId
+}
+
+// isTrashObjectKey returns true if the key is the object or one of its children
+func isTrashObjectKey(key string, resourceKey string) bool {
+	return key == resourceKey || strings.HasPrefix(key, resourceKey+"/")
+}
+
+// SaveToTrash stores the metadata (Key-Value records and labels) of the object and its children as a trash item
+// without deleting them. Records whose key matches exclude (if given) are not stored.
+func SaveToTrash(nsId string, resourceType string, resourceId string, resourceKey string, cspResourceKept bool, exclude func(key string) bool) (model.TrashItem, error) {
+	now := time.Now().UTC()
+	item := model.TrashItem{
+		Id:              GenUid(),
+		NsId:            nsId,
+		ResourceType:    resourceType,
+		ResourceId:      resourceId,
+		ResourceKey:     resourceKey,
+		DeletedAt:       now,
+		ExpiresAt:       now.Add(TrashRetention()),
+		CspResourceKept: cspResourceKept,
+		Objects:         []model.NsExportKv{},
+		Labels:          []model.NsExportKv{},
+	}
+
+	keyValue, err := kvstore.GetKvList(resourceKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return item, err
+	}
+	for _, kv := range keyValue {
+		if !isTrashObjectKey(kv.Key, resourceKey) || (exclude != nil && exclude(kv.Key)) {
+			continue
+		}
+		if !json.Valid([]byte(kv.Value)) {
+			log.Warn().Msgf("Skip %s in the trash (not a JSON value)", kv.Key)
+			continue
+		}
+		item.Objects = append(item.Objects, model.NsExportKv{Key: kv.Key, Value: json.RawMessage(kv.Value)})
+	}
+	if len(item.Objects) == 0 {
+		return item, NewResourceNotFoundError(resourceType, resourceId)
+	}
+
+	labelKeyValue, err := kvstore.GetKvList("/label/")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return item, err
+	}
+	for _, kv := range labelKeyValue {
+		labelInfo := model.LabelInfo{}
+		if err := json.Unmarshal([]byte(kv.Value), &labelInfo); err != nil {
+			continue
+		}
+		if isTrashObjectKey(labelInfo.ResourceKey, resourceKey) && (exclude == nil || !exclude(labelInfo.ResourceKey)) {
+			item.Labels = append(item.Labels, model.NsExportKv{Key: kv.Key, Value: json.RawMessage(kv.Value)})
+		}
+	}
+
+	val, err := json.Marshal(item)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return item, err
+	}
+	if err := kvstore.Put(GenTrashKey(nsId, item.Id), string(val)); err != nil {
+		log.Error().Err(err).Msg("")
+		return item, err
+	}
+	log.Info().Msgf("Moved %s %s to the trash (%s, %d objects, %d labels)", resourceType, resourceId, item.Id, len(item.Objects), len(item.Labels))
+	return item, nil
+}
+
+// MoveToTrash stores the metadata of the object and its children as a trash item and deletes them
+func MoveToTrash(nsId string, resourceType string, resourceId string, resourceKey string, cspResourceKept bool) (model.TrashItem, error) {
+	item, err := SaveToTrash(nsId, resourceType, resourceId, resourceKey, cspResourceKept, nil)
+	if err != nil {
+		return item, err
+	}
+	for _, kv := range item.Labels {
+		if err := kvstore.Delete(kv.Key); err != nil {
+			log.Error().Err(err).Msg("")
+		}
+	}
+	for _, kv := range item.Objects {
+		if err := kvstore.Delete(kv.Key); err != nil {
+			log.Error().Err(err).Msg("")
+			return item, err
+		}
+	}
+	return item, nil
+}
+
+// GetTrashItem returns the trash item (with its objects and labels)
+func GetTrashItem(nsId string, itemId string) (model.TrashItem, error) {
+	item := model.TrashItem{}
+	if err := CheckString(nsId); err != nil {
+		return item, err
+	}
+	value, err := kvstore.Get(GenTrashKey(nsId, itemId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return item, err
+	}
+	if value == "" {
+		return item, NewResourceNotFoundError("trash item", itemId)
+	}
+	if err := json.Unmarshal([]byte(value), &item); err != nil {
+		log.Error().Err(err).Msg("")
+		return item, err
+	}
+	return item, nil
+}
+
+// ListTrashItems returns the trash items of the namespace (all namespaces if nsId is empty), oldest first.
+// Objects and labels of the items are omitted.
+func ListTrashItems(nsId string) ([]model.TrashItem, error) {
+	items := []model.TrashItem{}
+	prefix := "/trash/"
+	if nsId != "" {
+		if err := CheckString(nsId); err != nil {
+			return items, err
+		}
+		prefix += nsId + "/"
+	}
+	keyValue, err := kvstore.GetKvList(prefix)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return items, err
+	}
+	for _, kv := range keyValue {
+		item := model.TrashItem{}
+		if err := json.Unmarshal([]byte(kv.Value), &item); err != nil {
+			continue
+		}
+		item.Objects = nil
+		item.Labels = nil
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].DeletedAt.Before(items[j].DeletedAt) })
+	return items, nil
+}
+
+// DeleteTrashItem removes the trash item
+func DeleteTrashItem(nsId string, itemId string) error {
+	err := kvstore.Delete(GenTrashKey(nsId, itemId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+	return err
+}
+
+// TrashRestoreId returns the id to restore the item: the original id if it is free, otherwise id-1, id-2, ...
+func TrashRestoreId(item model.TrashItem, exists func(id string) (bool, error)) (string, error) {
+	taken, err := exists(item.ResourceId)
+	if err != nil {
+		return "", err
+	}
+	if !taken {
+		return item.ResourceId, nil
+	}
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", item.ResourceId, i)
+		taken, err := exists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+}
+
+// RestoreTrashObjects puts the Key-Value records and labels of the trash item back under the id.
+// If the id differs from the original id, the keys, the id of the object and the labels referring to it are rewritten.
+// It returns the key of the restored object and the number of restored records.
+func RestoreTrashObjects(item model.TrashItem, id string) (string, int, error) {
+	resourceKey := item.ResourceKey
+	if id != item.ResourceId {
+		resourceKey = strings.TrimSuffix(item.ResourceKey, item.ResourceId) + id
+	}
+	rewriteKey := func(key string) string {
+		return resourceKey + strings.TrimPrefix(key, item.ResourceKey)
+	}
+
+	restored := 0
+	for _, kv := range item.Objects {
+		value := []byte(kv.Value)
+		if kv.Key == item.ResourceKey && id != item.ResourceId {
+			obj := map[string]interface{}{}
+			if err := json.Unmarshal(value, &obj); err == nil {
+				obj["id"] = id
+				if name, ok := obj["name"].(string); ok && name == item.ResourceId {
+					obj["name"] = id
+				}
+				value, _ = json.Marshal(obj)
+			}
+		}
+		if err := kvstore.Put(rewriteKey(kv.Key), string(value)); err != nil {
+			log.Error().Err(err).Msg("")
+			return resourceKey, restored, err
+		}
+		restored++
+	}
+
+	for _, kv := range item.Labels {
+		labelInfo := model.LabelInfo{}
+		if err := json.Unmarshal([]byte(kv.Value), &labelInfo); err != nil {
+			continue
+		}
+		if id != item.ResourceId {
+			isRoot := labelInfo.ResourceKey == item.ResourceKey
+			labelInfo.ResourceKey = rewriteKey(labelInfo.ResourceKey)
+			for k, v := range labelInfo.Labels {
+				if v != item.ResourceId {
+					continue
+				}
+				if k == model.LabelMciId || k == model.LabelVNetId || (isRoot && (k == model.LabelId || k == model.LabelName)) {
+					labelInfo.Labels[k] = id
+				}
+			}
+		}
+		val, _ := json.Marshal(labelInfo)
+		if err := kvstore.Put(kv.Key, string(val)); err != nil {
+			log.Error().Err(err).Msg("")
+			return resourceKey, restored, err
+		}
+	}
+	return resourceKey, restored, nil
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUpstreamUnavailable is matched (errors.Is) by the error returned when the circuit of an upstream is open
+var ErrUpstreamUnavailable = errors.New("upstream is unavailable")
+
+// UpstreamUnavailableError is returned by ExecuteHttpRequest without calling the upstream
+// while its circuit breaker is open after consecutive failures
+type UpstreamUnavailableError struct {
+	Upstream   string // the key of the circuit breaker (see circuitBreakerKey)
+	RetryAfter time.Duration
+}
+
+func (e *UpstreamUnavailableError) Error() string {
+	return fmt.Sprintf("upstream (%s) is unavailable after consecutive failures; retry after %v", e.Upstream, e.RetryAfter.Round(time.Second))
+}
+
+// Is makes errors.Is(err, ErrUpstreamUnavailable) true
+func (e *UpstreamUnavailableError) Is(target error) bool {
+	return target == ErrUpstreamUnavailable
+}
+
+// RetryPolicy is the retry policy of an HTTP request to an upstream
+type RetryPolicy struct {
+	MaxAttempts        int           // total number of attempts (1 disables retry)
+	Backoff            time.Duration // wait before the 2nd attempt (doubled for each next attempt)
+	MaxBackoff         time.Duration // upper bound of the wait
+	NonIdempotent      bool          // retry POST as well (only idempotent methods are retried by default)
+	RetryOnServerError bool          // retry on 500 as well as 502, 503 and 504
+}
+
+// httpRequestOptions are the per-call options of ExecuteHttpRequest
+type httpRequestOptions struct {
+	retry          RetryPolicy
+	circuitBreaker bool
+	ctx            context.Context
+	// provider is the provider of the connection of the call to CB-Spider (for the rate limit)
+	provider string
+	// skipConnConfigHealth disables recording an auth error of the call in the connection config
+	skipConnConfigHealth bool
+}
+
+// HttpRequestOption is a per-call option of ExecuteHttpRequest
+type HttpRequestOption func(*httpRequestOptions)
+
+// WithRetryPolicy overrides the default retry policy of the call
+func WithRetryPolicy(policy RetryPolicy) HttpRequestOption {
+	return func(o *httpRequestOptions) {
+		o.retry = policy
+	}
+}
+
+// WithoutRetry disables retry of the call (e.g., for an operation which must not be repeated)
+func WithoutRetry() HttpRequestOption {
+	return func(o *httpRequestOptions) {
+		o.retry.MaxAttempts = 1
+	}
+}
+
+// WithoutCircuitBreaker makes the call bypass the circuit breaker (e.g., for a health check of the upstream)
+func WithoutCircuitBreaker() HttpRequestOption {
+	return func(o *httpRequestOptions) {
+		o.circuitBreaker = false
+	}
+}
+
+// WithRequestContext propagates the request ID of the context to the upstream (X-Request-Id header)
+// and logs the call with the logger of the context. The call is not canceled with the context.
+func WithRequestContext(ctx context.Context) HttpRequestOption {
+	return func(o *httpRequestOptions) {
+		o.ctx = ctx
+	}
+}
+
+// envInt returns the integer value of the environment variable or the default value
+func envInt(key string, defaultValue int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+// DefaultRetryPolicy returns the retry policy configured by TB_HTTP_RETRY_ATTEMPTS (default 3),
+// TB_HTTP_RETRY_BACKOFF_MS (default 500) and TB_HTTP_RETRY_MAX_BACKOFF_MS (default 5000)
+func DefaultRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts: envInt("TB_HTTP_RETRY_ATTEMPTS", 3),
+		Backoff:     time.Duration(envInt("TB_HTTP_RETRY_BACKOFF_MS", 500)) * time.Millisecond,
+		MaxBackoff:  time.Duration(envInt("TB_HTTP_RETRY_MAX_BACKOFF_MS", 5000)) * time.Millisecond,
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return policy
+}
+
+func newHttpRequestOptions(opts []HttpRequestOption) httpRequestOptions {
+	o := httpRequestOptions{retry: DefaultRetryPolicy(), circuitBreaker: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// isIdempotentMethod returns true for the HTTP methods which can be repeated safely
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isUpstreamFailure returns true if the result means the upstream is unavailable
+// (a transport error or a gateway status) rather than the request is rejected
+func isUpstreamFailure(err error, statusCode int) bool {
+	if err != nil {
+		return true
+	}
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// ShouldRetry returns true if the attempt (starts from 1) should be retried by the policy
+func (p RetryPolicy) ShouldRetry(method string, attempt int, err error, statusCode int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if !p.NonIdempotent && !isIdempotentMethod(method) {
+		return false
+	}
+	if isUpstreamFailure(err, statusCode) || statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return p.RetryOnServerError && statusCode == http.StatusInternalServerError
+}
+
+// Wait returns the wait before the next attempt (attempt starts from 1)
+func (p RetryPolicy) Wait(attempt int) time.Duration {
+	d := p.Backoff << (attempt - 1)
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// circuitBreaker is a circuit breaker of an upstream endpoint (see circuitBreakerKey)
+type circuitBreaker struct {
+	mu               sync.Mutex
+	consecutiveFails int
+	openUntil        time.Time
+	halfOpenTrial    bool
+}
+
+// circuitBreakers keeps the circuit breakers by circuitBreakerKey
+var circuitBreakers = sync.Map{}
+
+// circuitThreshold returns the number of consecutive failures which opens the circuit (TB_HTTP_CIRCUIT_THRESHOLD, default 5, 0 disables)
+func circuitThreshold() int {
+	return envInt("TB_HTTP_CIRCUIT_THRESHOLD", 5)
+}
+
+// circuitOpenDuration returns how long the circuit stays open (TB_HTTP_CIRCUIT_OPEN_SEC, default 30)
+func circuitOpenDuration() time.Duration {
+	return time.Duration(envInt("TB_HTTP_CIRCUIT_OPEN_SEC", 30)) * time.Second
+}
+
+// upstreamBaseUrl returns scheme://host of the URL
+func upstreamBaseUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return rawUrl
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// circuitBreakerKey returns the key of the circuit breaker of the call.
+// Calls to CB-Spider are keyed by the endpoint (e.g., http://localhost:1024/spider/vm) and the provider of the connection
+// (e.g., http://localhost:1024/spider/vm@aws) so that failures of a provider or an endpoint do not block the others.
+// Calls to other upstreams are keyed by the base URL (scheme://host).
+func circuitBreakerKey(rawUrl string, provider string) string {
+	endpoint := spiderEndpoint(rawUrl)
+	if endpoint == "" {
+		return upstreamBaseUrl(rawUrl)
+	}
+	key := strings.TrimSuffix(model.SpiderRestUrl, "/") + endpoint
+	if provider != "" {
+		key += "@" + provider
+	}
+	return key
+}
+
+func getCircuitBreaker(key string) *circuitBreaker {
+	cb, _ := circuitBreakers.LoadOrStore(key, &circuitBreaker{})
+	return cb.(*circuitBreaker)
+}
+
+// allow returns nil if a call can be made. While the circuit is open, only one trial call is allowed
+// after the open duration (half-open); the others fail fast with UpstreamUnavailableError.
+func (cb *circuitBreaker) allow(key string) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.openUntil.IsZero() {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(cb.openUntil) || cb.halfOpenTrial {
+		retryAfter := cb.openUntil.Sub(now)
+		if retryAfter < 0 {
+			retryAfter = 0
+		}
+		return &UpstreamUnavailableError{Upstream: key, RetryAfter: retryAfter}
+	}
+	cb.halfOpenTrial = true
+	return nil
+}
+
+// record updates the circuit with the result of a call
+func (cb *circuitBreaker) record(key string, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if !failed {
+		if !cb.openUntil.IsZero() {
+			log.Info().Msgf("Circuit of upstream (%s) is closed", key)
+		}
+		cb.consecutiveFails = 0
+		cb.openUntil = time.Time{}
+		cb.halfOpenTrial = false
+		metrics.SetUpstreamCircuitOpen(key, false)
+		return
+	}
+
+	cb.consecutiveFails++
+	threshold := circuitThreshold()
+	if threshold <= 0 {
+		return
+	}
+	if cb.halfOpenTrial || cb.consecutiveFails >= threshold {
+		cb.openUntil = time.Now().Add(circuitOpenDuration())
+		cb.halfOpenTrial = false
+		log.Warn().Msgf("Circuit of upstream (%s) is open for %v after %d consecutive failures", key, circuitOpenDuration(), cb.consecutiveFails)
+		metrics.SetUpstreamCircuitOpen(key, true)
+	}
+}
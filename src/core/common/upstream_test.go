@@ -0,0 +1,143 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/go-resty/resty/v2"
+)
+
+// setTestSpiderServer points the CB-Spider URL to the test server
+func setTestSpiderServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	prev := model.SpiderRestUrl
+	model.SpiderRestUrl = server.URL + "/spider"
+	t.Cleanup(func() {
+		model.SpiderRestUrl = prev
+		server.Close()
+	})
+	return server
+}
+
+// resetCircuitBreakers removes the circuit breakers so that the test starts with closed circuits
+func resetCircuitBreakers(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		circuitBreakers.Range(func(key, _ interface{}) bool {
+			circuitBreakers.Delete(key)
+			return true
+		})
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestCircuitBreakerKey(t *testing.T) {
+	prev := model.SpiderRestUrl
+	model.SpiderRestUrl = "http://localhost:1024/spider"
+	defer func() { model.SpiderRestUrl = prev }()
+
+	tests := []struct {
+		url      string
+		provider string
+		want     string
+	}{
+		{"http://localhost:1024/spider/vm/vm01?ConnectionName=aws-ap-northeast-2", "aws", "http://localhost:1024/spider/vm@aws"},
+		{"http://localhost:1024/spider/vm/vm01", "", "http://localhost:1024/spider/vm"},
+		{"http://localhost:1024/spider/vpc", "aws", "http://localhost:1024/spider/vpc@aws"},
+		{"http://example.com:8080/api/v1/items", "", "http://example.com:8080"},
+	}
+	for _, tt := range tests {
+		if got := circuitBreakerKey(tt.url, tt.provider); got != tt.want {
+			t.Errorf("circuitBreakerKey(%s, %s) = %s, want %s", tt.url, tt.provider, got, tt.want)
+		}
+	}
+}
+
+// TestCircuitBreakerPerProviderAndEndpoint checks that failures of a provider (or an endpoint) of CB-Spider
+// open only its circuit and do not block the calls for the other providers and endpoints
+func TestCircuitBreakerPerProviderAndEndpoint(t *testing.T) {
+	t.Setenv("TB_HTTP_CIRCUIT_THRESHOLD", "2")
+	t.Setenv("TB_HTTP_CIRCUIT_OPEN_SEC", "60")
+	resetCircuitBreakers(t)
+
+	var calls int32
+	setTestSpiderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if strings.HasPrefix(r.URL.Path, "/spider/vm") && r.URL.Query().Get("ConnectionName") == "openstack-region01" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+
+	call := func(path string, provider string) error {
+		var body interface{}
+		result := map[string]interface{}{}
+		return ExecuteHttpRequest(resty.New(), http.MethodPost, model.SpiderRestUrl+path, nil, false, &body, &result, 0,
+			WithoutRetry(), WithProvider(provider))
+	}
+
+	failing := "/vm?ConnectionName=openstack-region01"
+	for i := 0; i < 2; i++ {
+		if err := call(failing, "openstack"); err == nil || errors.Is(err, ErrUpstreamUnavailable) {
+			t.Fatalf("call %d: expected the upstream error, got %v", i, err)
+		}
+	}
+	before := atomic.LoadInt32(&calls)
+	if err := call(failing, "openstack"); !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("expected the open circuit, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != before {
+		t.Errorf("the call was made while the circuit is open")
+	}
+
+	// the other provider and the other endpoint of the provider are not blocked
+	if err := call("/vm?ConnectionName=ncp-region01", "ncp"); err != nil {
+		t.Errorf("the call for the other provider is blocked: %v", err)
+	}
+	if err := call("/vpc?ConnectionName=openstack-region01", "openstack"); err != nil {
+		t.Errorf("the call to the other endpoint is blocked: %v", err)
+	}
+}
+
+func TestCircuitBreakerClosedAfterSuccess(t *testing.T) {
+	t.Setenv("TB_HTTP_CIRCUIT_THRESHOLD", "2")
+	resetCircuitBreakers(t)
+
+	var fail atomic.Bool
+	fail.Store(true)
+	setTestSpiderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+
+	call := func() error {
+		var body interface{}
+		result := map[string]interface{}{}
+		return ExecuteHttpRequest(resty.New(), http.MethodPost, model.SpiderRestUrl+"/vm", nil, false, &body, &result, 0, WithoutRetry())
+	}
+	call()
+	fail.Store(false)
+	// a success resets the consecutive failures
+	if err := call(); err != nil {
+		t.Fatal(err)
+	}
+	fail.Store(true)
+	call()
+	fail.Store(false)
+	if err := call(); err != nil {
+		t.Errorf("the circuit is open after non-consecutive failures: %v", err)
+	}
+}
@@ -15,9 +15,11 @@ limitations under the License.
 package common
 
 import (
+	"context"
 	"math/rand"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -53,30 +55,35 @@ func GenUid() string {
 	return uid.New().String()
 }
 
-// GenRandomPassword is func to return a RandomPassword
-func GenRandomPassword(length int) string {
-	rand.Seed(time.Now().Unix())
-
-	charset := "A1!$"
-	shuff := []rune(charset)
-	rand.Shuffle(len(shuff), func(i, j int) {
-		shuff[i], shuff[j] = shuff[j], shuff[i]
-	})
-	randomString := GenUid()
-	if len(randomString) < length {
-		randomString = randomString + GenUid()
-	}
-	reducedString := randomString[0 : length-len(charset)]
-	reducedString = reducedString + string(shuff)
-
-	shuff = []rune(reducedString)
-	rand.Shuffle(len(shuff), func(i, j int) {
-		shuff[i], shuff[j] = shuff[j], shuff[i]
-	})
-
-	pw := string(shuff)
+// Character classes of GenRandomPassword (ambiguous characters such as 0/O and 1/l are excluded)
+const (
+	passwordUpper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordLower   = "abcdefghijkmnopqrstuvwxyz"
+	passwordDigit   = "23456789"
+	passwordSpecial = "!#$%*+-_"
+)
 
-	return pw
+// GenRandomPassword is func to return a RandomPassword.
+// It uses crypto/rand and includes at least one upper, lower, digit and special character
+// (the special characters are accepted by the password policies of VMs and managed DBs of major CSPs).
+// The length is at least 8.
+func GenRandomPassword(length int) string {
+	if length < 8 {
+		length = 8
+	}
+	classes := []string{passwordUpper, passwordLower, passwordDigit, passwordSpecial}
+	all := strings.Join(classes, "")
+	pw := make([]byte, 0, length)
+	for _, class := range classes {
+		pw = append(pw, class[RandomIndex(len(class))])
+	}
+	pw = append(pw, RandomString(all, length-len(pw))...)
+	// shuffle (Fisher-Yates) so that the class of each position is not predictable
+	for i := len(pw) - 1; i > 0; i-- {
+		j := RandomIndex(i + 1)
+		pw[i], pw[j] = pw[j], pw[i]
+	}
+	return string(pw)
 }
 
 // RandomSleep is func to make a caller waits for during random time seconds (random value within x~y)
@@ -87,7 +94,6 @@ func RandomSleep(from int, to int) {
 		to = tmp
 	}
 	t := to - from
-	rand.Seed(time.Now().UnixNano())
 	n := rand.Intn(t * 1000)
 	time.Sleep(time.Duration(n) * time.Millisecond)
 }
@@ -176,6 +182,16 @@ func GenMciPolicyKey(nsId string, mciId string, vmId string) string {
 	}
 }
 
+// GenMciPolicyHistoryKey is func to generate a key of the evaluation history of the MCI policy
+func GenMciPolicyHistoryKey(nsId string, mciId string) string {
+	return "/ns/" + nsId + "/policyHistory/mci/" + mciId
+}
+
+// GenRecoveryPolicyKey is func to generate a key of the VM recovery policy of the MCI
+func GenRecoveryPolicyKey(nsId string, mciId string) string {
+	return "/ns/" + nsId + "/policy/recovery/" + mciId
+}
+
 // GenConnectionKey is func to generate a key for connection info
 func GenConnectionKey(connectionId string) string {
 	return "/connection/" + connectionId
@@ -215,7 +231,9 @@ func GenResourceKey(nsId string, resourceType string, resourceId string) string
 		resourceType == model.StrSpec ||
 		resourceType == model.StrVNet ||
 		resourceType == model.StrSecurityGroup ||
-		resourceType == model.StrDataDisk {
+		resourceType == model.StrDataDisk ||
+		resourceType == model.StrObjectStorage ||
+		resourceType == model.StrSqlDb {
 		//resourceType == "publicIp" ||
 		//resourceType == "vNic" {
 		return "/ns/" + nsId + "/resources/" + resourceType + "/" + resourceId
@@ -231,13 +249,80 @@ func GenChildResourceKey(nsId string, resourceType string, parentResourceId stri
 		parentResourceType := model.StrVNet
 		// return "/ns/" + nsId + "/resources/" + resourceType + "/" + resourceId
 		return fmt.Sprintf("/ns/%s/resources/%s/%s/%s/%s", nsId, parentResourceType, parentResourceId, resourceType, resourceId)
+	} else if resourceType == model.StrDataDiskSnapshot {
+		parentResourceType := model.StrDataDisk
+		return fmt.Sprintf("/ns/%s/resources/%s/%s/%s/%s", nsId, parentResourceType, parentResourceId, resourceType, resourceId)
 	} else {
 		return "/invalidKey"
 	}
 }
 
+// SetSystemLabels fills the system labels for the location of a resource (provider, region, zone, connectionName)
+// and the creation time. Labels which already have a value are not overwritten.
+func SetSystemLabels(labels map[string]string, connectionName string) {
+	setIfEmpty := func(key string, value string) {
+		if labels[key] == "" && value != "" {
+			labels[key] = value
+		}
+	}
+
+	setIfEmpty(model.LabelCreatedTime, time.Now().Format("2006-01-02 15:04:05"))
+	if connectionName == "" {
+		return
+	}
+	setIfEmpty(model.LabelConnectionName, connectionName)
+
+	connConfig, err := GetConnConfig(connectionName)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to get the connection (%s) to set system labels", connectionName)
+		return
+	}
+	setIfEmpty(model.LabelProvider, strings.ToLower(connConfig.ProviderName))
+	setIfEmpty(model.LabelRegion, connConfig.RegionDetail.RegionName)
+	setIfEmpty(model.LabelZone, connConfig.RegionZoneInfo.AssignedZone)
+}
+
 // GetConnConfig is func to get connection config
+// If the connection config is missing in the Key-Value store but exists in CB-Spider, it is reconstructed from CB-Spider.
 func GetConnConfig(ConnConfigName string) (model.ConnConfig, error) {
+	connConfig, found, err := getStoredConnConfig(ConnConfigName)
+	if err != nil {
+		return model.ConnConfig{}, err
+	}
+	if !found {
+		return recoverConnConfig(ConnConfigName)
+	}
+	return connConfig, nil
+}
+
+// GetConnConfigForZone returns the connection config of the zone in the region of the connection
+// (a connection is registered for each zone of a region, see RegisterRegionZone).
+// The connection itself is returned if the zone is empty or assigned to the connection.
+func GetConnConfigForZone(ConnConfigName string, zone string) (model.ConnConfig, error) {
+	connConfig, err := GetConnConfig(ConnConfigName)
+	if err != nil {
+		return model.ConnConfig{}, err
+	}
+	if zone == "" || strings.EqualFold(connConfig.RegionZoneInfo.AssignedZone, zone) {
+		return connConfig, nil
+	}
+	connConfigList, err := GetConnConfigList(connConfig.CredentialHolder, false, false)
+	if err != nil {
+		return model.ConnConfig{}, err
+	}
+	for _, candidate := range connConfigList.Connectionconfig {
+		if strings.EqualFold(candidate.ProviderName, connConfig.ProviderName) &&
+			candidate.CredentialName == connConfig.CredentialName &&
+			candidate.RegionZoneInfo.AssignedRegion == connConfig.RegionZoneInfo.AssignedRegion &&
+			strings.EqualFold(candidate.RegionZoneInfo.AssignedZone, zone) {
+			return candidate, nil
+		}
+	}
+	return model.ConnConfig{}, fmt.Errorf("no connection for the zone %s in the region (%s) of the connection %s", zone, connConfig.RegionZoneInfo.AssignedRegion, ConnConfigName)
+}
+
+// getStoredConnConfig is func to get the connection config from the Key-Value store only (found is false if missing)
+func getStoredConnConfig(ConnConfigName string) (model.ConnConfig, bool, error) {
 
 	connConfig := model.ConnConfig{}
 
@@ -245,22 +330,142 @@ func GetConnConfig(ConnConfigName string) (model.ConnConfig, error) {
 	keyValue, err := kvstore.GetKv(key)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return model.ConnConfig{}, err
+		return model.ConnConfig{}, false, err
 	}
 	if keyValue == (kvstore.KeyValue{}) {
-		return model.ConnConfig{}, fmt.Errorf("Cannot find the model.ConnConfig " + key)
+		return model.ConnConfig{}, false, nil
 	}
 	err = json.Unmarshal([]byte(keyValue.Value), &connConfig)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return model.ConnConfig{}, err
+		return model.ConnConfig{}, false, err
+	}
+	connConfig.Health = ConnConfigHealth(connConfig)
+
+	return connConfig, true, nil
+}
+
+// connConfigMissCache is the negative cache of the connection configs which exist neither in the Key-Value store nor in CB-Spider
+// (name -> expiry), so that repeated lookups of a nonexistent connection do not call CB-Spider
+var connConfigMissCache sync.Map
+
+// connConfigRecoverLocks serializes the reconstruction of each connection config (name -> *sync.Mutex)
+var connConfigRecoverLocks sync.Map
+
+// connConfigMissTtl returns how long a connection config missing in CB-Spider is not looked up again
+// (TB_CONNCONFIG_MISS_TTL_SEC, default 30)
+func connConfigMissTtl() time.Duration {
+	sec := envInt("TB_CONNCONFIG_MISS_TTL_SEC", 30)
+	if sec < 0 {
+		sec = 0
 	}
+	return time.Duration(sec) * time.Second
+}
+
+// recoverConnConfig is func to reconstruct the connection config missing in the Key-Value store from CB-Spider
+// (e.g., after an etcd restore) and store it again
+func recoverConnConfig(connConfigName string) (model.ConnConfig, error) {
+	notFoundErr := fmt.Errorf("Cannot find the model.ConnConfig " + GenConnectionKey(connConfigName))
 
+	isMissCached := func() bool {
+		if expiry, ok := connConfigMissCache.Load(connConfigName); ok {
+			if time.Now().Before(expiry.(time.Time)) {
+				return true
+			}
+			connConfigMissCache.Delete(connConfigName)
+		}
+		return false
+	}
+	if isMissCached() {
+		return model.ConnConfig{}, notFoundErr
+	}
+
+	lock, _ := connConfigRecoverLocks.LoadOrStore(connConfigName, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// the connection config may be reconstructed (or found missing) by another request while waiting
+	if isMissCached() {
+		return model.ConnConfig{}, notFoundErr
+	}
+	if connConfig, found, err := getStoredConnConfig(connConfigName); err != nil || found {
+		return connConfig, err
+	}
+
+	client := resty.New()
+	url := model.SpiderRestUrl + "/connectionconfig/" + connConfigName
+	method := "GET"
+	var callResult model.SpiderConnConfig
+	requestNoBody := NoBody
+
+	err := ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		SetUseBody(requestNoBody),
+		&requestNoBody,
+		&callResult,
+		VeryShortDuration,
+		WithoutRetry(),
+	)
+	if err != nil || callResult.ConfigName == "" {
+		log.Debug().Err(err).Msgf("The connection config %s does not exist in CB-Spider", connConfigName)
+		connConfigMissCache.Store(connConfigName, time.Now().Add(connConfigMissTtl()))
+		return model.ConnConfig{}, notFoundErr
+	}
+
+	// credentials of the default credential holder have no prefix (e.g., aws), the others are {holder}-{provider}
+	providerName := strings.ToLower(callResult.ProviderName)
+	credentialHolder := model.DefaultCredentialHolder
+	if callResult.CredentialName != providerName {
+		credentialHolder = strings.TrimSuffix(callResult.CredentialName, "-"+providerName)
+	}
+
+	connConfig, err := saveConnConfig(callResult, credentialHolder)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to reconstruct the connection config %s from CB-Spider", connConfigName)
+		return model.ConnConfig{}, notFoundErr
+	}
+	log.Warn().Msgf("The connection config %s was missing in the Key-Value store and is reconstructed from CB-Spider (the store may have drifted)", connConfigName)
+	connConfig.Health = ConnConfigHealth(connConfig)
+	return connConfig, nil
+}
+
+// UpdateConnConfigRootDisk sets the default root disk of VMs created dynamically with the connection
+func UpdateConnConfigRootDisk(connConfigName string, req *model.ConnConfigRootDiskReq) (model.ConnConfig, error) {
+	connConfig, err := GetConnConfig(connConfigName)
+	if err != nil {
+		return model.ConnConfig{}, NewResourceNotFoundError("connConfig", connConfigName)
+	}
+
+	if err := ValidateRootDisk(connConfig.ProviderName, req.RootDiskType, req.RootDiskSize); err != nil {
+		log.Error().Err(err).Msg("")
+		return model.ConnConfig{}, NewValidationFailedError("%v", err)
+	}
+	connConfig.RootDiskType = req.RootDiskType
+	connConfig.RootDiskSize = req.RootDiskSize
+	if isDefaultRootDisk(connConfig.RootDiskType) {
+		connConfig.RootDiskType = ""
+	}
+	if isDefaultRootDisk(connConfig.RootDiskSize) {
+		connConfig.RootDiskSize = ""
+	}
+
+	val, err := json.Marshal(connConfig)
+	if err != nil {
+		return model.ConnConfig{}, err
+	}
+	err = kvstore.Put(GenConnectionKey(connConfigName), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.ConnConfig{}, err
+	}
 	return connConfig, nil
 }
 
 // CheckConnConfigAvailable is func to check if connection config is available by checking allkeypair list
-func CheckConnConfigAvailable(connConfigName string) (bool, error) {
+func CheckConnConfigAvailable(ctx context.Context, connConfigName string) (bool, error) {
 
 	var callResult interface{}
 	client := resty.New()
@@ -278,6 +483,9 @@ func CheckConnConfigAvailable(connConfigName string) (bool, error) {
 		&requestBody,
 		&callResult,
 		ShortDuration,
+		WithConnectionProvider(connConfigName),
+		withoutConnConfigHealth(),
+		WithRequestContext(ctx),
 	)
 
 	if err != nil {
@@ -337,6 +545,7 @@ func GetConnConfigList(filterCredentialHolder string, filterVerified bool, filte
 				log.Error().Err(err).Msg("")
 				return filteredConnections, err
 			}
+			tempObj.Health = ConnConfigHealth(tempObj)
 			filteredConnections.Connectionconfig = append(filteredConnections.Connectionconfig, tempObj)
 		}
 	} else {
@@ -379,15 +588,22 @@ func GetConnConfigList(filterCredentialHolder string, filterVerified bool, filte
 	return filteredConnections, nil
 }
 
-// RegisterAllCloudInfo is func to register all cloud info from asset to CB-Spider
-func RegisterAllCloudInfo() error {
+// RegisterAllCloudInfo is func to register all cloud info from asset to CB-Spider.
+// It continues through all providers and returns the per-provider results.
+func RegisterAllCloudInfo() (CloudInfoRegisterSummary, error) {
+	summary := CloudInfoRegisterSummary{Providers: []CloudInfoRegisterResult{}}
 	for providerName := range RuntimeCloudInfo.CSPs {
-		err := RegisterCloudInfo(providerName)
+		result, err := RegisterCloudInfo(providerName)
 		if err != nil {
 			log.Error().Err(err).Msg("")
+			summary.FailedProviders++
 		}
+		summary.Providers = append(summary.Providers, result)
 	}
-	return nil
+	sort.Slice(summary.Providers, func(i, j int) bool {
+		return summary.Providers[i].ProviderName < summary.Providers[j].ProviderName
+	})
+	return summary, nil
 }
 
 // GetProviderList is func to list all cloud providers
@@ -399,8 +615,14 @@ func GetProviderList() (*model.IdList, error) {
 	return &providers, nil
 }
 
-// RegisterCloudInfo is func to register cloud info from asset to CB-Spider
-func RegisterCloudInfo(providerName string) error {
+// regionRegisterMaxRetry is the number of retries for the regions failed to be registered
+const regionRegisterMaxRetry = 2
+
+// RegisterCloudInfo is func to register cloud info from asset to CB-Spider.
+// It continues through all regions of the provider, retries failed regions a bounded number of times,
+// and returns the succeeded and failed regions (error is returned if the driver or any region failed).
+func RegisterCloudInfo(providerName string) (CloudInfoRegisterResult, error) {
+	result := CloudInfoRegisterResult{ProviderName: providerName, Succeeded: []string{}, Failed: []RegionRegisterError{}}
 
 	driverName := RuntimeCloudInfo.CSPs[providerName].Driver
 
@@ -423,18 +645,45 @@ func RegisterCloudInfo(providerName string) error {
 
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return err
+		result.DriverError = err.Error()
+		return result, fmt.Errorf("failed to register the driver of %s: %w", providerName, err)
 	}
 
-	for regionName, _ := range RuntimeCloudInfo.CSPs[providerName].Regions {
-		err := RegisterRegionZone(providerName, regionName)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-			return err
+	pending := make([]string, 0, len(RuntimeCloudInfo.CSPs[providerName].Regions))
+	for regionName := range RuntimeCloudInfo.CSPs[providerName].Regions {
+		pending = append(pending, regionName)
+	}
+	sort.Strings(pending)
+
+	regionErrors := map[string]error{}
+	for attempt := 0; attempt <= regionRegisterMaxRetry && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			log.Warn().Msgf("[%s] Retrying %d failed regions (%d/%d)", providerName, len(pending), attempt, regionRegisterMaxRetry)
+			time.Sleep(time.Duration(attempt) * 2 * time.Second)
+		}
+		failed := []string{}
+		for _, regionName := range pending {
+			err := RegisterRegionZone(providerName, regionName)
+			if err != nil {
+				regionErrors[regionName] = err
+				failed = append(failed, regionName)
+				continue
+			}
+			delete(regionErrors, regionName)
+			result.Succeeded = append(result.Succeeded, regionName)
 		}
+		pending = failed
 	}
+	sort.Strings(result.Succeeded)
 
-	return nil
+	for _, regionName := range pending {
+		result.Failed = append(result.Failed, RegionRegisterError{Region: regionName, Error: regionErrors[regionName].Error()})
+	}
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("failed to register %d of %d regions of %s", len(result.Failed), len(result.Failed)+len(result.Succeeded), providerName)
+	}
+
+	return result, nil
 }
 
 // RegisterRegionZone is func to register all regions to CB-Spider
@@ -552,14 +801,21 @@ func unpad(data []byte, blockSize int) ([]byte, error) {
 }
 
 // RegisterCredential is func to register credential and all related connection configs
-func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
+// The request ID of the context is propagated to CB-Spider and logged with the credential holder and provider.
+func RegisterCredential(ctx context.Context, req model.CredentialReq) (model.CredentialInfo, error) {
+	ctx = WithLogFields(ctx, "credentialHolder", req.CredentialHolder, "providerName", req.ProviderName)
+	logger := LoggerFromContext(ctx)
+
+	// Register the credential registration (including verification of connections) to the shutdown coordinator
+	endOperation := BeginOperation("registerCredential "+req.CredentialHolder+"/"+req.ProviderName, nil)
+	defer endOperation()
 
 	mu.Lock()
 	privateKey, exists := privateKeyStore[req.PublicKeyTokenId]
 	mu.Unlock()
 
 	if !exists {
-		return model.CredentialInfo{}, fmt.Errorf("private key not found for token ID: %s", req.PublicKeyTokenId)
+		return model.CredentialInfo{}, &ApiError{Code: model.ErrCodeResourceNotFound, Message: fmt.Sprintf("private key not found for token ID: %s", req.PublicKeyTokenId)}
 	}
 
 	// PrintJsonPretty(req)
@@ -567,14 +823,14 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 	// Decrypt the AES key
 	encryptedAesKey, err := base64.StdEncoding.DecodeString(req.EncryptedClientAesKeyByPublicKey)
 	if err != nil {
-		return model.CredentialInfo{}, fmt.Errorf("failed to decode encrypted AES key: %w", err)
+		return model.CredentialInfo{}, &ApiError{Code: model.ErrCodeValidationFailed, Message: "failed to decode encrypted AES key: " + err.Error(), Err: err}
 	}
 
 	aesKey, err := rsa.DecryptOAEP(
 		sha256.New(), crand.Reader, privateKey, encryptedAesKey, nil,
 	)
 	if err != nil {
-		return model.CredentialInfo{}, fmt.Errorf("failed to decrypt AES key: %w", err)
+		return model.CredentialInfo{}, &ApiError{Code: model.ErrCodeValidationFailed, Message: "failed to decrypt AES key: " + err.Error(), Err: err}
 	}
 
 	// Clear AES key from memory after use
@@ -590,8 +846,8 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 	for i, keyValue := range req.CredentialKeyValueList {
 		encryptedBytes, err := base64.StdEncoding.DecodeString(keyValue.Value)
 		if err != nil {
-			log.Error().Err(err).Msg("")
-			return model.CredentialInfo{}, fmt.Errorf("failed to decode encrypted value: %w", err)
+			logger.Error().Err(err).Msg("")
+			return model.CredentialInfo{}, &ApiError{Code: model.ErrCodeValidationFailed, Message: "failed to decode encrypted value: " + err.Error(), Err: err}
 		}
 
 		aesCipher, err := aes.NewCipher(aesKey)
@@ -608,7 +864,7 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 		// Remove padding
 		decryptedValue, err = unpad(decryptedValue, aes.BlockSize)
 		if err != nil {
-			return model.CredentialInfo{}, fmt.Errorf("failed to unpad decrypted value: %w", err)
+			return model.CredentialInfo{}, &ApiError{Code: model.ErrCodeValidationFailed, Message: "failed to unpad decrypted value: " + err.Error(), Err: err}
 		}
 
 		decryptedKeyValueList[i] = model.KeyValue{
@@ -658,10 +914,11 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 		&requestBody,
 		&callResult,
 		MediumDuration,
+		WithRequestContext(ctx),
 	)
 
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return model.CredentialInfo{}, err
 	}
 	//PrintJsonPretty(callResult)
@@ -704,7 +961,7 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 			}
 			_, err := RegisterConnectionConfig(connConfig)
 			if err != nil {
-				log.Error().Err(err).Msg("")
+				logger.Error().Err(err).Msg("")
 				return callResult, err
 			}
 		}
@@ -715,7 +972,7 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 	if validate {
 		allConnections, err := GetConnConfigList(req.CredentialHolder, false, false)
 		if err != nil {
-			log.Error().Err(err).Msg("")
+			logger.Error().Err(err).Msg("")
 			return callResult, err
 		}
 
@@ -735,20 +992,22 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 			go func(connConfig model.ConnConfig) {
 				defer wg.Done()
 				RandomSleep(0, 30)
-				verified, err := CheckConnConfigAvailable(connConfig.ConfigName)
+				verified, err := CheckConnConfigAvailable(ctx, connConfig.ConfigName)
 				if err != nil {
-					log.Error().Err(err).Msgf("Cannot check model.ConnConfig %s is available", connConfig.ConfigName)
+					logger.Error().Err(err).Msgf("Cannot check model.ConnConfig %s is available", connConfig.ConfigName)
 				}
 				connConfig.Verified = verified
 				if verified {
-					regionInfo, err := GetRegion(connConfig.ProviderName, connConfig.RegionDetail.RegionName)
-					if err != nil {
-						log.Error().Err(err).Msgf("Cannot get region for %s", connConfig.RegionDetail.RegionName)
+					regionInfo, regionErr := GetRegion(connConfig.ProviderName, connConfig.RegionDetail.RegionName)
+					if regionErr != nil {
+						logger.Error().Err(regionErr).Msgf("Cannot get region for %s", connConfig.RegionDetail.RegionName)
 						connConfig.Verified = false
+						err = regionErr
 					} else {
 						connConfig.RegionDetail = regionInfo
 					}
 				}
+				applyConnConfigCheck(&connConfig, err)
 				results <- connConfig
 			}(connConfig)
 		}
@@ -759,6 +1018,11 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 		}()
 
 		for result := range results {
+			if !result.Verified {
+				// keep the stored connection config and record the failed check only
+				recordConnConfigCheck(result.ConfigName, fmt.Errorf("%s", result.LastError))
+				continue
+			}
 			if result.Verified {
 				key := GenConnectionKey(result.ConfigName)
 				val, err := json.Marshal(result)
@@ -777,7 +1041,7 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 	if setRegionRepresentative {
 		allConnections, err := GetConnConfigList(req.CredentialHolder, false, false)
 		if err != nil {
-			log.Error().Err(err).Msg("")
+			logger.Error().Err(err).Msg("")
 			return callResult, err
 		}
 
@@ -787,7 +1051,7 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 				filteredConnections.Connectionconfig = append(filteredConnections.Connectionconfig, connConfig)
 			}
 		}
-		log.Info().Msgf("[%s] filtered connection config: %d", req.ProviderName, len(filteredConnections.Connectionconfig))
+		logger.Info().Msgf("[%s] filtered connection config: %d", req.ProviderName, len(filteredConnections.Connectionconfig))
 		regionRepresentative := make(map[string]model.ConnConfig)
 		for _, connConfig := range allConnections.Connectionconfig {
 			prefix := req.ProviderName + "-" + connConfig.RegionDetail.RegionName
@@ -815,7 +1079,7 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 	if verifyRegionRepresentativeAndUpdateZone {
 		verifiedConnections, err := GetConnConfigList(req.CredentialHolder, true, false)
 		if err != nil {
-			log.Error().Err(err).Msg("")
+			logger.Error().Err(err).Msg("")
 			return callResult, err
 		}
 		allRepresentativeRegionConnections, err := GetConnConfigList(req.CredentialHolder, false, true)
@@ -853,22 +1117,22 @@ func RegisterCredential(req model.CredentialReq) (model.CredentialInfo, error) {
 
 	callResult.AllConnections, err = GetConnConfigList(req.CredentialHolder, false, false)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return callResult, err
 	}
 
 	return callResult, nil
 }
 
-// RegisterConnectionConfig is func to register connection config to CB-Spider
-func RegisterConnectionConfig(connConfig model.ConnConfig) (model.ConnConfig, error) {
+// postSpiderConnConfig is func to create the connection config in CB-Spider only
+func postSpiderConnConfig(connConfig model.ConnConfig) (model.SpiderConnConfig, error) {
 	client := resty.New()
 	url := model.SpiderRestUrl + "/connectionconfig"
 	method := "POST"
 	var callResult model.SpiderConnConfig
 	requestBody := model.SpiderConnConfig{}
 	requestBody.ConfigName = connConfig.ConfigName
-	requestBody.ProviderName = connConfig.ProviderName
+	requestBody.ProviderName = strings.ToUpper(connConfig.ProviderName)
 	requestBody.DriverName = connConfig.DriverName
 	requestBody.CredentialName = connConfig.CredentialName
 	requestBody.RegionName = connConfig.RegionZoneInfoName
@@ -886,11 +1150,26 @@ func RegisterConnectionConfig(connConfig model.ConnConfig) (model.ConnConfig, er
 
 	if err != nil {
 		log.Error().Err(err).Msg("")
+		return model.SpiderConnConfig{}, err
+	}
+	return callResult, nil
+}
+
+// RegisterConnectionConfig is func to register connection config to CB-Spider
+func RegisterConnectionConfig(connConfig model.ConnConfig) (model.ConnConfig, error) {
+	callResult, err := postSpiderConnConfig(connConfig)
+	if err != nil {
 		return model.ConnConfig{}, err
 	}
+	return saveConnConfig(callResult, connConfig.CredentialHolder)
+}
+
+// saveConnConfig is func to store the connection config of CB-Spider in CB-Tumblebug with its region info
+func saveConnConfig(callResult model.SpiderConnConfig, credentialHolder string) (model.ConnConfig, error) {
+	client := resty.New()
 
 	// Register connection to cb-tumblebug with availability check
-	// verified, err := CheckConnConfigAvailable(callResult.ConfigName)
+	// verified, err := CheckConnConfigAvailable(ctx, callResult.ConfigName)
 	// if err != nil {
 	// 	log.Error().Err(err).Msgf("Cannot check model.ConnConfig %s is available", connConfig.ConfigName)
 	// }
@@ -915,15 +1194,15 @@ func RegisterConnectionConfig(connConfig model.ConnConfig) (model.ConnConfig, er
 	connection.DriverName = callResult.DriverName
 	connection.CredentialName = callResult.CredentialName
 	connection.RegionZoneInfoName = callResult.RegionName
-	connection.CredentialHolder = connConfig.CredentialHolder
+	connection.CredentialHolder = credentialHolder
 
 	// load region info
-	url = model.SpiderRestUrl + "/region/" + connection.RegionZoneInfoName
-	method = "GET"
+	url := model.SpiderRestUrl + "/region/" + connection.RegionZoneInfoName
+	method := "GET"
 	var callResultRegion model.SpiderRegionZoneInfo
 	requestNoBody := NoBody
 
-	err = ExecuteHttpRequest(
+	err := ExecuteHttpRequest(
 		client,
 		method,
 		url,
@@ -955,6 +1234,12 @@ func RegisterConnectionConfig(connConfig model.ConnConfig) (model.ConnConfig, er
 	}
 	connection.RegionDetail = regionDetail
 
+	// keep the defaults of the connection on re-registration
+	if existing, found, err := getStoredConnConfig(connection.ConfigName); err == nil && found {
+		connection.RootDiskType = existing.RootDiskType
+		connection.RootDiskSize = existing.RootDiskSize
+	}
+
 	key := GenConnectionKey(connection.ConfigName)
 	val, err := json.Marshal(connection)
 	if err != nil {
@@ -965,10 +1250,81 @@ func RegisterConnectionConfig(connConfig model.ConnConfig) (model.ConnConfig, er
 		log.Error().Err(err).Msg("")
 		return model.ConnConfig{}, err
 	}
+	connConfigMissCache.Delete(connection.ConfigName)
 
 	return connection, nil
 }
 
+// isDefaultRootDisk returns true if the root disk setting means the CSP default
+func isDefaultRootDisk(value string) bool {
+	return value == "" || strings.EqualFold(value, "default")
+}
+
+// ValidateRootDisk checks the root disk type and size against the values supported by the provider in cloud info.
+// "" and "default" are always accepted (the CSP default is used), and providers without rootdisk in cloud info are not checked.
+func ValidateRootDisk(providerName string, rootDiskType string, rootDiskSize string) error {
+	if !isDefaultRootDisk(rootDiskSize) {
+		size, err := strconv.Atoi(rootDiskSize)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid rootDiskSize %q (integer in GB or default)", rootDiskSize)
+		}
+	}
+
+	cloudInfo, err := GetCloudInfo()
+	if err != nil {
+		return err
+	}
+	rootDisk := cloudInfo.CSPs[strings.ToLower(providerName)].RootDisk
+
+	if !isDefaultRootDisk(rootDiskType) && len(rootDisk.Types) > 0 {
+		supported := false
+		for _, t := range rootDisk.Types {
+			if strings.EqualFold(t, rootDiskType) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return fmt.Errorf("rootDiskType %q is not supported by %s (supported: %s)", rootDiskType, providerName, strings.Join(rootDisk.Types, ", "))
+		}
+	}
+	if !isDefaultRootDisk(rootDiskSize) {
+		size, _ := strconv.Atoi(rootDiskSize)
+		if (rootDisk.MinSize > 0 && size < rootDisk.MinSize) || (rootDisk.MaxSize > 0 && size > rootDisk.MaxSize) {
+			return fmt.Errorf("rootDiskSize %s GB is out of the range of %s (%d-%d GB)", rootDiskSize, providerName, rootDisk.MinSize, rootDisk.MaxSize)
+		}
+	}
+	return nil
+}
+
+// ResolveRootDisk returns the root disk of a VM created with the connection.
+// The settings of the VM request win over the defaults of the connection, and the CSP default is used if neither is given.
+func ResolveRootDisk(connConfig model.ConnConfig, rootDiskType string, rootDiskSize string) (model.ResolvedRootDisk, error) {
+	resolved := model.ResolvedRootDisk{
+		RootDiskType:       "default",
+		RootDiskSize:       "default",
+		RootDiskTypeSource: model.RootDiskSourceDefault,
+		RootDiskSizeSource: model.RootDiskSourceDefault,
+	}
+	if !isDefaultRootDisk(rootDiskType) {
+		resolved.RootDiskType = rootDiskType
+		resolved.RootDiskTypeSource = model.RootDiskSourceVm
+	} else if !isDefaultRootDisk(connConfig.RootDiskType) {
+		resolved.RootDiskType = connConfig.RootDiskType
+		resolved.RootDiskTypeSource = model.RootDiskSourceConnection
+	}
+	if !isDefaultRootDisk(rootDiskSize) {
+		resolved.RootDiskSize = rootDiskSize
+		resolved.RootDiskSizeSource = model.RootDiskSourceVm
+	} else if !isDefaultRootDisk(connConfig.RootDiskSize) {
+		resolved.RootDiskSize = connConfig.RootDiskSize
+		resolved.RootDiskSizeSource = model.RootDiskSourceConnection
+	}
+
+	err := ValidateRootDisk(connConfig.ProviderName, resolved.RootDiskType, resolved.RootDiskSize)
+	return resolved, err
+}
+
 // GetRegion is func to get regionInfo with the native region name
 func GetRegion(ProviderName, RegionName string) (model.RegionDetail, error) {
 
@@ -999,6 +1355,34 @@ func GetRegion(ProviderName, RegionName string) (model.RegionDetail, error) {
 	return model.RegionDetail{}, fmt.Errorf("nativeRegion '%s' not found in Provider '%s'", RegionName, ProviderName)
 }
 
+// GetRegionDetailByConnection is func to get regionInfo of the connection config.
+// It resolves the provider and the region from the stored connection config instead of parsing the connection name
+// (connection names may be prefixed with a credential holder, e.g., teamA-aws-ap-northeast-2).
+func GetRegionDetailByConnection(connectionName string) (model.RegionDetail, error) {
+	connConfig, err := GetConnConfig(connectionName)
+	if err != nil {
+		return model.RegionDetail{}, fmt.Errorf("connection config '%s' not found: %w", connectionName, err)
+	}
+
+	regionName := connConfig.RegionDetail.RegionName
+	if regionName == "" {
+		regionName = connConfig.RegionZoneInfo.AssignedRegion
+	}
+	if regionName == "" {
+		return model.RegionDetail{}, fmt.Errorf("connection config '%s' has no region", connectionName)
+	}
+
+	// prefer the current cloud info (it may be updated at runtime by custom regions)
+	regionDetail, err := GetRegion(connConfig.ProviderName, regionName)
+	if err != nil {
+		if connConfig.RegionDetail.RegionName == "" {
+			return model.RegionDetail{}, err
+		}
+		return connConfig.RegionDetail, nil
+	}
+	return regionDetail, nil
+}
+
 // GetRegions is func to get regionInfo list
 func GetRegions(ProviderName string) (model.RegionList, error) {
 
@@ -1123,6 +1507,35 @@ func ConvertToOutput(outType string, obj interface{}) (string, error) {
 	return "", nil
 }
 
+// YamlToJson is func to convert a YAML document to JSON
+// (to decode YAML into the models which have json tags only)
+func YamlToJson(data []byte) ([]byte, error) {
+	var obj interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return json.Marshal(yamlToJsonValue(obj))
+}
+
+// yamlToJsonValue converts the maps decoded by yaml.v2 (map[interface{}]interface{}) to map[string]interface{}
+func yamlToJsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = yamlToJsonValue(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range t {
+			t[i] = yamlToJsonValue(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
 // CopySrcToDest is func to copy data from source to target
 func CopySrcToDest(src interface{}, dest interface{}) error {
 	//logger := logging.NewLogger()
@@ -1177,7 +1590,8 @@ func GetChildIdList(key string) []string {
 // GetObjectList is func to return IDs of each child objects that has the same key
 func GetObjectList(key string) []string {
 
-	keyValue, _ := kvstore.GetKvList(key)
+	// values are not needed (keys only)
+	keyValue, _, _ := kvstore.GetKvListPage(key, "", 0, true)
 
 	var childIdList []string
 	for _, v := range keyValue {
@@ -1188,6 +1602,109 @@ func GetObjectList(key string) []string {
 
 }
 
+// ObjectListOption is a set of options to list objects under a prefix
+type ObjectListOption struct {
+	KeysOnly      bool   // return keys without values
+	Limit         int    // max number of objects in a page (0 means no pagination)
+	ContinueToken string // token of the next page returned by the previous page
+	Depth         int    // 1 lists only the immediate children of the prefix (like a directory view); 0 lists all
+}
+
+// ObjectListResult is a page of objects under a prefix
+type ObjectListResult struct {
+	Object        []string           `json:"object"`                  // keys of the objects (or immediate children with depth 1)
+	Kvs           []kvstore.KeyValue `json:"kvs,omitempty"`           // keys and values (if not keysOnly and depth is 0)
+	ContinueToken string             `json:"continueToken,omitempty"` // token to get the next page ("" if no more)
+}
+
+// ObjectStats is the number and the total size of objects under a prefix
+type ObjectStats struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+	Size  int64  `json:"size"` // bytes of keys and values
+}
+
+// GetObjectListWithOption returns a page of objects under the key prefix.
+// With depth 1, only the immediate children are returned (a child with descendants is returned once
+// as {prefix}/{child}) and the descendants are skipped in the store without being transferred.
+func GetObjectListWithOption(key string, option ObjectListOption) (ObjectListResult, error) {
+	result := ObjectListResult{Object: []string{}}
+
+	startKey := ""
+	if option.ContinueToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(option.ContinueToken)
+		if err != nil || !strings.HasPrefix(string(decoded), key) {
+			return result, fmt.Errorf("invalid continueToken")
+		}
+		startKey = string(decoded)
+	}
+	if option.Limit < 0 || option.Depth < 0 {
+		return result, fmt.Errorf("limit and depth must not be negative")
+	}
+
+	if option.Depth == 0 {
+		keyValue, nextKey, err := kvstore.GetKvListPage(key, startKey, int64(option.Limit), option.KeysOnly)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		for _, kv := range keyValue {
+			result.Object = append(result.Object, kv.Key)
+		}
+		if !option.KeysOnly {
+			result.Kvs = keyValue
+		}
+		if nextKey != "" {
+			result.ContinueToken = base64.RawURLEncoding.EncodeToString([]byte(nextKey))
+		}
+		return result, nil
+	}
+
+	// depth-limited listing (only depth 1 is supported as the directory view)
+	if option.Depth > 1 {
+		return result, fmt.Errorf("depth %d is not supported (use 0 or 1)", option.Depth)
+	}
+	dirPrefix := strings.TrimSuffix(key, "/") + "/"
+	for {
+		keyValue, nextKey, err := kvstore.GetKvListPage(dirPrefix, startKey, 1, true)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		if len(keyValue) == 0 {
+			return result, nil
+		}
+		rest := strings.TrimPrefix(keyValue[0].Key, dirPrefix)
+		child := dirPrefix + strings.SplitN(rest, "/", 2)[0]
+		if len(result.Object) == 0 || result.Object[len(result.Object)-1] != child {
+			if option.Limit > 0 && len(result.Object) >= option.Limit {
+				result.ContinueToken = base64.RawURLEncoding.EncodeToString([]byte(child))
+				return result, nil
+			}
+			result.Object = append(result.Object, child)
+		}
+		if nextKey == "" {
+			return result, nil
+		}
+		if strings.Contains(rest, "/") {
+			// skip the descendants of the child ('0' is the next character of '/')
+			startKey = child + "0"
+		} else {
+			startKey = nextKey
+		}
+	}
+}
+
+// GetObjectStats returns the number and the total size of objects under the key prefix
+func GetObjectStats(key string) (ObjectStats, error) {
+	count, size, err := kvstore.GetStats(key)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return ObjectStats{}, err
+	}
+	return ObjectStats{Key: key, Count: count, Size: size}, nil
+}
+
 // GetObjectValue is func to return the object value
 func GetObjectValue(key string) (string, error) {
 
@@ -1235,30 +1752,9 @@ func CheckElement(a string, list []string) bool {
 	return false
 }
 
-const (
-	// Random string generation
-	letterBytes   = "abcdefghijklmnopqrstuvwxyz1234567890"
-	letterIdxBits = 6
-	letterIdxMask = 1<<letterIdxBits - 1
-	letterIdxMax  = 63 / letterIdxBits
-)
-
-/* generate a random string (from CB-MCKS source code) */
+// GenerateNewRandomString is func to return a random string of lowercase letters and digits (see RandomSuffix)
 func GenerateNewRandomString(n int) string {
-	randSrc := rand.NewSource(time.Now().UnixNano()) //Random source by nano time
-	b := make([]byte, n)
-	for i, cache, remain := n-1, randSrc.Int63(), letterIdxMax; i >= 0; {
-		if remain == 0 {
-			cache, remain = randSrc.Int63(), letterIdxMax
-		}
-		if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
-			b[i] = letterBytes[idx]
-			i--
-		}
-		cache >>= letterIdxBits
-		remain--
-	}
-	return string(b)
+	return RandomSuffix(n)
 }
 
 // GetK8sClusterInfo is func to get all kubernetes cluster info from the asset
@@ -1292,7 +1788,7 @@ func GetAvailableK8sClusterVersion(providerName string, regionName string) (*[]m
 	// Get model.K8sClusterDetail for providerName
 	k8sClusterDetail := getK8sClusterDetail(providerName)
 	if k8sClusterDetail == nil {
-		return nil, fmt.Errorf("unsupported provider(%s) for kubernetes cluster", providerName)
+		return nil, &NotSupportedError{Operation: "kubernetes cluster", ProviderName: providerName}
 	}
 
 	// Check if 'regionName' exists
@@ -1341,7 +1837,7 @@ func GetAvailableK8sClusterNodeImage(providerName string, regionName string) (*[
 	// Get model.K8sClusterDetail for providerName
 	k8sClusterDetail := getK8sClusterDetail(providerName)
 	if k8sClusterDetail == nil {
-		return nil, fmt.Errorf("unsupported provider(%s) for kubernetes cluster", providerName)
+		return nil, &NotSupportedError{Operation: "kubernetes cluster", ProviderName: providerName}
 	}
 
 	// Check if 'regionName' exists
@@ -1391,7 +1887,7 @@ func CheckNodeGroupsOnK8sCreation(providerName string) (*model.K8sClusterNodeGro
 	// Get model.K8sClusterDetail for providerName
 	k8sClusterDetail := getK8sClusterDetail(providerName)
 	if k8sClusterDetail == nil {
-		return nil, fmt.Errorf("unsupported provider(%s) for kubernetes cluster", providerName)
+		return nil, &NotSupportedError{Operation: "kubernetes cluster", ProviderName: providerName}
 	}
 
 	return &model.K8sClusterNodeGroupsOnCreation{
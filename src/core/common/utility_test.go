@@ -0,0 +1,188 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/labstack/echo/v4"
+)
+
+// requestIdRecorder records the X-Request-Id header of the requests to the test CB-Spider by path
+type requestIdRecorder struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+func (r *requestIdRecorder) get(path string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ids[path]
+}
+
+func newRequestIdRecorder(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) *requestIdRecorder {
+	recorder := &requestIdRecorder{ids: map[string]string{}}
+	setTestSpiderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		recorder.mu.Lock()
+		recorder.ids[r.URL.Path] = r.Header.Get(echo.HeaderXRequestID)
+		recorder.mu.Unlock()
+		respond(w, r)
+	})
+	return recorder
+}
+
+func TestCheckConnConfigAvailablePropagatesRequestId(t *testing.T) {
+	resetCircuitBreakers(t)
+	recorder := newRequestIdRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"keypair":[]}`))
+	})
+
+	ctx := WithRequestId(context.Background(), "req-check-conn")
+	verified, err := CheckConnConfigAvailable(ctx, "conn-request-id-test")
+	if err != nil || !verified {
+		t.Fatalf("verified %v, err %v", verified, err)
+	}
+	if got := recorder.get("/spider/allkeypair"); got != "req-check-conn" {
+		t.Errorf("X-Request-Id %q, want req-check-conn", got)
+	}
+}
+
+// encryptCredentialValue encrypts the value as the client of RegisterCredential does (AES-CBC with PKCS#7 padding)
+func encryptCredentialValue(t *testing.T, aesKey []byte, value string) string {
+	t.Helper()
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(value)%aes.BlockSize
+	plaintext := append([]byte(value), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	iv := make([]byte, aes.BlockSize)
+	crand.Read(iv)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+	return base64.StdEncoding.EncodeToString(append(iv, ciphertext...))
+}
+
+func TestRegisterCredentialPropagatesRequestId(t *testing.T) {
+	resetCircuitBreakers(t)
+	recorder := newRequestIdRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/spider/credential" {
+			// the rest of the registration is not the subject of the test
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"CredentialName":"request-id-test","ProviderName":"OPENSTACK","KeyValueInfoList":[{"Key":"Password","Value":"secret"}]}`))
+	})
+
+	privateKey, err := rsa.GenerateKey(crand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenId := "token-request-id-test"
+	mu.Lock()
+	privateKeyStore[tokenId] = privateKey
+	mu.Unlock()
+
+	aesKey := make([]byte, 32)
+	crand.Read(aesKey)
+	encryptedAesKey, err := rsa.EncryptOAEP(sha256.New(), crand.Reader, &privateKey.PublicKey, aesKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := model.CredentialReq{
+		CredentialHolder:                 "request-id-test",
+		ProviderName:                     "openstack",
+		PublicKeyTokenId:                 tokenId,
+		EncryptedClientAesKeyByPublicKey: base64.StdEncoding.EncodeToString(encryptedAesKey),
+		CredentialKeyValueList:           []model.KeyWithEncryptedValue{{Key: "Password", Value: encryptCredentialValue(t, aesKey, "secret")}},
+	}
+
+	RegisterCredential(WithRequestId(context.Background(), "req-register-credential"), req)
+	if got := recorder.get("/spider/credential"); got != "req-register-credential" {
+		t.Errorf("X-Request-Id %q, want req-register-credential", got)
+	}
+}
+
+// setTestCloudInfo sets the cloud info of the providers and regions for the test
+func setTestCloudInfo(t *testing.T, cloudInfo model.CloudInfo) {
+	t.Helper()
+	prev := RuntimeCloudInfo
+	RuntimeCloudInfo = cloudInfo
+	t.Cleanup(func() { RuntimeCloudInfo = prev })
+}
+
+func TestGetRegionDetailByConnection(t *testing.T) {
+	resetCircuitBreakers(t)
+	// connections which are not stored are not found in CB-Spider either
+	setTestSpiderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"not found"}`))
+	})
+	setTestCloudInfo(t, model.CloudInfo{CSPs: map[string]model.CSPDetail{
+		"aws": {Regions: map[string]model.RegionDetail{
+			"ap-northeast-2": {RegionId: "ap-northeast-2", RegionName: "ap-northeast-2", Zones: []string{"ap-northeast-2a", "ap-northeast-2c"}},
+		}},
+		"ibm": {Regions: map[string]model.RegionDetail{
+			"us-south": {RegionId: "us-south", RegionName: "us-south", Zones: []string{"us-south-1"}},
+		}},
+	}})
+
+	conns := []model.ConnConfig{
+		// credential-holder-prefixed name
+		{ConfigName: "teama-aws-ap-northeast-2", ProviderName: "aws", RegionDetail: model.RegionDetail{RegionName: "ap-northeast-2"}},
+		// name unrelated to the provider and the region (resolved by the assigned region)
+		{ConfigName: "my-conn-01", ProviderName: "ibm", RegionZoneInfo: model.RegionZoneInfo{AssignedRegion: "us-south"}},
+		// multi-dash region which is not in the cloud info (the stored region detail is used)
+		{ConfigName: "azure-korea-central-2", ProviderName: "azure", RegionDetail: model.RegionDetail{RegionName: "korea-central-2", Zones: []string{"1"}}},
+		// no region
+		{ConfigName: "aws-no-region", ProviderName: "aws"},
+	}
+	for _, conn := range conns {
+		if err := putConnConfig(conn); err != nil {
+			t.Fatal(err)
+		}
+		defer kvstore.Delete(GenConnectionKey(conn.ConfigName))
+	}
+
+	tests := []struct {
+		connectionName string
+		regionName     string
+		zones          int
+		wantErr        bool
+	}{
+		{"teama-aws-ap-northeast-2", "ap-northeast-2", 2, false},
+		{"my-conn-01", "us-south", 1, false},
+		{"azure-korea-central-2", "korea-central-2", 1, false},
+		{"aws-no-region", "", 0, true},
+		{"missing-aws-ap-northeast-2", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.connectionName, func(t *testing.T) {
+			region, err := GetRegionDetailByConnection(tt.connectionName)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", region)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if region.RegionName != tt.regionName || len(region.Zones) != tt.zones {
+				t.Errorf("got %+v, want region %s with %d zones", region, tt.regionName, tt.zones)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestValidationError is returned when the request body is invalid, with the details of the invalid fields
+type RequestValidationError struct {
+	Fields []model.FieldError
+}
+
+func (e *RequestValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Message)
+	}
+	return "invalid request body: " + strings.Join(messages, "; ")
+}
+
+// trimStructName removes the name of the top-level struct from the namespace of a validator error (TbMciReq.vm[0].name -> vm[0].name)
+func trimStructName(namespace string) string {
+	if idx := strings.Index(namespace, "."); idx >= 0 {
+		return namespace[idx+1:]
+	}
+	return namespace
+}
+
+// ToRequestValidationError converts validator errors and JSON decoding errors into RequestValidationError.
+// It returns the given error as it is if it cannot be converted.
+func ToRequestValidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var validationErr *RequestValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr
+	}
+
+	var validatorErrs validator.ValidationErrors
+	if errors.As(err, &validatorErrs) {
+		fields := make([]model.FieldError, 0, len(validatorErrs))
+		for _, fe := range validatorErrs {
+			field := trimStructName(fe.Namespace())
+			rule := fe.Tag()
+			if fe.Param() != "" {
+				rule += "=" + fe.Param()
+			}
+			fields = append(fields, model.FieldError{
+				Field:   field,
+				Value:   fe.Value(),
+				Rule:    rule,
+				Message: fmt.Sprintf("%s failed on the '%s' rule", field, rule),
+			})
+		}
+		return &RequestValidationError{Fields: fields}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field := jsonFieldPath(typeErr.Field)
+		if field == "" {
+			field = "(body)"
+		}
+		return &RequestValidationError{Fields: []model.FieldError{{
+			Field:   field,
+			Value:   typeErr.Value,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be %s (got %s)", field, typeErr.Type.String(), typeErr.Value),
+		}}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &RequestValidationError{Fields: []model.FieldError{{
+			Field:   "(body)",
+			Rule:    "syntax",
+			Message: fmt.Sprintf("malformed JSON at offset %d: %s", syntaxErr.Offset, syntaxErr.Error()),
+		}}}
+	}
+
+	// error of json.Decoder with DisallowUnknownFields (there is no typed error for it)
+	const unknownFieldPrefix = "json: unknown field "
+	if strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldPrefix), "\"")
+		return &RequestValidationError{Fields: []model.FieldError{{
+			Field:   field,
+			Rule:    "unknown",
+			Message: fmt.Sprintf("%s is not a field of the request body", field),
+		}}}
+	}
+	return err
+}
+
+// jsonFieldPath returns the field path of a JSON decoding error in the form of validator errors (vm.0.name -> vm[0].name)
+func jsonFieldPath(field string) string {
+	segments := strings.Split(field, ".")
+	path := ""
+	for _, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil && path != "" {
+			path += "[" + segment + "]"
+			continue
+		}
+		if path != "" {
+			path += "."
+		}
+		path += segment
+	}
+	return path
+}
+
+// jsonFieldNames returns the JSON names of the fields of the struct type (including the fields of embedded structs)
+func jsonFieldNames(t reflect.Type) map[string]reflect.Type {
+	names := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range jsonFieldNames(ft) {
+				if _, ok := names[embeddedName]; !ok {
+					names[embeddedName] = embeddedType
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = f.Type
+	}
+	return names
+}
+
+// checkUnknownFields returns the fields of the JSON value which are not the fields of the type by the exact name.
+// encoding/json matches field names case-insensitively, so a typo in the case (e.g., subgroupSize for subGroupSize)
+// is not rejected by DisallowUnknownFields.
+func checkUnknownFields(value interface{}, t reflect.Type, path string) []model.FieldError {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := []model.FieldError{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			names := jsonFieldNames(t)
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fieldPath := key
+				if path != "" {
+					fieldPath = path + "." + key
+				}
+				fieldType, ok := names[key]
+				if !ok {
+					fields = append(fields, model.FieldError{
+						Field:   fieldPath,
+						Rule:    "unknown",
+						Message: fmt.Sprintf("%s is not a field of the request body", fieldPath),
+					})
+					continue
+				}
+				fields = append(fields, checkUnknownFields(v[key], fieldType, fieldPath)...)
+			}
+		case reflect.Map:
+			for key, elem := range v {
+				fields = append(fields, checkUnknownFields(elem, t.Elem(), path+"."+key)...)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, elem := range v {
+				fields = append(fields, checkUnknownFields(elem, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return fields
+}
+
+// BindRequest binds the request body to req and converts the binding error into RequestValidationError.
+// With the query param strict=true, unknown fields in the body are rejected (e.g., a typo of a field name
+// including its case, such as subgroupSize for subGroupSize).
+func BindRequest(c echo.Context, req interface{}) error {
+	if c.QueryParam("strict") != "true" {
+		return ToRequestValidationError(c.Bind(req))
+	}
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return ToRequestValidationError(err)
+	}
+	if fields := checkUnknownFields(value, reflect.TypeOf(req), ""); len(fields) > 0 {
+		return &RequestValidationError{Fields: fields}
+	}
+	return ToRequestValidationError(json.Unmarshal(body, req))
+}
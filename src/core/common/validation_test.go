@@ -0,0 +1,163 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	validator "github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// validationTestReq is a request body for the tests of the validation errors
+type validationTestReq struct {
+	Name string                 `json:"name" validate:"required"`
+	Vm   []validationTestVmReq  `json:"vm" validate:"dive"`
+	Meta map[string]interface{} `json:"meta"`
+}
+
+type validationTestVmReq struct {
+	SubGroupSize string `json:"subGroupSize" validate:"required"`
+	RootDiskType string `json:"rootDiskType" validate:"omitempty,oneof=default ssd"`
+}
+
+// newValidationTestValidator returns the validator with the JSON names of the fields as the handlers do
+func newValidationTestValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		return strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	})
+	return v
+}
+
+// postValidationTest serves the body through BindRequest, the validator and EndRequestWithLog,
+// and returns the status and the response body
+func postValidationTest(t *testing.T, target string, body string) (int, string) {
+	t.Helper()
+	e := echo.New()
+	e.POST("/tumblebug/test", func(c echo.Context) error {
+		req := &validationTestReq{}
+		if err := BindRequest(c, req); err != nil {
+			return EndRequestWithLog(c, err, nil)
+		}
+		if err := newValidationTestValidator().Struct(req); err != nil {
+			return EndRequestWithLog(c, err, nil)
+		}
+		return EndRequestWithLog(c, nil, req)
+	})
+
+	reqId := "validation-test"
+	RequestMap.Store(reqId, RequestDetails{StartTime: time.Now(), Status: RequestStatusHandling})
+	t.Cleanup(func() { RequestMap.Delete(reqId) })
+
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRequestID, reqId)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+// assertJsonEqual compares the JSON documents regardless of the formatting
+func assertJsonEqual(t *testing.T, got string, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal([]byte(got), &gotValue); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid JSON %q: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestRequestValidationErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			"validator errors", "/tumblebug/test",
+			`{"vm":[{"subGroupSize":"1","rootDiskType":"hdd"},{}]}`,
+			http.StatusBadRequest,
+			`{"code":"ValidationFailed","requestId":"validation-test",
+			  "message":"invalid request body: name failed on the 'required' rule; vm[0].rootDiskType failed on the 'oneof=default ssd' rule; vm[1].subGroupSize failed on the 'required' rule",
+			  "details":[
+			    {"field":"name","value":"","rule":"required","message":"name failed on the 'required' rule"},
+			    {"field":"vm[0].rootDiskType","value":"hdd","rule":"oneof=default ssd","message":"vm[0].rootDiskType failed on the 'oneof=default ssd' rule"},
+			    {"field":"vm[1].subGroupSize","value":"","rule":"required","message":"vm[1].subGroupSize failed on the 'required' rule"}]}`,
+		},
+		{
+			"type error", "/tumblebug/test",
+			`{"name":"mci01","vm":[{"subGroupSize":2}]}`,
+			http.StatusBadRequest,
+			`{"code":"ValidationFailed","requestId":"validation-test",
+			  "message":"invalid request body: vm[0].subGroupSize must be string (got number)",
+			  "details":[{"field":"vm[0].subGroupSize","value":"number","rule":"type","message":"vm[0].subGroupSize must be string (got number)"}]}`,
+		},
+		{
+			"type error in strict mode", "/tumblebug/test?strict=true",
+			`{"name":"mci01","vm":[{"subGroupSize":2}]}`,
+			http.StatusBadRequest,
+			`{"code":"ValidationFailed","requestId":"validation-test",
+			  "message":"invalid request body: vm[0].subGroupSize must be string (got number)",
+			  "details":[{"field":"vm[0].subGroupSize","value":"number","rule":"type","message":"vm[0].subGroupSize must be string (got number)"}]}`,
+		},
+		{
+			"unknown top-level field in strict mode", "/tumblebug/test?strict=true",
+			`{"name":"mci01","nmae":"mci02","meta":{"any":{"key":1}}}`,
+			http.StatusBadRequest,
+			`{"code":"ValidationFailed","requestId":"validation-test",
+			  "message":"invalid request body: nmae is not a field of the request body",
+			  "details":[{"field":"nmae","rule":"unknown","message":"nmae is not a field of the request body"}]}`,
+		},
+		{
+			"unknown field in strict mode", "/tumblebug/test?strict=true",
+			`{"name":"mci01","vm":[{"subgroupSize":"2"}]}`,
+			http.StatusBadRequest,
+			`{"code":"ValidationFailed","requestId":"validation-test",
+			  "message":"invalid request body: vm[0].subgroupSize is not a field of the request body",
+			  "details":[{"field":"vm[0].subgroupSize","rule":"unknown","message":"vm[0].subgroupSize is not a field of the request body"}]}`,
+		},
+		{
+			// the field names are matched case-insensitively without strict mode
+			"unknown field without strict mode", "/tumblebug/test",
+			`{"name":"mci01","vm":[{"subGroupSize":"2","subgroupSize":"3"}]}`,
+			http.StatusOK,
+			`{"name":"mci01","vm":[{"subGroupSize":"3","rootDiskType":""}],"meta":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postValidationTest(t, tt.target, tt.body)
+			if status != tt.status {
+				t.Errorf("status %d, want %d (%s)", status, tt.status, body)
+			}
+			assertJsonEqual(t, body, tt.want)
+		})
+	}
+}
+
+func TestToRequestValidationErrorSyntax(t *testing.T) {
+	var req validationTestReq
+	err := ToRequestValidationError(json.Unmarshal([]byte(`{"name":`), &req))
+	validationErr, ok := err.(*RequestValidationError)
+	if !ok || len(validationErr.Fields) != 1 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if field := validationErr.Fields[0]; field.Field != "(body)" || field.Rule != "syntax" {
+		t.Errorf("unexpected field error: %+v", field)
+	}
+	if err := ToRequestValidationError(http.ErrBodyNotAllowed); err != http.ErrBodyNotAllowed {
+		t.Errorf("an unrelated error is converted: %v", err)
+	}
+}
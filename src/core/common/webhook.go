@@ -0,0 +1,496 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common is to include common methods for managing multi-cloud infra
+package common
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvutil"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// webhookMaxAttempts is the maximum number of delivery attempts of an event to a webhook
+	webhookMaxAttempts = 5
+	// webhookInitialBackoff is the wait before the first retry (doubled for each retry)
+	webhookInitialBackoff = 2 * time.Second
+	// webhookMaxDeliveries is the number of delivery attempts kept per webhook
+	webhookMaxDeliveries = 100
+	// webhookMaxConcurrency is the maximum number of concurrent deliveries
+	webhookMaxConcurrency = 16
+)
+
+// webhookRecord is the webhook object stored in the Key-Value store
+type webhookRecord struct {
+	model.WebhookInfo
+	// Secret is the signing secret encrypted by EncryptSecret (if TB_ENCRYPTION_KEY is set)
+	Secret string `json:"secret,omitempty"`
+}
+
+// webhookBlockedHosts are the host names of cloud metadata services which webhooks must not reach
+var webhookBlockedHosts = []string{"metadata.google.internal", "metadata.azure.com", "metadata"}
+
+// webhookMetadataIps are the addresses of cloud metadata services which are not link-local (e.g., AWS IPv6)
+var webhookMetadataIps = []net.IP{net.ParseIP("fd00:ec2::254"), net.ParseIP("100.100.100.200")}
+
+// checkWebhookIp returns an error if the IP is loopback, link-local (incl. 169.254.169.254), unspecified, multicast
+// or a cloud metadata address, so that webhooks cannot be used to reach the server itself or the metadata services (SSRF)
+var checkWebhookIp = func(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("the webhook address (%s) is not allowed (loopback, link-local or unspecified)", ip)
+	}
+	for _, metadataIp := range webhookMetadataIps {
+		if ip.Equal(metadataIp) {
+			return fmt.Errorf("the webhook address (%s) is not allowed (metadata service)", ip)
+		}
+	}
+	return nil
+}
+
+// newWebhookHttpClient returns the HTTP client for webhooks which checks the address of every connection
+// (including redirects and DNS names re-resolved to other addresses) by checkWebhookIp
+func newWebhookHttpClient() *resty.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("invalid webhook address (%s)", address)
+			}
+			return checkWebhookIp(ip)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return resty.New().SetTransport(transport).SetTimeout(10 * time.Second)
+}
+
+// validateWebhookUrl checks the scheme and the host of the webhook URL (the resolved addresses by checkWebhookIp)
+func validateWebhookUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid webhook URL (%s): http or https URL is required", rawUrl)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for _, blocked := range webhookBlockedHosts {
+		if host == blocked {
+			return fmt.Errorf("invalid webhook URL (%s): the host is not allowed (metadata service)", rawUrl)
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return checkWebhookIp(ip)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL (%s): %w", rawUrl, err)
+	}
+	for _, addr := range addrs {
+		if err := checkWebhookIp(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	webhookEventQueue    = make(chan model.WebhookEvent, 1000)
+	webhookDispatcherRun sync.Once
+	webhookDeliveryLock  sync.Mutex
+	webhookHttpClient    = newWebhookHttpClient()
+
+	// objectRequestMap keeps the request which is changing the object (target -> X-Request-Id)
+	objectRequestMap = sync.Map{}
+)
+
+// GenWebhookKey is func to generate the key of a webhook object
+func GenWebhookKey(webhookId string) string {
+	return "/webhook/" + webhookId
+}
+
+// genWebhookDeliveryKey is func to generate the key of delivery attempts of a webhook
+func genWebhookDeliveryKey(webhookId string) string {
+	return "/webhookDelivery/" + webhookId
+}
+
+// TrackObjectRequest records the API request which is changing the object (e.g., /ns/default/mci/mci01),
+// so that events from the object carry the request ID. The returned function must be called when the request is finished.
+func TrackObjectRequest(target string, requestId string) func() {
+	if target == "" || requestId == "" {
+		return func() {}
+	}
+	objectRequestMap.Store(target, requestId)
+	return func() {
+		objectRequestMap.CompareAndDelete(target, requestId)
+	}
+}
+
+// lookupObjectRequest returns the request ID which is changing the object or its parents
+func lookupObjectRequest(target string) string {
+	if v := lookupTrackedObject(&objectRequestMap, target); v != nil {
+		return v.(string)
+	}
+	return ""
+}
+
+// lookupTrackedObject returns the value tracked for the object or its nearest parent (nil if none)
+func lookupTrackedObject(m *sync.Map, target string) interface{} {
+	for t := target; t != "" && t != "/"; {
+		if v, ok := m.Load(t); ok {
+			return v
+		}
+		i := len(t) - 1
+		for i >= 0 && t[i] != '/' {
+			i--
+		}
+		if i <= 0 {
+			break
+		}
+		t = t[:i]
+	}
+	return nil
+}
+
+// CreateWebhook subscribes a URL to events
+func CreateWebhook(req *model.WebhookReq) (model.WebhookInfo, error) {
+	if err := validateWebhookUrl(req.Url); err != nil {
+		return model.WebhookInfo{}, err
+	}
+	for _, eventType := range req.Events {
+		known := false
+		for _, t := range model.WebhookEventTypes {
+			if eventType == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return model.WebhookInfo{}, fmt.Errorf("unknown event type (%s): one of %v is required", eventType, model.WebhookEventTypes)
+		}
+	}
+
+	secret, err := EncryptSecret(req.Secret)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encrypt the secret of the webhook")
+		return model.WebhookInfo{}, err
+	}
+	record := webhookRecord{
+		WebhookInfo: model.WebhookInfo{
+			Id:          "wh-" + GenUid(),
+			Url:         req.Url,
+			HasSecret:   req.Secret != "",
+			Events:      req.Events,
+			NsId:        req.NsId,
+			Description: req.Description,
+			CreatedTime: time.Now(),
+		},
+		Secret: secret,
+	}
+	val, err := json.Marshal(record)
+	if err != nil {
+		return model.WebhookInfo{}, err
+	}
+	err = kvstore.Put(GenWebhookKey(record.Id), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.WebhookInfo{}, err
+	}
+	return record.WebhookInfo, nil
+}
+
+func getWebhookRecord(webhookId string) (webhookRecord, error) {
+	record := webhookRecord{}
+	keyValue, err := kvstore.GetKv(GenWebhookKey(webhookId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return record, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return record, fmt.Errorf("the webhook (%s) is not found", webhookId)
+	}
+	err = json.Unmarshal([]byte(keyValue.Value), &record)
+	return record, err
+}
+
+func listWebhookRecords() ([]webhookRecord, error) {
+	records := []webhookRecord{}
+	keyValue, err := kvstore.GetKvList("/webhook/")
+	if err != nil {
+		return records, err
+	}
+	for _, kv := range kvutil.FilterKvListBy(keyValue, "/webhook", 1) {
+		record := webhookRecord{}
+		if err := json.Unmarshal([]byte(kv.Value), &record); err != nil {
+			log.Error().Err(err).Str("key", kv.Key).Msg("Failed to unmarshal webhook")
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedTime.Before(records[j].CreatedTime)
+	})
+	return records, nil
+}
+
+// GetWebhook returns the webhook (without the secret)
+func GetWebhook(webhookId string) (model.WebhookInfo, error) {
+	record, err := getWebhookRecord(webhookId)
+	return record.WebhookInfo, err
+}
+
+// ListWebhooks returns all webhooks (without secrets)
+func ListWebhooks() (model.WebhookInfoList, error) {
+	result := model.WebhookInfoList{Webhooks: []model.WebhookInfo{}}
+	records, err := listWebhookRecords()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	for _, record := range records {
+		result.Webhooks = append(result.Webhooks, record.WebhookInfo)
+	}
+	return result, nil
+}
+
+// DelWebhook unsubscribes the webhook and deletes its delivery attempts
+func DelWebhook(webhookId string) error {
+	if _, err := getWebhookRecord(webhookId); err != nil {
+		return err
+	}
+	if err := kvstore.Delete(GenWebhookKey(webhookId)); err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	return kvstore.Delete(genWebhookDeliveryKey(webhookId))
+}
+
+// GetWebhookDeliveries returns the recent delivery attempts of the webhook (newest first)
+func GetWebhookDeliveries(webhookId string) (model.WebhookDeliveryList, error) {
+	result := model.WebhookDeliveryList{Deliveries: []model.WebhookDelivery{}}
+	if _, err := getWebhookRecord(webhookId); err != nil {
+		return result, err
+	}
+	val, err := kvstore.Get(genWebhookDeliveryKey(webhookId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	if val != "" {
+		if err := json.Unmarshal([]byte(val), &result.Deliveries); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// recordWebhookDelivery prepends the delivery attempt to the list of the webhook (capped by webhookMaxDeliveries)
+func recordWebhookDelivery(webhookId string, delivery model.WebhookDelivery) {
+	webhookDeliveryLock.Lock()
+	defer webhookDeliveryLock.Unlock()
+
+	deliveries := []model.WebhookDelivery{}
+	if val, err := kvstore.Get(genWebhookDeliveryKey(webhookId)); err == nil && val != "" {
+		json.Unmarshal([]byte(val), &deliveries)
+	}
+	deliveries = append([]model.WebhookDelivery{delivery}, deliveries...)
+	if len(deliveries) > webhookMaxDeliveries {
+		deliveries = deliveries[:webhookMaxDeliveries]
+	}
+	val, err := json.Marshal(deliveries)
+	if err != nil {
+		return
+	}
+	if err := kvstore.Put(genWebhookDeliveryKey(webhookId), string(val)); err != nil {
+		log.Error().Err(err).Msgf("Failed to record delivery of webhook (%s)", webhookId)
+	}
+}
+
+// deliverWebhookEvent sends the event to the webhook once and records the attempt
+func deliverWebhookEvent(record webhookRecord, event model.WebhookEvent, attempt int) model.WebhookDelivery {
+	delivery := model.WebhookDelivery{
+		EventId:   event.Id,
+		EventType: event.Type,
+		Attempt:   attempt,
+		Time:      time.Now(),
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		"X-Tumblebug-Event":    event.Type,
+		"X-Tumblebug-Delivery": event.Id,
+	}
+	if event.RequestId != "" {
+		headers["X-Request-Id"] = event.RequestId
+	}
+	if record.Secret != "" {
+		secret, err := DecryptSecret(record.Secret)
+		if err != nil {
+			delivery.Error = "failed to decrypt the secret of the webhook: " + err.Error()
+			recordWebhookDelivery(record.Id, delivery)
+			return delivery
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		headers["X-Tumblebug-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	resp, err := webhookHttpClient.R().SetHeaders(headers).SetBody(body).Post(record.Url)
+	switch {
+	case err != nil:
+		delivery.Error = err.Error()
+	case resp.IsError():
+		delivery.StatusCode = resp.StatusCode()
+		delivery.Error = fmt.Sprintf("status code: %s", resp.Status())
+	default:
+		delivery.StatusCode = resp.StatusCode()
+		delivery.Success = true
+	}
+	recordWebhookDelivery(record.Id, delivery)
+	return delivery
+}
+
+// deliverWebhookEventWithRetry sends the event with exponential backoff until it succeeds or attempts are exhausted.
+// The caller holds a slot of the semaphore for the first attempt; the slot is released during the backoff
+// and taken again for each retry, so that retries do not block the deliveries of other events.
+func deliverWebhookEventWithRetry(semaphore chan struct{}, record webhookRecord, event model.WebhookEvent) {
+	backoff := webhookInitialBackoff
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		if attempt > 1 {
+			semaphore <- struct{}{}
+		}
+		delivery := deliverWebhookEvent(record, event, attempt)
+		<-semaphore
+		if delivery.Success {
+			return
+		}
+		if attempt == webhookMaxAttempts {
+			log.Warn().Msgf("Failed to deliver event (%s, %s) to webhook (%s) after %d attempts: %s", event.Type, event.Id, record.Id, attempt, delivery.Error)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		// the webhook may be deleted during the backoff
+		if _, err := getWebhookRecord(record.Id); err != nil {
+			log.Debug().Msgf("Stopped delivering event (%s, %s) to webhook (%s): %v", event.Type, event.Id, record.Id, err)
+			return
+		}
+	}
+}
+
+// webhookMatches returns true if the webhook subscribes to the event
+func webhookMatches(record webhookRecord, event model.WebhookEvent) bool {
+	if record.NsId != "" && record.NsId != event.NsId {
+		return false
+	}
+	if len(record.Events) == 0 {
+		return true
+	}
+	for _, t := range record.Events {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// runWebhookDispatcher delivers queued events to the matching webhooks in background
+func runWebhookDispatcher() {
+	semaphore := make(chan struct{}, webhookMaxConcurrency)
+	for event := range webhookEventQueue {
+		records, err := listWebhookRecords()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list webhooks for event delivery")
+			continue
+		}
+		for _, record := range records {
+			if !webhookMatches(record, event) {
+				continue
+			}
+			semaphore <- struct{}{}
+			go deliverWebhookEventWithRetry(semaphore, record, event)
+		}
+	}
+}
+
+// EmitEvent notifies the event on the object (target) to local subscribers and the subscribed webhooks asynchronously.
+// The request ID is filled from the API request which is changing the object (see TrackObjectRequest).
+func EmitEvent(eventType string, nsId string, target string, data interface{}) {
+	webhookDispatcherRun.Do(func() {
+		go runWebhookDispatcher()
+	})
+
+	event := model.WebhookEvent{
+		Id:        "evt-" + GenUid(),
+		Type:      eventType,
+		Time:      time.Now(),
+		NsId:      nsId,
+		Target:    target,
+		RequestId: lookupObjectRequest(target),
+		Data:      data,
+	}
+	publishEvent(event)
+
+	select {
+	case webhookEventQueue <- event:
+	default:
+		log.Warn().Msgf("Webhook event queue is full. Event (%s, %s) is dropped", eventType, target)
+	}
+}
+
+// TestWebhook sends a test event to the webhook once and returns the delivery attempt
+func TestWebhook(webhookId string, requestId string) (model.WebhookDelivery, error) {
+	record, err := getWebhookRecord(webhookId)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	event := model.WebhookEvent{
+		Id:        "evt-" + GenUid(),
+		Type:      model.EventWebhookTest,
+		Time:      time.Now(),
+		NsId:      record.NsId,
+		Target:    GenWebhookKey(webhookId),
+		RequestId: requestId,
+		Data:      map[string]string{"message": "This is a test event from CB-Tumblebug"},
+	}
+	return deliverWebhookEvent(record, event, 1), nil
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// allowLoopbackWebhooks lets webhooks reach the test servers on the loopback address
+func allowLoopbackWebhooks(t *testing.T) {
+	prev := checkWebhookIp
+	checkWebhookIp = func(ip net.IP) error {
+		if ip.IsLoopback() {
+			return nil
+		}
+		return prev(ip)
+	}
+	t.Cleanup(func() { checkWebhookIp = prev })
+}
+
+// setTestMasterKey enables the encryption of secrets for the test
+func setTestMasterKey(t *testing.T) {
+	loadMasterKey()
+	prev := masterKey
+	sum := sha256.Sum256([]byte("test master key"))
+	masterKey = sum[:]
+	t.Cleanup(func() { masterKey = prev })
+}
+
+func TestValidateWebhookUrl(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://203.0.113.10/hook", true},
+		{"http://10.0.0.5:8080/hook", true},
+		{"http://[2001:db8::1]/hook", true},
+		{"ftp://203.0.113.10/hook", false},
+		{"http:///hook", false},
+		{"http://127.0.0.1:1323/tumblebug/ns", false},
+		{"http://[::1]/hook", false},
+		{"http://localhost/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[fd00:ec2::254]/latest/meta-data/", false},
+		{"http://metadata.google.internal/computeMetadata/v1/", false},
+		{"http://METADATA.google.internal./computeMetadata/v1/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := validateWebhookUrl(tt.url)
+			if tt.allowed && err != nil {
+				t.Errorf("expected allowed, got %v", err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+// TestWebhookHttpClientBlocksLoopback checks the address at connection time (e.g., a DNS name re-resolved to loopback)
+func TestWebhookHttpClientBlocksLoopback(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	if _, err := newWebhookHttpClient().R().Post(server.URL); err == nil {
+		t.Errorf("expected the connection to the loopback address to be blocked")
+	}
+	if atomic.LoadInt32(&received) != 0 {
+		t.Errorf("the request reached the loopback server")
+	}
+}
+
+func TestWebhookSecretEncryptedAndSigned(t *testing.T) {
+	allowLoopbackWebhooks(t)
+	setTestMasterKey(t)
+
+	signatures := make(chan string, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		signatures <- r.Header.Get("X-Tumblebug-Signature")
+	}))
+	defer server.Close()
+
+	info, err := CreateWebhook(&model.WebhookReq{Url: server.URL, Secret: "signing-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DelWebhook(info.Id)
+
+	val, err := kvstore.Get(GenWebhookKey(info.Id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := webhookRecord{}
+	if err := json.Unmarshal([]byte(val), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncryptedSecret(stored.Secret) {
+		t.Fatalf("the secret is stored in plaintext: %q", stored.Secret)
+	}
+
+	delivery, err := TestWebhook(info.Id, "")
+	if err != nil || !delivery.Success {
+		t.Fatalf("delivery failed: %+v, %v", delivery, err)
+	}
+	mac := hmac.New(sha256.New, []byte("signing-secret"))
+	mac.Write(<-bodies)
+	if want, got := "sha256="+hex.EncodeToString(mac.Sum(nil)), <-signatures; got != want {
+		t.Errorf("signature %q, want %q", got, want)
+	}
+}
+
+// TestWebhookRetryReleasesSlot checks that a delivery in backoff does not hold a slot of the semaphore
+// (the webhook is not stored, so the delivery stops after the backoff)
+func TestWebhookRetryReleasesSlot(t *testing.T) {
+	allowLoopbackWebhooks(t)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	record := webhookRecord{WebhookInfo: model.WebhookInfo{Id: "wh-retry-test", Url: server.URL}}
+	event := model.WebhookEvent{Id: "evt-retry-test", Type: model.EventWebhookTest}
+	semaphore := make(chan struct{}, 1)
+	semaphore <- struct{}{}
+	go deliverWebhookEventWithRetry(semaphore, record, event)
+
+	deadline := time.Now().Add(webhookInitialBackoff / 2)
+	for atomic.LoadInt32(&attempts) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case semaphore <- struct{}{}:
+		// the slot is free during the backoff
+		<-semaphore
+	case <-time.After(webhookInitialBackoff / 2):
+		t.Fatal("the slot is held during the backoff")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("attempts %d, want 1 before the backoff", n)
+	}
+}
@@ -0,0 +1,808 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/rs/zerolog/log"
+)
+
+// applyItem is a change of the apply plan with the function to execute it (nil if nothing is executed)
+type applyItem struct {
+	change model.ApplyChange
+	run    func() error
+}
+
+// applyRef returns the reference of a resource in the plan (resourceType/id)
+func applyRef(resourceType string, id string) string {
+	return resourceType + "/" + id
+}
+
+// isSharedResourceId returns true if the resource is a shared resource created for dynamic MCIs (not managed by the manifest)
+func isSharedResourceId(nsId string, resourceType string, id string) bool {
+	return strings.Contains(id, model.StrSharedResourceName) || resource.IsSharedResourceName(nsId, resourceType, id)
+}
+
+// newApplyItem returns the item of the plan with the action and the function to execute it
+func newApplyItem(resourceType string, id string, action string, details []string, dependsOn []string, run func() error) applyItem {
+	sort.Strings(dependsOn)
+	return applyItem{
+		change: model.ApplyChange{
+			ResourceType: resourceType,
+			Id:           id,
+			Action:       action,
+			Details:      details,
+			DependsOn:    dependsOn,
+			Status:       model.ApplyStatusPlanned,
+		},
+		run: run,
+	}
+}
+
+// validateNsManifest checks the names in the manifest and the references between the resources
+func validateNsManifest(nsId string, manifest *model.NsManifest) error {
+	names := map[string]bool{}
+	check := func(resourceType string, name string) error {
+		if err := common.CheckString(name); err != nil {
+			return common.NewValidationFailedError("invalid name of %s (%s): %v", resourceType, name, err)
+		}
+		ref := applyRef(resourceType, name)
+		if names[ref] {
+			return common.NewValidationFailedError("%s %s is declared more than once in the manifest", resourceType, name)
+		}
+		names[ref] = true
+		return nil
+	}
+	for _, v := range manifest.VNet {
+		if err := check(model.StrVNet, v.Name); err != nil {
+			return err
+		}
+	}
+	for _, v := range manifest.SecurityGroup {
+		if err := check(model.StrSecurityGroup, v.Name); err != nil {
+			return err
+		}
+	}
+	for _, v := range manifest.SshKey {
+		if err := check(model.StrSSHKey, v.Name); err != nil {
+			return err
+		}
+	}
+	for _, v := range manifest.MciDynamic {
+		if err := check(model.StrMCI, v.Name); err != nil {
+			return err
+		}
+	}
+	for _, v := range manifest.Mci {
+		if err := check(model.StrMCI, v.Name); err != nil {
+			return err
+		}
+	}
+
+	// references should be in the manifest or in the namespace
+	exists := func(resourceType string, id string) bool {
+		if names[applyRef(resourceType, id)] {
+			return true
+		}
+		ok, _ := resource.CheckResource(nsId, resourceType, id)
+		return ok
+	}
+	for _, sg := range manifest.SecurityGroup {
+		if !exists(model.StrVNet, sg.VNetId) {
+			return common.NewValidationFailedError("the vNet %s of the securityGroup %s is neither in the manifest nor in the namespace", sg.VNetId, sg.Name)
+		}
+	}
+	for _, mci := range manifest.Mci {
+		for _, vm := range mci.Vm {
+			if !exists(model.StrVNet, vm.VNetId) {
+				return common.NewValidationFailedError("the vNet %s of the mci %s is neither in the manifest nor in the namespace", vm.VNetId, mci.Name)
+			}
+			if !exists(model.StrSSHKey, vm.SshKeyId) {
+				return common.NewValidationFailedError("the sshKey %s of the mci %s is neither in the manifest nor in the namespace", vm.SshKeyId, mci.Name)
+			}
+			for _, sgId := range vm.SecurityGroupIds {
+				if !exists(model.StrSecurityGroup, sgId) {
+					return common.NewValidationFailedError("the securityGroup %s of the mci %s is neither in the manifest nor in the namespace", sgId, mci.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// firewallRuleKey returns the key to compare firewall rules
+func firewallRuleKey(rule model.TbFirewallRuleInfo) string {
+	return strings.ToLower(strings.Join([]string{rule.Direction, rule.IPProtocol, rule.FromPort, rule.ToPort, rule.CIDR}, "|"))
+}
+
+// planVNets returns the changes of vNets (and their subnets) to converge to the manifest
+func planVNets(ctx context.Context, nsId string, desired []model.TbVNetReq, prune bool) ([]applyItem, error) {
+	items := []applyItem{}
+	for i := range desired {
+		req := desired[i]
+		exists, err := resource.CheckResource(nsId, model.StrVNet, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			items = append(items, newApplyItem(model.StrVNet, req.Name, model.ApplyActionCreate, nil, nil, func() error {
+				if err := resource.ValidateVNetReq(&req); err != nil {
+					return err
+				}
+				_, err := resource.CreateVNet(ctx, nsId, &req)
+				return err
+			}))
+			continue
+		}
+		current, err := resource.GetVNet(nsId, req.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		details := []string{}
+		if req.ConnectionName != current.ConnectionName {
+			details = append(details, fmt.Sprintf("connectionName: %s -> %s", current.ConnectionName, req.ConnectionName))
+		}
+		if req.CidrBlock != "" && req.CidrBlock != current.CidrBlock {
+			details = append(details, fmt.Sprintf("cidrBlock: %s -> %s", current.CidrBlock, req.CidrBlock))
+		}
+		if len(details) > 0 {
+			items = append(items, newApplyItem(model.StrVNet, req.Name, model.ApplyActionReplace, details, nil, nil))
+			continue
+		}
+
+		currentSubnets := map[string]bool{}
+		for _, s := range current.SubnetInfoList {
+			currentSubnets[s.Id] = true
+		}
+		subnetsToCreate := []model.TbSubnetReq{}
+		desiredSubnets := map[string]bool{}
+		for _, s := range req.SubnetInfoList {
+			desiredSubnets[s.Name] = true
+			if !currentSubnets[s.Name] {
+				subnetsToCreate = append(subnetsToCreate, s)
+				details = append(details, "subnet "+s.Name+": create")
+			}
+		}
+		subnetsToDelete := []string{}
+		for _, s := range current.SubnetInfoList {
+			if desiredSubnets[s.Id] {
+				continue
+			}
+			if prune {
+				subnetsToDelete = append(subnetsToDelete, s.Id)
+				details = append(details, "subnet "+s.Id+": delete")
+			} else {
+				details = append(details, "subnet "+s.Id+": not in the manifest (kept without prune)")
+			}
+		}
+		if len(subnetsToCreate) == 0 && len(subnetsToDelete) == 0 {
+			items = append(items, newApplyItem(model.StrVNet, req.Name, model.ApplyActionNoop, details, nil, nil))
+			continue
+		}
+		vNetId := req.Name
+		items = append(items, newApplyItem(model.StrVNet, vNetId, model.ApplyActionUpdate, details, nil, func() error {
+			for i := range subnetsToCreate {
+				if _, err := resource.CreateSubnet(nsId, vNetId, &subnetsToCreate[i]); err != nil {
+					return err
+				}
+			}
+			for _, subnetId := range subnetsToDelete {
+				if _, err := resource.DeleteSubnet(nsId, vNetId, subnetId, ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	}
+	return items, nil
+}
+
+// planSshKeys returns the changes of SSH keys to converge to the manifest
+func planSshKeys(ctx context.Context, nsId string, desired []model.TbSshKeyReq) ([]applyItem, error) {
+	items := []applyItem{}
+	for i := range desired {
+		req := desired[i]
+		exists, err := resource.CheckResource(nsId, model.StrSSHKey, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			items = append(items, newApplyItem(model.StrSSHKey, req.Name, model.ApplyActionCreate, nil, nil, func() error {
+				_, err := resource.CreateSshKey(ctx, nsId, &req, "")
+				return err
+			}))
+			continue
+		}
+		res, err := resource.GetResource(nsId, model.StrSSHKey, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		current, _ := res.(model.TbSshKeyInfo)
+
+		if req.ConnectionName != current.ConnectionName {
+			details := []string{fmt.Sprintf("connectionName: %s -> %s", current.ConnectionName, req.ConnectionName)}
+			items = append(items, newApplyItem(model.StrSSHKey, req.Name, model.ApplyActionReplace, details, nil, nil))
+			continue
+		}
+		if req.Description != current.Description {
+			details := []string{fmt.Sprintf("description: %q -> %q", current.Description, req.Description)}
+			items = append(items, newApplyItem(model.StrSSHKey, req.Name, model.ApplyActionUpdate, details, nil, func() error {
+				_, err := resource.UpdateSshKey(nsId, req.Name, model.TbSshKeyInfo{Description: req.Description})
+				return err
+			}))
+			continue
+		}
+		items = append(items, newApplyItem(model.StrSSHKey, req.Name, model.ApplyActionNoop, nil, nil, nil))
+	}
+	return items, nil
+}
+
+// planSecurityGroups returns the changes of security groups (and their firewall rules) to converge to the manifest
+func planSecurityGroups(ctx context.Context, nsId string, desired []model.TbSecurityGroupReq, vNetsInManifest map[string]bool) ([]applyItem, error) {
+	items := []applyItem{}
+	for i := range desired {
+		req := desired[i]
+		dependsOn := []string{}
+		if vNetsInManifest[req.VNetId] {
+			dependsOn = append(dependsOn, applyRef(model.StrVNet, req.VNetId))
+		}
+
+		exists, err := resource.CheckResource(nsId, model.StrSecurityGroup, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			items = append(items, newApplyItem(model.StrSecurityGroup, req.Name, model.ApplyActionCreate, nil, dependsOn, func() error {
+				_, err := resource.CreateSecurityGroup(ctx, nsId, &req, "")
+				return err
+			}))
+			continue
+		}
+		res, err := resource.GetResource(nsId, model.StrSecurityGroup, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		current, _ := res.(model.TbSecurityGroupInfo)
+
+		details := []string{}
+		if req.ConnectionName != current.ConnectionName {
+			details = append(details, fmt.Sprintf("connectionName: %s -> %s", current.ConnectionName, req.ConnectionName))
+		}
+		if req.VNetId != current.VNetId {
+			details = append(details, fmt.Sprintf("vNetId: %s -> %s", current.VNetId, req.VNetId))
+		}
+		if len(details) > 0 {
+			items = append(items, newApplyItem(model.StrSecurityGroup, req.Name, model.ApplyActionReplace, details, dependsOn, nil))
+			continue
+		}
+
+		// firewall rules are managed only if they are declared in the manifest
+		rulesToCreate := []model.TbFirewallRuleInfo{}
+		rulesToDelete := []model.TbFirewallRuleInfo{}
+		if req.FirewallRules != nil {
+			currentRules := map[string]bool{}
+			for _, rule := range current.FirewallRules {
+				currentRules[firewallRuleKey(rule)] = true
+			}
+			desiredRules := map[string]bool{}
+			for _, rule := range *req.FirewallRules {
+				key := firewallRuleKey(rule)
+				desiredRules[key] = true
+				if !currentRules[key] {
+					rulesToCreate = append(rulesToCreate, rule)
+					details = append(details, "firewallRule "+key+": create")
+				}
+			}
+			for _, rule := range current.FirewallRules {
+				key := firewallRuleKey(rule)
+				if !desiredRules[key] {
+					rulesToDelete = append(rulesToDelete, rule)
+					details = append(details, "firewallRule "+key+": delete")
+				}
+			}
+		}
+		if len(rulesToCreate) == 0 && len(rulesToDelete) == 0 {
+			items = append(items, newApplyItem(model.StrSecurityGroup, req.Name, model.ApplyActionNoop, nil, dependsOn, nil))
+			continue
+		}
+		sgId := req.Name
+		items = append(items, newApplyItem(model.StrSecurityGroup, sgId, model.ApplyActionUpdate, details, dependsOn, func() error {
+			if len(rulesToDelete) > 0 {
+				if _, err := resource.DeleteFirewallRules(nsId, sgId, rulesToDelete); err != nil {
+					return err
+				}
+			}
+			if len(rulesToCreate) > 0 {
+				if _, err := resource.CreateFirewallRules(nsId, sgId, rulesToCreate, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	}
+	return items, nil
+}
+
+// subGroupSize returns the size of the subgroup in the request (1 if not given)
+func subGroupSize(size string) int {
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// planMciSubGroups returns the differences of the subgroups of the existing MCI and the functions to converge them.
+// vmChanges returns the differences of an existing VM from the subgroup in the manifest (e.g., spec or image)
+// which cannot be applied in place; the MCI is reported to be replaced if any.
+// Scale-in and subgroups not in the manifest are reported only (VMs are not removed by apply).
+func planMciSubGroups(nsId string, mciId string, desiredSizes map[string]int, vmChanges func(name string, vm model.TbVmInfo) []string, addSubGroup func(name string) error) ([]string, []func() error, bool, error) {
+	currentIds, err := ListSubGroupId(nsId, mciId)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	current := map[string]int{}
+	currentVmIds := map[string][]string{}
+	for _, id := range currentIds {
+		vmIds, err := ListVmBySubGroup(nsId, mciId, id)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		current[id] = len(vmIds)
+		currentVmIds[id] = vmIds
+	}
+
+	names := make([]string, 0, len(desiredSizes))
+	for name := range desiredSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	details := []string{}
+	steps := []func() error{}
+	replace := false
+	for _, name := range names {
+		id := common.ToLower(name)
+		size := desiredSizes[name]
+		n, ok := current[id]
+		if ok {
+			changes := []string{}
+			for _, vmId := range currentVmIds[id] {
+				vm, err := GetVmObject(nsId, mciId, vmId)
+				if err != nil {
+					return nil, nil, false, err
+				}
+				if changes = vmChanges(name, vm); len(changes) > 0 {
+					break
+				}
+			}
+			if len(changes) > 0 {
+				for _, change := range changes {
+					details = append(details, fmt.Sprintf("subGroup %s: %s (VMs must be replaced)", id, change))
+				}
+				replace = true
+				continue
+			}
+		}
+		switch {
+		case !ok:
+			details = append(details, fmt.Sprintf("subGroup %s: create %d VM(s)", id, size))
+			steps = append(steps, func() error { return addSubGroup(name) })
+		case n < size:
+			details = append(details, fmt.Sprintf("subGroup %s: scale out %d -> %d", id, n, size))
+			toAdd := strconv.Itoa(size - n)
+			steps = append(steps, func() error {
+				_, err := ScaleOutMciSubGroup(nsId, mciId, id, toAdd)
+				return err
+			})
+		case n > size:
+			details = append(details, fmt.Sprintf("subGroup %s: %d VM(s) exceed the size %d (scale-in is not applied)", id, n, size))
+		}
+	}
+	for _, id := range currentIds {
+		found := false
+		for name := range desiredSizes {
+			if common.ToLower(name) == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			details = append(details, fmt.Sprintf("subGroup %s: not in the manifest (VMs are not removed by apply)", id))
+		}
+	}
+	return details, steps, replace, nil
+}
+
+// newMciApplyItem returns the item of the MCI by the differences and the steps to converge it.
+// The MCI is reported to be replaced (and no step is executed) if replace is set.
+func newMciApplyItem(mciId string, details []string, steps []func() error, replace bool, dependsOn []string) applyItem {
+	if replace {
+		return newApplyItem(model.StrMCI, mciId, model.ApplyActionReplace, details, dependsOn, nil)
+	}
+	if len(steps) == 0 {
+		action := model.ApplyActionNoop
+		if len(details) > 0 {
+			action = model.ApplyActionReplace
+		}
+		return newApplyItem(model.StrMCI, mciId, action, details, dependsOn, nil)
+	}
+	return newApplyItem(model.StrMCI, mciId, model.ApplyActionUpdate, details, dependsOn, func() error {
+		for _, step := range steps {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// planMcis returns the changes of MCIs (dynamic and static) to converge to the manifest
+func planMcis(ctx context.Context, nsId string, manifest *model.NsManifest, inManifest map[string]bool) ([]applyItem, error) {
+	items := []applyItem{}
+
+	for i := range manifest.MciDynamic {
+		req := manifest.MciDynamic[i]
+		exists, err := CheckMci(nsId, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			items = append(items, newApplyItem(model.StrMCI, req.Name, model.ApplyActionCreate, nil, nil, func() error {
+				_, err := CreateMciDynamic(ctx, "", nsId, &req, "")
+				return err
+			}))
+			continue
+		}
+		sizes := map[string]int{}
+		vmReqs := map[string]model.TbVmDynamicReq{}
+		for _, vm := range req.Vm {
+			sizes[vm.Name] = subGroupSize(vm.SubGroupSize)
+			vmReqs[vm.Name] = vm
+		}
+		mciId := req.Name
+		vmChanges := func(name string, vm model.TbVmInfo) []string {
+			vmReq := vmReqs[name]
+			changes := []string{}
+			if !strings.EqualFold(vmReq.CommonSpec, vm.SpecId) {
+				changes = append(changes, fmt.Sprintf("commonSpec: %s -> %s", vm.SpecId, vmReq.CommonSpec))
+			}
+			imageId := vmReq.CommonImage
+			if connection, err := common.GetConnConfig(vm.ConnectionName); err == nil {
+				if resolved, err := resolveCommonImageId(vmReq.CommonImage, connection); err == nil {
+					imageId = resolved
+				}
+			}
+			if !strings.EqualFold(imageId, vm.ImageId) {
+				changes = append(changes, fmt.Sprintf("commonImage: %s -> %s", vm.ImageId, vmReq.CommonImage))
+			}
+			return changes
+		}
+		details, steps, replace, err := planMciSubGroups(nsId, mciId, sizes, vmChanges, func(name string) error {
+			vmReq := vmReqs[name]
+			_, err := CreateMciVmDynamic(ctx, nsId, mciId, &vmReq)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, newMciApplyItem(mciId, details, steps, replace, nil))
+	}
+
+	for i := range manifest.Mci {
+		req := manifest.Mci[i]
+		dependsOn := []string{}
+		seen := map[string]bool{}
+		addDependency := func(resourceType string, id string) {
+			ref := applyRef(resourceType, id)
+			if inManifest[ref] && !seen[ref] {
+				seen[ref] = true
+				dependsOn = append(dependsOn, ref)
+			}
+		}
+		for _, vm := range req.Vm {
+			addDependency(model.StrVNet, vm.VNetId)
+			addDependency(model.StrSSHKey, vm.SshKeyId)
+			for _, sgId := range vm.SecurityGroupIds {
+				addDependency(model.StrSecurityGroup, sgId)
+			}
+		}
+
+		exists, err := CheckMci(nsId, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			items = append(items, newApplyItem(model.StrMCI, req.Name, model.ApplyActionCreate, nil, dependsOn, func() error {
+				_, err := CreateMci(ctx, nsId, &req, "")
+				return err
+			}))
+			continue
+		}
+		sizes := map[string]int{}
+		vmReqs := map[string]model.TbVmReq{}
+		for _, vm := range req.Vm {
+			sizes[vm.Name] = subGroupSize(vm.SubGroupSize)
+			vmReqs[vm.Name] = vm
+		}
+		mciId := req.Name
+		vmChanges := func(name string, vm model.TbVmInfo) []string {
+			vmReq := vmReqs[name]
+			changes := []string{}
+			if vmReq.SpecId != vm.SpecId {
+				changes = append(changes, fmt.Sprintf("specId: %s -> %s", vm.SpecId, vmReq.SpecId))
+			}
+			if vmReq.ImageId != vm.ImageId {
+				changes = append(changes, fmt.Sprintf("imageId: %s -> %s", vm.ImageId, vmReq.ImageId))
+			}
+			return changes
+		}
+		details, steps, replace, err := planMciSubGroups(nsId, mciId, sizes, vmChanges, func(name string) error {
+			vmReq := vmReqs[name]
+			_, err := CreateMciGroupVm(nsId, mciId, &vmReq, true)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, newMciApplyItem(mciId, details, steps, replace, dependsOn))
+	}
+	return items, nil
+}
+
+// planPrune returns the deletions of the resources which are in the namespace but not in the manifest.
+// Shared resources for dynamic MCIs are not pruned. The order is MCI, security group, SSH key and vNet.
+func planPrune(nsId string, inManifest map[string]bool) ([]applyItem, error) {
+	items := []applyItem{}
+	deleted := map[string]bool{}
+
+	mciIds, err := ListMciId(nsId)
+	if err != nil {
+		return nil, err
+	}
+	for _, mciId := range mciIds {
+		if inManifest[applyRef(model.StrMCI, mciId)] {
+			continue
+		}
+		id := mciId
+		deleted[applyRef(model.StrMCI, id)] = true
+		items = append(items, newApplyItem(model.StrMCI, id, model.ApplyActionDelete, []string{"not in the manifest"}, nil, func() error {
+			_, err := DelMci(nsId, id, "terminate")
+			return err
+		}))
+	}
+
+	for _, resourceType := range []string{model.StrSecurityGroup, model.StrSSHKey} {
+		ids, err := resource.ListResourceId(nsId, resourceType)
+		if err != nil {
+			return nil, err
+		}
+		for _, resourceId := range ids {
+			if inManifest[applyRef(resourceType, resourceId)] || isSharedResourceId(nsId, resourceType, resourceId) {
+				continue
+			}
+			rt, id := resourceType, resourceId
+			deleted[applyRef(rt, id)] = true
+			// resources in use by the MCIs being deleted should wait for the deletion
+			dependsOn := []string{}
+			for ref := range deleted {
+				if strings.HasPrefix(ref, model.StrMCI+"/") {
+					dependsOn = append(dependsOn, ref)
+				}
+			}
+			items = append(items, newApplyItem(rt, id, model.ApplyActionDelete, []string{"not in the manifest"}, dependsOn, func() error {
+				return resource.DelResource(nsId, rt, id, "false")
+			}))
+		}
+	}
+
+	vNetIds, err := resource.ListResourceId(nsId, model.StrVNet)
+	if err != nil {
+		return nil, err
+	}
+	for _, vNetId := range vNetIds {
+		if inManifest[applyRef(model.StrVNet, vNetId)] || isSharedResourceId(nsId, model.StrVNet, vNetId) {
+			continue
+		}
+		id := vNetId
+		dependsOn := []string{}
+		for ref := range deleted {
+			dependsOn = append(dependsOn, ref)
+		}
+		items = append(items, newApplyItem(model.StrVNet, id, model.ApplyActionDelete, []string{"not in the manifest"}, dependsOn, func() error {
+			_, err := resource.DeleteVNet(nsId, id, resource.ActionWithSubnets.String())
+			return err
+		}))
+	}
+	return items, nil
+}
+
+// planNs returns the changes to converge the namespace to the manifest in the order to execute them
+// (vNet, SSH key, security group and MCI, then the deletions in the reverse order if prune is true)
+func planNs(ctx context.Context, nsId string, manifest *model.NsManifest, prune bool) ([]applyItem, error) {
+	// objects created by the plan are recorded as created via apply (see model.Provenance)
+	ctx = common.WithCreatedVia(ctx, model.CreatedViaApply)
+	if _, err := common.GetNs(nsId); err != nil {
+		return nil, err
+	}
+	if err := validateNsManifest(nsId, manifest); err != nil {
+		return nil, err
+	}
+
+	inManifest := map[string]bool{}
+	vNetsInManifest := map[string]bool{}
+	for _, v := range manifest.VNet {
+		inManifest[applyRef(model.StrVNet, v.Name)] = true
+		vNetsInManifest[v.Name] = true
+	}
+	for _, v := range manifest.SecurityGroup {
+		inManifest[applyRef(model.StrSecurityGroup, v.Name)] = true
+	}
+	for _, v := range manifest.SshKey {
+		inManifest[applyRef(model.StrSSHKey, v.Name)] = true
+	}
+	for _, v := range manifest.MciDynamic {
+		inManifest[applyRef(model.StrMCI, v.Name)] = true
+	}
+	for _, v := range manifest.Mci {
+		inManifest[applyRef(model.StrMCI, v.Name)] = true
+	}
+
+	plan := []applyItem{}
+	vNetItems, err := planVNets(ctx, nsId, manifest.VNet, prune)
+	if err != nil {
+		return nil, err
+	}
+	plan = append(plan, vNetItems...)
+	sshKeyItems, err := planSshKeys(ctx, nsId, manifest.SshKey)
+	if err != nil {
+		return nil, err
+	}
+	plan = append(plan, sshKeyItems...)
+	sgItems, err := planSecurityGroups(ctx, nsId, manifest.SecurityGroup, vNetsInManifest)
+	if err != nil {
+		return nil, err
+	}
+	plan = append(plan, sgItems...)
+	mciItems, err := planMcis(ctx, nsId, manifest, inManifest)
+	if err != nil {
+		return nil, err
+	}
+	plan = append(plan, mciItems...)
+
+	if prune {
+		pruneItems, err := planPrune(nsId, inManifest)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, pruneItems...)
+	}
+	return plan, nil
+}
+
+// summarizeApplyResult fills the summary of the changes
+func summarizeApplyResult(result *model.ApplyResult) {
+	result.Summary = map[string]int{}
+	result.Failed = 0
+	for _, change := range result.Changes {
+		result.Summary[change.Action]++
+		if change.Status == model.ApplyStatusFailed || change.Status == model.ApplyStatusSkipped {
+			result.Failed++
+		}
+	}
+}
+
+// ApplyNs converges the namespace to the manifest (desired state) by the create, update and delete functions of each resource.
+// With planOnly, it returns the changes without executing them. Resources not in the manifest are deleted only with prune.
+// A change whose dependency failed is skipped; the other changes are executed regardless of failures.
+func ApplyNs(ctx context.Context, nsId string, manifest *model.NsManifest, planOnly bool, prune bool) (model.ApplyResult, error) {
+	result := model.ApplyResult{NsId: nsId, PlanOnly: planOnly, Prune: prune, Changes: []model.ApplyChange{}}
+
+	if !planOnly {
+		// applies to the same namespace should not run concurrently (the remaining changes are skipped if the lock is lost)
+		lockCtx, unlock, err := common.LockNsWithContext(ctx, nsId, "apply")
+		if err != nil {
+			return result, err
+		}
+		defer unlock()
+		ctx = lockCtx
+	}
+
+	plan, err := planNs(ctx, nsId, manifest, prune)
+	if err != nil {
+		return result, err
+	}
+	if planOnly {
+		for _, item := range plan {
+			result.Changes = append(result.Changes, item.change)
+		}
+		summarizeApplyResult(&result)
+		return result, nil
+	}
+
+	failed := map[string]bool{}
+	for i := range plan {
+		item := &plan[i]
+		ref := applyRef(item.change.ResourceType, item.change.Id)
+
+		if ctx.Err() != nil {
+			item.change.Status = model.ApplyStatusSkipped
+			item.change.Error = "the apply is canceled"
+			failed[ref] = true
+			continue
+		}
+		for _, dependency := range item.change.DependsOn {
+			if failed[dependency] {
+				item.change.Status = model.ApplyStatusSkipped
+				item.change.Error = "the dependency " + dependency + " is not applied"
+				failed[ref] = true
+				break
+			}
+		}
+		if item.change.Status == model.ApplyStatusSkipped {
+			continue
+		}
+
+		switch {
+		case item.change.Action == model.ApplyActionReplace:
+			// the resources depending on it are skipped as well since it is not converged to the manifest
+			item.change.Status = model.ApplyStatusSkipped
+			item.change.Error = "the differences cannot be applied in place; delete the resource or rename it in the manifest"
+			failed[ref] = true
+		case item.run == nil:
+			item.change.Status = model.ApplyStatusSucceeded
+		default:
+			common.UpdateJobProgress(ctx, fmt.Sprintf("(%d/%d) %s %s", i+1, len(plan), item.change.Action, ref))
+			log.Info().Msgf("Apply ns %s: %s %s", nsId, item.change.Action, ref)
+			if err := item.run(); err != nil {
+				log.Error().Err(err).Msgf("Apply ns %s: failed to %s %s", nsId, item.change.Action, ref)
+				item.change.Status = model.ApplyStatusFailed
+				item.change.Error = err.Error()
+				failed[ref] = true
+			} else {
+				item.change.Status = model.ApplyStatusSucceeded
+			}
+		}
+	}
+
+	for _, item := range plan {
+		result.Changes = append(result.Changes, item.change)
+	}
+	summarizeApplyResult(&result)
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+// ApplyNsAsync starts an async job to apply the manifest to the namespace and returns the job immediately.
+// The plan (and the validation of the manifest) is checked before the job is started.
+func ApplyNsAsync(nsId string, manifest *model.NsManifest, prune bool) (model.JobInfo, error) {
+	if _, err := planNs(context.Background(), nsId, manifest, prune); err != nil {
+		return model.JobInfo{}, err
+	}
+	return common.StartJob(model.JobTypeApplyNs, "/ns/"+nsId, func(ctx context.Context) (interface{}, error) {
+		return ApplyNs(ctx, nsId, manifest, false, prune)
+	})
+}
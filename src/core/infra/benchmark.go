@@ -15,9 +15,17 @@ limitations under the License.
 package infra
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
 
 	"strings"
 
@@ -30,6 +38,7 @@ import (
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
 	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
 	"github.com/rs/zerolog/log"
 )
 
@@ -165,7 +174,7 @@ func RunAllBenchmarks(nsId string, mciId string, host string) (*model.BenchmarkI
 
 	if !check {
 		temp := &model.BenchmarkInfoArray{}
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return temp, err
 	}
 
@@ -373,7 +382,7 @@ func RunLatencyBenchmark(nsId string, mciId string, host string) (*model.Benchma
 
 	if !check {
 		temp := &model.BenchmarkInfoArray{}
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return temp, err
 	}
 
@@ -512,7 +521,7 @@ func CoreGetBenchmark(nsId string, mciId string, action string, host string) (*m
 
 	if !check {
 		temp := &model.BenchmarkInfoArray{}
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return temp, err
 	}
 
@@ -572,3 +581,280 @@ func BenchmarkAction(nsId string, mciId string, action string, option string) (m
 	return results, nil
 
 }
+
+const (
+	// latencyBenchmarkDefaultConcurrency is the default number of measurements in parallel
+	latencyBenchmarkDefaultConcurrency = 10
+	latencyBenchmarkMaxConcurrency     = 50
+	// latencyMeasureTimeout is the timeout of a measurement of a VM pair
+	latencyMeasureTimeout = 30 * time.Second
+)
+
+// genLatencyBenchmarkKey returns the key of the latency benchmark run
+func genLatencyBenchmarkKey(nsId string, runId string) string {
+	return "/ns/" + nsId + "/benchmarkLatency/" + runId
+}
+
+// putLatencyBenchmarkRun stores the latency benchmark run
+func putLatencyBenchmarkRun(run model.LatencyBenchmarkRun) error {
+	val, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	err = kvstore.Put(genLatencyBenchmarkKey(run.NsId, run.RunId), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+	return err
+}
+
+// measureVmRtt measures the round-trip time from the source VM to the target IP via the benchmark agent of the source VM.
+// It returns the failure reason (e.g., timeout, no agent) if the measurement failed.
+func measureVmRtt(ctx context.Context, sourceIp string, targetIp string) (float64, string) {
+	ctx, cancel := context.WithTimeout(ctx, latencyMeasureTimeout)
+	defer cancel()
+
+	payload, _ := json.Marshal(model.BenchmarkReq{Host: targetIp})
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://"+sourceIp+model.MilkywayPort+"rtt", strings.NewReader(string(payload)))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+			return 0, "timeout"
+		case errors.Is(err, syscall.ECONNREFUSED):
+			return 0, "no agent (connection refused)"
+		case ctx.Err() != nil:
+			return 0, "canceled"
+		}
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err.Error()
+	}
+	if res.StatusCode >= 400 || res.StatusCode < 200 {
+		return 0, fmt.Sprintf("agent error (status %d): %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	result := model.BenchmarkInfo{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, "invalid response from agent"
+	}
+	rtt, err := strconv.ParseFloat(strings.TrimSpace(result.Result), 64)
+	if err != nil {
+		return 0, "invalid result from agent: " + result.Result
+	}
+	return rtt, ""
+}
+
+// summarizeLatencyByRegion returns min/avg/max of the measured round-trip times per region pair
+func summarizeLatencyByRegion(run model.LatencyBenchmarkRun) []model.LatencyRegionSummary {
+	summaryMap := map[string]*model.LatencyRegionSummary{}
+	keys := []string{}
+	for _, cell := range run.Cells {
+		if cell.Status != model.LatencyCellDone {
+			continue
+		}
+		sourceRegion, targetRegion := run.VmRegions[cell.SourceVmId], run.VmRegions[cell.TargetVmId]
+		key := sourceRegion + "|" + targetRegion
+		summary, ok := summaryMap[key]
+		if !ok {
+			summary = &model.LatencyRegionSummary{SourceRegion: sourceRegion, TargetRegion: targetRegion, MinMs: cell.RttMs, MaxMs: cell.RttMs}
+			summaryMap[key] = summary
+			keys = append(keys, key)
+		}
+		summary.MinMs = math.Min(summary.MinMs, cell.RttMs)
+		summary.MaxMs = math.Max(summary.MaxMs, cell.RttMs)
+		// AvgMs keeps the sum until all samples are added
+		summary.AvgMs += cell.RttMs
+		summary.Samples++
+	}
+	sort.Strings(keys)
+	result := []model.LatencyRegionSummary{}
+	for _, key := range keys {
+		summary := summaryMap[key]
+		summary.AvgMs = summary.AvgMs / float64(summary.Samples)
+		result = append(result, *summary)
+	}
+	return result
+}
+
+// StartLatencyBenchmark starts an async run which measures the round-trip time of all VM pairs in the MCI.
+// The measurements run with bounded concurrency and the partially filled matrix is stored as it progresses
+// (see GetLatencyBenchmarkResult). Pairs which cannot be measured are recorded as failed with the reason.
+func StartLatencyBenchmark(nsId string, mciId string, concurrency int) (model.LatencyBenchmarkRun, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.LatencyBenchmarkRun{}, err
+	}
+
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.LatencyBenchmarkRun{}, err
+	}
+	check, _ := CheckMci(nsId, mciId)
+
+	if !check {
+		err := common.NewResourceNotFoundError("mci", mciId)
+		return model.LatencyBenchmarkRun{}, err
+	}
+
+	if concurrency <= 0 {
+		concurrency = latencyBenchmarkDefaultConcurrency
+	}
+	if concurrency > latencyBenchmarkMaxConcurrency {
+		concurrency = latencyBenchmarkMaxConcurrency
+	}
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.LatencyBenchmarkRun{}, err
+	}
+	if len(vmList) < 2 {
+		return model.LatencyBenchmarkRun{}, fmt.Errorf("the MCI %s needs at least 2 VMs to measure latency", mciId)
+	}
+
+	run := model.LatencyBenchmarkRun{
+		RunId:     "latency-" + common.GenUid(),
+		NsId:      nsId,
+		MciId:     mciId,
+		Status:    model.JobRunning,
+		StartedAt: time.Now(),
+		VmRegions: map[string]string{},
+		Cells:     []model.LatencyCell{},
+	}
+	vmIps := map[string]string{}
+	for _, vmId := range vmList {
+		vmObj, err := GetVmObject(nsId, mciId, vmId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			continue
+		}
+		run.VmRegions[vmId] = vmObj.ConnectionConfig.ProviderName + "/" + vmObj.Region.Region
+		vmIps[vmId] = vmObj.PublicIP
+	}
+	for _, source := range vmList {
+		for _, target := range vmList {
+			if source == target {
+				continue
+			}
+			cell := model.LatencyCell{SourceVmId: source, TargetVmId: target, Status: model.LatencyCellPending}
+			if vmIps[source] == "" || vmIps[target] == "" {
+				cell.Status = model.LatencyCellFailed
+				cell.Reason = "no IP address"
+				run.Failed++
+			}
+			run.Cells = append(run.Cells, cell)
+		}
+	}
+	run.Total = len(run.Cells)
+
+	if err := putLatencyBenchmarkRun(run); err != nil {
+		return model.LatencyBenchmarkRun{}, err
+	}
+
+	var runLock sync.Mutex
+	lastSaved := time.Now()
+
+	job, err := common.StartJob(model.JobTypeBenchmarkLatency, genLatencyBenchmarkKey(nsId, run.RunId), func(ctx context.Context) (interface{}, error) {
+		semaphore := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+
+		for i := range run.Cells {
+			if run.Cells[i].Status != model.LatencyCellPending {
+				continue
+			}
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(i int, source string, target string) {
+				defer func() {
+					<-semaphore
+					wg.Done()
+				}()
+				rtt, reason := measureVmRtt(ctx, vmIps[source], vmIps[target])
+
+				runLock.Lock()
+				defer runLock.Unlock()
+				if reason != "" {
+					run.Cells[i].Status = model.LatencyCellFailed
+					run.Cells[i].Reason = reason
+					run.Failed++
+				} else {
+					run.Cells[i].Status = model.LatencyCellDone
+					run.Cells[i].RttMs = rtt
+					run.Done++
+				}
+				// store the partial matrix periodically
+				if time.Since(lastSaved) > time.Second {
+					putLatencyBenchmarkRun(run)
+					lastSaved = time.Now()
+				}
+			}(i, run.Cells[i].SourceVmId, run.Cells[i].TargetVmId)
+		}
+		wg.Wait()
+
+		runLock.Lock()
+		defer runLock.Unlock()
+		run.EndedAt = time.Now()
+		run.Summary = summarizeLatencyByRegion(run)
+		run.Status = model.JobSucceeded
+		if ctx.Err() != nil {
+			run.Status = model.JobCanceled
+		}
+		putLatencyBenchmarkRun(run)
+		log.Info().Msgf("Latency benchmark %s of the MCI %s is finished (done: %d, failed: %d, total: %d)", run.RunId, mciId, run.Done, run.Failed, run.Total)
+
+		result := map[string]interface{}{"runId": run.RunId, "done": run.Done, "failed": run.Failed, "total": run.Total}
+		return result, ctx.Err()
+	})
+	if err != nil {
+		return model.LatencyBenchmarkRun{}, err
+	}
+
+	runLock.Lock()
+	defer runLock.Unlock()
+	run.JobId = job.Id
+	putLatencyBenchmarkRun(run)
+	return run, nil
+}
+
+// GetLatencyBenchmarkResult returns the latency benchmark run (partial matrix while it is running)
+func GetLatencyBenchmarkResult(nsId string, runId string) (model.LatencyBenchmarkRun, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.LatencyBenchmarkRun{}, err
+	}
+	keyValue, err := kvstore.GetKv(genLatencyBenchmarkKey(nsId, runId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.LatencyBenchmarkRun{}, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return model.LatencyBenchmarkRun{}, fmt.Errorf("the latency benchmark run %s does not exist", runId)
+	}
+	run := model.LatencyBenchmarkRun{}
+	err = json.Unmarshal([]byte(keyValue.Value), &run)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.LatencyBenchmarkRun{}, err
+	}
+	return run, nil
+}
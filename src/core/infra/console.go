@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// vmConsoleMaxBytes is the max size of the console output returned (the last part is kept)
+	vmConsoleMaxBytes = 64 * 1024
+	// vmConsoleCacheDuration is how long a console output is reused to avoid throttling of the CSP API
+	vmConsoleCacheDuration = 30 * time.Second
+)
+
+// vmConsoleCache keeps the recent console output of VMs (key: VM key)
+var vmConsoleCache sync.Map
+
+// vmConsoleCacheItem is an item of vmConsoleCache
+type vmConsoleCacheItem struct {
+	output       string
+	providerName string
+	retrievedAt  time.Time
+}
+
+// tailConsoleOutput returns the last lines (all lines if tail <= 0) of the output within vmConsoleMaxBytes
+func tailConsoleOutput(output string, tail int) (string, int, bool) {
+	truncated := false
+	if len(output) > vmConsoleMaxBytes {
+		output = output[len(output)-vmConsoleMaxBytes:]
+		// drop the partial first line
+		if idx := strings.Index(output, "\n"); idx >= 0 {
+			output = output[idx+1:]
+		}
+		truncated = true
+	}
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return "", 0, truncated
+	}
+	lines := strings.Split(output, "\n")
+	if tail > 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+		truncated = true
+	}
+	return strings.Join(lines, "\n"), len(lines), truncated
+}
+
+// GetVmConsole returns the console output (serial port / boot log) of the VM from the CSP via CB-Spider.
+// It helps to debug VMs which are not reachable by SSH. The output is cached for a short time.
+func GetVmConsole(nsId string, mciId string, vmId string, tail int) (model.TbVmConsoleInfo, error) {
+	if err := common.CheckString(nsId); err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbVmConsoleInfo{}, err
+	}
+	if err := common.CheckString(mciId); err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbVmConsoleInfo{}, err
+	}
+	if err := common.CheckString(vmId); err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbVmConsoleInfo{}, err
+	}
+	if tail < 0 {
+		return model.TbVmConsoleInfo{}, fmt.Errorf("tail must not be negative (%d)", tail)
+	}
+
+	result := model.TbVmConsoleInfo{MciId: mciId, VmId: vmId}
+	key := common.GenMciKey(nsId, mciId, vmId)
+
+	if v, ok := vmConsoleCache.Load(key); ok {
+		item := v.(vmConsoleCacheItem)
+		if time.Since(item.retrievedAt) < vmConsoleCacheDuration {
+			result.ProviderName = item.providerName
+			result.Output, result.Lines, result.Truncated = tailConsoleOutput(item.output, tail)
+			result.RetrievedAt = item.retrievedAt
+			result.Cached = true
+			return result, nil
+		}
+	}
+
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmConsoleInfo{}, err
+	}
+	providerName := strings.ToLower(vm.ConnectionConfig.ProviderName)
+	if err := common.CheckCapability(providerName, common.CapabilityVmConsole, "VM console output"); err != nil {
+		return model.TbVmConsoleInfo{}, fmt.Errorf("%w (see GET /tumblebug/provider/%s/capabilities)", err, providerName)
+	}
+	if vm.CspResourceName == "" {
+		return model.TbVmConsoleInfo{}, fmt.Errorf("the VM %s is not created in the CSP (status: %s)", vmId, vm.Status)
+	}
+
+	requestBody := model.SpiderConnectionName{ConnectionName: vm.ConnectionName}
+	callResult := model.SpiderVMConsoleInfo{}
+	err = common.ExecuteHttpRequest(
+		resty.New(),
+		"GET",
+		fmt.Sprintf("%s/vm/%s/console", model.SpiderRestUrl, vm.CspResourceName),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.VeryShortDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get the console output of the VM %s", vmId)
+		return model.TbVmConsoleInfo{}, err
+	}
+
+	item := vmConsoleCacheItem{output: callResult.Output, providerName: providerName, retrievedAt: time.Now()}
+	vmConsoleCache.Store(key, item)
+
+	result.ProviderName = providerName
+	result.Output, result.Lines, result.Truncated = tailConsoleOutput(item.output, tail)
+	result.RetrievedAt = item.retrievedAt
+	return result, nil
+}
@@ -15,6 +15,7 @@ limitations under the License.
 package infra
 
 import (
+	"context"
 	"errors"
 
 	"encoding/json"
@@ -36,6 +37,12 @@ import (
 
 // HandleMciAction is func to handle actions to MCI
 func HandleMciAction(nsId string, mciId string, action string, force bool) (string, error) {
+	return HandleMciActionWithContext(context.Background(), nsId, mciId, action, force)
+}
+
+// HandleMciActionWithContext is func to handle actions to MCI.
+// Cancellation of ctx stops dispatching the action to the remaining VMs.
+func HandleMciActionWithContext(ctx context.Context, nsId string, mciId string, action string, force bool) (string, error) {
 	action = common.ToLower(action)
 
 	err := common.CheckString(nsId)
@@ -52,7 +59,7 @@ func HandleMciAction(nsId string, mciId string, action string, force bool) (stri
 	check, _ := CheckMci(nsId, mciId)
 
 	if !check {
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return err.Error(), err
 	}
 
@@ -60,7 +67,7 @@ func HandleMciAction(nsId string, mciId string, action string, force bool) (stri
 	if action == "suspend" {
 		log.Debug().Msg("[suspend MCI]")
 
-		err := ControlMciAsync(nsId, mciId, model.ActionSuspend, force)
+		err := ControlMciAsyncWithContext(ctx, nsId, mciId, model.ActionSuspend, force)
 		if err != nil {
 			return "", err
 		}
@@ -70,7 +77,7 @@ func HandleMciAction(nsId string, mciId string, action string, force bool) (stri
 	} else if action == "resume" {
 		log.Debug().Msg("[resume MCI]")
 
-		err := ControlMciAsync(nsId, mciId, model.ActionResume, force)
+		err := ControlMciAsyncWithContext(ctx, nsId, mciId, model.ActionResume, force)
 		if err != nil {
 			return "", err
 		}
@@ -80,7 +87,7 @@ func HandleMciAction(nsId string, mciId string, action string, force bool) (stri
 	} else if action == "reboot" {
 		log.Debug().Msg("[reboot MCI]")
 
-		err := ControlMciAsync(nsId, mciId, model.ActionReboot, force)
+		err := ControlMciAsyncWithContext(ctx, nsId, mciId, model.ActionReboot, force)
 		if err != nil {
 			return "", err
 		}
@@ -100,7 +107,7 @@ func HandleMciAction(nsId string, mciId string, action string, force bool) (stri
 			return "No VM to terminate in the MCI", nil
 		}
 
-		err = ControlMciAsync(nsId, mciId, model.ActionTerminate, force)
+		err = ControlMciAsyncWithContext(ctx, nsId, mciId, model.ActionTerminate, force)
 		if err != nil {
 			return "", err
 		}
@@ -145,6 +152,11 @@ func HandleMciAction(nsId string, mciId string, action string, force bool) (stri
 			// Remove VMs in model.StatusFailed or model.StatusUndefined
 			log.Debug().Msgf("[vmInfo.Status] %v", v.Status)
 			if v.Status == model.StatusFailed || v.Status == model.StatusUndefined {
+				// Keep protected VMs
+				if CheckVmProtection(nsId, mciId, v.Id) != nil {
+					log.Info().Msgf("Skip refining the protected VM %s", v.Id)
+					continue
+				}
 				// Delete VM sequentially for safety (for performance, need to use goroutine)
 				err := DelMciVm(nsId, mciId, v.Id, "force")
 				if err != nil {
@@ -184,7 +196,7 @@ func HandleMciVmAction(nsId string, mciId string, vmId string, action string, fo
 	check, _ := CheckVm(nsId, mciId, vmId)
 
 	if !check {
-		err := fmt.Errorf("The vm " + vmId + " does not exist.")
+		err := common.NewResourceNotFoundError("vm", vmId)
 		return err.Error(), err
 	}
 
@@ -205,6 +217,15 @@ func HandleMciVmAction(nsId string, mciId string, vmId string, action string, fo
 		}
 	}
 
+	// A protected VM cannot be terminated even with force
+	if strings.EqualFold(action, model.ActionTerminate) {
+		err = CheckVmProtection(nsId, mciId, vmId)
+		if err != nil {
+			log.Info().Msg(err.Error())
+			return "", err
+		}
+	}
+
 	err = CheckAllowedTransition(nsId, mciId, model.OptionalParameter{Set: true, Value: vmId}, action)
 	if err != nil {
 		if !force {
@@ -217,13 +238,13 @@ func HandleMciVmAction(nsId string, mciId string, vmId string, action string, fo
 	results := make(chan model.ControlVmResult, 1)
 	wg.Add(1)
 	if strings.EqualFold(action, model.ActionSuspend) {
-		go ControlVmAsync(&wg, nsId, mciId, vmId, model.ActionSuspend, results)
+		go ControlVmAsync(context.Background(), &wg, nsId, mciId, vmId, model.ActionSuspend, results)
 	} else if strings.EqualFold(action, model.ActionResume) {
-		go ControlVmAsync(&wg, nsId, mciId, vmId, model.ActionResume, results)
+		go ControlVmAsync(context.Background(), &wg, nsId, mciId, vmId, model.ActionResume, results)
 	} else if strings.EqualFold(action, model.ActionReboot) {
-		go ControlVmAsync(&wg, nsId, mciId, vmId, model.ActionReboot, results)
+		go ControlVmAsync(context.Background(), &wg, nsId, mciId, vmId, model.ActionReboot, results)
 	} else if strings.EqualFold(action, model.ActionTerminate) {
-		go ControlVmAsync(&wg, nsId, mciId, vmId, model.ActionTerminate, results)
+		go ControlVmAsync(context.Background(), &wg, nsId, mciId, vmId, model.ActionTerminate, results)
 	} else {
 		close(results)
 		wg.Done()
@@ -239,6 +260,12 @@ func HandleMciVmAction(nsId string, mciId string, vmId string, action string, fo
 
 // ControlMciAsync is func to control MCI async
 func ControlMciAsync(nsId string, mciId string, action string, force bool) error {
+	return ControlMciAsyncWithContext(context.Background(), nsId, mciId, action, force)
+}
+
+// ControlMciAsyncWithContext is func to control MCI async.
+// Cancellation of ctx stops dispatching the action to the remaining VMs (requests already sent to CSP are not canceled).
+func ControlMciAsyncWithContext(ctx context.Context, nsId string, mciId string, action string, force bool) error {
 
 	mci, err := GetMciObject(nsId, mciId)
 	if err != nil {
@@ -255,6 +282,15 @@ func ControlMciAsync(nsId string, mciId string, action string, force bool) error
 		}
 	}
 
+	// A protected MCI cannot be terminated even with force
+	if action == model.ActionTerminate {
+		err = CheckMciProtection(nsId, mciId)
+		if err != nil {
+			log.Info().Msg(err.Error())
+			return err
+		}
+	}
+
 	err = CheckAllowedTransition(nsId, mciId, model.OptionalParameter{Set: false}, action)
 	if err != nil {
 		if !force {
@@ -301,20 +337,57 @@ func ControlMciAsync(nsId string, mciId string, action string, force bool) error
 	}
 	UpdateMciInfo(nsId, mci)
 
+	// Resume and reboot follow the order of subGroups by their dependencies, and suspend follows the reverse order.
+	// Each stage is controlled after the VMs of the previous stage reach the target status.
+	stages := [][]string{vmList}
+	switch action {
+	case model.ActionResume, model.ActionReboot:
+		stages = vmStagesBySubGroupOrder(nsId, mciId, vmList, false)
+	case model.ActionSuspend:
+		stages = vmStagesBySubGroupOrder(nsId, mciId, vmList, true)
+	}
+
 	//goroutin sync wg
 	var wg sync.WaitGroup
 	results := make(chan model.ControlVmResult, len(vmList))
 
-	for _, vmId := range vmList {
-		// skip if control is not needed
-		err = CheckAllowedTransition(nsId, mciId, model.OptionalParameter{Set: true, Value: vmId}, action)
-		if err == nil || force {
-			wg.Add(1)
+	canceled := false
+	stageErr := ""
+	for stageIndex, stage := range stages {
+		stageVmIds := []string{}
+		for _, vmId := range stage {
+			if ctx.Err() != nil {
+				log.Info().Msgf("Stop dispatching %s to the remaining VMs of MCI %s: %v", action, mciId, ctx.Err())
+				canceled = true
+				break
+			}
+			// skip if control is not needed
+			err = CheckAllowedTransition(nsId, mciId, model.OptionalParameter{Set: true, Value: vmId}, action)
+			if err == nil || force {
+				wg.Add(1)
 
-			// Avoid concurrent requests to CSP.
-			time.Sleep(time.Millisecond * 1000)
+				// Avoid concurrent requests to CSP.
+				time.Sleep(time.Millisecond * 1000)
 
-			go ControlVmAsync(&wg, nsId, mciId, vmId, action, results)
+				stageVmIds = append(stageVmIds, vmId)
+				go ControlVmAsync(ctx, &wg, nsId, mciId, vmId, action, results)
+			}
+		}
+		if canceled || stageIndex == len(stages)-1 {
+			break
+		}
+
+		wg.Wait()
+		for _, vmId := range stageVmIds {
+			err := waitVmStatus(nsId, mciId, vmId, mci.TargetStatus, subGroupStageTimeout)
+			if err != nil {
+				stageErr = fmt.Sprintf("[stop %s of the next stages: %v]", action, err)
+				break
+			}
+		}
+		if stageErr != "" {
+			log.Error().Msgf("MCI %s: %s", mciId, stageErr)
+			break
 		}
 	}
 	go func() {
@@ -322,7 +395,7 @@ func ControlMciAsync(nsId string, mciId string, action string, force bool) error
 		close(results)
 	}()
 
-	checkErrFlag := ""
+	checkErrFlag := stageErr
 	for result := range results {
 		fmt.Println("Result:", result)
 		if result.Error != nil {
@@ -335,6 +408,9 @@ func ControlMciAsync(nsId string, mciId string, action string, force bool) error
 	if checkErrFlag != "" {
 		return fmt.Errorf(checkErrFlag)
 	}
+	if canceled {
+		return ctx.Err()
+	}
 
 	return nil
 
@@ -342,8 +418,9 @@ func ControlMciAsync(nsId string, mciId string, action string, force bool) error
 
 }
 
-// ControlVmAsync is func to control VM async
-func ControlVmAsync(wg *sync.WaitGroup, nsId string, mciId string, vmId string, action string, results chan<- model.ControlVmResult) {
+// ControlVmAsync is func to control VM async.
+// If ctx is canceled before the request is sent to CSP, the VM is not controlled and ctx.Err() is the result.
+func ControlVmAsync(ctx context.Context, wg *sync.WaitGroup, nsId string, mciId string, vmId string, action string, results chan<- model.ControlVmResult) {
 	defer wg.Done() //goroutine sync done
 
 	var err error
@@ -353,6 +430,12 @@ func ControlVmAsync(wg *sync.WaitGroup, nsId string, mciId string, vmId string,
 	callResult.Status = ""
 	temp := model.TbVmInfo{}
 
+	if ctx.Err() != nil {
+		callResult.Error = ctx.Err()
+		results <- callResult
+		return
+	}
+
 	key := common.GenMciKey(nsId, mciId, vmId)
 	log.Debug().Msg("[ControlVmAsync] " + key)
 
@@ -448,6 +531,7 @@ func ControlVmAsync(wg *sync.WaitGroup, nsId string, mciId string, vmId string,
 				&requestBody,
 				&callResult,
 				common.MediumDuration,
+				common.WithRequestContext(ctx),
 			)
 			if err != nil {
 				log.Error().Err(err).Msg("")
@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/rs/zerolog/log"
+)
+
+// subGroupStageTimeout is the timeout to wait for the VMs of a stage before the next stage is controlled
+const subGroupStageTimeout = 20 * time.Minute
+
+// ResolveSubGroupOrder resolves the dependencies of subGroups (subGroup -> subGroups it depends on) into stages
+// in topological order. SubGroups in a stage do not depend on each other, and each stage depends only on the previous stages.
+// It returns a validation error for an unknown subGroup or a cycle in the dependencies.
+func ResolveSubGroupOrder(dependsOn map[string][]string) ([][]string, error) {
+	indegree := map[string]int{}
+	dependents := map[string][]string{}
+	for subGroup, deps := range dependsOn {
+		indegree[subGroup] += 0
+		for _, dep := range deps {
+			if dep == subGroup {
+				return nil, common.NewValidationFailedError("subGroup %s depends on itself", subGroup)
+			}
+			if _, ok := dependsOn[dep]; !ok {
+				return nil, common.NewValidationFailedError("subGroup %s depends on an unknown subGroup %s", subGroup, dep)
+			}
+			indegree[subGroup]++
+			dependents[dep] = append(dependents[dep], subGroup)
+		}
+	}
+
+	order := [][]string{}
+	resolved := 0
+	stage := []string{}
+	for subGroup, n := range indegree {
+		if n == 0 {
+			stage = append(stage, subGroup)
+		}
+	}
+	for len(stage) > 0 {
+		sort.Strings(stage)
+		order = append(order, stage)
+		resolved += len(stage)
+		next := []string{}
+		for _, subGroup := range stage {
+			for _, dependent := range dependents[subGroup] {
+				indegree[dependent]--
+				if indegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		stage = next
+	}
+
+	if resolved < len(indegree) {
+		return nil, common.NewValidationFailedError("dependency cycle among subGroups: %s", strings.Join(findSubGroupCycle(dependsOn, indegree), " -> "))
+	}
+	return order, nil
+}
+
+// findSubGroupCycle returns a cycle (the first subGroup is repeated at the end) among the unresolved subGroups
+func findSubGroupCycle(dependsOn map[string][]string, indegree map[string]int) []string {
+	unresolved := []string{}
+	for subGroup, n := range indegree {
+		if n > 0 {
+			unresolved = append(unresolved, subGroup)
+		}
+	}
+	sort.Strings(unresolved)
+
+	// every unresolved subGroup depends on another unresolved one, so following them always reaches a cycle
+	visited := map[string]int{}
+	path := []string{}
+	current := unresolved[0]
+	for {
+		if i, ok := visited[current]; ok {
+			return append(path[i:], current)
+		}
+		visited[current] = len(path)
+		path = append(path, current)
+		deps := append([]string{}, dependsOn[current]...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if indegree[dep] > 0 {
+				current = dep
+				break
+			}
+		}
+	}
+}
+
+// subGroupDependsOnOfReq returns the dependencies of the subGroups in the MCI request (by subGroup ID)
+func subGroupDependsOnOfReq(vmRequests []model.TbVmReq) map[string][]string {
+	dependsOn := map[string][]string{}
+	for _, vmRequest := range vmRequests {
+		subGroupId := common.ToLower(vmRequest.Name)
+		deps := dependsOn[subGroupId]
+		for _, dep := range vmRequest.DependsOn {
+			deps = common.AppendIfMissing(deps, common.ToLower(dep))
+		}
+		dependsOn[subGroupId] = deps
+	}
+	return dependsOn
+}
+
+// GetSubGroupOrder returns the order of the subGroups of the MCI resolved from their dependencies
+func GetSubGroupOrder(nsId string, mciId string) ([][]string, error) {
+	subGroupIds, err := ListSubGroupId(nsId, mciId)
+	if err != nil {
+		return nil, err
+	}
+	dependsOn := map[string][]string{}
+	for _, subGroupId := range subGroupIds {
+		subGroup, err := GetSubGroup(nsId, mciId, subGroupId)
+		if err != nil {
+			return nil, err
+		}
+		dependsOn[subGroupId] = subGroup.DependsOn
+	}
+	return ResolveSubGroupOrder(dependsOn)
+}
+
+// refreshMciSubGroupOrder stores the order of the subGroups resolved from their dependencies in the MCI
+func refreshMciSubGroupOrder(nsId string, mciId string) error {
+	order, err := GetSubGroupOrder(nsId, mciId)
+	if err != nil {
+		return err
+	}
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		return err
+	}
+	mci.SubGroupOrder = order
+	UpdateMciInfo(nsId, mci)
+	return nil
+}
+
+// vmStagesBySubGroupOrder splits the VMs into stages by the order of their subGroups (reversed for suspend).
+// VMs not in a subGroup (or in a subGroup not in the order) are in the first stage.
+func vmStagesBySubGroupOrder(nsId string, mciId string, vmList []string, reverse bool) [][]string {
+	order, err := GetSubGroupOrder(nsId, mciId)
+	if err != nil || len(order) < 2 {
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to resolve the subGroup order of MCI %s; control all VMs in parallel", mciId)
+		}
+		return [][]string{vmList}
+	}
+	if reverse {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+	stageOf := map[string]int{}
+	for i, stage := range order {
+		for _, subGroupId := range stage {
+			stageOf[subGroupId] = i
+		}
+	}
+
+	stages := make([][]string, len(order))
+	for _, vmId := range vmList {
+		vm, err := GetVmObject(nsId, mciId, vmId)
+		if err != nil {
+			stages[0] = append(stages[0], vmId)
+			continue
+		}
+		i := stageOf[vm.SubGroupId]
+		stages[i] = append(stages[i], vmId)
+	}
+	return stages
+}
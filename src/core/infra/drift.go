@@ -0,0 +1,376 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/label"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// spiderVpcDriftInfo is the subset of CB-Spider's VPC info to detect drift
+type spiderVpcDriftInfo struct {
+	IId            model.IID
+	IPv4_CIDR      string
+	SubnetInfoList []struct {
+		IId       model.IID
+		IPv4_CIDR string
+	}
+	TagList []model.KeyValue
+}
+
+// spiderSecurityGroupDriftInfo is CB-Spider's security group info with the tags to detect drift
+type spiderSecurityGroupDriftInfo struct {
+	model.SpiderSecurityInfo
+	TagList []model.KeyValue
+}
+
+// spiderDiskDriftInfo is CB-Spider's disk info with the tags to detect drift
+type spiderDiskDriftInfo struct {
+	model.SpiderDiskInfo
+	TagList []model.KeyValue
+}
+
+// getSpiderResource gets the resource (e.g., vpc/{name}) of the connection from CB-Spider
+func getSpiderResource[T any](path string, connectionName string, result *T) error {
+	client := resty.New()
+	reqUrl := fmt.Sprintf("%s/%s?ConnectionName=%s", model.SpiderRestUrl, path, url.QueryEscape(connectionName))
+	requestBody := common.NoBody
+
+	return common.ExecuteHttpRequest(
+		client,
+		"GET",
+		reqUrl,
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		result,
+		common.VeryShortDuration,
+	)
+}
+
+// InspectResourcesWithDrift inspects the resources like InspectResources, and compares the definition of each resource
+// on Tumblebug with the CSP to find the drifted resources (vNet, securityGroup, sshKey, dataDisk, vm).
+// If suggest is true, the ways to reconcile the differences are suggested (but not executed).
+func InspectResourcesWithDrift(connConfig string, resourceType string, suggest bool) (model.InspectResource, error) {
+	result, err := InspectResources(connConfig, resourceType)
+	if err != nil {
+		return result, err
+	}
+
+	result.Resources.Drifted = model.ResourceDrifted{Info: []model.ResourceDriftInfo{}}
+	for _, r := range result.Resources.OnTumblebug.Info {
+		diffs, err := detectResourceDrift(connConfig, resourceType, r)
+		if err != nil {
+			log.Warn().Err(err).Msgf("cannot check the drift of %s %s", resourceType, r.ObjectKey)
+			result.SystemMessage += fmt.Sprintf("cannot check the drift of %s %s: %v; ", resourceType, r.IdByTb, err)
+			continue
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		drift := model.ResourceDriftInfo{
+			IdByTb:        r.IdByTb,
+			CspResourceId: r.CspResourceId,
+			NsId:          r.NsId,
+			MciId:         r.MciId,
+			ObjectKey:     r.ObjectKey,
+			Differences:   diffs,
+		}
+		if suggest {
+			drift.Suggestions = suggestReconcile(resourceType, r, diffs)
+		}
+		result.Resources.Drifted.Info = append(result.Resources.Drifted.Info, drift)
+	}
+	result.Resources.Drifted.Count = len(result.Resources.Drifted.Info)
+	result.ResourceOverview.Drifted = result.Resources.Drifted.Count
+	return result, nil
+}
+
+// detectResourceDrift returns the differences between the resource object on Tumblebug and the CSP.
+// Resource types without drift detection (e.g., customImage, nlb) have no differences.
+func detectResourceDrift(connConfig string, resourceType string, r model.ResourceOnTumblebugInfo) ([]model.ResourceFieldDiff, error) {
+	value, err := common.GetObjectValue(r.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, fmt.Errorf("the object %s does not exist", r.ObjectKey)
+	}
+
+	switch resourceType {
+	case model.StrVNet:
+		tb := model.TbVNetInfo{}
+		if err := json.Unmarshal([]byte(value), &tb); err != nil {
+			return nil, err
+		}
+		csp := spiderVpcDriftInfo{}
+		if err := getSpiderResource("vpc/"+url.PathEscape(tb.CspResourceName), connConfig, &csp); err != nil {
+			return nil, err
+		}
+		diffs := []model.ResourceFieldDiff{}
+		if tb.CidrBlock != csp.IPv4_CIDR {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: "cidrBlock", Tumblebug: tb.CidrBlock, Csp: csp.IPv4_CIDR})
+		}
+		cspSubnets := map[string]string{}
+		for _, s := range csp.SubnetInfoList {
+			cspSubnets[s.IId.SystemId] = s.IPv4_CIDR
+		}
+		tbSubnets := map[string]bool{}
+		for _, s := range tb.SubnetInfoList {
+			tbSubnets[s.CspResourceId] = true
+			cidr, ok := cspSubnets[s.CspResourceId]
+			switch {
+			case !ok:
+				diffs = append(diffs, model.ResourceFieldDiff{Field: "subnet[" + s.Id + "]", Tumblebug: s.CspResourceId})
+			case cidr != s.IPv4_CIDR:
+				diffs = append(diffs, model.ResourceFieldDiff{Field: "subnet[" + s.Id + "].ipv4_CIDR", Tumblebug: s.IPv4_CIDR, Csp: cidr})
+			}
+		}
+		for _, s := range csp.SubnetInfoList {
+			if !tbSubnets[s.IId.SystemId] {
+				diffs = append(diffs, model.ResourceFieldDiff{Field: "subnet", Csp: s.IId.SystemId})
+			}
+		}
+		return append(diffs, tagDrift(resourceType, tb.Uid, csp.TagList)...), nil
+
+	case model.StrSecurityGroup:
+		tb := model.TbSecurityGroupInfo{}
+		if err := json.Unmarshal([]byte(value), &tb); err != nil {
+			return nil, err
+		}
+		csp := spiderSecurityGroupDriftInfo{}
+		if err := getSpiderResource("securitygroup/"+url.PathEscape(tb.CspResourceName), connConfig, &csp); err != nil {
+			return nil, err
+		}
+		tbRules := map[string]bool{}
+		for _, rule := range tb.FirewallRules {
+			tbRules[firewallRuleKey(rule)] = true
+		}
+		cspRules := map[string]bool{}
+		for _, rule := range csp.SecurityRules {
+			cspRules[firewallRuleKey(model.TbFirewallRuleInfo(rule))] = true
+		}
+		diffs := []model.ResourceFieldDiff{}
+		for _, key := range sortedKeys(tbRules) {
+			if !cspRules[key] {
+				diffs = append(diffs, model.ResourceFieldDiff{Field: "firewallRule", Tumblebug: key})
+			}
+		}
+		for _, key := range sortedKeys(cspRules) {
+			if !tbRules[key] {
+				diffs = append(diffs, model.ResourceFieldDiff{Field: "firewallRule", Csp: key})
+			}
+		}
+		return append(diffs, tagDrift(resourceType, tb.Uid, csp.TagList)...), nil
+
+	case model.StrSSHKey:
+		tb := model.TbSshKeyInfo{}
+		if err := json.Unmarshal([]byte(value), &tb); err != nil {
+			return nil, err
+		}
+		csp := model.SpiderKeyPairInfo{}
+		if err := getSpiderResource("keypair/"+url.PathEscape(tb.CspResourceName), connConfig, &csp); err != nil {
+			return nil, err
+		}
+		diffs := []model.ResourceFieldDiff{}
+		if tb.Fingerprint != "" && tb.Fingerprint != cannotRetrieve && csp.Fingerprint != "" && tb.Fingerprint != csp.Fingerprint {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: "fingerprint", Tumblebug: tb.Fingerprint, Csp: csp.Fingerprint})
+		}
+		return diffs, nil
+
+	case model.StrDataDisk:
+		tb := model.TbDataDiskInfo{}
+		if err := json.Unmarshal([]byte(value), &tb); err != nil {
+			return nil, err
+		}
+		csp := spiderDiskDriftInfo{}
+		if err := getSpiderResource("disk/"+url.PathEscape(tb.CspResourceName), connConfig, &csp); err != nil {
+			return nil, err
+		}
+		diffs := []model.ResourceFieldDiff{}
+		if tb.DiskSize != "" && csp.DiskSize != "" && tb.DiskSize != csp.DiskSize {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: "diskSize", Tumblebug: tb.DiskSize, Csp: csp.DiskSize})
+		}
+		if tb.DiskType != "" && tb.DiskType != "default" && csp.DiskType != "" && !strings.EqualFold(tb.DiskType, csp.DiskType) {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: "diskType", Tumblebug: tb.DiskType, Csp: csp.DiskType})
+		}
+		tbAttached := len(tb.AssociatedObjectList) > 0
+		cspAttached := csp.OwnerVM.SystemId != "" || csp.OwnerVM.NameId != ""
+		if tbAttached != cspAttached {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: "attachedVm", Tumblebug: strings.Join(tb.AssociatedObjectList, ","), Csp: csp.OwnerVM.SystemId})
+		}
+		return append(diffs, tagDrift(resourceType, tb.Uid, csp.TagList)...), nil
+
+	case model.StrVM:
+		tb := model.TbVmInfo{}
+		if err := json.Unmarshal([]byte(value), &tb); err != nil {
+			return nil, err
+		}
+		csp := model.SpiderVMInfo{}
+		if err := getSpiderResource("vm/"+url.PathEscape(tb.CspResourceName), connConfig, &csp); err != nil {
+			return nil, err
+		}
+		diffs := []model.ResourceFieldDiff{}
+		if tb.CspSpecName != "" && csp.VMSpecName != "" && tb.CspSpecName != csp.VMSpecName {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: "cspSpecName", Tumblebug: tb.CspSpecName, Csp: csp.VMSpecName})
+		}
+		diffs = append(diffs, idSetDrift("securityGroupIds", r.NsId, model.StrSecurityGroup, tb.SecurityGroupIds, csp.SecurityGroupIIds)...)
+		diffs = append(diffs, idSetDrift("dataDiskIds", r.NsId, model.StrDataDisk, tb.DataDiskIds, csp.DataDiskIIDs)...)
+		return append(diffs, tagDrift(resourceType, tb.Uid, csp.TagList)...), nil
+	}
+	return nil, nil
+}
+
+// idSetDrift compares the CB-TB objects referenced by a VM (converted to CSP resource IDs) with the IIDs given by the CSP
+func idSetDrift(field string, nsId string, resourceType string, tbIds []string, cspIIds []model.IID) []model.ResourceFieldDiff {
+	cspIds := map[string]bool{}
+	for _, iid := range cspIIds {
+		cspIds[iid.SystemId] = true
+	}
+	tbCspIds := map[string]string{}
+	for _, id := range tbIds {
+		if id == "" || id == cannotRetrieve {
+			continue
+		}
+		res, err := resource.GetResource(nsId, resourceType, id)
+		if err != nil {
+			continue
+		}
+		cspResourceId := ""
+		switch v := res.(type) {
+		case model.TbSecurityGroupInfo:
+			cspResourceId = v.CspResourceId
+		case model.TbDataDiskInfo:
+			cspResourceId = v.CspResourceId
+		}
+		if cspResourceId != "" {
+			tbCspIds[cspResourceId] = id
+		}
+	}
+
+	diffs := []model.ResourceFieldDiff{}
+	for _, cspResourceId := range sortedKeys(tbCspIds) {
+		if !cspIds[cspResourceId] {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: field, Tumblebug: tbCspIds[cspResourceId] + " (" + cspResourceId + ")"})
+		}
+	}
+	for _, cspResourceId := range sortedKeys(cspIds) {
+		if _, ok := tbCspIds[cspResourceId]; !ok {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: field, Csp: cspResourceId})
+		}
+	}
+	return diffs
+}
+
+// tagDrift compares the user labels of the object with the CSP tags.
+// It is checked only if the labels have been propagated to the CSP tags successfully.
+func tagDrift(labelType string, uid string, cspTags []model.KeyValue) []model.ResourceFieldDiff {
+	if !label.IsTaggableLabelType(labelType) || uid == "" {
+		return nil
+	}
+	// CSP tags are not reported by the CB-Spider without TagList
+	if !common.IsSpiderFeatureSupported(model.SpiderFeatureTagList) {
+		return nil
+	}
+	labelInfo, err := label.GetLabels(labelType, uid)
+	if err != nil || labelInfo.LastSyncStatus != model.LabelSyncSuccess {
+		return nil
+	}
+	tags := map[string]string{}
+	for _, tag := range cspTags {
+		tags[tag.Key] = tag.Value
+	}
+	diffs := []model.ResourceFieldDiff{}
+	for _, key := range sortedKeys(labelInfo.Labels) {
+		if label.IsSystemLabelKey(key) {
+			continue
+		}
+		value, ok := tags[key]
+		if !ok || value != labelInfo.Labels[key] {
+			diffs = append(diffs, model.ResourceFieldDiff{Field: "tag." + key, Tumblebug: labelInfo.Labels[key], Csp: value})
+		}
+	}
+	return diffs
+}
+
+// suggestReconcile returns the ways to reconcile the differences of the resource (for information only)
+func suggestReconcile(resourceType string, r model.ResourceOnTumblebugInfo, diffs []model.ResourceFieldDiff) []model.ReconcileSuggestion {
+	fields := []string{}
+	pushable := []string{}
+	seen := map[string]bool{}
+	for _, diff := range diffs {
+		if seen[diff.Field] {
+			continue
+		}
+		seen[diff.Field] = true
+		fields = append(fields, diff.Field)
+		switch {
+		case strings.HasPrefix(diff.Field, "tag."),
+			resourceType == model.StrSecurityGroup && diff.Field == "firewallRule",
+			resourceType == model.StrVM && diff.Field == "cspSpecName":
+			pushable = append(pushable, diff.Field)
+		}
+	}
+
+	suggestions := []model.ReconcileSuggestion{{
+		Action:      model.ReconcileUpdateTumblebug,
+		Fields:      fields,
+		Description: fmt.Sprintf("Update the Tumblebug record of %s %s by the current definition in the CSP", resourceType, r.IdByTb),
+	}}
+	if len(pushable) == 0 {
+		return suggestions
+	}
+
+	descriptions := []string{}
+	for _, field := range pushable {
+		switch {
+		case strings.HasPrefix(field, "tag."):
+			if !seen["tagSync"] {
+				seen["tagSync"] = true
+				descriptions = append(descriptions, fmt.Sprintf("resync the labels to the CSP tags (POST /label/cspTagSync with labelType=%s)", resourceType))
+			}
+		case field == "firewallRule":
+			descriptions = append(descriptions, fmt.Sprintf("add the missing rules and delete the extra rules (POST/DELETE /ns/%s/resources/securityGroup/%s/rules)", r.NsId, r.IdByTb))
+		case field == "cspSpecName":
+			descriptions = append(descriptions, fmt.Sprintf("resize the VM to the spec of Tumblebug (POST /ns/%s/mci/%s/vm/%s/resize)", r.NsId, r.MciId, r.IdByTb))
+		}
+	}
+	suggestions = append(suggestions, model.ReconcileSuggestion{
+		Action:      model.ReconcilePushToCsp,
+		Fields:      pushable,
+		Description: "Push the Tumblebug state back to the CSP: " + strings.Join(descriptions, "; "),
+	})
+	return suggestions
+}
+
+// sortedKeys returns the keys of the map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/log"
+)
+
+// gcRoute is the route of the garbage collection recorded in the audit log
+const gcRoute = "/tumblebug/admin/gc"
+
+// gcTokenTTL is the lifetime of a confirm token returned by the dry run
+const gcTokenTTL = 10 * time.Minute
+
+// gcResourceTypes are the resource types scanned by the garbage collection (in the order of deletion)
+var gcResourceTypes = []string{model.StrVM, model.StrCustomImage, model.StrDataDisk, model.StrSSHKey, model.StrSecurityGroup, model.StrVNet}
+
+// gcSpiderPath is the path of CB-Spider to delete a resource of each type
+var gcSpiderPath = map[string]string{
+	model.StrVM:            "vm",
+	model.StrCustomImage:   "myimage",
+	model.StrDataDisk:      "disk",
+	model.StrSSHKey:        "keypair",
+	model.StrSecurityGroup: "securitygroup",
+	model.StrVNet:          "vpc",
+}
+
+// gcToken is the set of orphaned resources listed by a dry run (key: gcResourceKey)
+type gcToken struct {
+	resources map[string]bool
+	expiry    time.Time
+}
+
+var (
+	gcTokenLock sync.Mutex
+	gcTokens    = map[string]gcToken{}
+)
+
+// GcMinAge returns the min age of orphaned resources to be collected (TB_GC_MIN_AGE_MIN, default 60).
+// Younger resources may belong to a provisioning in progress and are skipped.
+func GcMinAge() time.Duration {
+	min, err := strconv.Atoi(common.NVL(os.Getenv("TB_GC_MIN_AGE_MIN"), "60"))
+	if err != nil || min < 0 {
+		min = 60
+	}
+	return time.Duration(min) * time.Minute
+}
+
+// gcResourceKey returns the key of an orphaned resource in a confirm token
+func gcResourceKey(connConfig string, r model.GcResource) string {
+	return connConfig + "/" + r.ResourceType + "/" + r.Name
+}
+
+// gcKnownNames returns the names and CSP ids of the resources of the connection recorded in Tumblebug
+func gcKnownNames(inspected model.InspectResource) map[string]bool {
+	known := map[string]bool{}
+	for _, r := range inspected.Resources.OnTumblebug.Info {
+		if r.CspResourceId != "" {
+			known[r.CspResourceId] = true
+		}
+		value, err := common.GetObjectValue(r.ObjectKey)
+		if err != nil || value == "" {
+			continue
+		}
+		obj := struct {
+			Uid             string `json:"uid"`
+			CspResourceName string `json:"cspResourceName"`
+		}{}
+		if err := json.Unmarshal([]byte(value), &obj); err != nil {
+			continue
+		}
+		if obj.Uid != "" {
+			known[obj.Uid] = true
+		}
+		if obj.CspResourceName != "" {
+			known[obj.CspResourceName] = true
+		}
+	}
+	return known
+}
+
+// scanOrphanedResources lists the resources of the connection in CB-Spider which are named by Tumblebug's uid
+// but have no record in Tumblebug (or in the trash). Resources younger than minAge are returned as skipped.
+func scanOrphanedResources(connConfig string, minAge time.Duration, trashed map[string]bool, protected map[string]bool) model.GcConnectionResult {
+	result := model.GcConnectionResult{ConnectionName: connConfig, Resources: []model.GcResource{}, Skipped: []model.GcResource{}}
+	now := time.Now()
+
+	for _, resourceType := range gcResourceTypes {
+		inspected, err := InspectResources(connConfig, resourceType)
+		if err != nil {
+			log.Warn().Err(err).Msgf("cannot scan %s of %s for orphaned resources", resourceType, connConfig)
+			result.SystemMessage += fmt.Sprintf("cannot scan %s: %v; ", resourceType, err)
+			continue
+		}
+		known := gcKnownNames(inspected)
+		for _, r := range inspected.Resources.OnSpider.Info {
+			id, err := xid.FromString(r.IdBySp)
+			if err != nil {
+				// not named by Tumblebug
+				continue
+			}
+			if known[r.IdBySp] || known[r.CspResourceId] || trashed[r.IdBySp] || trashed[r.CspResourceId] {
+				continue
+			}
+			// never collect the VMs protected by themselves or by their MCIs
+			if protected[r.IdBySp] || protected[r.CspResourceId] {
+				continue
+			}
+			orphan := model.GcResource{
+				ResourceType:  resourceType,
+				Name:          r.IdBySp,
+				CspResourceId: r.CspResourceId,
+				CreatedTime:   id.Time().UTC(),
+			}
+			if now.Sub(orphan.CreatedTime) < minAge {
+				result.Skipped = append(result.Skipped, orphan)
+				continue
+			}
+			result.Resources = append(result.Resources, orphan)
+		}
+	}
+	return result
+}
+
+// deleteOrphanedResource deletes the resource of the connection through CB-Spider
+func deleteOrphanedResource(connConfig string, r model.GcResource) error {
+	type JsonTemplate struct {
+		ConnectionName string
+	}
+	requestBody := JsonTemplate{ConnectionName: connConfig}
+	var callResult interface{}
+
+	return common.ExecuteHttpRequest(
+		resty.New(),
+		"DELETE",
+		fmt.Sprintf("%s/%s/%s", model.SpiderRestUrl, gcSpiderPath[r.ResourceType], url.PathEscape(r.Name)),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.VeryShortDuration,
+	)
+}
+
+// appendGcAuditRecord writes an action of the garbage collection to the audit log
+func appendGcAuditRecord(reqId string, method string, path string, body interface{}, status int) {
+	record := model.AuditRecord{
+		Timestamp:   time.Now(),
+		User:        "system:gc",
+		Method:      method,
+		Route:       gcRoute,
+		Path:        path,
+		RequestBody: body,
+		Status:      status,
+		RequestId:   reqId,
+	}
+	if err := common.AppendAuditRecord(record); err != nil {
+		log.Error().Err(err).Msgf("Failed to store audit record of garbage collection (%s %s)", method, path)
+	}
+}
+
+// CollectOrphanedResources finds the CSP resources created by Tumblebug (named by uid) which are no longer
+// recorded in Tumblebug (e.g., left by a failed provisioning), grouped by connection.
+// The dry run only lists them and returns a confirm token; with the token, the listed resources which are still
+// orphaned are deleted through CB-Spider. Resources younger than GcMinAge are never deleted.
+func CollectOrphanedResources(dryRun bool, confirmToken string, reqId string) (model.GcResult, error) {
+	minAge := GcMinAge()
+	result := model.GcResult{DryRun: dryRun, MinAge: minAge.String(), Connections: []model.GcConnectionResult{}}
+
+	var confirmed map[string]bool
+	if !dryRun {
+		if confirmToken == "" {
+			return result, common.NewValidationFailedError("confirmToken is required to delete orphaned resources (run with dryRun=true first)")
+		}
+		gcTokenLock.Lock()
+		token, ok := gcTokens[confirmToken]
+		delete(gcTokens, confirmToken)
+		gcTokenLock.Unlock()
+		if !ok || time.Now().After(token.expiry) {
+			return result, common.NewValidationFailedError("confirmToken %s is invalid or expired (run with dryRun=true again)", confirmToken)
+		}
+		confirmed = token.resources
+	}
+
+	connectionConfigList, err := common.GetConnConfigList(model.DefaultCredentialHolder, true, true)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, fmt.Errorf("cannot load the list of connection configs: %w", err)
+	}
+
+	trashed := trashedCspResourceNames()
+	protected := protectedCspResourceNames()
+	connections := make([]model.GcConnectionResult, len(connectionConfigList.Connectionconfig))
+	var wait sync.WaitGroup
+	for i, k := range connectionConfigList.Connectionconfig {
+		wait.Add(1)
+		go func(i int, connConfig string) {
+			defer wait.Done()
+			connections[i] = scanOrphanedResources(connConfig, minAge, trashed, protected)
+		}(i, k.ConfigName)
+	}
+	wait.Wait()
+
+	for _, conn := range connections {
+		if dryRun {
+			if len(conn.Resources) > 0 || len(conn.Skipped) > 0 || conn.SystemMessage != "" {
+				result.Connections = append(result.Connections, conn)
+			}
+			result.Total += len(conn.Resources)
+			continue
+		}
+
+		// delete only the resources listed by the dry run (in the order of gcResourceTypes)
+		targets := []model.GcResource{}
+		for _, r := range conn.Resources {
+			if !confirmed[gcResourceKey(conn.ConnectionName, r)] {
+				continue
+			}
+			path := fmt.Sprintf("%s/%s/%s", model.SpiderRestUrl, gcSpiderPath[r.ResourceType], r.Name)
+			body := map[string]interface{}{"connectionName": conn.ConnectionName, "resourceType": r.ResourceType, "name": r.Name, "cspResourceId": r.CspResourceId}
+			if err := deleteOrphanedResource(conn.ConnectionName, r); err != nil {
+				log.Error().Err(err).Msgf("Failed to delete orphaned %s %s of %s", r.ResourceType, r.Name, conn.ConnectionName)
+				r.Result = "failed"
+				r.Error = err.Error()
+				result.Failed++
+				appendGcAuditRecord(reqId, "DELETE", path, body, 500)
+			} else {
+				log.Info().Msgf("Deleted orphaned %s %s of %s", r.ResourceType, r.Name, conn.ConnectionName)
+				r.Result = "deleted"
+				result.Deleted++
+				appendGcAuditRecord(reqId, "DELETE", path, body, 200)
+			}
+			targets = append(targets, r)
+		}
+		conn.Resources = targets
+		result.Total += len(targets)
+		if len(targets) > 0 || conn.SystemMessage != "" {
+			result.Connections = append(result.Connections, conn)
+		}
+	}
+
+	if dryRun {
+		listed := map[string]bool{}
+		names := []string{}
+		for _, conn := range result.Connections {
+			for _, r := range conn.Resources {
+				listed[gcResourceKey(conn.ConnectionName, r)] = true
+				names = append(names, gcResourceKey(conn.ConnectionName, r))
+			}
+		}
+		if len(listed) > 0 {
+			expiry := time.Now().Add(gcTokenTTL).UTC()
+			token := common.GenUid()
+			gcTokenLock.Lock()
+			for t, v := range gcTokens {
+				if time.Now().After(v.expiry) {
+					delete(gcTokens, t)
+				}
+			}
+			gcTokens[token] = gcToken{resources: listed, expiry: expiry}
+			gcTokenLock.Unlock()
+			result.ConfirmToken = token
+			result.ConfirmTokenExpiry = &expiry
+		}
+		appendGcAuditRecord(reqId, "POST", gcRoute+"?dryRun=true", map[string]interface{}{"minAge": result.MinAge, "orphanedResources": names}, 200)
+	}
+
+	log.Info().Msgf("Garbage collection of orphaned resources (dryRun: %t): total %d, deleted %d, failed %d", dryRun, result.Total, result.Deleted, result.Failed)
+	return result, nil
+}
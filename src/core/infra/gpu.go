@@ -0,0 +1,425 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/rs/zerolog/log"
+)
+
+// gpuDriverLogTailLines is the number of lines of the installer log returned for a failure
+const gpuDriverLogTailLines = 50
+
+// gpuDriverRebootTimeout is the timeout to wait for the VM (and its SSH) after the reboot
+const gpuDriverRebootTimeout = 10 * time.Minute
+
+// gpuProbeCmd prints the OS (TB_OS=), the GPUs with the driver (TB_GPU=) and the CUDA version (TB_CUDA=) of the VM
+const gpuProbeCmd = `. /etc/os-release 2>/dev/null; echo "TB_OS=$ID $VERSION_ID"; ` +
+	`nvidia-smi --query-gpu=name,driver_version --format=csv,noheader 2>/dev/null | sed 's/^/TB_GPU=/'; ` +
+	`nvidia-smi 2>/dev/null | grep -o 'CUDA Version: [0-9.]*' | sed 's/CUDA Version: /TB_CUDA=/'; true`
+
+// gpuDriverScript installs the NVIDIA driver (and the CUDA toolkit if $1 is 1) from the NVIDIA CUDA repository
+// of the distribution. It runs as root and loads the driver without reboot if possible.
+const gpuDriverScript = `#!/bin/bash
+set -e
+TOOLKIT="$1"
+. /etc/os-release
+ARCH=$(uname -m)
+if [ "$ARCH" = "aarch64" ]; then ARCH=sbsa; fi
+echo "OS: $ID $VERSION_ID ($ARCH)"
+case "$ID" in
+ubuntu|debian)
+  if [ "$ID" = "ubuntu" ]; then DIST="ubuntu$(echo "$VERSION_ID" | tr -d .)"; else DIST="debian${VERSION_ID%%.*}"; fi
+  export DEBIAN_FRONTEND=noninteractive
+  apt-get update -qq
+  apt-get install -y -qq wget gcc make "linux-headers-$(uname -r)"
+  wget -q -O /tmp/cuda-keyring.deb "https://developer.download.nvidia.com/compute/cuda/repos/$DIST/$ARCH/cuda-keyring_1.1-1_all.deb"
+  dpkg -i /tmp/cuda-keyring.deb
+  apt-get update -qq
+  apt-get install -y -qq cuda-drivers
+  if [ "$TOOLKIT" = "1" ]; then apt-get install -y -qq cuda-toolkit; fi
+  ;;
+rhel|rocky|almalinux|centos|amzn)
+  if [ "$ID" = "amzn" ]; then DIST="amzn${VERSION_ID%%.*}"; else DIST="rhel${VERSION_ID%%.*}"; fi
+  PKG=dnf
+  if ! command -v dnf >/dev/null; then PKG=yum; fi
+  $PKG install -y -q wget gcc make "kernel-devel-$(uname -r)" "kernel-headers-$(uname -r)" || $PKG install -y -q wget gcc make kernel-devel kernel-headers
+  wget -q -O "/etc/yum.repos.d/cuda-$DIST.repo" "https://developer.download.nvidia.com/compute/cuda/repos/$DIST/$ARCH/cuda-$DIST.repo"
+  $PKG clean all -q
+  $PKG install -y -q cuda-drivers
+  if [ "$TOOLKIT" = "1" ]; then $PKG install -y -q cuda-toolkit; fi
+  ;;
+*)
+  echo "Unsupported OS: $ID $VERSION_ID"
+  exit 3
+  ;;
+esac
+if [ "$TOOLKIT" = "1" ]; then
+  echo 'export PATH=/usr/local/cuda/bin${PATH:+:${PATH}}' > /etc/profile.d/cuda.sh
+  echo 'export LD_LIBRARY_PATH=/usr/local/cuda/lib64${LD_LIBRARY_PATH:+:${LD_LIBRARY_PATH}}' >> /etc/profile.d/cuda.sh
+fi
+modprobe nvidia 2>/dev/null || echo "The driver is not loaded yet (reboot is required)"
+echo "Installation is finished"
+`
+
+// gpuDriverOsFamilies are the OS IDs (in /etc/os-release) supported by gpuDriverScript
+var gpuDriverOsFamilies = map[string]bool{"ubuntu": true, "debian": true, "rhel": true, "rocky": true, "almalinux": true, "centos": true, "amzn": true}
+
+// gpuDriverInstallTimeout returns the default timeout of the installation per VM (TB_GPU_DRIVER_INSTALL_TIMEOUT_MIN, default 60)
+func gpuDriverInstallTimeout() time.Duration {
+	minutes, err := strconv.Atoi(common.NVL(os.Getenv("TB_GPU_DRIVER_INSTALL_TIMEOUT_MIN"), "60"))
+	if err != nil || minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// gpuProbe is the GPU state of a VM parsed from the output of gpuProbeCmd
+type gpuProbe struct {
+	osFamily string
+	gpus     []string // "name, driver_version"
+	cuda     string
+}
+
+// parseGpuProbe parses the output of gpuProbeCmd
+func parseGpuProbe(out string) gpuProbe {
+	probe := gpuProbe{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "TB_OS="):
+			probe.osFamily = strings.TrimSpace(strings.TrimPrefix(line, "TB_OS="))
+		case strings.HasPrefix(line, "TB_GPU="):
+			probe.gpus = append(probe.gpus, strings.TrimPrefix(line, "TB_GPU="))
+		case strings.HasPrefix(line, "TB_CUDA="):
+			probe.cuda = strings.TrimPrefix(line, "TB_CUDA=")
+		}
+	}
+	return probe
+}
+
+// gpuInfo returns the GPU info of the VM from the probe (nil if nvidia-smi does not work)
+func (p gpuProbe) gpuInfo() *model.VmGpuInfo {
+	if len(p.gpus) == 0 {
+		return nil
+	}
+	info := &model.VmGpuInfo{Count: len(p.gpus), CudaVersion: p.cuda, VerifiedTime: time.Now()}
+	fields := strings.SplitN(p.gpus[0], ",", 2)
+	info.Model = strings.TrimSpace(fields[0])
+	if len(fields) > 1 {
+		info.DriverVersion = strings.TrimSpace(fields[1])
+	}
+	return info
+}
+
+// runRemoteCommandWithTimeout runs the commands on the VM (RunRemoteCommand) and gives up after the timeout.
+// The commands should be bounded on the VM as well (e.g., by timeout) since the SSH session is not interrupted.
+func runRemoteCommandWithTimeout(nsId string, mciId string, vmId string, userName string, cmds []string, timeout time.Duration) (map[int]string, map[int]string, error) {
+	type output struct {
+		stdout map[int]string
+		stderr map[int]string
+		err    error
+	}
+	done := make(chan output, 1)
+	go func() {
+		stdout, stderr, err := RunRemoteCommand(nsId, mciId, vmId, userName, cmds)
+		done <- output{stdout, stderr, err}
+	}()
+	select {
+	case o := <-done:
+		return o.stdout, o.stderr, o.err
+	case <-time.After(timeout):
+		return map[int]string{}, map[int]string{}, fmt.Errorf("the command is not finished in %s", timeout)
+	}
+}
+
+// probeVmGpu returns the GPU state of the VM by gpuProbeCmd
+func probeVmGpu(nsId string, mciId string, vmId string, userName string) (gpuProbe, error) {
+	stdout, _, err := runRemoteCommandWithTimeout(nsId, mciId, vmId, userName, []string{gpuProbeCmd}, 2*time.Minute)
+	if err != nil {
+		return gpuProbe{}, err
+	}
+	return parseGpuProbe(stdout[0]), nil
+}
+
+// InstallGpuDriverToMciAsync starts an async job to install the GPU driver to the MCI and returns the job immediately
+func InstallGpuDriverToMciAsync(nsId string, mciId string, subGroupId string, vmId string, req *model.GpuDriverInstallReq) (model.JobInfo, error) {
+	if err := validateGpuDriverInstallReq(nsId, mciId, req); err != nil {
+		return model.JobInfo{}, err
+	}
+	return common.StartJob(model.JobTypeInstallGpuDriver, common.GenMciKey(nsId, mciId, ""), func(ctx context.Context) (interface{}, error) {
+		return InstallGpuDriverToMci(ctx, nsId, mciId, subGroupId, vmId, req)
+	})
+}
+
+// validateGpuDriverInstallReq checks the MCI and the request (and fills the default reboot option)
+func validateGpuDriverInstallReq(nsId string, mciId string, req *model.GpuDriverInstallReq) error {
+	if err := common.CheckString(nsId); err != nil {
+		return err
+	}
+	if err := common.CheckString(mciId); err != nil {
+		return err
+	}
+	if check, _ := CheckMci(nsId, mciId); !check {
+		return common.NewResourceNotFoundError("mci", mciId)
+	}
+	req.Reboot = common.NVL(req.Reboot, model.GpuDriverRebootAuto)
+	switch req.Reboot {
+	case model.GpuDriverRebootAuto, model.GpuDriverRebootAlways, model.GpuDriverRebootNever:
+	default:
+		return common.NewValidationFailedError("invalid reboot (%s); use one of [%s, %s, %s]", req.Reboot, model.GpuDriverRebootAuto, model.GpuDriverRebootAlways, model.GpuDriverRebootNever)
+	}
+	if req.TimeoutMinutes < 0 {
+		return common.NewValidationFailedError("timeoutMinutes should not be negative")
+	}
+	return nil
+}
+
+// InstallGpuDriverToMci installs the NVIDIA driver (and the CUDA toolkit) to the GPU VMs of the MCI in parallel
+// (or the VMs of the subGroup, or the VM). VMs without a GPU in their spec are skipped.
+// The driver is verified by nvidia-smi and its versions are stored in the VM object.
+func InstallGpuDriverToMci(ctx context.Context, nsId string, mciId string, subGroupId string, vmId string, req *model.GpuDriverInstallReq) (model.GpuDriverInstallResult, error) {
+	result := model.GpuDriverInstallResult{MciId: mciId, Results: []model.GpuDriverInstallVmResult{}}
+	if err := validateGpuDriverInstallReq(nsId, mciId, req); err != nil {
+		return result, err
+	}
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	if subGroupId != "" {
+		vmList, err = ListVmBySubGroup(nsId, mciId, subGroupId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+	}
+	if vmId != "" {
+		if check, _ := CheckVm(nsId, mciId, vmId); !check {
+			return result, common.NewResourceNotFoundError("vm", vmId)
+		}
+		vmList = []string{vmId}
+	}
+	sort.Strings(vmList)
+
+	timeout := gpuDriverInstallTimeout()
+	if req.TimeoutMinutes > 0 {
+		timeout = time.Duration(req.TimeoutMinutes) * time.Minute
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	finished := 0
+	for _, id := range vmList {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			vmResult := installGpuDriverToVm(ctx, nsId, mciId, id, req, timeout)
+			recordMciNsEvent(nsId, mciId, id, model.NsEventCommandExecuted, "GPU driver installation: "+vmResult.Status+" "+vmResult.Message)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.Results = append(result.Results, vmResult)
+			finished++
+			common.UpdateJobProgress(ctx, fmt.Sprintf("%d/%d VMs finished", finished, len(vmList)))
+		}(id)
+	}
+	wg.Wait()
+
+	sort.Slice(result.Results, func(i, j int) bool {
+		return result.Results[i].VmId < result.Results[j].VmId
+	})
+	return result, ctx.Err()
+}
+
+// installGpuDriverToVm installs the GPU driver to the VM and verifies it by nvidia-smi
+func installGpuDriverToVm(ctx context.Context, nsId string, mciId string, vmId string, req *model.GpuDriverInstallReq, timeout time.Duration) model.GpuDriverInstallVmResult {
+	result := model.GpuDriverInstallVmResult{VmId: vmId}
+	skip := func(message string) model.GpuDriverInstallVmResult {
+		result.Status = model.GpuDriverSkipped
+		result.Message = message
+		return result
+	}
+	fail := func(message string) model.GpuDriverInstallVmResult {
+		result.Status = model.GpuDriverFailed
+		result.Message = message
+		log.Warn().Msgf("[GPU driver] %s/%s: %s", mciId, vmId, message)
+		return result
+	}
+
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if vm.Status != model.StatusRunning {
+		return skip(fmt.Sprintf("the VM is not running (%s)", vm.Status))
+	}
+
+	// detect the GPU from the spec metadata
+	spec, err := getSpecOfVm(nsId, vm.SpecId)
+	if err != nil {
+		return skip(fmt.Sprintf("cannot find the spec (%s) to detect the GPU", vm.SpecId))
+	}
+	if !strings.EqualFold(spec.AcceleratorType, "gpu") {
+		return skip(fmt.Sprintf("no GPU in the spec (%s)", vm.SpecId))
+	}
+	if spec.AcceleratorModel != "" && !strings.Contains(strings.ToLower(spec.AcceleratorModel), "nvidia") {
+		return skip(fmt.Sprintf("only NVIDIA GPUs are supported (%s)", spec.AcceleratorModel))
+	}
+	if GetVmOsType(nsId, mciId, vm) == model.VmOsTypeWindows {
+		return skip("Windows VMs are not supported (use the NVIDIA installer for Windows)")
+	}
+
+	// determine the OS family and check the driver installed already
+	probe, err := probeVmGpu(nsId, mciId, vmId, req.UserName)
+	if err != nil {
+		return fail("failed to connect to the VM: " + err.Error())
+	}
+	result.OsFamily = probe.osFamily
+	if gpu := probe.gpuInfo(); gpu != nil && !req.Force {
+		result.Status = model.GpuDriverAlreadyInstalled
+		result.Gpu = gpu
+		result.Message = fmt.Sprintf("driver %s (CUDA %s) is working", gpu.DriverVersion, gpu.CudaVersion)
+		storeVmGpuInfo(nsId, mciId, vmId, gpu)
+		return result
+	}
+	osId, _, _ := strings.Cut(probe.osFamily, " ")
+	if !gpuDriverOsFamilies[osId] {
+		return skip(fmt.Sprintf("the OS (%s) is not supported (Ubuntu, Debian, RHEL family and Amazon Linux)", probe.osFamily))
+	}
+	if ctx.Err() != nil {
+		return fail(ctx.Err().Error())
+	}
+
+	// run the installer (bounded by timeout on the VM) and keep its log for debugging
+	toolkit := "0"
+	if req.InstallToolkit {
+		toolkit = "1"
+	}
+	installCmd := fmt.Sprintf("cat > /tmp/tb-gpu-driver.sh <<'TB_GPU_DRIVER_EOF'\n%sTB_GPU_DRIVER_EOF\n"+
+		"sudo timeout %d bash /tmp/tb-gpu-driver.sh %s > /tmp/tb-gpu-driver.log 2>&1; echo \"TB_EXIT=$?\"; tail -n %d /tmp/tb-gpu-driver.log",
+		gpuDriverScript, int(timeout.Seconds()), toolkit, gpuDriverLogTailLines)
+	log.Info().Msgf("[GPU driver] Install the driver to %s/%s (%s, timeout: %s)", mciId, vmId, probe.osFamily, timeout)
+	stdout, _, err := runRemoteCommandWithTimeout(nsId, mciId, vmId, req.UserName, []string{installCmd}, timeout+5*time.Minute)
+	if err != nil {
+		return fail("failed to run the installer: " + err.Error())
+	}
+	exitCode, logTail := parseGpuInstallerOutput(stdout[0])
+	result.LogTail = logTail
+	switch exitCode {
+	case 0:
+	case 124:
+		return fail(fmt.Sprintf("the installer is not finished in %s", timeout))
+	default:
+		return fail(fmt.Sprintf("the installer failed (exit code %d)", exitCode))
+	}
+
+	// reboot if required, then verify the driver by nvidia-smi
+	probe, err = probeVmGpu(nsId, mciId, vmId, req.UserName)
+	loaded := err == nil && probe.gpuInfo() != nil
+	if req.Reboot == model.GpuDriverRebootAlways || (req.Reboot == model.GpuDriverRebootAuto && !loaded) {
+		if err := rebootVmForGpuDriver(ctx, nsId, mciId, vmId); err != nil {
+			return fail("failed to reboot the VM: " + err.Error())
+		}
+		result.Rebooted = true
+		probe, err = waitVmGpuProbe(ctx, nsId, mciId, vmId, req.UserName)
+	}
+	if err != nil {
+		return fail("failed to verify the driver: " + err.Error())
+	}
+	gpu := probe.gpuInfo()
+	if gpu == nil {
+		message := "nvidia-smi does not work after the installation"
+		if req.Reboot == model.GpuDriverRebootNever {
+			message += " (reboot may be required)"
+		}
+		return fail(message)
+	}
+
+	result.Status = model.GpuDriverInstalled
+	result.Gpu = gpu
+	result.Message = fmt.Sprintf("driver %s (CUDA %s) is installed", gpu.DriverVersion, gpu.CudaVersion)
+	result.LogTail = ""
+	storeVmGpuInfo(nsId, mciId, vmId, gpu)
+	return result
+}
+
+// parseGpuInstallerOutput returns the exit code of the installer (TB_EXIT=) and the log tail printed after it
+func parseGpuInstallerOutput(out string) (int, string) {
+	i := strings.Index(out, "TB_EXIT=")
+	if i < 0 {
+		return -1, strings.TrimSpace(out)
+	}
+	rest := out[i+len("TB_EXIT="):]
+	line, logTail, _ := strings.Cut(rest, "\n")
+	exitCode, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		exitCode = -1
+	}
+	return exitCode, strings.TrimSpace(logTail)
+}
+
+// rebootVmForGpuDriver reboots the VM via the CSP and waits for it to be running
+func rebootVmForGpuDriver(ctx context.Context, nsId string, mciId string, vmId string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if _, err := HandleMciVmAction(nsId, mciId, vmId, model.ActionReboot, true); err != nil {
+		return err
+	}
+	return waitVmStatus(nsId, mciId, vmId, model.StatusRunning, gpuDriverRebootTimeout)
+}
+
+// waitVmGpuProbe probes the GPU of the VM until nvidia-smi works (or SSH is back without the driver) after the reboot
+func waitVmGpuProbe(ctx context.Context, nsId string, mciId string, vmId string, userName string) (gpuProbe, error) {
+	deadline := time.Now().Add(gpuDriverRebootTimeout)
+	for {
+		probe, err := probeVmGpu(nsId, mciId, vmId, userName)
+		if err == nil && (probe.gpuInfo() != nil || time.Now().After(deadline)) {
+			return probe, nil
+		}
+		if time.Now().After(deadline) {
+			return probe, err
+		}
+		select {
+		case <-ctx.Done():
+			return probe, ctx.Err()
+		case <-time.After(20 * time.Second):
+		}
+	}
+}
+
+// storeVmGpuInfo stores the verified GPU driver in the VM object
+func storeVmGpuInfo(nsId string, mciId string, vmId string, gpu *model.VmGpuInfo) {
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	vm.Gpu = gpu
+	UpdateVmInfo(nsId, mciId, vm)
+}
@@ -15,6 +15,7 @@ limitations under the License.
 package infra
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -47,7 +48,7 @@ func TbNLBReqStructLevelValidation(sl validator.StructLevel) {
 }
 
 // CreateMcSwNlb func create a special purpose MCI for NLB and depoly and setting SW NLB
-func CreateMcSwNlb(nsId string, mciId string, req *model.TbNLBReq, option string) (model.McNlbInfo, error) {
+func CreateMcSwNlb(ctx context.Context, nsId string, mciId string, req *model.TbNLBReq, option string) (model.McNlbInfo, error) {
 	log.Info().Msg("CreateMcSwNlb")
 
 	emptyObj := model.McNlbInfo{}
@@ -108,7 +109,7 @@ func CreateMcSwNlb(nsId string, mciId string, req *model.TbNLBReq, option string
 	vmDynamicReq := model.TbVmDynamicReq{Name: vmGroupName, CommonSpec: commonSpec, CommonImage: commonImage, SubGroupSize: subGroupSize}
 	mciDynamicReq.Vm = append(mciDynamicReq.Vm, vmDynamicReq)
 
-	mciInfo, err := CreateMciDynamic("", nsId, &mciDynamicReq, "")
+	mciInfo, err := CreateMciDynamic(ctx, "", nsId, &mciDynamicReq, "")
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return emptyObj, err
@@ -191,7 +192,7 @@ func CreateNLB(nsId string, mciId string, u *model.TbNLBReq, option string) (mod
 	check, err := CheckNLB(nsId, mciId, u.TargetGroup.SubGroupId)
 
 	if check {
-		err := fmt.Errorf("The nlb " + u.TargetGroup.SubGroupId + " already exists.")
+		err := common.NewConflictError("The nlb %s already exists.", u.TargetGroup.SubGroupId)
 		return emptyObj, err
 	}
 
@@ -449,8 +450,7 @@ func GetNLB(nsId string, mciId string, resourceId string) (model.TbNLBInfo, erro
 	}
 
 	if !check {
-		errString := "The NLB " + resourceId + " does not exist."
-		err := fmt.Errorf(errString)
+		err := common.NewResourceNotFoundError("nlb", resourceId)
 		return emptyObj, err
 	}
 
@@ -688,8 +688,7 @@ func DelNLB(nsId string, mciId string, resourceId string, forceFlag string) erro
 	}
 
 	if !check {
-		errString := "The NLB " + resourceId + " does not exist."
-		err := fmt.Errorf(errString)
+		err := common.NewResourceNotFoundError("nlb", resourceId)
 		return err
 	}
 
@@ -863,7 +862,7 @@ func GetNLBHealth(nsId string, mciId string, nlbId string) (model.TbNLBHealthInf
 	check, err := CheckNLB(nsId, mciId, nlbId)
 
 	if !check {
-		err := fmt.Errorf("The nlb " + nlbId + " does not exist.")
+		err := common.NewResourceNotFoundError("nlb", nlbId)
 		return model.TbNLBHealthInfo{}, err
 	}
 
@@ -1007,7 +1006,7 @@ func AddNLBVMs(nsId string, mciId string, resourceId string, u *model.TbNLBAddRe
 
 	if !check {
 		temp := model.TbNLBInfo{}
-		err := fmt.Errorf("The nlb " + resourceId + " does not exist.")
+		err := common.NewResourceNotFoundError("nlb", resourceId)
 		return temp, err
 	}
 
@@ -1184,7 +1183,7 @@ func RemoveNLBVMs(nsId string, mciId string, resourceId string, u *model.TbNLBAd
 
 	if !check {
 		// temp := model.TbNLBInfo{}
-		err := fmt.Errorf("The nlb " + resourceId + " does not exist.")
+		err := common.NewResourceNotFoundError("nlb", resourceId)
 		return err
 	}
 
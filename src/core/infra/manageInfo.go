@@ -15,8 +15,10 @@ limitations under the License.
 package infra
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"reflect"
 
 	"strconv"
@@ -275,6 +277,26 @@ func ListSubGroupId(nsId string, mciId string) ([]string, error) {
 	return subGroupList, nil
 }
 
+// GetMciRevision is func to return the revision of the stored MCI object including its VMs and subGroups
+// (changed whenever any of them, such as a VM status, is updated)
+func GetMciRevision(nsId string, mciId string) (string, error) {
+	if err := common.CheckString(nsId); err != nil {
+		return "", err
+	}
+	if err := common.CheckString(mciId); err != nil {
+		return "", err
+	}
+	return common.GetKvRevision(common.GenMciKey(nsId, mciId, ""))
+}
+
+// GetMciListRevision is func to return the revision of all stored MCI objects in the namespace
+func GetMciListRevision(nsId string) (string, error) {
+	if err := common.CheckString(nsId); err != nil {
+		return "", err
+	}
+	return common.GetKvRevision(common.GenMciKey(nsId, "", "") + "/mci")
+}
+
 // GetMciInfo is func to return MCI information with the current status update
 func GetMciInfo(nsId string, mciId string) (*model.TbMciInfo, error) {
 
@@ -295,7 +317,7 @@ func GetMciInfo(nsId string, mciId string) (*model.TbMciInfo, error) {
 
 	if !check {
 		temp := &model.TbMciInfo{}
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return temp, err
 	}
 
@@ -378,7 +400,7 @@ func GetMciAccessInfo(nsId string, mciId string, option string) (*model.MciAcces
 	check, _ := CheckMci(nsId, mciId)
 
 	if !check {
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return temp, err
 	}
 
@@ -435,8 +457,11 @@ func GetMciAccessInfo(nsId string, mciId string, option string) (*model.MciAcces
 					vmAccessInfo.PrivateKey = ""
 					vmAccessInfo.VmUserName = ""
 				} else {
+					// the private key is masked unless it is requested (showSshKey)
 					if strings.EqualFold(option, "showSshKey") {
 						vmAccessInfo.PrivateKey = privateKey
+					} else if privateKey != "" {
+						vmAccessInfo.PrivateKey = common.SecretMask
 					}
 					vmAccessInfo.VmUserName = verifiedUserName
 				}
@@ -476,14 +501,50 @@ func ListMciInfo(nsId string, option string) ([]model.TbMciInfo, error) {
 	*/
 	// content := RestGetAllMciResponse{}
 
-	Mci := []model.TbMciInfo{}
-
 	mciList, err := ListMciId(nsId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return nil, err
 	}
 
+	return listMciInfoOf(nsId, mciList, option)
+}
+
+// ListMciInfoWithOption is func to get the page of MCI objects with the selected fields only.
+// MCIs are paged before their status is retrieved, so only the MCIs in the page are inspected.
+func ListMciInfoWithOption(nsId string, option string, listOption model.ListOption) (interface{}, model.ListPage, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, model.ListPage{}, err
+	}
+
+	mciList, err := ListMciId(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, model.ListPage{}, err
+	}
+
+	start, end, page := common.GetListPageRange(len(mciList), listOption)
+	mciInfoList, err := listMciInfoOf(nsId, mciList[start:end], option)
+	if err != nil {
+		return nil, model.ListPage{}, err
+	}
+
+	shaped, err := common.ShapeList(mciInfoList, listOption)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, model.ListPage{}, err
+	}
+	return shaped, page, nil
+}
+
+// listMciInfoOf is func to get the MCI objects of the MCI IDs
+func listMciInfoOf(nsId string, mciList []string, option string) ([]model.TbMciInfo, error) {
+
+	Mci := []model.TbMciInfo{}
+
 	for _, v := range mciList {
 
 		key := common.GenMciKey(nsId, v, "")
@@ -560,6 +621,8 @@ func ListMciInfo(nsId string, option string) ([]model.TbMciInfo, error) {
 			mciTmp.Vm = append(mciTmp.Vm, vmTmp)
 		}
 
+		// the passwords of the VM users are not listed
+		MaskMciInfo(&mciTmp)
 		Mci = append(Mci, mciTmp)
 	}
 
@@ -593,7 +656,7 @@ func ListVmInfo(nsId string, mciId string, vmId string) (*model.TbVmInfo, error)
 
 	if !check {
 		temp := &model.TbVmInfo{}
-		err := fmt.Errorf("The vm " + vmId + " does not exist.")
+		err := common.NewResourceNotFoundError("vm", vmId)
 		return temp, err
 	}
 
@@ -679,6 +742,41 @@ func GetVmObject(nsId string, mciId string, vmId string) (model.TbVmInfo, error)
 	return vmTmp, nil
 }
 
+// encryptVmUserPassword encrypts the password of the VM user (e.g., Administrator of Windows) to be stored in the Key-Value store
+func encryptVmUserPassword(vm *model.TbVmInfo) error {
+	encrypted, err := common.EncryptSecret(vm.VmUserPassword)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt the password of the VM user (%s): %w", vm.Id, err)
+	}
+	vm.VmUserPassword = encrypted
+	return nil
+}
+
+// DecryptVmUserPassword decrypts the password of the VM object read from the Key-Value store
+func DecryptVmUserPassword(vm *model.TbVmInfo) error {
+	decrypted, err := common.DecryptSecret(vm.VmUserPassword)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to decrypt the password of the VM user (%s)", vm.Id)
+		return err
+	}
+	vm.VmUserPassword = decrypted
+	return nil
+}
+
+// MaskVmInfo replaces the password of the VM user with the masked placeholder
+func MaskVmInfo(vm *model.TbVmInfo) {
+	if vm.VmUserPassword != "" {
+		vm.VmUserPassword = common.SecretMask
+	}
+}
+
+// MaskMciInfo replaces the passwords of the VM users in the MCI with the masked placeholder
+func MaskMciInfo(mci *model.TbMciInfo) {
+	for i := range mci.Vm {
+		MaskVmInfo(&mci.Vm[i])
+	}
+}
+
 // GetVmIdNameInDetail is func to get ID and Name details
 func GetVmIdNameInDetail(nsId string, mciId string, vmId string) (*model.TbIdNameInDetailInfo, error) {
 	key := common.GenMciKey(nsId, mciId, vmId)
@@ -739,8 +837,19 @@ func GetVmIdNameInDetail(nsId string, mciId string, vmId string) (*model.TbIdNam
 
 // [MCI and VM status management]
 
-// GetMciStatus is func to Get Mci Status
+// GetMciStatus is func to Get Mci Status (call to CSPs for all VMs)
 func GetMciStatus(nsId string, mciId string) (*model.MciStatusInfo, error) {
+	return getMciStatus(nsId, mciId, true)
+}
+
+// GetMciStatusFromCache is func to Get Mci Status from the VM statuses refreshed by the status reconciler.
+// VMs whose cached status is missing or stale (or in a control action) are fetched from the CSPs.
+func GetMciStatusFromCache(nsId string, mciId string) (*model.MciStatusInfo, error) {
+	return getMciStatus(nsId, mciId, false)
+}
+
+// getMciStatus returns the status of the MCI (refresh: fetch the status of all VMs from the CSPs)
+func getMciStatus(nsId string, mciId string, refresh bool) (*model.MciStatusInfo, error) {
 
 	err := common.CheckString(nsId)
 	if err != nil {
@@ -782,9 +891,22 @@ func GetMciStatus(nsId string, mciId string) (*model.MciStatusInfo, error) {
 		return &model.MciStatusInfo{}, nil
 	}
 
+	// serve the cached status of VMs if possible
+	vmListToFetch := vmList
+	if !refresh {
+		vmListToFetch = []string{}
+		for _, v := range vmList {
+			if vmStatus, ok := getCachedVmStatus(nsId, mciId, v); ok {
+				mciStatus.Vm = append(mciStatus.Vm, vmStatus)
+			} else {
+				vmListToFetch = append(vmListToFetch, v)
+			}
+		}
+	}
+
 	//goroutin sync wg
 	var wg sync.WaitGroup
-	for _, v := range vmList {
+	for _, v := range vmListToFetch {
 		wg.Add(1)
 		go FetchVmStatusAsync(&wg, nsId, mciId, v, &mciStatus)
 	}
@@ -908,8 +1030,8 @@ func GetMciStatus(nsId string, mciId string) (*model.MciStatusInfo, error) {
 
 }
 
-// ListMciStatus is func to get MCI status all
-func ListMciStatus(nsId string) ([]model.MciStatusInfo, error) {
+// ListMciStatus is func to get MCI status all (refresh: fetch the status of all VMs from the CSPs instead of the cache)
+func ListMciStatus(nsId string, refresh bool) ([]model.MciStatusInfo, error) {
 
 	//mciStatuslist := []model.MciStatusInfo{}
 	mciList, err := ListMciId(nsId)
@@ -926,7 +1048,7 @@ func ListMciStatus(nsId string) ([]model.MciStatusInfo, error) {
 		wg.Add(1)
 		go func(nsId string, mciId string, chanResults chan model.MciStatusInfo) {
 			defer wg.Done()
-			mciStatus, err := GetMciStatus(nsId, mciId)
+			mciStatus, err := getMciStatus(nsId, mciId, refresh)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 			}
@@ -1097,6 +1219,7 @@ func FetchVmStatus(nsId string, mciId string, vmId string) (model.TbVmStatusInfo
 	errorInfo.Location = temp.Location
 	errorInfo.MonAgentStatus = temp.MonAgentStatus
 	errorInfo.CreatedTime = temp.CreatedTime
+	errorInfo.Protected = temp.Protected
 	errorInfo.SystemMessage = "Error in FetchVmStatus"
 
 	cspResourceName := temp.CspResourceName
@@ -1112,6 +1235,7 @@ func FetchVmStatus(nsId string, mciId string, vmId string) (model.TbVmStatusInfo
 	}
 	callResult := statusResponse{}
 	callResult.Status = ""
+	fetched := false
 
 	if temp.Status != model.StatusTerminated && cspResourceName != "" {
 		client := resty.New()
@@ -1138,12 +1262,14 @@ func FetchVmStatus(nsId string, mciId string, vmId string) (model.TbVmStatusInfo
 				&requestBody,
 				&callResult,
 				common.MediumDuration,
+				common.WithProvider(temp.ConnectionConfig.ProviderName),
 			)
 			if err != nil {
 				errorInfo.SystemMessage = err.Error()
 				callResult.Status = model.StatusUndefined
 				break
 			}
+			fetched = true
 			if callResult.Status != "" {
 				break
 			}
@@ -1192,6 +1318,7 @@ func FetchVmStatus(nsId string, mciId string, vmId string) (model.TbVmStatusInfo
 	vmStatusTmp.Location = temp.Location
 	vmStatusTmp.MonAgentStatus = temp.MonAgentStatus
 	vmStatusTmp.CreatedTime = temp.CreatedTime
+	vmStatusTmp.Protected = temp.Protected
 	vmStatusTmp.SystemMessage = temp.SystemMessage
 
 	//Correct undefined status using TargetAction
@@ -1272,6 +1399,10 @@ func FetchVmStatus(nsId string, mciId string, vmId string) (model.TbVmStatusInfo
 	temp.TargetAction = vmStatusTmp.TargetAction
 	temp.TargetStatus = vmStatusTmp.TargetStatus
 	temp.SystemMessage = vmStatusTmp.SystemMessage
+	if fetched {
+		temp.LastSeen = time.Now().UTC().Format(time.RFC3339)
+	}
+	vmStatusTmp.LastSeen = temp.LastSeen
 
 	if cspResourceName != "" {
 		// don't update VM info, if cspResourceName is empty
@@ -1309,7 +1440,7 @@ func GetMciVmStatus(nsId string, mciId string, vmId string) (*model.TbVmStatusIn
 
 	if !check {
 		temp := &model.TbVmStatusInfo{}
-		err := fmt.Errorf("The vm " + vmId + " does not exist.")
+		err := common.NewResourceNotFoundError("vm", vmId)
 		return temp, err
 	}
 
@@ -1325,6 +1456,99 @@ func GetMciVmStatus(nsId string, mciId string, vmId string) (*model.TbVmStatusIn
 
 // [Update MCI and VM object]
 
+// UpdateMci is func to update the editable fields (description, installMonAgent, label, placementAlgo) of an MCI
+func UpdateMci(nsId string, mciId string, req *model.TbMciUpdateReq) (model.TbMciInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+
+	immutable := []string{}
+	if req.Name != nil {
+		immutable = append(immutable, "name")
+	}
+	if req.SystemLabel != nil {
+		immutable = append(immutable, "systemLabel")
+	}
+	if req.Vm != nil {
+		immutable = append(immutable, "vm")
+	}
+	if len(immutable) > 0 {
+		return model.TbMciInfo{}, common.NewValidationFailedError("The fields [%s] of MCI cannot be updated (editable fields: description, installMonAgent, label, placementAlgo)", strings.Join(immutable, ", "))
+	}
+	if req.InstallMonAgent != nil && *req.InstallMonAgent != "yes" && *req.InstallMonAgent != "no" {
+		return model.TbMciInfo{}, common.NewValidationFailedError("installMonAgent should be yes or no (given: %s)", *req.InstallMonAgent)
+	}
+	for key := range req.Label {
+		if label.IsSystemLabelKey(key) {
+			return model.TbMciInfo{}, common.NewValidationFailedError("The system label %s cannot be updated", key)
+		}
+	}
+
+	check, _ := CheckMci(nsId, mciId)
+	if !check {
+		return model.TbMciInfo{}, common.NewResourceNotFoundError("mci", mciId)
+	}
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+
+	if req.Description != nil {
+		mci.Description = *req.Description
+	}
+	if req.InstallMonAgent != nil {
+		mci.InstallMonAgent = *req.InstallMonAgent
+	}
+	if req.PlacementAlgo != nil {
+		mci.PlacementAlgo = *req.PlacementAlgo
+	}
+	if req.Label != nil {
+		mci.Label = req.Label
+	}
+	UpdateMciInfo(nsId, mci)
+
+	// propagate the changes to the label info of the MCI (system labels are kept)
+	if req.Description != nil || req.Label != nil {
+		key := common.GenMciKey(nsId, mciId, "")
+		labels := map[string]string{}
+		if req.Description != nil {
+			labels[model.LabelDescription] = mci.Description
+		}
+		if req.Label != nil {
+			labelInfo, err := label.GetLabels(model.StrMCI, mci.Uid)
+			if err == nil {
+				for existingKey := range labelInfo.Labels {
+					if _, ok := req.Label[existingKey]; !ok && !label.IsSystemLabelKey(existingKey) {
+						if err := label.RemoveLabel(model.StrMCI, mci.Uid, existingKey); err != nil {
+							log.Error().Err(err).Msg("")
+							return model.TbMciInfo{}, err
+						}
+					}
+				}
+			}
+			for labelKey, value := range req.Label {
+				labels[labelKey] = value
+			}
+		}
+		err = label.CreateOrUpdateLabel(model.StrMCI, mci.Uid, key, labels)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbMciInfo{}, err
+		}
+	}
+	log.Info().Msgf("Updated MCI %s/%s", nsId, mciId)
+
+	return GetMciObject(nsId, mciId)
+}
+
 // UpdateMciInfo is func to update MCI Info (without VM info in MCI)
 func UpdateMciInfo(nsId string, mciInfoData model.TbMciInfo) {
 
@@ -1346,6 +1570,14 @@ func UpdateMciInfo(nsId string, mciInfoData model.TbMciInfo) {
 		err = kvstore.Put(key, string(val))
 		if err != nil {
 			log.Error().Err(err).Msg("")
+			return
+		}
+		if mciTmp.Status != mciInfoData.Status {
+			common.EmitEvent(model.EventMciStatusChanged, nsId, key, map[string]string{
+				"previousStatus": mciTmp.Status,
+				"status":         mciInfoData.Status,
+			})
+			recordMciNsEvent(nsId, mciInfoData.Id, "", model.NsEventStatusChanged, mciTmp.Status+" -> "+mciInfoData.Status)
 		}
 	}
 }
@@ -1368,12 +1600,55 @@ func UpdateVmInfo(nsId string, mciId string, vmInfoData model.TbVmInfo) {
 		err = kvstore.Put(key, string(val))
 		if err != nil {
 			log.Error().Err(err).Msg("")
+			return
 		}
+		if vmTmp.Status != vmInfoData.Status {
+			common.EmitEvent(model.EventVmStatusChanged, nsId, key, map[string]string{
+				"previousStatus": vmTmp.Status,
+				"status":         vmInfoData.Status,
+				"systemMessage":  vmInfoData.SystemMessage,
+			})
+			message := vmTmp.Status + " -> " + vmInfoData.Status
+			if vmInfoData.Status == model.StatusFailed && vmInfoData.SystemMessage != "" {
+				message += " (" + vmInfoData.SystemMessage + ")"
+			}
+			recordMciNsEvent(nsId, mciId, vmInfoData.Id, model.NsEventStatusChanged, message)
+		}
+	}
+}
+
+// vmEventHistorySize is the number of events kept in a VM object
+const vmEventHistorySize = 20
+
+// recordVmEvent appends the event to the history of the VM object
+func recordVmEvent(nsId string, mciId string, vmId string, event model.VmEvent) {
+	vmObj, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	vmObj.Events = append(vmObj.Events, event)
+	if len(vmObj.Events) > vmEventHistorySize {
+		vmObj.Events = vmObj.Events[len(vmObj.Events)-vmEventHistorySize:]
 	}
+	UpdateVmInfo(nsId, mciId, vmObj)
+}
+
+// recordMciNsEvent appends the event of the MCI (or its VM if vmId is given) to the timeline of the namespace
+func recordMciNsEvent(nsId string, mciId string, vmId string, eventType string, message string) {
+	event := model.NsEvent{ResourceType: model.StrMCI, ResourceId: mciId, EventType: eventType, Message: message}
+	if vmId != "" {
+		event.ResourceType = model.StrVM
+		event.ResourceId = mciId + "/" + vmId
+	}
+	common.RecordNsEvent(nsId, common.GenMciKey(nsId, mciId, vmId), event)
 }
 
 // ProvisionDataDisk is func to provision DataDisk to VM (create and attach to VM)
-func ProvisionDataDisk(nsId string, mciId string, vmId string, u *model.TbDataDiskVmReq) (model.TbVmInfo, error) {
+func ProvisionDataDisk(ctx context.Context, nsId string, mciId string, vmId string, u *model.TbDataDiskVmReq) (model.TbVmInfo, error) {
 	vm, err := GetVmObject(nsId, mciId, vmId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -1388,7 +1663,7 @@ func ProvisionDataDisk(nsId string, mciId string, vmId string, u *model.TbDataDi
 		Description:    u.Description,
 	}
 
-	newDataDisk, err := resource.CreateDataDisk(nsId, &createDiskReq, "")
+	newDataDisk, err := resource.CreateDataDisk(ctx, nsId, &createDiskReq, "")
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return model.TbVmInfo{}, err
@@ -1406,6 +1681,177 @@ func ProvisionDataDisk(nsId string, mciId string, vmId string, u *model.TbDataDi
 	return model.TbVmInfo{}, err
 }
 
+// RegisterDataDisk is func to register a DataDisk created in CSP.
+// If the DataDisk is attached to a VM registered in the namespace, the DataDisk is linked to the VM.
+func RegisterDataDisk(ctx context.Context, nsId string, u *model.TbRegisterDataDiskReq) (model.TbDataDiskInfo, error) {
+	err := validate.Struct(u)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskInfo{}, err
+	}
+
+	registerDiskReq := model.TbDataDiskReq{
+		Name:           u.Name,
+		ConnectionName: u.ConnectionName,
+		Description:    u.Description,
+		CspResourceId:  u.CspResourceId,
+	}
+
+	dataDisk, err := resource.CreateDataDisk(ctx, nsId, &registerDiskReq, "register")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskInfo{}, err
+	}
+
+	if dataDisk.OwnerVmCspResourceId == "" {
+		return dataDisk, nil
+	}
+
+	// Link the DataDisk to the registered VM which the DataDisk is attached to
+	mciId, vm, found := findVmByCspResourceId(nsId, dataDisk.ConnectionName, dataDisk.OwnerVmCspResourceId)
+	if !found {
+		log.Info().Msgf("the VM (%s) which the dataDisk (%s) is attached to is not registered", dataDisk.OwnerVmCspResourceId, dataDisk.Id)
+		return dataDisk, nil
+	}
+	if !common.CheckElement(dataDisk.Id, vm.DataDiskIds) {
+		vm.DataDiskIds = append(vm.DataDiskIds, dataDisk.Id)
+		UpdateVmInfo(nsId, mciId, vm)
+	}
+	vmKey := common.GenMciKey(nsId, mciId, vm.Id)
+	dataDisk.AssociatedObjectList, err = resource.UpdateAssociatedObjectList(nsId, model.StrDataDisk, dataDisk.Id, model.StrAdd, vmKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return dataDisk, err
+	}
+
+	return dataDisk, nil
+}
+
+// DeregisterDataDisk is func to deregister a DataDisk from CB-Tumblebug and CB-Spider (the DataDisk in CSP is not deleted).
+// The DataDisk is unlinked from the VMs which it is attached to.
+func DeregisterDataDisk(nsId string, dataDiskId string) (model.SimpleMsg, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+	err = common.CheckString(dataDiskId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+
+	dataDiskKey := common.GenResourceKey(nsId, model.StrDataDisk, dataDiskId)
+	keyValue, err := kvstore.GetKv(dataDiskKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		err := common.NewResourceNotFoundError(model.StrDataDisk, dataDiskId)
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+
+	dataDisk := model.TbDataDiskInfo{}
+	err = json.Unmarshal([]byte(keyValue.Value), &dataDisk)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+
+	// [Via Spider] Deregister the DataDisk
+	client := resty.New()
+	method := "DELETE"
+	url := fmt.Sprintf("%s/regdisk/%s", model.SpiderRestUrl, dataDisk.CspResourceName)
+	requestBody := model.SpiderConnectionName{
+		ConnectionName: dataDisk.ConnectionName,
+	}
+	var callResult struct {
+		Result string
+	}
+
+	err = common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+	if ok, _ := strconv.ParseBool(callResult.Result); !ok {
+		err := fmt.Errorf("failed to deregister the dataDisk (%s)", dataDiskId)
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+
+	// Unlink the DataDisk from the VMs
+	for _, vmKey := range dataDisk.AssociatedObjectList {
+		// vmKey: /ns/{nsId}/mci/{mciId}/vm/{vmId}
+		keyParts := strings.Split(vmKey, "/")
+		if len(keyParts) != 7 || keyParts[3] != "mci" {
+			continue
+		}
+		mciId := keyParts[4]
+		vmKeyValue, err := kvstore.GetKv(vmKey)
+		if err != nil || vmKeyValue == (kvstore.KeyValue{}) {
+			continue
+		}
+		vm := model.TbVmInfo{}
+		if err := json.Unmarshal([]byte(vmKeyValue.Value), &vm); err != nil {
+			continue
+		}
+		dataDiskIds := []string{}
+		for _, id := range vm.DataDiskIds {
+			if id != dataDiskId {
+				dataDiskIds = append(dataDiskIds, id)
+			}
+		}
+		vm.DataDiskIds = dataDiskIds
+		UpdateVmInfo(nsId, mciId, vm)
+	}
+
+	err = kvstore.Delete(dataDiskKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+	err = label.RemoveLabel(model.StrDataDisk, dataDisk.Uid, dataDiskKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SimpleMsg{}, err
+	}
+
+	return model.SimpleMsg{Message: fmt.Sprintf("the dataDisk (%s) has been deregistered", dataDiskId)}, nil
+}
+
+// findVmByCspResourceId is func to find the VM of the connection by the CSP resource Id in the namespace
+func findVmByCspResourceId(nsId string, connectionName string, cspResourceId string) (string, model.TbVmInfo, bool) {
+	mciIds, err := ListMciId(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return "", model.TbVmInfo{}, false
+	}
+	for _, mciId := range mciIds {
+		mci, err := GetMciObject(nsId, mciId)
+		if err != nil {
+			continue
+		}
+		for _, vm := range mci.Vm {
+			if vm.ConnectionName == connectionName && vm.CspResourceId == cspResourceId {
+				return mciId, vm, true
+			}
+		}
+	}
+	return "", model.TbVmInfo{}, false
+}
+
 // AttachDetachDataDisk is func to attach/detach DataDisk to/from VM
 func AttachDetachDataDisk(nsId string, mciId string, vmId string, command string, dataDiskId string, force bool) (model.TbVmInfo, error) {
 	vmKey := common.GenMciKey(nsId, mciId, vmId)
@@ -1643,6 +2089,33 @@ func GetAvailableDataDisks(nsId string, mciId string, vmId string, option string
 
 // DelMci is func to delete MCI object
 func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
+	return DelMciWithContext(context.Background(), nsId, mciId, option)
+}
+
+// DelMciAsync starts an async job to delete MCI object (soft: keep the metadata in the trash) and returns the job immediately
+func DelMciAsync(nsId string, mciId string, option string, soft bool) (model.JobInfo, error) {
+	_, err := GetMciInfo(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot Delete Mci")
+		return model.JobInfo{}, err
+	}
+	err = CheckMciProtection(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.JobInfo{}, err
+	}
+
+	return common.StartJob(model.JobTypeDeleteMci, common.GenMciKey(nsId, mciId, ""), func(ctx context.Context) (interface{}, error) {
+		if soft {
+			return TrashMci(ctx, nsId, mciId, option)
+		}
+		return DelMciWithContext(ctx, nsId, mciId, option)
+	})
+}
+
+// DelMciWithContext is func to delete MCI object.
+// If ctx is canceled, the deletion stops before the next VM and the objects deleted so far are returned.
+func DelMciWithContext(ctx context.Context, nsId string, mciId string, option string) (model.IdList, error) {
 
 	option = common.ToLower(option)
 	deletedResources := model.IdList{}
@@ -1655,12 +2128,19 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 		return deletedResources, err
 	}
 
+	// A protected MCI (or an MCI with protected VMs) cannot be deleted even with option=force
+	err = CheckMciProtection(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.IdList{}, err
+	}
+
 	log.Debug().Msg("[Delete MCI] " + mciId)
 
 	// Check MCI status is Terminated so that approve deletion
 	mciStatus, _ := GetMciStatus(nsId, mciId)
 	if mciStatus == nil {
-		err := fmt.Errorf("MCI " + mciId + " status nil, Deletion is not allowed (use option=force for force deletion)")
+		err := common.NewConflictError("MCI %s status nil, Deletion is not allowed (use option=force for force deletion)", mciId)
 		log.Error().Err(err).Msg("")
 		if option != "force" {
 			return deletedResources, err
@@ -1673,17 +2153,24 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 		if strings.EqualFold(option, model.ActionTerminate) {
 
 			// ActionRefine
-			_, err := HandleMciAction(nsId, mciId, model.ActionRefine, true)
+			common.UpdateJobProgress(ctx, "Refining MCI "+mciId)
+			_, err := HandleMciActionWithContext(ctx, nsId, mciId, model.ActionRefine, true)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				return deletedResources, err
 			}
 
 			// model.ActionTerminate
-			_, err = HandleMciAction(nsId, mciId, model.ActionTerminate, true)
+			common.UpdateJobProgress(ctx, "Terminating VMs of MCI "+mciId)
+			failedVms, err := terminateMciVmsForDeletion(ctx, nsId, mciId)
 			if err != nil {
 				log.Error().Err(err).Msg("")
-				return deletedResources, err
+				return model.IdList{IdList: deletedResources.IdList}, err
+			}
+			if len(failedVms) > 0 {
+				err := common.NewConflictError("VMs %v of MCI %s are %s; clean up the VMs in CSP and retry, or delete with option=force to remove the records only", failedVms, mciId, model.StatusFailedToTerminate)
+				log.Error().Err(err).Msg("")
+				return model.IdList{IdList: deletedResources.IdList}, err
 			}
 			// for deletion, need to wait until termination is finished
 			// Sleep for 5 seconds
@@ -1696,7 +2183,7 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 
 	// Check MCI status is Terminated (not Partial)
 	if mciStatus.Id != "" && !(!strings.Contains(mciStatus.Status, "Partial-") && (strings.Contains(mciStatus.Status, model.StatusTerminated) || strings.Contains(mciStatus.Status, model.StatusUndefined) || strings.Contains(mciStatus.Status, model.StatusFailed))) {
-		err := fmt.Errorf("MCI " + mciId + " is " + mciStatus.Status + " and not " + model.StatusTerminated + "/" + model.StatusUndefined + "/" + model.StatusFailed + ", Deletion is not allowed (use option=force for force deletion)")
+		err := common.NewConflictError("MCI %s is %s and not %s/%s/%s, Deletion is not allowed (use option=force for force deletion)", mciId, mciStatus.Status, model.StatusTerminated, model.StatusUndefined, model.StatusFailed)
 		log.Error().Err(err).Msg("")
 		if option != "force" {
 			return deletedResources, err
@@ -1723,7 +2210,13 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 	}
 
 	// delete vms info
-	for _, v := range vmList {
+	for i, v := range vmList {
+		if ctx.Err() != nil {
+			log.Info().Msgf("Deletion of MCI %s is canceled", mciId)
+			return model.IdList{IdList: deletedResources.IdList}, ctx.Err()
+		}
+		common.UpdateJobProgress(ctx, fmt.Sprintf("Deleting VM object %s (%d/%d)", v, i+1, len(vmList)))
+
 		vmKey := common.GenMciKey(nsId, mciId, v)
 		fmt.Println(vmKey)
 
@@ -1734,6 +2227,11 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 			return deletedResources, err
 		}
 
+		// The VM may remain in CSP (e.g., FailedToTerminate) if the records are removed by force
+		if option == "force" && vmInfo.CspResourceId != "" && vmInfo.Status != model.StatusTerminated {
+			deletedResources.IdList = append(deletedResources.IdList, fmt.Sprintf("[Manual cleanup] VM: %s (status: %s, cspResourceId: %s, connectionName: %s)", v, vmInfo.Status, vmInfo.CspResourceId, vmInfo.ConnectionName))
+		}
+
 		err = kvstore.Delete(vmKey)
 		if err != nil {
 			log.Error().Err(err).Msg("")
@@ -1765,7 +2263,13 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 
 	}
 
+	if ctx.Err() != nil {
+		log.Info().Msgf("Deletion of MCI %s is canceled", mciId)
+		return model.IdList{IdList: deletedResources.IdList}, ctx.Err()
+	}
+
 	// delete subGroup info
+	common.UpdateJobProgress(ctx, "Deleting subGroups and NLBs of MCI "+mciId)
 	subGroupList, err := ListSubGroupId(nsId, mciId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -1808,7 +2312,7 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 	mciNlbId := mciId + "-nlb"
 	check, _ = CheckMci(nsId, mciNlbId)
 	if check {
-		mciNlbDeleteResult, err := DelMci(nsId, mciNlbId, option)
+		mciNlbDeleteResult, err := DelMciWithContext(ctx, nsId, mciNlbId, option)
 		if err != nil {
 			log.Error().Err(err).Msg("")
 			return deletedResources, err
@@ -1829,9 +2333,151 @@ func DelMci(nsId string, mciId string, option string) (model.IdList, error) {
 		log.Error().Err(err).Msg("")
 	}
 
+	// delete the recovery policy of the MCI
+	delRecoveryPolicyOfMci(nsId, mciId)
+
+	common.EmitEvent(model.EventMciDeleted, nsId, key, map[string]interface{}{"deleted": deletedResources.IdList})
+	recordMciNsEvent(nsId, mciId, "", model.NsEventDeleted, fmt.Sprintf("MCI is deleted (%d objects)", len(deletedResources.IdList)))
 	return deletedResources, nil
 }
 
+// mciDeleteParallelism returns the number of VMs terminated concurrently by MCI deletion (TB_MCI_DELETE_PARALLELISM, default 10)
+func mciDeleteParallelism() int {
+	n, err := strconv.Atoi(common.NVL(os.Getenv("TB_MCI_DELETE_PARALLELISM"), "10"))
+	if err != nil || n < 1 {
+		n = 10
+	}
+	return n
+}
+
+// vmTerminateMaxAttempts returns the number of attempts to terminate a VM by MCI deletion (TB_VM_TERMINATE_MAX_ATTEMPTS, default 3)
+func vmTerminateMaxAttempts() int {
+	n, err := strconv.Atoi(common.NVL(os.Getenv("TB_VM_TERMINATE_MAX_ATTEMPTS"), "3"))
+	if err != nil || n < 1 {
+		n = 3
+	}
+	return n
+}
+
+// terminateMciVmsForDeletion terminates the VMs of the MCI in parallel (bounded by mciDeleteParallelism) and reports
+// the progress of each VM to the job. A VM which fails in all attempts is marked as FailedToTerminate,
+// and the termination continues with the rest. It returns the Ids of the VMs failed to terminate.
+func terminateMciVmsForDeletion(ctx context.Context, nsId string, mciId string) ([]string, error) {
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+	mci.TargetAction = model.ActionTerminate
+	mci.TargetStatus = model.StatusTerminated
+	mci.Status = model.StatusTerminating
+	UpdateMciInfo(nsId, mci)
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
+	maxAttempts := vmTerminateMaxAttempts()
+	sem := make(chan struct{}, mciDeleteParallelism())
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+	failedVms := []string{}
+	reportProgress := func(vmId string, message string) {
+		common.UpdateJobProgress(ctx, fmt.Sprintf("Terminating VMs of MCI %s (%d/%d done, %d failed): VM %s %s", mciId, done, len(vmList), len(failedVms), vmId, message))
+	}
+
+	for _, vmId := range vmList {
+		if ctx.Err() != nil {
+			log.Info().Msgf("Stop terminating the remaining VMs of MCI %s: %v", mciId, ctx.Err())
+			break
+		}
+		// skip if the VM is already terminated
+		if CheckAllowedTransition(nsId, mciId, model.OptionalParameter{Set: true, Value: vmId}, model.ActionTerminate) != nil {
+			vmStatus, err := GetMciVmStatus(nsId, mciId, vmId)
+			if err == nil && vmStatus.Status == model.StatusTerminated {
+				mu.Lock()
+				done++
+				mu.Unlock()
+				continue
+			}
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(vmId string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var lastErr error
+			for attempt := 1; attempt <= maxAttempts; attempt++ {
+				if ctx.Err() != nil {
+					lastErr = ctx.Err()
+					break
+				}
+				mu.Lock()
+				reportProgress(vmId, fmt.Sprintf("terminating (attempt %d/%d)", attempt, maxAttempts))
+				mu.Unlock()
+
+				var vmWg sync.WaitGroup
+				results := make(chan model.ControlVmResult, 1)
+				vmWg.Add(1)
+				go ControlVmAsync(ctx, &vmWg, nsId, mciId, vmId, model.ActionTerminate, results)
+				vmWg.Wait()
+				close(results)
+
+				result, ok := <-results
+				if !ok {
+					lastErr = fmt.Errorf("no result of the termination of VM %s", vmId)
+				} else {
+					lastErr = result.Error
+				}
+				if lastErr == nil {
+					break
+				}
+				log.Warn().Err(lastErr).Msgf("Failed to terminate VM %s (attempt %d/%d)", vmId, attempt, maxAttempts)
+				if attempt < maxAttempts {
+					select {
+					case <-ctx.Done():
+					case <-time.After(time.Duration(attempt) * 5 * time.Second):
+					}
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if lastErr != nil && lastErr == ctx.Err() {
+				// the termination is not requested to CSP after the cancellation (the VM keeps its status)
+				reportProgress(vmId, "termination is canceled")
+				return
+			}
+			if lastErr != nil {
+				failedVms = append(failedVms, vmId)
+				vm, err := GetVmObject(nsId, mciId, vmId)
+				if err == nil {
+					vm.Status = model.StatusFailedToTerminate
+					vm.SystemMessage = lastErr.Error()
+					UpdateVmInfo(nsId, mciId, vm)
+				}
+				reportProgress(vmId, "is "+model.StatusFailedToTerminate)
+				return
+			}
+			reportProgress(vmId, "is terminated")
+		}(vmId)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return failedVms, ctx.Err()
+	}
+	sort.Strings(failedVms)
+	return failedVms, nil
+}
+
 // DelMciVm is func to delete VM object
 func DelMciVm(nsId string, mciId string, vmId string, option string) error {
 
@@ -1855,7 +2501,13 @@ func DelMciVm(nsId string, mciId string, vmId string, option string) error {
 	check, _ := CheckVm(nsId, mciId, vmId)
 
 	if !check {
-		err := fmt.Errorf("The vm " + vmId + " does not exist.")
+		err := common.NewResourceNotFoundError("vm", vmId)
+		return err
+	}
+
+	err = CheckVmProtection(nsId, mciId, vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
 		return err
 	}
 
@@ -1867,7 +2519,7 @@ func DelMciVm(nsId string, mciId string, vmId string, option string) error {
 		var wg sync.WaitGroup
 		results := make(chan model.ControlVmResult, 1)
 		wg.Add(1)
-		go ControlVmAsync(&wg, nsId, mciId, vmId, model.ActionTerminate, results)
+		go ControlVmAsync(context.Background(), &wg, nsId, mciId, vmId, model.ActionTerminate, results)
 		checkErr := <-results
 		wg.Wait()
 		close(results)
@@ -1938,6 +2590,7 @@ func DelMciVm(nsId string, mciId string, vmId string, option string) error {
 	if err != nil {
 		log.Error().Err(err).Msg("")
 	}
+	recordMciNsEvent(nsId, mciId, vmId, model.NsEventDeleted, "VM is deleted")
 
 	return nil
 }
@@ -1961,11 +2614,22 @@ func DelAllMci(nsId string, option string) (string, error) {
 		return "No MCI to delete", nil
 	}
 
+	// Protected MCIs are skipped
+	protectedMciList := []string{}
+	mciListToDelete := []string{}
+	for _, v := range mciList {
+		if CheckMciProtection(nsId, v) != nil {
+			protectedMciList = append(protectedMciList, v)
+			continue
+		}
+		mciListToDelete = append(mciListToDelete, v)
+	}
+
 	var wg sync.WaitGroup
-	errCh := make(chan error, len(mciList))
+	errCh := make(chan error, len(mciListToDelete))
 	defer close(errCh)
 
-	for _, v := range mciList {
+	for _, v := range mciListToDelete {
 		wg.Add(1)
 		go func(mciId string) {
 			defer wg.Done()
@@ -1983,6 +2647,9 @@ func DelAllMci(nsId string, option string) (string, error) {
 	case err := <-errCh:
 		return "", fmt.Errorf("failed to delete all MCIs: %v", err)
 	default:
+		if len(protectedMciList) > 0 {
+			return fmt.Sprintf("All MCIs have been deleted except the protected MCIs %v", protectedMciList), nil
+		}
 		return "All MCIs have been deleted", nil
 	}
 }
@@ -2017,7 +2684,6 @@ func GetVmTemplate(nsId string, mciId string, algo string) (model.TbVmInfo, erro
 		return model.TbVmInfo{}, nil
 	}
 
-	rand.Seed(time.Now().UnixNano())
 	index := rand.Intn(len(vmList))
 	vmObj, vmErr := GetVmObject(nsId, mciId, vmList[index])
 	var vmTemplate model.TbVmInfo
@@ -0,0 +1,43 @@
+package infra
+
+import (
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+func TestVmUserPasswordEncryption(t *testing.T) {
+	// the master key is loaded once by the first use in the test binary
+	t.Setenv("TB_ENCRYPTION_KEY", "test master key")
+	if !common.EncryptionEnabled() {
+		t.Skip("the master key is already loaded without TB_ENCRYPTION_KEY")
+	}
+
+	vm := model.TbVmInfo{Id: "vm01", VmUserPassword: "P@ssw0rd-1234"}
+	if err := encryptVmUserPassword(&vm); err != nil {
+		t.Fatal(err)
+	}
+	if !common.IsEncryptedSecret(vm.VmUserPassword) {
+		t.Fatalf("got %q, want an encrypted value", vm.VmUserPassword)
+	}
+	// an encrypted value is not encrypted again (e.g., a VM template from the stored VM)
+	encrypted := vm.VmUserPassword
+	if err := encryptVmUserPassword(&vm); err != nil || vm.VmUserPassword != encrypted {
+		t.Errorf("encrypted again: %q (%v)", vm.VmUserPassword, err)
+	}
+
+	revealed := vm
+	if err := DecryptVmUserPassword(&revealed); err != nil || revealed.VmUserPassword != "P@ssw0rd-1234" {
+		t.Errorf("got %q (%v), want the plaintext", revealed.VmUserPassword, err)
+	}
+
+	mci := model.TbMciInfo{Vm: []model.TbVmInfo{vm, {Id: "vm02"}}}
+	MaskMciInfo(&mci)
+	if mci.Vm[0].VmUserPassword != common.SecretMask || mci.Vm[1].VmUserPassword != "" {
+		t.Errorf("got %q, %q, want the mask and an empty password", mci.Vm[0].VmUserPassword, mci.Vm[1].VmUserPassword)
+	}
+	if vm.VmUserPassword != encrypted {
+		t.Errorf("the VM of the MCI was masked in place: %q", vm.VmUserPassword)
+	}
+}
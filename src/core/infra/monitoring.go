@@ -18,7 +18,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -32,6 +34,14 @@ import (
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
 )
 
+// monMetricFields are the fields of CB-Dragonfly metrics used as the value of each metric
+var monMetricFields = map[string]string{
+	model.MonMetricCpu:  "cpu_utilization",
+	model.MonMetricMem:  "mem_utilization",
+	model.MonMetricDisk: "disk_utilization",
+	model.MonMetricNet:  "bytes_out",
+}
+
 func DFMonAgentInstallReqStructLevelValidation(sl validator.StructLevel) {
 
 	u := sl.Current().Interface().(model.MonAgentInstallReq)
@@ -88,28 +98,63 @@ func CheckDragonflyEndpoint() error {
 
 }
 
-// CallMonitoringAsync is func to call CB-Dragonfly monitoring framework
-func CallMonitoringAsync(wg *sync.WaitGroup, nsID string, mciID string, mciServiceType string, vmID string, givenUserName string, method string, cmd string, returnResult *[]model.SshCmdResult) {
+// setMonAgentStatus updates the monitoring agent status of the VM object
+func setMonAgentStatus(nsId string, mciId string, vmId string, status string, message string) {
+	vmInfoTmp, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	vmInfoTmp.MonAgentStatus = status
+	vmInfoTmp.MonAgentMessage = message
+	UpdateVmInfo(nsId, mciId, vmInfoTmp)
+}
+
+// CallMonitoringAsync is func to call CB-Dragonfly monitoring framework to install (POST) or uninstall (DELETE) the agent of a VM.
+// The monitoring agent status of the VM is updated according to the progress.
+func CallMonitoringAsync(wg *sync.WaitGroup, nsID string, mciID string, mciServiceType string, vmID string, givenUserName string, method string, cmd string, returnResult *model.AgentInstallContent) {
 
 	defer wg.Done() //goroutin sync done
 
+	returnResult.MciId = mciID
+	returnResult.VmId = vmID
+
+	// set vm MonAgentStatus in progress (to avoid duplicated requests)
+	progressStatus, doneStatus := model.MonAgentInstalling, model.MonAgentInstalled
+	if method == http.MethodDelete {
+		progressStatus, doneStatus = model.MonAgentUninstalling, model.MonAgentNotInstalled
+	}
+	setMonAgentStatus(nsID, mciID, vmID, progressStatus, "")
+
+	fail := func(err error) {
+		log.Error().Err(err).Msgf("[Monitoring Agent %s error] %s/%s", method, mciID, vmID)
+		returnResult.Status = model.MonAgentFailed
+		returnResult.Error = err.Error()
+		message := err.Error()
+		if method == http.MethodDelete {
+			message = "failed to uninstall: " + message
+		}
+		setMonAgentStatus(nsID, mciID, vmID, model.MonAgentFailed, message)
+	}
+
 	vmIP, _, sshPort, err := GetVmIp(nsID, mciID, vmID)
-	errStr := ""
 	if err != nil {
-		log.Error().Err(err).Msg("")
-		errStr += "/ " + err.Error()
+		fail(err)
+		return
 	}
+	returnResult.VmIp = vmIP
 	userName, privateKey, err := VerifySshUserName(nsID, mciID, vmID, vmIP, sshPort, givenUserName)
 	if err != nil {
-		log.Error().Err(err).Msg("")
-		errStr += "/ " + err.Error()
+		fail(err)
+		return
+	}
+	if privateKey == "" {
+		fail(fmt.Errorf("request body to call monitoring agent: privateKey is empty"))
+		return
 	}
 	log.Debug().Msg("[CallMonitoringAsync] " + mciID + "/" + vmID + "(" + vmIP + ")" + "with userName:" + userName)
 
-	// set vm MonAgentStatus = "installing" (to avoid duplicated requests)
 	vmInfoTmp, _ := GetVmObject(nsID, mciID, vmID)
-	vmInfoTmp.MonAgentStatus = "installing"
-	UpdateVmInfo(nsID, mciID, vmInfoTmp)
 
 	if mciServiceType == "" {
 		mciServiceType = model.StrMCI
@@ -117,7 +162,7 @@ func CallMonitoringAsync(wg *sync.WaitGroup, nsID string, mciID string, mciServi
 
 	url := model.DragonflyRestUrl + cmd
 	log.Debug().Msg("\n[Calling DRAGONFLY] START")
-	log.Debug().Msg("VM:" + nsID + "/" + mciID + "/" + vmID + ", URL:" + url + ", userName:" + userName + ", cspType:" + vmInfoTmp.ConnectionConfig.ProviderName + ", service_type:" + mciServiceType)
+	log.Debug().Msg("VM:" + nsID + "/" + mciID + "/" + vmID + ", URL:" + url + ", method:" + method + ", userName:" + userName + ", cspType:" + vmInfoTmp.ConnectionConfig.ProviderName + ", service_type:" + mciServiceType)
 
 	requestBody := model.DfAgentInstallReq{
 		NsId:        nsID,
@@ -130,17 +175,11 @@ func CallMonitoringAsync(wg *sync.WaitGroup, nsID string, mciID string, mciServi
 		CspType:     vmInfoTmp.ConnectionConfig.ProviderName,
 		ServiceType: mciServiceType,
 	}
-	if requestBody.SshKey == "" {
-		common.PrintJsonPretty(requestBody)
-		err = fmt.Errorf("/request body to install monitoring agent: privateKey is empty/")
-		log.Error().Err(err).Msg("")
-		errStr += "/ " + err.Error()
-	}
 
 	payload, err := json.Marshal(requestBody)
 	if err != nil {
-		log.Error().Err(err).Msg("")
-		errStr += "/ " + err.Error()
+		fail(err)
+		return
 	}
 
 	responseLimit := 8
@@ -151,96 +190,59 @@ func CallMonitoringAsync(wg *sync.WaitGroup, nsID string, mciID string, mciServi
 		Timeout: time.Duration(responseLimit) * time.Minute,
 	}
 	req, err := http.NewRequest(method, url, strings.NewReader(string(payload)))
-
 	if err != nil {
-		log.Error().Err(err).Msg("")
-		errStr += "/ " + err.Error()
+		fail(err)
+		return
 	}
-
 	req.Header.Add("Content-Type", "application/json")
 
 	res, err := client.Do(req)
-
-	result := ""
-
 	log.Debug().Msg("Called CB-DRAGONFLY API")
 	if err != nil {
-		log.Error().Err(err).Msg("")
-		errStr += "/ " + err.Error()
-	} else {
-
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-			errStr += "/ " + err.Error()
-		}
-		defer res.Body.Close()
-
-		// fmt.Println("HTTP Status code: " + strconv.Itoa(res.StatusCode))
-		switch {
-		case res.StatusCode >= 400 || res.StatusCode < 200:
-			err = fmt.Errorf("CB-DF HTTP Status: " + strconv.Itoa(res.StatusCode) + " / " + string(body))
-			log.Error().Err(err).Msg("")
-			errStr += "/ " + err.Error()
-		}
-
-		result = string(body)
+		fail(err)
+		return
 	}
+	defer res.Body.Close()
 
-	//wg.Done() //goroutin sync done
-
-	//vmInfoTmp, _ := GetVmObject(nsID, mciID, vmID)
-
-	sshResultTmp := model.SshCmdResult{}
-	sshResultTmp.MciId = mciID
-	sshResultTmp.VmId = vmID
-	sshResultTmp.VmIp = vmIP
-
-	sshResultTmp.Stdout = make(map[int]string)
-	sshResultTmp.Stderr = make(map[int]string)
-
-	if err != nil || errStr != "" {
-		log.Error().Err(err).Msgf("[Monitoring Agent deployment errors] %s", errStr)
-		sshResultTmp.Stderr[0] = errStr
-		sshResultTmp.Err = err
-		*returnResult = append(*returnResult, sshResultTmp)
-		vmInfoTmp.MonAgentStatus = "failed"
-	} else {
-		fmt.Println("Result: " + result)
-		sshResultTmp.Stdout[0] = result
-		sshResultTmp.Err = nil
-		*returnResult = append(*returnResult, sshResultTmp)
-		vmInfoTmp.MonAgentStatus = "installed"
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if res.StatusCode >= 400 || res.StatusCode < 200 {
+		fail(fmt.Errorf("CB-DF HTTP Status: " + strconv.Itoa(res.StatusCode) + " / " + string(body)))
+		return
 	}
 
-	UpdateVmInfo(nsID, mciID, vmInfoTmp)
-
+	log.Debug().Msg("Result: " + string(body))
+	returnResult.Result = string(body)
+	returnResult.Status = doneStatus
+	setMonAgentStatus(nsID, mciID, vmID, doneStatus, "")
 }
 
-func InstallMonitorAgentToMci(nsId string, mciId string, mciServiceType string, req *model.MciCmdReq) (model.AgentInstallContentWrapper, error) {
+// callMonitoringAgentToMci calls CB-Dragonfly for the VMs of the MCI selected by the target function in parallel.
+// The VMs not selected are reported with their current status.
+func callMonitoringAgentToMci(nsId string, mciId string, mciServiceType string, userName string, method string, target func(status string) bool) (model.AgentInstallContentWrapper, error) {
 
 	err := common.CheckString(nsId)
 	if err != nil {
-		temp := model.AgentInstallContentWrapper{}
 		log.Error().Err(err).Msg("")
-		return temp, err
+		return model.AgentInstallContentWrapper{}, err
 	}
 
 	err = common.CheckString(mciId)
 	if err != nil {
-		temp := model.AgentInstallContentWrapper{}
 		log.Error().Err(err).Msg("")
-		return temp, err
+		return model.AgentInstallContentWrapper{}, err
 	}
 	check, _ := CheckMci(nsId, mciId)
 
 	if !check {
-		temp := model.AgentInstallContentWrapper{}
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
-		return temp, err
+		err := common.NewResourceNotFoundError("mci", mciId)
+		return model.AgentInstallContentWrapper{}, err
 	}
 
-	content := model.AgentInstallContentWrapper{}
+	content := model.AgentInstallContentWrapper{ResultArray: []model.AgentInstallContent{}}
 
 	//install script
 	cmd := "/agent"
@@ -251,59 +253,127 @@ func InstallMonitorAgentToMci(nsId string, mciId string, mciServiceType string,
 		return content, err
 	}
 
-	log.Debug().Msg("[Install agent for each VM]")
-
 	//goroutin sync wg
 	var wg sync.WaitGroup
 
-	var resultArray []model.SshCmdResult
+	resultArray := make([]model.AgentInstallContent, len(vmList))
 
-	method := "POST"
-
-	for _, v := range vmList {
+	for i, v := range vmList {
 		vmObjTmp, _ := GetVmObject(nsId, mciId, v)
-		fmt.Println("MonAgentStatus : " + vmObjTmp.MonAgentStatus)
+		log.Debug().Msgf("MonAgentStatus (%s): %s", v, vmObjTmp.MonAgentStatus)
+
+		if !target(vmObjTmp.MonAgentStatus) {
+			resultArray[i] = model.AgentInstallContent{
+				MciId:  mciId,
+				VmId:   v,
+				VmIp:   vmObjTmp.PublicIP,
+				Result: "skipped",
+				Status: vmObjTmp.MonAgentStatus,
+			}
+			continue
+		}
+		wg.Add(1)
+		go CallMonitoringAsync(&wg, nsId, mciId, mciServiceType, v, userName, method, cmd, &resultArray[i])
+	}
+	wg.Wait() //goroutin sync wg
 
-		// Request agent installation (skip if in installing or installed status)
-		if vmObjTmp.MonAgentStatus != "installed" && vmObjTmp.MonAgentStatus != "installing" {
+	content.ResultArray = resultArray
 
-			// Avoid RunRemoteCommand to not ready VM
-			if err == nil {
-				wg.Add(1)
-				go CallMonitoringAsync(&wg, nsId, mciId, mciServiceType, v, req.UserName, method, cmd, &resultArray)
-			} else {
-				log.Error().Err(err).Msg("")
-			}
+	return content, nil
+}
+
+// InstallMonitorAgentToMci installs the monitoring agent (CB-Dragonfly agent) to the VMs of the MCI.
+// VMs already installed or in progress are skipped. With option retryFailed, only the failed VMs are retried.
+func InstallMonitorAgentToMci(nsId string, mciId string, mciServiceType string, req *model.MciCmdReq, option string) (model.AgentInstallContentWrapper, error) {
 
+	log.Debug().Msg("[Install agent for each VM]")
+
+	target := func(status string) bool {
+		return status != model.MonAgentInstalled && status != model.MonAgentInstalling && status != model.MonAgentUninstalling
+	}
+	if option == model.MonAgentOptionRetryFailed {
+		target = func(status string) bool {
+			return status == model.MonAgentFailed
 		}
 	}
-	wg.Wait() //goroutin sync wg
+	return callMonitoringAgentToMci(nsId, mciId, mciServiceType, req.UserName, http.MethodPost, target)
+}
 
-	for _, v := range resultArray {
+// UninstallMonitorAgentFromMci uninstalls the monitoring agent (CB-Dragonfly agent) from the VMs of the MCI
+func UninstallMonitorAgentFromMci(nsId string, mciId string, mciServiceType string, userName string) (model.AgentInstallContentWrapper, error) {
 
-		resultTmp := model.AgentInstallContent{}
-		resultTmp.MciId = mciId
-		resultTmp.VmId = v.VmId
-		resultTmp.VmIp = v.VmIp
-		resultTmp.Result = v.Stdout[0]
-		content.ResultArray = append(content.ResultArray, resultTmp)
+	log.Debug().Msg("[Uninstall agent for each VM]")
+
+	target := func(status string) bool {
+		return status == model.MonAgentInstalled || status == model.MonAgentFailed
 	}
+	return callMonitoringAgentToMci(nsId, mciId, mciServiceType, userName, http.MethodDelete, target)
+}
 
-	common.PrintJsonPretty(content)
+// GetMonitorAgentStatus returns the monitoring agent status of the VMs in the MCI (kept in the VM objects)
+func GetMonitorAgentStatus(nsId string, mciId string) (model.MonAgentStatusResponse, error) {
 
-	return content, nil
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.MonAgentStatusResponse{}, err
+	}
 
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.MonAgentStatusResponse{}, err
+	}
+	check, _ := CheckMci(nsId, mciId)
+
+	if !check {
+		err := common.NewResourceNotFoundError("mci", mciId)
+		return model.MonAgentStatusResponse{}, err
+	}
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.MonAgentStatusResponse{}, err
+	}
+
+	content := model.MonAgentStatusResponse{NsId: nsId, MciId: mciId, Summary: map[string]int{}, Vm: []model.MonAgentVmStatus{}}
+	for _, v := range vmList {
+		vmObjTmp, err := GetVmObject(nsId, mciId, v)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			continue
+		}
+		status := vmObjTmp.MonAgentStatus
+		if status == "" {
+			status = model.MonAgentNotInstalled
+		}
+		content.Summary[status]++
+		content.Vm = append(content.Vm, model.MonAgentVmStatus{
+			VmId:       v,
+			SubGroupId: vmObjTmp.SubGroupId,
+			Status:     status,
+			Message:    vmObjTmp.MonAgentMessage,
+		})
+	}
+	return content, nil
 }
 
 // SetMonitoringAgentStatusInstalled is func to Set Monitoring Agent Status Installed
 func SetMonitoringAgentStatusInstalled(nsId string, mciId string, vmId string) error {
-	targetStatus := "installed"
+	targetStatus := model.MonAgentInstalled
 	return UpdateMonitoringAgentStatusManually(nsId, mciId, vmId, targetStatus)
 }
 
 // UpdateMonitoringAgentStatusManually is func to Update Monitoring Agent Installation Status Manually
 func UpdateMonitoringAgentStatusManually(nsId string, mciId string, vmId string, targetStatus string) error {
 
+	switch targetStatus {
+	case model.MonAgentNotInstalled, model.MonAgentInstalled, model.MonAgentFailed:
+	default:
+		return fmt.Errorf("not supported monitoring agent status: %s", targetStatus)
+	}
+
 	vmInfoTmp, err := GetVmObject(nsId, mciId, vmId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -312,10 +382,9 @@ func UpdateMonitoringAgentStatusManually(nsId string, mciId string, vmId string,
 
 	// set vm MonAgentStatus
 	vmInfoTmp.MonAgentStatus = targetStatus
+	vmInfoTmp.MonAgentMessage = "set manually"
 	UpdateVmInfo(nsId, mciId, vmInfoTmp)
 
-	//TODO: add validation for monitoring
-
 	return nil
 }
 
@@ -339,7 +408,7 @@ func GetMonitoringData(nsId string, mciId string, metric string) (model.MonResul
 
 	if !check {
 		temp := model.MonResultSimpleResponse{}
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return temp, err
 	}
 
@@ -442,20 +511,10 @@ func CallGetMonitoringAsync(wg *sync.WaitGroup, nsID string, mciID string, vmID
 		log.Debug().Msg("!gjson.Valid(response)")
 	}
 
-	switch metric {
-	case model.MonMetricCpu:
-		value := gjson.Get(response, "values.cpu_utilization")
+	if field, ok := monMetricFields[metric]; ok {
+		value := gjson.Get(response, "values."+field)
 		result = value.String()
-	case model.MonMetricMem:
-		value := gjson.Get(response, "values.mem_utilization")
-		result = value.String()
-	case model.MonMetricDisk:
-		value := gjson.Get(response, "values.disk_utilization")
-		result = value.String()
-	case model.MonMetricNet:
-		value := gjson.Get(response, "values.bytes_out")
-		result = value.String()
-	default:
+	} else {
 		result = response
 	}
 
@@ -477,3 +536,220 @@ func CallGetMonitoringAsync(wg *sync.WaitGroup, nsID string, mciID string, vmID
 	}
 
 }
+
+// aggregateMonValues returns the aggregate (avg, max, p95) of the values
+func aggregateMonValues(fn string, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	switch fn {
+	case model.MonAggregateMax:
+		result := values[0]
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+		return result
+	case model.MonAggregateP95:
+		sorted := append([]float64{}, values...)
+		sort.Float64s(sorted)
+		// nearest-rank percentile
+		rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return sorted[rank]
+	default:
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	}
+}
+
+// parseMonSeries returns the values of the field from the CB-Dragonfly series ({"columns": [...], "values": [[...], ...]})
+func parseMonSeries(series gjson.Result, field string) []float64 {
+	values := []float64{}
+	index := -1
+	for i, column := range series.Get("columns").Array() {
+		if column.String() == field {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return values
+	}
+	for _, row := range series.Get("values").Array() {
+		value := row.Array()
+		if index >= len(value) || value[index].Type == gjson.Null {
+			continue
+		}
+		values = append(values, value[index].Float())
+	}
+	return values
+}
+
+// fetchMonitoringSeries gets the metric series of the VM for the window from CB-Dragonfly
+func fetchMonitoringSeries(nsId string, mciId string, vmId string, metric string, window string) ([]float64, error) {
+	// DF: Get vm monitoring metric info
+	// Path Param: /ns/:nsId/mci/:mciId/vm/:vmId/metric/:metric_name/info
+	url := model.DragonflyRestUrl + "/ns/" + nsId + "/mci/" + mciId + "/vm/" + vmId + "/metric/" + metric + "/info" +
+		"?periodType=m&statisticsCriteria=avg&duration=" + window
+
+	client := &http.Client{Timeout: 1 * time.Minute}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 400 || res.StatusCode < 200 {
+		return nil, fmt.Errorf("CB-DF HTTP Status: %d / %s", res.StatusCode, string(body))
+	}
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("invalid response from CB-DF")
+	}
+
+	field := monMetricFields[metric]
+	response := gjson.ParseBytes(body)
+	values := []float64{}
+	if response.IsArray() {
+		for _, series := range response.Array() {
+			values = append(values, parseMonSeries(series, field)...)
+		}
+	} else {
+		values = parseMonSeries(response, field)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no %s samples in the last %s", metric, window)
+	}
+	return values, nil
+}
+
+// GetMonitoringAggregate returns the aggregate (avg, max, p95) of the metric for the window per VM, per subGroup and MCI-wide.
+// The series of VMs are fetched from CB-Dragonfly in parallel and VMs which failed to report are flagged.
+// If threshold is given, Exceeded reports whether the MCI-wide aggregate is over the threshold (e.g., for the MCI auto policy).
+func GetMonitoringAggregate(nsId string, mciId string, metric string, fn string, window string, threshold *float64) (model.MonAggregateResponse, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.MonAggregateResponse{}, err
+	}
+
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.MonAggregateResponse{}, err
+	}
+	check, _ := CheckMci(nsId, mciId)
+
+	if !check {
+		err := common.NewResourceNotFoundError("mci", mciId)
+		return model.MonAggregateResponse{}, err
+	}
+
+	if _, ok := monMetricFields[metric]; !ok {
+		return model.MonAggregateResponse{}, fmt.Errorf("not supported metric for aggregation: %s (cpu, mem, disk, net)", metric)
+	}
+	if fn == "" {
+		fn = model.MonAggregateAvg
+	}
+	if fn != model.MonAggregateAvg && fn != model.MonAggregateMax && fn != model.MonAggregateP95 {
+		return model.MonAggregateResponse{}, fmt.Errorf("not supported aggregate function: %s (avg, max, p95)", fn)
+	}
+	if window == "" {
+		window = "5m"
+	}
+	if d, err := time.ParseDuration(window); err != nil || d <= 0 {
+		return model.MonAggregateResponse{}, fmt.Errorf("invalid window: %s (e.g., 5m, 1h)", window)
+	}
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.MonAggregateResponse{}, err
+	}
+
+	vmResults := make([]model.MonVmAggregate, len(vmList))
+	vmSamples := make([][]float64, len(vmList))
+
+	var wg sync.WaitGroup
+	for i, vmId := range vmList {
+		wg.Add(1)
+		go func(i int, vmId string) {
+			defer wg.Done()
+			vmResults[i].VmId = vmId
+			vmObj, err := GetVmObject(nsId, mciId, vmId)
+			if err != nil {
+				vmResults[i].Error = err.Error()
+				return
+			}
+			vmResults[i].SubGroupId = vmObj.SubGroupId
+			values, err := fetchMonitoringSeries(nsId, mciId, vmId, metric, window)
+			if err != nil {
+				log.Debug().Err(err).Msgf("Failed to get %s of the VM %s", metric, vmId)
+				vmResults[i].Error = err.Error()
+				return
+			}
+			vmSamples[i] = values
+			vmResults[i].Reported = true
+			vmResults[i].Samples = len(values)
+			vmResults[i].Value = aggregateMonValues(fn, values)
+		}(i, vmId)
+	}
+	wg.Wait()
+
+	content := model.MonAggregateResponse{
+		NsId:      nsId,
+		MciId:     mciId,
+		Metric:    metric,
+		Fn:        fn,
+		Window:    window,
+		TotalVms:  len(vmList),
+		SubGroups: []model.MonSubGroupAggregate{},
+		Vm:        vmResults,
+		Threshold: threshold,
+	}
+
+	allSamples := []float64{}
+	subGroupSamples := map[string][]float64{}
+	subGroupIndex := map[string]int{}
+	for i, vmResult := range vmResults {
+		if _, ok := subGroupIndex[vmResult.SubGroupId]; !ok {
+			subGroupIndex[vmResult.SubGroupId] = len(content.SubGroups)
+			content.SubGroups = append(content.SubGroups, model.MonSubGroupAggregate{SubGroupId: vmResult.SubGroupId})
+		}
+		subGroup := &content.SubGroups[subGroupIndex[vmResult.SubGroupId]]
+		subGroup.TotalVms++
+		if !vmResult.Reported {
+			continue
+		}
+		subGroup.ReportedVms++
+		content.ReportedVms++
+		allSamples = append(allSamples, vmSamples[i]...)
+		subGroupSamples[vmResult.SubGroupId] = append(subGroupSamples[vmResult.SubGroupId], vmSamples[i]...)
+
+		if threshold != nil && vmResult.Value > *threshold {
+			content.ExceededVms = append(content.ExceededVms, vmResult.VmId)
+		}
+	}
+	for i := range content.SubGroups {
+		content.SubGroups[i].Value = aggregateMonValues(fn, subGroupSamples[content.SubGroups[i].SubGroupId])
+	}
+	sort.Slice(content.SubGroups, func(i, j int) bool { return content.SubGroups[i].SubGroupId < content.SubGroups[j].SubGroupId })
+	content.Value = aggregateMonValues(fn, allSamples)
+
+	if content.ReportedVms == 0 {
+		return content, fmt.Errorf("no VM in the MCI %s reported %s in the last %s", mciId, metric, window)
+	}
+	if threshold != nil {
+		content.Exceeded = content.Value > *threshold
+	}
+	return content, nil
+}
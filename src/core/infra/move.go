@@ -0,0 +1,297 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// mciMoveRef is a namespace resource referred by the VMs of the MCI being moved
+type mciMoveRef struct {
+	resourceType string
+	resourceId   string
+}
+
+// MoveMci is func to move the MCI (with its VMs, subGroups, NLBs, policies and labels) to another namespace or to rename it.
+// All Key-Value records are rewritten in a single transaction, so the MCI is either fully moved or untouched.
+// For a move to another namespace, the resources used by the VMs (vNet, securityGroup, sshKey, dataDisk)
+// should exist in the target namespace with the same IDs (e.g., registered by /registerCspResource).
+func MoveMci(nsId string, mciId string, req *model.MciMoveReq) (model.TbMciInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	targetNsId := req.TargetNsId
+	targetMciId := common.NVL(req.TargetMciId, mciId)
+	if err := common.CheckString(targetNsId); err != nil {
+		return model.TbMciInfo{}, common.NewValidationFailedError("Invalid targetNsId (%s): %s", targetNsId, err.Error())
+	}
+	if err := common.CheckString(targetMciId); err != nil {
+		return model.TbMciInfo{}, common.NewValidationFailedError("Invalid targetMciId (%s): %s", targetMciId, err.Error())
+	}
+	if targetNsId == nsId && targetMciId == mciId {
+		return model.TbMciInfo{}, common.NewValidationFailedError("The target (%s/%s) is the same as the MCI", targetNsId, targetMciId)
+	}
+
+	check, _ := CheckMci(nsId, mciId)
+	if !check {
+		return model.TbMciInfo{}, common.NewResourceNotFoundError("mci", mciId)
+	}
+	check, _ = common.CheckNs(targetNsId)
+	if !check {
+		return model.TbMciInfo{}, common.NewResourceNotFoundError("namespace", targetNsId)
+	}
+	check, _ = CheckMci(targetNsId, targetMciId)
+	if check {
+		return model.TbMciInfo{}, common.NewConflictError("The MCI %s already exists in the namespace %s", targetMciId, targetNsId)
+	}
+
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	if mci.TargetAction != "" && mci.TargetAction != model.ActionComplete {
+		return model.TbMciInfo{}, common.NewConflictError("The MCI %s is under %s; move it after the action is completed", mciId, mci.TargetAction)
+	}
+
+	crossNs := targetNsId != nsId
+	refs := []mciMoveRef{}
+	if crossNs {
+		// validate the connection allowlist and the quota of the target namespace first
+		requested := model.NsQuotaUsage{}
+		for _, vm := range mci.Vm {
+			if err := common.CheckNsConnectionAllowed(targetNsId, vm.ConnectionName); err != nil {
+				return model.TbMciInfo{}, err
+			}
+			vmUsage := resource.GetVmQuotaUsage(nsId, vm.ConnectionName, vm.SpecId, vm.RootDiskSize, 1)
+			requested.Vms += vmUsage.Vms
+			requested.VCpus += vmUsage.VCpus
+			requested.DataDiskGB += vmUsage.DataDiskGB
+		}
+		if err := resource.CheckNsQuota(targetNsId, requested); err != nil {
+			return model.TbMciInfo{}, err
+		}
+	}
+	for _, vm := range mci.Vm {
+		if vm.VNetId != "" {
+			refs = append(refs, mciMoveRef{model.StrVNet, vm.VNetId})
+		}
+		if vm.SshKeyId != "" {
+			refs = append(refs, mciMoveRef{model.StrSSHKey, vm.SshKeyId})
+		}
+		for _, sgId := range vm.SecurityGroupIds {
+			refs = append(refs, mciMoveRef{model.StrSecurityGroup, sgId})
+		}
+		for _, diskId := range vm.DataDiskIds {
+			refs = append(refs, mciMoveRef{model.StrDataDisk, diskId})
+		}
+	}
+
+	mciKey := common.GenMciKey(nsId, mciId, "")
+	targetMciKey := common.GenMciKey(targetNsId, targetMciId, "")
+	rewrite := func(key string, prefix string, targetPrefix string) string {
+		if key == prefix || strings.HasPrefix(key, prefix+"/") {
+			return targetPrefix + strings.TrimPrefix(key, prefix)
+		}
+		return key
+	}
+	rewriteKey := func(key string) string {
+		key = rewrite(key, mciKey, targetMciKey)
+		key = rewrite(key, common.GenMciPolicyKey(nsId, mciId, ""), common.GenMciPolicyKey(targetNsId, targetMciId, ""))
+		key = rewrite(key, common.GenRecoveryPolicyKey(nsId, mciId), common.GenRecoveryPolicyKey(targetNsId, targetMciId))
+		key = rewrite(key, common.GenMciPolicyHistoryKey(nsId, mciId), common.GenMciPolicyHistoryKey(targetNsId, targetMciId))
+		return key
+	}
+
+	puts := []kvstore.KeyValue{}
+	deletes := []string{}
+	rootKeys := map[string]bool{
+		mciKey:                                     true,
+		common.GenMciPolicyKey(nsId, mciId, ""):    true,
+		common.GenRecoveryPolicyKey(nsId, mciId):   true,
+		common.GenMciPolicyHistoryKey(nsId, mciId): true,
+	}
+
+	// the MCI and its children (VMs, subGroups, NLBs, ...) and the policies of the MCI
+	for prefix := range rootKeys {
+		keyValues, err := kvstore.GetKvList(prefix)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbMciInfo{}, err
+		}
+		for _, kv := range keyValues {
+			if kv.Key != prefix && !strings.HasPrefix(kv.Key, prefix+"/") {
+				continue
+			}
+			value := strings.ReplaceAll(kv.Value, mciKey+"/", targetMciKey+"/")
+			if rootKeys[kv.Key] {
+				obj := map[string]interface{}{}
+				if err := json.Unmarshal([]byte(value), &obj); err == nil {
+					if id, ok := obj["id"].(string); ok && id == mciId {
+						obj["id"] = targetMciId
+					}
+					if id, ok := obj["mciId"].(string); ok && id == mciId {
+						obj["mciId"] = targetMciId
+					}
+					if name, ok := obj["name"].(string); ok && name == mciId {
+						obj["name"] = targetMciId
+					}
+					val, _ := json.Marshal(obj)
+					value = string(val)
+				}
+			}
+			puts = append(puts, kvstore.KeyValue{Key: rewriteKey(kv.Key), Value: value})
+			deletes = append(deletes, kv.Key)
+		}
+	}
+
+	// the labels of the MCI and its children (the label keys are kept since they are based on the uid)
+	labelKeyValues, err := kvstore.GetKvList("/label/")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	for _, kv := range labelKeyValues {
+		labelInfo := model.LabelInfo{}
+		if err := json.Unmarshal([]byte(kv.Value), &labelInfo); err != nil {
+			continue
+		}
+		if labelInfo.ResourceKey != mciKey && !strings.HasPrefix(labelInfo.ResourceKey, mciKey+"/") {
+			continue
+		}
+		isRoot := labelInfo.ResourceKey == mciKey
+		labelInfo.ResourceKey = rewriteKey(labelInfo.ResourceKey)
+		for k, v := range labelInfo.Labels {
+			switch {
+			case k == model.LabelNamespace && v == nsId:
+				labelInfo.Labels[k] = targetNsId
+			case k == model.LabelMciId && v == mciId:
+				labelInfo.Labels[k] = targetMciId
+			case isRoot && (k == model.LabelId || k == model.LabelName) && v == mciId:
+				labelInfo.Labels[k] = targetMciId
+			}
+		}
+		val, _ := json.Marshal(labelInfo)
+		puts = append(puts, kvstore.KeyValue{Key: kv.Key, Value: string(val)})
+	}
+
+	// the associated object lists of the resources used by the VMs
+	refObjects := map[string]map[string]interface{}{}
+	refOrder := []string{}
+	getRefObject := func(refNsId string, ref mciMoveRef) (map[string]interface{}, error) {
+		key := common.GenResourceKey(refNsId, ref.resourceType, ref.resourceId)
+		if obj, ok := refObjects[key]; ok {
+			return obj, nil
+		}
+		keyValue, err := kvstore.GetKv(key)
+		if err != nil {
+			return nil, err
+		}
+		if keyValue == (kvstore.KeyValue{}) {
+			return nil, nil
+		}
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(keyValue.Value), &obj); err != nil {
+			return nil, err
+		}
+		refObjects[key] = obj
+		refOrder = append(refOrder, key)
+		return obj, nil
+	}
+	missing := []string{}
+	for _, ref := range refs {
+		obj, err := getRefObject(nsId, ref)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbMciInfo{}, err
+		}
+		moved := []interface{}{}
+		if obj != nil {
+			kept := []interface{}{}
+			list, _ := obj["associatedObjectList"].([]interface{})
+			for _, item := range list {
+				objectKey, _ := item.(string)
+				if newKey := rewriteKey(objectKey); newKey != objectKey {
+					moved = append(moved, newKey)
+					continue
+				}
+				kept = append(kept, item)
+			}
+			if !crossNs {
+				kept = append(kept, moved...)
+			}
+			obj["associatedObjectList"] = kept
+		}
+		if !crossNs {
+			continue
+		}
+		targetObj, err := getRefObject(targetNsId, ref)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbMciInfo{}, err
+		}
+		if targetObj == nil {
+			missing = common.AppendIfMissing(missing, ref.resourceType+"/"+ref.resourceId)
+			continue
+		}
+		list, _ := targetObj["associatedObjectList"].([]interface{})
+		targetObj["associatedObjectList"] = append(list, moved...)
+	}
+	if len(missing) > 0 {
+		return model.TbMciInfo{}, common.NewValidationFailedError("The resources used by the VMs do not exist in the namespace %s: [%s] (register them in the namespace first)", targetNsId, strings.Join(missing, ", "))
+	}
+	for _, key := range refOrder {
+		val, _ := json.Marshal(refObjects[key])
+		puts = append(puts, kvstore.KeyValue{Key: key, Value: string(val)})
+	}
+
+	// move the records only if the MCI is not changed (deleted and recreated) and the target is still free
+	createRevision, _, err := kvstore.GetRevision(mciKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	compares := []kvstore.TxnCompare{
+		{Key: mciKey, Revision: createRevision, Create: true},
+		{Key: targetMciKey, Revision: 0, Create: true},
+	}
+	ok, err := kvstore.Txn(compares, puts, deletes)
+	if err != nil {
+		err = fmt.Errorf("failed to move the MCI %s (%d records) atomically: %w", mciKey, len(puts)+len(deletes), err)
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	if !ok {
+		return model.TbMciInfo{}, common.NewConflictError("The MCI %s or the target %s is changed while moving the MCI; try again", mciKey, targetMciKey)
+	}
+	log.Info().Msgf("Moved MCI %s to %s (%d records)", mciKey, targetMciKey, len(deletes))
+
+	return GetMciObject(targetNsId, targetMciId)
+}
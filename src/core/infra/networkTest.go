@@ -0,0 +1,651 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// networkTestDefaultConcurrency is the default number of source VMs probing in parallel
+	networkTestDefaultConcurrency = 10
+	networkTestMaxConcurrency     = 50
+	// networkTestDefaultTimeoutSec is the default timeout of a probe
+	networkTestDefaultTimeoutSec = 3
+	networkTestMaxTimeoutSec     = 30
+	// networkTestProbeBatch is the number of probes run in parallel on a source VM
+	networkTestProbeBatch = 20
+	// networkTestResultPrefix prefixes the result line of a probe in the output of the remote command
+	networkTestResultPrefix = "TBNET"
+)
+
+// genNetworkTestKey returns the key of the network test run
+func genNetworkTestKey(nsId string, mciId string, runId string) string {
+	return "/ns/" + nsId + "/networkTest/" + mciId + "/" + runId
+}
+
+// putNetworkTestRun stores the network test run
+func putNetworkTestRun(run model.NetworkTestRun) error {
+	val, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	err = kvstore.Put(genNetworkTestKey(run.NsId, run.MciId, run.RunId), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+	return err
+}
+
+// validateNetworkTestReq validates the request and fills the defaults
+func validateNetworkTestReq(req *model.NetworkTestReq) error {
+	if len(req.Ports) == 0 {
+		req.Ports = []model.NetworkTestPort{{Protocol: model.NetworkTestTcp, Port: 22}}
+	}
+	for i, port := range req.Ports {
+		protocol := strings.ToLower(port.Protocol)
+		if protocol == "" {
+			protocol = model.NetworkTestTcp
+		}
+		switch protocol {
+		case model.NetworkTestTcp, model.NetworkTestUdp, model.NetworkTestHttp:
+			if port.Port < 1 || port.Port > 65535 {
+				return common.NewValidationFailedError("invalid port %d for %s (1-65535)", port.Port, protocol)
+			}
+		case model.NetworkTestIcmp:
+			req.Ports[i].Port = 0
+		default:
+			return common.NewValidationFailedError("invalid protocol %s (tcp, udp, http or icmp)", port.Protocol)
+		}
+		req.Ports[i].Protocol = protocol
+	}
+
+	req.TargetIpType = strings.ToLower(req.TargetIpType)
+	switch req.TargetIpType {
+	case "":
+		req.TargetIpType = "both"
+	case "private", "public", "both":
+	default:
+		return common.NewValidationFailedError("invalid targetIpType %s (private, public or both)", req.TargetIpType)
+	}
+
+	if req.TimeoutSec <= 0 {
+		req.TimeoutSec = networkTestDefaultTimeoutSec
+	}
+	if req.TimeoutSec > networkTestMaxTimeoutSec {
+		req.TimeoutSec = networkTestMaxTimeoutSec
+	}
+	if req.Concurrency <= 0 {
+		req.Concurrency = networkTestDefaultConcurrency
+	}
+	if req.Concurrency > networkTestMaxConcurrency {
+		req.Concurrency = networkTestMaxConcurrency
+	}
+	return nil
+}
+
+// networkProbeCommand returns the shell command which probes the target IP (exit code 0 if reachable).
+// A missing probe tool exits with 127.
+func networkProbeCommand(cell model.NetworkTestCell, timeoutSec int) string {
+	t := strconv.Itoa(timeoutSec)
+	port := strconv.Itoa(cell.Port)
+	switch cell.Protocol {
+	case model.NetworkTestUdp:
+		// udp is connectionless, so nc reports success unless an ICMP port unreachable is returned
+		return "if command -v nc >/dev/null; then timeout " + t + " nc -z -u -w " + t + " " + cell.TargetIp + " " + port + "; else (exit 127); fi"
+	case model.NetworkTestHttp:
+		return "if command -v curl >/dev/null; then curl -s -o /dev/null --connect-timeout " + t + " -m " + t + " http://" + net.JoinHostPort(cell.TargetIp, port) + "/; else (exit 127); fi"
+	case model.NetworkTestIcmp:
+		return "if command -v ping >/dev/null; then timeout " + t + " ping -c 1 -W " + t + " " + cell.TargetIp + "; else (exit 127); fi"
+	default:
+		// bash /dev/tcp is the fallback on images without nc
+		return "if command -v nc >/dev/null; then timeout " + t + " nc -z -w " + t + " " + cell.TargetIp + " " + port + "; else timeout " + t + " bash -c '</dev/tcp/" + cell.TargetIp + "/" + port + "'; fi"
+	}
+}
+
+// networkProbeScript returns the script which runs the probes of the cells on the source VM
+// (in batches of networkTestProbeBatch) and prints "TBNET <cell index> <exit code> <elapsed ms>" for each probe.
+func networkProbeScript(run model.NetworkTestRun, cellIndexes []int) string {
+	var script strings.Builder
+	for n, i := range cellIndexes {
+		fmt.Fprintf(&script, "( s=$(date +%%s%%N); { %s; } >/dev/null 2>&1; r=$?; echo \"%s %d $r $(( ($(date +%%s%%N)-s)/1000000 ))\" ) &\n",
+			networkProbeCommand(run.Cells[i], run.Request.TimeoutSec), networkTestResultPrefix, i)
+		if (n+1)%networkTestProbeBatch == 0 {
+			script.WriteString("wait\n")
+		}
+	}
+	script.WriteString("wait\n")
+	return script.String()
+}
+
+// networkProbeFailureReason returns the reason of a failed probe from the exit code of the probe command.
+// It returns the status of the cell and an empty reason for a reachable target.
+func networkProbeFailureReason(protocol string, exitCode int) (string, string) {
+	switch {
+	case exitCode == 0:
+		return model.NetworkTestCellReachable, ""
+	case exitCode == 124, protocol == model.NetworkTestHttp && exitCode == 28:
+		return model.NetworkTestCellUnreachable, "timeout"
+	case exitCode == 127:
+		return model.NetworkTestCellFailed, "probe tool not found on the source VM (nc, curl or ping)"
+	case protocol == model.NetworkTestHttp && exitCode == 7:
+		return model.NetworkTestCellUnreachable, "connection refused"
+	case protocol == model.NetworkTestIcmp:
+		return model.NetworkTestCellUnreachable, "no reply"
+	}
+	return model.NetworkTestCellUnreachable, fmt.Sprintf("connection failed (exit code %d)", exitCode)
+}
+
+// StartNetworkTest starts an async run which probes the reachability from each VM of the MCI to the private and
+// public IPs of every other VM on the given ports via the remote command channel. Source VMs probe with bounded
+// concurrency, the partially filled matrix is stored as it progresses, and the run is kept for later comparison.
+func StartNetworkTest(nsId string, mciId string, req model.NetworkTestReq) (model.NetworkTestRun, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NetworkTestRun{}, err
+	}
+
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NetworkTestRun{}, err
+	}
+	check, _ := CheckMci(nsId, mciId)
+
+	if !check {
+		err := common.NewResourceNotFoundError("mci", mciId)
+		return model.NetworkTestRun{}, err
+	}
+
+	if err := validateNetworkTestReq(&req); err != nil {
+		return model.NetworkTestRun{}, err
+	}
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NetworkTestRun{}, err
+	}
+
+	sources := []model.TbVmInfo{}
+	targets := []model.TbVmInfo{}
+	for _, vmId := range vmList {
+		vmObj, err := GetVmObject(nsId, mciId, vmId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			continue
+		}
+		if req.SourceSubGroupId == "" || vmObj.SubGroupId == req.SourceSubGroupId {
+			sources = append(sources, vmObj)
+		}
+		if req.TargetSubGroupId == "" || vmObj.SubGroupId == req.TargetSubGroupId {
+			targets = append(targets, vmObj)
+		}
+	}
+	if len(sources) == 0 || len(targets) == 0 {
+		return model.NetworkTestRun{}, common.NewValidationFailedError("no source or target VMs in the MCI %s (sourceSubGroupId: %s, targetSubGroupId: %s)", mciId, req.SourceSubGroupId, req.TargetSubGroupId)
+	}
+
+	run := model.NetworkTestRun{
+		RunId:     "nettest-" + common.GenUid(),
+		NsId:      nsId,
+		MciId:     mciId,
+		Status:    model.JobRunning,
+		StartedAt: time.Now(),
+		Request:   req,
+		Cells:     []model.NetworkTestCell{},
+	}
+	for _, source := range sources {
+		for _, target := range targets {
+			if source.Id == target.Id {
+				continue
+			}
+			targetIps := []struct{ ipType, ip string }{}
+			if req.TargetIpType != "public" {
+				targetIps = append(targetIps, struct{ ipType, ip string }{"private", target.PrivateIP})
+			}
+			if req.TargetIpType != "private" {
+				targetIps = append(targetIps, struct{ ipType, ip string }{"public", target.PublicIP})
+			}
+			for _, targetIp := range targetIps {
+				for _, port := range req.Ports {
+					cell := model.NetworkTestCell{
+						SourceVmId: source.Id,
+						TargetVmId: target.Id,
+						TargetIp:   targetIp.ip,
+						IpType:     targetIp.ipType,
+						Protocol:   port.Protocol,
+						Port:       port.Port,
+						Status:     model.NetworkTestCellPending,
+					}
+					switch {
+					case targetIp.ip == "":
+						cell.Status = model.NetworkTestCellFailed
+						cell.Reason = "no " + targetIp.ipType + " IP address"
+						run.Failed++
+					case net.ParseIP(targetIp.ip) == nil:
+						cell.Status = model.NetworkTestCellFailed
+						cell.Reason = "invalid IP address"
+						run.Failed++
+					}
+					run.Cells = append(run.Cells, cell)
+				}
+			}
+		}
+	}
+	run.Total = len(run.Cells)
+	if run.Total == 0 {
+		return model.NetworkTestRun{}, common.NewValidationFailedError("the MCI %s needs at least 2 VMs to test the network", mciId)
+	}
+
+	return startNetworkTestJob(run)
+}
+
+// startNetworkTestJob stores the run and starts the job which probes the pending cells of the run
+// (grouped by source VM, one remote command per source VM)
+func startNetworkTestJob(run model.NetworkTestRun) (model.NetworkTestRun, error) {
+	nsId, mciId, req := run.NsId, run.MciId, run.Request
+
+	sources := []string{}
+	sourceCells := map[string][]int{}
+	for i, cell := range run.Cells {
+		if cell.Status != model.NetworkTestCellPending {
+			continue
+		}
+		if _, ok := sourceCells[cell.SourceVmId]; !ok {
+			sources = append(sources, cell.SourceVmId)
+		}
+		sourceCells[cell.SourceVmId] = append(sourceCells[cell.SourceVmId], i)
+	}
+
+	if err := putNetworkTestRun(run); err != nil {
+		return model.NetworkTestRun{}, err
+	}
+
+	var runLock sync.Mutex
+	lastSaved := time.Now()
+
+	job, err := common.StartJob(model.JobTypeNetworkTest, genNetworkTestKey(nsId, mciId, run.RunId), func(ctx context.Context) (interface{}, error) {
+		semaphore := make(chan struct{}, req.Concurrency)
+		var wg sync.WaitGroup
+
+		for _, source := range sources {
+			cellIndexes := sourceCells[source]
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(sourceVmId string, cellIndexes []int) {
+				defer func() {
+					<-semaphore
+					wg.Done()
+				}()
+
+				runLock.Lock()
+				script := networkProbeScript(run, cellIndexes)
+				runLock.Unlock()
+
+				stdout, _, err := RunRemoteCommand(nsId, mciId, sourceVmId, req.UserName, []string{script})
+				results := map[int][2]int{}
+				if err == nil {
+					for _, line := range strings.Split(stdout[0], "\n") {
+						fields := strings.Fields(line)
+						if len(fields) != 4 || fields[0] != networkTestResultPrefix {
+							continue
+						}
+						i, err1 := strconv.Atoi(fields[1])
+						exitCode, err2 := strconv.Atoi(fields[2])
+						elapsed, err3 := strconv.Atoi(fields[3])
+						if err1 == nil && err2 == nil && err3 == nil {
+							results[i] = [2]int{exitCode, elapsed}
+						}
+					}
+				}
+
+				runLock.Lock()
+				defer runLock.Unlock()
+				for _, i := range cellIndexes {
+					cell := &run.Cells[i]
+					result, ok := results[i]
+					switch {
+					case err != nil:
+						cell.Status = model.NetworkTestCellFailed
+						cell.Reason = "remote command failed on the source VM: " + err.Error()
+					case !ok:
+						cell.Status = model.NetworkTestCellFailed
+						cell.Reason = "no result from the source VM"
+					default:
+						cell.Status, cell.Reason = networkProbeFailureReason(cell.Protocol, result[0])
+						if cell.Status == model.NetworkTestCellReachable {
+							cell.LatencyMs = float64(result[1])
+						}
+					}
+					switch cell.Status {
+					case model.NetworkTestCellReachable:
+						run.Reachable++
+					case model.NetworkTestCellUnreachable:
+						run.Unreachable++
+					default:
+						run.Failed++
+					}
+				}
+				// store the partial matrix periodically
+				if time.Since(lastSaved) > time.Second {
+					putNetworkTestRun(run)
+					lastSaved = time.Now()
+				}
+			}(source, cellIndexes)
+		}
+		wg.Wait()
+
+		runLock.Lock()
+		defer runLock.Unlock()
+		run.EndedAt = time.Now()
+		run.Status = model.JobSucceeded
+		if ctx.Err() != nil {
+			run.Status = model.JobCanceled
+		}
+		putNetworkTestRun(run)
+		log.Info().Msgf("Network test %s of the MCI %s is finished (reachable: %d, unreachable: %d, failed: %d, total: %d)", run.RunId, mciId, run.Reachable, run.Unreachable, run.Failed, run.Total)
+
+		result := map[string]interface{}{"runId": run.RunId, "reachable": run.Reachable, "unreachable": run.Unreachable, "failed": run.Failed, "total": run.Total}
+		return result, ctx.Err()
+	})
+	if err != nil {
+		return model.NetworkTestRun{}, err
+	}
+
+	runLock.Lock()
+	defer runLock.Unlock()
+	run.JobId = job.Id
+	putNetworkTestRun(run)
+	return run, nil
+}
+
+// GetNetworkTestResult returns the network test run (partial matrix while it is running)
+func GetNetworkTestResult(nsId string, mciId string, runId string) (model.NetworkTestRun, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NetworkTestRun{}, err
+	}
+	keyValue, err := kvstore.GetKv(genNetworkTestKey(nsId, mciId, runId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NetworkTestRun{}, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return model.NetworkTestRun{}, common.NewResourceNotFoundError("networkTest", runId)
+	}
+	run := model.NetworkTestRun{}
+	err = json.Unmarshal([]byte(keyValue.Value), &run)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.NetworkTestRun{}, err
+	}
+	return run, nil
+}
+
+// ListNetworkTestRuns returns the network test runs of the MCI without the cells (newest first)
+func ListNetworkTestRuns(nsId string, mciId string) (model.NetworkTestRunList, error) {
+	result := model.NetworkTestRunList{Runs: []model.NetworkTestRun{}}
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	keyValue, err := kvstore.GetKvList(genNetworkTestKey(nsId, mciId, ""))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	for _, v := range keyValue {
+		run := model.NetworkTestRun{}
+		if err := json.Unmarshal([]byte(v.Value), &run); err != nil {
+			log.Warn().Err(err).Msgf("Failed to parse the network test run %s", v.Key)
+			continue
+		}
+		run.Cells = nil
+		result.Runs = append(result.Runs, run)
+	}
+	sort.Slice(result.Runs, func(i, j int) bool { return result.Runs[i].StartedAt.After(result.Runs[j].StartedAt) })
+	return result, nil
+}
+
+// firewallRuleAllows returns true if the ingress rule allows the protocol and port from the source IP
+func firewallRuleAllows(rule model.TbFirewallRuleInfo, protocol string, port int, sourceIp string) bool {
+	if !strings.EqualFold(rule.Direction, "inbound") {
+		return false
+	}
+	ruleProtocol := strings.ToLower(rule.IPProtocol)
+	if ruleProtocol != "all" && ruleProtocol != "-1" && ruleProtocol != protocol {
+		return false
+	}
+	if protocol != model.NetworkTestIcmp && ruleProtocol == protocol {
+		fromPort, err1 := strconv.Atoi(rule.FromPort)
+		toPort, err2 := strconv.Atoi(rule.ToPort)
+		if err1 == nil && err2 == nil && fromPort != -1 && (port < fromPort || port > toPort) {
+			return false
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(rule.CIDR)
+	if err != nil {
+		return false
+	}
+	return ipNet.Contains(net.ParseIP(sourceIp))
+}
+
+// SuggestNetworkTestRules maps the unreachable probes of the network test run to the security groups attached to the
+// target VMs and proposes the missing ingress rules (protocol, port and /32 CIDR of the source VM) per security group.
+// 0.0.0.0/0 is proposed only for probes to public IPs from a source VM with a public IP if allowPublic is set.
+// With apply, the rules are pushed to the security groups and the fixed probes are re-run in a recheck run.
+func SuggestNetworkTestRules(nsId string, mciId string, runId string, apply bool, allowPublic bool) (model.NetworkTestRuleSuggestionResult, error) {
+	result := model.NetworkTestRuleSuggestionResult{
+		RunId:       runId,
+		Suggestions: []model.SecurityGroupRuleSuggestion{},
+		Skipped:     []model.NetworkTestSkippedProbe{},
+	}
+
+	run, err := GetNetworkTestResult(nsId, mciId, runId)
+	if err != nil {
+		return result, err
+	}
+	if run.Status == model.JobRunning {
+		return result, common.NewValidationFailedError("the network test %s is still running", runId)
+	}
+
+	vms := map[string]model.TbVmInfo{}
+	getVm := func(vmId string) (model.TbVmInfo, error) {
+		if vm, ok := vms[vmId]; ok {
+			return vm, nil
+		}
+		vm, err := GetVmObject(nsId, mciId, vmId)
+		if err == nil {
+			vms[vmId] = vm
+		}
+		return vm, err
+	}
+	securityGroups := map[string]model.TbSecurityGroupInfo{}
+	getSecurityGroup := func(securityGroupId string) (model.TbSecurityGroupInfo, error) {
+		if securityGroup, ok := securityGroups[securityGroupId]; ok {
+			return securityGroup, nil
+		}
+		res, err := resource.GetResource(nsId, model.StrSecurityGroup, securityGroupId)
+		if err != nil {
+			return model.TbSecurityGroupInfo{}, err
+		}
+		securityGroup, _ := res.(model.TbSecurityGroupInfo)
+		securityGroups[securityGroupId] = securityGroup
+		return securityGroup, nil
+	}
+
+	suggestionIndex := map[string]int{}
+	ruleKeys := map[string]bool{}
+	// cells fixed by the rules of each suggestion (re-run after apply)
+	suggestionCells := map[int][]model.NetworkTestCell{}
+
+	for _, cell := range run.Cells {
+		if cell.Status != model.NetworkTestCellUnreachable {
+			continue
+		}
+		probe := fmt.Sprintf("%s -> %s %s %s", cell.SourceVmId, cell.TargetVmId, cell.TargetIp, cell.Protocol)
+		if cell.Protocol != model.NetworkTestIcmp {
+			probe += "/" + strconv.Itoa(cell.Port)
+		}
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, model.NetworkTestSkippedProbe{Probe: probe, Reason: reason})
+		}
+
+		source, err := getVm(cell.SourceVmId)
+		if err != nil {
+			skip("the source VM is not found")
+			continue
+		}
+		target, err := getVm(cell.TargetVmId)
+		if err != nil {
+			skip("the target VM is not found")
+			continue
+		}
+		if len(target.SecurityGroupIds) == 0 {
+			skip("no security group is attached to the target VM")
+			continue
+		}
+
+		// traffic to a private IP comes from the private IP of the source, traffic to a public IP from its public IP
+		sourceIp := source.PrivateIP
+		if cell.IpType == "public" {
+			sourceIp = source.PublicIP
+		}
+		if sourceIp == "" {
+			skip("the source VM has no " + cell.IpType + " IP")
+			continue
+		}
+		cidr := sourceIp + "/32"
+		if cell.IpType == "public" && allowPublic {
+			cidr = "0.0.0.0/0"
+		}
+
+		protocol, port := cell.Protocol, strconv.Itoa(cell.Port)
+		switch protocol {
+		case model.NetworkTestHttp:
+			protocol = model.NetworkTestTcp
+		case model.NetworkTestIcmp:
+			port = "-1"
+		}
+
+		allowedBy := ""
+		for _, securityGroupId := range target.SecurityGroupIds {
+			securityGroup, err := getSecurityGroup(securityGroupId)
+			if err != nil {
+				continue
+			}
+			for _, rule := range securityGroup.FirewallRules {
+				if firewallRuleAllows(rule, protocol, cell.Port, sourceIp) {
+					allowedBy = securityGroupId
+					break
+				}
+			}
+			if allowedBy != "" {
+				break
+			}
+		}
+		if allowedBy != "" {
+			skip("already allowed by the security group " + allowedBy + " (check the routes, the network ACLs or the firewall of the target VM)")
+			continue
+		}
+
+		securityGroupId := target.SecurityGroupIds[0]
+		i, ok := suggestionIndex[securityGroupId]
+		if !ok {
+			i = len(result.Suggestions)
+			suggestionIndex[securityGroupId] = i
+			result.Suggestions = append(result.Suggestions, model.SecurityGroupRuleSuggestion{
+				SecurityGroupId: securityGroupId,
+				ConnectionName:  target.ConnectionName,
+				Rules:           []model.TbFirewallRuleInfo{},
+				Probes:          []string{},
+			})
+		}
+		rule := model.TbFirewallRuleInfo{FromPort: port, ToPort: port, IPProtocol: protocol, Direction: "inbound", CIDR: cidr}
+		ruleKey := strings.Join([]string{securityGroupId, rule.IPProtocol, rule.FromPort, rule.CIDR}, "|")
+		if !ruleKeys[ruleKey] {
+			ruleKeys[ruleKey] = true
+			result.Suggestions[i].Rules = append(result.Suggestions[i].Rules, rule)
+		}
+		result.Suggestions[i].Probes = append(result.Suggestions[i].Probes, probe)
+		suggestionCells[i] = append(suggestionCells[i], cell)
+	}
+
+	if !apply {
+		return result, nil
+	}
+
+	recheck := model.NetworkTestRun{
+		RunId:     "nettest-" + common.GenUid(),
+		NsId:      nsId,
+		MciId:     mciId,
+		Status:    model.JobRunning,
+		StartedAt: time.Now(),
+		Request:   run.Request,
+		RecheckOf: runId,
+		Cells:     []model.NetworkTestCell{},
+	}
+	for i := range result.Suggestions {
+		suggestion := &result.Suggestions[i]
+		rules := append([]model.TbFirewallRuleInfo{}, suggestion.Rules...)
+		if _, err := resource.CreateFirewallRules(nsId, suggestion.SecurityGroupId, rules, false); err != nil {
+			log.Error().Err(err).Msgf("Failed to apply the suggested rules to the security group %s", suggestion.SecurityGroupId)
+			suggestion.Error = err.Error()
+			continue
+		}
+		suggestion.Applied = true
+		for _, cell := range suggestionCells[i] {
+			cell.Status = model.NetworkTestCellPending
+			cell.LatencyMs = 0
+			cell.Reason = ""
+			recheck.Cells = append(recheck.Cells, cell)
+		}
+	}
+	if len(recheck.Cells) == 0 {
+		return result, nil
+	}
+	recheck.Total = len(recheck.Cells)
+	recheck, err = startNetworkTestJob(recheck)
+	if err != nil {
+		return result, err
+	}
+	result.Recheck = &recheck
+	return result, nil
+}
@@ -15,6 +15,7 @@ limitations under the License.
 package infra
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -76,6 +77,17 @@ func OrchestrationController() {
 				log.Debug().Msg("\n[MCI-Policy-StateMachine]")
 				common.PrintJsonPretty(mciPolicyTmp.Policy[policyIndex])
 
+				if mciPolicyTmp.Policy[policyIndex].Disabled {
+					continue
+				}
+				// scaling rules are evaluated by their own (see scaling.go), and invalid ones are skipped
+				if rule := mciPolicyTmp.Policy[policyIndex].ScalingRule; rule != nil {
+					if len(mciPolicyTmp.Policy[policyIndex].ValidationErrors) == 0 {
+						startScalingRuleEvaluation(nsId, mciPolicyTmp.Id, policyIndex, *rule)
+					}
+					continue
+				}
+
 				switch {
 				case mciPolicyTmp.Policy[policyIndex].Status == model.AutoStatusReady:
 					log.Debug().Msg("- PolicyStatus[" + model.AutoStatusReady + "],[" + v + "]")
@@ -267,7 +279,7 @@ func OrchestrationController() {
 
 						// ScaleOut MCI according to the VM requirement.
 						log.Debug().Msg("[Generating VM]")
-						result, vmCreateErr := CreateMciVmDynamic(nsId, mciPolicyTmp.Id, &autoAction.VmDynamicReq)
+						result, vmCreateErr := CreateMciVmDynamic(context.Background(), nsId, mciPolicyTmp.Id, &autoAction.VmDynamicReq)
 						if vmCreateErr != nil {
 							mciPolicyTmp.Policy[policyIndex].Status = model.AutoStatusError
 							UpdateMciPolicyInfo(nsId, mciPolicyTmp)
@@ -385,7 +397,19 @@ func CreateMciPolicy(nsId string, mciId string, u *model.MciPolicyReq) (model.Mc
 	}
 
 	for policyIndex := range u.Policy {
-		u.Policy[policyIndex].Status = model.AutoStatusReady
+		policy := &u.Policy[policyIndex]
+		policy.Status = model.AutoStatusReady
+		policy.ValidationErrors = nil
+		if policy.ScalingRule != nil {
+			normalizeScalingRule(policy.ScalingRule)
+			policy.ValidationErrors = ValidateScalingRule(nsId, mciId, *policy.ScalingRule)
+			if len(policy.ValidationErrors) > 0 {
+				policy.Status = model.AutoStatusInvalid
+			}
+		}
+		if policy.Disabled && policy.Status == model.AutoStatusReady {
+			policy.Status = model.AutoStatusSuspended
+		}
 	}
 
 	req := *u
@@ -531,6 +555,11 @@ func DelMciPolicy(nsId string, mciId string) error {
 		log.Error().Err(err).Msg("")
 		return err
 	}
+	err = kvstore.Delete(common.GenMciPolicyHistoryKey(nsId, mciId))
+	if err != nil && !strings.Contains(err.Error(), model.ErrStrKeyNotFound) {
+		log.Error().Err(err).Msg("")
+		return err
+	}
 
 	return nil
 }
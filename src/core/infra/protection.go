@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/rs/zerolog/log"
+)
+
+// SetMciProtection sets the protection of the MCI. A protected MCI cannot be deleted or terminated.
+func SetMciProtection(nsId string, mciId string, protected bool) (model.TbMciInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	check, _ := CheckMci(nsId, mciId)
+	if !check {
+		return model.TbMciInfo{}, common.NewResourceNotFoundError("mci", mciId)
+	}
+
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbMciInfo{}, err
+	}
+	mci.Protected = protected
+	UpdateMciInfo(nsId, mci)
+	log.Info().Msgf("Set the protection of MCI %s/%s: %t", nsId, mciId, protected)
+
+	return GetMciObject(nsId, mciId)
+}
+
+// SetVmProtection sets the protection of the VM. A protected VM cannot be deleted or terminated (nor its MCI).
+func SetVmProtection(nsId string, mciId string, vmId string, protected bool) (model.TbVmInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbVmInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbVmInfo{}, err
+	}
+	err = common.CheckString(vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbVmInfo{}, err
+	}
+	check, _ := CheckVm(nsId, mciId, vmId)
+	if !check {
+		return model.TbVmInfo{}, common.NewResourceNotFoundError("vm", vmId)
+	}
+
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbVmInfo{}, err
+	}
+	vm.Protected = protected
+	UpdateVmInfo(nsId, mciId, vm)
+	log.Info().Msgf("Set the protection of VM %s/%s/%s: %t", nsId, mciId, vmId, protected)
+
+	return GetVmObject(nsId, mciId, vmId)
+}
+
+// CheckMciProtection returns a conflict error if the MCI or any of its VMs is protected
+func CheckMciProtection(nsId string, mciId string) error {
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		return err
+	}
+	if mci.Protected {
+		return common.NewConflictError("MCI %s is protected; remove the protection (PUT /ns/%s/mci/%s/protection) first", mciId, nsId, mciId)
+	}
+	for _, vm := range mci.Vm {
+		if vm.Protected {
+			return common.NewConflictError("VM %s of MCI %s is protected; remove the protection (PUT /ns/%s/mci/%s/vm/%s/protection) first", vm.Id, mciId, nsId, mciId, vm.Id)
+		}
+	}
+	return nil
+}
+
+// CheckVmProtection returns a conflict error if the VM or its MCI is protected
+func CheckVmProtection(nsId string, mciId string, vmId string) error {
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return err
+	}
+	if vm.Protected {
+		return common.NewConflictError("VM %s of MCI %s is protected; remove the protection (PUT /ns/%s/mci/%s/vm/%s/protection) first", vmId, mciId, nsId, mciId, vmId)
+	}
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		return err
+	}
+	if mci.Protected {
+		return common.NewConflictError("MCI %s is protected; remove the protection (PUT /ns/%s/mci/%s/protection) first", mciId, nsId, mciId)
+	}
+	return nil
+}
+
+// protectedCspResourceNames returns the names and CSP ids of the VMs protected by themselves or by their MCIs
+func protectedCspResourceNames() map[string]bool {
+	names := map[string]bool{}
+	nsIdList, err := common.ListNsId()
+	if err != nil {
+		return names
+	}
+	for _, nsId := range nsIdList {
+		mciIdList, err := ListMciId(nsId)
+		if err != nil {
+			continue
+		}
+		for _, mciId := range mciIdList {
+			mci, err := GetMciObject(nsId, mciId)
+			if err != nil {
+				continue
+			}
+			for _, vm := range mci.Vm {
+				if !mci.Protected && !vm.Protected {
+					continue
+				}
+				for _, name := range []string{vm.Uid, vm.CspResourceName, vm.CspResourceId} {
+					if name != "" {
+						names[name] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
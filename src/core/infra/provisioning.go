@@ -15,7 +15,9 @@ limitations under the License.
 package infra
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -86,7 +88,7 @@ func CreateMciVm(nsId string, mciId string, vmInfoData *model.TbVmInfo) (*model.
 
 	if check {
 		temp := &model.TbVmInfo{}
-		err := fmt.Errorf("The vm " + vmInfoData.Name + " already exists.")
+		err := common.NewConflictError("The vm %s already exists.", vmInfoData.Name)
 		return temp, err
 	}
 
@@ -137,7 +139,7 @@ func CreateMciVm(nsId string, mciId string, vmInfoData *model.TbVmInfo) (*model.
 			reqToMon.UserName = "cb-user" // this MCI user name is temporal code. Need to improve.
 
 			fmt.Printf("\n[InstallMonitorAgentToMci]\n\n")
-			content, err := InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, reqToMon)
+			content, err := InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, reqToMon, "")
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				//mciTmp.InstallMonAgent = "no"
@@ -159,9 +161,23 @@ func ScaleOutMciSubGroup(nsId string, mciId string, subGroupId string, numVMsToA
 	}
 	vmObj, err := GetVmObject(nsId, mciId, vmIdList[0])
 
+	vmTemplate := getVmReqTemplate(vmObj)
+
+	vmTemplate.SubGroupSize = numVMsToAdd
+
+	result, err := CreateMciGroupVm(nsId, mciId, vmTemplate, true)
+	if err != nil {
+		temp := &model.TbMciInfo{}
+		return temp, err
+	}
+	return result, nil
+
+}
+
+// getVmReqTemplate returns the VM request (only the template required to create a VM) of the existing VM
+func getVmReqTemplate(vmObj model.TbVmInfo) *model.TbVmReq {
 	vmTemplate := &model.TbVmReq{}
 
-	// only take template required to create VM
 	vmTemplate.Name = vmObj.SubGroupId
 	vmTemplate.ConnectionName = vmObj.ConnectionName
 	vmTemplate.ImageId = vmObj.ImageId
@@ -176,15 +192,7 @@ func ScaleOutMciSubGroup(nsId string, mciId string, subGroupId string, numVMsToA
 	vmTemplate.RootDiskSize = vmObj.RootDiskSize
 	vmTemplate.Description = vmObj.Description
 
-	vmTemplate.SubGroupSize = numVMsToAdd
-
-	result, err := CreateMciGroupVm(nsId, mciId, vmTemplate, true)
-	if err != nil {
-		temp := &model.TbMciInfo{}
-		return temp, err
-	}
-	return result, nil
-
+	return vmTemplate
 }
 
 // CreateMciGroupVm is func to create MCI groupVM
@@ -258,6 +266,22 @@ func CreateMciGroupVm(nsId string, mciId string, vmRequest *model.TbVmReq, newSu
 		subGroupSize = 1
 	}
 
+	// Check the connection allowlist and the quota of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, vmRequest.ConnectionName)
+	if err != nil {
+		return &model.TbMciInfo{}, err
+	}
+	err = resource.CheckNsQuota(nsId, resource.GetVmQuotaUsage(nsId, vmRequest.ConnectionName, vmRequest.SpecId, vmRequest.RootDiskSize, subGroupSize))
+	if err != nil {
+		return &model.TbMciInfo{}, err
+	}
+
+	// Register the provisioning to the shutdown coordinator
+	endOperation := common.BeginOperation("createMciGroupVm "+common.GenMciSubGroupKey(nsId, mciId, vmRequest.Name), func(reason string) {
+		markMciVmsInterrupted(nsId, mciId, reason)
+	})
+	defer endOperation()
+
 	vmStartIndex := 1
 
 	tentativeVmId := common.ToLower(vmRequest.Name)
@@ -278,6 +302,14 @@ func CreateMciGroupVm(nsId string, mciId string, vmRequest *model.TbVmReq, newSu
 		subGroupInfoData.Name = tentativeVmId
 		subGroupInfoData.Uid = common.GenUid()
 		subGroupInfoData.SubGroupSize = vmRequest.SubGroupSize
+		for _, dep := range vmRequest.DependsOn {
+			dep = common.ToLower(dep)
+			check, _ := CheckSubGroup(nsId, mciId, dep)
+			if !check || dep == tentativeVmId {
+				return nil, common.NewValidationFailedError("subGroup %s depends on an unknown subGroup %s", tentativeVmId, dep)
+			}
+			subGroupInfoData.DependsOn = common.AppendIfMissing(subGroupInfoData.DependsOn, dep)
+		}
 
 		key := common.GenMciSubGroupKey(nsId, mciId, vmRequest.Name)
 		keyValue, err := kvstore.GetKv(key)
@@ -308,9 +340,10 @@ func CreateMciGroupVm(nsId string, mciId string, vmRequest *model.TbVmReq, newSu
 		}
 
 		val, _ := json.Marshal(subGroupInfoData)
-		err = kvstore.Put(key, string(val))
+		err = putMciChildObjects(nsId, mciId, []kvstore.KeyValue{{Key: key, Value: string(val)}})
 		if err != nil {
 			log.Error().Err(err).Msg("")
+			return nil, err
 		}
 		// check stored subGroup object
 		keyValue, err = kvstore.GetKv(key)
@@ -319,6 +352,10 @@ func CreateMciGroupVm(nsId string, mciId string, vmRequest *model.TbVmReq, newSu
 			log.Error().Err(err).Msg("")
 			// return nil, err
 		}
+		err = refreshMciSubGroupOrder(nsId, mciId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+		}
 
 	}
 
@@ -359,6 +396,7 @@ func CreateMciGroupVm(nsId string, mciId string, vmRequest *model.TbVmReq, newSu
 		vmInfoData.ImageId = vmRequest.ImageId
 		vmInfoData.VNetId = vmRequest.VNetId
 		vmInfoData.SubnetId = vmRequest.SubnetId
+		setVmZonePlacement(&vmInfoData, vmRequest, i-1)
 		vmInfoData.SecurityGroupIds = vmRequest.SecurityGroupIds
 		vmInfoData.DataDiskIds = vmRequest.DataDiskIds
 		vmInfoData.SshKeyId = vmRequest.SshKeyId
@@ -441,7 +479,7 @@ func CreateMciGroupVm(nsId string, mciId string, vmRequest *model.TbVmReq, newSu
 			reqToMon.UserName = "cb-user" // this MCI user name is temporal code. Need to improve.
 
 			fmt.Printf("\n[InstallMonitorAgentToMci]\n\n")
-			content, err := InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, reqToMon)
+			content, err := InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, reqToMon, "")
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				//mciTmp.InstallMonAgent = "no"
@@ -465,7 +503,7 @@ func CreateMciGroupVm(nsId string, mciId string, vmRequest *model.TbVmReq, newSu
 }
 
 // CreateMci is func to create MCI obeject and deploy requested VMs (register CSP native VM with option=register)
-func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInfo, error) {
+func CreateMci(ctx context.Context, nsId string, req *model.TbMciReq, option string) (*model.TbMciInfo, error) {
 
 	err := common.CheckString(nsId)
 	if err != nil {
@@ -484,11 +522,45 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 		return nil, err
 	}
 
+	// Resolve the order of subGroups by their dependencies (fail fast for an invalid graph)
+	subGroupDependsOn := subGroupDependsOnOfReq(req.Vm)
+	subGroupOrder, err := ResolveSubGroupOrder(subGroupDependsOn)
+	if err != nil {
+		return nil, err
+	}
+
 	// skip mci id checking for option=register
 	if option != "register" {
+		// Check the naming policy of the namespace
+		err = common.CheckNsNamingPolicy(ctx, nsId, model.StrMCI, req.Name)
+		if err != nil {
+			return nil, err
+		}
+
 		check, _ := CheckMci(nsId, req.Name)
 		if check {
-			err := fmt.Errorf("The mci " + req.Name + " already exists.")
+			err := common.NewConflictError("The mci %s already exists.", req.Name)
+			return nil, err
+		}
+
+		// Check the connection allowlist and the quota of the namespace for all VMs in the request
+		requested := model.NsQuotaUsage{}
+		for _, vmReq := range req.Vm {
+			err = common.CheckNsConnectionAllowed(nsId, vmReq.ConnectionName)
+			if err != nil {
+				return nil, err
+			}
+			subGroupSize, err := strconv.Atoi(vmReq.SubGroupSize)
+			if err != nil {
+				subGroupSize = 1
+			}
+			vmUsage := resource.GetVmQuotaUsage(nsId, vmReq.ConnectionName, vmReq.SpecId, vmReq.RootDiskSize, subGroupSize)
+			requested.Vms += vmUsage.Vms
+			requested.VCpus += vmUsage.VCpus
+			requested.DataDiskGB += vmUsage.DataDiskGB
+		}
+		err = resource.CheckNsQuota(nsId, requested)
+		if err != nil {
 			return nil, err
 		}
 	} else {
@@ -503,19 +575,28 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 	mciId := req.Name
 	vmRequests := req.Vm
 
+	createdVia := model.CreatedViaManual
+	if option == "register" {
+		createdVia = model.CreatedViaRegistered
+	}
+	provenance := common.NewProvenance(ctx, createdVia)
+
 	log.Info().Msg("Create MCI object")
 	key := common.GenMciKey(nsId, mciId, "")
 	mapA := map[string]string{
-		"resourceType":    model.StrMCI,
-		"id":              mciId,
-		"name":            req.Name,
-		"uid":             uid,
-		"description":     req.Description,
-		"status":          model.StatusCreating,
-		"targetAction":    targetAction,
-		"targetStatus":    targetStatus,
-		"installMonAgent": req.InstallMonAgent,
-		"systemLabel":     req.SystemLabel,
+		"resourceType":       model.StrMCI,
+		"id":                 mciId,
+		"name":               req.Name,
+		"uid":                uid,
+		"description":        req.Description,
+		"status":             model.StatusCreating,
+		"targetAction":       targetAction,
+		"targetStatus":       targetStatus,
+		"installMonAgent":    req.InstallMonAgent,
+		"systemLabel":        req.SystemLabel,
+		"createdByRequestId": provenance.CreatedByRequestId,
+		"createdByUser":      provenance.CreatedByUser,
+		"createdVia":         provenance.CreatedVia,
 	}
 	val, err := json.Marshal(mapA)
 	if err != nil {
@@ -542,9 +623,15 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 		model.LabelDescription: req.Description,
 	}
 	for key, value := range req.Label {
+		// system labels cannot be overwritten by user labels
+		if label.IsSystemLabelKey(key) {
+			continue
+		}
 		labels[key] = value
 	}
 
+	common.SetSystemLabels(labels, "")
+	common.SetProvenanceLabels(labels, provenance)
 	err = label.CreateOrUpdateLabel(model.StrMCI, uid, key, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -586,6 +673,12 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 		option = "create"
 	}
 
+	// Register the provisioning to the shutdown coordinator
+	endOperation := common.BeginOperation("createMci "+common.GenMciKey(nsId, mciId, ""), func(reason string) {
+		markMciVmsInterrupted(nsId, mciId, reason)
+	})
+	defer endOperation()
+
 	//goroutin
 	var wg sync.WaitGroup
 
@@ -611,15 +704,17 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 			subGroupInfoData.Name = common.ToLower(vmRequest.Name)
 			subGroupInfoData.Uid = common.GenUid()
 			subGroupInfoData.SubGroupSize = vmRequest.SubGroupSize
+			subGroupInfoData.DependsOn = subGroupDependsOn[subGroupInfoData.Id]
 
 			for i := vmStartIndex; i < subGroupSize+vmStartIndex; i++ {
 				subGroupInfoData.VmId = append(subGroupInfoData.VmId, subGroupInfoData.Id+"-"+strconv.Itoa(i))
 			}
 
 			val, _ := json.Marshal(subGroupInfoData)
-			err := kvstore.Put(key, string(val))
+			err := putMciChildObjects(nsId, mciId, []kvstore.KeyValue{{Key: key, Value: string(val)}})
 			if err != nil {
 				log.Error().Err(err).Msg("")
+				return nil, err
 			}
 
 			// Store label info using CreateOrUpdateLabel
@@ -635,6 +730,7 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 				model.LabelMciUid:         uid,
 				model.LabelMciDescription: req.Description,
 			}
+			common.SetSystemLabels(labels, "")
 			err = label.CreateOrUpdateLabel(model.StrSubGroup, uid, key, labels)
 			if err != nil {
 				log.Error().Err(err).Msg("")
@@ -680,6 +776,7 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 			vmInfoData.ImageId = vmRequest.ImageId
 			vmInfoData.VNetId = vmRequest.VNetId
 			vmInfoData.SubnetId = vmRequest.SubnetId
+			setVmZonePlacement(&vmInfoData, &vmRequest, i-1)
 			vmInfoData.SecurityGroupIds = vmRequest.SecurityGroupIds
 			vmInfoData.DataDiskIds = vmRequest.DataDiskIds
 			vmInfoData.SshKeyId = vmRequest.SshKeyId
@@ -699,41 +796,82 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 	}
 	wg.Wait()
 
-	for _, vmRequest := range vmRequests {
-		// subGroup handling
-		subGroupSize, err := strconv.Atoi(vmRequest.SubGroupSize)
-		if err != nil {
-			subGroupSize = 1
-		}
-
-		for i := vmStartIndex; i <= subGroupSize+vmStartIndex; i++ {
-			vmInfoData := model.TbVmInfo{}
+	err = refreshMciSubGroupOrder(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
 
-			if subGroupSize == 0 { // for VM (not in a group)
-				vmInfoData.Name = common.ToLower(vmRequest.Name)
-			} else { // for VM (in a group)
-				if i == subGroupSize+vmStartIndex {
-					break
-				}
-				vmInfoData.SubGroupId = common.ToLower(vmRequest.Name)
-				vmInfoData.Name = common.ToLower(vmRequest.Name) + "-" + strconv.Itoa(i)
+	// Create VMs stage by stage in the order of subGroups (subGroups in a stage are created in parallel)
+	stageOf := map[string]int{}
+	for i, stage := range subGroupOrder {
+		for _, subGroupId := range stage {
+			stageOf[subGroupId] = i
+		}
+	}
+	failedDependency := ""
+	for stageIndex := range subGroupOrder {
+		stageVmIds := []string{}
+		for _, vmRequest := range vmRequests {
+			if stageOf[common.ToLower(vmRequest.Name)] != stageIndex {
+				continue
 			}
-			vmInfoData.Id = vmInfoData.Name
-			vmId := vmInfoData.Id
-			vmInfoData, err := GetVmObject(nsId, mciId, vmId)
+			// subGroup handling
+			subGroupSize, err := strconv.Atoi(vmRequest.SubGroupSize)
 			if err != nil {
-				log.Error().Err(err).Msg("")
-				return nil, err
+				subGroupSize = 1
 			}
 
-			// Avoid concurrent requests to CSP.
-			time.Sleep(time.Millisecond * 1000)
+			for i := vmStartIndex; i <= subGroupSize+vmStartIndex; i++ {
+				vmInfoData := model.TbVmInfo{}
+
+				if subGroupSize == 0 { // for VM (not in a group)
+					vmInfoData.Name = common.ToLower(vmRequest.Name)
+				} else { // for VM (in a group)
+					if i == subGroupSize+vmStartIndex {
+						break
+					}
+					vmInfoData.SubGroupId = common.ToLower(vmRequest.Name)
+					vmInfoData.Name = common.ToLower(vmRequest.Name) + "-" + strconv.Itoa(i)
+				}
+				vmInfoData.Id = vmInfoData.Name
+				vmId := vmInfoData.Id
+				vmInfoData, err := GetVmObject(nsId, mciId, vmId)
+				if err != nil {
+					log.Error().Err(err).Msg("")
+					return nil, err
+				}
 
-			wg.Add(1)
-			go CreateVm(&wg, nsId, mciId, &vmInfoData, option)
+				// Skip the VMs of the stages after a failed dependency
+				if failedDependency != "" {
+					vmInfoData.Status = model.StatusFailed
+					vmInfoData.SystemMessage = "Not created since the dependency failed: " + failedDependency
+					UpdateVmInfo(nsId, mciId, vmInfoData)
+					continue
+				}
+
+				// Avoid concurrent requests to CSP.
+				time.Sleep(time.Millisecond * 1000)
+
+				stageVmIds = append(stageVmIds, vmId)
+				wg.Add(1)
+				go CreateVm(&wg, nsId, mciId, &vmInfoData, option)
+			}
+		}
+		wg.Wait()
+
+		// The next stage starts after all VMs of this stage are running
+		if failedDependency == "" && stageIndex < len(subGroupOrder)-1 && option != "register" {
+			log.Info().Msgf("MCI %s: stage %d (%v) is created; waiting for the VMs to be running", mciId, stageIndex, subGroupOrder[stageIndex])
+			for _, vmId := range stageVmIds {
+				err := waitVmStatus(nsId, mciId, vmId, model.StatusRunning, subGroupStageTimeout)
+				if err != nil {
+					failedDependency = fmt.Sprintf("VM %s (%v)", vmId, err)
+					log.Error().Err(err).Msgf("MCI %s: stop creating the next stages", mciId)
+					break
+				}
+			}
 		}
 	}
-	wg.Wait()
 
 	mciTmp, err := GetMciObject(nsId, mciId)
 	if err != nil {
@@ -777,7 +915,7 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 			time.Sleep(60 * time.Second)
 
 			fmt.Printf("\n[InstallMonitorAgentToMci]\n\n")
-			content, err := InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, reqToMon)
+			content, err := InstallMonitorAgentToMci(nsId, mciId, model.StrMCI, reqToMon, "")
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				//mciTmp.InstallMonAgent = "no"
@@ -792,6 +930,9 @@ func CreateMci(nsId string, req *model.TbMciReq, option string) (*model.TbMciInf
 		log.Error().Err(err).Msg("")
 		return nil, err
 	}
+	common.EmitEvent(model.EventMciCreated, nsId, common.GenMciKey(nsId, mciId, ""),
+		map[string]interface{}{"status": mciResult.Status, "vmCount": len(mciResult.Vm)})
+	recordMciNsEvent(nsId, mciId, "", model.NsEventCreated, fmt.Sprintf("MCI is created with %d VMs (%s)", len(mciResult.Vm), mciResult.Status))
 	return mciResult, nil
 }
 
@@ -827,6 +968,27 @@ func CheckMciDynamicReq(req *model.MciConnectionConfigCandidatesReq) (*model.Che
 		for _, connectionConfig := range connectionConfigList.Connectionconfig {
 			if connectionConfig.ProviderName == specInfo.ProviderName && strings.Contains(connectionConfig.RegionDetail.RegionName, specInfo.RegionName) {
 				vmReqInfo.ConnectionConfigCandidates = append(vmReqInfo.ConnectionConfigCandidates, connectionConfig.ConfigName)
+
+				rootDisk, err := common.ResolveRootDisk(connectionConfig, req.RootDiskType, req.RootDiskSize)
+				if err != nil {
+					errMessage += "//Invalid root disk for " + connectionConfig.ConfigName + ": " + err.Error()
+				}
+				if vmReqInfo.ResolvedRootDisk == nil {
+					vmReqInfo.ResolvedRootDisk = map[string]model.ResolvedRootDisk{}
+				}
+				vmReqInfo.ResolvedRootDisk[connectionConfig.ConfigName] = rootDisk
+
+				if req.CommonImage != "" {
+					imageId, err := resolveCommonImageId(req.CommonImage, connectionConfig)
+					if err != nil {
+						errMessage += "//Failed to get Image (" + req.CommonImage + ") for " + connectionConfig.ConfigName + ": " + err.Error()
+						continue
+					}
+					if vmReqInfo.SelectedImage == nil {
+						vmReqInfo.SelectedImage = map[string]string{}
+					}
+					vmReqInfo.SelectedImage[connectionConfig.ConfigName] = imageId
+				}
 			}
 		}
 
@@ -846,7 +1008,7 @@ func CheckMciDynamicReq(req *model.MciConnectionConfigCandidatesReq) (*model.Che
 }
 
 // CreateSystemMciDynamic is func to create MCI obeject and deploy requested VMs in a dynamic way
-func CreateSystemMciDynamic(option string) (*model.TbMciInfo, error) {
+func CreateSystemMciDynamic(ctx context.Context, option string) (*model.TbMciInfo, error) {
 	nsId := model.SystemCommonNs
 	req := &model.TbMciDynamicReq{}
 
@@ -910,11 +1072,12 @@ func CreateSystemMciDynamic(option string) (*model.TbMciInfo, error) {
 		return nil, err
 	}
 
-	return CreateMciDynamic("", nsId, req, "")
+	return CreateMciDynamic(ctx, "", nsId, req, "")
 }
 
 // CreateMciDynamic is func to create MCI obeject and deploy requested VMs in a dynamic way
-func CreateMciDynamic(reqID string, nsId string, req *model.TbMciDynamicReq, deployOption string) (*model.TbMciInfo, error) {
+func CreateMciDynamic(ctx context.Context, reqID string, nsId string, req *model.TbMciDynamicReq, deployOption string) (*model.TbMciInfo, error) {
+	ctx = common.WithCreatedVia(ctx, model.CreatedViaMciDynamic)
 
 	mciReq := model.TbMciReq{}
 	mciReq.Name = req.Name
@@ -930,6 +1093,11 @@ func CreateMciDynamic(reqID string, nsId string, req *model.TbMciDynamicReq, dep
 		log.Error().Err(err).Msg("")
 		return emptyMci, err
 	}
+	// Check the naming policy before creating any shared resource
+	err = common.CheckNsNamingPolicy(ctx, nsId, model.StrMCI, req.Name)
+	if err != nil {
+		return emptyMci, err
+	}
 	check, err := CheckMci(nsId, req.Name)
 	if err != nil {
 		err := fmt.Errorf("invalid mci name. %w", err)
@@ -937,11 +1105,22 @@ func CreateMciDynamic(reqID string, nsId string, req *model.TbMciDynamicReq, dep
 		return emptyMci, err
 	}
 	if check {
-		err := fmt.Errorf("The mci " + req.Name + " already exists.")
+		err := common.NewConflictError("The mci %s already exists.", req.Name)
 		return emptyMci, err
 	}
 
 	vmRequest := req.Vm
+
+	// Check the dependencies of subGroups before creating any shared resource
+	dependsOnReq := []model.TbVmReq{}
+	for _, k := range vmRequest {
+		dependsOnReq = append(dependsOnReq, model.TbVmReq{Name: k.Name, DependsOn: k.DependsOn})
+	}
+	_, err = ResolveSubGroupOrder(subGroupDependsOnOfReq(dependsOnReq))
+	if err != nil {
+		return emptyMci, err
+	}
+
 	// Check whether VM names meet requirement.
 	errStr := ""
 	for i, k := range vmRequest {
@@ -958,7 +1137,7 @@ func CreateMciDynamic(reqID string, nsId string, req *model.TbMciDynamicReq, dep
 
 	//If not, generate default resources dynamically.
 	for _, k := range vmRequest {
-		vmReq, err := getVmReqFromDynamicReq(reqID, nsId, &k)
+		vmReq, err := getVmReqFromDynamicReq(ctx, reqID, nsId, &k)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to prefare resources for dynamic MCI creation")
 			// Rollback created default resources
@@ -985,11 +1164,11 @@ func CreateMciDynamic(reqID string, nsId string, req *model.TbMciDynamicReq, dep
 	if deployOption == "hold" {
 		option = "hold"
 	}
-	return CreateMci(nsId, &mciReq, option)
+	return CreateMci(ctx, nsId, &mciReq, option)
 }
 
 // CreateMciVmDynamic is func to create requested VM in a dynamic way and add it to MCI
-func CreateMciVmDynamic(nsId string, mciId string, req *model.TbVmDynamicReq) (*model.TbMciInfo, error) {
+func CreateMciVmDynamic(ctx context.Context, nsId string, mciId string, req *model.TbVmDynamicReq) (*model.TbMciInfo, error) {
 
 	emptyMci := &model.TbMciInfo{}
 	subGroupId := req.Name
@@ -999,11 +1178,11 @@ func CreateMciVmDynamic(nsId string, mciId string, req *model.TbVmDynamicReq) (*
 		return emptyMci, err
 	}
 	if check {
-		err := fmt.Errorf("The name for SubGroup (prefix of VM Id) " + req.Name + " already exists.")
+		err := common.NewConflictError("The name for SubGroup (prefix of VM Id) %s already exists.", req.Name)
 		return emptyMci, err
 	}
 
-	vmReq, err := getVmReqFromDynamicReq("", nsId, req)
+	vmReq, err := getVmReqFromDynamicReq(ctx, "", nsId, req)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return emptyMci, err
@@ -1043,15 +1222,16 @@ func checkCommonResAvailable(req *model.TbVmDynamicReq) error {
 		return err
 	}
 
-	osType := strings.ReplaceAll(k.CommonImage, " ", "")
-	vmReq.ImageId = resource.GetProviderRegionZoneResourceKey(connection.ProviderName, connection.RegionDetail.RegionName, "", osType)
-	// incase of user provided image id completely (e.g. aws+ap-northeast-2+ubuntu22.04)
-	if strings.Contains(k.CommonImage, "+") {
-		vmReq.ImageId = k.CommonImage
+	_, err = resolveCommonImageId(k.CommonImage, connection)
+	if err != nil {
+		err := fmt.Errorf("Failed to get Image " + k.CommonImage + " from " + vmReq.ConnectionName + ": " + err.Error())
+		log.Error().Err(err).Msg("")
+		return err
 	}
-	_, err = resource.GetImage(model.SystemCommonNs, vmReq.ImageId)
+
+	_, err = common.ResolveRootDisk(connection, k.RootDiskType, k.RootDiskSize)
 	if err != nil {
-		err := fmt.Errorf("Failed to get Image " + k.CommonImage + " from " + vmReq.ConnectionName)
+		err := fmt.Errorf("Invalid root disk for " + vmReq.ConnectionName + ": " + err.Error())
 		log.Error().Err(err).Msg("")
 		return err
 	}
@@ -1059,8 +1239,32 @@ func checkCommonResAvailable(req *model.TbVmDynamicReq) error {
 	return nil
 }
 
+// resolveCommonImageId is func to get the id of the common image for the connection.
+// commonImage is a full image id (e.g., aws+ap-northeast-2+ubuntu22.04), an OS type registered for the region (e.g., ubuntu22.04),
+// or an alias resolved to the newest image matched by the normalized OS metadata in the region.
+func resolveCommonImageId(commonImage string, connection model.ConnConfig) (string, error) {
+	// incase of user provided image id completely (e.g. aws+ap-northeast-2+ubuntu22.04)
+	if strings.Contains(commonImage, "+") {
+		_, err := resource.GetImage(model.SystemCommonNs, commonImage)
+		return commonImage, err
+	}
+
+	osType := strings.ReplaceAll(commonImage, " ", "")
+	imageId := resource.GetProviderRegionZoneResourceKey(connection.ProviderName, connection.RegionDetail.RegionName, "", osType)
+	if _, err := resource.GetImage(model.SystemCommonNs, imageId); err == nil {
+		return imageId, nil
+	}
+
+	image, err := resource.ResolveImageAlias(commonImage, connection.ProviderName, connection.RegionDetail.RegionName)
+	if err != nil {
+		return "", err
+	}
+	log.Info().Msgf("Resolved the image alias (%s) to %s for %s", commonImage, image.Id, connection.ConfigName)
+	return image.Id, nil
+}
+
 // getVmReqForDynamicMci is func to getVmReqFromDynamicReq
-func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq) (*model.TbVmReq, error) {
+func getVmReqFromDynamicReq(ctx context.Context, reqID string, nsId string, req *model.TbVmDynamicReq) (*model.TbVmReq, error) {
 
 	onDemand := true
 
@@ -1091,20 +1295,18 @@ func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq
 		log.Error().Err(err).Msg("")
 		return &model.TbVmReq{}, err
 	}
+	err = common.CheckNsConnectionAllowed(nsId, vmReq.ConnectionName)
+	if err != nil {
+		return &model.TbVmReq{}, err
+	}
 
 	// Default resource name has this pattern (nsId + "-shared-" + vmReq.ConnectionName)
 	resourceName := nsId + model.StrSharedResourceName + vmReq.ConnectionName
 
 	vmReq.SpecId = specInfo.Id
-	osType := strings.ReplaceAll(k.CommonImage, " ", "")
-	vmReq.ImageId = resource.GetProviderRegionZoneResourceKey(connection.ProviderName, connection.RegionDetail.RegionName, "", osType)
-	// incase of user provided image id completely (e.g. aws+ap-northeast-2+ubuntu22.04)
-	if strings.Contains(k.CommonImage, "+") {
-		vmReq.ImageId = k.CommonImage
-	}
-	_, err = resource.GetImage(model.SystemCommonNs, vmReq.ImageId)
+	vmReq.ImageId, err = resolveCommonImageId(k.CommonImage, connection)
 	if err != nil {
-		err := fmt.Errorf("Failed to get the Image " + vmReq.ImageId + " from " + vmReq.ConnectionName)
+		err := fmt.Errorf("Failed to get the Image " + k.CommonImage + " from " + vmReq.ConnectionName + ": " + err.Error())
 		log.Error().Err(err).Msg("")
 		return &model.TbVmReq{}, err
 	}
@@ -1120,7 +1322,7 @@ func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq
 			return &model.TbVmReq{}, err
 		}
 		common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Loading default vNet:" + resourceName, Time: time.Now()})
-		err2 := resource.CreateSharedResource(nsId, model.StrVNet, vmReq.ConnectionName)
+		err2 := resource.CreateSharedResource(ctx, nsId, model.StrVNet, vmReq.ConnectionName)
 		if err2 != nil {
 			log.Error().Err(err2).Msg("Failed to create new default vNet " + vmReq.VNetId + " from " + vmReq.ConnectionName)
 			return &model.TbVmReq{}, err2
@@ -1132,8 +1334,23 @@ func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq
 	}
 	vmReq.SubnetId = resourceName
 
-	common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Setting SSHKey:" + resourceName, Time: time.Now()})
-	vmReq.SshKeyId = resourceName
+	// Zone-aware placement (a subnet in each requested zone)
+	if k.Zone != "" || k.SpreadAcrossZones {
+		err = setVmReqZonePlacement(reqID, nsId, vmReq, k)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return &model.TbVmReq{}, err
+		}
+	}
+
+	// SSHKey follows the naming convention of the namespace
+	sshKeyName, err := resource.GetSharedResourceName(nsId, model.StrSSHKey, vmReq.ConnectionName)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return &model.TbVmReq{}, err
+	}
+	common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Setting SSHKey:" + sshKeyName, Time: time.Now()})
+	vmReq.SshKeyId = sshKeyName
 	_, err = resource.GetResource(nsId, model.StrSSHKey, vmReq.SshKeyId)
 	if err != nil {
 		if !onDemand {
@@ -1141,16 +1358,16 @@ func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq
 			log.Error().Err(err).Msg("Failed to get the SSHKey")
 			return &model.TbVmReq{}, err
 		}
-		common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Loading default SSHKey:" + resourceName, Time: time.Now()})
-		err2 := resource.CreateSharedResource(nsId, model.StrSSHKey, vmReq.ConnectionName)
+		common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Loading default SSHKey:" + sshKeyName, Time: time.Now()})
+		err2 := resource.CreateSharedResource(ctx, nsId, model.StrSSHKey, vmReq.ConnectionName)
 		if err2 != nil {
 			log.Error().Err(err2).Msg("Failed to create new default SSHKey " + vmReq.SshKeyId + " from " + vmReq.ConnectionName)
 			return &model.TbVmReq{}, err2
 		} else {
-			log.Info().Msg("Created new default SSHKey: " + vmReq.VNetId)
+			log.Info().Msg("Created new default SSHKey: " + vmReq.SshKeyId)
 		}
 	} else {
-		log.Info().Msg("Found and utilize default SSHKey: " + vmReq.VNetId)
+		log.Info().Msg("Found and utilize default SSHKey: " + vmReq.SshKeyId)
 	}
 
 	common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Setting securityGroup:" + resourceName, Time: time.Now()})
@@ -1164,7 +1381,7 @@ func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq
 			return &model.TbVmReq{}, err
 		}
 		common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Loading default securityGroup:" + resourceName, Time: time.Now()})
-		err2 := resource.CreateSharedResource(nsId, model.StrSecurityGroup, vmReq.ConnectionName)
+		err2 := resource.CreateSharedResource(ctx, nsId, model.StrSecurityGroup, vmReq.ConnectionName)
 		if err2 != nil {
 			log.Error().Err(err2).Msg("Failed to create new default securityGroup " + securityGroup + " from " + vmReq.ConnectionName)
 			return &model.TbVmReq{}, err2
@@ -1182,9 +1399,16 @@ func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq
 	vmReq.Label = k.Label
 	vmReq.SubGroupSize = k.SubGroupSize
 	vmReq.Description = k.Description
-	vmReq.RootDiskType = k.RootDiskType
-	vmReq.RootDiskSize = k.RootDiskSize
+	// root disk of the VM request wins over the default of the connection
+	rootDisk, err := common.ResolveRootDisk(connection, k.RootDiskType, k.RootDiskSize)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return &model.TbVmReq{}, err
+	}
+	vmReq.RootDiskType = rootDisk.RootDiskType
+	vmReq.RootDiskSize = rootDisk.RootDiskSize
 	vmReq.VmUserPassword = k.VmUserPassword
+	vmReq.DependsOn = k.DependsOn
 
 	common.PrintJsonPretty(vmReq)
 	common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Prepared resources for VM:" + vmReq.Name, Info: vmReq, Time: time.Now()})
@@ -1192,33 +1416,394 @@ func getVmReqFromDynamicReq(reqID string, nsId string, req *model.TbVmDynamicReq
 	return vmReq, nil
 }
 
+// setVmReqZonePlacement validates the zone (or zones to spread) of the dynamic request against the region of the connection,
+// and sets subnets in the zones to the VM request (subnets are created in the shared vNet if needed)
+func setVmReqZonePlacement(reqID string, nsId string, vmReq *model.TbVmReq, req *model.TbVmDynamicReq) error {
+	regionDetail, err := common.GetRegionDetailByConnection(vmReq.ConnectionName)
+	if err != nil {
+		return err
+	}
+
+	zones := []string{}
+	if req.Zone != "" {
+		if !resource.ContainsZone(regionDetail.Zones, req.Zone) {
+			return fmt.Errorf("invalid zone %s for the connection %s (available: %v)", req.Zone, vmReq.ConnectionName, regionDetail.Zones)
+		}
+		zones = append(zones, req.Zone)
+	} else {
+		if len(regionDetail.Zones) == 0 {
+			return fmt.Errorf("no zones available to spread VMs for the connection %s", vmReq.ConnectionName)
+		}
+		zones = append(zones, regionDetail.Zones...)
+		// no need for more zones than VMs
+		subGroupSize, _ := strconv.Atoi(req.SubGroupSize)
+		if subGroupSize > 0 && subGroupSize < len(zones) {
+			zones = zones[:subGroupSize]
+		}
+	}
+
+	common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: fmt.Sprintf("Setting subnets for zones: %v", zones), Time: time.Now()})
+	zoneSubnets := []model.ZoneSubnet{}
+	for _, zone := range zones {
+		subnetId, err := resource.GetOrCreateZoneSubnet(nsId, vmReq.VNetId, zone)
+		if err != nil {
+			return err
+		}
+		zoneSubnets = append(zoneSubnets, model.ZoneSubnet{Zone: zone, SubnetId: subnetId})
+	}
+
+	vmReq.Zone = zoneSubnets[0].Zone
+	vmReq.SubnetId = zoneSubnets[0].SubnetId
+	if len(zoneSubnets) > 1 {
+		vmReq.ZoneSubnets = zoneSubnets
+	}
+	return nil
+}
+
+// setVmZonePlacement sets the zone and the subnet of the VM (index in the subGroup) by the placement of the VM request
+func setVmZonePlacement(vmInfoData *model.TbVmInfo, vmRequest *model.TbVmReq, index int) {
+	if len(vmRequest.ZoneSubnets) > 0 {
+		if index < 0 {
+			index = 0
+		}
+		placement := vmRequest.ZoneSubnets[index%len(vmRequest.ZoneSubnets)]
+		vmInfoData.SubnetId = placement.SubnetId
+		vmInfoData.Region.Zone = placement.Zone
+		return
+	}
+	if vmRequest.Zone != "" {
+		vmInfoData.Region.Zone = vmRequest.Zone
+	}
+}
+
+// resolveK8sClusterDynamicReq is func to resolve a K8sCluster dynamic request into a concrete creation plan
+func resolveK8sClusterDynamicReq(nsId string, req *model.TbK8sClusterDynamicReq) (*model.TbK8sClusterDynamicCheckInfo, error) {
+
+	err := common.CheckString(req.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+	if req.ConnectionName != "" {
+		err = common.CheckNsConnectionAllowed(nsId, req.ConnectionName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Build a deployment plan from the requirements and get spec candidates for K8s
+	deploymentPlan := model.DeploymentPlan{}
+	addStringFilter := func(metric string, operand string) {
+		if operand == "" {
+			return
+		}
+		deploymentPlan.Filter.Policy = append(deploymentPlan.Filter.Policy,
+			model.FilterCondition{Metric: metric, Condition: []model.Operation{{Operand: operand}}})
+	}
+	addRangeFilter := func(metric string, r model.Range) {
+		condition := []model.Operation{}
+		if r.Min > 0 {
+			condition = append(condition, model.Operation{Operator: ">=", Operand: strconv.FormatFloat(float64(r.Min), 'f', -1, 32)})
+		}
+		if r.Max > 0 {
+			condition = append(condition, model.Operation{Operator: "<=", Operand: strconv.FormatFloat(float64(r.Max), 'f', -1, 32)})
+		}
+		if len(condition) > 0 {
+			deploymentPlan.Filter.Policy = append(deploymentPlan.Filter.Policy, model.FilterCondition{Metric: metric, Condition: condition})
+		}
+	}
+
+	addStringFilter("infraType", model.StrK8s)
+	if req.ConnectionName != "" {
+		addStringFilter("connectionName", req.ConnectionName)
+	} else {
+		if !strings.EqualFold(req.ProviderName, "any") {
+			addStringFilter("providerName", strings.ToLower(req.ProviderName))
+		}
+		addStringFilter("regionName", strings.ToLower(req.RegionName))
+	}
+	addRangeFilter("vCPU", req.VCPU)
+	addRangeFilter("memoryGiB", req.MemoryGiB)
+
+	specList, err := RecommendVm(model.SystemCommonNs, deploymentPlan)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to recommend specs for K8sCluster")
+		return nil, err
+	}
+	if len(specList) == 0 {
+		err := fmt.Errorf("no spec satisfies the requirements for K8sCluster (%s)", req.Name)
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
+	// Pick the cheapest spec whose provider and region support K8sCluster with the requested version
+	var lastErr error
+	for _, spec := range specList {
+		if req.ConnectionName != "" && spec.ConnectionName != req.ConnectionName {
+			continue
+		}
+		// specs of the connections out of the allowlist of the namespace are not candidates
+		if err := common.CheckNsConnectionAllowed(nsId, spec.ConnectionName); err != nil {
+			lastErr = err
+			continue
+		}
+		connection, err := common.GetConnConfig(spec.ConnectionName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		providerName := connection.ProviderName
+		regionName := connection.RegionDetail.RegionName
+
+		availableVersion, err := common.GetAvailableK8sClusterVersion(providerName, regionName)
+		if err != nil || availableVersion == nil || len(*availableVersion) == 0 {
+			lastErr = fmt.Errorf("no available K8sCluster version for %s/%s", providerName, regionName)
+			continue
+		}
+		version := ""
+		for _, v := range *availableVersion {
+			if req.Version == "" || strings.EqualFold(req.Version, v.Name) || strings.EqualFold(req.Version, v.Id) {
+				version = v.Id
+				break
+			}
+		}
+		if version == "" {
+			lastErr = fmt.Errorf("K8sCluster version %s is not available for %s/%s", req.Version, providerName, regionName)
+			continue
+		}
+
+		nodeGroupsOnCreation, err := common.CheckNodeGroupsOnK8sCreation(providerName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		// Default resource name has this pattern (nsId + "-shared-" + connectionName)
+		resourceName := nsId + model.StrSharedResourceName + spec.ConnectionName
+		sshKeyName, err := resource.GetSharedResourceName(nsId, model.StrSSHKey, spec.ConnectionName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		nodeGroupReq := model.TbK8sNodeGroupReq{
+			Name:            req.NodeGroupName,
+			ImageId:         "default",
+			SpecId:          spec.Id,
+			RootDiskType:    req.RootDiskType,
+			RootDiskSize:    req.RootDiskSize,
+			SshKeyId:        sshKeyName,
+			OnAutoScaling:   req.OnAutoScaling,
+			DesiredNodeSize: req.DesiredNodeSize,
+			MinNodeSize:     req.MinNodeSize,
+			MaxNodeSize:     req.MaxNodeSize,
+		}
+		if nodeGroupReq.Name == "" {
+			nodeGroupReq.Name = "ng" + common.GenUid()[:6]
+		}
+		if nodeGroupReq.RootDiskType == "" {
+			nodeGroupReq.RootDiskType = "default"
+		}
+		if nodeGroupReq.RootDiskSize == "" {
+			nodeGroupReq.RootDiskSize = "default"
+		}
+		if nodeGroupReq.OnAutoScaling == "" {
+			nodeGroupReq.OnAutoScaling = "true"
+		}
+		if nodeGroupReq.DesiredNodeSize == "" {
+			nodeGroupReq.DesiredNodeSize = "1"
+		}
+		if nodeGroupReq.MinNodeSize == "" {
+			nodeGroupReq.MinNodeSize = "1"
+		}
+		if nodeGroupReq.MaxNodeSize == "" {
+			nodeGroupReq.MaxNodeSize = "2"
+		}
+
+		checkInfo := &model.TbK8sClusterDynamicCheckInfo{
+			ConnectionName:       spec.ConnectionName,
+			ProviderName:         providerName,
+			RegionName:           regionName,
+			Spec:                 spec,
+			NodeGroupsOnCreation: nodeGroupsOnCreation.Result == "true",
+			K8sClusterReq: model.TbK8sClusterReq{
+				ConnectionName: spec.ConnectionName,
+				Description:    req.Description,
+				Name:           req.Name,
+				Version:        version,
+				VNetId:         resourceName,
+				// the shared vNet is created with 2 subnets to satisfy CSPs that require multiple subnets
+				SubnetIds:        []string{resourceName, resourceName + "-01"},
+				SecurityGroupIds: []string{resourceName},
+			},
+			SharedResourcesToCreate: []string{},
+		}
+		if checkInfo.NodeGroupsOnCreation {
+			checkInfo.K8sClusterReq.K8sNodeGroupList = []model.TbK8sNodeGroupReq{nodeGroupReq}
+		} else {
+			checkInfo.K8sNodeGroupReq = &nodeGroupReq
+		}
+
+		for _, resType := range []string{model.StrVNet, model.StrSSHKey, model.StrSecurityGroup} {
+			name := resourceName
+			if resType == model.StrSSHKey {
+				name = sshKeyName
+			}
+			if _, err := resource.GetResource(nsId, resType, name); err != nil {
+				checkInfo.SharedResourcesToCreate = append(checkInfo.SharedResourcesToCreate, resType+"/"+name)
+			}
+		}
+
+		return checkInfo, nil
+	}
+
+	err = fmt.Errorf("no spec candidate supports K8sCluster for the requirements (%s): %v", req.Name, lastErr)
+	log.Error().Err(err).Msg("")
+	return nil, err
+}
+
+// CheckK8sClusterDynamicReq is func to check a K8sCluster dynamic request and return the resolved plan without creating anything
+func CheckK8sClusterDynamicReq(nsId string, req *model.TbK8sClusterDynamicReq) (*model.TbK8sClusterDynamicCheckInfo, error) {
+
+	_, err := common.GetNs(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
+	return resolveK8sClusterDynamicReq(nsId, req)
+}
+
+// CreateK8sClusterDynamic is func to create a K8sCluster from high-level requirements (with default resource option)
+func CreateK8sClusterDynamic(ctx context.Context, reqID string, nsId string, req *model.TbK8sClusterDynamicReq) (*model.TbK8sClusterInfo, error) {
+	ctx = common.WithCreatedVia(ctx, model.CreatedViaK8sClusterDynamic)
+
+	plan, err := CheckK8sClusterDynamicReq(nsId, req)
+	if err != nil {
+		return nil, err
+	}
+	// Check the naming policy before creating any shared resource
+	err = common.CheckNsNamingPolicy(ctx, nsId, model.StrK8s, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Resolved K8sCluster plan:" + req.Name, Info: plan, Time: time.Now()})
+
+	// Create default resources on demand
+	for _, resType := range []string{model.StrVNet, model.StrSSHKey, model.StrSecurityGroup} {
+		resourceName, err := resource.GetSharedResourceName(nsId, resType, plan.ConnectionName)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return nil, err
+		}
+		if _, err := resource.GetResource(nsId, resType, resourceName); err == nil {
+			log.Info().Msgf("Found and utilize default %s: %s", resType, resourceName)
+			continue
+		}
+		common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Loading default " + resType + ":" + resourceName, Time: time.Now()})
+		err = resource.CreateSharedResource(ctx, nsId, resType, plan.ConnectionName)
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to create new default %s %s from %s", resType, resourceName, plan.ConnectionName)
+			return nil, err
+		}
+		log.Info().Msgf("Created new default %s: %s", resType, resourceName)
+	}
+
+	common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: "Creating K8sCluster:" + req.Name, Info: plan.K8sClusterReq, Time: time.Now()})
+	result, err := resource.CreateK8sCluster(ctx, nsId, &plan.K8sClusterReq, "")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create K8sCluster dynamically")
+		return nil, err
+	}
+
+	// Some CSPs cannot create node groups with the cluster. Add it once the cluster becomes active.
+	if plan.K8sNodeGroupReq != nil {
+		go addK8sNodeGroupWhenActive(nsId, result.Id, plan.K8sNodeGroupReq)
+	}
+
+	return &result, nil
+}
+
+// addK8sNodeGroupWhenActive is func to wait for a K8sCluster to be active and add a node group to it
+func addK8sNodeGroupWhenActive(nsId string, k8sClusterId string, req *model.TbK8sNodeGroupReq) {
+	const (
+		interval = 30 * time.Second
+		timeout  = 1 * time.Hour
+	)
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		k8sClusterInfo, err := resource.GetK8sCluster(nsId, k8sClusterId)
+		if err != nil {
+			log.Error().Err(err).Msgf("Stop waiting for K8sCluster %s to add node group %s", k8sClusterId, req.Name)
+			return
+		}
+
+		switch k8sClusterInfo.CspViewK8sClusterDetail.Status {
+		case model.SpiderClusterActive:
+			_, err := resource.AddK8sNodeGroup(nsId, k8sClusterId, req)
+			if err != nil {
+				log.Error().Err(err).Msgf("Failed to add node group %s to K8sCluster %s", req.Name, k8sClusterId)
+				return
+			}
+			log.Info().Msgf("Added node group %s to K8sCluster %s", req.Name, k8sClusterId)
+			return
+		case model.SpiderClusterInactive, model.SpiderClusterDeleting:
+			log.Warn().Msgf("K8sCluster %s is %s. Skip adding node group %s", k8sClusterId, k8sClusterInfo.CspViewK8sClusterDetail.Status, req.Name)
+			return
+		}
+	}
+	log.Warn().Msgf("Timeout to wait for K8sCluster %s to add node group %s", k8sClusterId, req.Name)
+}
+
+// markMciVmsInterrupted marks the VMs of the MCI which are still being created as failed with the reason
+func markMciVmsInterrupted(nsId string, mciId string, reason string) {
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	for _, vmId := range vmList {
+		vmInfo, err := GetVmObject(nsId, mciId, vmId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			continue
+		}
+		if vmInfo.Status != model.StatusCreating {
+			continue
+		}
+		vmInfo.Status = model.StatusFailed
+		vmInfo.TargetAction = model.ActionComplete
+		vmInfo.TargetStatus = model.StatusComplete
+		vmInfo.SystemMessage = reason
+		UpdateVmInfo(nsId, mciId, vmInfo)
+		log.Warn().Msgf("VM %s of MCI %s is marked as %s (%s)", vmId, mciId, model.StatusFailed, reason)
+	}
+}
+
 // CreateVmObject is func to add VM to MCI
 func CreateVmObject(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.TbVmInfo) error {
 	log.Debug().Msg("Start to add VM To MCI")
 	//goroutin
 	defer wg.Done()
 
-	key := common.GenMciKey(nsId, mciId, "")
-	keyValue, err := kvstore.GetKv(key)
-	if err != nil {
-		log.Fatal().Err(err).Msg("AddVmToMci(); kvstore.GetKv() returned an error.")
-		return err
-	}
-	if keyValue == (kvstore.KeyValue{}) {
-		return fmt.Errorf("AddVmToMci: Cannot find mciId. Key: %s", key)
-	}
-
 	configTmp, err := common.GetConnConfig(vmInfoData.ConnectionName)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return err
 	}
 	vmInfoData.Location = configTmp.RegionDetail.Location
+	if err := encryptVmUserPassword(vmInfoData); err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
 
-	// Make VM object
-	key = common.GenMciKey(nsId, mciId, vmInfoData.Id)
+	// Make VM object (only if the MCI object exists)
+	key := common.GenMciKey(nsId, mciId, vmInfoData.Id)
 	val, _ := json.Marshal(vmInfoData)
-	err = kvstore.Put(key, string(val))
+	err = putMciChildObjects(nsId, mciId, []kvstore.KeyValue{{Key: key, Value: string(val)}})
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return err
@@ -1227,6 +1812,28 @@ func CreateVmObject(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *m
 	return nil
 }
 
+// putMciChildObjects stores the objects under the MCI (e.g., VMs, subGroups) atomically
+// only if the MCI object exists, so that no child object is left without its MCI
+func putMciChildObjects(nsId string, mciId string, kvs []kvstore.KeyValue) error {
+	mciKey := common.GenMciKey(nsId, mciId, "")
+	createRevision, _, err := kvstore.GetRevision(mciKey)
+	if err != nil {
+		return err
+	}
+	if createRevision == 0 {
+		return fmt.Errorf("cannot find the MCI (%s)", mciKey)
+	}
+	// the create revision is kept while the MCI exists (status updates do not change it)
+	ok, err := kvstore.Txn([]kvstore.TxnCompare{{Key: mciKey, Revision: createRevision, Create: true}}, kvs, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("the MCI (%s) is deleted while storing its objects", mciKey)
+	}
+	return nil
+}
+
 // CreateVm is func to create VM (option = "register" for register existing VM)
 func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.TbVmInfo, option string) error {
 	//goroutin
@@ -1277,6 +1884,21 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 
 	var callResult model.SpiderVMInfo
 
+	// CB-Spider places the VM in the zone of the connection, so the connection of the zone chosen for the VM
+	// (by the zone placement of the subGroup) is used for the VM and its later operations
+	if option != "register" && vmInfoData.Region.Zone != "" {
+		zoneConnConfig, err := common.GetConnConfigForZone(vmInfoData.ConnectionName, vmInfoData.Region.Zone)
+		if err != nil {
+			vmInfoData.Status = model.StatusFailed
+			vmInfoData.SystemMessage = err.Error()
+			UpdateVmInfo(nsId, mciId, *vmInfoData)
+			log.Error().Err(err).Msg("")
+			return err
+		}
+		vmInfoData.ConnectionName = zoneConnConfig.ConfigName
+		vmInfoData.ConnectionConfig = zoneConnConfig
+	}
+
 	// Fill VM creation reqest (request to cb-spider)
 	requestBody := model.SpiderVMReqInfoWrapper{}
 	requestBody.ConnectionName = vmInfoData.ConnectionName
@@ -1287,7 +1909,14 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 	customImageFlag := false
 
 	requestBody.ReqInfo.VMUserId = vmInfoData.VmUserName
-	requestBody.ReqInfo.VMUserPasswd = vmInfoData.VmUserPassword
+	requestBody.ReqInfo.VMUserPasswd, err = common.DecryptSecret(vmInfoData.VmUserPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		vmInfoData.Status = model.StatusFailed
+		vmInfoData.SystemMessage = err.Error()
+		UpdateVmInfo(nsId, mciId, *vmInfoData)
+		return err
+	}
 	// provide a random passwd, if it is not provided by user (the passwd required for Windows)
 	if requestBody.ReqInfo.VMUserPasswd == "" {
 		// assign random string (mixed Uid style)
@@ -1462,9 +2091,24 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 	vmInfoData.AddtionalDetails = callResult.KeyValueList
 	vmInfoData.VmUserName = callResult.VMUserId
 	vmInfoData.VmUserPassword = callResult.VMUserPasswd
+	if err := encryptVmUserPassword(vmInfoData); err != nil {
+		log.Error().Err(err).Msg("")
+		vmInfoData.VmUserPassword = ""
+		vmInfoData.Status = model.StatusFailed
+		vmInfoData.SystemMessage = err.Error()
+		UpdateVmInfo(nsId, mciId, *vmInfoData)
+		return err
+	}
 	vmInfoData.CspResourceName = callResult.IId.NameId
 	vmInfoData.CspResourceId = callResult.IId.SystemId
+	// record the zone where CB-Spider created the VM (the chosen zone if CB-Spider does not return it)
+	assignedZone := vmInfoData.Region.Zone
 	vmInfoData.Region = callResult.Region
+	if vmInfoData.Region.Zone == "" {
+		vmInfoData.Region.Zone = assignedZone
+	} else if assignedZone != "" && !strings.EqualFold(vmInfoData.Region.Zone, assignedZone) {
+		log.Warn().Msgf("VM %s is created in the zone %s instead of the chosen zone %s", vmInfoData.Id, vmInfoData.Region.Zone, assignedZone)
+	}
 	vmInfoData.PublicIP = callResult.PublicIP
 	vmInfoData.SSHPort, _ = TrimIP(callResult.SSHAccessPoint)
 	vmInfoData.PublicDNS = callResult.PublicDNS
@@ -1480,35 +2124,17 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 	vmInfoData.CspVNetId = callResult.VpcIID.SystemId
 	vmInfoData.CspSubnetId = callResult.SubnetIID.SystemId
 	vmInfoData.CspSshKeyId = callResult.KeyPairIId.SystemId
+	vmInfoData.OsType = detectVmOsType(nsId, *vmInfoData)
 
 	if option == "register" {
 
-		// Reconstuct resource IDs
-		// vNet
-		resourceListInNs, err := resource.ListResource(nsId, model.StrVNet, "cspResourceName", callResult.VpcIID.NameId)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-		} else {
-			resourcesInNs := resourceListInNs.([]model.TbVNetInfo) // type assertion
-			for _, resource := range resourcesInNs {
-				if resource.ConnectionName == requestBody.ConnectionName {
-					vmInfoData.VNetId = resource.Id
-					//vmInfoData.SubnetId = resource.SubnetInfoList
-				}
-			}
+		// Link (or register) the vNet, subnet, security groups and SSH key of the VM
+		if vmInfoData.VmUserName == "" {
+			vmInfoData.VmUserName = requestBody.ReqInfo.VMUserId
 		}
-
-		// access Key
-		resourceListInNs, err = resource.ListResource(nsId, model.StrSSHKey, "cspResourceName", callResult.KeyPairIId.NameId)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-		} else {
-			resourcesInNs := resourceListInNs.([]model.TbSshKeyInfo) // type assertion
-			for _, resource := range resourcesInNs {
-				if resource.ConnectionName == requestBody.ConnectionName {
-					vmInfoData.SshKeyId = resource.Id
-				}
-			}
+		linkMessages := linkRegisteredVmResources(nsId, vmKey, &callResult, vmInfoData)
+		if len(linkMessages) > 0 {
+			vmInfoData.SystemMessage = "Some CSP resources of the VM are not linked: " + strings.Join(linkMessages, "; ")
 		}
 
 	} else {
@@ -1540,7 +2166,7 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 			CspResourceId:  v.SystemId,
 		}
 
-		dataDisk, err := resource.CreateDataDisk(nsId, &tbDataDiskReq, "register")
+		dataDisk, err := resource.CreateDataDisk(context.Background(), nsId, &tbDataDiskReq, "register")
 		if err != nil {
 			err = fmt.Errorf("after starting VM %s, failed to register dataDisk %s. \n", vmInfoData.Name, v.NameId)
 			log.Err(err).Msg("")
@@ -1578,8 +2204,29 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 
 	vmInfoData.Status = vmStatusInfoTmp.Status
 
+	// Check SSH reachability of the registered VM if a private key is provided
+	if option == "register" && vmInfoData.Status == model.StatusRunning {
+		probeMessage := ""
+		userName, err := probeSshReachability(nsId, vmInfoData)
+		switch {
+		case errors.Is(err, errSshKeyNotProvided):
+		case err != nil:
+			log.Warn().Err(err).Msgf("SSH is not reachable to the registered VM %s", vmInfoData.Id)
+			probeMessage = "SSH is not reachable: " + err.Error()
+		default:
+			vmInfoData.VmUserName = userName
+			probeMessage = "SSH is reachable with the user " + userName
+		}
+		if probeMessage != "" {
+			if vmInfoData.SystemMessage != "" {
+				vmInfoData.SystemMessage += " / "
+			}
+			vmInfoData.SystemMessage += probeMessage
+		}
+	}
+
 	// Monitoring Agent Installation Status (init: notInstalled)
-	vmInfoData.MonAgentStatus = "notInstalled"
+	vmInfoData.MonAgentStatus = model.MonAgentNotInstalled
 	vmInfoData.NetworkAgentStatus = "notInstalled"
 
 	// set CreatedTime
@@ -1603,10 +2250,21 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 		model.LabelMciId:           mciId,
 		model.LabelCreatedTime:     vmInfoData.CreatedTime,
 		model.LabelConnectionName:  vmInfoData.ConnectionName,
+		model.LabelRegion:          vmInfoData.Region.Region,
+		model.LabelZone:            vmInfoData.Region.Zone,
+	}
+	if option == "register" {
+		labels[model.LabelRegistered] = "true"
+		labels[model.LabelVNetId] = vmInfoData.VNetId
 	}
 	for key, value := range vmInfoData.Label {
+		// system labels cannot be overwritten by user labels
+		if label.IsSystemLabelKey(key) {
+			continue
+		}
 		labels[key] = value
 	}
+	common.SetSystemLabels(labels, vmInfoData.ConnectionName)
 	err = label.CreateOrUpdateLabel(model.StrVM, vmInfoData.Uid, vmKey, labels)
 	if err != nil {
 		err = fmt.Errorf("cannot create label object: %v", err)
@@ -1616,6 +2274,7 @@ func CreateVm(wg *sync.WaitGroup, nsId string, mciId string, vmInfoData *model.T
 
 		log.Error().Err(err).Msg("")
 	}
+	recordMciNsEvent(nsId, mciId, vmInfoData.Id, model.NsEventCreated, fmt.Sprintf("VM is created (spec: %s, connection: %s)", vmInfoData.SpecId, vmInfoData.ConnectionName))
 
 	return nil
 }
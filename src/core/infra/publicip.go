@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// getVmForPublicIp returns the VM object after checking the params and the capability of the provider
+func getVmForPublicIp(nsId string, mciId string, vmId string) (model.TbVmInfo, error) {
+	for _, id := range []string{nsId, mciId, vmId} {
+		if err := common.CheckString(id); err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbVmInfo{}, err
+		}
+	}
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmInfo{}, err
+	}
+	providerName := strings.ToLower(vm.ConnectionConfig.ProviderName)
+	if err := common.CheckCapability(providerName, common.CapabilityPublicIp, "public IP association"); err != nil {
+		return model.TbVmInfo{}, err
+	}
+	if vm.CspResourceName == "" {
+		return model.TbVmInfo{}, fmt.Errorf("the VM %s is not created in the CSP (status: %s)", vmId, vm.Status)
+	}
+	return vm, nil
+}
+
+// checkPublicIpDetachable returns the reasons why detaching the public IP removes the access path to VMs.
+// It also returns true if the VM is a bastion of its subnet.
+func checkPublicIpDetachable(nsId string, mciId string, vm model.TbVmInfo) ([]string, bool, error) {
+	res, err := resource.GetResource(nsId, model.StrVNet, vm.VNetId)
+	if err != nil {
+		return nil, false, err
+	}
+	vNet, ok := res.(model.TbVNetInfo)
+	if !ok {
+		return nil, false, fmt.Errorf("failed to get the vNet %s of the VM %s", vm.VNetId, vm.Id)
+	}
+
+	var bastionNodes []model.BastionNode
+	for _, subnet := range vNet.SubnetInfoList {
+		if subnet.Id == vm.SubnetId {
+			bastionNodes = subnet.BastionNodes
+			break
+		}
+	}
+
+	isBastion := false
+	otherBastion := false
+	for _, node := range bastionNodes {
+		if node.MciId == mciId && node.VmId == vm.Id {
+			isBastion = true
+			continue
+		}
+		publicIp, _, _, err := GetVmIp(nsId, node.MciId, node.VmId)
+		if err == nil && publicIp != "" {
+			otherBastion = true
+		}
+	}
+
+	reasons := []string{}
+	if !otherBastion {
+		reasons = append(reasons, fmt.Sprintf("the VM %s will not be accessible (no other bastion with a public IP in the subnet %s)", vm.Id, vm.SubnetId))
+	}
+	if isBastion {
+		reasons = append(reasons, fmt.Sprintf("the VM %s is a bastion of the subnet %s", vm.Id, vm.SubnetId))
+	}
+	return reasons, isBastion, nil
+}
+
+// AttachPublicIp allocates (or reuses the given address) and associates a public IP to the existing VM via CB-Spider.
+// TbVmInfo.PublicIP is updated and the SSH port is checked with the new address.
+func AttachPublicIp(nsId string, mciId string, vmId string, req *model.TbVmPublicIpReq) (model.TbVmPublicIpInfo, error) {
+	vm, err := getVmForPublicIp(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmPublicIpInfo{}, err
+	}
+	if vm.PublicIP != "" {
+		return model.TbVmPublicIpInfo{}, fmt.Errorf("the VM %s already has a public IP (%s)", vmId, vm.PublicIP)
+	}
+
+	requestBody := model.SpiderPublicIpReqInfoWrapper{
+		ConnectionName: vm.ConnectionName,
+		ReqInfo:        model.SpiderPublicIpReqInfo{PublicIP: req.PublicIpAddress},
+	}
+	callResult := model.SpiderPublicIpInfo{}
+	err = common.ExecuteHttpRequest(
+		resty.New(),
+		"POST",
+		fmt.Sprintf("%s/vm/%s/publicip", model.SpiderRestUrl, vm.CspResourceName),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to attach a public IP to the VM %s", vmId)
+		recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventPublicIpAttached, Result: "Failed", Message: err.Error()})
+		return model.TbVmPublicIpInfo{}, err
+	}
+	if callResult.PublicIP == "" {
+		err := fmt.Errorf("no public IP is returned for the VM %s", vmId)
+		recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventPublicIpAttached, Result: "Failed", Message: err.Error()})
+		return model.TbVmPublicIpInfo{}, err
+	}
+
+	vm, err = GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmPublicIpInfo{}, err
+	}
+	vm.PublicIP = callResult.PublicIP
+	vm.PublicDNS = callResult.PublicDNS
+	UpdateVmInfo(nsId, mciId, vm)
+
+	result := model.TbVmPublicIpInfo{
+		MciId:     mciId,
+		VmId:      vmId,
+		PublicIP:  callResult.PublicIP,
+		PublicDNS: callResult.PublicDNS,
+	}
+	if vm.Status == model.StatusRunning {
+		if err := CheckConnectivity(vm.PublicIP, vm.SSHPort); err != nil {
+			result.Message = fmt.Sprintf("the public IP is attached, but the SSH port is not reachable: %s", err.Error())
+		} else {
+			result.SshReachable = true
+			result.Message = "the public IP is attached and the SSH port is reachable"
+		}
+	} else {
+		result.Message = fmt.Sprintf("the public IP is attached (SSH is not checked since the VM is %s)", vm.Status)
+	}
+
+	recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventPublicIpAttached, Result: "Succeeded", Message: fmt.Sprintf("%s (%s)", vm.PublicIP, result.Message)})
+	common.EmitEvent(model.EventVmPublicIpChanged, nsId, common.GenMciKey(nsId, mciId, vmId), map[string]string{
+		"previousPublicIP": "",
+		"publicIP":         vm.PublicIP,
+	})
+	log.Info().Msgf("Attached the public IP %s to the VM %s", vm.PublicIP, vmId)
+	return result, nil
+}
+
+// DetachPublicIp disassociates the public IP from the VM via CB-Spider (the address is released if release is true).
+// It fails if it removes the only access path (no other bastion in the subnet, or the VM is a bastion) unless force is true.
+func DetachPublicIp(nsId string, mciId string, vmId string, release bool, force bool) (model.TbVmPublicIpInfo, error) {
+	vm, err := getVmForPublicIp(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmPublicIpInfo{}, err
+	}
+	if vm.PublicIP == "" {
+		return model.TbVmPublicIpInfo{}, fmt.Errorf("the VM %s has no public IP", vmId)
+	}
+
+	reasons, isBastion, err := checkPublicIpDetachable(nsId, mciId, vm)
+	if err != nil {
+		return model.TbVmPublicIpInfo{}, err
+	}
+	if len(reasons) > 0 && !force {
+		err := fmt.Errorf("detaching the public IP removes the access path: %s (use force=true to detach anyway)", strings.Join(reasons, "; "))
+		return model.TbVmPublicIpInfo{}, err
+	}
+
+	requestBody := model.SpiderPublicIpReqInfoWrapper{
+		ConnectionName: vm.ConnectionName,
+		ReqInfo:        model.SpiderPublicIpReqInfo{PublicIP: vm.PublicIP, Release: release},
+	}
+	var callResult interface{}
+	err = common.ExecuteHttpRequest(
+		resty.New(),
+		"DELETE",
+		fmt.Sprintf("%s/vm/%s/publicip", model.SpiderRestUrl, vm.CspResourceName),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to detach the public IP from the VM %s", vmId)
+		recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventPublicIpDetached, Result: "Failed", Message: err.Error()})
+		return model.TbVmPublicIpInfo{}, err
+	}
+
+	if isBastion {
+		if _, err := RemoveBastionNodes(nsId, mciId, vmId); err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove the bastion %s", vmId)
+		}
+	}
+
+	previousPublicIp := vm.PublicIP
+	vm, err = GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmPublicIpInfo{}, err
+	}
+	vm.PublicIP = ""
+	vm.PublicDNS = ""
+	UpdateVmInfo(nsId, mciId, vm)
+
+	result := model.TbVmPublicIpInfo{
+		MciId:            mciId,
+		VmId:             vmId,
+		PreviousPublicIP: previousPublicIp,
+		Message:          "the public IP is detached",
+	}
+	if release {
+		result.Message += " and released"
+	}
+	if len(reasons) > 0 {
+		result.Message += fmt.Sprintf(" (forced: %s)", strings.Join(reasons, "; "))
+	}
+
+	recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventPublicIpDetached, Result: "Succeeded", Message: fmt.Sprintf("%s (%s)", previousPublicIp, result.Message)})
+	common.EmitEvent(model.EventVmPublicIpChanged, nsId, common.GenMciKey(nsId, mciId, vmId), map[string]string{
+		"previousPublicIP": previousPublicIp,
+		"publicIP":         "",
+	})
+	log.Info().Msgf("Detached the public IP %s from the VM %s", previousPublicIp, vmId)
+	return result, nil
+}
@@ -96,13 +96,52 @@ func applyRange(field reflect.Value, operator string, operand float32) error {
 
 // RecommendVm is func to recommend a VM
 func RecommendVm(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, error) {
+	result, _, err := RecommendVmWithDiagnostic(nsId, plan)
+	return result, err
+}
+
+// diagnoseEmptyFilter finds the filter condition which eliminated all candidates by applying the filter policies one by one
+func diagnoseEmptyFilter(nsId string, plan model.DeploymentPlan) string {
+	u := &model.FilterSpecsByRangeRequest{}
+	candidates, err := resource.FilterSpecsByRange(nsId, *u)
+	if err != nil {
+		return ""
+	}
+	if len(candidates) == 0 {
+		return "no spec is registered in the system (fetch specs first)"
+	}
+	for _, policy := range plan.Filter.Policy {
+		partialPlan := model.DeploymentPlan{Filter: model.FilterInfo{Policy: []model.FilterCondition{policy}}}
+		if err := applyFilterPolicies(u, &partialPlan); err != nil {
+			return err.Error()
+		}
+		specs, err := resource.FilterSpecsByRange(nsId, *u)
+		if err != nil {
+			return ""
+		}
+		if len(specs) == 0 {
+			conditions := []string{}
+			for _, condition := range policy.Condition {
+				conditions = append(conditions, strings.TrimSpace(condition.Operator+" "+condition.Operand))
+			}
+			return fmt.Sprintf("no spec satisfies the filter on %s (%s); %d specs satisfied the preceding conditions",
+				policy.Metric, strings.Join(conditions, ", "), len(candidates))
+		}
+		candidates = specs
+	}
+	return ""
+}
+
+// RecommendVmWithDiagnostic is func to recommend a VM.
+// If no spec satisfies the filter, it returns an empty list with a diagnostic describing the constraint which eliminated all candidates.
+func RecommendVmWithDiagnostic(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, string, error) {
 	// Filtering first
 
 	u := &model.FilterSpecsByRangeRequest{}
 	// Apply filter policies dynamically.
 	if err := applyFilterPolicies(u, &plan); err != nil {
 		log.Error().Err(err).Msg("Failed to apply filter policies")
-		return nil, err
+		return nil, "", err
 	}
 
 	// veryLargeValue := float32(math.MaxFloat32)
@@ -116,7 +155,7 @@ func RecommendVm(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, er
 
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return []model.TbSpecInfo{}, err
+		return []model.TbSpecInfo{}, "", err
 	}
 	elapsedTime := time.Since(startTime)
 	log.Info().
@@ -125,7 +164,9 @@ func RecommendVm(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, er
 		Msg("Filtering complete")
 
 	if len(filteredSpecs) == 0 {
-		return []model.TbSpecInfo{}, nil
+		diagnostic := diagnoseEmptyFilter(nsId, plan)
+		log.Info().Msgf("No spec is recommended: %s", diagnostic)
+		return []model.TbSpecInfo{}, diagnostic, nil
 	}
 
 	// // sorting based on VCPU and MemoryGiB
@@ -143,6 +184,9 @@ func RecommendVm(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, er
 	prioritySpecs := []model.TbSpecInfo{}
 
 	startTime = time.Now()
+	// scores of each policy by spec id (EvaluationScore09 is the normalized score of a policy, higher is better)
+	weightedScores := map[string]float32{}
+	weightSum := float32(0)
 	for _, v := range plan.Priority.Policy {
 		metric := v.Metric
 
@@ -152,7 +196,11 @@ func RecommendVm(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, er
 		case "performance":
 			prioritySpecs, err = RecommendVmPerformance(nsId, &filteredSpecs)
 		case "cost":
-			prioritySpecs, err = RecommendVmCost(nsId, &filteredSpecs)
+			if hasParameterValue(v.Parameter, "priceType", "spot") {
+				prioritySpecs, err = RecommendVmSpotCost(nsId, &filteredSpecs)
+			} else {
+				prioritySpecs, err = RecommendVmCost(nsId, &filteredSpecs)
+			}
 		case "random":
 			prioritySpecs, err = RecommendVmRandom(nsId, &filteredSpecs)
 		case "latency":
@@ -160,11 +208,26 @@ func RecommendVm(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, er
 		default:
 			prioritySpecs, err = RecommendVmCost(nsId, &filteredSpecs)
 		}
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to prioritize specs by %s", metric)
+			continue
+		}
 
+		weight, errParse := strconv.ParseFloat(v.Weight, 32)
+		if errParse != nil || weight <= 0 {
+			weight = 1
+		}
+		weightSum += float32(weight)
+		for _, spec := range prioritySpecs {
+			weightedScores[spec.Id] += float32(weight) * spec.EvaluationScore09
+		}
 	}
 	if plan.Priority.Policy == nil {
 		prioritySpecs, err = RecommendVmCost(nsId, &filteredSpecs)
 	}
+	if len(plan.Priority.Policy) > 1 && weightSum > 0 {
+		prioritySpecs = combinePriorityScores(filteredSpecs, weightedScores, weightSum)
+	}
 
 	elapsedTime = time.Since(startTime)
 	log.Info().
@@ -184,8 +247,41 @@ func RecommendVm(nsId string, plan model.DeploymentPlan) ([]model.TbSpecInfo, er
 		}
 	}
 
-	return result, nil
+	return result, "", nil
+
+}
+
+// hasParameterValue returns true if the parameter of the key includes the value (case-insensitive)
+func hasParameterValue(param []model.ParameterKeyVal, key string, value string) bool {
+	for _, p := range param {
+		if p.Key != key {
+			continue
+		}
+		for _, v := range p.Val {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
+// combinePriorityScores sorts specs by the weighted average of the scores of multiple priority policies
+func combinePriorityScores(specList []model.TbSpecInfo, weightedScores map[string]float32, weightSum float32) []model.TbSpecInfo {
+	result := append([]model.TbSpecInfo{}, specList...)
+	for i := range result {
+		result[i].EvaluationScore09 = weightedScores[result[i].Id] / weightSum
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].EvaluationScore09 != result[j].EvaluationScore09 {
+			return result[i].EvaluationScore09 > result[j].EvaluationScore09
+		}
+		return result[i].CostPerHour < result[j].CostPerHour
+	})
+	for i := range result {
+		result[i].OrderInFilteredResult = uint16(i + 1)
+	}
+	return result
 }
 
 // RecommendVmLatency func prioritize specs by latency based on given MCI (fair)
@@ -599,6 +695,32 @@ func RecommendVmCost(nsId string, specList *[]model.TbSpecInfo) ([]model.TbSpecI
 	return result, nil
 }
 
+// effectiveSpotCost returns the spot price of the spec, or the on-demand price if the spot price is unknown
+func effectiveSpotCost(spec model.TbSpecInfo) float32 {
+	if spec.SpotCostPerHour > 0 {
+		return spec.SpotCostPerHour
+	}
+	return spec.CostPerHour
+}
+
+// RecommendVmSpotCost func prioritize specs based on spot price (on-demand price is used if the spot price is unknown)
+func RecommendVmSpotCost(nsId string, specList *[]model.TbSpecInfo) ([]model.TbSpecInfo, error) {
+
+	result := append([]model.TbSpecInfo{}, (*specList)...)
+
+	sort.Slice(result, func(i, j int) bool { return effectiveSpotCost(result[i]) < effectiveSpotCost(result[j]) })
+
+	Max := effectiveSpotCost(result[len(result)-1])
+	Min := effectiveSpotCost(result[0])
+
+	for i := range result {
+		result[i].OrderInFilteredResult = uint16(i + 1)
+		result[i].EvaluationScore09 = float32((Max - effectiveSpotCost(result[i])) / (Max - Min + 0.0000001)) // Add small value to avoid NaN by division
+	}
+
+	return result, nil
+}
+
 // RecommendVmPerformance func prioritize specs based on given Performance condition
 func RecommendVmPerformance(nsId string, specList *[]model.TbSpecInfo) ([]model.TbSpecInfo, error) {
 
@@ -0,0 +1,571 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	recoveryDefaultCheckIntervalSec = 60
+	recoveryMinCheckIntervalSec     = 10
+	recoveryDefaultMaxRestarts      = 3
+	// recoveryEventHistorySize is the number of recovery events kept in a VM object
+	recoveryEventHistorySize = 20
+)
+
+var (
+	// recoveryPolicyLock serializes read-modify-write of recovery policies (API and controller)
+	recoveryPolicyLock sync.Mutex
+	// recoveryInProgress keeps the policies being checked to avoid overlapping checks
+	recoveryInProgress sync.Map
+)
+
+// validateRecoveryAction checks the action of a recovery policy
+func validateRecoveryAction(action string) error {
+	switch action {
+	case model.RecoveryActionReboot, model.RecoveryActionRecreateFromSpec, model.RecoveryActionRecreateFromSnapshot:
+		return nil
+	}
+	return fmt.Errorf("not supported recovery action '%s' (reboot, recreateFromSpec, recreateFromLatestSnapshot)", action)
+}
+
+// getRecoveryPolicy returns the recovery policy of the MCI (false if not exists)
+func getRecoveryPolicy(nsId string, mciId string) (model.RecoveryPolicyInfo, bool, error) {
+	policy := model.RecoveryPolicyInfo{}
+	keyValue, err := kvstore.GetKv(common.GenRecoveryPolicyKey(nsId, mciId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return policy, false, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return policy, false, nil
+	}
+	err = json.Unmarshal([]byte(keyValue.Value), &policy)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return policy, false, err
+	}
+	return policy, true, nil
+}
+
+// putRecoveryPolicy stores the recovery policy
+func putRecoveryPolicy(nsId string, policy model.RecoveryPolicyInfo) error {
+	val, _ := json.Marshal(policy)
+	err := kvstore.Put(common.GenRecoveryPolicyKey(nsId, policy.MciId), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	return nil
+}
+
+// updateRecoveryPolicyState applies the change to the stored recovery policy (skipped if the policy is deleted)
+func updateRecoveryPolicyState(nsId string, mciId string, change func(policy *model.RecoveryPolicyInfo)) {
+	recoveryPolicyLock.Lock()
+	defer recoveryPolicyLock.Unlock()
+
+	policy, exists, err := getRecoveryPolicy(nsId, mciId)
+	if err != nil || !exists {
+		return
+	}
+	change(&policy)
+	putRecoveryPolicy(nsId, policy)
+}
+
+// CreateRecoveryPolicy creates the VM auto-recovery policy of the MCI
+func CreateRecoveryPolicy(nsId string, mciId string, req *model.RecoveryPolicyReq) (model.RecoveryPolicyInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RecoveryPolicyInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RecoveryPolicyInfo{}, err
+	}
+	check, _ := CheckMci(nsId, mciId)
+	if !check {
+		return model.RecoveryPolicyInfo{}, fmt.Errorf("the MCI %s does not exist", mciId)
+	}
+	if req.SubGroupId != "" {
+		subGroupIds, err := ListSubGroupId(nsId, mciId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.RecoveryPolicyInfo{}, err
+		}
+		found := false
+		for _, subGroupId := range subGroupIds {
+			if subGroupId == req.SubGroupId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return model.RecoveryPolicyInfo{}, fmt.Errorf("the subGroup %s does not exist in the MCI %s", req.SubGroupId, mciId)
+		}
+	}
+	if err := validateRecoveryAction(req.Action); err != nil {
+		return model.RecoveryPolicyInfo{}, err
+	}
+
+	policy := model.RecoveryPolicyInfo{
+		MciId:            mciId,
+		SubGroupId:       req.SubGroupId,
+		CheckIntervalSec: req.CheckIntervalSec,
+		MaxRestarts:      req.MaxRestarts,
+		Action:           req.Action,
+		Status:           model.RecoveryPolicyActive,
+		Description:      req.Description,
+		CreatedAt:        time.Now(),
+		Restarts:         map[string]int{},
+	}
+	if policy.CheckIntervalSec <= 0 {
+		policy.CheckIntervalSec = recoveryDefaultCheckIntervalSec
+	}
+	if policy.CheckIntervalSec < recoveryMinCheckIntervalSec {
+		return model.RecoveryPolicyInfo{}, fmt.Errorf("checkIntervalSec must be at least %d", recoveryMinCheckIntervalSec)
+	}
+	if policy.MaxRestarts <= 0 {
+		policy.MaxRestarts = recoveryDefaultMaxRestarts
+	}
+
+	recoveryPolicyLock.Lock()
+	defer recoveryPolicyLock.Unlock()
+
+	_, exists, err := getRecoveryPolicy(nsId, mciId)
+	if err != nil {
+		return model.RecoveryPolicyInfo{}, err
+	}
+	if exists {
+		return model.RecoveryPolicyInfo{}, fmt.Errorf("the recovery policy of the MCI %s already exists", mciId)
+	}
+	if err := putRecoveryPolicy(nsId, policy); err != nil {
+		return model.RecoveryPolicyInfo{}, err
+	}
+	return policy, nil
+}
+
+// GetRecoveryPolicy returns the VM auto-recovery policy of the MCI
+func GetRecoveryPolicy(nsId string, mciId string) (model.RecoveryPolicyInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RecoveryPolicyInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RecoveryPolicyInfo{}, err
+	}
+	policy, exists, err := getRecoveryPolicy(nsId, mciId)
+	if err != nil {
+		return model.RecoveryPolicyInfo{}, err
+	}
+	if !exists {
+		return model.RecoveryPolicyInfo{}, fmt.Errorf("the recovery policy of the MCI %s does not exist", mciId)
+	}
+	return policy, nil
+}
+
+// ListRecoveryPolicy returns the VM auto-recovery policies in the namespace
+func ListRecoveryPolicy(nsId string) ([]model.RecoveryPolicyInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+	keyValue, err := kvstore.GetKvList(common.GenRecoveryPolicyKey(nsId, ""))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+	policies := []model.RecoveryPolicyInfo{}
+	for _, kv := range keyValue {
+		policy := model.RecoveryPolicyInfo{}
+		if err := json.Unmarshal([]byte(kv.Value), &policy); err != nil {
+			log.Warn().Err(err).Msgf("Skip the invalid recovery policy %s", kv.Key)
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].MciId < policies[j].MciId })
+	return policies, nil
+}
+
+// UpdateRecoveryPolicy updates (pauses or resumes) the VM auto-recovery policy of the MCI
+func UpdateRecoveryPolicy(nsId string, mciId string, req *model.RecoveryPolicyUpdateReq) (model.RecoveryPolicyInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RecoveryPolicyInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.RecoveryPolicyInfo{}, err
+	}
+	if req.Action != "" {
+		if err := validateRecoveryAction(req.Action); err != nil {
+			return model.RecoveryPolicyInfo{}, err
+		}
+	}
+	if req.CheckIntervalSec != 0 && req.CheckIntervalSec < recoveryMinCheckIntervalSec {
+		return model.RecoveryPolicyInfo{}, fmt.Errorf("checkIntervalSec must be at least %d", recoveryMinCheckIntervalSec)
+	}
+	if req.MaxRestarts < 0 {
+		return model.RecoveryPolicyInfo{}, fmt.Errorf("maxRestarts must not be negative")
+	}
+
+	recoveryPolicyLock.Lock()
+	defer recoveryPolicyLock.Unlock()
+
+	policy, exists, err := getRecoveryPolicy(nsId, mciId)
+	if err != nil {
+		return model.RecoveryPolicyInfo{}, err
+	}
+	if !exists {
+		return model.RecoveryPolicyInfo{}, fmt.Errorf("the recovery policy of the MCI %s does not exist", mciId)
+	}
+	if req.Paused != nil {
+		if *req.Paused {
+			policy.Status = model.RecoveryPolicyPaused
+		} else {
+			policy.Status = model.RecoveryPolicyActive
+		}
+	}
+	if req.CheckIntervalSec != 0 {
+		policy.CheckIntervalSec = req.CheckIntervalSec
+	}
+	if req.MaxRestarts != 0 {
+		policy.MaxRestarts = req.MaxRestarts
+	}
+	if req.Action != "" {
+		policy.Action = req.Action
+	}
+	if req.ResetRestarts {
+		policy.Restarts = map[string]int{}
+		policy.BackedOff = nil
+	}
+	if err := putRecoveryPolicy(nsId, policy); err != nil {
+		return model.RecoveryPolicyInfo{}, err
+	}
+	return policy, nil
+}
+
+// DelRecoveryPolicy deletes the VM auto-recovery policy of the MCI
+func DelRecoveryPolicy(nsId string, mciId string) error {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	recoveryPolicyLock.Lock()
+	defer recoveryPolicyLock.Unlock()
+
+	_, exists, err := getRecoveryPolicy(nsId, mciId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("the recovery policy of the MCI %s does not exist", mciId)
+	}
+	err = kvstore.Delete(common.GenRecoveryPolicyKey(nsId, mciId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	return nil
+}
+
+// delRecoveryPolicyOfMci deletes the recovery policy of the deleted MCI (if exists)
+func delRecoveryPolicyOfMci(nsId string, mciId string) {
+	recoveryPolicyLock.Lock()
+	defer recoveryPolicyLock.Unlock()
+
+	err := kvstore.Delete(common.GenRecoveryPolicyKey(nsId, mciId))
+	if err != nil && !strings.Contains(err.Error(), model.ErrStrKeyNotFound) {
+		log.Error().Err(err).Msg("")
+	}
+}
+
+// StartRecoveryController runs RecoveryController periodically
+func StartRecoveryController(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			RecoveryController()
+		}
+	}()
+}
+
+// RecoveryController starts the checks of the recovery policies which are due.
+// Each policy is checked in its own goroutine and a check is skipped while the previous one is in progress.
+func RecoveryController() {
+	nsList, err := common.ListNsId()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	for _, nsId := range nsList {
+		policies, err := ListRecoveryPolicy(nsId)
+		if err != nil {
+			continue
+		}
+		for _, policy := range policies {
+			if policy.Status == model.RecoveryPolicyPaused {
+				continue
+			}
+			if time.Since(policy.LastCheckedAt) < time.Duration(policy.CheckIntervalSec)*time.Second {
+				continue
+			}
+			key := common.GenRecoveryPolicyKey(nsId, policy.MciId)
+			if _, running := recoveryInProgress.LoadOrStore(key, true); running {
+				continue
+			}
+			go func(nsId string, policy model.RecoveryPolicyInfo) {
+				defer recoveryInProgress.Delete(key)
+				checkRecoveryPolicy(nsId, policy)
+			}(nsId, policy)
+		}
+	}
+}
+
+// isRecoveryTarget returns true if the VM failed or was terminated outside CB-Tumblebug
+func isRecoveryTarget(status model.TbVmStatusInfo) bool {
+	switch status.Status {
+	case model.StatusFailed:
+		return status.TargetAction != model.ActionTerminate && status.TargetAction != model.ActionCreate
+	case model.StatusTerminated:
+		// TargetStatus is kept as Terminated if the VM was terminated by CB-Tumblebug
+		return status.TargetStatus != model.StatusTerminated
+	}
+	return false
+}
+
+// checkRecoveryPolicy checks the VMs of the policy and executes the recovery action on failed VMs
+func checkRecoveryPolicy(nsId string, policy model.RecoveryPolicyInfo) {
+	mciId := policy.MciId
+	check, _ := CheckMci(nsId, mciId)
+	if !check {
+		log.Info().Msgf("Delete the recovery policy of the MCI %s (MCI not found)", mciId)
+		delRecoveryPolicyOfMci(nsId, mciId)
+		return
+	}
+	updateRecoveryPolicyState(nsId, mciId, func(p *model.RecoveryPolicyInfo) {
+		p.LastCheckedAt = time.Now()
+	})
+
+	var vmIds []string
+	var err error
+	if policy.SubGroupId != "" {
+		vmIds, err = ListVmBySubGroup(nsId, mciId, policy.SubGroupId)
+	} else {
+		vmIds, err = ListVmId(nsId, mciId)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+
+	backedOff := map[string]bool{}
+	for _, vmId := range policy.BackedOff {
+		backedOff[vmId] = true
+	}
+
+	for _, vmId := range vmIds {
+		if backedOff[vmId] {
+			continue
+		}
+		status, err := FetchVmStatus(nsId, mciId, vmId)
+		if err != nil || !isRecoveryTarget(status) {
+			continue
+		}
+		vmKey := common.GenMciKey(nsId, mciId, vmId)
+
+		restarts := policy.Restarts[vmId]
+		if restarts >= policy.MaxRestarts {
+			log.Warn().Msgf("Recovery of the VM %s is backed off (restarts: %d)", vmId, restarts)
+			updateRecoveryPolicyState(nsId, mciId, func(p *model.RecoveryPolicyInfo) {
+				p.BackedOff = append(p.BackedOff, vmId)
+			})
+			common.EmitEvent(model.EventVmRecoveryBackedOff, nsId, vmKey, map[string]interface{}{
+				"mciId": mciId, "vmId": vmId, "status": status.Status, "restarts": restarts, "maxRestarts": policy.MaxRestarts,
+			})
+			recordMciNsEvent(nsId, mciId, vmId, model.NsEventPolicyAction,
+				fmt.Sprintf("recovery is backed off (status: %s, restarts: %d/%d)", status.Status, restarts, policy.MaxRestarts))
+			continue
+		}
+
+		log.Info().Msgf("Recover the VM %s (status: %s, action: %s)", vmId, status.Status, policy.Action)
+		recoveredVmId, event := executeRecoveryAction(nsId, mciId, vmId, policy.Action, status.Status)
+
+		updateRecoveryPolicyState(nsId, mciId, func(p *model.RecoveryPolicyInfo) {
+			if p.Restarts == nil {
+				p.Restarts = map[string]int{}
+			}
+			if recoveredVmId != vmId {
+				delete(p.Restarts, vmId)
+			}
+			p.Restarts[recoveredVmId] = restarts + 1
+		})
+		recordVmRecoveryEvent(nsId, mciId, recoveredVmId, event)
+		common.EmitEvent(model.EventVmRecoveryExecuted, nsId, common.GenMciKey(nsId, mciId, recoveredVmId), map[string]interface{}{
+			"mciId": mciId, "vmId": recoveredVmId, "event": event, "restarts": restarts + 1,
+		})
+		recordMciNsEvent(nsId, mciId, recoveredVmId, model.NsEventPolicyAction,
+			fmt.Sprintf("recovery %s of %s (%s): %s %s", event.Action, vmId, event.Reason, event.Result, event.Message))
+	}
+}
+
+// executeRecoveryAction executes the recovery action on the VM.
+// It returns the ID of the recovered VM (the replacement VM for recreate actions) and the recovery event.
+func executeRecoveryAction(nsId string, mciId string, vmId string, action string, reason string) (string, model.VmRecoveryEvent) {
+	event := model.VmRecoveryEvent{Time: time.Now(), Action: action, Reason: reason, Result: "Failed"}
+
+	if action == model.RecoveryActionReboot {
+		_, err := HandleMciVmAction(nsId, mciId, vmId, model.ActionReboot, true)
+		if err != nil {
+			event.Message = err.Error()
+			return vmId, event
+		}
+		event.Result = "Succeeded"
+		return vmId, event
+	}
+
+	vmObj, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		event.Message = err.Error()
+		return vmId, event
+	}
+	vmTemplate := getVmReqTemplate(vmObj)
+	vmTemplate.SubGroupSize = "1"
+	if action == model.RecoveryActionRecreateFromSnapshot {
+		imageId, err := getLatestSnapshotOfVm(nsId, vmObj)
+		if err != nil || imageId == "" {
+			event.Message = "no available snapshot of the VM; recreated from the spec"
+		} else {
+			vmTemplate.ImageId = imageId
+			event.Message = "recreated from the snapshot " + imageId
+		}
+	}
+
+	vmIdsBefore, err := ListVmBySubGroup(nsId, mciId, vmObj.SubGroupId)
+	if err != nil {
+		event.Message = err.Error()
+		return vmId, event
+	}
+	_, err = CreateMciGroupVm(nsId, mciId, vmTemplate, true)
+	if err != nil {
+		event.Message = "failed to create the replacement VM: " + err.Error()
+		return vmId, event
+	}
+	vmIdsAfter, err := ListVmBySubGroup(nsId, mciId, vmObj.SubGroupId)
+	if err != nil {
+		event.Message = err.Error()
+		return vmId, event
+	}
+	newVmId := ""
+	for _, id := range vmIdsAfter {
+		if !common.CheckElement(id, vmIdsBefore) {
+			newVmId = id
+			break
+		}
+	}
+	if newVmId == "" {
+		event.Message = "the replacement VM is not found"
+		return vmId, event
+	}
+
+	// keep the recovery history in the replacement VM
+	if newVmObj, err := GetVmObject(nsId, mciId, newVmId); err == nil {
+		newVmObj.RecoveryEvents = vmObj.RecoveryEvents
+		UpdateVmInfo(nsId, mciId, newVmObj)
+	}
+
+	// remove the failed VM (force if it cannot be terminated via CSP)
+	if err := DelMciVm(nsId, mciId, vmId, ""); err != nil {
+		if err := DelMciVm(nsId, mciId, vmId, "force"); err != nil {
+			log.Warn().Err(err).Msgf("Failed to delete the VM %s replaced by %s", vmId, newVmId)
+		}
+	}
+
+	event.Result = "Succeeded"
+	event.ReplacedVmId = vmId
+	return newVmId, event
+}
+
+// getLatestSnapshotOfVm returns the latest available custom image (snapshot) of the VM or the VMs replaced by it
+func getLatestSnapshotOfVm(nsId string, vmObj model.TbVmInfo) (string, error) {
+	sourceVmIds := []string{vmObj.Id}
+	for _, event := range vmObj.RecoveryEvents {
+		if event.ReplacedVmId != "" {
+			sourceVmIds = append(sourceVmIds, event.ReplacedVmId)
+		}
+	}
+
+	resourceList, err := resource.ListResource(nsId, model.StrCustomImage, "", "")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return "", err
+	}
+	customImages, _ := resourceList.([]model.TbCustomImageInfo)
+	latest := model.TbCustomImageInfo{}
+	for _, image := range customImages {
+		if image.Status != model.MyImageAvailable || image.ConnectionName != vmObj.ConnectionName {
+			continue
+		}
+		if !common.CheckElement(image.SourceVmId, sourceVmIds) {
+			continue
+		}
+		if latest.Id == "" || image.CreationDate.After(latest.CreationDate) {
+			latest = image
+		}
+	}
+	return latest.Id, nil
+}
+
+// recordVmRecoveryEvent appends the recovery event to the VM object
+func recordVmRecoveryEvent(nsId string, mciId string, vmId string, event model.VmRecoveryEvent) {
+	vmObj, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	vmObj.RecoveryEvents = append(vmObj.RecoveryEvents, event)
+	if len(vmObj.RecoveryEvents) > recoveryEventHistorySize {
+		vmObj.RecoveryEvents = vmObj.RecoveryEvents[len(vmObj.RecoveryEvents)-recoveryEventHistorySize:]
+	}
+	UpdateVmInfo(nsId, mciId, vmObj)
+}
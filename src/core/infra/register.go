@@ -0,0 +1,393 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// registeredSystemLabel is the system label of objects registered from CSP resources
+const registeredSystemLabel = "Registered from CSP resource"
+
+// cannotRetrieve is the placeholder of the fields which cannot be retrieved from the CSP on registration
+const cannotRetrieve = "cannot retrieve"
+
+// errSshKeyNotProvided is returned by probeSshReachability if the SSH key of the VM has no usable private key
+var errSshKeyNotProvided = errors.New("no usable private key is provided for the VM")
+
+// registeredResourceName returns the name of a CSP resource registered to CB-TB (connectionName-cspResourceId)
+func registeredResourceName(connectionName string, cspResourceId string) string {
+	return common.ChangeIdString(connectionName + "-" + cspResourceId)
+}
+
+// matchIID returns true if the CSP resource ID or name of a CB-TB object is the IID given by CB-Spider
+func matchIID(cspResourceId string, cspResourceName string, iid model.IID) bool {
+	return (iid.SystemId != "" && cspResourceId == iid.SystemId) || (iid.NameId != "" && cspResourceName == iid.NameId)
+}
+
+// findRegisteredVNet returns the vNet of the connection in the namespace for the IID given by CB-Spider
+func findRegisteredVNet(nsId string, connectionName string, iid model.IID) (model.TbVNetInfo, bool, error) {
+	resourceList, err := resource.ListResource(nsId, model.StrVNet, "connectionName", connectionName)
+	if err != nil {
+		return model.TbVNetInfo{}, false, err
+	}
+	vNets, _ := resourceList.([]model.TbVNetInfo)
+	for _, v := range vNets {
+		if v.ConnectionName == connectionName && matchIID(v.CspResourceId, v.CspResourceName, iid) {
+			return v, true, nil
+		}
+	}
+	return model.TbVNetInfo{}, false, nil
+}
+
+// findRegisteredSecurityGroup returns the security group of the connection in the namespace for the IID given by CB-Spider
+func findRegisteredSecurityGroup(nsId string, connectionName string, iid model.IID) (model.TbSecurityGroupInfo, bool, error) {
+	resourceList, err := resource.ListResource(nsId, model.StrSecurityGroup, "connectionName", connectionName)
+	if err != nil {
+		return model.TbSecurityGroupInfo{}, false, err
+	}
+	securityGroups, _ := resourceList.([]model.TbSecurityGroupInfo)
+	for _, v := range securityGroups {
+		if v.ConnectionName == connectionName && matchIID(v.CspResourceId, v.CspResourceName, iid) {
+			return v, true, nil
+		}
+	}
+	return model.TbSecurityGroupInfo{}, false, nil
+}
+
+// findRegisteredSshKey returns the SSH key of the connection in the namespace for the IID given by CB-Spider
+func findRegisteredSshKey(nsId string, connectionName string, iid model.IID) (model.TbSshKeyInfo, bool, error) {
+	resourceList, err := resource.ListResource(nsId, model.StrSSHKey, "connectionName", connectionName)
+	if err != nil {
+		return model.TbSshKeyInfo{}, false, err
+	}
+	sshKeys, _ := resourceList.([]model.TbSshKeyInfo)
+	for _, v := range sshKeys {
+		if v.ConnectionName == connectionName && matchIID(v.CspResourceId, v.CspResourceName, iid) {
+			return v, true, nil
+		}
+	}
+	return model.TbSshKeyInfo{}, false, nil
+}
+
+// linkRegisteredVmResources resolves the vNet, subnet, security groups and SSH key of a VM registered from the CSP
+// into CB-TB objects. The objects already registered in the namespace are linked, and the others are registered.
+// It returns the messages of the resources which could not be linked (the VM registration continues regardless).
+func linkRegisteredVmResources(nsId string, vmKey string, spVm *model.SpiderVMInfo, vmInfo *model.TbVmInfo) []string {
+	messages := []string{}
+	connectionName := vmInfo.ConnectionName
+
+	// VMs of an MCI are registered concurrently and may share the resources
+	unlock, err := common.LockNs(nsId, "registerCspResource")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return append(messages, "cannot link the CSP resources of the VM: "+err.Error())
+	}
+	defer unlock()
+
+	// vNet and subnet
+	if spVm.VpcIID.SystemId != "" || spVm.VpcIID.NameId != "" {
+		vNet, found, err := findRegisteredVNet(nsId, connectionName, spVm.VpcIID)
+		if err == nil && !found {
+			req := model.TbRegisterVNetReq{
+				ConnectionName: connectionName,
+				CspResourceId:  spVm.VpcIID.SystemId,
+				Name:           registeredResourceName(connectionName, spVm.VpcIID.SystemId),
+				Description:    "Ref name: " + spVm.VpcIID.NameId + ". CSP managed resource (registered to CB-TB)",
+			}
+			vNet, err = resource.RegisterVNet(context.Background(), nsId, &req)
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to link the vNet (%s) of the VM", spVm.VpcIID.SystemId)
+			messages = append(messages, "vNet: "+err.Error())
+		} else {
+			vmInfo.VNetId = vNet.Id
+			vmInfo.CspVNetId = vNet.CspResourceId
+			resource.UpdateAssociatedObjectList(nsId, model.StrVNet, vNet.Id, model.StrAdd, vmKey)
+
+			subnetFound := false
+			for _, subnet := range vNet.SubnetInfoList {
+				if matchIID(subnet.CspResourceId, subnet.CspResourceName, spVm.SubnetIID) {
+					vmInfo.SubnetId = subnet.Id
+					vmInfo.CspSubnetId = subnet.CspResourceId
+					subnetFound = true
+					break
+				}
+			}
+			if !subnetFound {
+				messages = append(messages, fmt.Sprintf("subnet: %s is not found in the vNet %s", spVm.SubnetIID.SystemId, vNet.Id))
+			}
+		}
+	}
+
+	// security groups
+	if len(spVm.SecurityGroupIIds) > 0 {
+		securityGroupIds := []string{}
+		for _, iid := range spVm.SecurityGroupIIds {
+			sg, found, err := findRegisteredSecurityGroup(nsId, connectionName, iid)
+			if err == nil && !found {
+				req := model.TbSecurityGroupReq{
+					Name:           registeredResourceName(connectionName, iid.SystemId),
+					ConnectionName: connectionName,
+					VNetId:         vmInfo.VNetId,
+					CspResourceId:  iid.SystemId,
+					Description:    "Ref name: " + iid.NameId + ". CSP managed resource (registered to CB-TB)",
+				}
+				if vmInfo.VNetId == "" || vmInfo.VNetId == cannotRetrieve {
+					req.VNetId = "not defined"
+				}
+				sg, err = resource.CreateSecurityGroup(context.Background(), nsId, &req, "register")
+			}
+			if err != nil {
+				log.Error().Err(err).Msgf("failed to link the securityGroup (%s) of the VM", iid.SystemId)
+				messages = append(messages, "securityGroup: "+err.Error())
+				continue
+			}
+			securityGroupIds = append(securityGroupIds, sg.Id)
+			resource.UpdateAssociatedObjectList(nsId, model.StrSecurityGroup, sg.Id, model.StrAdd, vmKey)
+		}
+		if len(securityGroupIds) > 0 {
+			vmInfo.SecurityGroupIds = securityGroupIds
+		}
+	}
+
+	// SSH key (the key given by the request is kept if it exists in the namespace)
+	givenKeyExists := false
+	if vmInfo.SshKeyId != "" && vmInfo.SshKeyId != cannotRetrieve {
+		givenKeyExists, _ = resource.CheckResource(nsId, model.StrSSHKey, vmInfo.SshKeyId)
+	}
+	if !givenKeyExists && (spVm.KeyPairIId.SystemId != "" || spVm.KeyPairIId.NameId != "") {
+		sshKey, found, err := findRegisteredSshKey(nsId, connectionName, spVm.KeyPairIId)
+		if err == nil && !found {
+			cspKeyId := spVm.KeyPairIId.SystemId
+			if cspKeyId == "" {
+				cspKeyId = spVm.KeyPairIId.NameId
+			}
+			req := model.TbSshKeyReq{
+				Name:           registeredResourceName(connectionName, cspKeyId),
+				ConnectionName: connectionName,
+				CspResourceId:  cspKeyId,
+				Description:    "Ref name: " + spVm.KeyPairIId.NameId + ". CSP managed resource (registered to CB-TB)",
+				Fingerprint:    cannotRetrieve,
+				PrivateKey:     cannotRetrieve,
+				PublicKey:      cannotRetrieve,
+				Username:       cannotRetrieve,
+			}
+			sshKey, err = resource.CreateSshKey(context.Background(), nsId, &req, "register")
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to link the sshKey (%s) of the VM", spVm.KeyPairIId.SystemId)
+			messages = append(messages, "sshKey: "+err.Error())
+		} else {
+			vmInfo.SshKeyId = sshKey.Id
+		}
+	}
+	if vmInfo.SshKeyId != "" && vmInfo.SshKeyId != cannotRetrieve {
+		resource.UpdateAssociatedObjectList(nsId, model.StrSSHKey, vmInfo.SshKeyId, model.StrAdd, vmKey)
+	}
+
+	vmInfo.SystemLabel = registeredSystemLabel
+	return messages
+}
+
+// probeSshReachability checks the SSH login to the VM by the private key of its SSH key and returns the user name.
+// It returns errSshKeyNotProvided if the SSH key has no usable private key (e.g., a key pair registered from the CSP).
+func probeSshReachability(nsId string, vmInfo *model.TbVmInfo) (string, error) {
+	if vmInfo.SshKeyId == "" || vmInfo.SshKeyId == cannotRetrieve {
+		return "", errSshKeyNotProvided
+	}
+	res, err := resource.GetResource(nsId, model.StrSSHKey, vmInfo.SshKeyId)
+	if err != nil {
+		return "", errSshKeyNotProvided
+	}
+	sshKey, _ := res.(model.TbSshKeyInfo)
+	signer, err := ssh.ParsePrivateKey([]byte(sshKey.PrivateKey))
+	if err != nil {
+		return "", errSshKeyNotProvided
+	}
+
+	userName := vmInfo.VmUserName
+	if userName == "" && sshKey.Username != cannotRetrieve {
+		userName = sshKey.Username
+	}
+	if userName == "" {
+		userName = model.SshDefaultUserName[0]
+	}
+	host := vmInfo.PublicIP
+	if host == "" {
+		host = vmInfo.PrivateIP
+	}
+	port := vmInfo.SSHPort
+	if port == "" {
+		port = "22"
+	}
+	if host == "" {
+		return userName, fmt.Errorf("the VM has no IP address")
+	}
+
+	config := &ssh.ClientConfig{
+		User:            userName,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host, port), config)
+	if err != nil {
+		return userName, err
+	}
+	client.Close()
+	return userName, nil
+}
+
+// getCspVm returns the VM in the CSP (which is not registered to CB-Spider) by CB-Spider
+func getCspVm(connectionName string, cspResourceId string) (model.SpiderVMInfo, error) {
+	callResult := model.SpiderVMInfo{}
+	err := getSpiderResource("cspvm/"+url.PathEscape(cspResourceId), connectionName, &callResult)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+	return callResult, err
+}
+
+// matchTagFilter returns true if the tags include all the tags of the filter (an empty value matches any value)
+func matchTagFilter(tags []model.KeyValue, filter map[string]string) bool {
+	for key, value := range filter {
+		matched := false
+		for _, tag := range tags {
+			if tag.Key == key && (value == "" || tag.Value == value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportCspVms registers the CSP VMs of the connection (which are not managed by CB-TB) matching the tag filter into a new MCI.
+// The vNet, subnet, security groups and SSH key of each VM are linked or registered together.
+// With dryRun, it returns the VMs to be imported without registering them.
+func ImportCspVms(ctx context.Context, nsId string, req *model.CspVmImportReq, dryRun bool) (model.CspVmImportResult, error) {
+	result := model.CspVmImportResult{NsId: nsId, DryRun: dryRun, Candidates: []model.CspVmImportCandidate{}}
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	err = validate.Struct(req)
+	if err != nil {
+		return result, err
+	}
+	result.ConnectionName = req.ConnectionName
+	result.MciId = common.ChangeIdString(req.MciName)
+
+	if check, _ := CheckMci(nsId, result.MciId); check {
+		return result, common.NewConflictError("The mci %s already exists. CSP VMs are imported into a new MCI.", result.MciId)
+	}
+	if req.SshKeyId != "" {
+		if check, _ := resource.CheckResource(nsId, model.StrSSHKey, req.SshKeyId); !check {
+			return result, common.NewResourceNotFoundError(model.StrSSHKey, req.SshKeyId)
+		}
+	}
+	if len(req.TagFilter) > 0 {
+		if err := common.CheckSpiderFeature(model.SpiderFeatureTagList, "tag filter"); err != nil {
+			return result, err
+		}
+	}
+
+	inspected, err := InspectResources(req.ConnectionName, model.StrVM)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	for _, r := range inspected.Resources.OnCspOnly.Info {
+		candidate := model.CspVmImportCandidate{
+			CspResourceId: r.CspResourceId,
+			RefNameOrId:   r.RefNameOrId,
+			VmName:        common.ChangeIdString(req.ConnectionName + "-" + r.RefNameOrId + "-" + r.CspResourceId),
+		}
+		if len(req.TagFilter) > 0 {
+			spVm, err := getCspVm(req.ConnectionName, r.CspResourceId)
+			if err != nil {
+				log.Warn().Err(err).Msgf("skip the CSP VM %s (cannot get the tags)", r.CspResourceId)
+				continue
+			}
+			if !matchTagFilter(spVm.TagList, req.TagFilter) {
+				continue
+			}
+			candidate.Tags = spVm.TagList
+		}
+		result.Candidates = append(result.Candidates, candidate)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if len(result.Candidates) == 0 {
+		return result, common.NewValidationFailedError("no CSP VM to import in the connection %s", req.ConnectionName)
+	}
+
+	mciReq := model.TbMciReq{
+		Name:            result.MciId,
+		Description:     req.Description,
+		InstallMonAgent: "no",
+	}
+	if mciReq.Description == "" {
+		mciReq.Description = "MCI for CSP managed VMs (registered to CB-TB)"
+	}
+	sshKeyId := req.SshKeyId
+	if sshKeyId == "" {
+		sshKeyId = cannotRetrieve
+	}
+	for _, candidate := range result.Candidates {
+		vm := model.TbVmReq{
+			Name:             candidate.VmName,
+			ConnectionName:   req.ConnectionName,
+			CspResourceId:    candidate.CspResourceId,
+			Description:      "Ref name: " + candidate.RefNameOrId + ". CSP managed VM (registered to CB-TB)",
+			Label:            map[string]string{model.LabelRegistered: "true"},
+			ImageId:          cannotRetrieve,
+			SpecId:           cannotRetrieve,
+			SshKeyId:         sshKeyId,
+			SubnetId:         cannotRetrieve,
+			VNetId:           cannotRetrieve,
+			SecurityGroupIds: []string{cannotRetrieve},
+			VmUserName:       req.VmUserName,
+		}
+		mciReq.Vm = append(mciReq.Vm, vm)
+	}
+
+	mciInfo, err := CreateMci(ctx, nsId, &mciReq, "register")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	result.Mci = mciInfo
+	return result, nil
+}
@@ -95,11 +95,17 @@ func RemoteCommandToMci(nsId string, mciId string, subGroupId string, vmId strin
 		return temp, err
 	}
 
+	switch req.Shell {
+	case "", model.ShellBash, model.ShellPowershell, model.ShellCmd:
+	default:
+		return []model.SshCmdResult{}, common.NewValidationFailedError("invalid shell (%s); use one of [%s, %s, %s]", req.Shell, model.ShellBash, model.ShellPowershell, model.ShellCmd)
+	}
+
 	check, _ := CheckMci(nsId, mciId)
 
 	if !check {
 		temp := []model.SshCmdResult{}
-		err := fmt.Errorf("The mci " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return temp, err
 	}
 
@@ -147,10 +153,18 @@ func RemoteCommandToMci(nsId string, mciId string, subGroupId string, vmId strin
 	// Execute commands in parallel using goroutines
 	for vmId, commands := range vmCommands {
 		wg.Add(1)
-		go RunRemoteCommandAsync(&wg, nsId, mciId, vmId, req.UserName, commands, &resultArray)
+		go RunRemoteCommandAsync(&wg, nsId, mciId, vmId, req.UserName, req.Shell, commands, &resultArray)
 	}
 	wg.Wait() // goroutine sync wg
 
+	for _, result := range resultArray {
+		message := fmt.Sprintf("%d command(s) executed by %s", len(req.Command), result.Transport)
+		if result.Err != nil {
+			message += ": " + result.Err.Error()
+		}
+		recordMciNsEvent(nsId, mciId, result.VmId, model.NsEventCommandExecuted, message)
+	}
+
 	return resultArray, nil
 }
 
@@ -214,8 +228,9 @@ func RunRemoteCommand(nsId string, mciId string, vmId string, givenUserName stri
 
 }
 
-// RunRemoteCommandAsync is func to execute a SSH command to a VM (async call)
-func RunRemoteCommandAsync(wg *sync.WaitGroup, nsId string, mciId string, vmId string, givenUserName string, cmd []string, returnResult *[]model.SshCmdResult) {
+// RunRemoteCommandAsync is func to execute a command to a VM (async call).
+// Commands to Windows VMs are executed by WinRM or by SSH with PowerShell (see RunRemoteCommandWindows).
+func RunRemoteCommandAsync(wg *sync.WaitGroup, nsId string, mciId string, vmId string, givenUserName string, shell string, cmd []string, returnResult *[]model.SshCmdResult) {
 
 	defer wg.Done() //goroutine sync done
 
@@ -235,8 +250,21 @@ func RunRemoteCommandAsync(wg *sync.WaitGroup, nsId string, mciId string, vmId s
 		*returnResult = append(*returnResult, sshResultTmp)
 	}
 
-	// RunRemoteCommand
-	stdoutResults, stderrResults, err := RunRemoteCommand(nsId, mciId, vmId, givenUserName, cmd)
+	// RunRemoteCommand (or RunRemoteCommandWindows by the OS type of the VM)
+	var stdoutResults, stderrResults map[int]string
+	osType := model.VmOsTypeLinux
+	if vm, vmErr := GetVmObject(nsId, mciId, vmId); vmErr == nil {
+		osType = GetVmOsType(nsId, mciId, vm)
+	}
+	sshResultTmp.Shell, err = resolveShell(osType, shell)
+	if err == nil {
+		if osType == model.VmOsTypeWindows {
+			stdoutResults, stderrResults, sshResultTmp.Transport, err = RunRemoteCommandWindows(nsId, mciId, vmId, givenUserName, sshResultTmp.Shell, cmd)
+		} else {
+			sshResultTmp.Transport = model.TransportSsh
+			stdoutResults, stderrResults, err = RunRemoteCommand(nsId, mciId, vmId, givenUserName, cmd)
+		}
+	}
 
 	if err != nil {
 		sshResultTmp.Stdout = stdoutResults
@@ -308,6 +336,12 @@ func VerifySshUserName(nsId string, mciId string, vmId string, vmIp string, sshP
 		log.Error().Err(err).Msg("")
 		return "", "", err
 	}
+	// The sshKey registered without private key material (publicOnly) cannot be used for SSH
+	if privateKey == "" {
+		err := common.NewValidationFailedError("the VM (%s) uses the sshKey without private key (publicOnly); command execution is not available for the VM", vmId)
+		log.Error().Err(err).Msg("")
+		return "", "", err
+	}
 
 	theUserName := ""
 	if givenUserName != "" {
@@ -402,7 +436,13 @@ func GetVmSshKey(nsId string, mciId string, vmId string) (string, string, string
 		return "", "", "", err
 	}
 
-	return keyContent.Username, keyContent.VerifiedUsername, keyContent.PrivateKey, nil
+	privateKey, err := common.DecryptSecret(keyContent.PrivateKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return "", "", "", err
+	}
+
+	return keyContent.Username, keyContent.VerifiedUsername, privateKey, nil
 }
 
 // UpdateVmSshKey is func to update VM SShKey
@@ -592,26 +632,48 @@ func TransferFileToMci(nsId string, mciId string, subGroupId string, vmId string
 			log.Info().Msgf("Transferring file to VM: %s", vmId)
 
 			_, targetVmIP, targetSshPort, _ := GetVmIp(nsId, mciId, vmId)
-			targetUserName, targetPrivateKey, _ := VerifySshUserName(nsId, mciId, vmId, targetVmIP, targetSshPort, "")
-			// error will be handled in the next step
 
-			targetSshInfo := model.SshInfo{
-				EndPoint:   fmt.Sprintf("%s:%s", targetVmIP, targetSshPort),
-				UserName:   targetUserName,
-				PrivateKey: []byte(targetPrivateKey),
+			// Windows VMs with the password returned by the CSP use WinRM, and the others use SCP
+			osType := model.VmOsTypeLinux
+			vm, err := GetVmObject(nsId, mciId, vmId)
+			if err == nil {
+				osType = GetVmOsType(nsId, mciId, vm)
+			}
+			transport := model.TransportSsh
+			if osType == model.VmOsTypeWindows {
+				transport = windowsTransport(vm)
 			}
 
-			// Transfer file to the VM via bastion
-			err := transferFileToVmViaBastion(nsId, mciId, vmId, targetSshInfo, fileData, fileName, targetPath)
+			command := fmt.Sprintf("scp %s to %s", fileName, targetPath)
+			if err == nil && transport == model.TransportWinrm {
+				command = fmt.Sprintf("winrm copy %s to %s", fileName, targetPath)
+				err = transferFileToWindowsVm(nsId, mciId, vm, fileData, fileName, targetPath)
+			} else if err == nil {
+				var targetUserName, targetPrivateKey string
+				targetUserName, targetPrivateKey, err = VerifySshUserName(nsId, mciId, vmId, targetVmIP, targetSshPort, "")
+				// error will be handled in the next step
+
+				targetSshInfo := model.SshInfo{
+					EndPoint:   fmt.Sprintf("%s:%s", targetVmIP, targetSshPort),
+					UserName:   targetUserName,
+					PrivateKey: []byte(targetPrivateKey),
+				}
+
+				// Transfer file to the VM via bastion
+				if err == nil {
+					err = transferFileToVmViaBastion(nsId, mciId, vmId, osType, targetSshInfo, fileData, fileName, targetPath)
+				}
+			}
 
 			// Create the result for this VM
 			result := model.SshCmdResult{
-				MciId:   mciId,
-				VmId:    vmId,
-				VmIp:    targetVmIP,
-				Command: map[int]string{0: fmt.Sprintf("scp %s to %s", fileName, targetPath)},
-				Stdout:  map[int]string{},
-				Stderr:  map[int]string{},
+				MciId:     mciId,
+				VmId:      vmId,
+				VmIp:      targetVmIP,
+				Command:   map[int]string{0: command},
+				Stdout:    map[int]string{},
+				Stderr:    map[int]string{},
+				Transport: transport,
 			}
 
 			if err != nil {
@@ -635,7 +697,7 @@ func TransferFileToMci(nsId string, mciId string, subGroupId string, vmId string
 }
 
 // transferFileToVmViaBastion is a function to transfer a file to a specific VM via Bastion Host
-func transferFileToVmViaBastion(nsId string, mciId string, vmId string, targetSshInfo model.SshInfo, fileData []byte, fileName string, targetPath string) error {
+func transferFileToVmViaBastion(nsId string, mciId string, vmId string, osType string, targetSshInfo model.SshInfo, fileData []byte, fileName string, targetPath string) error {
 
 	bastionNodes, err := GetBastionNodes(nsId, mciId, vmId)
 	if err != nil || len(bastionNodes) == 0 {
@@ -659,7 +721,7 @@ func transferFileToVmViaBastion(nsId string, mciId string, vmId string, targetSs
 		PrivateKey: []byte(bastionPrivateKey),
 	}
 
-	err = runSCPWithBastion(bastionSshInfo, targetSshInfo, fileData, fileName, targetPath)
+	err = runSCPWithBastion(bastionSshInfo, targetSshInfo, osType, fileData, fileName, targetPath)
 	if err != nil {
 		return fmt.Errorf("failed to transfer file to VM via bastion: %v", err)
 	}
@@ -669,7 +731,8 @@ func transferFileToVmViaBastion(nsId string, mciId string, vmId string, targetSs
 }
 
 // runSCPWithBastion is func to send a file using SCP over SSH via a Bastion host
-func runSCPWithBastion(bastionInfo model.SshInfo, targetInfo model.SshInfo, fileData []byte, fileName string, targetPath string) error {
+// (OpenSSH on Windows runs the command by cmd.exe, so the path is double-quoted for Windows VMs)
+func runSCPWithBastion(bastionInfo model.SshInfo, targetInfo model.SshInfo, osType string, fileData []byte, fileName string, targetPath string) error {
 	log.Info().Msg("Setting up SCP connection via Bastion Host")
 
 	// Parse the private key for the bastion host
@@ -747,6 +810,10 @@ func runSCPWithBastion(bastionInfo model.SshInfo, targetInfo model.SshInfo, file
 	// Construct the SCP command and log it
 	targetFullPath := fmt.Sprintf("%s/%s", targetPath, fileName)
 	cmd := fmt.Sprintf("scp -t '%s'", targetFullPath)
+	if osType == model.VmOsTypeWindows {
+		targetFullPath = windowsFilePath(targetPath, fileName)
+		cmd = fmt.Sprintf("scp -t \"%s\"", targetFullPath)
+	}
 	log.Info().Msgf("Executing SCP command: %s", cmd)
 
 	// Run the SCP command
@@ -0,0 +1,291 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/masterzen/winrm"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// winrmDefaultUserName is the user of Windows VMs if the CSP does not return one
+const winrmDefaultUserName = "Administrator"
+
+// winrmTimeout is the timeout of a WinRM request
+const winrmTimeout = 10 * time.Minute
+
+// winrmFileChunkSize is the size of a file chunk sent by a WinRM command (as base64 in stdin, within the envelope size)
+const winrmFileChunkSize = 32 * 1024
+
+// winrmPort returns the WinRM port of Windows VMs (TB_WINRM_PORT, default 5986 for HTTPS)
+func winrmPort() int {
+	port, err := strconv.Atoi(common.NVL(os.Getenv("TB_WINRM_PORT"), "5986"))
+	if err != nil || port <= 0 {
+		port = 5986
+	}
+	return port
+}
+
+// winrmHttps returns whether WinRM uses HTTPS (TB_WINRM_HTTPS, default true).
+// The certificate is not verified since the listeners of Windows VMs use self-signed certificates.
+func winrmHttps() bool {
+	return common.NVL(os.Getenv("TB_WINRM_HTTPS"), "true") != "false"
+}
+
+// detectVmOsType returns the OS type of the VM (windows, linux) from the image metadata.
+// The image is looked up in the namespace and the system common namespace, then the image name is normalized.
+func detectVmOsType(nsId string, vm model.TbVmInfo) string {
+	for _, imageNsId := range []string{nsId, model.SystemCommonNs} {
+		image, err := resource.GetImage(imageNsId, vm.ImageId)
+		if err != nil {
+			continue
+		}
+		if image.OSDistribution == model.ImageOsWindows || strings.Contains(strings.ToLower(image.GuestOS), "windows") {
+			return model.VmOsTypeWindows
+		}
+		if image.OSDistribution != "" && image.OSDistribution != model.ImageOsUnknown {
+			return model.VmOsTypeLinux
+		}
+	}
+	distribution, _, _ := resource.NormalizeImageOs(vm.ConnectionConfig.ProviderName, vm.ImageId, vm.CspImageName)
+	if distribution == model.ImageOsWindows {
+		return model.VmOsTypeWindows
+	}
+	return model.VmOsTypeLinux
+}
+
+// GetVmOsType returns the OS type of the VM (detected and stored for the VMs created before the OS type was recorded)
+func GetVmOsType(nsId string, mciId string, vm model.TbVmInfo) string {
+	if vm.OsType != "" {
+		return vm.OsType
+	}
+	vm.OsType = detectVmOsType(nsId, vm)
+	UpdateVmInfo(nsId, mciId, vm)
+	return vm.OsType
+}
+
+// resolveShell returns the shell of the commands for the OS type (bash for Linux, powershell for Windows by default)
+func resolveShell(osType string, shell string) (string, error) {
+	switch shell {
+	case "":
+		if osType == model.VmOsTypeWindows {
+			return model.ShellPowershell, nil
+		}
+		return model.ShellBash, nil
+	case model.ShellBash:
+		if osType == model.VmOsTypeWindows {
+			return "", common.NewValidationFailedError("shell %s is not supported by Windows VMs (use %s or %s)", shell, model.ShellPowershell, model.ShellCmd)
+		}
+		return shell, nil
+	case model.ShellPowershell, model.ShellCmd:
+		if osType != model.VmOsTypeWindows {
+			return "", common.NewValidationFailedError("shell %s is not supported by Linux VMs (use %s)", shell, model.ShellBash)
+		}
+		return shell, nil
+	default:
+		return "", common.NewValidationFailedError("invalid shell (%s); use one of [%s, %s, %s]", shell, model.ShellBash, model.ShellPowershell, model.ShellCmd)
+	}
+}
+
+// wrapWindowsCommand wraps the command for the shell on Windows (cmd.exe is the default shell of both WinRM and OpenSSH)
+func wrapWindowsCommand(shell string, cmd string) string {
+	if shell == model.ShellPowershell {
+		return winrm.Powershell(cmd)
+	}
+	return "cmd.exe /c " + cmd
+}
+
+// windowsTransport returns the transport for the Windows VM.
+// WinRM is used with the Administrator password returned by the CSP, and SSH (OpenSSH on the image) with the key otherwise.
+func windowsTransport(vm model.TbVmInfo) string {
+	if vm.VmUserPassword != "" {
+		return model.TransportWinrm
+	}
+	return model.TransportSsh
+}
+
+// RunRemoteCommandWindows is func to execute commands to a Windows VM by WinRM or by SSH with PowerShell (sync call)
+func RunRemoteCommandWindows(nsId string, mciId string, vmId string, givenUserName string, shell string, cmds []string) (map[int]string, map[int]string, string, error) {
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return map[int]string{}, map[int]string{}, "", err
+	}
+
+	wrapped := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		wrapped[i] = wrapWindowsCommand(shell, cmd)
+	}
+
+	transport := windowsTransport(vm)
+	if transport == model.TransportSsh {
+		stdout, stderr, err := RunRemoteCommand(nsId, mciId, vmId, givenUserName, wrapped)
+		return stdout, stderr, transport, err
+	}
+
+	client, closeClient, err := newWinrmClient(nsId, mciId, vm, givenUserName)
+	if err != nil {
+		return map[int]string{}, map[int]string{}, transport, err
+	}
+	defer closeClient()
+
+	log.Debug().Msgf("[WinRM] %s.%s with userName: %s", mciId, vmId, winrmUserName(vm, givenUserName))
+	stdoutMap := make(map[int]string)
+	stderrMap := make(map[int]string)
+	for i, cmd := range wrapped {
+		stdout, stderr, exitCode, err := client.RunWithString(cmd, "")
+		if err != nil {
+			return stdoutMap, stderrMap, transport, err
+		}
+		stdoutMap[i] = stdout
+		stderrMap[i] = stderr
+		if exitCode != 0 {
+			stderrMap[i] = fmt.Sprintf("(exit code %d)\nStderr: %s", exitCode, stderr)
+			break
+		}
+	}
+	return stdoutMap, stderrMap, transport, nil
+}
+
+// winrmUserName returns the user for WinRM (given, returned by the CSP, or Administrator)
+func winrmUserName(vm model.TbVmInfo, givenUserName string) string {
+	if givenUserName != "" {
+		return givenUserName
+	}
+	return common.NVL(vm.VmUserName, winrmDefaultUserName)
+}
+
+// newWinrmClient returns a WinRM client to the Windows VM through its bastion node (directly if the VM is its own bastion).
+// The returned func closes the connection to the bastion.
+func newWinrmClient(nsId string, mciId string, vm model.TbVmInfo, givenUserName string) (*winrm.Client, func(), error) {
+	closeClient := func() {}
+
+	bastionNodes, err := GetBastionNodes(nsId, mciId, vm.Id)
+	if err != nil || len(bastionNodes) == 0 {
+		return nil, closeClient, fmt.Errorf("failed to get bastion nodes: %v", err)
+	}
+	bastionNode := bastionNodes[0]
+
+	host := vm.PrivateIP
+	params := *winrm.DefaultParameters
+	if bastionNode.MciId == mciId && bastionNode.VmId == vm.Id {
+		host = vm.PublicIP
+	} else {
+		bastionIp, _, bastionSshPort, err := GetVmIp(nsId, bastionNode.MciId, bastionNode.VmId)
+		if err != nil {
+			return nil, closeClient, fmt.Errorf("failed to get bastion VM IP and SSH port: %v", err)
+		}
+		bastionUserName, bastionPrivateKey, err := VerifySshUserName(nsId, bastionNode.MciId, bastionNode.VmId, bastionIp, bastionSshPort, "")
+		if err != nil {
+			return nil, closeClient, fmt.Errorf("failed to verify SSH username for bastion: %v", err)
+		}
+		bastionClient, err := dialSshBastion(model.SshInfo{
+			EndPoint:   fmt.Sprintf("%s:%s", bastionIp, bastionSshPort),
+			UserName:   bastionUserName,
+			PrivateKey: []byte(bastionPrivateKey),
+		})
+		if err != nil {
+			return nil, closeClient, err
+		}
+		closeClient = func() { bastionClient.Close() }
+		params.Dial = bastionClient.Dial
+	}
+
+	// the password is encrypted in the Key-Value store
+	password, err := common.DecryptSecret(vm.VmUserPassword)
+	if err != nil {
+		closeClient()
+		return nil, func() {}, fmt.Errorf("failed to decrypt the password of the VM user: %w", err)
+	}
+	endpoint := winrm.NewEndpoint(host, winrmPort(), winrmHttps(), true, nil, nil, nil, winrmTimeout)
+	client, err := winrm.NewClientWithParameters(endpoint, winrmUserName(vm, givenUserName), password, &params)
+	if err != nil {
+		closeClient()
+		return nil, func() {}, err
+	}
+	return client, closeClient, nil
+}
+
+// dialSshBastion opens the SSH connection to the bastion host
+func dialSshBastion(bastionInfo model.SshInfo) (*ssh.Client, error) {
+	bastionSigner, err := ssh.ParsePrivateKey(bastionInfo.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bastion private key: %v", err)
+	}
+	bastionConfig := &ssh.ClientConfig{
+		User: bastionInfo.UserName,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(bastionSigner),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	bastionClient, err := ssh.Dial("tcp", bastionInfo.EndPoint, bastionConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial bastion: %v", err)
+	}
+	return bastionClient, nil
+}
+
+// windowsFilePath joins the directory and the file name of a Windows path
+func windowsFilePath(targetPath string, fileName string) string {
+	return strings.TrimRight(strings.ReplaceAll(targetPath, "/", `\`), `\`) + `\` + fileName
+}
+
+// transferFileToWindowsVm transfers a file to the Windows VM by WinRM (in chunks written by PowerShell)
+func transferFileToWindowsVm(nsId string, mciId string, vm model.TbVmInfo, fileData []byte, fileName string, targetPath string) error {
+	client, closeClient, err := newWinrmClient(nsId, mciId, vm, "")
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+	dir := strings.TrimRight(strings.ReplaceAll(targetPath, "/", `\`), `\`)
+	fullPath := windowsFilePath(targetPath, fileName)
+
+	for offset := 0; offset == 0 || offset < len(fileData); offset += winrmFileChunkSize {
+		end := offset + winrmFileChunkSize
+		if end > len(fileData) {
+			end = len(fileData)
+		}
+		mode := "Append"
+		if offset == 0 {
+			mode = "Create"
+		}
+		script := fmt.Sprintf(
+			"New-Item -ItemType Directory -Force -Path %s | Out-Null; "+
+				"$bytes = [Convert]::FromBase64String([Console]::In.ReadToEnd().Trim()); "+
+				"$f = [IO.File]::Open(%s, '%s'); $f.Write($bytes, 0, $bytes.Length); $f.Close()",
+			quote(dir+`\`), quote(fullPath), mode)
+		_, stderr, exitCode, err := client.RunWithString(winrm.Powershell(script), base64.StdEncoding.EncodeToString(fileData[offset:end]))
+		if err != nil {
+			return fmt.Errorf("failed to transfer file by WinRM: %v", err)
+		}
+		if exitCode != 0 {
+			return fmt.Errorf("failed to write %s (exit code %d): %s", fullPath, exitCode, stderr)
+		}
+	}
+	log.Info().Msgf("File successfully transferred to %s of VM %s by WinRM", fullPath, vm.Id)
+	return nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// vmStatusPollInterval is the interval to check the status of a VM while waiting for a status
+	vmStatusPollInterval = 10 * time.Second
+	// vmStatusWaitTimeout is the max time to wait for a VM to be in a status
+	vmStatusWaitTimeout = 10 * time.Minute
+)
+
+// getSpecOfVm returns the spec from the namespace (or the system namespace if not found)
+func getSpecOfVm(nsId string, specId string) (model.TbSpecInfo, error) {
+	spec, err := resource.GetSpec(nsId, specId)
+	if err != nil {
+		spec, err = resource.GetSpec(model.SystemCommonNs, specId)
+	}
+	return spec, err
+}
+
+// waitVmStatus waits until the status of the VM (from the CSP) is the given status
+func waitVmStatus(nsId string, mciId string, vmId string, status string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		vmStatus, err := GetMciVmStatus(nsId, mciId, vmId)
+		if err == nil && vmStatus.Status == status {
+			return nil
+		}
+		if err == nil && vmStatus.Status == model.StatusFailed {
+			return fmt.Errorf("the VM %s is %s while waiting for %s", vmId, vmStatus.Status, status)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout while waiting for the VM %s to be %s", vmId, status)
+		}
+		time.Sleep(vmStatusPollInterval)
+	}
+}
+
+// checkVmResizable checks the target spec against the VM before any change is made to the VM.
+// The spec must be in the same provider, region and architecture, and must not require a different root disk type.
+func checkVmResizable(vm model.TbVmInfo, currentSpec *model.TbSpecInfo, targetSpec model.TbSpecInfo) error {
+	providerName := strings.ToLower(vm.ConnectionConfig.ProviderName)
+	regionName := vm.ConnectionConfig.RegionDetail.RegionName
+
+	if err := common.CheckCapability(providerName, common.CapabilityVmResize, "VM resize"); err != nil {
+		return err
+	}
+	if targetSpec.Unavailable {
+		return fmt.Errorf("the spec %s is no longer provided by the CSP", targetSpec.Id)
+	}
+	if !strings.EqualFold(targetSpec.ProviderName, providerName) {
+		return fmt.Errorf("the spec %s is for the provider %s (the VM is in %s)", targetSpec.Id, targetSpec.ProviderName, providerName)
+	}
+	if !strings.EqualFold(targetSpec.RegionName, regionName) {
+		return fmt.Errorf("the spec %s is for the region %s (the VM is in %s)", targetSpec.Id, targetSpec.RegionName, regionName)
+	}
+	if strings.EqualFold(targetSpec.CspSpecName, vm.CspSpecName) {
+		return fmt.Errorf("the VM %s already has the spec %s", vm.Id, targetSpec.CspSpecName)
+	}
+	if targetSpec.InfraType != "" && !strings.Contains(strings.ToLower(targetSpec.InfraType), "vm") {
+		return fmt.Errorf("the spec %s is not for VMs (infraType: %s)", targetSpec.Id, targetSpec.InfraType)
+	}
+	if currentSpec != nil && currentSpec.Architecture != "" && targetSpec.Architecture != "" &&
+		!strings.EqualFold(currentSpec.Architecture, targetSpec.Architecture) {
+		return fmt.Errorf("the spec %s is for %s (the VM is %s)", targetSpec.Id, targetSpec.Architecture, currentSpec.Architecture)
+	}
+	rootDiskType := strings.ToLower(vm.RootDiskType)
+	targetRootDiskType := strings.ToLower(targetSpec.RootDiskType)
+	if rootDiskType != "" && rootDiskType != "default" && targetRootDiskType != "" && targetRootDiskType != "default" &&
+		rootDiskType != targetRootDiskType {
+		return fmt.Errorf("the spec %s requires the root disk type %s (the VM has %s)", targetSpec.Id, targetSpec.RootDiskType, vm.RootDiskType)
+	}
+	return nil
+}
+
+// ResizeVm changes the spec (instance type) of the VM via CB-Spider.
+// A running VM is stopped (only if confirmStop is true) and restarted after the change.
+// All checks are done before the VM is stopped, and each step is recorded to the request (reqID).
+func ResizeVm(reqID string, nsId string, mciId string, vmId string, req *model.TbVmResizeReq) (model.TbVmResizeResult, error) {
+	for _, id := range []string{nsId, mciId, vmId} {
+		if err := common.CheckString(id); err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbVmResizeResult{}, err
+		}
+	}
+	if req.SpecId == "" {
+		return model.TbVmResizeResult{}, fmt.Errorf("specId is required")
+	}
+
+	result := model.TbVmResizeResult{MciId: mciId, VmId: vmId, Steps: []model.VmResizeStep{}}
+	addStep := func(title string) {
+		step := model.VmResizeStep{Title: title, Time: time.Now()}
+		result.Steps = append(result.Steps, step)
+		common.UpdateRequestProgress(reqID, common.ProgressInfo{Title: title, Time: step.Time})
+		log.Info().Msgf("[Resize VM %s] %s", vmId, title)
+	}
+
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmResizeResult{}, err
+	}
+	if vm.TargetAction != "" && vm.TargetAction != model.ActionComplete {
+		return model.TbVmResizeResult{}, fmt.Errorf("the VM %s is under %s, please try later", vmId, vm.TargetAction)
+	}
+	if vm.CspResourceName == "" {
+		return model.TbVmResizeResult{}, fmt.Errorf("the VM %s is not created in the CSP (status: %s)", vmId, vm.Status)
+	}
+
+	targetSpec, err := getSpecOfVm(nsId, req.SpecId)
+	if err != nil {
+		return model.TbVmResizeResult{}, err
+	}
+	var currentSpec *model.TbSpecInfo
+	if spec, err := getSpecOfVm(nsId, vm.SpecId); err == nil {
+		currentSpec = &spec
+	} else {
+		log.Warn().Err(err).Msgf("Failed to get the current spec %s of the VM %s", vm.SpecId, vmId)
+	}
+	if err := checkVmResizable(vm, currentSpec, targetSpec); err != nil {
+		return model.TbVmResizeResult{}, err
+	}
+
+	vmStatus, err := GetMciVmStatus(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmResizeResult{}, err
+	}
+	wasRunning := false
+	switch vmStatus.Status {
+	case model.StatusRunning:
+		if !req.ConfirmStop {
+			return model.TbVmResizeResult{}, fmt.Errorf("the VM %s is running; set confirmStop to stop (and restart) the VM for resizing", vmId)
+		}
+		wasRunning = true
+	case model.StatusSuspended:
+	default:
+		return model.TbVmResizeResult{}, fmt.Errorf("the VM %s cannot be resized in the status %s", vmId, vmStatus.Status)
+	}
+
+	result.PreviousSpecId = vm.SpecId
+	result.PreviousCostPerHour = vm.CostPerHour
+	if currentSpec != nil && result.PreviousCostPerHour == 0 {
+		result.PreviousCostPerHour = currentSpec.CostPerHour
+	}
+	addStep(fmt.Sprintf("Validated the spec %s for the VM", targetSpec.Id))
+
+	// resume the VM if the resize fails after it is stopped
+	fail := func(err error) (model.TbVmResizeResult, error) {
+		addStep("Failed: " + err.Error())
+		if wasRunning {
+			if _, resumeErr := HandleMciVmAction(nsId, mciId, vmId, model.ActionResume, true); resumeErr != nil {
+				log.Error().Err(resumeErr).Msgf("Failed to resume the VM %s after the failed resize", vmId)
+			} else {
+				addStep("Resumed the VM with the previous spec")
+			}
+		}
+		recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventResized, Result: "Failed", Message: err.Error()})
+		return result, err
+	}
+
+	if wasRunning {
+		if _, err := HandleMciVmAction(nsId, mciId, vmId, model.ActionSuspend, false); err != nil {
+			// the VM is not stopped; nothing to restore
+			addStep("Failed to stop the VM: " + err.Error())
+			return result, err
+		}
+		if err := waitVmStatus(nsId, mciId, vmId, model.StatusSuspended, vmStatusWaitTimeout); err != nil {
+			return fail(err)
+		}
+		addStep("Stopped the VM")
+	}
+
+	requestBody := model.SpiderVMResizeReqInfoWrapper{
+		ConnectionName: vm.ConnectionName,
+		ReqInfo:        model.SpiderVMResizeReqInfo{VMSpecName: targetSpec.CspSpecName},
+	}
+	callResult := model.SpiderVMInfo{}
+	err = common.ExecuteHttpRequest(
+		resty.New(),
+		"PUT",
+		fmt.Sprintf("%s/vm/%s/spec", model.SpiderRestUrl, vm.CspResourceName),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		return fail(fmt.Errorf("failed to change the spec of the VM %s: %w", vmId, err))
+	}
+	addStep(fmt.Sprintf("Changed the spec to %s", targetSpec.CspSpecName))
+
+	// update the spec reference and the cost of the VM
+	vm, err = GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return result, err
+	}
+	vm.SpecId = targetSpec.Id
+	vm.CspSpecName = targetSpec.CspSpecName
+	vm.CostPerHour = targetSpec.CostPerHour
+	UpdateVmInfo(nsId, mciId, vm)
+
+	result.SpecId = targetSpec.Id
+	result.CspSpecName = targetSpec.CspSpecName
+	result.CostPerHour = targetSpec.CostPerHour
+	result.Status = model.StatusSuspended
+
+	if wasRunning {
+		if _, err := HandleMciVmAction(nsId, mciId, vmId, model.ActionResume, true); err != nil {
+			addStep("Failed to restart the VM: " + err.Error())
+			recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventResized, Result: "Succeeded", Message: fmt.Sprintf("%s -> %s (failed to restart: %s)", result.PreviousSpecId, result.SpecId, err.Error())})
+			return result, err
+		}
+		if err := waitVmStatus(nsId, mciId, vmId, model.StatusRunning, vmStatusWaitTimeout); err != nil {
+			addStep("Failed to restart the VM: " + err.Error())
+			recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventResized, Result: "Succeeded", Message: fmt.Sprintf("%s -> %s (failed to restart: %s)", result.PreviousSpecId, result.SpecId, err.Error())})
+			return result, err
+		}
+		addStep("Restarted the VM")
+		result.Status = model.StatusRunning
+	}
+
+	recordVmEvent(nsId, mciId, vmId, model.VmEvent{Type: model.VmEventResized, Result: "Succeeded", Message: fmt.Sprintf("%s -> %s", result.PreviousSpecId, result.SpecId)})
+	return result, nil
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// retryFailedInFlight guards an MCI from overlapping retries of its failed VMs (nsId/mciId -> struct{})
+var retryFailedInFlight sync.Map
+
+// vmCreateMaxRetries returns the number of creation retries allowed per VM (TB_VM_CREATE_MAX_RETRIES, default 3)
+func vmCreateMaxRetries() int {
+	max, err := strconv.Atoi(common.NVL(os.Getenv("TB_VM_CREATE_MAX_RETRIES"), "3"))
+	if err != nil || max <= 0 {
+		max = 3
+	}
+	return max
+}
+
+// RetryFailedMciVms re-provisions the failed VMs of the MCI with their stored requests.
+// The remnant of each VM in the CSP is deleted before the retry, and a VM is not retried more than maxRetries times.
+// It is safe to call repeatedly: VMs which are not failed are not touched.
+func RetryFailedMciVms(nsId string, mciId string, req *model.MciRetryFailedReq) (model.MciRetryFailedResult, error) {
+	result := model.MciRetryFailedResult{MciId: mciId, Retried: []string{}, Recovered: []string{}, RemainFailed: []model.VmRetryFailure{}}
+
+	if err := common.CheckString(nsId); err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	if err := common.CheckString(mciId); err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	if check, _ := CheckMci(nsId, mciId); !check {
+		return result, common.NewResourceNotFoundError("mci", mciId)
+	}
+	if req.MaxRetries < 0 {
+		return result, common.NewValidationFailedError("maxRetries should not be negative")
+	}
+	maxRetries := req.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = vmCreateMaxRetries()
+	}
+
+	key := nsId + "/" + mciId
+	if _, running := retryFailedInFlight.LoadOrStore(key, struct{}{}); running {
+		return result, common.NewConflictError("failed VMs of MCI %s are being retried", mciId)
+	}
+	defer retryFailedInFlight.Delete(key)
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	sort.Strings(vmList)
+
+	failedVms := []model.TbVmInfo{}
+	for _, vmId := range vmList {
+		vm, err := GetVmObject(nsId, mciId, vmId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+		switch vm.Status {
+		case model.StatusCreating:
+			return result, common.NewConflictError("VM %s of MCI %s is being created; retry after the creation is finished", vmId, mciId)
+		case model.StatusFailed:
+			failedVms = append(failedVms, vm)
+		}
+	}
+
+	if len(failedVms) > 0 {
+		mci, err := GetMciObject(nsId, mciId)
+		if err != nil {
+			return result, err
+		}
+		mci.TargetAction = model.ActionCreate
+		mci.TargetStatus = model.StatusRunning
+		UpdateMciInfo(nsId, mci)
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	createErrs := map[string]error{}
+	for _, vm := range failedVms {
+		if vm.CreateRetries >= maxRetries {
+			result.RemainFailed = append(result.RemainFailed, model.VmRetryFailure{
+				VmId:    vm.Id,
+				Retries: vm.CreateRetries,
+				Message: fmt.Sprintf("exceeded maxRetries (%d): %s", maxRetries, vm.SystemMessage),
+			})
+			continue
+		}
+
+		if err := deleteFailedVmRemnant(vm); err != nil {
+			log.Error().Err(err).Msgf("Failed to delete the remnant of VM %s in the CSP", vm.Id)
+			result.RemainFailed = append(result.RemainFailed, model.VmRetryFailure{
+				VmId:    vm.Id,
+				Retries: vm.CreateRetries,
+				Message: "failed to delete the remnant in the CSP: " + err.Error(),
+			})
+			continue
+		}
+
+		// reset the VM to be created again (with a new name in the CSP in case the remnant is still being deleted)
+		vm.CreateRetries++
+		vm.Uid = common.GenUid()
+		vm.Status = model.StatusCreating
+		vm.TargetAction = model.ActionCreate
+		vm.TargetStatus = model.StatusRunning
+		vm.SystemMessage = ""
+		vm.CspResourceName = ""
+		vm.CspResourceId = ""
+		vm.PublicIP = ""
+		vm.PrivateIP = ""
+		vm.Events = append(vm.Events, model.VmEvent{
+			Time:    time.Now(),
+			Type:    model.VmEventCreateRetried,
+			Result:  "Started",
+			Message: fmt.Sprintf("retry %d/%d", vm.CreateRetries, maxRetries),
+		})
+		if len(vm.Events) > vmEventHistorySize {
+			vm.Events = vm.Events[len(vm.Events)-vmEventHistorySize:]
+		}
+		UpdateVmInfo(nsId, mciId, vm)
+		result.Retried = append(result.Retried, vm.Id)
+
+		// Avoid concurrent requests to CSP.
+		time.Sleep(time.Millisecond * 1000)
+
+		wg.Add(1)
+		go func(vm model.TbVmInfo) {
+			defer wg.Done()
+			// CreateVm signals its own WaitGroup, so that the error is recorded before the retry is waited
+			var vmWg sync.WaitGroup
+			vmWg.Add(1)
+			err := CreateVm(&vmWg, nsId, mciId, &vm, "create")
+			mutex.Lock()
+			createErrs[vm.Id] = err
+			mutex.Unlock()
+		}(vm)
+	}
+	wg.Wait()
+
+	for _, vmId := range result.Retried {
+		vm, err := GetVmObject(nsId, mciId, vmId)
+		if err != nil {
+			result.RemainFailed = append(result.RemainFailed, model.VmRetryFailure{VmId: vmId, Message: err.Error()})
+			continue
+		}
+		if createErrs[vmId] != nil || vm.Status == model.StatusFailed {
+			message := vm.SystemMessage
+			if createErrs[vmId] != nil {
+				message = createErrs[vmId].Error()
+			}
+			result.RemainFailed = append(result.RemainFailed, model.VmRetryFailure{VmId: vmId, Retries: vm.CreateRetries, Message: message})
+			continue
+		}
+		result.Recovered = append(result.Recovered, vmId)
+		if vm.SubGroupId != "" {
+			if err := addVmToSubGroup(nsId, mciId, vm.SubGroupId, vmId); err != nil {
+				log.Error().Err(err).Msg("")
+			}
+		}
+	}
+	log.Info().Msgf("[Retry failed VMs] MCI %s: retried %v, recovered %v, remain failed %d", mciId, result.Retried, result.Recovered, len(result.RemainFailed))
+
+	mci, err := GetMciObject(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	mciStatus, err := GetMciStatus(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	mci.Status = mciStatus.Status
+	if mci.TargetStatus == mci.Status {
+		mci.TargetStatus = model.StatusComplete
+		mci.TargetAction = model.ActionComplete
+	}
+	UpdateMciInfo(nsId, mci)
+	result.Status = mci.Status
+
+	return result, nil
+}
+
+// deleteFailedVmRemnant deletes the VM of the failed creation in the CSP via CB-Spider (nothing to delete is not an error)
+func deleteFailedVmRemnant(vm model.TbVmInfo) error {
+	cspResourceName := common.NVL(vm.CspResourceName, vm.Uid)
+	if cspResourceName == "" {
+		return nil
+	}
+
+	client := resty.New()
+	url := model.SpiderRestUrl + "/vm/" + cspResourceName
+	requestBody := model.SpiderConnectionName{ConnectionName: vm.ConnectionName}
+	callResult := model.SpiderVMInfo{}
+
+	err := common.ExecuteHttpRequest(
+		client,
+		"DELETE",
+		url,
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil && !isSpiderNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+// isSpiderNotFoundError returns true if the error from CB-Spider means the resource does not exist in the CSP
+func isSpiderNotFoundError(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, pattern := range []string{"not found", "not exist", "notfound", "status code: 404"} {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// addVmToSubGroup adds the VM to the members of the subGroup (if missing)
+func addVmToSubGroup(nsId string, mciId string, subGroupId string, vmId string) error {
+	subGroup, err := GetSubGroup(nsId, mciId, subGroupId)
+	if err != nil {
+		return err
+	}
+	for _, id := range subGroup.VmId {
+		if id == vmId {
+			return nil
+		}
+	}
+	subGroup.VmId = append(subGroup.VmId, vmId)
+	subGroup.SubGroupSize = strconv.Itoa(len(subGroup.VmId))
+	val, err := json.Marshal(subGroup)
+	if err != nil {
+		return err
+	}
+	return putMciChildObjects(nsId, mciId, []kvstore.KeyValue{{Key: common.GenMciSubGroupKey(nsId, mciId, subGroupId), Value: string(val)}})
+}
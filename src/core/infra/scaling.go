@@ -0,0 +1,331 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// scalingSample is a metric value of the subGroup aggregated across its VMs at a time
+type scalingSample struct {
+	time  time.Time
+	value float64
+}
+
+// scalingSamples keeps the samples in the window of each scaling rule (nsId/mciId/policyIndex -> []scalingSample).
+// The samples are refilled after a restart, while the cooldown is kept by the policy history.
+var scalingSamples sync.Map
+
+// scalingInFlight guards a scaling rule from being evaluated by overlapping ticks (nsId/mciId/policyIndex -> struct{})
+var scalingInFlight sync.Map
+
+// mciPolicyHistoryMax returns the number of evaluations kept in the history of an MCI policy
+// (TB_MCI_POLICY_HISTORY_MAX, default 200)
+func mciPolicyHistoryMax() int {
+	max, err := strconv.Atoi(common.NVL(os.Getenv("TB_MCI_POLICY_HISTORY_MAX"), "200"))
+	if err != nil || max <= 0 {
+		max = 200
+	}
+	return max
+}
+
+// normalizeScalingRule fills the defaults of the scaling rule
+func normalizeScalingRule(rule *model.ScalingRule) {
+	if rule.Aggregation == "" {
+		rule.Aggregation = "avg"
+	}
+	if rule.Step == 0 {
+		rule.Step = 1
+	}
+}
+
+// ValidateScalingRule returns the validation errors of the scaling rule (empty if valid)
+func ValidateScalingRule(nsId string, mciId string, rule model.ScalingRule) []string {
+	errs := []string{}
+	if rule.SubGroupId == "" {
+		errs = append(errs, "subGroupId is required")
+	} else if check, _ := CheckSubGroup(nsId, mciId, rule.SubGroupId); !check {
+		errs = append(errs, fmt.Sprintf("subGroup %s does not exist in the MCI %s", rule.SubGroupId, mciId))
+	}
+	switch rule.Metric {
+	case model.MonMetricCpu, model.MonMetricCpufreq, model.MonMetricMem, model.MonMetricNet, model.MonMetricSwap, model.MonMetricDisk, model.MonMetricDiskio:
+	default:
+		errs = append(errs, fmt.Sprintf("metric %q is not supported", rule.Metric))
+	}
+	switch rule.Aggregation {
+	case "avg", "max", "min":
+	default:
+		errs = append(errs, fmt.Sprintf("aggregation %q is not supported (avg, max, min)", rule.Aggregation))
+	}
+	switch rule.Operator {
+	case "<", "<=", ">", ">=":
+	default:
+		errs = append(errs, fmt.Sprintf("operator %q is not supported (<, <=, >, >=)", rule.Operator))
+	}
+	switch rule.Action {
+	case model.AutoActionScaleOut, model.AutoActionScaleIn:
+	default:
+		errs = append(errs, fmt.Sprintf("action %q is not supported (%s, %s)", rule.Action, model.AutoActionScaleOut, model.AutoActionScaleIn))
+	}
+	if rule.WindowSec <= 0 {
+		errs = append(errs, "windowSec should be positive")
+	}
+	if rule.Step <= 0 {
+		errs = append(errs, "step should be positive")
+	}
+	if rule.CooldownSec < 0 {
+		errs = append(errs, "cooldownSec should not be negative")
+	}
+	if rule.MinSize < 0 || rule.MaxSize < 1 || rule.MinSize > rule.MaxSize {
+		errs = append(errs, fmt.Sprintf("invalid bounds (minSize: %d, maxSize: %d); 0 <= minSize <= maxSize and maxSize >= 1", rule.MinSize, rule.MaxSize))
+	}
+	return errs
+}
+
+// aggregateValues returns the avg, max or min of the values
+func aggregateValues(aggregation string, values []float64) float64 {
+	result := values[0]
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+		switch aggregation {
+		case "max":
+			if v > result {
+				result = v
+			}
+		case "min":
+			if v < result {
+				result = v
+			}
+		}
+	}
+	if aggregation == "avg" {
+		return sum / float64(len(values))
+	}
+	return result
+}
+
+// compareThreshold returns the result of (value operator threshold)
+func compareThreshold(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
+// startScalingRuleEvaluation evaluates the scaling rule in a goroutine unless its previous evaluation is still running
+// (e.g., while the subGroup is being scaled out)
+func startScalingRuleEvaluation(nsId string, mciId string, policyIndex int, rule model.ScalingRule) {
+	key := fmt.Sprintf("%s/%s/%d", nsId, mciId, policyIndex)
+	if _, running := scalingInFlight.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer scalingInFlight.Delete(key)
+		item := evaluateScalingRule(nsId, mciId, policyIndex, rule, key)
+		if err := appendMciPolicyHistory(nsId, mciId, item); err != nil {
+			log.Error().Err(err).Msg("")
+		}
+	}()
+}
+
+// evaluateScalingRule samples the metric of the subGroup, evaluates the rule over the window and scales the subGroup
+// within the bounds. The rule is not evaluated in the cooldown after an action.
+func evaluateScalingRule(nsId string, mciId string, policyIndex int, rule model.ScalingRule, key string) model.MciPolicyHistoryItem {
+	now := time.Now().UTC()
+	item := model.MciPolicyHistoryItem{Time: now, PolicyIndex: policyIndex, SubGroupId: rule.SubGroupId}
+
+	if errs := ValidateScalingRule(nsId, mciId, rule); len(errs) > 0 {
+		item.Error = fmt.Sprintf("invalid scaling rule: %v", errs)
+		return item
+	}
+
+	history, _ := GetMciPolicyHistory(nsId, mciId)
+	for i := len(history.Items) - 1; i >= 0; i-- {
+		h := history.Items[i]
+		if h.PolicyIndex != policyIndex || h.Count == 0 {
+			continue
+		}
+		if until := h.Time.Add(time.Duration(rule.CooldownSec) * time.Second); now.Before(until) {
+			item.Message = fmt.Sprintf("in cooldown until %s after %s", until.Format(time.RFC3339), h.Action)
+			return item
+		}
+		break
+	}
+
+	vmIds, err := ListVmBySubGroup(nsId, mciId, rule.SubGroupId)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	sort.Strings(vmIds)
+	inSubGroup := map[string]bool{}
+	for _, vmId := range vmIds {
+		inSubGroup[vmId] = true
+	}
+
+	// sample the metric aggregated across the VMs of the subGroup
+	monData, err := GetMonitoringData(nsId, mciId, rule.Metric)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	values := []float64{}
+	for _, v := range monData.MciMonitoring {
+		if !inSubGroup[v.VmId] || v.Err != "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	window := time.Duration(rule.WindowSec) * time.Second
+	samples := []scalingSample{}
+	if stored, ok := scalingSamples.Load(key); ok {
+		for _, sample := range stored.([]scalingSample) {
+			if now.Sub(sample.time) <= window {
+				samples = append(samples, sample)
+			}
+		}
+	}
+	if len(values) > 0 {
+		samples = append(samples, scalingSample{time: now, value: aggregateValues(rule.Aggregation, values)})
+	}
+	scalingSamples.Store(key, samples)
+	item.Samples = len(samples)
+
+	// the condition should hold for the window, so the samples should cover it (up to an evaluation interval)
+	interval, _ := strconv.Atoi(model.AutocontrolDurationMs)
+	if len(samples) == 0 || now.Sub(samples[0].time)+time.Duration(interval)*time.Millisecond < window {
+		item.Message = "collecting samples for the window"
+		return item
+	}
+	sampleValues := make([]float64, len(samples))
+	for i, sample := range samples {
+		sampleValues[i] = sample.value
+	}
+	item.Value = aggregateValues(rule.Aggregation, sampleValues)
+	item.Detected = compareThreshold(item.Value, rule.Operator, rule.Threshold)
+	if !item.Detected {
+		return item
+	}
+
+	size := len(vmIds)
+	switch rule.Action {
+	case model.AutoActionScaleOut:
+		count := rule.Step
+		if size+count > rule.MaxSize {
+			count = rule.MaxSize - size
+		}
+		if count <= 0 {
+			item.Message = fmt.Sprintf("the subGroup is at the maxSize (%d)", rule.MaxSize)
+			return item
+		}
+		item.Action = model.AutoActionScaleOut
+		log.Info().Msgf("[Scaling] Scale out %s/%s/%s by %d (%s %s of %s: %f %s %f)", nsId, mciId, rule.SubGroupId, count, rule.Aggregation, rule.Metric, window, item.Value, rule.Operator, rule.Threshold)
+		if _, err := ScaleOutMciSubGroup(nsId, mciId, rule.SubGroupId, strconv.Itoa(count)); err != nil {
+			item.Error = err.Error()
+			return item
+		}
+		item.Count = count
+
+	case model.AutoActionScaleIn:
+		count := rule.Step
+		if size-count < rule.MinSize {
+			count = size - rule.MinSize
+		}
+		if count <= 0 {
+			item.Message = fmt.Sprintf("the subGroup is at the minSize (%d)", rule.MinSize)
+			return item
+		}
+		item.Action = model.AutoActionScaleIn
+		log.Info().Msgf("[Scaling] Scale in %s/%s/%s by %d (%s %s of %s: %f %s %f)", nsId, mciId, rule.SubGroupId, count, rule.Aggregation, rule.Metric, window, item.Value, rule.Operator, rule.Threshold)
+		// remove the latest VMs of the subGroup (protected VMs are kept)
+		for i := len(vmIds) - 1; i >= 0 && item.Count < count; i-- {
+			if err := DelMciVm(nsId, mciId, vmIds[i], ""); err != nil {
+				item.Error = err.Error()
+				continue
+			}
+			item.Count++
+		}
+	}
+
+	recordMciNsEvent(nsId, mciId, "", model.NsEventPolicyAction, fmt.Sprintf("%s subGroup %s by %d (%s %s of %s: %f %s %f)", item.Action, rule.SubGroupId, item.Count, rule.Aggregation, rule.Metric, window, item.Value, rule.Operator, rule.Threshold))
+
+	// restart the window after the action, so that the next evaluation reflects the new size
+	scalingSamples.Delete(key)
+	return item
+}
+
+// appendMciPolicyHistory appends the evaluation to the history of the MCI policy (the oldest ones are dropped)
+func appendMciPolicyHistory(nsId string, mciId string, item model.MciPolicyHistoryItem) error {
+	history, err := GetMciPolicyHistory(nsId, mciId)
+	if err != nil {
+		return err
+	}
+	history.Items = append(history.Items, item)
+	if max := mciPolicyHistoryMax(); len(history.Items) > max {
+		history.Items = history.Items[len(history.Items)-max:]
+	}
+	val, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return kvstore.Put(common.GenMciPolicyHistoryKey(nsId, mciId), string(val))
+}
+
+// GetMciPolicyHistory returns the evaluation history of the MCI policy (latest last)
+func GetMciPolicyHistory(nsId string, mciId string) (model.MciPolicyHistory, error) {
+	history := model.MciPolicyHistory{MciId: mciId, Items: []model.MciPolicyHistoryItem{}}
+	if err := common.CheckString(nsId); err != nil {
+		return history, err
+	}
+	if err := common.CheckString(mciId); err != nil {
+		return history, err
+	}
+	value, err := kvstore.Get(common.GenMciPolicyHistoryKey(nsId, mciId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return history, err
+	}
+	if value == "" {
+		return history, nil
+	}
+	if err := json.Unmarshal([]byte(value), &history); err != nil {
+		log.Error().Err(err).Msg("")
+		return history, err
+	}
+	return history, nil
+}
@@ -131,7 +131,7 @@ func CreateVmSnapshot(nsId string, mciId string, vmId string, snapshotName strin
 	// // create 'n' dataDisks
 	// for _, v := range difference_dataDisks {
 	// 	tempTbDataDiskReq := model.TbDataDiskReq{
-	// 		Name:           fmt.Sprintf("%s-%s", vm.Name, common.GenerateNewRandomString(5)),
+	// 		Name:           fmt.Sprintf("%s-%s", vm.Name, common.RandomSuffix(5)),
 	// 		ConnectionName: vm.ConnectionName,
 	// 		CspResourceId:  v.CspResourceId,
 	// 	}
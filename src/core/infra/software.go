@@ -0,0 +1,367 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/rs/zerolog/log"
+)
+
+// RuntimeSoftwareCatalog is the software catalog loaded from assets/softwarecatalog.yaml
+var RuntimeSoftwareCatalog model.SoftwareCatalog
+
+// softwareLocks serializes the install and uninstall of a software in an MCI (nsId/mciId/name -> *sync.Mutex)
+var softwareLocks sync.Map
+
+// softwareVersionPattern is the allowed format of the requested version (passed to the templates in the shell)
+var softwareVersionPattern = regexp.MustCompile(`^[0-9A-Za-z._+~-]*$`)
+
+// softwareLogTailLines is the number of lines of the command log returned for a failure
+const softwareLogTailLines = 30
+
+// softwareDefaultTimeout is the timeout of a software action per VM if the catalog does not set it
+const softwareDefaultTimeout = 15 * time.Minute
+
+// softwareScriptHeader detects the OS family (TB_OS_FAMILY), the package manager (PKG) and the architecture (ARCH)
+// of the VM for the command templates of the catalog
+const softwareScriptHeader = `#!/bin/bash
+. /etc/os-release
+case " $ID $ID_LIKE " in
+*" debian "*|*" ubuntu "*) TB_OS_FAMILY=debian; PKG=apt-get; export DEBIAN_FRONTEND=noninteractive ;;
+*" rhel "*|*" fedora "*|*" centos "*|*" amzn "*) TB_OS_FAMILY=rhel; PKG=yum; if command -v dnf >/dev/null; then PKG=dnf; fi ;;
+*) TB_OS_FAMILY=unknown; PKG= ;;
+esac
+case "$(uname -m)" in aarch64|arm64) ARCH=arm64 ;; *) ARCH=amd64 ;; esac
+`
+
+// getSoftwareSpec returns the software of the catalog by name
+func getSoftwareSpec(name string) (model.SoftwareSpec, error) {
+	spec, ok := RuntimeSoftwareCatalog.Software[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(RuntimeSoftwareCatalog.Software))
+		for n := range RuntimeSoftwareCatalog.Software {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return spec, common.NewResourceNotFoundError("software", fmt.Sprintf("%s (available: %s)", name, strings.Join(names, ", ")))
+	}
+	return spec, nil
+}
+
+// softwareCaseBranches returns the branches of a case statement on TB_OS_FAMILY with the command templates
+// (linux is the fallback for any family). orElse is the branch for the families without commands.
+func softwareCaseBranches(spec model.SoftwareSpec, command func(model.SoftwareCommands) string, orElse string) string {
+	families := make([]string, 0, len(spec.OsFamily))
+	for family := range spec.OsFamily {
+		if family != model.SoftwareOsFamilyAny {
+			families = append(families, family)
+		}
+	}
+	sort.Strings(families)
+
+	var b strings.Builder
+	b.WriteString("case \"$TB_OS_FAMILY\" in\n")
+	for _, family := range families {
+		if cmd := command(spec.OsFamily[family]); cmd != "" {
+			b.WriteString(family + ")\n" + strings.TrimRight(cmd, "\n") + "\n;;\n")
+		}
+	}
+	if cmd := command(spec.OsFamily[model.SoftwareOsFamilyAny]); cmd != "" {
+		b.WriteString("*)\n" + strings.TrimRight(cmd, "\n") + "\n;;\n")
+	} else {
+		b.WriteString("*)\n" + orElse + "\n;;\n")
+	}
+	b.WriteString("esac\n")
+	return b.String()
+}
+
+// buildSoftwareScript returns the script of the software action on a VM.
+// "bash script status" prints the OS family (TB_OS_FAMILY=) and the installed version (TB_VERSION=),
+// and "bash script run" runs the action (install or uninstall).
+func buildSoftwareScript(name string, spec model.SoftwareSpec, action string, version string) string {
+	var b strings.Builder
+	b.WriteString(softwareScriptHeader)
+	b.WriteString(fmt.Sprintf("export PKG ARCH TB_SOFTWARE_VERSION='%s'\n", version))
+
+	b.WriteString("tb_status() {\n")
+	b.WriteString(softwareCaseBranches(spec, func(c model.SoftwareCommands) string { return c.Status }, ":"))
+	b.WriteString("}\n")
+	b.WriteString("if [ \"$1\" = \"status\" ]; then\n")
+	b.WriteString("echo \"TB_OS_FAMILY=$TB_OS_FAMILY\"\n")
+	b.WriteString("echo \"TB_VERSION=$(tb_status 2>/dev/null | tail -n 1)\"\n")
+	b.WriteString("exit 0\nfi\n")
+
+	if action != model.SoftwareActionStatus {
+		b.WriteString("set -e\n")
+		b.WriteString(softwareCaseBranches(spec, func(c model.SoftwareCommands) string {
+			if action == model.SoftwareActionInstall {
+				return c.Install
+			}
+			return c.Uninstall
+		}, fmt.Sprintf("echo \"No %s command of %s for OS family $TB_OS_FAMILY ($ID $VERSION_ID)\"\nexit 3", action, name)))
+	}
+	return b.String()
+}
+
+// softwareOutput is the output of a software action on a VM
+type softwareOutput struct {
+	exitCode int
+	osFamily string
+	version  string
+	logTail  string
+}
+
+// parseSoftwareOutput parses the markers (TB_EXIT=, TB_OS_FAMILY=, TB_VERSION=) and the log tail printed after them
+func parseSoftwareOutput(out string) (softwareOutput, bool) {
+	o := softwareOutput{exitCode: -1}
+	found := false
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "TB_EXIT="):
+			if code, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "TB_EXIT="))); err == nil {
+				o.exitCode = code
+			}
+		case strings.HasPrefix(line, "TB_OS_FAMILY="):
+			o.osFamily = strings.TrimSpace(strings.TrimPrefix(line, "TB_OS_FAMILY="))
+		case strings.HasPrefix(line, "TB_VERSION="):
+			o.version = strings.TrimSpace(strings.TrimPrefix(line, "TB_VERSION="))
+			o.logTail = strings.TrimSpace(strings.Join(lines[i+1:], "\n"))
+			found = true
+			return o, found
+		}
+	}
+	return o, found
+}
+
+// HandleMciSoftwareAsync starts an async job of the software action on the MCI and returns the job immediately
+func HandleMciSoftwareAsync(nsId string, mciId string, subGroupId string, name string, action string, req *model.SoftwareInstallReq) (model.JobInfo, error) {
+	if _, err := validateSoftwareReq(nsId, mciId, name, req); err != nil {
+		return model.JobInfo{}, err
+	}
+	return common.StartJob(model.JobTypeManageSoftware, common.GenMciKey(nsId, mciId, ""), func(ctx context.Context) (interface{}, error) {
+		return HandleMciSoftware(ctx, nsId, mciId, subGroupId, name, action, req)
+	})
+}
+
+// validateSoftwareReq checks the MCI, the software and the request
+func validateSoftwareReq(nsId string, mciId string, name string, req *model.SoftwareInstallReq) (model.SoftwareSpec, error) {
+	if err := common.CheckString(nsId); err != nil {
+		return model.SoftwareSpec{}, err
+	}
+	if err := common.CheckString(mciId); err != nil {
+		return model.SoftwareSpec{}, err
+	}
+	if check, _ := CheckMci(nsId, mciId); !check {
+		return model.SoftwareSpec{}, common.NewResourceNotFoundError("mci", mciId)
+	}
+	if !softwareVersionPattern.MatchString(req.Version) {
+		return model.SoftwareSpec{}, common.NewValidationFailedError("invalid version (%s)", req.Version)
+	}
+	return getSoftwareSpec(name)
+}
+
+// HandleMciSoftware installs, uninstalls or checks (action) the software of the catalog in the VMs of the MCI
+// (or the VMs of the subGroup) in parallel and tracks the installed version in the VM objects.
+// Installs and uninstalls of the same software in the same MCI are serialized.
+func HandleMciSoftware(ctx context.Context, nsId string, mciId string, subGroupId string, name string, action string, req *model.SoftwareInstallReq) (model.SoftwareActionResult, error) {
+	name = strings.ToLower(name)
+	result := model.SoftwareActionResult{MciId: mciId, Software: name, Action: action, Results: []model.SoftwareVmResult{}}
+
+	switch action {
+	case model.SoftwareActionInstall, model.SoftwareActionUninstall, model.SoftwareActionStatus:
+	default:
+		return result, common.NewValidationFailedError("invalid software action (%s)", action)
+	}
+	spec, err := validateSoftwareReq(nsId, mciId, name, req)
+	if err != nil {
+		return result, err
+	}
+	if action != model.SoftwareActionInstall {
+		req.Version = ""
+	}
+
+	vmList, err := ListVmId(nsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	if subGroupId != "" {
+		vmList, err = ListVmBySubGroup(nsId, mciId, subGroupId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return result, err
+		}
+	}
+
+	if action != model.SoftwareActionStatus {
+		lock, _ := softwareLocks.LoadOrStore(nsId+"/"+mciId+"/"+name, &sync.Mutex{})
+		common.UpdateJobProgress(ctx, fmt.Sprintf("waiting for the other %s actions on MCI %s", name, mciId))
+		lock.(*sync.Mutex).Lock()
+		defer lock.(*sync.Mutex).Unlock()
+	}
+
+	timeout := softwareDefaultTimeout
+	if spec.TimeoutMinutes > 0 {
+		timeout = time.Duration(spec.TimeoutMinutes) * time.Minute
+	}
+	script := buildSoftwareScript(name, spec, action, req.Version)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	finished := 0
+	for _, vmId := range vmList {
+		wg.Add(1)
+		go func(vmId string) {
+			defer wg.Done()
+			vmResult := handleVmSoftware(ctx, nsId, mciId, vmId, name, spec, action, script, req.UserName, timeout)
+			if action != model.SoftwareActionStatus {
+				recordMciNsEvent(nsId, mciId, vmId, model.NsEventCommandExecuted, fmt.Sprintf("software %s %s: %s %s", name, action, vmResult.Status, vmResult.Version))
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.Results = append(result.Results, vmResult)
+			finished++
+			common.UpdateJobProgress(ctx, fmt.Sprintf("%d/%d VMs finished", finished, len(vmList)))
+		}(vmId)
+	}
+	wg.Wait()
+
+	sort.Slice(result.Results, func(i, j int) bool {
+		return result.Results[i].VmId < result.Results[j].VmId
+	})
+	return result, ctx.Err()
+}
+
+// handleVmSoftware runs the software action on the VM and stores the installed version in the VM object
+func handleVmSoftware(ctx context.Context, nsId string, mciId string, vmId string, name string, spec model.SoftwareSpec, action string, script string, userName string, timeout time.Duration) model.SoftwareVmResult {
+	result := model.SoftwareVmResult{VmId: vmId}
+
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		result.Status = model.SoftwareFailed
+		result.Message = err.Error()
+		return result
+	}
+	if vm.Status != model.StatusRunning {
+		result.Status = model.SoftwareSkipped
+		result.Message = fmt.Sprintf("the VM is not running (%s)", vm.Status)
+		return result
+	}
+	if GetVmOsType(nsId, mciId, vm) == model.VmOsTypeWindows {
+		result.Status = model.SoftwareSkipped
+		result.Message = "Windows VMs are not supported by the software catalog"
+		return result
+	}
+	if ctx.Err() != nil {
+		result.Status = model.SoftwareFailed
+		result.Message = ctx.Err().Error()
+		return result
+	}
+
+	// the script is written to a unique file since the other actions may run on the VM at the same time
+	cmd := fmt.Sprintf("TB_SCRIPT=/tmp/tb-software-%s-$$.sh; cat > $TB_SCRIPT <<'TB_SOFTWARE_EOF'\n%sTB_SOFTWARE_EOF\n", name, script)
+	if action != model.SoftwareActionStatus {
+		logFile := fmt.Sprintf("/tmp/tb-software-%s.log", name)
+		cmd += fmt.Sprintf("sudo timeout %d bash $TB_SCRIPT run > %s 2>&1; echo \"TB_EXIT=$?\"; sudo bash $TB_SCRIPT status; tail -n %d %s; ",
+			int(timeout.Seconds()), logFile, softwareLogTailLines, logFile)
+	} else {
+		cmd += "echo TB_EXIT=0; sudo bash $TB_SCRIPT status; "
+	}
+	cmd += "rm -f $TB_SCRIPT"
+
+	log.Info().Msgf("[Software] %s %s on %s/%s", action, name, mciId, vmId)
+	stdout, stderr, err := runRemoteCommandWithTimeout(nsId, mciId, vmId, userName, []string{cmd}, timeout+2*time.Minute)
+	if err != nil {
+		result.Status = model.SoftwareFailed
+		result.Message = "failed to run the commands: " + err.Error()
+		return result
+	}
+	out, ok := parseSoftwareOutput(stdout[0])
+	if !ok {
+		result.Status = model.SoftwareFailed
+		result.Message = "failed to run the commands: " + strings.TrimSpace(stderr[0])
+		return result
+	}
+	result.OsFamily = out.osFamily
+	result.Version = out.version
+
+	_, familySupported := spec.OsFamily[out.osFamily]
+	_, anySupported := spec.OsFamily[model.SoftwareOsFamilyAny]
+	switch {
+	case !familySupported && !anySupported:
+		result.Status = model.SoftwareSkipped
+		result.Message = fmt.Sprintf("%s does not support the OS family (%s)", name, out.osFamily)
+	case out.exitCode == 124:
+		result.Status = model.SoftwareFailed
+		result.Message = fmt.Sprintf("the %s is not finished in %s", action, timeout)
+		result.LogTail = out.logTail
+	case out.exitCode != 0:
+		result.Status = model.SoftwareFailed
+		result.Message = fmt.Sprintf("the %s failed (exit code %d)", action, out.exitCode)
+		result.LogTail = out.logTail
+	case action == model.SoftwareActionInstall && out.version == "":
+		result.Status = model.SoftwareFailed
+		result.Message = "the status command prints no version after the install"
+		result.LogTail = out.logTail
+	case action == model.SoftwareActionUninstall && out.version != "":
+		result.Status = model.SoftwareFailed
+		result.Message = "the status command still prints the version after the uninstall"
+		result.LogTail = out.logTail
+	case out.version != "":
+		result.Status = model.SoftwareInstalled
+	default:
+		result.Status = model.SoftwareNotInstalled
+	}
+	if result.Status == model.SoftwareFailed {
+		log.Warn().Msgf("[Software] %s %s on %s/%s: %s", action, name, mciId, vmId, result.Message)
+	}
+
+	// track the version observed by the status command regardless of the result of the action
+	storeVmSoftwareInfo(nsId, mciId, vmId, name, out.version)
+	return result
+}
+
+// storeVmSoftwareInfo stores the installed version of the software in the VM object (removes it if not installed)
+func storeVmSoftwareInfo(nsId string, mciId string, vmId string, name string, version string) {
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	if version == "" {
+		if _, ok := vm.Software[name]; !ok {
+			return
+		}
+		delete(vm.Software, name)
+	} else {
+		if vm.Software == nil {
+			vm.Software = map[string]model.VmSoftwareInfo{}
+		}
+		vm.Software[name] = model.VmSoftwareInfo{Version: version, UpdatedTime: time.Now()}
+	}
+	UpdateVmInfo(nsId, mciId, vm)
+}
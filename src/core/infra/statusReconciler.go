@@ -0,0 +1,249 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/rs/zerolog/log"
+)
+
+// statusReconcileMaxBackoff is the max multiplier of the interval while CB-Spider errors spike
+const statusReconcileMaxBackoff = 8
+
+// statusCacheStaleRounds is the number of intervals after which a cached VM status is stale
+const statusCacheStaleRounds = 3
+
+var (
+	statusReconcilerLock      sync.Mutex
+	statusReconcilerStarted   time.Time
+	statusReconcilerLastRound time.Time
+	statusReconcilerBackoff   = 1
+)
+
+// StatusReconcileInterval returns the interval of the VM status reconciler (TB_STATUS_RECONCILE_INTERVAL_SEC, default 60).
+// 0 disables the reconciler and the status of MCIs is always fetched from the CSPs.
+func StatusReconcileInterval() time.Duration {
+	sec, err := strconv.Atoi(common.NVL(os.Getenv("TB_STATUS_RECONCILE_INTERVAL_SEC"), "60"))
+	if err != nil || sec < 0 {
+		sec = 60
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// statusReconcileBatchSize returns the number of concurrent status calls per provider (TB_STATUS_RECONCILE_BATCH, default 5)
+func statusReconcileBatchSize() int {
+	size, err := strconv.Atoi(common.NVL(os.Getenv("TB_STATUS_RECONCILE_BATCH"), "5"))
+	if err != nil || size < 1 {
+		size = 5
+	}
+	return size
+}
+
+// getCachedVmStatus returns the VM status stored by the last fetch from the CSP.
+// It returns false if the status is stale or the VM is in a control action (the status is expected to change soon).
+func getCachedVmStatus(nsId string, mciId string, vmId string) (model.TbVmStatusInfo, bool) {
+	interval := StatusReconcileInterval()
+	if interval <= 0 {
+		return model.TbVmStatusInfo{}, false
+	}
+	vm, err := GetVmObject(nsId, mciId, vmId)
+	if err != nil {
+		return model.TbVmStatusInfo{}, false
+	}
+	if vm.Status != model.StatusTerminated {
+		if vm.TargetAction != model.ActionComplete {
+			return model.TbVmStatusInfo{}, false
+		}
+		lastSeen, err := time.Parse(time.RFC3339, vm.LastSeen)
+		if err != nil || time.Since(lastSeen) > statusCacheStaleRounds*interval {
+			return model.TbVmStatusInfo{}, false
+		}
+	}
+
+	return model.TbVmStatusInfo{
+		Id:              vm.Id,
+		Uid:             vm.Uid,
+		CspResourceName: vm.CspResourceName,
+		CspResourceId:   vm.CspResourceId,
+		Name:            vm.Name,
+		Status:          vm.Status,
+		TargetStatus:    vm.TargetStatus,
+		TargetAction:    vm.TargetAction,
+		NativeStatus:    vm.Status,
+		Protected:       vm.Protected,
+		MonAgentStatus:  vm.MonAgentStatus,
+		SystemMessage:   vm.SystemMessage,
+		CreatedTime:     vm.CreatedTime,
+		LastSeen:        vm.LastSeen,
+		PublicIp:        vm.PublicIP,
+		PrivateIp:       vm.PrivateIP,
+		SSHPort:         vm.SSHPort,
+		Location:        vm.Location,
+	}, true
+}
+
+// statusReconcileTarget is a VM whose status is refreshed by the reconciler
+type statusReconcileTarget struct {
+	nsId     string
+	mciId    string
+	vmId     string
+	lastSeen string
+}
+
+// listStatusReconcileTargets returns the VMs to refresh grouped by provider (terminated MCIs and VMs are skipped)
+func listStatusReconcileTargets() map[string][]statusReconcileTarget {
+	targets := map[string][]statusReconcileTarget{}
+
+	nsList, err := common.ListNsId()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return targets
+	}
+	for _, nsId := range nsList {
+		mciList, err := ListMciId(nsId)
+		if err != nil {
+			continue
+		}
+		for _, mciId := range mciList {
+			mci, err := GetMciObject(nsId, mciId)
+			if err != nil {
+				continue
+			}
+			if strings.HasPrefix(mci.Status, model.StatusTerminated) {
+				continue
+			}
+			for _, vm := range mci.Vm {
+				if vm.Status == model.StatusTerminated || vm.CspResourceName == "" {
+					continue
+				}
+				provider := strings.ToLower(vm.ConnectionConfig.ProviderName)
+				targets[provider] = append(targets[provider], statusReconcileTarget{nsId: nsId, mciId: mciId, vmId: vm.Id, lastSeen: vm.LastSeen})
+			}
+		}
+	}
+	return targets
+}
+
+// ReconcileVmStatus refreshes the status of all VMs from the CSPs once.
+// Providers are processed in parallel and each provider has at most statusReconcileBatchSize calls in flight.
+// It returns the number of VMs and the number of VMs whose status could not be fetched.
+func ReconcileVmStatus() (int, int) {
+	targets := listStatusReconcileTargets()
+	batchSize := statusReconcileBatchSize()
+
+	var lock sync.Mutex
+	total, failed := 0, 0
+	var wg sync.WaitGroup
+	for provider, vms := range targets {
+		wg.Add(1)
+		go func(provider string, vms []statusReconcileTarget) {
+			defer wg.Done()
+			sem := make(chan struct{}, batchSize)
+			var providerWg sync.WaitGroup
+			for _, t := range vms {
+				sem <- struct{}{}
+				providerWg.Add(1)
+				go func(t statusReconcileTarget) {
+					defer providerWg.Done()
+					defer func() { <-sem }()
+					vmStatus, err := FetchVmStatus(t.nsId, t.mciId, t.vmId)
+					// lastSeen is not updated if CB-Spider fails to return the status
+					fetchFailed := err != nil || vmStatus.LastSeen == "" || vmStatus.LastSeen == t.lastSeen
+					lock.Lock()
+					total++
+					if fetchFailed {
+						failed++
+					}
+					lock.Unlock()
+				}(t)
+			}
+			providerWg.Wait()
+			log.Trace().Msgf("Reconciled the status of %d VMs of %s", len(vms), provider)
+		}(provider, vms)
+	}
+	wg.Wait()
+	return total, failed
+}
+
+// statusReconcilerLag returns the time since the last completed round of the reconciler
+func statusReconcilerLag() time.Duration {
+	statusReconcilerLock.Lock()
+	defer statusReconcilerLock.Unlock()
+	if statusReconcilerLastRound.IsZero() {
+		return time.Since(statusReconcilerStarted)
+	}
+	return time.Since(statusReconcilerLastRound)
+}
+
+// StartStatusReconciler refreshes the status of VMs in background by StatusReconcileInterval (with jitter),
+// so that the status of MCIs can be served from the stored VM objects.
+// The interval is doubled (up to statusReconcileMaxBackoff times) while the majority of status calls fail.
+func StartStatusReconciler() {
+	interval := StatusReconcileInterval()
+	if interval <= 0 {
+		log.Info().Msg("VM status reconciler is disabled (TB_STATUS_RECONCILE_INTERVAL_SEC=0)")
+		return
+	}
+
+	statusReconcilerLock.Lock()
+	statusReconcilerStarted = time.Now()
+	statusReconcilerLock.Unlock()
+
+	metrics.RegisterGaugeFunc("tumblebug_status_reconciler_lag_seconds",
+		"Seconds since the last completed round of the VM status reconciler",
+		func() float64 { return statusReconcilerLag().Seconds() })
+	metrics.RegisterGaugeFunc("tumblebug_status_reconciler_backoff",
+		"Multiplier of the interval of the VM status reconciler (greater than 1 while CB-Spider errors spike)",
+		func() float64 {
+			statusReconcilerLock.Lock()
+			defer statusReconcilerLock.Unlock()
+			return float64(statusReconcilerBackoff)
+		})
+
+	go func() {
+		for {
+			statusReconcilerLock.Lock()
+			backoff := statusReconcilerBackoff
+			statusReconcilerLock.Unlock()
+
+			// jitter (up to 10% of the interval) to spread the calls of multiple instances
+			jitter := time.Duration(rand.Int63n(int64(interval)/10 + 1))
+			time.Sleep(interval*time.Duration(backoff) + jitter)
+
+			total, failed := ReconcileVmStatus()
+
+			statusReconcilerLock.Lock()
+			statusReconcilerLastRound = time.Now()
+			if total > 0 && failed*2 > total {
+				if statusReconcilerBackoff < statusReconcileMaxBackoff {
+					statusReconcilerBackoff *= 2
+				}
+				log.Warn().Msgf("VM status reconciler: %d of %d status calls failed, backing off (interval x%d)", failed, total, statusReconcilerBackoff)
+			} else {
+				statusReconcilerBackoff = 1
+			}
+			statusReconcilerLock.Unlock()
+		}
+	}()
+}
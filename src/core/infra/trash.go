@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/rs/zerolog/log"
+)
+
+// TrashMci deletes the MCI like DelMciWithContext but keeps its metadata (MCI, subGroups and VMs) in the trash,
+// so that it can be restored within the retention window. NLBs are deleted permanently.
+func TrashMci(ctx context.Context, nsId string, mciId string, option string) (model.IdList, error) {
+	mciKey := common.GenMciKey(nsId, mciId, "")
+	item, err := common.SaveToTrash(nsId, model.StrMCI, mciId, mciKey, false, func(key string) bool {
+		return strings.Contains(strings.TrimPrefix(key, mciKey), "/nlb")
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.IdList{}, err
+	}
+
+	deletedResources, err := DelMciWithContext(ctx, nsId, mciId, option)
+	if err != nil {
+		// the MCI is not deleted (or partially), so keep it out of the trash
+		common.DeleteTrashItem(nsId, item.Id)
+		return model.IdList{IdList: deletedResources.IdList}, err
+	}
+	deletedResources.IdList = append(deletedResources.IdList, "[Done] Trash: "+item.Id)
+	return model.IdList{IdList: deletedResources.IdList}, nil
+}
+
+// restoreTrashedMci restores the MCI in the trash item and refreshes the status of its VMs from the CSPs
+func restoreTrashedMci(item model.TrashItem) (model.TrashRestoreResult, error) {
+	result := model.TrashRestoreResult{NsId: item.NsId, ResourceType: item.ResourceType, OriginalId: item.ResourceId}
+
+	mciId, err := common.TrashRestoreId(item, func(id string) (bool, error) {
+		return CheckMci(item.NsId, id)
+	})
+	if err != nil {
+		return result, err
+	}
+	_, restored, err := common.RestoreTrashObjects(item, mciId)
+	if err != nil {
+		return result, err
+	}
+
+	// re-associate the VMs with the resources they use
+	vmList, err := ListVmId(item.NsId, mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+	for _, vmId := range vmList {
+		vmInfo, err := GetVmObject(item.NsId, mciId, vmId)
+		if err != nil {
+			continue
+		}
+		vmKey := common.GenMciKey(item.NsId, mciId, vmId)
+		if _, err := resource.UpdateAssociatedObjectList(item.NsId, model.StrImage, vmInfo.ImageId, model.StrAdd, vmKey); err != nil {
+			resource.UpdateAssociatedObjectList(item.NsId, model.StrCustomImage, vmInfo.ImageId, model.StrAdd, vmKey)
+		}
+		resource.UpdateAssociatedObjectList(item.NsId, model.StrSSHKey, vmInfo.SshKeyId, model.StrAdd, vmKey)
+		resource.UpdateAssociatedObjectList(item.NsId, model.StrVNet, vmInfo.VNetId, model.StrAdd, vmKey)
+		for _, sgId := range vmInfo.SecurityGroupIds {
+			resource.UpdateAssociatedObjectList(item.NsId, model.StrSecurityGroup, sgId, model.StrAdd, vmKey)
+		}
+		for _, diskId := range vmInfo.DataDiskIds {
+			resource.UpdateAssociatedObjectList(item.NsId, model.StrDataDisk, diskId, model.StrAdd, vmKey)
+		}
+	}
+
+	if err := common.DeleteTrashItem(item.NsId, item.Id); err != nil {
+		return result, err
+	}
+
+	result.ResourceId = mciId
+	result.Renamed = mciId != item.ResourceId
+	result.Restored = restored
+
+	// re-register the VMs against the CSP resources (VMs which no longer exist get the status from the CSP)
+	mciStatus, err := GetMciStatus(item.NsId, mciId)
+	if err != nil {
+		result.SystemMessage = fmt.Sprintf("failed to refresh the status of VMs from the CSPs: %v", err)
+	} else {
+		result.SystemMessage = "status of VMs refreshed from the CSPs: " + mciStatus.Status
+	}
+	return result, nil
+}
+
+// RestoreTrashItem restores the MCI or resource in the trash item.
+// On id collision, the restored object gets a suffixed id (e.g., mci01-1).
+func RestoreTrashItem(nsId string, itemId string) (model.TrashRestoreResult, error) {
+	item, err := common.GetTrashItem(nsId, itemId)
+	if err != nil {
+		return model.TrashRestoreResult{}, err
+	}
+
+	var result model.TrashRestoreResult
+	if item.ResourceType == model.StrMCI {
+		result, err = restoreTrashedMci(item)
+	} else {
+		result, err = resource.RestoreTrashedResource(item)
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to restore the trash item %s", itemId)
+		return result, err
+	}
+	log.Info().Msgf("Restored %s %s from the trash as %s", item.ResourceType, item.ResourceId, result.ResourceId)
+	return result, nil
+}
+
+// PurgeTrashItem deletes the trash item permanently (and the CSP resource kept for it).
+// If the CSP resource cannot be deleted, the item is kept unless force is true.
+func PurgeTrashItem(nsId string, itemId string, force bool) (model.SimpleMsg, error) {
+	item, err := common.GetTrashItem(nsId, itemId)
+	if err != nil {
+		return model.SimpleMsg{}, err
+	}
+
+	if err := resource.PurgeTrashedResource(item); err != nil {
+		log.Error().Err(err).Msgf("Failed to delete the CSP resource of the trash item %s", itemId)
+		if !force {
+			return model.SimpleMsg{}, fmt.Errorf("failed to delete the CSP resource of %s %s (use force=true to purge the item anyway): %w", item.ResourceType, item.ResourceId, err)
+		}
+	}
+	if err := common.DeleteTrashItem(nsId, itemId); err != nil {
+		return model.SimpleMsg{}, err
+	}
+	return model.SimpleMsg{Message: fmt.Sprintf("The trash item %s (%s %s) has been purged", itemId, item.ResourceType, item.ResourceId)}, nil
+}
+
+// PurgeExpiredTrashItems purges the trash items whose retention has expired
+func PurgeExpiredTrashItems() int {
+	items, err := common.ListTrashItems("")
+	if err != nil {
+		return 0
+	}
+	purged := 0
+	for _, item := range items {
+		if time.Now().Before(item.ExpiresAt) {
+			continue
+		}
+		if _, err := PurgeTrashItem(item.NsId, item.Id, false); err != nil {
+			continue
+		}
+		purged++
+	}
+	return purged
+}
+
+// StartTrashRetention runs PurgeExpiredTrashItems periodically in background
+func StartTrashRetention(interval time.Duration) {
+	log.Info().Msgf("Retention policy for the trash: %v", common.TrashRetention())
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if purged := PurgeExpiredTrashItems(); purged > 0 {
+				log.Info().Msgf("Purged %d trash items by the retention policy", purged)
+			}
+		}
+	}()
+}
+
+// trashedCspResourceNames returns the uids and CSP resource names of the objects in the trash
+// (their CSP resources may be kept and must not be collected as orphaned resources)
+func trashedCspResourceNames() map[string]bool {
+	names := map[string]bool{}
+	items, err := common.ListTrashItems("")
+	if err != nil {
+		return names
+	}
+	for _, summary := range items {
+		item, err := common.GetTrashItem(summary.NsId, summary.Id)
+		if err != nil {
+			continue
+		}
+		for _, kv := range item.Objects {
+			obj := struct {
+				Uid             string `json:"uid"`
+				CspResourceName string `json:"cspResourceName"`
+				CspResourceId   string `json:"cspResourceId"`
+			}{}
+			if json.Unmarshal(kv.Value, &obj) != nil {
+				continue
+			}
+			for _, name := range []string{obj.Uid, obj.CspResourceName, obj.CspResourceId} {
+				if name != "" {
+					names[name] = true
+				}
+			}
+		}
+	}
+	return names
+}
@@ -15,6 +15,7 @@ limitations under the License.
 package infra
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strconv"
@@ -550,6 +551,9 @@ func InspectResources(connConfig string, resourceType string) (model.InspectReso
 	for _, v := range (*temp).AllList.OnlyCSPList {
 		tmpResourceOnCsp.CspResourceId = v.SystemId
 		tmpResourceOnCsp.RefNameOrId = v.NameId
+		if resourceType == model.StrDataDisk {
+			tmpResourceOnCsp.RegisterHint = dataDiskRegisterHint(connConfig, v.SystemId, v.NameId)
+		}
 
 		result.Resources.OnCspTotal.Info = append(result.Resources.OnCspTotal.Info, tmpResourceOnCsp)
 		result.Resources.OnCspOnly.Info = append(result.Resources.OnCspOnly.Info, tmpResourceOnCsp)
@@ -568,7 +572,24 @@ func InspectResources(connConfig string, resourceType string) (model.InspectReso
 	return result, nil
 }
 
-// InspectResourcesOverview func is to check all resources in CB-TB and CSPs
+// dataDiskRegisterHint returns the request to register the dataDisk on CSP only (nsId is to be filled by the user)
+func dataDiskRegisterHint(connConfig string, cspResourceId string, refNameOrId string) *model.ResourceRegisterHint {
+	name := common.ChangeIdString(common.NVL(refNameOrId, cspResourceId))
+	if err := common.CheckString(name); err != nil {
+		name = "disk-" + name
+	}
+	return &model.ResourceRegisterHint{
+		Method: "POST",
+		Path:   "/tumblebug/ns/{nsId}/registerCspResource/dataDisk",
+		Body: model.TbRegisterDataDiskReq{
+			ConnectionName: connConfig,
+			CspResourceId:  cspResourceId,
+			Name:           name,
+		},
+	}
+}
+
+// InspectResourcesOverview func is to check all resources in CB-TB and CSPs (including the resources drifted from CSPs)
 func InspectResourcesOverview() (model.InspectResourceAllResult, error) {
 	startTime := time.Now()
 
@@ -592,7 +613,7 @@ func InspectResourcesOverview() (model.InspectResourceAllResult, error) {
 			temp.ConnectionName = k.ConfigName
 			startTimeForConnection := time.Now()
 
-			inspectResult, err := InspectResources(k.ConfigName, model.StrVNet)
+			inspectResult, err := InspectResourcesWithDrift(k.ConfigName, model.StrVNet, false)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				temp.SystemMessage = err.Error()
@@ -603,7 +624,7 @@ func InspectResourcesOverview() (model.InspectResourceAllResult, error) {
 			if strings.Contains(temp.SystemMessage, rateLimitMessage) {
 				for i := 0; i < maxTrials; i++ {
 					common.RandomSleep(40, 80)
-					inspectResult, err = InspectResources(k.ConfigName, model.StrVNet)
+					inspectResult, err = InspectResourcesWithDrift(k.ConfigName, model.StrVNet, false)
 					if err != nil {
 						log.Error().Err(err).Msg("")
 						temp.SystemMessage = err.Error()
@@ -615,54 +636,61 @@ func InspectResourcesOverview() (model.InspectResourceAllResult, error) {
 			}
 			temp.TumblebugOverview.VNet = inspectResult.ResourceOverview.OnTumblebug
 			temp.CspOnlyOverview.VNet = inspectResult.ResourceOverview.OnCspOnly
+			temp.DriftedOverview.VNet = inspectResult.ResourceOverview.Drifted
 
-			inspectResult, err = InspectResources(k.ConfigName, model.StrSecurityGroup)
+			inspectResult, err = InspectResourcesWithDrift(k.ConfigName, model.StrSecurityGroup, false)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				temp.SystemMessage += err.Error()
 			}
 			temp.TumblebugOverview.SecurityGroup = inspectResult.ResourceOverview.OnTumblebug
 			temp.CspOnlyOverview.SecurityGroup = inspectResult.ResourceOverview.OnCspOnly
+			temp.DriftedOverview.SecurityGroup = inspectResult.ResourceOverview.Drifted
 
-			inspectResult, err = InspectResources(k.ConfigName, model.StrSSHKey)
+			inspectResult, err = InspectResourcesWithDrift(k.ConfigName, model.StrSSHKey, false)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				temp.SystemMessage += err.Error()
 			}
 			temp.TumblebugOverview.SshKey = inspectResult.ResourceOverview.OnTumblebug
 			temp.CspOnlyOverview.SshKey = inspectResult.ResourceOverview.OnCspOnly
+			temp.DriftedOverview.SshKey = inspectResult.ResourceOverview.Drifted
 
-			inspectResult, err = InspectResources(k.ConfigName, model.StrDataDisk)
+			inspectResult, err = InspectResourcesWithDrift(k.ConfigName, model.StrDataDisk, false)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				temp.SystemMessage += err.Error()
 			}
 			temp.TumblebugOverview.DataDisk = inspectResult.ResourceOverview.OnTumblebug
 			temp.CspOnlyOverview.DataDisk = inspectResult.ResourceOverview.OnCspOnly
+			temp.DriftedOverview.DataDisk = inspectResult.ResourceOverview.Drifted
 
-			inspectResult, err = InspectResources(k.ConfigName, model.StrCustomImage)
+			inspectResult, err = InspectResourcesWithDrift(k.ConfigName, model.StrCustomImage, false)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				temp.SystemMessage += err.Error()
 			}
 			temp.TumblebugOverview.CustomImage = inspectResult.ResourceOverview.OnTumblebug
 			temp.CspOnlyOverview.CustomImage = inspectResult.ResourceOverview.OnCspOnly
+			temp.DriftedOverview.CustomImage = inspectResult.ResourceOverview.Drifted
 
-			inspectResult, err = InspectResources(k.ConfigName, model.StrVM)
+			inspectResult, err = InspectResourcesWithDrift(k.ConfigName, model.StrVM, false)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				temp.SystemMessage += err.Error()
 			}
 			temp.TumblebugOverview.Vm = inspectResult.ResourceOverview.OnTumblebug
 			temp.CspOnlyOverview.Vm = inspectResult.ResourceOverview.OnCspOnly
+			temp.DriftedOverview.Vm = inspectResult.ResourceOverview.Drifted
 
-			inspectResult, err = InspectResources(k.ConfigName, model.StrNLB)
+			inspectResult, err = InspectResourcesWithDrift(k.ConfigName, model.StrNLB, false)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				temp.SystemMessage += err.Error()
 			}
 			temp.TumblebugOverview.NLB = inspectResult.ResourceOverview.OnTumblebug
 			temp.CspOnlyOverview.NLB = inspectResult.ResourceOverview.OnCspOnly
+			temp.DriftedOverview.NLB = inspectResult.ResourceOverview.Drifted
 
 			temp.ElapsedTime = int(math.Round(time.Now().Sub(startTimeForConnection).Seconds()))
 
@@ -683,12 +711,19 @@ func InspectResourcesOverview() (model.InspectResourceAllResult, error) {
 		output.TumblebugOverview.NLB += k.TumblebugOverview.NLB
 
 		output.CspOnlyOverview.VNet += k.CspOnlyOverview.VNet
+		output.DriftedOverview.VNet += k.DriftedOverview.VNet
 		output.CspOnlyOverview.SecurityGroup += k.CspOnlyOverview.SecurityGroup
+		output.DriftedOverview.SecurityGroup += k.DriftedOverview.SecurityGroup
 		output.CspOnlyOverview.SshKey += k.CspOnlyOverview.SshKey
+		output.DriftedOverview.SshKey += k.DriftedOverview.SshKey
 		output.CspOnlyOverview.DataDisk += k.CspOnlyOverview.DataDisk
+		output.DriftedOverview.DataDisk += k.DriftedOverview.DataDisk
 		output.CspOnlyOverview.CustomImage += k.CspOnlyOverview.CustomImage
+		output.DriftedOverview.CustomImage += k.DriftedOverview.CustomImage
 		output.CspOnlyOverview.Vm += k.CspOnlyOverview.Vm
+		output.DriftedOverview.Vm += k.DriftedOverview.Vm
 		output.CspOnlyOverview.NLB += k.CspOnlyOverview.NLB
+		output.DriftedOverview.NLB += k.DriftedOverview.NLB
 
 		if k.SystemMessage != "" {
 			errorConnectionCnt++
@@ -708,6 +743,19 @@ func InspectResourcesOverview() (model.InspectResourceAllResult, error) {
 
 // RegisterCspNativeResourcesAll func registers all CSP-native resources into CB-TB
 func RegisterCspNativeResourcesAll(nsId string, mciId string, option string, mciFlag string) (model.RegisterResourceAllResult, error) {
+	return RegisterCspNativeResourcesAllWithContext(context.Background(), nsId, mciId, option, mciFlag)
+}
+
+// RegisterCspNativeResourcesAllAsync starts an async job to register all CSP-native resources and returns the job immediately
+func RegisterCspNativeResourcesAllAsync(nsId string, mciId string, option string, mciFlag string) (model.JobInfo, error) {
+	return common.StartJob(model.JobTypeRegisterCspResourcesAll, common.GenMciKey(nsId, "", ""), func(ctx context.Context) (interface{}, error) {
+		return RegisterCspNativeResourcesAllWithContext(ctx, nsId, mciId, option, mciFlag)
+	})
+}
+
+// RegisterCspNativeResourcesAllWithContext func registers all CSP-native resources into CB-TB.
+// If ctx is canceled, connections and resources which are not started yet are skipped.
+func RegisterCspNativeResourcesAllWithContext(ctx context.Context, nsId string, mciId string, option string, mciFlag string) (model.RegisterResourceAllResult, error) {
 	startTime := time.Now()
 
 	connectionConfigList, err := common.GetConnConfigList(model.DefaultCredentialHolder, true, true)
@@ -720,10 +768,15 @@ func RegisterCspNativeResourcesAll(nsId string, mciId string, option string, mci
 	output := model.RegisterResourceAllResult{}
 
 	var wait sync.WaitGroup
+	var outputLock sync.Mutex
+	var doneConnectionCnt int
 	for _, k := range connectionConfigList.Connectionconfig {
 		wait.Add(1)
 		go func(k model.ConnConfig) {
 			defer wait.Done()
+			if ctx.Err() != nil {
+				return
+			}
 
 			mciNameForRegister := mciId + "-" + k.ConfigName
 			// Assign RandomSleep range by clouds
@@ -742,12 +795,16 @@ func RegisterCspNativeResourcesAll(nsId string, mciId string, option string, mci
 
 			common.RandomSleep(0, 50)
 
-			registerResult, err := RegisterCspNativeResources(nsId, k.ConfigName, mciNameForRegister, option, mciFlag)
+			registerResult, err := RegisterCspNativeResourcesWithContext(ctx, nsId, k.ConfigName, mciNameForRegister, option, mciFlag)
 			if err != nil {
 				log.Error().Err(err).Msg("")
 			}
 
+			outputLock.Lock()
 			output.RegisterationResult = append(output.RegisterationResult, registerResult)
+			doneConnectionCnt++
+			common.UpdateJobProgress(ctx, fmt.Sprintf("Registered resources from %d/%d connections", doneConnectionCnt, len(connectionConfigList.Connectionconfig)))
+			outputLock.Unlock()
 
 		}(k)
 	}
@@ -777,16 +834,25 @@ func RegisterCspNativeResourcesAll(nsId string, mciId string, option string, mci
 		return output.RegisterationResult[i].ConnectionName < output.RegisterationResult[j].ConnectionName
 	})
 
+	if ctx.Err() != nil {
+		return output, ctx.Err()
+	}
 	return output, err
 }
 
 // RegisterCspNativeResources func registers all CSP-native resources into CB-TB
-func RegisterCspNativeResources(nsId string, connConfig string, mciId string, option string, mciFlag string) (model.RegisterResourceResult, error) {
+func RegisterCspNativeResources(nsId string, connConfig string, mciId string, option string, mciFlag string) (*model.RegisterResourceResult, error) {
+	return RegisterCspNativeResourcesWithContext(context.Background(), nsId, connConfig, mciId, option, mciFlag)
+}
+
+// RegisterCspNativeResourcesWithContext func registers all CSP-native resources of a connection into CB-TB.
+// If ctx is canceled, the resources which are not registered yet are skipped.
+func RegisterCspNativeResourcesWithContext(ctx context.Context, nsId string, connConfig string, mciId string, option string, mciFlag string) (*model.RegisterResourceResult, error) {
 	startTime := time.Now()
 
 	optionFlag := "register"
 	registeredStatus := ""
-	result := model.RegisterResourceResult{}
+	result := &model.RegisterResourceResult{}
 
 	startTime01 := time.Now() //tmp
 	var err error
@@ -799,6 +865,9 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			result.SystemMessage = err.Error()
 		}
 		for _, r := range inspectedResources.Resources.OnCspOnly.Info {
+			if ctx.Err() != nil {
+				break
+			}
 			req := model.TbRegisterVNetReq{}
 			req.ConnectionName = connConfig
 			req.CspResourceId = r.CspResourceId
@@ -806,7 +875,7 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			req.Name = req.ConnectionName + "-" + req.CspResourceId
 			req.Name = common.ChangeIdString(req.Name)
 
-			_, err = resource.RegisterVNet(nsId, &req)
+			_, err = resource.RegisterVNet(ctx, nsId, &req)
 
 			registeredStatus = ""
 			if err != nil {
@@ -829,6 +898,9 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			result.SystemMessage += "//" + err.Error()
 		}
 		for _, r := range inspectedResources.Resources.OnCspOnly.Info {
+			if ctx.Err() != nil {
+				break
+			}
 			req := model.TbSecurityGroupReq{}
 			req.ConnectionName = connConfig
 			req.VNetId = "not defined"
@@ -837,7 +909,7 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			req.Name = req.ConnectionName + "-" + req.CspResourceId
 			req.Name = common.ChangeIdString(req.Name)
 
-			_, err = resource.CreateSecurityGroup(nsId, &req, optionFlag)
+			_, err = resource.CreateSecurityGroup(ctx, nsId, &req, optionFlag)
 
 			registeredStatus = ""
 			if err != nil {
@@ -860,6 +932,9 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			result.SystemMessage += "//" + err.Error()
 		}
 		for _, r := range inspectedResources.Resources.OnCspOnly.Info {
+			if ctx.Err() != nil {
+				break
+			}
 			req := model.TbSshKeyReq{}
 			req.ConnectionName = connConfig
 			req.CspResourceId = r.CspResourceId
@@ -872,7 +947,7 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			req.PublicKey = "cannot retrieve"
 			req.Username = "cannot retrieve"
 
-			_, err = resource.CreateSshKey(nsId, &req, optionFlag)
+			_, err = resource.CreateSshKey(ctx, nsId, &req, optionFlag)
 
 			registeredStatus = ""
 			if err != nil {
@@ -896,6 +971,9 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			result.SystemMessage += "//" + err.Error()
 		}
 		for _, r := range inspectedResources.Resources.OnCspOnly.Info {
+			if ctx.Err() != nil {
+				break
+			}
 			req := model.TbDataDiskReq{
 				Name:           fmt.Sprintf("%s-%s", connConfig, r.CspResourceId),
 				ConnectionName: connConfig,
@@ -903,7 +981,7 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			}
 			req.Name = common.ChangeIdString(req.Name)
 
-			_, err = resource.CreateDataDisk(nsId, &req, optionFlag)
+			_, err = resource.CreateDataDisk(ctx, nsId, &req, optionFlag)
 
 			registeredStatus = ""
 			if err != nil {
@@ -927,6 +1005,9 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			result.SystemMessage += "//" + err.Error()
 		}
 		for _, r := range inspectedResources.Resources.OnCspOnly.Info {
+			if ctx.Err() != nil {
+				break
+			}
 			req := model.TbCustomImageReq{
 				Name:           fmt.Sprintf("%s-%s", connConfig, r.CspResourceId),
 				ConnectionName: connConfig,
@@ -961,6 +1042,9 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 			result.SystemMessage += "//" + err.Error()
 		}
 		for _, r := range inspectedResourcesVm.Resources.OnCspOnly.Info {
+			if ctx.Err() != nil {
+				break
+			}
 			req := model.TbMciReq{}
 			req.Description = "MCI for CSP managed VMs (registered to CB-TB)"
 			req.InstallMonAgent = "no"
@@ -991,7 +1075,7 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 
 			req.Vm = append(req.Vm, vm)
 
-			_, err = CreateMci(nsId, &req, optionFlag)
+			_, err = CreateMci(ctx, nsId, &req, optionFlag)
 
 			registeredStatus = ""
 			if err != nil {
@@ -1012,6 +1096,10 @@ func RegisterCspNativeResources(nsId string, connConfig string, mciId string, op
 
 	fmt.Printf("\n\n%s [Elapsed]Total %d \n\n", connConfig, int(math.Round(time.Now().Sub(startTime).Seconds())))
 
+	if ctx.Err() != nil {
+		result.SystemMessage += "//registration is canceled"
+		return result, ctx.Err()
+	}
 	return result, err
 
 }
@@ -1039,7 +1127,7 @@ func FindTbVmByCspId(nsId string, mciId string, vmCspResourceId string) (model.T
 	check, err := CheckMci(nsId, mciId)
 
 	if !check {
-		err := fmt.Errorf("The MCI " + mciId + " does not exist.")
+		err := common.NewResourceNotFoundError("mci", mciId)
 		return model.TbVmInfo{}, err
 	}
 
@@ -0,0 +1,939 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package infra is to manage multi-cloud infra
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/netutil"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	terrariumModel "github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// vpnHealthCacheDuration is how long a VPN health result is reused
+	vpnHealthCacheDuration = 30 * time.Second
+	// vpnHealthHistorySize is the number of status changes kept in the VPN health object
+	vpnHealthHistorySize = 20
+)
+
+var (
+	// vpnLock serializes the access to the VPN objects in the Key-Value store
+	vpnLock sync.Mutex
+	// vpnUpdating keeps the VPNs being created, updated or deleted ({nsId}/{mciId}/{vpnId})
+	vpnUpdating = map[string]bool{}
+)
+
+// genVpnHealthKey returns the key of the VPN health object
+func genVpnHealthKey(nsId string, mciId string, vpnId string) string {
+	return "/ns/" + nsId + "/vpn/" + mciId + "/" + vpnId
+}
+
+// vpnTerrariumResponse is the common response of mc-terrarium
+type vpnTerrariumResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message"`
+	Detail  string        `json:"details,omitempty"`
+	List    []interface{} `json:"list,omitempty"`
+}
+
+// newTerrariumClient returns a resty client for mc-terrarium (with basic auth)
+func newTerrariumClient() *resty.Client {
+	client := resty.New()
+	client.SetBasicAuth(os.Getenv("TB_API_USERNAME"), os.Getenv("TB_API_PASSWORD"))
+	return client
+}
+
+// callTerrarium calls the API of mc-terrarium (body is a pointer to common.NoBody if no request body)
+func callTerrarium[B any, T any](client *resty.Client, method string, path string, body *B, result *T) error {
+	return common.ExecuteHttpRequest(
+		client,
+		method,
+		model.TerrariumRestUrl+path,
+		nil,
+		common.SetUseBody(*body),
+		body,
+		result,
+		common.VeryShortDuration,
+	)
+}
+
+// getVpnTerrariumResources returns the resources (tofu state) of a VPN connection from mc-terrarium
+func getVpnTerrariumResources(client *resty.Client, trId string) ([]gjson.Result, error) {
+	_, resources, err := getVpnTerrarium(client, trId)
+	return resources, err
+}
+
+// getVpnTerrarium returns the enrichments and the resources (tofu state) of a VPN connection from mc-terrarium
+func getVpnTerrarium(client *resty.Client, trId string) (string, []gjson.Result, error) {
+	noBody := common.NoBody
+	trInfo := new(terrariumModel.TerrariumInfo)
+	err := callTerrarium(client, "GET", fmt.Sprintf("/tr/%s", trId), &noBody, trInfo)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get the terrarium %s from mc-terrarium: %w", trId, err)
+	}
+	if trInfo.Enrichments == "" {
+		return "", nil, fmt.Errorf("the terrarium %s is not configured in mc-terrarium", trId)
+	}
+
+	resourceInfo := new(vpnTerrariumResponse)
+	err = callTerrarium(client, "GET", fmt.Sprintf("/tr/%s/%s?detail=raw", trId, trInfo.Enrichments), &noBody, resourceInfo)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get the resources of the terrarium %s from mc-terrarium: %w", trId, err)
+	}
+	if !resourceInfo.Success {
+		return "", nil, fmt.Errorf("failed to get the resources of the terrarium %s from mc-terrarium: %s", trId, resourceInfo.Message)
+	}
+
+	raw, _ := json.Marshal(resourceInfo.List)
+	return trInfo.Enrichments, gjson.ParseBytes(raw).Array(), nil
+}
+
+// lastPathSegment returns the last segment of the resource path or URL (e.g., the name of a GCP network)
+func lastPathSegment(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// parseVpnSites extracts the sites of a VPN connection from the tofu resources
+// (the gateway of each CSP is in the vNet of the site)
+func parseVpnSites(resources []gjson.Result) []model.VpnSite {
+	sites := []model.VpnSite{}
+	gatewaySubnetCidr := ""
+	for _, resource := range resources {
+		values := resource.Get("values")
+		switch resource.Get("type").String() {
+		case "aws_vpn_gateway":
+			// arn:aws:ec2:{region}:{account}:vpn-gateway/{id}
+			arn := strings.Split(values.Get("arn").String(), ":")
+			region := ""
+			if len(arn) > 3 {
+				region = arn[3]
+			}
+			sites = append(sites, model.VpnSite{CSP: "aws", Region: region, VNet: values.Get("vpc_id").String()})
+		case "google_compute_ha_vpn_gateway":
+			sites = append(sites, model.VpnSite{
+				CSP:    "gcp",
+				Region: values.Get("region").String(),
+				VNet:   lastPathSegment(values.Get("network").String()),
+			})
+		case "azurerm_virtual_network_gateway":
+			// .../virtualNetworks/{vnet}/subnets/GatewaySubnet
+			vNet := ""
+			if _, after, ok := strings.Cut(values.Get("ip_configuration.0.subnet_id").String(), "/virtualNetworks/"); ok {
+				vNet, _, _ = strings.Cut(after, "/")
+			}
+			sites = append(sites, model.VpnSite{
+				CSP:           "azure",
+				Region:        values.Get("location").String(),
+				VNet:          vNet,
+				ResourceGroup: values.Get("resource_group_name").String(),
+			})
+		case "azurerm_subnet":
+			if values.Get("name").String() == "GatewaySubnet" {
+				gatewaySubnetCidr = values.Get("address_prefixes.0").String()
+			}
+		}
+	}
+	for i := range sites {
+		if sites[i].CSP == "azure" {
+			sites[i].GatewaySubnetCidr = gatewaySubnetCidr
+		}
+	}
+	return sites
+}
+
+// recoverLegacyVpnInfo builds the VPN object of the VPN created before the sites are recorded
+// from its single legacy terrarium in mc-terrarium
+func recoverLegacyVpnInfo(nsId string, mciId string, vpnId string) (model.VpnInfo, error) {
+	trId := genLegacyVpnTrId(nsId, mciId, vpnId)
+	enrichments, resources, err := getVpnTerrarium(newTerrariumClient(), trId)
+	if err != nil {
+		return model.VpnInfo{}, err
+	}
+	sites := parseVpnSites(resources)
+	if len(sites) != 2 {
+		return model.VpnInfo{}, fmt.Errorf("failed to find the 2 sites of the VPN in the terrarium %s (found %d)", trId, len(sites))
+	}
+	return model.VpnInfo{
+		NsId:  nsId,
+		MciId: mciId,
+		VpnId: vpnId,
+		Sites: sites,
+		Connections: []model.VpnConnection{{
+			TrId:        trId,
+			Enrichments: enrichments,
+			SiteIds:     [2]string{GenVpnSiteId(sites[0]), GenVpnSiteId(sites[1])},
+		}},
+	}, nil
+}
+
+// normalizeGcpTunnelStatus normalizes the (detailed) status of a GCP VPN tunnel
+func normalizeGcpTunnelStatus(status string) string {
+	lower := strings.ToLower(status)
+	switch {
+	case strings.Contains(lower, "established") || strings.Contains(lower, "up and running"):
+		return model.VpnStatusUp
+	case strings.Contains(lower, "provisioning") || strings.Contains(lower, "waiting") ||
+		strings.Contains(lower, "handshake") || strings.Contains(lower, "allocating"):
+		return model.VpnStatusDegraded
+	}
+	return model.VpnStatusDown
+}
+
+// parseVpnTunnels extracts the tunnels from the tofu resources and normalizes their status
+func parseVpnTunnels(resources []gjson.Result) []model.VpnTunnelHealth {
+	tunnels := []model.VpnTunnelHealth{}
+	for _, resource := range resources {
+		resourceType := resource.Get("type").String()
+		name := resourceType + "." + resource.Get("name").String()
+		values := resource.Get("values")
+
+		switch resourceType {
+		case "aws_vpn_connection":
+			telemetry := values.Get("vgw_telemetry").Array()
+			if len(telemetry) == 0 {
+				tunnels = append(tunnels, model.VpnTunnelHealth{
+					Name: name, ResourceType: resourceType, Status: model.VpnStatusDegraded,
+					Message: "tunnel telemetry is not reported yet",
+				})
+			}
+			for _, t := range telemetry {
+				rawStatus := t.Get("status").String()
+				status := model.VpnStatusDown
+				if strings.EqualFold(rawStatus, "UP") {
+					status = model.VpnStatusUp
+				}
+				tunnels = append(tunnels, model.VpnTunnelHealth{
+					Name:         name + "/" + t.Get("outside_ip_address").String(),
+					ResourceType: resourceType,
+					Status:       status,
+					RawStatus:    rawStatus,
+					Message:      t.Get("status_message").String(),
+				})
+			}
+		case "google_compute_vpn_tunnel":
+			rawStatus := values.Get("detailed_status").String()
+			if rawStatus == "" {
+				rawStatus = values.Get("status").String()
+			}
+			tunnels = append(tunnels, model.VpnTunnelHealth{
+				Name:         name + "/" + values.Get("peer_ip").String(),
+				ResourceType: resourceType,
+				Status:       normalizeGcpTunnelStatus(rawStatus),
+				RawStatus:    rawStatus,
+			})
+		case "azurerm_virtual_network_gateway_connection":
+			rawStatus := values.Get("connection_status").String()
+			status := model.VpnStatusDegraded
+			message := ""
+			switch strings.ToLower(rawStatus) {
+			case "connected":
+				status = model.VpnStatusUp
+			case "notconnected":
+				status = model.VpnStatusDown
+			case "":
+				message = "connection status is not reported"
+			}
+			tunnels = append(tunnels, model.VpnTunnelHealth{
+				Name:         name,
+				ResourceType: resourceType,
+				Status:       status,
+				RawStatus:    rawStatus,
+				Message:      message,
+			})
+		}
+	}
+	return tunnels
+}
+
+// summarizeVpnStatus returns Up if all tunnels are up, Down if none is up, and Degraded otherwise
+func summarizeVpnStatus(tunnels []model.VpnTunnelHealth) (string, string) {
+	if len(tunnels) == 0 {
+		return model.VpnStatusDown, "no tunnel found"
+	}
+	up := 0
+	for _, tunnel := range tunnels {
+		if tunnel.Status == model.VpnStatusUp {
+			up++
+		}
+	}
+	switch {
+	case up == len(tunnels):
+		return model.VpnStatusUp, ""
+	case up == 0:
+		return model.VpnStatusDown, fmt.Sprintf("0 of %d tunnels are up", len(tunnels))
+	}
+	return model.VpnStatusDegraded, fmt.Sprintf("%d of %d tunnels are up", up, len(tunnels))
+}
+
+// GetVpnHealth returns the health of the tunnels of the site-to-site VPN.
+// The result is cached for a short time unless refresh is true.
+// An event (vpn.degraded) is emitted when the VPN becomes Degraded or Down.
+func GetVpnHealth(nsId string, mciId string, vpnId string, refresh bool) (model.VpnHealthInfo, error) {
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.VpnHealthInfo{}, err
+	}
+	err = common.CheckString(mciId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.VpnHealthInfo{}, err
+	}
+	err = common.CheckString(vpnId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.VpnHealthInfo{}, err
+	}
+
+	vpnLock.Lock()
+	defer vpnLock.Unlock()
+
+	key := genVpnHealthKey(nsId, mciId, vpnId)
+	health := model.VpnHealthInfo{NsId: nsId, MciId: mciId, VpnId: vpnId}
+	keyValue, err := kvstore.GetKv(key)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.VpnHealthInfo{}, err
+	}
+	if keyValue != (kvstore.KeyValue{}) {
+		json.Unmarshal([]byte(keyValue.Value), &health)
+		if !refresh && time.Since(health.LastHealthCheck) < vpnHealthCacheDuration {
+			health.Cached = true
+			return health, nil
+		}
+	}
+
+	client := newTerrariumClient()
+	resources := []gjson.Result{}
+	for _, trId := range getVpnTrIds(nsId, mciId, vpnId) {
+		trResources, err := getVpnTerrariumResources(client, trId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.VpnHealthInfo{}, err
+		}
+		resources = append(resources, trResources...)
+	}
+
+	previousStatus := health.Status
+	health.Tunnels = parseVpnTunnels(resources)
+	health.Status, health.Message = summarizeVpnStatus(health.Tunnels)
+	health.LastHealthCheck = time.Now()
+	health.Cached = false
+	if health.Status != previousStatus {
+		health.History = append(health.History, model.VpnHealthRecord{Time: health.LastHealthCheck, Status: health.Status, Message: health.Message})
+		if len(health.History) > vpnHealthHistorySize {
+			health.History = health.History[len(health.History)-vpnHealthHistorySize:]
+		}
+		if health.Status != model.VpnStatusUp {
+			common.EmitEvent(model.EventVpnDegraded, nsId, key, map[string]interface{}{
+				"mciId": mciId, "vpnId": vpnId, "status": health.Status, "previousStatus": previousStatus,
+				"message": health.Message, "tunnels": health.Tunnels,
+			})
+		}
+	}
+
+	val, _ := json.Marshal(health)
+	if err := kvstore.Put(key, string(val)); err != nil {
+		log.Error().Err(err).Msg("")
+		return health, err
+	}
+	return health, nil
+}
+
+// DelVpnHealth deletes the health object of the VPN
+func DelVpnHealth(nsId string, mciId string, vpnId string) {
+	vpnLock.Lock()
+	defer vpnLock.Unlock()
+
+	err := kvstore.Delete(genVpnHealthKey(nsId, mciId, vpnId))
+	if err != nil && !strings.Contains(err.Error(), model.ErrStrKeyNotFound) {
+		log.Error().Err(err).Msg("")
+	}
+}
+
+// genVpnInfoKey returns the key of the VPN object (sites and connections)
+func genVpnInfoKey(nsId string, mciId string, vpnId string) string {
+	return "/ns/" + nsId + "/vpnInfo/" + mciId + "/" + vpnId
+}
+
+// genLegacyVpnTrId returns the terrarium ID of the first connection of the VPN
+func genLegacyVpnTrId(nsId string, mciId string, vpnId string) string {
+	return fmt.Sprintf("%s-%s-%s", nsId, mciId, vpnId)
+}
+
+// GenVpnSiteId returns the ID of the VPN site ({csp}/{region}/{vnet})
+func GenVpnSiteId(site model.VpnSite) string {
+	return strings.ToLower(site.CSP) + "/" + site.Region + "/" + site.VNet
+}
+
+// getVpnEnrichments returns the enrichments (template) of mc-terrarium for the VPN between the CSPs
+func getVpnEnrichments(csp1 string, csp2 string) (string, error) {
+	csps := []string{strings.ToLower(csp1), strings.ToLower(csp2)}
+	sort.Strings(csps)
+	switch strings.Join(csps, ",") {
+	case "aws,gcp":
+		return "vpn/gcp-aws", nil
+	case "azure,gcp":
+		return "vpn/gcp-azure", nil
+	}
+	return "", fmt.Errorf("currently not supported, VPN between %s and %s", csp1, csp2)
+}
+
+// getVpnInfo returns the VPN object (the caller holds vpnLock). found is false if not recorded.
+func getVpnInfo(nsId string, mciId string, vpnId string) (model.VpnInfo, bool, error) {
+	keyValue, err := kvstore.GetKv(genVpnInfoKey(nsId, mciId, vpnId))
+	if err != nil {
+		return model.VpnInfo{}, false, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return model.VpnInfo{}, false, nil
+	}
+	info := model.VpnInfo{}
+	if err := json.Unmarshal([]byte(keyValue.Value), &info); err != nil {
+		return model.VpnInfo{}, false, err
+	}
+	return info, true, nil
+}
+
+// putVpnInfo stores the VPN object
+func putVpnInfo(info model.VpnInfo) error {
+	vpnLock.Lock()
+	defer vpnLock.Unlock()
+
+	val, _ := json.Marshal(info)
+	if err := kvstore.Put(genVpnInfoKey(info.NsId, info.MciId, info.VpnId), string(val)); err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	return nil
+}
+
+// getVpnTrIds returns the terrarium IDs of the connections of the VPN (the caller holds vpnLock).
+// The VPN created before the sites are recorded has the single legacy terrarium.
+func getVpnTrIds(nsId string, mciId string, vpnId string) []string {
+	info, found, err := getVpnInfo(nsId, mciId, vpnId)
+	if err != nil || !found {
+		return []string{genLegacyVpnTrId(nsId, mciId, vpnId)}
+	}
+	trIds := []string{}
+	for _, connection := range info.Connections {
+		trIds = append(trIds, connection.TrId)
+	}
+	return trIds
+}
+
+// GetVpnInfo returns the sites and the connections of the VPN.
+// For the VPN created before the sites are recorded, the VPN object is recovered from mc-terrarium and stored.
+func GetVpnInfo(nsId string, mciId string, vpnId string) (model.VpnInfo, error) {
+	vpnLock.Lock()
+	info, found, err := getVpnInfo(nsId, mciId, vpnId)
+	vpnLock.Unlock()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.VpnInfo{}, err
+	}
+	if found {
+		return info, nil
+	}
+
+	info, err = recoverLegacyVpnInfo(nsId, mciId, vpnId)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return model.VpnInfo{}, fmt.Errorf("the sites of the VPN %s are not recorded and not recovered from mc-terrarium (the VPN does not exist): %w", vpnId, err)
+	}
+	if err := putVpnInfo(info); err != nil {
+		return model.VpnInfo{}, err
+	}
+	log.Info().Msgf("recovered the sites of the VPN %s from mc-terrarium: %v", vpnId, info.Connections[0].SiteIds)
+	return info, nil
+}
+
+// GetVpnUpdateStatus returns the status of the update request of the VPN. found is false if the request is not the last update.
+func GetVpnUpdateStatus(nsId string, mciId string, vpnId string, requestId string) (model.VpnUpdateStatus, bool) {
+	vpnLock.Lock()
+	defer vpnLock.Unlock()
+
+	info, found, err := getVpnInfo(nsId, mciId, vpnId)
+	if err != nil || !found || info.LastUpdate == nil || info.LastUpdate.RequestId != requestId {
+		return model.VpnUpdateStatus{}, false
+	}
+	return *info.LastUpdate, true
+}
+
+// startVpnOperation marks the VPN busy. It returns an error if another operation is in progress on the VPN.
+func startVpnOperation(nsId string, mciId string, vpnId string) error {
+	vpnLock.Lock()
+	defer vpnLock.Unlock()
+
+	key := nsId + "/" + mciId + "/" + vpnId
+	if vpnUpdating[key] {
+		return fmt.Errorf("another operation is in progress on the VPN %s", vpnId)
+	}
+	vpnUpdating[key] = true
+	return nil
+}
+
+// endVpnOperation clears the busy mark of the VPN
+func endVpnOperation(nsId string, mciId string, vpnId string) {
+	vpnLock.Lock()
+	defer vpnLock.Unlock()
+	delete(vpnUpdating, nsId+"/"+mciId+"/"+vpnId)
+}
+
+// createVpnConnection creates a VPN connection between two sites by a terrarium
+// (issue, init env, generate infracode, plan and apply). progress receives the messages of the steps.
+func createVpnConnection(trId string, site1 model.VpnSite, site2 model.VpnSite, progress func(string)) (model.VpnConnection, error) {
+	enrichments, err := getVpnEnrichments(site1.CSP, site2.CSP)
+	if err != nil {
+		return model.VpnConnection{}, err
+	}
+	client := newTerrariumClient()
+	noBody := common.NoBody
+
+	// issue a terrarium
+	reqTr := terrariumModel.TerrariumInfo{Id: trId, Description: fmt.Sprintf("VPN between %s and %s", GenVpnSiteId(site1), GenVpnSiteId(site2))}
+	resTrInfo := new(terrariumModel.TerrariumInfo)
+	if err := callTerrarium(client, "POST", "/tr", &reqTr, resTrInfo); err != nil {
+		return model.VpnConnection{}, err
+	}
+	progress("successully created a terrarium (trId: " + resTrInfo.Id + ")")
+
+	// init env
+	resEnv := new(vpnTerrariumResponse)
+	if err := callTerrarium(client, "POST", fmt.Sprintf("/tr/%s/%s/env", trId, enrichments), &noBody, resEnv); err != nil {
+		return model.VpnConnection{}, err
+	}
+	progress(resEnv.Message)
+
+	// generate infracode
+	var reqInfracode interface{}
+	gcpSite, peerSite := site1, site2
+	if strings.EqualFold(site2.CSP, "gcp") {
+		gcpSite, peerSite = site2, site1
+	}
+	switch enrichments {
+	case "vpn/gcp-aws":
+		req := terrariumModel.CreateInfracodeOfGcpAwsVpnRequest{}
+		req.TfVars.AwsRegion = peerSite.Region
+		req.TfVars.AwsVpcId = peerSite.VNet
+		req.TfVars.AwsSubnetId = peerSite.Subnet
+		req.TfVars.GcpRegion = gcpSite.Region
+		req.TfVars.GcpVpcNetworkName = gcpSite.VNet
+		reqInfracode = &req
+	case "vpn/gcp-azure":
+		req := terrariumModel.CreateInfracodeOfGcpAzureVpnRequest{}
+		req.TfVars.AzureRegion = peerSite.Region
+		req.TfVars.AzureVirtualNetworkName = peerSite.VNet
+		req.TfVars.AzureResourceGroupName = peerSite.ResourceGroup
+		req.TfVars.AzureGatewaySubnetCidrBlock = peerSite.GatewaySubnetCidr
+		req.TfVars.GcpRegion = gcpSite.Region
+		req.TfVars.GcpVpcNetworkName = gcpSite.VNet
+		reqInfracode = &req
+	}
+	resInfracode := new(vpnTerrariumResponse)
+	if err := callTerrarium(client, "POST", fmt.Sprintf("/tr/%s/%s/infracode", trId, enrichments), &reqInfracode, resInfracode); err != nil {
+		return model.VpnConnection{}, err
+	}
+	progress(resInfracode.Message)
+
+	// check the infracode by plan
+	resPlan := new(vpnTerrariumResponse)
+	if err := callTerrarium(client, "POST", fmt.Sprintf("/tr/%s/%s/plan", trId, enrichments), &noBody, resPlan); err != nil {
+		return model.VpnConnection{}, err
+	}
+	progress(resPlan.Message)
+
+	// apply
+	resApply := new(vpnTerrariumResponse)
+	if err := callTerrarium(client, "POST", fmt.Sprintf("/tr/%s/%s", trId, enrichments), &noBody, resApply); err != nil {
+		return model.VpnConnection{}, err
+	}
+	progress(resApply.Message)
+
+	return model.VpnConnection{
+		TrId:        trId,
+		Enrichments: enrichments,
+		SiteIds:     [2]string{GenVpnSiteId(site1), GenVpnSiteId(site2)},
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// deleteVpnConnection deletes the VPN connection (destroy the resources, delete the env and the terrarium)
+func deleteVpnConnection(trId string, progress func(string)) error {
+	client := newTerrariumClient()
+	noBody := common.NoBody
+
+	trInfo := new(terrariumModel.TerrariumInfo)
+	if err := callTerrarium(client, "GET", fmt.Sprintf("/tr/%s", trId), &noBody, trInfo); err != nil {
+		return err
+	}
+	progress(fmt.Sprintf("successully got the terrarium (trId: %s) for the enrichment (%s)", trInfo.Id, trInfo.Enrichments))
+
+	resDeleteEnrichments := new(vpnTerrariumResponse)
+	if err := callTerrarium(client, "DELETE", fmt.Sprintf("/tr/%s/%s", trId, trInfo.Enrichments), &noBody, resDeleteEnrichments); err != nil {
+		return err
+	}
+	progress(resDeleteEnrichments.Message)
+
+	resDeleteEnv := new(vpnTerrariumResponse)
+	if err := callTerrarium(client, "DELETE", fmt.Sprintf("/tr/%s/%s/env", trId, trInfo.Enrichments), &noBody, resDeleteEnv); err != nil {
+		return err
+	}
+	progress(resDeleteEnv.Message)
+
+	resDeleteTr := new(vpnTerrariumResponse)
+	if err := callTerrarium(client, "DELETE", fmt.Sprintf("/tr/%s", trId), &noBody, resDeleteTr); err != nil {
+		return err
+	}
+	progress(resDeleteTr.Message)
+	return nil
+}
+
+// CreateVpn creates a site-to-site VPN between two sites and records its sites
+func CreateVpn(nsId string, mciId string, vpnId string, site1 model.VpnSite, site2 model.VpnSite, progress func(string)) (model.VpnInfo, error) {
+	if err := startVpnOperation(nsId, mciId, vpnId); err != nil {
+		return model.VpnInfo{}, err
+	}
+	defer endVpnOperation(nsId, mciId, vpnId)
+
+	connection, err := createVpnConnection(genLegacyVpnTrId(nsId, mciId, vpnId), site1, site2, progress)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.VpnInfo{}, err
+	}
+	info := model.VpnInfo{
+		NsId:        nsId,
+		MciId:       mciId,
+		VpnId:       vpnId,
+		Sites:       []model.VpnSite{site1, site2},
+		Connections: []model.VpnConnection{connection},
+	}
+	if err := putVpnInfo(info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// DeleteVpn deletes all connections of the site-to-site VPN
+func DeleteVpn(nsId string, mciId string, vpnId string, progress func(string)) error {
+	if err := startVpnOperation(nsId, mciId, vpnId); err != nil {
+		return err
+	}
+	defer endVpnOperation(nsId, mciId, vpnId)
+
+	vpnLock.Lock()
+	info, found, err := getVpnInfo(nsId, mciId, vpnId)
+	trIds := getVpnTrIds(nsId, mciId, vpnId)
+	vpnLock.Unlock()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	for i, trId := range trIds {
+		if err := deleteVpnConnection(trId, progress); err != nil {
+			log.Error().Err(err).Msgf("failed to delete the VPN connection %s", trId)
+			if found {
+				// keep the connections not deleted yet
+				info.Connections = info.Connections[i:]
+				putVpnInfo(info)
+			}
+			return err
+		}
+	}
+
+	vpnLock.Lock()
+	err = kvstore.Delete(genVpnInfoKey(nsId, mciId, vpnId))
+	vpnLock.Unlock()
+	if err != nil && !strings.Contains(err.Error(), model.ErrStrKeyNotFound) {
+		log.Error().Err(err).Msg("")
+	}
+	DelVpnHealth(nsId, mciId, vpnId)
+	return nil
+}
+
+// validateVpnSites validates the sites of the VPN
+func validateVpnSites(sites []model.VpnSite) error {
+	if len(sites) < 2 {
+		return fmt.Errorf("a VPN requires at least 2 sites")
+	}
+	seen := map[string]bool{}
+	for _, site := range sites {
+		switch strings.ToLower(site.CSP) {
+		case "aws", "gcp", "azure":
+		default:
+			return fmt.Errorf("currently not supported, VPN site of %s", site.CSP)
+		}
+		if site.Region == "" || site.VNet == "" {
+			return fmt.Errorf("region and vnet of the VPN site are required (%s)", GenVpnSiteId(site))
+		}
+		siteId := GenVpnSiteId(site)
+		if seen[siteId] {
+			return fmt.Errorf("duplicated VPN site (%s)", siteId)
+		}
+		seen[siteId] = true
+	}
+	return nil
+}
+
+// CheckVpnSiteCidrOverlap rejects the VPN if the CIDR blocks of the vNets of two sites overlap
+// (the routes between the sites are ambiguous). The sites are matched to the vNets in the namespace
+// by the CSP resource ID (or the ID) of the vNet; sites of vNets not managed by CB-Tumblebug are skipped.
+// With allowOverlap, the overlap is only warned.
+func CheckVpnSiteCidrOverlap(nsId string, sites []model.VpnSite, allowOverlap bool) error {
+	resourceList, err := resource.ListResource(nsId, model.StrVNet, "", "")
+	if err != nil {
+		return err
+	}
+	vNetList, _ := resourceList.([]model.TbVNetInfo)
+
+	siteVNets := []model.TbVNetInfo{}
+	for _, site := range sites {
+		for _, vNet := range vNetList {
+			if vNet.CidrBlock != "" && (vNet.CspResourceId == site.VNet || vNet.Id == site.VNet) {
+				siteVNets = append(siteVNets, vNet)
+				break
+			}
+		}
+	}
+
+	conflicts := []string{}
+	for i := 0; i < len(siteVNets); i++ {
+		for j := i + 1; j < len(siteVNets); j++ {
+			overlap, err := netutil.CidrOverlap(siteVNets[i].CidrBlock, siteVNets[j].CidrBlock)
+			if err != nil {
+				return err
+			}
+			if overlap {
+				conflicts = append(conflicts, fmt.Sprintf("%s of vNet %s overlaps %s of vNet %s",
+					siteVNets[i].CidrBlock, siteVNets[i].Id, siteVNets[j].CidrBlock, siteVNets[j].Id))
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if allowOverlap {
+		log.Warn().Msgf("CIDR blocks of the VPN sites overlap (allowed by allowOverlap): [%s]", strings.Join(conflicts, "; "))
+		return nil
+	}
+	return common.NewConflictError("CIDR blocks of the VPN sites overlap (use allowOverlap=true to connect them anyway): [%s]", strings.Join(conflicts, "; "))
+}
+
+// UpdateVpnSites updates the sites of the VPN incrementally without recreating it.
+// The connections of the removed sites are deleted and the connections for the added sites
+// (with each site of a supported CSP pair) are created, while the other connections are untouched.
+// If a site addition fails, only the connections created for the site are rolled back.
+// The per-site operation states are kept in the last update of the VPN (see GetVpnUpdateStatus).
+func UpdateVpnSites(nsId string, mciId string, vpnId string, sites []model.VpnSite, progress func(string)) (model.VpnUpdateStatus, error) {
+	if err := validateVpnSites(sites); err != nil {
+		return model.VpnUpdateStatus{}, err
+	}
+	if err := startVpnOperation(nsId, mciId, vpnId); err != nil {
+		return model.VpnUpdateStatus{}, err
+	}
+	defer endVpnOperation(nsId, mciId, vpnId)
+
+	info, err := GetVpnInfo(nsId, mciId, vpnId)
+	if err != nil {
+		return model.VpnUpdateStatus{}, err
+	}
+
+	// compute the diff of the sites
+	current := map[string]model.VpnSite{}
+	for _, site := range info.Sites {
+		current[GenVpnSiteId(site)] = site
+	}
+	desired := map[string]bool{}
+	update := &model.VpnUpdateStatus{
+		RequestId: "vpnupdate-" + common.GenUid(),
+		Status:    model.VpnOperationInProgress,
+		StartedAt: time.Now(),
+		Sites:     []model.VpnSiteOperation{},
+	}
+	added := []model.VpnSite{}
+	for _, site := range sites {
+		siteId := GenVpnSiteId(site)
+		desired[siteId] = true
+		if _, ok := current[siteId]; !ok {
+			added = append(added, site)
+			update.Sites = append(update.Sites, model.VpnSiteOperation{SiteId: siteId, Operation: model.VpnSiteOperationAdd, Status: model.VpnOperationPending})
+		}
+	}
+	for _, site := range info.Sites {
+		siteId := GenVpnSiteId(site)
+		if !desired[siteId] {
+			update.Sites = append(update.Sites, model.VpnSiteOperation{SiteId: siteId, Operation: model.VpnSiteOperationRemove, Status: model.VpnOperationPending})
+		}
+	}
+	if len(update.Sites) == 0 {
+		return model.VpnUpdateStatus{}, fmt.Errorf("no change in the sites of the VPN %s", vpnId)
+	}
+
+	info.LastUpdate = update
+	if err := putVpnInfo(info); err != nil {
+		return model.VpnUpdateStatus{}, err
+	}
+	progress(fmt.Sprintf("started to update the VPN %s (requestId: %s, %d site operations)", vpnId, update.RequestId, len(update.Sites)))
+
+	// setSiteOperation records the state of the site operation; the update stops if it is not recorded
+	setSiteOperation := func(i int, status string, message string) error {
+		update.Sites[i].Status = status
+		update.Sites[i].Message = message
+		if err := putVpnInfo(info); err != nil {
+			update.Status = model.VpnOperationFailed
+			return fmt.Errorf("failed to record the %s operation of the site %s (%s): %w", update.Sites[i].Operation, update.Sites[i].SiteId, status, err)
+		}
+		progress(fmt.Sprintf("[%s %s] %s %s", update.Sites[i].Operation, update.Sites[i].SiteId, status, message))
+		return nil
+	}
+
+	// remove the sites first (delete their connections)
+	for i := range update.Sites {
+		if update.Sites[i].Operation != model.VpnSiteOperationRemove {
+			continue
+		}
+		siteId := update.Sites[i].SiteId
+		if err := setSiteOperation(i, model.VpnOperationInProgress, ""); err != nil {
+			return *update, err
+		}
+		var removeErr error
+		remaining := []model.VpnConnection{}
+		for _, connection := range info.Connections {
+			if removeErr != nil || (connection.SiteIds[0] != siteId && connection.SiteIds[1] != siteId) {
+				remaining = append(remaining, connection)
+				continue
+			}
+			if err := deleteVpnConnection(connection.TrId, progress); err != nil {
+				removeErr = fmt.Errorf("failed to delete the connection %s: %w", connection.TrId, err)
+				remaining = append(remaining, connection)
+			}
+		}
+		info.Connections = remaining
+		if removeErr != nil {
+			log.Error().Err(removeErr).Msg("")
+			if err := setSiteOperation(i, model.VpnOperationFailed, removeErr.Error()); err != nil {
+				return *update, err
+			}
+			continue
+		}
+		for j, site := range info.Sites {
+			if GenVpnSiteId(site) == siteId {
+				info.Sites = append(info.Sites[:j], info.Sites[j+1:]...)
+				break
+			}
+		}
+		if err := setSiteOperation(i, model.VpnOperationSucceeded, ""); err != nil {
+			return *update, err
+		}
+	}
+
+	// add the sites (create the connections with the peer sites)
+	for _, site := range added {
+		siteId := GenVpnSiteId(site)
+		i := 0
+		for i = range update.Sites {
+			if update.Sites[i].SiteId == siteId {
+				break
+			}
+		}
+		if err := setSiteOperation(i, model.VpnOperationInProgress, ""); err != nil {
+			return *update, err
+		}
+
+		created := []model.VpnConnection{}
+		var addErr error
+		for _, peer := range info.Sites {
+			if _, err := getVpnEnrichments(site.CSP, peer.CSP); err != nil {
+				continue
+			}
+			info.ConnectionSeq++
+			trId := fmt.Sprintf("%s-%d", genLegacyVpnTrId(nsId, mciId, vpnId), info.ConnectionSeq)
+			connection, err := createVpnConnection(trId, peer, site, progress)
+			if err != nil {
+				addErr = fmt.Errorf("failed to create the connection %s with %s: %w", trId, GenVpnSiteId(peer), err)
+				// the failed connection is also cleaned up by the rollback
+				created = append(created, model.VpnConnection{TrId: trId})
+				break
+			}
+			created = append(created, connection)
+		}
+		if addErr == nil && len(created) == 0 {
+			if err := setSiteOperation(i, model.VpnOperationFailed, "no site of a supported CSP pair to connect"); err != nil {
+				return *update, err
+			}
+			continue
+		}
+		if addErr != nil {
+			log.Error().Err(addErr).Msg("")
+			// roll back the connections of this site only (the pre-existing connections are untouched)
+			rollbackMsg := ""
+			for _, connection := range created {
+				if err := deleteVpnConnection(connection.TrId, progress); err != nil {
+					if connection.Enrichments == "" {
+						// the failed connection may not be issued in mc-terrarium
+						log.Warn().Err(err).Msgf("failed to clean up the VPN connection %s", connection.TrId)
+						continue
+					}
+					log.Error().Err(err).Msgf("failed to roll back the VPN connection %s", connection.TrId)
+					rollbackMsg += fmt.Sprintf("; failed to roll back %s (%v)", connection.TrId, err)
+				}
+			}
+			if rollbackMsg != "" {
+				if err := setSiteOperation(i, model.VpnOperationFailed, addErr.Error()+rollbackMsg); err != nil {
+					return *update, err
+				}
+			} else {
+				if err := setSiteOperation(i, model.VpnOperationRolledBack, addErr.Error()); err != nil {
+					return *update, err
+				}
+			}
+			continue
+		}
+		info.Connections = append(info.Connections, created...)
+		info.Sites = append(info.Sites, site)
+		if err := setSiteOperation(i, model.VpnOperationSucceeded, fmt.Sprintf("%d connections created", len(created))); err != nil {
+			return *update, err
+		}
+	}
+
+	update.Status = model.VpnOperationSucceeded
+	for _, operation := range update.Sites {
+		if operation.Status != model.VpnOperationSucceeded {
+			update.Status = model.VpnOperationFailed
+			break
+		}
+	}
+	update.CompletedAt = time.Now()
+	if err := putVpnInfo(info); err != nil {
+		return *update, err
+	}
+	progress(fmt.Sprintf("completed to update the VPN %s (%s)", vpnId, update.Status))
+	return *update, nil
+}
@@ -0,0 +1,51 @@
+package infra
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseVpnSites(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources string
+		sites     []model.VpnSite
+	}{
+		{
+			"gcp-aws",
+			`[{"type":"google_compute_ha_vpn_gateway","name":"vpn_gw","values":{"region":"asia-northeast3",
+			   "network":"https://www.googleapis.com/compute/v1/projects/p01/global/networks/vnet01"}},
+			  {"type":"google_compute_vpn_tunnel","name":"tunnel","values":{"status":"ESTABLISHED"}},
+			  {"type":"aws_vpn_gateway","name":"vpn_gw","values":{"vpc_id":"vpc-0123",
+			   "arn":"arn:aws:ec2:ap-northeast-2:123456789012:vpn-gateway/vgw-0123"}}]`,
+			[]model.VpnSite{
+				{CSP: "gcp", Region: "asia-northeast3", VNet: "vnet01"},
+				{CSP: "aws", Region: "ap-northeast-2", VNet: "vpc-0123"},
+			},
+		},
+		{
+			"gcp-azure",
+			`[{"type":"azurerm_subnet","name":"gw_subnet","values":{"name":"GatewaySubnet","address_prefixes":["10.1.255.0/27"]}},
+			  {"type":"azurerm_virtual_network_gateway","name":"vpn_gw","values":{"location":"koreacentral","resource_group_name":"rg01",
+			   "ip_configuration":[{"subnet_id":"/subscriptions/s01/resourceGroups/rg01/providers/Microsoft.Network/virtualNetworks/vnet02/subnets/GatewaySubnet"}]}},
+			  {"type":"google_compute_ha_vpn_gateway","name":"vpn_gw","values":{"region":"asia-northeast3",
+			   "network":"projects/p01/global/networks/vnet01"}}]`,
+			[]model.VpnSite{
+				{CSP: "azure", Region: "koreacentral", VNet: "vnet02", ResourceGroup: "rg01", GatewaySubnetCidr: "10.1.255.0/27"},
+				{CSP: "gcp", Region: "asia-northeast3", VNet: "vnet01"},
+			},
+		},
+		{"no gateway", `[{"type":"google_compute_vpn_tunnel","name":"tunnel","values":{}}]`, []model.VpnSite{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sites := parseVpnSites(gjson.Parse(tt.resources).Array())
+			if !reflect.DeepEqual(sites, tt.sites) {
+				t.Errorf("got %+v, want %+v", sites, tt.sites)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Scopes of API keys
+const (
+	ApiKeyScopeReadOnly  string = "read-only"
+	ApiKeyScopeReadWrite string = "read-write"
+)
+
+// ApiKeyHeader is the HTTP header for API key authentication (TB_AUTH_MODE=apikey)
+const ApiKeyHeader = "X-API-Key"
+
+// ApiKeyReq is struct for a request to create an API key
+type ApiKeyReq struct {
+	// Name is unique identifier for the key (lowercase letters, digits and dashes)
+	Name string `json:"name" validate:"required" example:"ci-pipeline"`
+	// Scope is read-only (GET routes without secrets and read-only POST routes, same as the readonly role) or read-write
+	Scope string `json:"scope" validate:"required" enums:"read-only,read-write" example:"read-only"`
+	// NsId restricts the key to a namespace (empty for all namespaces)
+	NsId        string `json:"nsId,omitempty" example:"default"`
+	Description string `json:"description,omitempty" example:"Key for CI pipeline"`
+}
+
+// ApiKeyInfo is struct for an API key (the secret is never included)
+type ApiKeyInfo struct {
+	Name        string    `json:"name" example:"ci-pipeline"`
+	Scope       string    `json:"scope" example:"read-only"`
+	NsId        string    `json:"nsId,omitempty" example:"default"`
+	Description string    `json:"description,omitempty" example:"Key for CI pipeline"`
+	CreatedTime time.Time `json:"createdTime" example:"2024-10-01T00:00:00Z"`
+	RotatedTime time.Time `json:"rotatedTime,omitempty" example:"2024-11-01T00:00:00Z"`
+	Revoked     bool      `json:"revoked" example:"false"`
+	RevokedTime time.Time `json:"revokedTime,omitempty" example:"2024-12-01T00:00:00Z"`
+}
+
+// ApiKeyCreateResult is struct for a created or rotated API key.
+// Key is shown only once and cannot be retrieved later.
+type ApiKeyCreateResult struct {
+	ApiKeyInfo
+	Key string `json:"key" example:"tb.ci-pipeline.3f7c2b..."`
+}
+
+// ApiKeyInfoList is struct for a list of API keys
+type ApiKeyInfoList struct {
+	ApiKeys []ApiKeyInfo `json:"apiKeys"`
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+// Actions of a change in the apply plan
+const (
+	ApplyActionCreate string = "create"
+	ApplyActionUpdate string = "update"
+	ApplyActionDelete string = "delete"
+	ApplyActionNoop   string = "noop"
+	// ApplyActionReplace means the resource differs in a field which cannot be updated in place.
+	// It is not executed; delete the resource (or rename it in the manifest) to converge.
+	ApplyActionReplace string = "replace"
+)
+
+// Results of a change in the apply
+const (
+	ApplyStatusPlanned   string = "Planned"
+	ApplyStatusSucceeded string = "Succeeded"
+	ApplyStatusFailed    string = "Failed"
+	ApplyStatusSkipped   string = "Skipped"
+)
+
+// NsManifest is the desired state of a namespace (infrastructure as code).
+// Each object is the same request as the create API of the object, and the object ID is its name.
+type NsManifest struct {
+	VNet          []TbVNetReq          `json:"vNet,omitempty"`
+	SecurityGroup []TbSecurityGroupReq `json:"securityGroup,omitempty"`
+	SshKey        []TbSshKeyReq        `json:"sshKey,omitempty"`
+	// MciDynamic is MCIs created by common specs and images (the shared vNet, SG and SSH key are created automatically)
+	MciDynamic []TbMciDynamicReq `json:"mciDynamic,omitempty"`
+	// Mci is MCIs created by the specs, images, vNets, SGs and SSH keys in the namespace
+	Mci []TbMciReq `json:"mci,omitempty"`
+}
+
+// ApplyChange is a change of a resource to converge the namespace to the manifest
+type ApplyChange struct {
+	// ResourceType is the type of the resource (vNet, securityGroup, sshKey, mci)
+	ResourceType string `json:"resourceType" example:"vNet"`
+	Id           string `json:"id" example:"vnet01"`
+	// Action is create, update, delete, noop or replace (not executed)
+	Action string `json:"action" example:"create" enums:"create,update,delete,noop,replace"`
+	// Details are the differences between the manifest and the current state
+	Details []string `json:"details,omitempty"`
+	// DependsOn are the resources which should be applied before this change (resourceType/id)
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// Status is Planned (plan only), Succeeded, Failed or Skipped (a dependency failed)
+	Status string `json:"status" example:"Planned"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ApplyResult is the result (or the plan) of applying the manifest to the namespace
+type ApplyResult struct {
+	NsId     string        `json:"nsId" example:"default"`
+	PlanOnly bool          `json:"planOnly"`
+	Prune    bool          `json:"prune"`
+	Changes  []ApplyChange `json:"changes"`
+	// Summary is the number of changes by action (e.g., create: 2, noop: 1)
+	Summary map[string]int `json:"summary"`
+	// Failed is the number of failed or skipped changes
+	Failed int `json:"failed"`
+}
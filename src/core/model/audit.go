@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// AuditRedactedValue replaces sensitive values (e.g., credentials) in audit records
+const AuditRedactedValue = "********"
+
+// AuditRecord is struct for an audit record of a mutating API call (POST, PUT, DELETE).
+// Records are chained by hash so that modification or deletion in the middle of the chain can be detected.
+type AuditRecord struct {
+	// Id is unique identifier for the record (used as the last segment of the key)
+	Id string `json:"id" example:"1727740800000000000-1727740800000000000"`
+	// Seq is the append order of the record (strictly increasing; the first part of Id)
+	Seq int64 `json:"seq,omitempty" example:"1727740800000000000"`
+	// Timestamp is the time when the call was received
+	Timestamp time.Time `json:"timestamp" example:"2024-10-01T00:00:00Z"`
+	// User is the authenticated user (basic auth username or JWT user name)
+	User string `json:"user" example:"default"`
+	// Method is the HTTP method
+	Method string `json:"method" example:"POST"`
+	// Route is the registered route of the API
+	Route string `json:"route" example:"/tumblebug/ns/:nsId/mci"`
+	// Path is the actual request path
+	Path string `json:"path" example:"/tumblebug/ns/default/mci"`
+	// PathParams is the path parameters of the route
+	PathParams map[string]string `json:"pathParams,omitempty"`
+	// RequestBody is the request body with sensitive values redacted
+	RequestBody interface{} `json:"requestBody,omitempty"`
+	// Status is the HTTP status code of the response
+	Status int `json:"status" example:"200"`
+	// RequestId is the X-Request-Id of the call
+	RequestId string `json:"requestId" example:"1727740800000000000"`
+	// RemoteIp is the IP address of the client
+	RemoteIp string `json:"remoteIp,omitempty" example:"127.0.0.1"`
+
+	// PrevHash is the hash of the previous record in the chain
+	PrevHash string `json:"prevHash"`
+	// Hash is the SHA-256 of PrevHash and the content of this record
+	Hash string `json:"hash"`
+}
+
+// AuditRecordList is struct for a page of audit records
+type AuditRecordList struct {
+	// Total is the number of records matched by the filter
+	Total int `json:"total" example:"1"`
+	// Page is the page number of the result
+	Page int `json:"page,omitempty" example:"1"`
+	// PageSize is the page size of the result
+	PageSize int `json:"pageSize,omitempty" example:"100"`
+	// Records are the records in the page (newest first)
+	Records []AuditRecord `json:"records"`
+}
+
+// AuditVerifyResult is struct for the result of the hash chain verification of audit records
+type AuditVerifyResult struct {
+	// Valid is true if all records are consistent with the hash chain
+	Valid bool `json:"valid" example:"true"`
+	// Checked is the number of verified records
+	Checked int `json:"checked" example:"100"`
+	// BrokenAt is the id of the first record which breaks the chain
+	BrokenAt string `json:"brokenAt,omitempty" example:""`
+	// Message describes the verification result
+	Message string `json:"message" example:"audit records are consistent"`
+}
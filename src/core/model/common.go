@@ -17,6 +17,7 @@ package model
 import (
 	"database/sql"
 	"sync"
+	"time"
 
 	"xorm.io/xorm"
 )
@@ -26,6 +27,25 @@ type SimpleMsg struct {
 	Message string `json:"message" example:"Any message"`
 }
 
+// FieldError is struct for an invalid field of a request body
+type FieldError struct {
+	// Field is the path of the field in the request body
+	Field string `json:"field" example:"vm[0].subGroupSize"`
+	// Value is the rejected value
+	Value interface{} `json:"value,omitempty"`
+	// Rule is the violated rule (e.g., required, oneof, type, unknown)
+	Rule    string `json:"rule" example:"required"`
+	Message string `json:"message" example:"vm[0].subGroupSize is required"`
+}
+
+// ValidationErrorMsg is the ErrorResponse of an invalid request body (code: ValidationFailed)
+type ValidationErrorMsg struct {
+	Code      string       `json:"code" example:"ValidationFailed"`
+	Message   string       `json:"message" example:"invalid request body: vm[0].subGroupSize failed on the 'required' rule"`
+	Details   []FieldError `json:"details"`
+	RequestId string       `json:"requestId,omitempty" example:"1730000000000000000"`
+}
+
 // KeyValue is struct for key-value pair
 type KeyValue struct {
 	Key   string `json:"key"`
@@ -73,6 +93,8 @@ var AutocontrolDurationMs string
 var DefaultNamespace string
 var DefaultCredentialHolder string
 var EtcdEndpoints string
+var KvStoreType string
+var KvStoreBoltPath string
 var SelfEndpoint string
 var MyDB *sql.DB
 var err error
@@ -100,6 +122,9 @@ const (
 	StrVNet                  string = "vNet"
 	StrSubnet                string = "subnet"
 	StrDataDisk              string = "dataDisk"
+	StrDataDiskSnapshot      string = "dataDiskSnapshot"
+	StrObjectStorage         string = "objectStorage"
+	StrSqlDb                 string = "sqlDb"
 	StrNLB                   string = "nlb"
 	StrVM                    string = "vm"
 	StrMCI                   string = "mci"
@@ -139,6 +164,8 @@ var ResourceTypeRegistry = map[string]func() interface{}{
 	StrVNet:          func() interface{} { return &TbVNetInfo{} },
 	StrSubnet:        func() interface{} { return &TbSubnetInfo{} },
 	StrDataDisk:      func() interface{} { return &TbDataDiskInfo{} },
+	StrObjectStorage: func() interface{} { return &TbObjectStorageInfo{} },
+	StrSqlDb:         func() interface{} { return &TbSqlDbInfo{} },
 	StrNLB:           func() interface{} { return &TbNLBInfo{} },
 	StrVM:            func() interface{} { return &TbVmInfo{} },
 	StrMCI:           func() interface{} { return &TbMciInfo{} },
@@ -165,6 +192,76 @@ type ConnConfig struct {
 	RegionDetail         RegionDetail   `json:"regionDetail"`
 	RegionRepresentative bool           `json:"regionRepresentative"`
 	Verified             bool           `json:"verified"`
+	// RootDiskType and RootDiskSize are the defaults of root disks of VMs created dynamically with the connection
+	RootDiskType string `json:"rootDiskType,omitempty"`
+	RootDiskSize string `json:"rootDiskSize,omitempty"`
+
+	// LastVerifiedTime is the time of the latest successful verification of the connection
+	LastVerifiedTime time.Time `json:"lastVerifiedTime,omitempty" example:"2024-10-01T00:00:00Z"`
+	// LastCheckedTime is the time of the latest verification (or the latest call failed by an auth error)
+	LastCheckedTime time.Time `json:"lastCheckedTime,omitempty" example:"2024-10-01T00:00:00Z"`
+	// ConsecutiveFailures is the number of failed checks since the latest successful verification
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// LastError is the error of the latest failed check
+	LastError string `json:"lastError,omitempty"`
+	// Health is derived from the checks (healthy, failing, neverChecked)
+	Health string `json:"health,omitempty" enums:"healthy,failing,neverChecked"`
+}
+
+// Health of connection configs
+const (
+	ConnConfigHealthy      string = "healthy"
+	ConnConfigFailing      string = "failing"
+	ConnConfigNeverChecked string = "neverChecked"
+)
+
+// ConnConfigRootDiskReq is struct for the default root disk of VMs created dynamically with a connection ("" or "default" to use the CSP default)
+type ConnConfigRootDiskReq struct {
+	RootDiskType string `json:"rootDiskType" example:"gp3"`
+	RootDiskSize string `json:"rootDiskSize" example:"100"`
+}
+
+// ConnConfigExportItem is struct for a connection config and its region assignment in the export document (no secrets)
+type ConnConfigExportItem struct {
+	ConfigName       string `json:"configName" example:"aws-ap-northeast-2"`
+	ProviderName     string `json:"providerName" example:"aws"`
+	DriverName       string `json:"driverName" example:"aws-driver-v1.0.so"`
+	CredentialName   string `json:"credentialName" example:"aws"`
+	CredentialHolder string `json:"credentialHolder" example:"admin"`
+	// RegionZoneInfoName is the region (zone) of CB-Spider assigned to the connection
+	RegionZoneInfoName   string         `json:"regionZoneInfoName" example:"aws-ap-northeast-2"`
+	RegionZoneInfo       RegionZoneInfo `json:"regionZoneInfo"`
+	RegionRepresentative bool           `json:"regionRepresentative"`
+	RootDiskType         string         `json:"rootDiskType,omitempty"`
+	RootDiskSize         string         `json:"rootDiskSize,omitempty"`
+}
+
+// ConnConfigExport is struct for the document of connection configs to bootstrap another CB-Tumblebug
+// which uses the same CSP accounts (the credentials must be registered in its CB-Spider by name)
+type ConnConfigExport struct {
+	ExportedAt  time.Time              `json:"exportedAt"`
+	Connections []ConnConfigExportItem `json:"connections"`
+}
+
+// ConnConfigImportFailure is struct for a connection config which could not be imported
+type ConnConfigImportFailure struct {
+	ConfigName     string `json:"configName" example:"aws-ap-northeast-2"`
+	CredentialName string `json:"credentialName" example:"aws"`
+	Error          string `json:"error"`
+}
+
+// ConnConfigImportResult is struct for the result of importing connection configs
+type ConnConfigImportResult struct {
+	// Created are the connections created in CB-Spider and CB-Tumblebug
+	Created []string `json:"created"`
+	// Existing are the connections which already existed (the import is idempotent)
+	Existing []string `json:"existing"`
+	// Unverified are the imported connections which failed the availability check
+	Unverified []string `json:"unverified"`
+	// MissingCredential are the connections whose credential is not registered in CB-Spider
+	MissingCredential []ConnConfigImportFailure `json:"missingCredential"`
+	// Failed are the connections which could not be imported for other reasons
+	Failed []ConnConfigImportFailure `json:"failed"`
 }
 
 // SpiderConnConfig is struct for containing a CB-Spider struct for connection config
@@ -250,6 +347,7 @@ type InspectResourceAllResult struct {
 	AvailableConnection  int                     `json:"availableConnection"`
 	TumblebugOverview    inspectOverview         `json:"tumblebugOverview"`
 	CspOnlyOverview      inspectOverview         `json:"cspOnlyOverview"`
+	DriftedOverview      inspectOverview         `json:"driftedOverview"`
 	InspectResult        []InspectResourceResult `json:"inspectResult"`
 }
 
@@ -260,6 +358,7 @@ type InspectResourceResult struct {
 	ElapsedTime       int             `json:"elapsedTime"`
 	TumblebugOverview inspectOverview `json:"tumblebugOverview"`
 	CspOnlyOverview   inspectOverview `json:"cspOnlyOverview"`
+	DriftedOverview   inspectOverview `json:"driftedOverview"`
 }
 
 type inspectOverview struct {
@@ -311,6 +410,8 @@ type ResourceCountOverview struct {
 	OnSpider    int `json:"onSpider"`
 	OnCspTotal  int `json:"onCspTotal"`
 	OnCspOnly   int `json:"onCspOnly"`
+	// Drifted is the number of resources on Tumblebug whose definition differs from the CSP
+	Drifted int `json:"drifted"`
 }
 
 // ResourcesByManageType is struct for Resources by Manage Type
@@ -319,6 +420,7 @@ type ResourcesByManageType struct {
 	OnSpider    ResourceOnSpider    `json:"onSpider"`
 	OnCspTotal  ResourceOnCsp       `json:"onCspTotal"`
 	OnCspOnly   ResourceOnCsp       `json:"onCspOnly"`
+	Drifted     ResourceDrifted     `json:"drifted"`
 }
 
 // ResourceOnSpider is struct for Resource on Spider
@@ -343,6 +445,16 @@ type ResourceOnCsp struct {
 type ResourceOnCspInfo struct {
 	CspResourceId string `json:"cspResourceId"`
 	RefNameOrId   string `json:"refNameOrId"`
+
+	// RegisterHint is the request to register the resource (only for the resource types which can be registered one by one)
+	RegisterHint *ResourceRegisterHint `json:"registerHint,omitempty"`
+}
+
+// ResourceRegisterHint is struct for the request to register a resource on CSP only
+type ResourceRegisterHint struct {
+	Method string      `json:"method" example:"POST"`
+	Path   string      `json:"path" example:"/tumblebug/ns/{nsId}/registerCspResource/dataDisk"`
+	Body   interface{} `json:"body"`
 }
 
 // ResourceOnTumblebug is struct for Resource on Tumblebug
@@ -360,13 +472,52 @@ type ResourceOnTumblebugInfo struct {
 	ObjectKey     string `json:"objectKey"`
 }
 
+const (
+	// ReconcileUpdateTumblebug is the suggestion to update the Tumblebug record by the CSP
+	ReconcileUpdateTumblebug string = "updateTumblebug"
+	// ReconcilePushToCsp is the suggestion to push the Tumblebug state back to the CSP
+	ReconcilePushToCsp string = "pushToCsp"
+)
+
+// ResourceDrifted is struct for Resources on Tumblebug drifted from the CSP
+type ResourceDrifted struct {
+	Count int                 `json:"count"`
+	Info  []ResourceDriftInfo `json:"info"`
+}
+
+// ResourceDriftInfo is struct for a Resource on Tumblebug with the differences from the CSP
+type ResourceDriftInfo struct {
+	IdByTb        string              `json:"idByTb"`
+	CspResourceId string              `json:"cspResourceId"`
+	NsId          string              `json:"nsId"`
+	MciId         string              `json:"mciId,omitempty"`
+	ObjectKey     string              `json:"objectKey"`
+	Differences   []ResourceFieldDiff `json:"differences"`
+	// Suggestions are the ways to reconcile the differences (given only if requested, not executed)
+	Suggestions []ReconcileSuggestion `json:"suggestions,omitempty"`
+}
+
+// ResourceFieldDiff is struct for a field which differs between Tumblebug and the CSP
+type ResourceFieldDiff struct {
+	Field     string `json:"field" example:"firewallRule"`
+	Tumblebug string `json:"tumblebug" example:"inbound|tcp|22|22|0.0.0.0/0"`
+	Csp       string `json:"csp" example:""`
+}
+
+// ReconcileSuggestion is struct for a suggestion to reconcile the differences of a resource
+type ReconcileSuggestion struct {
+	Action      string   `json:"action" enums:"updateTumblebug,pushToCsp"`
+	Fields      []string `json:"fields"`
+	Description string   `json:"description"`
+}
+
 // RegisterResourceAllResult is struct for Register Resource Result for All Clouds
 type RegisterResourceAllResult struct {
-	ElapsedTime           int                      `json:"elapsedTime"`
-	RegisteredConnection  int                      `json:"registeredConnection"`
-	AvailableConnection   int                      `json:"availableConnection"`
-	RegisterationOverview RegisterationOverview    `json:"registerationOverview"`
-	RegisterationResult   []RegisterResourceResult `json:"registerationResult"`
+	ElapsedTime           int                       `json:"elapsedTime"`
+	RegisteredConnection  int                       `json:"registeredConnection"`
+	AvailableConnection   int                       `json:"availableConnection"`
+	RegisterationOverview RegisterationOverview     `json:"registerationOverview"`
+	RegisterationResult   []*RegisterResourceResult `json:"registerationResult"`
 }
 
 // RegisterResourceResult is struct for Register Resource Result
@@ -378,6 +529,42 @@ type RegisterResourceResult struct {
 	RegisterationOutputs  IdList                `json:"registerationOutputs"`
 }
 
+// CspVmImportReq is struct for the request to import the CSP VMs of a connection into a new MCI
+type CspVmImportReq struct {
+	// ConnectionName is the connection of the CSP VMs to import
+	ConnectionName string `json:"connectionName" validate:"required" example:"aws-ap-northeast-2"`
+	// MciName is the name of the new MCI for the imported VMs
+	MciName     string `json:"mciName" validate:"required" example:"imported01"`
+	Description string `json:"description,omitempty" example:"Imported CSP VMs"`
+	// TagFilter imports only the VMs which have all the tags (key: value). An empty value matches any value of the key.
+	TagFilter map[string]string `json:"tagFilter,omitempty" example:"env:prod"`
+	// SshKeyId is an SSH key in the namespace (with the private key) to link to the VMs instead of the key pair in the CSP.
+	// If it is given, the SSH reachability of each VM is checked by the key.
+	SshKeyId string `json:"sshKeyId,omitempty" example:"sshkey01"`
+	// VmUserName is the user name for SSH to the VMs
+	VmUserName string `json:"vmUserName,omitempty" example:"ubuntu"`
+}
+
+// CspVmImportCandidate is struct for a CSP VM which matches the import request
+type CspVmImportCandidate struct {
+	CspResourceId string `json:"cspResourceId"`
+	RefNameOrId   string `json:"refNameOrId"`
+	// VmName is the name of the VM in the MCI after the import
+	VmName string     `json:"vmName"`
+	Tags   []KeyValue `json:"tags,omitempty"`
+}
+
+// CspVmImportResult is struct for the result of importing CSP VMs into an MCI
+type CspVmImportResult struct {
+	NsId           string                 `json:"nsId"`
+	MciId          string                 `json:"mciId"`
+	ConnectionName string                 `json:"connectionName"`
+	DryRun         bool                   `json:"dryRun"`
+	Candidates     []CspVmImportCandidate `json:"candidates"`
+	// Mci is the MCI of the imported VMs (empty for dryRun)
+	Mci *TbMciInfo `json:"mci,omitempty"`
+}
+
 // RegisterResource is struct for Register Resource
 type RegisterationOverview struct {
 	VNet          int `json:"vNet"`
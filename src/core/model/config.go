@@ -24,9 +24,34 @@ type CSPDetail struct {
 	Description string                  `mapstructure:"description" json:"description"`
 	Driver      string                  `mapstructure:"driver" json:"driver"`
 	Links       []string                `mapstructure:"link" json:"links"`
+	RootDisk    RootDiskDetail          `mapstructure:"rootdisk" json:"rootDisk"`
+	Network     NetworkDetail           `mapstructure:"network" json:"network"`
 	Regions     map[string]RegionDetail `mapstructure:"region" json:"regions"`
 }
 
+// RootDiskDetail is structure for root disk types and sizes supported by a CSP (empty means no validation)
+type RootDiskDetail struct {
+	Types   []string `mapstructure:"type" json:"types"`
+	MinSize int      `mapstructure:"minsize" json:"minSize"`
+	MaxSize int      `mapstructure:"maxsize" json:"maxSize"`
+}
+
+// NetworkDetail is structure for network constraints of a CSP (zero values mean no validation)
+type NetworkDetail struct {
+	// MaxSubnetsPerVNet is the max number of subnets in a vNet
+	MaxSubnetsPerVNet int `mapstructure:"maxsubnetspervnet" json:"maxSubnetsPerVNet"`
+	// MinVNetPrefix and MaxVNetPrefix are the range of the prefix length of a vNet CIDR block (e.g., 16 and 28)
+	MinVNetPrefix int `mapstructure:"minvnetprefix" json:"minVNetPrefix"`
+	MaxVNetPrefix int `mapstructure:"maxvnetprefix" json:"maxVNetPrefix"`
+	// MinSubnetPrefix and MaxSubnetPrefix are the range of the prefix length of a subnet CIDR block
+	MinSubnetPrefix int `mapstructure:"minsubnetprefix" json:"minSubnetPrefix"`
+	MaxSubnetPrefix int `mapstructure:"maxsubnetprefix" json:"maxSubnetPrefix"`
+	// ReservedCidrs cannot be used by vNets and subnets
+	ReservedCidrs []string `mapstructure:"reservedcidr" json:"reservedCidrs"`
+	// DiscouragedCidrs can be used but conflict with services of the CSP or common tools (e.g., the docker bridge)
+	DiscouragedCidrs []string `mapstructure:"discouragedcidr" json:"discouragedCidrs"`
+}
+
 // RegionDetail is structure for region information
 type RegionDetail struct {
 	RegionId    string   `mapstructure:"id" json:"regionId"`
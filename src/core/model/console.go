@@ -0,0 +1,39 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// SpiderVMConsoleInfo is struct for the console output of a VM from CB-Spider
+type SpiderVMConsoleInfo struct {
+	// Output is the console output (serial port or boot diagnostics log) of the VM
+	Output string `json:"Output"`
+}
+
+// TbVmConsoleInfo is struct for the console (serial port / boot log) output of a VM
+type TbVmConsoleInfo struct {
+	MciId        string `json:"mciId" example:"mci01"`
+	VmId         string `json:"vmId" example:"g1-1"`
+	ProviderName string `json:"providerName" example:"aws"`
+	// Output is the tail of the console output (max 64 KB)
+	Output string `json:"output"`
+	// Lines is the number of lines in the output
+	Lines int `json:"lines" example:"100"`
+	// Truncated is true if the output is cut by the size limit or the tail param
+	Truncated   bool      `json:"truncated" example:"true"`
+	RetrievedAt time.Time `json:"retrievedAt"`
+	// Cached is true if the output is reused from the recent retrieval
+	Cached bool `json:"cached" example:"false"`
+}
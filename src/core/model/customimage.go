@@ -23,6 +23,8 @@ type CustomImageStatus string
 const (
 	MyImageAvailable   CustomImageStatus = "Available"
 	MyImageUnavailable CustomImageStatus = "Unavailable"
+	MyImageCopying     CustomImageStatus = "Copying"
+	MyImageFailed      CustomImageStatus = "Failed"
 )
 
 // TbVmSnapshotReq is a struct to handle 'Create VM snapshot' request toward CB-Tumblebug.
@@ -57,6 +59,24 @@ type SpiderMyImageRegisterReq struct {
 	}
 }
 
+// SpiderMyImageCopyReq is a struct to copy a MyImage of another region into the region of the connection
+type SpiderMyImageCopyReq struct {
+	ConnectionName string
+	ReqInfo        struct {
+		Name          string
+		SourceRegion  string
+		SourceMyImage string // CSP id of the source MyImage
+	}
+}
+
+// TbCustomImageCopyReq is a struct to handle a request for copying a custom image to another region
+type TbCustomImageCopyReq struct {
+	// TargetConnectionName is the connection of the region where the copy is created
+	TargetConnectionName string `json:"targetConnectionName" validate:"required" example:"aws-us-east-1"`
+	// Name (optional) of the copied custom image (default: {source id}-{target connection})
+	Name string `json:"name,omitempty" example:"my-image-us-east-1"`
+}
+
 // TbCustomImageReq is a struct to handle a request for Create custom image (VM snapshot)
 type TbCustomImageReq struct {
 	// This field is for 'Register existing custom image'
@@ -87,6 +107,7 @@ type TbCustomImageInfo struct {
 	Namespace            string            `json:"namespace,omitempty" example:"default"` // required to save in RDB
 	ConnectionName       string            `json:"connectionName" example:"aws-ap-southeast-1"`
 	SourceVmId           string            `json:"sourceVmId" example:"aws-ap-southeast-1-1"`
+	SourceCustomImageId  string            `json:"sourceCustomImageId,omitempty" example:"my-image"` // source of the copied custom image
 	Description          string            `json:"description"`
 	CreationDate         time.Time         `json:"creationDate,omitempty" example:"2022-10-18T08:12:48Z"`
 	GuestOS              string            `json:"guestOS,omitempty"` // Windows7, Ubuntu etc.
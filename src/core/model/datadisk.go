@@ -64,8 +64,9 @@ type SpiderDiskReqInfoWrapper struct {
 // SpiderDiskInfo is a struct to create JSON body of 'Get disk request'
 type SpiderDiskInfo struct {
 	// Fields for request
-	Name  string
-	CSPid string
+	Name           string
+	CSPid          string
+	SourceSnapshot string // NameId of the disk snapshot to create the disk from
 
 	// Fields for both request and response
 	DiskType string // "", "SSD(gp2)", "Premium SSD", ...
@@ -92,6 +93,17 @@ type TbDataDiskReq struct {
 	// Fields for "Register existing dataDisk" feature
 	// CspResourceId is required to register object from CSP (option=register)
 	CspResourceId string `json:"cspResourceId"`
+
+	// SourceSnapshotCspName is the disk snapshot to create the dataDisk from (set by CreateDataDiskFromSnapshot)
+	SourceSnapshotCspName string `json:"-"`
+}
+
+// TbRegisterDataDiskReq is a struct to handle 'Register dataDisk' (created in CSP) request toward CB-Tumblebug.
+type TbRegisterDataDiskReq struct {
+	ConnectionName string `json:"connectionName" validate:"required" example:"aws-ap-southeast-1"`
+	CspResourceId  string `json:"cspResourceId" validate:"required" example:"vol-0a1b2c3d4e5f67890"`
+	Name           string `json:"name" validate:"required" example:"aws-ap-southeast-1-datadisk"`
+	Description    string `json:"description,omitempty"`
 }
 
 // TbDataDiskVmReq is a struct to handle 'Provisioning dataDisk to VM' request toward CB-Tumblebug.
@@ -127,6 +139,9 @@ type TbDataDiskInfo struct {
 	KeyValueList         []KeyValue `json:"keyValueList,omitempty"`
 	Description          string     `json:"description,omitempty" example:"Available"`
 
+	// OwnerVmCspResourceId is the CSP VM which the registered dataDisk is attached to
+	OwnerVmCspResourceId string `json:"ownerVmCspResourceId,omitempty" example:"i-0a1b2c3d4e5f67890"`
+
 	// Latest system message such as error message
 	SystemMessage string `json:"systemMessage" example:"Failed because ..." default:""` // systeam-given string message
 
@@ -134,6 +149,9 @@ type TbDataDiskInfo struct {
 
 	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
 	SystemLabel string `json:"systemLabel,omitempty" example:"Managed by CB-Tumblebug" default:""`
+
+	// Provenance records the request which created the object (createdByRequestId, createdByUser, createdVia)
+	Provenance
 }
 
 // TbDataDiskUpsizeReq is a struct to handle 'Upsize dataDisk' request toward CB-Tumblebug.
@@ -141,3 +159,86 @@ type TbDataDiskUpsizeReq struct {
 	DiskSize    string `json:"diskSize" validate:"required"`
 	Description string `json:"description"`
 }
+
+// TbDataDiskResizeReq is a struct to handle 'Resize dataDisk' request toward CB-Tumblebug.
+type TbDataDiskResizeReq struct {
+	// DiskSize is the new size (GB), which must be larger than the current size
+	DiskSize int `json:"diskSize" validate:"required" example:"200"`
+}
+
+// TbDataDiskResizeResult is a struct for the result of 'Resize dataDisk' request
+type TbDataDiskResizeResult struct {
+	DataDisk TbDataDiskInfo `json:"dataDisk"`
+	// FilesystemExpansionRequired is true if the partition and filesystem must be expanded in the guest OS
+	FilesystemExpansionRequired bool   `json:"filesystemExpansionRequired"`
+	Message                     string `json:"message,omitempty"`
+}
+
+// SpiderDiskSnapshotReqWrapper is a wrapper struct to create JSON body of 'Create disk snapshot request'
+type SpiderDiskSnapshotReqWrapper struct {
+	ConnectionName string
+	ReqInfo        SpiderDiskSnapshotReq
+}
+
+// SpiderDiskSnapshotReq is a struct to create JSON body of 'Create disk snapshot request'
+type SpiderDiskSnapshotReq struct {
+	Name       string
+	SourceDisk string // NameId of the source disk
+}
+
+// SpiderDiskSnapshotInfo is a struct of the response of 'Create disk snapshot request'
+type SpiderDiskSnapshotInfo struct {
+	IId        IID // {NameId, SystemId}
+	SourceDisk IID
+	DiskSize   string // (GB)
+	Status     DiskStatus
+
+	CreatedTime  time.Time
+	KeyValueList []KeyValue
+}
+
+// TbDataDiskSnapshotReq is a struct to handle 'Create dataDisk snapshot' request toward CB-Tumblebug.
+type TbDataDiskSnapshotReq struct {
+	Name        string `json:"name" validate:"required" example:"datadisk01-snapshot"`
+	Description string `json:"description,omitempty"`
+	// Force takes the snapshot of an attached dataDisk (the data may not be consistent unless the filesystem is frozen)
+	Force bool `json:"force,omitempty" example:"false"`
+}
+
+// TbDataDiskSnapshotInfo is a struct that represents TB dataDisk snapshot object.
+type TbDataDiskSnapshotInfo struct {
+	// Id is unique identifier for the object
+	Id string `json:"id" example:"datadisk01-snapshot"`
+	// Uid is universally unique identifier for the object
+	Uid string `json:"uid,omitempty" example:"wef12awefadf1221edcf"`
+	// CspResourceName is name assigned to the CSP resource. This name is internally used to handle the resource.
+	CspResourceName string `json:"cspResourceName,omitempty" example:"we12fawefadf1221edcf"`
+	// CspResourceId is resource identifier managed by CSP
+	CspResourceId string `json:"cspResourceId,omitempty" example:"snap-06eb41e14121c550a"`
+
+	Name             string     `json:"name" example:"datadisk01-snapshot"`
+	ConnectionName   string     `json:"connectionName" example:"aws-ap-southeast-1"`
+	SourceDataDiskId string     `json:"sourceDataDiskId" example:"datadisk01"`
+	DiskType         string     `json:"diskType" example:"gp2"`
+	DiskSize         string     `json:"diskSize" example:"77"`
+	Status           DiskStatus `json:"status" example:"Available"`
+	CreatedTime      time.Time  `json:"createdTime,omitempty" example:"2022-10-12T05:09:51.05Z"`
+	KeyValueList     []KeyValue `json:"keyValueList,omitempty"`
+	Description      string     `json:"description,omitempty"`
+}
+
+// TbDataDiskSnapshotListResponse is a struct for the list of dataDisk snapshots
+type TbDataDiskSnapshotListResponse struct {
+	Snapshot []TbDataDiskSnapshotInfo `json:"snapshot"`
+}
+
+// TbDataDiskFromSnapshotReq is a struct to handle 'Create dataDisk from snapshot' request toward CB-Tumblebug.
+type TbDataDiskFromSnapshotReq struct {
+	Name string `json:"name" validate:"required" example:"datadisk01-restored"`
+	// ConnectionName (optional) is a connection in the region of the snapshot (default: the connection of the snapshot)
+	ConnectionName string `json:"connectionName,omitempty" example:"aws-ap-southeast-1"`
+	DiskType       string `json:"diskType,omitempty" example:"default"`
+	// DiskSize (optional) must not be less than the size of the snapshot (default: the size of the snapshot)
+	DiskSize    string `json:"diskSize,omitempty" example:"100"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+// Machine-readable codes of API errors
+const (
+	// ErrCodeResourceNotFound is for a resource which does not exist (404)
+	ErrCodeResourceNotFound string = "ResourceNotFound"
+	// ErrCodeResourceInUse is for a resource which cannot be changed since it is used by other objects (409)
+	ErrCodeResourceInUse string = "ResourceInUse"
+	// ErrCodeConflict is for a request which conflicts with the current state (e.g., already exists) (409)
+	ErrCodeConflict string = "Conflict"
+	// ErrCodePreconditionFailed is for a request whose precondition (e.g., If-Match) does not match the current state (412)
+	ErrCodePreconditionFailed string = "PreconditionFailed"
+	// ErrCodeValidationFailed is for an invalid request (400)
+	ErrCodeValidationFailed string = "ValidationFailed"
+	// ErrCodeUpstreamUnavailable is for a failure to reach an upstream such as CB-Spider (502, or 503 while the circuit is open)
+	ErrCodeUpstreamUnavailable string = "UpstreamUnavailable"
+	// ErrCodeNotSupported is for an operation which is not supported by the provider (501)
+	ErrCodeNotSupported string = "NotSupported"
+	// ErrCodeForbidden is for a request which is not allowed in the namespace (e.g., a connection out of the allowlist) (403)
+	ErrCodeForbidden string = "Forbidden"
+	// ErrCodeQuotaExceeded is for a request which exceeds a quota of the namespace (429)
+	ErrCodeQuotaExceeded string = "QuotaExceeded"
+	// ErrCodeRateLimited is for a call to a provider which is throttled by the rate limit of CB-Tumblebug (429)
+	ErrCodeRateLimited string = "RateLimited"
+	// ErrCodeBadRequest is for other errors of a request (400)
+	ErrCodeBadRequest string = "BadRequest"
+	// ErrCodeInternal is for other errors while handling a request (500)
+	ErrCodeInternal string = "InternalError"
+)
+
+// ErrorResponse is the standard response body of an API error
+type ErrorResponse struct {
+	Code    string      `json:"code" example:"ResourceNotFound"`
+	Message string      `json:"message" example:"The vNet vnet01 does not exist."`
+	Details interface{} `json:"details,omitempty"`
+	// RequestId is the ID to get the details of the request (GET /request/{reqId})
+	RequestId string `json:"requestId,omitempty" example:"1730000000000000000"`
+}
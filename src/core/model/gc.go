@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// GcResource is struct for a CSP resource created by Tumblebug (named by uid) without a record in Tumblebug
+type GcResource struct {
+	// ResourceType is the type of the resource (vNet, securityGroup, sshKey, dataDisk, customImage, vm)
+	ResourceType string `json:"resourceType" example:"vNet"`
+	// Name is the name of the resource in CB-Spider (uid generated by Tumblebug)
+	Name string `json:"name" example:"cr3bqk6lnsm0vnb6kbqg"`
+	// CspResourceId is the id of the resource in the CSP
+	CspResourceId string `json:"cspResourceId" example:"vpc-0a1b2c3d4e5f"`
+	// CreatedTime is the time embedded in the uid
+	CreatedTime time.Time `json:"createdTime" example:"2024-10-01T00:00:00Z"`
+	// Result is the result of the deletion (only in confirm mode)
+	Result string `json:"result,omitempty" example:"deleted"`
+	// Error is the error of the deletion (only in confirm mode)
+	Error string `json:"error,omitempty"`
+}
+
+// GcConnectionResult is struct for orphaned resources of a connection
+type GcConnectionResult struct {
+	ConnectionName string       `json:"connectionName" example:"aws-ap-northeast-2"`
+	Resources      []GcResource `json:"resources"`
+	// Skipped are orphaned resources younger than the min age (not deleted)
+	Skipped []GcResource `json:"skipped"`
+	// SystemMessage describes the failures of the scan of the connection
+	SystemMessage string `json:"systemMessage,omitempty"`
+}
+
+// GcResult is struct for the result of the garbage collection of orphaned CSP resources
+type GcResult struct {
+	// DryRun is true if the resources are only listed
+	DryRun bool `json:"dryRun" example:"true"`
+	// MinAge is the min age of resources to be collected
+	MinAge string `json:"minAge" example:"1h0m0s"`
+	// ConfirmToken is returned by the dry run and required to delete the listed resources
+	ConfirmToken string `json:"confirmToken,omitempty" example:"cr3bqk6lnsm0vnb6kbqg"`
+	// ConfirmTokenExpiry is the expiry of the confirm token
+	ConfirmTokenExpiry *time.Time           `json:"confirmTokenExpiry,omitempty" example:"2024-10-01T00:10:00Z"`
+	Total              int                  `json:"total" example:"3"`
+	Deleted            int                  `json:"deleted" example:"0"`
+	Failed             int                  `json:"failed" example:"0"`
+	Connections        []GcConnectionResult `json:"connections"`
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Results of the GPU driver installation of a VM
+const (
+	GpuDriverInstalled        string = "Installed"
+	GpuDriverAlreadyInstalled string = "AlreadyInstalled"
+	GpuDriverSkipped          string = "Skipped"
+	GpuDriverFailed           string = "Failed"
+)
+
+// Reboot options of the GPU driver installation
+const (
+	GpuDriverRebootAuto   string = "auto"
+	GpuDriverRebootAlways string = "always"
+	GpuDriverRebootNever  string = "never"
+)
+
+// GpuDriverInstallReq is struct for a request to install the NVIDIA driver to the GPU VMs of an MCI
+type GpuDriverInstallReq struct {
+	// UserName is the SSH user of the VMs (default: the verified user of each VM)
+	UserName string `json:"userName,omitempty" example:"cb-user"`
+	// InstallToolkit installs the CUDA toolkit with the driver
+	InstallToolkit bool `json:"installToolkit" example:"true"`
+	// Reboot is whether to reboot the VM after the installation (auto: only if the driver is not loaded without reboot)
+	Reboot string `json:"reboot,omitempty" example:"auto" enums:"auto,always,never" default:"auto"`
+	// TimeoutMinutes is the timeout of the installation per VM (default: TB_GPU_DRIVER_INSTALL_TIMEOUT_MIN or 60)
+	TimeoutMinutes int `json:"timeoutMinutes,omitempty" example:"60"`
+	// Force reinstalls the driver even if nvidia-smi already works
+	Force bool `json:"force,omitempty" example:"false"`
+}
+
+// VmGpuInfo is struct for the GPU driver of a VM verified by nvidia-smi
+type VmGpuInfo struct {
+	Model         string    `json:"model,omitempty" example:"NVIDIA T4"`
+	Count         int       `json:"count,omitempty" example:"1"`
+	DriverVersion string    `json:"driverVersion,omitempty" example:"550.54.15"`
+	CudaVersion   string    `json:"cudaVersion,omitempty" example:"12.4"`
+	VerifiedTime  time.Time `json:"verifiedTime" example:"2024-10-01T00:00:00Z"`
+}
+
+// GpuDriverInstallVmResult is struct for the result of the GPU driver installation of a VM
+type GpuDriverInstallVmResult struct {
+	VmId string `json:"vmId" example:"g1-1"`
+	// Status is one of Installed, AlreadyInstalled, Skipped, Failed
+	Status string `json:"status" example:"Installed"`
+	// Message is the reason of the status (e.g., why the VM is skipped)
+	Message  string `json:"message,omitempty" example:""`
+	OsFamily string `json:"osFamily,omitempty" example:"ubuntu 22.04"`
+	Rebooted bool   `json:"rebooted" example:"true"`
+	// Gpu is the driver verified by nvidia-smi after the installation
+	Gpu *VmGpuInfo `json:"gpu,omitempty"`
+	// LogTail is the tail of the installer log (for a failure)
+	LogTail string `json:"logTail,omitempty"`
+}
+
+// GpuDriverInstallResult is struct for the result of the GPU driver installation of an MCI
+type GpuDriverInstallResult struct {
+	MciId   string                     `json:"mciId" example:"mci01"`
+	Results []GpuDriverInstallVmResult `json:"results"`
+}
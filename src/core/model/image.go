@@ -57,8 +57,11 @@ type TbImageInfo struct {
 	InfraType            string     `json:"infraType,omitempty"` // vm|k8s|kubernetes|container, etc.
 	Description          string     `json:"description,omitempty"`
 	CreationDate         string     `json:"creationDate,omitempty"`
-	GuestOS              string     `json:"guestOS,omitempty"` // Windows7, Ubuntu etc.
-	Status               string     `json:"status,omitempty"`  // available, unavailable
+	GuestOS              string     `json:"guestOS,omitempty"`                         // Windows7, Ubuntu etc.
+	OSDistribution       string     `json:"osDistribution,omitempty" example:"ubuntu"` // normalized OS distribution (unknown if not recognized)
+	OSVersion            string     `json:"osVersion,omitempty" example:"22.04"`       // normalized OS version
+	Architecture         string     `json:"architecture,omitempty" example:"x86_64"`   // CPU architecture (x86_64, arm64)
+	Status               string     `json:"status,omitempty"`                          // available, unavailable
 	KeyValueList         []KeyValue `json:"keyValueList,omitempty"`
 	AssociatedObjectList []string   `json:"associatedObjectList,omitempty"`
 	IsAutoGenerated      bool       `json:"isAutoGenerated,omitempty"`
@@ -67,6 +70,32 @@ type TbImageInfo struct {
 	SystemLabel string `json:"systemLabel,omitempty" example:"Managed by CB-Tumblebug" default:""`
 }
 
+// Normalized OS distributions of images
+const (
+	ImageOsUbuntu      = "ubuntu"
+	ImageOsDebian      = "debian"
+	ImageOsRhel        = "rhel"
+	ImageOsCentos      = "centos"
+	ImageOsRocky       = "rocky"
+	ImageOsAlma        = "almalinux"
+	ImageOsAmazonLinux = "amazonlinux"
+	ImageOsOracle      = "oraclelinux"
+	ImageOsSles        = "sles"
+	ImageOsFedora      = "fedora"
+	ImageOsCos         = "cos"
+	ImageOsWindows     = "windows"
+	ImageOsUnknown     = "unknown"
+)
+
+// SearchImageRequest is struct for searching images by keywords and normalized OS metadata
+type SearchImageRequest struct {
+	Keywords       []string `json:"keywords"`                                    // substrings of the image name
+	OSDistribution string   `json:"osDistribution" example:"ubuntu"`             // normalized OS distribution
+	OSVersion      string   `json:"osVersion" example:"22.04"`                   // OS version (22 matches 22.04 as well)
+	Architecture   string   `json:"architecture" example:"x86_64"`               // x86_64 or arm64
+	ConnectionName string   `json:"connectionName" example:"aws-ap-northeast-2"` // connection of the image
+}
+
 // SpiderImageList is struct for Spider Image List
 type SpiderImageList struct {
 	Image []SpiderImageInfo `json:"image"`
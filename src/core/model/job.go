@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Status of an async job
+const (
+	JobPending     string = "Pending"
+	JobRunning     string = "Running"
+	JobSucceeded   string = "Succeeded"
+	JobFailed      string = "Failed"
+	JobCanceling   string = "Canceling"
+	JobCanceled    string = "Canceled"
+	JobInterrupted string = "Interrupted"
+)
+
+// Types of async jobs
+const (
+	JobTypeDeleteMci               string = "deleteMci"
+	JobTypeRegisterCspResourcesAll string = "registerCspResourcesAll"
+	JobTypeBenchmarkLatency        string = "benchmarkLatency"
+	JobTypeNetworkTest             string = "networkTest"
+	JobTypeApplyNs                 string = "applyNs"
+	JobTypeInstallGpuDriver        string = "installGpuDriver"
+	JobTypeManageSoftware          string = "manageSoftware"
+)
+
+// JobInfo is struct for an async job which handles a long-running operation
+type JobInfo struct {
+	// Id is unique identifier for the job
+	Id string `json:"id" example:"job-cs6c2ljuelr8l5l7m2n0"`
+	// Type is the kind of operation (e.g., deleteMci, registerCspResourcesAll)
+	Type string `json:"type" example:"deleteMci"`
+	// Target is the object handled by the job (e.g., /ns/default/mci/mci01)
+	Target string `json:"target" example:"/ns/default/mci/mci01"`
+	// Status is the state of the job (Pending, Running, Succeeded, Failed, Canceling, Canceled, Interrupted)
+	Status string `json:"status" example:"Running"`
+	// Progress is the latest progress message of the job
+	Progress string `json:"progress,omitempty" example:"Deleting VM (2/5)"`
+
+	CreatedTime time.Time `json:"createdTime" example:"2024-10-01T00:00:00Z"`
+	UpdatedTime time.Time `json:"updatedTime" example:"2024-10-01T00:01:00Z"`
+	EndTime     time.Time `json:"endTime,omitempty" example:"2024-10-01T00:05:00Z"`
+
+	// Result is the output of the operation (available when the job is finished)
+	Result interface{} `json:"result,omitempty"`
+	// Error is the error message if the job is failed, canceled or interrupted
+	Error string `json:"error,omitempty" example:"context canceled"`
+}
+
+// JobInfoList is struct for a list of async jobs
+type JobInfoList struct {
+	Jobs []JobInfo `json:"jobs"`
+}
@@ -20,9 +20,6 @@ import (
 
 // 2023-11-13 https://github.com/cloud-barista/cb-spider/blob/fa4bd91fdaa6bb853ea96eca4a7b4f58a2abebf2/cloud-control-manager/cloud-driver/interfaces/resources/ClusterHandler.go#L1
 
-/*
-TODO: Implement Register/Unregister
-
 // SpiderClusterRegisterReqInfoWrapper is a wrapper struct to create JSON body of 'Register Cluster request'
 type SpiderClusterRegisterReqInfoWrapper struct {
 	ConnectionName string
@@ -44,7 +41,17 @@ type SpiderClusterRegisterReqInfo struct {
 type SpiderClusterUnregisterReqInfoWrapper struct {
 	ConnectionName string
 }
-*/
+
+// TbRegisterK8sClusterReq is a struct to handle 'Register K8sCluster' request toward CB-Tumblebug.
+type TbRegisterK8sClusterReq struct {
+	ConnectionName string `json:"connectionName" validate:"required" example:"aws-ap-northeast-2"`
+	CspResourceId  string `json:"cspResourceId" validate:"required" example:"my-eks-cluster"`
+	Name           string `json:"name" validate:"required" example:"k8scluster-01"`
+	Description    string `json:"description,omitempty" example:"Registered K8sCluster"`
+
+	// VNetId is the ID of the vNet (registered in the namespace) that the K8sCluster belongs to (optional)
+	VNetId string `json:"vNetId,omitempty" example:"vnet-01"`
+}
 
 /*
  * K8sCluster Request
@@ -98,6 +105,53 @@ type TbK8sClusterReq struct { // Tumblebug
 	CspResourceId string `json:"cspResourceId" example:"required when option is register"`
 }
 
+// TbK8sClusterDynamicReq is struct to get high-level requirements to create a new K8sCluster dynamically (with default resource option)
+type TbK8sClusterDynamicReq struct {
+	Name        string `json:"name" validate:"required" example:"k8scluster01"`
+	Description string `json:"description" example:"Made in CB-TB"`
+
+	// ProviderName is the preferred provider ("any" or empty means no preference)
+	ProviderName string `json:"providerName" example:"aws" default:"any"`
+	// RegionName is the preferred region (empty means no preference)
+	RegionName string `json:"regionName" example:"ap-northeast-2" default:""`
+	// if ConnectionName is given, ProviderName and RegionName are ignored
+	ConnectionName string `json:"connectionName,omitempty" default:""`
+
+	// Version is K8sCluster version. If it is empty, the latest version in k8sclusterinfo.yaml is used
+	Version string `json:"version,omitempty" example:"1.30" default:""`
+
+	// VCPU and MemoryGiB are the acceptable ranges of the spec for each node (0 means no limit)
+	VCPU      Range `json:"vCPU"`
+	MemoryGiB Range `json:"memoryGiB"`
+
+	NodeGroupName   string `json:"nodeGroupName" example:"ng01" default:""`
+	RootDiskType    string `json:"rootDiskType,omitempty" example:"default" default:"default"`
+	RootDiskSize    string `json:"rootDiskSize,omitempty" example:"default" default:"default"`
+	OnAutoScaling   string `json:"onAutoScaling" example:"true" default:"true"`
+	DesiredNodeSize string `json:"desiredNodeSize" example:"1" default:"1"`
+	MinNodeSize     string `json:"minNodeSize" example:"1" default:"1"`
+	MaxNodeSize     string `json:"maxNodeSize" example:"2" default:"2"`
+}
+
+// TbK8sClusterDynamicCheckInfo is struct to show the resolved plan of a K8sCluster dynamic request
+type TbK8sClusterDynamicCheckInfo struct {
+	ConnectionName string     `json:"connectionName" example:"aws-ap-northeast-2"`
+	ProviderName   string     `json:"providerName" example:"aws"`
+	RegionName     string     `json:"regionName" example:"ap-northeast-2"`
+	Spec           TbSpecInfo `json:"spec"`
+
+	// NodeGroupsOnCreation shows whether the node group is created with the cluster or added after it
+	NodeGroupsOnCreation bool `json:"nodeGroupsOnCreation" example:"true"`
+
+	// K8sClusterReq is the concrete request that will be used to create the K8sCluster
+	K8sClusterReq TbK8sClusterReq `json:"k8sClusterReq"`
+	// K8sNodeGroupReq is the node group request to be added after the K8sCluster is created (NodeGroupsOnCreation is false)
+	K8sNodeGroupReq *TbK8sNodeGroupReq `json:"k8sNodeGroupReq,omitempty"`
+
+	// SharedResourcesToCreate lists the default resources (vNet, sshKey, securityGroup) that do not exist yet
+	SharedResourcesToCreate []string `json:"sharedResourcesToCreate"`
+}
+
 // 2023-11-13 https://github.com/cloud-barista/cb-spider/blob/fa4bd91fdaa6bb853ea96eca4a7b4f58a2abebf2/api-runtime/rest-runtime/ClusterRest.go#L441
 
 // SpiderNodeGroupReq is a wrapper struct to create JSON body of 'Add NodeGroup' request
@@ -337,7 +391,26 @@ type TbK8sClusterInfo struct {
 	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
 	SystemLabel string `json:"systemLabel" example:"Managed by CB-Tumblebug" default:""`
 
+	// Provenance records the request which created the object (createdByRequestId, createdByUser, createdVia)
+	Provenance
+
 	CspViewK8sClusterDetail SpiderClusterInfo `json:cspViewK8sClusterDetail,omitempty"`
+
+	// LastRefreshed is the time when the cluster information was refreshed from CSP
+	LastRefreshed time.Time `json:"lastRefreshed,omitempty" example:"2024-10-01T00:00:00Z"`
+}
+
+// TbK8sClusterEvent is a struct to represent a status transition of a K8sCluster
+type TbK8sClusterEvent struct {
+	OldStatus SpiderClusterStatus `json:"oldStatus" example:"Creating"`
+	NewStatus SpiderClusterStatus `json:"newStatus" example:"Active"`
+	Time      time.Time           `json:"time" example:"2024-10-01T00:00:00Z"`
+	Message   string              `json:"message" example:"Status changed by the status poller"`
+}
+
+// TbK8sClusterEventList is a struct to handle the event history of a K8sCluster
+type TbK8sClusterEventList struct {
+	Events []TbK8sClusterEvent `json:"events"`
 }
 
 // SpiderNetworkInfo is a struct to handle Cluster Network information from the CB-Spider's REST API response
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+// KvStoreConfig is struct for a backend of the key-value store
+type KvStoreConfig struct {
+	// Type is the backend type of the key-value store (etcd or bolt)
+	Type string `json:"type" validate:"required" enums:"etcd,bolt" example:"bolt"`
+	// Endpoints is the endpoints of etcd (type etcd; TB_ETCD_ENDPOINTS or listed in TB_KVSTORE_MIGRATE_ETCD_ENDPOINTS).
+	// The credential of etcd is given by the server configuration (TB_ETCD_USERNAME and TB_ETCD_PASSWORD).
+	Endpoints []string `json:"endpoints,omitempty" example:"http://localhost:2379"`
+	// Path is the path of the BoltDB file (type bolt, created if missing; TB_KVSTORE_BOLT_PATH or listed in TB_KVSTORE_MIGRATE_BOLT_PATHS)
+	Path string `json:"path,omitempty" example:"../meta_db/dat/kvstore.db"`
+}
+
+// KvStoreMigrateReq is struct for the request to copy the keys between backends of the key-value store
+type KvStoreMigrateReq struct {
+	// Source is the backend to copy from (the current backend of CB-Tumblebug if omitted)
+	Source *KvStoreConfig `json:"source,omitempty"`
+	// Target is the backend to copy to (must differ from the source)
+	Target KvStoreConfig `json:"target" validate:"required"`
+	// KeyPrefix limits the copy to the keys with the prefix (all keys if empty)
+	KeyPrefix string `json:"keyPrefix,omitempty" example:""`
+	// Overwrite replaces the existing keys of the target (the existing keys are kept if false)
+	Overwrite bool `json:"overwrite" example:"false"`
+}
+
+// KvStoreMigrateResult is struct for the result of copying the keys between backends of the key-value store
+type KvStoreMigrateResult struct {
+	Source      string `json:"source" example:"etcd"`
+	Target      string `json:"target" example:"bolt"`
+	KeyPrefix   string `json:"keyPrefix,omitempty" example:""`
+	TotalKeys   int64  `json:"totalKeys" example:"1520"`
+	CopiedKeys  int64  `json:"copiedKeys" example:"1520"`
+	SkippedKeys int64  `json:"skippedKeys" example:"0"`
+	// Message is the error of the copy if it stopped in the middle (the copied keys are kept in the target)
+	Message string `json:"message,omitempty" example:""`
+	// ElapsedTime is the elapsed time of the copy in seconds
+	ElapsedTime int `json:"elapsedTime" example:"3"`
+}
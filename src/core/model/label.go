@@ -24,6 +24,62 @@ type LabelableResource interface {
 type LabelInfo struct {
 	ResourceKey string            `json:"resourceKey"`
 	Labels      map[string]string `json:"labels"`
+
+	// LastSyncStatus is the result of the latest propagation of labels to CSP tags
+	LastSyncStatus string `json:"lastSyncStatus,omitempty" enums:"Success,Failed"`
+	// LastSyncError is the error message of the latest failed propagation
+	LastSyncError string `json:"lastSyncError,omitempty"`
+	// PendingTagRemovals is the list of label keys which have not been removed from CSP tags yet
+	PendingTagRemovals []string `json:"pendingTagRemovals,omitempty"`
+}
+
+const (
+	// LabelSyncSuccess is the status when labels are propagated to CSP tags successfully
+	LabelSyncSuccess string = "Success"
+	// LabelSyncFailed is the status when labels are failed to be propagated to CSP tags
+	LabelSyncFailed string = "Failed"
+)
+
+// LabelBulkReq is a struct to add/remove labels of the resources matched by a label selector
+type LabelBulkReq struct {
+	// LabelType is the type of target resources ("all" means every label type)
+	LabelType string `json:"labelType" example:"vm" default:"all"`
+	// LabelSelector is to pick the target resources (see GetResourcesByLabelSelector for the grammar)
+	LabelSelector string `json:"labelSelector" validate:"required" example:"sys.namespace=default"`
+	// AddLabels is the set of labels to be added or updated
+	AddLabels map[string]string `json:"addLabels"`
+	// RemoveLabels is the list of label keys to be removed
+	RemoveLabels []string `json:"removeLabels"`
+	// DryRun returns the resources which would be modified without modifying them
+	DryRun bool `json:"dryRun" example:"false" default:"false"`
+}
+
+// LabelBulkResult is a struct to return the result of a bulk label request
+type LabelBulkResult struct {
+	DryRun  bool                  `json:"dryRun"`
+	Results []LabelBulkResultItem `json:"results"`
+}
+
+// LabelBulkResultItem is a struct to represent the result of a bulk label request for a resource
+type LabelBulkResultItem struct {
+	LabelType   string `json:"labelType" example:"vm"`
+	Uid         string `json:"uid" example:"wef12awefadf1221edcf"`
+	ResourceKey string `json:"resourceKey" example:"/ns/default/mci/mci01/vm/vm01"`
+	Status      string `json:"status" example:"Modified" enums:"Modified,WouldModify,Unchanged,Failed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// LabelSyncResult is a struct to return the result of re-syncing labels to CSP tags
+type LabelSyncResult struct {
+	Results []LabelSyncResultItem `json:"results"`
+}
+
+// LabelSyncResultItem is a struct to represent the re-sync result of a label object
+type LabelSyncResultItem struct {
+	LabelType string `json:"labelType" example:"vNet"`
+	Uid       string `json:"uid" example:"wef12awefadf1221edcf"`
+	Status    string `json:"status" example:"Success"`
+	Error     string `json:"error,omitempty"`
 }
 
 // Label is a struct to handle labels
@@ -67,6 +123,12 @@ const (
 	LabelCspVNetId       string = "sys.cspVNetId"
 	LabelCspVNetName     string = "sys.cspVNetName"
 	LabelCidr            string = "sys.cidr"
+	LabelProvider        string = "sys.provider"
+	LabelRegion          string = "sys.region"
+	LabelImported        string = "sys.imported"
+	LabelCreatedBy       string = "sys.createdBy"
+	LabelCreatedByReqId  string = "sys.createdByRequestId"
+	LabelCreatedVia      string = "sys.createdVia"
 )
 
 // GetLabelConstantsMap returns a map with label-related system constants as keys and their example values.
@@ -98,6 +160,9 @@ func GetLabelConstantsMap() map[string]string {
 		LabelCspVNetId:       "csp-vnet-1234",
 		LabelCspVNetName:     "csp-vnet-1234",
 		LabelCidr:            "10.0.0.0/24",
+		LabelProvider:        "aws",
+		LabelRegion:          "ap-northeast-2",
+		LabelImported:        NsImportUnverified,
 	}
 }
 
@@ -107,6 +172,8 @@ func GetLabelTypes() []string {
 		StrVNet,
 		StrSubnet,
 		StrDataDisk,
+		StrObjectStorage,
+		StrSqlDb,
 		StrNLB,
 		StrVM,
 		StrMCI,
@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+// ListOption is the option for paging and shaping a list of objects
+type ListOption struct {
+	// Limit is the max number of items in a page (0: all items)
+	Limit int
+	// Offset is the index of the first item in the list
+	Offset int
+	// NextToken is the continue token from the previous page (overrides Offset)
+	NextToken string
+	// Fields are the JSON paths of the fields to return (e.g., id, vm.id); empty for all fields
+	Fields []string
+	// ExcludeKeyValue excludes the keyValueList of objects
+	ExcludeKeyValue bool
+}
+
+// IsSet returns true if any option is given (no option keeps the original list response)
+func (o ListOption) IsSet() bool {
+	return o.Limit > 0 || o.Offset > 0 || o.NextToken != "" || len(o.Fields) > 0 || o.ExcludeKeyValue
+}
+
+// ListPage is the paging information of a list response
+type ListPage struct {
+	// TotalCount is the number of all items in the list
+	TotalCount int `json:"totalCount" example:"40"`
+	// NextToken is the continue token for the next page (empty for the last page)
+	NextToken string `json:"nextToken,omitempty" example:"MjA"`
+}
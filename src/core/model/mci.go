@@ -71,6 +71,9 @@ const (
 	// StatusUndefined is const for Undefined
 	StatusUndefined string = "Undefined"
 
+	// StatusFailedToTerminate is const for FailedToTerminate (termination failed after retries during MCI deletion)
+	StatusFailedToTerminate string = "FailedToTerminate"
+
 	// StatusComplete is const for Complete
 	StatusComplete string = "None"
 )
@@ -142,6 +145,12 @@ type TbMciInfo struct {
 	// SystemLabel is for describing the mci in a keyword (any string can be used) for special System purpose
 	SystemLabel string `json:"systemLabel" example:"Managed by CB-Tumblebug" default:""`
 
+	// Provenance records the request which created the object (createdByRequestId, createdByUser, createdVia)
+	Provenance
+
+	// Protected blocks deletion and termination of the MCI (and its VMs) until the protection is removed
+	Protected bool `json:"protected" example:"false"`
+
 	// Latest system message such as error message
 	SystemMessage string `json:"systemMessage" example:"Failed because ..." default:""` // systeam-given string message
 
@@ -149,10 +158,75 @@ type TbMciInfo struct {
 	Description   string     `json:"description"`
 	Vm            []TbVmInfo `json:"vm"`
 
+	// SubGroupOrder is the order of subGroups resolved from dependsOn. SubGroups in a stage are handled in parallel,
+	// and a stage is created (resumed) after the previous stages are running (suspended in reverse order).
+	SubGroupOrder [][]string `json:"subGroupOrder,omitempty"`
+
 	// List of IDs for new VMs. Return IDs if the VMs are newly added. This field should be used for return body only.
 	NewVmList []string `json:"newVmList"`
 }
 
+// ProtectionReq is struct to set the protection of an MCI or a VM
+type ProtectionReq struct {
+	// Protected blocks deletion and termination (true) or allows them (false)
+	Protected bool `json:"protected" example:"true"`
+}
+
+// TbMciUpdateReq is struct to update the editable fields of an MCI (omitted fields are not changed)
+type TbMciUpdateReq struct {
+	// Description of the MCI
+	Description *string `json:"description,omitempty" example:"Updated by CB-TB"`
+
+	// InstallMonAgent Option for CB-Dragonfly agent installation ([yes/no])
+	InstallMonAgent *string `json:"installMonAgent,omitempty" example:"no" enums:"yes,no"`
+
+	// Label replaces the user labels of the MCI (system labels with sys.* keys cannot be changed)
+	Label map[string]string `json:"label,omitempty"`
+
+	// PlacementAlgo of the MCI
+	PlacementAlgo *string `json:"placementAlgo,omitempty"`
+
+	// Name, SystemLabel and Vm are immutable. They are rejected if given.
+	Name        *string   `json:"name,omitempty" swaggerignore:"true"`
+	SystemLabel *string   `json:"systemLabel,omitempty" swaggerignore:"true"`
+	Vm          []TbVmReq `json:"vm,omitempty" swaggerignore:"true"`
+}
+
+// MciMoveReq is struct to move an MCI to another namespace or to rename it
+type MciMoveReq struct {
+	// TargetNsId is the namespace to move the MCI to (the current namespace to rename the MCI only)
+	TargetNsId string `json:"targetNsId" validate:"required" example:"ns02"`
+
+	// TargetMciId is the new ID of the MCI (default: the current ID)
+	TargetMciId string `json:"targetMciId,omitempty" example:"mci02"`
+}
+
+// MciRetryFailedReq is struct for the request to retry the creation of the failed VMs of an MCI
+type MciRetryFailedReq struct {
+	// MaxRetries is the number of retries allowed per VM (default: TB_VM_CREATE_MAX_RETRIES or 3)
+	MaxRetries int `json:"maxRetries,omitempty" example:"3"`
+}
+
+// MciRetryFailedResult is struct for the result of the retry of the failed VMs of an MCI
+type MciRetryFailedResult struct {
+	MciId string `json:"mciId" example:"mci01"`
+	// Status is the status of the MCI after the retry
+	Status string `json:"status" example:"Running:3 (R:3/3)"`
+	// Retried is the list of VMs re-provisioned by the retry
+	Retried []string `json:"retried"`
+	// Recovered is the list of retried VMs which are running
+	Recovered []string `json:"recovered"`
+	// RemainFailed is the list of VMs which are still failed (not recovered or over maxRetries)
+	RemainFailed []VmRetryFailure `json:"remainFailed"`
+}
+
+// VmRetryFailure is struct for a VM which remains failed after the retry
+type VmRetryFailure struct {
+	VmId    string `json:"vmId" example:"g1-1"`
+	Retries int    `json:"retries" example:"3"`
+	Message string `json:"message"`
+}
+
 // TbVmReq is struct to get requirements to create a new server instance
 type TbVmReq struct {
 	// VM name or subGroup name if is (not empty) && (> 0). If it is a group, actual VM name will be generated with -N postfix.
@@ -182,6 +256,20 @@ type TbVmReq struct {
 	RootDiskType     string   `json:"rootDiskType,omitempty" example:"default, TYPE1, ..."`  // "", "default", "TYPE1", AWS: ["standard", "gp2", "gp3"], Azure: ["PremiumSSD", "StandardSSD", "StandardHDD"], GCP: ["pd-standard", "pd-balanced", "pd-ssd", "pd-extreme"], ALIBABA: ["cloud_efficiency", "cloud", "cloud_ssd"], TENCENT: ["CLOUD_PREMIUM", "CLOUD_SSD"]
 	RootDiskSize     string   `json:"rootDiskSize,omitempty" example:"default, 30, 42, ..."` // "default", Integer (GB): ["50", ..., "1000"]
 	DataDiskIds      []string `json:"dataDiskIds"`
+
+	// Zone is the zone where the VM(s) are placed (optional; the subnet must be in the zone)
+	Zone string `json:"zone,omitempty" example:"ap-northeast-2a"`
+	// ZoneSubnets distributes VMs of the subGroup round-robin across the zones (overrides Zone and SubnetId)
+	ZoneSubnets []ZoneSubnet `json:"zoneSubnets,omitempty"`
+
+	// DependsOn is the list of subGroups which should be running before this subGroup is created or resumed
+	DependsOn []string `json:"dependsOn,omitempty" example:"db"`
+}
+
+// ZoneSubnet is a pair of a zone and a subnet in the zone for VM placement
+type ZoneSubnet struct {
+	Zone     string `json:"zone" example:"ap-northeast-2a"`
+	SubnetId string `json:"subnetId" example:"default-shared-aws-ap-northeast-2-ap-northeast-2a"`
 }
 
 // TbVmReq is struct to get requirements to create a new server instance
@@ -225,7 +313,8 @@ type TbVmDynamicReq struct {
 
 	// CommonSpec is field for id of a spec in common namespace
 	CommonSpec string `json:"commonSpec" validate:"required" example:"aws+ap-northeast-2+t2.small"`
-	// CommonImage is field for id of a image in common namespace
+	// CommonImage is field for id of a image in common namespace,
+	// or an alias (e.g., ubuntu22.04) which is resolved to the newest matched image in the region of the connection
 	CommonImage string `json:"commonImage" validate:"required" example:"ubuntu18.04"`
 
 	RootDiskType string `json:"rootDiskType,omitempty" example:"default, TYPE1, ..." default:"default"`  // "", "default", "TYPE1", AWS: ["standard", "gp2", "gp3"], Azure: ["PremiumSSD", "StandardSSD", "StandardHDD"], GCP: ["pd-standard", "pd-balanced", "pd-ssd", "pd-extreme"], ALIBABA: ["cloud_efficiency", "cloud", "cloud_essd"], TENCENT: ["CLOUD_PREMIUM", "CLOUD_SSD"]
@@ -235,14 +324,43 @@ type TbVmDynamicReq struct {
 	// if ConnectionName is given, the VM tries to use associtated credential.
 	// if not, it will use predefined ConnectionName in Spec objects
 	ConnectionName string `json:"connectionName,omitempty" default:""`
+
+	// Zone (optional) is the zone of the region to place the VM(s). If not given, the default zone of the region is used.
+	Zone string `json:"zone,omitempty" example:"ap-northeast-2a" default:""`
+	// SpreadAcrossZones distributes VMs of the subGroup round-robin across the available zones of the region (ignored if zone is given)
+	SpreadAcrossZones bool `json:"spreadAcrossZones,omitempty" example:"false" default:"false"`
+
+	// DependsOn is the list of subGroups which should be running before this subGroup is created or resumed
+	DependsOn []string `json:"dependsOn,omitempty" example:"db"`
 }
 
 // MciConnectionConfigCandidatesReq is struct for a request to check requirements to create a new MCI instance dynamically (with default resource option)
 type MciConnectionConfigCandidatesReq struct {
 	// CommonSpec is field for id of a spec in common namespace
 	CommonSpecs []string `json:"commonSpec" validate:"required" example:"aws+ap-northeast-2+t2.small,gcp+us-west1+g1-small"`
+	// CommonImage (optional) is an image id or alias (e.g., ubuntu22.04) to be resolved for each connection candidate
+	CommonImage string `json:"commonImage,omitempty" example:"ubuntu22.04"`
+	// RootDiskType and RootDiskSize (optional) are the root disk of the VMs (the default of each connection is used if not given)
+	RootDiskType string `json:"rootDiskType,omitempty" example:"gp3"`
+	RootDiskSize string `json:"rootDiskSize,omitempty" example:"100"`
+}
+
+// ResolvedRootDisk is struct for the root disk resolved for a VM (VM request, connection default or CSP default)
+type ResolvedRootDisk struct {
+	RootDiskType string `json:"rootDiskType" example:"gp3"`
+	RootDiskSize string `json:"rootDiskSize" example:"100"`
+	// RootDiskTypeSource and RootDiskSizeSource are where each setting comes from (vm, connection or default)
+	RootDiskTypeSource string `json:"rootDiskTypeSource" example:"connection"`
+	RootDiskSizeSource string `json:"rootDiskSizeSource" example:"vm"`
 }
 
+// Sources of a resolved root disk setting
+const (
+	RootDiskSourceVm         string = "vm"
+	RootDiskSourceConnection string = "connection"
+	RootDiskSourceDefault    string = "default"
+)
+
 // CheckMciDynamicReqInfo is struct to check requirements to create a new MCI instance dynamically (with default resource option)
 type CheckMciDynamicReqInfo struct {
 	ReqCheck []CheckVmDynamicReqInfo `json:"reqCheck" validate:"required"`
@@ -260,6 +378,12 @@ type CheckVmDynamicReqInfo struct {
 	Image  []TbImageInfo `json:"image" default:""`
 	Region RegionDetail  `json:"region" default:""`
 
+	// SelectedImage is the image id resolved from CommonImage for each connection candidate
+	SelectedImage map[string]string `json:"selectedImage,omitempty"`
+
+	// ResolvedRootDisk is the root disk resolved for each connection candidate
+	ResolvedRootDisk map[string]ResolvedRootDisk `json:"resolvedRootDisk,omitempty"`
+
 	// Latest system message such as error message
 	SystemMessage string `json:"systemMessage" example:"Failed because ..." default:""` // systeam-given string message
 
@@ -331,6 +455,7 @@ type SpiderVMInfo struct {
 	PrivateDNS        string
 	RootDeviceName    string // "/dev/sda1", ...
 	SSHAccessPoint    string
+	TagList           []KeyValue
 	KeyValueList      []KeyValue
 }
 
@@ -348,6 +473,9 @@ type TbSubGroupInfo struct {
 
 	VmId         []string `json:"vmId"`
 	SubGroupSize string   `json:"subGroupSize"`
+
+	// DependsOn is the list of subGroups which should be running before this subGroup is created or resumed
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 // TbVmInfo is struct to define a server instance object
@@ -377,8 +505,13 @@ type TbVmInfo struct {
 	TargetStatus string `json:"targetStatus"`
 	TargetAction string `json:"targetAction"`
 
+	// Protected blocks deletion and termination of the VM until the protection is removed
+	Protected bool `json:"protected" example:"false"`
+
 	// Montoring agent status
 	MonAgentStatus string `json:"monAgentStatus" example:"[installed, notInstalled, failed]"` // yes or no// installed, notInstalled, failed
+	// MonAgentMessage is the latest message of the monitoring agent installation (e.g., error message)
+	MonAgentMessage string `json:"monAgentMessage,omitempty"`
 
 	// NetworkAgent status
 	NetworkAgentStatus string `json:"networkAgentStatus" example:"[notInstalled, installing, installed, failed]"` // notInstalled, installing, installed, failed
@@ -388,9 +521,13 @@ type TbVmInfo struct {
 
 	// Created time
 	CreatedTime string `json:"createdTime" example:"2022-11-10 23:00:00" default:""`
+	// LastSeen is the time when the status of the VM was last fetched from the CSP
+	LastSeen string `json:"lastSeen,omitempty" example:"2024-10-01T00:00:00Z"`
 
 	Label       map[string]string `json:"label"`
 	Description string            `json:"description"`
+	// SystemLabel is for describing the VM in a keyword (e.g., "Registered from CSP resource")
+	SystemLabel string `json:"systemLabel,omitempty" example:"Registered from CSP resource"`
 
 	Region         RegionInfo `json:"region"` // AWS, ex) {us-east1, us-east1-c} or {ap-northeast-2}
 	PublicIP       string     `json:"publicIP"`
@@ -406,19 +543,35 @@ type TbVmInfo struct {
 	ConnectionConfig ConnConfig `json:"connectionConfig"`
 	SpecId           string     `json:"specId"`
 	CspSpecName      string     `json:"cspSpecName"`
-	ImageId          string     `json:"imageId"`
-	CspImageName     string     `json:"cspImageName"`
-	VNetId           string     `json:"vNetId"`
-	CspVNetId        string     `json:"cspVNetId"`
-	SubnetId         string     `json:"subnetId"`
-	CspSubnetId      string     `json:"cspSubnetId"`
-	NetworkInterface string     `json:"networkInterface"`
-	SecurityGroupIds []string   `json:"securityGroupIds"`
-	DataDiskIds      []string   `json:"dataDiskIds"`
-	SshKeyId         string     `json:"sshKeyId"`
-	CspSshKeyId      string     `json:"cspSshKeyId"`
-	VmUserName       string     `json:"vmUserName,omitempty"`
-	VmUserPassword   string     `json:"vmUserPassword,omitempty"`
+	// CostPerHour is the hourly cost of the spec (updated when the VM is resized)
+	CostPerHour  float32 `json:"costPerHour,omitempty" example:"0.0116"`
+	ImageId      string  `json:"imageId"`
+	CspImageName string  `json:"cspImageName"`
+	// OsType is the OS type of the VM detected from the image metadata (decides the transport of remote commands)
+	OsType           string   `json:"osType,omitempty" example:"linux" enums:"linux,windows"`
+	VNetId           string   `json:"vNetId"`
+	CspVNetId        string   `json:"cspVNetId"`
+	SubnetId         string   `json:"subnetId"`
+	CspSubnetId      string   `json:"cspSubnetId"`
+	NetworkInterface string   `json:"networkInterface"`
+	SecurityGroupIds []string `json:"securityGroupIds"`
+	DataDiskIds      []string `json:"dataDiskIds"`
+	SshKeyId         string   `json:"sshKeyId"`
+	CspSshKeyId      string   `json:"cspSshKeyId"`
+	VmUserName       string   `json:"vmUserName,omitempty"`
+	// VmUserPassword is encrypted in the Key-Value store and masked in responses unless revealPassword=true
+	VmUserPassword string `json:"vmUserPassword,omitempty"`
+
+	// RecoveryEvents is the history of recovery actions by the recovery policy of the MCI
+	RecoveryEvents []VmRecoveryEvent `json:"recoveryEvents,omitempty"`
+	// Events is the history of operations which changed the VM (e.g., public IP association)
+	Events []VmEvent `json:"events,omitempty"`
+	// CreateRetries is the number of creation retries of the failed VM (by the retryFailed action of the MCI)
+	CreateRetries int `json:"createRetries,omitempty"`
+	// Gpu is the GPU driver of the VM verified by nvidia-smi (set by the installGpuDriver action of the MCI)
+	Gpu *VmGpuInfo `json:"gpu,omitempty"`
+	// Software is the software of the catalog installed in the VM by name (set by the software actions of the MCI)
+	Software map[string]VmSoftwareInfo `json:"software,omitempty"`
 
 	AddtionalDetails []KeyValue `json:"addtionalDetails,omitempty"`
 }
@@ -540,6 +693,9 @@ type MciStatusInfo struct {
 	TargetStatus string          `json:"targetStatus"`
 	TargetAction string          `json:"targetAction"`
 
+	// Protected blocks deletion and termination of the MCI until the protection is removed
+	Protected bool `json:"protected" example:"false"`
+
 	// InstallMonAgent Option for CB-Dragonfly agent installation ([yes/no] default:yes)
 	InstallMonAgent string `json:"installMonAgent" example:"[yes, no]"` // yes or no
 
@@ -588,6 +744,9 @@ type TbVmStatusInfo struct {
 	TargetAction string `json:"targetAction"`
 	NativeStatus string `json:"nativeStatus"`
 
+	// Protected blocks deletion and termination of the VM until the protection is removed
+	Protected bool `json:"protected" example:"false"`
+
 	// Montoring agent status
 	MonAgentStatus string `json:"monAgentStatus" example:"[installed, notInstalled, failed]"` // yes or no// installed, notInstalled, failed
 
@@ -596,6 +755,8 @@ type TbVmStatusInfo struct {
 
 	// Created time
 	CreatedTime string `json:"createdTime" example:"2022-11-10 23:00:00" default:""`
+	// LastSeen is the time when the status of the VM was last fetched from the CSP
+	LastSeen string `json:"lastSeen,omitempty" example:"2024-10-01T00:00:00Z"`
 
 	PublicIp  string `json:"publicIp"`
 	PrivateIp string `json:"privateIp"`
@@ -629,6 +790,9 @@ const (
 
 	// AutoStatusSuspended is const for "Suspended" status.
 	AutoStatusSuspended string = "Suspended"
+
+	// AutoStatusInvalid is const for "Invalid" status (the policy has validation errors).
+	AutoStatusInvalid string = "Invalid"
 )
 
 // Action for mci automation
@@ -661,11 +825,70 @@ type AutoAction struct {
 	PlacementAlgo string    `json:"placementAlgo" example:"random"`
 }
 
+// ScalingRule is struct for the threshold-based scaling of a subGroup
+// (e.g., if avg cpu across the subGroup web > 70 for 300 seconds, scale out by 2 up to 20 VMs)
+type ScalingRule struct {
+	// SubGroupId is the subGroup to watch and scale
+	SubGroupId string `json:"subGroupId" example:"web"`
+
+	// Metric is the monitoring metric (e.g., cpu, mem)
+	Metric string `json:"metric" example:"cpu"`
+	// Aggregation of the metric across the VMs of the subGroup and over the window
+	Aggregation string `json:"aggregation" example:"avg" default:"avg" enums:"avg,max,min"`
+	// WindowSec is the period the aggregated metric should breach the threshold
+	WindowSec int `json:"windowSec" example:"300"`
+	// Operator compares the aggregated metric (left) with the threshold (right)
+	Operator  string  `json:"operator" example:">" enums:"<,<=,>,>="`
+	Threshold float64 `json:"threshold" example:"70"`
+
+	// Action is taken if the condition holds
+	Action string `json:"action" example:"ScaleOut" enums:"ScaleOut,ScaleIn"`
+	// Step is the number of VMs to add or remove at once
+	Step int `json:"step" example:"2" default:"1"`
+	// MinSize and MaxSize bound the number of VMs of the subGroup
+	MinSize int `json:"minSize" example:"1"`
+	MaxSize int `json:"maxSize" example:"20"`
+	// CooldownSec is the period after an action where the rule is not evaluated (to prevent flapping)
+	CooldownSec int `json:"cooldownSec" example:"300"`
+}
+
 // Policy is struct for MCI auto-control Policy request that includes AutoCondition, AutoAction, Status.
 type Policy struct {
 	AutoCondition AutoCondition `json:"autoCondition"`
 	AutoAction    AutoAction    `json:"autoAction"`
 	Status        string        `json:"status"`
+
+	// ScalingRule (if given) scales the subGroup by the threshold instead of AutoCondition and AutoAction
+	ScalingRule *ScalingRule `json:"scalingRule,omitempty"`
+	// Disabled policies are not evaluated
+	Disabled bool `json:"disabled,omitempty"`
+	// ValidationErrors of the policy (invalid policies are not evaluated)
+	ValidationErrors []string `json:"validationErrors,omitempty"`
+}
+
+// MciPolicyHistoryItem is struct for an evaluation (and the action) of an MCI policy
+type MciPolicyHistoryItem struct {
+	Time        time.Time `json:"time"`
+	PolicyIndex int       `json:"policyIndex"`
+	SubGroupId  string    `json:"subGroupId,omitempty"`
+
+	// Value is the metric aggregated over the window (if the window is covered by the samples)
+	Value float64 `json:"value"`
+	// Samples is the number of samples in the window
+	Samples  int  `json:"samples"`
+	Detected bool `json:"detected"`
+
+	// Action is the taken action (ScaleOut, ScaleIn) and Count is the number of VMs added or removed
+	Action  string `json:"action,omitempty"`
+	Count   int    `json:"count,omitempty"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// MciPolicyHistory is struct for the evaluation history of the policies of an MCI (latest last)
+type MciPolicyHistory struct {
+	MciId string                 `json:"mciId"`
+	Items []MciPolicyHistoryItem `json:"items"`
 }
 
 // MciPolicyInfo is struct for MCI auto-control Policy object.
@@ -687,10 +910,31 @@ type MciPolicyReq struct {
 // SshDefaultUserName is array for temporal constants
 var SshDefaultUserName = []string{"cb-user", "ubuntu", "root", "ec2-user"}
 
+// OS types of VMs
+const (
+	VmOsTypeLinux   string = "linux"
+	VmOsTypeWindows string = "windows"
+)
+
+// Shells for remote commands
+const (
+	ShellBash       string = "bash"
+	ShellPowershell string = "powershell"
+	ShellCmd        string = "cmd"
+)
+
+// Transports for remote commands and file transfer
+const (
+	TransportSsh   string = "ssh"
+	TransportWinrm string = "winrm"
+)
+
 // MciCmdReq is struct for remote command
 type MciCmdReq struct {
 	UserName string   `json:"userName" example:"cb-user" default:""`
 	Command  []string `json:"command" validate:"required" example:"client_ip=$(echo $SSH_CLIENT | awk '{print $1}'); echo SSH client IP is: $client_ip"`
+	// Shell is the hint for the shell of the commands (default: bash for Linux VMs, powershell for Windows VMs)
+	Shell string `json:"shell,omitempty" example:"bash" enums:"bash,powershell,cmd"`
 }
 
 // SshCmdResult is struct for SshCmd Result
@@ -702,6 +946,9 @@ type SshCmdResult struct { // Tumblebug
 	Stdout  map[int]string `json:"stdout"`
 	Stderr  map[int]string `json:"stderr"`
 	Err     error          `json:"err"`
+	// Transport is the transport used for the VM (ssh, winrm) and Shell is the shell of the commands
+	Transport string `json:"transport,omitempty" example:"ssh"`
+	Shell     string `json:"shell,omitempty" example:"bash"`
 }
 
 // MciSshCmdResult is struct for Set of SshCmd Results in terms of MCI
@@ -735,7 +982,7 @@ type FilterInfo struct {
 
 // FilterCondition is struct for .
 type FilterCondition struct {
-	Metric    string      `json:"metric" example:"vCPU" enums:"vCPU,memoryGiB,costPerHour"`
+	Metric    string      `json:"metric" example:"vCPU" enums:"vCPU,memoryGiB,costPerHour,spotCostPerHour,acceleratorModel,acceleratorCount,acceleratorMemoryGB,architecture"`
 	Condition []Operation `json:"condition"`
 }
 
@@ -753,14 +1000,14 @@ type PriorityInfo struct {
 // FilterCondition is struct for .
 type PriorityCondition struct {
 	Metric    string            `json:"metric" example:"location" enums:"location,cost,random,performance,latency"`
-	Weight    string            `json:"weight" example:"0.3" enums:"0.1,0.2,..."`
+	Weight    string            `json:"weight" example:"0.3" enums:"0.1,0.2,..."` // used to combine scores of multiple priority policies (default 1)
 	Parameter []ParameterKeyVal `json:"parameter,omitempty"`
 }
 
 // Operation is struct for .
 type ParameterKeyVal struct {
-	Key string   `json:"key" example:"coordinateClose" enums:"coordinateClose,coordinateWithin,coordinateFair"` // coordinate
-	Val []string `json:"val" example:"44.146838/-116.411403"`                                                   // ["Latitude,Longitude","12,543",..,"31,433"]
+	Key string   `json:"key" example:"coordinateClose" enums:"coordinateClose,coordinateWithin,coordinateFair,priceType"` // coordinate, priceType (spot or onDemand for cost)
+	Val []string `json:"val" example:"44.146838/-116.411403"`                                                             // ["Latitude,Longitude","12,543",..,"31,433"]
 }
 
 // SpecBenchmarkInfo is struct for SpecBenchmarkInfo
@@ -794,6 +1041,163 @@ type BenchmarkInfoArray struct {
 	ResultArray []BenchmarkInfo `json:"resultarray"`
 }
 
+// Status of a cell (VM pair) in the latency benchmark matrix
+const (
+	LatencyCellPending string = "pending"
+	LatencyCellDone    string = "done"
+	LatencyCellFailed  string = "failed"
+)
+
+// LatencyCell is the round-trip time from the source VM to the target VM
+type LatencyCell struct {
+	SourceVmId string  `json:"sourceVmId" example:"g1-1"`
+	TargetVmId string  `json:"targetVmId" example:"g2-1"`
+	Status     string  `json:"status" example:"done" enums:"pending,done,failed"`
+	RttMs      float64 `json:"rttMs,omitempty" example:"32.5"`
+	// Reason is the cause of the failure (e.g., timeout, no agent)
+	Reason string `json:"reason,omitempty" example:"timeout"`
+}
+
+// LatencyRegionSummary is the summary of round-trip times between VMs of a region pair
+type LatencyRegionSummary struct {
+	SourceRegion string  `json:"sourceRegion" example:"aws/ap-northeast-2"`
+	TargetRegion string  `json:"targetRegion" example:"gcp/us-east1"`
+	Samples      int     `json:"samples" example:"4"`
+	MinMs        float64 `json:"minMs" example:"180.2"`
+	AvgMs        float64 `json:"avgMs" example:"182.7"`
+	MaxMs        float64 `json:"maxMs" example:"190.1"`
+}
+
+// LatencyBenchmarkRun is an async run of the pairwise latency benchmark of an MCI
+type LatencyBenchmarkRun struct {
+	RunId string `json:"runId" example:"latency-cs6c2ljuelr8l5l7m2n0"`
+	// JobId is the async job which runs the measurements (GET /jobs/{jobId})
+	JobId     string    `json:"jobId" example:"job-cs6c2ljuelr8l5l7m2n0"`
+	NsId      string    `json:"nsId" example:"default"`
+	MciId     string    `json:"mciId" example:"mci01"`
+	Status    string    `json:"status" example:"Running"`
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt,omitempty"`
+
+	// VmRegions is the region ({provider}/{region}) of each VM
+	VmRegions map[string]string `json:"vmRegions"`
+	Total     int               `json:"total" example:"870"`
+	Done      int               `json:"done" example:"600"`
+	Failed    int               `json:"failed" example:"2"`
+	Cells     []LatencyCell     `json:"cells"`
+	// Summary is available when the run is completed
+	Summary []LatencyRegionSummary `json:"summary,omitempty"`
+}
+
+// Protocols of the probes of the network test
+const (
+	NetworkTestTcp  string = "tcp"
+	NetworkTestUdp  string = "udp"
+	NetworkTestHttp string = "http"
+	NetworkTestIcmp string = "icmp"
+)
+
+// Status of a cell (probe of a VM pair) in the network test matrix
+const (
+	NetworkTestCellPending     string = "pending"
+	NetworkTestCellReachable   string = "reachable"
+	NetworkTestCellUnreachable string = "unreachable"
+	NetworkTestCellFailed      string = "failed"
+)
+
+// NetworkTestPort is a port to probe in the network test
+type NetworkTestPort struct {
+	// Protocol is the probe: tcp (nc or bash /dev/tcp), udp (nc -u, best effort), http (curl), icmp (ping)
+	Protocol string `json:"protocol" example:"tcp" enums:"tcp,udp,http,icmp" default:"tcp"`
+	// Port is ignored for icmp
+	Port int `json:"port" example:"22"`
+}
+
+// NetworkTestReq is the request of the network reachability test between VMs of an MCI
+type NetworkTestReq struct {
+	// Ports to probe (default: tcp/22)
+	Ports []NetworkTestPort `json:"ports"`
+	// SourceSubGroupId and TargetSubGroupId limit the source and target VMs (default: all VMs)
+	SourceSubGroupId string `json:"sourceSubGroupId,omitempty" example:"g1"`
+	TargetSubGroupId string `json:"targetSubGroupId,omitempty" example:"g2"`
+	// TargetIpType is the IPs of the target VMs to probe
+	TargetIpType string `json:"targetIpType,omitempty" example:"both" enums:"private,public,both" default:"both"`
+	// TimeoutSec is the timeout of each probe (default 3, max 30)
+	TimeoutSec int `json:"timeoutSec,omitempty" example:"3"`
+	// Concurrency is the number of source VMs probing in parallel (default 10, max 50)
+	Concurrency int `json:"concurrency,omitempty" example:"10"`
+	// UserName is the SSH user of the source VMs (default: the user of each VM)
+	UserName string `json:"userName,omitempty" example:"cb-user"`
+}
+
+// NetworkTestCell is the result of a probe from the source VM to an IP of the target VM
+type NetworkTestCell struct {
+	SourceVmId string `json:"sourceVmId" example:"g1-1"`
+	TargetVmId string `json:"targetVmId" example:"g2-1"`
+	TargetIp   string `json:"targetIp" example:"10.0.1.5"`
+	IpType     string `json:"ipType" example:"private" enums:"private,public"`
+	Protocol   string `json:"protocol" example:"tcp"`
+	Port       int    `json:"port,omitempty" example:"22"`
+	Status     string `json:"status" example:"reachable" enums:"pending,reachable,unreachable,failed"`
+	// LatencyMs is the time of the probe (connection time for tcp, response time for http and icmp)
+	LatencyMs float64 `json:"latencyMs,omitempty" example:"12"`
+	// Reason is the cause of the failure (e.g., timeout, connection refused, probe tool not found)
+	Reason string `json:"reason,omitempty" example:"timeout"`
+}
+
+// NetworkTestRun is an async run of the network reachability test of an MCI (kept for later comparison)
+type NetworkTestRun struct {
+	RunId string `json:"runId" example:"nettest-cs6c2ljuelr8l5l7m2n0"`
+	// JobId is the async job which runs the probes (GET /jobs/{jobId})
+	JobId     string         `json:"jobId" example:"job-cs6c2ljuelr8l5l7m2n0"`
+	NsId      string         `json:"nsId" example:"default"`
+	MciId     string         `json:"mciId" example:"mci01"`
+	Status    string         `json:"status" example:"Running"`
+	StartedAt time.Time      `json:"startedAt"`
+	EndedAt   time.Time      `json:"endedAt,omitempty"`
+	Request   NetworkTestReq `json:"request"`
+	// RecheckOf is the run whose failed probes are re-run by this run (after the suggested rules are applied)
+	RecheckOf   string `json:"recheckOf,omitempty" example:"nettest-cs6c2ljuelr8l5l7m2n0"`
+	Total       int    `json:"total" example:"48"`
+	Reachable   int    `json:"reachable" example:"40"`
+	Unreachable int    `json:"unreachable" example:"6"`
+	Failed      int    `json:"failed" example:"2"`
+	// Cells are omitted in the list of runs
+	Cells []NetworkTestCell `json:"cells,omitempty"`
+}
+
+// NetworkTestRunList is the list of network test runs of an MCI (newest first)
+type NetworkTestRunList struct {
+	Runs []NetworkTestRun `json:"runs"`
+}
+
+// SecurityGroupRuleSuggestion is the set of ingress rules proposed for a security group from the failed probes
+type SecurityGroupRuleSuggestion struct {
+	SecurityGroupId string               `json:"securityGroupId" example:"aws-ap-northeast-2"`
+	ConnectionName  string               `json:"connectionName" example:"aws-ap-northeast-2"`
+	Rules           []TbFirewallRuleInfo `json:"rules"`
+	// Probes are the failed probes (source -> target ip/protocol/port) fixed by the rules
+	Probes []string `json:"probes"`
+	// Applied is true if the rules are pushed to the security group (apply=true)
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NetworkTestSkippedProbe is a failed probe for which no rule is suggested
+type NetworkTestSkippedProbe struct {
+	Probe  string `json:"probe" example:"g1-1 -> g2-1 10.0.1.5 tcp/22"`
+	Reason string `json:"reason" example:"already allowed by the security group aws-ap-northeast-2"`
+}
+
+// NetworkTestRuleSuggestionResult is the result of the security group rule suggestion from a network test run
+type NetworkTestRuleSuggestionResult struct {
+	RunId       string                        `json:"runId" example:"nettest-cs6c2ljuelr8l5l7m2n0"`
+	Suggestions []SecurityGroupRuleSuggestion `json:"suggestions"`
+	Skipped     []NetworkTestSkippedProbe     `json:"skipped"`
+	// Recheck is the run which re-runs the probes fixed by the applied rules (apply=true)
+	Recheck *NetworkTestRun `json:"recheck,omitempty"`
+}
+
 // BenchmarkReq is struct for BenchmarkReq
 type BenchmarkReq struct {
 	Host string `json:"host"`
@@ -819,4 +1223,7 @@ type AgentInstallContent struct {
 	VmId   string `json:"vmId"`
 	VmIp   string `json:"vmIp"`
 	Result string `json:"result"`
+	// Status is the monitoring agent status of the VM after the request
+	Status string `json:"status" example:"installed"`
+	Error  string `json:"error,omitempty"`
 }
@@ -14,6 +14,21 @@ limitations under the License.
 // Package model is to handle object of CB-Tumblebug
 package model
 
+// Status of monitoring agent (CB-Dragonfly agent) in a VM
+const (
+	MonAgentNotInstalled string = "notInstalled"
+	MonAgentInstalling   string = "installing"
+	MonAgentInstalled    string = "installed"
+	MonAgentFailed       string = "failed"
+	MonAgentUninstalling string = "uninstalling"
+)
+
+// Options of monitoring agent installation
+const (
+	// MonAgentOptionRetryFailed installs the agent only to the VMs whose installation failed
+	MonAgentOptionRetryFailed string = "retryFailed"
+)
+
 const (
 	MonMetricAll     string = "all"
 	MonMetricCpu     string = "cpu"
@@ -64,3 +79,67 @@ type DfAgentInstallReq struct {
 	ServiceType string `json:"service_type"`
 	Port        string `json:"port"`
 }
+
+// MonAgentVmStatus is the monitoring agent status of a VM
+type MonAgentVmStatus struct {
+	VmId       string `json:"vmId" example:"g1-1"`
+	SubGroupId string `json:"subGroupId" example:"g1"`
+	Status     string `json:"status" example:"installed" enums:"notInstalled,installing,installed,failed,uninstalling"`
+	Message    string `json:"message,omitempty"`
+}
+
+// MonAgentStatusResponse is the monitoring agent status of the VMs in an MCI
+type MonAgentStatusResponse struct {
+	NsId  string `json:"nsId" example:"default"`
+	MciId string `json:"mciId" example:"mci01"`
+	// Summary is the number of VMs for each status
+	Summary map[string]int     `json:"summary"`
+	Vm      []MonAgentVmStatus `json:"vm"`
+}
+
+// Aggregate functions of monitoring data
+const (
+	MonAggregateAvg string = "avg"
+	MonAggregateMax string = "max"
+	MonAggregateP95 string = "p95"
+)
+
+// MonVmAggregate is the aggregate of the metric series of a VM
+type MonVmAggregate struct {
+	VmId       string  `json:"vmId" example:"g1-1"`
+	SubGroupId string  `json:"subGroupId" example:"g1"`
+	Value      float64 `json:"value" example:"42.5"`
+	Samples    int     `json:"samples" example:"10"`
+	// Reported is false if the VM failed to report the metric (see error)
+	Reported bool   `json:"reported"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MonSubGroupAggregate is the aggregate of the metric series of the VMs in a subGroup
+type MonSubGroupAggregate struct {
+	SubGroupId  string  `json:"subGroupId" example:"g1"`
+	Value       float64 `json:"value" example:"42.5"`
+	ReportedVms int     `json:"reportedVms" example:"2"`
+	TotalVms    int     `json:"totalVms" example:"3"`
+}
+
+// MonAggregateResponse is the aggregate of a metric across an MCI (per subGroup and MCI-wide)
+type MonAggregateResponse struct {
+	NsId   string `json:"nsId" example:"default"`
+	MciId  string `json:"mciId" example:"mci01"`
+	Metric string `json:"metric" example:"cpu"`
+	Fn     string `json:"fn" example:"avg" enums:"avg,max,p95"`
+	Window string `json:"window" example:"5m"`
+	// Value is the MCI-wide aggregate over the samples of all reported VMs
+	Value       float64                `json:"value" example:"42.5"`
+	ReportedVms int                    `json:"reportedVms" example:"2"`
+	TotalVms    int                    `json:"totalVms" example:"3"`
+	SubGroups   []MonSubGroupAggregate `json:"subGroups"`
+	Vm          []MonVmAggregate       `json:"vm"`
+
+	// Threshold is given by the request; Exceeded is true if the MCI-wide aggregate is over the threshold
+	Threshold *float64 `json:"threshold,omitempty" example:"80"`
+	Exceeded  bool     `json:"exceeded"`
+	// ExceededVms are the VMs whose aggregate is over the threshold
+	ExceededVms []string `json:"exceededVms,omitempty"`
+}
@@ -14,9 +14,19 @@ limitations under the License.
 // Package model is to handle object of CB-Tumblebug
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type NsReq struct {
 	Name        string `json:"name" example:"default"`
 	Description string `json:"description" example:"Description for this namespace"`
+
+	// AllowedConnections and AllowedProviders restrict the connections usable in the namespace (empty: unrestricted).
+	// A connection is allowed if it is listed or its provider is listed.
+	AllowedConnections []string `json:"allowedConnections,omitempty" example:"aws-ap-northeast-2"`
+	AllowedProviders   []string `json:"allowedProviders,omitempty" example:"aws"`
 }
 
 // swagger:response NsInfo
@@ -33,4 +43,173 @@ type NsInfo struct {
 	Name string `json:"name" example:"default"`
 
 	Description string `json:"description" example:"Description for this namespace"`
+
+	// AllowedConnections and AllowedProviders restrict the connections usable in the namespace (empty: unrestricted)
+	AllowedConnections []string `json:"allowedConnections,omitempty" example:"aws-ap-northeast-2"`
+	AllowedProviders   []string `json:"allowedProviders,omitempty" example:"aws"`
+}
+
+// Quota dimensions of a namespace
+const (
+	QuotaDimensionVm         string = "vm"
+	QuotaDimensionVCpu       string = "vCPU"
+	QuotaDimensionVNet       string = "vNet"
+	QuotaDimensionDataDiskGB string = "dataDiskGB"
+)
+
+// NsQuota is struct for the resource limits of a namespace (0 means unlimited)
+type NsQuota struct {
+	// MaxVms is the maximum number of VMs (including K8s nodes)
+	MaxVms int `json:"maxVms" example:"100" default:"0"`
+	// MaxVCpus is the maximum number of vCPUs in total
+	MaxVCpus int `json:"maxVCpus" example:"400" default:"0"`
+	// MaxVNets is the maximum number of vNets
+	MaxVNets int `json:"maxVNets" example:"10" default:"0"`
+	// MaxDataDiskGB is the maximum size of disks in total (GB): data disks and root disks of the VMs of MCIs
+	MaxDataDiskGB int `json:"maxDataDiskGB" example:"10000" default:"0"`
+}
+
+// NsQuotaUsage is struct for the resource consumption of a namespace
+type NsQuotaUsage struct {
+	Vms        int `json:"vms" example:"12"`
+	VCpus      int `json:"vCpus" example:"48"`
+	VNets      int `json:"vNets" example:"2"`
+	DataDiskGB int `json:"dataDiskGB" example:"500"`
+}
+
+// NsQuotaUsageInfo is struct for the quota and the current consumption of a namespace
+type NsQuotaUsageInfo struct {
+	NsId  string       `json:"nsId" example:"default"`
+	Quota NsQuota      `json:"quota"`
+	Usage NsQuotaUsage `json:"usage"`
+}
+
+// NsNamingPolicy is struct for the naming convention of the objects created in a namespace
+// (vNet, securityGroup, sshKey, MCI and K8sCluster). Empty fields are not enforced.
+// The policy is checked only at creation, so existing objects are not affected by a change of the policy.
+type NsNamingPolicy struct {
+	// RequiredPrefix is the prefix which every name must start with (e.g., a team prefix)
+	RequiredPrefix string `json:"requiredPrefix,omitempty" example:"team-a-"`
+	// MaxLength is the maximum length of a name (0 means no limit)
+	MaxLength int `json:"maxLength,omitempty" example:"30"`
+	// AllowPatterns are regular expressions; a name must match at least one of them
+	AllowPatterns []string `json:"allowPatterns,omitempty" example:"^[a-z0-9-]+$"`
+	// DenyPatterns are regular expressions; a name must not match any of them (e.g., forbidden words)
+	DenyPatterns []string `json:"denyPatterns,omitempty" example:"test|tmp"`
+}
+
+// NsNamingValidateReq is struct for the request to check names against the naming policy of a namespace
+type NsNamingValidateReq struct {
+	// ResourceType is the type of the objects to be named (vNet, securityGroup, sshKey, mci, k8sCluster)
+	ResourceType string   `json:"resourceType" example:"vNet"`
+	Names        []string `json:"names" validate:"required" example:"team-a-vnet01"`
+}
+
+// NsNamingValidation is struct for the result of checking a name against the naming policy
+type NsNamingValidation struct {
+	Name  string `json:"name" example:"team-a-vnet01"`
+	Valid bool   `json:"valid" example:"true"`
+	// Violation is the violated element of the policy (requiredPrefix, maxLength, allowPatterns, denyPatterns[i])
+	Violation string `json:"violation,omitempty" example:"requiredPrefix"`
+	Message   string `json:"message,omitempty"`
+}
+
+// NsNamingValidateResult is struct for the result of checking names against the naming policy of a namespace
+type NsNamingValidateResult struct {
+	NsId    string               `json:"nsId" example:"default"`
+	Policy  NsNamingPolicy       `json:"policy"`
+	Results []NsNamingValidation `json:"results"`
+}
+
+// Placeholders of NsResourceDefaults.SshKeyNameFormat
+const (
+	ResourceDefaultsNsIdPlaceholder       string = "{nsId}"
+	ResourceDefaultsConnectionPlaceholder string = "{connectionName}"
+)
+
+// NsResourceDefaults is struct for the defaults of shared resources (vNet, securityGroup, sshKey)
+// created per connection by loadSharedResource and dynamic provisioning. Empty fields follow the global defaults.
+type NsResourceDefaults struct {
+	// CidrSupernet is the address space where the vNet of each connection is allocated
+	CidrSupernet string `json:"cidrSupernet,omitempty" example:"10.0.0.0/8"`
+	// VNetPrefixLength is the prefix length of the vNet of each connection (2 subnets of VNetPrefixLength+2 are created)
+	VNetPrefixLength int `json:"vNetPrefixLength,omitempty" example:"16"`
+	// SecurityGroupRules are the firewall rules of the default securityGroup
+	SecurityGroupRules []TbFirewallRuleInfo `json:"securityGroupRules,omitempty"`
+	// SshKeyNameFormat is the naming convention of the default sshKey ({nsId} and {connectionName} are replaced)
+	SshKeyNameFormat string `json:"sshKeyNameFormat,omitempty" example:"{nsId}-shared-{connectionName}"`
+}
+
+// NsResourceDefaultsInfo is struct for the shared resource defaults of a namespace
+type NsResourceDefaultsInfo struct {
+	NsId string `json:"nsId" example:"default"`
+	// Override is the defaults set for the namespace
+	Override NsResourceDefaults `json:"override"`
+	// Effective is the namespace override merged over the global defaults
+	Effective NsResourceDefaults `json:"effective"`
+}
+
+// GlobalResourceDefaults returns the global defaults of shared resources
+func GlobalResourceDefaults() NsResourceDefaults {
+	return NsResourceDefaults{
+		CidrSupernet:     "10.0.0.0/8",
+		VNetPrefixLength: 16,
+		// open all firewall for default securityGroup
+		SecurityGroupRules: []TbFirewallRuleInfo{
+			{FromPort: "1", ToPort: "65535", IPProtocol: "tcp", Direction: "inbound", CIDR: "0.0.0.0/0"},
+			{FromPort: "1", ToPort: "65535", IPProtocol: "udp", Direction: "inbound", CIDR: "0.0.0.0/0"},
+			{FromPort: "-1", ToPort: "-1", IPProtocol: "icmp", Direction: "inbound", CIDR: "0.0.0.0/0"},
+		},
+		SshKeyNameFormat: ResourceDefaultsNsIdPlaceholder + StrSharedResourceName + ResourceDefaultsConnectionPlaceholder,
+	}
+}
+
+// Modes of id-collision handling in namespace import
+const (
+	NsImportModeFail   string = "fail"   // fail if any object of the namespace already exists
+	NsImportModeSkip   string = "skip"   // keep the existing objects and import the others
+	NsImportModeRename string = "rename" // import the colliding objects with new ids (e.g., vnet01-1)
+)
+
+// NsImportUnverified is the value of the label (sys.imported) of imported objects which reference CSP resources.
+// A reconcile is expected to verify the CSP resources still exist.
+const NsImportUnverified = "unverified"
+
+// NsExportKv is a Key-Value record in a namespace export (the key is relative to /ns/{nsId})
+type NsExportKv struct {
+	Key   string          `json:"key" example:"/resources/vNet/vnet01"`
+	Value json.RawMessage `json:"value" swaggertype:"object"`
+}
+
+// NsExportDoc is the document of all metadata of a namespace (for backup and restore on another instance)
+type NsExportDoc struct {
+	Version    string    `json:"version" example:"v1"`
+	ExportedAt time.Time `json:"exportedAt"`
+	NsId       string    `json:"nsId" example:"default"`
+	// SecretsIncluded is false if secrets (e.g., private keys of sshKeys) are excluded
+	SecretsIncluded bool `json:"secretsIncluded"`
+
+	Namespace json.RawMessage `json:"namespace" swaggertype:"object"`
+	// Objects are the Key-Value records under the namespace (resources, MCIs, policies, ...)
+	Objects []NsExportKv `json:"objects"`
+	// Labels are the label records of the namespace and its objects (the key is /label/{labelType}/{uid})
+	Labels []NsExportKv `json:"labels"`
+	// Specs and Images are the specs and images of the namespace (kept in the database)
+	Specs  []TbSpecInfo  `json:"specs"`
+	Images []TbImageInfo `json:"images"`
+}
+
+// NsExportReq is the request to export a namespace with secrets
+type NsExportReq struct {
+	// IncludeSecrets includes the secrets (e.g., private keys of sshKeys) in plaintext
+	IncludeSecrets bool `json:"includeSecrets" example:"true"`
+}
+
+// NsImportResult is the result of a namespace import
+type NsImportResult struct {
+	NsId     string   `json:"nsId" example:"default"`
+	Imported int      `json:"imported" example:"42"`
+	Skipped  []string `json:"skipped,omitempty"`
+	// Unverified are the keys of imported objects which reference CSP resources (labeled sys.imported=unverified)
+	Unverified []string `json:"unverified,omitempty"`
 }
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Types of events in the timeline of a namespace
+const (
+	NsEventCreated         string = "created"
+	NsEventDeleted         string = "deleted"
+	NsEventStatusChanged   string = "statusChanged"
+	NsEventPolicyAction    string = "policyAction"
+	NsEventCommandExecuted string = "commandExecuted"
+)
+
+// NsEventActorSystem is the actor of events caused by background changes (e.g., status reconciliation, policies)
+const NsEventActorSystem = "system"
+
+// NsEvent is struct for an event in the timeline of a namespace
+type NsEvent struct {
+	// Id is unique identifier for the event (used as the last segment of the key, sorted by time)
+	Id   string    `json:"id" example:"01727740800000000000-cs6c2ljuelr8l5l7m2n0"`
+	Time time.Time `json:"time" example:"2024-10-01T00:00:00Z"`
+	// Actor is the user of the API call which caused the event (system for background changes)
+	Actor string `json:"actor" example:"default"`
+	// RequestId is the X-Request-Id of the API call which caused the event (empty for background changes)
+	RequestId    string `json:"requestId,omitempty" example:"1727740800000000000"`
+	ResourceType string `json:"resourceType" example:"mci"`
+	// ResourceId is the ID of the resource (mciId/vmId for a VM)
+	ResourceId string `json:"resourceId" example:"mci01"`
+	EventType  string `json:"eventType" example:"created"`
+	Message    string `json:"message,omitempty" example:"MCI is created (Running:3 (R:3/3))"`
+}
+
+// NsEventList is struct for a page of events in the timeline of a namespace
+type NsEventList struct {
+	// Total is the number of events matched by the filter
+	Total int `json:"total" example:"1"`
+	// Page is the page number of the result
+	Page int `json:"page,omitempty" example:"1"`
+	// PageSize is the page size of the result
+	PageSize int `json:"pageSize,omitempty" example:"100"`
+	// Events are the events in the page (newest first)
+	Events []NsEvent `json:"events"`
+}
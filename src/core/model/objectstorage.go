@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import (
+	"time"
+)
+
+// SpiderObjectStorageReqInfoWrapper is a wrapper struct to create JSON body of 'Create bucket request'
+type SpiderObjectStorageReqInfoWrapper struct {
+	ConnectionName string
+	ReqInfo        SpiderObjectStorageInfo
+}
+
+// SpiderObjectStorageInfo is a struct to create JSON body of 'Create bucket request' of CB-Spider
+type SpiderObjectStorageInfo struct {
+	// Fields for request
+	Name string
+
+	// Fields for both request and response
+	Versioning        bool
+	PublicAccessBlock bool
+
+	// Fields for response
+	IId          IID    // {NameId, SystemId}
+	Endpoint     string // e.g., https://{bucket}.s3.{region}.amazonaws.com
+	CreatedTime  time.Time
+	KeyValueList []KeyValue
+}
+
+// SpiderObjectStorageOptionReqWrapper is a wrapper struct to create JSON body of 'Update bucket options request'
+type SpiderObjectStorageOptionReqWrapper struct {
+	ConnectionName string
+	ReqInfo        struct {
+		Versioning        bool
+		PublicAccessBlock bool
+	}
+}
+
+// TbObjectStorageReq is a struct to handle 'Create objectStorage' request toward CB-Tumblebug.
+type TbObjectStorageReq struct {
+	Name           string `json:"name" validate:"required" example:"aws-ap-northeast-2-bucket"`
+	ConnectionName string `json:"connectionName" validate:"required" example:"aws-ap-northeast-2"`
+	// Versioning keeps the versions of objects (default: false)
+	Versioning bool `json:"versioning,omitempty" example:"false"`
+	// PublicAccessBlock blocks the public access to the bucket (default: true)
+	PublicAccessBlock *bool  `json:"publicAccessBlock,omitempty" example:"true"`
+	Description       string `json:"description,omitempty"`
+}
+
+// TbObjectStorageUpdateReq is a struct to handle 'Update objectStorage options' request toward CB-Tumblebug.
+type TbObjectStorageUpdateReq struct {
+	Versioning        *bool  `json:"versioning,omitempty" example:"true"`
+	PublicAccessBlock *bool  `json:"publicAccessBlock,omitempty" example:"true"`
+	Description       string `json:"description,omitempty"`
+}
+
+// TbObjectStorageInfo is a struct that represents TB objectStorage (bucket) object.
+type TbObjectStorageInfo struct {
+	// ResourceType is the type of the resource
+	ResourceType string `json:"resourceType"`
+
+	// Id is unique identifier for the object
+	Id string `json:"id" example:"aws-ap-northeast-2-bucket"`
+	// Uid is universally unique identifier for the object, used for labelSelector
+	Uid string `json:"uid,omitempty" example:"wef12awefadf1221edcf"`
+	// CspResourceName is name assigned to the CSP resource (bucket name). This name is internally used to handle the resource.
+	CspResourceName string `json:"cspResourceName,omitempty" example:"wef12awefadf1221edcf"`
+	// CspResourceId is resource identifier managed by CSP
+	CspResourceId string `json:"cspResourceId,omitempty" example:"wef12awefadf1221edcf"`
+
+	// Name is human-readable string to represent the object
+	Name                 string     `json:"name" example:"aws-ap-northeast-2-bucket"`
+	ConnectionName       string     `json:"connectionName,omitempty" example:"aws-ap-northeast-2"`
+	Versioning           bool       `json:"versioning" example:"false"`
+	PublicAccessBlock    bool       `json:"publicAccessBlock" example:"true"`
+	Endpoint             string     `json:"endpoint,omitempty" example:"https://wef12awefadf1221edcf.s3.ap-northeast-2.amazonaws.com"`
+	AssociatedObjectList []string   `json:"associatedObjectList"`
+	CreatedTime          time.Time  `json:"createdTime,omitempty" example:"2022-10-12T05:09:51.05Z"`
+	KeyValueList         []KeyValue `json:"keyValueList,omitempty"`
+	Description          string     `json:"description,omitempty"`
+
+	// Latest system message such as error message
+	SystemMessage string `json:"systemMessage" example:"Failed because ..." default:""` // systeam-given string message
+
+	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
+	SystemLabel string `json:"systemLabel,omitempty" example:"Managed by CB-Tumblebug" default:""`
+}
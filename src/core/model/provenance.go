@@ -0,0 +1,43 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+// Ways an object is created (CreatedVia of Provenance)
+const (
+	// CreatedViaManual is for an object created by its own API (e.g., POST /ns/{nsId}/resources/vNet)
+	CreatedViaManual string = "manual"
+	// CreatedViaMciDynamic is for an MCI created by the dynamic provisioning
+	CreatedViaMciDynamic string = "mciDynamic"
+	// CreatedViaK8sClusterDynamic is for a K8sCluster created by the dynamic provisioning
+	CreatedViaK8sClusterDynamic string = "k8sClusterDynamic"
+	// CreatedViaSharedResource is for a default shared resource (vNet, sshKey, securityGroup) created on demand
+	CreatedViaSharedResource string = "sharedResource"
+	// CreatedViaRegistered is for an object registered from an existing CSP resource
+	CreatedViaRegistered string = "registered"
+	// CreatedViaApply is for an object created by the declarative apply of a namespace
+	CreatedViaApply string = "apply"
+	// CreatedViaLoad is for an object created from the assets or a loaded file
+	CreatedViaLoad string = "load"
+)
+
+// Provenance records which request created the object (see the audit log for the request)
+type Provenance struct {
+	// CreatedByRequestId is the X-Request-Id of the request which created the object
+	CreatedByRequestId string `json:"createdByRequestId,omitempty" example:"1727740800000000000"`
+	// CreatedByUser is the authenticated user of the request (JWT user name, basic auth username or client certificate CN)
+	CreatedByUser string `json:"createdByUser,omitempty" example:"default"`
+	// CreatedVia is the way the object is created (manual, mciDynamic, k8sClusterDynamic, sharedResource, registered, apply, load)
+	CreatedVia string `json:"createdVia,omitempty" example:"manual"`
+}
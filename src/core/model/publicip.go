@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Types of VM events
+const (
+	VmEventPublicIpAttached string = "publicIpAttached"
+	VmEventPublicIpDetached string = "publicIpDetached"
+	VmEventResized          string = "resized"
+	VmEventCreateRetried    string = "createRetried"
+)
+
+// VmEvent is a record of an operation which changed the VM
+type VmEvent struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type" example:"publicIpAttached"`
+	// Result is Succeeded or Failed
+	Result  string `json:"result" example:"Succeeded"`
+	Message string `json:"message,omitempty"`
+}
+
+// SpiderPublicIpReqInfoWrapper is struct for a request to associate (or release) a public IP of a VM via CB-Spider
+type SpiderPublicIpReqInfoWrapper struct {
+	ConnectionName string
+	ReqInfo        SpiderPublicIpReqInfo
+}
+
+// SpiderPublicIpReqInfo is struct for the public IP of a VM in CB-Spider
+type SpiderPublicIpReqInfo struct {
+	// PublicIP is an allocated address to reuse (a new address is allocated if empty)
+	PublicIP string `json:"PublicIP,omitempty"`
+	// Release is to release the address when it is disassociated from the VM
+	Release bool `json:"Release,omitempty"`
+}
+
+// SpiderPublicIpInfo is struct for the result of public IP association of CB-Spider
+type SpiderPublicIpInfo struct {
+	PublicIP  string `json:"PublicIP"`
+	PublicDNS string `json:"PublicDNS"`
+}
+
+// TbVmPublicIpReq is struct for a request to attach a public IP to a VM
+type TbVmPublicIpReq struct {
+	// PublicIpAddress is an allocated (elastic/static) address to reuse. A new address is allocated if empty.
+	PublicIpAddress string `json:"publicIpAddress,omitempty" example:"3.38.12.34"`
+}
+
+// TbVmPublicIpInfo is struct for the result of attaching/detaching a public IP of a VM
+type TbVmPublicIpInfo struct {
+	MciId            string `json:"mciId" example:"mci01"`
+	VmId             string `json:"vmId" example:"g1-1"`
+	PublicIP         string `json:"publicIP" example:"3.38.12.34"`
+	PublicDNS        string `json:"publicDNS,omitempty"`
+	PreviousPublicIP string `json:"previousPublicIP,omitempty"`
+	// SshReachable is the result of the SSH port check with the new public IP (false if detached)
+	SshReachable bool   `json:"sshReachable" example:"true"`
+	Message      string `json:"message,omitempty"`
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Actions of VM recovery policy
+const (
+	// RecoveryActionReboot reboots the failed VM
+	RecoveryActionReboot string = "reboot"
+	// RecoveryActionRecreateFromSpec creates a replacement VM with the spec and image of the failed VM
+	RecoveryActionRecreateFromSpec string = "recreateFromSpec"
+	// RecoveryActionRecreateFromSnapshot creates a replacement VM from the latest snapshot (customImage) of the failed VM
+	RecoveryActionRecreateFromSnapshot string = "recreateFromLatestSnapshot"
+)
+
+// Status of VM recovery policy
+const (
+	RecoveryPolicyActive string = "Active"
+	RecoveryPolicyPaused string = "Paused"
+)
+
+// RecoveryPolicyReq is struct for a request to create a VM auto-recovery policy of an MCI
+type RecoveryPolicyReq struct {
+	// SubGroupId limits the policy to the VMs of the subGroup (all VMs of the MCI if empty)
+	SubGroupId string `json:"subGroupId,omitempty" example:"g1"`
+	// CheckIntervalSec is the interval to check the status of VMs (default: 60, min: 10)
+	CheckIntervalSec int `json:"checkIntervalSec,omitempty" example:"60" default:"60"`
+	// MaxRestarts is the max number of recovery actions for a VM before backing off (default: 3)
+	MaxRestarts int    `json:"maxRestarts,omitempty" example:"3" default:"3"`
+	Action      string `json:"action" validate:"required" example:"reboot" enums:"reboot,recreateFromSpec,recreateFromLatestSnapshot"`
+	Description string `json:"description,omitempty" example:"Recover failed VMs"`
+}
+
+// RecoveryPolicyUpdateReq is struct for a request to update (or pause) a VM auto-recovery policy
+type RecoveryPolicyUpdateReq struct {
+	Paused           *bool  `json:"paused,omitempty" example:"true"`
+	CheckIntervalSec int    `json:"checkIntervalSec,omitempty" example:"60"`
+	MaxRestarts      int    `json:"maxRestarts,omitempty" example:"3"`
+	Action           string `json:"action,omitempty" example:"recreateFromSpec" enums:"reboot,recreateFromSpec,recreateFromLatestSnapshot"`
+	// ResetRestarts clears the restart counts (and the back-off) of all VMs
+	ResetRestarts bool `json:"resetRestarts,omitempty" example:"false"`
+}
+
+// RecoveryPolicyInfo is struct for a VM auto-recovery policy of an MCI
+type RecoveryPolicyInfo struct {
+	MciId            string `json:"mciId" example:"mci01"`
+	SubGroupId       string `json:"subGroupId,omitempty" example:"g1"`
+	CheckIntervalSec int    `json:"checkIntervalSec" example:"60"`
+	MaxRestarts      int    `json:"maxRestarts" example:"3"`
+	Action           string `json:"action" example:"reboot"`
+	Status           string `json:"status" example:"Active" enums:"Active,Paused"`
+	Description      string `json:"description,omitempty"`
+
+	CreatedAt     time.Time `json:"createdAt"`
+	LastCheckedAt time.Time `json:"lastCheckedAt,omitempty"`
+	// Restarts is the number of recovery actions for each VM (a replacement VM inherits the count of the failed VM)
+	Restarts map[string]int `json:"restarts,omitempty"`
+	// BackedOff is the VMs which reached MaxRestarts (no more recovery until resetRestarts)
+	BackedOff []string `json:"backedOff,omitempty"`
+}
+
+// VmRecoveryEvent is a record of a recovery action on a VM
+type VmRecoveryEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action" example:"reboot"`
+	// Reason is the status of the VM which triggered the recovery
+	Reason string `json:"reason" example:"Failed"`
+	// Result is Succeeded or Failed
+	Result  string `json:"result" example:"Succeeded"`
+	Message string `json:"message,omitempty"`
+	// ReplacedVmId is the failed VM replaced by this VM (recreate actions)
+	ReplacedVmId string `json:"replacedVmId,omitempty" example:"g1-1"`
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// SpiderVMResizeReqInfoWrapper is struct for a request to change the spec (instance type) of a VM via CB-Spider
+type SpiderVMResizeReqInfoWrapper struct {
+	ConnectionName string
+	ReqInfo        SpiderVMResizeReqInfo
+}
+
+// SpiderVMResizeReqInfo is struct for the target spec of a VM in CB-Spider
+type SpiderVMResizeReqInfo struct {
+	VMSpecName string
+}
+
+// TbVmResizeReq is struct for a request to change the spec of a VM
+type TbVmResizeReq struct {
+	// SpecId is the target spec (in the same provider, region and architecture)
+	SpecId string `json:"specId" validate:"required" example:"aws+ap-northeast-2+t3.medium"`
+	// ConfirmStop allows to stop (and restart) the VM if it is running
+	ConfirmStop bool `json:"confirmStop" example:"true"`
+}
+
+// VmResizeStep is a step of resizing a VM
+type VmResizeStep struct {
+	Title string    `json:"title" example:"Suspended the VM"`
+	Time  time.Time `json:"time"`
+}
+
+// TbVmResizeResult is struct for the result of resizing a VM
+type TbVmResizeResult struct {
+	MciId               string         `json:"mciId" example:"mci01"`
+	VmId                string         `json:"vmId" example:"g1-1"`
+	PreviousSpecId      string         `json:"previousSpecId" example:"aws+ap-northeast-2+t3.small"`
+	SpecId              string         `json:"specId" example:"aws+ap-northeast-2+t3.medium"`
+	CspSpecName         string         `json:"cspSpecName" example:"t3.medium"`
+	PreviousCostPerHour float32        `json:"previousCostPerHour,omitempty" example:"0.026"`
+	CostPerHour         float32        `json:"costPerHour,omitempty" example:"0.052"`
+	Status              string         `json:"status" example:"Running"`
+	Steps               []VmResizeStep `json:"steps"`
+}
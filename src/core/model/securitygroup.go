@@ -74,6 +74,18 @@ type TbSecurityGroupReq struct { // Tumblebug
 	CspResourceId string `json:"cspResourceId"`
 }
 
+// TbRegisterSecurityGroupReq is a struct to handle 'Register security group' request toward CB-Tumblebug.
+// The security group already exists in the CSP and is registered with its firewall rules as they are.
+type TbRegisterSecurityGroupReq struct {
+	ConnectionName string `json:"connectionName" validate:"required"`
+	CspResourceId  string `json:"cspResourceId" validate:"required"`
+	Name           string `json:"name" validate:"required"`
+	Description    string `json:"description,omitempty"`
+
+	// VNetId is the registered vNet of the security group (optional, resolved from the CSP vNet of the security group if empty)
+	VNetId string `json:"vNetId,omitempty"`
+}
+
 // TbFirewallRuleInfo is a struct to handle firewall rule info of CB-Tumblebug.
 type TbFirewallRuleInfo struct {
 	FromPort   string `validate:"required"` //`json:"fromPort"`
@@ -108,9 +120,17 @@ type TbSecurityGroupInfo struct {
 	AssociatedObjectList []string             `json:"associatedObjectList"`
 	IsAutoGenerated      bool                 `json:"isAutoGenerated"`
 
+	// CspVNetId is the CSP vNet of a registered security group whose vNet is not registered in CB-Tumblebug
+	CspVNetId string `json:"cspVNetId,omitempty" example:"vpc-0a1b2c3d4e5f67890"`
+	// VNetUnregistered is true while the vNet of the registered security group (cspVNetId) is not registered
+	VNetUnregistered bool `json:"vNetUnregistered,omitempty"`
+
 	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
 	SystemLabel string `json:"systemLabel" example:"Managed by CB-Tumblebug" default:""`
 
+	// Provenance records the request which created the object (createdByRequestId, createdByUser, createdVia)
+	Provenance
+
 	// Disabled for now
 	//ResourceGroupName  string `json:"resourceGroupName"`
 }
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Actions on the software of the catalog
+const (
+	SoftwareActionInstall   string = "install"
+	SoftwareActionUninstall string = "uninstall"
+	SoftwareActionStatus    string = "status"
+)
+
+// States of the software in a VM after a software action
+const (
+	SoftwareInstalled    string = "Installed"
+	SoftwareNotInstalled string = "NotInstalled"
+	SoftwareSkipped      string = "Skipped"
+	SoftwareFailed       string = "Failed"
+)
+
+// OS families of the software catalog (SoftwareOsFamilyAny is the fallback for any Linux family)
+const (
+	SoftwareOsFamilyDebian string = "debian"
+	SoftwareOsFamilyRhel   string = "rhel"
+	SoftwareOsFamilyAny    string = "linux"
+)
+
+// SoftwareCatalog is struct for the software catalog (assets/softwarecatalog.yaml)
+type SoftwareCatalog struct {
+	Software map[string]SoftwareSpec `mapstructure:"software" json:"software"`
+}
+
+// SoftwareSpec is struct for a software of the catalog
+type SoftwareSpec struct {
+	Description string `mapstructure:"description" json:"description" example:"Docker Engine"`
+	// TimeoutMinutes is the timeout of an action per VM (default: 15)
+	TimeoutMinutes int `mapstructure:"timeoutminutes" json:"timeoutMinutes,omitempty" example:"15"`
+	// OsFamily is the command templates by OS family (debian, rhel, or linux for any family)
+	OsFamily map[string]SoftwareCommands `mapstructure:"osfamily" json:"osFamily"`
+}
+
+// SoftwareCommands is struct for the command templates of a software for an OS family
+type SoftwareCommands struct {
+	Install   string `mapstructure:"install" json:"install"`
+	Uninstall string `mapstructure:"uninstall" json:"uninstall"`
+	// Status prints the installed version (nothing if the software is not installed)
+	Status string `mapstructure:"status" json:"status"`
+}
+
+// SoftwareInstallReq is struct for a request to install a software of the catalog to an MCI
+type SoftwareInstallReq struct {
+	// UserName is the SSH user of the VMs (default: the verified user of each VM)
+	UserName string `json:"userName,omitempty" example:"cb-user"`
+	// Version is passed to the templates as TB_SOFTWARE_VERSION (the default version of the template if empty)
+	Version string `json:"version,omitempty" example:""`
+}
+
+// VmSoftwareInfo is struct for a software of the catalog installed in a VM
+type VmSoftwareInfo struct {
+	Version     string    `json:"version" example:"27.3.1"`
+	UpdatedTime time.Time `json:"updatedTime" example:"2024-10-01T00:00:00Z"`
+}
+
+// SoftwareVmResult is struct for the result of a software action in a VM
+type SoftwareVmResult struct {
+	VmId string `json:"vmId" example:"g1-1"`
+	// Status is one of Installed, NotInstalled, Skipped, Failed
+	Status   string `json:"status" example:"Installed"`
+	OsFamily string `json:"osFamily,omitempty" example:"debian"`
+	Version  string `json:"version,omitempty" example:"27.3.1"`
+	// Message is the reason of the status (e.g., why the VM is skipped)
+	Message string `json:"message,omitempty" example:""`
+	// LogTail is the tail of the command log (for a failure)
+	LogTail string `json:"logTail,omitempty"`
+}
+
+// SoftwareActionResult is struct for the result of a software action in an MCI
+type SoftwareActionResult struct {
+	MciId    string             `json:"mciId" example:"mci01"`
+	Software string             `json:"software" example:"docker"`
+	Action   string             `json:"action" example:"install"`
+	Results  []SoftwareVmResult `json:"results"`
+}
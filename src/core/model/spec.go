@@ -86,7 +86,9 @@ type TbSpecInfo struct { // Tumblebug
 	AcceleratorCount      uint8    `json:"acceleratorCount,omitempty"`
 	AcceleratorMemoryGB   float32  `json:"acceleratorMemoryGB,omitempty"`
 	AcceleratorType       string   `json:"acceleratorType,omitempty"`
+	Architecture          string   `json:"architecture,omitempty" example:"x86_64" enums:"x86_64,arm64"` // CPU architecture
 	CostPerHour           float32  `json:"costPerHour,omitempty"`
+	SpotCostPerHour       float32  `json:"spotCostPerHour,omitempty"` // spot (preemptible) price if it is known
 	Description           string   `json:"description,omitempty"`
 	OrderInFilteredResult uint16   `json:"orderInFilteredResult,omitempty"`
 	EvaluationStatus      string   `json:"evaluationStatus,omitempty"`
@@ -104,11 +106,38 @@ type TbSpecInfo struct { // Tumblebug
 	RootDiskSize          string   `json:"rootDiskSize"`
 	AssociatedObjectList  []string `json:"associatedObjectList,omitempty"`
 	IsAutoGenerated       bool     `json:"isAutoGenerated,omitempty"`
+	// Unavailable is set when the spec is no longer provided by the CSP (detected by fetchSpecs)
+	Unavailable bool `json:"unavailable,omitempty"`
 
 	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
 	SystemLabel string `json:"systemLabel,omitempty" example:"Managed by CB-Tumblebug" default:""`
 }
 
+// SpecFetchResult is the result of fetching specs of a connection
+type SpecFetchResult struct {
+	ConnectionName string `json:"connectionName"`
+	ProviderName   string `json:"providerName"`
+	// Added is the number of specs newly found in the CSP
+	Added int `json:"added"`
+	// Updated is the number of specs whose fields are changed (or which are available again)
+	Updated int `json:"updated"`
+	// Unchanged is the number of specs which are not written
+	Unchanged int `json:"unchanged"`
+	// Removed is the number of specs which disappeared from the CSP and are marked as unavailable
+	Removed int    `json:"removed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SpecFetchSummary is the result of fetching specs of connections
+type SpecFetchSummary struct {
+	ConnConfigCount int               `json:"connConfigCount"`
+	Added           int               `json:"added"`
+	Updated         int               `json:"updated"`
+	Unchanged       int               `json:"unchanged"`
+	Removed         int               `json:"removed"`
+	Results         []SpecFetchResult `json:"results"`
+}
+
 // FilterSpecsByRangeRequest is for 'FilterSpecsByRange'
 type FilterSpecsByRangeRequest struct {
 	Id                  string `json:"id"`
@@ -128,7 +157,9 @@ type FilterSpecsByRangeRequest struct {
 	AcceleratorCount    Range  `json:"acceleratorCount"`
 	AcceleratorMemoryGB Range  `json:"acceleratorMemoryGB"`
 	AcceleratorType     string `json:"acceleratorType"`
+	Architecture        string `json:"architecture"`
 	CostPerHour         Range  `json:"costPerHour"`
+	SpotCostPerHour     Range  `json:"spotCostPerHour"`
 	Description         string `json:"description"`
 	EvaluationStatus    string `json:"evaluationStatus"`
 	EvaluationScore01   Range  `json:"evaluationScore01"`
@@ -141,6 +172,16 @@ type FilterSpecsByRangeRequest struct {
 	EvaluationScore08   Range  `json:"evaluationScore08"`
 	EvaluationScore09   Range  `json:"evaluationScore09"`
 	EvaluationScore10   Range  `json:"evaluationScore10"`
+
+	// SortBy orders the result by a numeric field (vCPU, memoryGiB, storageGiB, costPerHour, evaluationScore01, ...).
+	// Specs with unknown cost are placed last when sorting by costPerHour or spotCostPerHour.
+	SortBy string `json:"sortBy,omitempty" filter:"-" example:"costPerHour"`
+	// Order is the direction of SortBy
+	Order string `json:"order,omitempty" filter:"-" example:"asc" enums:"asc,desc" default:"asc"`
+	// Limit is the max number of specs in the result (0: all)
+	Limit int `json:"limit,omitempty" filter:"-" example:"100"`
+	// Offset is the number of specs skipped from the beginning of the sorted result
+	Offset int `json:"offset,omitempty" filter:"-" example:"0"`
 }
 
 // SpiderSpecList is a struct to handle spec list from the CB-Spider's REST API response
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Supported range of CB-Spider versions (the request bodies of CB-Tumblebug match the API of CB-Spider in the range)
+const (
+	// SpiderMinVersion is the lowest supported version of CB-Spider (inclusive)
+	SpiderMinVersion string = "0.9.0"
+	// SpiderMaxVersion is the upper bound of supported versions of CB-Spider (exclusive)
+	SpiderMaxVersion string = "0.10.0"
+)
+
+// Request features of CB-Spider which depend on its version
+const (
+	// SpiderFeatureIdTransformMode is the IDTransformMode field of the requests to create resources
+	SpiderFeatureIdTransformMode string = "IDTransformMode"
+	// SpiderFeatureTagList is the TagList field of resources and the tag API
+	SpiderFeatureTagList string = "TagList"
+	// SpiderFeatureMyImageCopy is the API to copy a MyImage to another region (POST /myimage/copy),
+	// which is not in the released versions of CB-Spider yet
+	SpiderFeatureMyImageCopy string = "MyImageCopy"
+)
+
+// SpiderFeatureMinVersions is the lowest version of CB-Spider which supports each request feature
+var SpiderFeatureMinVersions = map[string]string{
+	SpiderFeatureIdTransformMode: "0.9.1",
+	SpiderFeatureTagList:         "0.9.4",
+	SpiderFeatureMyImageCopy:     "0.10.0",
+}
+
+// SpiderVersionInfo is struct for the version of CB-Spider detected by CB-Tumblebug
+type SpiderVersionInfo struct {
+	// Version is the detected version of CB-Spider (empty if it is not detected)
+	Version string `json:"version" example:"0.9.7"`
+	// Source is the endpoint of CB-Spider which reported the version
+	Source     string `json:"source,omitempty" example:"/version"`
+	MinVersion string `json:"minVersion" example:"0.9.0"`
+	MaxVersion string `json:"maxVersion" example:"0.10.0"`
+	// Compatible is false if the detected version is out of the supported range (true if not detected)
+	Compatible bool   `json:"compatible" example:"true"`
+	Message    string `json:"message,omitempty" example:""`
+	// Features is whether each version-dependent request feature is enabled for the detected version
+	Features     map[string]bool `json:"features"`
+	DetectedTime time.Time       `json:"detectedTime"`
+}
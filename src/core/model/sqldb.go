@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import (
+	"time"
+)
+
+// Engines of managed database (sqlDb)
+const (
+	SqlDbEngineMysql      string = "mysql"
+	SqlDbEnginePostgresql string = "postgresql"
+)
+
+// Status of managed database (sqlDb)
+const (
+	SqlDbCreating  string = "Creating"
+	SqlDbAvailable string = "Available"
+	SqlDbDeleting  string = "Deleting"
+	SqlDbError     string = "Error"
+)
+
+// SpiderSqlDbReqInfoWrapper is a wrapper struct to create JSON body of 'Create managed DB request'
+type SpiderSqlDbReqInfoWrapper struct {
+	ConnectionName string
+	ReqInfo        SpiderSqlDbInfo
+}
+
+// SpiderSqlDbInfo is a struct to create JSON body of 'Create managed DB request' of CB-Spider
+type SpiderSqlDbInfo struct {
+	// Fields for request
+	Name          string
+	VPCName       string
+	SubnetName    string
+	AdminUsername string
+	AdminPassword string
+
+	// Fields for both request and response
+	Engine        string // mysql | postgresql
+	EngineVersion string
+	InstanceType  string // e.g., db.t3.micro
+	StorageSize   string // GB
+
+	// Fields for response
+	IId          IID // {NameId, SystemId}
+	Status       string
+	Endpoint     string
+	Port         string
+	CreatedTime  time.Time
+	KeyValueList []KeyValue
+}
+
+// TbSqlDbReq is a struct to handle 'Create sqlDb' request toward CB-Tumblebug.
+type TbSqlDbReq struct {
+	Name           string `json:"name" validate:"required" example:"aws-ap-northeast-2-db"`
+	ConnectionName string `json:"connectionName" validate:"required" example:"aws-ap-northeast-2"`
+	Engine         string `json:"engine" validate:"required" example:"mysql" enums:"mysql,postgresql"`
+	EngineVersion  string `json:"engineVersion,omitempty" example:"8.0"`
+	// InstanceType is the size (class) of the DB instance of the CSP
+	InstanceType string `json:"instanceType" validate:"required" example:"db.t3.micro"`
+	// StorageSize is the storage size (GB) of the DB instance
+	StorageSize string `json:"storageSize" validate:"required" example:"20"`
+	// VNetId and SubnetId are the placement of the DB instance (the subnet must belong to the vNet)
+	VNetId   string `json:"vNetId" validate:"required" example:"vnet01"`
+	SubnetId string `json:"subnetId" validate:"required" example:"subnet01"`
+	// AdminUsername is the admin user of the DB (default: tbadmin). The password is generated.
+	AdminUsername string `json:"adminUsername,omitempty" example:"tbadmin"`
+	Description   string `json:"description,omitempty"`
+}
+
+// TbSqlDbInfo is a struct that represents TB sqlDb (managed database) object.
+type TbSqlDbInfo struct {
+	// ResourceType is the type of the resource
+	ResourceType string `json:"resourceType"`
+
+	// Id is unique identifier for the object
+	Id string `json:"id" example:"aws-ap-northeast-2-db"`
+	// Uid is universally unique identifier for the object, used for labelSelector
+	Uid string `json:"uid,omitempty" example:"wef12awefadf1221edcf"`
+	// CspResourceName is name assigned to the CSP resource. This name is internally used to handle the resource.
+	CspResourceName string `json:"cspResourceName,omitempty" example:"wef12awefadf1221edcf"`
+	// CspResourceId is resource identifier managed by CSP
+	CspResourceId string `json:"cspResourceId,omitempty" example:"db-xxxxxxxx"`
+
+	// Name is human-readable string to represent the object
+	Name           string `json:"name" example:"aws-ap-northeast-2-db"`
+	ConnectionName string `json:"connectionName,omitempty" example:"aws-ap-northeast-2"`
+	Engine         string `json:"engine" example:"mysql"`
+	EngineVersion  string `json:"engineVersion,omitempty" example:"8.0"`
+	InstanceType   string `json:"instanceType" example:"db.t3.micro"`
+	StorageSize    string `json:"storageSize" example:"20"`
+	VNetId         string `json:"vNetId" example:"vnet01"`
+	SubnetId       string `json:"subnetId" example:"subnet01"`
+	Status         string `json:"status" example:"Available" enums:"Creating,Available,Deleting,Error"`
+	// Endpoint and Port are the connection endpoint of the DB (available when the status is Available)
+	Endpoint      string `json:"endpoint,omitempty" example:"db.xxxx.ap-northeast-2.rds.amazonaws.com"`
+	Port          string `json:"port,omitempty" example:"3306"`
+	AdminUsername string `json:"adminUsername" example:"tbadmin"`
+	// AdminPassword is encrypted in the Key-Value store and masked in responses except the creation
+	AdminPassword string `json:"adminPassword,omitempty" example:"********"`
+
+	AssociatedObjectList []string   `json:"associatedObjectList"`
+	CreatedTime          time.Time  `json:"createdTime,omitempty" example:"2022-10-12T05:09:51.05Z"`
+	KeyValueList         []KeyValue `json:"keyValueList,omitempty"`
+	Description          string     `json:"description,omitempty"`
+
+	// Latest system message such as error message
+	SystemMessage string `json:"systemMessage" example:"Failed because ..." default:""` // systeam-given string message
+
+	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
+	SystemLabel string `json:"systemLabel,omitempty" example:"Managed by CB-Tumblebug" default:""`
+}
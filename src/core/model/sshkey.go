@@ -51,6 +51,21 @@ type TbSshKeyReq struct {
 	PrivateKey       string `json:"privateKey"`
 }
 
+// TbRegisterSshKeyReq is a struct to handle 'Register SSH key' request toward CB-Tumblebug.
+// The key pair already exists in the CSP. Without privateKey, the sshKey is registered as publicOnly.
+type TbRegisterSshKeyReq struct {
+	ConnectionName string `json:"connectionName" validate:"required"`
+	// CspResourceId is the name (or id) of the key pair in the CSP
+	CspResourceId string `json:"cspResourceId" validate:"required" example:"my-keypair"`
+	Name          string `json:"name" validate:"required"`
+	Description   string `json:"description,omitempty"`
+
+	// Username is the VM user to access VMs with the key pair (optional)
+	Username string `json:"username,omitempty" example:"ubuntu"`
+	// PrivateKey is the private key material of the key pair (optional, required for command execution on VMs)
+	PrivateKey string `json:"privateKey,omitempty"`
+}
+
 // TbSshKeyInfo is a struct that represents TB SSH key object.
 type TbSshKeyInfo struct {
 	// ResourceType is the type of the resource
@@ -80,6 +95,12 @@ type TbSshKeyInfo struct {
 	AssociatedObjectList []string   `json:"associatedObjectList,omitempty"`
 	IsAutoGenerated      bool       `json:"isAutoGenerated,omitempty"`
 
+	// PublicOnly is true if the sshKey is registered without private key material (command execution is not available)
+	PublicOnly bool `json:"publicOnly,omitempty"`
+
 	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
 	SystemLabel string `json:"systemLabel,omitempty" example:"Managed by CB-Tumblebug" default:""`
+
+	// Provenance records the request which created the object (createdByRequestId, createdByUser, createdVia)
+	Provenance
 }
@@ -18,6 +18,7 @@ package model
 type TbSubnetReq struct { // Tumblebug
 	Name        string `json:"name" validate:"required" example:"subnet00"`
 	IPv4_CIDR   string `json:"ipv4_CIDR" validate:"required" example:"10.0.1.0/24"`
+	IPv6_CIDR   string `json:"ipv6_CIDR,omitempty" example:"2600:1f18:1234:5601::/64"` // optional /64 in the ipv6CidrBlock of the vNet (dual-stack)
 	Zone        string `json:"zone,omitempty"`
 	Description string `json:"description,omitempty" example:"subnet00 managed by CB-Tumblebug"`
 	// todo: restore the tag list later
@@ -55,6 +56,7 @@ type TbSubnetInfo struct { // Tumblebug
 	CspVNetId    string        `json:"cspResourceId,omitempty" example:"csp-45eb41e14121c550a"`
 	Status       string        `json:"status"`
 	IPv4_CIDR    string        `json:"ipv4_CIDR"`
+	IPv6_CIDR    string        `json:"ipv6_CIDR,omitempty"`
 	Zone         string        `json:"zone,omitempty"`
 	BastionNodes []BastionNode `json:"bastionNodes,omitempty"`
 	KeyValueList []KeyValue    `json:"keyValueList,omitempty"`
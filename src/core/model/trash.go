@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// TrashItem is struct for the metadata of a soft-deleted MCI or resource kept in the trash
+type TrashItem struct {
+	// Id is the id of the trash item
+	Id   string `json:"id" example:"cr3bqk6lnsm0vnb6kbqg"`
+	NsId string `json:"nsId" example:"default"`
+	// ResourceType is the type of the deleted object (mci, vNet, securityGroup, sshKey, dataDisk)
+	ResourceType string `json:"resourceType" example:"mci"`
+	ResourceId   string `json:"resourceId" example:"mci01"`
+	// ResourceKey is the key of the deleted object
+	ResourceKey string    `json:"resourceKey" example:"/ns/default/mci/mci01"`
+	DeletedAt   time.Time `json:"deletedAt" example:"2024-10-01T00:00:00Z"`
+	// ExpiresAt is the time when the item is purged automatically
+	ExpiresAt time.Time `json:"expiresAt" example:"2024-10-04T00:00:00Z"`
+	// CspResourceKept is true if the CSP resource was not deleted (it is deleted when the item is purged)
+	CspResourceKept bool `json:"cspResourceKept" example:"false"`
+	// Objects are the Key-Value records of the object and its children (omitted in the list)
+	Objects []NsExportKv `json:"objects,omitempty"`
+	// Labels are the label records of the object and its children (omitted in the list)
+	Labels []NsExportKv `json:"labels,omitempty"`
+}
+
+// TrashItemList is struct for the list of trash items
+type TrashItemList struct {
+	Items []TrashItem `json:"items"`
+}
+
+// TrashRestoreResult is struct for the result of restoring a trash item
+type TrashRestoreResult struct {
+	NsId         string `json:"nsId" example:"default"`
+	ResourceType string `json:"resourceType" example:"mci"`
+	// ResourceId is the id of the restored object (suffixed if the original id is taken)
+	ResourceId string `json:"resourceId" example:"mci01-1"`
+	// OriginalId is the id of the object when it was deleted
+	OriginalId string `json:"originalId" example:"mci01"`
+	Renamed    bool   `json:"renamed" example:"true"`
+	// Restored is the number of restored Key-Value records
+	Restored int `json:"restored" example:"4"`
+	// SystemMessage describes the re-registration against the CSP resources
+	SystemMessage string `json:"systemMessage,omitempty"`
+}
@@ -21,10 +21,19 @@ type TbVNetReq struct { // Tumblebug
 	CidrBlock      string        `json:"cidrBlock" example:"10.0.0.0/16"`
 	SubnetInfoList []TbSubnetReq `json:"subnetInfoList"`
 	Description    string        `json:"description" example:"vnet00 managed by CB-Tumblebug"`
+	// AllowOverlap allows the CIDR block to overlap other vNets in the same namespace and CSP account (warned only)
+	AllowOverlap bool `json:"allowOverlap,omitempty" example:"false"`
+	// IPv6CidrBlock is the IPv6 CIDR block of a dual-stack vNet (optional, /44 to /60)
+	IPv6CidrBlock string `json:"ipv6CidrBlock,omitempty" example:"2600:1f18:1234:5600::/56"`
+	// AssignIpv6 "auto" lets the CSP allocate the IPv6 ranges of the vNet and its subnets (instead of IPv6CidrBlock)
+	AssignIpv6 string `json:"assignIpv6,omitempty" enums:"auto" example:"auto"`
 	// todo: restore the tag list later
 	// TagList        []KeyValue    `json:"tagList,omitempty"`
 }
 
+// AssignIpv6Auto is TbVNetReq.AssignIpv6 for the IPv6 ranges allocated by the CSP
+const AssignIpv6Auto string = "auto"
+
 // TbRegisterVNetReq TbRegisterVNetReq contains the information needed to register a vNet
 // that has already been created via another external method.
 type TbRegisterVNetReq struct {
@@ -52,6 +61,7 @@ type TbVNetInfo struct {
 	Name                 string         `json:"name" example:"aws-ap-southeast-1"`
 	ConnectionName       string         `json:"connectionName"`
 	CidrBlock            string         `json:"cidrBlock"`
+	IPv6CidrBlock        string         `json:"ipv6CidrBlock,omitempty"`
 	SubnetInfoList       []TbSubnetInfo `json:"subnetInfoList"`
 	Description          string         `json:"description"`
 	Status               string         `json:"status"`
@@ -64,6 +74,9 @@ type TbVNetInfo struct {
 	// SystemLabel is for describing the Resource in a keyword (any string can be used) for special System purpose
 	SystemLabel string `json:"systemLabel" example:"Managed by CB-Tumblebug" default:""`
 
+	// Provenance records the request which created the object (createdByRequestId, createdByUser, createdVia)
+	Provenance
+
 	// Disabled for now
 	//Region         string `json:"region"`
 	//ResourceGroupName string `json:"resourceGroupName"`
@@ -87,6 +100,21 @@ type McNetConfigurationDetails struct {
 	Regions []RegionDetails `json:"regions"`
 }
 
+// VNetValidationIssue is an error or a warning found by the dry-run validation of a vNet request
+type VNetValidationIssue struct {
+	// Field is the field of the request (e.g., cidrBlock, subnetInfoList[0].zone), empty for the whole request
+	Field   string `json:"field,omitempty" example:"subnetInfoList[0].ipv4_CIDR"`
+	Message string `json:"message" example:"prefix length /30 is out of the range of aws (/16-/28)"`
+}
+
+// VNetValidationResult is the result of the dry-run validation of a vNet request (nothing is created)
+type VNetValidationResult struct {
+	// Valid is true if the request has no errors (warnings do not fail the creation)
+	Valid    bool                  `json:"valid"`
+	Errors   []VNetValidationIssue `json:"errors"`
+	Warnings []VNetValidationIssue `json:"warnings"`
+}
+
 type RegionDetails struct {
 	Name  string        `json:"name"`
 	VNets []VNetDetails `json:"vNets"`
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Health status of a site-to-site VPN and its tunnels
+const (
+	VpnStatusUp       string = "Up"
+	VpnStatusDown     string = "Down"
+	VpnStatusDegraded string = "Degraded"
+)
+
+// VpnTunnelHealth is the health of a VPN tunnel (or connection) of a CSP
+type VpnTunnelHealth struct {
+	// Name is the resource name with the tunnel address (e.g., aws_vpn_connection.vpn_cnx_1/34.1.2.3)
+	Name         string `json:"name" example:"aws_vpn_connection.vpn_cnx_1/3.35.10.1"`
+	ResourceType string `json:"resourceType" example:"aws_vpn_connection"`
+	Status       string `json:"status" example:"Up" enums:"Up,Down,Degraded"`
+	// RawStatus is the status reported by the CSP
+	RawStatus string `json:"rawStatus,omitempty" example:"UP"`
+	Message   string `json:"message,omitempty"`
+}
+
+// VpnHealthRecord is a change of the health status of a VPN
+type VpnHealthRecord struct {
+	Time    time.Time `json:"time"`
+	Status  string    `json:"status" example:"Degraded"`
+	Message string    `json:"message,omitempty" example:"1 of 4 tunnels are down"`
+}
+
+// VpnHealthInfo is the health of a site-to-site VPN of an MCI
+type VpnHealthInfo struct {
+	NsId            string            `json:"nsId" example:"default"`
+	MciId           string            `json:"mciId" example:"mci01"`
+	VpnId           string            `json:"vpnId" example:"vpn01"`
+	Status          string            `json:"status" example:"Up" enums:"Up,Down,Degraded"`
+	Message         string            `json:"message,omitempty"`
+	Tunnels         []VpnTunnelHealth `json:"tunnels"`
+	LastHealthCheck time.Time         `json:"lastHealthCheck"`
+	// History is the recent changes of the health status
+	History []VpnHealthRecord `json:"history,omitempty"`
+	// Cached is true if the result is reused from the recent check
+	Cached bool `json:"cached"`
+}
+
+// Operations and their status of a site in a VPN update
+const (
+	VpnSiteOperationAdd    string = "add"
+	VpnSiteOperationRemove string = "remove"
+
+	VpnOperationPending    string = "Pending"
+	VpnOperationInProgress string = "InProgress"
+	VpnOperationSucceeded  string = "Succeeded"
+	VpnOperationFailed     string = "Failed"
+	// VpnOperationRolledBack means the site addition failed and its new connections are removed
+	VpnOperationRolledBack string = "RolledBack"
+)
+
+// VpnSite is a site (a vNet of a CSP region) of a site-to-site VPN
+// (same fields as the SiteDetail of the REST API)
+type VpnSite struct {
+	CSP               string `json:"csp" example:"aws"`
+	Region            string `json:"region" example:"ap-northeast-2"`
+	Zone              string `json:"zone,omitempty" example:"ap-northeast-2a"`
+	VNet              string `json:"vnet" example:"vpc-xxxxx"`
+	Subnet            string `json:"subnet,omitempty" example:"subnet-xxxxx"`
+	GatewaySubnetCidr string `json:"gatewaySubnetCidr,omitempty" example:"xxx.xxx.xxx.xxx/xx"`
+	ResourceGroup     string `json:"resourceGroup,omitempty" example:"rg-xxxxx"`
+}
+
+// VpnConnection is a VPN connection between two sites (a terrarium of mc-terrarium)
+type VpnConnection struct {
+	TrId        string    `json:"trId" example:"default-mci01-vpn01"`
+	Enrichments string    `json:"enrichments" example:"vpn/gcp-aws"`
+	SiteIds     [2]string `json:"siteIds"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// VpnSiteOperation is the operation state of a site in a VPN update
+type VpnSiteOperation struct {
+	SiteId    string `json:"siteId" example:"aws/ap-northeast-2/vpc-xxxxx"`
+	Operation string `json:"operation" example:"add" enums:"add,remove"`
+	Status    string `json:"status" example:"Succeeded" enums:"Pending,InProgress,Succeeded,Failed,RolledBack"`
+	Message   string `json:"message,omitempty"`
+}
+
+// VpnUpdateStatus is the status of a request to update the sites of a VPN
+type VpnUpdateStatus struct {
+	RequestId   string             `json:"requestId" example:"vpnupdate-xxxxx"`
+	Status      string             `json:"status" example:"Succeeded" enums:"InProgress,Succeeded,Failed"`
+	StartedAt   time.Time          `json:"startedAt"`
+	CompletedAt time.Time          `json:"completedAt,omitempty"`
+	Sites       []VpnSiteOperation `json:"sites"`
+}
+
+// VpnInfo is the sites and the connections of a site-to-site VPN of an MCI
+type VpnInfo struct {
+	NsId        string          `json:"nsId" example:"default"`
+	MciId       string          `json:"mciId" example:"mci01"`
+	VpnId       string          `json:"vpnId" example:"vpn01"`
+	Sites       []VpnSite       `json:"sites"`
+	Connections []VpnConnection `json:"connections"`
+	// ConnectionSeq is the sequence for the terrarium IDs of the added connections
+	ConnectionSeq int              `json:"connectionSeq"`
+	LastUpdate    *VpnUpdateStatus `json:"lastUpdate,omitempty"`
+}
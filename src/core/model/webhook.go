@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package model is to handle object of CB-Tumblebug
+package model
+
+import "time"
+
+// Types of events notified to webhooks
+const (
+	EventMciCreated              string = "mci.created"
+	EventMciDeleted              string = "mci.deleted"
+	EventMciStatusChanged        string = "mci.statusChanged"
+	EventVmStatusChanged         string = "vm.statusChanged"
+	EventVmRecoveryExecuted      string = "vm.recoveryExecuted"
+	EventVmRecoveryBackedOff     string = "vm.recoveryBackedOff"
+	EventVmPublicIpChanged       string = "vm.publicIpChanged"
+	EventK8sClusterCreated       string = "k8scluster.created"
+	EventK8sClusterDeleted       string = "k8scluster.deleted"
+	EventK8sClusterStatusChanged string = "k8scluster.statusChanged"
+	EventVNetStatusChanged       string = "vNet.statusChanged"
+	EventVpnDegraded             string = "vpn.degraded"
+	EventWebhookTest             string = "webhook.test"
+)
+
+// WebhookEventTypes is the list of event types which can be subscribed
+var WebhookEventTypes = []string{
+	EventMciCreated,
+	EventMciDeleted,
+	EventMciStatusChanged,
+	EventVmStatusChanged,
+	EventVmRecoveryExecuted,
+	EventVmRecoveryBackedOff,
+	EventVmPublicIpChanged,
+	EventK8sClusterCreated,
+	EventK8sClusterDeleted,
+	EventK8sClusterStatusChanged,
+	EventVNetStatusChanged,
+	EventVpnDegraded,
+}
+
+// WebhookReq is struct for a request to subscribe to events
+type WebhookReq struct {
+	// Url is the target URL which receives events by POST
+	Url string `json:"url" validate:"required" example:"https://example.com/hooks/tumblebug"`
+	// Secret is the key to sign payloads (HMAC-SHA256 in X-Tumblebug-Signature header)
+	Secret string `json:"secret,omitempty" example:"my-webhook-secret"`
+	// Events is the list of event types to receive (empty for all events)
+	Events []string `json:"events,omitempty" example:"mci.created,vm.statusChanged"`
+	// NsId is the namespace of events to receive (empty for all namespaces)
+	NsId        string `json:"nsId,omitempty" example:"default"`
+	Description string `json:"description,omitempty" example:"Notify provisioning results to Slack"`
+}
+
+// WebhookInfo is struct for a webhook subscription (the secret is never included)
+type WebhookInfo struct {
+	Id          string    `json:"id" example:"wh-cs6c2ljuelr8l5l7m2n0"`
+	Url         string    `json:"url" example:"https://example.com/hooks/tumblebug"`
+	HasSecret   bool      `json:"hasSecret" example:"true"`
+	Events      []string  `json:"events,omitempty" example:"mci.created,vm.statusChanged"`
+	NsId        string    `json:"nsId,omitempty" example:"default"`
+	Description string    `json:"description,omitempty" example:"Notify provisioning results to Slack"`
+	CreatedTime time.Time `json:"createdTime" example:"2024-10-01T00:00:00Z"`
+}
+
+// WebhookInfoList is struct for a list of webhooks
+type WebhookInfoList struct {
+	Webhooks []WebhookInfo `json:"webhooks"`
+}
+
+// WebhookEvent is the payload delivered to webhooks
+type WebhookEvent struct {
+	Id   string    `json:"id" example:"evt-cs6c2ljuelr8l5l7m2n0"`
+	Type string    `json:"type" example:"mci.created"`
+	Time time.Time `json:"time" example:"2024-10-01T00:00:00Z"`
+	NsId string    `json:"nsId,omitempty" example:"default"`
+	// Target is the object of the event (e.g., /ns/default/mci/mci01/vm/vm01)
+	Target string `json:"target" example:"/ns/default/mci/mci01"`
+	// RequestId is the X-Request-Id of the API call which triggered the event (empty for background changes)
+	RequestId string `json:"requestId,omitempty" example:"1727740800000000000"`
+	// Data is the detail of the event (e.g., previous and current status)
+	Data interface{} `json:"data,omitempty"`
+}
+
+// WebhookDelivery is struct for a delivery attempt of an event to a webhook
+type WebhookDelivery struct {
+	EventId    string    `json:"eventId" example:"evt-cs6c2ljuelr8l5l7m2n0"`
+	EventType  string    `json:"eventType" example:"mci.created"`
+	Attempt    int       `json:"attempt" example:"1"`
+	Time       time.Time `json:"time" example:"2024-10-01T00:00:00Z"`
+	StatusCode int       `json:"statusCode,omitempty" example:"200"`
+	Success    bool      `json:"success" example:"true"`
+	Error      string    `json:"error,omitempty" example:""`
+}
+
+// WebhookDeliveryList is struct for the recent delivery attempts of a webhook (newest first)
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
@@ -16,6 +16,7 @@ package resource
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -69,6 +70,8 @@ func init() {
 	validate.RegisterStructValidation(TbSpecReqStructLevelValidation, model.TbSpecReq{})
 	validate.RegisterStructValidation(TbSshKeyReqStructLevelValidation, model.TbSshKeyReq{})
 	validate.RegisterStructValidation(TbVNetReqStructLevelValidation, model.TbVNetReq{})
+	validate.RegisterStructValidation(TbObjectStorageReqStructLevelValidation, model.TbObjectStorageReq{})
+	validate.RegisterStructValidation(TbSqlDbReqStructLevelValidation, model.TbSqlDbReq{})
 }
 
 // DelAllResources deletes all TB Resource object of given resourceType
@@ -135,8 +138,7 @@ func DelResource(nsId string, resourceType string, resourceId string, forceFlag
 	}
 
 	if !check {
-		errString := "The " + resourceType + " " + resourceId + " does not exist."
-		err := fmt.Errorf(errString)
+		err := common.NewResourceNotFoundError(resourceType, resourceId)
 		return err
 	}
 
@@ -288,6 +290,28 @@ func DelResource(nsId string, resourceType string, resourceId string, forceFlag
 		requestBody.ConnectionName = temp.ConnectionName
 		url = model.SpiderRestUrl + "/disk/" + temp.CspResourceName
 		uid = temp.Uid
+
+	case model.StrObjectStorage:
+		temp := model.TbObjectStorageInfo{}
+		err = json.Unmarshal([]byte(keyValue.Value), &temp)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return err
+		}
+		requestBody.ConnectionName = temp.ConnectionName
+		url = model.SpiderRestUrl + "/s3/bucket/" + temp.CspResourceName
+		uid = temp.Uid
+
+	case model.StrSqlDb:
+		temp := model.TbSqlDbInfo{}
+		err = json.Unmarshal([]byte(keyValue.Value), &temp)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return err
+		}
+		requestBody.ConnectionName = temp.ConnectionName
+		url = model.SpiderRestUrl + "/sqldb/" + temp.CspResourceName
+		uid = temp.Uid
 	/*
 		case "subnet":
 			temp := subnetInfo{}
@@ -405,7 +429,9 @@ func ListResourceId(nsId string, resourceType string) ([]string, error) {
 		//resourceType == "publicIp" ||
 		//resourceType == "vNic" ||
 		resourceType == model.StrSecurityGroup ||
-		resourceType == model.StrDataDisk {
+		resourceType == model.StrDataDisk ||
+		resourceType == model.StrObjectStorage ||
+		resourceType == model.StrSqlDb {
 		// continue
 	} else {
 		err = fmt.Errorf("invalid resource type")
@@ -442,6 +468,35 @@ func ListResourceId(nsId string, resourceType string) ([]string, error) {
 
 }
 
+// ListResourceWithOption returns the page of TB Resource objects of given resourceType with the selected fields only.
+// Sensitive fields (e.g., private keys and passwords) are masked since the list is for the response.
+func ListResourceWithOption(nsId string, resourceType string, filterKey string, filterVal string, listOption model.ListOption) (interface{}, model.ListPage, error) {
+
+	resourceList, err := ListResource(nsId, resourceType, filterKey, filterVal)
+	if err != nil {
+		return nil, model.ListPage{}, err
+	}
+
+	switch list := resourceList.(type) {
+	case []model.TbSshKeyInfo:
+		for i := range list {
+			MaskSshKeyInfo(&list[i])
+		}
+	case []model.TbSqlDbInfo:
+		for i := range list {
+			MaskSqlDbInfo(&list[i])
+		}
+	}
+
+	paged, page := common.PageList(resourceList, listOption)
+	shaped, err := common.ShapeList(paged, listOption)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, model.ListPage{}, err
+	}
+	return shaped, page, nil
+}
+
 // ListResource returns the list of TB Resource objects of given resourceType
 func ListResource(nsId string, resourceType string, filterKey string, filterVal string) (interface{}, error) {
 
@@ -460,7 +515,9 @@ func ListResource(nsId string, resourceType string, filterKey string, filterVal
 		//resourceType == "publicIp" ||
 		//resourceType == "vNic" ||
 		resourceType == model.StrSecurityGroup ||
-		resourceType == model.StrDataDisk {
+		resourceType == model.StrDataDisk ||
+		resourceType == model.StrObjectStorage ||
+		resourceType == model.StrSqlDb {
 		// continue
 	} else {
 		errString := "Cannot list " + resourceType + "s."
@@ -588,6 +645,9 @@ func ListResource(nsId string, resourceType string, filterKey string, filterVal
 					log.Error().Err(err).Msg("")
 					return nil, err
 				}
+				if err := decryptSshKeyInfo(&tempObj); err != nil {
+					return nil, err
+				}
 				// Check the JSON body inclues both filterKey and filterVal strings. (assume key and value)
 				if filterKey != "" {
 					// If not inclues both, do not append current item to the list result.
@@ -651,6 +711,49 @@ func ListResource(nsId string, resourceType string, filterKey string, filterVal
 				res = append(res, tempObj)
 			}
 			return res, nil
+		case model.StrSqlDb:
+			res := []model.TbSqlDbInfo{}
+			for _, v := range keyValue {
+				tempObj := model.TbSqlDbInfo{}
+				err = json.Unmarshal([]byte(v.Value), &tempObj)
+				if err != nil {
+					log.Error().Err(err).Msg("")
+					return nil, err
+				}
+				if err := decryptSqlDbInfo(&tempObj); err != nil {
+					return nil, err
+				}
+				// Check the JSON body inclues both filterKey and filterVal strings. (assume key and value)
+				if filterKey != "" {
+					// If not inclues both, do not append current item to the list result.
+					itemValueForCompare := strings.ToLower(v.Value)
+					if !(strings.Contains(itemValueForCompare, strings.ToLower(filterKey)) && strings.Contains(itemValueForCompare, strings.ToLower(filterVal))) {
+						continue
+					}
+				}
+				res = append(res, tempObj)
+			}
+			return res, nil
+		case model.StrObjectStorage:
+			res := []model.TbObjectStorageInfo{}
+			for _, v := range keyValue {
+				tempObj := model.TbObjectStorageInfo{}
+				err = json.Unmarshal([]byte(v.Value), &tempObj)
+				if err != nil {
+					log.Error().Err(err).Msg("")
+					return nil, err
+				}
+				// Check the JSON body inclues both filterKey and filterVal strings. (assume key and value)
+				if filterKey != "" {
+					// If not inclues both, do not append current item to the list result.
+					itemValueForCompare := strings.ToLower(v.Value)
+					if !(strings.Contains(itemValueForCompare, strings.ToLower(filterKey)) && strings.Contains(itemValueForCompare, strings.ToLower(filterVal))) {
+						continue
+					}
+				}
+				res = append(res, tempObj)
+			}
+			return res, nil
 		}
 
 	} else { //return empty object according to resourceType
@@ -669,6 +772,10 @@ func ListResource(nsId string, resourceType string, filterKey string, filterVal
 			return []model.TbVNetInfo{}, nil
 		case model.StrDataDisk:
 			return []model.TbDataDiskInfo{}, nil
+		case model.StrObjectStorage:
+			return []model.TbObjectStorageInfo{}, nil
+		case model.StrSqlDb:
+			return []model.TbSqlDbInfo{}, nil
 		}
 	}
 
@@ -693,8 +800,7 @@ func GetAssociatedObjectCount(nsId string, resourceType string, resourceId strin
 	check, err := CheckResource(nsId, resourceType, resourceId)
 
 	if !check {
-		errString := "The " + resourceType + " " + resourceId + " does not exist."
-		err := fmt.Errorf(errString)
+		err := common.NewResourceNotFoundError(resourceType, resourceId)
 		return -1, err
 	}
 
@@ -738,8 +844,7 @@ func GetAssociatedObjectList(nsId string, resourceType string, resourceId string
 	check, err := CheckResource(nsId, resourceType, resourceId)
 
 	if !check {
-		errString := "The " + resourceType + " " + resourceId + " does not exist."
-		err := fmt.Errorf(errString)
+		err := common.NewResourceNotFoundError(resourceType, resourceId)
 		return nil, err
 	}
 
@@ -793,8 +898,7 @@ func UpdateAssociatedObjectList(nsId string, resourceType string, resourceId str
 		check, err := CheckResource(nsId, resourceType, resourceId)
 
 		if !check {
-			errString := "The " + resourceType + " " + resourceId + " does not exist."
-			err := fmt.Errorf(errString)
+			err := common.NewResourceNotFoundError(resourceType, resourceId)
 			return -1, err
 		}
 
@@ -881,6 +985,22 @@ func UpdateAssociatedObjectList(nsId string, resourceType string, resourceId str
 	return nil, err
 }
 
+// GetResourceRevision returns the revision of the stored TB Resource object (changed whenever the object is updated)
+func GetResourceRevision(nsId string, resourceType string, resourceId string) (string, error) {
+	if err := common.CheckString(nsId); err != nil {
+		return "", err
+	}
+	return common.GetKvRevision(common.GenResourceKey(nsId, resourceType, resourceId))
+}
+
+// GetResourceListRevision returns the revision of all stored TB Resource objects of given resourceType
+func GetResourceListRevision(nsId string, resourceType string) (string, error) {
+	if err := common.CheckString(nsId); err != nil {
+		return "", err
+	}
+	return common.GetKvRevision("/ns/" + nsId + "/resources/" + resourceType)
+}
+
 // GetResource returns the requested TB Resource object
 func GetResource(nsId string, resourceType string, resourceId string) (interface{}, error) {
 
@@ -902,8 +1022,7 @@ func GetResource(nsId string, resourceType string, resourceId string) (interface
 	}
 
 	if !check {
-		errString := fmt.Sprintf("The %s %s does not exist.", resourceType, resourceId)
-		err := fmt.Errorf(errString)
+		err := common.NewResourceNotFoundError(resourceType, resourceId)
 		return nil, err
 	}
 
@@ -995,6 +1114,9 @@ func GetResource(nsId string, resourceType string, resourceId string) (interface
 				log.Error().Err(err).Msg("")
 				return nil, err
 			}
+			if err := decryptSshKeyInfo(&res); err != nil {
+				return nil, err
+			}
 			return res, nil
 		case model.StrVNet:
 			res := model.TbVNetInfo{}
@@ -1048,6 +1170,25 @@ func GetResource(nsId string, resourceType string, resourceId string) (interface
 			fmt.Printf("res.Status: %s \n", res.Status) // for debug
 			UpdateResourceObject(nsId, model.StrDataDisk, res)
 
+			return res, nil
+		case model.StrObjectStorage:
+			res := model.TbObjectStorageInfo{}
+			err = json.Unmarshal([]byte(keyValue.Value), &res)
+			if err != nil {
+				log.Error().Err(err).Msg("")
+				return nil, err
+			}
+			return res, nil
+		case model.StrSqlDb:
+			res := model.TbSqlDbInfo{}
+			err = json.Unmarshal([]byte(keyValue.Value), &res)
+			if err != nil {
+				log.Error().Err(err).Msg("")
+				return nil, err
+			}
+			if err := decryptSqlDbInfo(&res); err != nil {
+				return nil, err
+			}
 			return res, nil
 		}
 
@@ -1100,7 +1241,9 @@ func CheckResource(nsId string, resourceType string, resourceId string) (bool, e
 		resourceType == model.StrSpec ||
 		resourceType == model.StrVNet ||
 		resourceType == model.StrSecurityGroup ||
-		resourceType == model.StrDataDisk {
+		resourceType == model.StrDataDisk ||
+		resourceType == model.StrObjectStorage ||
+		resourceType == model.StrSqlDb {
 		//resourceType == "subnet" ||
 		//resourceType == "publicIp" ||
 		//resourceType == "vNic" {
@@ -1313,6 +1456,7 @@ func LoadAssets() (model.IdList, error) {
 		wg.Add(1)
 		go func(connConfig model.ConnConfig) {
 			defer wg.Done()
+			InvalidateLookupCache(LookupCacheSpec, connConfig.ConfigName)
 			specsInConnection, err := LookupSpecList(connConfig.ConfigName)
 			if err != nil {
 				log.Error().Err(err).Msgf("Cannot LookupSpecList in %s", connConfig.ConfigName)
@@ -1700,6 +1844,7 @@ func LoadAssets() (model.IdList, error) {
 						tmpImageInfo.GuestOS = osType
 						tmpImageInfo.Description = description
 						tmpImageInfo.InfraType = expandedInfraType
+						NormalizeImageInfo(&tmpImageInfo, providerName)
 
 						tmpImageList = append(tmpImageList, tmpImageInfo)
 
@@ -1768,8 +1913,17 @@ func LoadAssets() (model.IdList, error) {
 	return regiesteredIds, nil
 }
 
+// provenanceVia returns the default way (model.CreatedVia*) an object is created with the given option
+func provenanceVia(option string) string {
+	if option == "register" {
+		return model.CreatedViaRegistered
+	}
+	return model.CreatedViaManual
+}
+
 // CreateSharedResource is to register default resource from asset files (../assets/*.csv)
-func CreateSharedResource(nsId string, resType string, connectionName string) error {
+func CreateSharedResource(ctx context.Context, nsId string, resType string, connectionName string) error {
+	ctx = common.WithCreatedVia(ctx, model.CreatedViaSharedResource)
 
 	// Check 'nsId' namespace.
 	_, err := common.GetNs(nsId)
@@ -1811,14 +1965,42 @@ func CreateSharedResource(nsId string, resType string, connectionName string) er
 		log.Error().Err(err).Msg("Failed to CreateSharedResource")
 		return err
 	}
-	sliceIndex = (sliceIndex % 254) + 1
+
+	// Defaults of the namespace (merged over the global defaults) apply to the resources created from now on
+	defaults, err := getEffectiveResourceDefaults(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
 
 	//resourceName := connectionName
 	// Default resource name has this pattern (nsId + "-shared-" + connectionName)
 	resourceName := nsId + model.StrSharedResourceName + connectionName
+	sshKeyName := formatSharedSshKeyName(defaults.SshKeyNameFormat, nsId, connectionName)
 	description := "Generated Default Resource"
 
+	// Serialize the creation of shared resources in the namespace (concurrent requests may create the same ones)
+	unlock, err := common.LockNs(nsId, "sharedResource")
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	defer unlock()
+
+	sharedResourceTypes := map[string]string{"vnet": model.StrVNet, "sshkey": model.StrSSHKey, "sg": model.StrSecurityGroup, "securitygroup": model.StrSecurityGroup}
 	for _, resType := range resList {
+		// the resource may be created by another request while waiting for the lock
+		if tbResType, ok := sharedResourceTypes[resType]; ok {
+			name := resourceName
+			if tbResType == model.StrSSHKey {
+				name = sshKeyName
+			}
+			if exists, err := CheckResource(nsId, tbResType, name); err == nil && exists {
+				log.Info().Msgf("Shared %s %s already exists", tbResType, name)
+				continue
+			}
+		}
+
 		if resType == "vnet" {
 			log.Debug().Msg("vnet")
 
@@ -1827,8 +2009,13 @@ func CreateSharedResource(nsId string, resType string, connectionName string) er
 			reqTmp.Name = resourceName
 			reqTmp.Description = description
 
-			// set isolated private address space for each cloud region (10.i.0.0/16)
-			reqTmp.CidrBlock = "10." + strconv.Itoa(sliceIndex) + ".0.0/16"
+			// set isolated private address space for each cloud region (10.i.0.0/16 by default)
+			vNetCidr, subnetCidrs, err := sharedVNetCidrs(defaults, sliceIndex)
+			if err != nil {
+				log.Error().Err(err).Msg("")
+				return err
+			}
+			reqTmp.CidrBlock = vNetCidr
 			if strings.EqualFold(provider, "cloudit") {
 				// CLOUDIT: the list of subnets that can be created is
 				// 10.0.4.0/22,10.0.8.0/22,10.0.12.0/22,10.0.28.0/22,10.0.32.0/22,
@@ -1848,21 +2035,19 @@ func CreateSharedResource(nsId string, resType string, connectionName string) er
 				reqTmp.CidrBlock = "10.0.40.0/22"
 			}
 
-			// Consist 2 subnets (10.i.0.0/18, 10.i.64.0/18)
-			// Reserve spaces for tentative 2 subnets (10.i.128.0/18, 10.i.192.0/18)
+			// Consist 2 subnets (10.i.0.0/18, 10.i.64.0/18 by default)
+			// Reserve spaces for tentative 2 subnets (10.i.128.0/18, 10.i.192.0/18 by default)
 			subnetName := reqTmp.Name
-			subnetCidr := "10." + strconv.Itoa(sliceIndex) + ".0.0/18"
-			subnet := model.TbSubnetReq{Name: subnetName, IPv4_CIDR: subnetCidr}
+			subnet := model.TbSubnetReq{Name: subnetName, IPv4_CIDR: subnetCidrs[0]}
 			reqTmp.SubnetInfoList = append(reqTmp.SubnetInfoList, subnet)
 
 			subnetName = reqTmp.Name + "-01"
-			subnetCidr = "10." + strconv.Itoa(sliceIndex) + ".64.0/18"
-			subnet = model.TbSubnetReq{Name: subnetName, IPv4_CIDR: subnetCidr}
+			subnet = model.TbSubnetReq{Name: subnetName, IPv4_CIDR: subnetCidrs[1]}
 			reqTmp.SubnetInfoList = append(reqTmp.SubnetInfoList, subnet)
 
 			common.PrintJsonPretty(reqTmp)
 
-			resultInfo, err := CreateVNet(nsId, &reqTmp)
+			resultInfo, err := CreateVNet(ctx, nsId, &reqTmp)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to create vNet")
 				return err
@@ -1879,15 +2064,13 @@ func CreateSharedResource(nsId string, resType string, connectionName string) er
 
 			reqTmp.VNetId = resourceName
 
-			// open all firewall for default securityGroup
-			rule := model.TbFirewallRuleInfo{FromPort: "1", ToPort: "65535", IPProtocol: "tcp", Direction: "inbound", CIDR: "0.0.0.0/0"}
+			// rules of the default securityGroup (open all firewall by default)
 			var ruleList []model.TbFirewallRuleInfo
-			ruleList = append(ruleList, rule)
-			rule = model.TbFirewallRuleInfo{FromPort: "1", ToPort: "65535", IPProtocol: "udp", Direction: "inbound", CIDR: "0.0.0.0/0"}
-			ruleList = append(ruleList, rule)
-			// CloudIt only offers tcp, udp Protocols
-			if !strings.EqualFold(provider, "cloudit") {
-				rule = model.TbFirewallRuleInfo{FromPort: "-1", ToPort: "-1", IPProtocol: "icmp", Direction: "inbound", CIDR: "0.0.0.0/0"}
+			for _, rule := range defaults.SecurityGroupRules {
+				// CloudIt only offers tcp, udp Protocols
+				if strings.EqualFold(provider, "cloudit") && strings.EqualFold(rule.IPProtocol, "icmp") {
+					continue
+				}
 				ruleList = append(ruleList, rule)
 			}
 
@@ -1896,7 +2079,7 @@ func CreateSharedResource(nsId string, resType string, connectionName string) er
 
 			common.PrintJsonPretty(reqTmp)
 
-			resultInfo, err := CreateSecurityGroup(nsId, &reqTmp, "")
+			resultInfo, err := CreateSecurityGroup(ctx, nsId, &reqTmp, "")
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to create SecurityGroup")
 				return err
@@ -1909,12 +2092,12 @@ func CreateSharedResource(nsId string, resType string, connectionName string) er
 			reqTmp := model.TbSshKeyReq{}
 
 			reqTmp.ConnectionName = connectionName
-			reqTmp.Name = resourceName
+			reqTmp.Name = sshKeyName
 			reqTmp.Description = description
 
 			common.PrintJsonPretty(reqTmp)
 
-			_, err := CreateSshKey(nsId, &reqTmp, "")
+			_, err := CreateSshKey(ctx, nsId, &reqTmp, "")
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to create SshKey")
 				return err
@@ -1947,12 +2130,22 @@ func DelAllSharedResources(nsId string) (model.IdList, error) {
 	}
 	output.IdList = append(output.IdList, list.IdList...)
 
-	list, err = DelAllResources(nsId, model.StrSSHKey, matchedSubstring, "false")
+	// sshKeys may follow the naming convention of the namespace
+	sshKeyIds, err := ListResourceId(nsId, model.StrSSHKey)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		output.IdList = append(output.IdList, err.Error())
 	}
-	output.IdList = append(output.IdList, list.IdList...)
+	for _, id := range sshKeyIds {
+		if !IsSharedResourceName(nsId, model.StrSSHKey, id) {
+			continue
+		}
+		if err := DelResource(nsId, model.StrSSHKey, id, "false"); err != nil {
+			output.IdList = append(output.IdList, "[Failed] "+model.StrSSHKey+": "+id+" ("+err.Error()+")")
+			continue
+		}
+		output.IdList = append(output.IdList, "[Done] "+model.StrSSHKey+": "+id)
+	}
 
 	list, err = DelAllResources(nsId, model.StrVNet, matchedSubstring, "false")
 	if err != nil {
@@ -2107,6 +2300,14 @@ func GetCspResourceName(nsId string, resourceType string, resourceId string) (st
 		content := model.ResourceIds{}
 		json.Unmarshal([]byte(keyValue.Value), &content)
 		return content.CspResourceName, nil
+	case model.StrObjectStorage:
+		content := model.ResourceIds{}
+		json.Unmarshal([]byte(keyValue.Value), &content)
+		return content.CspResourceName, nil
+	case model.StrSqlDb:
+		content := model.ResourceIds{}
+		json.Unmarshal([]byte(keyValue.Value), &content)
+		return content.CspResourceName, nil
 
 	default:
 		return "", fmt.Errorf("invalid resourceType")
@@ -18,6 +18,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
@@ -269,3 +272,189 @@ func RegisterCustomImageWithId(nsId string, u *model.TbCustomImageReq) (model.Tb
 	}
 	return content, nil
 }
+
+// customImageCopyPollInterval is the interval to check the status of a copied custom image
+const customImageCopyPollInterval = 20 * time.Second
+
+// customImageCopyTimeout returns the time limit of copying a custom image (TB_CUSTOM_IMAGE_COPY_TIMEOUT_MIN, default 60)
+func customImageCopyTimeout() time.Duration {
+	minutes, err := strconv.Atoi(common.NVL(os.Getenv("TB_CUSTOM_IMAGE_COPY_TIMEOUT_MIN"), "60"))
+	if err != nil || minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// CopyCustomImage copies the custom image to the region of the target connection.
+// It returns the new custom image object (status: Copying) right after the copy is requested,
+// and the status is tracked in background until the copy is available.
+// NotSupportedError is returned up front if the provider does not support copying images,
+// and PreconditionFailedError if the detected CB-Spider does not provide the copy API.
+func CopyCustomImage(nsId string, customImageId string, req *model.TbCustomImageCopyReq) (model.TbCustomImageInfo, error) {
+
+	resourceType := model.StrCustomImage
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	err = common.CheckString(customImageId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	err = validate.Struct(req)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+
+	keyValue, err := kvstore.GetKv(common.GenResourceKey(nsId, resourceType, customImageId))
+	if keyValue == (kvstore.KeyValue{}) || err != nil {
+		err := fmt.Errorf("Failed to find the customImage %s in ns %s", customImageId, nsId)
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	source := model.TbCustomImageInfo{}
+	err = json.Unmarshal([]byte(keyValue.Value), &source)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	if source.Status != model.MyImageAvailable {
+		err := fmt.Errorf("The customImage %s is not available to copy (status: %s)", customImageId, source.Status)
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+
+	sourceConn, err := common.GetConnConfig(source.ConnectionName)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	targetConn, err := common.GetConnConfig(req.TargetConnectionName)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	providerName := strings.ToLower(sourceConn.ProviderName)
+	if err := common.CheckCapability(providerName, common.CapabilityCustomImageCopy, "copying a customImage"); err != nil {
+		return model.TbCustomImageInfo{}, err
+	}
+	if err := common.CheckSpiderFeature(model.SpiderFeatureMyImageCopy, "copying a customImage"); err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	if !strings.EqualFold(providerName, targetConn.ProviderName) {
+		err := fmt.Errorf("The target connection %s (%s) is not of the provider of the customImage (%s)", targetConn.ConfigName, targetConn.ProviderName, sourceConn.ProviderName)
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	if sourceConn.RegionDetail.RegionName == targetConn.RegionDetail.RegionName {
+		err := fmt.Errorf("The customImage %s is already in the region %s", customImageId, targetConn.RegionDetail.RegionName)
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = ToNamingRuleCompatible(source.Id + "-" + targetConn.ConfigName)
+	}
+	err = common.CheckString(name)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+	check, err := CheckResource(nsId, resourceType, name)
+	if check {
+		err := fmt.Errorf("The customImage " + name + " already exists.")
+		return model.TbCustomImageInfo{}, err
+	}
+	if err != nil {
+		err := fmt.Errorf("Failed to check the existence of the customImage " + name + ".")
+		return model.TbCustomImageInfo{}, err
+	}
+
+	// [Via Spider] Request the copy in the target region
+	client := resty.New()
+	client.SetTimeout(2 * time.Minute)
+	requestBody := model.SpiderMyImageCopyReq{ConnectionName: targetConn.ConfigName}
+	requestBody.ReqInfo.Name = name
+	requestBody.ReqInfo.SourceRegion = sourceConn.RegionDetail.RegionName
+	requestBody.ReqInfo.SourceMyImage = source.CspResourceId
+	callResult := model.SpiderMyImageInfo{}
+
+	err = common.ExecuteHttpRequest(
+		client,
+		"POST",
+		fmt.Sprintf("%s/myimage/copy", model.SpiderRestUrl),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+		common.WithoutRetry(),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+
+	content := model.TbCustomImageInfo{
+		Name:                name,
+		ConnectionName:      targetConn.ConfigName,
+		SourceVmId:          source.SourceVmId,
+		SourceCustomImageId: source.Id,
+		CspResourceId:       callResult.IId.SystemId,
+		CspResourceName:     callResult.IId.NameId,
+		Description:         source.Description,
+		CreationDate:        callResult.CreatedTime,
+		GuestOS:             source.GuestOS,
+		Status:              model.MyImageCopying,
+		KeyValueList:        callResult.KeyValueList,
+		SystemLabel:         "Copied from customImage " + source.Id,
+	}
+	if callResult.Status == model.MyImageAvailable {
+		content.Status = model.MyImageAvailable
+	}
+
+	content, err = RegisterCustomImageWithInfo(nsId, content)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbCustomImageInfo{}, err
+	}
+
+	if content.Status == model.MyImageCopying {
+		go trackCustomImageCopy(nsId, content)
+	}
+	return content, nil
+}
+
+// trackCustomImageCopy polls the copied custom image until it is available (or failed by timeout) and updates its status
+func trackCustomImageCopy(nsId string, content model.TbCustomImageInfo) {
+	endOperation := common.BeginOperation("copyCustomImage "+nsId+"/"+content.Id, nil)
+	defer endOperation()
+
+	deadline := time.Now().Add(customImageCopyTimeout())
+	for time.Now().Before(deadline) {
+		time.Sleep(customImageCopyPollInterval)
+
+		spiderMyImage, err := LookupMyImage(content.ConnectionName, content.CspResourceName)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Copying the customImage %s is in progress", content.Id)
+			continue
+		}
+		if spiderMyImage.Status == model.MyImageAvailable {
+			content.Status = model.MyImageAvailable
+			content.CreationDate = spiderMyImage.CreatedTime
+			UpdateResourceObject(nsId, model.StrCustomImage, content)
+			log.Info().Msgf("Copied the customImage %s to %s", content.SourceCustomImageId, content.ConnectionName)
+			return
+		}
+	}
+
+	content.Status = model.MyImageFailed
+	UpdateResourceObject(nsId, model.StrCustomImage, content)
+	log.Error().Msgf("Failed to copy the customImage %s to %s within %v", content.SourceCustomImageId, content.ConnectionName, customImageCopyTimeout())
+}
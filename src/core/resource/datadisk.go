@@ -15,9 +15,11 @@ limitations under the License.
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/rs/zerolog/log"
@@ -43,7 +45,7 @@ func TbDataDiskReqStructLevelValidation(sl validator.StructLevel) {
 }
 
 // CreateDataDisk accepts DataDisk creation request, creates and returns an TB dataDisk object
-func CreateDataDisk(nsId string, u *model.TbDataDiskReq, option string) (model.TbDataDiskInfo, error) {
+func CreateDataDisk(ctx context.Context, nsId string, u *model.TbDataDiskReq, option string) (model.TbDataDiskInfo, error) {
 
 	resourceType := model.StrDataDisk
 
@@ -63,6 +65,19 @@ func CreateDataDisk(nsId string, u *model.TbDataDiskReq, option string) (model.T
 
 			return model.TbDataDiskInfo{}, err
 		}
+
+		// Check the quota of the namespace ("default" size is not known before creation)
+		diskSize, _ := strconv.Atoi(u.DiskSize)
+		err = CheckNsQuota(nsId, model.NsQuotaUsage{DataDiskGB: diskSize})
+		if err != nil {
+			return model.TbDataDiskInfo{}, err
+		}
+	}
+
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, u.ConnectionName)
+	if err != nil {
+		return model.TbDataDiskInfo{}, err
 	}
 
 	check, err := CheckResource(nsId, resourceType, u.Name)
@@ -82,10 +97,11 @@ func CreateDataDisk(nsId string, u *model.TbDataDiskReq, option string) (model.T
 	requestBody := model.SpiderDiskReqInfoWrapper{
 		ConnectionName: u.ConnectionName,
 		ReqInfo: model.SpiderDiskInfo{
-			Name:     uid,
-			CSPid:    u.CspResourceId, // for option=register
-			DiskType: u.DiskType,
-			DiskSize: u.DiskSize,
+			Name:           uid,
+			CSPid:          u.CspResourceId, // for option=register
+			DiskType:       u.DiskType,
+			DiskSize:       u.DiskSize,
+			SourceSnapshot: u.SourceSnapshotCspName,
 		},
 	}
 
@@ -149,6 +165,9 @@ func CreateDataDisk(nsId string, u *model.TbDataDiskReq, option string) (model.T
 		Description:          u.Description,
 		IsAutoGenerated:      false,
 	}
+	if option == "register" && tempSpiderDiskInfo.Status == model.DiskAttached {
+		content.OwnerVmCspResourceId = tempSpiderDiskInfo.OwnerVM.SystemId
+	}
 
 	if option == "register" {
 		if u.CspResourceId == "" {
@@ -157,6 +176,7 @@ func CreateDataDisk(nsId string, u *model.TbDataDiskReq, option string) (model.T
 			content.SystemLabel = "Registered from CSP resource"
 		}
 	}
+	content.Provenance = common.NewProvenance(ctx, provenanceVia(option))
 
 	log.Info().Msg("PUT CreateDataDisk")
 	Key := common.GenResourceKey(nsId, resourceType, content.Id)
@@ -183,6 +203,8 @@ func CreateDataDisk(nsId string, u *model.TbDataDiskReq, option string) (model.T
 		model.LabelCreatedTime:     content.CreatedTime.String(),
 		model.LabelConnectionName:  content.ConnectionName,
 	}
+	common.SetSystemLabels(labels, content.ConnectionName)
+	common.SetProvenanceLabels(labels, content.Provenance)
 	err = label.CreateOrUpdateLabel(model.StrDataDisk, uid, Key, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -198,18 +220,23 @@ type TbDataDiskUpsizeReq struct {
 	Description string `json:"description"`
 }
 
+// dataDiskMaxSizeGB is the max size (GB) of a data disk of each provider
+var dataDiskMaxSizeGB = map[string]int{
+	"aws":     16384,
+	"azure":   32767,
+	"gcp":     65536,
+	"alibaba": 32768,
+	"tencent": 32000,
+	"ibm":     16000,
+	"ncp":     2000,
+	"nhn":     2000,
+	"ktcloud": 500,
+}
+
 // UpsizeDataDisk accepts DataDisk upsize request, creates and returns an TB dataDisk object
 func UpsizeDataDisk(nsId string, resourceId string, u *model.TbDataDiskUpsizeReq) (model.TbDataDiskInfo, error) {
 
-	resourceType := model.StrDataDisk
-
-	err := common.CheckString(nsId)
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return model.TbDataDiskInfo{}, err
-	}
-
-	err = validate.Struct(u)
+	err := validate.Struct(u)
 	if err != nil {
 		if _, ok := err.(*validator.InvalidValidationError); ok {
 			log.Err(err).Msg("")
@@ -219,42 +246,89 @@ func UpsizeDataDisk(nsId string, resourceId string, u *model.TbDataDiskUpsizeReq
 		return model.TbDataDiskInfo{}, err
 	}
 
+	diskSize_to_be, err := strconv.Atoi(u.DiskSize)
+	if err != nil {
+		err := fmt.Errorf("Failed to convert the desired disk size (%s) into int.", u.DiskSize)
+		return model.TbDataDiskInfo{}, err
+	}
+
+	result, err := ResizeDataDisk(nsId, resourceId, diskSize_to_be)
+	if err != nil {
+		return model.TbDataDiskInfo{}, err
+	}
+
+	content := result.DataDisk
+	content.Description = u.Description
+
+	Key := common.GenResourceKey(nsId, model.StrDataDisk, content.Id)
+	Val, _ := json.Marshal(content)
+	err = kvstore.Put(Key, string(Val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return content, err
+	}
+	return content, nil
+}
+
+// ResizeDataDisk grows the DataDisk to newSizeGB and returns the updated TB dataDisk object
+// with whether the filesystem must be expanded in the guest OS.
+// An attached dataDisk can be resized only for the providers which support online resize.
+func ResizeDataDisk(nsId string, resourceId string, newSizeGB int) (model.TbDataDiskResizeResult, error) {
+
+	resourceType := model.StrDataDisk
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskResizeResult{}, err
+	}
+
 	check, err := CheckResource(nsId, resourceType, resourceId)
 
 	if !check {
 		err := fmt.Errorf("The dataDisk %s does not exist.", resourceId)
-		return model.TbDataDiskInfo{}, err
+		return model.TbDataDiskResizeResult{}, err
 	}
 
 	if err != nil {
 		err := fmt.Errorf("Failed to check the existence of the dataDisk %s.", resourceId)
-		return model.TbDataDiskInfo{}, err
+		return model.TbDataDiskResizeResult{}, err
 	}
 
 	dataDiskInterface, err := GetResource(nsId, resourceType, resourceId)
 	if err != nil {
 		err := fmt.Errorf("Failed to get the dataDisk object %s.", resourceId)
-		return model.TbDataDiskInfo{}, err
+		return model.TbDataDiskResizeResult{}, err
 	}
 
 	dataDisk := dataDiskInterface.(model.TbDataDiskInfo)
 
 	diskSize_as_is, _ := strconv.Atoi(dataDisk.DiskSize)
-	diskSize_to_be, err := strconv.Atoi(u.DiskSize)
-	if err != nil {
-		err := fmt.Errorf("Failed to convert the desired disk size (%s) into int.", u.DiskSize)
-		return model.TbDataDiskInfo{}, err
+	if !(diskSize_as_is < newSizeGB) {
+		err := fmt.Errorf("Desired disk size (%d GB) should be > %s GB.", newSizeGB, dataDisk.DiskSize)
+		return model.TbDataDiskResizeResult{}, err
 	}
 
-	if !(diskSize_as_is < diskSize_to_be) {
-		err := fmt.Errorf("Desired disk size (%s GB) should be > %s GB.", u.DiskSize, dataDisk.DiskSize)
-		return model.TbDataDiskInfo{}, err
+	connConfig, err := common.GetConnConfig(dataDisk.ConnectionName)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskResizeResult{}, err
+	}
+	providerName := strings.ToLower(connConfig.ProviderName)
+	if maxSize, ok := dataDiskMaxSizeGB[providerName]; ok && newSizeGB > maxSize {
+		err := fmt.Errorf("Desired disk size (%d GB) exceeds the limit of %s (%d GB).", newSizeGB, connConfig.ProviderName, maxSize)
+		return model.TbDataDiskResizeResult{}, err
+	}
+	attached := dataDisk.Status == model.DiskAttached
+	if attached && !common.IsCapabilitySupported(providerName, common.CapabilityDataDiskOnlineResize) {
+		err := fmt.Errorf("%s does not support resizing an attached disk; detach the dataDisk %s from %v first.", connConfig.ProviderName, resourceId, dataDisk.AssociatedObjectList)
+		return model.TbDataDiskResizeResult{}, err
 	}
 
 	requestBody := model.SpiderDiskUpsizeReqWrapper{
 		ConnectionName: dataDisk.ConnectionName,
 		ReqInfo: model.SpiderDiskUpsizeReq{
-			Size: u.DiskSize,
+			Size: strconv.Itoa(newSizeGB),
 		},
 	}
 
@@ -276,7 +350,7 @@ func UpsizeDataDisk(nsId string, resourceId string, u *model.TbDataDiskUpsizeReq
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		err := fmt.Errorf("an error occurred while requesting to CB-Spider")
-		return model.TbDataDiskInfo{}, err
+		return model.TbDataDiskResizeResult{}, err
 	}
 
 	fmt.Printf("HTTP Status code: %d \n", resp.StatusCode())
@@ -285,28 +359,28 @@ func UpsizeDataDisk(nsId string, resourceId string, u *model.TbDataDiskUpsizeReq
 		err := fmt.Errorf(string(resp.Body()))
 		fmt.Println("body: ", string(resp.Body()))
 		log.Error().Err(err).Msg("")
-		return model.TbDataDiskInfo{}, err
+		return model.TbDataDiskResizeResult{}, err
 	}
 
-	/*
-		isSuccessful := resp.Result().(bool)
-		if isSuccessful == false {
-			err := fmt.Errorf("Failed to upsize the dataDisk %s", resourceId)
-			return model.TbDataDiskInfo{}, err
-		}
-	*/
-
 	content := dataDisk
-	content.DiskSize = u.DiskSize
-	content.Description = u.Description
+	content.DiskSize = strconv.Itoa(newSizeGB)
 
-	log.Info().Msg("PUT UpsizeDataDisk")
+	log.Info().Msg("PUT ResizeDataDisk")
 	Key := common.GenResourceKey(nsId, resourceType, content.Id)
 	Val, _ := json.Marshal(content)
 	err = kvstore.Put(Key, string(Val))
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return content, err
+		return model.TbDataDiskResizeResult{DataDisk: content}, err
 	}
-	return content, nil
+
+	result := model.TbDataDiskResizeResult{DataDisk: content}
+	if attached {
+		// the block device grows online, but the partition and filesystem on it are not expanded by the providers
+		result.FilesystemExpansionRequired = true
+		result.Message = fmt.Sprintf("Expand the partition and filesystem of the disk in the guest OS of %v (e.g., growpart, resize2fs or xfs_growfs)", dataDisk.AssociatedObjectList)
+	} else {
+		result.Message = "The filesystem on the disk (if any) can be expanded after the disk is attached"
+	}
+	return result, nil
 }
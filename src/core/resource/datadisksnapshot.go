@@ -0,0 +1,332 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// getDataDisk returns the TB dataDisk object
+func getDataDisk(nsId string, dataDiskId string) (model.TbDataDiskInfo, error) {
+	keyValue, err := kvstore.GetKv(common.GenResourceKey(nsId, model.StrDataDisk, dataDiskId))
+	if keyValue == (kvstore.KeyValue{}) || err != nil {
+		err := fmt.Errorf("The dataDisk %s does not exist.", dataDiskId)
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskInfo{}, err
+	}
+	dataDisk := model.TbDataDiskInfo{}
+	err = json.Unmarshal([]byte(keyValue.Value), &dataDisk)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskInfo{}, err
+	}
+	return dataDisk, nil
+}
+
+// SnapshotDataDisk creates a snapshot of the dataDisk and returns the TB dataDisk snapshot object.
+// The dataDisk must be Available; an Attached dataDisk requires the force flag since its data may not be consistent.
+func SnapshotDataDisk(nsId string, dataDiskId string, u *model.TbDataDiskSnapshotReq) (model.TbDataDiskSnapshotInfo, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+	err = validate.Struct(u)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+	err = common.CheckString(u.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+
+	dataDisk, err := getDataDisk(nsId, dataDiskId)
+	if err != nil {
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+	switch dataDisk.Status {
+	case model.DiskAvailable:
+	case model.DiskAttached:
+		if !u.Force {
+			err := fmt.Errorf("The dataDisk %s is attached to %v; detach it or set force to take a snapshot which may not be consistent", dataDiskId, dataDisk.AssociatedObjectList)
+			log.Error().Err(err).Msg("")
+			return model.TbDataDiskSnapshotInfo{}, err
+		}
+	default:
+		err := fmt.Errorf("The dataDisk %s is not in a consistent state to take a snapshot (status: %s)", dataDiskId, dataDisk.Status)
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+
+	key := common.GenChildResourceKey(nsId, model.StrDataDiskSnapshot, dataDiskId, u.Name)
+	keyValue, _ := kvstore.GetKv(key)
+	if keyValue != (kvstore.KeyValue{}) {
+		err := fmt.Errorf("The snapshot %s of the dataDisk %s already exists.", u.Name, dataDiskId)
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+
+	uid := common.GenUid()
+	requestBody := model.SpiderDiskSnapshotReqWrapper{
+		ConnectionName: dataDisk.ConnectionName,
+		ReqInfo: model.SpiderDiskSnapshotReq{
+			Name:       uid,
+			SourceDisk: dataDisk.CspResourceName,
+		},
+	}
+	callResult := model.SpiderDiskSnapshotInfo{}
+
+	client := resty.New()
+	err = common.ExecuteHttpRequest(
+		client,
+		"POST",
+		fmt.Sprintf("%s/disksnapshot", model.SpiderRestUrl),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+
+	content := model.TbDataDiskSnapshotInfo{
+		Id:               u.Name,
+		Uid:              uid,
+		Name:             u.Name,
+		CspResourceId:    callResult.IId.SystemId,
+		CspResourceName:  callResult.IId.NameId,
+		ConnectionName:   dataDisk.ConnectionName,
+		SourceDataDiskId: dataDiskId,
+		DiskType:         dataDisk.DiskType,
+		DiskSize:         common.NVL(callResult.DiskSize, dataDisk.DiskSize),
+		Status:           callResult.Status,
+		CreatedTime:      callResult.CreatedTime,
+		KeyValueList:     callResult.KeyValueList,
+		Description:      u.Description,
+	}
+	if content.Status == "" {
+		content.Status = model.DiskCreating
+	}
+
+	val, _ := json.Marshal(content)
+	err = kvstore.Put(key, string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return content, err
+	}
+	return content, nil
+}
+
+// ListDataDiskSnapshots returns the list of TB snapshot objects of the dataDisk
+func ListDataDiskSnapshots(nsId string, dataDiskId string) ([]model.TbDataDiskSnapshotInfo, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
+	prefix := common.GenChildResourceKey(nsId, model.StrDataDiskSnapshot, dataDiskId, "")
+	keyValue, err := kvstore.GetKvList(prefix)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
+	result := []model.TbDataDiskSnapshotInfo{}
+	for _, v := range keyValue {
+		if strings.Contains(strings.TrimPrefix(v.Key, prefix), "/") {
+			continue
+		}
+		snapshot := model.TbDataDiskSnapshotInfo{}
+		if err := json.Unmarshal([]byte(v.Value), &snapshot); err != nil {
+			log.Warn().Err(err).Msgf("Failed to read the snapshot %s", v.Key)
+			continue
+		}
+		result = append(result, snapshot)
+	}
+	return result, nil
+}
+
+// getDataDiskSnapshot returns the TB snapshot object of the dataDisk
+func getDataDiskSnapshot(nsId string, dataDiskId string, snapshotId string) (model.TbDataDiskSnapshotInfo, error) {
+	keyValue, err := kvstore.GetKv(common.GenChildResourceKey(nsId, model.StrDataDiskSnapshot, dataDiskId, snapshotId))
+	if keyValue == (kvstore.KeyValue{}) || err != nil {
+		err := fmt.Errorf("The snapshot %s of the dataDisk %s does not exist.", snapshotId, dataDiskId)
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+	snapshot := model.TbDataDiskSnapshotInfo{}
+	err = json.Unmarshal([]byte(keyValue.Value), &snapshot)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskSnapshotInfo{}, err
+	}
+	return snapshot, nil
+}
+
+// DeleteDataDiskSnapshot deletes the snapshot of the dataDisk in the CSP and its TB object
+func DeleteDataDiskSnapshot(nsId string, dataDiskId string, snapshotId string) error {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	snapshot, err := getDataDiskSnapshot(nsId, dataDiskId, snapshotId)
+	if err != nil {
+		return err
+	}
+
+	requestBody := model.SpiderConnectionName{ConnectionName: snapshot.ConnectionName}
+	callResult := model.SimpleMsg{}
+
+	client := resty.New()
+	err = common.ExecuteHttpRequest(
+		client,
+		"DELETE",
+		fmt.Sprintf("%s/disksnapshot/%s", model.SpiderRestUrl, url.QueryEscape(snapshot.CspResourceName)),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	err = kvstore.Delete(common.GenChildResourceKey(nsId, model.StrDataDiskSnapshot, dataDiskId, snapshotId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	return nil
+}
+
+// CreateDataDiskFromSnapshot creates a new dataDisk from the snapshot of the dataDisk.
+// The new dataDisk is created by the connection of the snapshot or another connection in the same region (e.g., for another zone).
+func CreateDataDiskFromSnapshot(ctx context.Context, nsId string, dataDiskId string, snapshotId string, u *model.TbDataDiskFromSnapshotReq) (model.TbDataDiskInfo, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskInfo{}, err
+	}
+	err = validate.Struct(u)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbDataDiskInfo{}, err
+	}
+
+	snapshot, err := getDataDiskSnapshot(nsId, dataDiskId, snapshotId)
+	if err != nil {
+		return model.TbDataDiskInfo{}, err
+	}
+	if snapshot.Status != model.DiskAvailable {
+		// refresh the status since the snapshot may have been completed after it was requested
+		spiderSnapshot := model.SpiderDiskSnapshotInfo{}
+		requestBody := model.SpiderConnectionName{ConnectionName: snapshot.ConnectionName}
+		client := resty.New()
+		err = common.ExecuteHttpRequest(
+			client,
+			"GET",
+			fmt.Sprintf("%s/disksnapshot/%s", model.SpiderRestUrl, url.QueryEscape(snapshot.CspResourceName)),
+			nil,
+			common.SetUseBody(requestBody),
+			&requestBody,
+			&spiderSnapshot,
+			common.VeryShortDuration,
+		)
+		if err == nil && spiderSnapshot.Status != "" {
+			snapshot.Status = spiderSnapshot.Status
+			val, _ := json.Marshal(snapshot)
+			kvstore.Put(common.GenChildResourceKey(nsId, model.StrDataDiskSnapshot, dataDiskId, snapshotId), string(val))
+		}
+		if snapshot.Status != model.DiskAvailable {
+			err := fmt.Errorf("The snapshot %s is not available yet (status: %s)", snapshotId, snapshot.Status)
+			log.Error().Err(err).Msg("")
+			return model.TbDataDiskInfo{}, err
+		}
+	}
+
+	connectionName := snapshot.ConnectionName
+	if u.ConnectionName != "" && u.ConnectionName != snapshot.ConnectionName {
+		sourceConn, err := common.GetConnConfig(snapshot.ConnectionName)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbDataDiskInfo{}, err
+		}
+		targetConn, err := common.GetConnConfig(u.ConnectionName)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbDataDiskInfo{}, err
+		}
+		if !strings.EqualFold(sourceConn.ProviderName, targetConn.ProviderName) || sourceConn.RegionDetail.RegionName != targetConn.RegionDetail.RegionName {
+			err := fmt.Errorf("The connection %s is not in the region of the snapshot (%s %s)", u.ConnectionName, sourceConn.ProviderName, sourceConn.RegionDetail.RegionName)
+			log.Error().Err(err).Msg("")
+			return model.TbDataDiskInfo{}, err
+		}
+		connectionName = u.ConnectionName
+	}
+
+	diskSize := common.NVL(u.DiskSize, snapshot.DiskSize)
+	snapshotSize, _ := strconv.Atoi(snapshot.DiskSize)
+	if size, err := strconv.Atoi(diskSize); err != nil || size < snapshotSize {
+		err := fmt.Errorf("Disk size (%s GB) should be >= the size of the snapshot (%s GB).", diskSize, snapshot.DiskSize)
+		return model.TbDataDiskInfo{}, err
+	}
+
+	req := model.TbDataDiskReq{
+		Name:                  u.Name,
+		ConnectionName:        connectionName,
+		DiskType:              common.NVL(u.DiskType, snapshot.DiskType),
+		DiskSize:              diskSize,
+		Description:           u.Description,
+		SourceSnapshotCspName: snapshot.CspResourceName,
+	}
+	content, err := CreateDataDisk(ctx, nsId, &req, "")
+	if err != nil {
+		return content, err
+	}
+
+	content.SystemLabel = "Created from the snapshot " + snapshotId + " of the dataDisk " + dataDiskId
+	val, _ := json.Marshal(content)
+	err = kvstore.Put(common.GenResourceKey(nsId, model.StrDataDisk, content.Id), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return content, err
+	}
+	return content, nil
+}
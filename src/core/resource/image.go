@@ -95,6 +95,7 @@ func GetImageInfoFromLookupImage(nsId string, u model.TbImageReq) (model.TbImage
 	content.Id = u.Name
 	content.Name = u.Name
 	content.AssociatedObjectList = []string{}
+	normalizeImageInfoOfConnection(&content)
 
 	return content, nil
 }
@@ -204,6 +205,7 @@ func RegisterImageWithId(nsId string, u *model.TbImageReq, update bool, RDBonly
 	content.Id = u.Name
 	content.Name = u.Name
 	content.AssociatedObjectList = []string{}
+	normalizeImageInfoOfConnection(&content)
 
 	if !RDBonly {
 		Key := common.GenResourceKey(nsId, resourceType, content.Id)
@@ -305,42 +307,49 @@ func LookupImageList(connConfig string) (model.SpiderImageList, error) {
 		return content, err
 	}
 
-	url := model.SpiderRestUrl + "/vmimage"
+	payload, cached := getLookupCache(LookupCacheImage, connConfig)
+	if !cached {
+		url := model.SpiderRestUrl + "/vmimage"
 
-	// Create Req body
-	requestBody := model.SpiderConnectionName{}
-	requestBody.ConnectionName = connConfig
+		// Create Req body
+		requestBody := model.SpiderConnectionName{}
+		requestBody.ConnectionName = connConfig
 
-	client := resty.New().SetCloseConnection(true)
-	client.SetAllowGetMethodPayload(true)
+		client := resty.New().SetCloseConnection(true)
+		client.SetAllowGetMethodPayload(true)
 
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(requestBody).
-		SetResult(&model.SpiderImageList{}). // or SetResult(AuthSuccess{}).
-		//SetError(&AuthError{}).       // or SetError(AuthError{}).
-		Get(url)
+		resp, err := client.R().
+			SetHeader("Content-Type", "application/json").
+			SetBody(requestBody).
+			Get(url)
 
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		content := model.SpiderImageList{}
-		err := fmt.Errorf("an error occurred while requesting to CB-Spider")
-		return content, err
-	}
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			content := model.SpiderImageList{}
+			err := fmt.Errorf("an error occurred while requesting to CB-Spider")
+			return content, err
+		}
 
-	log.Debug().Msg(string(resp.Body()))
+		switch {
+		case resp.StatusCode() >= 400 || resp.StatusCode() < 200:
+			err := fmt.Errorf(string(resp.Body()))
+			log.Error().Err(err).Msg("")
+			content := model.SpiderImageList{}
+			return content, err
+		}
 
-	// fmt.Println("HTTP Status code: " + strconv.Itoa(resp.StatusCode()))
-	switch {
-	case resp.StatusCode() >= 400 || resp.StatusCode() < 200:
-		err := fmt.Errorf(string(resp.Body()))
-		log.Error().Err(err).Msg("")
-		content := model.SpiderImageList{}
-		return content, err
+		payload = json.RawMessage(resp.Body())
+		putLookupCache(LookupCacheImage, connConfig, payload)
 	}
 
-	temp := resp.Result().(*model.SpiderImageList)
-	return *temp, nil
+	// decode the raw payload for each call so that callers do not share the cached object
+	content := model.SpiderImageList{}
+	err := json.Unmarshal(payload, &content)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SpiderImageList{}, err
+	}
+	return content, nil
 
 }
 
@@ -359,6 +368,18 @@ func LookupImage(connConfig string, imageId string) (model.SpiderImageInfo, erro
 		return content, err
 	}
 
+	// use the cached image list of the connection if available
+	if payload, ok := loadLookupCache(LookupCacheImage, connConfig); ok {
+		imageList := model.SpiderImageList{}
+		if err := json.Unmarshal(payload, &imageList); err == nil {
+			for _, image := range imageList.Image {
+				if image.IId.NameId == imageId || image.IId.SystemId == imageId {
+					return image, nil
+				}
+			}
+		}
+	}
+
 	client := resty.New()
 	client.SetTimeout(2 * time.Minute)
 	url := model.SpiderRestUrl + "/vmimage/" + url.QueryEscape(imageId)
@@ -390,11 +411,17 @@ func LookupImage(connConfig string, imageId string) (model.SpiderImageInfo, erro
 func FetchImagesForConnConfig(connConfig string, nsId string) (imageCount uint, err error) {
 	log.Debug().Msg("FetchImagesForConnConfig(" + connConfig + ")")
 
+	// refresh the cached lookup since the images are repopulated from the latest list
+	InvalidateLookupCache(LookupCacheImage, connConfig)
 	spiderImageList, err := LookupImageList(connConfig)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return 0, err
 	}
+	providerName := ""
+	if connConfigInfo, err := common.GetConnConfig(connConfig); err == nil {
+		providerName = connConfigInfo.ProviderName
+	}
 
 	for _, spiderImage := range spiderImageList.Image {
 		tumblebugImage, err := ConvertSpiderImageToTumblebugImage(spiderImage)
@@ -415,6 +442,7 @@ func FetchImagesForConnConfig(connConfig string, nsId string) (imageCount uint,
 		} else {
 			tumblebugImage.Name = tumblebugImageId
 			tumblebugImage.ConnectionName = connConfig
+			NormalizeImageInfo(&tumblebugImage, providerName)
 
 			_, err := RegisterImageWithInfo(nsId, &tumblebugImage, true)
 			if err != nil {
@@ -478,6 +506,45 @@ func SearchImage(nsId string, keywords ...string) ([]model.TbImageInfo, error) {
 	return tempList, nil
 }
 
+// SearchImageWithOption returns the list of TB image objects matched by keywords and normalized OS metadata.
+// OSVersion matches the exact version or its minor versions (e.g., 22 matches 22.04).
+func SearchImageWithOption(nsId string, req model.SearchImageRequest) ([]model.TbImageInfo, error) {
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
+	tempList := []model.TbImageInfo{}
+
+	sqlQuery := model.ORM.Where("Namespace = ?", nsId)
+
+	for _, keyword := range req.Keywords {
+		keyword = ToNamingRuleCompatible(keyword)
+		sqlQuery = sqlQuery.And("Name LIKE ?", "%"+keyword+"%")
+	}
+	if req.OSDistribution != "" {
+		sqlQuery = sqlQuery.And("OSDistribution = ?", strings.ToLower(req.OSDistribution))
+	}
+	if req.OSVersion != "" {
+		sqlQuery = sqlQuery.And("(OSVersion = ? OR OSVersion LIKE ?)", req.OSVersion, req.OSVersion+".%")
+	}
+	if req.Architecture != "" {
+		sqlQuery = sqlQuery.And("Architecture = ?", strings.ToLower(req.Architecture))
+	}
+	if req.ConnectionName != "" {
+		sqlQuery = sqlQuery.And("ConnectionName = ?", req.ConnectionName)
+	}
+
+	err = sqlQuery.Find(&tempList)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return tempList, err
+	}
+	return tempList, nil
+}
+
 // UpdateImage accepts to-be TB image objects,
 // updates and returns the updated TB image objects
 func UpdateImage(nsId string, imageId string, fieldsToUpdate model.TbImageInfo, RDBonly bool) (model.TbImageInfo, error) {
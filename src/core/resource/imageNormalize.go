@@ -0,0 +1,246 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+// imageOsPattern maps a pattern in the image name to an OS distribution.
+// version (optional) extracts the version from the name; its submatches are joined with '.'.
+type imageOsPattern struct {
+	match        *regexp.Regexp
+	distribution string
+	version      *regexp.Regexp
+}
+
+// providerImageOsPatterns are the naming rules of OS images specific to each provider (checked before the common rules)
+var providerImageOsPatterns = map[string][]imageOsPattern{
+	// e.g., amzn2-ami-hvm-2.0.20240131-x86_64-gp2, al2023-ami-2023.3.20240131.0-kernel-6.1-arm64,
+	// Windows_Server-2022-English-Full-Base-2024.01.16
+	"aws": {
+		{regexp.MustCompile(`amzn2|amazon linux 2([^0]|$)`), model.ImageOsAmazonLinux, regexp.MustCompile(`()(2)`)},
+		{regexp.MustCompile(`al2023|amazon linux 2023`), model.ImageOsAmazonLinux, regexp.MustCompile(`()(2023)`)},
+	},
+	// e.g., Canonical:0001-com-ubuntu-server-jammy:22_04-lts-gen2:latest, RedHat:RHEL:9_2:latest,
+	// MicrosoftWindowsServer:WindowsServer:2022-datacenter:latest
+	"azure": {
+		{regexp.MustCompile(`^canonical:`), model.ImageOsUbuntu, regexp.MustCompile(`:(\d{2})_(\d{2})`)},
+		{regexp.MustCompile(`^redhat:rhel`), model.ImageOsRhel, regexp.MustCompile(`:(\d+)_?(\d*)`)},
+		{regexp.MustCompile(`^microsoftwindowsserver:`), model.ImageOsWindows, regexp.MustCompile(`()(20\d\d)`)},
+	},
+	// e.g., ubuntu-2204-jammy-v20240126, windows-server-2022-dc-v20240111, rhel-9-v20240110
+	"gcp": {
+		{regexp.MustCompile(`^ubuntu-(minimal-)?\d{4}`), model.ImageOsUbuntu, regexp.MustCompile(`ubuntu-(?:minimal-)?(\d{2})(\d{2})`)},
+		{regexp.MustCompile(`^cos-`), model.ImageOsCos, regexp.MustCompile(`cos-(?:[a-z]+-)?()(\d+)`)},
+	},
+	// e.g., SW.VSVR.OS.LNX64.UBNTU.SVR2004.B050, SW.VSVR.OS.LNX64.CNTOS.0703.B050, SW.VSVR.OS.WND64.WND.SVR2019EN.B100
+	"ncp": {
+		{regexp.MustCompile(`ubntu`), model.ImageOsUbuntu, regexp.MustCompile(`ubntu\.svr(\d{2})(\d{2})`)},
+		{regexp.MustCompile(`cntos`), model.ImageOsCentos, regexp.MustCompile(`cntos\.(?:srvr)?0?(\d)0?(\d)`)},
+		{regexp.MustCompile(`rocky`), model.ImageOsRocky, regexp.MustCompile(`rocky\.0?(\d)0?(\d)`)},
+		{regexp.MustCompile(`wnd`), model.ImageOsWindows, regexp.MustCompile(`svr()(20\d\d)`)},
+	},
+	// e.g., ubuntu_22_04_x64_20G_alibase_20240130.vhd, win2022_21H2_x64_dtc_en-us_40G_alibase_20240117.vhd
+	"alibaba": {
+		{regexp.MustCompile(`^ubuntu_`), model.ImageOsUbuntu, regexp.MustCompile(`ubuntu_(\d{2})_(\d{2})`)},
+		{regexp.MustCompile(`^win20`), model.ImageOsWindows, regexp.MustCompile(`win()(20\d\d)`)},
+		{regexp.MustCompile(`^centos_`), model.ImageOsCentos, regexp.MustCompile(`centos_(\d+)_(\d+)`)},
+	},
+}
+
+// imageOsName returns the pattern of an OS name which is not a part of another word (a version may follow it)
+func imageOsName(expr string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^a-z0-9])(?:` + expr + `)(?:[^a-z]|$)`)
+}
+
+// imageOsVersion returns the pattern of a version which is not a part of another number (e.g., a date)
+func imageOsVersion(expr string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^0-9])` + expr + `(?:[^0-9]|$)`)
+}
+
+// commonImageOsPatterns are the naming rules of OS images shared by providers (checked in order)
+var commonImageOsPatterns = []imageOsPattern{
+	{imageOsName(`ubuntu`), model.ImageOsUbuntu, imageOsVersion(`(\d{2})[._-]?(04|10)`)},
+	{imageOsName(`windows|win20\d\d`), model.ImageOsWindows, imageOsVersion(`()(20\d\d)`)},
+	{imageOsName(`rhel|red ?hat`), model.ImageOsRhel, imageOsVersion(`(?:rhel|red ?hat[a-z ]*)[-_ ]?(\d+)(?:[._](\d+))?`)},
+	{imageOsName(`rocky(?: ?linux)?`), model.ImageOsRocky, imageOsVersion(`rocky[a-z -]*?[-_ ]?(\d+)(?:[._](\d+))?`)},
+	{imageOsName(`alma(?: ?linux)?`), model.ImageOsAlma, imageOsVersion(`alma[a-z -]*?[-_ ]?(\d+)(?:[._](\d+))?`)},
+	{imageOsName(`centos(?: ?stream)?`), model.ImageOsCentos, imageOsVersion(`centos[a-z -]*?[-_ ]?(\d+)(?:[._](\d+))?`)},
+	{imageOsName(`debian`), model.ImageOsDebian, imageOsVersion(`debian[-_ ]?(\d+)()`)},
+	{imageOsName(`sles|(?:open)?suse`), model.ImageOsSles, imageOsVersion(`(?:sles|suse)[a-z -]*?[-_ ]?(\d+)(?:[._-]sp(\d+))?`)},
+	{imageOsName(`oracle ?linux`), model.ImageOsOracle, imageOsVersion(`linux[-_ ]?(\d+)(?:[._](\d+))?`)},
+	{imageOsName(`amzn|amazon ?linux`), model.ImageOsAmazonLinux, imageOsVersion(`()(2023|2)`)},
+	{imageOsName(`fedora`), model.ImageOsFedora, imageOsVersion(`fedora[a-z -]*?[-_ ]?(\d+)()`)},
+}
+
+// imageOsCodenames maps release codenames to versions (used when the name has no version number)
+var imageOsCodenames = map[string]map[string]string{
+	model.ImageOsUbuntu: {"xenial": "16.04", "bionic": "18.04", "focal": "20.04", "jammy": "22.04", "noble": "24.04"},
+	model.ImageOsDebian: {"stretch": "9", "buster": "10", "bullseye": "11", "bookworm": "12"},
+}
+
+var (
+	imageArchArm64 = regexp.MustCompile(`arm64|aarch64|graviton`)
+	imageArchX8664 = regexp.MustCompile(`x86[_-]64|amd64|x64|64bit|lnx64|wnd64`)
+)
+
+// extractImageOsVersion returns the version matched by the pattern (e.g., 22.04, 9.2, 2022)
+func extractImageOsVersion(pattern *regexp.Regexp, text string) string {
+	if pattern == nil {
+		return ""
+	}
+	submatches := pattern.FindStringSubmatch(text)
+	if submatches == nil {
+		return ""
+	}
+	parts := []string{}
+	for _, part := range submatches[1:] {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// NormalizeImageOs extracts the OS distribution, version and architecture from the naming of the image.
+// Each text is matched separately in order: the distribution is taken from the first text recognized,
+// and the version from the first text which names the distribution (or its codename).
+// It returns ImageOsUnknown as the distribution if the image is not recognized.
+func NormalizeImageOs(providerName string, texts ...string) (distribution string, version string, architecture string) {
+	patterns := append(append([]imageOsPattern{}, providerImageOsPatterns[strings.ToLower(providerName)]...), commonImageOsPatterns...)
+	lowered := make([]string, 0, len(texts))
+	for _, text := range texts {
+		if text = strings.ToLower(strings.TrimSpace(text)); text != "" {
+			lowered = append(lowered, text)
+		}
+	}
+
+	distribution = model.ImageOsUnknown
+	for _, text := range lowered {
+		for _, p := range patterns {
+			if p.match.MatchString(text) {
+				distribution = p.distribution
+				break
+			}
+		}
+		if distribution != model.ImageOsUnknown {
+			break
+		}
+	}
+	if distribution != model.ImageOsUnknown {
+		for _, text := range lowered {
+			for _, p := range patterns {
+				if p.distribution == distribution && p.match.MatchString(text) {
+					if version = extractImageOsVersion(p.version, text); version != "" {
+						break
+					}
+				}
+			}
+			if version == "" {
+				for codename, v := range imageOsCodenames[distribution] {
+					if strings.Contains(text, codename) {
+						version = v
+						break
+					}
+				}
+			}
+			if version != "" {
+				break
+			}
+		}
+	}
+
+	for _, text := range lowered {
+		switch {
+		case imageArchArm64.MatchString(text):
+			architecture = "arm64"
+		case imageArchX8664.MatchString(text):
+			architecture = "x86_64"
+		}
+		if architecture != "" {
+			break
+		}
+	}
+	return distribution, version, architecture
+}
+
+// NormalizeImageInfo sets the normalized OS metadata (OSDistribution, OSVersion, Architecture) of the image
+func NormalizeImageInfo(image *model.TbImageInfo, providerName string) {
+	// GuestOS is given first since it is the most reliable (the name may include ids or dates)
+	image.OSDistribution, image.OSVersion, image.Architecture = NormalizeImageOs(providerName, image.GuestOS, image.CspImageName)
+}
+
+// normalizeImageInfoOfConnection sets the normalized OS metadata of the image with the provider of its connection
+func normalizeImageInfoOfConnection(image *model.TbImageInfo) {
+	providerName := ""
+	if connConfig, err := common.GetConnConfig(image.ConnectionName); err == nil {
+		providerName = connConfig.ProviderName
+	}
+	NormalizeImageInfo(image, providerName)
+}
+
+// ParseImageAlias parses an image alias (e.g., ubuntu22.04, rhel9, windows2022) into the normalized OS metadata.
+// ok is false if the alias does not name a known OS distribution.
+func ParseImageAlias(alias string) (distribution string, version string, architecture string, ok bool) {
+	distribution, version, architecture = NormalizeImageOs("", strings.ReplaceAll(alias, " ", ""))
+	if distribution == model.ImageOsUnknown {
+		return "", "", "", false
+	}
+	return distribution, version, architecture, true
+}
+
+// ResolveImageAlias returns the newest common image matched by the alias in the region of the provider.
+// The error names the alias and the region so that an image can be registered for the region manually.
+func ResolveImageAlias(alias string, providerName string, regionName string) (model.TbImageInfo, error) {
+	distribution, version, architecture, ok := ParseImageAlias(alias)
+	if !ok {
+		return model.TbImageInfo{}, fmt.Errorf("image alias (%s) does not name a known OS distribution", alias)
+	}
+
+	req := model.SearchImageRequest{OSDistribution: distribution, OSVersion: version, Architecture: architecture}
+	candidates := []model.TbImageInfo{}
+	// images for all regions of the provider are used if the region has no image
+	for _, region := range []string{regionName, "all"} {
+		req.Keywords = []string{GetProviderRegionZoneResourceKey(providerName, region, "", "")}
+		imageList, err := SearchImageWithOption(model.SystemCommonNs, req)
+		if err != nil {
+			return model.TbImageInfo{}, fmt.Errorf("failed to search images for the alias (%s) in region (%s) of %s: %w", alias, regionName, providerName, err)
+		}
+		for _, image := range imageList {
+			if !strings.EqualFold(image.Status, "unavailable") {
+				candidates = append(candidates, image)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+	if len(candidates) == 0 {
+		return model.TbImageInfo{}, fmt.Errorf("no image matches the alias (%s) in region (%s) of %s; register an image for the region and specify its id", alias, regionName, providerName)
+	}
+
+	// prefer the newest image (CreationDate is given in ISO 8601 by the providers)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].CreationDate > candidates[j].CreationDate
+	})
+	return candidates[0], nil
+}
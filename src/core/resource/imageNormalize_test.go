@@ -0,0 +1,75 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+)
+
+func TestNormalizeImageOs(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     string
+		guestOs      string
+		cspImageName string
+		distribution string
+		version      string
+		architecture string
+	}{
+		{"aws ubuntu", "aws", "Linux/UNIX", "ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-20240207", model.ImageOsUbuntu, "22.04", "x86_64"},
+		{"aws amazon linux 2", "aws", "", "amzn2-ami-hvm-2.0.20240131-x86_64-gp2", model.ImageOsAmazonLinux, "2", "x86_64"},
+		{"aws amazon linux 2023", "aws", "", "al2023-ami-2023.3.20240131.0-kernel-6.1-arm64", model.ImageOsAmazonLinux, "2023", "arm64"},
+		{"aws windows", "aws", "Windows", "Windows_Server-2022-English-Full-Base-2024.01.16", model.ImageOsWindows, "2022", ""},
+		{"azure ubuntu", "azure", "", "Canonical:0001-com-ubuntu-server-jammy:22_04-lts-gen2:latest", model.ImageOsUbuntu, "22.04", ""},
+		{"azure rhel", "azure", "", "RedHat:RHEL:9_2:latest", model.ImageOsRhel, "9.2", ""},
+		{"gcp ubuntu", "gcp", "", "ubuntu-2204-jammy-v20240126", model.ImageOsUbuntu, "22.04", ""},
+		{"gcp rhel", "gcp", "", "rhel-9-v20240110", model.ImageOsRhel, "9", ""},
+		{"gcp debian", "gcp", "", "debian-12-bookworm-arm64-v20240110", model.ImageOsDebian, "12", "arm64"},
+		{"ncp ubuntu", "ncp", "", "SW.VSVR.OS.LNX64.UBNTU.SVR2004.B050", model.ImageOsUbuntu, "20.04", "x86_64"},
+		{"alibaba ubuntu", "alibaba", "", "ubuntu_22_04_x64_20G_alibase_20240130.vhd", model.ImageOsUbuntu, "22.04", "x86_64"},
+		{"version in guest os", "", "Ubuntu 20.04", "ubuntu-2204-jammy-v20240126", model.ImageOsUbuntu, "20.04", ""},
+		{"version from the name naming the distribution", "", "Ubuntu", "ubuntu-pro-2404-noble-v20240410", model.ImageOsUbuntu, "24.04", ""},
+		{"codename only", "", "Ubuntu", "ubuntu-focal-server-cloudimg", model.ImageOsUbuntu, "20.04", ""},
+		// a date in the name is not a version
+		{"date in the name", "", "Ubuntu", "custom-image-20240410", model.ImageOsUbuntu, "", ""},
+		{"date after the name", "", "", "ubuntu-20241004-build", model.ImageOsUbuntu, "", ""},
+		// an OS name in another word is not the OS
+		{"os name in another word", "", "", "palmalab-base-image", model.ImageOsUnknown, "", ""},
+		{"sles in another word", "", "", "bundles-v1", model.ImageOsUnknown, "", ""},
+		{"almalinux", "", "", "almalinux-9.3-x86_64", model.ImageOsAlma, "9.3", "x86_64"},
+		{"unknown", "", "Linux/UNIX", "my-custom-image", model.ImageOsUnknown, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distribution, version, architecture := NormalizeImageOs(tt.provider, tt.guestOs, tt.cspImageName)
+			if distribution != tt.distribution || version != tt.version || architecture != tt.architecture {
+				t.Errorf("got (%q, %q, %q), want (%q, %q, %q)", distribution, version, architecture, tt.distribution, tt.version, tt.architecture)
+			}
+		})
+	}
+}
+
+func TestParseImageAlias(t *testing.T) {
+	tests := []struct {
+		alias        string
+		distribution string
+		version      string
+		ok           bool
+	}{
+		{"ubuntu22.04", model.ImageOsUbuntu, "22.04", true},
+		{"ubuntu 24.04", model.ImageOsUbuntu, "24.04", true},
+		{"rhel9", model.ImageOsRhel, "9", true},
+		{"windows2022", model.ImageOsWindows, "2022", true},
+		{"amazonlinux2023", model.ImageOsAmazonLinux, "2023", true},
+		{"debian12", model.ImageOsDebian, "12", true},
+		{"unknownos1", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			distribution, version, _, ok := ParseImageAlias(tt.alias)
+			if ok != tt.ok || distribution != tt.distribution || version != tt.version {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, %v)", distribution, version, ok, tt.distribution, tt.version, tt.ok)
+			}
+		})
+	}
+}
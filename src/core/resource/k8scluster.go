@@ -15,10 +15,14 @@ limitations under the License.
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
@@ -29,6 +33,7 @@ import (
 	validator "github.com/go-playground/validator/v10"
 	"github.com/go-resty/resty/v2"
 	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
 )
 
 // TbK8sClusterReqStructLevelValidation is a function to validate 'model.TbK8sClusterReq' object.
@@ -44,11 +49,21 @@ func TbK8sClusterReqStructLevelValidation(sl validator.StructLevel) {
 }
 
 // CreateK8sCluster create a k8s cluster
-func CreateK8sCluster(nsId string, req *model.TbK8sClusterReq, option string) (model.TbK8sClusterInfo, error) {
+func CreateK8sCluster(ctx context.Context, nsId string, req *model.TbK8sClusterReq, option string) (model.TbK8sClusterInfo, error) {
 	log.Info().Msg("CreateK8sCluster")
 
 	emptyObj := model.TbK8sClusterInfo{}
 
+	if option == "register" {
+		return RegisterK8sCluster(ctx, nsId, &model.TbRegisterK8sClusterReq{
+			ConnectionName: req.ConnectionName,
+			CspResourceId:  req.CspResourceId,
+			Name:           req.Name,
+			Description:    req.Description,
+			VNetId:         req.VNetId,
+		})
+	}
+
 	reqId := req.Name
 
 	err := validate.Struct(req)
@@ -61,6 +76,12 @@ func CreateK8sCluster(nsId string, req *model.TbK8sClusterReq, option string) (m
 		return emptyObj, err
 	}
 
+	// Check the naming policy of the namespace
+	err = common.CheckNsNamingPolicy(ctx, nsId, model.StrK8s, reqId)
+	if err != nil {
+		return emptyObj, err
+	}
+
 	check, err := CheckK8sCluster(nsId, reqId)
 	if err != nil {
 		log.Err(err).Msg("Failed to Create a K8sCluster")
@@ -73,6 +94,25 @@ func CreateK8sCluster(nsId string, req *model.TbK8sClusterReq, option string) (m
 		return emptyObj, err
 	}
 
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, req.ConnectionName)
+	if err != nil {
+		return emptyObj, err
+	}
+
+	// Check the quota of the namespace for the nodes of the node groups
+	requested := model.NsQuotaUsage{}
+	for _, ng := range req.K8sNodeGroupList {
+		nodeSize, _ := strconv.Atoi(ng.DesiredNodeSize)
+		vCpu, _ := GetSpecVCpu(nsId, ng.SpecId)
+		requested.Vms += nodeSize
+		requested.VCpus += nodeSize * vCpu
+	}
+	err = CheckNsQuota(nsId, requested)
+	if err != nil {
+		return emptyObj, err
+	}
+
 	/*
 	 * Check for K8sCluster Enablement from K8sClusterSetting
 	 */
@@ -187,6 +227,11 @@ func CreateK8sCluster(nsId string, req *model.TbK8sClusterReq, option string) (m
 		}
 
 		spSpecName, err := GetCspResourceName(nsId, model.StrSpec, v.SpecId)
+		if err != nil {
+			// If cannot find the spec in the namespace, use common spec
+			log.Warn().Msgf("Not found the Spec: %s in nsId: %s, find it from SystemCommonNs", v.SpecId, nsId)
+			spSpecName, err = GetCspResourceName(model.SystemCommonNs, model.StrSpec, v.SpecId)
+		}
 		if err != nil {
 			log.Err(err).Msg("Failed to Create a K8sCluster")
 			return emptyObj, err
@@ -232,13 +277,7 @@ func CreateK8sCluster(nsId string, req *model.TbK8sClusterReq, option string) (m
 	method := "POST"
 	client.SetTimeout(20 * time.Minute)
 
-	url := model.SpiderRestUrl
-
-	if option == "register" {
-		url = url + "/regcluster"
-	} else { // option != "register"
-		url = url + "/cluster"
-	}
+	url := model.SpiderRestUrl + "/cluster"
 
 	var spClusterRes model.SpiderClusterRes
 
@@ -273,13 +312,8 @@ func CreateK8sCluster(nsId string, req *model.TbK8sClusterReq, option string) (m
 		ConnectionConfig:        connectionConfig,
 		Description:             req.Description,
 		CspViewK8sClusterDetail: spClusterRes.SpiderClusterInfo,
-	}
-
-	if option == "register" && req.CspResourceId == "" {
-		tbK8sCInfo.SystemLabel = "Registered from CB-Spider resource"
-		// TODO: check to handle something to register
-	} else if option == "register" && req.CspResourceId != "" {
-		tbK8sCInfo.SystemLabel = "Registered from CSP resource"
+		LastRefreshed:           time.Now(),
+		Provenance:              common.NewProvenance(ctx, model.CreatedViaManual),
 	}
 
 	/*
@@ -314,15 +348,362 @@ func CreateK8sCluster(nsId string, req *model.TbK8sClusterReq, option string) (m
 		model.LabelCreatedTime:     tbK8sCInfo.CspViewK8sClusterDetail.CreatedTime.String(),
 		model.LabelConnectionName:  tbK8sCInfo.ConnectionName,
 	}
+	common.SetSystemLabels(labels, tbK8sCInfo.ConnectionName)
+	common.SetProvenanceLabels(labels, tbK8sCInfo.Provenance)
 	err = label.CreateOrUpdateLabel(model.StrK8s, uid, k, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return storedTbK8sCInfo, err
 	}
 
+	event := model.TbK8sClusterEvent{
+		NewStatus: tbK8sCInfo.CspViewK8sClusterDetail.Status,
+		Time:      tbK8sCInfo.LastRefreshed,
+		Message:   "K8sCluster has been requested to create",
+	}
+	err = appendK8sClusterEvent(nsId, tbK8sCInfo.Id, event)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to record an event of K8sCluster")
+	}
+
+	if isK8sClusterStatusInProgress(tbK8sCInfo.CspViewK8sClusterDetail.Status) {
+		StartK8sClusterStatusPoller(nsId, tbK8sCInfo.Id)
+	}
+
+	common.EmitEvent(model.EventK8sClusterCreated, nsId, k,
+		map[string]string{"status": string(tbK8sCInfo.CspViewK8sClusterDetail.Status)})
+	common.RecordNsEvent(nsId, k, model.NsEvent{ResourceType: model.StrK8s, ResourceId: tbK8sCInfo.Id, EventType: model.NsEventCreated,
+		Message: fmt.Sprintf("K8sCluster is created (status: %s, connection: %s)", tbK8sCInfo.CspViewK8sClusterDetail.Status, tbK8sCInfo.ConnectionName)})
 	return storedTbK8sCInfo, nil
 }
 
+// RegisterK8sCluster registers a K8sCluster, which was created in CSP, to CB-Tumblebug
+func RegisterK8sCluster(ctx context.Context, nsId string, req *model.TbRegisterK8sClusterReq) (model.TbK8sClusterInfo, error) {
+	log.Info().Msg("RegisterK8sCluster")
+
+	emptyObj := model.TbK8sClusterInfo{}
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+
+	err = common.CheckString(req.Name)
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+
+	check, err := CheckK8sCluster(nsId, req.Name)
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+	if check {
+		err := fmt.Errorf("The k8s cluster " + req.Name + " already exists.")
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+
+	err = checkK8sClusterEnablement(req.ConnectionName)
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+
+	connectionConfig, err := common.GetConnConfig(req.ConnectionName)
+	if err != nil {
+		err = fmt.Errorf("Cannot retrieve ConnectionConfig: " + err.Error())
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+
+	spVPCName := ""
+	if req.VNetId != "" {
+		tmpInf, err := GetResource(nsId, model.StrVNet, req.VNetId)
+		if err != nil {
+			log.Err(err).Msg("Failed to Register a K8sCluster")
+			return emptyObj, err
+		}
+		tbVNetInfo := model.TbVNetInfo{}
+		err = common.CopySrcToDest(&tmpInf, &tbVNetInfo)
+		if err != nil {
+			log.Err(err).Msg("Failed to Register a K8sCluster")
+			return emptyObj, err
+		}
+		if tbVNetInfo.ConnectionName != req.ConnectionName {
+			err := fmt.Errorf("the vNet (%s) belongs to the connection (%s), not (%s)", req.VNetId, tbVNetInfo.ConnectionName, req.ConnectionName)
+			log.Err(err).Msg("Failed to Register a K8sCluster")
+			return emptyObj, err
+		}
+		spVPCName = tbVNetInfo.CspResourceName
+	}
+
+	uid := common.GenUid()
+
+	requestBody := model.SpiderClusterRegisterReqInfoWrapper{
+		ConnectionName: req.ConnectionName,
+		ReqInfo: model.SpiderClusterRegisterReqInfo{
+			VPCName: spVPCName,
+			Name:    uid,
+			CSPId:   req.CspResourceId,
+		},
+	}
+
+	client := resty.New()
+	client.SetTimeout(10 * time.Minute)
+	url := model.SpiderRestUrl + "/regcluster"
+	method := "POST"
+
+	var spClusterRes model.SpiderClusterRes
+
+	err = common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&spClusterRes,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return emptyObj, err
+	}
+
+	err = validateK8sClusterRegion(connectionConfig, req.CspResourceId, spClusterRes.SpiderClusterInfo.KeyValueList)
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		// Unregister the K8sCluster from CB-Spider since it does not belong to the connection
+		unregErr := unregisterSpiderCluster(req.ConnectionName, spClusterRes.SpiderClusterInfo.IId.NameId)
+		if unregErr != nil {
+			log.Warn().Err(unregErr).Msgf("Failed to clean up the K8sCluster (%s) in CB-Spider", spClusterRes.SpiderClusterInfo.IId.NameId)
+		}
+		return emptyObj, err
+	}
+
+	tbK8sCInfo := model.TbK8sClusterInfo{
+		ResourceType:            model.StrK8s,
+		Id:                      req.Name,
+		Uid:                     uid,
+		CspResourceName:         spClusterRes.SpiderClusterInfo.IId.NameId,
+		CspResourceId:           spClusterRes.SpiderClusterInfo.IId.SystemId,
+		Name:                    req.Name,
+		ConnectionName:          req.ConnectionName,
+		ConnectionConfig:        connectionConfig,
+		Description:             req.Description,
+		CspViewK8sClusterDetail: spClusterRes.SpiderClusterInfo,
+		SystemLabel:             "Registered from CSP resource",
+		LastRefreshed:           time.Now(),
+		Provenance:              common.NewProvenance(ctx, model.CreatedViaRegistered),
+	}
+
+	k := GenK8sClusterKey(nsId, tbK8sCInfo.Id)
+	Val, _ := json.Marshal(tbK8sCInfo)
+
+	err = kvstore.Put(k, string(Val))
+	if err != nil {
+		log.Err(err).Msg("Failed to Register a K8sCluster")
+		return tbK8sCInfo, err
+	}
+
+	// Store label info using CreateOrUpdateLabel
+	labels := map[string]string{
+		model.LabelManager:         model.StrManager,
+		model.LabelNamespace:       nsId,
+		model.LabelLabelType:       model.StrK8s,
+		model.LabelId:              tbK8sCInfo.Id,
+		model.LabelName:            tbK8sCInfo.Name,
+		model.LabelUid:             tbK8sCInfo.Uid,
+		model.LabelVersion:         tbK8sCInfo.CspViewK8sClusterDetail.Version,
+		model.LabelCspResourceId:   tbK8sCInfo.CspResourceId,
+		model.LabelCspResourceName: tbK8sCInfo.CspResourceName,
+		model.LabelDescription:     tbK8sCInfo.Description,
+		model.LabelCreatedTime:     tbK8sCInfo.CspViewK8sClusterDetail.CreatedTime.String(),
+		model.LabelConnectionName:  tbK8sCInfo.ConnectionName,
+	}
+	common.SetSystemLabels(labels, tbK8sCInfo.ConnectionName)
+	common.SetProvenanceLabels(labels, tbK8sCInfo.Provenance)
+	err = label.CreateOrUpdateLabel(model.StrK8s, uid, k, labels)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return tbK8sCInfo, err
+	}
+
+	event := model.TbK8sClusterEvent{
+		NewStatus: tbK8sCInfo.CspViewK8sClusterDetail.Status,
+		Time:      tbK8sCInfo.LastRefreshed,
+		Message:   "K8sCluster has been registered",
+	}
+	err = appendK8sClusterEvent(nsId, tbK8sCInfo.Id, event)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to record an event of K8sCluster")
+	}
+
+	if isK8sClusterStatusInProgress(tbK8sCInfo.CspViewK8sClusterDetail.Status) {
+		StartK8sClusterStatusPoller(nsId, tbK8sCInfo.Id)
+	}
+
+	return tbK8sCInfo, nil
+}
+
+// DeregisterK8sCluster removes a K8sCluster from CB-Tumblebug and CB-Spider without deleting it in CSP
+func DeregisterK8sCluster(nsId string, k8sClusterId string) (model.SimpleMsg, error) {
+	log.Info().Msg("DeregisterK8sCluster")
+
+	emptyRet := model.SimpleMsg{}
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Err(err).Msg("Failed to Deregister K8sCluster")
+		return emptyRet, err
+	}
+	err = common.CheckString(k8sClusterId)
+	if err != nil {
+		log.Err(err).Msg("Failed to Deregister K8sCluster")
+		return emptyRet, err
+	}
+
+	k := GenK8sClusterKey(nsId, k8sClusterId)
+	kv, err := kvstore.GetKv(k)
+	if err != nil {
+		log.Err(err).Msg("Failed to Deregister K8sCluster")
+		return emptyRet, err
+	}
+	if kv == (kvstore.KeyValue{}) {
+		err := fmt.Errorf("The K8sCluster " + k8sClusterId + " does not exist.")
+		log.Err(err).Msg("Failed to Deregister K8sCluster")
+		return emptyRet, err
+	}
+
+	tbK8sCInfo := model.TbK8sClusterInfo{}
+	err = json.Unmarshal([]byte(kv.Value), &tbK8sCInfo)
+	if err != nil {
+		log.Err(err).Msg("Failed to Deregister K8sCluster")
+		return emptyRet, err
+	}
+
+	err = unregisterSpiderCluster(tbK8sCInfo.ConnectionName, tbK8sCInfo.CspResourceName)
+	if err != nil {
+		log.Err(err).Msg("Failed to Deregister K8sCluster")
+		return emptyRet, err
+	}
+
+	err = kvstore.Delete(k)
+	if err != nil {
+		log.Err(err).Msg("Failed to Deregister K8sCluster")
+		return emptyRet, err
+	}
+	err = kvstore.Delete(genK8sClusterEventKey(nsId, k8sClusterId))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to Delete K8sCluster Events")
+	}
+
+	err = label.RemoveLabel(model.StrK8s, tbK8sCInfo.Uid, k)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	return model.SimpleMsg{Message: fmt.Sprintf("the K8sCluster (%s) has been deregistered", k8sClusterId)}, nil
+}
+
+// unregisterSpiderCluster removes the K8sCluster record in CB-Spider without deleting it in CSP
+func unregisterSpiderCluster(connectionName string, cspResourceName string) error {
+	requestBody := model.SpiderClusterUnregisterReqInfoWrapper{
+		ConnectionName: connectionName,
+	}
+
+	client := resty.New()
+	url := model.SpiderRestUrl + "/regcluster/" + cspResourceName
+	method := "DELETE"
+
+	var spResp spiderBooleanInfoResp
+
+	err := common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&spResp,
+		common.VeryShortDuration,
+	)
+	if err != nil {
+		return err
+	}
+
+	ok, err := strconv.ParseBool(spResp.Result)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("failed to unregister the K8sCluster (%s) in CB-Spider", cspResourceName)
+	}
+	return nil
+}
+
+// validateK8sClusterRegion checks the region of a CSP K8sCluster matches the region of the connection.
+// The region is inferred from the CSP resource ID (e.g., EKS ARN, GKE resource path) or KeyValueList.
+// If no region information is available, the validation is skipped.
+func validateK8sClusterRegion(connConfig model.ConnConfig, cspResourceId string, keyValueList []model.KeyValue) error {
+	connRegion := strings.ToLower(strings.ReplaceAll(connConfig.RegionDetail.RegionName, " ", ""))
+	if connRegion == "" {
+		return nil
+	}
+
+	clusterRegion := ""
+	if strings.HasPrefix(cspResourceId, "arn:") {
+		// arn:aws:eks:{region}:{account}:cluster/{name}
+		parts := strings.Split(cspResourceId, ":")
+		if len(parts) > 3 {
+			clusterRegion = parts[3]
+		}
+	} else if strings.Contains(cspResourceId, "/locations/") {
+		// projects/{project}/locations/{location}/clusters/{name}
+		parts := strings.Split(cspResourceId, "/")
+		for i, v := range parts {
+			if v == "locations" && i+1 < len(parts) {
+				clusterRegion = parts[i+1]
+				break
+			}
+		}
+	}
+	if clusterRegion == "" {
+		for _, kv := range keyValueList {
+			switch strings.ToLower(kv.Key) {
+			case "region", "regionid", "location":
+				clusterRegion = kv.Value
+			}
+			if clusterRegion != "" {
+				break
+			}
+		}
+	}
+	if clusterRegion == "" {
+		log.Debug().Msgf("Cannot find the region of the K8sCluster (%s). Skip the region validation", cspResourceId)
+		return nil
+	}
+
+	clusterRegion = strings.ToLower(strings.ReplaceAll(clusterRegion, " ", ""))
+	// a zonal cluster has a location like {region}-{zone}
+	if clusterRegion == connRegion || strings.HasPrefix(clusterRegion, connRegion+"-") {
+		return nil
+	}
+
+	return fmt.Errorf("the region (%s) of the K8sCluster (%s) does not match the region (%s) of the connection (%s)",
+		clusterRegion, cspResourceId, connRegion, connConfig.ConfigName)
+}
+
 // AddK8sNodeGroup adds a NodeGroup
 func AddK8sNodeGroup(nsId string, k8sClusterId string, u *model.TbK8sNodeGroupReq) (model.TbK8sClusterInfo, error) {
 	log.Info().Msg("AddK8sNodeGroup")
@@ -363,6 +744,13 @@ func AddK8sNodeGroup(nsId string, k8sClusterId string, u *model.TbK8sNodeGroupRe
 		return emptyObj, err
 	}
 
+	// Check the quota of the namespace for the nodes of the node group
+	nodeSize, _ := strconv.Atoi(u.DesiredNodeSize)
+	err = CheckNsQuotaForVms(nsId, u.SpecId, nodeSize)
+	if err != nil {
+		return emptyObj, err
+	}
+
 	/*
 	 * Get model.TbK8sClusterInfo from kvstore
 	 */
@@ -416,6 +804,11 @@ func AddK8sNodeGroup(nsId string, k8sClusterId string, u *model.TbK8sNodeGroupRe
 	}
 
 	spSpecName, err := GetCspResourceName(nsId, model.StrSpec, u.SpecId)
+	if err != nil {
+		// If cannot find the spec in the namespace, use common spec
+		log.Warn().Msgf("Not found the Spec: %s in nsId: %s, find it from SystemCommonNs", u.SpecId, nsId)
+		spSpecName, err = GetCspResourceName(model.SystemCommonNs, model.StrSpec, u.SpecId)
+	}
 	if err != nil {
 		log.Err(err).Msg("Failed to Create a K8sCluster")
 		return emptyObj, err
@@ -483,6 +876,7 @@ func AddK8sNodeGroup(nsId string, k8sClusterId string, u *model.TbK8sNodeGroupRe
 		ConnectionName:          oldTbK8sCInfo.ConnectionName,
 		Description:             oldTbK8sCInfo.Description,
 		CspViewK8sClusterDetail: spClusterRes.SpiderClusterInfo,
+		LastRefreshed:           time.Now(),
 	}
 
 	/*
@@ -511,6 +905,9 @@ func AddK8sNodeGroup(nsId string, k8sClusterId string, u *model.TbK8sNodeGroupRe
 	if err != nil {
 		log.Err(err).Msg("")
 	}
+
+	StartK8sClusterStatusPoller(nsId, newTbK8sCInfo.Id)
+
 	return storedTbK8sCInfo, nil
 }
 
@@ -878,6 +1275,7 @@ func GetK8sCluster(nsId string, k8sClusterId string) (model.TbK8sClusterInfo, er
 		ConnectionName:          storedTbK8sCInfo.ConnectionName,
 		Description:             storedTbK8sCInfo.Description,
 		CspViewK8sClusterDetail: spClusterRes.SpiderClusterInfo,
+		LastRefreshed:           time.Now(),
 	}
 
 	/*
@@ -903,6 +1301,147 @@ func GetK8sCluster(nsId string, k8sClusterId string) (model.TbK8sClusterInfo, er
 	return tbK8sCInfo, nil
 }
 
+// GetK8sClusterKubeconfig retrieves the kubeconfig of a k8s cluster from CB-Spider.
+// The kubeconfig contains credentials, so it is always fetched fresh and never cached.
+// If serverEndpoint is given (ex: a private endpoint reached via a bastion tunnel),
+// the server address of every cluster entry in the kubeconfig is rewritten to it.
+func GetK8sClusterKubeconfig(nsId string, k8sClusterId string, serverEndpoint string) (string, error) {
+
+	if serverEndpoint != "" {
+		if err := validateKubeconfigServer(serverEndpoint); err != nil {
+			log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+			return "", err
+		}
+	}
+
+	check, err := CheckK8sCluster(nsId, k8sClusterId)
+	if err != nil {
+		log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+		return "", err
+	}
+
+	if !check {
+		err := fmt.Errorf("The K8sCluster " + k8sClusterId + " does not exist.")
+		log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+		return "", err
+	}
+
+	k := GenK8sClusterKey(nsId, k8sClusterId)
+	kv, err := kvstore.GetKv(k)
+	if err != nil {
+		log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+		return "", err
+	}
+
+	storedTbK8sCInfo := model.TbK8sClusterInfo{}
+	err = json.Unmarshal([]byte(kv.Value), &storedTbK8sCInfo)
+	if err != nil {
+		log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+		return "", err
+	}
+
+	// Create Request body for GetCluster of CB-Spider
+	type JsonTemplate struct {
+		ConnectionName string
+	}
+	requestBody := JsonTemplate{
+		ConnectionName: storedTbK8sCInfo.ConnectionName,
+	}
+
+	// Do not use common.ExecuteHttpRequest here to avoid caching credentials
+	client := resty.New().SetCloseConnection(true)
+	client.SetAllowGetMethodPayload(true)
+	client.SetTimeout(10 * time.Minute)
+	url := model.SpiderRestUrl + "/cluster/" + storedTbK8sCInfo.CspResourceName
+
+	var spClusterRes model.SpiderClusterRes
+	resp, err := client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(requestBody).
+		SetResult(&spClusterRes).
+		Get(url)
+	if err != nil {
+		log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+		return "", fmt.Errorf("an error occurred while requesting to CB-Spider")
+	}
+	if resp.IsError() {
+		err := fmt.Errorf("[Error from: %s] Status code: %s, Message: %s", url, resp.Status(), resp.Body())
+		log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+		return "", err
+	}
+
+	kubeconfig := spClusterRes.SpiderClusterInfo.AccessInfo.Kubeconfig
+	if kubeconfig == "" {
+		err := fmt.Errorf("The kubeconfig of K8sCluster %s is not available yet (status: %s)",
+			k8sClusterId, spClusterRes.SpiderClusterInfo.Status)
+		log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+		return "", err
+	}
+
+	if serverEndpoint != "" {
+		kubeconfig, err = rewriteKubeconfigServer(kubeconfig, serverEndpoint)
+		if err != nil {
+			log.Err(err).Msg("Failed to Get K8sCluster Kubeconfig")
+			return "", err
+		}
+	}
+
+	return kubeconfig, nil
+}
+
+// validateKubeconfigServer checks that the endpoint to put in the kubeconfig is an http(s) URL with a host only
+func validateKubeconfigServer(serverEndpoint string) error {
+	u, err := url.Parse(serverEndpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil ||
+		u.RawQuery != "" || u.Fragment != "" || strings.ContainsAny(serverEndpoint, " \t\r\n") {
+		return common.NewValidationFailedError("Invalid endpoint %q (ex: https://127.0.0.1:6443)", serverEndpoint)
+	}
+	return nil
+}
+
+// rewriteKubeconfigServer replaces the server of every cluster entry (clusters[].cluster.server) in the kubeconfig
+// with the given endpoint. The kubeconfig is rewritten by the YAML marshaller, so the endpoint is always a scalar value.
+func rewriteKubeconfigServer(kubeconfig string, serverEndpoint string) (string, error) {
+	config := yaml.MapSlice{}
+	if err := yaml.Unmarshal([]byte(kubeconfig), &config); err != nil {
+		return "", fmt.Errorf("failed to parse the kubeconfig: %w", err)
+	}
+	for i := range config {
+		if config[i].Key != "clusters" {
+			continue
+		}
+		clusters, ok := config[i].Value.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, entry := range clusters {
+			entryMap, ok := entry.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+			for j := range entryMap {
+				if entryMap[j].Key != "cluster" {
+					continue
+				}
+				cluster, ok := entryMap[j].Value.(yaml.MapSlice)
+				if !ok {
+					continue
+				}
+				for k := range cluster {
+					if cluster[k].Key == "server" {
+						cluster[k].Value = serverEndpoint
+					}
+				}
+			}
+		}
+	}
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to write the kubeconfig: %w", err)
+	}
+	return string(out), nil
+}
+
 /*
 func isEqualTbK8sClusterInfoExceptStatus(info1 model.TbK8sClusterInfo, info2 model.TbK8sClusterInfo) bool {
 
@@ -1150,6 +1689,10 @@ func DeleteK8sCluster(nsId string, k8sClusterId string, forceFlag string) (bool,
 			log.Err(err).Msg("Failed to Delete K8sCluster")
 			return false, err
 		}
+		err = kvstore.Delete(genK8sClusterEventKey(nsId, k8sClusterId))
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to Delete K8sCluster Events")
+		}
 	}
 
 	if err != nil {
@@ -1167,8 +1710,14 @@ func DeleteK8sCluster(nsId string, k8sClusterId string, forceFlag string) (bool,
 						log.Err(err).Msg("Failed to Delete K8sCluster")
 						return false, err
 					}
+					err = kvstore.Delete(genK8sClusterEventKey(nsId, k8sClusterId))
+					if err != nil {
+						log.Warn().Err(err).Msg("Failed to Delete K8sCluster Events")
+					}
 				}
 
+				common.EmitEvent(model.EventK8sClusterDeleted, nsId, k, nil)
+				common.RecordNsEvent(nsId, k, model.NsEvent{ResourceType: model.StrK8s, ResourceId: k8sClusterId, EventType: model.NsEventDeleted, Message: "K8sCluster is deleted"})
 				return true, nil
 			}
 		}
@@ -1325,6 +1874,7 @@ func UpgradeK8sCluster(nsId string, k8sClusterId string, u *model.TbUpgradeK8sCl
 		ConnectionName:          oldTbK8sCInfo.ConnectionName,
 		Description:             oldTbK8sCInfo.Description,
 		CspViewK8sClusterDetail: spClusterRes.SpiderClusterInfo,
+		LastRefreshed:           time.Now(),
 	}
 
 	/*
@@ -1354,6 +1904,8 @@ func UpgradeK8sCluster(nsId string, k8sClusterId string, u *model.TbUpgradeK8sCl
 		log.Err(err).Msg("")
 	}
 
+	StartK8sClusterStatusPoller(nsId, newTbK8sCInfo.Id)
+
 	return storedTbK8sCInfo, nil
 }
 
@@ -1596,3 +2148,241 @@ func validateK8sClusterVersion(providerName, regionName, version string) error {
 			providerName, regionName, strings.Join(versionIdList, ", "))
 	}
 }
+
+/*
+ * K8sCluster status poller
+ */
+
+const (
+	// k8sClusterStatusPollInterval is the interval to refresh the status of a K8sCluster in progress
+	k8sClusterStatusPollInterval = 30 * time.Second
+	// k8sClusterStatusPollTimeout is the maximum duration to poll the status of a K8sCluster
+	k8sClusterStatusPollTimeout = 3 * time.Hour
+)
+
+// k8sClusterPollers is a map to prevent duplicated pollers for the same K8sCluster
+var k8sClusterPollers = sync.Map{}
+
+// isK8sClusterStatusInProgress returns true if the status is not a terminal status
+func isK8sClusterStatusInProgress(status model.SpiderClusterStatus) bool {
+	return status == model.SpiderClusterCreating ||
+		status == model.SpiderClusterUpdating ||
+		status == model.SpiderClusterDeleting
+}
+
+// genK8sClusterEventKey is func to generate a key for the event list of a K8sCluster
+func genK8sClusterEventKey(nsId string, k8sClusterId string) string {
+	return GenK8sClusterKey(nsId, k8sClusterId) + "/events"
+}
+
+// appendK8sClusterEvent appends a status transition event to the event list of a K8sCluster
+func appendK8sClusterEvent(nsId string, k8sClusterId string, event model.TbK8sClusterEvent) error {
+	k := genK8sClusterEventKey(nsId, k8sClusterId)
+
+	eventList := model.TbK8sClusterEventList{}
+	kv, err := kvstore.GetKv(k)
+	if err != nil {
+		return err
+	}
+	if kv.Value != "" {
+		err = json.Unmarshal([]byte(kv.Value), &eventList)
+		if err != nil {
+			return err
+		}
+	}
+
+	eventList.Events = append(eventList.Events, event)
+
+	val, err := json.Marshal(eventList)
+	if err != nil {
+		return err
+	}
+	err = kvstore.Put(k, string(val))
+	if err != nil {
+		return err
+	}
+
+	if event.OldStatus != event.NewStatus {
+		common.EmitEvent(model.EventK8sClusterStatusChanged, nsId, GenK8sClusterKey(nsId, k8sClusterId), map[string]string{
+			"previousStatus": string(event.OldStatus),
+			"status":         string(event.NewStatus),
+			"message":        event.Message,
+		})
+		common.RecordNsEvent(nsId, GenK8sClusterKey(nsId, k8sClusterId), model.NsEvent{ResourceType: model.StrK8s, ResourceId: k8sClusterId, EventType: model.NsEventStatusChanged,
+			Message: fmt.Sprintf("%s -> %s %s", event.OldStatus, event.NewStatus, event.Message)})
+	}
+	return nil
+}
+
+// GetK8sClusterEvents returns the status transition history of a K8sCluster
+func GetK8sClusterEvents(nsId string, k8sClusterId string) (model.TbK8sClusterEventList, error) {
+
+	eventList := model.TbK8sClusterEventList{Events: []model.TbK8sClusterEvent{}}
+
+	check, err := CheckK8sCluster(nsId, k8sClusterId)
+	if err != nil {
+		log.Err(err).Msg("Failed to Get K8sCluster Events")
+		return eventList, err
+	}
+
+	if !check {
+		err := fmt.Errorf("The K8sCluster " + k8sClusterId + " does not exist.")
+		log.Err(err).Msg("Failed to Get K8sCluster Events")
+		return eventList, err
+	}
+
+	kv, err := kvstore.GetKv(genK8sClusterEventKey(nsId, k8sClusterId))
+	if err != nil {
+		log.Err(err).Msg("Failed to Get K8sCluster Events")
+		return eventList, err
+	}
+	if kv.Value == "" {
+		return eventList, nil
+	}
+
+	err = json.Unmarshal([]byte(kv.Value), &eventList)
+	if err != nil {
+		log.Err(err).Msg("Failed to Get K8sCluster Events")
+		return eventList, err
+	}
+
+	return eventList, nil
+}
+
+// refreshK8sClusterStatus refreshes the stored K8sCluster object from CB-Spider
+// and records an event if the status has been changed. It returns the latest status.
+func refreshK8sClusterStatus(nsId string, k8sClusterId string) (model.SpiderClusterStatus, error) {
+
+	k := GenK8sClusterKey(nsId, k8sClusterId)
+	kv, err := kvstore.GetKv(k)
+	if err != nil {
+		return "", err
+	}
+	if kv == (kvstore.KeyValue{}) {
+		return "", fmt.Errorf("The K8sCluster " + k8sClusterId + " does not exist.")
+	}
+
+	storedTbK8sCInfo := model.TbK8sClusterInfo{}
+	err = json.Unmarshal([]byte(kv.Value), &storedTbK8sCInfo)
+	if err != nil {
+		return "", err
+	}
+	oldStatus := storedTbK8sCInfo.CspViewK8sClusterDetail.Status
+
+	client := resty.New()
+	client.SetTimeout(10 * time.Minute)
+	url := model.SpiderRestUrl + "/cluster/" + storedTbK8sCInfo.CspResourceName
+	method := "GET"
+
+	type JsonTemplate struct {
+		ConnectionName string
+	}
+	requestBody := JsonTemplate{
+		ConnectionName: storedTbK8sCInfo.ConnectionName,
+	}
+
+	var spClusterRes model.SpiderClusterRes
+	err = common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&spClusterRes,
+		common.VeryShortDuration,
+	)
+	if err != nil {
+		return oldStatus, err
+	}
+
+	newStatus := spClusterRes.SpiderClusterInfo.Status
+
+	storedTbK8sCInfo.CspViewK8sClusterDetail = spClusterRes.SpiderClusterInfo
+	storedTbK8sCInfo.LastRefreshed = time.Now()
+
+	val, _ := json.Marshal(storedTbK8sCInfo)
+	err = kvstore.Put(k, string(val))
+	if err != nil {
+		return newStatus, err
+	}
+
+	if oldStatus != newStatus {
+		event := model.TbK8sClusterEvent{
+			OldStatus: oldStatus,
+			NewStatus: newStatus,
+			Time:      storedTbK8sCInfo.LastRefreshed,
+			Message:   "Status changed (refreshed from CSP)",
+		}
+		err = appendK8sClusterEvent(nsId, k8sClusterId, event)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to record an event of K8sCluster %s", k8sClusterId)
+		}
+	}
+
+	return newStatus, nil
+}
+
+// StartK8sClusterStatusPoller starts a goroutine which refreshes the status of a K8sCluster
+// periodically while it is in progress (Creating, Updating, Deleting).
+// The poller stops when the cluster reaches a terminal status or disappears.
+func StartK8sClusterStatusPoller(nsId string, k8sClusterId string) {
+	pollerKey := GenK8sClusterKey(nsId, k8sClusterId)
+	if _, loaded := k8sClusterPollers.LoadOrStore(pollerKey, true); loaded {
+		log.Debug().Msgf("The status poller for K8sCluster %s is already running", k8sClusterId)
+		return
+	}
+
+	go func() {
+		defer k8sClusterPollers.Delete(pollerKey)
+
+		log.Info().Msgf("Start the status poller for K8sCluster %s (ns: %s)", k8sClusterId, nsId)
+		ticker := time.NewTicker(k8sClusterStatusPollInterval)
+		defer ticker.Stop()
+		deadline := time.Now().Add(k8sClusterStatusPollTimeout)
+
+		for range ticker.C {
+			check, err := CheckK8sCluster(nsId, k8sClusterId)
+			if err != nil || !check {
+				log.Info().Msgf("Stop the status poller for K8sCluster %s (not found)", k8sClusterId)
+				return
+			}
+
+			status, err := refreshK8sClusterStatus(nsId, k8sClusterId)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed to refresh the status of K8sCluster %s", k8sClusterId)
+			} else if !isK8sClusterStatusInProgress(status) {
+				log.Info().Msgf("Stop the status poller for K8sCluster %s (status: %s)", k8sClusterId, status)
+				return
+			}
+
+			if time.Now().After(deadline) {
+				log.Warn().Msgf("Stop the status poller for K8sCluster %s (timeout: %s)", k8sClusterId, k8sClusterStatusPollTimeout)
+				return
+			}
+		}
+	}()
+}
+
+// ResumeK8sClusterStatusPollers scans all namespaces for K8sClusters in progress
+// and starts the status poller for them (used at server boot)
+func ResumeK8sClusterStatusPollers() {
+	nsIdList, err := common.ListNsId()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resume K8sCluster status pollers")
+		return
+	}
+
+	for _, nsId := range nsIdList {
+		k8sClusterList, err := ListK8sCluster(nsId, "", "")
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to list K8sClusters in ns %s", nsId)
+			continue
+		}
+		for _, v := range k8sClusterList.([]model.TbK8sClusterInfo) {
+			if isK8sClusterStatusInProgress(v.CspViewK8sClusterDetail.Status) {
+				StartK8sClusterStatusPoller(nsId, v.Id)
+			}
+		}
+	}
+}
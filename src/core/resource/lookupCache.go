@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/metrics"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// Kinds of the Spider lookup cache
+const (
+	LookupCacheSpec  = "spec"
+	LookupCacheImage = "image"
+)
+
+// lookupCacheEntry is a raw Spider payload of a lookup (e.g., the spec list of a connection)
+type lookupCacheEntry struct {
+	Payload   json.RawMessage `json:"payload"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+	StoredAt  time.Time       `json:"storedAt"`
+}
+
+var (
+	lookupCacheLock sync.Mutex
+	lookupCacheMap  = map[string]lookupCacheEntry{} // {kind}/{connectionName} -> entry
+)
+
+// lookupCacheTtl returns the TTL of the cache (TB_LOOKUP_CACHE_TTL_MIN, default 60, 0 disables the cache)
+func lookupCacheTtl() time.Duration {
+	minutes, err := strconv.Atoi(common.NVL(os.Getenv("TB_LOOKUP_CACHE_TTL_MIN"), "60"))
+	if err != nil || minutes < 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// lookupCacheMaxEntries returns the max number of cached payloads in memory (TB_LOOKUP_CACHE_MAX_ENTRIES, default 200)
+func lookupCacheMaxEntries() int {
+	n, err := strconv.Atoi(common.NVL(os.Getenv("TB_LOOKUP_CACHE_MAX_ENTRIES"), "200"))
+	if err != nil || n <= 0 {
+		n = 200
+	}
+	return n
+}
+
+// lookupCacheKvstoreEnabled returns true if payloads are also kept in the Key-Value store
+// to survive restarts (TB_LOOKUP_CACHE_KVSTORE=true)
+func lookupCacheKvstoreEnabled() bool {
+	return strings.EqualFold(os.Getenv("TB_LOOKUP_CACHE_KVSTORE"), "true")
+}
+
+func genLookupCacheKey(kind string, connConfig string) string {
+	return kind + "/" + connConfig
+}
+
+func genLookupCacheKvKey(kind string, connConfig string) string {
+	return "/lookupCache/" + genLookupCacheKey(kind, connConfig)
+}
+
+// getLookupCache returns the cached payload of the lookup if it is not expired (counted as a hit or miss)
+func getLookupCache(kind string, connConfig string) (json.RawMessage, bool) {
+	payload, ok := loadLookupCache(kind, connConfig)
+	if ok {
+		metrics.IncLookupCache(kind, "hit")
+	} else {
+		metrics.IncLookupCache(kind, "miss")
+	}
+	return payload, ok
+}
+
+// loadLookupCache returns the cached payload of the lookup if it is not expired
+func loadLookupCache(kind string, connConfig string) (json.RawMessage, bool) {
+	if lookupCacheTtl() == 0 {
+		return nil, false
+	}
+	key := genLookupCacheKey(kind, connConfig)
+	now := time.Now()
+
+	lookupCacheLock.Lock()
+	entry, ok := lookupCacheMap[key]
+	if ok && now.After(entry.ExpiresAt) {
+		delete(lookupCacheMap, key)
+		ok = false
+	}
+	lookupCacheLock.Unlock()
+
+	if !ok && lookupCacheKvstoreEnabled() {
+		value, err := kvstore.Get(genLookupCacheKvKey(kind, connConfig))
+		if err == nil && value != "" && json.Unmarshal([]byte(value), &entry) == nil && now.Before(entry.ExpiresAt) {
+			ok = true
+			putLookupCacheMemory(key, entry)
+		}
+	}
+
+	if !ok {
+		return nil, false
+	}
+	return entry.Payload, true
+}
+
+// putLookupCacheMemory stores the entry in memory and evicts the oldest entry beyond the size limit
+func putLookupCacheMemory(key string, entry lookupCacheEntry) {
+	lookupCacheLock.Lock()
+	defer lookupCacheLock.Unlock()
+
+	lookupCacheMap[key] = entry
+	for len(lookupCacheMap) > lookupCacheMaxEntries() {
+		oldestKey := ""
+		for k, e := range lookupCacheMap {
+			if oldestKey == "" || e.StoredAt.Before(lookupCacheMap[oldestKey].StoredAt) {
+				oldestKey = k
+			}
+		}
+		delete(lookupCacheMap, oldestKey)
+	}
+}
+
+// putLookupCache stores the raw Spider payload of the lookup
+func putLookupCache(kind string, connConfig string, payload json.RawMessage) {
+	ttl := lookupCacheTtl()
+	if ttl == 0 {
+		return
+	}
+	now := time.Now()
+	entry := lookupCacheEntry{Payload: payload, ExpiresAt: now.Add(ttl), StoredAt: now}
+	putLookupCacheMemory(genLookupCacheKey(kind, connConfig), entry)
+
+	if lookupCacheKvstoreEnabled() {
+		value, err := json.Marshal(entry)
+		if err == nil {
+			err = kvstore.Put(genLookupCacheKvKey(kind, connConfig), string(value))
+		}
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to store the %s lookup of %s in the Key-Value store", kind, connConfig)
+		}
+	}
+}
+
+// InvalidateLookupCache deletes the cached Spider lookup of the connection (all connections if connConfig is empty)
+func InvalidateLookupCache(kind string, connConfig string) {
+	lookupCacheLock.Lock()
+	for key := range lookupCacheMap {
+		if key == genLookupCacheKey(kind, connConfig) || (connConfig == "" && strings.HasPrefix(key, kind+"/")) {
+			delete(lookupCacheMap, key)
+		}
+	}
+	lookupCacheLock.Unlock()
+
+	if lookupCacheKvstoreEnabled() {
+		kvKey := genLookupCacheKvKey(kind, connConfig)
+		if connConfig == "" {
+			keyValue, err := kvstore.GetKvList(kvKey)
+			if err != nil {
+				log.Warn().Err(err).Msg("")
+				return
+			}
+			for _, kv := range keyValue {
+				kvstore.Delete(kv.Key)
+			}
+			return
+		}
+		if err := kvstore.Delete(kvKey); err != nil {
+			log.Warn().Err(err).Msgf("Failed to delete the %s lookup of %s in the Key-Value store", kind, connConfig)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/label"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+
+	validator "github.com/go-playground/validator/v10"
+)
+
+// TbObjectStorageReqStructLevelValidation func is for Validation
+func TbObjectStorageReqStructLevelValidation(sl validator.StructLevel) {
+
+	u := sl.Current().Interface().(model.TbObjectStorageReq)
+
+	err := common.CheckString(u.Name)
+	if err != nil {
+		// ReportError(field interface{}, fieldName, structFieldName, tag, param string)
+		sl.ReportError(u.Name, "name", "Name", err.Error(), "")
+	}
+}
+
+// storeObjectStorage stores the objectStorage object and its labels
+func storeObjectStorage(nsId string, content model.TbObjectStorageInfo) error {
+	Key := common.GenResourceKey(nsId, model.StrObjectStorage, content.Id)
+	Val, _ := json.Marshal(content)
+	err := kvstore.Put(Key, string(Val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	labels := map[string]string{
+		model.LabelManager:         model.StrManager,
+		model.LabelNamespace:       nsId,
+		model.LabelLabelType:       model.StrObjectStorage,
+		model.LabelId:              content.Id,
+		model.LabelName:            content.Name,
+		model.LabelUid:             content.Uid,
+		model.LabelCspResourceId:   content.CspResourceId,
+		model.LabelCspResourceName: content.CspResourceName,
+		model.LabelDescription:     content.Description,
+		model.LabelCreatedTime:     content.CreatedTime.String(),
+		model.LabelConnectionName:  content.ConnectionName,
+	}
+	common.SetSystemLabels(labels, content.ConnectionName)
+	err = label.CreateOrUpdateLabel(model.StrObjectStorage, content.Uid, Key, labels)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	return nil
+}
+
+// CreateObjectStorage accepts objectStorage (bucket) creation request, creates and returns an TB objectStorage object
+func CreateObjectStorage(nsId string, u *model.TbObjectStorageReq) (model.TbObjectStorageInfo, error) {
+
+	resourceType := model.StrObjectStorage
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbObjectStorageInfo{}, err
+	}
+
+	err = validate.Struct(u)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			log.Err(err).Msg("")
+			return model.TbObjectStorageInfo{}, err
+		}
+		return model.TbObjectStorageInfo{}, err
+	}
+
+	connConfig, err := common.GetConnConfig(u.ConnectionName)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbObjectStorageInfo{}, err
+	}
+	err = common.CheckCapability(connConfig.ProviderName, common.CapabilityObjectStorage, "objectStorage")
+	if err != nil {
+		return model.TbObjectStorageInfo{}, err
+	}
+
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, u.ConnectionName)
+	if err != nil {
+		return model.TbObjectStorageInfo{}, err
+	}
+
+	check, err := CheckResource(nsId, resourceType, u.Name)
+	if check {
+		err := fmt.Errorf("The objectStorage %s already exists.", u.Name)
+		return model.TbObjectStorageInfo{}, err
+	}
+	if err != nil {
+		err := fmt.Errorf("Failed to check the existence of the objectStorage %s.", u.Name)
+		return model.TbObjectStorageInfo{}, err
+	}
+
+	// bucket names are globally unique and lowercase in most CSPs
+	uid := common.GenUid()
+	publicAccessBlock := true
+	if u.PublicAccessBlock != nil {
+		publicAccessBlock = *u.PublicAccessBlock
+	}
+
+	requestBody := model.SpiderObjectStorageReqInfoWrapper{
+		ConnectionName: u.ConnectionName,
+		ReqInfo: model.SpiderObjectStorageInfo{
+			Name:              uid,
+			Versioning:        u.Versioning,
+			PublicAccessBlock: publicAccessBlock,
+		},
+	}
+
+	callResult := model.SpiderObjectStorageInfo{}
+	err = common.ExecuteHttpRequest(
+		resty.New(),
+		"POST",
+		fmt.Sprintf("%s/s3/bucket", model.SpiderRestUrl),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbObjectStorageInfo{}, err
+	}
+
+	content := model.TbObjectStorageInfo{
+		ResourceType:         resourceType,
+		Id:                   u.Name,
+		Name:                 u.Name,
+		Uid:                  uid,
+		ConnectionName:       u.ConnectionName,
+		CspResourceId:        callResult.IId.SystemId,
+		CspResourceName:      callResult.IId.NameId,
+		Versioning:           callResult.Versioning,
+		PublicAccessBlock:    callResult.PublicAccessBlock,
+		Endpoint:             callResult.Endpoint,
+		AssociatedObjectList: []string{},
+		CreatedTime:          callResult.CreatedTime,
+		KeyValueList:         callResult.KeyValueList,
+		Description:          u.Description,
+	}
+	if content.CspResourceName == "" {
+		content.CspResourceName = uid
+	}
+
+	log.Info().Msg("PUT CreateObjectStorage")
+	err = storeObjectStorage(nsId, content)
+	if err != nil {
+		return content, err
+	}
+	return content, nil
+}
+
+// UpdateObjectStorage updates the options (versioning, public access block) of the objectStorage
+func UpdateObjectStorage(nsId string, objectStorageId string, u *model.TbObjectStorageUpdateReq) (model.TbObjectStorageInfo, error) {
+
+	resourceInterface, err := GetResource(nsId, model.StrObjectStorage, objectStorageId)
+	if err != nil {
+		return model.TbObjectStorageInfo{}, err
+	}
+	content := resourceInterface.(model.TbObjectStorageInfo)
+
+	if u.Versioning != nil || u.PublicAccessBlock != nil {
+		requestBody := model.SpiderObjectStorageOptionReqWrapper{ConnectionName: content.ConnectionName}
+		requestBody.ReqInfo.Versioning = content.Versioning
+		requestBody.ReqInfo.PublicAccessBlock = content.PublicAccessBlock
+		if u.Versioning != nil {
+			requestBody.ReqInfo.Versioning = *u.Versioning
+		}
+		if u.PublicAccessBlock != nil {
+			requestBody.ReqInfo.PublicAccessBlock = *u.PublicAccessBlock
+		}
+
+		callResult := model.SpiderObjectStorageInfo{}
+		err = common.ExecuteHttpRequest(
+			resty.New(),
+			"PUT",
+			fmt.Sprintf("%s/s3/bucket/%s", model.SpiderRestUrl, content.CspResourceName),
+			nil,
+			common.SetUseBody(requestBody),
+			&requestBody,
+			&callResult,
+			common.MediumDuration,
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return model.TbObjectStorageInfo{}, err
+		}
+		content.Versioning = requestBody.ReqInfo.Versioning
+		content.PublicAccessBlock = requestBody.ReqInfo.PublicAccessBlock
+	}
+	if u.Description != "" {
+		content.Description = u.Description
+	}
+
+	err = storeObjectStorage(nsId, content)
+	if err != nil {
+		return content, err
+	}
+	return content, nil
+}
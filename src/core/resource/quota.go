@@ -0,0 +1,268 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvutil"
+	"github.com/rs/zerolog/log"
+)
+
+// getQuotaSpec returns the spec of a VM for quota (the system common namespace is checked as a fallback)
+func getQuotaSpec(nsId string, specId string) (model.TbSpecInfo, error) {
+	specInfo, err := GetSpec(nsId, specId)
+	if err != nil {
+		specInfo, err = GetSpec(model.SystemCommonNs, specId)
+		if err != nil {
+			return model.TbSpecInfo{}, err
+		}
+	}
+	return specInfo, nil
+}
+
+// GetSpecVCpu returns the number of vCPUs of the spec (the system common namespace is checked as a fallback)
+func GetSpecVCpu(nsId string, specId string) (int, error) {
+	specInfo, err := getQuotaSpec(nsId, specId)
+	if err != nil {
+		return 0, err
+	}
+	return int(specInfo.VCPU), nil
+}
+
+// GetVmQuotaUsage returns the resources consumed by the given number of VMs: VMs, vCPUs of the spec and root disks.
+// A root disk size which is not given ("default") is resolved to the default of the connection, then to the root disk size of the spec.
+func GetVmQuotaUsage(nsId string, connectionName string, specId string, rootDiskSize string, vmCount int) model.NsQuotaUsage {
+	spec, err := getQuotaSpec(nsId, specId)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to get spec (%s) for quota", specId)
+	}
+	return model.NsQuotaUsage{
+		Vms:        vmCount,
+		VCpus:      vmCount * int(spec.VCPU),
+		DataDiskGB: vmCount * resolveRootDiskGB(connectionName, spec, rootDiskSize),
+	}
+}
+
+// resolveRootDiskGB returns the root disk size (GB) of a VM: the size of the VM, the default of the connection or the root disk size of the spec
+func resolveRootDiskGB(connectionName string, spec model.TbSpecInfo, rootDiskSize string) int {
+	if size, err := strconv.Atoi(rootDiskSize); err == nil && size > 0 {
+		return size
+	}
+	if connectionName != "" {
+		connConfig, err := common.GetConnConfig(connectionName)
+		if err == nil {
+			if size, err := strconv.Atoi(connConfig.RootDiskSize); err == nil && size > 0 {
+				return size
+			}
+		}
+	}
+	if size, err := strconv.Atoi(spec.RootDiskSize); err == nil && size > 0 {
+		return size
+	}
+	return 0
+}
+
+// nsQuotaScope is the set of the usage dimensions to compute, so that a check reads only the objects it needs
+type nsQuotaScope struct {
+	// vms is for the VMs and vCPUs (VMs of MCIs and nodes of K8sClusters)
+	vms bool
+	// vNets is for the vNets
+	vNets bool
+	// disks is for the data disks and the root disks of the VMs of MCIs
+	disks bool
+}
+
+// GetNsQuotaUsage computes the current resource consumption of the namespace from the Key-Value store
+func GetNsQuotaUsage(nsId string) (model.NsQuotaUsageInfo, error) {
+	usageInfo := model.NsQuotaUsageInfo{NsId: nsId}
+
+	quota, err := common.GetNsQuota(nsId)
+	if err != nil {
+		return usageInfo, err
+	}
+	usageInfo.Quota = quota
+
+	usageInfo.Usage, err = getNsQuotaUsageOf(nsId, nsQuotaScope{vms: true, vNets: true, disks: true})
+	if err != nil {
+		return usageInfo, err
+	}
+	return usageInfo, nil
+}
+
+// getNsQuotaUsageOf computes the usage of the dimensions in the scope (the others are left 0)
+func getNsQuotaUsageOf(nsId string, scope nsQuotaScope) (model.NsQuotaUsage, error) {
+	usage := model.NsQuotaUsage{}
+
+	// cache specs to avoid repeated lookups
+	specCache := map[string]model.TbSpecInfo{}
+	getSpec := func(specId string) model.TbSpecInfo {
+		if specId == "" {
+			return model.TbSpecInfo{}
+		}
+		if v, ok := specCache[specId]; ok {
+			return v
+		}
+		v, err := getQuotaSpec(nsId, specId)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Failed to get spec (%s) for quota usage", specId)
+		}
+		specCache[specId] = v
+		return v
+	}
+
+	if scope.vms || scope.disks {
+		// VMs of MCIs (key: /ns/{nsId}/mci/{mciId}/vm/{vmId})
+		mciKey := common.GenMciKey(nsId, "", "") + "/mci"
+		keyValue, err := kvstore.GetKvList(mciKey)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return usage, err
+		}
+		for _, kv := range kvutil.FilterKvListBy(keyValue, mciKey, 3) {
+			segments := strings.Split(strings.TrimPrefix(kv.Key, mciKey+"/"), "/")
+			if segments[1] != "vm" {
+				continue
+			}
+			vmInfo := model.TbVmInfo{}
+			err = json.Unmarshal([]byte(kv.Value), &vmInfo)
+			if err != nil {
+				log.Error().Err(err).Str("key", kv.Key).Msg("Failed to unmarshal VM object")
+				continue
+			}
+			if vmInfo.Status == model.StatusTerminated {
+				continue
+			}
+			spec := getSpec(vmInfo.SpecId)
+			if scope.vms {
+				usage.Vms++
+				usage.VCpus += int(spec.VCPU)
+			}
+			if scope.disks {
+				usage.DataDiskGB += resolveRootDiskGB(vmInfo.ConnectionName, spec, vmInfo.RootDiskSize)
+			}
+		}
+	}
+
+	if scope.vms {
+		// nodes of K8sClusters (key: /ns/{nsId}/k8scluster/{k8sClusterId})
+		k8sKey := fmt.Sprintf("/ns/%s/k8scluster", nsId)
+		keyValue, err := kvstore.GetKvList(k8sKey)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return usage, err
+		}
+		for _, kv := range kvutil.FilterKvListBy(keyValue, k8sKey, 1) {
+			k8sInfo := model.TbK8sClusterInfo{}
+			err = json.Unmarshal([]byte(kv.Value), &k8sInfo)
+			if err != nil {
+				log.Error().Err(err).Str("key", kv.Key).Msg("Failed to unmarshal K8sCluster object")
+				continue
+			}
+			for _, ng := range k8sInfo.CspViewK8sClusterDetail.NodeGroupList {
+				usage.Vms += ng.DesiredNodeSize
+				usage.VCpus += ng.DesiredNodeSize * int(getSpec(ng.VMSpecName).VCPU)
+			}
+		}
+	}
+
+	if scope.vNets {
+		vNetKey := common.GenResourceKey(nsId, model.StrVNet, "")
+		keyValue, err := kvstore.GetKvList(vNetKey)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return usage, err
+		}
+		usage.VNets = len(kvutil.FilterKvListBy(keyValue, vNetKey, 1))
+	}
+
+	if scope.disks {
+		dataDiskKey := common.GenResourceKey(nsId, model.StrDataDisk, "")
+		keyValue, err := kvstore.GetKvList(dataDiskKey)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return usage, err
+		}
+		for _, kv := range kvutil.FilterKvListBy(keyValue, dataDiskKey, 1) {
+			dataDiskInfo := model.TbDataDiskInfo{}
+			err = json.Unmarshal([]byte(kv.Value), &dataDiskInfo)
+			if err != nil {
+				log.Error().Err(err).Str("key", kv.Key).Msg("Failed to unmarshal DataDisk object")
+				continue
+			}
+			size, err := strconv.Atoi(dataDiskInfo.DiskSize)
+			if err != nil {
+				continue
+			}
+			usage.DataDiskGB += size
+		}
+	}
+
+	return usage, nil
+}
+
+// CheckNsQuota checks whether the requested resources can be added to the namespace without exceeding its quota.
+// Only the dimensions which have a limit and are requested are computed from the Key-Value store.
+// It returns common.QuotaExceededError which describes the violated dimension and the current usage.
+func CheckNsQuota(nsId string, requested model.NsQuotaUsage) error {
+	quota, err := common.GetNsQuota(nsId)
+	if err != nil {
+		return err
+	}
+
+	scope := nsQuotaScope{
+		vms:   (quota.MaxVms > 0 && requested.Vms > 0) || (quota.MaxVCpus > 0 && requested.VCpus > 0),
+		vNets: quota.MaxVNets > 0 && requested.VNets > 0,
+		disks: quota.MaxDataDiskGB > 0 && requested.DataDiskGB > 0,
+	}
+	if scope == (nsQuotaScope{}) {
+		// no limit is set for the requested resources
+		return nil
+	}
+
+	usage, err := getNsQuotaUsageOf(nsId, scope)
+	if err != nil {
+		return err
+	}
+
+	if err := common.CheckQuotaLimit(nsId, model.QuotaDimensionVm, quota.MaxVms, usage.Vms, requested.Vms); err != nil {
+		return err
+	}
+	if err := common.CheckQuotaLimit(nsId, model.QuotaDimensionVCpu, quota.MaxVCpus, usage.VCpus, requested.VCpus); err != nil {
+		return err
+	}
+	if err := common.CheckQuotaLimit(nsId, model.QuotaDimensionVNet, quota.MaxVNets, usage.VNets, requested.VNets); err != nil {
+		return err
+	}
+	if err := common.CheckQuotaLimit(nsId, model.QuotaDimensionDataDiskGB, quota.MaxDataDiskGB, usage.DataDiskGB, requested.DataDiskGB); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CheckNsQuotaForVms checks the quota of the namespace for the given number of VMs (or K8s nodes) of the spec
+func CheckNsQuotaForVms(nsId string, specId string, vmCount int) error {
+	vCpu, err := GetSpecVCpu(nsId, specId)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to get vCPU of spec (%s) for quota check", specId)
+	}
+	return CheckNsQuota(nsId, model.NsQuotaUsage{Vms: vmCount, VCpus: vCpu * vmCount})
+}
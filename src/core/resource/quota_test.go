@@ -0,0 +1,88 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// TestMain runs the tests with the embedded kvstore in a temporary directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "tb-resource")
+	if err != nil {
+		panic(err)
+	}
+	store, err := bolt.NewBoltStore(context.Background(), bolt.Config{Path: filepath.Join(dir, "kvstore.db")})
+	if err != nil {
+		panic(err)
+	}
+	if err := kvstore.InitializeStore(store); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	store.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestResolveRootDiskGB(t *testing.T) {
+	spec := model.TbSpecInfo{RootDiskSize: "50"}
+	tests := []struct {
+		name         string
+		spec         model.TbSpecInfo
+		rootDiskSize string
+		want         int
+	}{
+		{"size of the VM", spec, "100", 100},
+		{"default is the size of the spec", spec, "default", 50},
+		{"empty is the size of the spec", spec, "", 50},
+		{"unknown size of the spec", model.TbSpecInfo{RootDiskSize: "-1"}, "default", 0},
+	}
+	for _, tt := range tests {
+		if got := resolveRootDiskGB("", tt.spec, tt.rootDiskSize); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckNsQuotaRootDisks(t *testing.T) {
+	nsId := "quota-ns"
+	if _, err := common.UpdateNsQuota(nsId, &model.NsQuota{MaxDataDiskGB: 100}); err != nil {
+		t.Fatal(err)
+	}
+	defer kvstore.Delete(common.GenNsQuotaKey(nsId))
+
+	vmKey := common.GenMciKey(nsId, "mci01", "vm01")
+	val, _ := json.Marshal(model.TbVmInfo{Id: "vm01", Status: model.StatusRunning, RootDiskSize: "60"})
+	if err := kvstore.Put(vmKey, string(val)); err != nil {
+		t.Fatal(err)
+	}
+	defer kvstore.Delete(vmKey)
+
+	// the root disks of the VMs are counted with the data disks
+	err := CheckNsQuota(nsId, model.NsQuotaUsage{Vms: 1, DataDiskGB: 50})
+	var quotaErr *common.QuotaExceededError
+	if !errors.As(err, &quotaErr) || quotaErr.Dimension != model.QuotaDimensionDataDiskGB || quotaErr.Usage != 60 {
+		t.Fatalf("got %v, want the disk quota exceeded with the usage 60", err)
+	}
+	if err := CheckNsQuota(nsId, model.NsQuotaUsage{DataDiskGB: 40}); err != nil {
+		t.Errorf("got %v within the quota", err)
+	}
+	// no limit is set for VMs
+	if err := CheckNsQuota(nsId, model.NsQuotaUsage{Vms: 1000}); err != nil {
+		t.Errorf("got %v without the limit of VMs", err)
+	}
+
+	usage, err := GetNsQuotaUsage(nsId)
+	if err != nil || usage.Usage != (model.NsQuotaUsage{Vms: 1, DataDiskGB: 60}) {
+		t.Errorf("usage: got %+v (%v)", usage.Usage, err)
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"github.com/rs/zerolog/log"
+)
+
+// maxSharedVNetPrefixLength keeps room for the 2 subnets (prefix+2) of a shared vNet
+const maxSharedVNetPrefixLength = 28
+
+// getNsResourceDefaultsOverride returns the shared resource defaults set for the namespace (empty if not set)
+func getNsResourceDefaultsOverride(nsId string) (model.NsResourceDefaults, error) {
+	override := model.NsResourceDefaults{}
+
+	keyValue, err := kvstore.GetKv(common.GenNsResourceDefaultsKey(nsId))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return override, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		return override, nil
+	}
+	err = json.Unmarshal([]byte(keyValue.Value), &override)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return override, err
+	}
+	return override, nil
+}
+
+// mergeResourceDefaults returns the override merged over the global defaults
+func mergeResourceDefaults(override model.NsResourceDefaults) model.NsResourceDefaults {
+	effective := model.GlobalResourceDefaults()
+	if override.CidrSupernet != "" {
+		effective.CidrSupernet = override.CidrSupernet
+	}
+	if override.VNetPrefixLength != 0 {
+		effective.VNetPrefixLength = override.VNetPrefixLength
+	}
+	if len(override.SecurityGroupRules) > 0 {
+		effective.SecurityGroupRules = override.SecurityGroupRules
+	}
+	if override.SshKeyNameFormat != "" {
+		effective.SshKeyNameFormat = override.SshKeyNameFormat
+	}
+	return effective
+}
+
+// validateFirewallRule checks the protocol, direction, ports and CIDR of a firewall rule
+func validateFirewallRule(rule model.TbFirewallRuleInfo) error {
+	switch strings.ToLower(rule.IPProtocol) {
+	case "tcp", "udp", "icmp", "all":
+	default:
+		return fmt.Errorf("invalid IPProtocol %q (tcp, udp, icmp or all)", rule.IPProtocol)
+	}
+	switch strings.ToLower(rule.Direction) {
+	case "inbound", "outbound":
+	default:
+		return fmt.Errorf("invalid Direction %q (inbound or outbound)", rule.Direction)
+	}
+	from, err := strconv.Atoi(rule.FromPort)
+	if err != nil || from < -1 || from > 65535 {
+		return fmt.Errorf("invalid FromPort %q (-1 or 0-65535)", rule.FromPort)
+	}
+	to, err := strconv.Atoi(rule.ToPort)
+	if err != nil || to < -1 || to > 65535 {
+		return fmt.Errorf("invalid ToPort %q (-1 or 0-65535)", rule.ToPort)
+	}
+	if from > to {
+		return fmt.Errorf("FromPort %d is greater than ToPort %d", from, to)
+	}
+	if rule.CIDR != "" {
+		if _, err := netip.ParsePrefix(rule.CIDR); err != nil {
+			return fmt.Errorf("invalid CIDR %q: %v", rule.CIDR, err)
+		}
+	}
+	return nil
+}
+
+// validateResourceDefaults checks the effective shared resource defaults
+func validateResourceDefaults(d model.NsResourceDefaults) error {
+	supernet, err := netip.ParsePrefix(d.CidrSupernet)
+	if err != nil {
+		return fmt.Errorf("invalid cidrSupernet %q: %v", d.CidrSupernet, err)
+	}
+	if !supernet.Addr().Is4() {
+		return fmt.Errorf("cidrSupernet %q must be an IPv4 CIDR block", d.CidrSupernet)
+	}
+	if supernet.Masked() != supernet {
+		return fmt.Errorf("cidrSupernet %q has host bits set (use %s)", d.CidrSupernet, supernet.Masked())
+	}
+	if d.VNetPrefixLength < supernet.Bits() || d.VNetPrefixLength > maxSharedVNetPrefixLength {
+		return fmt.Errorf("vNetPrefixLength %d must be between %d (the prefix of cidrSupernet) and %d", d.VNetPrefixLength, supernet.Bits(), maxSharedVNetPrefixLength)
+	}
+
+	for i, rule := range d.SecurityGroupRules {
+		if err := validateFirewallRule(rule); err != nil {
+			return fmt.Errorf("securityGroupRules[%d]: %w", i, err)
+		}
+	}
+
+	if !strings.Contains(d.SshKeyNameFormat, model.ResourceDefaultsConnectionPlaceholder) {
+		return fmt.Errorf("sshKeyNameFormat %q must contain %s to be unique per connection", d.SshKeyNameFormat, model.ResourceDefaultsConnectionPlaceholder)
+	}
+	sample := formatSharedSshKeyName(d.SshKeyNameFormat, "default", "aws-ap-northeast-2")
+	if err := common.CheckString(sample); err != nil {
+		return fmt.Errorf("sshKeyNameFormat %q generates an invalid name: %v", d.SshKeyNameFormat, err)
+	}
+	return nil
+}
+
+// GetNsResourceDefaults returns the shared resource defaults of the namespace (override and effective)
+func GetNsResourceDefaults(nsId string) (model.NsResourceDefaultsInfo, error) {
+	info := model.NsResourceDefaultsInfo{NsId: nsId}
+
+	if _, err := common.GetNs(nsId); err != nil {
+		log.Error().Err(err).Msg("")
+		return info, err
+	}
+	override, err := getNsResourceDefaultsOverride(nsId)
+	if err != nil {
+		return info, err
+	}
+	info.Override = override
+	info.Effective = mergeResourceDefaults(override)
+	return info, nil
+}
+
+// UpdateNsResourceDefaults sets the shared resource defaults of the namespace.
+// The defaults apply only to the shared resources created afterwards.
+func UpdateNsResourceDefaults(nsId string, req *model.NsResourceDefaults) (model.NsResourceDefaultsInfo, error) {
+	info := model.NsResourceDefaultsInfo{NsId: nsId}
+
+	if _, err := common.GetNs(nsId); err != nil {
+		log.Error().Err(err).Msg("")
+		return info, err
+	}
+
+	effective := mergeResourceDefaults(*req)
+	if err := validateResourceDefaults(effective); err != nil {
+		log.Error().Err(err).Msg("")
+		return info, common.NewValidationFailedError("invalid resource defaults: %v", err)
+	}
+
+	val, err := json.Marshal(req)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return info, err
+	}
+	err = kvstore.Put(common.GenNsResourceDefaultsKey(nsId), string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return info, err
+	}
+
+	info.Override = *req
+	info.Effective = effective
+	return info, nil
+}
+
+// getEffectiveResourceDefaults returns the shared resource defaults applied in the namespace
+func getEffectiveResourceDefaults(nsId string) (model.NsResourceDefaults, error) {
+	override, err := getNsResourceDefaultsOverride(nsId)
+	if err != nil {
+		return model.NsResourceDefaults{}, err
+	}
+	return mergeResourceDefaults(override), nil
+}
+
+// formatSharedSshKeyName returns the name of the shared sshKey by the naming convention
+func formatSharedSshKeyName(format string, nsId string, connectionName string) string {
+	name := strings.ReplaceAll(format, model.ResourceDefaultsNsIdPlaceholder, nsId)
+	return strings.ReplaceAll(name, model.ResourceDefaultsConnectionPlaceholder, connectionName)
+}
+
+// GetSharedResourceName returns the id of the shared resource (vNet, subnet, securityGroup, sshKey) of the connection.
+// The sshKey follows the naming convention of the namespace; the others are named nsId + "-shared-" + connectionName.
+func GetSharedResourceName(nsId string, resourceType string, connectionName string) (string, error) {
+	if resourceType != model.StrSSHKey {
+		return nsId + model.StrSharedResourceName + connectionName, nil
+	}
+	defaults, err := getEffectiveResourceDefaults(nsId)
+	if err != nil {
+		return "", err
+	}
+	return formatSharedSshKeyName(defaults.SshKeyNameFormat, nsId, connectionName), nil
+}
+
+// IsSharedResourceName returns true if the id is the name of a shared resource of the namespace
+func IsSharedResourceName(nsId string, resourceType string, id string) bool {
+	if strings.HasPrefix(id, nsId+model.StrSharedResourceName) {
+		return true
+	}
+	if resourceType != model.StrSSHKey {
+		return false
+	}
+	defaults, err := getEffectiveResourceDefaults(nsId)
+	if err != nil {
+		return false
+	}
+	pattern := regexp.QuoteMeta(defaults.SshKeyNameFormat)
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(model.ResourceDefaultsNsIdPlaceholder), regexp.QuoteMeta(nsId))
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(model.ResourceDefaultsConnectionPlaceholder), ".+")
+	matched, _ := regexp.MatchString("^"+pattern+"$", id)
+	return matched
+}
+
+// sharedVNetCidrs returns the CIDR block of the shared vNet and its 2 subnets for the index of the connection.
+// The vNet is the (index+1)-th block of vNetPrefixLength in the supernet (10.i.0.0/16 in 10.0.0.0/8 by default),
+// and the subnets are the first 2 quarters of the vNet (the others are reserved for additional subnets).
+func sharedVNetCidrs(d model.NsResourceDefaults, index int) (string, []string, error) {
+	supernet, err := netip.ParsePrefix(d.CidrSupernet)
+	if err != nil {
+		return "", nil, err
+	}
+	blocks := 1 << (d.VNetPrefixLength - supernet.Bits())
+	blockIndex := 0
+	if blocks > 1 {
+		available := blocks - 1
+		if available > 254 {
+			available = 254
+		}
+		blockIndex = (index % available) + 1
+	}
+
+	base := supernet.Masked().Addr().As4()
+	baseInt := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+	blockSize := uint32(1) << (32 - d.VNetPrefixLength)
+	toPrefix := func(v uint32, bits int) string {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}), bits).String()
+	}
+
+	vNetStart := baseInt + uint32(blockIndex)*blockSize
+	subnetBits := d.VNetPrefixLength + 2
+	subnets := []string{
+		toPrefix(vNetStart, subnetBits),
+		toPrefix(vNetStart+blockSize/4, subnetBits),
+	}
+	return toPrefix(vNetStart, d.VNetPrefixLength), subnets, nil
+}
@@ -15,10 +15,12 @@ limitations under the License.
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
@@ -43,7 +45,7 @@ func TbSecurityGroupReqStructLevelValidation(sl validator.StructLevel) {
 }
 
 // CreateSecurityGroup accepts SG creation request, creates and returns an TB SG object
-func CreateSecurityGroup(nsId string, u *model.TbSecurityGroupReq, option string) (model.TbSecurityGroupInfo, error) {
+func CreateSecurityGroup(ctx context.Context, nsId string, u *model.TbSecurityGroupReq, option string) (model.TbSecurityGroupInfo, error) {
 
 	resourceType := model.StrSecurityGroup
 
@@ -54,6 +56,14 @@ func CreateSecurityGroup(nsId string, u *model.TbSecurityGroupReq, option string
 		return temp, err
 	}
 
+	// Check the naming policy of the namespace
+	if option != "register" {
+		err = common.CheckNsNamingPolicy(ctx, nsId, resourceType, u.Name)
+		if err != nil {
+			return model.TbSecurityGroupInfo{}, err
+		}
+	}
+
 	// if option == "register" {
 	// 	mockFirewallRule := model.SpiderSecurityRuleInfo{
 	// 		FromPort:   "22",
@@ -90,6 +100,12 @@ func CreateSecurityGroup(nsId string, u *model.TbSecurityGroupReq, option string
 		return temp, err
 	}
 
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, u.ConnectionName)
+	if err != nil {
+		return model.TbSecurityGroupInfo{}, err
+	}
+
 	check, err := CheckResource(nsId, resourceType, u.Name)
 
 	if check {
@@ -243,6 +259,7 @@ func CreateSecurityGroup(nsId string, u *model.TbSecurityGroupReq, option string
 	} else if option == "register" && u.CspResourceId != "" {
 		content.SystemLabel = "Registered from CSP resource"
 	}
+	content.Provenance = common.NewProvenance(ctx, provenanceVia(option))
 
 	log.Info().Msg("PUT CreateSecurityGroup")
 	Key := common.GenResourceKey(nsId, resourceType, content.Id)
@@ -267,6 +284,8 @@ func CreateSecurityGroup(nsId string, u *model.TbSecurityGroupReq, option string
 		model.LabelDescription:     content.Description,
 		model.LabelConnectionName:  content.ConnectionName,
 	}
+	common.SetSystemLabels(labels, content.ConnectionName)
+	common.SetProvenanceLabels(labels, content.Provenance)
 	err = label.CreateOrUpdateLabel(model.StrSecurityGroup, uid, Key, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -617,3 +636,358 @@ func DeleteFirewallRules(nsId string, securityGroupId string, req []model.TbFire
 	// }
 	return newSecurityGroup, nil
 }
+
+// RegisterSecurityGroup registers a security group that already exists in the CSP, and returns the TB SG object.
+// The SG is linked to the registered vNet which owns it in the CSP. If the vNet is not registered yet,
+// the CSP vNet Id is stored with VNetUnregistered flag, and the SG is linked when the vNet is registered.
+func RegisterSecurityGroup(ctx context.Context, nsId string, req *model.TbRegisterSecurityGroupReq) (model.TbSecurityGroupInfo, error) {
+	log.Info().Msg("RegisterSecurityGroup")
+
+	var emptyRet model.TbSecurityGroupInfo
+	resourceType := model.StrSecurityGroup
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	err = validate.Struct(req)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			log.Error().Err(err).Msg("")
+			return emptyRet, err
+		}
+		return emptyRet, err
+	}
+	err = common.CheckString(req.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, req.ConnectionName)
+	if err != nil {
+		return emptyRet, err
+	}
+
+	exists, err := CheckResource(nsId, resourceType, req.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	if exists {
+		err := common.NewConflictError("the securityGroup (%s) already exists", req.Name)
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	// Resolve the vNet which owns the SG
+	vNetInfo := model.TbVNetInfo{}
+	cspVNetId := ""
+	if req.VNetId != "" {
+		tempInterface, err := GetResource(nsId, model.StrVNet, req.VNetId)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return emptyRet, err
+		}
+		err = common.CopySrcToDest(&tempInterface, &vNetInfo)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return emptyRet, err
+		}
+		if vNetInfo.ConnectionName != req.ConnectionName {
+			err := common.NewValidationFailedError("the vNet (%s) is not in the connection (%s)", req.VNetId, req.ConnectionName)
+			log.Error().Err(err).Msg("")
+			return emptyRet, err
+		}
+		cspVNetId = vNetInfo.CspResourceId
+	} else {
+		cspVNetId, err = getSecurityGroupOwnerVNet(req.ConnectionName, req.CspResourceId)
+		if err != nil {
+			// The owner vNet is resolved again from the registration result
+			log.Warn().Err(err).Msgf("failed to get the owner vNet of the securityGroup (%s)", req.CspResourceId)
+		}
+		if cspVNetId != "" {
+			vNetInfo, _ = findVNetByCspResourceId(nsId, req.ConnectionName, cspVNetId)
+		}
+	}
+
+	uid := common.GenUid()
+
+	// [Via Spider] Register the SG
+	spReqt := model.SpiderSecurityReqInfoWrapper{}
+	spReqt.ConnectionName = req.ConnectionName
+	spReqt.ReqInfo.Name = uid
+	spReqt.ReqInfo.VPCName = vNetInfo.CspResourceName
+	spReqt.ReqInfo.CSPId = req.CspResourceId
+
+	url := fmt.Sprintf("%s/regsecuritygroup", model.SpiderRestUrl)
+
+	var spResp model.SpiderSecurityInfo
+
+	client := resty.New()
+	method := "POST"
+
+	err = common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(spReqt),
+		&spReqt,
+		&spResp,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	// Resolve the vNet from the registration result if it is still unknown
+	if cspVNetId == "" && spResp.VpcIID.SystemId != "" {
+		cspVNetId = spResp.VpcIID.SystemId
+		vNetInfo, _ = findVNetByCspResourceId(nsId, req.ConnectionName, cspVNetId)
+	}
+
+	content := model.TbSecurityGroupInfo{}
+	content.ResourceType = resourceType
+	content.Id = req.Name
+	content.Name = req.Name
+	content.Uid = uid
+	content.ConnectionName = req.ConnectionName
+	content.VNetId = vNetInfo.Id
+	if vNetInfo.Id == "" {
+		content.CspVNetId = cspVNetId
+		content.VNetUnregistered = true
+	}
+	content.CspResourceId = spResp.IId.SystemId
+	content.CspResourceName = spResp.IId.NameId
+	content.Description = req.Description
+	content.KeyValueList = spResp.KeyValueList
+	content.AssociatedObjectList = []string{}
+
+	tempTbFirewallRules := []model.TbFirewallRuleInfo{}
+	for _, v := range spResp.SecurityRules {
+		tempTbFirewallRules = append(tempTbFirewallRules, model.TbFirewallRuleInfo(v))
+	}
+	content.FirewallRules = tempTbFirewallRules
+
+	content.SystemLabel = "Registered from CSP resource"
+	content.Provenance = common.NewProvenance(ctx, model.CreatedViaRegistered)
+
+	Key := common.GenResourceKey(nsId, resourceType, content.Id)
+	Val, _ := json.Marshal(content)
+	err = kvstore.Put(Key, string(Val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return content, err
+	}
+
+	// Store label info using CreateOrUpdateLabel
+	labels := map[string]string{
+		model.LabelManager:         model.StrManager,
+		model.LabelNamespace:       nsId,
+		model.LabelLabelType:       model.StrSecurityGroup,
+		model.LabelId:              content.Id,
+		model.LabelName:            content.Name,
+		model.LabelUid:             content.Uid,
+		model.LabelVNetId:          content.VNetId,
+		model.LabelCspResourceId:   content.CspResourceId,
+		model.LabelCspResourceName: content.CspResourceName,
+		model.LabelDescription:     content.Description,
+		model.LabelConnectionName:  content.ConnectionName,
+	}
+	common.SetSystemLabels(labels, content.ConnectionName)
+	common.SetProvenanceLabels(labels, content.Provenance)
+	err = label.CreateOrUpdateLabel(model.StrSecurityGroup, uid, Key, labels)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return content, err
+	}
+
+	return content, nil
+}
+
+// DeregisterSecurityGroup deregisters a security group from CB-Tumblebug and CB-Spider.
+// The SG in the CSP is not deleted.
+func DeregisterSecurityGroup(nsId string, securityGroupId string) (model.SimpleMsg, error) {
+	log.Info().Msg("DeregisterSecurityGroup")
+
+	var emptyRet model.SimpleMsg
+	var ret model.SimpleMsg
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	err = common.CheckString(securityGroupId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	securityGroupKey := common.GenResourceKey(nsId, model.StrSecurityGroup, securityGroupId)
+	keyValue, err := kvstore.GetKv(securityGroupKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		err := common.NewResourceNotFoundError(model.StrSecurityGroup, securityGroupId)
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	securityGroupInfo := model.TbSecurityGroupInfo{}
+	err = json.Unmarshal([]byte(keyValue.Value), &securityGroupInfo)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	if len(securityGroupInfo.AssociatedObjectList) > 0 {
+		err := common.NewResourceInUseError(model.StrSecurityGroup, securityGroupId, securityGroupInfo.AssociatedObjectList)
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	// [Via Spider] Deregister the SG
+	spReqt := spiderConnectionRequest{}
+	spReqt.ConnectionName = securityGroupInfo.ConnectionName
+
+	url := fmt.Sprintf("%s/regsecuritygroup/%s", model.SpiderRestUrl, securityGroupInfo.CspResourceName)
+
+	var spResp spiderBooleanInfoResp
+
+	client := resty.New()
+	method := "DELETE"
+
+	err = common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(spReqt),
+		&spReqt,
+		&spResp,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	ok, err := strconv.ParseBool(spResp.Result)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	if !ok {
+		err := fmt.Errorf("failed to deregister the securityGroup (%s)", securityGroupId)
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	err = kvstore.Delete(securityGroupKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	err = label.RemoveLabel(model.StrSecurityGroup, securityGroupInfo.Uid, securityGroupKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	ret.Message = fmt.Sprintf("the securityGroup (%s) has been deregistered", securityGroupId)
+
+	return ret, nil
+}
+
+// getSecurityGroupOwnerVNet returns the CSP Id of the vNet which owns the CSP security group
+func getSecurityGroupOwnerVNet(connectionName string, cspSecurityGroupId string) (string, error) {
+
+	spReqt := spiderGetSGOwnerVPCRequest{}
+	spReqt.ConnectionName = connectionName
+	spReqt.ReqInfo.CSPId = cspSecurityGroupId
+
+	url := fmt.Sprintf("%s/getsecuritygroupowervpc", model.SpiderRestUrl)
+
+	var spResp model.IID
+
+	client := resty.New()
+	method := "POST"
+
+	err := common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(spReqt),
+		&spReqt,
+		&spResp,
+		common.MediumDuration,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return spResp.SystemId, nil
+}
+
+// findVNetByCspResourceId returns the registered vNet of the connection which has the CSP resource Id
+func findVNetByCspResourceId(nsId string, connectionName string, cspResourceId string) (model.TbVNetInfo, error) {
+
+	resourceList, err := ListResource(nsId, model.StrVNet, "", "")
+	if err != nil {
+		return model.TbVNetInfo{}, err
+	}
+	vNetList, _ := resourceList.([]model.TbVNetInfo)
+	for _, v := range vNetList {
+		if v.ConnectionName == connectionName && v.CspResourceId == cspResourceId {
+			return v, nil
+		}
+	}
+
+	return model.TbVNetInfo{}, common.NewResourceNotFoundError(model.StrVNet, cspResourceId)
+}
+
+// linkRegisteredSecurityGroups links the registered SGs waiting for their vNet (VNetUnregistered) to the vNet
+func linkRegisteredSecurityGroups(nsId string, vNetInfo model.TbVNetInfo) error {
+
+	sgKvs, err := kvstore.GetKvList(common.GenResourceKey(nsId, model.StrSecurityGroup, ""))
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range sgKvs {
+		sg := model.TbSecurityGroupInfo{}
+		err = json.Unmarshal([]byte(kv.Value), &sg)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			continue
+		}
+		if !sg.VNetUnregistered || sg.CspVNetId != vNetInfo.CspResourceId || sg.ConnectionName != vNetInfo.ConnectionName {
+			continue
+		}
+
+		sg.VNetId = vNetInfo.Id
+		sg.CspVNetId = ""
+		sg.VNetUnregistered = false
+
+		val, _ := json.Marshal(sg)
+		err = kvstore.Put(kv.Key, string(val))
+		if err != nil {
+			return err
+		}
+		err = label.CreateOrUpdateLabel(model.StrSecurityGroup, sg.Uid, kv.Key, map[string]string{model.LabelVNetId: sg.VNetId})
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("the securityGroup (%s) has been linked to the vNet (%s)", sg.Id, vNetInfo.Id)
+	}
+
+	return nil
+}
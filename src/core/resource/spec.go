@@ -15,10 +15,15 @@ limitations under the License.
 package resource
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
@@ -62,9 +67,70 @@ func ConvertSpiderSpecToTumblebugSpec(spiderSpec model.SpiderSpecInfo) (model.Tb
 	tempFloat64, _ := strconv.ParseFloat(spiderSpec.Mem, 32)
 	tumblebugSpec.MemoryGiB = float32(tempFloat64 / 1024)
 
+	// accelerators (Gpu.Mem is given in MiB)
+	for _, gpu := range spiderSpec.Gpu {
+		count, _ := strconv.Atoi(gpu.Count)
+		if count <= 0 {
+			continue
+		}
+		tumblebugSpec.AcceleratorType = "gpu"
+		tumblebugSpec.AcceleratorModel = strings.TrimSpace(gpu.Mfr + " " + gpu.Model)
+		tumblebugSpec.AcceleratorCount += uint8(count)
+		mem, _ := strconv.ParseFloat(gpu.Mem, 32)
+		tumblebugSpec.AcceleratorMemoryGB += float32(mem / 1024 * float64(count))
+	}
+
+	tumblebugSpec.Architecture = inferSpecArchitecture(spiderSpec)
+
+	for _, kv := range spiderSpec.KeyValueList {
+		if strings.Contains(strings.ToLower(kv.Key), "spotprice") {
+			if price, err := strconv.ParseFloat(kv.Value, 32); err == nil {
+				tumblebugSpec.SpotCostPerHour = float32(price)
+			}
+		}
+	}
+
 	return tumblebugSpec, nil
 }
 
+// naming rules of arm64 specs used by inferSpecArchitecture
+var (
+	specNameAwsGraviton = regexp.MustCompile(`^[a-z]+[0-9]+g[a-z]*\.`)
+	specNameAzureAmpere = regexp.MustCompile(`^standard_[a-z]+[0-9]+[a-z]*p[a-z]*_v[0-9]+$`)
+)
+
+// inferSpecArchitecture returns the CPU architecture (arm64 or x86_64) of the Spider spec.
+// The architecture given in KeyValueList is used first, and then the naming rule of the CSP.
+func inferSpecArchitecture(spiderSpec model.SpiderSpecInfo) string {
+	for _, kv := range spiderSpec.KeyValueList {
+		key := strings.ToLower(kv.Key)
+		if !strings.Contains(key, "architecture") && !strings.Contains(key, "processorinfo") {
+			continue
+		}
+		value := strings.ToLower(kv.Value)
+		switch {
+		case strings.Contains(value, "arm64") || strings.Contains(value, "aarch64"):
+			return "arm64"
+		case strings.Contains(value, "x86_64") || strings.Contains(value, "amd64") || strings.Contains(value, "i386"):
+			return "x86_64"
+		}
+	}
+
+	name := strings.ToLower(spiderSpec.Name)
+	switch {
+	// AWS Graviton (e.g., m6g.large, c7gn.xlarge, t4g.micro)
+	case specNameAwsGraviton.MatchString(name):
+		return "arm64"
+	// GCP Tau T2A and Axion (e.g., t2a-standard-1, c4a-standard-4)
+	case strings.HasPrefix(name, "t2a-") || strings.HasPrefix(name, "c4a-"):
+		return "arm64"
+	// Azure Ampere Altra (e.g., Standard_D2ps_v5, Standard_E4pds_v5)
+	case specNameAzureAmpere.MatchString(name):
+		return "arm64"
+	}
+	return "x86_64"
+}
+
 // LookupSpecList accepts Spider conn config,
 // lookups and returns the list of all specs in the region of conn config
 // in the form of the list of Spider spec objects
@@ -78,32 +144,43 @@ func LookupSpecList(connConfig string) (model.SpiderSpecList, error) {
 	}
 
 	var callResult model.SpiderSpecList
-	client := resty.New()
-	client.SetTimeout(10 * time.Minute)
-	url := model.SpiderRestUrl + "/vmspec"
-	method := "GET"
-	requestBody := model.SpiderConnectionName{}
-	requestBody.ConnectionName = connConfig
 
-	err := common.ExecuteHttpRequest(
-		client,
-		method,
-		url,
-		nil,
-		common.SetUseBody(requestBody),
-		&requestBody,
-		&callResult,
-		common.MediumDuration,
-	)
+	payload, cached := getLookupCache(LookupCacheSpec, connConfig)
+	if !cached {
+		client := resty.New()
+		client.SetTimeout(10 * time.Minute)
+		url := model.SpiderRestUrl + "/vmspec"
+		method := "GET"
+		requestBody := model.SpiderConnectionName{}
+		requestBody.ConnectionName = connConfig
+
+		err := common.ExecuteHttpRequest(
+			client,
+			method,
+			url,
+			nil,
+			common.SetUseBody(requestBody),
+			&requestBody,
+			&payload,
+			common.MediumDuration,
+			common.WithConnectionProvider(connConfig),
+		)
 
-	if err != nil {
-		log.Trace().Err(err).Msg("")
-		content := model.SpiderSpecList{}
-		return content, err
+		if err != nil {
+			log.Trace().Err(err).Msg("")
+			content := model.SpiderSpecList{}
+			return content, err
+		}
+		putLookupCache(LookupCacheSpec, connConfig, payload)
 	}
 
-	temp := callResult
-	return temp, nil
+	// decode the raw payload for each call so that callers do not share the cached object
+	err := json.Unmarshal(payload, &callResult)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.SpiderSpecList{}, err
+	}
+	return callResult, nil
 
 }
 
@@ -122,6 +199,18 @@ func LookupSpec(connConfig string, specName string) (model.SpiderSpecInfo, error
 		return content, err
 	}
 
+	// use the cached spec list of the connection if available
+	if payload, ok := loadLookupCache(LookupCacheSpec, connConfig); ok {
+		specList := model.SpiderSpecList{}
+		if err := json.Unmarshal(payload, &specList); err == nil {
+			for _, spec := range specList.Vmspec {
+				if spec.Name == specName {
+					return spec, nil
+				}
+			}
+		}
+	}
+
 	client := resty.New()
 	client.SetTimeout(2 * time.Minute)
 	url := model.SpiderRestUrl + "/vmspec/" + specName
@@ -139,6 +228,7 @@ func LookupSpec(connConfig string, specName string) (model.SpiderSpecInfo, error
 		&requestBody,
 		&callResult,
 		common.MediumDuration,
+		common.WithConnectionProvider(connConfig),
 	)
 
 	if err != nil {
@@ -149,73 +239,194 @@ func LookupSpec(connConfig string, specName string) (model.SpiderSpecInfo, error
 	return callResult, nil
 }
 
-// FetchSpecsForConnConfig lookups all specs for region of conn config, and saves into TB spec objects
-func FetchSpecsForConnConfig(connConfigName string, nsId string) (uint, error) {
+// specFetchCols are the columns of a spec object which are given by CB-Spider and compared by fetchSpecs
+// (other columns such as cost and evaluation scores are enriched separately and kept as they are)
+var specFetchCols = []string{"CspSpecName", "ConnectionName", "ProviderName", "RegionName", "VCPU", "MemoryGiB",
+	"AcceleratorType", "AcceleratorModel", "AcceleratorCount", "AcceleratorMemoryGB", "Architecture", "Unavailable"}
+
+// specFetchLock serializes writing the fetched specs to the database
+// (connections are looked up in parallel, but SQLite does not allow concurrent writes)
+var specFetchLock sync.Mutex
+
+// isSpecChanged returns true if the columns given by CB-Spider differ
+func isSpecChanged(stored model.TbSpecInfo, fetched model.TbSpecInfo) bool {
+	return stored.CspSpecName != fetched.CspSpecName ||
+		stored.ConnectionName != fetched.ConnectionName ||
+		stored.ProviderName != fetched.ProviderName ||
+		stored.RegionName != fetched.RegionName ||
+		stored.VCPU != fetched.VCPU ||
+		stored.MemoryGiB != fetched.MemoryGiB ||
+		stored.AcceleratorType != fetched.AcceleratorType ||
+		stored.AcceleratorModel != fetched.AcceleratorModel ||
+		stored.AcceleratorCount != fetched.AcceleratorCount ||
+		stored.AcceleratorMemoryGB != fetched.AcceleratorMemoryGB ||
+		stored.Architecture != fetched.Architecture ||
+		(fetched.SpotCostPerHour != 0 && stored.SpotCostPerHour != fetched.SpotCostPerHour) ||
+		stored.Unavailable
+}
+
+// FetchSpecsForConnConfig lookups all specs for region of conn config, and saves the difference from the stored specs.
+// Only new or changed specs are written, and specs which disappeared from the CSP are marked as unavailable.
+func FetchSpecsForConnConfig(connConfigName string, nsId string) (model.SpecFetchResult, error) {
 	log.Debug().Msg("FetchSpecsForConnConfig(" + connConfigName + ")")
-	specCount := uint(0)
+	result := model.SpecFetchResult{ConnectionName: connConfigName}
 
 	connConfig, err := common.GetConnConfig(connConfigName)
 	if err != nil {
 		log.Error().Err(err).Msgf("Cannot GetConnConfig in %s", connConfigName)
-		return specCount, err
+		return result, err
 	}
+	result.ProviderName = strings.ToLower(connConfig.ProviderName)
 
+	// refresh the cached lookup since the specs are repopulated from the latest list
+	InvalidateLookupCache(LookupCacheSpec, connConfigName)
 	specsInConnection, err := LookupSpecList(connConfigName)
 	if err != nil {
 		log.Error().Err(err).Msgf("Cannot LookupSpecList in %s", connConfigName)
-		return specCount, err
+		return result, err
 	}
 
+	specFetchLock.Lock()
+	defer specFetchLock.Unlock()
+
+	storedSpecs := []model.TbSpecInfo{}
+	err = model.ORM.Where("Namespace = ? AND ConnectionName = ?", nsId, connConfigName).Find(&storedSpecs)
+	if err != nil {
+		log.Error().Err(err).Msgf("Cannot get the stored specs of %s", connConfigName)
+		return result, err
+	}
+	storedSpecMap := make(map[string]model.TbSpecInfo, len(storedSpecs))
+	for _, spec := range storedSpecs {
+		storedSpecMap[spec.Id] = spec
+	}
+
+	fetchedIds := map[string]bool{}
 	for _, spec := range specsInConnection.Vmspec {
 		spiderSpec := spec
 		//log.Info().Msgf("Found spec in the map: %s", spiderSpec.Name)
 		tumblebugSpec, errConvert := ConvertSpiderSpecToTumblebugSpec(spiderSpec)
 		if errConvert != nil {
 			log.Error().Err(errConvert).Msg("Cannot ConvertSpiderSpecToTumblebugSpec")
-		} else {
-			key := GetProviderRegionZoneResourceKey(connConfig.ProviderName, connConfig.RegionDetail.RegionName, "", spec.Name)
-			tumblebugSpec.Name = key
-			tumblebugSpec.ConnectionName = connConfig.ConfigName
-			tumblebugSpec.ProviderName = strings.ToLower(connConfig.ProviderName)
-			tumblebugSpec.RegionName = connConfig.RegionDetail.RegionName
-			tumblebugSpec.InfraType = "vm" // default value
-			tumblebugSpec.SystemLabel = "auto-gen"
-			tumblebugSpec.CostPerHour = 99999999.9
-			tumblebugSpec.EvaluationScore01 = -99.9
-
+			continue
+		}
+		key := GetProviderRegionZoneResourceKey(connConfig.ProviderName, connConfig.RegionDetail.RegionName, "", spec.Name)
+		tumblebugSpec.Name = key
+		tumblebugSpec.ConnectionName = connConfig.ConfigName
+		tumblebugSpec.ProviderName = strings.ToLower(connConfig.ProviderName)
+		tumblebugSpec.RegionName = connConfig.RegionDetail.RegionName
+		tumblebugSpec.InfraType = "vm" // default value
+		tumblebugSpec.SystemLabel = "auto-gen"
+		tumblebugSpec.CostPerHour = 99999999.9
+		tumblebugSpec.EvaluationScore01 = -99.9
+		fetchedIds[key] = true
+
+		stored, exists := storedSpecMap[key]
+		if !exists {
 			_, err := RegisterSpecWithInfo(nsId, &tumblebugSpec, true)
 			if err != nil {
 				log.Error().Err(err).Msg("")
-				return 0, err
+				return result, err
 			}
-			specCount++
+			result.Added++
+			continue
+		}
+		if !isSpecChanged(stored, tumblebugSpec) {
+			result.Unchanged++
+			continue
+		}
+		tumblebugSpec.Unavailable = false
+		cols := specFetchCols
+		if tumblebugSpec.SpotCostPerHour != 0 {
+			// keep the spot price enriched by other sources if CB-Spider does not give it
+			cols = append(append([]string{}, cols...), "SpotCostPerHour")
+		}
+		_, err := model.ORM.Cols(cols...).Update(&tumblebugSpec, &model.TbSpecInfo{Namespace: nsId, Id: key})
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot update spec (%s)", key)
+			return result, err
+		}
+		result.Updated++
+	}
+
+	for id, stored := range storedSpecMap {
+		if fetchedIds[id] || stored.Unavailable {
+			continue
 		}
+		_, err := model.ORM.Cols("Unavailable").Update(&model.TbSpecInfo{Unavailable: true}, &model.TbSpecInfo{Namespace: nsId, Id: id})
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot mark spec (%s) as unavailable", id)
+			return result, err
+		}
+		result.Removed++
+	}
 
+	log.Info().Msgf("Fetched specs of %s: added %d, updated %d, unchanged %d, removed %d",
+		connConfigName, result.Added, result.Updated, result.Unchanged, result.Removed)
+	return result, nil
+}
+
+// fetchSpecsConcurrency returns the max number of connections processed in parallel (TB_FETCH_CONCURRENCY, default 10)
+func fetchSpecsConcurrency() int {
+	n, err := strconv.Atoi(common.NVL(os.Getenv("TB_FETCH_CONCURRENCY"), "10"))
+	if err != nil || n <= 0 {
+		n = 10
 	}
-	return specCount, nil
+	return n
 }
 
-// FetchSpecsForAllConnConfigs gets all conn configs from Spider, lookups all specs for each region of conn config, and saves into TB spec objects
-func FetchSpecsForAllConnConfigs(nsId string) (connConfigCount uint, specCount uint, err error) {
+// FetchSpecsForAllConnConfigs gets all conn configs from Spider, lookups all specs for each region of conn config in parallel,
+// and saves the difference into TB spec objects. providerName (optional) limits the connections to the provider.
+func FetchSpecsForAllConnConfigs(nsId string, providerName string) (model.SpecFetchSummary, error) {
+	summary := model.SpecFetchSummary{Results: []model.SpecFetchResult{}}
 
-	err = common.CheckString(nsId)
+	err := common.CheckString(nsId)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return 0, 0, err
+		return summary, err
 	}
 
 	connConfigs, err := common.GetConnConfigList(model.DefaultCredentialHolder, true, true)
 	if err != nil {
 		log.Error().Err(err).Msg("")
-		return 0, 0, err
+		return summary, err
 	}
 
+	targets := []model.ConnConfig{}
 	for _, connConfig := range connConfigs.Connectionconfig {
-		temp, _ := FetchSpecsForConnConfig(connConfig.ConfigName, nsId)
-		specCount += temp
-		connConfigCount++
+		if providerName != "" && !strings.EqualFold(connConfig.ProviderName, providerName) {
+			continue
+		}
+		targets = append(targets, connConfig)
+	}
+
+	results := make([]model.SpecFetchResult, len(targets))
+	semaphore := make(chan struct{}, fetchSpecsConcurrency())
+	var wg sync.WaitGroup
+	for i, connConfig := range targets {
+		wg.Add(1)
+		go func(i int, connConfigName string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			result, err := FetchSpecsForConnConfig(connConfigName, nsId)
+			if err != nil {
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}(i, connConfig.ConfigName)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		summary.ConnConfigCount++
+		summary.Added += result.Added
+		summary.Updated += result.Updated
+		summary.Unchanged += result.Unchanged
+		summary.Removed += result.Removed
+		summary.Results = append(summary.Results, result)
 	}
-	return connConfigCount, specCount, nil
+	return summary, nil
 }
 
 // RegisterSpecWithCspResourceId accepts spec creation request, creates and returns an TB spec object
@@ -408,13 +619,90 @@ func GetSpec(nsId string, specKey string) (model.TbSpecInfo, error) {
 
 // FilterSpecsByRange accepts criteria ranges for filtering, and returns the list of filtered TB spec objects
 func FilterSpecsByRange(nsId string, filter model.FilterSpecsByRangeRequest) ([]model.TbSpecInfo, error) {
+	specs, _, err := FilterSpecsByRangeWithTotal(nsId, filter)
+	return specs, err
+}
+
+// specSortKeys are the fields of TbSpecInfo allowed for SortBy of FilterSpecsByRangeRequest
+var specSortKeys = map[string]func(spec *model.TbSpecInfo) float64{
+	"vCPU":                func(spec *model.TbSpecInfo) float64 { return float64(spec.VCPU) },
+	"memoryGiB":           func(spec *model.TbSpecInfo) float64 { return float64(spec.MemoryGiB) },
+	"storageGiB":          func(spec *model.TbSpecInfo) float64 { return float64(spec.StorageGiB) },
+	"maxTotalStorageTiB":  func(spec *model.TbSpecInfo) float64 { return float64(spec.MaxTotalStorageTiB) },
+	"netBwGbps":           func(spec *model.TbSpecInfo) float64 { return float64(spec.NetBwGbps) },
+	"acceleratorCount":    func(spec *model.TbSpecInfo) float64 { return float64(spec.AcceleratorCount) },
+	"acceleratorMemoryGB": func(spec *model.TbSpecInfo) float64 { return float64(spec.AcceleratorMemoryGB) },
+	"costPerHour":         func(spec *model.TbSpecInfo) float64 { return float64(spec.CostPerHour) },
+	"spotCostPerHour":     func(spec *model.TbSpecInfo) float64 { return float64(spec.SpotCostPerHour) },
+	"evaluationScore01":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore01) },
+	"evaluationScore02":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore02) },
+	"evaluationScore03":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore03) },
+	"evaluationScore04":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore04) },
+	"evaluationScore05":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore05) },
+	"evaluationScore06":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore06) },
+	"evaluationScore07":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore07) },
+	"evaluationScore08":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore08) },
+	"evaluationScore09":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore09) },
+	"evaluationScore10":   func(spec *model.TbSpecInfo) float64 { return float64(spec.EvaluationScore10) },
+}
+
+// specCostUnknown returns true if the cost is not known (0 or the placeholder of fetched specs without price)
+func specCostUnknown(cost float64) bool {
+	return cost <= 0 || cost >= 99999999
+}
+
+// sortSpecs sorts the specs by the sort key in the order (asc or desc).
+// Specs with unknown cost are placed last regardless of the order when sorting by cost.
+func sortSpecs(specs []model.TbSpecInfo, sortBy string, order string) error {
+	getValue, ok := specSortKeys[sortBy]
+	if !ok {
+		keys := make([]string, 0, len(specSortKeys))
+		for key := range specSortKeys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return common.NewValidationFailedError("invalid sortBy %s (allowed: %s)", sortBy, strings.Join(keys, ", "))
+	}
+	order = strings.ToLower(order)
+	if order != "" && order != "asc" && order != "desc" {
+		return common.NewValidationFailedError("invalid order %s (allowed: asc, desc)", order)
+	}
+	isCost := sortBy == "costPerHour" || sortBy == "spotCostPerHour"
+
+	sort.SliceStable(specs, func(i, j int) bool {
+		vi, vj := getValue(&specs[i]), getValue(&specs[j])
+		if isCost {
+			unknownI, unknownJ := specCostUnknown(vi), specCostUnknown(vj)
+			if unknownI != unknownJ {
+				return unknownJ
+			}
+			if unknownI {
+				return false
+			}
+		}
+		if order == "desc" {
+			return vi > vj
+		}
+		return vi < vj
+	})
+	return nil
+}
+
+// FilterSpecsByRangeWithTotal filters the specs by the request, sorts them (SortBy, Order) and returns
+// the page of the result (Limit, Offset) with the total number of matched specs
+func FilterSpecsByRangeWithTotal(nsId string, filter model.FilterSpecsByRangeRequest) ([]model.TbSpecInfo, int, error) {
 	if err := common.CheckString(nsId); err != nil {
 		log.Error().Err(err).Msg("Invalid namespace ID")
-		return nil, err
+		return nil, 0, err
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, 0, common.NewValidationFailedError("limit and offset must not be negative")
 	}
 
 	// Start building the query using field names as database column names
 	session := model.ORM.Where("Namespace = ?", nsId)
+	// Exclude specs which are no longer provided by the CSP
+	session = session.And("(Unavailable IS NULL OR Unavailable = ?)", false)
 
 	// Use reflection to iterate over filter struct
 	val := reflect.ValueOf(filter)
@@ -424,6 +712,11 @@ func FilterSpecsByRange(nsId string, filter model.FilterSpecsByRangeRequest) ([]
 		field := typ.Field(i)
 		value := val.Field(i)
 
+		// skip the options which are not filters (e.g., sortBy, limit)
+		if field.Tag.Get("filter") == "-" {
+			continue
+		}
+
 		// Convert the first letter of the field name to lowercase to match typical database column naming conventions
 		dbFieldName := strings.ToLower(field.Name[:1]) + field.Name[1:]
 		//log.Debug().Msgf("Field: %s, Value: %v", dbFieldName, value)
@@ -455,7 +748,7 @@ func FilterSpecsByRange(nsId string, filter model.FilterSpecsByRangeRequest) ([]
 	err := session.Find(&specs)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to execute query")
-		return nil, err
+		return nil, 0, err
 	}
 
 	elapsedTime := time.Since(startTime)
@@ -463,7 +756,21 @@ func FilterSpecsByRange(nsId string, filter model.FilterSpecsByRangeRequest) ([]
 		Dur("elapsedTime", elapsedTime).
 		Msg("ORM:session.Find(&specs)")
 
-	return specs, nil
+	if filter.SortBy != "" {
+		if err := sortSpecs(specs, filter.SortBy, filter.Order); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	total := len(specs)
+	if filter.Offset >= total {
+		return []model.TbSpecInfo{}, total, nil
+	}
+	specs = specs[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(specs) {
+		specs = specs[:filter.Limit]
+	}
+	return specs, total, nil
 }
 
 // // SortSpecs accepts the list of TB spec objects, criteria and sorting direction,
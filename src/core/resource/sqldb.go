@@ -0,0 +1,345 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/common/label"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+
+	validator "github.com/go-playground/validator/v10"
+)
+
+const (
+	// sqlDbDefaultAdminUsername is the admin user of sqlDb if not given
+	sqlDbDefaultAdminUsername = "tbadmin"
+	// sqlDbPasswordLength is the length of the generated admin password
+	sqlDbPasswordLength = 20
+	// sqlDbPollInterval and sqlDbPollTimeout are for polling the status of a sqlDb being created
+	sqlDbPollInterval = 20 * time.Second
+	sqlDbPollTimeout  = 40 * time.Minute
+)
+
+// TbSqlDbReqStructLevelValidation func is for Validation
+func TbSqlDbReqStructLevelValidation(sl validator.StructLevel) {
+
+	u := sl.Current().Interface().(model.TbSqlDbReq)
+
+	err := common.CheckString(u.Name)
+	if err != nil {
+		// ReportError(field interface{}, fieldName, structFieldName, tag, param string)
+		sl.ReportError(u.Name, "name", "Name", err.Error(), "")
+	}
+	if u.Engine != model.SqlDbEngineMysql && u.Engine != model.SqlDbEnginePostgresql {
+		sl.ReportError(u.Engine, "engine", "Engine", "oneof", "mysql postgresql")
+	}
+}
+
+// decryptSqlDbInfo decrypts the admin password of the sqlDb object read from the Key-Value store
+func decryptSqlDbInfo(content *model.TbSqlDbInfo) error {
+	decrypted, err := common.DecryptSecret(content.AdminPassword)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to decrypt the admin password of the sqlDb %s", content.Id)
+		return err
+	}
+	content.AdminPassword = decrypted
+	return nil
+}
+
+// MaskSqlDbInfo replaces the admin password of the sqlDb object with the masked placeholder
+func MaskSqlDbInfo(content *model.TbSqlDbInfo) {
+	if content.AdminPassword != "" {
+		content.AdminPassword = common.SecretMask
+	}
+}
+
+// storeSqlDb stores the sqlDb object (with the encrypted admin password) and its labels
+func storeSqlDb(nsId string, content model.TbSqlDbInfo) error {
+	encrypted, err := common.EncryptSecret(content.AdminPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	content.AdminPassword = encrypted
+
+	Key := common.GenResourceKey(nsId, model.StrSqlDb, content.Id)
+	Val, _ := json.Marshal(content)
+	err = kvstore.Put(Key, string(Val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	labels := map[string]string{
+		model.LabelManager:         model.StrManager,
+		model.LabelNamespace:       nsId,
+		model.LabelLabelType:       model.StrSqlDb,
+		model.LabelId:              content.Id,
+		model.LabelName:            content.Name,
+		model.LabelUid:             content.Uid,
+		model.LabelVNetId:          content.VNetId,
+		model.LabelStatus:          content.Status,
+		model.LabelCspResourceId:   content.CspResourceId,
+		model.LabelCspResourceName: content.CspResourceName,
+		model.LabelDescription:     content.Description,
+		model.LabelCreatedTime:     content.CreatedTime.String(),
+		model.LabelConnectionName:  content.ConnectionName,
+	}
+	common.SetSystemLabels(labels, content.ConnectionName)
+	err = label.CreateOrUpdateLabel(model.StrSqlDb, content.Uid, Key, labels)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+	return nil
+}
+
+// getSpiderSqlDb returns the managed DB from CB-Spider
+func getSpiderSqlDb(connectionName string, cspResourceName string) (model.SpiderSqlDbInfo, error) {
+	requestBody := model.SpiderConnectionName{ConnectionName: connectionName}
+	callResult := model.SpiderSqlDbInfo{}
+	err := common.ExecuteHttpRequest(
+		resty.New(),
+		"GET",
+		fmt.Sprintf("%s/sqldb/%s", model.SpiderRestUrl, cspResourceName),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.VeryShortDuration,
+	)
+	return callResult, err
+}
+
+// CreateSqlDb accepts sqlDb (managed MySQL/PostgreSQL) creation request, creates and returns an TB sqlDb object.
+// The admin password is generated and returned only in this response (it is stored encrypted).
+// The status is polled in background until the DB is Available.
+func CreateSqlDb(nsId string, u *model.TbSqlDbReq) (model.TbSqlDbInfo, error) {
+
+	resourceType := model.StrSqlDb
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbSqlDbInfo{}, err
+	}
+
+	err = validate.Struct(u)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			log.Err(err).Msg("")
+			return model.TbSqlDbInfo{}, err
+		}
+		return model.TbSqlDbInfo{}, err
+	}
+
+	connConfig, err := common.GetConnConfig(u.ConnectionName)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbSqlDbInfo{}, err
+	}
+	err = common.CheckCapability(connConfig.ProviderName, common.CapabilitySqlDb, "sqlDb")
+	if err != nil {
+		return model.TbSqlDbInfo{}, err
+	}
+
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, u.ConnectionName)
+	if err != nil {
+		return model.TbSqlDbInfo{}, err
+	}
+
+	check, err := CheckResource(nsId, resourceType, u.Name)
+	if check {
+		err := fmt.Errorf("The sqlDb %s already exists.", u.Name)
+		return model.TbSqlDbInfo{}, err
+	}
+	if err != nil {
+		err := fmt.Errorf("Failed to check the existence of the sqlDb %s.", u.Name)
+		return model.TbSqlDbInfo{}, err
+	}
+
+	// validate the placement (the subnet must belong to the vNet in the same connection)
+	vNetInterface, err := GetResource(nsId, model.StrVNet, u.VNetId)
+	if err != nil {
+		return model.TbSqlDbInfo{}, err
+	}
+	vNet := vNetInterface.(model.TbVNetInfo)
+	if vNet.ConnectionName != u.ConnectionName {
+		err := fmt.Errorf("the vNet %s is in the connection %s, not %s", u.VNetId, vNet.ConnectionName, u.ConnectionName)
+		return model.TbSqlDbInfo{}, err
+	}
+	cspSubnetName := ""
+	for _, subnet := range vNet.SubnetInfoList {
+		if subnet.Id == u.SubnetId {
+			cspSubnetName = subnet.CspResourceName
+			break
+		}
+	}
+	if cspSubnetName == "" {
+		err := fmt.Errorf("the subnet %s does not belong to the vNet %s", u.SubnetId, u.VNetId)
+		return model.TbSqlDbInfo{}, err
+	}
+
+	adminUsername := u.AdminUsername
+	if adminUsername == "" {
+		adminUsername = sqlDbDefaultAdminUsername
+	}
+	adminPassword := common.GenRandomPassword(sqlDbPasswordLength)
+	uid := common.GenUid()
+
+	requestBody := model.SpiderSqlDbReqInfoWrapper{
+		ConnectionName: u.ConnectionName,
+		ReqInfo: model.SpiderSqlDbInfo{
+			Name:          uid,
+			VPCName:       vNet.CspResourceName,
+			SubnetName:    cspSubnetName,
+			AdminUsername: adminUsername,
+			AdminPassword: adminPassword,
+			Engine:        u.Engine,
+			EngineVersion: u.EngineVersion,
+			InstanceType:  u.InstanceType,
+			StorageSize:   u.StorageSize,
+		},
+	}
+
+	callResult := model.SpiderSqlDbInfo{}
+	err = common.ExecuteHttpRequest(
+		resty.New(),
+		"POST",
+		fmt.Sprintf("%s/sqldb", model.SpiderRestUrl),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TbSqlDbInfo{}, err
+	}
+
+	content := model.TbSqlDbInfo{
+		ResourceType:         resourceType,
+		Id:                   u.Name,
+		Name:                 u.Name,
+		Uid:                  uid,
+		ConnectionName:       u.ConnectionName,
+		CspResourceId:        callResult.IId.SystemId,
+		CspResourceName:      callResult.IId.NameId,
+		Engine:               u.Engine,
+		EngineVersion:        callResult.EngineVersion,
+		InstanceType:         u.InstanceType,
+		StorageSize:          u.StorageSize,
+		VNetId:               u.VNetId,
+		SubnetId:             u.SubnetId,
+		Status:               model.SqlDbCreating,
+		Endpoint:             callResult.Endpoint,
+		Port:                 callResult.Port,
+		AdminUsername:        adminUsername,
+		AdminPassword:        adminPassword,
+		AssociatedObjectList: []string{},
+		CreatedTime:          callResult.CreatedTime,
+		KeyValueList:         callResult.KeyValueList,
+		Description:          u.Description,
+	}
+	if content.CspResourceName == "" {
+		content.CspResourceName = uid
+	}
+	if content.EngineVersion == "" {
+		content.EngineVersion = u.EngineVersion
+	}
+	if strings.EqualFold(callResult.Status, model.SqlDbAvailable) {
+		content.Status = model.SqlDbAvailable
+	}
+
+	log.Info().Msg("PUT CreateSqlDb")
+	err = storeSqlDb(nsId, content)
+	if err != nil {
+		return content, err
+	}
+
+	if content.Status != model.SqlDbAvailable {
+		go waitSqlDbAvailable(nsId, content.Id)
+	}
+	return content, nil
+}
+
+// waitSqlDbAvailable polls the status of the sqlDb until it is Available (or Error, deleted, timeout)
+// and updates the endpoint of the sqlDb object
+func waitSqlDbAvailable(nsId string, sqlDbId string) {
+	deadline := time.Now().Add(sqlDbPollTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(sqlDbPollInterval)
+
+		resourceInterface, err := GetResource(nsId, model.StrSqlDb, sqlDbId)
+		if err != nil {
+			// deleted while creating
+			return
+		}
+		content := resourceInterface.(model.TbSqlDbInfo)
+		if content.Status != model.SqlDbCreating {
+			return
+		}
+
+		spiderSqlDb, err := getSpiderSqlDb(content.ConnectionName, content.CspResourceName)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to get the status of the sqlDb %s", sqlDbId)
+			continue
+		}
+		status := spiderSqlDb.Status
+		switch {
+		case strings.EqualFold(status, model.SqlDbAvailable):
+			content.Status = model.SqlDbAvailable
+		case strings.EqualFold(status, model.SqlDbError) || strings.EqualFold(status, "Failed"):
+			content.Status = model.SqlDbError
+			content.SystemMessage = fmt.Sprintf("the status of the DB in the CSP is %s", status)
+		default:
+			continue
+		}
+		if spiderSqlDb.Endpoint != "" {
+			content.Endpoint = spiderSqlDb.Endpoint
+		}
+		if spiderSqlDb.Port != "" {
+			content.Port = spiderSqlDb.Port
+		}
+		if err := storeSqlDb(nsId, content); err != nil {
+			log.Error().Err(err).Msgf("Failed to update the sqlDb %s", sqlDbId)
+		}
+		log.Info().Msgf("The sqlDb %s is %s (endpoint: %s:%s)", sqlDbId, content.Status, content.Endpoint, content.Port)
+		return
+	}
+
+	log.Warn().Msgf("Timeout while waiting for the sqlDb %s to be available", sqlDbId)
+	resourceInterface, err := GetResource(nsId, model.StrSqlDb, sqlDbId)
+	if err != nil {
+		return
+	}
+	content := resourceInterface.(model.TbSqlDbInfo)
+	if content.Status == model.SqlDbCreating {
+		content.Status = model.SqlDbError
+		content.SystemMessage = fmt.Sprintf("the DB is not available in %s", sqlDbPollTimeout)
+		storeSqlDb(nsId, content)
+	}
+}
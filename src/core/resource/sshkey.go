@@ -15,8 +15,10 @@ limitations under the License.
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
 	"github.com/cloud-barista/cb-tumblebug/src/core/common/label"
@@ -25,6 +27,7 @@ import (
 	validator "github.com/go-playground/validator/v10"
 	"github.com/go-resty/resty/v2"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
 )
 
 // TbSshKeyReqStructLevelValidation is a function to validate 'TbSshKeyReq' object.
@@ -40,7 +43,7 @@ func TbSshKeyReqStructLevelValidation(sl validator.StructLevel) {
 }
 
 // CreateSshKey accepts SSH key creation request, creates and returns an TB sshKey object
-func CreateSshKey(nsId string, u *model.TbSshKeyReq, option string) (model.TbSshKeyInfo, error) {
+func CreateSshKey(ctx context.Context, nsId string, u *model.TbSshKeyReq, option string) (model.TbSshKeyInfo, error) {
 
 	emptyObj := model.TbSshKeyInfo{}
 
@@ -51,6 +54,14 @@ func CreateSshKey(nsId string, u *model.TbSshKeyReq, option string) (model.TbSsh
 		log.Error().Err(err).Msg("")
 		return emptyObj, err
 	}
+
+	// Check the naming policy of the namespace
+	if option != "register" {
+		err = common.CheckNsNamingPolicy(ctx, nsId, resourceType, u.Name)
+		if err != nil {
+			return emptyObj, err
+		}
+	}
 	uid := common.GenUid()
 
 	if option == "register" { // fields validation
@@ -79,6 +90,12 @@ func CreateSshKey(nsId string, u *model.TbSshKeyReq, option string) (model.TbSsh
 		return emptyObj, err
 	}
 
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, u.ConnectionName)
+	if err != nil {
+		return emptyObj, err
+	}
+
 	check, err := CheckResource(nsId, resourceType, u.Name)
 
 	if check {
@@ -167,11 +184,145 @@ func CreateSshKey(nsId string, u *model.TbSshKeyReq, option string) (model.TbSsh
 		content.PublicKey = u.PublicKey
 		content.PrivateKey = u.PrivateKey
 	}
+	content.Provenance = common.NewProvenance(ctx, provenanceVia(option))
 
 	log.Info().Msg("PUT CreateSshKey")
 	Key := common.GenResourceKey(nsId, resourceType, content.Id)
-	Val, _ := json.Marshal(content)
-	err = kvstore.Put(Key, string(Val))
+	err = putSshKeyObject(Key, content)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return content, err
+	}
+
+	// Store label info using CreateOrUpdateLabel
+	labels := map[string]string{
+		model.LabelManager:         model.StrManager,
+		model.LabelNamespace:       nsId,
+		model.LabelLabelType:       model.StrSSHKey,
+		model.LabelId:              content.Id,
+		model.LabelName:            content.Name,
+		model.LabelUid:             content.Uid,
+		model.LabelCspResourceId:   content.CspResourceId,
+		model.LabelCspResourceName: content.CspResourceName,
+		model.LabelDescription:     content.Description,
+		model.LabelConnectionName:  content.ConnectionName,
+	}
+	common.SetSystemLabels(labels, content.ConnectionName)
+	common.SetProvenanceLabels(labels, content.Provenance)
+	err = label.CreateOrUpdateLabel(model.StrSSHKey, uid, Key, labels)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return content, err
+	}
+
+	return content, nil
+}
+
+// RegisterSshKey registers a key pair which already exists in the CSP, and returns the TB sshKey object.
+// The private key material is optional. Without it, the sshKey is marked publicOnly,
+// and command execution is not available for VMs using the sshKey.
+func RegisterSshKey(ctx context.Context, nsId string, req *model.TbRegisterSshKeyReq) (model.TbSshKeyInfo, error) {
+	log.Info().Msg("RegisterSshKey")
+
+	emptyObj := model.TbSshKeyInfo{}
+	resourceType := model.StrSSHKey
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyObj, err
+	}
+	err = validate.Struct(req)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			log.Error().Err(err).Msg("")
+			return emptyObj, err
+		}
+		return emptyObj, err
+	}
+	err = common.CheckString(req.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyObj, err
+	}
+
+	// Reject the private key material which cannot be used for SSH
+	if req.PrivateKey != "" {
+		if _, err := ssh.ParsePrivateKey([]byte(req.PrivateKey)); err != nil {
+			err := common.NewValidationFailedError("invalid privateKey for the sshKey (%s): %v", req.Name, err)
+			log.Error().Err(err).Msg("")
+			return emptyObj, err
+		}
+	}
+
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, req.ConnectionName)
+	if err != nil {
+		return emptyObj, err
+	}
+
+	exists, err := CheckResource(nsId, resourceType, req.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyObj, err
+	}
+	if exists {
+		err := common.NewConflictError("the sshKey (%s) already exists", req.Name)
+		log.Error().Err(err).Msg("")
+		return emptyObj, err
+	}
+
+	uid := common.GenUid()
+
+	// [Via Spider] Register the key pair
+	spReqt := model.SpiderKeyPairReqInfoWrapper{}
+	spReqt.ConnectionName = req.ConnectionName
+	spReqt.ReqInfo.Name = uid
+	spReqt.ReqInfo.CSPId = req.CspResourceId
+
+	url := fmt.Sprintf("%s/regkeypair", model.SpiderRestUrl)
+
+	var spResp model.SpiderKeyPairInfo
+
+	client := resty.New()
+	method := "POST"
+
+	err = common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(spReqt),
+		&spReqt,
+		&spResp,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyObj, err
+	}
+
+	content := model.TbSshKeyInfo{}
+	content.ResourceType = resourceType
+	content.Id = req.Name
+	content.Name = req.Name
+	content.ConnectionName = req.ConnectionName
+	content.Uid = uid
+	content.CspResourceId = spResp.IId.SystemId
+	content.CspResourceName = spResp.IId.NameId
+	content.Fingerprint = spResp.Fingerprint
+	content.Username = common.NVL(req.Username, spResp.VMUserID)
+	content.PublicKey = spResp.PublicKey
+	content.PrivateKey = req.PrivateKey
+	content.PublicOnly = req.PrivateKey == ""
+	content.Description = req.Description
+	content.KeyValueList = spResp.KeyValueList
+	content.AssociatedObjectList = []string{}
+	content.SystemLabel = "Registered from CSP resource"
+	content.Provenance = common.NewProvenance(ctx, model.CreatedViaRegistered)
+
+	Key := common.GenResourceKey(nsId, resourceType, content.Id)
+	err = putSshKeyObject(Key, content)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return content, err
@@ -190,6 +341,8 @@ func CreateSshKey(nsId string, u *model.TbSshKeyReq, option string) (model.TbSsh
 		model.LabelDescription:     content.Description,
 		model.LabelConnectionName:  content.ConnectionName,
 	}
+	common.SetSystemLabels(labels, content.ConnectionName)
+	common.SetProvenanceLabels(labels, content.Provenance)
 	err = label.CreateOrUpdateLabel(model.StrSSHKey, uid, Key, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -199,6 +352,103 @@ func CreateSshKey(nsId string, u *model.TbSshKeyReq, option string) (model.TbSsh
 	return content, nil
 }
 
+// DeregisterSshKey deregisters a sshKey from CB-Tumblebug and CB-Spider.
+// The key pair in the CSP is not deleted.
+func DeregisterSshKey(nsId string, sshKeyId string) (model.SimpleMsg, error) {
+	log.Info().Msg("DeregisterSshKey")
+
+	var emptyRet model.SimpleMsg
+	var ret model.SimpleMsg
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	err = common.CheckString(sshKeyId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	sshKeyKey := common.GenResourceKey(nsId, model.StrSSHKey, sshKeyId)
+	keyValue, err := kvstore.GetKv(sshKeyKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	if keyValue == (kvstore.KeyValue{}) {
+		err := common.NewResourceNotFoundError(model.StrSSHKey, sshKeyId)
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	sshKeyInfo := model.TbSshKeyInfo{}
+	err = json.Unmarshal([]byte(keyValue.Value), &sshKeyInfo)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	if len(sshKeyInfo.AssociatedObjectList) > 0 {
+		err := common.NewResourceInUseError(model.StrSSHKey, sshKeyId, sshKeyInfo.AssociatedObjectList)
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	// [Via Spider] Deregister the key pair
+	spReqt := spiderConnectionRequest{}
+	spReqt.ConnectionName = sshKeyInfo.ConnectionName
+
+	url := fmt.Sprintf("%s/regkeypair/%s", model.SpiderRestUrl, sshKeyInfo.CspResourceName)
+
+	var spResp spiderBooleanInfoResp
+
+	client := resty.New()
+	method := "DELETE"
+
+	err = common.ExecuteHttpRequest(
+		client,
+		method,
+		url,
+		nil,
+		common.SetUseBody(spReqt),
+		&spReqt,
+		&spResp,
+		common.MediumDuration,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	ok, err := strconv.ParseBool(spResp.Result)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	if !ok {
+		err := fmt.Errorf("failed to deregister the sshKey (%s)", sshKeyId)
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	err = kvstore.Delete(sshKeyKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	err = label.RemoveLabel(model.StrSSHKey, sshKeyInfo.Uid, sshKeyKey)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	ret.Message = fmt.Sprintf("the sshKey (%s) has been deregistered", sshKeyId)
+
+	return ret, nil
+}
+
 // UpdateSshKey accepts to-be TB sshKey objects,
 // updates and returns the updated TB sshKey objects
 func UpdateSshKey(nsId string, sshKeyId string, fieldsToUpdate model.TbSshKeyInfo) (model.TbSshKeyInfo, error) {
@@ -243,6 +493,11 @@ func UpdateSshKey(nsId string, sshKeyId string, fieldsToUpdate model.TbSshKeyInf
 		return emptyObj, err
 	}
 
+	// The masked placeholder (returned by GET) does not replace the private key
+	if fieldsToUpdate.PrivateKey == common.SecretMask {
+		fieldsToUpdate.PrivateKey = ""
+	}
+
 	// Update specified fields only
 	toBeSshKey := asIsSshKey
 	toBeSshKeyJSON, _ := json.Marshal(fieldsToUpdate)
@@ -250,8 +505,7 @@ func UpdateSshKey(nsId string, sshKeyId string, fieldsToUpdate model.TbSshKeyInf
 
 	log.Info().Msg("PUT UpdateSshKey")
 	Key := common.GenResourceKey(nsId, resourceType, toBeSshKey.Id)
-	Val, _ := json.Marshal(toBeSshKey)
-	err = kvstore.Put(Key, string(Val))
+	err = putSshKeyObject(Key, toBeSshKey)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return emptyObj, err
@@ -265,7 +519,77 @@ func UpdateSshKey(nsId string, sshKeyId string, fieldsToUpdate model.TbSshKeyInf
 		// return nil, err
 	}
 
-	fmt.Printf("<%s> \n %s \n", keyValue.Key, keyValue.Value)
+	log.Debug().Msgf("Updated the sshKey %s", keyValue.Key)
 
 	return toBeSshKey, nil
 }
+
+// putSshKeyObject stores the sshKey object with the private key encrypted (if TB_ENCRYPTION_KEY is set)
+func putSshKeyObject(key string, content model.TbSshKeyInfo) error {
+	encrypted, err := common.EncryptSecret(content.PrivateKey)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encrypt the private key")
+		return err
+	}
+	content.PrivateKey = encrypted
+	val, _ := json.Marshal(content)
+	return kvstore.Put(key, string(val))
+}
+
+// decryptSshKeyInfo decrypts the private key of the sshKey object read from the Key-Value store
+func decryptSshKeyInfo(content *model.TbSshKeyInfo) error {
+	decrypted, err := common.DecryptSecret(content.PrivateKey)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to decrypt the private key of the sshKey %s", content.Id)
+		return err
+	}
+	content.PrivateKey = decrypted
+	return nil
+}
+
+// MaskSshKeyInfo replaces the private key of the sshKey object with the masked placeholder
+func MaskSshKeyInfo(content *model.TbSshKeyInfo) {
+	if content.PrivateKey != "" {
+		content.PrivateKey = common.SecretMask
+	}
+}
+
+// EncryptPlaintextSshKeys encrypts the private keys stored in plaintext in all namespaces (migration for TB_ENCRYPTION_KEY).
+// It returns the ids (ns/sshKeyId) of the encrypted sshKeys.
+func EncryptPlaintextSshKeys() ([]string, error) {
+	if !common.EncryptionEnabled() {
+		return nil, fmt.Errorf("TB_ENCRYPTION_KEY is not set; private keys cannot be encrypted")
+	}
+
+	nsIdList, err := common.ListNsId()
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, err
+	}
+
+	encrypted := []string{}
+	for _, nsId := range nsIdList {
+		prefix := "/ns/" + nsId + "/resources/" + model.StrSSHKey + "/"
+		keyValue, err := kvstore.GetKvList(prefix)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return encrypted, err
+		}
+		for _, kv := range keyValue {
+			content := model.TbSshKeyInfo{}
+			if err := json.Unmarshal([]byte(kv.Value), &content); err != nil {
+				log.Warn().Err(err).Msgf("Failed to read the sshKey %s", kv.Key)
+				continue
+			}
+			if content.PrivateKey == "" || common.IsEncryptedSecret(content.PrivateKey) {
+				continue
+			}
+			if err := putSshKeyObject(kv.Key, content); err != nil {
+				return encrypted, err
+			}
+			encrypted = append(encrypted, nsId+"/"+content.Id)
+		}
+	}
+	log.Info().Msgf("Encrypted the private keys of %d sshKeys", len(encrypted))
+	return encrypted, nil
+}
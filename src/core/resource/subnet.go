@@ -15,8 +15,10 @@ limitations under the License.
 package resource
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -54,48 +56,13 @@ func ValidateSubnetReq(subnetReq *model.TbSubnetReq, existingVNet model.TbVNetIn
 		return err
 	}
 
-	// Validate zone in each subnet
-	// TODO: Update the validation logic
-	// It's a temporary validation logic due to the connection name pattern
-	// Split the connection name into provider and region/zone
-	parts := strings.SplitN(existingVNet.ConnectionName, "-", 2)
-	provider := parts[0]
-	regionZone := parts[1]
-
-	// Get the region list
-	regionsObj, err := common.GetRegions(provider)
+	// Validate the zone against the region and the assigned zone of the connection
+	err = ValidateSubnetZone(existingVNet.ConnectionName, subnetReq.Zone)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return err
 	}
 
-	// Try to match and get the region detail
-	var regionDetail model.RegionDetail
-	for _, region := range regionsObj.Regions {
-		exists := strings.HasPrefix(regionZone, region.RegionName)
-		if exists {
-			regionDetail = region
-			break
-		}
-	}
-
-	// Check if the region detail exists or not
-	if regionDetail.RegionName == "" && len(regionDetail.Zones) == 0 {
-		err := fmt.Errorf("invalid region/zone: %s", regionZone)
-		log.Error().Err(err).Msg("")
-		return err
-	}
-
-	// Validate the zone
-	zones := regionDetail.Zones
-	if subnetReq.Zone != "" {
-		if !ContainsZone(zones, subnetReq.Zone) {
-			err := fmt.Errorf("invalid zone: %s", subnetReq.Zone)
-			log.Error().Err(err).Msg("")
-			return err
-		}
-	}
-
 	// A network object for validation
 	var network netutil.Network
 	var subnets []netutil.Network
@@ -124,6 +91,33 @@ func ValidateSubnetReq(subnetReq *model.TbSubnetReq, existingVNet model.TbVNetIn
 		return err
 	}
 
+	// Validate the IPv6 CIDR block of a dual-stack subnet
+	if subnetReq.IPv6_CIDR != "" {
+		if existingVNet.IPv6CidrBlock == "" {
+			err := common.NewValidationFailedError("ipv6_CIDR requires a dual-stack vNet (the vNet %s has no IPv6 CIDR block)", existingVNet.Id)
+			log.Error().Err(err).Msg("")
+			return err
+		}
+		err = checkIpv6Capability(existingVNet.ConnectionName, true)
+		if err != nil {
+			log.Error().Err(err).Msg("")
+			return err
+		}
+		ipv6Network := netutil.Network{CidrBlock: existingVNet.IPv6CidrBlock}
+		for _, subnetInfo := range existingVNet.SubnetInfoList {
+			if subnetInfo.IPv6_CIDR != "" {
+				ipv6Network.Subnets = append(ipv6Network.Subnets, netutil.Network{CidrBlock: subnetInfo.IPv6_CIDR})
+			}
+		}
+		ipv6Network.Subnets = append(ipv6Network.Subnets, netutil.Network{CidrBlock: subnetReq.IPv6_CIDR})
+		err = netutil.ValidateIPv6Network(ipv6Network)
+		if err != nil {
+			err = common.NewValidationFailedError("invalid IPv6 CIDR block: %v", err)
+			log.Error().Err(err).Msg("")
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -141,10 +135,12 @@ type spiderAddSubnetRequest struct {
 }
 
 type spiderAddSubnetRequestInfo struct {
-	Name      string           `json:"Name" validate:"required" example:"subnet-01"`
-	Zone      string           `json:"Zone,omitempty" validate:"omitempty" example:"us-east-1b"` // target zone for the subnet, if not specified, it will be created in the same zone as the Connection.
-	IPv4_CIDR string           `json:"IPv4_CIDR" validate:"required" example:"10.0.12.0/22"`
-	TagList   []model.KeyValue `json:"TagList,omitempty" validate:"omitempty"`
+	Name       string           `json:"Name" validate:"required" example:"subnet-01"`
+	Zone       string           `json:"Zone,omitempty" validate:"omitempty" example:"us-east-1b"` // target zone for the subnet, if not specified, it will be created in the same zone as the Connection.
+	IPv4_CIDR  string           `json:"IPv4_CIDR" validate:"required" example:"10.0.12.0/22"`
+	IPv6_CIDR  string           `json:"IPv6_CIDR,omitempty" validate:"omitempty" example:"2600:1f18:1234:5601::/64"`
+	AssignIPv6 bool             `json:"AssignIPv6,omitempty" validate:"omitempty"` // IPv6 range allocated by the CSP (dual-stack subnet)
+	TagList    []model.KeyValue `json:"TagList,omitempty" validate:"omitempty"`
 }
 
 // SubnetRegisterRequest represents the request body for registering a subnet.
@@ -181,6 +177,7 @@ type spiderSubnetInfo struct {
 	IId          model.IID        // {NameId, SystemId}
 	Zone         string           // Zone of the Subnet
 	IPv4_CIDR    string           // CIDR block of the Subnet
+	IPv6_CIDR    string           // IPv6 CIDR block of the Subnet (dual-stack)
 	TagList      []model.KeyValue // List of key-value tags for the Subnet
 	KeyValueList []model.KeyValue // List of key-value pairs indicating CSP-side response
 	// Name         string           // Name of the Subnet
@@ -229,6 +226,14 @@ func CreateSubnet(nsId string, vNetId string, subnetReq *model.TbSubnetReq) (mod
 	subnetInfo.Id = subnetReq.Name
 	subnetInfo.Name = subnetReq.Name
 
+	// Serialize subnet creation in the vNet to keep the CIDR validation against other subnets valid
+	unlock, err := common.LockNs(nsId, model.StrVNet+"/"+vNetId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	defer unlock()
+
 	// Set the resource type
 	parentResourceType := model.StrVNet
 	resourceType := model.StrSubnet
@@ -237,7 +242,7 @@ func CreateSubnet(nsId string, vNetId string, subnetReq *model.TbSubnetReq) (mod
 	exists, err := CheckChildResource(nsId, resourceType, vNetId, subnetInfo.Id)
 	if exists {
 		log.Error().Err(err).Msg("")
-		err := fmt.Errorf("already exists, subnet: %s", subnetInfo.Id)
+		err := common.NewConflictError("The subnet %s already exists.", subnetInfo.Id)
 		return emptyRet, err
 	}
 	if err != nil {
@@ -257,7 +262,7 @@ func CreateSubnet(nsId string, vNetId string, subnetReq *model.TbSubnetReq) (mod
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -304,10 +309,13 @@ func CreateSubnet(nsId string, vNetId string, subnetReq *model.TbSubnetReq) (mod
 	// [Via Spider] Add subnet
 	spReqt := spiderAddSubnetRequest{}
 	spReqt.ConnectionName = vNetInfo.ConnectionName
-	spReqt.IDTransformMode = "OFF"
+	if common.IsSpiderFeatureSupported(model.SpiderFeatureIdTransformMode) {
+		spReqt.IDTransformMode = "OFF"
+	}
 	spReqt.ReqInfo.Name = subnetInfo.Uid
 	spReqt.ReqInfo.Zone = subnetReq.Zone
 	spReqt.ReqInfo.IPv4_CIDR = subnetReq.IPv4_CIDR
+	spReqt.ReqInfo.IPv6_CIDR = subnetReq.IPv6_CIDR
 	// todo: restore the tag list later
 	// spReqt.ReqInfo.TagList = subnetReq.TagList
 
@@ -363,6 +371,7 @@ func CreateSubnet(nsId string, vNetId string, subnetReq *model.TbSubnetReq) (mod
 			subnetInfo.CspResourceId = spSubnetInfo.IId.SystemId
 			subnetInfo.CspResourceName = spSubnetInfo.IId.NameId
 			subnetInfo.IPv4_CIDR = spSubnetInfo.IPv4_CIDR
+			subnetInfo.IPv6_CIDR = common.NVL(spSubnetInfo.IPv6_CIDR, subnetReq.IPv6_CIDR)
 			subnetInfo.Zone = spSubnetInfo.Zone
 			// todo: restore the tag list later
 			// subnetInfo.TagList = spSubnetInfo.TagList
@@ -431,6 +440,7 @@ func CreateSubnet(nsId string, vNetId string, subnetReq *model.TbSubnetReq) (mod
 		model.LabelDescription:     subnetInfo.Description,
 		model.LabelConnectionName:  subnetInfo.ConnectionName,
 	}
+	common.SetSystemLabels(labels, subnetInfo.ConnectionName)
 	err = label.CreateOrUpdateLabel(model.StrSubnet, uid, subnetKey, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -506,7 +516,7 @@ func GetSubnet(nsId string, vNetId string, subnetId string) (model.TbSubnetInfo,
 		return emptyRet, err
 	}
 	if subnetKeyValue == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, subnet: %s", subnetId)
+		err := common.NewResourceNotFoundError(model.StrSubnet, subnetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -550,6 +560,7 @@ func GetSubnet(nsId string, vNetId string, subnetId string) (model.TbSubnetInfo,
 	subnetInfo.CspResourceId = spResp.IId.SystemId
 	subnetInfo.CspResourceName = spResp.IId.NameId
 	subnetInfo.IPv4_CIDR = spResp.IPv4_CIDR
+	subnetInfo.IPv6_CIDR = spResp.IPv6_CIDR
 	subnetInfo.Zone = spResp.Zone
 	subnetInfo.KeyValueList = spResp.KeyValueList
 	// TODO: restore the tag list later
@@ -600,6 +611,92 @@ func ListSubnet(nsId string, vNetId string) ([]model.TbSubnetInfo, error) {
 	return subnetInfoList, nil
 }
 
+// GetOrCreateZoneSubnet returns the subnet in the zone of the vNet.
+// If the vNet has no subnet in the zone, a new subnet ({vNetId}-{zone}) is created in a free address range of the vNet.
+func GetOrCreateZoneSubnet(nsId string, vNetId string, zone string) (string, error) {
+
+	// Serialize the allocation of zone subnets in the vNet (concurrent subGroups may request the same zone)
+	unlock, err := common.LockNs(nsId, "zoneSubnet/"+vNetId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return "", err
+	}
+	defer unlock()
+
+	vNetInfo, err := GetVNet(nsId, vNetId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return "", err
+	}
+	for _, subnetInfo := range vNetInfo.SubnetInfoList {
+		if subnetInfo.Zone == zone {
+			return subnetInfo.Id, nil
+		}
+	}
+
+	cidr, err := allocateSubnetCidr(vNetInfo)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return "", err
+	}
+	subnetReq := model.TbSubnetReq{
+		Name:        vNetId + "-" + strings.ToLower(zone),
+		IPv4_CIDR:   cidr,
+		Zone:        zone,
+		Description: "Subnet for zone " + zone,
+	}
+	subnetInfo, err := CreateSubnet(nsId, vNetId, &subnetReq)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to create the subnet for zone %s", zone)
+		return "", err
+	}
+	log.Info().Msgf("Created subnet %s (%s) for zone %s", subnetInfo.Id, cidr, zone)
+	return subnetInfo.Id, nil
+}
+
+// allocateSubnetCidr returns a free address range (/22, or a quarter of a small vNet) of the vNet
+func allocateSubnetCidr(vNetInfo model.TbVNetInfo) (string, error) {
+	_, vNetNet, err := net.ParseCIDR(vNetInfo.CidrBlock)
+	if err != nil || vNetNet.IP.To4() == nil {
+		return "", fmt.Errorf("cannot allocate a subnet in the vNet %s (invalid CIDR: %s)", vNetInfo.Id, vNetInfo.CidrBlock)
+	}
+	vNetPrefix, _ := vNetNet.Mask.Size()
+	prefix := 22
+	if vNetPrefix+2 > prefix {
+		prefix = vNetPrefix + 2
+	}
+	if prefix > 28 {
+		return "", fmt.Errorf("the vNet %s (%s) is too small to add a subnet", vNetInfo.Id, vNetInfo.CidrBlock)
+	}
+
+	used := []*net.IPNet{}
+	for _, subnetInfo := range vNetInfo.SubnetInfoList {
+		if _, subnetNet, err := net.ParseCIDR(subnetInfo.IPv4_CIDR); err == nil {
+			used = append(used, subnetNet)
+		}
+	}
+
+	base := binary.BigEndian.Uint32(vNetNet.IP.To4())
+	size := uint32(1) << (32 - prefix)
+	total := uint32(1) << (32 - vNetPrefix)
+	for offset := uint32(0); offset < total; offset += size {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, base+offset)
+		candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(prefix, 32)}
+		overlapped := false
+		for _, subnetNet := range used {
+			if subnetNet.Contains(candidate.IP) || candidate.Contains(subnetNet.IP) {
+				overlapped = true
+				break
+			}
+		}
+		if !overlapped {
+			return candidate.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free address range in the vNet %s (%s)", vNetInfo.Id, vNetInfo.CidrBlock)
+}
+
 // DeleteSubnet deletes and returns the result
 func DeleteSubnet(nsId string, vNetId string, subnetId string, actionParam string) (model.SimpleMsg, error) {
 	log.Info().Msg("DeleteSubnet")
@@ -653,7 +750,7 @@ func DeleteSubnet(nsId string, vNetId string, subnetId string, actionParam strin
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -672,7 +769,7 @@ func DeleteSubnet(nsId string, vNetId string, subnetId string, actionParam strin
 		return emptyRet, err
 	}
 	if subnetKeyValue == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, subnet: %s", subnetId)
+		err := common.NewResourceNotFoundError(model.StrSubnet, subnetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -687,7 +784,7 @@ func DeleteSubnet(nsId string, vNetId string, subnetId string, actionParam strin
 	// Todo: Check if the subnet is being used by any resouces, such as virtual machines, gateways, etc.
 	// Check if the vNet has subnets or not
 	if action == ActionNone && subnetInfo.Status == string(NetworkInUse) {
-		err := fmt.Errorf("the subnet (%s) is in-use, may have any resources", subnetId)
+		err := common.NewResourceInUseError(model.StrSubnet, subnetId, nil)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -852,7 +949,7 @@ func RefineSubnet(nsId string, vNetId string, subnetId string) (model.SimpleMsg,
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -870,7 +967,7 @@ func RefineSubnet(nsId string, vNetId string, subnetId string) (model.SimpleMsg,
 		return emptyRet, err
 	}
 	if subnetKeyValue == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, subnet: %s", subnetId)
+		err := common.NewResourceNotFoundError(model.StrSubnet, subnetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1025,7 +1122,7 @@ func RegisterSubnet(nsId string, vNetId string, subnetReq *model.TbRegisterSubne
 	exists, err := CheckChildResource(nsId, resourceType, vNetId, subnetInfo.Id)
 	if exists {
 		log.Error().Err(err).Msg("")
-		err := fmt.Errorf("already exists, subnet: %s", subnetInfo.Id)
+		err := common.NewConflictError("The subnet %s already exists.", subnetInfo.Id)
 		return emptyRet, err
 	}
 	if err != nil {
@@ -1045,7 +1142,7 @@ func RegisterSubnet(nsId string, vNetId string, subnetReq *model.TbRegisterSubne
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1139,6 +1236,7 @@ func RegisterSubnet(nsId string, vNetId string, subnetReq *model.TbRegisterSubne
 	subnetInfo.CspResourceId = spResp.IId.SystemId
 	subnetInfo.CspResourceName = spResp.IId.NameId
 	subnetInfo.IPv4_CIDR = spResp.IPv4_CIDR
+	subnetInfo.IPv6_CIDR = spResp.IPv6_CIDR
 	subnetInfo.Zone = spResp.Zone
 	subnetInfo.KeyValueList = spResp.KeyValueList
 	// todo: restore the tag list later
@@ -1206,6 +1304,7 @@ func RegisterSubnet(nsId string, vNetId string, subnetReq *model.TbRegisterSubne
 		model.LabelDescription:     subnetInfo.Description,
 		model.LabelConnectionName:  subnetInfo.ConnectionName,
 	}
+	common.SetSystemLabels(labels, subnetInfo.ConnectionName)
 	err = label.CreateOrUpdateLabel(model.StrSubnet, uid, vNetKey, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -1259,7 +1358,7 @@ func DeregisterSubnet(nsId string, vNetId string, subnetId string) (model.Simple
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1278,7 +1377,7 @@ func DeregisterSubnet(nsId string, vNetId string, subnetId string) (model.Simple
 		return emptyRet, err
 	}
 	if subnetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, subnet: %s", subnetId)
+		err := common.NewResourceNotFoundError(model.StrSubnet, subnetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1293,7 +1392,7 @@ func DeregisterSubnet(nsId string, vNetId string, subnetId string) (model.Simple
 	// Todo: Check if the subnet is being used by any resouces, such as virtual machines, gateways, etc.
 	// Check if the vNet has subnets or not
 	if subnetInfo.Status == string(NetworkInUse) {
-		err := fmt.Errorf("the subnet (%s) is in-use, may have any resources", subnetId)
+		err := common.NewResourceInUseError(model.StrSubnet, subnetId, nil)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1410,37 +1509,13 @@ func DeregisterSubnet(nsId string, vNetId string, subnetId string) (model.Simple
 // GetFirstNZones returns the first N zones of the given connection
 func GetFirstNZones(connectionName string, firstN int) ([]string, int, error) {
 
-	// TODO: Update the validation logic
-	// It's a temporary validation logic due to the connection name pattern
-
-	// Splite the connectionName into provider and region
-	parts := strings.SplitN(connectionName, "-", 2)
-	provider := parts[0]
-	regionZone := parts[1]
-
-	// Get the region details
-	regionsObj, err := common.GetRegions(provider)
+	// Get the region detail of the connection
+	regionDetail, err := common.GetRegionDetailByConnection(connectionName)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return nil, 0, err
 	}
 
-	// Try to match and get the region detail
-	var regionDetail model.RegionDetail
-	for _, region := range regionsObj.Regions {
-		exists := strings.HasPrefix(regionZone, region.RegionName)
-		if exists {
-			regionDetail = region
-			break
-		}
-	}
-	// Check if the region detail exists or not
-	if regionDetail.RegionName == "" && len(regionDetail.Zones) == 0 {
-		err := fmt.Errorf("invalid region/zone: %s", regionZone)
-		log.Error().Err(err).Msg("")
-		return nil, 0, err
-	}
-
 	// Get the first N zones
 	zones := regionDetail.Zones
 	length := len(zones)
@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resource is to manage multi-cloud infra resource
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cloud-barista/cb-tumblebug/src/core/common"
+	"github.com/cloud-barista/cb-tumblebug/src/core/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// trashSpiderPath is the path of CB-Spider for each resource type which can be moved to the trash
+var trashSpiderPath = map[string]string{
+	model.StrVNet:          "vpc",
+	model.StrSecurityGroup: "securitygroup",
+	model.StrSSHKey:        "keypair",
+	model.StrDataDisk:      "disk",
+}
+
+// IsTrashableResourceType returns true if the resource type supports soft delete
+func IsTrashableResourceType(resourceType string) bool {
+	_, ok := trashSpiderPath[resourceType]
+	return ok
+}
+
+// trashedCspResource returns the connection and the CSP resource name of the object in the trash item
+func trashedCspResource(item model.TrashItem) (string, string, error) {
+	for _, kv := range item.Objects {
+		if kv.Key != item.ResourceKey {
+			continue
+		}
+		obj := struct {
+			ConnectionName  string `json:"connectionName"`
+			CspResourceName string `json:"cspResourceName"`
+		}{}
+		if err := json.Unmarshal(kv.Value, &obj); err != nil {
+			return "", "", err
+		}
+		if obj.ConnectionName == "" || obj.CspResourceName == "" {
+			return "", "", fmt.Errorf("%s %s has no CSP resource", item.ResourceType, item.ResourceId)
+		}
+		return obj.ConnectionName, obj.CspResourceName, nil
+	}
+	return "", "", fmt.Errorf("the trash item %s has no %s object", item.Id, item.ResourceType)
+}
+
+// callSpiderForTrashedResource calls CB-Spider (GET or DELETE) for the CSP resource of the trash item
+func callSpiderForTrashedResource(item model.TrashItem, method string) error {
+	connectionName, cspResourceName, err := trashedCspResource(item)
+	if err != nil {
+		return err
+	}
+	requestBody := model.SpiderConnectionName{ConnectionName: connectionName}
+	var callResult interface{}
+	return common.ExecuteHttpRequest(
+		resty.New(),
+		method,
+		fmt.Sprintf("%s/%s/%s", model.SpiderRestUrl, trashSpiderPath[item.ResourceType], url.PathEscape(cspResourceName)),
+		nil,
+		common.SetUseBody(requestBody),
+		&requestBody,
+		&callResult,
+		common.VeryShortDuration,
+	)
+}
+
+// TrashResource moves the resource to the trash without deleting the CSP resource.
+// The CSP resource is deleted when the trash item is purged.
+func TrashResource(nsId string, resourceType string, resourceId string) (model.TrashItem, error) {
+	if !IsTrashableResourceType(resourceType) {
+		return model.TrashItem{}, common.NewValidationFailedError("%s does not support soft delete (use permanent=true)", resourceType)
+	}
+	check, err := CheckResource(nsId, resourceType, resourceId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return model.TrashItem{}, err
+	}
+	if !check {
+		return model.TrashItem{}, common.NewResourceNotFoundError(resourceType, resourceId)
+	}
+
+	// the CSP resource is kept, so the resource must not be in use
+	associatedList, _ := GetAssociatedObjectList(nsId, resourceType, resourceId)
+	if len(associatedList) > 0 {
+		return model.TrashItem{}, common.NewConflictError("%s %s is associated with [%s]", resourceType, resourceId, strings.Join(associatedList, ", "))
+	}
+
+	return common.MoveToTrash(nsId, resourceType, resourceId, common.GenResourceKey(nsId, resourceType, resourceId), true)
+}
+
+// RestoreTrashedResource restores the resource in the trash item if its CSP resource still exists.
+// The id is suffixed (e.g., vnet01-1) if the original id is taken.
+func RestoreTrashedResource(item model.TrashItem) (model.TrashRestoreResult, error) {
+	result := model.TrashRestoreResult{NsId: item.NsId, ResourceType: item.ResourceType, OriginalId: item.ResourceId}
+	if !IsTrashableResourceType(item.ResourceType) {
+		return result, common.NewValidationFailedError("cannot restore %s from the trash", item.ResourceType)
+	}
+
+	if err := callSpiderForTrashedResource(item, "GET"); err != nil {
+		log.Error().Err(err).Msg("")
+		return result, common.NewConflictError("the CSP resource of %s %s is not available (%v); purge the trash item instead", item.ResourceType, item.ResourceId, err)
+	}
+
+	id, err := common.TrashRestoreId(item, func(id string) (bool, error) {
+		return CheckResource(item.NsId, item.ResourceType, id)
+	})
+	if err != nil {
+		return result, err
+	}
+	_, restored, err := common.RestoreTrashObjects(item, id)
+	if err != nil {
+		return result, err
+	}
+	if err := common.DeleteTrashItem(item.NsId, item.Id); err != nil {
+		return result, err
+	}
+
+	result.ResourceId = id
+	result.Renamed = id != item.ResourceId
+	result.Restored = restored
+	result.SystemMessage = "the CSP resource exists"
+	return result, nil
+}
+
+// PurgeTrashedResource deletes the CSP resource kept for the trash item
+func PurgeTrashedResource(item model.TrashItem) error {
+	if !item.CspResourceKept || !IsTrashableResourceType(item.ResourceType) {
+		return nil
+	}
+	return callSpiderForTrashedResource(item, "DELETE")
+}
@@ -15,9 +15,11 @@ limitations under the License.
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 
@@ -145,48 +147,11 @@ func ValidateVNetReq(vNetReq *model.TbVNetReq) error {
 		return err
 	}
 
-	// Validate zone in each subnet
-	// TODO: Update the validation logic
-	// It's a temporary validation logic due to the connection name pattern
-
-	// Split the connection name into provider and region/zone
-	parts := strings.SplitN(vNetReq.ConnectionName, "-", 2)
-	provider := parts[0]
-	regionZone := parts[1]
-
-	// Get the region list
-	regionsObj, err := common.GetRegions(provider)
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return err
-	}
-
-	// Try to match and get the region detail
-	var regionDetail model.RegionDetail
-	for _, region := range regionsObj.Regions {
-		exists := strings.HasPrefix(regionZone, region.RegionName)
-		if exists {
-			regionDetail = region
-			break
-		}
-	}
-
-	// Check if the region detail exists or not
-	if regionDetail.RegionName == "" && len(regionDetail.Zones) == 0 {
-		err := fmt.Errorf("invalid region/zone: %s", regionZone)
-		log.Error().Err(err).Msg("")
-		return err
-	}
-
-	// Validate the zone in each subnet
-	zones := regionDetail.Zones
+	// Validate the zone in each subnet against the region and the assigned zone of the connection
 	for _, subnetInfo := range vNetReq.SubnetInfoList {
-		if subnetInfo.Zone != "" {
-			if !ContainsZone(zones, subnetInfo.Zone) {
-				err := fmt.Errorf("invalid zone: %s", subnetInfo.Zone)
-				log.Error().Err(err).Msg("")
-				return err
-			}
+		if err := ValidateSubnetZone(vNetReq.ConnectionName, subnetInfo.Zone); err != nil {
+			log.Error().Err(err).Msg("")
+			return err
 		}
 	}
 
@@ -207,6 +172,13 @@ func ValidateVNetReq(vNetReq *model.TbVNetReq) error {
 	network.Subnets = subnets
 	log.Debug().Msgf("network: %+v", network)
 
+	// The CIDR blocks are IPv4 (IPv6 blocks are given by ipv6CidrBlock and ipv6_CIDR)
+	if family, err := netutil.CidrFamily(vNetReq.CidrBlock); err == nil && family != 4 {
+		err := common.NewValidationFailedError("cidrBlock %s is not an IPv4 CIDR block (use ipv6CidrBlock for IPv6)", vNetReq.CidrBlock)
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
 	// Validate the network object
 	err = netutil.ValidateNetwork(network)
 	if err != nil {
@@ -214,9 +186,348 @@ func ValidateVNetReq(vNetReq *model.TbVNetReq) error {
 		return err
 	}
 
+	// Validate the IPv6 CIDR blocks of a dual-stack vNet
+	err = validateVNetIpv6(vNetReq)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return err
+	}
+
+	return nil
+}
+
+// validateVNetIpv6 validates the IPv6 (dual-stack) settings of the vNet request.
+// Subnets without ipv6_CIDR are IPv4 only, except with assignIpv6=auto where the CSP allocates the range of each subnet.
+func validateVNetIpv6(vNetReq *model.TbVNetReq) error {
+	subnetIpv6 := false
+	for _, subnetInfo := range vNetReq.SubnetInfoList {
+		if subnetInfo.IPv6_CIDR != "" {
+			subnetIpv6 = true
+		}
+	}
+	if vNetReq.IPv6CidrBlock == "" && vNetReq.AssignIpv6 == "" && !subnetIpv6 {
+		return nil
+	}
+
+	if vNetReq.AssignIpv6 != "" && vNetReq.AssignIpv6 != model.AssignIpv6Auto {
+		return common.NewValidationFailedError("invalid assignIpv6: %s (only %s is supported)", vNetReq.AssignIpv6, model.AssignIpv6Auto)
+	}
+	explicitRange := vNetReq.IPv6CidrBlock != "" || subnetIpv6
+	err := checkIpv6Capability(vNetReq.ConnectionName, explicitRange)
+	if err != nil {
+		return err
+	}
+
+	if vNetReq.AssignIpv6 == model.AssignIpv6Auto {
+		if explicitRange {
+			return common.NewValidationFailedError("ipv6CidrBlock and ipv6_CIDR of the subnets cannot be set with assignIpv6=%s (the CSP allocates the ranges)", model.AssignIpv6Auto)
+		}
+		return nil
+	}
+	if vNetReq.IPv6CidrBlock == "" {
+		return common.NewValidationFailedError("ipv6_CIDR of a subnet requires ipv6CidrBlock of the vNet (or assignIpv6=%s)", model.AssignIpv6Auto)
+	}
+
+	network := netutil.Network{CidrBlock: vNetReq.IPv6CidrBlock}
+	for _, subnetInfo := range vNetReq.SubnetInfoList {
+		if subnetInfo.IPv6_CIDR != "" {
+			network.Subnets = append(network.Subnets, netutil.Network{CidrBlock: subnetInfo.IPv6_CIDR})
+		}
+	}
+	err = netutil.ValidateIPv6Network(network)
+	if err != nil {
+		return common.NewValidationFailedError("invalid IPv6 CIDR block: %v", err)
+	}
+	return nil
+}
+
+// checkIpv6Capability rejects a dual-stack request if the provider of the connection does not support IPv6
+// in the capability matrix. Partially supported providers accept only the ranges allocated by the CSP (no explicit range).
+func checkIpv6Capability(connectionName string, explicitRange bool) error {
+	connConfig, err := common.GetConnConfig(connectionName)
+	if err != nil {
+		return fmt.Errorf("connection config '%s' not found: %w", connectionName, err)
+	}
+	providerName := strings.ToLower(connConfig.ProviderName)
+	capability := common.GetCapability(providerName, common.CapabilityIpv6)
+
+	switch {
+	case capability.Support == common.CapabilityUnsupported:
+		return common.NewValidationFailedError("dual-stack (IPv6) vNets are not supported for the provider %s (capability %s: %s; %s)",
+			providerName, common.CapabilityIpv6, capability.Support, capability.Note)
+	case capability.Support == common.CapabilityPartial && explicitRange:
+		return common.NewValidationFailedError("IPv6 CIDR blocks cannot be specified for the provider %s (capability %s: %s; %s)",
+			providerName, common.CapabilityIpv6, capability.Support, capability.Note)
+	}
 	return nil
 }
 
+// subnetZoneStrict returns true if a subnet zone other than the assigned zone of the connection is rejected
+// (TB_SUBNET_ZONE_STRICT, default false: the request is accepted with a warning)
+func subnetZoneStrict() bool {
+	return strings.EqualFold(os.Getenv("TB_SUBNET_ZONE_STRICT"), "true")
+}
+
+// ValidateSubnetZone checks the zone of a subnet against the zones of the region assigned to the connection.
+// A zone other than the assigned zone of the connection is created in a different location than the connection
+// on some CSPs, so it is warned (or rejected if TB_SUBNET_ZONE_STRICT=true). An empty zone is always valid.
+func ValidateSubnetZone(connectionName string, zone string) error {
+	warning, err := checkSubnetZone(connectionName, zone)
+	if err != nil || warning == "" {
+		return err
+	}
+	if subnetZoneStrict() {
+		return common.NewValidationFailedError("%s (use the connection of the zone or leave the zone empty)", warning)
+	}
+	log.Warn().Msgf("%s; the subnet may be created in an unexpected location on some CSPs", warning)
+	return nil
+}
+
+// checkSubnetZone returns an error for a zone which is not in the region of the connection,
+// and a warning for a zone which differs from the zone assigned to the connection
+func checkSubnetZone(connectionName string, zone string) (string, error) {
+	if zone == "" {
+		return "", nil
+	}
+
+	connConfig, err := common.GetConnConfig(connectionName)
+	if err != nil {
+		return "", fmt.Errorf("connection config '%s' not found: %w", connectionName, err)
+	}
+	regionDetail, err := common.GetRegionDetailByConnection(connectionName)
+	if err != nil {
+		return "", err
+	}
+
+	zones := regionDetail.Zones
+	if len(zones) == 0 {
+		return "", common.NewValidationFailedError("invalid zone: %s (region %s of the connection %s has no zones; leave the zone empty)",
+			zone, regionDetail.RegionName, connectionName)
+	}
+	if !ContainsZone(zones, zone) {
+		return "", common.NewValidationFailedError("invalid zone: %s (valid zones of region %s of the connection %s: [%s])",
+			zone, regionDetail.RegionName, connectionName, strings.Join(zones, ", "))
+	}
+
+	// the connection may have no assigned zone (empty or "N/A" for regions without zones)
+	assignedZone := connConfig.RegionZoneInfo.AssignedZone
+	if assignedZone == "" || strings.EqualFold(assignedZone, "N/A") || zone == assignedZone {
+		return "", nil
+	}
+	return fmt.Sprintf("zone %s differs from the zone %s assigned to the connection %s", zone, assignedZone, connectionName), nil
+}
+
+// FindOverlappingVNets returns the vNets in the namespace whose CIDR block overlaps cidrBlock
+// and whose connection shares the CSP account (provider and credential holder) with connectionName.
+// Each conflict describes the vNet and the overlapping ranges.
+func FindOverlappingVNets(nsId string, connectionName string, cidrBlock string) ([]string, error) {
+	conflicts := []string{}
+	if cidrBlock == "" {
+		return conflicts, nil
+	}
+
+	connConfig, err := common.GetConnConfig(connectionName)
+	if err != nil {
+		return conflicts, fmt.Errorf("connection config '%s' not found: %w", connectionName, err)
+	}
+
+	resourceList, err := ListResource(nsId, model.StrVNet, "", "")
+	if err != nil {
+		return conflicts, err
+	}
+	vNetList, ok := resourceList.([]model.TbVNetInfo)
+	if !ok {
+		return conflicts, nil
+	}
+
+	// cache of whether each connection shares the CSP account with the connection
+	sameAccount := map[string]bool{connectionName: true}
+	for _, vNet := range vNetList {
+		if vNet.CidrBlock == "" {
+			continue
+		}
+		same, checked := sameAccount[vNet.ConnectionName]
+		if !checked {
+			otherConfig, err := common.GetConnConfig(vNet.ConnectionName)
+			same = err == nil &&
+				strings.EqualFold(otherConfig.ProviderName, connConfig.ProviderName) &&
+				otherConfig.CredentialHolder == connConfig.CredentialHolder
+			sameAccount[vNet.ConnectionName] = same
+		}
+		if !same {
+			continue
+		}
+		overlap, err := netutil.CidrOverlap(cidrBlock, vNet.CidrBlock)
+		if err != nil {
+			return conflicts, err
+		}
+		if overlap {
+			conflicts = append(conflicts, fmt.Sprintf("%s overlaps %s of vNet %s (connection %s)", cidrBlock, vNet.CidrBlock, vNet.Id, vNet.ConnectionName))
+		}
+	}
+	return conflicts, nil
+}
+
+// CheckVNetCidrOverlap rejects cidrBlock if it overlaps a vNet in the same namespace and CSP account
+// (overlapping ranges break peering and VPN routing). With allowOverlap, the overlap is only warned.
+func CheckVNetCidrOverlap(nsId string, connectionName string, cidrBlock string, allowOverlap bool) error {
+	conflicts, err := FindOverlappingVNets(nsId, connectionName, cidrBlock)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if allowOverlap {
+		log.Warn().Msgf("CIDR block overlaps existing vNets (allowed by allowOverlap): [%s]", strings.Join(conflicts, "; "))
+		return nil
+	}
+	return common.NewConflictError("CIDR block overlaps existing vNets in the namespace %s (use allowOverlap=true to create it anyway): [%s]",
+		nsId, strings.Join(conflicts, "; "))
+}
+
+// checkCidrNetworkConstraints returns the errors and warnings of a CIDR block against the network constraints of the provider
+func checkCidrNetworkConstraints(field string, cidrBlock string, minPrefix int, maxPrefix int, network model.NetworkDetail, providerName string) ([]model.VNetValidationIssue, []model.VNetValidationIssue) {
+	errs := []model.VNetValidationIssue{}
+	warnings := []model.VNetValidationIssue{}
+
+	_, ipNet, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return append(errs, model.VNetValidationIssue{Field: field, Message: fmt.Sprintf("invalid CIDR block %s", cidrBlock)}), warnings
+	}
+	prefix, _ := ipNet.Mask.Size()
+	if (minPrefix > 0 && prefix < minPrefix) || (maxPrefix > 0 && prefix > maxPrefix) {
+		allowed := fmt.Sprintf("/%d-/%d", minPrefix, maxPrefix)
+		if minPrefix == 0 {
+			allowed = fmt.Sprintf("up to /%d", maxPrefix)
+		} else if maxPrefix == 0 {
+			allowed = fmt.Sprintf("from /%d", minPrefix)
+		}
+		errs = append(errs, model.VNetValidationIssue{Field: field,
+			Message: fmt.Sprintf("prefix length /%d is out of the range of %s (%s)", prefix, providerName, allowed)})
+	}
+	for _, reserved := range network.ReservedCidrs {
+		if overlap, err := netutil.CidrOverlap(cidrBlock, reserved); err == nil && overlap {
+			errs = append(errs, model.VNetValidationIssue{Field: field,
+				Message: fmt.Sprintf("%s overlaps %s reserved by %s", cidrBlock, reserved, providerName)})
+		}
+	}
+	for _, discouraged := range network.DiscouragedCidrs {
+		if overlap, err := netutil.CidrOverlap(cidrBlock, discouraged); err == nil && overlap {
+			warnings = append(warnings, model.VNetValidationIssue{Field: field,
+				Message: fmt.Sprintf("%s overlaps %s which conflicts with services of %s or common tools (e.g., the docker bridge)", cidrBlock, discouraged, providerName)})
+		}
+	}
+	return errs, warnings
+}
+
+// ValidateVNetReqDryRun validates the vNet request without creating anything: ValidateVNetReq, the connection allowlist,
+// the existence of the vNet, the CIDR overlap with other vNets, the zones of the subnets, and the network constraints
+// of the provider in cloud info (prefix lengths, max subnets, reserved CIDRs). It returns all errors and warnings found.
+func ValidateVNetReqDryRun(nsId string, vNetReq *model.TbVNetReq) (model.VNetValidationResult, error) {
+	result := model.VNetValidationResult{Errors: []model.VNetValidationIssue{}, Warnings: []model.VNetValidationIssue{}}
+	addError := func(field string, err error) {
+		result.Errors = append(result.Errors, model.VNetValidationIssue{Field: field, Message: err.Error()})
+	}
+
+	err := common.CheckString(nsId)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return result, err
+	}
+
+	if err := ValidateVNetReq(vNetReq); err != nil {
+		addError("", err)
+	}
+
+	connConfig, err := common.GetConnConfig(vNetReq.ConnectionName)
+	if err != nil {
+		addError("connectionName", fmt.Errorf("connection config '%s' not found", vNetReq.ConnectionName))
+		return result, nil
+	}
+	if err := common.CheckNsConnectionAllowed(nsId, vNetReq.ConnectionName); err != nil {
+		addError("connectionName", err)
+	}
+
+	if vNetReq.Name != "" {
+		if check, _ := CheckResource(nsId, model.StrVNet, vNetReq.Name); check {
+			addError("name", fmt.Errorf("the vNet %s already exists", vNetReq.Name))
+		}
+	}
+	if vNetReq.CidrBlock != "" {
+		conflicts, err := FindOverlappingVNets(nsId, vNetReq.ConnectionName, vNetReq.CidrBlock)
+		if err == nil && len(conflicts) > 0 {
+			issue := model.VNetValidationIssue{Field: "cidrBlock", Message: "CIDR block overlaps existing vNets: [" + strings.Join(conflicts, "; ") + "]"}
+			if vNetReq.AllowOverlap {
+				result.Warnings = append(result.Warnings, issue)
+			} else {
+				result.Errors = append(result.Errors, issue)
+			}
+		}
+	}
+
+	// zones of the subnets (ValidateVNetReq stops at the first error, so each zone is checked here)
+	for i, subnetInfo := range vNetReq.SubnetInfoList {
+		field := fmt.Sprintf("subnetInfoList[%d].zone", i)
+		warning, err := checkSubnetZone(vNetReq.ConnectionName, subnetInfo.Zone)
+		if err != nil {
+			addError(field, err)
+			continue
+		}
+		if warning != "" {
+			issue := model.VNetValidationIssue{Field: field, Message: warning}
+			if subnetZoneStrict() {
+				result.Errors = append(result.Errors, issue)
+			} else {
+				result.Warnings = append(result.Warnings, issue)
+			}
+		}
+	}
+
+	// network constraints of the provider
+	providerName := strings.ToLower(connConfig.ProviderName)
+	cloudInfo, err := common.GetCloudInfo()
+	if err != nil {
+		return result, err
+	}
+	network := cloudInfo.CSPs[providerName].Network
+	if network.MaxSubnetsPerVNet > 0 && len(vNetReq.SubnetInfoList) > network.MaxSubnetsPerVNet {
+		addError("subnetInfoList", fmt.Errorf("%d subnets exceed the max subnets per vNet of %s (%d)", len(vNetReq.SubnetInfoList), providerName, network.MaxSubnetsPerVNet))
+	}
+	if vNetReq.CidrBlock != "" {
+		errs, warnings := checkCidrNetworkConstraints("cidrBlock", vNetReq.CidrBlock, network.MinVNetPrefix, network.MaxVNetPrefix, network, providerName)
+		result.Errors = append(result.Errors, errs...)
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+	for i, subnetInfo := range vNetReq.SubnetInfoList {
+		if subnetInfo.IPv4_CIDR == "" {
+			continue
+		}
+		errs, warnings := checkCidrNetworkConstraints(fmt.Sprintf("subnetInfoList[%d].ipv4_CIDR", i), subnetInfo.IPv4_CIDR, network.MinSubnetPrefix, network.MaxSubnetPrefix, network, providerName)
+		result.Errors = append(result.Errors, errs...)
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+
+	// drop the error of ValidateVNetReq if the same error is reported for a field
+	fieldMessages := map[string]bool{}
+	for _, issue := range result.Errors {
+		if issue.Field != "" {
+			fieldMessages[issue.Message] = true
+		}
+	}
+	errs := []model.VNetValidationIssue{}
+	for _, issue := range result.Errors {
+		if issue.Field == "" && fieldMessages[issue.Message] {
+			continue
+		}
+		errs = append(errs, issue)
+	}
+	result.Errors = errs
+
+	result.Valid = len(result.Errors) == 0
+	return result, nil
+}
+
 func ContainsZone(zones []string, zone string) bool {
 	for _, z := range zones {
 		if z == zone {
@@ -259,6 +570,8 @@ type spiderCreateVPCRequest struct {
 type spiderCreateVPCRequestInfo struct {
 	Name           string                       `json:"Name" validate:"required" example:"vpc-01"`
 	IPv4_CIDR      string                       `json:"IPv4_CIDR" validate:"omitempty"` // Some CSPs unsupported VPC CIDR
+	IPv6_CIDR      string                       `json:"IPv6_CIDR,omitempty" validate:"omitempty"`
+	AssignIPv6     bool                         `json:"AssignIPv6,omitempty" validate:"omitempty"` // IPv6 range allocated by the CSP (dual-stack vNet)
 	SubnetInfoList []spiderAddSubnetRequestInfo `json:"SubnetInfoList" validate:"required"`
 	TagList        []model.KeyValue             `json:"TagList,omitempty" validate:"omitempty"`
 }
@@ -283,12 +596,12 @@ type spiderBooleanInfoResp struct {
 	Result string // Result of the operation
 }
 
-// type spiderGetSGOwnerVPCRequest struct {
-// 	ConnectionName string `json:"ConnectionName" validate:"required" example:"aws-connection"`
-// 	ReqInfo        struct {
-// 		CSPId string `json:"CSPId" validate:"required" example:"csp-sg-1234"`
-// 	} `json:"ReqInfo" validate:"required"`
-// }
+type spiderGetSGOwnerVPCRequest struct {
+	ConnectionName string `json:"ConnectionName" validate:"required" example:"aws-connection"`
+	ReqInfo        struct {
+		CSPId string `json:"CSPId" validate:"required" example:"csp-sg-1234"`
+	} `json:"ReqInfo" validate:"required"`
+}
 
 /*
 	Based on polymorphism, the following Spider-related structs have been designed.
@@ -321,15 +634,67 @@ type spiderBooleanInfoResp struct {
 type spiderVPCInfo struct {
 	IId            model.IID          `json:"IId" validate:"required"` // {NameId, SystemId}
 	IPv4_CIDR      string             `json:"IPv4_CIDR" validate:"required" example:"10.0.0.0/16" description:"The IPv4 CIDR block for the VPC"`
+	IPv6_CIDR      string             `json:"IPv6_CIDR,omitempty" validate:"omitempty" example:"2600:1f18:1234:5600::/56" description:"The IPv6 CIDR block for the VPC (dual-stack)"`
 	SubnetInfoList []spiderSubnetInfo `json:"SubnetInfoList" validate:"required" description:"A list of subnet information associated with this VPC"`
 
 	TagList      []model.KeyValue `json:"TagList,omitempty" validate:"omitempty" description:"A list of tags associated with this VPC"`
 	KeyValueList []model.KeyValue `json:"KeyValueList,omitempty" validate:"omitempty" description:"Additional key-value pairs associated with this VPC"`
 }
 
+// markVNetInterrupted marks the vNet which is still being configured as ErrorOnConfiguring
+func markVNetInterrupted(vNetKey string, reason string) {
+	keyValue, err := kvstore.GetKv(vNetKey)
+	if err != nil || keyValue == (kvstore.KeyValue{}) {
+		return
+	}
+	vNetInfo := model.TbVNetInfo{}
+	err = json.Unmarshal([]byte(keyValue.Value), &vNetInfo)
+	if err != nil || vNetInfo.Status != string(NetworkOnConfiguring) {
+		return
+	}
+	vNetInfo.Status = string(NetworkErrorOnConfiguring)
+	vNetInfo.Description = fmt.Sprintf("%s (interrupted by %s)", vNetInfo.Description, reason)
+	val, err := json.Marshal(vNetInfo)
+	if err != nil {
+		return
+	}
+	err = kvstore.Put(vNetKey, string(val))
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return
+	}
+	emitVNetStatusEvent(vNetKey, vNetInfo.Status)
+	log.Warn().Msgf("vNet (%s) is marked as %s (%s)", vNetKey, NetworkErrorOnConfiguring, reason)
+}
+
+// emitVNetStatusEvent notifies the status of the vNet to event subscribers (SSE streams and webhooks)
+// and records it in the timeline of the namespace
+func emitVNetStatusEvent(vNetKey string, status string) {
+	// key: /ns/{nsId}/resources/vNet/{vNetId}
+	segments := strings.Split(vNetKey, "/")
+	if len(segments) < 3 {
+		return
+	}
+	common.EmitEvent(model.EventVNetStatusChanged, segments[2], vNetKey, map[string]string{"status": status})
+	recordVNetNsEvent(vNetKey, model.NsEventStatusChanged, status)
+}
+
+// recordVNetNsEvent appends the event of the vNet (by its key) to the timeline of the namespace
+func recordVNetNsEvent(vNetKey string, eventType string, message string) {
+	// key: /ns/{nsId}/resources/vNet/{vNetId}
+	segments := strings.Split(vNetKey, "/")
+	if len(segments) < 6 {
+		return
+	}
+	common.RecordNsEvent(segments[2], vNetKey, model.NsEvent{ResourceType: model.StrVNet, ResourceId: segments[5], EventType: eventType, Message: message})
+}
+
 // CreateVNet accepts vNet creation request, creates and returns an TB vNet object
-func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error) {
-	log.Info().Msg("CreateVNet")
+// The request ID of the context is propagated to CB-Spider and logged with the nsId and resourceId.
+func CreateVNet(ctx context.Context, nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error) {
+	ctx = common.WithLogFields(ctx, "nsId", nsId, "resourceId", vNetReq.Name)
+	logger := common.LoggerFromContext(ctx)
+	logger.Info().Msg("CreateVNet")
 
 	// vNet objects
 	var emptyRet model.TbVNetInfo
@@ -343,16 +708,34 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 	// Validate the input parameters
 	err = common.CheckString(nsId)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
 	err = validate.Struct(vNetReq)
 	if err != nil {
 		if _, ok := err.(*validator.InvalidValidationError); ok {
-			log.Error().Err(err).Msg("")
+			logger.Error().Err(err).Msg("")
 			return emptyRet, err
 		}
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
+	// Check the naming policy of the namespace
+	err = common.CheckNsNamingPolicy(ctx, nsId, model.StrVNet, vNetReq.Name)
+	if err != nil {
+		return emptyRet, err
+	}
+
+	// Check the connection allowlist of the namespace
+	err = common.CheckNsConnectionAllowed(nsId, vNetReq.ConnectionName)
+	if err != nil {
+		return emptyRet, err
+	}
+
+	// Check the quota of the namespace
+	err = CheckNsQuota(nsId, model.NsQuotaUsage{VNets: 1})
+	if err != nil {
 		return emptyRet, err
 	}
 
@@ -368,6 +751,7 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 	vNetInfo.Uid = uid
 	vNetInfo.ConnectionName = vNetReq.ConnectionName
 	vNetInfo.Description = vNetReq.Description
+	vNetInfo.Provenance = common.NewProvenance(ctx, model.CreatedViaManual)
 	// todo: restore the tag list later
 	// vNetInfo.TagList = vNetReq.TagList
 
@@ -380,29 +764,37 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 			Name:         subnetInfo.Name,
 			Uid:          common.GenUid(),
 			IPv4_CIDR:    subnetInfo.IPv4_CIDR,
+			IPv6_CIDR:    subnetInfo.IPv6_CIDR,
 			Zone:         subnetInfo.Zone,
 			// todo: restore the tag list later
 			// TagList:   subnetInfo.TagList,
 		})
 	}
 
-	log.Debug().Msgf("vNetInfo: %+v", vNetInfo)
+	logger.Debug().Msgf("vNetInfo: %+v", vNetInfo)
 
 	// Set a vNetKey for the vNet object
 	vNetKey := common.GenResourceKey(nsId, resourceType, vNetInfo.Id)
 	// Check if the vNet already exists or not
 	exists, err := CheckResource(nsId, resourceType, vNetInfo.Id)
 	if exists {
-		log.Error().Err(err).Msg("")
-		err := fmt.Errorf("already exists, vNet: %s", vNetInfo.Id)
+		logger.Error().Err(err).Msg("")
+		err := common.NewConflictError("The vNet %s already exists.", vNetInfo.Id)
 		return emptyRet, err
 	}
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		err := fmt.Errorf("failed to check if the vNet (%s) exists or not", vNetInfo.Id)
 		return emptyRet, err
 	}
 
+	// Check if the CIDR block overlaps the other vNets in the same CSP account
+	err = CheckVNetCidrOverlap(nsId, vNetReq.ConnectionName, vNetReq.CidrBlock, vNetReq.AllowOverlap)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+
 	/*
 	 *	Create vNet with at least one subnet
 	 */
@@ -411,34 +803,45 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 	vNetInfo.Status = string(NetworkOnConfiguring)
 	val, err := json.Marshal(vNetInfo)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
 	err = kvstore.Put(vNetKey, string(val))
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
+	emitVNetStatusEvent(vNetKey, vNetInfo.Status)
+
+	// Register the configuration to the shutdown coordinator
+	endOperation := common.BeginOperation("createVNet "+vNetKey, func(reason string) {
+		markVNetInterrupted(vNetKey, reason)
+	})
+	defer endOperation()
 
 	// [Via Spider] Create a vNet and subnets
 	spReqt := spiderCreateVPCRequest{}
 	spReqt.ConnectionName = vNetReq.ConnectionName
 	spReqt.ReqInfo.Name = vNetInfo.Uid
 	spReqt.ReqInfo.IPv4_CIDR = vNetReq.CidrBlock
+	spReqt.ReqInfo.IPv6_CIDR = vNetReq.IPv6CidrBlock
+	spReqt.ReqInfo.AssignIPv6 = vNetReq.AssignIpv6 == model.AssignIpv6Auto
 
 	// Note: Use the subnets in the vNetInfo object (instead of the vNetReq object)
 	//       since each subnet uid must be consistent
 	for _, subnetInfo := range vNetInfo.SubnetInfoList {
 		spReqt.ReqInfo.SubnetInfoList = append(spReqt.ReqInfo.SubnetInfoList, spiderAddSubnetRequestInfo{
-			Name:      subnetInfo.Uid,
-			IPv4_CIDR: subnetInfo.IPv4_CIDR,
-			Zone:      subnetInfo.Zone,
+			Name:       subnetInfo.Uid,
+			IPv4_CIDR:  subnetInfo.IPv4_CIDR,
+			IPv6_CIDR:  subnetInfo.IPv6_CIDR,
+			AssignIPv6: vNetReq.AssignIpv6 == model.AssignIpv6Auto,
+			Zone:       subnetInfo.Zone,
 			// todo: restore the tag list later
 			// TagList:   subnetInfo.TagList,
 		})
 	}
 
-	log.Debug().Msgf("spReqt: %+v", spReqt)
+	logger.Debug().Msgf("spReqt: %+v", spReqt)
 
 	client := resty.New()
 	method := "POST"
@@ -452,7 +855,7 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 		// Only if this operation fails, the vNet will be deleted
 		if err != nil && vNetInfo.Status == string(NetworkOnConfiguring) {
 			if vNetInfo.CspResourceId == "" { // Delete the saved the subnet info
-				log.Warn().Msgf("failed to create vNet, cleaning up the vNet: %v", vNetInfo.Id)
+				logger.Warn().Msgf("failed to create vNet, cleaning up the vNet: %v", vNetInfo.Id)
 				// Delete the subnets associated with the vNet
 				for _, subnetInfo := range vNetInfo.SubnetInfoList {
 					if subnetInfo.CspResourceId == "" {
@@ -460,14 +863,14 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 						subnetKey := common.GenChildResourceKey(nsId, childResourceType, vNetInfo.Id, subnetInfo.Id)
 						deleteErr := kvstore.Delete(subnetKey)
 						if deleteErr != nil {
-							log.Warn().Err(deleteErr).Msgf("failed to delete the subnet: %v from kvstore", subnetInfo.Id)
+							logger.Warn().Err(deleteErr).Msgf("failed to delete the subnet: %v from kvstore", subnetInfo.Id)
 						}
 					}
 				}
 				// Delete the saved the vNet info
 				deleteErr := kvstore.Delete(vNetKey)
 				if deleteErr != nil {
-					log.Warn().Err(deleteErr).Msgf("failed to delete the vNet: %v from kvstore", vNetInfo.Id)
+					logger.Warn().Err(deleteErr).Msgf("failed to delete the vNet: %v from kvstore", vNetInfo.Id)
 				}
 			}
 			// todo: check if the following operation is obviously required or not
@@ -475,7 +878,7 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 			// 	// [Via Spider] Delete the vNet withSubnets == true
 			// 	_, deleteErr := DeleteVNet(nsId, vNetInfo.Id, "true")
 			// 	if deleteErr != nil {
-			// 		log.Warn().Err(err).Msgf("failed to delete vNet: %v from CSP", vNetInfo.Id)
+			// 		logger.Warn().Err(err).Msgf("failed to delete vNet: %v from CSP", vNetInfo.Id)
 			// 	}
 			// }
 		}
@@ -490,10 +893,11 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 		&spReqt,
 		&spResp,
 		common.MediumDuration,
+		common.WithRequestContext(ctx),
 	)
 
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
 
@@ -501,6 +905,7 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 	vNetInfo.CspResourceId = spResp.IId.SystemId
 	vNetInfo.CspResourceName = spResp.IId.NameId
 	vNetInfo.CidrBlock = spResp.IPv4_CIDR
+	vNetInfo.IPv6CidrBlock = common.NVL(spResp.IPv6_CIDR, vNetReq.IPv6CidrBlock)
 	vNetInfo.KeyValueList = spResp.KeyValueList
 	// todo: restore the tag list later
 	// vNetInfo.TagList = spResp.TagList
@@ -520,6 +925,7 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 				vNetInfo.SubnetInfoList[i].KeyValueList = spSubnetInfo.KeyValueList
 				vNetInfo.SubnetInfoList[i].Zone = spSubnetInfo.Zone
 				vNetInfo.SubnetInfoList[i].IPv4_CIDR = spSubnetInfo.IPv4_CIDR
+				vNetInfo.SubnetInfoList[i].IPv6_CIDR = common.NVL(spSubnetInfo.IPv6_CIDR, tbSubnetInfo.IPv6_CIDR)
 				// todo: restore the tag list later
 				// vNetInfo.SubnetInfoList[i].TagList = spSubnetInfo.TagList
 			}
@@ -533,39 +939,37 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 		vNetInfo.Status = string(NetworkInUse)
 	} else {
 		vNetInfo.Status = string(NetworkUnknown)
-		log.Warn().Msgf("The status of the vNet (%s) is unknown", vNetInfo.Id)
+		logger.Warn().Msgf("The status of the vNet (%s) is unknown", vNetInfo.Id)
 	}
 
-	log.Debug().Msgf("vNetInfo: %+v", vNetInfo)
+	logger.Debug().Msgf("vNetInfo: %+v", vNetInfo)
 
-	// Store vNet object into the key-value store
+	// Store the vNet object and its subnet objects into the key-value store atomically
 	value, err := json.Marshal(vNetInfo)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
-	err = kvstore.Put(vNetKey, string(value))
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return emptyRet, err
-	}
-
-	// Store subnet objects into the key-value store
+	kvs := []kvstore.KeyValue{{Key: vNetKey, Value: string(value)}}
 	for _, subnetInfo := range vNetInfo.SubnetInfoList {
 		// Set a subnetKey for the subnet object
 		subnetKey := common.GenChildResourceKey(nsId, childResourceType, vNetInfo.Id, subnetInfo.Id)
 		value, err := json.Marshal(subnetInfo)
 		if err != nil {
-			log.Error().Err(err).Msg("")
+			logger.Error().Err(err).Msg("")
 			return emptyRet, err
 		}
+		kvs = append(kvs, kvstore.KeyValue{Key: subnetKey, Value: string(value)})
+	}
+	err = kvstore.PutMulti(kvs)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	emitVNetStatusEvent(vNetKey, vNetInfo.Status)
 
-		// Store the subnet object into the key-value store
-		err = kvstore.Put(subnetKey, string(value))
-		if err != nil {
-			log.Error().Err(err).Msg("")
-			return emptyRet, err
-		}
+	for _, subnetInfo := range vNetInfo.SubnetInfoList {
+		subnetKey := common.GenChildResourceKey(nsId, childResourceType, vNetInfo.Id, subnetInfo.Id)
 
 		// Store label info using CreateOrUpdateLabel
 		labels := map[string]string{
@@ -584,9 +988,10 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 			model.LabelVNetId:          vNetInfo.Id,
 			model.LabelConnectionName:  vNetInfo.ConnectionName,
 		}
+		common.SetSystemLabels(labels, vNetInfo.ConnectionName)
 		err = label.CreateOrUpdateLabel(model.StrSubnet, subnetInfo.Uid, subnetKey, labels)
 		if err != nil {
-			log.Error().Err(err).Msg("")
+			logger.Error().Err(err).Msg("")
 			return emptyRet, err
 		}
 	}
@@ -594,17 +999,17 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 	// Check if the vNet info is stored
 	vNetKv, err := kvstore.GetKv(vNetKey)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetInfo.Id)
-		log.Error().Err(err).Msg("")
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetInfo.Id)
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
 	err = json.Unmarshal([]byte(vNetKv.Value), &vNetInfo)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
 
@@ -623,11 +1028,14 @@ func CreateVNet(nsId string, vNetReq *model.TbVNetReq) (model.TbVNetInfo, error)
 		model.LabelDescription:     vNetInfo.Description,
 		model.LabelConnectionName:  vNetInfo.ConnectionName,
 	}
+	common.SetSystemLabels(labels, vNetInfo.ConnectionName)
+	common.SetProvenanceLabels(labels, vNetInfo.Provenance)
 	err = label.CreateOrUpdateLabel(model.StrVNet, vNetInfo.Uid, vNetKey, labels)
 	if err != nil {
-		log.Error().Err(err).Msg("")
+		logger.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
+	recordVNetNsEvent(vNetKey, model.NsEventCreated, fmt.Sprintf("vNet is created (%s, connection: %s)", vNetInfo.CidrBlock, vNetInfo.ConnectionName))
 
 	return vNetInfo, nil
 }
@@ -668,7 +1076,7 @@ func GetVNet(nsId string, vNetId string) (model.TbVNetInfo, error) {
 	}
 
 	if keyValue == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -714,6 +1122,7 @@ func GetVNet(nsId string, vNetId string) (model.TbVNetInfo, error) {
 	vNetInfo.CspResourceId = spResp.IId.SystemId
 	vNetInfo.CspResourceName = spResp.IId.NameId
 	vNetInfo.CidrBlock = spResp.IPv4_CIDR
+	vNetInfo.IPv6CidrBlock = common.NVL(spResp.IPv6_CIDR, vNetInfo.IPv6CidrBlock)
 	vNetInfo.KeyValueList = spResp.KeyValueList
 	// todo: restore the tag list later
 	// vNetInfo.TagList = spResp.TagList
@@ -780,7 +1189,11 @@ func DeleteVNet(nsId string, vNetId string, actionParam string) (model.SimpleMsg
 
 	// normal case: action == ""
 	if action == ActionNone && len(subnetsKv) > 0 {
-		err := fmt.Errorf("the vNet (%s) is in-use, may have subnets", vNetId)
+		subnetIds := make([]string, 0, len(subnetsKv))
+		for _, kv := range subnetsKv {
+			subnetIds = append(subnetIds, kv.Key[strings.LastIndex(kv.Key, "/")+1:])
+		}
+		err := common.NewResourceInUseError(model.StrVNet, vNetId, subnetIds)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -824,7 +1237,7 @@ func DeleteVNet(nsId string, vNetId string, actionParam string) (model.SimpleMsg
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -850,6 +1263,7 @@ func DeleteVNet(nsId string, vNetId string, actionParam string) (model.SimpleMsg
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
+	emitVNetStatusEvent(vNetKey, vNetInfo.Status)
 
 	// [Via Spider] Delete the vNet
 	spReqt := spiderVpcDeleteReq{}
@@ -912,6 +1326,8 @@ func DeleteVNet(nsId string, vNetId string, actionParam string) (model.SimpleMsg
 		return emptyRet, err
 	}
 
+	recordVNetNsEvent(vNetKey, model.NsEventDeleted, "vNet is deleted")
+
 	// [Output] the message
 	ret.Message = fmt.Sprintf("the vNet (%s) has been deleted", vNetId)
 
@@ -963,7 +1379,7 @@ func RefineVNet(nsId string, vNetId string) (model.SimpleMsg, error) {
 	}
 
 	if keyValue == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1103,7 +1519,7 @@ func RefineVNet(nsId string, vNetId string) (model.SimpleMsg, error) {
 }
 
 // RegisterVNet accepts vNet registration request, register and returns an TB vNet object
-func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.TbVNetInfo, error) {
+func RegisterVNet(ctx context.Context, nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.TbVNetInfo, error) {
 	log.Info().Msg("RegisterVNet")
 
 	// vNet objects
@@ -1149,7 +1565,7 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 	// Check if the vNet already exists or not
 	exists, err := CheckResource(nsId, resourceType, vNetRegisterReq.Name)
 	if exists {
-		err := fmt.Errorf("already exists, vNet: %s", vNetRegisterReq.Name)
+		err := common.NewConflictError("The vNet %s already exists.", vNetRegisterReq.Name)
 		return emptyRet, err
 	}
 	if err != nil {
@@ -1173,6 +1589,7 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 	if err != nil {
 		return emptyRet, err
 	}
+	emitVNetStatusEvent(vNetKey, vNetInfo.Status)
 
 	// [Via Spider] Register vNet and subnets
 	var spReqt = spiderVPCRegisterRequest{}
@@ -1250,6 +1667,7 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 	vNetInfo.CspResourceId = spResp.IId.SystemId
 	vNetInfo.CspResourceName = spResp.IId.NameId
 	vNetInfo.CidrBlock = spResp.IPv4_CIDR
+	vNetInfo.IPv6CidrBlock = spResp.IPv6_CIDR
 	vNetInfo.KeyValueList = spResp.KeyValueList
 	// todo: restore the tag list later
 	// vNetInfo.TagList = spResp.TagList
@@ -1259,6 +1677,7 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 	} else if vNetRegisterReq.CspResourceId == "" {
 		vNetInfo.SystemLabel = "Registered from CB-Spider resource"
 	}
+	vNetInfo.Provenance = common.NewProvenance(ctx, model.CreatedViaRegistered)
 
 	// Note: Check one by one and update the vNet object with the response from the Spider
 	//       since the order may differ different between slices
@@ -1276,23 +1695,41 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 			KeyValueList:    spSubnetInfo.KeyValueList,
 			Zone:            spSubnetInfo.Zone,
 			IPv4_CIDR:       spSubnetInfo.IPv4_CIDR,
+			IPv6_CIDR:       spSubnetInfo.IPv6_CIDR,
 			// todo: restore the tag list later
 			// TagList:        spSubnetInfo.TagList,
 		}
 		vNetInfo.SubnetInfoList = append(vNetInfo.SubnetInfoList, subnetInfo)
+	}
 
-		// Set a subnetKey for the subnet object
+	log.Debug().Msgf("vNetInfo: %+v", vNetInfo)
+
+	// [Set and store status]
+	vNetInfo.Status = string(NetworkAvailable)
+	// Put the vNet object and its subnet objects into the key-value store atomically
+	value, err := json.Marshal(vNetInfo)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	kvs := []kvstore.KeyValue{{Key: vNetKey, Value: string(value)}}
+	for _, subnetInfo := range vNetInfo.SubnetInfoList {
 		subnetKey := common.GenChildResourceKey(nsId, childResourceType, vNetInfo.Id, subnetInfo.Id)
-		// Save the subnet object
 		value, err := json.Marshal(subnetInfo)
 		if err != nil {
 			return emptyRet, err
 		}
-		err = kvstore.Put(subnetKey, string(value))
-		if err != nil {
-			log.Error().Err(err).Msg("")
-			return emptyRet, err
-		}
+		kvs = append(kvs, kvstore.KeyValue{Key: subnetKey, Value: string(value)})
+	}
+	err = kvstore.PutMulti(kvs)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return emptyRet, err
+	}
+	emitVNetStatusEvent(vNetKey, vNetInfo.Status)
+
+	for _, subnetInfo := range vNetInfo.SubnetInfoList {
+		subnetKey := common.GenChildResourceKey(nsId, childResourceType, vNetInfo.Id, subnetInfo.Id)
 
 		// Store label info using CreateOrUpdateLabel
 		labels := map[string]string{
@@ -1311,6 +1748,7 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 			model.LabelVNetId:          vNetInfo.Id,
 			model.LabelConnectionName:  vNetInfo.ConnectionName,
 		}
+		common.SetSystemLabels(labels, vNetInfo.ConnectionName)
 		err = label.CreateOrUpdateLabel(model.StrSubnet, subnetInfo.Uid, subnetKey, labels)
 		if err != nil {
 			log.Error().Err(err).Msg("")
@@ -1319,27 +1757,11 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 
 	}
 
-	log.Debug().Msgf("vNetInfo: %+v", vNetInfo)
-
-	// [Set and store status]
-	vNetInfo.Status = string(NetworkAvailable)
-	// Put vNet object into the key-value store
-	value, err := json.Marshal(vNetInfo)
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return emptyRet, err
-	}
-	err = kvstore.Put(vNetKey, string(value))
-	if err != nil {
-		log.Error().Err(err).Msg("")
-		return emptyRet, err
-	}
-
 	// Check if the vNet info is stored
 	keyValue, err := kvstore.GetKv(vNetKey)
 
 	if keyValue == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetRegisterReq.Name)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetRegisterReq.Name)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1369,12 +1791,20 @@ func RegisterVNet(nsId string, vNetRegisterReq *model.TbRegisterVNetReq) (model.
 		model.LabelDescription:     vNetInfo.Description,
 		model.LabelConnectionName:  vNetInfo.ConnectionName,
 	}
+	common.SetSystemLabels(labels, vNetInfo.ConnectionName)
+	common.SetProvenanceLabels(labels, vNetInfo.Provenance)
 	err = label.CreateOrUpdateLabel(model.StrVNet, vNetInfo.Uid, vNetKey, labels)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
 
+	// Link the registered SGs which have been waiting for the vNet
+	err = linkRegisteredSecurityGroups(nsId, vNetInfo)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to link the registered securityGroups to the vNet")
+	}
+
 	return vNetInfo, nil
 }
 
@@ -1426,7 +1856,11 @@ func DeregisterVNet(nsId string, vNetId string, withSubnets string) (model.Simpl
 	log.Debug().Msgf("subnetsKv: %+v", subnetsKv)
 
 	if withSubnets == "false" && len(subnetsKv) > 0 {
-		err := fmt.Errorf("the vNet (%s) is in-use, may have subnets", vNetId)
+		subnetIds := make([]string, 0, len(subnetsKv))
+		for _, kv := range subnetsKv {
+			subnetIds = append(subnetIds, kv.Key[strings.LastIndex(kv.Key, "/")+1:])
+		}
+		err := common.NewResourceInUseError(model.StrVNet, vNetId, subnetIds)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1457,7 +1891,7 @@ func DeregisterVNet(nsId string, vNetId string, withSubnets string) (model.Simpl
 		return emptyRet, err
 	}
 	if vNetKv == (kvstore.KeyValue{}) {
-		err := fmt.Errorf("does not exist, vNet: %s", vNetId)
+		err := common.NewResourceNotFoundError(model.StrVNet, vNetId)
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
@@ -1483,6 +1917,7 @@ func DeregisterVNet(nsId string, vNetId string, withSubnets string) (model.Simpl
 		log.Error().Err(err).Msg("")
 		return emptyRet, err
 	}
+	emitVNetStatusEvent(vNetKey, vNetInfo.Status)
 
 	// [Via Spider] Deregister the vNet
 	spReqt := spiderConnectionRequest{}
@@ -0,0 +1,658 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// Buckets of the embedded store
+var (
+	// bucketData keeps the values by key
+	bucketData = []byte("kv")
+	// bucketMeta keeps the revisions of each key (create revision, mod revision and version)
+	bucketMeta = []byte("meta")
+	// bucketSys keeps the current revision of the store
+	bucketSys   = []byte("sys")
+	keyRevision = []byte("revision")
+)
+
+// BoltStore represents an embedded key-value store on a BoltDB file for single-node deployments.
+// It keeps the semantics of EtcdStore (keys sorted in byte order, revisions of keys, transactions and watches)
+// except sessions and locks of etcd.
+type BoltStore struct {
+	db  *bbolt.DB
+	ctx context.Context
+
+	watchLock sync.Mutex
+	watchers  map[*watcher]struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Config holds the configuration for BoltStore.
+type Config struct {
+	// Path is the path of the BoltDB file (created if missing)
+	Path string
+	// OpenTimeout is the timeout to get the file lock (the file can be opened by only one process)
+	OpenTimeout time.Duration
+}
+
+// keyMeta is the revisions of a key (same as etcd)
+type keyMeta struct {
+	createRevision int64
+	modRevision    int64
+	version        int64
+}
+
+func (m keyMeta) encode() []byte {
+	b := make([]byte, 24)
+	binary.BigEndian.PutUint64(b[0:8], uint64(m.createRevision))
+	binary.BigEndian.PutUint64(b[8:16], uint64(m.modRevision))
+	binary.BigEndian.PutUint64(b[16:24], uint64(m.version))
+	return b
+}
+
+func decodeKeyMeta(b []byte) keyMeta {
+	if len(b) < 24 {
+		return keyMeta{}
+	}
+	return keyMeta{
+		createRevision: int64(binary.BigEndian.Uint64(b[0:8])),
+		modRevision:    int64(binary.BigEndian.Uint64(b[8:16])),
+		version:        int64(binary.BigEndian.Uint64(b[16:24])),
+	}
+}
+
+// NewBoltStore creates a new instance of BoltStore on the file of the config.
+func NewBoltStore(ctx context.Context, config Config) (kvstore.Store, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("path of the embedded store is not provided")
+	}
+	if config.OpenTimeout == 0 {
+		config.OpenTimeout = 5 * time.Second
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the embedded store: %w", err)
+	}
+
+	db, err := bbolt.Open(config.Path, 0600, &bbolt.Options{Timeout: config.OpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the embedded store (%s): %w", config.Path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{bucketData, bucketMeta, bucketSys} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize the embedded store: %w", err)
+	}
+
+	return &BoltStore{db: db, ctx: ctx, watchers: map[*watcher]struct{}{}, closed: make(chan struct{})}, nil
+}
+
+// NewSession is not supported by the embedded store (sessions are tied to etcd).
+func (s *BoltStore) NewSession(ctx context.Context) (*concurrency.Session, error) {
+	return nil, fmt.Errorf("sessions are not supported by the embedded store")
+}
+
+// NewLock is not supported by the embedded store (locks are tied to etcd sessions).
+func (s *BoltStore) NewLock(ctx context.Context, session *concurrency.Session, lockKey string) (*concurrency.Mutex, error) {
+	return nil, fmt.Errorf("locks are not supported by the embedded store")
+}
+
+// Put stores a key-value pair in the store.
+func (s *BoltStore) Put(key, value string) error {
+	return s.PutWith(s.ctx, key, value)
+}
+
+// PutWith stores a key-value pair in the store using the provided context.
+func (s *BoltStore) PutWith(ctx context.Context, key, value string) error {
+	if _, err := s.TxnWith(ctx, nil, []kvstore.KeyValue{{Key: key, Value: value}}, nil); err != nil {
+		return fmt.Errorf("failed to put key-value: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves the value for a given key from the store.
+func (s *BoltStore) Get(key string) (string, error) {
+	return s.GetWith(s.ctx, key)
+}
+
+// GetWith retrieves the value for a given key from the store using the provided context.
+// It returns an empty string if the key does not exist.
+func (s *BoltStore) GetWith(ctx context.Context, key string) (string, error) {
+	kv, err := s.GetKvWith(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key: %w", err)
+	}
+	return kv.Value, nil
+}
+
+// GetList retrieves multiple values for keys with the given keyPrefix from the store.
+func (s *BoltStore) GetList(keyPrefix string) ([]string, error) {
+	return s.GetListWith(s.ctx, keyPrefix)
+}
+
+// GetListWith retrieves multiple values for keys with the given keyPrefix (ascending by key) using the provided context.
+func (s *BoltStore) GetListWith(ctx context.Context, keyPrefix string) ([]string, error) {
+	kvs, err := s.GetKvListWith(ctx, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	values := []string{}
+	for _, kv := range kvs {
+		values = append(values, kv.Value)
+	}
+	return values, nil
+}
+
+// GetKv retrieves a key-value pair from the store.
+func (s *BoltStore) GetKv(key string) (kvstore.KeyValue, error) {
+	return s.GetKvWith(s.ctx, key)
+}
+
+// GetKvWith retrieves a key-value pair from the store using the provided context.
+// It returns an empty key-value pair if the key does not exist.
+func (s *BoltStore) GetKvWith(ctx context.Context, key string) (kvstore.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return kvstore.KeyValue{}, err
+	}
+	keyValue := kvstore.KeyValue{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if key == "" || tx.Bucket(bucketMeta).Get([]byte(key)) == nil {
+			return nil
+		}
+		keyValue = kvstore.KeyValue{Key: key, Value: string(tx.Bucket(bucketData).Get([]byte(key)))}
+		return nil
+	})
+	if err != nil {
+		return kvstore.KeyValue{}, fmt.Errorf("failed to get key: %w", err)
+	}
+	return keyValue, nil
+}
+
+// scanPrefix calls fn for the keys with the given keyPrefix from startKey (inclusive) in ascending order until fn returns false
+func scanPrefix(tx *bbolt.Tx, keyPrefix string, startKey string, fn func(key []byte, value []byte, meta keyMeta) bool) {
+	if startKey < keyPrefix {
+		startKey = keyPrefix
+	}
+	prefix := []byte(keyPrefix)
+	data := tx.Bucket(bucketData)
+	c := tx.Bucket(bucketMeta).Cursor()
+	for k, m := c.Seek([]byte(startKey)); k != nil && bytes.HasPrefix(k, prefix); k, m = c.Next() {
+		if !fn(k, data.Get(k), decodeKeyMeta(m)) {
+			return
+		}
+	}
+}
+
+// GetKvList retrieves multiple key-value pairs with the given keyPrefix from the store.
+func (s *BoltStore) GetKvList(keyPrefix string) ([]kvstore.KeyValue, error) {
+	return s.GetKvListWith(s.ctx, keyPrefix)
+}
+
+// GetKvListWith retrieves multiple key-value pairs with the given keyPrefix (ascending by key) using the provided context.
+func (s *BoltStore) GetKvListWith(ctx context.Context, keyPrefix string) ([]kvstore.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	kvs := []kvstore.KeyValue{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		scanPrefix(tx, keyPrefix, "", func(key []byte, value []byte, _ keyMeta) bool {
+			kvs = append(kvs, kvstore.KeyValue{Key: string(key), Value: string(value)})
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list with keyPrefix: %w", err)
+	}
+	return kvs, nil
+}
+
+// GetSortedKvList retrieves multiple key-value pairs with the given keyPrefix, sortBy, and order from the store.
+func (s *BoltStore) GetSortedKvList(keyPrefix string, sortBy clientv3.SortTarget, order clientv3.SortOrder) ([]kvstore.KeyValue, error) {
+	return s.GetSortedKvListWith(s.ctx, keyPrefix, sortBy, order)
+}
+
+// GetSortedKvListWith retrieves multiple key-value pairs with the given keyPrefix, sortBy, and order using the provided context.
+// The order is ascending if it is not specified (same as etcd).
+func (s *BoltStore) GetSortedKvListWith(ctx context.Context, keyPrefix string, sortBy clientv3.SortTarget, order clientv3.SortOrder) ([]kvstore.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type entry struct {
+		kv   kvstore.KeyValue
+		meta keyMeta
+	}
+	entries := []entry{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		scanPrefix(tx, keyPrefix, "", func(key []byte, value []byte, meta keyMeta) bool {
+			entries = append(entries, entry{kv: kvstore.KeyValue{Key: string(key), Value: string(value)}, meta: meta})
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list with keyPrefix: %w", err)
+	}
+
+	less := func(a, b entry) bool {
+		switch sortBy {
+		case clientv3.SortByVersion:
+			return a.meta.version < b.meta.version
+		case clientv3.SortByCreateRevision:
+			return a.meta.createRevision < b.meta.createRevision
+		case clientv3.SortByModRevision:
+			return a.meta.modRevision < b.meta.modRevision
+		case clientv3.SortByValue:
+			return a.kv.Value < b.kv.Value
+		default:
+			return a.kv.Key < b.kv.Key
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if order == clientv3.SortDescend {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+
+	kvs := []kvstore.KeyValue{}
+	for _, e := range entries {
+		kvs = append(kvs, e.kv)
+	}
+	return kvs, nil
+}
+
+// GetKvMap retrieves multiple key-value pairs with the given keyPrefix from the store.
+func (s *BoltStore) GetKvMap(keyPrefix string) (kvstore.KeyValueMap, error) {
+	return s.GetKvMapWith(s.ctx, keyPrefix)
+}
+
+// GetKvMapWith retrieves multiple key-value pairs with the given keyPrefix from the store using the provided context.
+func (s *BoltStore) GetKvMapWith(ctx context.Context, keyPrefix string) (kvstore.KeyValueMap, error) {
+	kvList, err := s.GetKvListWith(ctx, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	kvs := kvstore.KeyValueMap{}
+	for _, kv := range kvList {
+		kvs[kv.Key] = kv.Value
+	}
+	return kvs, nil
+}
+
+// GetRevision returns the create and mod revisions of the key (0, 0 if the key does not exist).
+func (s *BoltStore) GetRevision(key string) (int64, int64, error) {
+	return s.GetRevisionWith(s.ctx, key)
+}
+
+// GetRevisionWith returns the create and mod revisions of the key using the provided context.
+func (s *BoltStore) GetRevisionWith(ctx context.Context, key string) (int64, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	meta := keyMeta{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if key != "" {
+			meta = decodeKeyMeta(tx.Bucket(bucketMeta).Get([]byte(key)))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get revision: %w", err)
+	}
+	return meta.createRevision, meta.modRevision, nil
+}
+
+// GetKvRevision retrieves the key-value pair and its mod revision in one read (0 if the key does not exist).
+func (s *BoltStore) GetKvRevision(key string) (kvstore.KeyValue, int64, error) {
+	return s.GetKvRevisionWith(s.ctx, key)
+}
+
+// GetKvRevisionWith retrieves the key-value pair and its mod revision in one read using the provided context.
+func (s *BoltStore) GetKvRevisionWith(ctx context.Context, key string) (kvstore.KeyValue, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return kvstore.KeyValue{}, 0, err
+	}
+	keyValue := kvstore.KeyValue{}
+	var modRevision int64
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if key == "" {
+			return nil
+		}
+		rawMeta := tx.Bucket(bucketMeta).Get([]byte(key))
+		if rawMeta == nil {
+			return nil
+		}
+		modRevision = decodeKeyMeta(rawMeta).modRevision
+		keyValue = kvstore.KeyValue{Key: key, Value: string(tx.Bucket(bucketData).Get([]byte(key)))}
+		return nil
+	})
+	if err != nil {
+		return kvstore.KeyValue{}, 0, fmt.Errorf("failed to get key: %w", err)
+	}
+	return keyValue, modRevision, nil
+}
+
+// PutMulti stores the key-value pairs in the store atomically.
+func (s *BoltStore) PutMulti(kvs []kvstore.KeyValue) error {
+	return s.PutMultiWith(s.ctx, kvs)
+}
+
+// PutMultiWith stores the key-value pairs in the store atomically using the provided context.
+func (s *BoltStore) PutMultiWith(ctx context.Context, kvs []kvstore.KeyValue) error {
+	_, err := s.TxnWith(ctx, nil, kvs, nil)
+	return err
+}
+
+// Txn stores and deletes the keys in the store atomically if all compares hold.
+func (s *BoltStore) Txn(compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, error) {
+	return s.TxnWith(s.ctx, compares, puts, deletes)
+}
+
+// TxnWith stores and deletes the keys in the store atomically if all compares hold using the provided context.
+// All changes of a transaction have the same revision (same as etcd).
+func (s *BoltStore) TxnWith(ctx context.Context, compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, error) {
+	succeeded, _, err := s.TxnRevisionWith(ctx, compares, puts, deletes)
+	return succeeded, err
+}
+
+// TxnRevision is Txn which also returns the revision of the transaction.
+func (s *BoltStore) TxnRevision(compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, int64, error) {
+	return s.TxnRevisionWith(s.ctx, compares, puts, deletes)
+}
+
+// TxnRevisionWith is TxnWith which also returns the revision of the transaction (0 if it did not change any key).
+func (s *BoltStore) TxnRevisionWith(ctx context.Context, compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return false, 0, err
+	}
+	for _, kv := range puts {
+		if kv.Key == "" {
+			return false, 0, fmt.Errorf("key is not provided")
+		}
+	}
+
+	succeeded := false
+	events := []*clientv3.Event{}
+	var revision int64
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketData)
+		metas := tx.Bucket(bucketMeta)
+		sys := tx.Bucket(bucketSys)
+
+		for _, c := range compares {
+			meta := decodeKeyMeta(metas.Get([]byte(c.Key)))
+			actual := meta.modRevision
+			if c.Create {
+				actual = meta.createRevision
+			}
+			if actual != c.Revision {
+				return nil
+			}
+		}
+		succeeded = true
+
+		revision = int64(0)
+		if b := sys.Get(keyRevision); len(b) == 8 {
+			revision = int64(binary.BigEndian.Uint64(b))
+		}
+		revision++
+
+		for _, kv := range puts {
+			key := []byte(kv.Key)
+			meta := decodeKeyMeta(metas.Get(key))
+			if meta.createRevision == 0 {
+				meta.createRevision = revision
+			}
+			meta.modRevision = revision
+			meta.version++
+			if err := data.Put(key, []byte(kv.Value)); err != nil {
+				return err
+			}
+			if err := metas.Put(key, meta.encode()); err != nil {
+				return err
+			}
+			events = append(events, &clientv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{
+				Key: key, Value: []byte(kv.Value), CreateRevision: meta.createRevision, ModRevision: meta.modRevision, Version: meta.version,
+			}})
+		}
+		for _, k := range deletes {
+			key := []byte(k)
+			if k == "" || metas.Get(key) == nil {
+				continue
+			}
+			if err := data.Delete(key); err != nil {
+				return err
+			}
+			if err := metas.Delete(key); err != nil {
+				return err
+			}
+			events = append(events, &clientv3.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: key, ModRevision: revision}})
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(revision))
+		return sys.Put(keyRevision, b)
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if len(events) == 0 {
+		return succeeded, 0, nil
+	}
+	s.notifyWatchers(revision, events)
+	return succeeded, revision, nil
+}
+
+// GetKvListPage retrieves a page of key-value pairs with the given keyPrefix from startKey (inclusive) from the store.
+func (s *BoltStore) GetKvListPage(keyPrefix string, startKey string, limit int64, keysOnly bool) ([]kvstore.KeyValue, string, error) {
+	return s.GetKvListPageWith(s.ctx, keyPrefix, startKey, limit, keysOnly)
+}
+
+// GetKvListPageWith retrieves a page of key-value pairs with the given keyPrefix from startKey (inclusive) using the provided context.
+// It returns the start key of the next page ("" if no more pairs). A limit of 0 means no limit (same as etcd).
+func (s *BoltStore) GetKvListPageWith(ctx context.Context, keyPrefix string, startKey string, limit int64, keysOnly bool) ([]kvstore.KeyValue, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	kvs := []kvstore.KeyValue{}
+	nextKey := ""
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		scanPrefix(tx, keyPrefix, startKey, func(key []byte, value []byte, _ keyMeta) bool {
+			if limit > 0 && int64(len(kvs)) == limit {
+				// the smallest key after the last key
+				nextKey = kvs[len(kvs)-1].Key + "\x00"
+				return false
+			}
+			kv := kvstore.KeyValue{Key: string(key)}
+			if !keysOnly {
+				kv.Value = string(value)
+			}
+			kvs = append(kvs, kv)
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get list with keyPrefix: %w", err)
+	}
+	return kvs, nextKey, nil
+}
+
+// GetStats returns the number of keys and the total size (bytes of keys and values) with the given keyPrefix in the store.
+func (s *BoltStore) GetStats(keyPrefix string) (int64, int64, error) {
+	return s.GetStatsWith(s.ctx, keyPrefix)
+}
+
+// GetStatsWith returns the number of keys and the total size with the given keyPrefix using the provided context.
+func (s *BoltStore) GetStatsWith(ctx context.Context, keyPrefix string) (int64, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	var count, size int64
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		scanPrefix(tx, keyPrefix, "", func(key []byte, value []byte, _ keyMeta) bool {
+			count++
+			size += int64(len(key) + len(value))
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get stats with keyPrefix: %w", err)
+	}
+	return count, size, nil
+}
+
+// Delete removes a key-value pair from the store.
+func (s *BoltStore) Delete(key string) error {
+	return s.DeleteWith(s.ctx, key)
+}
+
+// DeleteWith removes a key-value pair from the store using the provided context.
+func (s *BoltStore) DeleteWith(ctx context.Context, key string) error {
+	if _, err := s.TxnWith(ctx, nil, nil, []string{key}); err != nil {
+		return fmt.Errorf("failed to delete key: %w", err)
+	}
+	return nil
+}
+
+// watcher delivers the events on a key (or keys with a prefix) to a watch channel in order
+type watcher struct {
+	key    string
+	prefix bool
+	ch     chan clientv3.WatchResponse
+
+	lock   sync.Mutex
+	queue  []clientv3.WatchResponse
+	notify chan struct{}
+}
+
+// matches returns true if the key is watched by the watcher
+func (w *watcher) matches(key string) bool {
+	if w.prefix {
+		return strings.HasPrefix(key, w.key)
+	}
+	return key == w.key
+}
+
+// run sends the queued responses until the context is done or the store is closed, then closes the channel
+func (w *watcher) run(ctx context.Context, closed <-chan struct{}, unregister func()) {
+	defer close(w.ch)
+	defer unregister()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		case <-w.notify:
+		}
+		w.lock.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.lock.Unlock()
+		for _, resp := range queue {
+			select {
+			case w.ch <- resp:
+			case <-ctx.Done():
+				return
+			case <-closed:
+				return
+			}
+		}
+	}
+}
+
+// watch registers a watcher on the key (or the keyPrefix)
+func (s *BoltStore) watch(ctx context.Context, key string, prefix bool) clientv3.WatchChan {
+	w := &watcher{key: key, prefix: prefix, ch: make(chan clientv3.WatchResponse), notify: make(chan struct{}, 1)}
+	s.watchLock.Lock()
+	s.watchers[w] = struct{}{}
+	s.watchLock.Unlock()
+	go w.run(ctx, s.closed, func() {
+		s.watchLock.Lock()
+		delete(s.watchers, w)
+		s.watchLock.Unlock()
+	})
+	return w.ch
+}
+
+// notifyWatchers queues the events of a revision to the watchers of their keys
+func (s *BoltStore) notifyWatchers(revision int64, events []*clientv3.Event) {
+	s.watchLock.Lock()
+	defer s.watchLock.Unlock()
+	for w := range s.watchers {
+		matched := []*clientv3.Event{}
+		for _, event := range events {
+			if w.matches(string(event.Kv.Key)) {
+				matched = append(matched, event)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		w.lock.Lock()
+		w.queue = append(w.queue, clientv3.WatchResponse{Header: pb.ResponseHeader{Revision: revision}, Events: matched})
+		w.lock.Unlock()
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchKey watches for changes on the given key.
+func (s *BoltStore) WatchKey(key string) clientv3.WatchChan {
+	return s.WatchKeyWith(s.ctx, key)
+}
+
+// WatchKeyWith watches for changes on the given key using the provided context.
+func (s *BoltStore) WatchKeyWith(ctx context.Context, key string) clientv3.WatchChan {
+	return s.watch(ctx, key, false)
+}
+
+// WatchKeys watches for changes on keys with the given keyPrefix.
+func (s *BoltStore) WatchKeys(keyPrefix string) clientv3.WatchChan {
+	return s.WatchKeysWith(s.ctx, keyPrefix)
+}
+
+// WatchKeysWith watches for changes on keys with the given keyPrefix using the provided context.
+func (s *BoltStore) WatchKeysWith(ctx context.Context, keyPrefix string) clientv3.WatchChan {
+	return s.watch(ctx, keyPrefix, true)
+}
+
+// Close closes the watch channels and the BoltDB file.
+func (s *BoltStore) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	return s.db.Close()
+}
@@ -0,0 +1,316 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// newTestStore opens an embedded store on a temporary file, closed at the end of the test
+func newTestStore(t *testing.T) kvstore.Store {
+	t.Helper()
+	store, err := NewBoltStore(context.Background(), Config{Path: filepath.Join(t.TempDir(), "kvstore.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestBoltStoreGetKvListOrdered(t *testing.T) {
+	store := newTestStore(t)
+	// stored out of order, listed in byte order of the keys
+	for _, key := range []string{"/ns/ns01/b", "/ns/ns01/a/2", "/ns/ns010/a", "/ns/ns01/a/10", "/ns/ns00/a"} {
+		if err := store.Put(key, "v"+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kvs, err := store.GetKvList("/ns/ns01/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key)
+		if kv.Value != "v"+kv.Key {
+			t.Errorf("value of %s: got %q", kv.Key, kv.Value)
+		}
+	}
+	want := []string{"/ns/ns01/a/10", "/ns/ns01/a/2", "/ns/ns01/b"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+
+	values, err := store.GetList("/ns/ns01/a/")
+	if err != nil || !reflect.DeepEqual(values, []string{"v/ns/ns01/a/10", "v/ns/ns01/a/2"}) {
+		t.Errorf("GetList: got %v (%v)", values, err)
+	}
+	if kvs, err := store.GetKvList("/ns/none/"); err != nil || kvs == nil || len(kvs) != 0 {
+		t.Errorf("GetKvList of no keys: got %#v (%v), want an empty list", kvs, err)
+	}
+}
+
+func TestBoltStoreGetKvListPage(t *testing.T) {
+	store := newTestStore(t)
+	all := []string{"/p/a", "/p/b", "/p/c", "/p/d", "/p/e"}
+	for _, key := range all {
+		if err := store.Put(key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put("/q/a", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		limit int64
+		pages [][]string
+	}{
+		{"limit 2", 2, [][]string{{"/p/a", "/p/b"}, {"/p/c", "/p/d"}, {"/p/e"}}},
+		{"limit of all keys", 5, [][]string{all}},
+		{"no limit", 0, [][]string{all}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := [][]string{}
+			startKey := ""
+			for {
+				kvs, nextKey, err := store.GetKvListPage("/p/", startKey, tt.limit, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				page := []string{}
+				for _, kv := range kvs {
+					if kv.Value != "" {
+						t.Errorf("keysOnly: got value %q of %s", kv.Value, kv.Key)
+					}
+					page = append(page, kv.Key)
+				}
+				pages = append(pages, page)
+				if nextKey == "" {
+					break
+				}
+				if len(pages) > len(all) {
+					t.Fatalf("no end of pages: %v", pages)
+				}
+				startKey = nextKey
+			}
+			if !reflect.DeepEqual(pages, tt.pages) {
+				t.Errorf("got %v, want %v", pages, tt.pages)
+			}
+		})
+	}
+
+	kvs, _, err := store.GetKvListPage("/p/", "/p/d", 0, false)
+	if err != nil || len(kvs) != 2 || kvs[0].Key != "/p/d" || kvs[0].Value != "value" {
+		t.Errorf("page from /p/d: got %v (%v)", kvs, err)
+	}
+}
+
+func TestBoltStoreGetMissingAndEmptyValue(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.Put("/empty", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// both have an empty value, but only the stored key is returned with its key and revision
+	kv, err := store.GetKv("/missing")
+	if err != nil || kv != (kvstore.KeyValue{}) {
+		t.Errorf("GetKv of a missing key: got %+v (%v), want an empty key-value", kv, err)
+	}
+	kv, err = store.GetKv("/empty")
+	if err != nil || kv != (kvstore.KeyValue{Key: "/empty"}) {
+		t.Errorf("GetKv of an empty value: got %+v (%v)", kv, err)
+	}
+	if value, err := store.Get("/missing"); err != nil || value != "" {
+		t.Errorf("Get of a missing key: got %q (%v)", value, err)
+	}
+
+	if _, modRevision, _ := store.GetKvRevision("/missing"); modRevision != 0 {
+		t.Errorf("revision of a missing key: got %d, want 0", modRevision)
+	}
+	if _, modRevision, _ := store.GetKvRevision("/empty"); modRevision == 0 {
+		t.Errorf("revision of an empty value: got 0")
+	}
+	if kvs, _ := store.GetKvList("/empty"); len(kvs) != 1 {
+		t.Errorf("GetKvList of an empty value: got %v", kvs)
+	}
+}
+
+func TestBoltStoreTxnCompare(t *testing.T) {
+	store := newTestStore(t)
+	succeeded, err := store.Txn([]kvstore.TxnCompare{{Key: "/k", Revision: 0}}, []kvstore.KeyValue{{Key: "/k", Value: "v1"}}, nil)
+	if err != nil || !succeeded {
+		t.Fatalf("create if missing: %v (%v)", succeeded, err)
+	}
+
+	tests := []struct {
+		name      string
+		compare   func(createRevision, modRevision int64) kvstore.TxnCompare
+		succeeded bool
+	}{
+		{"mod revision", func(c, m int64) kvstore.TxnCompare { return kvstore.TxnCompare{Key: "/k", Revision: m} }, true},
+		{"stale mod revision", func(c, m int64) kvstore.TxnCompare { return kvstore.TxnCompare{Key: "/k", Revision: m - 1} }, false},
+		{"create revision", func(c, m int64) kvstore.TxnCompare { return kvstore.TxnCompare{Key: "/k", Revision: c, Create: true} }, true},
+		{"wrong create revision", func(c, m int64) kvstore.TxnCompare { return kvstore.TxnCompare{Key: "/k", Revision: m, Create: true} }, false},
+		{"missing on an existing key", func(c, m int64) kvstore.TxnCompare { return kvstore.TxnCompare{Key: "/k"} }, false},
+		{"missing key", func(c, m int64) kvstore.TxnCompare { return kvstore.TxnCompare{Key: "/none"} }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the mod revision is moved away from the create revision
+			if err := store.Put("/k", "v2"); err != nil {
+				t.Fatal(err)
+			}
+			createRevision, modRevision, err := store.GetRevision("/k")
+			if err != nil || createRevision == 0 || modRevision <= createRevision {
+				t.Fatalf("revisions: %d, %d (%v)", createRevision, modRevision, err)
+			}
+			compare := tt.compare(createRevision, modRevision)
+			puts := []kvstore.KeyValue{{Key: "/k", Value: "v3"}, {Key: "/other", Value: "v3"}}
+			succeeded, revision, err := store.TxnRevision([]kvstore.TxnCompare{compare}, puts, nil)
+			if err != nil || succeeded != tt.succeeded {
+				t.Fatalf("got %v (%v), want %v", succeeded, err, tt.succeeded)
+			}
+			if succeeded != (revision > modRevision) {
+				t.Errorf("revision of the transaction: got %d after %d", revision, modRevision)
+			}
+			// all changes of a transaction are applied (or not) together
+			wantValue := "v2"
+			if tt.succeeded {
+				wantValue = "v3"
+			}
+			if value, _ := store.Get("/k"); value != wantValue {
+				t.Errorf("value: got %q, want %q", value, wantValue)
+			}
+			if other, _ := store.Get("/other"); (other == "v3") != tt.succeeded {
+				t.Errorf("other key: got %q", other)
+			}
+			if err := store.Delete("/other"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	_, modRevision, _ := store.GetRevision("/k")
+	if succeeded, _ := store.Txn([]kvstore.TxnCompare{{Key: "/k", Revision: modRevision - 1}}, nil, []string{"/k"}); succeeded {
+		t.Errorf("delete with a stale revision succeeded")
+	}
+	if succeeded, _ := store.Txn([]kvstore.TxnCompare{{Key: "/k", Revision: modRevision}}, nil, []string{"/k"}); !succeeded {
+		t.Errorf("delete with the revision failed")
+	}
+	if kv, _ := store.GetKv("/k"); kv.Key != "" {
+		t.Errorf("not deleted: %+v", kv)
+	}
+}
+
+// watchEvent is the type and key of an event of a watch
+type watchEvent struct {
+	typ mvccpb.Event_EventType
+	key string
+}
+
+// receiveWatchEvents receives n events from the watch channel
+func receiveWatchEvents(t *testing.T, ch clientv3.WatchChan, n int) []watchEvent {
+	t.Helper()
+	events := []watchEvent{}
+	timeout := time.After(5 * time.Second)
+	for len(events) < n {
+		select {
+		case resp, ok := <-ch:
+			if !ok {
+				t.Fatalf("channel closed after %v", events)
+			}
+			for _, e := range resp.Events {
+				events = append(events, watchEvent{e.Type, string(e.Kv.Key)})
+			}
+		case <-timeout:
+			t.Fatalf("timeout after %v", events)
+		}
+	}
+	return events
+}
+
+func TestBoltStoreWatch(t *testing.T) {
+	store := newTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keysCh := store.WatchKeysWith(ctx, "/w/")
+	keyCh := store.WatchKeyWith(ctx, "/w/a")
+
+	if err := store.Put("/w/a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("/x/a", "ignored"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PutMulti([]kvstore.KeyValue{{Key: "/w/b", Value: "2"}, {Key: "/w/c", Value: "3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("/w/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []watchEvent{{mvccpb.PUT, "/w/a"}, {mvccpb.PUT, "/w/b"}, {mvccpb.PUT, "/w/c"}, {mvccpb.DELETE, "/w/a"}}
+	if got := receiveWatchEvents(t, keysCh, 4); !reflect.DeepEqual(got, want) {
+		t.Errorf("prefix watch: got %v, want %v", got, want)
+	}
+	want = []watchEvent{{mvccpb.PUT, "/w/a"}, {mvccpb.DELETE, "/w/a"}}
+	if got := receiveWatchEvents(t, keyCh, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("key watch: got %v, want %v", got, want)
+	}
+
+	// the channels are closed when the context is canceled, and later changes are not delivered
+	cancel()
+	for _, ch := range []clientv3.WatchChan{keysCh, keyCh} {
+		select {
+		case resp, ok := <-ch:
+			if ok {
+				t.Errorf("received %v after cancel", resp.Events)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+	if err := store.Put("/w/a", "after cancel"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBoltStoreCloseWatch(t *testing.T) {
+	store, err := NewBoltStore(context.Background(), Config{Path: filepath.Join(t.TempDir(), "kvstore.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := store.WatchKeys("/w/")
+	store.Close()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("received a response after close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed after close")
+	}
+}
+
+func TestBoltStoreSessionAndLockUnsupported(t *testing.T) {
+	store := newTestStore(t)
+	session, err := store.NewSession(context.Background())
+	if err == nil || session != nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("NewSession: got %v (%v), want a not supported error", session, err)
+	}
+	lock, err := store.NewLock(context.Background(), nil, "/lock")
+	if err == nil || lock != nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("NewLock: got %v (%v), want a not supported error", lock, err)
+	}
+}
@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/etcd"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// Types of the key-value store backends
+const (
+	// TypeEtcd is the etcd cluster (default)
+	TypeEtcd string = "etcd"
+	// TypeBolt is the embedded store on a BoltDB file for single-node deployments
+	TypeBolt string = "bolt"
+)
+
+// Config holds the configuration to create a key-value store of a backend type.
+type Config struct {
+	// Type is the backend type (etcd or bolt)
+	Type string
+
+	// etcd
+	EtcdEndpoints   []string
+	EtcdDialTimeout time.Duration
+	EtcdUsername    string
+	EtcdPassword    string
+
+	// bolt
+	BoltPath string
+}
+
+// NewStore creates a key-value store of the backend type in the config.
+func NewStore(ctx context.Context, config Config) (kvstore.Store, error) {
+	switch config.Type {
+	case TypeEtcd, "":
+		if len(config.EtcdEndpoints) == 0 {
+			return nil, fmt.Errorf("endpoints of etcd are not provided")
+		}
+		dialTimeout := config.EtcdDialTimeout
+		if dialTimeout == 0 {
+			dialTimeout = 5 * time.Second
+		}
+		return etcd.NewEtcdStore(ctx, etcd.Config{
+			Endpoints:   config.EtcdEndpoints,
+			DialTimeout: dialTimeout,
+			Username:    config.EtcdUsername,
+			Password:    config.EtcdPassword,
+		})
+	case TypeBolt:
+		return bolt.NewBoltStore(ctx, bolt.Config{Path: config.BoltPath})
+	default:
+		return nil, fmt.Errorf("unknown type of kvstore: %s (supported: %s, %s)", config.Type, TypeEtcd, TypeBolt)
+	}
+}
@@ -207,6 +207,170 @@ func (s *EtcdStore) GetKvMapWith(ctx context.Context, keyPrefix string) (kvstore
 	return kvs, nil
 }
 
+// GetRevision returns the create and mod revisions of the key in etcd (0, 0 if the key does not exist).
+func (s *EtcdStore) GetRevision(key string) (int64, int64, error) {
+	return s.GetRevisionWith(s.ctx, key)
+}
+
+// GetRevisionWith returns the create and mod revisions of the key in etcd using the provided context.
+func (s *EtcdStore) GetRevisionWith(ctx context.Context, key string) (int64, int64, error) {
+	resp, err := s.cli.Get(ctx, key, clientv3.WithKeysOnly())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get revision: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, 0, nil
+	}
+	return resp.Kvs[0].CreateRevision, resp.Kvs[0].ModRevision, nil
+}
+
+// GetKvRevision retrieves the key-value pair and its mod revision from etcd in one read (0 if the key does not exist).
+func (s *EtcdStore) GetKvRevision(key string) (kvstore.KeyValue, int64, error) {
+	return s.GetKvRevisionWith(s.ctx, key)
+}
+
+// GetKvRevisionWith retrieves the key-value pair and its mod revision from etcd in one read using the provided context.
+func (s *EtcdStore) GetKvRevisionWith(ctx context.Context, key string) (kvstore.KeyValue, int64, error) {
+	resp, err := s.cli.Get(ctx, key)
+	if err != nil {
+		return kvstore.KeyValue{}, 0, fmt.Errorf("failed to get key: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return kvstore.KeyValue{}, 0, nil
+	}
+	return kvstore.KeyValue{Key: string(resp.Kvs[0].Key), Value: string(resp.Kvs[0].Value)}, resp.Kvs[0].ModRevision, nil
+}
+
+// PutMulti stores the key-value pairs in etcd atomically.
+func (s *EtcdStore) PutMulti(kvs []kvstore.KeyValue) error {
+	return s.PutMultiWith(s.ctx, kvs)
+}
+
+// PutMultiWith stores the key-value pairs in etcd atomically using the provided context.
+func (s *EtcdStore) PutMultiWith(ctx context.Context, kvs []kvstore.KeyValue) error {
+	_, err := s.TxnWith(ctx, nil, kvs, nil)
+	return err
+}
+
+// Txn stores and deletes the keys in etcd atomically if all compares hold.
+func (s *EtcdStore) Txn(compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, error) {
+	return s.TxnWith(s.ctx, compares, puts, deletes)
+}
+
+// TxnWith stores and deletes the keys in etcd atomically if all compares hold using the provided context.
+// The number of operations is limited by etcd (--max-txn-ops, 128 by default).
+func (s *EtcdStore) TxnWith(ctx context.Context, compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, error) {
+	succeeded, _, err := s.TxnRevisionWith(ctx, compares, puts, deletes)
+	return succeeded, err
+}
+
+// TxnRevision is Txn which also returns the revision of the transaction.
+func (s *EtcdStore) TxnRevision(compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, int64, error) {
+	return s.TxnRevisionWith(s.ctx, compares, puts, deletes)
+}
+
+// TxnRevisionWith is TxnWith which also returns the revision of the transaction (0 if it did not change any key).
+func (s *EtcdStore) TxnRevisionWith(ctx context.Context, compares []kvstore.TxnCompare, puts []kvstore.KeyValue, deletes []string) (bool, int64, error) {
+	cmps := []clientv3.Cmp{}
+	for _, c := range compares {
+		if c.Create {
+			cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(c.Key), "=", c.Revision))
+		} else {
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(c.Key), "=", c.Revision))
+		}
+	}
+	ops := []clientv3.Op{}
+	for _, kv := range puts {
+		ops = append(ops, clientv3.OpPut(kv.Key, kv.Value))
+	}
+	for _, key := range deletes {
+		ops = append(ops, clientv3.OpDelete(key))
+	}
+
+	resp, err := s.cli.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if !resp.Succeeded || len(ops) == 0 {
+		return resp.Succeeded, 0, nil
+	}
+	return true, resp.Header.Revision, nil
+}
+
+// GetKvListPage retrieves a page of key-value pairs with the given keyPrefix from startKey (inclusive) from etcd.
+func (s *EtcdStore) GetKvListPage(keyPrefix string, startKey string, limit int64, keysOnly bool) ([]kvstore.KeyValue, string, error) {
+	return s.GetKvListPageWith(s.ctx, keyPrefix, startKey, limit, keysOnly)
+}
+
+// GetKvListPageWith retrieves a page of key-value pairs with the given keyPrefix from startKey (inclusive) from etcd using the provided context.
+// It returns the start key of the next page ("" if no more pairs).
+func (s *EtcdStore) GetKvListPageWith(ctx context.Context, keyPrefix string, startKey string, limit int64, keysOnly bool) ([]kvstore.KeyValue, string, error) {
+	if startKey < keyPrefix {
+		startKey = keyPrefix
+	}
+	if startKey == "" {
+		// etcd does not accept an empty key
+		startKey = "\x00"
+	}
+	opts := []clientv3.OpOption{
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(keyPrefix)),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+		clientv3.WithLimit(limit),
+	}
+	if keysOnly {
+		opts = append(opts, clientv3.WithKeysOnly())
+	}
+
+	resp, err := s.cli.Get(ctx, startKey, opts...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get list with keyPrefix: %w", err)
+	}
+
+	kvs := []kvstore.KeyValue{}
+	for _, kv := range resp.Kvs {
+		kvs = append(kvs, kvstore.KeyValue{Key: string(kv.Key), Value: string(kv.Value)})
+	}
+	nextKey := ""
+	if resp.More && len(resp.Kvs) > 0 {
+		// the smallest key after the last key
+		nextKey = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+	return kvs, nextKey, nil
+}
+
+// GetStats returns the number of keys and the total size (bytes of keys and values) with the given keyPrefix in etcd.
+func (s *EtcdStore) GetStats(keyPrefix string) (int64, int64, error) {
+	return s.GetStatsWith(s.ctx, keyPrefix)
+}
+
+// GetStatsWith returns the number of keys and the total size with the given keyPrefix in etcd using the provided context.
+// The pairs are read page by page to bound the memory usage.
+func (s *EtcdStore) GetStatsWith(ctx context.Context, keyPrefix string) (int64, int64, error) {
+	const pageSize = 1000
+	var count, size int64
+	startKey := keyPrefix
+	if startKey == "" {
+		startKey = "\x00"
+	}
+	for {
+		resp, err := s.cli.Get(ctx, startKey,
+			clientv3.WithRange(clientv3.GetPrefixRangeEnd(keyPrefix)),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+			clientv3.WithLimit(pageSize))
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get stats with keyPrefix: %w", err)
+		}
+		for _, kv := range resp.Kvs {
+			count++
+			size += int64(len(kv.Key) + len(kv.Value))
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return count, size, nil
+		}
+		startKey = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
 // Delete removes a key-value pair from etcd without using a context.
 func (s *EtcdStore) Delete(key string) error {
 	return s.DeleteWith(s.ctx, key)
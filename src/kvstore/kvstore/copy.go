@@ -0,0 +1,61 @@
+package kvstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// copyBatchSize is the number of keys written in a transaction (etcd allows 128 operations in a transaction by default)
+const copyBatchSize = 100
+
+// CopyStats is the result of CopyStore
+type CopyStats struct {
+	Total   int64
+	Copied  int64
+	Skipped int64
+}
+
+// CopyStore copies all key-value pairs with the given keyPrefix from src to dst in the order of keys.
+// The existing keys of dst are kept unless overwrite is true.
+// The pairs are written in batches, so the copied pairs are kept in dst if an error is returned.
+func CopyStore(ctx context.Context, src Store, dst Store, keyPrefix string, overwrite bool) (CopyStats, error) {
+	stats := CopyStats{}
+	if src == nil || dst == nil {
+		return stats, fmt.Errorf("source or target store is nil")
+	}
+
+	startKey := keyPrefix
+	for {
+		kvs, nextKey, err := src.GetKvListPageWith(ctx, keyPrefix, startKey, copyBatchSize, false)
+		if err != nil {
+			return stats, fmt.Errorf("failed to read the source store from %q: %w", startKey, err)
+		}
+		stats.Total += int64(len(kvs))
+
+		puts := []KeyValue{}
+		for _, kv := range kvs {
+			if !overwrite {
+				create, _, err := dst.GetRevisionWith(ctx, kv.Key)
+				if err != nil {
+					return stats, fmt.Errorf("failed to read the target store (%s): %w", kv.Key, err)
+				}
+				if create != 0 {
+					stats.Skipped++
+					continue
+				}
+			}
+			puts = append(puts, kv)
+		}
+		if len(puts) > 0 {
+			if err := dst.PutMultiWith(ctx, puts); err != nil {
+				return stats, fmt.Errorf("failed to write the target store from %q: %w", puts[0].Key, err)
+			}
+			stats.Copied += int64(len(puts))
+		}
+
+		if nextKey == "" {
+			return stats, nil
+		}
+		startKey = nextKey
+	}
+}
@@ -32,6 +32,20 @@ type Store interface {
 	GetSortedKvListWith(ctx context.Context, keyPrefix string, sortBy clientv3.SortTarget, order clientv3.SortOrder) ([]KeyValue, error)
 	GetKvMap(keyPrefix string) (KeyValueMap, error)
 	GetKvMapWith(ctx context.Context, keyPrefix string) (KeyValueMap, error)
+	GetKvListPage(keyPrefix string, startKey string, limit int64, keysOnly bool) ([]KeyValue, string, error)
+	GetKvListPageWith(ctx context.Context, keyPrefix string, startKey string, limit int64, keysOnly bool) ([]KeyValue, string, error)
+	GetStats(keyPrefix string) (int64, int64, error)
+	GetStatsWith(ctx context.Context, keyPrefix string) (int64, int64, error)
+	GetRevision(key string) (int64, int64, error)
+	GetRevisionWith(ctx context.Context, key string) (int64, int64, error)
+	GetKvRevision(key string) (KeyValue, int64, error)
+	GetKvRevisionWith(ctx context.Context, key string) (KeyValue, int64, error)
+	PutMulti(kvs []KeyValue) error
+	PutMultiWith(ctx context.Context, kvs []KeyValue) error
+	Txn(compares []TxnCompare, puts []KeyValue, deletes []string) (bool, error)
+	TxnWith(ctx context.Context, compares []TxnCompare, puts []KeyValue, deletes []string) (bool, error)
+	TxnRevision(compares []TxnCompare, puts []KeyValue, deletes []string) (bool, int64, error)
+	TxnRevisionWith(ctx context.Context, compares []TxnCompare, puts []KeyValue, deletes []string) (bool, int64, error)
 	Delete(key string) error
 	DeleteWith(ctx context.Context, key string) error
 	WatchKey(key string) clientv3.WatchChan
@@ -48,6 +62,15 @@ type KeyValue struct {
 	Value string `json:"value"`
 }
 
+// TxnCompare is a condition of a transaction on the revision of a key
+type TxnCompare struct {
+	Key string
+	// Revision is the expected revision (0 means the key does not exist)
+	Revision int64
+	// Create compares the create revision (unchanged while the key exists) instead of the mod revision
+	Create bool
+}
+
 // KeyValueMap represents a key-value pair.
 type KeyValueMap map[string]string
 
@@ -79,6 +102,11 @@ func getStore() (Store, error) {
 	return globalStore, nil
 }
 
+// CurrentStore returns the initialized global Store (e.g., to copy its keys to another Store)
+func CurrentStore() (Store, error) {
+	return getStore()
+}
+
 // NewSession creates a new session
 func NewSession(ctx context.Context) (*concurrency.Session, error) {
 	store, err := getStore()
@@ -223,6 +251,135 @@ func GetKvMapWith(ctx context.Context, keyPrefix string) (KeyValueMap, error) {
 	return store.GetKvMapWith(ctx, keyPrefix)
 }
 
+// GetRevision returns the create and mod revisions of the key (0, 0 if the key does not exist)
+func GetRevision(key string) (int64, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return 0, 0, err
+	}
+	return store.GetRevision(key)
+}
+
+// GetRevisionWith returns the create and mod revisions of the key using the provided context
+func GetRevisionWith(ctx context.Context, key string) (int64, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return 0, 0, err
+	}
+	return store.GetRevisionWith(ctx, key)
+}
+
+// GetKvRevision returns the key-value pair and its mod revision in one read (0 if the key does not exist)
+func GetKvRevision(key string) (KeyValue, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return KeyValue{}, 0, err
+	}
+	return store.GetKvRevision(key)
+}
+
+// GetKvRevisionWith returns the key-value pair and its mod revision in one read using the provided context
+func GetKvRevisionWith(ctx context.Context, key string) (KeyValue, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return KeyValue{}, 0, err
+	}
+	return store.GetKvRevisionWith(ctx, key)
+}
+
+// PutMulti stores the key-value pairs atomically (all or nothing)
+func PutMulti(kvs []KeyValue) error {
+	store, err := getStore()
+	if err != nil {
+		return err
+	}
+	return store.PutMulti(kvs)
+}
+
+// PutMultiWith stores the key-value pairs atomically using the provided context
+func PutMultiWith(ctx context.Context, kvs []KeyValue) error {
+	store, err := getStore()
+	if err != nil {
+		return err
+	}
+	return store.PutMultiWith(ctx, kvs)
+}
+
+// Txn stores and deletes the keys atomically if all compares hold (compare-and-swap).
+// It returns false without any change if a compare does not hold.
+func Txn(compares []TxnCompare, puts []KeyValue, deletes []string) (bool, error) {
+	store, err := getStore()
+	if err != nil {
+		return false, err
+	}
+	return store.Txn(compares, puts, deletes)
+}
+
+// TxnWith stores and deletes the keys atomically if all compares hold using the provided context
+func TxnWith(ctx context.Context, compares []TxnCompare, puts []KeyValue, deletes []string) (bool, error) {
+	store, err := getStore()
+	if err != nil {
+		return false, err
+	}
+	return store.TxnWith(ctx, compares, puts, deletes)
+}
+
+// TxnRevision is Txn which also returns the revision of the transaction (the mod revision of the put keys).
+// The revision is 0 if the transaction did not change any key.
+func TxnRevision(compares []TxnCompare, puts []KeyValue, deletes []string) (bool, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return false, 0, err
+	}
+	return store.TxnRevision(compares, puts, deletes)
+}
+
+// TxnRevisionWith is TxnWith which also returns the revision of the transaction using the provided context
+func TxnRevisionWith(ctx context.Context, compares []TxnCompare, puts []KeyValue, deletes []string) (bool, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return false, 0, err
+	}
+	return store.TxnRevisionWith(ctx, compares, puts, deletes)
+}
+
+// GetKvListPage retrieves up to limit key-value pairs with the given prefix from startKey (inclusive).
+// It returns the start key of the next page ("" if no more pairs). Values are empty if keysOnly is true.
+func GetKvListPage(keyPrefix string, startKey string, limit int64, keysOnly bool) ([]KeyValue, string, error) {
+	store, err := getStore()
+	if err != nil {
+		return nil, "", err
+	}
+	return store.GetKvListPage(keyPrefix, startKey, limit, keysOnly)
+}
+
+// GetKvListPageWith retrieves a page of key-value pairs with the given prefix using the provided context
+func GetKvListPageWith(ctx context.Context, keyPrefix string, startKey string, limit int64, keysOnly bool) ([]KeyValue, string, error) {
+	store, err := getStore()
+	if err != nil {
+		return nil, "", err
+	}
+	return store.GetKvListPageWith(ctx, keyPrefix, startKey, limit, keysOnly)
+}
+
+// GetStats returns the number of keys and the total size (bytes of keys and values) with the given prefix
+func GetStats(keyPrefix string) (int64, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return 0, 0, err
+	}
+	return store.GetStats(keyPrefix)
+}
+
+// GetStatsWith returns the number of keys and the total size with the given prefix using the provided context
+func GetStatsWith(ctx context.Context, keyPrefix string) (int64, int64, error) {
+	store, err := getStore()
+	if err != nil {
+		return 0, 0, err
+	}
+	return store.GetStatsWith(ctx, keyPrefix)
+}
+
 // Detete removes a key-value pair
 func Delete(key string) error {
 	store, err := getStore()
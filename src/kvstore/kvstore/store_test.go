@@ -0,0 +1,201 @@
+package kvstore_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/bolt"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/etcd"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
+)
+
+// testBackends returns the stores to run the same cases against: the embedded store always,
+// and etcd if TB_TEST_ETCD_ENDPOINTS (comma-separated) is set
+func testBackends(t *testing.T) map[string]kvstore.Store {
+	t.Helper()
+	backends := map[string]kvstore.Store{}
+
+	boltStore, err := bolt.NewBoltStore(context.Background(), bolt.Config{Path: filepath.Join(t.TempDir(), "kvstore.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { boltStore.Close() })
+	backends["bolt"] = boltStore
+
+	if endpoints := os.Getenv("TB_TEST_ETCD_ENDPOINTS"); endpoints != "" {
+		etcdStore, err := etcd.NewEtcdStore(context.Background(), etcd.Config{Endpoints: strings.Split(endpoints, ","), DialTimeout: 5 * time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		backends["etcd"] = etcdStore
+	} else {
+		t.Log("etcd is skipped (TB_TEST_ETCD_ENDPOINTS is not set)")
+	}
+	return backends
+}
+
+// TestStoreBackends runs the operations of the resource handlers (create if missing, list of a namespace,
+// update on the revision, paging and delete) against each backend.
+func TestStoreBackends(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store kvstore.Store, root string)
+	}{
+		{"create if missing", func(t *testing.T, store kvstore.Store, root string) {
+			key := root + "/ns/ns01/resources/vNet/vnet01"
+			create := []kvstore.TxnCompare{{Key: key, Revision: 0}}
+			if succeeded, err := store.Txn(create, []kvstore.KeyValue{{Key: key, Value: `{"id":"vnet01"}`}}, nil); err != nil || !succeeded {
+				t.Fatalf("create: %v (%v)", succeeded, err)
+			}
+			if succeeded, err := store.Txn(create, []kvstore.KeyValue{{Key: key, Value: `{"id":"duplicated"}`}}, nil); err != nil || succeeded {
+				t.Fatalf("duplicated create: %v (%v)", succeeded, err)
+			}
+			if value, _ := store.Get(key); value != `{"id":"vnet01"}` {
+				t.Errorf("value: got %s", value)
+			}
+		}},
+		{"list of a namespace", func(t *testing.T, store kvstore.Store, root string) {
+			for _, key := range []string{"/ns/ns01/resources/sshKey/key02", "/ns/ns01/resources/sshKey/key01", "/ns/ns010/resources/sshKey/key01", "/ns/ns01/resources/sshKeyX/key01"} {
+				if err := store.Put(root+key, key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			kvs, err := store.GetKvList(root + "/ns/ns01/resources/sshKey/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []kvstore.KeyValue{
+				{Key: root + "/ns/ns01/resources/sshKey/key01", Value: "/ns/ns01/resources/sshKey/key01"},
+				{Key: root + "/ns/ns01/resources/sshKey/key02", Value: "/ns/ns01/resources/sshKey/key02"},
+			}
+			if !reflect.DeepEqual(kvs, want) {
+				t.Errorf("got %v, want %v", kvs, want)
+			}
+			if kvs, err := store.GetKvList(root + "/ns/ns02/"); err != nil || len(kvs) != 0 {
+				t.Errorf("list of an empty namespace: got %v (%v)", kvs, err)
+			}
+		}},
+		{"update on the revision", func(t *testing.T, store kvstore.Store, root string) {
+			key := root + "/ns/ns01/mci/mci01"
+			if err := store.Put(key, "v1"); err != nil {
+				t.Fatal(err)
+			}
+			kv, revision, err := store.GetKvRevision(key)
+			if err != nil || kv.Value != "v1" || revision == 0 {
+				t.Fatalf("got %+v, %d (%v)", kv, revision, err)
+			}
+			if succeeded, err := store.Txn([]kvstore.TxnCompare{{Key: key, Revision: revision}}, []kvstore.KeyValue{{Key: key, Value: "v2"}}, nil); err != nil || !succeeded {
+				t.Fatalf("update on the revision: %v (%v)", succeeded, err)
+			}
+			// the revision read before the first update is stale
+			if succeeded, err := store.Txn([]kvstore.TxnCompare{{Key: key, Revision: revision}}, []kvstore.KeyValue{{Key: key, Value: "v3"}}, nil); err != nil || succeeded {
+				t.Fatalf("update on a stale revision: %v (%v)", succeeded, err)
+			}
+			createRevision, modRevision, err := store.GetRevision(key)
+			if err != nil || createRevision != revision || modRevision <= revision {
+				t.Errorf("revisions: got %d, %d (%v) after %d", createRevision, modRevision, err, revision)
+			}
+			if value, _ := store.Get(key); value != "v2" {
+				t.Errorf("value: got %s, want v2", value)
+			}
+		}},
+		{"missing key and empty value", func(t *testing.T, store kvstore.Store, root string) {
+			if err := store.Put(root+"/empty", ""); err != nil {
+				t.Fatal(err)
+			}
+			if kv, err := store.GetKv(root + "/missing"); err != nil || kv != (kvstore.KeyValue{}) {
+				t.Errorf("missing key: got %+v (%v)", kv, err)
+			}
+			if kv, err := store.GetKv(root + "/empty"); err != nil || kv != (kvstore.KeyValue{Key: root + "/empty"}) {
+				t.Errorf("empty value: got %+v (%v)", kv, err)
+			}
+		}},
+		{"paging", func(t *testing.T, store kvstore.Store, root string) {
+			kvs := []kvstore.KeyValue{}
+			for i := 0; i < 5; i++ {
+				kvs = append(kvs, kvstore.KeyValue{Key: fmt.Sprintf("%s/ns/ns01/vm/vm%02d", root, i), Value: "vm"})
+			}
+			if err := store.PutMulti(kvs); err != nil {
+				t.Fatal(err)
+			}
+			keys := []string{}
+			startKey := ""
+			for pages := 0; ; pages++ {
+				if pages > len(kvs) {
+					t.Fatalf("no end of pages: %v", keys)
+				}
+				page, nextKey, err := store.GetKvListPage(root+"/ns/ns01/vm/", startKey, 2, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, kv := range page {
+					keys = append(keys, strings.TrimPrefix(kv.Key, root))
+				}
+				if nextKey == "" {
+					break
+				}
+				startKey = nextKey
+			}
+			want := []string{"/ns/ns01/vm/vm00", "/ns/ns01/vm/vm01", "/ns/ns01/vm/vm02", "/ns/ns01/vm/vm03", "/ns/ns01/vm/vm04"}
+			if !reflect.DeepEqual(keys, want) {
+				t.Errorf("got %v, want %v", keys, want)
+			}
+		}},
+		{"delete", func(t *testing.T, store kvstore.Store, root string) {
+			key := root + "/ns/ns01/resources/securityGroup/sg01"
+			if err := store.Put(key, "sg01"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Delete(key); err != nil {
+				t.Fatal(err)
+			}
+			if kv, _ := store.GetKv(key); kv.Key != "" {
+				t.Errorf("not deleted: %+v", kv)
+			}
+			// deleting a missing key is not an error
+			if err := store.Delete(key); err != nil {
+				t.Errorf("delete of a missing key: %v", err)
+			}
+		}},
+		{"watch", func(t *testing.T, store kvstore.Store, root string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ch := store.WatchKeysWith(ctx, root+"/ns/ns01/mci/")
+			if err := store.Put(root+"/ns/ns01/mci/mci01", "Creating"); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case resp := <-ch:
+				if len(resp.Events) != 1 || string(resp.Events[0].Kv.Value) != "Creating" {
+					t.Errorf("got %v", resp.Events)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no event")
+			}
+		}},
+	}
+
+	for name, store := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, tt := range tests {
+				// the keys of each case are under their own root (an etcd server can be shared)
+				root := fmt.Sprintf("/tb-test/%d/%d", time.Now().UnixNano(), i)
+				t.Run(tt.name, func(t *testing.T) {
+					defer func() {
+						kvs, _ := store.GetKvList(root + "/")
+						for _, kv := range kvs {
+							store.Delete(kv.Key)
+						}
+					}()
+					tt.run(t, store, root)
+				})
+			}
+		})
+	}
+}
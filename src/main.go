@@ -28,7 +28,7 @@ import (
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common/logger"
 	"github.com/cloud-barista/cb-tumblebug/src/core/model"
-	"github.com/cloud-barista/cb-tumblebug/src/kvstore/etcd"
+	"github.com/cloud-barista/cb-tumblebug/src/kvstore/driver"
 	"github.com/cloud-barista/cb-tumblebug/src/kvstore/kvstore"
 	"github.com/rs/zerolog/log"
 
@@ -39,7 +39,9 @@ import (
 
 	"github.com/cloud-barista/cb-tumblebug/src/core/common"
 	"github.com/cloud-barista/cb-tumblebug/src/core/infra"
+	"github.com/cloud-barista/cb-tumblebug/src/core/resource"
 
+	grpcServer "github.com/cloud-barista/cb-tumblebug/src/api/grpc/server"
 	restServer "github.com/cloud-barista/cb-tumblebug/src/api/rest/server"
 
 	"xorm.io/xorm"
@@ -65,6 +67,10 @@ func init() {
 	// Etcd
 	model.EtcdEndpoints = common.NVL(os.Getenv("TB_ETCD_ENDPOINTS"), "localhost:2379")
 
+	// Kvstore backend (etcd, or bolt for the embedded store of single-node deployments)
+	model.KvStoreType = common.NVL(os.Getenv("TB_KVSTORE_TYPE"), driver.TypeEtcd)
+	model.KvStoreBoltPath = common.NVL(os.Getenv("TB_KVSTORE_BOLT_PATH"), "../meta_db/dat/kvstore.db")
+
 	// load the latest configuration from DB (if exist)
 
 	log.Info().Msg("[Update system environment]")
@@ -236,6 +242,29 @@ func setConfig() {
 		panic(err)
 	}
 
+	//
+	// Load softwarecatalog
+	//
+	softwareCatalogViper := viper.New()
+	fileName = "softwarecatalog"
+	softwareCatalogViper.AddConfigPath(".")
+	softwareCatalogViper.AddConfigPath("./assets/")
+	softwareCatalogViper.AddConfigPath("../assets/")
+	softwareCatalogViper.SetConfigName(fileName)
+	softwareCatalogViper.SetConfigType("yaml")
+	err = softwareCatalogViper.ReadInConfig()
+	if err != nil {
+		panic(fmt.Errorf("fatal error reading softwarecatalog config file: %w", err))
+	}
+
+	log.Info().Msg(softwareCatalogViper.ConfigFileUsed())
+	err = softwareCatalogViper.Unmarshal(&infra.RuntimeSoftwareCatalog)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		panic(err)
+	}
+	log.Info().Msgf("Software catalog: %d software", len(infra.RuntimeSoftwareCatalog.Software))
+
 	//
 	// Wait until CB-Spider is ready
 	//
@@ -256,59 +285,101 @@ func setConfig() {
 		panic("Failed to confirm CB-Spider readiness within the allowed time. \nCheck the connection to CB-Spider.")
 	}
 
-	// Setup etcd and kvstore
-	var etcdAuthEnabled bool
-	var etcdUsername string
-	var etcdPassword string
-	etcdAuthEnabled = os.Getenv("TB_ETCD_AUTH_ENABLED") == "true"
-	if etcdAuthEnabled {
-		etcdUsername = os.Getenv("TB_ETCD_USERNAME")
-		etcdPassword = os.Getenv("TB_ETCD_PASSWORD")
-	}
-
-	etcdEndpoints := strings.Split(model.EtcdEndpoints, ",")
+	// Check the version of CB-Spider against the supported range
+	common.DetectSpiderVersion()
 
+	// Setup kvstore (etcd or the embedded store)
 	ctx := context.Background()
-	config := etcd.Config{
-		Endpoints:   etcdEndpoints,
-		DialTimeout: 5 * time.Second,
-	}
-	if etcdAuthEnabled && etcdUsername != "" && etcdPassword != "" {
-		config.Username = etcdUsername
-		config.Password = etcdPassword
-	}
-
-	// Wait until etcd is ready
-	var etcdStore kvstore.Store
+	var kvStore kvstore.Store
 	var err2 error
-	etcdMaxAttempts := 10 // (50 sec)
-	etcdAttempt := 1
-	for ; etcdAttempt <= etcdMaxAttempts; etcdAttempt++ {
-		etcdStore, err2 = etcd.NewEtcdStore(ctx, config)
-		if err2 == nil {
-			log.Info().Msg("etcd is now available.")
-			break
+
+	switch model.KvStoreType {
+	case driver.TypeBolt:
+		kvStore, err2 = driver.NewStore(ctx, driver.Config{Type: driver.TypeBolt, BoltPath: model.KvStoreBoltPath})
+		if err2 != nil {
+			log.Fatal().Err(err2).Msg("failed to initialize the embedded kvstore")
+		}
+		log.Info().Msgf("embedded kvstore (%s) is now available.", model.KvStoreBoltPath)
+
+	case driver.TypeEtcd:
+		var etcdAuthEnabled bool
+		var etcdUsername string
+		var etcdPassword string
+		etcdAuthEnabled = os.Getenv("TB_ETCD_AUTH_ENABLED") == "true"
+		if etcdAuthEnabled {
+			etcdUsername = os.Getenv("TB_ETCD_USERNAME")
+			etcdPassword = os.Getenv("TB_ETCD_PASSWORD")
 		}
-		log.Warn().Err(err2).Msgf("etcd at %s is not ready. Attempt %d/%d", model.EtcdEndpoints, etcdAttempt, maxAttempts)
-		time.Sleep(5 * time.Second)
-	}
 
-	if err2 != nil {
-		log.Fatal().Err(err2).Msg("failed to initialize etcd")
+		etcdEndpoints := strings.Split(model.EtcdEndpoints, ",")
+
+		config := driver.Config{
+			Type:            driver.TypeEtcd,
+			EtcdEndpoints:   etcdEndpoints,
+			EtcdDialTimeout: 5 * time.Second,
+		}
+		if etcdAuthEnabled && etcdUsername != "" && etcdPassword != "" {
+			config.EtcdUsername = etcdUsername
+			config.EtcdPassword = etcdPassword
+		}
+
+		// Wait until etcd is ready
+		etcdMaxAttempts := 10 // (50 sec)
+		etcdAttempt := 1
+		for ; etcdAttempt <= etcdMaxAttempts; etcdAttempt++ {
+			kvStore, err2 = driver.NewStore(ctx, config)
+			if err2 == nil {
+				log.Info().Msg("etcd is now available.")
+				break
+			}
+			log.Warn().Err(err2).Msgf("etcd at %s is not ready. Attempt %d/%d", model.EtcdEndpoints, etcdAttempt, maxAttempts)
+			time.Sleep(5 * time.Second)
+		}
+
+		if err2 != nil {
+			log.Fatal().Err(err2).Msg("failed to initialize etcd")
+		}
+
+	default:
+		log.Fatal().Msgf("unknown TB_KVSTORE_TYPE: %s (supported: %s, %s)", model.KvStoreType, driver.TypeEtcd, driver.TypeBolt)
 	}
 
-	err2 = kvstore.InitializeStore(etcdStore)
+	err2 = kvstore.InitializeStore(kvStore)
 	if err2 != nil {
 		log.Fatal().Err(err2).Msg("")
 	}
 	log.Info().Msg("kvstore is initialized successfully. Initializing CB-Tumblebug...")
 
+	// Apply custom regions added at runtime (kept in kvstore)
+	if err := common.LoadCustomRegions(); err != nil {
+		log.Warn().Err(err).Msg("Failed to load custom regions")
+	}
+
 	// Register all cloud info
-	err = common.RegisterAllCloudInfo()
+	registerSummary, err := common.RegisterAllCloudInfo()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to register cloud info")
 		panic(err)
 	}
+	for _, result := range registerSummary.Providers {
+		if result.DriverError != "" {
+			log.Error().Msgf("[%s] Failed to register the driver: %s", result.ProviderName, result.DriverError)
+			continue
+		}
+		if len(result.Failed) == 0 {
+			log.Info().Msgf("[%s] Registered %d regions", result.ProviderName, len(result.Succeeded))
+			continue
+		}
+		for _, failed := range result.Failed {
+			log.Warn().Msgf("[%s] Failed to register the region %s: %s", result.ProviderName, failed.Region, failed.Error)
+		}
+		log.Warn().Msgf("[%s] Registered %d regions, failed %d regions", result.ProviderName, len(result.Succeeded), len(result.Failed))
+	}
+
+	// Re-create connection configs lost in CB-Spider (e.g., CB-Spider restarted with a fresh database)
+	if _, err := common.SyncSpider(); err != nil {
+		log.Warn().Err(err).Msg("Failed to sync cloud info with CB-Spider")
+	}
 
 	// Load credentials
 	usr, err := user.Current()
@@ -416,6 +487,12 @@ func addIndexes() error {
 // @BasePath /tumblebug
 
 // @securityDefinitions.basic BasicAuth
+// @description Users with roles are set by TB_API_USERS or TB_API_USERS_FILE. The readonly role can call GET routes and read-only POST routes (e.g., lookupSpec, mciDynamicCheckRequest) only.
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key issued by POST /apikeys (TB_AUTH_MODE=apikey)
 
 // @securityDefinitions.apikey Bearer
 // @in header
@@ -437,6 +514,41 @@ func main() {
 	}()
 	defer ticker.Stop()
 
+	// Check VM auto-recovery policies (each policy is checked by its own interval)
+	infra.StartRecoveryController(10 * time.Second)
+
+	// Prune request details periodically by the retention policy
+	requestRetentionMinutes, _ := strconv.Atoi(common.NVL(os.Getenv("TB_REQUEST_RETENTION_MINUTES"), "1440"))
+	requestMaxCount, _ := strconv.Atoi(common.NVL(os.Getenv("TB_REQUEST_MAX_COUNT"), "10000"))
+	common.StartRequestRetention(time.Minute, time.Duration(requestRetentionMinutes)*time.Minute, requestMaxCount)
+
+	// Prune audit records periodically by the retention policy
+	auditRetentionDays, _ := strconv.Atoi(common.NVL(os.Getenv("TB_AUDIT_RETENTION_DAYS"), "90"))
+	common.StartAuditRetention(time.Hour, auditRetentionDays)
+
+	// Prune namespace events periodically by the retention policy (and the cap per namespace)
+	nsEventRetentionDays, _ := strconv.Atoi(common.NVL(os.Getenv("TB_NS_EVENT_RETENTION_DAYS"), "30"))
+	common.StartNsEventRetention(time.Hour, nsEventRetentionDays)
+
+	// Purge soft-deleted MCIs and resources periodically by the retention policy of the trash
+	infra.StartTrashRetention(time.Hour)
+
+	// Refresh the number of objects per namespace for the metrics endpoint periodically
+	metricsRefreshSec, err := strconv.Atoi(common.NVL(os.Getenv("TB_METRICS_REFRESH_SEC"), "60"))
+	if err != nil || metricsRefreshSec <= 0 {
+		metricsRefreshSec = 60
+	}
+	common.StartMetricsCollector(time.Duration(metricsRefreshSec) * time.Second)
+
+	// Refresh the status of VMs periodically so that the status of MCIs is served from the stored VM objects
+	infra.StartStatusReconciler()
+
+	// Mark jobs left unfinished by the previous server process as Interrupted
+	common.MarkOrphanedJobsInterrupted()
+
+	// Resume status pollers for K8sClusters in progress (Creating, Updating, Deleting)
+	go resource.ResumeK8sClusterStatusPollers()
+
 	go func() {
 		viper.WatchConfig()
 		viper.OnConfigChange(func(e fsnotify.Event) {
@@ -454,7 +566,7 @@ func main() {
 		})
 	}()
 
-	// Launch API servers (REST)
+	// Launch API servers (REST and gRPC)
 	wg := new(sync.WaitGroup)
 	wg.Add(1)
 
@@ -464,5 +576,14 @@ func main() {
 		wg.Done()
 	}()
 
+	// Start gRPC Server (optional, only if TB_GRPC_PORT is set)
+	if grpcPort := os.Getenv("TB_GRPC_PORT"); grpcPort != "" {
+		wg.Add(1)
+		go func() {
+			grpcServer.RunServer(grpcPort)
+			wg.Done()
+		}()
+	}
+
 	wg.Wait()
 }